@@ -0,0 +1,425 @@
+// Protobuf envelope of the plugin ABI.
+//
+// Plugins exporting process_pb(ptr, len) receive a serialized
+// PluginRequest and return a serialized PluginResponse, so inputs and
+// outputs of several typed fields look the same to plugins written in any
+// language with a protobuf library that compiles to WebAssembly. The call
+// sequence is that of process_bytes; see ABI.md.
+//
+// Regenerate the Go code after editing this file:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative abi/abi.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: abi/abi.proto
+
+package abi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// PluginRequest is the input of process_pb.
+type PluginRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Fields are the named inputs of the call.
+	Fields map[string]*Value `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// RequestId tags the call's logs; empty outside a server request.
+	RequestId     string `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginRequest) Reset() {
+	*x = PluginRequest{}
+	mi := &file_abi_abi_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginRequest) ProtoMessage() {}
+
+func (x *PluginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_abi_abi_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginRequest.ProtoReflect.Descriptor instead.
+func (*PluginRequest) Descriptor() ([]byte, []int) {
+	return file_abi_abi_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PluginRequest) GetFields() map[string]*Value {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *PluginRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+// PluginResponse is the output of process_pb.
+type PluginResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Fields are the named outputs of the call.
+	Fields        map[string]*Value `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginResponse) Reset() {
+	*x = PluginResponse{}
+	mi := &file_abi_abi_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginResponse) ProtoMessage() {}
+
+func (x *PluginResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_abi_abi_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginResponse.ProtoReflect.Descriptor instead.
+func (*PluginResponse) Descriptor() ([]byte, []int) {
+	return file_abi_abi_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PluginResponse) GetFields() map[string]*Value {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// Value is one typed field of a request or response.
+type Value struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Kind:
+	//
+	//	*Value_BoolValue
+	//	*Value_IntValue
+	//	*Value_DoubleValue
+	//	*Value_StringValue
+	//	*Value_BytesValue
+	//	*Value_ListValue
+	Kind          isValue_Kind `protobuf_oneof:"kind"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Value) Reset() {
+	*x = Value{}
+	mi := &file_abi_abi_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Value) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Value) ProtoMessage() {}
+
+func (x *Value) ProtoReflect() protoreflect.Message {
+	mi := &file_abi_abi_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Value.ProtoReflect.Descriptor instead.
+func (*Value) Descriptor() ([]byte, []int) {
+	return file_abi_abi_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Value) GetKind() isValue_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return nil
+}
+
+func (x *Value) GetBoolValue() bool {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_BoolValue); ok {
+			return x.BoolValue
+		}
+	}
+	return false
+}
+
+func (x *Value) GetIntValue() int64 {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_IntValue); ok {
+			return x.IntValue
+		}
+	}
+	return 0
+}
+
+func (x *Value) GetDoubleValue() float64 {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_DoubleValue); ok {
+			return x.DoubleValue
+		}
+	}
+	return 0
+}
+
+func (x *Value) GetStringValue() string {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_StringValue); ok {
+			return x.StringValue
+		}
+	}
+	return ""
+}
+
+func (x *Value) GetBytesValue() []byte {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_BytesValue); ok {
+			return x.BytesValue
+		}
+	}
+	return nil
+}
+
+func (x *Value) GetListValue() *ListValue {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_ListValue); ok {
+			return x.ListValue
+		}
+	}
+	return nil
+}
+
+type isValue_Kind interface {
+	isValue_Kind()
+}
+
+type Value_BoolValue struct {
+	BoolValue bool `protobuf:"varint,1,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type Value_IntValue struct {
+	IntValue int64 `protobuf:"varint,2,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type Value_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,3,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type Value_StringValue struct {
+	StringValue string `protobuf:"bytes,4,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type Value_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,5,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+type Value_ListValue struct {
+	ListValue *ListValue `protobuf:"bytes,6,opt,name=list_value,json=listValue,proto3,oneof"`
+}
+
+func (*Value_BoolValue) isValue_Kind() {}
+
+func (*Value_IntValue) isValue_Kind() {}
+
+func (*Value_DoubleValue) isValue_Kind() {}
+
+func (*Value_StringValue) isValue_Kind() {}
+
+func (*Value_BytesValue) isValue_Kind() {}
+
+func (*Value_ListValue) isValue_Kind() {}
+
+// ListValue is a repeated field, whose values may differ in type.
+type ListValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []*Value               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListValue) Reset() {
+	*x = ListValue{}
+	mi := &file_abi_abi_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListValue) ProtoMessage() {}
+
+func (x *ListValue) ProtoReflect() protoreflect.Message {
+	mi := &file_abi_abi_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListValue.ProtoReflect.Descriptor instead.
+func (*ListValue) Descriptor() ([]byte, []int) {
+	return file_abi_abi_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListValue) GetValues() []*Value {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+var File_abi_abi_proto protoreflect.FileDescriptor
+
+const file_abi_abi_proto_rawDesc = "" +
+	"\n" +
+	"\rabi/abi.proto\x12\x11wasmplugin.abi.v1\"\xc9\x01\n" +
+	"\rPluginRequest\x12D\n" +
+	"\x06fields\x18\x01 \x03(\v2,.wasmplugin.abi.v1.PluginRequest.FieldsEntryR\x06fields\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x1aS\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.wasmplugin.abi.v1.ValueR\x05value:\x028\x01\"\xac\x01\n" +
+	"\x0ePluginResponse\x12E\n" +
+	"\x06fields\x18\x01 \x03(\v2-.wasmplugin.abi.v1.PluginResponse.FieldsEntryR\x06fields\x1aS\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.wasmplugin.abi.v1.ValueR\x05value:\x028\x01\"\xfb\x01\n" +
+	"\x05Value\x12\x1f\n" +
+	"\n" +
+	"bool_value\x18\x01 \x01(\bH\x00R\tboolValue\x12\x1d\n" +
+	"\tint_value\x18\x02 \x01(\x03H\x00R\bintValue\x12#\n" +
+	"\fdouble_value\x18\x03 \x01(\x01H\x00R\vdoubleValue\x12#\n" +
+	"\fstring_value\x18\x04 \x01(\tH\x00R\vstringValue\x12!\n" +
+	"\vbytes_value\x18\x05 \x01(\fH\x00R\n" +
+	"bytesValue\x12=\n" +
+	"\n" +
+	"list_value\x18\x06 \x01(\v2\x1c.wasmplugin.abi.v1.ListValueH\x00R\tlistValueB\x06\n" +
+	"\x04kind\"=\n" +
+	"\tListValue\x120\n" +
+	"\x06values\x18\x01 \x03(\v2\x18.wasmplugin.abi.v1.ValueR\x06valuesB,Z*github.com/mrhapile/wasm-plugin-system/abib\x06proto3"
+
+var (
+	file_abi_abi_proto_rawDescOnce sync.Once
+	file_abi_abi_proto_rawDescData []byte
+)
+
+func file_abi_abi_proto_rawDescGZIP() []byte {
+	file_abi_abi_proto_rawDescOnce.Do(func() {
+		file_abi_abi_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_abi_abi_proto_rawDesc), len(file_abi_abi_proto_rawDesc)))
+	})
+	return file_abi_abi_proto_rawDescData
+}
+
+var file_abi_abi_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_abi_abi_proto_goTypes = []any{
+	(*PluginRequest)(nil),  // 0: wasmplugin.abi.v1.PluginRequest
+	(*PluginResponse)(nil), // 1: wasmplugin.abi.v1.PluginResponse
+	(*Value)(nil),          // 2: wasmplugin.abi.v1.Value
+	(*ListValue)(nil),      // 3: wasmplugin.abi.v1.ListValue
+	nil,                    // 4: wasmplugin.abi.v1.PluginRequest.FieldsEntry
+	nil,                    // 5: wasmplugin.abi.v1.PluginResponse.FieldsEntry
+}
+var file_abi_abi_proto_depIdxs = []int32{
+	4, // 0: wasmplugin.abi.v1.PluginRequest.fields:type_name -> wasmplugin.abi.v1.PluginRequest.FieldsEntry
+	5, // 1: wasmplugin.abi.v1.PluginResponse.fields:type_name -> wasmplugin.abi.v1.PluginResponse.FieldsEntry
+	3, // 2: wasmplugin.abi.v1.Value.list_value:type_name -> wasmplugin.abi.v1.ListValue
+	2, // 3: wasmplugin.abi.v1.ListValue.values:type_name -> wasmplugin.abi.v1.Value
+	2, // 4: wasmplugin.abi.v1.PluginRequest.FieldsEntry.value:type_name -> wasmplugin.abi.v1.Value
+	2, // 5: wasmplugin.abi.v1.PluginResponse.FieldsEntry.value:type_name -> wasmplugin.abi.v1.Value
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_abi_abi_proto_init() }
+func file_abi_abi_proto_init() {
+	if File_abi_abi_proto != nil {
+		return
+	}
+	file_abi_abi_proto_msgTypes[2].OneofWrappers = []any{
+		(*Value_BoolValue)(nil),
+		(*Value_IntValue)(nil),
+		(*Value_DoubleValue)(nil),
+		(*Value_StringValue)(nil),
+		(*Value_BytesValue)(nil),
+		(*Value_ListValue)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_abi_abi_proto_rawDesc), len(file_abi_abi_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_abi_abi_proto_goTypes,
+		DependencyIndexes: file_abi_abi_proto_depIdxs,
+		MessageInfos:      file_abi_abi_proto_msgTypes,
+	}.Build()
+	File_abi_abi_proto = out.File
+	file_abi_abi_proto_goTypes = nil
+	file_abi_abi_proto_depIdxs = nil
+}
@@ -0,0 +1,15 @@
+package abi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestABI bootstraps the Ginkgo test suite for the abi package.
+// Run with: go test -v ./abi/...
+func TestABI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ABI Suite")
+}
@@ -0,0 +1,174 @@
+// Package abi holds the protobuf envelope of the plugin ABI: the
+// PluginRequest a process_pb export receives and the PluginResponse it
+// returns. The types are generated from abi.proto, which plugins in other
+// languages generate theirs from; the helpers here convert fields to and
+// from plain Go values.
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// NewBool returns a bool Value.
+func NewBool(v bool) *Value { return &Value{Kind: &Value_BoolValue{BoolValue: v}} }
+
+// NewInt returns an integer Value.
+func NewInt(v int64) *Value { return &Value{Kind: &Value_IntValue{IntValue: v}} }
+
+// NewDouble returns a floating-point Value.
+func NewDouble(v float64) *Value { return &Value{Kind: &Value_DoubleValue{DoubleValue: v}} }
+
+// NewString returns a string Value.
+func NewString(v string) *Value { return &Value{Kind: &Value_StringValue{StringValue: v}} }
+
+// NewBytes returns a bytes Value.
+func NewBytes(v []byte) *Value { return &Value{Kind: &Value_BytesValue{BytesValue: v}} }
+
+// NewList returns a list Value of values.
+func NewList(values ...*Value) *Value {
+	return &Value{Kind: &Value_ListValue{ListValue: &ListValue{Values: values}}}
+}
+
+// NewValue converts a Go value to a Value: bools, integers that fit in an
+// int64, floats, strings, byte slices, json.Numbers, and slices of these.
+// Maps and other types have no Value and are an error.
+func NewValue(v any) (*Value, error) {
+	switch v := v.(type) {
+	case *Value:
+		return v, nil
+	case bool:
+		return NewBool(v), nil
+	case int:
+		return NewInt(int64(v)), nil
+	case int8:
+		return NewInt(int64(v)), nil
+	case int16:
+		return NewInt(int64(v)), nil
+	case int32:
+		return NewInt(int64(v)), nil
+	case int64:
+		return NewInt(v), nil
+	case uint8:
+		return NewInt(int64(v)), nil
+	case uint16:
+		return NewInt(int64(v)), nil
+	case uint32:
+		return NewInt(int64(v)), nil
+	case uint:
+		return newUint(uint64(v))
+	case uint64:
+		return newUint(v)
+	case float32:
+		return NewDouble(float64(v)), nil
+	case float64:
+		return NewDouble(v), nil
+	case string:
+		return NewString(v), nil
+	case []byte:
+		return NewBytes(v), nil
+	case json.Number:
+		// Integer literals stay integers, so that JSON input keeps them
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				return NewInt(i), nil
+			}
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range", v)
+		}
+		return NewDouble(f), nil
+	case []any:
+		list := make([]*Value, len(v))
+		for i, item := range v {
+			value, err := NewValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			list[i] = value
+		}
+		return NewList(list...), nil
+	case nil:
+		return nil, fmt.Errorf("null has no protobuf value")
+	default:
+		return nil, fmt.Errorf("%T has no protobuf value", v)
+	}
+}
+
+// newUint returns an integer Value of v, which must fit in an int64.
+func newUint(v uint64) (*Value, error) {
+	if v > math.MaxInt64 {
+		return nil, fmt.Errorf("integer %d out of range", v)
+	}
+	return NewInt(int64(v)), nil
+}
+
+// NewFields converts a map of Go values to request or response fields, as
+// NewValue does each value.
+func NewFields(m map[string]any) (map[string]*Value, error) {
+	fields := make(map[string]*Value, len(m))
+	for name, v := range m {
+		value, err := NewValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+// NewFieldsJSON converts a JSON object to fields. Integer literals become
+// int values and other numbers double values; nested objects and nulls
+// are an error. Bytes cannot be written in JSON and come as strings.
+func NewFieldsJSON(data []byte) (map[string]*Value, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var m map[string]any
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("fields must be a JSON object: %w", err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("fields must be a JSON object")
+	}
+	return NewFields(m)
+}
+
+// AsInterface converts x to a Go value: a bool, int64, float64, string,
+// []byte, or []any of these. A Value without a kind is nil.
+func (x *Value) AsInterface() any {
+	switch kind := x.GetKind().(type) {
+	case *Value_BoolValue:
+		return kind.BoolValue
+	case *Value_IntValue:
+		return kind.IntValue
+	case *Value_DoubleValue:
+		return kind.DoubleValue
+	case *Value_StringValue:
+		return kind.StringValue
+	case *Value_BytesValue:
+		return kind.BytesValue
+	case *Value_ListValue:
+		values := kind.ListValue.GetValues()
+		list := make([]any, len(values))
+		for i, value := range values {
+			list[i] = value.AsInterface()
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// AsMap converts fields to a map of Go values, as AsInterface does each
+// value.
+func AsMap(fields map[string]*Value) map[string]any {
+	m := make(map[string]any, len(fields))
+	for name, value := range fields {
+		m[name] = value.AsInterface()
+	}
+	return m
+}
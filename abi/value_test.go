@@ -0,0 +1,77 @@
+package abi_test
+
+import (
+	"encoding/json"
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrhapile/wasm-plugin-system/abi"
+)
+
+var _ = Describe("Values", func() {
+	// =========================================================================
+	// TEST: Conversion from Go values
+	// Why: Callers build requests from plain Go and JSON values; each must
+	//      keep its type across the envelope, integers in particular.
+	// =========================================================================
+	DescribeTable("should convert Go values",
+		func(v any, want *abi.Value) {
+			value, err := abi.NewValue(v)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proto.Equal(value, want)).To(BeTrue(), "%v", value)
+		},
+		Entry("a bool", true, abi.NewBool(true)),
+		Entry("an int", 42, abi.NewInt(42)),
+		Entry("a uint32", uint32(7), abi.NewInt(7)),
+		Entry("a float", 1.5, abi.NewDouble(1.5)),
+		Entry("a string", "hi", abi.NewString("hi")),
+		Entry("bytes", []byte{1, 2}, abi.NewBytes([]byte{1, 2})),
+		Entry("an integer literal", json.Number("12"), abi.NewInt(12)),
+		Entry("a decimal literal", json.Number("12.0"), abi.NewDouble(12)),
+		Entry("a list", []any{1, "a"}, abi.NewList(abi.NewInt(1), abi.NewString("a"))),
+	)
+
+	It("should reject values without a protobuf kind", func() {
+		for _, v := range []any{nil, map[string]any{}, uint64(math.MaxUint64), []any{struct{}{}}} {
+			_, err := abi.NewValue(v)
+			Expect(err).To(HaveOccurred(), "%#v", v)
+		}
+	})
+
+	It("should convert JSON objects to fields", func() {
+		fields, err := abi.NewFieldsJSON([]byte(`{"count": 3, "ratio": 0.5, "name": "x", "tags": ["a", 1]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(abi.AsMap(fields)).To(Equal(map[string]any{
+			"count": int64(3),
+			"ratio": 0.5,
+			"name":  "x",
+			"tags":  []any{"a", int64(1)},
+		}))
+
+		for _, invalid := range []string{`[1]`, `null`, `{"a": {"b": 1}}`, `{"a": null}`, `{`} {
+			_, err := abi.NewFieldsJSON([]byte(invalid))
+			Expect(err).To(HaveOccurred(), invalid)
+		}
+	})
+
+	// =========================================================================
+	// TEST: Wire format
+	// Why: Plugins in other languages decode the envelope with their own
+	//      generated code; a round trip must preserve every field.
+	// =========================================================================
+	It("should round-trip requests through the wire format", func() {
+		fields, err := abi.NewFields(map[string]any{"n": 1, "data": []byte("raw"), "ok": false})
+		Expect(err).NotTo(HaveOccurred())
+		req := &abi.PluginRequest{Fields: fields, RequestId: "req-1"}
+
+		wire, err := proto.Marshal(req)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded abi.PluginRequest
+		Expect(proto.Unmarshal(wire, &decoded)).To(Succeed())
+		Expect(proto.Equal(&decoded, req)).To(BeTrue())
+		Expect(abi.AsMap(decoded.Fields)).To(Equal(map[string]any{"n": int64(1), "data": []byte("raw"), "ok": false}))
+	})
+})
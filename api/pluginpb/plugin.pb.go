@@ -0,0 +1,1051 @@
+// gRPC API of the plugin server.
+//
+// It mirrors the HTTP API described in api/openapi.yaml for callers that
+// would rather not pay for JSON: Execute is POST /run, ListPlugins is
+// GET /plugins. Failures carry the same stable codes as the HTTP problem
+// responses, as the reason of a google.rpc.ErrorInfo status detail.
+//
+// Regenerate the Go code after editing this file:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//	    api/pluginpb/plugin.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: api/pluginpb/plugin.proto
+
+package pluginpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ExecuteRequest selects a plugin and its input.
+//
+// The request ID tagging the plugin's log messages is read from the
+// x-request-id metadata key and returned in the x-request-id header.
+type ExecuteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Plugin name, e.g. "hello", optionally with a version: "hello@1.2.0"
+	// or "hello@latest".
+	Plugin string `protobuf:"bytes,1,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	// text and data select the memory-based payload ABI (process_bytes);
+	// otherwise number (default 0) is passed to process().
+	//
+	// Types that are valid to be assigned to Input:
+	//
+	//	*ExecuteRequest_Number
+	//	*ExecuteRequest_Text
+	//	*ExecuteRequest_Data
+	Input isExecuteRequest_Input `protobuf_oneof:"input"`
+	// Shortens the server's execution timeout for this call; 0 keeps it.
+	TimeoutMs int32 `protobuf:"varint,5,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	// Return the messages the plugin logged during the call.
+	IncludeLogs bool `protobuf:"varint,6,opt,name=include_logs,json=includeLogs,proto3" json:"include_logs,omitempty"`
+	// Run the call at most once per tenant, client, plugin, and key: a
+	// redelivery gets the first successful response back, with replayed
+	// set.
+	DedupKey string `protobuf:"bytes,7,opt,name=dedup_key,json=dedupKey,proto3" json:"dedup_key,omitempty"`
+	// Record the call's exports and host function calls. The trace is kept
+	// under the call's request ID for GET /debug/traces/{request_id} on the
+	// HTTP API; requires the server's DEBUG_TRACES.
+	Debug bool `protobuf:"varint,8,opt,name=debug,proto3" json:"debug,omitempty"`
+	// Version to run, e.g. "1.2.0"; the same as appending it to plugin.
+	Version       string `protobuf:"bytes,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteRequest) Reset() {
+	*x = ExecuteRequest{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteRequest) ProtoMessage() {}
+
+func (x *ExecuteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteRequest) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExecuteRequest) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetInput() isExecuteRequest_Input {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *ExecuteRequest) GetNumber() int32 {
+	if x != nil {
+		if x, ok := x.Input.(*ExecuteRequest_Number); ok {
+			return x.Number
+		}
+	}
+	return 0
+}
+
+func (x *ExecuteRequest) GetText() string {
+	if x != nil {
+		if x, ok := x.Input.(*ExecuteRequest_Text); ok {
+			return x.Text
+		}
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetData() []byte {
+	if x != nil {
+		if x, ok := x.Input.(*ExecuteRequest_Data); ok {
+			return x.Data
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteRequest) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *ExecuteRequest) GetIncludeLogs() bool {
+	if x != nil {
+		return x.IncludeLogs
+	}
+	return false
+}
+
+func (x *ExecuteRequest) GetDedupKey() string {
+	if x != nil {
+		return x.DedupKey
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetDebug() bool {
+	if x != nil {
+		return x.Debug
+	}
+	return false
+}
+
+func (x *ExecuteRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type isExecuteRequest_Input interface {
+	isExecuteRequest_Input()
+}
+
+type ExecuteRequest_Number struct {
+	Number int32 `protobuf:"varint,2,opt,name=number,proto3,oneof"`
+}
+
+type ExecuteRequest_Text struct {
+	Text string `protobuf:"bytes,3,opt,name=text,proto3,oneof"`
+}
+
+type ExecuteRequest_Data struct {
+	Data []byte `protobuf:"bytes,4,opt,name=data,proto3,oneof"`
+}
+
+func (*ExecuteRequest_Number) isExecuteRequest_Input() {}
+
+func (*ExecuteRequest_Text) isExecuteRequest_Input() {}
+
+func (*ExecuteRequest_Data) isExecuteRequest_Input() {}
+
+// ExecuteResponse carries the plugin's result.
+type ExecuteResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Result of process(), or the payload length in bytes for text and
+	// data requests. Unset when the plugin produced no output.
+	Output *int32 `protobuf:"varint,1,opt,name=output,proto3,oneof" json:"output,omitempty"`
+	// Payload output, in the same form as the request's input.
+	//
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ExecuteResponse_Text
+	//	*ExecuteResponse_Data
+	Payload isExecuteResponse_Payload `protobuf_oneof:"payload"`
+	// Non-fatal conditions observed during the call.
+	Warnings []*Warning `protobuf:"bytes,4,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// Plugin log messages, if the request asked for them.
+	Logs []*LogEntry `protobuf:"bytes,5,rep,name=logs,proto3" json:"logs,omitempty"`
+	// Recorded response of an earlier call with the same dedup key.
+	Replayed bool `protobuf:"varint,6,opt,name=replayed,proto3" json:"replayed,omitempty"`
+	// Outbox effects the plugin enqueued, committed for delivery.
+	Effects int32 `protobuf:"varint,7,opt,name=effects,proto3" json:"effects,omitempty"`
+	// Version of the build that ran; empty for an unversioned plugin.
+	Version       string `protobuf:"bytes,8,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteResponse) Reset() {
+	*x = ExecuteResponse{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteResponse) ProtoMessage() {}
+
+func (x *ExecuteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteResponse) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExecuteResponse) GetOutput() int32 {
+	if x != nil && x.Output != nil {
+		return *x.Output
+	}
+	return 0
+}
+
+func (x *ExecuteResponse) GetPayload() isExecuteResponse_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetText() string {
+	if x != nil {
+		if x, ok := x.Payload.(*ExecuteResponse_Text); ok {
+			return x.Text
+		}
+	}
+	return ""
+}
+
+func (x *ExecuteResponse) GetData() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*ExecuteResponse_Data); ok {
+			return x.Data
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetWarnings() []*Warning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetLogs() []*LogEntry {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetReplayed() bool {
+	if x != nil {
+		return x.Replayed
+	}
+	return false
+}
+
+func (x *ExecuteResponse) GetEffects() int32 {
+	if x != nil {
+		return x.Effects
+	}
+	return 0
+}
+
+func (x *ExecuteResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type isExecuteResponse_Payload interface {
+	isExecuteResponse_Payload()
+}
+
+type ExecuteResponse_Text struct {
+	Text string `protobuf:"bytes,2,opt,name=text,proto3,oneof"`
+}
+
+type ExecuteResponse_Data struct {
+	Data []byte `protobuf:"bytes,3,opt,name=data,proto3,oneof"`
+}
+
+func (*ExecuteResponse_Text) isExecuteResponse_Payload() {}
+
+func (*ExecuteResponse_Data) isExecuteResponse_Payload() {}
+
+// Warning describes a non-fatal condition, e.g. code "deprecated".
+type Warning struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Warning) Reset() {
+	*x = Warning{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Warning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Warning) ProtoMessage() {}
+
+func (x *Warning) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Warning.ProtoReflect.Descriptor instead.
+func (*Warning) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Warning) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Warning) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// LogEntry is one message a plugin logged.
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"` // "debug", "info", "warn", or "error"
+	Plugin        string                 `protobuf:"bytes,3,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	RequestId     string                 `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LogEntry) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *LogEntry) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogEntry) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *LogEntry) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *LogEntry) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ListPluginsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPluginsRequest) Reset() {
+	*x = ListPluginsRequest{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPluginsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPluginsRequest) ProtoMessage() {}
+
+func (x *ListPluginsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPluginsRequest.ProtoReflect.Descriptor instead.
+func (*ListPluginsRequest) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{4}
+}
+
+type ListPluginsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plugins       []*PluginInfo          `protobuf:"bytes,1,rep,name=plugins,proto3" json:"plugins,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPluginsResponse) Reset() {
+	*x = ListPluginsResponse{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPluginsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPluginsResponse) ProtoMessage() {}
+
+func (x *ListPluginsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPluginsResponse.ProtoReflect.Descriptor instead.
+func (*ListPluginsResponse) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListPluginsResponse) GetPlugins() []*PluginInfo {
+	if x != nil {
+		return x.Plugins
+	}
+	return nil
+}
+
+type GetPluginInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plugin        string                 `protobuf:"bytes,1,opt,name=plugin,proto3" json:"plugin,omitempty"` // Name, or name@version
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPluginInfoRequest) Reset() {
+	*x = GetPluginInfoRequest{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPluginInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPluginInfoRequest) ProtoMessage() {}
+
+func (x *GetPluginInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPluginInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetPluginInfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetPluginInfoRequest) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+// PluginInfo describes a plugin available in the store.
+type PluginInfo struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Name    string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size    int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"` // Size of the .wasm file in bytes
+	ModTime *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	// The plugin's plugin.json; unset if it has none or it is invalid.
+	Manifest *Manifest `protobuf:"bytes,4,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	// Build version; empty for an unversioned build.
+	Version       string `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginInfo) Reset() {
+	*x = PluginInfo{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginInfo) ProtoMessage() {}
+
+func (x *PluginInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginInfo.ProtoReflect.Descriptor instead.
+func (*PluginInfo) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PluginInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PluginInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PluginInfo) GetModTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModTime
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetManifest() *Manifest {
+	if x != nil {
+		return x.Manifest
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+// Manifest mirrors plugin.json.
+type Manifest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	AbiVersion    int32                  `protobuf:"varint,3,opt,name=abi_version,json=abiVersion,proto3" json:"abi_version,omitempty"`
+	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Exports       []string               `protobuf:"bytes,5,rep,name=exports,proto3" json:"exports,omitempty"`
+	Limits        *Limits                `protobuf:"bytes,6,opt,name=limits,proto3" json:"limits,omitempty"`
+	Config        []byte                 `protobuf:"bytes,7,opt,name=config,proto3" json:"config,omitempty"` // JSON object passed to init_with_config(), verbatim
+	Reentrant     bool                   `protobuf:"varint,8,opt,name=reentrant,proto3" json:"reentrant,omitempty"`
+	Sizing        *Sizing                `protobuf:"bytes,9,opt,name=sizing,proto3" json:"sizing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Manifest) Reset() {
+	*x = Manifest{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Manifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Manifest) ProtoMessage() {}
+
+func (x *Manifest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Manifest.ProtoReflect.Descriptor instead.
+func (*Manifest) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Manifest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Manifest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Manifest) GetAbiVersion() int32 {
+	if x != nil {
+		return x.AbiVersion
+	}
+	return 0
+}
+
+func (x *Manifest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Manifest) GetExports() []string {
+	if x != nil {
+		return x.Exports
+	}
+	return nil
+}
+
+func (x *Manifest) GetLimits() *Limits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
+func (x *Manifest) GetConfig() []byte {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *Manifest) GetReentrant() bool {
+	if x != nil {
+		return x.Reentrant
+	}
+	return false
+}
+
+func (x *Manifest) GetSizing() *Sizing {
+	if x != nil {
+		return x.Sizing
+	}
+	return nil
+}
+
+type Limits struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MemoryPages   int32                  `protobuf:"varint,1,opt,name=memory_pages,json=memoryPages,proto3" json:"memory_pages,omitempty"`
+	TimeoutMs     int32                  `protobuf:"varint,2,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Limits) Reset() {
+	*x = Limits{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Limits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Limits) ProtoMessage() {}
+
+func (x *Limits) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Limits.ProtoReflect.Descriptor instead.
+func (*Limits) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Limits) GetMemoryPages() int32 {
+	if x != nil {
+		return x.MemoryPages
+	}
+	return 0
+}
+
+func (x *Limits) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+// Sizing is the load a plugin expects; it seeds the plugin's pool sizes.
+type Sizing struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ExpectedQps       float64                `protobuf:"fixed64,1,opt,name=expected_qps,json=expectedQps,proto3" json:"expected_qps,omitempty"`
+	LatencyTargetMs   int32                  `protobuf:"varint,2,opt,name=latency_target_ms,json=latencyTargetMs,proto3" json:"latency_target_ms,omitempty"`
+	InstanceMemoryMib int32                  `protobuf:"varint,3,opt,name=instance_memory_mib,json=instanceMemoryMib,proto3" json:"instance_memory_mib,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Sizing) Reset() {
+	*x = Sizing{}
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sizing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sizing) ProtoMessage() {}
+
+func (x *Sizing) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pluginpb_plugin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sizing.ProtoReflect.Descriptor instead.
+func (*Sizing) Descriptor() ([]byte, []int) {
+	return file_api_pluginpb_plugin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Sizing) GetExpectedQps() float64 {
+	if x != nil {
+		return x.ExpectedQps
+	}
+	return 0
+}
+
+func (x *Sizing) GetLatencyTargetMs() int32 {
+	if x != nil {
+		return x.LatencyTargetMs
+	}
+	return 0
+}
+
+func (x *Sizing) GetInstanceMemoryMib() int32 {
+	if x != nil {
+		return x.InstanceMemoryMib
+	}
+	return 0
+}
+
+var File_api_pluginpb_plugin_proto protoreflect.FileDescriptor
+
+const file_api_pluginpb_plugin_proto_rawDesc = "" +
+	"\n" +
+	"\x19api/pluginpb/plugin.proto\x12\rwasmplugin.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x86\x02\n" +
+	"\x0eExecuteRequest\x12\x16\n" +
+	"\x06plugin\x18\x01 \x01(\tR\x06plugin\x12\x18\n" +
+	"\x06number\x18\x02 \x01(\x05H\x00R\x06number\x12\x14\n" +
+	"\x04text\x18\x03 \x01(\tH\x00R\x04text\x12\x14\n" +
+	"\x04data\x18\x04 \x01(\fH\x00R\x04data\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x05 \x01(\x05R\ttimeoutMs\x12!\n" +
+	"\finclude_logs\x18\x06 \x01(\bR\vincludeLogs\x12\x1b\n" +
+	"\tdedup_key\x18\a \x01(\tR\bdedupKey\x12\x14\n" +
+	"\x05debug\x18\b \x01(\bR\x05debug\x12\x18\n" +
+	"\aversion\x18\t \x01(\tR\aversionB\a\n" +
+	"\x05input\"\xa1\x02\n" +
+	"\x0fExecuteResponse\x12\x1b\n" +
+	"\x06output\x18\x01 \x01(\x05H\x01R\x06output\x88\x01\x01\x12\x14\n" +
+	"\x04text\x18\x02 \x01(\tH\x00R\x04text\x12\x14\n" +
+	"\x04data\x18\x03 \x01(\fH\x00R\x04data\x122\n" +
+	"\bwarnings\x18\x04 \x03(\v2\x16.wasmplugin.v1.WarningR\bwarnings\x12+\n" +
+	"\x04logs\x18\x05 \x03(\v2\x17.wasmplugin.v1.LogEntryR\x04logs\x12\x1a\n" +
+	"\breplayed\x18\x06 \x01(\bR\breplayed\x12\x18\n" +
+	"\aeffects\x18\a \x01(\x05R\aeffects\x12\x18\n" +
+	"\aversion\x18\b \x01(\tR\aversionB\t\n" +
+	"\apayloadB\t\n" +
+	"\a_output\"7\n" +
+	"\aWarning\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xa1\x01\n" +
+	"\bLogEntry\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12\x16\n" +
+	"\x06plugin\x18\x03 \x01(\tR\x06plugin\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x04 \x01(\tR\trequestId\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"\x14\n" +
+	"\x12ListPluginsRequest\"J\n" +
+	"\x13ListPluginsResponse\x123\n" +
+	"\aplugins\x18\x01 \x03(\v2\x19.wasmplugin.v1.PluginInfoR\aplugins\".\n" +
+	"\x14GetPluginInfoRequest\x12\x16\n" +
+	"\x06plugin\x18\x01 \x01(\tR\x06plugin\"\xba\x01\n" +
+	"\n" +
+	"PluginInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x125\n" +
+	"\bmod_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\amodTime\x123\n" +
+	"\bmanifest\x18\x04 \x01(\v2\x17.wasmplugin.v1.ManifestR\bmanifest\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\"\xa9\x02\n" +
+	"\bManifest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x1f\n" +
+	"\vabi_version\x18\x03 \x01(\x05R\n" +
+	"abiVersion\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x18\n" +
+	"\aexports\x18\x05 \x03(\tR\aexports\x12-\n" +
+	"\x06limits\x18\x06 \x01(\v2\x15.wasmplugin.v1.LimitsR\x06limits\x12\x16\n" +
+	"\x06config\x18\a \x01(\fR\x06config\x12\x1c\n" +
+	"\treentrant\x18\b \x01(\bR\treentrant\x12-\n" +
+	"\x06sizing\x18\t \x01(\v2\x15.wasmplugin.v1.SizingR\x06sizing\"J\n" +
+	"\x06Limits\x12!\n" +
+	"\fmemory_pages\x18\x01 \x01(\x05R\vmemoryPages\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x05R\ttimeoutMs\"\x87\x01\n" +
+	"\x06Sizing\x12!\n" +
+	"\fexpected_qps\x18\x01 \x01(\x01R\vexpectedQps\x12*\n" +
+	"\x11latency_target_ms\x18\x02 \x01(\x05R\x0flatencyTargetMs\x12.\n" +
+	"\x13instance_memory_mib\x18\x03 \x01(\x05R\x11instanceMemoryMib2\x80\x02\n" +
+	"\rPluginService\x12H\n" +
+	"\aExecute\x12\x1d.wasmplugin.v1.ExecuteRequest\x1a\x1e.wasmplugin.v1.ExecuteResponse\x12T\n" +
+	"\vListPlugins\x12!.wasmplugin.v1.ListPluginsRequest\x1a\".wasmplugin.v1.ListPluginsResponse\x12O\n" +
+	"\rGetPluginInfo\x12#.wasmplugin.v1.GetPluginInfoRequest\x1a\x19.wasmplugin.v1.PluginInfoB5Z3github.com/mrhapile/wasm-plugin-system/api/pluginpbb\x06proto3"
+
+var (
+	file_api_pluginpb_plugin_proto_rawDescOnce sync.Once
+	file_api_pluginpb_plugin_proto_rawDescData []byte
+)
+
+func file_api_pluginpb_plugin_proto_rawDescGZIP() []byte {
+	file_api_pluginpb_plugin_proto_rawDescOnce.Do(func() {
+		file_api_pluginpb_plugin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_pluginpb_plugin_proto_rawDesc), len(file_api_pluginpb_plugin_proto_rawDesc)))
+	})
+	return file_api_pluginpb_plugin_proto_rawDescData
+}
+
+var file_api_pluginpb_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_api_pluginpb_plugin_proto_goTypes = []any{
+	(*ExecuteRequest)(nil),        // 0: wasmplugin.v1.ExecuteRequest
+	(*ExecuteResponse)(nil),       // 1: wasmplugin.v1.ExecuteResponse
+	(*Warning)(nil),               // 2: wasmplugin.v1.Warning
+	(*LogEntry)(nil),              // 3: wasmplugin.v1.LogEntry
+	(*ListPluginsRequest)(nil),    // 4: wasmplugin.v1.ListPluginsRequest
+	(*ListPluginsResponse)(nil),   // 5: wasmplugin.v1.ListPluginsResponse
+	(*GetPluginInfoRequest)(nil),  // 6: wasmplugin.v1.GetPluginInfoRequest
+	(*PluginInfo)(nil),            // 7: wasmplugin.v1.PluginInfo
+	(*Manifest)(nil),              // 8: wasmplugin.v1.Manifest
+	(*Limits)(nil),                // 9: wasmplugin.v1.Limits
+	(*Sizing)(nil),                // 10: wasmplugin.v1.Sizing
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_api_pluginpb_plugin_proto_depIdxs = []int32{
+	2,  // 0: wasmplugin.v1.ExecuteResponse.warnings:type_name -> wasmplugin.v1.Warning
+	3,  // 1: wasmplugin.v1.ExecuteResponse.logs:type_name -> wasmplugin.v1.LogEntry
+	11, // 2: wasmplugin.v1.LogEntry.time:type_name -> google.protobuf.Timestamp
+	7,  // 3: wasmplugin.v1.ListPluginsResponse.plugins:type_name -> wasmplugin.v1.PluginInfo
+	11, // 4: wasmplugin.v1.PluginInfo.mod_time:type_name -> google.protobuf.Timestamp
+	8,  // 5: wasmplugin.v1.PluginInfo.manifest:type_name -> wasmplugin.v1.Manifest
+	9,  // 6: wasmplugin.v1.Manifest.limits:type_name -> wasmplugin.v1.Limits
+	10, // 7: wasmplugin.v1.Manifest.sizing:type_name -> wasmplugin.v1.Sizing
+	0,  // 8: wasmplugin.v1.PluginService.Execute:input_type -> wasmplugin.v1.ExecuteRequest
+	4,  // 9: wasmplugin.v1.PluginService.ListPlugins:input_type -> wasmplugin.v1.ListPluginsRequest
+	6,  // 10: wasmplugin.v1.PluginService.GetPluginInfo:input_type -> wasmplugin.v1.GetPluginInfoRequest
+	1,  // 11: wasmplugin.v1.PluginService.Execute:output_type -> wasmplugin.v1.ExecuteResponse
+	5,  // 12: wasmplugin.v1.PluginService.ListPlugins:output_type -> wasmplugin.v1.ListPluginsResponse
+	7,  // 13: wasmplugin.v1.PluginService.GetPluginInfo:output_type -> wasmplugin.v1.PluginInfo
+	11, // [11:14] is the sub-list for method output_type
+	8,  // [8:11] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_api_pluginpb_plugin_proto_init() }
+func file_api_pluginpb_plugin_proto_init() {
+	if File_api_pluginpb_plugin_proto != nil {
+		return
+	}
+	file_api_pluginpb_plugin_proto_msgTypes[0].OneofWrappers = []any{
+		(*ExecuteRequest_Number)(nil),
+		(*ExecuteRequest_Text)(nil),
+		(*ExecuteRequest_Data)(nil),
+	}
+	file_api_pluginpb_plugin_proto_msgTypes[1].OneofWrappers = []any{
+		(*ExecuteResponse_Text)(nil),
+		(*ExecuteResponse_Data)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_pluginpb_plugin_proto_rawDesc), len(file_api_pluginpb_plugin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_pluginpb_plugin_proto_goTypes,
+		DependencyIndexes: file_api_pluginpb_plugin_proto_depIdxs,
+		MessageInfos:      file_api_pluginpb_plugin_proto_msgTypes,
+	}.Build()
+	File_api_pluginpb_plugin_proto = out.File
+	file_api_pluginpb_plugin_proto_goTypes = nil
+	file_api_pluginpb_plugin_proto_depIdxs = nil
+}
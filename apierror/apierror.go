@@ -0,0 +1,306 @@
+// Package apierror defines the machine-readable error taxonomy of the plugin
+// server's HTTP API.
+//
+// All error responses use RFC 7807 "Problem Details for HTTP APIs"
+// (Content-Type: application/problem+json). Each problem carries a stable
+// Code and a Type URI derived from it; clients should branch on those,
+// never on Title or Detail, which are human-readable and may change.
+//
+// The package has no server dependencies so that clients can import it to
+// decode and classify errors:
+//
+//	var p apierror.Problem
+//	json.NewDecoder(resp.Body).Decode(&p)
+//	if p.Code == apierror.CodePluginNotFound {
+//	    // ...
+//	}
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// TypePrefix is prepended to a Code to form the problem's Type URI.
+const TypePrefix = "urn:wasm-plugin-system:problem:"
+
+// Code identifies a class of API error. Codes are part of the API contract:
+// they are never renamed or reused for a different meaning.
+type Code string
+
+const (
+	// CodeInvalidRequest means the request body could not be parsed.
+	CodeInvalidRequest Code = "invalid_request"
+
+	// CodeMissingPluginName means the request did not name a plugin.
+	CodeMissingPluginName Code = "missing_plugin_name"
+
+	// CodeInvalidPluginName means the plugin name contains disallowed characters.
+	CodeInvalidPluginName Code = "invalid_plugin_name"
+
+	// CodePluginNotFound means the store has no plugin with the given name.
+	CodePluginNotFound Code = "plugin_not_found"
+
+	// CodeMethodNotAllowed means the endpoint does not support the HTTP method.
+	CodeMethodNotAllowed Code = "method_not_allowed"
+
+	// CodePluginLoadFailed means the plugin binary could not be loaded or validated.
+	CodePluginLoadFailed Code = "plugin_load_failed"
+
+	// CodePluginInitFailed means the plugin's init() failed.
+	CodePluginInitFailed Code = "plugin_init_failed"
+
+	// CodePluginExecutionFailed means the plugin's process() trapped or returned an error code.
+	CodePluginExecutionFailed Code = "plugin_execution_failed"
+
+	// CodePayloadUnsupported means the request carried a text or byte payload
+	// but the plugin does not implement the memory-based payload ABI.
+	CodePayloadUnsupported Code = "payload_unsupported"
+
+	// CodePluginTimeout means the plugin did not finish within the execution
+	// timeout and was interrupted.
+	CodePluginTimeout Code = "plugin_timeout"
+
+	// CodeMemoryLimitExceeded means the plugin ran out of linear memory
+	// under its memory limit while processing the request's input.
+	CodeMemoryLimitExceeded Code = "memory_limit_exceeded"
+
+	// CodeInstructionLimitExceeded means the plugin executed more
+	// instructions than EXECUTION_MAX_INSTRUCTIONS allows in one call.
+	CodeInstructionLimitExceeded Code = "instruction_limit_exceeded"
+
+	// CodeFileQuotaExceeded means the plugin left more bytes or files in
+	// its scratch directory than WASI_SCRATCH_MAX_MIB or
+	// WASI_SCRATCH_MAX_FILES allow.
+	CodeFileQuotaExceeded Code = "file_quota_exceeded"
+
+	// CodeDuplicateRequest means a request with the same dedup key is still
+	// running; the client should retry once it has finished.
+	CodeDuplicateRequest Code = "duplicate_request"
+
+	// CodeUnauthorized means the request lacks valid credentials.
+	CodeUnauthorized Code = "unauthorized"
+
+	// CodeForbidden means the authenticated client may not run the plugin
+	// or call the endpoint.
+	CodeForbidden Code = "forbidden"
+
+	// CodeInvalidPlugin means an uploaded binary is not a loadable plugin,
+	// e.g. it lacks a required export.
+	CodeInvalidPlugin Code = "invalid_plugin"
+
+	// CodePluginTooLarge means an uploaded binary exceeds the size limit.
+	CodePluginTooLarge Code = "plugin_too_large"
+
+	// CodeTraceNotFound means no debug trace is kept for the request ID.
+	CodeTraceNotFound Code = "trace_not_found"
+
+	// CodeScheduleNotFound means the plugin's manifest declares no schedule
+	// of the given name.
+	CodeScheduleNotFound Code = "schedule_not_found"
+
+	// CodeMaintenance means executions are paused by a maintenance window;
+	// the response's Retry-After header says when it ends.
+	CodeMaintenance Code = "maintenance"
+
+	// CodeMaintenanceWindowNotFound means no maintenance window has the
+	// given ID; it may have ended.
+	CodeMaintenanceWindowNotFound Code = "maintenance_window_not_found"
+
+	// CodeBatchNotFound means no batch of the given ID is remembered.
+	CodeBatchNotFound Code = "batch_not_found"
+
+	// CodeJobNotFound means no job of the given ID is remembered; finished
+	// jobs are forgotten after a while.
+	CodeJobNotFound Code = "job_not_found"
+
+	// CodeTooManyExecutions means a concurrency limit on plugin executions
+	// was reached; the response's Retry-After header says when to retry.
+	CodeTooManyExecutions Code = "too_many_executions"
+
+	// CodeRateLimited means the client exceeded its rate limit for the
+	// plugin; the response's Retry-After header says when to retry.
+	CodeRateLimited Code = "rate_limited"
+
+	// CodeInternal is used for errors that fit no other code.
+	CodeInternal Code = "internal_error"
+)
+
+// definition holds the fixed attributes of a Code.
+type definition struct {
+	status int
+	title  string
+}
+
+// definitions maps each Code to its HTTP status and default title.
+var definitions = map[Code]definition{
+	CodeInvalidRequest:            {http.StatusBadRequest, "Invalid request"},
+	CodeMissingPluginName:         {http.StatusBadRequest, "Plugin name is required"},
+	CodeInvalidPluginName:         {http.StatusBadRequest, "Invalid plugin name"},
+	CodePluginNotFound:            {http.StatusNotFound, "Plugin not found"},
+	CodeMethodNotAllowed:          {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodePluginLoadFailed:          {http.StatusInternalServerError, "Plugin failed to load"},
+	CodePluginInitFailed:          {http.StatusInternalServerError, "Plugin failed to initialize"},
+	CodePluginExecutionFailed:     {http.StatusInternalServerError, "Plugin execution failed"},
+	CodePayloadUnsupported:        {http.StatusUnprocessableEntity, "Plugin does not accept payloads"},
+	CodePluginTimeout:             {http.StatusGatewayTimeout, "Plugin execution timed out"},
+	CodeMemoryLimitExceeded:       {http.StatusUnprocessableEntity, "Plugin exceeded its memory limit"},
+	CodeInstructionLimitExceeded:  {http.StatusUnprocessableEntity, "Plugin exceeded its instruction limit"},
+	CodeFileQuotaExceeded:         {http.StatusUnprocessableEntity, "Plugin exceeded its file quota"},
+	CodeDuplicateRequest:          {http.StatusConflict, "Duplicate request in progress"},
+	CodeUnauthorized:              {http.StatusUnauthorized, "Unauthorized"},
+	CodeForbidden:                 {http.StatusForbidden, "Forbidden"},
+	CodeInvalidPlugin:             {http.StatusUnprocessableEntity, "Invalid plugin binary"},
+	CodePluginTooLarge:            {http.StatusRequestEntityTooLarge, "Plugin too large"},
+	CodeTraceNotFound:             {http.StatusNotFound, "Trace not found"},
+	CodeScheduleNotFound:          {http.StatusNotFound, "Schedule not found"},
+	CodeMaintenance:               {http.StatusServiceUnavailable, "Paused for maintenance"},
+	CodeMaintenanceWindowNotFound: {http.StatusNotFound, "Maintenance window not found"},
+	CodeBatchNotFound:             {http.StatusNotFound, "Batch not found"},
+	CodeJobNotFound:               {http.StatusNotFound, "Job not found"},
+	CodeTooManyExecutions:         {http.StatusTooManyRequests, "Too many concurrent executions"},
+	CodeRateLimited:               {http.StatusTooManyRequests, "Rate limit exceeded"},
+	CodeInternal:                  {http.StatusInternalServerError, "Internal server error"},
+}
+
+// Codes returns every defined Code.
+func Codes() []Code {
+	codes := make([]Code, 0, len(definitions))
+	for code := range definitions {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// Status returns the HTTP status associated with a Code.
+// Unknown codes map to 500.
+func (c Code) Status() int {
+	if def, ok := definitions[c]; ok {
+		return def.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Title returns the default human-readable summary of a Code.
+func (c Code) Title() string {
+	if def, ok := definitions[c]; ok {
+		return def.title
+	}
+	return definitions[CodeInternal].title
+}
+
+// Type returns the stable Type URI of a Code.
+func (c Code) Type() string {
+	return TypePrefix + string(c)
+}
+
+// Problem is an RFC 7807 problem details object extended with a Code.
+type Problem struct {
+	Type     string `json:"type"`               // Stable URI identifying the problem type
+	Title    string `json:"title"`              // Short summary of the problem type
+	Status   int    `json:"status"`             // HTTP status code
+	Detail   string `json:"detail,omitempty"`   // Explanation specific to this occurrence
+	Instance string `json:"instance,omitempty"` // URI reference of the failing request
+	Code     Code   `json:"code"`               // Stable machine-readable code
+
+	// RequestID is the X-Request-ID of the failing request, to quote when
+	// reporting the problem
+	RequestID string `json:"request_id,omitempty"`
+
+	// PluginError is set when the plugin itself reported the failure
+	PluginError *PluginError `json:"plugin_error,omitempty"`
+}
+
+// PluginError is the error a plugin reported through the ABI: the code it
+// returned and, if it exports last_error(), its own explanation.
+type PluginError struct {
+	Code    int32  `json:"code"`              // Negative ABI error code, e.g. -3
+	Name    string `json:"name"`              // Symbolic code, e.g. "ABI_ERROR_INVALID_INPUT"
+	Message string `json:"message,omitempty"` // Plugin-provided message
+}
+
+// New creates a Problem for the given code and occurrence detail.
+func New(code Code, detail string) *Problem {
+	return &Problem{
+		Type:   code.Type(),
+		Title:  code.Title(),
+		Status: code.Status(),
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Error implements the error interface so decoded problems can be returned
+// directly by clients.
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("%s (%s)", p.Title, p.Code)
+	}
+	return fmt.Sprintf("%s (%s): %s", p.Title, p.Code, p.Detail)
+}
+
+// Error attaches a Code to an underlying error so that handlers can
+// classify failures that bubble up from lower layers.
+type Error struct {
+	Code Code
+	Err  error
+
+	// RetryAfter is how long clients should wait before retrying, sent as
+	// the Retry-After header; zero if unknown or pointless.
+	RetryAfter time.Duration
+}
+
+// Wrap attaches a Code to err. It returns nil if err is nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// WrapRetry is Wrap for a failure that clears up by itself, telling
+// clients to retry after the given delay.
+func WrapRetry(code Code, err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err, RetryAfter: after}
+}
+
+// RetryAfterOf returns the delay attached to err by WrapRetry, or zero if
+// none.
+func RetryAfterOf(err error) time.Duration {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.RetryAfter
+	}
+	return 0
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf returns the Code attached to err by Wrap, or CodeInternal if none.
+func CodeOf(err error) Code {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,148 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/text/language"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+func TestAPIError(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "APIError Suite")
+}
+
+var _ = Describe("Code", func() {
+	// =========================================================================
+	// TEST: Every code is fully defined
+	// Why: A code without a status or title would surface as a generic 500
+	//      with a misleading title.
+	// =========================================================================
+	It("should define status, title, and type for every code", func() {
+		for _, code := range apierror.Codes() {
+			Expect(code.Status()).To(BeNumerically(">=", 400), string(code))
+			Expect(code.Title()).NotTo(BeEmpty(), string(code))
+			Expect(code.Type()).To(Equal(apierror.TypePrefix + string(code)))
+		}
+	})
+
+	It("should map unknown codes to internal errors", func() {
+		code := apierror.Code("no_such_code")
+
+		Expect(code.Status()).To(Equal(http.StatusInternalServerError))
+		Expect(code.Title()).To(Equal(apierror.CodeInternal.Title()))
+	})
+
+	DescribeTable("HTTP status",
+		func(code apierror.Code, status int) {
+			Expect(code.Status()).To(Equal(status))
+		},
+		Entry("invalid request", apierror.CodeInvalidRequest, http.StatusBadRequest),
+		Entry("not found", apierror.CodePluginNotFound, http.StatusNotFound),
+		Entry("method", apierror.CodeMethodNotAllowed, http.StatusMethodNotAllowed),
+		Entry("execution", apierror.CodePluginExecutionFailed, http.StatusInternalServerError),
+		Entry("maintenance", apierror.CodeMaintenance, http.StatusServiceUnavailable),
+		Entry("too many executions", apierror.CodeTooManyExecutions, http.StatusTooManyRequests),
+		Entry("rate limited", apierror.CodeRateLimited, http.StatusTooManyRequests),
+		Entry("forbidden", apierror.CodeForbidden, http.StatusForbidden),
+	)
+
+	It("should carry a retry delay through wrapping", func() {
+		err := fmt.Errorf("run failed: %w",
+			apierror.WrapRetry(apierror.CodeMaintenance, errors.New("paused"), 90*time.Second))
+
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeMaintenance))
+		Expect(apierror.RetryAfterOf(err)).To(Equal(90 * time.Second))
+		Expect(apierror.RetryAfterOf(apierror.Wrap(apierror.CodeInternal, errors.New("boom")))).To(BeZero())
+	})
+})
+
+var _ = Describe("Problem", func() {
+	It("should serialize RFC 7807 members", func() {
+		problem := apierror.New(apierror.CodePluginNotFound, "plugin not found: foo")
+		problem.Instance = "/run"
+
+		body, err := json.Marshal(problem)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(MatchJSON(`{
+			"type": "urn:wasm-plugin-system:problem:plugin_not_found",
+			"title": "Plugin not found",
+			"status": 404,
+			"detail": "plugin not found: foo",
+			"instance": "/run",
+			"code": "plugin_not_found"
+		}`))
+	})
+
+	It("should implement error", func() {
+		var err error = apierror.New(apierror.CodeInternal, "boom")
+
+		Expect(err.Error()).To(Equal("Internal server error (internal_error): boom"))
+	})
+})
+
+var _ = Describe("Wrap and CodeOf", func() {
+	It("should recover the code through further wrapping", func() {
+		base := errors.New("trap")
+		err := fmt.Errorf("handler: %w", apierror.Wrap(apierror.CodePluginExecutionFailed, base))
+
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginExecutionFailed))
+		Expect(errors.Is(err, base)).To(BeTrue())
+	})
+
+	It("should classify problems and plain errors", func() {
+		Expect(apierror.CodeOf(apierror.New(apierror.CodePluginNotFound, ""))).To(Equal(apierror.CodePluginNotFound))
+		Expect(apierror.CodeOf(errors.New("plain"))).To(Equal(apierror.CodeInternal))
+	})
+
+	It("should return nil when wrapping nil", func() {
+		Expect(apierror.Wrap(apierror.CodeInternal, nil)).To(BeNil())
+	})
+})
+
+var _ = Describe("Localization", func() {
+	// =========================================================================
+	// TEST: Accept-Language negotiation
+	// Why: Consumer teams show these titles to end users; the best supported
+	//      language must win, and garbage headers must not break responses.
+	// =========================================================================
+	DescribeTable("MatchLocale",
+		func(header string, expected language.Tag) {
+			Expect(apierror.MatchLocale(header)).To(Equal(expected))
+		},
+		Entry("empty header", "", language.English),
+		Entry("exact match", "de", language.German),
+		Entry("regional variant", "es-MX", language.Spanish),
+		Entry("quality ordering", "fr;q=0.5, de;q=0.9", language.German),
+		Entry("unsupported language", "ja", language.English),
+		Entry("malformed header", ";;;q=x", language.English),
+	)
+
+	It("should translate titles while keeping codes stable", func() {
+		problem := apierror.NewLocalized(apierror.CodePluginNotFound, "plugin not found: foo", language.German)
+
+		Expect(problem.Title).To(Equal("Plugin nicht gefunden"))
+		Expect(problem.Code).To(Equal(apierror.CodePluginNotFound))
+		Expect(problem.Type).To(Equal(apierror.CodePluginNotFound.Type()))
+	})
+
+	It("should translate every code in every supported locale", func() {
+		for _, locale := range apierror.SupportedLocales() {
+			for _, code := range apierror.Codes() {
+				Expect(code.LocalizedTitle(locale)).NotTo(BeEmpty())
+				if locale != apierror.DefaultLocale {
+					Expect(code.LocalizedTitle(locale)).NotTo(Equal(code.Title()),
+						"%s missing in %s", code, locale)
+				}
+			}
+		}
+	})
+})
@@ -0,0 +1,149 @@
+package apierror
+
+import (
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when a request has no Accept-Language header or
+// none of its languages are supported.
+var DefaultLocale = language.English
+
+// titles holds the translated problem titles per locale. English titles
+// come from the code definitions; other locales may omit codes, which then
+// fall back to English.
+//
+// Only titles are translated. Codes and Type URIs never change with the
+// locale, so clients can keep branching on them.
+var titles = map[language.Tag]map[Code]string{
+	language.German: {
+		CodeInvalidRequest:            "Ungültige Anfrage",
+		CodeMissingPluginName:         "Plugin-Name ist erforderlich",
+		CodeInvalidPluginName:         "Ungültiger Plugin-Name",
+		CodePluginNotFound:            "Plugin nicht gefunden",
+		CodeMethodNotAllowed:          "Methode nicht erlaubt",
+		CodePluginLoadFailed:          "Plugin konnte nicht geladen werden",
+		CodePluginInitFailed:          "Plugin konnte nicht initialisiert werden",
+		CodePluginExecutionFailed:     "Plugin-Ausführung fehlgeschlagen",
+		CodePayloadUnsupported:        "Plugin akzeptiert keine Nutzdaten",
+		CodePluginTimeout:             "Zeitüberschreitung bei der Plugin-Ausführung",
+		CodeMemoryLimitExceeded:       "Plugin hat sein Speicherlimit überschritten",
+		CodeInstructionLimitExceeded:  "Plugin hat sein Instruktionslimit überschritten",
+		CodeFileQuotaExceeded:         "Plugin hat sein Dateikontingent überschritten",
+		CodeDuplicateRequest:          "Doppelte Anfrage wird bereits bearbeitet",
+		CodeUnauthorized:              "Nicht autorisiert",
+		CodeForbidden:                 "Zugriff verweigert",
+		CodeInvalidPlugin:             "Ungültige Plugin-Binärdatei",
+		CodePluginTooLarge:            "Plugin ist zu groß",
+		CodeTraceNotFound:             "Trace nicht gefunden",
+		CodeScheduleNotFound:          "Zeitplan nicht gefunden",
+		CodeMaintenance:               "Wegen Wartung pausiert",
+		CodeMaintenanceWindowNotFound: "Wartungsfenster nicht gefunden",
+		CodeBatchNotFound:             "Stapelauftrag nicht gefunden",
+		CodeJobNotFound:               "Auftrag nicht gefunden",
+		CodeTooManyExecutions:         "Zu viele gleichzeitige Ausführungen",
+		CodeRateLimited:               "Ratenlimit überschritten",
+		CodeInternal:                  "Interner Serverfehler",
+	},
+	language.Spanish: {
+		CodeInvalidRequest:            "Solicitud no válida",
+		CodeMissingPluginName:         "El nombre del plugin es obligatorio",
+		CodeInvalidPluginName:         "Nombre de plugin no válido",
+		CodePluginNotFound:            "Plugin no encontrado",
+		CodeMethodNotAllowed:          "Método no permitido",
+		CodePluginLoadFailed:          "No se pudo cargar el plugin",
+		CodePluginInitFailed:          "No se pudo inicializar el plugin",
+		CodePluginExecutionFailed:     "Falló la ejecución del plugin",
+		CodePayloadUnsupported:        "El plugin no acepta cargas útiles",
+		CodePluginTimeout:             "Se agotó el tiempo de ejecución del plugin",
+		CodeMemoryLimitExceeded:       "El plugin superó su límite de memoria",
+		CodeInstructionLimitExceeded:  "El plugin superó su límite de instrucciones",
+		CodeFileQuotaExceeded:         "El plugin superó su cuota de archivos",
+		CodeDuplicateRequest:          "Ya se está procesando una solicitud duplicada",
+		CodeUnauthorized:              "No autorizado",
+		CodeForbidden:                 "Prohibido",
+		CodeInvalidPlugin:             "Binario de plugin no válido",
+		CodePluginTooLarge:            "El plugin es demasiado grande",
+		CodeTraceNotFound:             "Traza no encontrada",
+		CodeScheduleNotFound:          "Programación no encontrada",
+		CodeMaintenance:               "En pausa por mantenimiento",
+		CodeMaintenanceWindowNotFound: "Ventana de mantenimiento no encontrada",
+		CodeBatchNotFound:             "Lote no encontrado",
+		CodeJobNotFound:               "Trabajo no encontrado",
+		CodeTooManyExecutions:         "Demasiadas ejecuciones simultáneas",
+		CodeRateLimited:               "Límite de solicitudes superado",
+		CodeInternal:                  "Error interno del servidor",
+	},
+	language.French: {
+		CodeInvalidRequest:            "Requête invalide",
+		CodeMissingPluginName:         "Le nom du plugin est obligatoire",
+		CodeInvalidPluginName:         "Nom de plugin invalide",
+		CodePluginNotFound:            "Plugin introuvable",
+		CodeMethodNotAllowed:          "Méthode non autorisée",
+		CodePluginLoadFailed:          "Échec du chargement du plugin",
+		CodePluginInitFailed:          "Échec de l'initialisation du plugin",
+		CodePluginExecutionFailed:     "Échec de l'exécution du plugin",
+		CodePayloadUnsupported:        "Le plugin n'accepte pas de données utiles",
+		CodePluginTimeout:             "Délai d'exécution du plugin dépassé",
+		CodeMemoryLimitExceeded:       "Le plugin a dépassé sa limite de mémoire",
+		CodeInstructionLimitExceeded:  "Le plugin a dépassé sa limite d'instructions",
+		CodeFileQuotaExceeded:         "Le plugin a dépassé son quota de fichiers",
+		CodeDuplicateRequest:          "Une requête en double est déjà en cours",
+		CodeUnauthorized:              "Non autorisé",
+		CodeForbidden:                 "Interdit",
+		CodeInvalidPlugin:             "Binaire de plugin invalide",
+		CodePluginTooLarge:            "Le plugin est trop volumineux",
+		CodeTraceNotFound:             "Trace introuvable",
+		CodeScheduleNotFound:          "Planification introuvable",
+		CodeMaintenance:               "En pause pour maintenance",
+		CodeMaintenanceWindowNotFound: "Fenêtre de maintenance introuvable",
+		CodeBatchNotFound:             "Lot introuvable",
+		CodeJobNotFound:               "Tâche introuvable",
+		CodeTooManyExecutions:         "Trop d'exécutions simultanées",
+		CodeRateLimited:               "Limite de débit dépassée",
+		CodeInternal:                  "Erreur interne du serveur",
+	},
+}
+
+// supportedLocales lists the catalog locales, default first so the matcher
+// falls back to it.
+var supportedLocales = []language.Tag{
+	DefaultLocale,
+	language.German,
+	language.Spanish,
+	language.French,
+}
+
+var matcher = language.NewMatcher(supportedLocales)
+
+// SupportedLocales returns the locales the catalog can serve.
+func SupportedLocales() []language.Tag {
+	return append([]language.Tag(nil), supportedLocales...)
+}
+
+// MatchLocale selects the best supported locale for an Accept-Language
+// header value. Malformed or empty headers yield DefaultLocale.
+func MatchLocale(acceptLanguage string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, _ := matcher.Match(tags...)
+	return supportedLocales[index]
+}
+
+// LocalizedTitle returns the title of a code in the given locale, falling
+// back to English if the locale or code is not in the catalog.
+func (c Code) LocalizedTitle(locale language.Tag) string {
+	if translated, ok := titles[locale][c]; ok {
+		return translated
+	}
+	return c.Title()
+}
+
+// NewLocalized creates a Problem whose title is translated to locale.
+func NewLocalized(code Code, detail string, locale language.Tag) *Problem {
+	problem := New(code, detail)
+	problem.Title = code.LocalizedTitle(locale)
+	return problem
+}
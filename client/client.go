@@ -0,0 +1,850 @@
+// Package client is a Go client for the WASM plugin server HTTP API.
+//
+// The package is pure Go: it does not import the runtime package, so tools
+// and services can call a remote plugin server without linking WasmEdge.
+//
+// Errors returned by the server are decoded into *apierror.Problem, so
+// callers can branch on stable codes:
+//
+//	c := client.New("http://localhost:8080")
+//	resp, err := c.Run(ctx, "hello", 21)
+//	if apierror.CodeOf(err) == apierror.CodePluginNotFound {
+//	    // ...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/websocket"
+)
+
+// DefaultTimeout bounds each request when no custom http.Client is given.
+const DefaultTimeout = 30 * time.Second
+
+// TenantHeader carries the tenant a request acts on behalf of.
+const TenantHeader = "X-Tenant"
+
+// APIKeyHeader carries the API key the server's rate limits identify a
+// client by.
+const APIKeyHeader = "X-API-Key"
+
+// CallerHeader carries the identity of the client a request comes from.
+// It is meant to be set by an authenticating proxy; see WithHeader.
+const CallerHeader = "X-Caller"
+
+// RequestIDHeader carries the ID that tags a run's plugin log messages; set
+// it with WithHeader to correlate runs with your own logs.
+const RequestIDHeader = "X-Request-ID"
+
+// RunRequest is the body of POST /run. Set at most one of Text and Data to
+// use the payload ABI, Payload to use the JSON payload ABI, or Fields to
+// use the protobuf payload ABI; otherwise Input is passed to process().
+type RunRequest struct {
+	Plugin  string          `json:"plugin"` // Name, optionally with a version: "hello@1.2.0"
+	Input   int             `json:"input"`
+	Text    *string         `json:"text,omitempty"`
+	Data    []byte          `json:"data,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"` // Any JSON document, e.g. from json.Marshal
+
+	// Fields are the typed inputs of process_pb(): bools, numbers, strings,
+	// and lists of these. Integers stay integers; []byte values are sent
+	// as base64 strings.
+	Fields map[string]any `json:"fields,omitempty"`
+
+	// TimeoutMs shortens the server's execution timeout (0 = server default)
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// IncludeLogs returns the plugin's log messages in RunResponse.Logs
+	IncludeLogs bool `json:"include_logs,omitempty"`
+
+	// IncludeOutput returns what the plugin wrote to stdout and stderr in
+	// RunResponse.Stdout and Stderr; the server logs it otherwise
+	IncludeOutput bool `json:"include_output,omitempty"`
+
+	// DedupKey runs the request at most once; retries with the same key get
+	// the first successful response back, with RunResponse.Replayed set
+	DedupKey string `json:"dedup_key,omitempty"`
+
+	// Debug records the run's export and host function calls, returned in
+	// RunResponse.Trace and kept on the server for Trace, even if the run
+	// fails. The server must enable DEBUG_TRACES.
+	Debug bool `json:"debug,omitempty"`
+
+	// Version pins the plugin build to run; RunResponse.Version reports
+	// the one that ran, e.g. for "latest"
+	Version string `json:"version,omitempty"`
+}
+
+// Warning is a non-fatal condition reported with a successful run.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// LogEntry is a message a plugin logged during a run.
+type LogEntry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"` // "debug", "info", "warn", or "error"
+	Plugin    string    `json:"plugin"`
+	RequestID string    `json:"request_id,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// RunResponse is the body of a successful POST /run.
+//
+// Output is nil when the plugin succeeded without producing a result, which
+// is distinct from a zero result.
+type RunResponse struct {
+	Output   *int            `json:"output"`
+	Text     *string         `json:"text,omitempty"`
+	Data     []byte          `json:"data,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"` // Output document of a Payload request
+	Fields   map[string]any  `json:"fields,omitempty"`  // Output fields of a Fields request; bytes as base64 strings
+	Warnings []Warning       `json:"warnings,omitempty"`
+	Logs     []LogEntry      `json:"logs,omitempty"`
+	Replayed bool            `json:"replayed,omitempty"`
+	Effects  int             `json:"effects,omitempty"`
+	Trace    *Trace          `json:"trace,omitempty"`
+	Version  string          `json:"version,omitempty"` // Empty for an unversioned plugin
+
+	// Output of an IncludeOutput request, cut at the server's
+	// MAX_OUTPUT_BYTES per stream
+	Stdout          string `json:"stdout,omitempty"`
+	Stderr          string `json:"stderr,omitempty"`
+	OutputTruncated bool   `json:"output_truncated,omitempty"`
+}
+
+// Trace is the host-call trace of a debug run.
+type Trace struct {
+	RequestID string       `json:"request_id"`
+	Plugin    string       `json:"plugin"`
+	Time      time.Time    `json:"time"`
+	Error     string       `json:"error,omitempty"` // Why the run failed
+	Events    []TraceEvent `json:"events"`
+	Dropped   int          `json:"dropped,omitempty"`
+	WASI      []string     `json:"wasi,omitempty"` // WASI imports, whose calls are not traced
+}
+
+// TraceEvent is an export the server called ("export") or a host function
+// the plugin called ("host"), in call order.
+type TraceEvent struct {
+	Seq      int            `json:"seq"`
+	Time     time.Time      `json:"time"`
+	Kind     string         `json:"kind"`
+	Module   string         `json:"module,omitempty"`
+	Function string         `json:"function"`
+	Args     []interface{}  `json:"args"`
+	Results  []interface{}  `json:"results,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Duration time.Duration  `json:"duration_ns"`
+	Memory   []MemoryAccess `json:"memory,omitempty"`
+}
+
+// MemoryAccess is guest memory a host function read or wrote.
+type MemoryAccess struct {
+	Op        string `json:"op"` // "read" or "write"
+	Ptr       int32  `json:"ptr"`
+	Len       int32  `json:"len"`
+	Data      []byte `json:"data,omitempty"` // At most the first 256 bytes
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// PluginInfo is one entry of GET /plugins.
+type PluginInfo struct {
+	Name    string    `json:"name"`
+	Version string    `json:"version,omitempty"` // Empty for an unversioned build
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256,omitempty"` // Digest the binary must have to load
+	Digest  string    `json:"digest,omitempty"` // Digest of the stored binary; only from PluginDigests
+}
+
+// PoolInfo is the statistics of one pool GET /admin/pools lists.
+type PoolInfo struct {
+	Plugin           string                    `json:"plugin"`
+	Path             string                    `json:"path"`
+	Strategy         string                    `json:"strategy"`
+	Idle             int                       `json:"idle"`
+	InUse            int                       `json:"in_use"`
+	Waiting          int                       `json:"waiting"`
+	MinSize          int                       `json:"min_size"`
+	MaxSize          int                       `json:"max_size"`
+	Instantiated     uint64                    `json:"instantiated"`
+	Restored         uint64                    `json:"restored"`
+	Evictions        map[string]uint64         `json:"evictions"`
+	ShutdownFailures uint64                    `json:"shutdown_failures"`
+	CheckoutWait     metrics.HistogramSnapshot `json:"checkout_wait_seconds"`
+}
+
+// MemoryReport is the response of GET /debug/memory.
+type MemoryReport struct {
+	Process         ProcessMemory  `json:"process"`
+	Plugins         []PluginMemory `json:"plugins"` // Largest TotalBytes first
+	AttributedBytes int64          `json:"attributed_bytes"`
+}
+
+// ProcessMemory is the memory of the server process as a whole.
+type ProcessMemory struct {
+	RSSBytes    int64  `json:"rss_bytes,omitempty"` // Zero where the server cannot measure it
+	GoHeapBytes uint64 `json:"go_heap_bytes"`
+	GoSysBytes  uint64 `json:"go_sys_bytes"`
+}
+
+// PluginMemory is the memory the server attributes to one plugin build.
+type PluginMemory struct {
+	Plugins           []string `json:"plugins"`
+	Digest            string   `json:"digest"`
+	Paths             []string `json:"paths"`
+	Instances         int      `json:"instances"`
+	LinearMemoryBytes int64    `json:"linear_memory_bytes"`
+	SnapshotBytes     int64    `json:"snapshot_bytes"`
+	ModuleBytes       int64    `json:"module_bytes"`
+	ArtifactBytes     int64    `json:"artifact_bytes"`
+	TotalBytes        int64    `json:"total_bytes"`
+}
+
+// LogOverride is the log verbosity of one plugin, as returned by
+// /plugins/{name}/logging.
+type LogOverride struct {
+	Plugin  string    `json:"plugin"`
+	Level   string    `json:"level"` // Lowest level of the plugin's messages that is logged
+	Trace   bool      `json:"trace,omitempty"`
+	Expires time.Time `json:"expires,omitzero"` // Zero when no override is active
+}
+
+// LogOverrideRequest changes the log verbosity of a plugin for a while.
+type LogOverrideRequest struct {
+	Level           string `json:"level,omitempty"`            // Defaults to "debug"
+	Trace           bool   `json:"trace,omitempty"`            // Keep a debug trace of every run
+	DurationSeconds int    `json:"duration_seconds,omitempty"` // Defaults to 15 minutes on the server
+}
+
+// Schedule is one of the schedules a plugin's manifest declares, as
+// returned by /plugins/{name}/schedules.
+type Schedule struct {
+	Plugin        string    `json:"plugin"`
+	Name          string    `json:"name"`
+	Cron          string    `json:"cron"`
+	Enabled       bool      `json:"enabled"`
+	Next          time.Time `json:"next,omitzero"` // Zero while disabled
+	LastRun       time.Time `json:"last_run,omitzero"`
+	LastRequestID string    `json:"last_request_id,omitempty"`
+	LastError     string    `json:"last_error,omitempty"` // Why the last run failed
+}
+
+// BatchRequest starts a batch running a plugin over every line of a file
+// under the server's BATCH_DIR.
+type BatchRequest struct {
+	Plugin      string `json:"plugin"`
+	Input       string `json:"input"`                 // Relative to BATCH_DIR
+	Concurrency int    `json:"concurrency,omitempty"` // Zero means the server's maximum
+	TimeoutMs   int    `json:"timeout_ms,omitempty"`  // Shortens each line's execution timeout
+}
+
+// Batch describes a batch and its progress, as returned by /batches.
+type Batch struct {
+	ID          string    `json:"id"`
+	Plugin      string    `json:"plugin"`
+	Input       string    `json:"input"`
+	Output      string    `json:"output"` // Result file, relative to BATCH_DIR
+	State       string    `json:"state"`  // running, completed, failed, or cancelled
+	Concurrency int       `json:"concurrency"`
+	SizeBytes   int64     `json:"size_bytes"`
+	BytesRead   int64     `json:"bytes_read"`
+	Processed   int       `json:"processed"`
+	Succeeded   int       `json:"succeeded"`
+	Failed      int       `json:"failed"`
+	Retries     int       `json:"retries,omitempty"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished,omitzero"`
+	Error       string    `json:"error,omitempty"` // Why the batch failed
+}
+
+// Job describes a request running asynchronously, as returned by /jobs.
+type Job struct {
+	ID       string            `json:"id"`
+	Plugin   string            `json:"plugin"`
+	State    string            `json:"state"`            // queued, running, succeeded, failed, or cancelled
+	Result   *RunResponse      `json:"result,omitempty"` // Set once the job succeeded
+	Error    *apierror.Problem `json:"error,omitempty"`  // Why the job failed
+	Created  time.Time         `json:"created"`
+	Started  time.Time         `json:"started,omitzero"`
+	Finished time.Time         `json:"finished,omitzero"`
+}
+
+// StreamMessage is an input sent on a stream; see Client.Stream. Set at
+// most one of Text, Data, Payload, and Fields, as in RunRequest.
+type StreamMessage struct {
+	ID          string          `json:"id,omitempty"` // Echoed in the result; defaults to the message's sequence number
+	Input       int             `json:"input"`
+	Text        *string         `json:"text,omitempty"`
+	Data        []byte          `json:"data,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Fields      map[string]any  `json:"fields,omitempty"`
+	TimeoutMs   int             `json:"timeout_ms,omitempty"`
+	IncludeLogs bool            `json:"include_logs,omitempty"`
+}
+
+// StreamResult is the outcome of a StreamMessage: the response of the
+// call, or Error if it failed.
+type StreamResult struct {
+	ID string `json:"id"`
+	*RunResponse
+	Error *apierror.Problem `json:"error,omitempty"`
+}
+
+// Stream is a WebSocket connection to GET /run/ws, running the messages
+// sent on it on one instance of a plugin. Send and Recv may be called
+// from different goroutines, each from one at a time.
+type Stream struct {
+	conn *websocket.Conn
+}
+
+// MaintenanceRequest defines a maintenance window pausing executions. An
+// empty Plugin or Tenant pauses every plugin or tenant; a zero Start means
+// now. Set exactly one of End and DurationSeconds.
+type MaintenanceRequest struct {
+	Plugin          string    `json:"plugin,omitempty"`
+	Tenant          string    `json:"tenant,omitempty"`
+	Start           time.Time `json:"start,omitzero"`
+	End             time.Time `json:"end,omitzero"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+}
+
+// MaintenanceWindow is a period during which /run rejects the matching
+// requests with apierror.CodeMaintenance and schedules do not fire.
+type MaintenanceWindow struct {
+	ID     string    `json:"id"`
+	Plugin string    `json:"plugin,omitempty"`
+	Tenant string    `json:"tenant,omitempty"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Client calls a plugin server. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	token   string
+	tenant  string
+	headers http.Header
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying http.Client (e.g., for custom TLS).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithToken sends the token as a bearer credential on every request.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithTenant sends the tenant in the X-Tenant header on every request.
+func WithTenant(tenant string) Option {
+	return func(c *Client) { c.tenant = tenant }
+}
+
+// WithAPIKey sends the key in the X-API-Key header on every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.headers.Set(APIKeyHeader, key) }
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.headers.Add(key, value) }
+}
+
+// New creates a Client for the server at baseURL (e.g., "http://localhost:8080").
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: DefaultTimeout},
+		headers: make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the server URL the client talks to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Run executes a plugin with the given input.
+func (c *Client) Run(ctx context.Context, plugin string, input int) (*RunResponse, error) {
+	return c.RunRequest(ctx, RunRequest{Plugin: plugin, Input: input})
+}
+
+// RunString executes a payload-ABI plugin on text and returns its text output.
+func (c *Client) RunString(ctx context.Context, plugin, text string) (string, error) {
+	resp, err := c.RunRequest(ctx, RunRequest{Plugin: plugin, Text: &text})
+	if err != nil {
+		return "", err
+	}
+	if resp.Text == nil {
+		return "", nil
+	}
+	return *resp.Text, nil
+}
+
+// RunBytes executes a payload-ABI plugin on binary data and returns its output.
+func (c *Client) RunBytes(ctx context.Context, plugin string, data []byte) ([]byte, error) {
+	req := RunRequest{Plugin: plugin, Data: data}
+	if len(data) == 0 {
+		// omitempty drops an empty Data, which would select the integer ABI;
+		// an empty text payload reaches process_bytes with the same bytes
+		empty := ""
+		req = RunRequest{Plugin: plugin, Text: &empty}
+	}
+	resp, err := c.RunRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Text != nil {
+		return []byte(*resp.Text), nil
+	}
+	return resp.Data, nil
+}
+
+// RunRequest sends a fully specified POST /run request.
+func (c *Client) RunRequest(ctx context.Context, req RunRequest) (*RunResponse, error) {
+	var resp RunResponse
+	if err := c.do(ctx, http.MethodPost, "/run", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Plugins returns the plugins available on the server, sorted by name.
+func (c *Client) Plugins(ctx context.Context) ([]PluginInfo, error) {
+	var plugins []PluginInfo
+	if err := c.do(ctx, http.MethodGet, "/plugins", nil, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+// PluginDigests returns the plugins like Plugins, with the SHA-256 of every
+// stored binary in Digest. The server reads each binary for it.
+func (c *Client) PluginDigests(ctx context.Context) ([]PluginInfo, error) {
+	var plugins []PluginInfo
+	if err := c.do(ctx, http.MethodGet, "/plugins?digest=true", nil, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+// UploadPlugin installs a plugin build on the server under name, replacing
+// any previous build. A name with a version ("hello@1.2.0") adds or
+// replaces that version only. The server validates the binary first and
+// requires an admin token (see WithToken).
+func (c *Client) UploadPlugin(ctx context.Context, name string, wasm io.Reader) (*PluginInfo, error) {
+	path := "/plugins?name=" + url.QueryEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	c.decorate(req)
+	req.Header.Set("Content-Type", "application/wasm")
+
+	var info PluginInfo
+	if err := c.send(req, path, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ExportCatalog writes every plugin build on the server to w as a catalog
+// bundle, a gzip-compressed tar archive ImportCatalog accepts. It requires
+// an admin token (see WithToken).
+func (c *Client) ExportCatalog(ctx context.Context, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/catalog", nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	c.decorate(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET /catalog: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decodeProblem(resp)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download catalog: %w", err)
+	}
+	return nil
+}
+
+// ImportCatalog installs the builds of a catalog bundle from ExportCatalog
+// on the server, replacing builds of the same name and version, and
+// returns them. It requires an admin token (see WithToken).
+func (c *Client) ImportCatalog(ctx context.Context, bundle io.Reader) ([]PluginInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/catalog", bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	c.decorate(req)
+	req.Header.Set("Content-Type", "application/gzip")
+
+	var imported []PluginInfo
+	if err := c.send(req, "/catalog", &imported); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// DeletePlugin retires a plugin, or with "name@version" one of its
+// versions, on the server. It returns once calls already running on the
+// plugin have completed, and requires an admin token (see WithToken).
+func (c *Client) DeletePlugin(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/plugins/"+url.PathEscape(name), nil, nil)
+}
+
+// LogOverride returns the log verbosity of a plugin: its active override,
+// or the server's level with a zero Expires. It requires an admin token
+// (see WithToken).
+func (c *Client) LogOverride(ctx context.Context, plugin string) (*LogOverride, error) {
+	var override LogOverride
+	if err := c.do(ctx, http.MethodGet, logOverridePath(plugin), nil, &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// SetLogOverride changes the log verbosity of a plugin until the override
+// expires, replacing any active override. It requires an admin token (see
+// WithToken).
+func (c *Client) SetLogOverride(ctx context.Context, plugin string, req LogOverrideRequest) (*LogOverride, error) {
+	var override LogOverride
+	if err := c.do(ctx, http.MethodPut, logOverridePath(plugin), req, &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// ClearLogOverride returns a plugin to the server's log level before its
+// override expires. It requires an admin token (see WithToken).
+func (c *Client) ClearLogOverride(ctx context.Context, plugin string) error {
+	return c.do(ctx, http.MethodDelete, logOverridePath(plugin), nil, nil)
+}
+
+func logOverridePath(plugin string) string {
+	return "/plugins/" + url.PathEscape(plugin) + "/logging"
+}
+
+// Schedules returns the schedules a plugin's manifest declares. It
+// requires an admin token (see WithToken).
+func (c *Client) Schedules(ctx context.Context, plugin string) ([]Schedule, error) {
+	var schedules []Schedule
+	if err := c.do(ctx, http.MethodGet, "/plugins/"+url.PathEscape(plugin)+"/schedules", nil, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// SetScheduleEnabled enables or disables a schedule of a plugin, overriding
+// its manifest until the server restarts. It requires an admin token (see
+// WithToken).
+func (c *Client) SetScheduleEnabled(ctx context.Context, plugin, schedule string, enabled bool) (*Schedule, error) {
+	path := "/plugins/" + url.PathEscape(plugin) + "/schedules/" + url.PathEscape(schedule)
+	body := struct {
+		Enabled bool `json:"enabled"`
+	}{enabled}
+	var out Schedule
+	if err := c.do(ctx, http.MethodPut, path, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartBatch starts running a plugin over every line of a file on the
+// server. It requires an admin token (see WithToken).
+func (c *Client) StartBatch(ctx context.Context, req BatchRequest) (*Batch, error) {
+	var b Batch
+	if err := c.do(ctx, http.MethodPost, "/batches", req, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Batch returns the progress of a batch. It requires an admin token (see
+// WithToken).
+func (c *Client) Batch(ctx context.Context, id string) (*Batch, error) {
+	var b Batch
+	if err := c.do(ctx, http.MethodGet, "/batches/"+url.PathEscape(id), nil, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Batches returns the batches the server remembers, newest first. It
+// requires an admin token (see WithToken).
+func (c *Client) Batches(ctx context.Context) ([]Batch, error) {
+	var batches []Batch
+	if err := c.do(ctx, http.MethodGet, "/batches", nil, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// CancelBatch cancels a batch and returns its final state. It requires an
+// admin token (see WithToken).
+func (c *Client) CancelBatch(ctx context.Context, id string) (*Batch, error) {
+	var b Batch
+	if err := c.do(ctx, http.MethodDelete, "/batches/"+url.PathEscape(id), nil, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// SubmitJob queues a request to run in the background and returns the job
+// at once; poll it with Job.
+func (c *Client) SubmitJob(ctx context.Context, req RunRequest) (*Job, error) {
+	var j Job
+	if err := c.do(ctx, http.MethodPost, "/jobs", req, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Job returns the state of a job and, once it has finished, its result or
+// error.
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var j Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CancelJob cancels a job and returns its final state.
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	var j Job
+	if err := c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Stream opens a stream to plugin ("hello" or "hello@1.2.0"). Messages
+// sent on it run one after another on the same instance, which is not
+// reset in between, saving high-frequency callers the per-call setup of
+// Run. A server refusing the stream returns an *apierror.Problem.
+func (c *Client) Stream(ctx context.Context, plugin string) (*Stream, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/run/ws?plugin="+url.QueryEscape(plugin), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	c.decorate(req)
+
+	var opts websocket.Options
+	if transport, ok := c.http.Transport.(*http.Transport); ok {
+		opts.TLSConfig = transport.TLSClientConfig
+	}
+	conn, resp, err := websocket.Dial(ctx, req.URL.String(), req.Header, opts)
+	if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+		return nil, decodeProblem(resp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GET /run/ws: %w", err)
+	}
+	return &Stream{conn: conn}, nil
+}
+
+// Send sends a message to run on the stream.
+func (s *Stream) Send(msg StreamMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Recv returns the result of the next message, in the order they were
+// sent. Once the server closes the stream, the error is a
+// *websocket.CloseError.
+func (s *Stream) Recv() (*StreamResult, error) {
+	_, data, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	var result StreamResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode stream result: %w", err)
+	}
+	return &result, nil
+}
+
+// Close closes the stream, returning its instance to the server's pool.
+func (s *Stream) Close() error {
+	return s.conn.Close(websocket.CloseNormal, "")
+}
+
+// Maintenance returns the maintenance windows that have not ended. It
+// requires an admin token (see WithToken).
+func (c *Client) Maintenance(ctx context.Context) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	if err := c.do(ctx, http.MethodGet, "/maintenance", nil, &windows); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// AddMaintenance defines a maintenance window. It requires an admin token
+// (see WithToken).
+func (c *Client) AddMaintenance(ctx context.Context, req MaintenanceRequest) (*MaintenanceWindow, error) {
+	var window MaintenanceWindow
+	if err := c.do(ctx, http.MethodPost, "/maintenance", req, &window); err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// EndMaintenance ends a maintenance window early. It requires an admin
+// token (see WithToken).
+func (c *Client) EndMaintenance(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/maintenance/"+url.PathEscape(id), nil, nil)
+}
+
+// Pools returns instance pool statistics for every plugin, least
+// recently used first. It requires an admin token (see WithToken).
+func (c *Client) Pools(ctx context.Context) ([]PoolInfo, error) {
+	var pools []struct {
+		Plugin string   `json:"plugin"`
+		Stats  PoolInfo `json:"stats"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/pools", nil, &pools); err != nil {
+		return nil, err
+	}
+	infos := make([]PoolInfo, len(pools))
+	for i, pool := range pools {
+		infos[i] = pool.Stats
+		infos[i].Plugin = pool.Plugin
+	}
+	return infos, nil
+}
+
+// Memory returns the server's memory use, broken down by plugin build. It
+// requires an admin token (see WithToken).
+func (c *Client) Memory(ctx context.Context) (*MemoryReport, error) {
+	var report MemoryReport
+	if err := c.do(ctx, http.MethodGet, "/debug/memory", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Trace returns the trace the server kept for a debug run, by the request
+// ID it was sent or answered with (see RequestIDHeader). It requires an
+// admin token (see WithToken).
+func (c *Client) Trace(ctx context.Context, requestID string) (*Trace, error) {
+	var trace Trace
+	if err := c.do(ctx, http.MethodGet, "/debug/traces/"+url.PathEscape(requestID), nil, &trace); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// do sends a JSON request and decodes a JSON response into out.
+// Non-2xx responses are returned as *apierror.Problem.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	c.decorate(req)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, path, out)
+}
+
+// send sends a prepared request and decodes a JSON response into out.
+// Non-2xx responses are returned as *apierror.Problem.
+func (c *Client) send(req *http.Request, path string, out interface{}) error {
+	method := req.Method
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeProblem(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// decorate adds authentication, tenant, and custom headers to a request.
+func (c *Client) decorate(req *http.Request) {
+	req.Header.Set("Accept", "application/json")
+	for key, values := range c.headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenant != "" {
+		req.Header.Set(TenantHeader, c.tenant)
+	}
+}
+
+// decodeProblem converts an error response into a *apierror.Problem.
+// Bodies that are not problem documents (e.g., from a proxy) are wrapped in
+// a synthesized internal_error problem carrying the HTTP status.
+func decodeProblem(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), apierror.ContentType) {
+		var problem apierror.Problem
+		if err := json.Unmarshal(data, &problem); err == nil && problem.Code != "" {
+			return &problem
+		}
+	}
+
+	problem := apierror.New(apierror.CodeInternal, strings.TrimSpace(string(data)))
+	problem.Status = resp.StatusCode
+	problem.Title = http.StatusText(resp.StatusCode)
+	return problem
+}
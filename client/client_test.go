@@ -0,0 +1,541 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/client"
+	"github.com/mrhapile/wasm-plugin-system/websocket"
+)
+
+func TestClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Client Suite")
+}
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		received *http.Request
+	)
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+
+			var req client.RunRequest
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+
+			switch {
+			case req.Text != nil:
+				upper := strings.ToUpper(*req.Text)
+				length := len(upper)
+				json.NewEncoder(w).Encode(client.RunResponse{Output: &length, Text: &upper})
+				return
+			case req.Data != nil:
+				length := len(req.Data)
+				json.NewEncoder(w).Encode(client.RunResponse{Output: &length, Data: bytes.ToUpper(req.Data)})
+				return
+			}
+
+			if req.Plugin == "missing" {
+				w.Header().Set("Content-Type", apierror.ContentType)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(apierror.New(apierror.CodePluginNotFound, "plugin not found: missing"))
+				return
+			}
+			if req.Plugin == "notify" {
+				w.Write([]byte(`{"output":null}`))
+				return
+			}
+			if req.Version != "" {
+				fmt.Fprintf(w, `{"output":1,"version":%q}`, req.Version)
+				return
+			}
+			if req.IncludeLogs {
+				w.Write([]byte(`{"output":0,"logs":[{"time":"2024-05-01T12:00:00Z","level":"warn","plugin":"logger","request_id":"` +
+					r.Header.Get(client.RequestIDHeader) + `","message":"input is zero"}]}`))
+				return
+			}
+			output := req.Input*2 + 1
+			json.NewEncoder(w).Encode(client.RunResponse{
+				Output:   &output,
+				Warnings: []client.Warning{{Code: "deprecated", Message: "old ABI"}},
+			})
+		})
+		mux.HandleFunc("/plugins", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.Method == http.MethodPost {
+				data, _ := io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"name":%q,"size":%d,"mod_time":"2024-05-01T12:00:00Z"}`, r.URL.Query().Get("name"), len(data))
+				return
+			}
+			w.Write([]byte(`[{"name":"hello","size":1234,"mod_time":"2024-05-01T12:00:00Z"}]`))
+		})
+		mux.HandleFunc("/plugins/", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.URL.Path == "/plugins/hello/logging" && r.Method != http.MethodDelete {
+				level, expires := "info", ""
+				if r.Method == http.MethodPut {
+					var req client.LogOverrideRequest
+					Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+					level, expires = req.Level, `,"expires":"2024-05-01T12:15:00Z"`
+				}
+				fmt.Fprintf(w, `{"plugin":"hello","level":%q%s}`, level, expires)
+				return
+			}
+			if r.URL.Path == "/plugins/rollup/schedules" {
+				w.Write([]byte(`[{"plugin":"rollup","name":"nightly","cron":"0 2 * * *","enabled":true,"next":"2024-05-02T02:00:00Z"}]`))
+				return
+			}
+			if r.URL.Path == "/plugins/rollup/schedules/nightly" {
+				var req struct{ Enabled bool }
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				fmt.Fprintf(w, `{"plugin":"rollup","name":"nightly","cron":"0 2 * * *","enabled":%t}`, req.Enabled)
+				return
+			}
+			if r.URL.Path == "/plugins/missing" {
+				w.Header().Set("Content-Type", apierror.ContentType)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(apierror.New(apierror.CodePluginNotFound, "plugin not found: missing"))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("/debug/traces/", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.URL.Path != "/debug/traces/req-1" {
+				w.Header().Set("Content-Type", apierror.ContentType)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(apierror.New(apierror.CodeTraceNotFound, "no trace kept"))
+				return
+			}
+			w.Write([]byte(`{"request_id":"req-1","plugin":"logger","time":"2024-05-01T12:00:00Z","error":"trap","wasi":["fd_write"],"events":[` +
+				`{"seq":1,"kind":"export","function":"process","args":[0],"error":"trap","duration_ns":1500},` +
+				`{"seq":2,"kind":"host","module":"logging","function":"log","args":[2,1024,13],"duration_ns":200,` +
+				`"memory":[{"op":"read","ptr":1024,"len":13,"data":"aW5wdXQgaXMgemVybw=="}]}]}`))
+		})
+		mux.HandleFunc("/batches", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.Method == http.MethodPost {
+				var req client.BatchRequest
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				w.WriteHeader(http.StatusAccepted)
+				fmt.Fprintf(w, `{"id":"b1","plugin":%q,"input":%q,"state":"running","concurrency":4,"started":"2024-05-01T12:00:00Z"}`, req.Plugin, req.Input)
+				return
+			}
+			w.Write([]byte(`[{"id":"b1","plugin":"enrich","state":"running"}]`))
+		})
+		mux.HandleFunc("/batches/", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			state := "running"
+			if r.Method == http.MethodDelete {
+				state = "cancelled"
+			}
+			fmt.Fprintf(w, `{"id":"b1","plugin":"enrich","state":%q,"size_bytes":100,"bytes_read":40,"processed":3}`, state)
+		})
+		mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			var req client.RunRequest
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+			w.Header().Set("Location", "/jobs/j1")
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintf(w, `{"id":"j1","plugin":%q,"state":"queued","created":"2024-05-01T12:00:00Z"}`, req.Plugin)
+		})
+		mux.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.Method == http.MethodDelete {
+				w.Write([]byte(`{"id":"j1","plugin":"slow","state":"cancelled"}`))
+				return
+			}
+			w.Write([]byte(`{"id":"j1","plugin":"slow","state":"failed",` +
+				`"error":{"type":"about:blank","title":"Plugin timed out","status":504,"code":"plugin_timeout"}}`))
+		})
+		mux.HandleFunc("/maintenance", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.Method == http.MethodPost {
+				var req client.MaintenanceRequest
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"id":"m1","plugin":%q,"start":"2024-05-01T12:00:00Z","end":"2024-05-01T13:00:00Z"}`, req.Plugin)
+				return
+			}
+			w.Write([]byte(`[{"id":"m1","plugin":"billing","start":"2024-05-01T12:00:00Z","end":"2024-05-01T13:00:00Z"}]`))
+		})
+		mux.HandleFunc("/maintenance/", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			w.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("/admin/pools", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"plugin":"hello","last_used":"2026-10-16T12:00:00Z",` +
+				`"stats":{"path":"/plugins/hello/hello.wasm","strategy":"restore","idle":2,"evictions":{"discarded":1}},` +
+				`"memory":{"instances":2,"linear_memory_bytes":131072,"snapshot_bytes":131072}}]`))
+		})
+		mux.HandleFunc("/debug/memory", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"process":{"rss_bytes":104857600,"go_heap_bytes":4194304,"go_sys_bytes":16777216},` +
+				`"plugins":[{"plugins":["hello"],"digest":"ab12","paths":["/plugins/hello/hello.wasm"],"instances":2,` +
+				`"linear_memory_bytes":262144,"snapshot_bytes":262144,"module_bytes":1342,"artifact_bytes":0,"total_bytes":525630}],` +
+				`"attributed_bytes":525630}`))
+		})
+		mux.HandleFunc("/run/ws", func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if r.URL.Query().Get("plugin") == "missing" {
+				w.Header().Set("Content-Type", apierror.ContentType)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(apierror.New(apierror.CodePluginNotFound, "plugin not found: missing"))
+				return
+			}
+			conn, err := websocket.Upgrade(w, r, websocket.Options{})
+			if err != nil {
+				return
+			}
+			// Counts its inputs, failing on negative ones
+			defer conn.Close(websocket.CloseNormal, "")
+			total := 0
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				var msg client.StreamMessage
+				json.Unmarshal(data, &msg)
+				result := client.StreamResult{ID: msg.ID}
+				if msg.Input < 0 {
+					result.Error = apierror.New(apierror.CodePluginExecutionFailed, "negative input")
+				} else {
+					total += msg.Input
+					output := total
+					result.RunResponse = &client.RunResponse{Output: &output}
+				}
+				data, _ = json.Marshal(result)
+				conn.WriteMessage(websocket.TextMessage, data)
+			}
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should run a plugin and decode warnings", func() {
+		c := client.New(server.URL + "/")
+
+		resp, err := c.Run(context.Background(), "hello", 20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Output).To(HaveValue(Equal(41)))
+		Expect(resp.Warnings).To(ConsistOf(client.Warning{Code: "deprecated", Message: "old ABI"}))
+	})
+
+	It("should request and decode plugin logs", func() {
+		c := client.New(server.URL, client.WithHeader(client.RequestIDHeader, "req-1"))
+
+		resp, err := c.RunRequest(context.Background(), client.RunRequest{Plugin: "logger", IncludeLogs: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Logs).To(ConsistOf(client.LogEntry{
+			Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Level:     "warn",
+			Plugin:    "logger",
+			RequestID: "req-1",
+			Message:   "input is zero",
+		}))
+	})
+
+	It("should pin a plugin version", func() {
+		resp, err := client.New(server.URL).RunRequest(context.Background(), client.RunRequest{Plugin: "hello", Version: "1.2.0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Version).To(Equal("1.2.0"))
+	})
+
+	It("should distinguish no output from zero", func() {
+		resp, err := client.New(server.URL).Run(context.Background(), "notify", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Output).To(BeNil())
+	})
+
+	// =========================================================================
+	// TEST: Auth and tenant headers
+	// Why: Context profiles in pluginctl rely on the client attaching the
+	//      configured credentials to every request.
+	// =========================================================================
+	It("should send token, tenant, API key, and custom headers", func() {
+		c := client.New(server.URL,
+			client.WithToken("s3cret"),
+			client.WithTenant("team-a"),
+			client.WithAPIKey("k3y"),
+			client.WithHeader("X-Trace", "abc"),
+		)
+
+		_, err := c.Run(context.Background(), "hello", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer s3cret"))
+		Expect(received.Header.Get(client.TenantHeader)).To(Equal("team-a"))
+		Expect(received.Header.Get(client.APIKeyHeader)).To(Equal("k3y"))
+		Expect(received.Header.Get("X-Trace")).To(Equal("abc"))
+	})
+
+	// =========================================================================
+	// TEST: Problem decoding
+	// Why: Callers branch on apierror codes, so server problems must come back
+	//      as *apierror.Problem rather than opaque strings.
+	// =========================================================================
+	It("should return problems with their stable code", func() {
+		c := client.New(server.URL)
+
+		_, err := c.Run(context.Background(), "missing", 1)
+		Expect(err).To(HaveOccurred())
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginNotFound))
+
+		var problem *apierror.Problem
+		Expect(err).To(BeAssignableToTypeOf(problem))
+		Expect(err.(*apierror.Problem).Status).To(Equal(http.StatusNotFound))
+	})
+
+	It("should synthesize a problem for non-problem error bodies", func() {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}))
+		defer proxy.Close()
+
+		_, err := client.New(proxy.URL).Pools(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeInternal))
+		Expect(err.(*apierror.Problem).Status).To(Equal(http.StatusBadGateway))
+	})
+
+	// =========================================================================
+	// TEST: Payload requests
+	// Why: Text and bytes must select process_bytes on the server, including
+	//      empty input, which JSON omitempty would otherwise drop.
+	// =========================================================================
+	It("should run text and byte payloads", func() {
+		c := client.New(server.URL)
+
+		text, err := c.RunString(context.Background(), "upper", "hi")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal("HI"))
+
+		data, err := c.RunBytes(context.Background(), "upper", []byte{'a', 0})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal([]byte{'A', 0}))
+
+		data, err = c.RunBytes(context.Background(), "upper", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeEmpty())
+	})
+
+	It("should list plugins", func() {
+		c := client.New(server.URL)
+
+		plugins, err := c.Plugins(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(1))
+		Expect(plugins[0].Name).To(Equal("hello"))
+		Expect(plugins[0].Size).To(Equal(int64(1234)))
+		Expect(plugins[0].ModTime).To(Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)))
+	})
+
+	It("should upload a plugin as the raw body", func() {
+		c := client.New(server.URL, client.WithToken("admin"))
+
+		info, err := c.UploadPlugin(context.Background(), "hello", strings.NewReader("\x00asm"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Name).To(Equal("hello"))
+		Expect(info.Size).To(Equal(int64(4)))
+		Expect(received.Header.Get("Content-Type")).To(Equal("application/wasm"))
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer admin"))
+	})
+
+	It("should delete a plugin", func() {
+		c := client.New(server.URL, client.WithToken("admin"))
+
+		Expect(c.DeletePlugin(context.Background(), "hello")).To(Succeed())
+		Expect(received.Method).To(Equal(http.MethodDelete))
+		Expect(received.URL.Path).To(Equal("/plugins/hello"))
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer admin"))
+
+		err := c.DeletePlugin(context.Background(), "missing")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginNotFound))
+	})
+
+	It("should manage the log override of a plugin", func() {
+		c := client.New(server.URL, client.WithToken("admin"))
+
+		override, err := c.LogOverride(context.Background(), "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.URL.Path).To(Equal("/plugins/hello/logging"))
+		Expect(override.Level).To(Equal("info"))
+		Expect(override.Expires).To(BeZero())
+
+		override, err = c.SetLogOverride(context.Background(), "hello", client.LogOverrideRequest{Level: "debug", DurationSeconds: 900})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Method).To(Equal(http.MethodPut))
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer admin"))
+		Expect(override.Level).To(Equal("debug"))
+		Expect(override.Expires).To(Equal(time.Date(2024, 5, 1, 12, 15, 0, 0, time.UTC)))
+
+		Expect(c.ClearLogOverride(context.Background(), "hello")).To(Succeed())
+		Expect(received.Method).To(Equal(http.MethodDelete))
+	})
+
+	It("should list and pause the schedules of a plugin", func() {
+		c := client.New(server.URL, client.WithToken("admin"))
+
+		schedules, err := c.Schedules(context.Background(), "rollup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedules).To(Equal([]client.Schedule{{
+			Plugin: "rollup", Name: "nightly", Cron: "0 2 * * *", Enabled: true,
+			Next: time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC),
+		}}))
+
+		schedule, err := c.SetScheduleEnabled(context.Background(), "rollup", "nightly", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Method).To(Equal(http.MethodPut))
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer admin"))
+		Expect(schedule.Enabled).To(BeFalse())
+	})
+
+	It("should start, follow, and cancel batches", func() {
+		c := client.New(server.URL, client.WithToken("admin"))
+
+		b, err := c.StartBatch(context.Background(), client.BatchRequest{Plugin: "enrich", Input: "events.jsonl"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer admin"))
+		Expect(b.Input).To(Equal("events.jsonl"))
+		Expect(b.State).To(Equal("running"))
+
+		b, err = c.Batch(context.Background(), "b1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.URL.Path).To(Equal("/batches/b1"))
+		Expect(b.BytesRead).To(Equal(int64(40)))
+
+		batches, err := c.Batches(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batches).To(HaveLen(1))
+
+		b, err = c.CancelBatch(context.Background(), "b1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Method).To(Equal(http.MethodDelete))
+		Expect(b.State).To(Equal("cancelled"))
+	})
+
+	It("should submit, follow, and cancel jobs", func() {
+		c := client.New(server.URL)
+
+		j, err := c.SubmitJob(context.Background(), client.RunRequest{Plugin: "slow", Input: 5})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(j.ID).To(Equal("j1"))
+		Expect(j.State).To(Equal("queued"))
+
+		j, err = c.Job(context.Background(), "j1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.URL.Path).To(Equal("/jobs/j1"))
+		Expect(j.Error.Code).To(Equal(apierror.CodePluginTimeout))
+
+		j, err = c.CancelJob(context.Background(), "j1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Method).To(Equal(http.MethodDelete))
+		Expect(j.State).To(Equal("cancelled"))
+	})
+
+	It("should stream inputs and receive their results in order", func() {
+		c := client.New(server.URL, client.WithToken("secret"))
+
+		stream, err := c.Stream(context.Background(), "counter@1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		defer stream.Close()
+		Expect(received.URL.Query().Get("plugin")).To(Equal("counter@1.0.0"))
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer secret"))
+
+		for i, input := range []int{1, 2, -1} {
+			Expect(stream.Send(client.StreamMessage{ID: fmt.Sprint(i), Input: input})).To(Succeed())
+		}
+		result, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.ID).To(Equal("0"))
+		Expect(*result.Output).To(Equal(1))
+		result, err = stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*result.Output).To(Equal(3))
+		result, err = stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RunResponse).To(BeNil())
+		Expect(result.Error.Code).To(Equal(apierror.CodePluginExecutionFailed))
+
+		_, err = c.Stream(context.Background(), "missing")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginNotFound))
+	})
+
+	It("should define, list, and end maintenance windows", func() {
+		c := client.New(server.URL, client.WithToken("admin"))
+
+		window, err := c.AddMaintenance(context.Background(), client.MaintenanceRequest{Plugin: "billing", DurationSeconds: 3600})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer admin"))
+		Expect(window.End).To(Equal(time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)))
+
+		windows, err := c.Maintenance(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(windows).To(Equal([]client.MaintenanceWindow{*window}))
+
+		Expect(c.EndMaintenance(context.Background(), "m1")).To(Succeed())
+		Expect(received.Method).To(Equal(http.MethodDelete))
+		Expect(received.URL.Path).To(Equal("/maintenance/m1"))
+	})
+
+	It("should fetch the trace of a debug run", func() {
+		c := client.New(server.URL)
+
+		trace, err := c.Trace(context.Background(), "req-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.URL.Path).To(Equal("/debug/traces/req-1"))
+		Expect(trace.Error).To(Equal("trap"))
+		Expect(trace.WASI).To(Equal([]string{"fd_write"}))
+		Expect(trace.Events).To(HaveLen(2))
+		Expect(trace.Events[0].Duration).To(Equal(1500 * time.Nanosecond))
+		Expect(trace.Events[1].Module).To(Equal("logging"))
+		Expect(trace.Events[1].Memory).To(Equal([]client.MemoryAccess{
+			{Op: "read", Ptr: 1024, Len: 13, Data: []byte("input is zero")},
+		}))
+
+		_, err = c.Trace(context.Background(), "req-2")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTraceNotFound))
+	})
+
+	It("should list pools", func() {
+		c := client.New(server.URL)
+
+		pools, err := c.Pools(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pools).To(HaveLen(1))
+		Expect(pools[0].Plugin).To(Equal("hello"))
+		Expect(pools[0].Idle).To(Equal(2))
+		Expect(pools[0].Evictions).To(HaveKeyWithValue("discarded", uint64(1)))
+	})
+
+	It("should report memory by plugin build", func() {
+		c := client.New(server.URL)
+
+		report, err := c.Memory(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Process.RSSBytes).To(Equal(int64(100 << 20)))
+		Expect(report.Plugins).To(HaveLen(1))
+		Expect(report.Plugins[0].Plugins).To(Equal([]string{"hello"}))
+		Expect(report.Plugins[0].Instances).To(Equal(2))
+		Expect(report.Plugins[0].TotalBytes).To(Equal(report.AttributedBytes))
+	})
+})
@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"github.com/second-state/WasmEdge-go/wasmedge"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
 )
 
 func main() {
@@ -62,16 +64,13 @@ func main() {
 		fmt.Printf("Warning: Plugin does not export get_abi_version: %v\n", err)
 		fmt.Println("Continuing without version check (backward compatibility)")
 	} else {
-		version := result[0].(int32)
-		major := version / 10000
-		minor := (version % 10000) / 100
-		patch := version % 100
-		fmt.Printf("Plugin ABI version: v%d.%d.%d\n", major, minor, patch)
-
-		// Check major version compatibility
-		requiredMajor := int32(1)
-		if major != requiredMajor {
-			fmt.Printf("Error: Incompatible ABI version (expected major %d, got %d)\n", requiredMajor, major)
+		version := runtime.ABIVersion(result[0].(int32))
+		fmt.Printf("Plugin ABI version: v%s\n", version)
+
+		// Check compatibility against the versions the runtime implements;
+		// runtime.LoadPlugin runs this check on every load
+		if !runtime.SupportedABI.Contains(version) {
+			fmt.Printf("Error: Incompatible ABI version (supported: %s)\n", runtime.SupportedABI)
 			os.Exit(1)
 		}
 	}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestEnginecanary bootstraps the Ginkgo test suite for the engine canary.
+// Run with: go test -v ./cmd/enginecanary/...
+func TestEnginecanary(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Enginecanary Suite")
+}
@@ -0,0 +1,298 @@
+// Command enginecanary smoke-tests a plugin catalog against the WasmEdge
+// library it runs with, so that an engine upgrade can be checked before it
+// is rolled out.
+//
+// Usage:
+//
+//	enginecanary [flags] PLUGIN_DIR
+//
+// Every build in PLUGIN_DIR (laid out like the local plugin store), or a
+// sample of them, is loaded, initialized, and called with a few inputs in
+// a child process of its own, so that a build crashing or hanging the new
+// engine is reported rather than taking the run down. The report records
+// the engine version and, per build, how far it got and what each call
+// returned.
+//
+// Run it once with the current library and once with the candidate, then
+// compare: with --baseline the exit status is 1 if any build behaves
+// differently than in the baseline report, and 0 otherwise.
+//
+//	enginecanary -o wasmedge-0.13.json plugins/
+//	LD_LIBRARY_PATH=/opt/wasmedge-0.14/lib \
+//	    enginecanary --baseline wasmedge-0.13.json -o wasmedge-0.14.json plugins/
+//
+// A candidate whose C API changed needs enginecanary rebuilt against it
+// instead of LD_LIBRARY_PATH.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// probeFlag runs enginecanary as the child process smoke-testing one
+// build; it is not meant to be used directly.
+const probeFlag = "probe"
+
+// Options configures the smoke test of a build.
+type Options struct {
+	Inputs  []int         // Inputs process() is called with
+	Text    string        // Input of process_bytes(), for plugins with the payload ABI
+	Timeout time.Duration // Bound on the whole test of a build
+}
+
+// probeFunc smoke-tests the build of ref at path; tests replace it to
+// avoid WasmEdge.
+var probeFunc = probeIsolated
+
+// capabilitiesFunc reports the engine; tests replace it.
+var capabilitiesFunc = runtime.EngineCapabilities
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run parses flags, tests the catalog, and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("enginecanary", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "write the JSON report to this file")
+	baseline := fs.String("baseline", "", "report of the current engine to compare with; differences fail the run")
+	sample := fs.Int("sample", 0, "test this many builds picked at random, 0 for all")
+	seed := fs.Int64("seed", 1, "seed of --sample; keep it to test the same builds with both engines")
+	inputs := fs.String("inputs", "0,1,42", "comma-separated process() inputs")
+	text := fs.String("text", "canary", "process_bytes() input")
+	timeout := fs.Duration("timeout", 10*time.Second, "bound on the test of each build")
+	probe := fs.String(probeFlag, "", "")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts := Options{Text: *text, Timeout: *timeout}
+	for _, field := range strings.Split(*inputs, ",") {
+		input, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			fmt.Fprintf(stderr, "enginecanary: invalid input %q\n", field)
+			return 2
+		}
+		opts.Inputs = append(opts.Inputs, input)
+	}
+
+	// The child process prints the result of one build
+	if *probe != "" {
+		json.NewEncoder(stdout).Encode(probeBuild(*probe, fs.Arg(0), opts))
+		return 0
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: enginecanary [flags] PLUGIN_DIR")
+		return 2
+	}
+
+	var old *CanaryReport
+	if *baseline != "" {
+		data, err := os.ReadFile(*baseline)
+		if err == nil {
+			old = &CanaryReport{}
+			err = json.Unmarshal(data, old)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "enginecanary: reading baseline: %v\n", err)
+			return 1
+		}
+	}
+
+	store := fluid.NewLocalPluginStore(fs.Arg(0))
+	builds, err := store.List()
+	if err != nil {
+		fmt.Fprintf(stderr, "enginecanary: listing %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	if *sample > 0 && *sample < len(builds) {
+		rand.New(rand.NewSource(*seed)).Shuffle(len(builds), func(i, j int) {
+			builds[i], builds[j] = builds[j], builds[i]
+		})
+		builds = builds[:*sample]
+		sort.Slice(builds, func(i, j int) bool { return reference(builds[i]) < reference(builds[j]) })
+	}
+
+	report := CanaryReport{Engine: capabilitiesFunc(), Time: time.Now().UTC(), Plugins: []Result{}}
+	for _, build := range builds {
+		ref := reference(build)
+		path, err := store.Resolve(ref)
+		if err != nil {
+			report.Plugins = append(report.Plugins, Result{Plugin: ref, Status: StatusInvalid, Error: err.Error()})
+			continue
+		}
+		result := probeFunc(ref, path, opts)
+		result.Plugin = ref
+		report.Plugins = append(report.Plugins, result)
+	}
+	if old != nil {
+		report.Baseline = old.Engine.Version
+		report.Incompatibilities = Compare(*old, report)
+	}
+
+	if *output != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+			fmt.Fprintf(stderr, "enginecanary: %v\n", err)
+			return 1
+		}
+	}
+	printReport(stdout, report, old != nil)
+
+	if len(report.Incompatibilities) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// reference returns the plugin reference of a build: name or name@version.
+func reference(build fluid.PluginInfo) string {
+	if build.Version == "" {
+		return build.Name
+	}
+	return build.Name + "@" + build.Version
+}
+
+// probeIsolated tests the build at path in a child process, which is
+// killed after opts.Timeout. A child that dies or prints no result is
+// reported as crashed.
+func probeIsolated(ref, path string, opts Options) Result {
+	self, err := os.Executable()
+	if err != nil {
+		return Result{Status: StatusCrashed, Error: err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout+time.Second)
+	defer cancel()
+
+	inputs := make([]string, len(opts.Inputs))
+	for i, input := range opts.Inputs {
+		inputs[i] = strconv.Itoa(input)
+	}
+	cmd := exec.CommandContext(ctx, self,
+		"-"+probeFlag, ref,
+		"-inputs", strings.Join(inputs, ","),
+		"-text", opts.Text,
+		"-timeout", opts.Timeout.String(),
+		path)
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err = cmd.Run()
+
+	// The result is the last line; output of init() may precede it
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	var result Result
+	if jsonErr := json.Unmarshal(lines[len(lines)-1], &result); err == nil && jsonErr == nil {
+		return result
+	}
+	if ctx.Err() != nil {
+		return Result{Status: StatusTimeout, Error: fmt.Sprintf("no result within %s", opts.Timeout)}
+	}
+	message := strings.TrimSpace(errOut.String())
+	if len(message) > 1024 {
+		message = message[len(message)-1024:]
+	}
+	if message == "" && err != nil {
+		message = err.Error()
+	}
+	return Result{Status: StatusCrashed, Error: message}
+}
+
+// probeBuild loads, initializes, and calls the build at path in this
+// process, with the host modules that need no server.
+func probeBuild(ref, path string, opts Options) Result {
+	result := Result{Plugin: ref, Status: StatusOK}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	plugin, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+		HostModules: []*runtime.HostModule{
+			runtime.NewLogModule(func(runtime.LogEntry) {}),
+			runtime.NewContextModule(),
+			runtime.NewStdlibModule(),
+			runtime.NewOutboxModule(func(runtime.Effect) error { return nil }),
+		},
+		CaptureOutput: true,
+	})
+	result.Load = time.Since(started)
+	if err != nil {
+		result.Status, result.Error = StatusLoadFailed, err.Error()
+		return result
+	}
+	defer plugin.Close()
+	result.ABIVersion = plugin.ABIVersion().String()
+
+	// Output of the calls is discarded; it would precede the result
+	ctx = runtime.WithOutputCapture(ctx, runtime.NewOutputCapture(0))
+	if err := plugin.InitContext(ctx); err != nil {
+		result.Status, result.Error = StatusInitFailed, err.Error()
+		return result
+	}
+
+	for _, input := range opts.Inputs {
+		output, err := plugin.ExecuteContext(ctx, input)
+		result.Calls = append(result.Calls, callResult("process", strconv.Itoa(input), strconv.Itoa(output), err))
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Status = StatusTimeout
+			return result
+		}
+	}
+	if plugin.SupportsPayloads() {
+		output, err := plugin.ExecuteBytesContext(ctx, []byte(opts.Text))
+		result.Calls = append(result.Calls, callResult("process_bytes", opts.Text, string(output), err))
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Status = StatusTimeout
+		}
+	}
+	return result
+}
+
+// callResult records a call, keeping its output only if it succeeded.
+func callResult(export, input, output string, err error) Call {
+	if err != nil {
+		return Call{Export: export, Input: input, Error: err.Error()}
+	}
+	return Call{Export: export, Input: input, Output: output}
+}
+
+// printReport writes the outcome per build and, when comparing, every
+// incompatibility.
+func printReport(w io.Writer, r CanaryReport, compared bool) {
+	fmt.Fprintf(w, "engine: wasmedge %s\n", r.Engine.Version)
+	for _, p := range r.Plugins {
+		if p.Error != "" {
+			fmt.Fprintf(w, "%-12s %s: %s\n", p.Status, p.Plugin, p.Error)
+		} else {
+			fmt.Fprintf(w, "%-12s %s\n", p.Status, p.Plugin)
+		}
+	}
+	if !compared {
+		return
+	}
+
+	if len(r.Incompatibilities) == 0 {
+		fmt.Fprintf(w, "PASS: %d build(s) behave as with wasmedge %s\n", len(r.Plugins), r.Baseline)
+		return
+	}
+	for _, inc := range r.Incompatibilities {
+		fmt.Fprintf(w, "FAIL %s: %s\n", inc.Plugin, inc.Reason)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("enginecanary", func() {
+	var (
+		dir            string
+		stdout, stderr *bytes.Buffer
+		probed         []string
+		outputs        map[string]string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		for _, name := range []string{"alpha", "beta", "gamma"} {
+			Expect(os.MkdirAll(filepath.Join(dir, name), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, name, name+".wasm"), []byte("\x00asm"), 0o644)).To(Succeed())
+		}
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		probed = nil
+		outputs = map[string]string{"alpha": "1", "beta": "2", "gamma": "3"}
+
+		originalProbe, originalCapabilities := probeFunc, capabilitiesFunc
+		probeFunc = func(ref, path string, opts Options) Result {
+			probed = append(probed, ref)
+			Expect(path).To(Equal(filepath.Join(dir, ref, ref+".wasm")))
+			if ref == "gamma" {
+				return Result{Status: StatusCrashed, Error: "signal: segmentation fault"}
+			}
+			return Result{Status: StatusOK, Calls: []Call{{Export: "process", Input: "0", Output: outputs[ref]}}}
+		}
+		capabilitiesFunc = func() runtime.Capabilities { return runtime.Capabilities{Version: "0.14.0"} }
+		DeferCleanup(func() { probeFunc, capabilitiesFunc = originalProbe, originalCapabilities })
+	})
+
+	// =========================================================================
+	// TEST: Catalog run and report
+	// Why: The report of one engine is the baseline of the next run, so it
+	//      must cover every build, including those that crash the child.
+	// =========================================================================
+	It("should test every build and write the report", func() {
+		path := filepath.Join(dir, "report.json")
+		Expect(run([]string{"-o", path, dir}, stdout, stderr)).To(Equal(0), stderr.String())
+		Expect(probed).To(Equal([]string{"alpha", "beta", "gamma"}))
+		Expect(stdout.String()).To(ContainSubstring("engine: wasmedge 0.14.0"))
+		Expect(stdout.String()).To(ContainSubstring("crashed      gamma: signal: segmentation fault"))
+
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var report CanaryReport
+		Expect(json.Unmarshal(data, &report)).To(Succeed())
+		Expect(report.Engine.Version).To(Equal("0.14.0"))
+		Expect(report.Plugins).To(HaveLen(3))
+		Expect(report.Plugins[0].Plugin).To(Equal("alpha"))
+	})
+
+	It("should test the same sample for the same seed", func() {
+		Expect(run([]string{"-sample", "2", "-seed", "7", dir}, stdout, stderr)).To(Equal(0))
+		first := probed
+		Expect(first).To(HaveLen(2))
+
+		probed = nil
+		Expect(run([]string{"-sample", "2", "-seed", "7", dir}, stdout, stderr)).To(Equal(0))
+		Expect(probed).To(Equal(first))
+	})
+
+	// =========================================================================
+	// TEST: Baseline comparison
+	// Why: Upgrade pipelines act on the exit status alone.
+	// =========================================================================
+	It("should pass when every build behaves as in the baseline", func() {
+		baseline := filepath.Join(dir, "baseline.json")
+		Expect(run([]string{"-o", baseline, dir}, stdout, stderr)).To(Equal(0))
+
+		stdout.Reset()
+		Expect(run([]string{"-baseline", baseline, dir}, stdout, stderr)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring("PASS: 3 build(s) behave as with wasmedge 0.14.0"))
+	})
+
+	It("should fail on a changed output", func() {
+		baseline := filepath.Join(dir, "baseline.json")
+		Expect(run([]string{"-o", baseline, dir}, stdout, stderr)).To(Equal(0))
+
+		outputs["beta"] = "20"
+		stdout.Reset()
+		Expect(run([]string{"-baseline", baseline, dir}, stdout, stderr)).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring(`FAIL beta: process("0") returned "2", now "20"`))
+	})
+
+	It("should reject unusable arguments", func() {
+		Expect(run(nil, stdout, stderr)).To(Equal(2))
+		Expect(run([]string{"-inputs", "1,x", dir}, stdout, stderr)).To(Equal(2))
+		Expect(run([]string{"-baseline", filepath.Join(dir, "missing.json"), dir}, stdout, stderr)).To(Equal(1))
+	})
+})
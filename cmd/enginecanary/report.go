@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// Statuses of a build, in the order a smoke test gets through them.
+const (
+	StatusInvalid    = "invalid"     // The store refuses the build, e.g. for its manifest
+	StatusLoadFailed = "load_failed" // The engine did not load or instantiate it
+	StatusInitFailed = "init_failed" // init() failed
+	StatusCrashed    = "crashed"     // The child process died without a result
+	StatusTimeout    = "timeout"     // The test did not finish within --timeout
+	StatusOK         = "ok"          // Every call ran; they may still have failed
+)
+
+// CanaryReport is the JSON output of enginecanary.
+type CanaryReport struct {
+	Engine  runtime.Capabilities `json:"engine"`
+	Time    time.Time            `json:"time"`
+	Plugins []Result             `json:"plugins"`
+
+	// Baseline is the engine version of the report compared with, and
+	// Incompatibilities how the builds behaved differently from it.
+	Baseline          string            `json:"baseline,omitempty"`
+	Incompatibilities []Incompatibility `json:"incompatibilities,omitempty"`
+}
+
+// Result is the outcome of the smoke test of one build.
+type Result struct {
+	Plugin     string        `json:"plugin"` // name or name@version
+	Status     string        `json:"status"`
+	Error      string        `json:"error,omitempty"` // Why the build did not get to StatusOK
+	ABIVersion string        `json:"abi_version,omitempty"`
+	Load       time.Duration `json:"load_ns,omitempty"` // Load and instantiation time
+	Calls      []Call        `json:"calls,omitempty"`
+}
+
+// Call is a call made by the smoke test.
+type Call struct {
+	Export string `json:"export"` // process or process_bytes
+	Input  string `json:"input"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Incompatibility is a build that behaves differently on the new engine.
+type Incompatibility struct {
+	Plugin string `json:"plugin"`
+	Reason string `json:"reason"`
+}
+
+// Compare returns how the builds tested in newer behave differently than
+// in older. Builds tested only in one of them, e.g. because of --sample,
+// are not compared, and neither are timings.
+func Compare(older, newer CanaryReport) []Incompatibility {
+	before := make(map[string]Result, len(older.Plugins))
+	for _, result := range older.Plugins {
+		before[result.Plugin] = result
+	}
+
+	var incompatibilities []Incompatibility
+	add := func(plugin, format string, args ...interface{}) {
+		incompatibilities = append(incompatibilities, Incompatibility{Plugin: plugin, Reason: fmt.Sprintf(format, args...)})
+	}
+	for _, result := range newer.Plugins {
+		old, ok := before[result.Plugin]
+		if !ok {
+			continue
+		}
+		// Step 1: How far the test got
+		if old.Status != result.Status {
+			reason := result.Status
+			if result.Error != "" {
+				reason += ": " + result.Error
+			}
+			add(result.Plugin, "was %s, now %s", old.Status, reason)
+			continue
+		}
+		if old.ABIVersion != result.ABIVersion {
+			add(result.Plugin, "reported ABI version %s, now %s", old.ABIVersion, result.ABIVersion)
+		}
+
+		// Step 2: What every call returned; error messages are compared
+		// as the plugin's error codes are part of them
+		if len(old.Calls) != len(result.Calls) {
+			add(result.Plugin, "made %d calls, now %d; were the inputs the same?", len(old.Calls), len(result.Calls))
+			continue
+		}
+		for i, call := range result.Calls {
+			was := old.Calls[i]
+			switch {
+			case was.Export != call.Export || was.Input != call.Input:
+				add(result.Plugin, "called %s(%q), now %s(%q); were the inputs the same?", was.Export, was.Input, call.Export, call.Input)
+			case was.Error != call.Error:
+				add(result.Plugin, "%s(%q) failed with %q, now %q", call.Export, call.Input, was.Error, call.Error)
+			case was.Output != call.Output:
+				add(result.Plugin, "%s(%q) returned %q, now %q", call.Export, call.Input, was.Output, call.Output)
+			}
+		}
+	}
+	return incompatibilities
+}
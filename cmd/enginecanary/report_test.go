@@ -0,0 +1,61 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compare", func() {
+	// =========================================================================
+	// TEST: What counts as incompatible
+	// Why: An upgrade is blocked on the report; it must flag every change in
+	//      behavior and nothing else, or people learn to ignore it.
+	// =========================================================================
+	ok := func(plugin string, calls ...Call) Result {
+		return Result{Plugin: plugin, Status: StatusOK, ABIVersion: "1.0.0", Calls: calls}
+	}
+	report := func(results ...Result) CanaryReport {
+		return CanaryReport{Plugins: results}
+	}
+
+	It("should accept identical behavior and ignore timings", func() {
+		older := ok("hello", Call{Export: "process", Input: "1", Output: "2"})
+		newer := older
+		newer.Load = 12345
+
+		Expect(Compare(report(older), report(newer))).To(BeEmpty())
+	})
+
+	It("should flag builds that get less far", func() {
+		newer := Result{Plugin: "hello", Status: StatusLoadFailed, Error: "unknown import"}
+
+		Expect(Compare(report(ok("hello")), report(newer))).To(ConsistOf(
+			Incompatibility{Plugin: "hello", Reason: "was ok, now load_failed: unknown import"}))
+	})
+
+	It("should flag builds that fail differently or that now pass", func() {
+		older := Result{Plugin: "broken", Status: StatusInitFailed, Error: "init failed: -1"}
+		newer := Result{Plugin: "broken", Status: StatusInitFailed, Error: "init failed: -1"}
+		Expect(Compare(report(older), report(newer))).To(BeEmpty())
+
+		Expect(Compare(report(older), report(ok("broken")))).To(HaveLen(1))
+	})
+
+	It("should flag changed outputs and errors", func() {
+		older := ok("math",
+			Call{Export: "process", Input: "0", Output: "0"},
+			Call{Export: "process", Input: "1", Error: "plugin error -3"})
+		newer := ok("math",
+			Call{Export: "process", Input: "0", Output: "7"},
+			Call{Export: "process", Input: "1", Output: "1"})
+
+		Expect(Compare(report(older), report(newer))).To(Equal([]Incompatibility{
+			{Plugin: "math", Reason: `process("0") returned "0", now "7"`},
+			{Plugin: "math", Reason: `process("1") failed with "plugin error -3", now ""`},
+		}))
+	})
+
+	It("should only compare builds tested in both", func() {
+		Expect(Compare(report(ok("a"), ok("b")), report(ok("b"), ok("c")))).To(BeEmpty())
+	})
+})
@@ -23,6 +23,10 @@ func main() {
 
 	fmt.Printf("Loaded plugin: %s\n", plugin.Path())
 
+	// LoadPlugin has checked the ABI version the plugin reports, if any,
+	// against the versions the runtime supports
+	fmt.Printf("Plugin ABI version: %s\n", plugin.ABIVersion())
+
 	// Initialize the plugin
 	// Must be called before Execute()
 	if err := plugin.Init(); err != nil {
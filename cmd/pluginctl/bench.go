@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+// benchReport is the result of `pluginctl bench`.
+type benchReport struct {
+	Plugin      string       `json:"plugin"`
+	Runs        int          `json:"runs"`
+	Failed      int          `json:"failed"`
+	Concurrency int          `json:"concurrency"`
+	Seconds     float64      `json:"seconds"`
+	PerSecond   float64      `json:"per_second"`
+	Latency     latencyStats `json:"latency_ms"` // Of the runs that succeeded
+	FirstError  string       `json:"first_error,omitempty"`
+}
+
+// latencyStats summarizes latencies in milliseconds.
+type latencyStats struct {
+	Min float64 `json:"min"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// newLatencyStats summarizes latencies, which it sorts.
+func newLatencyStats(latencies []time.Duration) latencyStats {
+	if len(latencies) == 0 {
+		return latencyStats{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	// Nearest-rank percentiles
+	at := func(q float64) float64 {
+		i := int(math.Ceil(q*float64(len(latencies)))) - 1
+		return float64(latencies[max(i, 0)]) / float64(time.Millisecond)
+	}
+	return latencyStats{Min: at(0), P50: at(0.5), P95: at(0.95), P99: at(0.99), Max: at(1)}
+}
+
+// benchOptions are the options of a local `pluginctl bench`.
+type benchOptions struct {
+	rounds, iterations, warmup, input int
+	format                            string // text, json, or csv
+}
+
+// bench implements `pluginctl bench PLUGIN INPUT`.
+//
+// It runs the plugin n times through the server, concurrency at a time,
+// and reports throughput and the latency clients see, including the
+// network and the server's queueing. Warm-up runs load the pool first and
+// are not counted.
+//
+// A PLUGIN ending in .wasm is benchmarked here instead, with
+// runtime.Benchmark: cold load and init over --rounds, then n calls one
+// at a time, with the allocations the host makes per call.
+func (c *cli) bench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	n := fs.Int("n", 100, "runs to measure")
+	concurrency := fs.Int("concurrency", 1, "runs at once, through the server")
+	warmup := fs.Int("warmup", 1, "runs before measuring, not counted")
+	rounds := fs.Int("rounds", 10, "cold loads to measure, of a local FILE.wasm")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	asCSV := fs.Bool("csv", false, "print the report as CSV, for regression tracking")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || (*asJSON && *asCSV) {
+		return fmt.Errorf("usage: pluginctl bench [--n N] [--concurrency C] [--warmup W] [--rounds R] [--json|--csv] PLUGIN|FILE.wasm INPUT")
+	}
+	if *n < 1 || *concurrency < 1 || *rounds < 1 || *warmup < 0 {
+		return fmt.Errorf("--n, --concurrency, and --rounds must be positive, --warmup not negative")
+	}
+	input, err := strconv.Atoi(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("input must be an integer: %w", err)
+	}
+	format := "text"
+	if *asJSON {
+		format = "json"
+	} else if *asCSV {
+		format = "csv"
+	}
+
+	if strings.HasSuffix(fs.Arg(0), ".wasm") {
+		if *concurrency != 1 {
+			return fmt.Errorf("--concurrency needs a server; a local benchmark makes one call at a time")
+		}
+		return benchLocal(c.stdout, fs.Arg(0), benchOptions{
+			rounds:     *rounds,
+			iterations: *n,
+			warmup:     *warmup,
+			input:      input,
+			format:     format,
+		})
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	req := client.RunRequest{Plugin: fs.Arg(0), Input: input}
+	for i := 0; i < *warmup; i++ {
+		if _, err := api.RunRequest(ctx, req); err != nil {
+			return fmt.Errorf("warm-up run failed: %w", err)
+		}
+	}
+
+	report := benchReport{Plugin: req.Plugin, Runs: *n, Concurrency: *concurrency}
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+	runs := make(chan struct{}, *n)
+	for i := 0; i < *n; i++ {
+		runs <- struct{}{}
+	}
+	close(runs)
+	start := time.Now()
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range runs {
+				began := time.Now()
+				_, err := api.RunRequest(ctx, req)
+				took := time.Since(began)
+
+				mu.Lock()
+				if err != nil {
+					report.Failed++
+					if report.FirstError == "" {
+						report.FirstError = err.Error()
+					}
+				} else {
+					latencies = append(latencies, took)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report.Seconds = elapsed.Seconds()
+	report.PerSecond = float64(*n) / elapsed.Seconds()
+	report.Latency = newLatencyStats(latencies)
+
+	switch format {
+	case "json":
+		return writeJSON(c.stdout, report)
+	case "csv":
+		return report.writeCSV(c.stdout)
+	}
+	fmt.Fprintf(c.stdout, "%s: %d runs (%d failed) in %s, %.1f/s at concurrency %d\n",
+		report.Plugin, report.Runs, report.Failed, elapsed.Round(time.Millisecond), report.PerSecond, report.Concurrency)
+	l := report.Latency
+	fmt.Fprintf(c.stdout, "latency: min %.2fms  p50 %.2fms  p95 %.2fms  p99 %.2fms  max %.2fms\n", l.Min, l.P50, l.P95, l.P99, l.Max)
+	if report.FirstError != "" {
+		fmt.Fprintf(c.stderr, "first error: %s\n", report.FirstError)
+	}
+	return nil
+}
+
+// writeCSV writes r as a header and one row, latencies in milliseconds.
+func (r benchReport) writeCSV(w io.Writer) error {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	l := r.Latency
+	return csv.NewWriter(w).WriteAll([][]string{
+		{"plugin", "runs", "failed", "concurrency", "seconds", "per_second",
+			"latency_min_ms", "latency_p50_ms", "latency_p95_ms", "latency_p99_ms", "latency_max_ms"},
+		{r.Plugin, strconv.Itoa(r.Runs), strconv.Itoa(r.Failed), strconv.Itoa(r.Concurrency), f(r.Seconds), f(r.PerSecond),
+			f(l.Min), f(l.P50), f(l.P95), f(l.P99), f(l.Max)},
+	})
+}
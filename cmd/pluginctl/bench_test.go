@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+var _ = Describe("pluginctl bench", func() {
+	// =========================================================================
+	// TEST: Remote benchmark
+	// Why: The report must count every measured run, failures included, and
+	//      leave the warm-up runs out of it.
+	// =========================================================================
+	var (
+		runs           atomic.Int64
+		stdout, stderr *bytes.Buffer
+		run            func(args ...string) int
+	)
+
+	BeforeEach(func() {
+		runs.Store(0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req client.RunRequest
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+			Expect(req).To(Equal(client.RunRequest{Plugin: "hello", Input: 5}))
+			// Every fourth run fails, counting the warm-up run
+			if runs.Add(1)%4 == 0 {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			output := 11
+			json.NewEncoder(w).Encode(client.RunResponse{Output: &output})
+		}))
+		DeferCleanup(server.Close)
+
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", path}, args...), stdout, stderr)
+		}
+		Expect(run("config", "set-context", "dev", "--server", server.URL)).To(Equal(0))
+		Expect(run("config", "use-context", "dev")).To(Equal(0))
+	})
+
+	It("should report throughput and latency", func() {
+		Expect(run("bench", "--n", "20", "--concurrency", "4", "--json", "hello", "5")).To(Equal(0), stderr.String())
+		Expect(runs.Load()).To(Equal(int64(21)))
+
+		var report benchReport
+		Expect(json.Unmarshal(stdout.Bytes(), &report)).To(Succeed())
+		Expect(report.Runs).To(Equal(20))
+		Expect(report.Failed).To(Equal(5))
+		Expect(report.FirstError).NotTo(BeEmpty())
+		Expect(report.PerSecond).To(BeNumerically(">", 0))
+		Expect(report.Latency.Min).To(BeNumerically("<=", report.Latency.P50))
+		Expect(report.Latency.P99).To(BeNumerically("<=", report.Latency.Max))
+	})
+
+	It("should print the report as CSV", func() {
+		Expect(run("bench", "--n", "2", "--warmup", "0", "--csv", "hello", "5")).To(Equal(0), stderr.String())
+		records, err := csv.NewReader(stdout).ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0][:3]).To(Equal([]string{"plugin", "runs", "failed"}))
+		Expect(records[1][:3]).To(Equal([]string{"hello", "2", "0"}))
+	})
+
+	It("should print a summary", func() {
+		Expect(run("bench", "--n", "3", "--warmup", "0", "hello", "5")).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(HavePrefix("hello: 3 runs (0 failed)"))
+		Expect(stdout.String()).To(ContainSubstring("p95"))
+	})
+})
+
+var _ = Describe("newLatencyStats", func() {
+	It("should take nearest-rank percentiles", func() {
+		var latencies []time.Duration
+		for i := 100; i >= 1; i-- {
+			latencies = append(latencies, time.Duration(i)*time.Millisecond)
+		}
+		Expect(newLatencyStats(latencies)).To(Equal(latencyStats{Min: 1, P50: 50, P95: 95, P99: 99, Max: 100}))
+		Expect(newLatencyStats(nil)).To(Equal(latencyStats{}))
+	})
+})
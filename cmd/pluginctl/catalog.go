@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// catalog implements `pluginctl catalog export FILE` and
+// `pluginctl catalog import FILE`.
+//
+// Export saves every plugin build of the server, with manifests, checksum
+// files, and signatures, to one bundle; import installs such a bundle on
+// another server, or the same one after losing its store. FILE "-" is
+// stdout or stdin.
+func (c *cli) catalog(args []string) error {
+	fs := flag.NewFlagSet("catalog", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || (fs.Arg(0) != "export" && fs.Arg(0) != "import") {
+		return fmt.Errorf("usage: pluginctl catalog export|import FILE")
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	path := fs.Arg(1)
+	if fs.Arg(0) == "export" {
+		if path == "-" {
+			return api.ExportCatalog(context.Background(), c.stdout)
+		}
+		// Written next to the target and renamed, so a failed export
+		// leaves no truncated bundle behind
+		tmp := path + ".partial"
+		f, err := os.Create(tmp)
+		if err != nil {
+			return err
+		}
+		err = api.ExportCatalog(context.Background(), f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		fmt.Fprintf(c.stderr, "catalog written to %s\n", path)
+		return nil
+	}
+
+	bundle := os.Stdin
+	if path != "-" {
+		if bundle, err = os.Open(path); err != nil {
+			return err
+		}
+		defer bundle.Close()
+	}
+	imported, err := api.ImportCatalog(context.Background(), bundle)
+	if err != nil {
+		return err
+	}
+	for _, p := range imported {
+		ref := p.Name
+		if p.Version != "" {
+			ref += "@" + p.Version
+		}
+		fmt.Fprintf(c.stdout, "imported %s\n", ref)
+	}
+	fmt.Fprintf(c.stdout, "%d build(s) imported\n", len(imported))
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv overrides the default config file location.
+const ConfigEnv = "PLUGINCTL_CONFIG"
+
+// Config is the persisted pluginctl configuration.
+//
+// Like kubeconfig, it holds any number of named contexts and remembers which
+// one is current, so operators switch between dev/staging/prod servers with
+// `pluginctl config use-context <name>` instead of repeating flags.
+type Config struct {
+	CurrentContext string          `yaml:"current-context,omitempty"`
+	Contexts       []*NamedContext `yaml:"contexts,omitempty"`
+}
+
+// NamedContext describes how to reach one plugin server.
+type NamedContext struct {
+	Name   string    `yaml:"name"`
+	Server string    `yaml:"server"`
+	Token  string    `yaml:"token,omitempty"`
+	Tenant string    `yaml:"tenant,omitempty"`
+	TLS    TLSConfig `yaml:"tls,omitempty"`
+}
+
+// TLSConfig holds the TLS settings for a context. All fields are optional;
+// an empty TLSConfig uses the system roots.
+type TLSConfig struct {
+	CAFile             string `yaml:"ca-file,omitempty"`
+	CertFile           string `yaml:"cert-file,omitempty"`
+	KeyFile            string `yaml:"key-file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify,omitempty"`
+}
+
+// ErrNoContext is returned when no context is selected and none is named.
+var ErrNoContext = errors.New("no context selected; run `pluginctl config use-context <name>`")
+
+// DefaultConfigPath returns $PLUGINCTL_CONFIG, or pluginctl/config.yaml under
+// the user config directory (e.g., ~/.config on Linux).
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "pluginctl", "config.yaml"), nil
+}
+
+// LoadConfig reads the config at path. A missing file yields an empty Config
+// so the first `set-context` can create it.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config to path, creating parent directories.
+// The file is private to the user because contexts may carry tokens.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// Context returns the named context, or nil if it does not exist.
+func (c *Config) Context(name string) *NamedContext {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// SetContext adds ctx, replacing any existing context with the same name.
+// Contexts are kept sorted by name so the file diffs cleanly.
+func (c *Config) SetContext(ctx *NamedContext) {
+	for i, existing := range c.Contexts {
+		if existing.Name == ctx.Name {
+			c.Contexts[i] = ctx
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, ctx)
+	sort.Slice(c.Contexts, func(i, j int) bool {
+		return c.Contexts[i].Name < c.Contexts[j].Name
+	})
+}
+
+// UseContext makes the named context current.
+func (c *Config) UseContext(name string) error {
+	if c.Context(name) == nil {
+		return fmt.Errorf("context %q not found", name)
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// DeleteContext removes the named context. Deleting the current context
+// clears the selection.
+func (c *Config) DeleteContext(name string) error {
+	for i, ctx := range c.Contexts {
+		if ctx.Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.CurrentContext == name {
+				c.CurrentContext = ""
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("context %q not found", name)
+}
+
+// Resolve returns the context to use: the named one if name is set,
+// otherwise the current context.
+func (c *Config) Resolve(name string) (*NamedContext, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return nil, ErrNoContext
+	}
+	ctx := c.Context(name)
+	if ctx == nil {
+		return nil, fmt.Errorf("context %q not found", name)
+	}
+	return ctx, nil
+}
+
+// Client builds an API client for the context. opts are applied after the
+// context's settings.
+func (ctx *NamedContext) Client(opts ...client.Option) (*client.Client, error) {
+	if ctx.Server == "" {
+		return nil, fmt.Errorf("context %q has no server", ctx.Name)
+	}
+
+	tlsConfig, err := ctx.TLS.build()
+	if err != nil {
+		return nil, fmt.Errorf("context %q: %w", ctx.Name, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return client.New(ctx.Server, append([]client.Option{
+		client.WithHTTPClient(&http.Client{Transport: transport, Timeout: client.DefaultTimeout}),
+		client.WithToken(ctx.Token),
+		client.WithTenant(ctx.Tenant),
+	}, opts...)...), nil
+}
+
+// build converts the settings into a *tls.Config.
+func (t TLSConfig) build() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
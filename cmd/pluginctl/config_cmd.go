@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config implements `pluginctl config <subcommand>`.
+//
+// Subcommands:
+//
+//	view                     Print the config file (tokens redacted)
+//	get-contexts             List contexts, marking the current one
+//	current-context          Print the current context name
+//	use-context NAME         Select the context used by other commands
+//	set-context NAME [flags] Create or update a context
+//	delete-context NAME      Remove a context
+func (c *cli) config(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: pluginctl config view|get-contexts|current-context|use-context|set-context|delete-context")
+	}
+
+	cfg, err := LoadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	sub, args := args[0], args[1:]
+	switch sub {
+	case "view":
+		return c.configView(cfg)
+
+	case "get-contexts":
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tSERVER\tTENANT")
+		for _, ctx := range cfg.Contexts {
+			marker := ""
+			if ctx.Name == cfg.CurrentContext {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, ctx.Name, ctx.Server, ctx.Tenant)
+		}
+		return w.Flush()
+
+	case "current-context":
+		if cfg.CurrentContext == "" {
+			return ErrNoContext
+		}
+		fmt.Fprintln(c.stdout, cfg.CurrentContext)
+		return nil
+
+	case "use-context":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: pluginctl config use-context NAME")
+		}
+		if err := cfg.UseContext(args[0]); err != nil {
+			return err
+		}
+		if err := cfg.Save(c.configPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Switched to context %q.\n", args[0])
+		return nil
+
+	case "set-context":
+		return c.configSetContext(cfg, args)
+
+	case "delete-context":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: pluginctl config delete-context NAME")
+		}
+		if err := cfg.DeleteContext(args[0]); err != nil {
+			return err
+		}
+		if err := cfg.Save(c.configPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Deleted context %q.\n", args[0])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config subcommand %q", sub)
+	}
+}
+
+// configSetContext creates a context or updates only the fields whose
+// flags were given, so `set-context prod --tenant b` keeps the server URL.
+// The first context created becomes current automatically.
+func (c *cli) configSetContext(cfg *Config, args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return fmt.Errorf("usage: pluginctl config set-context NAME [--server URL] [--token TOKEN] [--tenant TENANT] [--ca-file FILE] [--cert-file FILE] [--key-file FILE] [--insecure-skip-verify]")
+	}
+	name := args[0]
+
+	ctx := &NamedContext{Name: name}
+	if existing := cfg.Context(name); existing != nil {
+		copied := *existing
+		ctx = &copied
+	}
+
+	fs := flag.NewFlagSet("set-context", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&ctx.Server, "server", ctx.Server, "server base URL (e.g., https://plugins.example.com)")
+	fs.StringVar(&ctx.Token, "token", ctx.Token, "bearer token sent as Authorization header")
+	fs.StringVar(&ctx.Tenant, "tenant", ctx.Tenant, "tenant sent as X-Tenant header")
+	fs.StringVar(&ctx.TLS.CAFile, "ca-file", ctx.TLS.CAFile, "PEM CA bundle used to verify the server")
+	fs.StringVar(&ctx.TLS.CertFile, "cert-file", ctx.TLS.CertFile, "PEM client certificate for mutual TLS")
+	fs.StringVar(&ctx.TLS.KeyFile, "key-file", ctx.TLS.KeyFile, "PEM client key for mutual TLS")
+	fs.BoolVar(&ctx.TLS.InsecureSkipVerify, "insecure-skip-verify", ctx.TLS.InsecureSkipVerify, "skip server certificate verification (testing only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if ctx.Server == "" {
+		return fmt.Errorf("context %q needs --server", name)
+	}
+
+	cfg.SetContext(ctx)
+	if cfg.CurrentContext == "" {
+		cfg.CurrentContext = name
+	}
+	if err := cfg.Save(c.configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Context %q saved.\n", name)
+	return nil
+}
+
+// configView prints the config as YAML with tokens redacted.
+func (c *cli) configView(cfg *Config) error {
+	redacted := &Config{CurrentContext: cfg.CurrentContext}
+	for _, ctx := range cfg.Contexts {
+		copied := *ctx
+		if copied.Token != "" {
+			copied.Token = "REDACTED"
+		}
+		redacted.Contexts = append(redacted.Contexts, &copied)
+	}
+
+	data, err := yaml.Marshal(redacted)
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(data)
+	return err
+}
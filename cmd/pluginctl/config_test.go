@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "nested", "config.yaml")
+	})
+
+	It("should treat a missing file as an empty config", func() {
+		cfg, err := LoadConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Contexts).To(BeEmpty())
+		Expect(cfg.CurrentContext).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Persistence round trip
+	// Why: Contexts must survive between invocations, and the file must not
+	//      be world-readable because it may contain tokens.
+	// =========================================================================
+	It("should save and reload contexts privately", func() {
+		cfg := &Config{}
+		cfg.SetContext(&NamedContext{Name: "prod", Server: "https://prod", Token: "t", TLS: TLSConfig{CAFile: "/ca.pem"}})
+		cfg.SetContext(&NamedContext{Name: "dev", Server: "http://localhost:8080"})
+		Expect(cfg.UseContext("prod")).To(Succeed())
+		Expect(cfg.Save(path)).To(Succeed())
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		loaded, err := LoadConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.CurrentContext).To(Equal("prod"))
+		Expect(loaded.Contexts).To(HaveLen(2))
+		Expect(loaded.Contexts[0].Name).To(Equal("dev"))
+		Expect(loaded.Context("prod").TLS.CAFile).To(Equal("/ca.pem"))
+	})
+
+	It("should resolve the named context over the current one", func() {
+		cfg := &Config{}
+		cfg.SetContext(&NamedContext{Name: "a", Server: "http://a"})
+		cfg.SetContext(&NamedContext{Name: "b", Server: "http://b"})
+
+		_, err := cfg.Resolve("")
+		Expect(err).To(MatchError(ErrNoContext))
+
+		Expect(cfg.UseContext("a")).To(Succeed())
+		ctx, err := cfg.Resolve("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ctx.Server).To(Equal("http://a"))
+
+		ctx, err = cfg.Resolve("b")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ctx.Server).To(Equal("http://b"))
+
+		_, err = cfg.Resolve("c")
+		Expect(err).To(MatchError(ContainSubstring(`context "c" not found`)))
+	})
+
+	It("should clear the current context when it is deleted", func() {
+		cfg := &Config{}
+		cfg.SetContext(&NamedContext{Name: "a", Server: "http://a"})
+		Expect(cfg.UseContext("a")).To(Succeed())
+
+		Expect(cfg.DeleteContext("a")).To(Succeed())
+		Expect(cfg.CurrentContext).To(BeEmpty())
+		Expect(cfg.DeleteContext("a")).NotTo(Succeed())
+	})
+
+	It("should reject unknown contexts in use-context", func() {
+		Expect((&Config{}).UseContext("nope")).NotTo(Succeed())
+	})
+
+	It("should fail to build a client when the CA file is missing", func() {
+		ctx := &NamedContext{Name: "x", Server: "https://x", TLS: TLSConfig{CAFile: "/does/not/exist.pem"}}
+		_, err := ctx.Client()
+		Expect(err).To(MatchError(ContainSubstring("failed to read CA file")))
+	})
+})
+
+var _ = Describe("pluginctl", func() {
+	var (
+		path     string
+		server   *httptest.Server
+		received *http.Request
+		stdout   *bytes.Buffer
+		stderr   *bytes.Buffer
+	)
+
+	run := func(args ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		return execute(append([]string{"--config", path}, args...), stdout, stderr)
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			var req struct{ Input int }
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(map[string]int{"output": req.Input*2 + 1})
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// =========================================================================
+	// TEST: Context workflow end to end
+	// Why: Operators set up contexts once and then run commands without
+	//      flags; the selected context's server and credentials must be used.
+	// =========================================================================
+	It("should run against the current context", func() {
+		Expect(run("config", "set-context", "dev", "--server", server.URL, "--token", "abc", "--tenant", "team-a")).To(Equal(0))
+		Expect(run("config", "current-context")).To(Equal(0))
+		Expect(stdout.String()).To(Equal("dev\n"))
+
+		Expect(run("run", "hello", "20")).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("41\n"))
+		Expect(received.Header.Get("Authorization")).To(Equal("Bearer abc"))
+		Expect(received.Header.Get("X-Tenant")).To(Equal("team-a"))
+	})
+
+	It("should switch contexts with use-context and --context", func() {
+		Expect(run("config", "set-context", "dev", "--server", server.URL)).To(Equal(0))
+		Expect(run("config", "set-context", "prod", "--server", "http://127.0.0.1:1")).To(Equal(0))
+
+		Expect(run("config", "use-context", "prod")).To(Equal(0))
+		Expect(run("config", "get-contexts")).To(Equal(0))
+		Expect(stdout.String()).To(MatchRegexp(`\*\s+prod`))
+
+		Expect(run("--context", "dev", "run", "hello", "1")).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("3\n"))
+
+		Expect(run("config", "use-context", "missing")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring(`context "missing" not found`))
+	})
+
+	It("should update only the given fields in set-context", func() {
+		Expect(run("config", "set-context", "dev", "--server", server.URL, "--token", "abc")).To(Equal(0))
+		Expect(run("config", "set-context", "dev", "--tenant", "team-b")).To(Equal(0))
+
+		cfg, err := LoadConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*cfg.Context("dev")).To(Equal(NamedContext{Name: "dev", Server: server.URL, Token: "abc", Tenant: "team-b"}))
+	})
+
+	It("should redact tokens in config view", func() {
+		Expect(run("config", "set-context", "dev", "--server", server.URL, "--token", "abc")).To(Equal(0))
+		Expect(run("config", "view")).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring("token: REDACTED"))
+		Expect(stdout.String()).NotTo(ContainSubstring("abc"))
+	})
+
+	It("should explain how to select a context when none is set", func() {
+		Expect(run("run", "hello", "1")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("use-context"))
+	})
+
+	It("should reject unknown commands", func() {
+		Expect(run("frobnicate")).To(Equal(2))
+		Expect(stderr.String()).To(ContainSubstring(`unknown command "frobnicate"`))
+	})
+})
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultPollInterval is how often `pluginctl dev` scans the source tree.
+// Polling keeps the CLI dependency-free and works on network filesystems
+// where inotify events are unreliable.
+const defaultPollInterval = 500 * time.Millisecond
+
+// buildSpec describes how to turn a plugin source directory into a .wasm file.
+type buildSpec struct {
+	Command  string // Shell command run in the source directory
+	Artifact string // Path of the produced .wasm, relative to the source directory
+}
+
+// detectBuild picks a build command from the files in dir:
+//   - Cargo.toml: cargo for wasm32-wasip1
+//   - go.mod: tinygo with the WASI target
+//   - asconfig.json: the AssemblyScript compiler's release target
+//   - Makefile: make, e.g. in a C or C++ project from `pluginctl new`
+//   - <name>.cpp: clang++ with the exports of the core and payload ABIs
+func detectBuild(dir, name string) (buildSpec, error) {
+	exists := func(file string) bool {
+		_, err := os.Stat(filepath.Join(dir, file))
+		return err == nil
+	}
+
+	switch {
+	case exists("Cargo.toml"):
+		// Cargo replaces '-' with '_' in artifact names
+		crate := strings.ReplaceAll(name, "-", "_")
+		return buildSpec{
+			Command:  "cargo build --release --target wasm32-wasip1",
+			Artifact: filepath.Join("target", "wasm32-wasip1", "release", crate+".wasm"),
+		}, nil
+
+	case exists("go.mod"):
+		return buildSpec{
+			Command:  fmt.Sprintf("tinygo build -o %s.wasm -target=wasi .", name),
+			Artifact: name + ".wasm",
+		}, nil
+
+	case exists("asconfig.json"):
+		return buildSpec{
+			Command:  "npx asc --target release",
+			Artifact: filepath.Join("build", name+".wasm"),
+		}, nil
+
+	case exists("Makefile"):
+		return buildSpec{Command: "make", Artifact: name + ".wasm"}, nil
+
+	case exists(name + ".cpp"):
+		exports := []string{"init", "process", "cleanup", "get_abi_version", "allocate", "deallocate", "process_bytes"}
+		var flags []string
+		for _, export := range exports {
+			flags = append(flags, "-Wl,--export-if-defined="+export)
+		}
+		return buildSpec{
+			Command: fmt.Sprintf("clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry %s -O3 -o %s.wasm %s.cpp",
+				strings.Join(flags, " "), name, name),
+			Artifact: name + ".wasm",
+		}, nil
+
+	default:
+		return buildSpec{}, fmt.Errorf("cannot detect build for %s (no Cargo.toml, go.mod, asconfig.json, Makefile, or %s.cpp); pass --build and --artifact", dir, name)
+	}
+}
+
+// sourceState maps source files to their modification times.
+type sourceState map[string]time.Time
+
+// scanSources records the modification time of every file under dir,
+// skipping build output, hidden directories, and .wasm artifacts so that a
+// build does not retrigger itself.
+func scanSources(dir string) (sourceState, error) {
+	state := sourceState{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || name == "target" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".wasm") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		state[path] = info.ModTime()
+		return nil
+	})
+	return state, err
+}
+
+// changed reports whether any file was added, removed, or modified.
+func (s sourceState) changed(other sourceState) bool {
+	if len(s) != len(other) {
+		return true
+	}
+	for path, mod := range s {
+		if otherMod, ok := other[path]; !ok || !otherMod.Equal(mod) {
+			return true
+		}
+	}
+	return false
+}
+
+// installArtifact copies a built plugin into the local store layout
+// (<pluginsDir>/<name>/<name>.wasm). The copy is written to a temporary file
+// and renamed so the server never loads a partially written module.
+func installArtifact(src, pluginsDir, name string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer in.Close()
+
+	destDir := filepath.Join(pluginsDir, name)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", destDir, err)
+	}
+
+	tmp, err := os.CreateTemp(destDir, "."+name+"-*.wasm")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary artifact: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy artifact: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(destDir, name+".wasm")); err != nil {
+		return fmt.Errorf("failed to install artifact: %w", err)
+	}
+	return nil
+}
+
+// prefixWriter copies lines from a process to out with a fixed prefix, so
+// build and server output stay distinguishable in one terminal.
+func prefixWriter(out io.Writer, mu *sync.Mutex, prefix string) io.WriteCloser {
+	r, w := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64<<10), 1<<20)
+		for scanner.Scan() {
+			mu.Lock()
+			fmt.Fprintf(out, "%s %s\n", prefix, scanner.Text())
+			mu.Unlock()
+		}
+		r.CloseWithError(scanner.Err())
+	}()
+	return w
+}
+
+// devSession is one run of `pluginctl dev`.
+type devSession struct {
+	dir        string
+	name       string
+	build      buildSpec
+	pluginsDir string
+	out        io.Writer
+	outMu      sync.Mutex
+}
+
+// rebuild runs the build command and installs the artifact on success.
+func (d *devSession) rebuild(ctx context.Context) error {
+	d.logf("building: %s", d.build.Command)
+	start := time.Now()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", d.build.Command)
+	cmd.Dir = d.dir
+	logs := prefixWriter(d.out, &d.outMu, "[build]")
+	defer logs.Close()
+	cmd.Stdout, cmd.Stderr = logs, logs
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	artifact := d.build.Artifact
+	if !filepath.IsAbs(artifact) {
+		artifact = filepath.Join(d.dir, artifact)
+	}
+	if err := installArtifact(artifact, d.pluginsDir, d.name); err != nil {
+		return err
+	}
+
+	d.logf("installed %s in %s", d.name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (d *devSession) logf(format string, args ...interface{}) {
+	d.outMu.Lock()
+	defer d.outMu.Unlock()
+	fmt.Fprintf(d.out, "[dev] "+format+"\n", args...)
+}
+
+// dev implements `pluginctl dev`.
+//
+// Inner loop for plugin authors:
+// 1. Build the plugin and install it into a private plugin directory
+// 2. Start a local server on that directory and stream its logs
+// 3. Poll the source tree; on change, rebuild and install the new artifact
+//
+// The server notices the replaced file on the next request and swaps in a
+// fresh instance pool, so it keeps running across rebuilds.
+func (c *cli) dev(args []string) error {
+	fs := flag.NewFlagSet("dev", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	dir := fs.String("dir", ".", "plugin source directory to watch")
+	name := fs.String("name", "", "plugin name (default: base name of --dir)")
+	buildCmd := fs.String("build", "", "build command (default: detected from Cargo.toml, go.mod, asconfig.json, Makefile, or <name>.cpp)")
+	artifact := fs.String("artifact", "", "built .wasm path relative to --dir (required with --build unless <name>.wasm)")
+	serverCmd := fs.String("server-cmd", "go run ./cmd/server", "command that starts the plugin server")
+	addr := fs.String("addr", "127.0.0.1:8080", "address for the local server")
+	interval := fs.Duration("interval", defaultPollInterval, "source polling interval")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = filepath.Base(absDir)
+	}
+	if !isPluginName(*name) {
+		return fmt.Errorf("invalid plugin name %q; use --name", *name)
+	}
+
+	spec, err := resolveBuild(absDir, *name, *buildCmd, *artifact)
+	if err != nil {
+		return err
+	}
+
+	pluginsDir, err := os.MkdirTemp("", "pluginctl-dev-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(pluginsDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := &devSession{dir: absDir, name: *name, build: spec, pluginsDir: pluginsDir, out: c.stdout}
+
+	// The first build must succeed: there is nothing to serve otherwise
+	if err := d.rebuild(ctx); err != nil {
+		return err
+	}
+
+	server, err := d.startServer(ctx, *serverCmd, *addr)
+	if err != nil {
+		return err
+	}
+	d.logf("serving %s at http://%s", *name, *addr)
+
+	state, err := scanSources(absDir)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.logf("stopping")
+			<-server
+			return nil
+
+		case err := <-server:
+			return fmt.Errorf("server exited: %v", err)
+
+		case <-ticker.C:
+			current, err := scanSources(absDir)
+			if err != nil {
+				d.logf("scan failed: %v", err)
+				continue
+			}
+			if !current.changed(state) {
+				continue
+			}
+			state = current
+
+			// Keep serving the previous build if this one fails
+			if err := d.rebuild(ctx); err != nil && ctx.Err() == nil {
+				d.logf("%v; still serving the previous build", err)
+			}
+		}
+	}
+}
+
+// resolveBuild combines explicit flags with detection.
+func resolveBuild(dir, name, command, artifact string) (buildSpec, error) {
+	if command == "" {
+		spec, err := detectBuild(dir, name)
+		if err != nil {
+			return buildSpec{}, err
+		}
+		if artifact != "" {
+			spec.Artifact = artifact
+		}
+		return spec, nil
+	}
+	if artifact == "" {
+		artifact = name + ".wasm"
+	}
+	return buildSpec{Command: command, Artifact: artifact}, nil
+}
+
+// startServer launches the server on the private plugin directory and
+// streams its output. The returned channel yields the exit error.
+func (d *devSession) startServer(ctx context.Context, command, addr string) (<-chan error, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"PLUGIN_STORE=local",
+		"PLUGIN_DIR="+d.pluginsDir,
+		"LISTEN_ADDR="+addr,
+	)
+	// Stop the whole process tree: `go run` leaves the server binary behind
+	// if only the go command is killed
+	stopProcessGroup(cmd)
+	cmd.WaitDelay = 5 * time.Second
+
+	logs := prefixWriter(d.out, &d.outMu, "[server]")
+	cmd.Stdout, cmd.Stderr = logs, logs
+
+	if err := cmd.Start(); err != nil {
+		logs.Close()
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		logs.Close()
+		if err == nil {
+			err = errors.New("exit status 0")
+		}
+		done <- err
+	}()
+	return done, nil
+}
+
+// isPluginName mirrors the server's plugin name rules.
+func isPluginName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// stopProcessGroup is a no-op where process groups are unavailable; context
+// cancellation kills only the direct child.
+func stopProcessGroup(cmd *exec.Cmd) {}
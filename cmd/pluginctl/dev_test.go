@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pluginctl dev", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	// =========================================================================
+	// TEST: Build detection
+	// Why: Plugin authors should get a working loop without spelling out the
+	//      toolchain invocation and artifact location.
+	// =========================================================================
+	DescribeTable("detectBuild",
+		func(file, command, artifact string) {
+			write(file, "")
+
+			spec, err := detectBuild(dir, "my-plugin")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Command).To(HavePrefix(command))
+			Expect(spec.Artifact).To(Equal(artifact))
+		},
+		Entry("cargo", "Cargo.toml", "cargo build", filepath.Join("target", "wasm32-wasip1", "release", "my_plugin.wasm")),
+		Entry("tinygo", "go.mod", "tinygo build", "my-plugin.wasm"),
+		Entry("assemblyscript", "asconfig.json", "npx asc", filepath.Join("build", "my-plugin.wasm")),
+		Entry("make", "Makefile", "make", "my-plugin.wasm"),
+		Entry("clang++", "my-plugin.cpp", "clang++", "my-plugin.wasm"),
+	)
+
+	It("should ask for --build when nothing is detected", func() {
+		_, err := detectBuild(dir, "x")
+		Expect(err).To(MatchError(ContainSubstring("--build")))
+	})
+
+	It("should let flags override detection", func() {
+		spec, err := resolveBuild(dir, "x", "make", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec).To(Equal(buildSpec{Command: "make", Artifact: "x.wasm"}))
+	})
+
+	// =========================================================================
+	// TEST: Change detection
+	// Why: Builds write .wasm files and target/ directories; treating those
+	//      as source changes would rebuild in an endless loop.
+	// =========================================================================
+	It("should detect source changes but ignore build output", func() {
+		write("src/lib.rs", "fn a() {}")
+		before, err := scanSources(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		write("x.wasm", "artifact")
+		write("target/debug/out", "artifact")
+		write(".git/HEAD", "ref")
+		after, err := scanSources(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after.changed(before)).To(BeFalse())
+
+		future := time.Now().Add(time.Minute)
+		Expect(os.Chtimes(filepath.Join(dir, "src/lib.rs"), future, future)).To(Succeed())
+		after, err = scanSources(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after.changed(before)).To(BeTrue())
+
+		write("src/new.rs", "")
+		added, err := scanSources(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(added.changed(after)).To(BeTrue())
+	})
+
+	It("should install artifacts in the local store layout", func() {
+		write("out.wasm", "v1")
+		store := filepath.Join(dir, "store")
+
+		Expect(installArtifact(filepath.Join(dir, "out.wasm"), store, "demo")).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(store, "demo", "demo.wasm"))).To(Equal([]byte("v1")))
+
+		// No temporary files are left behind next to the artifact
+		entries, err := os.ReadDir(filepath.Join(store, "demo"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should build, install, and stream build output", func() {
+		var out bytes.Buffer
+		d := &devSession{
+			dir:        dir,
+			name:       "demo",
+			build:      buildSpec{Command: "echo compiling && printf v2 > demo.wasm", Artifact: "demo.wasm"},
+			pluginsDir: filepath.Join(dir, "store"),
+			out:        &out,
+		}
+
+		Expect(d.rebuild(context.Background())).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(dir, "store", "demo", "demo.wasm"))).To(Equal([]byte("v2")))
+		Eventually(func() string {
+			d.outMu.Lock()
+			defer d.outMu.Unlock()
+			return out.String()
+		}).Should(ContainSubstring("[build] compiling"))
+	})
+
+	It("should report failed builds", func() {
+		d := &devSession{
+			dir:        dir,
+			name:       "demo",
+			build:      buildSpec{Command: "exit 3", Artifact: "demo.wasm"},
+			pluginsDir: filepath.Join(dir, "store"),
+			out:        &bytes.Buffer{},
+		}
+
+		Expect(d.rebuild(context.Background())).To(MatchError(ContainSubstring("build failed")))
+	})
+})
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// errBreaking makes `pluginctl diff` exit non-zero so it can gate CI.
+var errBreaking = errors.New("breaking changes detected")
+
+// diff implements `pluginctl diff OLD.wasm NEW.wasm`.
+//
+// Both binaries are inspected offline; no server is contacted. The command
+// exits with status 1 if any change is breaking, unless --allow-breaking
+// is set (e.g. for a planned major version).
+func (c *cli) diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the comparison as JSON")
+	allowBreaking := fs.Bool("allow-breaking", false, "exit 0 even if changes are breaking")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: pluginctl diff [--json] [--allow-breaking] OLD.wasm NEW.wasm")
+	}
+
+	older, err := wasminfo.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newer, err := wasminfo.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d := wasminfo.Compare(older, newer)
+	if *asJSON {
+		if err := writeJSON(c.stdout, d); err != nil {
+			return err
+		}
+	} else {
+		c.printDiff(d, older, newer)
+	}
+
+	if len(d.Breaking()) > 0 && !*allowBreaking {
+		return errBreaking
+	}
+	return nil
+}
+
+// printDiff writes a human-readable comparison.
+func (c *cli) printDiff(d wasminfo.Diff, older, newer *wasminfo.Module) {
+	fmt.Fprintf(c.stdout, "size: %d -> %d bytes (%+.1f%%)\n", d.OldSize, d.NewSize, d.SizeDelta()*100)
+	fmt.Fprintf(c.stdout, "capabilities: %v -> %v\n", older.Capabilities(), newer.Capabilities())
+
+	if len(d.Changes) == 0 {
+		fmt.Fprintln(c.stdout, "no interface changes")
+		return
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, change := range d.Changes {
+		marker := ""
+		if change.Breaking {
+			marker = "BREAKING"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, change.Kind, change.Subject, change.Detail)
+	}
+	w.Flush()
+	fmt.Fprintf(c.stdout, "%d change(s), %d breaking\n", len(d.Changes), len(d.Breaking()))
+}
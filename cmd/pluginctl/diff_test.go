@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// exportsModule encodes a WebAssembly binary exporting one `() -> i32`
+// function per name. Names must be shorter than 128 bytes.
+func exportsModule(names ...string) []byte {
+	n := byte(len(names))
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = append(out, 0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f) // type 0: () -> i32
+
+	funcs := []byte{n}
+	exports := []byte{n}
+	code := []byte{n}
+	for i, name := range names {
+		funcs = append(funcs, 0x00)
+		exports = append(exports, byte(len(name)))
+		exports = append(exports, name...)
+		exports = append(exports, 0x00, byte(i))
+		code = append(code, 0x04, 0x00, 0x41, 0x00, 0x0b) // i32.const 0
+	}
+	for _, s := range []struct {
+		id      byte
+		payload []byte
+	}{{0x03, funcs}, {0x07, exports}, {0x0a, code}} {
+		out = append(out, s.id, byte(len(s.payload)))
+		out = append(out, s.payload...)
+	}
+	return out
+}
+
+var _ = Describe("pluginctl diff", func() {
+	var (
+		dir            string
+		stdout, stderr *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	})
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+		return path
+	}
+
+	run := func(args ...string) int {
+		return execute(append([]string{"--config", filepath.Join(dir, "config.yaml"), "diff"}, args...), stdout, stderr)
+	}
+
+	// =========================================================================
+	// TEST: CI gate exit status
+	// Why: Pipelines rely on the exit status alone to stop a release that
+	//      would break hosts running the previous version.
+	// =========================================================================
+	It("should succeed for compatible changes", func() {
+		v1 := write("v1.wasm", exportsModule("init", "process"))
+		v2 := write("v2.wasm", exportsModule("init", "process", "extra"))
+
+		Expect(run(v1, v2)).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(ContainSubstring("export_added"))
+		Expect(stdout.String()).To(ContainSubstring("1 change(s), 0 breaking"))
+	})
+
+	It("should fail on breaking changes", func() {
+		v1 := write("v1.wasm", exportsModule("init", "process"))
+		v2 := write("v2.wasm", exportsModule("init"))
+
+		Expect(run(v1, v2)).To(Equal(1))
+		Expect(stdout.String()).To(MatchRegexp(`BREAKING\s+export_removed\s+process`))
+		Expect(stderr.String()).To(ContainSubstring("breaking changes detected"))
+	})
+
+	It("should allow breaking changes on request", func() {
+		v1 := write("v1.wasm", exportsModule("init", "process"))
+		v2 := write("v2.wasm", exportsModule("init"))
+
+		Expect(run("--allow-breaking", v1, v2)).To(Equal(0))
+	})
+
+	It("should print JSON", func() {
+		v1 := write("v1.wasm", exportsModule("init"))
+
+		Expect(run("--json", v1, v1)).To(Equal(0))
+		var d struct {
+			OldSize int64             `json:"old_size"`
+			Changes []json.RawMessage `json:"changes"`
+		}
+		Expect(json.Unmarshal(stdout.Bytes(), &d)).To(Succeed())
+		Expect(d.OldSize).To(BeNumerically(">", 0))
+		Expect(d.Changes).To(BeEmpty())
+	})
+
+	It("should report files that are not WebAssembly", func() {
+		v1 := write("v1.wasm", exportsModule("init"))
+		bad := write("bad.wasm", []byte("not wasm"))
+
+		Expect(run(v1, bad)).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("not a WebAssembly binary"))
+	})
+})
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// inspect implements `pluginctl inspect FILE.wasm`.
+//
+// Like diff, it reads the binary offline: what the plugin exports, what it
+// imports from the host, and the capabilities those imports amount to.
+func (c *cli) inspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the interface as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pluginctl inspect [--json] FILE.wasm")
+	}
+
+	module, err := wasminfo.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(c.stdout, module)
+	}
+
+	abiVersion := "unknown"
+	if module.ABIVersion != nil {
+		v := *module.ABIVersion
+		abiVersion = fmt.Sprintf("%d.%d.%d", v/10000, v/100%100, v%100)
+	}
+	capabilities := "none"
+	if caps := module.Capabilities(); len(caps) > 0 {
+		capabilities = strings.Join(caps, ", ")
+	}
+	fmt.Fprintf(c.stdout, "size: %s\n", formatBytes(module.Size))
+	fmt.Fprintf(c.stdout, "abi version: %s\n", abiVersion)
+	fmt.Fprintf(c.stdout, "memory: %d page(s) initially\n", module.MemoryPages)
+	fmt.Fprintf(c.stdout, "capabilities: %s\n", capabilities)
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nEXPORT\tKIND\tSIGNATURE")
+	for _, e := range module.Exports {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Name, e.Kind, e.Signature)
+	}
+	if len(module.Imports) > 0 {
+		fmt.Fprintln(w, "\nIMPORT\tKIND\tSIGNATURE")
+		for _, i := range module.Imports {
+			fmt.Fprintf(w, "%s.%s\t%s\t%s\n", i.Module, i.Name, i.Kind, i.Signature)
+		}
+	}
+	return w.Flush()
+}
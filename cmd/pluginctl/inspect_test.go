@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pluginctl inspect", func() {
+	// =========================================================================
+	// TEST: Offline inspection
+	// Why: Developers check what a build exports and imports before they
+	//      upload it, with no server and no WasmEdge at hand.
+	// =========================================================================
+	var (
+		path           string
+		stdout, stderr *bytes.Buffer
+		run            func(args ...string) int
+	)
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		path = filepath.Join(dir, "plugin.wasm")
+		Expect(os.WriteFile(path, exportsModule("init", "process", "cleanup"), 0644)).To(Succeed())
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", filepath.Join(dir, "config.yaml")}, args...), stdout, stderr)
+		}
+	})
+
+	It("should list the exports and capabilities", func() {
+		Expect(run("inspect", path)).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(MatchRegexp(`abi version:\s+unknown`))
+		Expect(stdout.String()).To(MatchRegexp(`capabilities:\s+none`))
+		Expect(stdout.String()).To(MatchRegexp(`process\s+func\s+\(\) -> i32`))
+	})
+
+	It("should print the module as JSON", func() {
+		Expect(run("inspect", "--json", path)).To(Equal(0), stderr.String())
+		var module struct {
+			Exports []struct {
+				Name string `json:"name"`
+			} `json:"exports"`
+		}
+		Expect(json.Unmarshal(stdout.Bytes(), &module)).To(Succeed())
+		Expect(module.Exports).To(HaveLen(3))
+	})
+
+	It("should reject files that are not WebAssembly", func() {
+		Expect(os.WriteFile(path, []byte("not wasm"), 0644)).To(Succeed())
+		Expect(run("inspect", path)).To(Equal(1))
+	})
+})
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+// logLevel implements `pluginctl log-level PLUGIN [LEVEL]`.
+//
+// Without LEVEL it shows the plugin's log verbosity. With LEVEL it logs the
+// plugin's messages from that level up for the --for duration, whatever the
+// server's own level; --reset ends an override early.
+func (c *cli) logLevel(args []string) error {
+	fs := flag.NewFlagSet("log-level", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	duration := fs.Duration("for", 0, "how long the override lasts (server default 15m, at most 24h)")
+	trace := fs.Bool("trace", false, "also keep a debug trace of every run (requires DEBUG_TRACES)")
+	reset := fs.Bool("reset", false, "return the plugin to the server's log level")
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 || (*reset && fs.NArg() != 1) {
+		return fmt.Errorf("usage: pluginctl log-level [--for DURATION] [--trace] [--json] PLUGIN [LEVEL] | --reset PLUGIN")
+	}
+	if *duration < 0 || *duration%time.Second != 0 {
+		return fmt.Errorf("--for must be a whole number of seconds, got %s", *duration)
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	plugin := fs.Arg(0)
+	if *reset {
+		if err := api.ClearLogOverride(context.Background(), plugin); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "%s: log override removed\n", plugin)
+		return nil
+	}
+
+	var override *client.LogOverride
+	if fs.NArg() == 2 {
+		override, err = api.SetLogOverride(context.Background(), plugin, client.LogOverrideRequest{
+			Level:           fs.Arg(1),
+			Trace:           *trace,
+			DurationSeconds: int(duration.Seconds()),
+		})
+	} else {
+		override, err = api.LogOverride(context.Background(), plugin)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, override)
+	}
+	if override.Expires.IsZero() {
+		fmt.Fprintf(c.stdout, "%s: %s (server level)\n", override.Plugin, override.Level)
+		return nil
+	}
+	traced := ""
+	if override.Trace {
+		traced = ", tracing every run"
+	}
+	fmt.Fprintf(c.stdout, "%s: %s until %s%s\n", override.Plugin, override.Level,
+		override.Expires.Local().Format(time.RFC3339), traced)
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+var _ = Describe("pluginctl log-level", func() {
+	// =========================================================================
+	// TEST: Per-plugin log overrides
+	// Why: Operators switch one plugin to debug from the command line; the
+	//      request must carry the level and duration, and the answer must say
+	//      until when the override holds.
+	// =========================================================================
+	var (
+		stdout   *bytes.Buffer
+		stderr   *bytes.Buffer
+		run      func(args ...string) int
+		received client.LogOverrideRequest
+		method   string
+	)
+
+	BeforeEach(func() {
+		received, method = client.LogOverrideRequest{}, ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/plugins/hello/logging"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer admin"))
+			method = r.Method
+			switch r.Method {
+			case http.MethodPut:
+				Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+				fmt.Fprintf(w, `{"plugin":"hello","level":%q,"trace":%t,"expires":"2024-05-01T12:15:00Z"}`,
+					received.Level, received.Trace)
+			case http.MethodDelete:
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.Write([]byte(`{"plugin":"hello","level":"info"}`))
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", path}, args...), stdout, stderr)
+		}
+		Expect(run("config", "set-context", "dev", "--server", server.URL, "--token", "admin")).To(Equal(0))
+		Expect(run("config", "use-context", "dev")).To(Equal(0))
+	})
+
+	It("should show the server level without an override", func() {
+		Expect(run("log-level", "hello")).To(Equal(0), stderr.String())
+		Expect(method).To(Equal(http.MethodGet))
+		Expect(stdout.String()).To(Equal("hello: info (server level)\n"))
+	})
+
+	It("should set an override for the given duration", func() {
+		Expect(run("log-level", "--for", "30m", "--trace", "hello", "debug")).To(Equal(0), stderr.String())
+		Expect(received).To(Equal(client.LogOverrideRequest{Level: "debug", Trace: true, DurationSeconds: 1800}))
+
+		expires := time.Date(2024, 5, 1, 12, 15, 0, 0, time.UTC).Local().Format(time.RFC3339)
+		Expect(stdout.String()).To(Equal("hello: debug until " + expires + ", tracing every run\n"))
+	})
+
+	It("should remove an override with --reset", func() {
+		Expect(run("log-level", "--reset", "hello")).To(Equal(0), stderr.String())
+		Expect(method).To(Equal(http.MethodDelete))
+	})
+
+	It("should reject a fractional duration", func() {
+		Expect(run("log-level", "--for", "1500ms", "hello", "debug")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("whole number of seconds"))
+		Expect(method).To(BeEmpty())
+	})
+})
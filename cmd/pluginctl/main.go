@@ -0,0 +1,447 @@
+// Command pluginctl manages remote WASM plugin servers.
+//
+// Usage:
+//
+//	pluginctl [--config FILE] [--context NAME] <command> [args]
+//
+// Connection settings come from named contexts in the config file
+// (default: ~/.config/pluginctl/config.yaml, or $PLUGINCTL_CONFIG):
+//
+//	pluginctl config set-context staging --server https://plugins.staging:8443 --tenant team-a
+//	pluginctl config use-context staging
+//	pluginctl run hello 21
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+// cli holds the global options and output streams shared by all commands.
+type cli struct {
+	configPath  string
+	contextName string
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+// command is a pluginctl subcommand.
+type command struct {
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"bench":     {"Measure a plugin through the server, or a local FILE.wasm: bench PLUGIN INPUT", (*cli).bench},
+		"catalog":   {"Export or import every plugin build: catalog export|import FILE", (*cli).catalog},
+		"config":    {"Manage connection contexts", (*cli).config},
+		"dev":       {"Rebuild a plugin on change and serve it locally", (*cli).dev},
+		"diff":      {"Compare two plugin binaries: diff OLD.wasm NEW.wasm", (*cli).diff},
+		"inspect":   {"Show a plugin binary's exports, imports, and capabilities: inspect FILE.wasm", (*cli).inspect},
+		"list":      {"List plugins available on the server (alias of plugins)", (*cli).plugins},
+		"new":       {"Create a plugin project from a template: new [--lang LANG] NAME", (*cli).newPlugin},
+		"run":       {"Execute a plugin, or a local FILE.wasm: run PLUGIN INPUT", (*cli).runPlugin},
+		"plugins":   {"List plugins available on the server", (*cli).plugins},
+		"log-level": {"Show or change a plugin's log level for a while: log-level PLUGIN [LEVEL]", (*cli).logLevel},
+		"memory":    {"Show server memory attributed to plugin builds", (*cli).memory},
+		"pools":     {"Show instance pool statistics", (*cli).pools},
+		"sync":      {"Reconcile the server's plugins with a plugins.lock.yaml", (*cli).sync},
+		"trace":     {"Show the host-call trace of a debug run: trace REQUEST_ID", (*cli).trace},
+		"upload":    {"Upload a plugin build: upload [--name NAME] FILE.wasm", (*cli).upload},
+		"validate":  {"Check a plugin binary against the ABI and dry-run it: validate FILE.wasm", (*cli).validate},
+	}
+}
+
+func main() {
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// execute parses global flags, dispatches to a command, and returns the
+// process exit code. It is separate from main so tests can drive the CLI.
+func execute(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("pluginctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.configPath, "config", "", "config file (default $"+ConfigEnv+" or ~/.config/pluginctl/config.yaml)")
+	fs.StringVar(&c.contextName, "context", "", "context to use instead of the current context")
+	fs.Usage = func() { c.usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "pluginctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	if c.configPath == "" {
+		path, err := DefaultConfigPath()
+		if err != nil {
+			fmt.Fprintf(stderr, "pluginctl: %v\n", err)
+			return 1
+		}
+		c.configPath = path
+	}
+
+	if err := cmd.run(c, fs.Args()[1:]); err != nil {
+		fmt.Fprintf(stderr, "pluginctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (c *cli) usage(fs *flag.FlagSet) {
+	fmt.Fprintln(c.stderr, "Usage: pluginctl [flags] <command> [args]")
+	fmt.Fprintln(c.stderr, "\nCommands:")
+	w := tabwriter.NewWriter(c.stderr, 0, 4, 2, ' ', 0)
+	for _, name := range sortedKeys(commands) {
+		fmt.Fprintf(w, "  %s\t%s\n", name, commands[name].summary)
+	}
+	w.Flush()
+	fmt.Fprintln(c.stderr, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+// client builds an API client from the selected context, with opts
+// applied after its settings.
+func (c *cli) client(opts ...client.Option) (*client.Client, error) {
+	cfg, err := LoadConfig(c.configPath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := cfg.Resolve(c.contextName)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.Client(opts...)
+}
+
+// runPlugin implements `pluginctl run PLUGIN INPUT`.
+//
+// With --trace the run is recorded on the server, which must enable
+// DEBUG_TRACES, and its trace is printed to stderr, also when it fails.
+func (c *cli) runPlugin(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	timeout := fs.Duration("timeout", 0, "execution timeout (default: server default)")
+	traced := fs.Bool("trace", false, "record and print the run's host-call trace")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: pluginctl run [--json] [--timeout D] [--trace] PLUGIN INPUT")
+	}
+	input, err := strconv.Atoi(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("input must be an integer: %w", err)
+	}
+
+	// A .wasm file runs here, without a server
+	if strings.HasSuffix(fs.Arg(0), ".wasm") {
+		if *traced || *timeout != 0 {
+			return fmt.Errorf("--trace and --timeout need a server; drop them to run %s locally", fs.Arg(0))
+		}
+		output, err := runLocal(fs.Arg(0), input)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(c.stdout, client.RunResponse{Output: &output})
+		}
+		fmt.Fprintln(c.stdout, output)
+		return nil
+	}
+
+	// A traced run sends its own request ID, under which the server keeps
+	// the trace of a failed run
+	var opts []client.Option
+	requestID := ""
+	if *traced {
+		var b [8]byte
+		_, _ = rand.Read(b[:])
+		requestID = hex.EncodeToString(b[:])
+		opts = append(opts, client.WithHeader(client.RequestIDHeader, requestID))
+	}
+
+	api, err := c.client(opts...)
+	if err != nil {
+		return err
+	}
+	resp, err := api.RunRequest(context.Background(), client.RunRequest{
+		Plugin:    fs.Arg(0),
+		Input:     input,
+		TimeoutMs: int(timeout.Milliseconds()),
+		Debug:     *traced,
+	})
+	if err != nil {
+		if *traced {
+			if trace, traceErr := api.Trace(context.Background(), requestID); traceErr == nil {
+				c.printTrace(trace, *asJSON)
+			}
+		}
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, resp)
+	}
+	if resp.Output == nil {
+		fmt.Fprintln(c.stderr, "(no output)")
+	} else {
+		fmt.Fprintln(c.stdout, *resp.Output)
+	}
+	for _, w := range resp.Warnings {
+		fmt.Fprintf(c.stderr, "warning: %s: %s\n", w.Code, w.Message)
+	}
+	if resp.Trace != nil {
+		c.printTrace(resp.Trace, false)
+	}
+	return nil
+}
+
+// trace implements `pluginctl trace REQUEST_ID`.
+func (c *cli) trace(args []string) error {
+	fs := flag.NewFlagSet("trace", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pluginctl trace [--json] REQUEST_ID")
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	trace, err := api.Trace(context.Background(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, trace)
+	}
+	writeTrace(c.stdout, trace)
+	return nil
+}
+
+// printTrace prints the trace of a `run --trace`: as JSON on stdout, or as
+// text on stderr so that stdout keeps only the output.
+func (c *cli) printTrace(trace *client.Trace, asJSON bool) {
+	if asJSON {
+		writeJSON(c.stdout, trace)
+		return
+	}
+	writeTrace(c.stderr, trace)
+}
+
+// writeTrace prints a trace as an indented call list, e.g.
+//
+//	trace 1f2e3d4c (logger): failed: ...
+//	#1 process(0) -> error: ...  1.2ms
+//	#2   logging.log(2, 1024, 13)  15µs
+//	       read 13 bytes at 0x400: "input is zero"
+func writeTrace(w io.Writer, trace *client.Trace) {
+	status := "succeeded"
+	if trace.Error != "" {
+		status = "failed: " + trace.Error
+	}
+	fmt.Fprintf(w, "trace %s (%s): %s\n", trace.RequestID, trace.Plugin, status)
+
+	for _, event := range trace.Events {
+		name, indent := event.Function, ""
+		if event.Kind == "host" {
+			name, indent = event.Module+"."+event.Function, "  "
+		}
+		outcome := ""
+		switch {
+		case event.Error != "":
+			outcome = " -> error: " + event.Error
+		case len(event.Results) > 0:
+			outcome = " -> " + traceValues(event.Results)
+		}
+		fmt.Fprintf(w, "#%d %s%s(%s)%s  %s\n", event.Seq, indent, name, traceValues(event.Args), outcome, event.Duration)
+		for _, access := range event.Memory {
+			data := strconv.Quote(string(access.Data))
+			if access.Truncated {
+				data += "..."
+			}
+			fmt.Fprintf(w, "     %s%s %d bytes at %#x: %s\n", indent, access.Op, access.Len, access.Ptr, data)
+		}
+	}
+	if trace.Dropped > 0 {
+		fmt.Fprintf(w, "(%d more events dropped)\n", trace.Dropped)
+	}
+	if len(trace.WASI) > 0 {
+		fmt.Fprintf(w, "WASI imports (not traced): %s\n", strings.Join(trace.WASI, ", "))
+	}
+}
+
+// traceValues formats call arguments or results as a comma-separated list.
+func traceValues(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plugins implements `pluginctl plugins`.
+func (c *cli) plugins(args []string) error {
+	fs := flag.NewFlagSet("plugins", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	plugins, err := api.Plugins(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, plugins)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSIZE\tMODIFIED")
+	for _, p := range plugins {
+		version := p.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", p.Name, version, p.Size, p.ModTime.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// pools implements `pluginctl pools`.
+func (c *cli) pools(args []string) error {
+	fs := flag.NewFlagSet("pools", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	pools, err := api.Pools(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, pools)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PLUGIN\tSTRATEGY\tIDLE\tIN USE\tINSTANTIATED\tRESTORED")
+	for _, p := range pools {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", p.Plugin, p.Strategy, p.Idle, p.InUse, p.Instantiated, p.Restored)
+	}
+	return w.Flush()
+}
+
+func (c *cli) memory(args []string) error {
+	fs := flag.NewFlagSet("memory", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	report, err := api.Memory(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, report)
+	}
+	rss := "unknown"
+	if report.Process.RSSBytes > 0 {
+		rss = formatBytes(report.Process.RSSBytes)
+	}
+	fmt.Fprintf(c.stdout, "process: rss %s, go heap %s, plugins %s\n",
+		rss, formatBytes(int64(report.Process.GoHeapBytes)), formatBytes(report.AttributedBytes))
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PLUGINS\tDIGEST\tINSTANCES\tLINEAR\tSNAPSHOTS\tMODULE\tARTIFACT\tTOTAL")
+	for _, p := range report.Plugins {
+		digest := p.Digest
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", strings.Join(p.Plugins, ","), digest, p.Instances,
+			formatBytes(p.LinearMemoryBytes), formatBytes(p.SnapshotBytes), formatBytes(p.ModuleBytes),
+			formatBytes(p.ArtifactBytes), formatBytes(p.TotalBytes))
+	}
+	return w.Flush()
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
+
+// sortedKeys returns the command names in alphabetical order.
+func sortedKeys(m map[string]command) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+var _ = Describe("pluginctl memory", func() {
+	// =========================================================================
+	// TEST: Memory breakdown
+	// Why: The table is what an operator reads when the server's memory
+	//      grows; the largest plugin builds must be named with readable sizes.
+	// =========================================================================
+	var (
+		stdout *bytes.Buffer
+		stderr *bytes.Buffer
+		run    func(args ...string) int
+	)
+
+	BeforeEach(func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/debug/memory"))
+			json.NewEncoder(w).Encode(client.MemoryReport{
+				Process: client.ProcessMemory{RSSBytes: 6 << 30, GoHeapBytes: 12 << 20},
+				Plugins: []client.PluginMemory{{
+					Plugins:           []string{"upper", "upper-v2"},
+					Digest:            "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+					Instances:         16,
+					LinearMemoryBytes: 2 << 30,
+					SnapshotBytes:     2 << 30,
+					ModuleBytes:       2210,
+					TotalBytes:        4<<30 + 2210,
+				}},
+				AttributedBytes: 4<<30 + 2210,
+			})
+		}))
+		DeferCleanup(server.Close)
+
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", path}, args...), stdout, stderr)
+		}
+		Expect(run("config", "set-context", "dev", "--server", server.URL)).To(Equal(0))
+		Expect(run("config", "use-context", "dev")).To(Equal(0))
+	})
+
+	It("should print the breakdown as a table", func() {
+		Expect(run("memory")).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(HavePrefix("process: rss 6.0 GiB, go heap 12.0 MiB, plugins 4.0 GiB\n"))
+		Expect(stdout.String()).To(MatchRegexp(`upper,upper-v2\s+9f86d081884c\s+16\s+2\.0 GiB\s+2\.0 GiB\s+2\.2 KiB\s+0 B\s+4\.0 GiB`))
+	})
+
+	It("should print the JSON response with --json", func() {
+		Expect(run("memory", "--json")).To(Equal(0), stderr.String())
+		var report client.MemoryReport
+		Expect(json.Unmarshal(stdout.Bytes(), &report)).To(Succeed())
+		Expect(report.Plugins[0].Instances).To(Equal(16))
+	})
+})
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/sdk"
+)
+
+// newPlugin implements `pluginctl new [--lang LANG] [--dir DIR] NAME`.
+//
+// The project is written to DIR (default: ./NAME) from the templates of
+// the sdk package, ready for `pluginctl dev` and for upload: its manifest
+// declares tests the generated plugin passes.
+func (c *cli) newPlugin(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	lang := fs.String("lang", "cpp", "plugin language: "+strings.Join(sdk.Languages(), ", "))
+	dir := fs.String("dir", "", "project directory (default: ./NAME)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pluginctl new [--lang LANG] [--dir DIR] NAME")
+	}
+	name := fs.Arg(0)
+	if !isPluginName(name) {
+		return fmt.Errorf("invalid plugin name %q", name)
+	}
+	if *dir == "" {
+		*dir = name
+	}
+
+	paths, err := sdk.Scaffold(*dir, name, *lang)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		fmt.Fprintf(c.stdout, "created %s\n", path)
+	}
+	fmt.Fprintf(c.stdout, "run `pluginctl dev --dir %s` to build and serve it\n", *dir)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+var _ = Describe("pluginctl new", func() {
+	var (
+		dir            string
+		stdout, stderr *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	})
+
+	run := func(args ...string) int {
+		return execute(append([]string{"--config", filepath.Join(dir, "config.yaml"), "new"}, args...), stdout, stderr)
+	}
+
+	// =========================================================================
+	// TEST: Templates
+	// Why: Authors on C, C++, and AssemblyScript toolchains should start from
+	//      a project that builds with `pluginctl dev` and passes its own
+	//      manifest tests, rather than reverse-engineer the ABI from hello.
+	// =========================================================================
+	DescribeTable("should scaffold a project that pluginctl dev can build",
+		func(lang string, files ...string) {
+			project := filepath.Join(dir, "shout")
+			Expect(run("--lang", lang, "--dir", project, "shout")).To(Equal(0), stderr.String())
+			for _, file := range files {
+				Expect(filepath.Join(project, file)).To(BeAnExistingFile())
+				Expect(stdout.String()).To(ContainSubstring("created " + file))
+			}
+
+			m, err := manifest.Load(filepath.Join(project, "plugin.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Name).To(Equal("shout"))
+			Expect(m.Tests).NotTo(BeEmpty())
+
+			spec, err := detectBuild(project, "shout")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Base(spec.Artifact)).To(Equal("shout.wasm"))
+		},
+		Entry("c", "c", "shout.c", "plugin.h", "Makefile"),
+		Entry("cpp", "cpp", "shout.cpp", "plugin.h", "Makefile"),
+		Entry("assemblyscript", "assemblyscript", "assembly/index.ts", "assembly/plugin.ts", "asconfig.json", "package.json"),
+	)
+
+	It("should not overwrite an existing project", func() {
+		project := filepath.Join(dir, "shout")
+		Expect(os.MkdirAll(project, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(project, "shout.cpp"), []byte("mine"), 0644)).To(Succeed())
+
+		Expect(run("--dir", project, "shout")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("already exists"))
+		Expect(os.ReadFile(filepath.Join(project, "shout.cpp"))).To(Equal([]byte("mine")))
+		Expect(filepath.Join(project, "plugin.h")).NotTo(BeAnExistingFile())
+	})
+
+	It("should reject unknown languages and invalid names", func() {
+		Expect(run("--lang", "cobol", "shout")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("unknown plugin language"))
+		Expect(run("no/slashes")).To(Equal(1))
+	})
+})
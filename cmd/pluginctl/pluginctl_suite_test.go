@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestPluginctl bootstraps the Ginkgo test suite for the CLI.
+// Run with: go test -v ./cmd/pluginctl/...
+func TestPluginctl(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pluginctl Suite")
+}
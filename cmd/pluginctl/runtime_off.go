@@ -0,0 +1,24 @@
+//go:build !wasmedge
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// errNoRuntime is returned by the commands that load plugins themselves
+// when pluginctl was built without WasmEdge, which it is by default.
+var errNoRuntime = errors.New("this pluginctl cannot load plugins; build it with -tags wasmedge, which needs WasmEdge")
+
+func runLocal(path string, input int) (int, error) {
+	return 0, errNoRuntime
+}
+
+func validateLocal(path string, opts validateOptions) (*abiReport, error) {
+	return nil, errNoRuntime
+}
+
+func benchLocal(w io.Writer, path string, opts benchOptions) error {
+	return errNoRuntime
+}
@@ -0,0 +1,32 @@
+//go:build !wasmedge
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pluginctl without WasmEdge", func() {
+	// =========================================================================
+	// TEST: Local commands in the default build
+	// Why: The default build does not link WasmEdge; the commands that need
+	//      it must say how to get it rather than fail obscurely.
+	// =========================================================================
+	It("should explain how to run, validate, and benchmark plugins locally", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "plugin.wasm")
+		Expect(os.WriteFile(path, exportsModule("init", "process", "cleanup"), 0644)).To(Succeed())
+		config := filepath.Join(dir, "config.yaml")
+
+		for _, args := range [][]string{{"run", path, "1"}, {"validate", path}, {"bench", path, "1"}} {
+			var stdout, stderr bytes.Buffer
+			Expect(execute(append([]string{"--config", config}, args...), &stdout, &stderr)).To(Equal(1))
+			Expect(stderr.String()).To(ContainSubstring("-tags wasmedge"), args[0])
+		}
+	})
+})
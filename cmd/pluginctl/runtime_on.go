@@ -0,0 +1,87 @@
+//go:build wasmedge
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// runLocal loads the plugin at path, runs it once with input, and unloads
+// it, the way a server instance would.
+func runLocal(path string, input int) (int, error) {
+	plugin, err := runtime.LoadPlugin(path)
+	if err != nil {
+		return 0, err
+	}
+	defer plugin.Close()
+	if err := plugin.Init(); err != nil {
+		return 0, err
+	}
+	output, err := plugin.Execute(input)
+	if err != nil {
+		return 0, err
+	}
+	return output, plugin.Cleanup()
+}
+
+// validateLocal runs runtime.ValidateABI on the plugin at path, with the
+// standard library host module the server provides.
+func validateLocal(path string, opts validateOptions) (*abiReport, error) {
+	result := runtime.ValidateABI(path, runtime.LoadOptions{
+		MaxMemoryPages:    opts.maxMemoryPages,
+		RequireABIVersion: opts.requireABIVersion,
+		HostModules:       []*runtime.HostModule{runtime.NewStdlibModule()},
+	})
+	report := &abiReport{
+		Path:        result.Path,
+		Valid:       result.Valid,
+		ABIVersion:  result.ABIVersion,
+		MemoryPages: result.MemoryPages,
+		Payloads:    result.Payloads,
+	}
+	for _, check := range result.Checks {
+		report.Checks = append(report.Checks, abiCheck{Name: check.Name, Status: string(check.Status), Message: check.Message})
+	}
+	return report, nil
+}
+
+// benchLocal benchmarks the plugin at path with runtime.Benchmark, and
+// writes the result to w in opts.format.
+func benchLocal(w io.Writer, path string, opts benchOptions) error {
+	result, err := runtime.Benchmark(path, runtime.BenchmarkOptions{
+		Rounds:     opts.rounds,
+		Iterations: opts.iterations,
+		Warmup:     opts.warmup,
+		Input:      opts.input,
+		Load:       runtime.LoadOptions{HostModules: []*runtime.HostModule{runtime.NewStdlibModule()}},
+	})
+	if err != nil {
+		return err
+	}
+	switch opts.format {
+	case "json":
+		return writeJSON(w, result)
+	case "csv":
+		return result.WriteCSV(w)
+	}
+
+	fmt.Fprintf(w, "%s (%s): %d cold starts, %d calls\n", result.Path, formatBytes(result.Size), result.Rounds, result.Iterations)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tMIN\tMEAN\tP50\tP95\tP99\tMAX")
+	for _, phase := range []struct {
+		name  string
+		stats runtime.LatencyStats
+	}{{"load", result.Load}, {"init", result.Init}, {"execute", result.Execute}} {
+		s := phase.stats
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", phase.name, s.Min, s.Mean, s.P50, s.P95, s.P99, s.Max)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "allocations: %.1f per call, %s\n", result.AllocsPerCall, formatBytes(int64(result.BytesPerCall)))
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build wasmedge
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pluginctl validate", func() {
+	// =========================================================================
+	// TEST: CI gate
+	// Why: Plugin authors' pipelines read the report and rely on the exit
+	//      status to stop a build the server would refuse.
+	// =========================================================================
+	It("should fail plugins that do not implement the ABI", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "plugin.wasm")
+		Expect(os.WriteFile(path, exportsModule("init", "process"), 0644)).To(Succeed())
+		var stdout, stderr bytes.Buffer
+		run := func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", filepath.Join(dir, "config.yaml")}, args...), &stdout, &stderr)
+		}
+
+		Expect(run("validate", path)).To(Equal(1))
+		Expect(stdout.String()).To(MatchRegexp(`failed\s+export.process\s+process has signature \(\) -> i32`))
+		Expect(stdout.String()).To(MatchRegexp(`failed\s+export.cleanup\s+cleanup is not exported`))
+		Expect(stdout.String()).To(ContainSubstring(path + ": NOT valid"))
+
+		Expect(run("validate", "--json", path)).To(Equal(1))
+		var report abiReport
+		Expect(json.Unmarshal(stdout.Bytes(), &report)).To(Succeed())
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Checks).To(ContainElement(abiCheck{Name: "dry_run.init", Status: "skipped", Message: "a static check failed"}))
+	})
+})
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+// DefaultLockFile is the lock file sync reads without --file.
+const DefaultLockFile = "plugins.lock.yaml"
+
+// LockFile is the set of plugin builds a server should have, as
+// infrastructure-as-code pipelines keep it per environment:
+//
+//	plugins:
+//	  - name: hello
+//	    digest: sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    source: build/hello.wasm
+//	  - name: upper
+//	    version: 1.2.0
+//	    digest: sha256:...
+//	    source: https://artifacts.example.com/upper/1.2.0/upper.wasm
+type LockFile struct {
+	Plugins []LockedPlugin `yaml:"plugins"`
+}
+
+// LockedPlugin is one build of a LockFile.
+type LockedPlugin struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"` // Empty for the unversioned build
+	Digest  string `yaml:"digest"`            // "sha256:<hex>", or the bare hex
+	Source  string `yaml:"source"`            // Path relative to the lock file, or http(s) URL
+}
+
+// Ref returns the build's reference as the server names it, e.g.
+// "upper@1.2.0".
+func (p LockedPlugin) Ref() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + "@" + p.Version
+}
+
+// sha256 returns the hex digest p pins, without its "sha256:" prefix.
+func (p LockedPlugin) sha256() string {
+	return strings.ToLower(strings.TrimPrefix(p.Digest, "sha256:"))
+}
+
+// LoadLockFile reads and validates a lock file. Relative sources are
+// resolved against the file's directory.
+func LoadLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var lock LockFile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range lock.Plugins {
+		p := &lock.Plugins[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("%s: plugin %d has no name", path, i+1)
+		}
+		if seen[p.Ref()] {
+			return nil, fmt.Errorf("%s: %s is listed twice", path, p.Ref())
+		}
+		seen[p.Ref()] = true
+		if sum, err := hex.DecodeString(p.sha256()); err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("%s: digest of %s must be sha256: and 64 hex digits", path, p.Ref())
+		}
+		if p.Source == "" {
+			return nil, fmt.Errorf("%s: %s has no source", path, p.Ref())
+		}
+		if !isURL(p.Source) && !filepath.IsAbs(p.Source) {
+			p.Source = filepath.Join(filepath.Dir(path), p.Source)
+		}
+	}
+	return &lock, nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// syncAction is what sync does to one build.
+type syncAction struct {
+	op     string // "install", "update", "delete", or "unchanged"
+	ref    string
+	locked *LockedPlugin // nil for deletions
+}
+
+// planSync compares the lock file with the server's builds. Builds the lock
+// file does not list are deleted with prune, and otherwise left alone.
+func planSync(lock *LockFile, installed []client.PluginInfo, prune bool) []syncAction {
+	digests := make(map[string]string, len(installed))
+	for _, p := range installed {
+		ref := p.Name
+		if p.Version != "" {
+			ref += "@" + p.Version
+		}
+		digests[ref] = p.Digest
+	}
+
+	var plan []syncAction
+	for i := range lock.Plugins {
+		p := &lock.Plugins[i]
+		digest, ok := digests[p.Ref()]
+		op := "install"
+		switch {
+		case ok && digest == p.sha256():
+			op = "unchanged"
+		case ok:
+			op = "update"
+		}
+		plan = append(plan, syncAction{op: op, ref: p.Ref(), locked: p})
+		delete(digests, p.Ref())
+	}
+	if prune {
+		extra := make([]string, 0, len(digests))
+		for ref := range digests {
+			extra = append(extra, ref)
+		}
+		sort.Strings(extra)
+		for _, ref := range extra {
+			plan = append(plan, syncAction{op: "delete", ref: ref})
+		}
+	}
+	return plan
+}
+
+// sync implements `pluginctl sync [--file FILE] [--prune] [--dry-run]`.
+//
+// It reconciles the server with a lock file: builds that are missing or
+// whose binary differs from the pinned digest are uploaded from their
+// source, after the download is checked against that digest, and with
+// --prune builds the file does not list are deleted. A server already in
+// the locked state is not touched, so pipelines can run sync on every
+// deploy; --dry-run only prints the plan, and --check fails if it is not
+// empty.
+func (c *cli) sync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	file := fs.String("file", DefaultLockFile, "lock file listing the plugin builds to install")
+	prune := fs.Bool("prune", false, "delete builds the lock file does not list")
+	dryRun := fs.Bool("dry-run", false, "print the changes without making them")
+	check := fs.Bool("check", false, "like --dry-run, but fail if there are changes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: pluginctl sync [--file FILE] [--prune] [--dry-run | --check]")
+	}
+
+	lock, err := LoadLockFile(*file)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	installed, err := api.PluginDigests(ctx)
+	if err != nil {
+		return err
+	}
+
+	plan := planSync(lock, installed, *prune)
+	changes := 0
+	for _, action := range plan {
+		if action.op == "unchanged" {
+			continue
+		}
+		changes++
+		fmt.Fprintf(c.stdout, "%s %s\n", action.op, action.ref)
+		if *dryRun || *check {
+			continue
+		}
+		if err := syncBuild(ctx, api, action); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case changes == 0:
+		fmt.Fprintf(c.stdout, "%d plugin(s) up to date\n", len(lock.Plugins))
+	case *check:
+		return fmt.Errorf("%d change(s) pending", changes)
+	case *dryRun:
+		fmt.Fprintf(c.stdout, "%d change(s) pending\n", changes)
+	default:
+		fmt.Fprintf(c.stdout, "%d change(s) applied\n", changes)
+	}
+	return nil
+}
+
+// syncBuild makes one change of a sync plan.
+func syncBuild(ctx context.Context, api *client.Client, action syncAction) error {
+	if action.locked == nil {
+		if err := api.DeletePlugin(ctx, action.ref); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", action.ref, err)
+		}
+		return nil
+	}
+
+	data, err := fetchSource(ctx, action.locked.Source)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", action.ref, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != action.locked.sha256() {
+		return fmt.Errorf("%s: %s has digest sha256:%s, the lock file pins %s",
+			action.ref, action.locked.Source, got, action.locked.Digest)
+	}
+	if _, err := api.UploadPlugin(ctx, action.ref, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", action.ref, err)
+	}
+	return nil
+}
+
+// fetchSource reads a build from a file or an http(s) URL.
+func fetchSource(ctx context.Context, source string) ([]byte, error) {
+	if !isURL(source) {
+		return os.ReadFile(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
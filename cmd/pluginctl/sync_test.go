@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+func digestOf(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("pluginctl sync", func() {
+	// =========================================================================
+	// TEST: Declarative plugin sets
+	// Why: Pipelines run sync on every deploy; it must change exactly the
+	//      builds that differ from the lock file, never upload a source that
+	//      does not match its pin, and delete only when asked to prune.
+	// =========================================================================
+	var (
+		stdout, stderr *bytes.Buffer
+		run            func(args ...string) int
+		dir            string
+		mu             sync.Mutex
+		installed      map[string]string // ref -> contents
+		calls          []string
+	)
+
+	BeforeEach(func() {
+		installed = map[string]string{"hello": "hello v1", "upper@1.0.0": "upper 1.0.0", "legacy": "legacy"}
+		calls = nil
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/plugins":
+				Expect(r.URL.Query().Get("digest")).To(Equal("true"))
+				var plugins []client.PluginInfo
+				for ref, data := range installed {
+					name, version, _ := bytes.Cut([]byte(ref), []byte("@"))
+					plugins = append(plugins, client.PluginInfo{Name: string(name), Version: string(version), Digest: digestOf(data)})
+				}
+				json.NewEncoder(w).Encode(plugins)
+			case r.Method == http.MethodPost && r.URL.Path == "/plugins":
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer admin"))
+				data, _ := io.ReadAll(r.Body)
+				ref := r.URL.Query().Get("name")
+				installed[ref] = string(data)
+				calls = append(calls, "upload "+ref)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"name":"x","size":1,"mod_time":"2024-05-01T12:00:00Z"}`))
+			case r.Method == http.MethodDelete:
+				ref := r.URL.Path[len("/plugins/"):]
+				delete(installed, ref)
+				calls = append(calls, "delete "+ref)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		dir = GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, "build"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "build", "hello.wasm"), []byte("hello v2"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "build", "upper.wasm"), []byte("upper 1.0.0"), 0644)).To(Succeed())
+		writeLock(dir, "hello v2")
+
+		path := filepath.Join(dir, "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", path}, args...), stdout, stderr)
+		}
+		Expect(run("config", "set-context", "dev", "--server", server.URL, "--token", "admin")).To(Equal(0))
+		Expect(run("config", "use-context", "dev")).To(Equal(0))
+	})
+
+	lockFile := func() string { return filepath.Join(dir, DefaultLockFile) }
+
+	It("should upload the builds that differ and keep the rest", func() {
+		Expect(run("sync", "--file", lockFile())).To(Equal(0), stderr.String())
+		Expect(calls).To(Equal([]string{"upload hello"}))
+		Expect(installed["hello"]).To(Equal("hello v2"))
+		Expect(installed).To(HaveKey("legacy"))
+		Expect(stdout.String()).To(Equal("update hello\n1 change(s) applied\n"))
+
+		Expect(run("sync", "--file", lockFile())).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("2 plugin(s) up to date\n"))
+	})
+
+	It("should delete unlisted builds with --prune", func() {
+		Expect(run("sync", "--file", lockFile(), "--prune")).To(Equal(0), stderr.String())
+		Expect(calls).To(Equal([]string{"upload hello", "delete legacy"}))
+	})
+
+	It("should only print the plan with --dry-run and fail on it with --check", func() {
+		Expect(run("sync", "--file", lockFile(), "--prune", "--dry-run")).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("update hello\ndelete legacy\n2 change(s) pending\n"))
+
+		Expect(run("sync", "--file", lockFile(), "--check")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("1 change(s) pending"))
+		Expect(calls).To(BeEmpty())
+	})
+
+	It("should refuse a source that does not match its digest", func() {
+		writeLock(dir, "hello v3")
+
+		Expect(run("sync", "--file", lockFile())).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("the lock file pins sha256:" + digestOf("hello v3")))
+		Expect(calls).To(BeEmpty())
+	})
+
+	It("should reject an invalid lock file", func() {
+		Expect(os.WriteFile(lockFile(), []byte("plugins:\n  - name: hello\n    digest: sha256:abc\n    source: x.wasm\n"), 0644)).To(Succeed())
+
+		Expect(run("sync", "--file", lockFile())).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("digest of hello must be sha256: and 64 hex digits"))
+	})
+})
+
+// writeLock writes a lock file pinning hello to the digest of helloData and
+// upper@1.0.0 to its installed build.
+func writeLock(dir, helloData string) {
+	lock := "plugins:\n" +
+		"  - name: hello\n" +
+		"    digest: sha256:" + digestOf(helloData) + "\n" +
+		"    source: build/hello.wasm\n" +
+		"  - name: upper\n" +
+		"    version: 1.0.0\n" +
+		"    digest: " + digestOf("upper 1.0.0") + "\n" +
+		"    source: build/upper.wasm\n"
+	Expect(os.WriteFile(filepath.Join(dir, DefaultLockFile), []byte(lock), 0644)).To(Succeed())
+}
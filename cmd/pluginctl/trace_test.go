@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+var _ = Describe("pluginctl trace", func() {
+	// =========================================================================
+	// TEST: Traced runs
+	// Why: Plugin authors debugging a failing run need its host calls even
+	//      though the run returned an error, so a traced run must fetch the
+	//      trace kept under its own request ID.
+	// =========================================================================
+	var (
+		server *httptest.Server
+		traces map[string]client.Trace
+		stdout *bytes.Buffer
+		stderr *bytes.Buffer
+		run    func(args ...string) int
+	)
+
+	BeforeEach(func() {
+		traces = map[string]client.Trace{}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+			var req client.RunRequest
+			json.NewDecoder(r.Body).Decode(&req)
+
+			requestID := r.Header.Get(client.RequestIDHeader)
+			trace := client.Trace{RequestID: requestID, Plugin: req.Plugin, WASI: []string{"fd_write"}, Events: []client.TraceEvent{
+				{Seq: 1, Kind: "export", Function: "process", Args: []interface{}{float64(req.Input)}, Duration: time.Millisecond},
+				{Seq: 2, Kind: "host", Module: "logging", Function: "log", Args: []interface{}{2.0, 1024.0, 5.0}, Duration: time.Microsecond,
+					Memory: []client.MemoryAccess{{Op: "read", Ptr: 1024, Len: 5, Data: []byte("hello")}}},
+			}}
+			if req.Input < 0 {
+				trace.Error = "plugin returned -3"
+				trace.Events[0].Error = trace.Error
+			} else {
+				trace.Events[0].Results = []interface{}{7.0}
+			}
+			if req.Debug {
+				traces[requestID] = trace
+			}
+
+			if trace.Error != "" {
+				w.Header().Set("Content-Type", apierror.ContentType)
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(apierror.New(apierror.CodePluginExecutionFailed, trace.Error))
+				return
+			}
+			output := 7
+			resp := client.RunResponse{Output: &output}
+			if req.Debug {
+				resp.Trace = &trace
+			}
+			json.NewEncoder(w).Encode(resp)
+		})
+		mux.HandleFunc("/debug/traces/", func(w http.ResponseWriter, r *http.Request) {
+			trace, ok := traces[r.URL.Path[len("/debug/traces/"):]]
+			if !ok {
+				w.Header().Set("Content-Type", apierror.ContentType)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(apierror.New(apierror.CodeTraceNotFound, "no trace kept"))
+				return
+			}
+			json.NewEncoder(w).Encode(trace)
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", path}, args...), stdout, stderr)
+		}
+		Expect(run("config", "set-context", "dev", "--server", server.URL)).To(Equal(0))
+		Expect(run("config", "use-context", "dev")).To(Equal(0))
+	})
+
+	It("should print the trace of a successful run to stderr", func() {
+		Expect(run("run", "--trace", "logger", "3")).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("7\n"))
+		Expect(stderr.String()).To(ContainSubstring("(logger): succeeded"))
+		Expect(stderr.String()).To(ContainSubstring("#1 process(3) -> 7  1ms"))
+		Expect(stderr.String()).To(ContainSubstring("#2   logging.log(2, 1024, 5)  1µs"))
+		Expect(stderr.String()).To(ContainSubstring(`read 5 bytes at 0x400: "hello"`))
+		Expect(stderr.String()).To(ContainSubstring("WASI imports (not traced): fd_write"))
+	})
+
+	It("should fetch the trace of a failed run", func() {
+		Expect(run("run", "--trace", "logger", "-1")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("(logger): failed: plugin returned -3"))
+		Expect(stderr.String()).To(ContainSubstring("#1 process(-1) -> error: plugin returned -3"))
+		Expect(stderr.String()).To(ContainSubstring("(plugin_execution_failed): plugin returned -3"))
+	})
+
+	It("should not trace runs without --trace", func() {
+		Expect(run("run", "logger", "3")).To(Equal(0), stderr.String())
+		Expect(stderr.String()).To(BeEmpty())
+		Expect(traces).To(BeEmpty())
+	})
+
+	It("should show a kept trace by request ID", func() {
+		Expect(run("run", "--trace", "logger", "-1")).To(Equal(1))
+		Expect(traces).To(HaveLen(1))
+		var requestID string
+		for id := range traces {
+			requestID = id
+		}
+
+		Expect(run("trace", requestID)).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(HavePrefix("trace " + requestID + " (logger): failed"))
+
+		Expect(run("trace", "--json", requestID)).To(Equal(0), stderr.String())
+		var trace client.Trace
+		Expect(json.Unmarshal(stdout.Bytes(), &trace)).To(Succeed())
+		Expect(trace.Events).To(HaveLen(2))
+
+		Expect(run("trace", "unknown")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("no trace kept"))
+	})
+})
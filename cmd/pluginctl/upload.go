@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// upload implements `pluginctl upload [--name NAME] FILE.wasm`.
+//
+// The name defaults to the file's base name without .wasm, so
+// dist/upper.wasm becomes upper; name a version with --name upper@1.2.0.
+// The server validates the binary before it replaces the stored build.
+func (c *cli) upload(args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	name := fs.String("name", "", "plugin name, optionally with @version (default: base name of FILE)")
+	asJSON := fs.Bool("json", false, "print the full JSON response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pluginctl upload [--name NAME] [--json] FILE.wasm")
+	}
+	path := fs.Arg(0)
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(path), ".wasm")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	info, err := api.UploadPlugin(context.Background(), *name, f)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, info)
+	}
+	ref := info.Name
+	if info.Version != "" {
+		ref += "@" + info.Version
+	}
+	fmt.Fprintf(c.stdout, "uploaded %s (%s)\n", ref, formatBytes(info.Size))
+	return nil
+}
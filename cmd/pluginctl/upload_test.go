@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+var _ = Describe("pluginctl upload", func() {
+	// =========================================================================
+	// TEST: Uploads
+	// Why: A build must reach the server byte for byte under the name the
+	//      developer meant, which is its file name unless they say otherwise.
+	// =========================================================================
+	var (
+		dir      string
+		uploaded map[string][]byte
+		stdout   *bytes.Buffer
+		stderr   *bytes.Buffer
+		run      func(args ...string) int
+	)
+
+	BeforeEach(func() {
+		uploaded = map[string][]byte{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/plugins"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer admin"))
+			data, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			name := r.URL.Query().Get("name")
+			uploaded[name] = data
+			json.NewEncoder(w).Encode(client.PluginInfo{Name: name, Size: int64(len(data))})
+		}))
+		DeferCleanup(server.Close)
+
+		dir = GinkgoT().TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		run = func(args ...string) int {
+			stdout.Reset()
+			stderr.Reset()
+			return execute(append([]string{"--config", path}, args...), stdout, stderr)
+		}
+		Expect(run("config", "set-context", "dev", "--server", server.URL, "--token", "admin")).To(Equal(0))
+		Expect(run("config", "use-context", "dev")).To(Equal(0))
+	})
+
+	It("should upload a build under its file name", func() {
+		wasm := exportsModule("init", "process")
+		path := filepath.Join(dir, "upper.wasm")
+		Expect(os.WriteFile(path, wasm, 0644)).To(Succeed())
+
+		Expect(run("upload", path)).To(Equal(0), stderr.String())
+		Expect(uploaded).To(Equal(map[string][]byte{"upper": wasm}))
+		Expect(stdout.String()).To(HavePrefix("uploaded upper ("))
+
+		Expect(run("upload", "--name", "shout", path)).To(Equal(0), stderr.String())
+		Expect(uploaded).To(HaveKeyWithValue("shout", wasm))
+	})
+
+	It("should fail for missing files", func() {
+		Expect(run("upload", filepath.Join(dir, "missing.wasm"))).To(Equal(1))
+		Expect(uploaded).To(BeEmpty())
+	})
+})
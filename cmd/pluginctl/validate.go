@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+)
+
+// errInvalid makes `pluginctl validate` exit non-zero so it can gate CI.
+var errInvalid = errors.New("plugin is not valid")
+
+// abiReport mirrors runtime.ABIReport, which only builds with WasmEdge.
+type abiReport struct {
+	Path        string     `json:"path"`
+	Valid       bool       `json:"valid"`
+	ABIVersion  string     `json:"abi_version"`
+	MemoryPages int        `json:"memory_pages"`
+	Payloads    []string   `json:"payloads,omitempty"`
+	Checks      []abiCheck `json:"checks"`
+}
+
+// abiCheck mirrors runtime.ABICheck.
+type abiCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // passed, warning, failed, or skipped
+	Message string `json:"message,omitempty"`
+}
+
+// validateOptions are the load options of `pluginctl validate`.
+type validateOptions struct {
+	maxMemoryPages    int
+	requireABIVersion bool
+}
+
+// validate implements `pluginctl validate FILE.wasm`.
+//
+// It checks the binary's exports, their signatures, its ABI version and
+// memory, and dry-runs init() and cleanup() with the runtime the server
+// uses (see runtime.ValidateABI). Imports of the server's own host
+// modules cannot be resolved here, so plugins with them skip the dry run.
+// The command exits with status 1 if a check failed or, with --strict,
+// warned.
+func (c *cli) validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	strict := fs.Bool("strict", false, "exit 1 on warnings too")
+	var opts validateOptions
+	fs.IntVar(&opts.maxMemoryPages, "max-memory-pages", 0, "memory limit of the server, like its MAX_MEMORY_PAGES (0: none)")
+	fs.BoolVar(&opts.requireABIVersion, "require-abi-version", false, "fail plugins without get_abi_version, like a server with REQUIRE_ABI_VERSION")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pluginctl validate [--json] [--strict] [--max-memory-pages N] [--require-abi-version] FILE.wasm")
+	}
+
+	report, err := validateLocal(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := writeJSON(c.stdout, report); err != nil {
+			return err
+		}
+	} else {
+		c.printValidation(report)
+	}
+
+	if !report.Valid {
+		return errInvalid
+	}
+	for _, check := range report.Checks {
+		if *strict && check.Status == "warning" {
+			return errInvalid
+		}
+	}
+	return nil
+}
+
+// printValidation writes a human-readable report.
+func (c *cli) printValidation(report *abiReport) {
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Status, check.Name, check.Message)
+	}
+	w.Flush()
+
+	verdict := "valid"
+	if !report.Valid {
+		verdict = "NOT valid"
+	}
+	fmt.Fprintf(c.stdout, "%s: %s (abi %s, %d page(s) of memory)\n", report.Path, verdict, report.ABIVersion, report.MemoryPages)
+}
@@ -0,0 +1,120 @@
+// Command plugingate fails a plugin publish when the new version regresses
+// size, validation time, or cold-start latency beyond configured limits.
+//
+// Usage:
+//
+//	plugingate [flags] PREVIOUS.wasm NEW.wasm
+//
+// Both versions are profiled on the same machine in the same run, so the
+// comparison is independent of the hardware CI happens to use. The exit
+// status is 1 if any limit is violated:
+//
+//	plugingate --max-size-growth 5 --max-cold-start 20ms \
+//	    dist/upper-1.3.0.wasm plugins/upper/upper.wasm
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// GateReport is the JSON output of plugingate.
+type GateReport struct {
+	Previous   runtime.StartupProfile `json:"previous"`
+	New        runtime.StartupProfile `json:"new"`
+	Violations []Violation            `json:"violations"`
+}
+
+// profileFunc measures a plugin; tests replace it to avoid WasmEdge.
+var profileFunc = runtime.ProfileStartup
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run parses flags, profiles both versions, and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	policy := DefaultPolicy
+
+	fs := flag.NewFlagSet("plugingate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	sizeGrowth := fs.Float64("max-size-growth", policy.MaxSizeGrowth*100, "allowed size growth in percent (-1 disables)")
+	validateGrowth := fs.Float64("max-validate-growth", policy.MaxValidateGrowth*100, "allowed validation time growth in percent (-1 disables)")
+	coldStartGrowth := fs.Float64("max-cold-start-growth", policy.MaxColdStartGrowth*100, "allowed cold-start growth in percent (-1 disables)")
+	fs.Int64Var(&policy.MaxSize, "max-size", 0, "size budget in bytes (0 disables)")
+	fs.DurationVar(&policy.MaxColdStart, "max-cold-start", 0, "cold-start budget (0 disables)")
+	fs.DurationVar(&policy.MinTimeDelta, "min-time-delta", policy.MinTimeDelta, "ignore timing regressions smaller than this")
+	rounds := fs.Int("rounds", 20, "measurement rounds per version")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "usage: plugingate [flags] PREVIOUS.wasm NEW.wasm")
+		return 2
+	}
+	policy.MaxSizeGrowth = percent(*sizeGrowth)
+	policy.MaxValidateGrowth = percent(*validateGrowth)
+	policy.MaxColdStartGrowth = percent(*coldStartGrowth)
+
+	var report GateReport
+	var err error
+	if report.Previous, err = profileFunc(fs.Arg(0), *rounds); err != nil {
+		fmt.Fprintf(stderr, "plugingate: profiling %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	if report.New, err = profileFunc(fs.Arg(1), *rounds); err != nil {
+		fmt.Fprintf(stderr, "plugingate: profiling %s: %v\n", fs.Arg(1), err)
+		return 1
+	}
+	report.Violations = policy.Check(report.Previous, report.New)
+	if report.Violations == nil {
+		report.Violations = []Violation{}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(stdout, report)
+	}
+
+	if len(report.Violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// percent converts a flag value in percent to a fraction, keeping negative
+// values (disabled checks) negative.
+func percent(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return v / 100
+}
+
+// printReport writes a human-readable comparison.
+func printReport(w io.Writer, r GateReport) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tPREVIOUS\tNEW")
+	fmt.Fprintf(tw, "%s\t%d\t%d\n", MetricSize, r.Previous.Size, r.New.Size)
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", MetricValidate, r.Previous.Validate, r.New.Validate)
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", MetricColdStart, r.Previous.ColdStart, r.New.ColdStart)
+	tw.Flush()
+
+	if len(r.Violations) == 0 {
+		fmt.Fprintln(w, "PASS")
+		return
+	}
+	for _, v := range r.Violations {
+		fmt.Fprintf(w, "FAIL %s: %s\n", v.Metric, v.Reason)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("plugingate", func() {
+	var (
+		stdout, stderr *bytes.Buffer
+		profiles       map[string]runtime.StartupProfile
+		rounds         int
+	)
+
+	BeforeEach(func() {
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		profiles = map[string]runtime.StartupProfile{
+			"v1.wasm":  {Size: 1000, Validate: time.Millisecond, ColdStart: 5 * time.Millisecond},
+			"v2.wasm":  {Size: 1050, Validate: time.Millisecond, ColdStart: 5 * time.Millisecond},
+			"big.wasm": {Size: 2000, Validate: time.Millisecond, ColdStart: 5 * time.Millisecond},
+		}
+
+		original := profileFunc
+		profileFunc = func(path string, n int) (runtime.StartupProfile, error) {
+			rounds = n
+			profile, ok := profiles[path]
+			if !ok {
+				return runtime.StartupProfile{}, errors.New("plugin file not found")
+			}
+			return profile, nil
+		}
+		DeferCleanup(func() { profileFunc = original })
+	})
+
+	// =========================================================================
+	// TEST: Exit status
+	// Why: Publish pipelines act on the exit status alone.
+	// =========================================================================
+	It("should pass within limits", func() {
+		Expect(run([]string{"v1.wasm", "v2.wasm"}, stdout, stderr)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring("PASS"))
+		Expect(rounds).To(Equal(20))
+	})
+
+	It("should fail on regressions", func() {
+		Expect(run([]string{"v1.wasm", "big.wasm"}, stdout, stderr)).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring("FAIL size: grew 100.0%, limit 10.0%"))
+	})
+
+	It("should apply limits from flags", func() {
+		Expect(run([]string{"--max-size-growth", "200", "--rounds", "3", "v1.wasm", "big.wasm"}, stdout, stderr)).To(Equal(0))
+		Expect(rounds).To(Equal(3))
+
+		Expect(run([]string{"--max-size-growth", "1", "v1.wasm", "v2.wasm"}, stdout, stderr)).To(Equal(1))
+	})
+
+	It("should print a JSON report", func() {
+		Expect(run([]string{"--json", "v1.wasm", "big.wasm"}, stdout, stderr)).To(Equal(1))
+
+		var report GateReport
+		Expect(json.Unmarshal(stdout.Bytes(), &report)).To(Succeed())
+		Expect(report.New.Size).To(Equal(int64(2000)))
+		Expect(report.Violations).To(HaveLen(1))
+	})
+
+	It("should report profiling errors", func() {
+		Expect(run([]string{"v1.wasm", "missing.wasm"}, stdout, stderr)).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("profiling missing.wasm"))
+	})
+
+	It("should require two plugins", func() {
+		Expect(run([]string{"v1.wasm"}, stdout, stderr)).To(Equal(2))
+	})
+})
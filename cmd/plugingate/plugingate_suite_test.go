@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestPlugingate bootstraps the Ginkgo test suite for the regression gate.
+// Run with: go test -v ./cmd/plugingate/...
+func TestPlugingate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Plugingate Suite")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// Policy bounds how much a new plugin version may regress.
+//
+// Growth limits are fractions of the previous version's value (0.1 = 10%).
+// A negative limit disables the check. Budgets are absolute and apply to
+// the new version alone; zero disables them.
+type Policy struct {
+	MaxSizeGrowth      float64
+	MaxValidateGrowth  float64
+	MaxColdStartGrowth float64
+
+	MaxSize      int64         // Size budget in bytes
+	MaxColdStart time.Duration // Cold-start budget
+
+	// MinTimeDelta ignores timing regressions smaller than this, since
+	// sub-millisecond timings vary more between runs than any threshold.
+	MinTimeDelta time.Duration
+}
+
+// DefaultPolicy is used unless flags override it.
+var DefaultPolicy = Policy{
+	MaxSizeGrowth:      0.10,
+	MaxValidateGrowth:  0.25,
+	MaxColdStartGrowth: 0.25,
+	MinTimeDelta:       time.Millisecond,
+}
+
+// Metric names used in reports.
+const (
+	MetricSize      = "size"
+	MetricValidate  = "validate"
+	MetricColdStart = "cold_start"
+)
+
+// Violation is a check the new version failed.
+type Violation struct {
+	Metric string `json:"metric"`
+	Reason string `json:"reason"`
+}
+
+// Check compares the new version's profile with the previous one and
+// returns every violated limit.
+func (p Policy) Check(older, newer runtime.StartupProfile) []Violation {
+	var violations []Violation
+	add := func(metric, format string, args ...interface{}) {
+		violations = append(violations, Violation{Metric: metric, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// Step 1: Relative growth against the previous version
+	if g := growth(float64(older.Size), float64(newer.Size)); p.MaxSizeGrowth >= 0 && g > p.MaxSizeGrowth {
+		add(MetricSize, "grew %.1f%%, limit %.1f%%", g*100, p.MaxSizeGrowth*100)
+	}
+	timings := []struct {
+		metric    string
+		old, new  time.Duration
+		maxGrowth float64
+	}{
+		{MetricValidate, older.Validate, newer.Validate, p.MaxValidateGrowth},
+		{MetricColdStart, older.ColdStart, newer.ColdStart, p.MaxColdStartGrowth},
+	}
+	for _, t := range timings {
+		if t.maxGrowth < 0 || t.new-t.old < p.MinTimeDelta {
+			continue
+		}
+		if g := growth(float64(t.old), float64(t.new)); g > t.maxGrowth {
+			add(t.metric, "grew %.1f%% (%s -> %s), limit %.1f%%", g*100, t.old, t.new, t.maxGrowth*100)
+		}
+	}
+
+	// Step 2: Absolute budgets
+	if p.MaxSize > 0 && newer.Size > p.MaxSize {
+		add(MetricSize, "%d bytes exceeds the %d byte budget", newer.Size, p.MaxSize)
+	}
+	if p.MaxColdStart > 0 && newer.ColdStart > p.MaxColdStart {
+		add(MetricColdStart, "%s exceeds the %s budget", newer.ColdStart, p.MaxColdStart)
+	}
+
+	return violations
+}
+
+// growth returns the relative change from older to newer. Growth from zero
+// counts as unbounded.
+func growth(older, newer float64) float64 {
+	if newer <= older {
+		return 0
+	}
+	if older == 0 {
+		return math.Inf(1)
+	}
+	return (newer - older) / older
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Policy", func() {
+	baseline := runtime.StartupProfile{Size: 10000, Validate: 2 * time.Millisecond, ColdStart: 10 * time.Millisecond}
+
+	metrics := func(violations []Violation) []string {
+		var out []string
+		for _, v := range violations {
+			out = append(out, v.Metric)
+		}
+		return out
+	}
+
+	It("should pass an unchanged plugin", func() {
+		Expect(DefaultPolicy.Check(baseline, baseline)).To(BeEmpty())
+	})
+
+	It("should pass improvements", func() {
+		better := runtime.StartupProfile{Size: 5000, Validate: time.Millisecond, ColdStart: 5 * time.Millisecond}
+		Expect(DefaultPolicy.Check(baseline, better)).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Regression thresholds
+	// Why: The gate is the last check before a plugin reaches the Fluid cache;
+	//      each metric must be judged against its own limit.
+	// =========================================================================
+	DescribeTable("growth limits",
+		func(newer runtime.StartupProfile, expected []string) {
+			Expect(metrics(DefaultPolicy.Check(baseline, newer))).To(Equal(expected))
+		},
+		Entry("size within 10%", runtime.StartupProfile{Size: 10900, Validate: 2 * time.Millisecond, ColdStart: 10 * time.Millisecond}, nil),
+		Entry("size over 10%", runtime.StartupProfile{Size: 11500, Validate: 2 * time.Millisecond, ColdStart: 10 * time.Millisecond}, []string{MetricSize}),
+		Entry("cold start over 25%", runtime.StartupProfile{Size: 10000, Validate: 2 * time.Millisecond, ColdStart: 15 * time.Millisecond}, []string{MetricColdStart}),
+		Entry("everything regressed", runtime.StartupProfile{Size: 20000, Validate: 4 * time.Millisecond, ColdStart: 20 * time.Millisecond},
+			[]string{MetricSize, MetricValidate, MetricColdStart}),
+	)
+
+	It("should ignore timing noise below MinTimeDelta", func() {
+		fast := runtime.StartupProfile{Size: 100, Validate: 10 * time.Microsecond, ColdStart: 100 * time.Microsecond}
+		noisy := fast
+		noisy.ColdStart = 300 * time.Microsecond // +200%, but only 0.2ms
+
+		Expect(DefaultPolicy.Check(fast, noisy)).To(BeEmpty())
+	})
+
+	It("should enforce absolute budgets", func() {
+		policy := DefaultPolicy
+		policy.MaxSize = 8000
+		policy.MaxColdStart = 5 * time.Millisecond
+
+		violations := policy.Check(baseline, baseline)
+		Expect(metrics(violations)).To(Equal([]string{MetricSize, MetricColdStart}))
+		Expect(violations[0].Reason).To(ContainSubstring("8000 byte budget"))
+	})
+
+	It("should skip disabled growth checks", func() {
+		policy := DefaultPolicy
+		policy.MaxSizeGrowth = -1
+
+		Expect(policy.Check(baseline, runtime.StartupProfile{Size: 50000, Validate: 2 * time.Millisecond, ColdStart: 10 * time.Millisecond})).To(BeEmpty())
+	})
+})
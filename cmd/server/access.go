@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+// accessEntry collects what the handlers of a request tell the access log.
+type accessEntry struct {
+	plugin string        // Plugin the request named, if any
+	code   apierror.Code // Code of the problem written, if any
+}
+
+// accessKey is the context key of a request's *accessEntry.
+type accessKey struct{}
+
+// notePlugin records the plugin a request named in its access log entry.
+// It does nothing for requests that are not logged.
+func notePlugin(ctx context.Context, plugin string) {
+	if entry, ok := ctx.Value(accessKey{}).(*accessEntry); ok {
+		entry.plugin = plugin
+	}
+}
+
+// accessLogged wraps the HTTP API. Every request gets an ID: the client's
+// X-Request-ID if usable, or a new one, returned in the response header of
+// the same name and in problem details. The ID replaces the request header,
+// so that handlers, and peers a request is forwarded to, see the same one.
+//
+// With logging enabled, each request is logged once it completes, with its
+// plugin, the size of its body, its duration and status, and the code of
+// the error it failed with.
+func (s *Server) accessLogged(next http.Handler, logRequests bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := requestIDOf(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, requestID)
+		r = r.Clone(r.Context())
+		r.Header.Set(RequestIDHeader, requestID)
+		if !logRequests {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entry := &accessEntry{}
+		body := &countingBody{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		recorder := &accessRecorder{ResponseWriter: w, entry: entry}
+		started := time.Now()
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessKey{}, entry)))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := []slog.Attr{
+			slog.String("request_id", requestID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.Int64("input_bytes", body.n),
+			slog.Int64("output_bytes", recorder.n),
+		}
+		if entry.plugin != "" {
+			attrs = append(attrs, slog.String("plugin", entry.plugin))
+		}
+		if entry.code != "" {
+			attrs = append(attrs, slog.String("error", string(entry.code)))
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		s.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// countingBody counts the bytes a handler reads of a request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// accessRecorder records the status and size of a response. It passes
+// flushes and hijacks through, for event streams and WebSockets.
+type accessRecorder struct {
+	http.ResponseWriter
+	entry  *accessEntry
+	status int
+	n      int64
+}
+
+func (w *accessRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *accessRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *accessRecorder) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
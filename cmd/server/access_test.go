@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Request log", func() {
+	var (
+		srv     *Server
+		logs    *bytes.Buffer
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		srv = NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		logs = &bytes.Buffer{}
+		srv.logger = slog.New(slog.NewJSONHandler(logs, nil))
+		handler = srv.accessLogged(http.HandlerFunc(srv.handleRun), true)
+	})
+
+	logged := func() map[string]interface{} {
+		var entry map[string]interface{}
+		Expect(json.Unmarshal(logs.Bytes(), &entry)).To(Succeed(), logs.String())
+		return entry
+	}
+
+	// =========================================================================
+	// TEST: Correlation
+	// Why: Support finds a failed request in the logs by the ID the client
+	//      got back; the response header, the problem, and the log entry
+	//      must all carry the same one.
+	// =========================================================================
+	It("should tag the response, the problem, and the log entry with the client's ID", func() {
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "nonexistent"}`))
+		req.Header.Set(RequestIDHeader, "req-42")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Header().Get(RequestIDHeader)).To(Equal("req-42"))
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+		Expect(problem.RequestID).To(Equal("req-42"))
+
+		entry := logged()
+		Expect(entry).To(HaveKeyWithValue("msg", "request"))
+		Expect(entry).To(HaveKeyWithValue("request_id", "req-42"))
+		Expect(entry).To(HaveKeyWithValue("method", "POST"))
+		Expect(entry).To(HaveKeyWithValue("path", "/run"))
+		Expect(entry).To(HaveKeyWithValue("status", BeNumerically("==", http.StatusNotFound)))
+		Expect(entry).To(HaveKeyWithValue("plugin", "nonexistent"))
+		Expect(entry).To(HaveKeyWithValue("error", "plugin_not_found"))
+		Expect(entry).To(HaveKeyWithValue("input_bytes", BeNumerically("==", len(`{"plugin": "nonexistent"}`))))
+		Expect(entry).To(HaveKeyWithValue("output_bytes", BeNumerically("==", rec.Body.Len())))
+		Expect(entry).To(HaveKey("duration_ms"))
+	})
+
+	It("should generate an ID for requests without a usable one", func() {
+		req := httptest.NewRequest(http.MethodGet, "/run", nil)
+		req.Header.Set(RequestIDHeader, "not usable")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(RequestIDHeader)
+		Expect(id).To(MatchRegexp(`^[0-9a-f]{16}$`))
+		Expect(logged()).To(HaveKeyWithValue("request_id", id))
+		Expect(logged()).To(HaveKeyWithValue("error", "method_not_allowed"))
+		Expect(logged()).NotTo(HaveKey("plugin"))
+	})
+
+	It("should tag requests without logging them when logging is off", func() {
+		handler = srv.accessLogged(http.HandlerFunc(srv.handleRun), false)
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "nonexistent"}`))
+		req.Header.Set(RequestIDHeader, "req-43")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		Expect(rec.Header().Get(RequestIDHeader)).To(Equal("req-43"))
+		Expect(rec.Body.String()).To(ContainSubstring(`"request_id":"req-43"`))
+		Expect(logs.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// adminAPI serves the operational endpoints under /admin, apart from the
+// tenant-facing API: every request needs admin rights, checked once for
+// all of them.
+type adminAPI struct {
+	server  *Server
+	config  *config.Config // The configuration the server started with
+	watcher *fluid.Watcher // Rescanned by POST /admin/reload; nil without a plugin directory
+	level   *slog.LevelVar // Level of the server's logger
+	mux     *http.ServeMux
+}
+
+// newAdminAPI returns the admin API of s, started with cfg, whose logger
+// writes records at level and above.
+func newAdminAPI(s *Server, cfg *config.Config, watcher *fluid.Watcher, level *slog.LevelVar) *adminAPI {
+	a := &adminAPI{server: s, config: cfg, watcher: watcher, level: level, mux: http.NewServeMux()}
+	a.mux.HandleFunc("/admin/reload", a.handleReload)
+	a.mux.HandleFunc("/admin/evict", a.handleEvict)
+	a.mux.HandleFunc("/admin/pools", a.handlePools)
+	a.mux.HandleFunc("/admin/config", a.handleConfig)
+	a.mux.HandleFunc("/admin/log-level", a.handleLogLevel)
+	return a
+}
+
+// ServeHTTP serves the admin endpoints to requests with admin rights.
+func (a *adminAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.server.admin(w, r) {
+		return
+	}
+	a.mux.ServeHTTP(w, r)
+}
+
+// ReloadedBuild is a plugin build POST /admin/reload found changed.
+type ReloadedBuild struct {
+	Plugin string `json:"plugin"` // name@version, or the name of an unversioned build
+	Change string `json:"change"` // created, updated, or removed
+}
+
+// ReloadResponse is the response of POST /admin/reload.
+type ReloadResponse struct {
+	Changes []ReloadedBuild `json:"changes"`
+}
+
+// handleReload handles POST /admin/reload
+//
+// Rescans the plugin directory at once rather than at the next watch
+// interval, and evicts what the server derived from the builds replaced or
+// deleted since the previous scan, waiting for their calls to finish.
+func (a *adminAPI) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if a.watcher == nil {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "reloading needs a local, memory, or Fluid store")
+		return
+	}
+
+	changes := a.watcher.Scan()
+	resp := ReloadResponse{Changes: make([]ReloadedBuild, 0, len(changes))}
+	var wg sync.WaitGroup
+	for _, change := range changes {
+		resp.Changes = append(resp.Changes, ReloadedBuild{
+			Plugin: fluid.Reference(change.Name, change.Version),
+			Change: change.Op.String(),
+		})
+		wg.Add(1)
+		go func(change fluid.Change) {
+			defer wg.Done()
+			a.server.reload(change)
+		}(change)
+	}
+	wg.Wait()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleEvict handles POST /admin/evict
+//
+// Evicts the pools of one plugin, or all of them, whatever the eviction
+// policy, e.g. to free memory before a batch of other plugins runs.
+func (a *adminAPI) handleEvict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	s := a.server
+
+	var req EvictRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, apierror.CodeInvalidRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	match := func(poolUse) bool { return true }
+	if req.Plugin != "" {
+		if err := checkPluginName(req.Plugin); err != nil {
+			writeExecutionError(w, r, err)
+			return
+		}
+		name, version := fluid.ParseReference(req.Plugin)
+		match = func(use poolUse) bool { return pluginNameFromPath(use.path) == name }
+		if version != "" {
+			path, err := s.store.Resolve(req.Plugin)
+			if err != nil {
+				writeError(w, r, apierror.CodePluginNotFound, fmt.Sprintf("plugin not found: %s", req.Plugin))
+				return
+			}
+			match = func(use poolUse) bool { return use.path == path }
+		}
+	}
+
+	resp := EvictResponse{Evicted: []EvictedPool{}}
+	for _, use := range s.poolUses() {
+		if !match(use) {
+			continue
+		}
+		if evicted, ok := s.evict(use, evictManual); ok {
+			resp.Evicted = append(resp.Evicted, evicted)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AdminPool is a plugin build's pool as GET /admin/pools lists it.
+type AdminPool struct {
+	Plugin   string             `json:"plugin"`
+	LastUsed time.Time          `json:"last_used"` // Last checkout by a request
+	Stats    runtime.PoolStats  `json:"stats"`
+	Memory   runtime.PoolMemory `json:"memory"`
+}
+
+// handlePools handles GET /admin/pools
+//
+// Lists every pool with its instances, memory, and last use, least
+// recently used first: the order in which eviction releases them.
+func (a *adminAPI) handlePools(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	uses := a.server.poolUses()
+	pools := make([]AdminPool, 0, len(uses))
+	for _, use := range uses {
+		pools = append(pools, AdminPool{
+			Plugin:   pluginNameFromPath(use.path),
+			LastUsed: use.lastUsed,
+			Stats:    use.pool.Stats(),
+			Memory:   use.memory,
+		})
+	}
+	writeJSON(w, http.StatusOK, pools)
+}
+
+// handleConfig handles GET /admin/config
+//
+// Returns the configuration the server runs with, as a YAML file it would
+// accept, with credentials redacted.
+func (a *adminAPI) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	data, err := yaml.Marshal(a.config.Redacted())
+	if err != nil {
+		writeError(w, r, apierror.CodeInternal, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+// LogLevel is the body of PUT /admin/log-level and the response of both
+// its methods.
+type LogLevel struct {
+	Level string `json:"level"` // debug, info, warn, or error
+}
+
+// handleLogLevel handles GET and PUT /admin/log-level
+//
+// Reports or changes the level of the server's own log until the next
+// restart, e.g. to debug a problem without redeploying. Plugin log levels
+// are set per plugin with PUT /plugins/{name}/logging.
+func (a *adminAPI) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req LogLevel
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		var level slog.Level
+		switch req.Level {
+		case "debug", "info", "warn", "error":
+			level.UnmarshalText([]byte(req.Level))
+		default:
+			writeError(w, r, apierror.CodeInvalidRequest,
+				fmt.Sprintf("level must be debug, info, warn, or error, got %q", req.Level))
+			return
+		}
+		if level != a.level.Level() {
+			a.server.logger.LogAttrs(context.Background(), slog.LevelWarn, "server log level changed",
+				slog.String("from", strings.ToLower(a.level.Level().String())),
+				slog.String("to", req.Level))
+			a.level.Set(level)
+		}
+	default:
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, LogLevel{Level: strings.ToLower(a.level.Level().String())})
+}
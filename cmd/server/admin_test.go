@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Admin API", func() {
+	const token = "secret"
+	var (
+		srv   *Server
+		dir   string
+		cfg   *config.Config
+		level *slog.LevelVar
+		logs  *bytes.Buffer
+		admin *adminAPI
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(dir))
+		srv.adminToken = token
+		logs = &bytes.Buffer{}
+		srv.logger = slog.New(slog.NewJSONHandler(logs, nil))
+		cfg = config.Default()
+		level = new(slog.LevelVar)
+		admin = newAdminAPI(srv, cfg, fluid.NewWatcher(dir, 0), level)
+	})
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != "" {
+			reader = bytes.NewBufferString(body)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// =========================================================================
+	// TEST: Admin rights
+	// Why: The admin endpoints change what the server holds and reveal how
+	//      it is configured; tenants calling /run must never reach them.
+	// =========================================================================
+	It("should require admin rights on every endpoint", func() {
+		for _, path := range []string{"/admin/reload", "/admin/evict", "/admin/pools", "/admin/config", "/admin/log-level"} {
+			rec := httptest.NewRecorder()
+			admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized), path)
+		}
+
+		srv.adminToken = ""
+		Expect(call(http.MethodGet, "/admin/pools", "").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should not serve unknown admin paths", func() {
+		Expect(call(http.MethodGet, "/admin/unknown", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	// =========================================================================
+	// TEST: POST /admin/reload
+	// Why: An operator who just replaced a build must not wait for the next
+	//      watch interval, or rely on inotify, which FUSE mounts lack.
+	// =========================================================================
+	It("should report and apply the builds changed since the previous scan", func() {
+		reload := func() ReloadResponse {
+			rec := call(http.MethodPost, "/admin/reload", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var resp ReloadResponse
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			return resp
+		}
+		wasm := filepath.Join(dir, "hello", "hello.wasm")
+		Expect(os.MkdirAll(filepath.Dir(wasm), 0o755)).To(Succeed())
+
+		Expect(reload().Changes).To(BeEmpty())
+
+		Expect(os.WriteFile(wasm, []byte("v1"), 0o644)).To(Succeed())
+		Expect(reload().Changes).To(Equal([]ReloadedBuild{{Plugin: "hello", Change: "created"}}))
+
+		Expect(os.WriteFile(wasm, []byte("v2, longer"), 0o644)).To(Succeed())
+		Expect(reload().Changes).To(Equal([]ReloadedBuild{{Plugin: "hello", Change: "updated"}}))
+
+		Expect(os.Remove(wasm)).To(Succeed())
+		Expect(reload().Changes).To(Equal([]ReloadedBuild{{Plugin: "hello", Change: "removed"}}))
+	})
+
+	It("should not reload stores without a plugin directory", func() {
+		admin = newAdminAPI(srv, cfg, nil, level)
+		Expect(call(http.MethodPost, "/admin/reload", "").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(call(http.MethodGet, "/admin/reload", "").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should list the pools", func() {
+		rec := call(http.MethodGet, "/admin/pools", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`[]`))
+	})
+
+	// =========================================================================
+	// TEST: GET /admin/config
+	// Why: Support compares what a server runs with against what was meant
+	//      to be deployed; the dump must load as a config file, and must
+	//      not leak the credentials it holds.
+	// =========================================================================
+	It("should dump the configuration with credentials redacted", func() {
+		cfg.Admin.Token = "s3cr3t-admin"
+		cfg.Pool.MaxSize = 4
+
+		rec := call(http.MethodGet, "/admin/config", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/yaml"))
+		Expect(rec.Body.String()).NotTo(ContainSubstring("s3cr3t-admin"))
+
+		var dumped config.Config
+		Expect(yaml.Unmarshal(rec.Body.Bytes(), &dumped)).To(Succeed())
+		Expect(dumped.Admin.Token).To(Equal(config.RedactedValue))
+		Expect(dumped.Pool.MaxSize).To(Equal(4))
+		Expect(dumped.Execution.Timeout).To(Equal(cfg.Execution.Timeout))
+	})
+
+	// =========================================================================
+	// TEST: /admin/log-level
+	// Why: Debug logging is too verbose to run with all the time, and
+	//      needed exactly when something goes wrong; restarting to turn it
+	//      on would lose the state being debugged.
+	// =========================================================================
+	It("should report and change the server's log level", func() {
+		rec := call(http.MethodGet, "/admin/log-level", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"level": "info"}`))
+
+		rec = call(http.MethodPut, "/admin/log-level", `{"level": "debug"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"level": "debug"}`))
+		Expect(level.Level()).To(Equal(slog.LevelDebug))
+		Expect(logs.String()).To(ContainSubstring("server log level changed"))
+
+		Expect(call(http.MethodPut, "/admin/log-level", `{"level": "verbose"}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(call(http.MethodPut, "/admin/log-level", `{`).Code).To(Equal(http.StatusBadRequest))
+		Expect(call(http.MethodDelete, "/admin/log-level", "").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(level.Level()).To(Equal(slog.LevelDebug))
+	})
+})
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+// grpcAuthorizationKey is the metadata counterpart of the Authorization
+// header.
+const grpcAuthorizationKey = "authorization"
+
+// authCredentials are what a request authenticates with.
+type authCredentials struct {
+	apiKey string // From the X-API-Key header
+	bearer string // From the Authorization header
+}
+
+// principal is an authenticated client and what it may do.
+type principal struct {
+	Name    string
+	Plugins []string // Plugins it may run; "*" allows every plugin
+	Admin   bool     // Whether it may call the admin endpoints
+	Tenant  string   // Tenant all its requests act on behalf of; empty lets it choose
+}
+
+// canRun reports whether p may run plugin, in any version.
+func (p *principal) canRun(plugin string) bool {
+	return slices.Contains(p.Plugins, "*") || slices.Contains(p.Plugins, plugin)
+}
+
+// authenticator checks one kind of credentials. It returns a nil principal
+// and no error for credentials that are not of its kind, so that the next
+// authenticator may try them.
+type authenticator interface {
+	authenticate(ctx context.Context, creds authCredentials) (*principal, error)
+}
+
+// apiKeyAuthenticator authenticates static API keys.
+type apiKeyAuthenticator struct {
+	keys map[string]*principal // By API key
+}
+
+// authenticate looks up the API key of creds.
+func (a *apiKeyAuthenticator) authenticate(_ context.Context, creds authCredentials) (*principal, error) {
+	if creds.apiKey == "" {
+		return nil, nil
+	}
+	for key, p := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(creds.apiKey)) == 1 {
+			return p, nil
+		}
+	}
+	if creds.bearer != "" {
+		// The key may only be meant for rate limiting, next to a token
+		return nil, nil
+	}
+	return nil, errors.New("unknown API key")
+}
+
+// authConfig is the AUTH_CONFIG_FILE format: static API keys, each read
+// from an environment variable, and the issuer of JWT bearer tokens, with
+// the plugins each client may run, whether it may call the admin
+// endpoints, and the tenant it is bound to, if any.
+//
+//	{
+//	  "keys": {
+//	    "payments": {"key_env": "PAYMENTS_API_KEY", "plugins": ["billing", "hello"], "tenant": "acme"},
+//	    "deploy": {"key_env": "DEPLOY_API_KEY", "admin": true}
+//	  },
+//	  "jwt": {"jwks_url": "https://idp.example.com/.well-known/jwks.json", "issuer": "https://idp.example.com", "audience": "wasm-plugins"}
+//	}
+type authConfig struct {
+	Keys map[string]authKey `json:"keys"` // By client name
+	JWT  *jwtConfig         `json:"jwt"`
+}
+
+// authKey is a static API key and what its client may do.
+type authKey struct {
+	KeyEnv  string   `json:"key_env"`
+	Plugins []string `json:"plugins"`
+	Admin   bool     `json:"admin"`
+	Tenant  string   `json:"tenant"`
+}
+
+// auth authenticates API requests with a chain of authenticators and
+// authorizes them by the plugins and endpoints their principal may use.
+type auth struct {
+	authenticators []authenticator
+}
+
+// loadAuth reads AUTH_CONFIG_FILE at path. getenv looks up the API keys.
+func loadAuth(path string, getenv func(string) string) (*auth, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg authConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	a := &auth{}
+	if len(cfg.Keys) > 0 {
+		keys := &apiKeyAuthenticator{keys: make(map[string]*principal, len(cfg.Keys))}
+		for name, key := range cfg.Keys {
+			if key.KeyEnv == "" {
+				return nil, fmt.Errorf("keys.%s: key_env is required", name)
+			}
+			value := getenv(key.KeyEnv)
+			if value == "" {
+				return nil, fmt.Errorf("keys.%s: %s is not set", name, key.KeyEnv)
+			}
+			if other, ok := keys.keys[value]; ok {
+				return nil, fmt.Errorf("keys.%s: same key as keys.%s", name, other.Name)
+			}
+			for _, plugin := range key.Plugins {
+				if plugin != "*" && !isValidPluginName(plugin) {
+					return nil, fmt.Errorf("keys.%s: invalid plugin name %q", name, plugin)
+				}
+			}
+			if key.Tenant != "" && !validID(key.Tenant) {
+				return nil, fmt.Errorf("keys.%s: invalid tenant %q", name, key.Tenant)
+			}
+			keys.keys[value] = &principal{Name: name, Plugins: key.Plugins, Admin: key.Admin, Tenant: key.Tenant}
+		}
+		a.authenticators = append(a.authenticators, keys)
+	}
+	if cfg.JWT != nil {
+		jwt, err := newJWTAuthenticator(*cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.authenticators = append(a.authenticators, jwt)
+	}
+	if len(a.authenticators) == 0 {
+		return nil, errors.New("neither keys nor jwt is configured")
+	}
+	return a, nil
+}
+
+// authenticate returns the principal of creds, or a CodeUnauthorized
+// error if no authenticator accepts them.
+func (a *auth) authenticate(ctx context.Context, creds authCredentials) (*principal, error) {
+	for _, authn := range a.authenticators {
+		p, err := authn.authenticate(ctx, creds)
+		if err != nil {
+			return nil, apierror.Wrap(apierror.CodeUnauthorized, err)
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	if creds == (authCredentials{}) {
+		return nil, apierror.Wrap(apierror.CodeUnauthorized, errors.New("an API key or bearer token is required"))
+	}
+	return nil, apierror.Wrap(apierror.CodeUnauthorized, errors.New("unsupported credentials"))
+}
+
+// principalKey is the context key of a request's principal.
+type principalKey struct{}
+
+// withPrincipal returns ctx carrying the principal a request
+// authenticated as.
+func withPrincipal(ctx context.Context, p *principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalOf returns the principal set by withPrincipal. Requests without
+// one, like scheduled and batch runs, act on behalf of the server.
+func principalOf(ctx context.Context) (*principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*principal)
+	return p, ok
+}
+
+// adminPrincipal is the principal of requests carrying ADMIN_TOKEN.
+var adminPrincipal = &principal{Name: "admin", Plugins: []string{"*"}, Admin: true}
+
+// authenticateRequest returns the principal of the request's credentials.
+// ADMIN_TOKEN keeps working alongside the configured authenticators.
+func (s *Server) authenticateRequest(ctx context.Context, creds authCredentials) (*principal, error) {
+	if s.adminToken != "" && subtle.ConstantTimeCompare([]byte(creds.bearer), []byte(s.adminToken)) == 1 {
+		return adminPrincipal, nil
+	}
+	return s.auth.authenticate(ctx, creds)
+}
+
+// checkPluginAccess rejects a call of plugin by a principal not allowed to
+// run it.
+func (s *Server) checkPluginAccess(ctx context.Context, plugin string) error {
+	if p, ok := principalOf(ctx); ok && !p.canRun(plugin) {
+		return apierror.Wrap(apierror.CodeForbidden, fmt.Errorf("%s may not run plugin %s", p.Name, plugin))
+	}
+	return nil
+}
+
+// credentialsOf returns the credentials of an HTTP request.
+func credentialsOf(r *http.Request) authCredentials {
+	bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return authCredentials{apiKey: r.Header.Get(APIKeyHeader), bearer: bearer}
+}
+
+// authenticated is middleware rejecting HTTP requests without valid
+// credentials when authentication is configured, and attaching the
+// principal of the others to their context.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next(w, r)
+			return
+		}
+		p, err := s.authenticateRequest(r.Context(), credentialsOf(r))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeExecutionError(w, r, err)
+			return
+		}
+		next(w, r.WithContext(withPrincipal(r.Context(), p)))
+	}
+}
+
+// debugOnly is middleware restricting a /debug endpoint to requests with
+// admin rights, as s.admin checks them. The endpoints expose plugin inputs
+// and memory, and how the server is set up, so they are no more open than
+// /admin.
+func (s *Server) debugOnly(next http.HandlerFunc) http.HandlerFunc {
+	return s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		if !s.admin(w, r) {
+			return
+		}
+		next(w, r)
+	})
+}
+
+// authInterceptor is the gRPC counterpart of authenticated, covering
+// every method.
+func (s *Server) authInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if s.auth == nil {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	bearer, _ := strings.CutPrefix(firstValue(md, grpcAuthorizationKey), "Bearer ")
+	p, err := s.authenticateRequest(ctx, authCredentials{apiKey: firstValue(md, grpcAPIKeyKey), bearer: bearer})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return handler(withPrincipal(ctx, p), req)
+}
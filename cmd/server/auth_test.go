@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// signJWT returns a token of claims signed with key by RS256 or ES256.
+func signJWT(key crypto.Signer, kid string, claims map[string]any) string {
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+var _ = Describe("Authentication", func() {
+	var (
+		rsaKey  *rsa.PrivateKey
+		ecKey   *ecdsa.PrivateKey
+		jwks    *httptest.Server
+		fetches int
+		stall   chan struct{} // If set, JWKS fetches signal it and wait on it
+		srv     *Server
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+		fetches = 0
+		stall = nil
+		jwks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches++
+			if stall != nil {
+				stall <- struct{}{}
+				<-stall
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+				{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			}})
+		}))
+		DeferCleanup(jwks.Close)
+
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		// Not a valid module: authorized runs fail to load it
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+		config := filepath.Join(GinkgoT().TempDir(), "auth.json")
+		Expect(os.WriteFile(config, []byte(`{
+			"keys": {
+				"payments": {"key_env": "PAYMENTS_API_KEY", "plugins": ["billing"]},
+				"deploy": {"key_env": "DEPLOY_API_KEY", "plugins": ["*"], "admin": true}
+			},
+			"jwt": {"jwks_url": "`+jwks.URL+`", "issuer": "https://idp.example.com", "audience": "wasm-plugins"}
+		}`), 0644)).To(Succeed())
+		env := map[string]string{"PAYMENTS_API_KEY": "pay-key", "DEPLOY_API_KEY": "deploy-key"}
+
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.adminToken = "secret"
+		srv.auth, err = loadAuth(config, func(name string) string { return env[name] })
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Now()
+		srv.auth.authenticators[1].(*jwtAuthenticator).now = func() time.Time { return now }
+	})
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":     "reports",
+			"iss":     "https://idp.example.com",
+			"aud":     []string{"wasm-plugins"},
+			"exp":     now.Add(time.Hour).Unix(),
+			"plugins": []string{"hello"},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	run := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello", "input": 1}`))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		srv.authenticated(srv.handleRun)(rec, req)
+		return rec
+	}
+
+	codeOf := func(rec *httptest.ResponseRecorder) apierror.Code {
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+		return problem.Code
+	}
+
+	// =========================================================================
+	// TEST: Authentication
+	// Why: With AUTH_CONFIG_FILE set, only clients presenting a configured
+	//      API key or a valid token may reach the API at all.
+	// =========================================================================
+	DescribeTable("should reject requests without valid credentials",
+		func(header string, value func() string) {
+			rec := run(header, value())
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rec.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+			Expect(codeOf(rec)).To(Equal(apierror.CodeUnauthorized))
+		},
+		Entry("without credentials", "", func() string { return "" }),
+		Entry("with an unknown API key", APIKeyHeader, func() string { return "guess" }),
+		Entry("with an opaque bearer token", "Authorization", func() string { return "Bearer guess" }),
+		Entry("with an expired token", "Authorization", func() string {
+			return "Bearer " + signJWT(rsaKey, "rsa-1", claims(map[string]any{"exp": now.Add(-time.Hour).Unix()}))
+		}),
+		Entry("with another issuer", "Authorization", func() string {
+			return "Bearer " + signJWT(rsaKey, "rsa-1", claims(map[string]any{"iss": "https://evil.example.com"}))
+		}),
+		Entry("with another audience", "Authorization", func() string {
+			return "Bearer " + signJWT(rsaKey, "rsa-1", claims(map[string]any{"aud": "billing"}))
+		}),
+		Entry("with a key ID of another key", "Authorization", func() string {
+			return "Bearer " + signJWT(rsaKey, "ec-1", claims(nil))
+		}),
+		Entry("with a forged signature", "Authorization", func() string {
+			other, _ := rsa.GenerateKey(rand.Reader, 2048)
+			return "Bearer " + signJWT(other, "rsa-1", claims(nil))
+		}),
+	)
+
+	It("should authenticate API keys and RS256 and ES256 tokens", func() {
+		// Authorized runs reach the plugin, which fails to load
+		rec := run(APIKeyHeader, "deploy-key")
+		Expect(codeOf(rec)).NotTo(BeElementOf(apierror.CodeUnauthorized, apierror.CodeForbidden))
+		rec = run("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(nil)))
+		Expect(codeOf(rec)).NotTo(BeElementOf(apierror.CodeUnauthorized, apierror.CodeForbidden))
+		rec = run("Authorization", "Bearer "+signJWT(ecKey, "ec-1", claims(map[string]any{"plugins": "billing hello"})))
+		Expect(codeOf(rec)).NotTo(BeElementOf(apierror.CodeUnauthorized, apierror.CodeForbidden))
+
+		// The JWKS is cached
+		Expect(fetches).To(Equal(1))
+	})
+
+	It("should not hold up requests while the JWKS is refreshed", func() {
+		rec := run("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(nil)))
+		Expect(codeOf(rec)).NotTo(Equal(apierror.CodeUnauthorized))
+
+		// A token of an unknown key refreshes the JWKS, which stalls
+		now = now.Add(jwksMinRefresh)
+		stall = make(chan struct{})
+		refreshed := make(chan int)
+		go func() {
+			refreshed <- run("Authorization", "Bearer "+signJWT(rsaKey, "rsa-2", claims(nil))).Code
+		}()
+		Eventually(stall).Should(Receive())
+
+		// Tokens of known keys are verified meanwhile
+		rec = run("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(nil)))
+		Expect(codeOf(rec)).NotTo(Equal(apierror.CodeUnauthorized))
+
+		stall <- struct{}{}
+		Eventually(refreshed).Should(Receive(Equal(http.StatusUnauthorized)))
+		Expect(fetches).To(Equal(2))
+	})
+
+	// =========================================================================
+	// TEST: Authorization
+	// Why: A client may only run the plugins it is allowed, and only admins
+	//      may upload, delete, or pause plugins.
+	// =========================================================================
+	It("should reject plugins outside the client's allowlist", func() {
+		rec := run(APIKeyHeader, "pay-key")
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(codeOf(rec)).To(Equal(apierror.CodeForbidden))
+
+		rec = run("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(map[string]any{"plugins": []string{"billing"}})))
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("should restrict admin endpoints to admins", func() {
+		send := func(header, value string) int {
+			req := httptest.NewRequest(http.MethodPost, "/maintenance", bytes.NewBufferString(`{"plugin": "hello", "duration_seconds": 60}`))
+			req.Header.Set(header, value)
+			rec := httptest.NewRecorder()
+			srv.authenticated(srv.handleMaintenance)(rec, req)
+			return rec.Code
+		}
+		Expect(send(APIKeyHeader, "pay-key")).To(Equal(http.StatusForbidden))
+		Expect(send("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(nil)))).To(Equal(http.StatusForbidden))
+		Expect(send("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(map[string]any{"admin": true})))).To(Equal(http.StatusCreated))
+		Expect(send(APIKeyHeader, "deploy-key")).To(Equal(http.StatusCreated))
+		// ADMIN_TOKEN keeps working
+		Expect(send("Authorization", "Bearer secret")).To(Equal(http.StatusCreated))
+	})
+
+	It("should bind clients to their tenant", func() {
+		send := func(tenant string) int {
+			req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello", "input": 1}`))
+			req.Header.Set("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(map[string]any{"tenant": "acme"})))
+			req.Header.Set(TenantHeader, tenant)
+			rec := httptest.NewRecorder()
+			srv.authenticated(srv.handleRun)(rec, req)
+			return rec.Code
+		}
+		Expect(send("globex")).To(Equal(http.StatusForbidden))
+		Expect(send("acme")).NotTo(Equal(http.StatusForbidden))
+		Expect(send("")).NotTo(Equal(http.StatusForbidden))
+
+		rec := run("Authorization", "Bearer "+signJWT(rsaKey, "rsa-1", claims(map[string]any{"tenant": "team a"})))
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+		ctx := withPrincipal(context.Background(), &principal{Name: "payments", Tenant: "acme"})
+		call, err := callInfoOf(ctx, "req-1", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(call.Tenant).To(Equal("acme"))
+	})
+
+	It("should let scheduled and batch runs through", func() {
+		Expect(srv.checkPluginAccess(context.Background(), "hello")).To(Succeed())
+	})
+
+	DescribeTable("should reject invalid configuration",
+		func(body string) {
+			path := filepath.Join(GinkgoT().TempDir(), "auth.json")
+			Expect(os.WriteFile(path, []byte(body), 0644)).To(Succeed())
+			_, err := loadAuth(path, func(string) string { return "" })
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("without authenticators", `{}`),
+		Entry("with an unset key", `{"keys": {"payments": {"key_env": "PAYMENTS_API_KEY"}}}`),
+		Entry("with a JWKS path", `{"jwt": {"jwks_url": "/etc/jwks.json"}}`),
+	)
+})
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// BatchCaller is the caller of batch runs, as plugins see it in their
+// execution context.
+const BatchCaller = "batch"
+
+// BatchResultSuffix is appended to a batch's input path to name the file
+// its results are written to.
+const BatchResultSuffix = ".results.jsonl"
+
+// maxBatchLine bounds a line of batch input.
+const maxBatchLine = 1 << 20
+
+// maxBatchHistory bounds how many finished batches are remembered.
+const maxBatchHistory = 100
+
+// BatchState is the state of a batch.
+type BatchState string
+
+const (
+	BatchRunning   BatchState = "running"
+	BatchCompleted BatchState = "completed" // Every line was run; some may have failed
+	BatchFailed    BatchState = "failed"    // Reading the input or writing results failed
+	BatchCancelled BatchState = "cancelled"
+)
+
+// BatchRequest is the JSON request body for POST /batches.
+type BatchRequest struct {
+	Plugin string `json:"plugin"` // Plugin name (e.g., "hello" or "hello@1.2.0")
+	Input  string `json:"input"`  // Path of the input file, relative to BATCH_DIR
+
+	// Concurrency is how many lines run at once; zero or more than the
+	// server allows means the server's maximum
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// TimeoutMs shortens the server's execution timeout for each line
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+// BatchInfo describes a batch and its progress in GET /batches.
+type BatchInfo struct {
+	ID          string     `json:"id"`
+	Plugin      string     `json:"plugin"`
+	Input       string     `json:"input"`
+	Output      string     `json:"output"`
+	State       BatchState `json:"state"`
+	Concurrency int        `json:"concurrency"`
+
+	// Progress: bytes of the input read so far, and the lines run
+	SizeBytes int64 `json:"size_bytes"`
+	BytesRead int64 `json:"bytes_read"`
+	Processed int   `json:"processed"`
+	Succeeded int   `json:"succeeded"`
+	Failed    int   `json:"failed"`
+
+	// Retries counts runs repeated because the server was saturated or
+	// the plugin paused for maintenance
+	Retries int `json:"retries,omitempty"`
+
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitzero"`
+	Error    string    `json:"error,omitempty"` // Why the batch failed
+}
+
+// BatchResult is a line of a batch's result file: the outcome of the input
+// line of the same number, counted from 1. Results are written as runs
+// finish, so they need not be in input order.
+type BatchResult struct {
+	Line   int               `json:"line"`
+	Result *Response         `json:"result,omitempty"`
+	Error  *apierror.Problem `json:"error,omitempty"`
+}
+
+// batch is a batch and the means to stop it.
+type batch struct {
+	info   BatchInfo // Guarded by batchRunner.mu
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// batchRunner runs plugins over files of newline-delimited inputs under
+// dir, e.g. on the Fluid mount, writing each file's results beside it.
+// Batches live in memory: a restart abandons the running ones.
+type batchRunner struct {
+	server         *Server
+	dir            string
+	maxConcurrency int
+
+	// maxRetryDelay bounds the wait before retrying a run that was
+	// rejected for backpressure
+	maxRetryDelay time.Duration
+	now           func() time.Time
+
+	mu      sync.Mutex
+	batches map[string]*batch
+}
+
+// newBatchRunner creates a runner for inputs under dir, running at most
+// maxConcurrency lines of a batch at once.
+func newBatchRunner(server *Server, dir string, maxConcurrency int) *batchRunner {
+	return &batchRunner{
+		server:         server,
+		dir:            dir,
+		maxConcurrency: maxConcurrency,
+		maxRetryDelay:  time.Minute,
+		now:            time.Now,
+		batches:        make(map[string]*batch),
+	}
+}
+
+// inputPath returns the path of a batch input, which must be a relative
+// path staying within dir.
+func (br *batchRunner) inputPath(input string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(input))
+	if input == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("input must be a relative path within the batch directory"))
+	}
+	return filepath.Join(br.dir, clean), nil
+}
+
+// start validates a request and starts its batch.
+func (br *batchRunner) start(req BatchRequest) (BatchInfo, error) {
+	if err := checkPluginName(req.Plugin); err != nil {
+		return BatchInfo{}, err
+	}
+	if _, err := br.server.store.Resolve(req.Plugin); err != nil {
+		return BatchInfo{}, apierror.Wrap(apierror.CodePluginNotFound,
+			fmt.Errorf("plugin not found: %s", req.Plugin))
+	}
+	if req.Concurrency < 0 || req.TimeoutMs < 0 {
+		return BatchInfo{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("concurrency and timeout_ms must not be negative"))
+	}
+	concurrency := req.Concurrency
+	if concurrency == 0 || concurrency > br.maxConcurrency {
+		concurrency = br.maxConcurrency
+	}
+
+	path, err := br.inputPath(req.Input)
+	if err != nil {
+		return BatchInfo{}, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil || !stat.Mode().IsRegular() {
+		return BatchInfo{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			fmt.Errorf("input %s is not a readable file", req.Input))
+	}
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	for _, b := range br.batches {
+		if b.info.State == BatchRunning && filepath.Clean(b.info.Input) == filepath.Clean(req.Input) {
+			return BatchInfo{}, apierror.Wrap(apierror.CodeDuplicateRequest,
+				fmt.Errorf("batch %s is still processing %s", b.info.ID, req.Input))
+		}
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return BatchInfo{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			fmt.Errorf("input %s is not a readable file", req.Input))
+	}
+	out, err := os.Create(path + BatchResultSuffix)
+	if err != nil {
+		in.Close()
+		return BatchInfo{}, apierror.Wrap(apierror.CodeInternal,
+			fmt.Errorf("failed to create result file: %w", err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &batch{
+		info: BatchInfo{
+			ID:          requestIDOf(""),
+			Plugin:      req.Plugin,
+			Input:       req.Input,
+			Output:      req.Input + BatchResultSuffix,
+			State:       BatchRunning,
+			Concurrency: concurrency,
+			SizeBytes:   stat.Size(),
+			Started:     br.now().UTC(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	br.batches[b.info.ID] = b
+	br.prune()
+
+	go br.process(ctx, b, in, out, req.TimeoutMs)
+	return b.info, nil
+}
+
+// prune forgets the oldest finished batches beyond maxBatchHistory.
+// br.mu must be held.
+func (br *batchRunner) prune() {
+	var finished []*batch
+	for _, b := range br.batches {
+		if b.info.State != BatchRunning {
+			finished = append(finished, b)
+		}
+	}
+	if len(finished) <= maxBatchHistory {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].info.Finished.Before(finished[j].info.Finished) })
+	for _, b := range finished[:len(finished)-maxBatchHistory] {
+		delete(br.batches, b.info.ID)
+	}
+}
+
+// batchItem is a line of batch input to run.
+type batchItem struct {
+	line int
+	req  Request
+	err  error // Why the line could not be parsed
+}
+
+// process runs a batch to the end. The input is read only as fast as the
+// workers take lines, so memory stays bounded however large it is.
+func (br *batchRunner) process(ctx context.Context, b *batch, in, out *os.File, timeoutMs int) {
+	defer close(b.done)
+	defer in.Close()
+
+	items := make(chan batchItem)
+	results := make(chan BatchResult)
+	var workers sync.WaitGroup
+	for i := 0; i < b.info.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for item := range items {
+				results <- br.runItem(ctx, b, item)
+			}
+		}()
+	}
+
+	// Results are written by one goroutine, in the order runs finish
+	written := make(chan error, 1)
+	go func() {
+		w := bufio.NewWriter(out)
+		enc := json.NewEncoder(w)
+		var err error
+		for result := range results {
+			if err == nil {
+				err = enc.Encode(result)
+			}
+			br.mu.Lock()
+			b.info.Processed++
+			if result.Error == nil {
+				b.info.Succeeded++
+			} else {
+				b.info.Failed++
+			}
+			br.mu.Unlock()
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		written <- err
+	}()
+
+	readErr := br.read(ctx, b, in, timeoutMs, items)
+	close(items)
+	workers.Wait()
+	close(results)
+	writeErr := <-written
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	b.info.Finished = br.now().UTC()
+	switch {
+	case ctx.Err() != nil:
+		b.info.State = BatchCancelled
+	case readErr != nil:
+		b.info.State = BatchFailed
+		b.info.Error = fmt.Sprintf("failed to read input: %v", readErr)
+	case writeErr != nil:
+		b.info.State = BatchFailed
+		b.info.Error = fmt.Sprintf("failed to write results: %v", writeErr)
+	default:
+		b.info.State = BatchCompleted
+	}
+	br.server.logger.LogAttrs(context.Background(), slog.LevelInfo, "batch finished",
+		slog.String("batch", b.info.ID),
+		slog.String("plugin", b.info.Plugin),
+		slog.String("state", string(b.info.State)),
+		slog.Int("succeeded", b.info.Succeeded),
+		slog.Int("failed", b.info.Failed))
+}
+
+// read parses the input into items until it ends or ctx is done. Blank
+// lines are skipped but counted, so line numbers match the file.
+func (br *batchRunner) read(ctx context.Context, b *batch, in *os.File, timeoutMs int, items chan<- batchItem) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxBatchLine)
+	for line := 1; scanner.Scan(); line++ {
+		br.mu.Lock()
+		b.info.BytesRead += int64(len(scanner.Bytes())) + 1
+		br.mu.Unlock()
+
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		req, err := parseBatchLine(text)
+		req.Plugin = b.info.Plugin
+		req.TimeoutMs = timeoutMs
+		select {
+		case items <- batchItem{line: line, req: req, err: err}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	br.mu.Lock()
+	b.info.BytesRead = min(b.info.BytesRead, b.info.SizeBytes)
+	br.mu.Unlock()
+	return scanner.Err()
+}
+
+// parseBatchLine parses a line of batch input: a JSON number is the input
+// of process(), a string the text of process_bytes(), and an object holds
+// /run request fields other than plugin.
+func parseBatchLine(line []byte) (Request, error) {
+	var req Request
+	var err error
+	switch line[0] {
+	case '{':
+		err = json.Unmarshal(line, &req)
+	case '"':
+		req.Text = new(string)
+		err = json.Unmarshal(line, req.Text)
+	default:
+		err = json.Unmarshal(line, &req.Input)
+	}
+	if err != nil {
+		return Request{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			fmt.Errorf("line is not a number, string, or request object: %v", err))
+	}
+	return req, nil
+}
+
+// runItem runs a line, retrying while the server is saturated or the
+// plugin paused for maintenance, as long as the batch runs.
+func (br *batchRunner) runItem(ctx context.Context, b *batch, item batchItem) BatchResult {
+	if item.err != nil {
+		return BatchResult{Line: item.line, Error: apierror.New(apierror.CodeOf(item.err), item.err.Error())}
+	}
+
+	call := runtime.CallInfo{RequestID: fmt.Sprintf("%s-%d", b.info.ID, item.line), Caller: BatchCaller}
+	resp, err := br.server.runAdmitted(ctx, item.req, call, br.maxRetryDelay, func() {
+		br.mu.Lock()
+		b.info.Retries++
+		br.mu.Unlock()
+	})
+	if err != nil {
+		return BatchResult{Line: item.line, Error: apierror.New(apierror.CodeOf(err), err.Error())}
+	}
+	return BatchResult{Line: item.line, Result: &resp}
+}
+
+// list returns every batch remembered, newest first.
+func (br *batchRunner) list() []BatchInfo {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	infos := make([]BatchInfo, 0, len(br.batches))
+	for _, b := range br.batches {
+		infos = append(infos, b.info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Started.Equal(infos[j].Started) {
+			return infos[i].Started.After(infos[j].Started)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// get returns a batch.
+func (br *batchRunner) get(id string) (BatchInfo, bool) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	b, ok := br.batches[id]
+	if !ok {
+		return BatchInfo{}, false
+	}
+	return b.info, true
+}
+
+// cancel stops a batch, waiting for the lines running to finish, and
+// returns its final state.
+func (br *batchRunner) cancel(id string) (BatchInfo, bool) {
+	br.mu.Lock()
+	b, ok := br.batches[id]
+	br.mu.Unlock()
+	if !ok {
+		return BatchInfo{}, false
+	}
+	b.cancel()
+	<-b.done
+	return br.get(id)
+}
+
+var errBatchesDisabled = errors.New("batches are disabled on this server")
+
+// handleBatches handles POST and GET /batches and GET and DELETE
+// /batches/{id}, admin endpoints running a plugin over every line of a
+// file under BATCH_DIR for bulk reprocessing.
+//
+// POST starts a batch in the background and returns 202. Its results are
+// written to the input's path plus BatchResultSuffix as lines finish, and
+// GET reports its progress. DELETE cancels a running batch.
+func (s *Server) handleBatches(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/batches"), "/")
+	allowed := r.Method == http.MethodGet || (id == "" && r.Method == http.MethodPost) ||
+		(id != "" && r.Method == http.MethodDelete)
+	if !allowed {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+	if s.batches == nil {
+		writeError(w, r, apierror.CodeBatchNotFound, errBatchesDisabled.Error())
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost:
+		var req BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		info, err := s.batches.start(req)
+		if err != nil {
+			writeExecutionError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, info)
+
+	case id == "":
+		writeJSON(w, http.StatusOK, s.batches.list())
+
+	default:
+		var info BatchInfo
+		var ok bool
+		if r.Method == http.MethodDelete {
+			info, ok = s.batches.cancel(id)
+		} else {
+			info, ok = s.batches.get(id)
+		}
+		if !ok {
+			writeError(w, r, apierror.CodeBatchNotFound, fmt.Sprintf("no batch %s", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Batches", func() {
+	var (
+		batchDir string
+		srv      *Server
+	)
+
+	BeforeEach(func() {
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		// Not a valid module, so every line that runs fails to load
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+		batchDir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.adminToken = "secret"
+		srv.batches = newBatchRunner(srv, batchDir, 2)
+		srv.batches.maxRetryDelay = 5 * time.Millisecond
+	})
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		srv.handleBatches(rec, req)
+		return rec
+	}
+
+	// finished waits for a batch to stop running.
+	finished := func(id string) BatchInfo {
+		Eventually(func() BatchState {
+			info, _ := srv.batches.get(id)
+			return info.State
+		}).ShouldNot(Equal(BatchRunning))
+		info, _ := srv.batches.get(id)
+		return info
+	}
+
+	readResults := func(path string) map[int]BatchResult {
+		f, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		results := make(map[int]BatchResult)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var result BatchResult
+			Expect(json.Unmarshal(scanner.Bytes(), &result)).To(Succeed())
+			results[result.Line] = result
+		}
+		return results
+	}
+
+	// =========================================================================
+	// TEST: Processing a file
+	// Why: Every line must get a result beside the input, numbered like the
+	//      input, and progress must account for all of it.
+	// =========================================================================
+	It("should run every line and write the results beside the input", func() {
+		Expect(os.MkdirAll(filepath.Join(batchDir, "2026-10"), 0755)).To(Succeed())
+		input := "21\n\"some text\"\n{\"input\": 3}\n\nnot json\n"
+		Expect(os.WriteFile(filepath.Join(batchDir, "2026-10", "inputs.jsonl"), []byte(input), 0644)).To(Succeed())
+
+		rec := send(http.MethodPost, "/batches", `{"plugin": "hello", "input": "2026-10/inputs.jsonl", "concurrency": 8}`)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		var started BatchInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &started)).To(Succeed())
+		Expect(started.Concurrency).To(Equal(2))
+		Expect(started.Output).To(Equal("2026-10/inputs.jsonl" + BatchResultSuffix))
+
+		info := finished(started.ID)
+		Expect(info.State).To(Equal(BatchCompleted))
+		Expect(info.Processed).To(Equal(4))
+		Expect(info.Failed).To(Equal(4))
+		Expect(info.BytesRead).To(Equal(int64(len(input))))
+
+		results := readResults(filepath.Join(batchDir, "2026-10", "inputs.jsonl"+BatchResultSuffix))
+		Expect(results).To(HaveLen(4))
+		Expect(results).To(HaveKey(1))
+		Expect(results).NotTo(HaveKey(4))
+		Expect(results[5].Error.Code).To(Equal(apierror.CodeInvalidRequest))
+		Expect(results[1].Error.Code).NotTo(Equal(apierror.CodeInvalidRequest))
+
+		rec = send(http.MethodGet, "/batches/"+started.ID, "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		rec = send(http.MethodGet, "/batches", "")
+		var list []BatchInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &list)).To(Succeed())
+		Expect(list).To(HaveLen(1))
+	})
+
+	// =========================================================================
+	// TEST: Backpressure
+	// Why: A batch must wait out saturation and maintenance instead of
+	//      recording them as failed lines.
+	// =========================================================================
+	It("should retry lines paused for maintenance", func() {
+		Expect(os.WriteFile(filepath.Join(batchDir, "in.jsonl"), []byte("1\n"), 0644)).To(Succeed())
+		_, err := srv.maintenance.add(MaintenanceRequest{Plugin: "hello", End: time.Now().Add(100 * time.Millisecond)})
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := srv.batches.start(BatchRequest{Plugin: "hello", Input: "in.jsonl"})
+		Expect(err).NotTo(HaveOccurred())
+		info = finished(info.ID)
+		Expect(info.Retries).To(BeNumerically(">", 0))
+
+		result := readResults(filepath.Join(batchDir, "in.jsonl"+BatchResultSuffix))[1]
+		Expect(result.Error.Code).NotTo(Equal(apierror.CodeMaintenance))
+	})
+
+	It("should cancel a running batch", func() {
+		Expect(os.WriteFile(filepath.Join(batchDir, "in.jsonl"), []byte("1\n2\n3\n"), 0644)).To(Succeed())
+		_, err := srv.maintenance.add(MaintenanceRequest{Plugin: "hello", DurationSeconds: 3600})
+		Expect(err).NotTo(HaveOccurred())
+		info, err := srv.batches.start(BatchRequest{Plugin: "hello", Input: "in.jsonl"})
+		Expect(err).NotTo(HaveOccurred())
+
+		// The same input cannot be processed twice at once
+		_, err = srv.batches.start(BatchRequest{Plugin: "hello", Input: "./in.jsonl"})
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeDuplicateRequest))
+
+		rec := send(http.MethodDelete, "/batches/"+info.ID, "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(json.Unmarshal(rec.Body.Bytes(), &info)).To(Succeed())
+		Expect(info.State).To(Equal(BatchCancelled))
+		Expect(info.Processed).To(BeNumerically("<", 3))
+	})
+
+	DescribeTable("should reject invalid batches",
+		func(body string, code apierror.Code) {
+			rec := send(http.MethodPost, "/batches", body)
+			var problem apierror.Problem
+			Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+			Expect(problem.Code).To(Equal(code))
+		},
+		Entry("outside the batch directory", `{"plugin": "hello", "input": "../etc/passwd"}`, apierror.CodeInvalidRequest),
+		Entry("with an absolute path", `{"plugin": "hello", "input": "/etc/passwd"}`, apierror.CodeInvalidRequest),
+		Entry("with a missing input", `{"plugin": "hello", "input": "missing.jsonl"}`, apierror.CodeInvalidRequest),
+		Entry("for an unknown plugin", `{"plugin": "nope", "input": "in.jsonl"}`, apierror.CodePluginNotFound),
+	)
+
+	It("should report unknown and disabled batches", func() {
+		Expect(send(http.MethodGet, "/batches/nope", "").Code).To(Equal(http.StatusNotFound))
+
+		srv.batches = nil
+		rec := send(http.MethodGet, "/batches", "")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+})
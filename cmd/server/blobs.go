@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// s3Blobs is the blob store of blob_get() and blob_put(): an S3 bucket, or
+// a GCS bucket through its S3-compatible endpoint.
+type s3Blobs struct {
+	client *fluid.S3Client
+}
+
+// Open returns the object at key and its size.
+func (b s3Blobs) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	body, info, err := b.client.Get(ctx, key, fluid.ObjectInfo{})
+	if errors.Is(err, fluid.ErrObjectNotFound) {
+		return nil, 0, fmt.Errorf("%w: %s", runtime.ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, info.Size, nil
+}
+
+// Put stores data at key.
+func (b s3Blobs) Put(ctx context.Context, key string, data []byte) error {
+	return b.client.Put(ctx, key, data)
+}
+
+// newBlobs configures the object storage host API, or returns nil if no
+// bucket is configured. The region and endpoint default to those of the S3
+// plugin store, and the credentials are its AWS credentials. For GCS, set
+// the endpoint to https://storage.googleapis.com and use HMAC keys.
+func newBlobs(cfg *config.Config) (*runtime.Blobs, error) {
+	if cfg.Blobs.Bucket == "" {
+		return nil, nil
+	}
+	s3cfg := fluid.S3Config{
+		Bucket:          cfg.Blobs.Bucket,
+		Region:          cfg.Blobs.Region,
+		Endpoint:        cfg.Blobs.Endpoint,
+		AccessKeyID:     cfg.AWS.AccessKeyID,
+		SecretAccessKey: cfg.AWS.SecretAccessKey,
+		SessionToken:    cfg.AWS.SessionToken,
+	}
+	if s3cfg.Region == "" {
+		s3cfg.Region = cfg.Store.S3.Region
+	}
+	if s3cfg.Endpoint == "" {
+		s3cfg.Endpoint = cfg.Store.S3.Endpoint
+	}
+	client, err := fluid.NewS3Client(s3cfg)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.NewBlobs(s3Blobs{client: client}, runtime.BlobOptions{
+		Prefix:      cfg.Blobs.Prefix,
+		MaxGetBytes: cfg.Blobs.MaxGetBytes,
+		MaxPutBytes: cfg.Blobs.MaxPutBytes,
+	}), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("newBlobs", func() {
+	It("should leave blob storage unconfigured without a bucket", func() {
+		blobs, err := newBlobs(config.Default())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blobs).To(BeNil())
+	})
+
+	// =========================================================================
+	// TEST: S3 blob store
+	// Why: Plugins address keys under the host's BLOB_PREFIX, and a missing
+	//      object must reach them as BlobNotFound rather than a failure.
+	// =========================================================================
+	It("should read and write objects under the prefix", func() {
+		objects := map[string]string{}
+		bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				data, _ := io.ReadAll(r.Body)
+				objects[r.URL.Path] = string(data)
+				return
+			}
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+				return
+			}
+			io.WriteString(w, data)
+		}))
+		DeferCleanup(bucket.Close)
+
+		cfg := config.Default()
+		cfg.Blobs.Bucket = "data"
+		cfg.Blobs.Prefix = "plugins/"
+		cfg.Store.S3.Endpoint = bucket.URL
+		blobs, err := newBlobs(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		m := &manifest.Manifest{Blobs: manifest.Blobs{Read: []string{"out/"}, Write: []string{"out/"}}}
+		ctx := context.Background()
+
+		Expect(blobs.Put(ctx, m, "out/result.txt", []byte("done"))).To(Succeed())
+		Expect(objects).To(HaveKeyWithValue("/data/plugins/out/result.txt", "done"))
+		data, _, err := blobs.Get(ctx, m, "out/result.txt", 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("done"))
+
+		_, _, err = blobs.Get(ctx, m, "out/missing.txt", 100)
+		Expect(errors.Is(err, runtime.ErrBlobNotFound)).To(BeTrue())
+	})
+})
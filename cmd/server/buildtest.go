@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// testBuild runs the tests the manifest m declares against wasm, a build
+// of ref about to be published, and returns an error with the
+// invalid_plugin code naming the first one it fails.
+//
+// The build is staged with its manifest in a directory of its own, and
+// every test runs on a fresh instance with the options of the plugin's
+// pools, so that one test cannot leave state for the next. Effects the
+// plugin enqueues are discarded and its output is not logged.
+func (s *Server) testBuild(ref string, wasm []byte, m *manifest.Manifest) error {
+	if m == nil || len(m.Tests) == 0 {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "plugin-test-*")
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInternal, fmt.Errorf("failed to stage build for testing: %w", err))
+	}
+	defer os.RemoveAll(dir)
+	name, _ := fluid.ParseReference(ref)
+	path := filepath.Join(dir, name+".wasm")
+	data, err := json.Marshal(m)
+	if err == nil {
+		err = os.WriteFile(manifest.Path(path), data, 0o644)
+	}
+	if err == nil {
+		err = os.WriteFile(path, wasm, 0o644)
+	}
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInternal, fmt.Errorf("failed to stage build for testing: %w", err))
+	}
+
+	opts, err := s.pluginPoolOptions(ref)
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInternal, err)
+	}
+	for _, test := range m.Tests {
+		if err := s.runBuildTest(path, opts, m, test); err != nil {
+			return apierror.Wrap(apierror.CodeInvalidPlugin, fmt.Errorf("plugin failed test %s: %w", test.Name, err))
+		}
+	}
+	return nil
+}
+
+// runBuildTest runs one test of the build at path and compares its
+// outcome with the one the test expects.
+func (s *Server) runBuildTest(path string, opts runtime.PoolOptions, m *manifest.Manifest, test manifest.Test) error {
+	req := Request{Input: test.Input, Text: test.Text, TimeoutMs: m.Limits.TimeoutMs}
+	ctx := context.Background()
+	if timeout := s.timeout(req); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx = runtime.WithOutputCapture(ctx, runtime.NewOutputCapture(0))
+	ctx = runtime.WithOutbox(ctx, runtime.NewOutbox())
+
+	plugin, err := runtime.LoadPluginWithOptions(path, opts.LoadOptions())
+	if err != nil {
+		return fmt.Errorf("failed to load: %w", err)
+	}
+	defer plugin.Close()
+	config, withConfig, err := opts.InitConfig(m)
+	if err == nil && withConfig {
+		err = plugin.InitWithConfigContext(ctx, config)
+	} else if err == nil {
+		err = plugin.InitContext(ctx)
+	}
+	if err == nil {
+		err = req.checkSupport(plugin)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	resp, err := invoke(ctx, plugin, req)
+	var pluginErr *runtime.PluginError
+	switch {
+	case test.ErrorCode != 0:
+		if errors.As(err, &pluginErr) && pluginErr.Code == test.ErrorCode {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("expected error code %d, got: %w", test.ErrorCode, err)
+		}
+		return fmt.Errorf("expected error code %d, got output %s", test.ErrorCode, shadowOutput(resp))
+	case err != nil:
+		return err
+	case test.OutputText != nil:
+		if resp.Text == nil || *resp.Text != *test.OutputText {
+			return fmt.Errorf("expected output_text %q, got %s", *test.OutputText, shadowOutput(resp))
+		}
+	case resp.Output == nil || *resp.Output != *test.Output:
+		return fmt.Errorf("expected output %d, got %s", *test.Output, shadowOutput(resp))
+	}
+	return nil
+}
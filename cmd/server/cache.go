@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// redisCacheTimeout bounds a cache command, so a slow Redis delays plugin
+// calls by at most this much.
+const redisCacheTimeout = time.Second
+
+// redisKeyPrefix prefixes the keys of plugin cache entries, so the server
+// can share a Redis database.
+const redisKeyPrefix = "wasm-plugin:cache:"
+
+// redisCache is a runtime.CacheStore in Redis, shared by every server
+// using the same database, with keys "wasm-plugin:cache:<namespace>:<key>",
+// the namespace being "<tenant>/<plugin>" or, without a tenant, "<plugin>".
+// Redis expires entries with their TTL; quotas are left to its maxmemory
+// policy. It speaks RESP over one connection, dialed on first use and again
+// after it fails, and serializes commands.
+type redisCache struct {
+	addr     string
+	tls      bool
+	username string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newRedisCache creates a store for a redis:// or rediss:// URL, e.g.
+// redis://:password@cache:6379/2. Redis is not contacted until first use.
+func newRedisCache(rawURL string) (*redisCache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, fmt.Errorf("Redis URL must be redis://[user:password@]host:port[/db], got %q", rawURL)
+	}
+	c := &redisCache{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil || c.db < 0 {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Get returns the entry at key.
+func (c *redisCache) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	reply, err := c.do(ctx, "GET", redisKeyPrefix+namespace+":"+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, fmt.Errorf("%w: %s", runtime.ErrCacheMiss, key)
+	}
+	return reply, nil
+}
+
+// Set stores value at key, expiring after ttl.
+func (c *redisCache) Set(ctx context.Context, namespace, key string, value []byte, ttl time.Duration) error {
+	ms := max(ttl.Milliseconds(), 1)
+	_, err := c.do(ctx, "SET", redisKeyPrefix+namespace+":"+key, string(value), "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+// Close closes the connection, if any.
+func (c *redisCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reset()
+}
+
+// do runs a command and returns its bulk string reply, nil for a nil
+// reply. A command failing on a connection that was already open is
+// retried once on a new one, since Redis closes idle connections.
+func (c *redisCache) do(ctx context.Context, args ...string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		fresh := c.conn == nil
+		if fresh {
+			if err = c.connect(ctx); err != nil {
+				return nil, err
+			}
+		}
+		var reply []byte
+		if reply, err = c.roundTrip(ctx, args); err == nil {
+			return reply, nil
+		}
+		var redisErr redisError
+		if errors.As(err, &redisErr) || fresh {
+			break
+		}
+	}
+	return nil, err
+}
+
+// connect dials Redis, authenticates, and selects the database.
+func (c *redisCache) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: redisCacheTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if c.tls {
+		tlsDialer := tls.Dialer{NetDialer: &dialer}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	c.conn, c.reader = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	switch {
+	case c.username != "":
+		setup = append(setup, []string{"AUTH", c.username, c.password})
+	case c.password != "":
+		setup = append(setup, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(ctx, args); err != nil {
+			c.reset()
+			return fmt.Errorf("failed to connect to Redis: %s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundTrip sends a command and reads its reply. Any error but an error
+// reply closes the connection.
+func (c *redisCache) roundTrip(ctx context.Context, args []string) ([]byte, error) {
+	deadline := time.Now().Add(redisCacheTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, cmd.String()); err != nil {
+		c.reset()
+		return nil, err
+	}
+
+	reply, err := c.readReply()
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.reset()
+	}
+	return reply, err
+}
+
+// readReply reads a simple string, error, integer, or bulk string reply.
+func (c *redisCache) readReply() ([]byte, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty Redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
+
+// reset closes the connection so the next command dials a new one.
+func (c *redisCache) reset() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.reader = nil, nil
+	return err
+}
+
+// redisError is an error reply from Redis.
+type redisError string
+
+func (e redisError) Error() string {
+	return "Redis: " + string(e)
+}
+
+// newCache configures the cache host API: in memory, or in Redis if a
+// Redis URL is configured. It returns nil if the cache is disabled. The
+// returned closer, if not nil, releases the Redis connection.
+func newCache(cfg config.Cache) (*runtime.Cache, io.Closer, error) {
+	if !cfg.Enabled {
+		return nil, nil, nil
+	}
+	opts := runtime.CacheOptions{MaxValueBytes: cfg.MaxValueBytes, MaxTTL: cfg.MaxTTL}
+	if cfg.RedisURL != "" {
+		store, err := newRedisCache(cfg.RedisURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return runtime.NewCache(store, opts), store, nil
+	}
+	return runtime.NewCache(runtime.NewMemoryCache(cfg.QuotaBytes), opts), nil, nil
+}
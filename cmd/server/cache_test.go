@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// fakeRedis is a Redis server supporting AUTH, SELECT, GET, and SET with
+// PX, recording the commands it receives.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+	conns    []net.Conn
+}
+
+func newFakeRedis(password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	f := &fakeRedis{listener: listener, password: password, values: map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	DeferCleanup(func() {
+		listener.Close()
+		f.dropConnections()
+	})
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			header, _ := reader.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			args[i] = string(data[:size])
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == f.password
+			if authed {
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid username-password pair\r\n")
+			}
+		case !authed:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "SET":
+			f.values[args[1]] = args[2]
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "GET":
+			if value, ok := f.values[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
+// snapshot returns the stored values and the commands received so far.
+func (f *fakeRedis) snapshot() (map[string]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := make(map[string]string, len(f.values))
+	for key, value := range f.values {
+		values[key] = value
+	}
+	return values, append([]string(nil), f.commands...)
+}
+
+func (f *fakeRedis) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+var _ = Describe("newCache", func() {
+	redisConfig := func(rawURL string) config.Cache {
+		cfg := config.Default().Cache
+		cfg.RedisURL = rawURL
+		return cfg
+	}
+
+	It("should cache in memory by default and not at all when disabled", func() {
+		cfg := config.Default().Cache
+		cache, closer, err := newCache(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache).NotTo(BeNil())
+		Expect(closer).To(BeNil())
+
+		cfg.Enabled = false
+		cache, _, err = newCache(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache).To(BeNil())
+	})
+
+	It("should reject invalid Redis URLs", func() {
+		for _, rawURL := range []string{"cache:6379", "http://cache:6379", "redis://cache:6379/two"} {
+			_, _, err := newCache(redisConfig(rawURL))
+			Expect(err).To(HaveOccurred(), rawURL)
+		}
+	})
+
+	// =========================================================================
+	// TEST: Redis cache
+	// Why: Servers sharing Redis share plugin cache entries, so keys must be
+	//      namespaced per plugin, and a connection Redis closed when idle
+	//      must not fail the next call.
+	// =========================================================================
+	It("should cache in Redis under per-plugin keys", func() {
+		redis := newFakeRedis("secret")
+		cache, closer, err := newCache(redisConfig("redis://:secret@" + redis.listener.Addr().String() + "/2"))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(closer.Close)
+		ctx := context.Background()
+
+		Expect(cache.Set(ctx, "enrich", "rate:EUR", []byte("1.08"), time.Minute)).To(Succeed())
+		values, _ := redis.snapshot()
+		Expect(values).To(Equal(map[string]string{"wasm-plugin:cache:enrich:rate:EUR": "1.08"}))
+
+		redis.dropConnections()
+		Expect(cache.Get(ctx, "enrich", "rate:EUR")).To(Equal([]byte("1.08")))
+		_, err = cache.Get(ctx, "audit", "rate:EUR")
+		Expect(errors.Is(err, runtime.ErrCacheMiss)).To(BeTrue())
+		_, commands := redis.snapshot()
+		Expect(commands).To(Equal([]string{"AUTH", "SELECT", "SET", "AUTH", "SELECT", "GET", "GET"}))
+	})
+
+	It("should report a wrong Redis password", func() {
+		redis := newFakeRedis("secret")
+		cache, closer, err := newCache(redisConfig("redis://:wrong@" + redis.listener.Addr().String()))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(closer.Close)
+
+		_, err = cache.Get(context.Background(), "enrich", "key")
+		Expect(err).To(MatchError(ContainSubstring("WRONGPASS")))
+	})
+})
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// cacheStatsTimeout bounds reading the runtime's metrics; a runtime slower
+// than that counts as down for the interval.
+const cacheStatsTimeout = 5 * time.Second
+
+// cacheStatsCollector exports the cache metrics of the Fluid runtime
+// behind the plugin dataset, so plugin latency can be correlated with how
+// much of the dataset is cached. The runtime is polled every interval
+// rather than on scrape, so a slow runtime cannot hold up /metrics.
+type cacheStatsCollector struct {
+	reader   fluid.CacheStatsReader
+	runtime  string // Runtime name, e.g. alluxio
+	dataset  string
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	stats fluid.CacheStats
+	read  bool // Whether stats were read at least once
+	up    bool // Whether the last read succeeded
+}
+
+// newCacheStatsCollector creates a collector reading the runtime's
+// metrics with reader.
+func newCacheStatsCollector(reader fluid.CacheStatsReader, runtime, dataset string, interval time.Duration, logger *slog.Logger) *cacheStatsCollector {
+	return &cacheStatsCollector{reader: reader, runtime: runtime, dataset: dataset, interval: interval, logger: logger}
+}
+
+// run polls the runtime until ctx is done.
+func (c *cacheStatsCollector) run(ctx context.Context) {
+	c.poll(ctx)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.poll(ctx)
+		}
+	}
+}
+
+// poll reads the runtime's metrics once. A failure keeps the last stats,
+// but marks the runtime down; it is logged when the runtime goes down.
+func (c *cacheStatsCollector) poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, cacheStatsTimeout)
+	defer cancel()
+	stats, err := c.reader.CacheStats(ctx)
+
+	c.mu.Lock()
+	wasUp := c.up || !c.read
+	c.up = err == nil
+	if err == nil {
+		c.stats = stats
+		c.read = true
+	}
+	c.mu.Unlock()
+
+	if err != nil && wasUp {
+		c.logger.Warn("failed to read the dataset cache metrics",
+			slog.String("runtime", c.runtime), slog.String("error", err.Error()))
+	}
+}
+
+// collectMetrics reports whether the runtime is up and, once read, its
+// cache state.
+func (c *cacheStatsCollector) collectMetrics() []metrics.Family {
+	c.mu.Lock()
+	stats, read, up := c.stats, c.read, c.up
+	c.mu.Unlock()
+
+	labels := []metrics.Label{{Name: "dataset", Value: c.dataset}, {Name: "runtime", Value: c.runtime}}
+	upValue := 0.0
+	if up {
+		upValue = 1
+	}
+	families := []metrics.Family{
+		{Name: "plugin_dataset_cache_up", Help: "Whether the last read of the dataset cache runtime's metrics succeeded.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Labels: labels, Value: upValue}}},
+	}
+	if !read {
+		return families
+	}
+
+	withSource := func(source string) []metrics.Label {
+		return append(labels[:len(labels):len(labels)], metrics.Label{Name: "source", Value: source})
+	}
+	families = append(families,
+		metrics.Family{Name: "plugin_dataset_cached_bytes", Help: "Bytes of the plugin dataset held by the Fluid cache.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Labels: labels, Value: float64(stats.CachedBytes)}}},
+		metrics.Family{Name: "plugin_dataset_cache_hit_ratio", Help: "Share of bytes read from the plugin dataset that the Fluid cache served since the runtime started.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Labels: labels, Value: stats.HitRatio()}}},
+		metrics.Family{Name: "plugin_dataset_cache_read_bytes_total", Help: "Bytes read from the plugin dataset, by source: the cache, or the underlying storage on a miss.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: withSource("cache"), Value: float64(stats.HitBytes)},
+				{Labels: withSource("storage"), Value: float64(stats.MissBytes)},
+			}},
+	)
+	if stats.CapacityBytes > 0 {
+		families = append(families, metrics.Family{Name: "plugin_dataset_cache_capacity_bytes", Help: "Bytes the Fluid cache of the plugin dataset may hold.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Labels: labels, Value: float64(stats.CapacityBytes)}}})
+	}
+	return families
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// runtimeStats is a fluid.CacheStatsReader returning stats or err.
+type runtimeStats struct {
+	stats fluid.CacheStats
+	err   error
+}
+
+func (r *runtimeStats) CacheStats(context.Context) (fluid.CacheStats, error) {
+	return r.stats, r.err
+}
+
+var _ = Describe("Dataset cache metrics", func() {
+	var (
+		reader    *runtimeStats
+		collector *cacheStatsCollector
+		logs      *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		reader = &runtimeStats{stats: fluid.CacheStats{CachedBytes: 4096, CapacityBytes: 8192, HitBytes: 30, MissBytes: 10}}
+		logs = &bytes.Buffer{}
+		collector = newCacheStatsCollector(reader, "alluxio", "plugins", time.Minute, slog.New(slog.NewJSONHandler(logs, nil)))
+	})
+
+	labels := []metrics.Label{{Name: "dataset", Value: "plugins"}, {Name: "runtime", Value: "alluxio"}}
+
+	// family returns the collected family called name.
+	family := func(name string) metrics.Family {
+		for _, f := range collector.collectMetrics() {
+			if f.Name == name {
+				return f
+			}
+		}
+		return metrics.Family{}
+	}
+
+	// =========================================================================
+	// TEST: Exported cache state
+	// Why: Operators correlate plugin latency with these series, so the
+	//      runtime's stats must be exported under stable names and labels.
+	// =========================================================================
+	It("should export the runtime's cache state", func() {
+		collector.poll(context.Background())
+
+		Expect(family("plugin_dataset_cache_up").Samples).To(Equal([]metrics.Sample{{Labels: labels, Value: 1}}))
+		Expect(family("plugin_dataset_cached_bytes").Samples).To(Equal([]metrics.Sample{{Labels: labels, Value: 4096}}))
+		Expect(family("plugin_dataset_cache_capacity_bytes").Samples).To(Equal([]metrics.Sample{{Labels: labels, Value: 8192}}))
+		Expect(family("plugin_dataset_cache_hit_ratio").Samples).To(Equal([]metrics.Sample{{Labels: labels, Value: 0.75}}))
+		Expect(family("plugin_dataset_cache_read_bytes_total").Samples).To(Equal([]metrics.Sample{
+			{Labels: append(labels[:2:2], metrics.Label{Name: "source", Value: "cache"}), Value: 30},
+			{Labels: append(labels[:2:2], metrics.Label{Name: "source", Value: "storage"}), Value: 10},
+		}))
+	})
+
+	It("should only report the runtime down before its first read", func() {
+		reader.err = errors.New("connection refused")
+		collector.poll(context.Background())
+
+		families := collector.collectMetrics()
+		Expect(families).To(HaveLen(1))
+		Expect(families[0].Samples).To(Equal([]metrics.Sample{{Labels: labels, Value: 0}}))
+		Expect(logs.String()).To(ContainSubstring("connection refused"))
+	})
+
+	It("should keep the last stats while the runtime is down, logging once", func() {
+		collector.poll(context.Background())
+		reader.err = errors.New("connection refused")
+		collector.poll(context.Background())
+		collector.poll(context.Background())
+
+		Expect(family("plugin_dataset_cache_up").Samples[0].Value).To(BeZero())
+		Expect(family("plugin_dataset_cached_bytes").Samples[0].Value).To(BeEquivalentTo(4096))
+		Expect(bytes.Count(logs.Bytes(), []byte("connection refused"))).To(Equal(1))
+	})
+
+	It("should omit the capacity when the runtime does not report it", func() {
+		reader.stats.CapacityBytes = 0
+		collector.poll(context.Background())
+		Expect(family("plugin_dataset_cache_capacity_bytes").Name).To(BeEmpty())
+	})
+})
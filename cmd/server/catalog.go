@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// maxCatalogBytes bounds the size of an imported catalog bundle, both as
+// sent and uncompressed, since the bundle is checked in memory before any
+// build is written.
+const maxCatalogBytes = 1 << 30
+
+// handleCatalog handles GET /catalog and POST /catalog, admin endpoints
+// exporting every plugin build of the store as one bundle and importing
+// such a bundle, to clone an environment or restore a lost store (see
+// fluid.ExportCatalog).
+//
+// An import replaces the builds in the bundle and leaves the others as
+// they are. It needs a store that can write manifests and checksum files
+// too; running pools switch to the imported builds on their next checkout.
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if s.admin(w, r) {
+			s.exportCatalog(w, r)
+		}
+	case http.MethodPost:
+		writer, ok := s.adminWriter(w, r)
+		if !ok {
+			return
+		}
+		files, ok := writer.(fluid.BuildFileWriter)
+		if !ok {
+			writeError(w, r, apierror.CodeMethodNotAllowed, "the plugin store cannot import catalogs")
+			return
+		}
+		s.importCatalog(w, r, files)
+	default:
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) exportCatalog(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.store.List(); err != nil {
+		writeError(w, r, apierror.CodeInternal, fmt.Sprintf("failed to list plugins: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="plugin-catalog.tar.gz"`)
+	index, err := fluid.ExportCatalog(s.store, w)
+	if err != nil {
+		// The bundle is streamed; abort the response so the client sees a
+		// broken transfer rather than a truncated bundle
+		s.logger.LogAttrs(r.Context(), slog.LevelError, "failed to export plugin catalog",
+			slog.String("error", err.Error()))
+		panic(http.ErrAbortHandler)
+	}
+	for ref, reason := range index.Skipped {
+		s.logger.LogAttrs(r.Context(), slog.LevelWarn, "plugin build left out of catalog export",
+			slog.String("plugin", ref),
+			slog.String("error", reason))
+	}
+}
+
+func (s *Server) importCatalog(w http.ResponseWriter, r *http.Request, store fluid.BuildFileWriter) {
+	body := http.MaxBytesReader(w, r.Body, maxCatalogBytes)
+	imported, err := fluid.ImportCatalog(store, body, maxCatalogBytes, s.testBuild)
+	if len(imported) > 0 {
+		s.logger.LogAttrs(context.Background(), slog.LevelInfo, "plugin catalog imported",
+			slog.Int("builds", len(imported)))
+		// The imported manifests may declare schedules
+		s.syncSchedules()
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, r, apierror.CodePluginTooLarge, fmt.Sprintf("catalog exceeds %d bytes", maxCatalogBytes))
+		return
+	case errors.Is(err, fluid.ErrInvalidCatalog):
+		writeError(w, r, apierror.CodeInvalidRequest, err.Error())
+		return
+	case apierror.CodeOf(err) == apierror.CodeInvalidPlugin:
+		writeExecutionError(w, r, err)
+		return
+	case err != nil:
+		writeError(w, r, apierror.CodeInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, imported)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("/catalog", func() {
+	// =========================================================================
+	// TEST: Catalog export and import
+	// Why: Operators clone environments and restore lost stores with these
+	//      endpoints; they hand out and replace every build, so only admins
+	//      may call them.
+	// =========================================================================
+	const token = "s3cret"
+
+	newServer := func(dir string) *Server {
+		srv := NewServer(fluid.NewLocalPluginStore(dir))
+		srv.adminToken = token
+		return srv
+	}
+
+	call := func(srv *Server, method string, body []byte, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/catalog", bytes.NewReader(body))
+		if auth {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.handleCatalog(rec, req)
+		return rec
+	}
+
+	It("should copy every build from one server to another", func() {
+		source := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(source, "hello"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(source, "hello", "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+
+		rec := call(newServer(source), http.MethodGet, nil, true)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/gzip"))
+
+		target := GinkgoT().TempDir()
+		rec = call(newServer(target), http.MethodPost, rec.Body.Bytes(), true)
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+		var imported []fluid.PluginInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &imported)).To(Succeed())
+		Expect(imported).To(HaveLen(1))
+		Expect(imported[0].Name).To(Equal("hello"))
+		Expect(os.ReadFile(filepath.Join(target, "hello", "hello.wasm"))).To(Equal([]byte("wasm")))
+	})
+
+	It("should require the admin token", func() {
+		srv := newServer(GinkgoT().TempDir())
+		Expect(call(srv, http.MethodGet, nil, false).Code).To(Equal(http.StatusUnauthorized))
+		Expect(call(srv, http.MethodPost, nil, false).Code).To(Equal(http.StatusUnauthorized))
+
+		srv.adminToken = ""
+		Expect(call(srv, http.MethodGet, nil, true).Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should reject a body that is not a bundle", func() {
+		rec := call(newServer(GinkgoT().TempDir()), http.MethodPost, []byte("not a bundle"), true)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("invalid catalog bundle"))
+	})
+})
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Execution context", func() {
+	// =========================================================================
+	// TEST: Caller metadata
+	// Why: Plugins attribute work to the tenant and caller they are given;
+	//      an unusable value must fail the request instead of being dropped.
+	// =========================================================================
+	It("should reject unusable tenants and callers", func() {
+		call, err := callInfoOf(context.Background(), "req-1", "payments", "billing-service")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(call).To(Equal(runtime.CallInfo{RequestID: "req-1", Tenant: "payments", Caller: "billing-service"}))
+
+		_, err = callInfoOf(context.Background(), "req-1", "team a", "")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeInvalidRequest))
+		_, err = callInfoOf(context.Background(), "req-1", "", strings.Repeat("x", 200))
+		Expect(err).To(MatchError(ContainSubstring("caller must be printable ASCII")))
+	})
+
+	It("should answer 400 to a /run request with an unusable tenant", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello"}`))
+		req.Header.Set(TenantHeader, "team a")
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Header().Get(RequestIDHeader)).NotTo(BeEmpty())
+	})
+
+	It("should pass the request's metadata to the plugin", func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "whoami", "whoami.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: whoami.wasm")
+		}
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		logs := &bytes.Buffer{}
+		srv.logger = slog.New(slog.NewJSONHandler(logs, nil))
+
+		req := httptest.NewRequest(http.MethodPost, "/run",
+			bytes.NewBufferString(`{"plugin": "whoami", "input": 512, "timeout_ms": 5000, "include_logs": true}`))
+		req.Header.Set(RequestIDHeader, "req-7")
+		req.Header.Set(TenantHeader, "payments")
+		req.Header.Set(CallerHeader, "billing-service")
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+
+		var resp Response
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.Logs).To(HaveLen(1))
+		Expect(resp.Logs[0].Tenant).To(Equal("payments"))
+
+		var fields map[string]interface{}
+		Expect(json.Unmarshal([]byte(resp.Logs[0].Message), &fields)).To(Succeed())
+		Expect(fields).To(HaveKeyWithValue("request_id", "req-7"))
+		Expect(fields).To(HaveKeyWithValue("tenant", "payments"))
+		Expect(fields).To(HaveKeyWithValue("caller", "billing-service"))
+		Expect(fields["remaining_ms"]).To(BeNumerically("<=", 5000))
+
+		Expect(logs.String()).To(ContainSubstring(`"tenant":"payments"`))
+	})
+})
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// PoolInfo describes one plugin's instance pool in the pool metrics.
+type PoolInfo struct {
+	Plugin string `json:"plugin"` // Plugin name derived from the pool's path
+	runtime.PoolStats
+}
+
+// poolInfos returns stats for all pools, sorted by plugin name.
+func (s *Server) poolInfos() []PoolInfo {
+	s.poolsMu.Lock()
+	pools := make([]*runtime.Pool, 0, len(s.pools))
+	for _, pool := range s.pools {
+		pools = append(pools, pool)
+	}
+	s.poolsMu.Unlock()
+
+	infos := make([]PoolInfo, 0, len(pools))
+	for _, pool := range pools {
+		infos = append(infos, PoolInfo{
+			Plugin:    pluginNameFromPath(pool.Path()),
+			PoolStats: pool.Stats(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Plugin < infos[j].Plugin
+	})
+	return infos
+}
+
+// MemoryReport is the response of GET /debug/memory: what the process
+// uses, and how much of it each plugin build accounts for.
+type MemoryReport struct {
+	Process ProcessMemory `json:"process"`
+
+	// Plugins breaks down plugin memory by build, largest first.
+	Plugins []PluginMemoryInfo `json:"plugins"`
+
+	// AttributedBytes is the total of every build's TotalBytes.
+	AttributedBytes int64 `json:"attributed_bytes"`
+}
+
+// ProcessMemory is the memory of the server process as a whole.
+type ProcessMemory struct {
+	RSSBytes    int64  `json:"rss_bytes,omitempty"` // Resident set size; Linux only
+	GoHeapBytes uint64 `json:"go_heap_bytes"`       // Go heap in use
+	GoSysBytes  uint64 `json:"go_sys_bytes"`        // Memory obtained by the Go runtime
+}
+
+// PluginMemoryInfo is the memory of one plugin build, with the plugin
+// names derived from its paths.
+type PluginMemoryInfo struct {
+	Plugins []string `json:"plugins"`
+	runtime.PluginMemory
+}
+
+// handleDebugMemory handles GET /debug/memory
+//
+// Attributes the memory held by instance pools, parsed modules, and AOT
+// artifacts to the plugin builds they belong to, so a large resident set
+// can be traced to the plugins responsible.
+func (s *Server) handleDebugMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.memoryReport())
+}
+
+// memoryReport measures the process and attributes plugin memory.
+func (s *Server) memoryReport() MemoryReport {
+	s.poolsMu.Lock()
+	pools := make([]*runtime.Pool, 0, len(s.pools))
+	for _, pool := range s.pools {
+		pools = append(pools, pool)
+	}
+	s.poolsMu.Unlock()
+
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	report := MemoryReport{
+		Process: ProcessMemory{
+			RSSBytes:    residentBytes(),
+			GoHeapBytes: stats.HeapInuse,
+			GoSysBytes:  stats.Sys,
+		},
+		Plugins: []PluginMemoryInfo{},
+	}
+
+	for _, build := range runtime.AttributeMemory(pools, s.poolOptions.Modules, s.poolOptions.Compiler) {
+		names := make([]string, len(build.Paths))
+		for i, path := range build.Paths {
+			names[i] = pluginNameFromPath(path)
+		}
+		report.Plugins = append(report.Plugins, PluginMemoryInfo{Plugins: names, PluginMemory: build})
+		report.AttributedBytes += build.TotalBytes
+	}
+	return report
+}
+
+// residentBytes returns the process's resident set size from
+// /proc/self/statm, or 0 where that is unavailable.
+func residentBytes() int64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := bytes.Fields(data)
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseInt(string(fields[1]), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * int64(os.Getpagesize())
+}
+
+// collectCompilerMetrics reports AOT compiler cache counters. It reports
+// nothing when AOT compilation is disabled.
+func (s *Server) collectCompilerMetrics() []metrics.Family {
+	cache := s.poolOptions.Compiler
+	if cache == nil {
+		return nil
+	}
+	stats := cache.Stats()
+
+	return []metrics.Family{
+		{Name: "plugin_aot_cache_hits_total", Help: "Plugin loads served from a cached AOT artifact.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Hits)}}},
+		{Name: "plugin_aot_compilations_total", Help: "Plugins compiled by the AOT compiler.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Compiled)}}},
+		{Name: "plugin_aot_failures_total", Help: "AOT compilations that failed; the plugin was interpreted.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Failures)}}},
+	}
+}
+
+// collectModuleCacheMetrics reports module cache counters. It reports
+// nothing when the cache is disabled.
+func (s *Server) collectModuleCacheMetrics() []metrics.Family {
+	cache := s.poolOptions.Modules
+	if cache == nil {
+		return nil
+	}
+	stats := cache.Stats()
+
+	return []metrics.Family{
+		{Name: "plugin_module_cache_hits_total", Help: "Plugin loads served from a cached parsed module.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Hits)}}},
+		{Name: "plugin_module_cache_misses_total", Help: "Plugin loads that parsed and validated the .wasm file.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Misses)}}},
+		{Name: "plugin_module_cache_modules", Help: "Parsed modules currently cached.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Value: float64(stats.Modules)}}},
+	}
+}
+
+// collectOutboxMetrics reports outbox delivery counters. It reports
+// nothing when plugin effects are disabled.
+func (s *Server) collectOutboxMetrics() []metrics.Family {
+	if s.outbox == nil {
+		return nil
+	}
+	stats, pending := s.outbox.snapshot()
+
+	return []metrics.Family{
+		{Name: "plugin_outbox_pending_effects", Help: "Committed plugin effects awaiting delivery.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Value: float64(pending)}}},
+		{Name: "plugin_outbox_delivered_total", Help: "Plugin effects delivered.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Delivered)}}},
+		{Name: "plugin_outbox_retries_total", Help: "Effect delivery attempts that failed and were rescheduled.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Retries)}}},
+		{Name: "plugin_outbox_dropped_total", Help: "Plugin effects dropped after a permanent delivery failure.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: float64(stats.Dropped)}}},
+	}
+}
+
+// collectLimiterMetrics reports running and rejected executions.
+func (s *Server) collectLimiterMetrics() []metrics.Family {
+	return s.limiter.collectMetrics()
+}
+
+// collectTenantMetrics reports the executions and memory of tenants.
+func (s *Server) collectTenantMetrics() []metrics.Family {
+	if s.tenants == nil {
+		return nil
+	}
+	return s.tenants.collectMetrics()
+}
+
+// collectRateLimitMetrics reports requests rejected by rate limits.
+func (s *Server) collectRateLimitMetrics() []metrics.Family {
+	if s.rateLimits == nil {
+		return nil
+	}
+	return s.rateLimits.collectMetrics()
+}
+
+// collectLocalityMetrics reports where /run requests ran.
+func (s *Server) collectLocalityMetrics() []metrics.Family {
+	if s.locality == nil {
+		return nil
+	}
+	return s.locality.collectMetrics()
+}
+
+// collectCacheStatsMetrics reports the cache state of the plugin dataset.
+func (s *Server) collectCacheStatsMetrics() []metrics.Family {
+	if s.cacheStats == nil {
+		return nil
+	}
+	return s.cacheStats.collectMetrics()
+}
+
+// collectPoolMetrics reports pool stats as metric families.
+func (s *Server) collectPoolMetrics() []metrics.Family {
+	warm := metrics.Family{Name: "plugin_pool_warm_instances", Help: "Idle initialized instances ready for checkout.", Type: metrics.TypeGauge}
+	inUse := metrics.Family{Name: "plugin_pool_in_use_instances", Help: "Instances currently checked out.", Type: metrics.TypeGauge}
+	waiting := metrics.Family{Name: "plugin_pool_waiting_checkouts", Help: "Checkouts blocked because the pool is at its maximum size.", Type: metrics.TypeGauge}
+	created := metrics.Family{Name: "plugin_pool_instantiations_total", Help: "Instances created via full load and init.", Type: metrics.TypeCounter}
+	restored := metrics.Family{Name: "plugin_pool_restores_total", Help: "Instances reset via snapshot restore.", Type: metrics.TypeCounter}
+	evicted := metrics.Family{Name: "plugin_pool_evictions_total", Help: "Instances discarded, by reason.", Type: metrics.TypeCounter}
+	shutdownFailed := metrics.Family{Name: "plugin_pool_shutdown_failures_total", Help: "Discarded instances whose on_shutdown() failed or timed out.", Type: metrics.TypeCounter}
+	wait := metrics.Family{Name: "plugin_pool_checkout_wait_seconds", Help: "Time spent waiting for an instance checkout.", Type: metrics.TypeHistogram}
+
+	for _, info := range s.poolInfos() {
+		labels := []metrics.Label{{Name: "plugin", Value: info.Plugin}}
+
+		warm.Samples = append(warm.Samples, metrics.Sample{Labels: labels, Value: float64(info.Idle)})
+		inUse.Samples = append(inUse.Samples, metrics.Sample{Labels: labels, Value: float64(info.InUse)})
+		waiting.Samples = append(waiting.Samples, metrics.Sample{Labels: labels, Value: float64(info.Waiting)})
+		created.Samples = append(created.Samples, metrics.Sample{Labels: labels, Value: float64(info.Instantiated)})
+		restored.Samples = append(restored.Samples, metrics.Sample{Labels: labels, Value: float64(info.Restored)})
+		shutdownFailed.Samples = append(shutdownFailed.Samples, metrics.Sample{Labels: labels, Value: float64(info.ShutdownFailures)})
+
+		reasons := make([]string, 0, len(info.Evictions))
+		for reason := range info.Evictions {
+			reasons = append(reasons, string(reason))
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			evicted.Samples = append(evicted.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "plugin", Value: info.Plugin}, {Name: "reason", Value: reason}},
+				Value:  float64(info.Evictions[runtime.EvictionReason(reason)]),
+			})
+		}
+
+		histogram := info.CheckoutWait
+		wait.Samples = append(wait.Samples, metrics.Sample{Labels: labels, Histogram: &histogram})
+	}
+
+	// Every VM in the process, whether pooled, checked out, or held
+	// elsewhere; one that keeps growing is a missing Close()
+	handles := runtime.LiveHandles()
+	vms := metrics.Family{Name: "plugin_open_vms", Help: "WasmEdge VMs of plugins loaded and not yet closed.", Type: metrics.TypeGauge,
+		Samples: []metrics.Sample{{Value: float64(handles.VMs)}}}
+	hostModules := metrics.Family{Name: "plugin_open_host_modules", Help: "Host module instances registered with those VMs.", Type: metrics.TypeGauge,
+		Samples: []metrics.Sample{{Value: float64(handles.HostModules)}}}
+
+	// Calls that waited for another call into the same instance; pooled
+	// instances are checked out by one request at a time, so these are
+	// instances shared some other way
+	waits := make(map[string]runtime.Contention)
+	for path, c := range runtime.CallContention() {
+		name := pluginNameFromPath(path)
+		waits[name] = runtime.Contention{Waits: waits[name].Waits + c.Waits, WaitTime: waits[name].WaitTime + c.WaitTime}
+	}
+	names := make([]string, 0, len(waits))
+	for name := range waits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	contended := metrics.Family{Name: "plugin_call_contention_total", Help: "Calls that waited for another call into the same plugin instance.", Type: metrics.TypeCounter}
+	contendedWait := metrics.Family{Name: "plugin_call_contention_wait_seconds_total", Help: "Time calls spent waiting for another call into the same plugin instance.", Type: metrics.TypeCounter}
+	for _, name := range names {
+		labels := []metrics.Label{{Name: "plugin", Value: name}}
+		contended.Samples = append(contended.Samples, metrics.Sample{Labels: labels, Value: float64(waits[name].Waits)})
+		contendedWait.Samples = append(contendedWait.Samples, metrics.Sample{Labels: labels, Value: waits[name].WaitTime.Seconds()})
+	}
+
+	return []metrics.Family{warm, inUse, waiting, created, restored, evicted, shutdownFailed, wait, vms, hostModules, contended, contendedWait}
+}
+
+// pluginNameFromPath returns the plugin name for a resolved .wasm path.
+// Stores lay plugins out as <base>/<name>/<name>.wasm.
+func pluginNameFromPath(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".wasm")
+}
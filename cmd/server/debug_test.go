@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Debug Endpoints", func() {
+	var (
+		server *httptest.Server
+		srv    *Server
+	)
+
+	BeforeEach(func() {
+		srv = NewServer(fluid.NewLocalPluginStore("plugins"))
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", srv.metrics.Handler())
+		mux.HandleFunc("/debug/memory", srv.handleDebugMemory)
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// =========================================================================
+	// TEST: Admin rights
+	// Why: Debug endpoints reveal plugin inputs and memory, and how the
+	//      server is set up; like /admin, only admins may call them.
+	// =========================================================================
+	It("should require admin rights on every endpoint", func() {
+		srv.adminToken = "secret"
+		handlers := map[string]http.HandlerFunc{
+			"/debug/memory":  srv.handleDebugMemory,
+			"/debug/traces":  srv.handleDebugTraces,
+			"/debug/verify":  srv.handleDebugVerify,
+			"/debug/shadow":  srv.handleDebugShadow,
+			"/debug/startup": srv.handleDebugStartup,
+		}
+		for path, handler := range handlers {
+			rec := httptest.NewRecorder()
+			srv.debugOnly(handler)(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized), path)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/debug/memory", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		srv.debugOnly(srv.handleDebugMemory)(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		srv.adminToken = ""
+		rec = httptest.NewRecorder()
+		srv.debugOnly(srv.handleDebugMemory)(rec, httptest.NewRequest(http.MethodGet, "/debug/memory", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	// =========================================================================
+	// TEST: GET /debug/memory
+	// Why: Operators chasing a large resident set need the process figures
+	//      next to the per-plugin breakdown, even before any plugin has run.
+	// =========================================================================
+	Describe("GET /debug/memory", func() {
+		It("should report process memory and no plugins before any run", func() {
+			resp, err := http.Get(server.URL + "/debug/memory")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var report MemoryReport
+			Expect(json.NewDecoder(resp.Body).Decode(&report)).To(Succeed())
+			Expect(report.Plugins).NotTo(BeNil())
+			Expect(report.Plugins).To(BeEmpty())
+			Expect(report.AttributedBytes).To(BeZero())
+			Expect(report.Process.GoHeapBytes).To(BeNumerically(">", 0))
+			Expect(report.Process.GoSysBytes).To(BeNumerically(">=", report.Process.GoHeapBytes))
+		})
+
+		It("should return 405 for POST", func() {
+			resp, err := http.Post(server.URL+"/debug/memory", "application/json", nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	// =========================================================================
+	// TEST: GET /metrics
+	// Why: Pool metric families must be declared even with no samples so
+	//      dashboards and alerts can be built before traffic arrives.
+	// =========================================================================
+	Describe("GET /metrics", func() {
+		It("should expose pool metric families", func() {
+			resp, err := http.Get(server.URL + "/metrics")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring("# TYPE plugin_pool_warm_instances gauge"))
+			Expect(string(body)).To(ContainSubstring("# TYPE plugin_pool_checkout_wait_seconds histogram"))
+			Expect(string(body)).To(MatchRegexp(`(?m)^plugin_open_vms \d+$`))
+		})
+
+		It("should expose the metrics plugins record", func() {
+			Expect(srv.pluginMetrics.Add("shop", "orders_total", 3, "region=eu")).To(Succeed())
+
+			resp, err := http.Get(server.URL + "/metrics")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`plugin_custom_shop_orders_total{plugin="shop",region="eu"} 3`))
+		})
+	})
+})
+
+var _ = Describe("pluginNameFromPath", func() {
+	DescribeTable("name extraction",
+		func(path, expected string) {
+			Expect(pluginNameFromPath(path)).To(Equal(expected))
+		},
+		Entry("local layout", "plugins/hello/hello.wasm", "hello"),
+		Entry("fluid layout", "/mnt/fluid/plugins/transform/transform.wasm", "transform"),
+	)
+})
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+// DefaultDedupTTL is how long the outcome of a request carrying a dedup key
+// is kept when DEDUP_TTL is unset, i.e. how late a redelivery is still
+// recognized.
+const DefaultDedupTTL = 24 * time.Hour
+
+// dedupPruneInterval bounds how often expired records are removed.
+const dedupPruneInterval = time.Minute
+
+// dedupScope is what a dedup key is unique within: the same key sent by
+// another tenant or client, or to another plugin, is a different request.
+type dedupScope struct {
+	Tenant    string // Resolved tenant; empty without tenants
+	Principal string // Name of the authenticated client; empty without auth
+	Plugin    string
+}
+
+// dedupRecord is the stored outcome of a request carrying a dedup key.
+type dedupRecord struct {
+	Tenant    string    `json:"tenant,omitempty"`
+	Principal string    `json:"principal,omitempty"`
+	Plugin    string    `json:"plugin"`
+	Key       string    `json:"key"`
+	Done      time.Time `json:"done"`
+	Response  Response  `json:"response"`
+}
+
+// deduplicator runs requests carrying a dedup key at most once per scope
+// and key: the response of the first successful run is recorded, and
+// redeliveries within the TTL get it back instead of running the plugin
+// again. Failed runs are not recorded, so they may be retried.
+//
+// Records are kept in memory, or as one JSON file per key in dir so that
+// they survive restarts. Requests are only deduplicated within one server
+// process; replicas sharing dir see each other's completed records but not
+// their in-flight ones.
+type deduplicator struct {
+	ttl time.Duration
+	dir string // Empty keeps records in memory
+
+	mu        sync.Mutex
+	inFlight  map[string]bool
+	records   map[string]dedupRecord // Used when dir is empty
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// newDeduplicator creates a deduplicator keeping records for ttl, in dir if
+// it is not empty. The directory is created if needed.
+func newDeduplicator(ttl time.Duration, dir string) (*deduplicator, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create dedup directory: %w", err)
+		}
+	}
+	return &deduplicator{
+		ttl:      ttl,
+		dir:      dir,
+		inFlight: make(map[string]bool),
+		records:  make(map[string]dedupRecord),
+		now:      time.Now,
+	}, nil
+}
+
+// dedupID identifies a key within its scope; it is also the record's file
+// name.
+func dedupID(scope dedupScope, key string) string {
+	id := scope.Plugin + "\x00" + key
+	if scope.Tenant != "" || scope.Principal != "" {
+		// Records of requests without either keep the IDs they were
+		// written with
+		id = scope.Tenant + "\x00" + scope.Principal + "\x00" + id
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// claim returns the recorded response for the key in scope, or nil if the
+// request should run; the caller must then call complete or release. A key
+// whose request is still running is rejected with CodeDuplicateRequest.
+func (d *deduplicator) claim(scope dedupScope, key string) (*Response, error) {
+	id := dedupID(scope, key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune()
+
+	if d.inFlight[id] {
+		return nil, apierror.Wrap(apierror.CodeDuplicateRequest,
+			fmt.Errorf("a request to %s with dedup key %q is still running", scope.Plugin, key))
+	}
+	record, ok, err := d.lookup(id)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.CodeInternal, err)
+	}
+	if ok {
+		resp := record.Response
+		return &resp, nil
+	}
+	d.inFlight[id] = true
+	return nil, nil
+}
+
+// complete records the response of a claimed key and releases it.
+func (d *deduplicator) complete(scope dedupScope, key string, resp Response) error {
+	id := dedupID(scope, key)
+	record := dedupRecord{
+		Tenant:    scope.Tenant,
+		Principal: scope.Principal,
+		Plugin:    scope.Plugin,
+		Key:       key,
+		Done:      d.now(),
+		Response:  resp,
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, id)
+
+	if d.dir == "" {
+		d.records[id] = record
+		return nil
+	}
+	if err := writeRecord(d.dir, id, record); err != nil {
+		return fmt.Errorf("failed to write dedup record: %w", err)
+	}
+	return nil
+}
+
+// writeRecord stores v as JSON in dir/<id>.json. It writes a temporary file
+// and renames it into place, so a crash never leaves a truncated record.
+func writeRecord(dir, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, id+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// release gives up a claimed key without recording an outcome, so the
+// request can be retried.
+func (d *deduplicator) release(scope dedupScope, key string) {
+	d.mu.Lock()
+	delete(d.inFlight, dedupID(scope, key))
+	d.mu.Unlock()
+}
+
+// lookup returns the unexpired record for id. Called with mu held.
+func (d *deduplicator) lookup(id string) (dedupRecord, bool, error) {
+	if d.dir == "" {
+		record, ok := d.records[id]
+		if !ok || d.expired(record) {
+			delete(d.records, id)
+			return dedupRecord{}, false, nil
+		}
+		return record, true, nil
+	}
+
+	path := filepath.Join(d.dir, id+".json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return dedupRecord{}, false, nil
+	}
+	if err != nil {
+		return dedupRecord{}, false, fmt.Errorf("failed to read dedup record: %w", err)
+	}
+	var record dedupRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return dedupRecord{}, false, fmt.Errorf("failed to decode dedup record %s: %w", path, err)
+	}
+	if d.expired(record) {
+		os.Remove(path)
+		return dedupRecord{}, false, nil
+	}
+	return record, true, nil
+}
+
+// expired reports whether a record is older than the TTL.
+func (d *deduplicator) expired(record dedupRecord) bool {
+	return d.now().Sub(record.Done) >= d.ttl
+}
+
+// prune removes expired records, at most once per dedupPruneInterval.
+// Called with mu held.
+func (d *deduplicator) prune() {
+	now := d.now()
+	if now.Sub(d.lastPrune) < dedupPruneInterval {
+		return
+	}
+	d.lastPrune = now
+
+	if d.dir == "" {
+		for id, record := range d.records {
+			if d.expired(record) {
+				delete(d.records, id)
+			}
+		}
+		return
+	}
+
+	// File modification times stand in for Done, saving a read per record
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err == nil && now.Sub(info.ModTime()) >= d.ttl {
+			os.Remove(filepath.Join(d.dir, entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request deduplication", func() {
+	// =========================================================================
+	// TEST: Replay protection
+	// Why: Event sources redeliver after consumer restarts; a side-effecting
+	//      plugin must run once per dedup key, while failed attempts stay
+	//      retryable and records expire with the TTL.
+	// =========================================================================
+	output := 43
+	recorded := Response{Output: &output}
+	hello := dedupScope{Plugin: "hello"}
+
+	for _, mode := range []string{"memory", "directory"} {
+		Context("with records in "+mode, func() {
+			var (
+				d     *deduplicator
+				clock time.Time
+			)
+
+			BeforeEach(func() {
+				dir := ""
+				if mode == "directory" {
+					dir = filepath.Join(GinkgoT().TempDir(), "dedup")
+				}
+				var err error
+				d, err = newDeduplicator(time.Hour, dir)
+				Expect(err).NotTo(HaveOccurred())
+				clock = time.Now()
+				d.now = func() time.Time { return clock }
+			})
+
+			It("should return the recorded response to a redelivery", func() {
+				resp, err := d.claim(hello, "order-1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp).To(BeNil())
+				Expect(d.complete(hello, "order-1", recorded)).To(Succeed())
+
+				resp, err = d.claim(hello, "order-1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp).NotTo(BeNil())
+				Expect(*resp.Output).To(Equal(43))
+			})
+
+			It("should scope keys to the plugin", func() {
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+				Expect(d.complete(hello, "order-1", recorded)).To(Succeed())
+
+				Expect(d.claim(dedupScope{Plugin: "other"}, "order-1")).To(BeNil())
+			})
+
+			It("should scope keys to the tenant and client", func() {
+				acme := dedupScope{Tenant: "acme", Principal: "orders", Plugin: "hello"}
+				Expect(d.claim(acme, "order-1")).To(BeNil())
+				Expect(d.complete(acme, "order-1", recorded)).To(Succeed())
+
+				Expect(d.claim(dedupScope{Tenant: "globex", Principal: "orders", Plugin: "hello"}, "order-1")).To(BeNil())
+				Expect(d.claim(dedupScope{Tenant: "acme", Principal: "billing", Plugin: "hello"}, "order-1")).To(BeNil())
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+				Expect(d.claim(acme, "order-1")).NotTo(BeNil())
+			})
+
+			It("should reject a key whose request is still running", func() {
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+
+				_, err := d.claim(hello, "order-1")
+				Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeDuplicateRequest))
+			})
+
+			It("should let a released key run again", func() {
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+				d.release(hello, "order-1")
+
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+			})
+
+			It("should forget records after the TTL", func() {
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+				Expect(d.complete(hello, "order-1", recorded)).To(Succeed())
+
+				clock = clock.Add(time.Hour)
+				Expect(d.claim(hello, "order-1")).To(BeNil())
+			})
+		})
+	}
+
+	It("should keep records in the directory across restarts", func() {
+		dir := GinkgoT().TempDir()
+		d, err := newDeduplicator(time.Hour, dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.claim(hello, "order-1")).To(BeNil())
+		Expect(d.complete(hello, "order-1", recorded)).To(Succeed())
+
+		restarted, err := newDeduplicator(time.Hour, dir)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := restarted.claim(hello, "order-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp).NotTo(BeNil())
+		Expect(*resp.Output).To(Equal(43))
+	})
+
+	Describe("POST /run", func() {
+		var srv *Server
+
+		BeforeEach(func() {
+			srv = NewServer(fluid.NewLocalPluginStore(filepath.Join("..", "..", "plugins")))
+		})
+
+		run := func(body string) (*httptest.ResponseRecorder, Response) {
+			req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body))
+			rec := httptest.NewRecorder()
+			srv.handleRun(rec, req)
+			var resp Response
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			return rec, resp
+		}
+
+		It("should reject an unusable dedup key", func() {
+			rec, _ := run(`{"plugin": "hello", "dedup_key": "` + strings.Repeat("x", 200) + `"}`)
+
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("dedup_key"))
+		})
+
+		It("should not record a failed request", func() {
+			rec, _ := run(`{"plugin": "missing", "dedup_key": "order-1"}`)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+
+			rec, _ = run(`{"plugin": "missing", "dedup_key": "order-1"}`)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("should replay the first response without running the plugin again", func() {
+			if _, err := os.Stat(filepath.Join("..", "..", "plugins", "hello", "hello.wasm")); os.IsNotExist(err) {
+				Skip("Test plugin not found: hello.wasm")
+			}
+
+			rec, first := run(`{"plugin": "hello", "input": 21, "dedup_key": "order-1"}`)
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			Expect(first.Replayed).To(BeFalse())
+
+			// A different input proves the recorded response is returned
+			rec, second := run(`{"plugin": "hello", "input": 1, "dedup_key": "order-1"}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(second.Replayed).To(BeTrue())
+			Expect(*second.Output).To(Equal(*first.Output))
+		})
+	})
+})
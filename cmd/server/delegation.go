@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/hostext"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// delegation is who a plugin's outbound call acts for: the principal of
+// the request that ran the plugin.
+type delegation struct {
+	Subject   string `json:"subject"` // Principal name
+	Plugin    string `json:"plugin"`
+	Tenant    string `json:"tenant,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// delegationOf returns the delegation of a call of plugin made with ctx.
+// Calls without a principal, like scheduled and batch runs, have none.
+func delegationOf(ctx context.Context, plugin string) *delegation {
+	p, ok := principalOf(ctx)
+	if !ok {
+		return nil
+	}
+	info := runtime.CallInfoFrom(ctx)
+	return &delegation{Subject: p.Name, Plugin: plugin, Tenant: info.Tenant, RequestID: info.RequestID}
+}
+
+// delegatedClaims are the claims of a delegated token. act names the
+// plugin acting for sub (RFC 8693); scope the only call it is good for.
+type delegatedClaims struct {
+	Issuer    string     `json:"iss"`
+	Subject   string     `json:"sub"`
+	Audience  string     `json:"aud"`
+	IssuedAt  int64      `json:"iat"`
+	Expires   int64      `json:"exp"`
+	ID        string     `json:"jti"`
+	Scope     string     `json:"scope"`
+	Actor     actorClaim `json:"act"`
+	Tenant    string     `json:"tenant,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+}
+
+// actorClaim is the act claim of a delegated token.
+type actorClaim struct {
+	Subject string `json:"sub"`
+}
+
+// delegator signs the short-lived tokens a plugin's outbound calls carry,
+// so that the systems they reach see the client the plugin runs for
+// rather than a credential shared by every plugin and client. Receivers
+// verify them against the key served at /.well-known/jwks.json.
+type delegator struct {
+	key    ed25519.PrivateKey
+	keyID  string
+	issuer string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// loadDelegator reads the PKCS #8 PEM Ed25519 key at path.
+func loadDelegator(path, issuer string, ttl time.Duration) (*delegator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return newDelegator(edKey, issuer, ttl), nil
+}
+
+// newDelegator creates a delegator signing with key.
+func newDelegator(key ed25519.PrivateKey, issuer string, ttl time.Duration) *delegator {
+	d := &delegator{key: key, issuer: issuer, ttl: ttl, now: time.Now}
+	// The key ID is the JWK thumbprint (RFC 7638)
+	thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, d.publicJWK().X)))
+	d.keyID = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	return d
+}
+
+// mint returns a token for a call made under grant to target, a URL,
+// that is good for scope only. Its audience is the URL's origin.
+func (d *delegator) mint(grant *delegation, target, scope string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid audience %q", target)
+	}
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	now := d.now()
+	claims := delegatedClaims{
+		Issuer:    d.issuer,
+		Subject:   grant.Subject,
+		Audience:  u.Scheme + "://" + u.Host,
+		IssuedAt:  now.Unix(),
+		Expires:   now.Add(d.ttl).Unix(),
+		ID:        hex.EncodeToString(id[:]),
+		Scope:     scope,
+		Actor:     actorClaim{Subject: "plugin:" + grant.Plugin},
+		Tenant:    grant.Tenant,
+		RequestID: grant.RequestID,
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": d.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(d.key, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// publicJWK returns the public key tokens are verified with.
+func (d *delegator) publicJWK() jwk {
+	public := d.key.Public().(ed25519.PublicKey)
+	return jwk{Kty: "OKP", Kid: d.keyID, Use: "sig", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public)}
+}
+
+// bridgeCredentials implements hostext.Credentials: a call to a host
+// module bridge carries a token for that function, when the plugin runs
+// for an authenticated client.
+func (s *Server) bridgeCredentials(ctx context.Context, audience string, req hostext.RPCRequest) (string, error) {
+	if s.delegator == nil {
+		return "", nil
+	}
+	grant := delegationOf(ctx, req.Plugin)
+	if grant == nil {
+		return "", nil
+	}
+	return s.delegator.mint(grant, audience, "hostext:"+req.Module+"."+req.Function)
+}
+
+// JWKS is the response of GET /.well-known/jwks.json.
+type JWKS struct {
+	Keys []jwk `json:"keys"`
+}
+
+// handleJWKS handles GET /.well-known/jwks.json
+//
+// Publishes the key delegated tokens are signed with, so that the systems
+// plugins call can verify them like the tokens of any other issuer.
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.delegator == nil {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "token delegation is disabled")
+		return
+	}
+	w.Header().Set("Cache-Control", "max-age=300")
+	writeJSON(w, http.StatusOK, JWKS{Keys: []jwk{s.delegator.publicJWK()}})
+}
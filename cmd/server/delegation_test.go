@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/hostext"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token delegation", func() {
+	// =========================================================================
+	// TEST: Delegated tokens
+	// Why: The systems a plugin calls must see the client it runs for, in a
+	//      token they can verify that is good for one call only, and never
+	//      a token when there is no client to act for.
+	// =========================================================================
+	var (
+		d     *delegator
+		clock time.Time
+		grant = &delegation{Subject: "ci", Plugin: "relay", Tenant: "payments", RequestID: "req-1"}
+	)
+
+	BeforeEach(func() {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		d = newDelegator(key, "wasm-plugin-server", time.Minute)
+		clock = time.Unix(1700000000, 0)
+		d.now = func() time.Time { return clock }
+	})
+
+	// verify checks token against the delegator's published key and
+	// returns its claims.
+	verify := func(token string) delegatedClaims {
+		parts := strings.Split(token, ".")
+		Expect(parts).To(HaveLen(3))
+		header, err := base64.RawURLEncoding.DecodeString(parts[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(header)).To(ContainSubstring(`"kid":"` + d.keyID + `"`))
+
+		jwk := d.publicJWK()
+		public, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		Expect(err).NotTo(HaveOccurred())
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ed25519.Verify(public, []byte(parts[0]+"."+parts[1]), signature)).To(BeTrue())
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		Expect(err).NotTo(HaveOccurred())
+		var claims delegatedClaims
+		Expect(json.Unmarshal(payload, &claims)).To(Succeed())
+		return claims
+	}
+
+	It("should sign tokens for the call's origin and scope", func() {
+		token, err := d.mint(grant, "https://inventory.internal:8443/call?x=1", "hostext:inventory.stock")
+		Expect(err).NotTo(HaveOccurred())
+
+		claims := verify(token)
+		Expect(claims.Issuer).To(Equal("wasm-plugin-server"))
+		Expect(claims.Subject).To(Equal("ci"))
+		Expect(claims.Actor.Subject).To(Equal("plugin:relay"))
+		Expect(claims.Audience).To(Equal("https://inventory.internal:8443"))
+		Expect(claims.Scope).To(Equal("hostext:inventory.stock"))
+		Expect(claims.Tenant).To(Equal("payments"))
+		Expect(claims.RequestID).To(Equal("req-1"))
+		Expect(claims.IssuedAt).To(Equal(clock.Unix()))
+		Expect(claims.Expires).To(Equal(clock.Add(time.Minute).Unix()))
+
+		// Every token is unique, so receivers can refuse replays
+		again, err := d.mint(grant, "https://inventory.internal:8443/call", "hostext:inventory.stock")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verify(again).ID).NotTo(Equal(claims.ID))
+
+		_, err = d.mint(grant, "not a url", "hostext:inventory.stock")
+		Expect(err).To(MatchError(ContainSubstring("invalid audience")))
+	})
+
+	It("should load a PKCS #8 Ed25519 key", func() {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		path := filepath.Join(GinkgoT().TempDir(), "delegation.pem")
+		Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)).To(Succeed())
+
+		loaded, err := loadDelegator(path, "issuer", time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.publicJWK().X).To(Equal(base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))))
+
+		Expect(os.WriteFile(path, []byte("not a key"), 0o600)).To(Succeed())
+		_, err = loadDelegator(path, "issuer", time.Minute)
+		Expect(err).To(MatchError(ContainSubstring("no PEM block")))
+	})
+
+	It("should only mint bridge credentials for calls with a principal", func() {
+		s := &Server{delegator: d}
+		req := hostext.RPCRequest{Module: "inventory", Function: "stock", Plugin: "relay"}
+
+		token, err := s.bridgeCredentials(context.Background(), "http://inventory.internal", req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(BeEmpty())
+
+		ctx := withPrincipal(context.Background(), &principal{Name: "ci"})
+		ctx = runtime.WithCallInfo(ctx, runtime.CallInfo{RequestID: "req-2"})
+		token, err = s.bridgeCredentials(ctx, "http://inventory.internal", req)
+		Expect(err).NotTo(HaveOccurred())
+		claims := verify(token)
+		Expect(claims.Subject).To(Equal("ci"))
+		Expect(claims.Scope).To(Equal("hostext:inventory.stock"))
+		Expect(claims.RequestID).To(Equal("req-2"))
+
+		token, err = (&Server{}).bridgeCredentials(ctx, "http://inventory.internal", req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(BeEmpty())
+	})
+
+	It("should sign the effects of calls with a principal", func() {
+		var headers []string
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = append(headers, r.Header.Get("Authorization"))
+		}))
+		DeferCleanup(receiver.Close)
+		u, err := url.Parse(receiver.URL)
+		Expect(err).NotTo(HaveOccurred())
+		outbox, err := newOutboxDispatcher([]string{u.Hostname()}, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+		Expect(err).NotTo(HaveOccurred())
+		outbox.delegator = d
+
+		effect := runtime.Effect{Kind: runtime.EffectHTTP, Target: receiver.URL + "/hook", Plugin: "relay"}
+		Expect(outbox.commit("req-1", grant, []runtime.Effect{effect})).To(Succeed())
+		Expect(outbox.commit("req-2", nil, []runtime.Effect{effect})).To(Succeed())
+		outbox.deliverDue(context.Background())
+
+		Expect(headers).To(HaveLen(2))
+		Expect(headers[1]).To(BeEmpty())
+		claims := verify(strings.TrimPrefix(headers[0], "Bearer "))
+		Expect(claims.Audience).To(Equal(receiver.URL))
+		Expect(claims.Scope).To(Equal("outbox:http"))
+	})
+
+	It("should publish the signing key", func() {
+		rec := httptest.NewRecorder()
+		(&Server{}).handleJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+
+		s := &Server{delegator: d}
+		rec = httptest.NewRecorder()
+		s.handleJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var keys JWKS
+		Expect(json.Unmarshal(rec.Body.Bytes(), &keys)).To(Succeed())
+		Expect(keys.Keys).To(Equal([]jwk{d.publicJWK()}))
+		Expect(keys.Keys[0].Kid).To(Equal(d.keyID))
+
+		rec = httptest.NewRecorder()
+		s.handleJWKS(rec, httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// demoPlugins are the sample plugins demo mode serves: hello, computing
+// (input * 2) + 1, and upper, upper-casing text. They are written in
+// WebAssembly text (demo/*.wat) rather than C++ like the plugins in
+// plugins/, so the binaries are small enough to check in and embed.
+//
+//go:embed demo/*.wasm
+var demoPlugins embed.FS
+
+// seedDemo publishes the sample plugins to store and returns their names.
+func seedDemo(store fluid.PluginWriter) ([]string, error) {
+	files, err := fs.Glob(demoPlugins, "demo/*.wasm")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".wasm")
+		f, err := demoPlugins.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		_, err = store.Put(name, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish demo plugin %s: %w", name, err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// demoExamples returns curl commands trying the sample plugins against the
+// HTTP API listening on addr.
+func demoExamples(addr string, tls bool) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+	// A wildcard address accepts local connections too
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	base := scheme + "://" + host
+	if port != "" {
+		base = scheme + "://" + net.JoinHostPort(host, port)
+	}
+
+	var b strings.Builder
+	b.WriteString("Demo mode is on. Try the sample plugins:\n\n")
+	fmt.Fprintf(&b, "  curl %s/plugins\n", base)
+	fmt.Fprintf(&b, "  curl -X POST %s/run -d '{\"plugin\": \"hello\", \"input\": 5}'\n", base)
+	fmt.Fprintf(&b, "  curl -X POST %s/run -d '{\"plugin\": \"upper\", \"text\": \"hello, wasm\"}'\n", base)
+	fmt.Fprintf(&b, "  curl %s/version\n", base)
+	fmt.Fprintf(&b, "\nor open the playground at %s/ui/\n", base)
+	return b.String()
+}
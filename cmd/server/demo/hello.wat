@@ -0,0 +1,22 @@
+;; Hello plugin of demo mode: plugins/hello/hello.cpp in WebAssembly text.
+;;
+;; Regenerate hello.wasm after editing this file:
+;;
+;;   wat2wasm cmd/server/demo/hello.wat -o cmd/server/demo/hello.wasm
+(module
+  (memory (export "memory") 1)
+  (global $initialized (mut i32) (i32.const 0))
+
+  (func (export "init") (result i32)
+    (global.set $initialized (i32.const 1))
+    (i32.const 0))
+
+  ;; Compute: (input * 2) + 1
+  (func (export "process") (param $input i32) (result i32)
+    (if (result i32) (i32.eqz (global.get $initialized))
+      (then (i32.const -1))
+      (else (i32.add (i32.shl (local.get $input) (i32.const 1)) (i32.const 1)))))
+
+  (func (export "cleanup") (result i32)
+    (global.set $initialized (i32.const 0))
+    (i32.const 0)))
@@ -0,0 +1,71 @@
+;; Upper plugin of demo mode: plugins/upper/upper.cpp in WebAssembly text.
+;; process_bytes() returns its input with ASCII letters upper-cased.
+;;
+;; Regenerate upper.wasm after editing this file:
+;;
+;;   wat2wasm cmd/server/demo/upper.wat -o cmd/server/demo/upper.wasm
+(module
+  (memory (export "memory") 2)
+  (global $initialized (mut i32) (i32.const 0))
+  ;; Bump allocator over the memory above 1 KiB, reset once every buffer
+  ;; is freed
+  (global $heap_top (mut i32) (i32.const 1024))
+  (global $live_allocations (mut i32) (i32.const 0))
+
+  (func (export "init") (result i32)
+    (global.set $initialized (i32.const 1))
+    (i32.const 0))
+
+  (func (export "process") (param $input i32) (result i32)
+    (if (result i32) (i32.eqz (global.get $initialized))
+      (then (i32.const -1))
+      (else (local.get $input))))
+
+  (func (export "cleanup") (result i32)
+    (global.set $initialized (i32.const 0))
+    (global.set $heap_top (i32.const 1024))
+    (global.set $live_allocations (i32.const 0))
+    (i32.const 0))
+
+  (func $allocate (export "allocate") (param $size i32) (result i32)
+    (local $aligned i32) (local $ptr i32)
+    (if (i32.lt_s (local.get $size) (i32.const 0))
+      (then (return (i32.const 0))))
+    ;; Keep buffers 8-byte aligned
+    (local.set $aligned (i32.and (i32.add (local.get $size) (i32.const 7)) (i32.const -8)))
+    (if (i32.gt_u (local.get $aligned)
+                  (i32.sub (i32.shl (memory.size) (i32.const 16)) (global.get $heap_top)))
+      (then (return (i32.const 0))))
+    (local.set $ptr (global.get $heap_top))
+    (global.set $heap_top (i32.add (global.get $heap_top) (local.get $aligned)))
+    (global.set $live_allocations (i32.add (global.get $live_allocations) (i32.const 1)))
+    (local.get $ptr))
+
+  (func (export "deallocate") (param $ptr i32) (param $size i32)
+    (if (global.get $live_allocations)
+      (then
+        (global.set $live_allocations (i32.sub (global.get $live_allocations) (i32.const 1)))
+        (if (i32.eqz (global.get $live_allocations))
+          (then (global.set $heap_top (i32.const 1024)))))))
+
+  (func (export "process_bytes") (param $ptr i32) (param $len i32) (result i64)
+    (local $out i32) (local $i i32) (local $c i32)
+    (if (i32.eqz (global.get $initialized))
+      (then (return (i64.const -1))))
+    (if (i32.lt_s (local.get $len) (i32.const 0))
+      (then (return (i64.const -3))))
+    (if (i32.eqz (local.tee $out (call $allocate (local.get $len))))
+      (then (return (i64.const -4))))
+    (local.set $i (i32.const 0))
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (local.get $len)))
+        (local.set $c (i32.load8_u (i32.add (local.get $ptr) (local.get $i))))
+        (if (i32.lt_u (i32.sub (local.get $c) (i32.const 97)) (i32.const 26))
+          (then (local.set $c (i32.sub (local.get $c) (i32.const 32)))))
+        (i32.store8 (i32.add (local.get $out) (local.get $i)) (local.get $c))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    ;; Pack the output location: high 32 bits pointer, low 32 bits length
+    (i64.or (i64.shl (i64.extend_i32_u (local.get $out)) (i64.const 32))
+            (i64.extend_i32_u (local.get $len)))))
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Demo mode", func() {
+	newDemoServer := func() *Server {
+		store, err := fluid.NewMemoryPluginStore()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(store.Close)
+		Expect(seedDemo(store)).To(Equal([]string{"hello", "upper"}))
+		return NewServer(store)
+	}
+
+	// =========================================================================
+	// TEST: Sample plugins
+	// Why: Demo mode is how new users first meet the server; the plugins it
+	//      embeds must be listed and must run the requests it suggests.
+	// =========================================================================
+	It("should publish the sample plugins to the store", func() {
+		srv := newDemoServer()
+
+		plugins, err := srv.store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(2))
+		Expect(plugins[0].Name).To(Equal("hello"))
+		Expect(plugins[1].Name).To(Equal("upper"))
+	})
+
+	It("should run the sample plugins", func() {
+		srv := newDemoServer()
+		run := func(body string) Response {
+			rec := httptest.NewRecorder()
+			srv.handleRun(rec, httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body)))
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			var response Response
+			Expect(json.Unmarshal(rec.Body.Bytes(), &response)).To(Succeed())
+			return response
+		}
+
+		Expect(run(`{"plugin": "hello", "input": 5}`).Output).To(HaveValue(Equal(11)))
+		Expect(run(`{"plugin": "upper", "text": "hello, wasm"}`).Text).To(HaveValue(Equal("HELLO, WASM")))
+	})
+
+	It("should print examples against the listening address", func() {
+		examples := demoExamples("[::]:8080", false)
+		Expect(examples).To(ContainSubstring("curl http://localhost:8080/plugins\n"))
+		Expect(examples).To(ContainSubstring(`curl -X POST http://localhost:8080/run -d '{"plugin": "hello", "input": 5}'`))
+		Expect(examples).To(ContainSubstring("http://localhost:8080/ui/\n"))
+
+		Expect(demoExamples("10.0.0.7:8443", true)).To(ContainSubstring("curl https://10.0.0.7:8443/plugins\n"))
+	})
+})
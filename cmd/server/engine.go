@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	goruntime "runtime"
+	"runtime/debug"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// VersionInfo is the body of GET /version: the server build, the engine
+// it runs on, and the execution limits it enforces.
+type VersionInfo struct {
+	Version  string               `json:"version"` // Module version of the build, "(devel)" for local builds
+	Go       string               `json:"go"`      // Go version the server was built with
+	Engine   runtime.Capabilities `json:"engine"`
+	Features Features             `json:"features"`
+}
+
+// Features are the execution limits in force. A limit the configuration
+// asks for but the engine cannot enforce is off and listed in Degraded,
+// so clients and operators can tell it apart from one never configured.
+type Features struct {
+	WallClockLimits   bool     `json:"wall_clock_limits"`  // Calls are interrupted after EXECUTION_TIMEOUT
+	InstructionLimits bool     `json:"instruction_limits"` // Calls are stopped after EXECUTION_MAX_INSTRUCTIONS
+	Degraded          []string `json:"degraded,omitempty"` // Configured features the engine cannot provide
+}
+
+// Names of features in Features.Degraded
+const featureInstructionLimits = "instruction_limits"
+
+// features returns the execution limits the server enforces.
+func (s *Server) features() Features {
+	features := Features{WallClockLimits: s.execTimeout > 0}
+	if s.poolOptions.MaxInstructions > 0 {
+		if s.engine.Statistics {
+			features.InstructionLimits = true
+		} else {
+			features.Degraded = append(features.Degraded, featureInstructionLimits)
+		}
+	}
+	return features
+}
+
+// primaryEngine returns the engine plugins run on (execution.engine).
+func (s *Server) primaryEngine() runtime.Engine {
+	if s.poolOptions.Engine == nil {
+		return runtime.WasmEdge
+	}
+	return s.poolOptions.Engine
+}
+
+// diagnose returns the warnings about instance after a call. Only WasmEdge
+// instances report any.
+func diagnose(instance runtime.Instance) []runtime.Warning {
+	if plugin, ok := instance.(*runtime.Plugin); ok {
+		return plugin.Diagnose()
+	}
+	return nil
+}
+
+// checkEngine logs a warning for every configured feature the engine
+// cannot provide. The server runs without them rather than refusing to
+// start, bounded by what it can still enforce.
+func (s *Server) checkEngine() {
+	for _, feature := range s.features().Degraded {
+		switch feature {
+		case featureInstructionLimits:
+			fallback := "nothing bounds plugin calls"
+			if s.execTimeout > 0 {
+				fallback = "plugin calls are bounded by the execution timeout only"
+			}
+			reason := "WasmEdge statistics are unavailable"
+			if engine := s.primaryEngine(); engine != runtime.WasmEdge {
+				reason = "engine " + engine.Name() + " does not count instructions"
+			}
+			s.logger.Warn(reason+", instruction limits are not enforced; "+fallback,
+				slog.Uint64("max_instructions", s.poolOptions.MaxInstructions),
+				slog.Duration("timeout", s.execTimeout),
+				slog.String("wasmedge", s.engine.Version))
+		}
+	}
+}
+
+// handleVersion serves GET /version.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, VersionInfo{
+		Version:  buildVersion(),
+		Go:       goruntime.Version(),
+		Engine:   s.engine,
+		Features: s.features(),
+	})
+}
+
+// buildVersion returns the module version of the server build, or
+// "(devel)".
+func buildVersion() string {
+	if build, ok := debug.ReadBuildInfo(); ok && build.Main.Version != "" {
+		return build.Main.Version
+	}
+	return "(devel)"
+}
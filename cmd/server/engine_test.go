@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("GET /version", func() {
+	var srv *Server
+
+	BeforeEach(func() {
+		srv = NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+	})
+
+	version := func() VersionInfo {
+		rec := httptest.NewRecorder()
+		srv.handleVersion(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+
+		var info VersionInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &info)).To(Succeed())
+		return info
+	}
+
+	It("should report the build and the engine", func() {
+		info := version()
+		Expect(info.Version).NotTo(BeEmpty())
+		Expect(info.Go).To(HavePrefix("go"))
+		Expect(info.Engine).To(Equal(runtime.EngineCapabilities()))
+		Expect(info.Features.WallClockLimits).To(BeTrue())
+		Expect(info.Features.InstructionLimits).To(BeFalse())
+		Expect(info.Features.Degraded).To(BeEmpty())
+	})
+
+	It("should reject other methods", func() {
+		rec := httptest.NewRecorder()
+		srv.handleVersion(rec, httptest.NewRequest(http.MethodPost, "/version", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	// =========================================================================
+	// TEST: Degraded limits
+	// Why: Without WasmEdge statistics an instruction limit cannot be
+	//      enforced. The server must still start, warn about it, and say so
+	//      at /version, rather than leave operators believing it is enforced.
+	// =========================================================================
+	It("should report instruction limits the engine cannot enforce as degraded", func() {
+		var logs bytes.Buffer
+		srv.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+		srv.engine = runtime.Capabilities{Version: "0.13.5"}
+		srv.poolOptions.MaxInstructions = 1_000_000
+		srv.execTimeout = 5 * time.Second
+
+		srv.checkEngine()
+		Expect(logs.String()).To(ContainSubstring("instruction limits are not enforced"))
+		Expect(logs.String()).To(ContainSubstring("bounded by the execution timeout only"))
+
+		info := version()
+		Expect(info.Features.InstructionLimits).To(BeFalse())
+		Expect(info.Features.WallClockLimits).To(BeTrue())
+		Expect(info.Features.Degraded).To(ConsistOf("instruction_limits"))
+
+		// Nothing else bounds calls without a timeout either
+		logs.Reset()
+		srv.execTimeout = 0
+		srv.checkEngine()
+		Expect(logs.String()).To(ContainSubstring("nothing bounds plugin calls"))
+		Expect(version().Features.WallClockLimits).To(BeFalse())
+	})
+
+	It("should report instruction limits as degraded on another engine", func() {
+		wazero, err := runtime.LookupEngine("wazero")
+		Expect(err).NotTo(HaveOccurred())
+		var logs bytes.Buffer
+		srv.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+		srv.poolOptions.Engine = wazero
+		srv.engine = runtime.Capabilities{Name: "wazero", Version: "0.13.5"}
+		srv.poolOptions.MaxInstructions = 1_000_000
+
+		srv.checkEngine()
+		Expect(logs.String()).To(ContainSubstring("engine wazero does not count instructions"))
+		info := version()
+		Expect(info.Engine.Name).To(Equal("wazero"))
+		Expect(info.Features.Degraded).To(ConsistOf("instruction_limits"))
+	})
+
+	It("should report instruction limits as enforced with statistics", func() {
+		var logs bytes.Buffer
+		srv.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+		srv.engine = runtime.Capabilities{Version: "0.13.5", Statistics: true}
+		srv.poolOptions.MaxInstructions = 1_000_000
+
+		srv.checkEngine()
+		Expect(logs.String()).To(BeEmpty())
+		Expect(version().Features).To(Equal(Features{WallClockLimits: true, InstructionLimits: true}))
+	})
+})
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// EventStreamType is the media type of Server-Sent Events. POST /run
+// requests accepting it get the progress the plugin reports as a stream of
+// events, ended by the result.
+const EventStreamType = "text/event-stream"
+
+// Events of a /run event stream
+const (
+	EventProgress = "progress" // data: ProgressEvent
+	EventResult   = "result"   // data: Response
+	EventError    = "error"    // data: apierror.Problem
+)
+
+// ProgressEvent is the data of a progress event.
+type ProgressEvent struct {
+	Percent int `json:"percent"`
+}
+
+// acceptsEventStream reports whether the request's Accept header asks for
+// Server-Sent Events.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == EventStreamType {
+			return true
+		}
+	}
+	return false
+}
+
+// eventStream writes the events of a /run request. The response starts
+// with the first event, so that requests failing before the plugin reports
+// any progress get the usual problem response with its status.
+type eventStream struct {
+	w http.ResponseWriter
+	r *http.Request
+
+	mu      sync.Mutex
+	started bool
+	ended   bool // No more events are written once the result is
+	percent int  // Last progress sent, to skip repeats
+}
+
+// runEvents runs a /run request whose client accepts an event stream.
+func (s *Server) runEvents(ctx context.Context, w http.ResponseWriter, r *http.Request, req Request, call runtime.CallInfo) error {
+	events := &eventStream{w: w, r: r, percent: -1}
+	resp, err := s.run(runtime.WithProgress(ctx, events.progress), req, call)
+
+	// A plugin outliving its interrupted call may still report progress
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	events.ended = true
+	if err != nil {
+		if !events.started {
+			writeExecutionError(w, r, err)
+			return err
+		}
+		problem := localizedProblem(w, r, apierror.CodeOf(err), err.Error())
+		problem.PluginError = pluginErrorOf(err)
+		events.send(EventError, problem)
+		return err
+	}
+	events.send(EventResult, resp)
+	return nil
+}
+
+// progress sends a progress event, unless the percentage is unchanged.
+func (e *eventStream) progress(percent int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ended || percent == e.percent {
+		return
+	}
+	e.percent = percent
+	e.send(EventProgress, ProgressEvent{Percent: percent})
+}
+
+// send writes an event and flushes it to the client. e.mu must be held.
+func (e *eventStream) send(event string, data interface{}) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", EventStreamType)
+		e.w.Header().Set("Cache-Control", "no-cache")
+		e.w.WriteHeader(http.StatusOK)
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	// A client gone away cancels the call through the request context
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, encoded)
+	http.NewResponseController(e.w).Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Event streams", func() {
+	// =========================================================================
+	// TEST: Choosing the response
+	// Why: Only clients asking for Server-Sent Events may get them; every
+	//      other client relies on the JSON response of POST /run.
+	// =========================================================================
+	It("should stream events only to clients accepting them", func() {
+		accepts := func(accept string) bool {
+			req := httptest.NewRequest(http.MethodPost, "/run", nil)
+			req.Header.Set("Accept", accept)
+			return acceptsEventStream(req)
+		}
+		Expect(accepts("text/event-stream")).To(BeTrue())
+		Expect(accepts("application/json, text/event-stream;q=0.9")).To(BeTrue())
+		Expect(accepts("application/json")).To(BeFalse())
+		Expect(accepts("")).To(BeFalse())
+	})
+
+	// =========================================================================
+	// TEST: Event encoding
+	// Why: Clients show every progress event; repeats would only add noise,
+	//      and nothing may follow the event ending the stream.
+	// =========================================================================
+	It("should send changed progress until the stream ends", func() {
+		rec := httptest.NewRecorder()
+		events := &eventStream{w: rec, r: httptest.NewRequest(http.MethodPost, "/run", nil), percent: -1}
+		events.progress(10)
+		events.progress(10)
+		events.progress(60)
+
+		events.mu.Lock()
+		events.ended = true
+		events.send(EventResult, Response{Version: "1.0.0"})
+		events.mu.Unlock()
+		events.progress(80)
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(EventStreamType))
+		Expect(rec.Body.String()).To(Equal(
+			"event: progress\ndata: {\"percent\":10}\n\n" +
+				"event: progress\ndata: {\"percent\":60}\n\n" +
+				"event: result\ndata: " + mustJSON(Response{Version: "1.0.0"}) + "\n\n"))
+	})
+
+	It("should end a started stream with an error event", func() {
+		rec := httptest.NewRecorder()
+		events := &eventStream{w: rec, r: httptest.NewRequest(http.MethodPost, "/run", nil), percent: -1}
+		events.progress(50)
+
+		events.mu.Lock()
+		problem := localizedProblem(rec, events.r, apierror.CodePluginTimeout, "timed out")
+		events.send(EventError, problem)
+		events.mu.Unlock()
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		Expect(lines).To(HaveLen(5))
+		Expect(lines[3]).To(Equal("event: error"))
+		var sent apierror.Problem
+		Expect(json.Unmarshal([]byte(strings.TrimPrefix(lines[4], "data: ")), &sent)).To(Succeed())
+		Expect(sent.Code).To(Equal(apierror.CodePluginTimeout))
+	})
+
+	// =========================================================================
+	// TEST: Failing before the first event
+	// Why: Until the plugin reports progress nothing is sent, so requests
+	//      that cannot run keep the status code of their problem.
+	// =========================================================================
+	It("should answer requests failing before any progress with a problem", func() {
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		// Not a valid module, so the call fails to load it
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+
+		for plugin, code := range map[string]apierror.Code{
+			"missing": apierror.CodePluginNotFound,
+			"hello":   apierror.CodePluginLoadFailed,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(`{"plugin": "`+plugin+`", "input": 1}`))
+			req.Header.Set("Accept", EventStreamType)
+			rec := httptest.NewRecorder()
+			srv.handleRun(rec, req)
+
+			Expect(rec.Header().Get("Content-Type")).To(Equal(apierror.ContentType))
+			var problem apierror.Problem
+			Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+			Expect(problem.Code).To(Equal(code))
+			Expect(rec.Code).To(Equal(problem.Status))
+		}
+	})
+})
+
+// mustJSON returns the JSON encoding of v.
+func mustJSON(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	Expect(err).NotTo(HaveOccurred())
+	return string(encoded)
+}
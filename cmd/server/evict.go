@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// Reasons a plugin's pool is evicted, as EvictedPool.Reason.
+const (
+	evictIdle      = "idle"      // No request used it for eviction.idle_timeout
+	evictMemory    = "memory"    // The pools held more than eviction.max_memory_mib
+	evictInstances = "instances" // The pools held more than eviction.max_instances
+	evictManual    = "manual"    // POST /admin/evict
+)
+
+// evictReasons lists the reasons in the order metrics report them.
+var evictReasons = []string{evictIdle, evictMemory, evictInstances, evictManual}
+
+// EvictedPool is a plugin build whose pool was evicted.
+type EvictedPool struct {
+	Plugin      string `json:"plugin"`
+	Reason      string `json:"reason"`
+	Instances   int    `json:"instances"`    // Live instances the pool had, idle or checked out
+	MemoryBytes int64  `json:"memory_bytes"` // Their linear memory and snapshots
+}
+
+// EvictRequest is the body of POST /admin/evict.
+type EvictRequest struct {
+	// Plugin names the plugin whose pools are evicted, in every version, or
+	// with @version only that build; empty evicts every pool
+	Plugin string `json:"plugin,omitempty"`
+}
+
+// EvictResponse is the response of POST /admin/evict.
+type EvictResponse struct {
+	Evicted []EvictedPool `json:"evicted"`
+}
+
+// evictionPolicy bounds what the pools of plugins no request uses keep
+// in memory. Zero values disable a bound.
+type evictionPolicy struct {
+	idleTimeout  time.Duration
+	maxMemory    int64 // Bytes of linear memory and snapshots across pools
+	maxInstances int   // Live instances across pools
+	interval     time.Duration
+}
+
+// poolUse is a pool with what the eviction policy weighs.
+type poolUse struct {
+	path     string
+	pool     *runtime.Pool
+	lastUsed time.Time
+	inUse    int
+	memory   runtime.PoolMemory
+}
+
+// poolUses returns every pool, least recently used first.
+func (s *Server) poolUses() []poolUse {
+	s.poolsMu.Lock()
+	uses := make([]poolUse, 0, len(s.pools))
+	for path, pool := range s.pools {
+		uses = append(uses, poolUse{path: path, pool: pool, lastUsed: s.poolUsed[path]})
+	}
+	s.poolsMu.Unlock()
+
+	for i := range uses {
+		uses[i].inUse = uses[i].pool.Stats().InUse
+		uses[i].memory = uses[i].pool.Memory()
+	}
+	sort.Slice(uses, func(i, j int) bool { return uses[i].lastUsed.Before(uses[j].lastUsed) })
+	return uses
+}
+
+// runEviction applies the eviction policy every interval until ctx is
+// done.
+func (s *Server) runEviction(ctx context.Context) {
+	ticker := time.NewTicker(s.eviction.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.enforceEviction(now)
+		}
+	}
+}
+
+// enforceEviction evicts the pools the policy no longer allows at now:
+// first those idle for too long, then, while the pools together exceed
+// the memory or instance bound, the least recently used ones. Pools with
+// calls in progress are left alone; they were just used.
+func (s *Server) enforceEviction(now time.Time) []EvictedPool {
+	policy := s.eviction
+	var evicted []EvictedPool
+	var memory int64
+	var instances int
+	var remaining []poolUse
+	for _, use := range s.poolUses() {
+		if policy.idleTimeout > 0 && use.inUse == 0 && now.Sub(use.lastUsed) >= policy.idleTimeout {
+			if e, ok := s.evict(use, evictIdle); ok {
+				evicted = append(evicted, e)
+				continue
+			}
+		}
+		memory += use.memory.LinearMemoryBytes + use.memory.SnapshotBytes
+		instances += use.memory.Instances
+		remaining = append(remaining, use)
+	}
+
+	for _, use := range remaining {
+		reason := ""
+		switch {
+		case policy.maxMemory > 0 && memory > policy.maxMemory:
+			reason = evictMemory
+		case policy.maxInstances > 0 && instances > policy.maxInstances:
+			reason = evictInstances
+		default:
+			return evicted
+		}
+		if use.inUse > 0 {
+			continue
+		}
+		if e, ok := s.evict(use, reason); ok {
+			evicted = append(evicted, e)
+			memory -= e.MemoryBytes
+			instances -= e.Instances
+		}
+	}
+	return evicted
+}
+
+// evict closes the pool of use, unless it was replaced in the meantime,
+// and releases the build's cached module. Calls still running finish on
+// their instances, which are then discarded. The build's AOT artifact is
+// kept, so that loading it again is cheap.
+func (s *Server) evict(use poolUse, reason string) (EvictedPool, bool) {
+	s.poolsMu.Lock()
+	if s.pools[use.path] != use.pool {
+		s.poolsMu.Unlock()
+		return EvictedPool{}, false
+	}
+	delete(s.pools, use.path)
+	delete(s.poolUsed, use.path)
+	s.poolsMu.Unlock()
+
+	use.pool.Close()
+	if s.poolOptions.Modules != nil {
+		s.poolOptions.Modules.Forget(use.path)
+	}
+	s.evictions[reason].Inc()
+
+	evicted := EvictedPool{
+		Plugin:      pluginNameFromPath(use.path),
+		Reason:      reason,
+		Instances:   use.memory.Instances,
+		MemoryBytes: use.memory.LinearMemoryBytes + use.memory.SnapshotBytes,
+	}
+	s.logger.LogAttrs(context.Background(), slog.LevelInfo, "evicted plugin pool",
+		slog.String("plugin", evicted.Plugin),
+		slog.String("reason", reason),
+		slog.Int("instances", evicted.Instances),
+		slog.Int64("memory_bytes", evicted.MemoryBytes))
+	return evicted, true
+}
+
+// collectEvictionMetrics reports the pools evicted, by reason.
+func (s *Server) collectEvictionMetrics() []metrics.Family {
+	family := metrics.Family{Name: "plugin_pools_evicted_total", Help: "Plugin pools closed by eviction, by reason.", Type: metrics.TypeCounter}
+	for _, reason := range evictReasons {
+		family.Samples = append(family.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "reason", Value: reason}},
+			Value:  float64(s.evictions[reason].Value()),
+		})
+	}
+	return []metrics.Family{family}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Pool eviction", func() {
+	const token = "secret"
+	var srv *Server
+
+	BeforeEach(func() {
+		srv = NewServer(fluid.NewLocalPluginStore(filepath.Join("..", "..", "plugins")))
+		srv.adminToken = token
+	})
+
+	// warm creates the pools of the plugins, skipping if one is not built.
+	warm := func(names ...string) {
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join("..", "..", "plugins", name, name+".wasm")); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + name + ".wasm")
+			}
+		}
+		for _, result := range srv.warmup(names) {
+			Expect(result.Error).To(BeEmpty())
+		}
+	}
+
+	// lastUsed backdates the last use of the plugin's pool.
+	lastUsed := func(name string, at time.Time) {
+		srv.poolsMu.Lock()
+		defer srv.poolsMu.Unlock()
+		for path := range srv.pools {
+			if pluginNameFromPath(path) == name {
+				srv.poolUsed[path] = at
+			}
+		}
+	}
+
+	pooled := func() []string {
+		var names []string
+		for _, info := range srv.poolInfos() {
+			names = append(names, info.Plugin)
+		}
+		return names
+	}
+
+	// =========================================================================
+	// TEST: Eviction policy
+	// Why: A long-running server must not keep a pool, with its VMs and
+	//      memory, for every plugin it ever ran. Pools in recent use must
+	//      survive, so that eviction never turns hot plugins cold.
+	// =========================================================================
+	It("should evict pools idle for longer than the idle timeout", func() {
+		warm("hello", "upper")
+		srv.eviction = evictionPolicy{idleTimeout: time.Minute}
+		lastUsed("hello", time.Now().Add(-2*time.Minute))
+
+		evicted := srv.enforceEviction(time.Now())
+		Expect(evicted).To(ConsistOf(HaveField("Plugin", "hello")))
+		Expect(evicted[0].Reason).To(Equal(evictIdle))
+		Expect(evicted[0].Instances).To(BeNumerically(">=", 1))
+		Expect(pooled()).To(ConsistOf("upper"))
+		Expect(srv.evictions[evictIdle].Value()).To(BeEquivalentTo(1))
+	})
+
+	It("should evict the least recently used pools while over the instance bound", func() {
+		warm("hello", "upper", "calc")
+		srv.eviction = evictionPolicy{maxInstances: 1}
+		lastUsed("upper", time.Now().Add(-3*time.Minute))
+		lastUsed("calc", time.Now().Add(-2*time.Minute))
+		lastUsed("hello", time.Now().Add(-time.Minute))
+
+		evicted := srv.enforceEviction(time.Now())
+		Expect(evicted).To(HaveLen(2))
+		Expect(evicted[0].Plugin).To(Equal("upper"))
+		Expect(evicted[1].Plugin).To(Equal("calc"))
+		Expect(evicted[0].Reason).To(Equal(evictInstances))
+		Expect(pooled()).To(ConsistOf("hello"))
+	})
+
+	It("should keep pools within the bounds", func() {
+		warm("hello")
+		srv.eviction = evictionPolicy{idleTimeout: time.Hour, maxMemory: 1 << 40, maxInstances: 100}
+		Expect(srv.enforceEviction(time.Now())).To(BeEmpty())
+		Expect(pooled()).To(ConsistOf("hello"))
+	})
+
+	// =========================================================================
+	// TEST: POST /admin/evict
+	// Why: Operators free memory on demand, e.g. ahead of a batch run; the
+	//      endpoint changes what the server holds, so it needs admin rights.
+	// =========================================================================
+	evict := func(body string, authorized bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/evict", bytes.NewBufferString(body))
+		if authorized {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		newAdminAPI(srv, config.Default(), nil, new(slog.LevelVar)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("should evict the pools of the plugin named", func() {
+		warm("hello", "upper")
+
+		rec := evict(`{"plugin": "hello"}`, true)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var resp EvictResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.Evicted).To(ConsistOf(HaveField("Reason", evictManual)))
+		Expect(pooled()).To(ConsistOf("upper"))
+
+		// Its next request creates the pool again
+		warm("hello")
+		Expect(pooled()).To(ConsistOf("hello", "upper"))
+
+		rec = evict("", true)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(pooled()).To(BeEmpty())
+	})
+
+	It("should require admin rights", func() {
+		Expect(evict("", false).Code).To(Equal(http.StatusUnauthorized))
+
+		srv.adminToken = ""
+		Expect(evict("", true).Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should reject invalid requests", func() {
+		Expect(evict(`{"plugin": "../etc"}`, true).Code).To(Equal(http.StatusBadRequest))
+		Expect(evict(`{"plugin": "hello@9.9.9"}`, true).Code).To(Equal(http.StatusNotFound))
+		Expect(evict(`{`, true).Code).To(Equal(http.StatusBadRequest))
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/evict", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		newAdminAPI(srv, config.Default(), nil, new(slog.LevelVar)).ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
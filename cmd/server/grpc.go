@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mrhapile/wasm-plugin-system/api/pluginpb"
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// grpcRequestIDKey is the metadata key carrying the request ID of an
+// Execute call, the gRPC counterpart of RequestIDHeader.
+const grpcRequestIDKey = "x-request-id"
+
+// grpcTenantKey and grpcCallerKey are the metadata counterparts of
+// TenantHeader and CallerHeader.
+const (
+	grpcTenantKey = "x-tenant"
+	grpcCallerKey = "x-caller"
+)
+
+// firstValue returns the first value of a metadata key, or "".
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// errorDomain is the domain of the ErrorInfo attached to gRPC errors.
+const errorDomain = "wasm-plugin-system"
+
+// grpcServer implements the gRPC PluginService on top of a Server, so both
+// APIs share its plugin store, pools, and error codes.
+type grpcServer struct {
+	pluginpb.UnimplementedPluginServiceServer
+	server *Server
+}
+
+// newGRPCServer creates a gRPC server exposing s as PluginService.
+func newGRPCServer(s *Server, opts ...grpc.ServerOption) *grpc.Server {
+	g := grpc.NewServer(append(opts, grpc.ChainUnaryInterceptor(s.authInterceptor))...)
+	pluginpb.RegisterPluginServiceServer(g, &grpcServer{server: s})
+	return g
+}
+
+// Execute runs a plugin, the gRPC counterpart of POST /run.
+func (g *grpcServer) Execute(ctx context.Context, in *pluginpb.ExecuteRequest) (*pluginpb.ExecuteResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := requestIDOf(firstValue(md, grpcRequestIDKey))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(grpcRequestIDKey, requestID))
+
+	ctx, span := startRequestSpan(ctx, strings.TrimPrefix(pluginpb.PluginService_Execute_FullMethodName, "/"), metadataCarrier(md), requestID)
+	var err error
+	defer func() { endRequestSpan(span, err) }()
+
+	call, err := callInfoOf(ctx, requestID, firstValue(md, grpcTenantKey), firstValue(md, grpcCallerKey))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	ctx = g.server.grpcRateClient(ctx, md)
+
+	req := Request{
+		Plugin:      in.GetPlugin(),
+		TimeoutMs:   int(in.GetTimeoutMs()),
+		IncludeLogs: in.GetIncludeLogs(),
+		DedupKey:    in.GetDedupKey(),
+		Debug:       in.GetDebug(),
+		Version:     in.GetVersion(),
+	}
+	switch input := in.GetInput().(type) {
+	case *pluginpb.ExecuteRequest_Number:
+		req.Input = int(input.Number)
+	case *pluginpb.ExecuteRequest_Text:
+		req.Text = &input.Text
+	case *pluginpb.ExecuteRequest_Data:
+		// An empty payload is still a payload request
+		req.Data = append([]byte{}, input.Data...)
+	}
+
+	annotateRequestSpan(ctx, req)
+
+	resp, err := g.server.run(ctx, req, call)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return executeResponse(resp), nil
+}
+
+// ListPlugins lists the runnable plugins, the gRPC counterpart of
+// GET /plugins.
+func (g *grpcServer) ListPlugins(ctx context.Context, _ *pluginpb.ListPluginsRequest) (*pluginpb.ListPluginsResponse, error) {
+	plugins, err := g.server.store.List()
+	if err != nil {
+		return nil, grpcError(apierror.Wrap(apierror.CodeInternal, fmt.Errorf("failed to list plugins: %w", err)))
+	}
+
+	out := &pluginpb.ListPluginsResponse{}
+	for _, p := range runnablePlugins(plugins) {
+		out.Plugins = append(out.Plugins, pluginInfo(p))
+	}
+	return out, nil
+}
+
+// GetPluginInfo returns a single runnable plugin.
+func (g *grpcServer) GetPluginInfo(ctx context.Context, in *pluginpb.GetPluginInfoRequest) (*pluginpb.PluginInfo, error) {
+	if err := checkPluginName(in.GetPlugin()); err != nil {
+		return nil, grpcError(err)
+	}
+
+	plugins, err := g.server.store.List()
+	if err != nil {
+		return nil, grpcError(apierror.Wrap(apierror.CodeInternal, fmt.Errorf("failed to list plugins: %w", err)))
+	}
+	// The build Execute would run, e.g. the latest version for a bare name
+	name, version := fluid.ParseReference(in.GetPlugin())
+	if path, err := g.server.store.Resolve(in.GetPlugin()); err == nil {
+		version = fluid.BuildVersion(path)
+	}
+	for _, p := range plugins {
+		if p.Name == name && p.Version == version {
+			return pluginInfo(p), nil
+		}
+	}
+	return nil, grpcError(apierror.Wrap(apierror.CodePluginNotFound,
+		fmt.Errorf("plugin not found: %s", in.GetPlugin())))
+}
+
+// grpcError converts an error carrying an apierror code into a gRPC status.
+// The code is attached as the reason of an ErrorInfo detail, together with
+// the plugin's own error if it reported the failure.
+func grpcError(err error) error {
+	code := apierror.CodeOf(err)
+	info := &errdetails.ErrorInfo{Reason: string(code), Domain: errorDomain}
+
+	var pluginErr *runtime.PluginError
+	if errors.As(err, &pluginErr) {
+		info.Metadata = map[string]string{
+			"plugin_error_code":    strconv.Itoa(int(pluginErr.Code)),
+			"plugin_error_name":    pluginErr.Name(),
+			"plugin_error_message": pluginErr.Message,
+		}
+	}
+
+	st := status.New(grpcCode(code), err.Error())
+	if detailed, derr := st.WithDetails(info); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// grpcCode maps an apierror code to the gRPC code matching its HTTP status.
+func grpcCode(code apierror.Code) codes.Code {
+	switch code.Status() {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// executeResponse converts a /run response to its protobuf form.
+func executeResponse(resp Response) *pluginpb.ExecuteResponse {
+	out := &pluginpb.ExecuteResponse{Replayed: resp.Replayed, Effects: int32(resp.Effects), Version: resp.Version}
+	if resp.Output != nil {
+		output := int32(*resp.Output)
+		out.Output = &output
+	}
+	switch {
+	case resp.Text != nil:
+		out.Payload = &pluginpb.ExecuteResponse_Text{Text: *resp.Text}
+	case resp.Data != nil:
+		out.Payload = &pluginpb.ExecuteResponse_Data{Data: resp.Data}
+	}
+	for _, w := range resp.Warnings {
+		out.Warnings = append(out.Warnings, &pluginpb.Warning{Code: string(w.Code), Message: w.Message})
+	}
+	for _, entry := range resp.Logs {
+		out.Logs = append(out.Logs, &pluginpb.LogEntry{
+			Time:      timestamppb.New(entry.Time),
+			Level:     entry.Level.String(),
+			Plugin:    entry.Plugin,
+			RequestId: entry.RequestID,
+			Message:   entry.Message,
+		})
+	}
+	return out
+}
+
+// pluginInfo converts a store entry to its protobuf form.
+func pluginInfo(p fluid.PluginInfo) *pluginpb.PluginInfo {
+	return &pluginpb.PluginInfo{
+		Name:     p.Name,
+		Size:     p.Size,
+		ModTime:  timestamppb.New(p.ModTime),
+		Manifest: manifestInfo(p.Manifest),
+		Version:  p.Version,
+	}
+}
+
+// manifestInfo converts a manifest to its protobuf form; nil stays nil.
+func manifestInfo(m *manifest.Manifest) *pluginpb.Manifest {
+	if m == nil {
+		return nil
+	}
+	return &pluginpb.Manifest{
+		Name:        m.Name,
+		Version:     m.Version,
+		AbiVersion:  int32(m.ABIVersion),
+		Description: m.Description,
+		Exports:     m.Exports,
+		Limits: &pluginpb.Limits{
+			MemoryPages: int32(m.Limits.MemoryPages),
+			TimeoutMs:   int32(m.Limits.TimeoutMs),
+		},
+		Sizing: &pluginpb.Sizing{
+			ExpectedQps:       m.Sizing.ExpectedQPS,
+			LatencyTargetMs:   int32(m.Sizing.LatencyTargetMs),
+			InstanceMemoryMib: int32(m.Sizing.InstanceMemoryMiB),
+		},
+		Config:    m.Config,
+		Reentrant: m.Reentrant,
+	}
+}
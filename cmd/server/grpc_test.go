@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mrhapile/wasm-plugin-system/api/pluginpb"
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("gRPC PluginService", func() {
+	var (
+		pluginsDir string
+		grpcSrv    *grpc.Server
+		conn       *grpc.ClientConn
+		client     pluginpb.PluginServiceClient
+	)
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		for _, name := range []string{"hello", "echo", "bad.name"} {
+			Expect(os.MkdirAll(filepath.Join(pluginsDir, name), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(pluginsDir, name, name+".wasm"), []byte("wasm"), 0644)).To(Succeed())
+		}
+	})
+
+	// serve starts the service over an in-memory connection
+	serve := func(store fluid.PluginStore) {
+		listener := bufconn.Listen(1 << 20)
+		grpcSrv = newGRPCServer(NewServer(store))
+		go grpcSrv.Serve(listener)
+
+		var err error
+		conn, err = grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		client = pluginpb.NewPluginServiceClient(conn)
+	}
+
+	AfterEach(func() {
+		if conn != nil {
+			conn.Close()
+			conn = nil
+		}
+		if grpcSrv != nil {
+			grpcSrv.Stop()
+			grpcSrv = nil
+		}
+	})
+
+	// expectCode checks the gRPC code and the apierror code in ErrorInfo
+	expectCode := func(err error, want codes.Code, reason apierror.Code) {
+		st, ok := status.FromError(err)
+		Expect(ok).To(BeTrue(), "not a gRPC status: %v", err)
+		Expect(st.Code()).To(Equal(want))
+
+		var infos []*errdetails.ErrorInfo
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.ErrorInfo); ok {
+				infos = append(infos, info)
+			}
+		}
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].GetReason()).To(Equal(string(reason)))
+	}
+
+	// =========================================================================
+	// TEST: Plugin discovery over gRPC
+	// Why: ListPlugins must agree with GET /plugins so callers can switch
+	//      transports without seeing a different catalog.
+	// =========================================================================
+	It("should list runnable plugins", func() {
+		serve(fluid.NewLocalPluginStore(pluginsDir))
+
+		resp, err := client.ListPlugins(context.Background(), &pluginpb.ListPluginsRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.GetPlugins()).To(HaveLen(2))
+		Expect(resp.GetPlugins()[0].GetName()).To(Equal("echo"))
+		Expect(resp.GetPlugins()[1].GetName()).To(Equal("hello"))
+		Expect(resp.GetPlugins()[1].GetSize()).To(Equal(int64(4)))
+		Expect(resp.GetPlugins()[1].GetModTime().AsTime()).NotTo(BeZero())
+	})
+
+	It("should return Internal when the store cannot be listed", func() {
+		serve(fluid.NewFluidPluginStore(filepath.Join(pluginsDir, "unmounted")))
+
+		_, err := client.ListPlugins(context.Background(), &pluginpb.ListPluginsRequest{})
+		expectCode(err, codes.Internal, apierror.CodeInternal)
+	})
+
+	Describe("GetPluginInfo", func() {
+		It("should return the plugin and its manifest", func() {
+			Expect(os.WriteFile(filepath.Join(pluginsDir, "hello", "plugin.json"),
+				[]byte(`{"name": "hello", "version": "1.2.0", "config": {"factor": 2}}`), 0644)).To(Succeed())
+			serve(fluid.NewLocalPluginStore(pluginsDir))
+
+			info, err := client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{Plugin: "hello"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.GetName()).To(Equal("hello"))
+			Expect(info.GetManifest().GetVersion()).To(Equal("1.2.0"))
+			Expect(info.GetManifest().GetConfig()).To(MatchJSON(`{"factor": 2}`))
+		})
+
+		It("should return the build a versioned reference resolves to", func() {
+			for _, version := range []string{"1.0.0", "1.1.0"} {
+				dir := filepath.Join(pluginsDir, "hello", version)
+				Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte(version), 0644)).To(Succeed())
+			}
+			serve(fluid.NewLocalPluginStore(pluginsDir))
+
+			info, err := client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{Plugin: "hello@latest"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.GetVersion()).To(Equal("1.1.0"))
+
+			info, err = client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{Plugin: "hello@1.0.0"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.GetSize()).To(Equal(int64(len("1.0.0"))))
+
+			_, err = client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{Plugin: "hello@2.0.0"})
+			expectCode(err, codes.NotFound, apierror.CodePluginNotFound)
+		})
+
+		It("should map name and lookup failures to gRPC codes", func() {
+			serve(fluid.NewLocalPluginStore(pluginsDir))
+
+			_, err := client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{})
+			expectCode(err, codes.InvalidArgument, apierror.CodeMissingPluginName)
+
+			_, err = client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{Plugin: "bad.name"})
+			expectCode(err, codes.InvalidArgument, apierror.CodeInvalidPluginName)
+
+			_, err = client.GetPluginInfo(context.Background(), &pluginpb.GetPluginInfoRequest{Plugin: "missing"})
+			expectCode(err, codes.NotFound, apierror.CodePluginNotFound)
+		})
+	})
+
+	// =========================================================================
+	// TEST: Execute validation
+	// Why: Execute shares the /run validation; its errors must carry the
+	//      same stable codes so clients can branch on them.
+	// =========================================================================
+	Describe("Execute", func() {
+		It("should reject invalid requests", func() {
+			serve(fluid.NewLocalPluginStore(pluginsDir))
+
+			_, err := client.Execute(context.Background(), &pluginpb.ExecuteRequest{})
+			expectCode(err, codes.InvalidArgument, apierror.CodeMissingPluginName)
+
+			_, err = client.Execute(context.Background(), &pluginpb.ExecuteRequest{Plugin: "../etc"})
+			expectCode(err, codes.InvalidArgument, apierror.CodeInvalidPluginName)
+
+			_, err = client.Execute(context.Background(), &pluginpb.ExecuteRequest{Plugin: "hello", TimeoutMs: -1})
+			expectCode(err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+
+			_, err = client.Execute(context.Background(), &pluginpb.ExecuteRequest{Plugin: "hello@1.0.0", Version: "1.1.0"})
+			expectCode(err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), grpcTenantKey, "team a")
+			_, err = client.Execute(ctx, &pluginpb.ExecuteRequest{Plugin: "hello"})
+			expectCode(err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+		})
+
+		It("should return NotFound and echo the request ID", func() {
+			serve(fluid.NewLocalPluginStore(pluginsDir))
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), grpcRequestIDKey, "req-42")
+			var header metadata.MD
+			_, err := client.Execute(ctx, &pluginpb.ExecuteRequest{Plugin: "missing"}, grpc.Header(&header))
+			expectCode(err, codes.NotFound, apierror.CodePluginNotFound)
+			Expect(header.Get(grpcRequestIDKey)).To(Equal([]string{"req-42"}))
+		})
+
+		It("should execute a plugin", func() {
+			pluginsDir, err := filepath.Abs(filepath.Join("..", "..", "plugins"))
+			Expect(err).NotTo(HaveOccurred())
+			if _, err := os.Stat(filepath.Join(pluginsDir, "hello", "hello.wasm")); os.IsNotExist(err) {
+				Skip("Test plugin not found: hello.wasm")
+			}
+			serve(fluid.NewLocalPluginStore(pluginsDir))
+
+			resp, err := client.Execute(context.Background(), &pluginpb.ExecuteRequest{
+				Plugin: "hello",
+				Input:  &pluginpb.ExecuteRequest_Number{Number: 21},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Output).NotTo(BeNil())
+			Expect(resp.GetOutput()).To(Equal(int32(43)))
+		})
+	})
+})
@@ -3,12 +3,21 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/config"
 	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
@@ -75,7 +84,7 @@ var _ = Describe("HTTP Server", func() {
 				var response Response
 				err = json.NewDecoder(resp.Body).Decode(&response)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(response.Output).To(Equal(43)) // 21 * 2 + 1 = 43
+				Expect(response.Output).To(HaveValue(Equal(43))) // 21 * 2 + 1 = 43
 			})
 		})
 
@@ -93,9 +102,10 @@ var _ = Describe("HTTP Server", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
 
-				var errorResp ErrorResponse
-				json.NewDecoder(resp.Body).Decode(&errorResp)
-				Expect(errorResp.Error).To(ContainSubstring("invalid JSON"))
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("invalid JSON"))
+				Expect(problem.Code).To(Equal(apierror.CodeInvalidRequest))
 			})
 		})
 
@@ -113,9 +123,228 @@ var _ = Describe("HTTP Server", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
 
-				var errorResp ErrorResponse
-				json.NewDecoder(resp.Body).Decode(&errorResp)
-				Expect(errorResp.Error).To(ContainSubstring("plugin name is required"))
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("plugin name is required"))
+				Expect(problem.Code).To(Equal(apierror.CodeMissingPluginName))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Conflicting payload forms
+		// Why: A request must select exactly one entry point; guessing between
+		//      text and data would silently drop part of the input.
+		// =====================================================================
+		Context("with both text and data", func() {
+			It("should return 400 Bad Request", func() {
+				jsonBody := []byte(`{"plugin": "upper", "text": "hi", "data": "aGk="}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("mutually exclusive"))
+				Expect(problem.Code).To(Equal(apierror.CodeInvalidRequest))
+			})
+		})
+
+		Context("with a JSON payload and text", func() {
+			It("should return 400 Bad Request", func() {
+				jsonBody := []byte(`{"plugin": "envelope", "payload": {"id": 1}, "text": "hi"}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("mutually exclusive"))
+				Expect(problem.Code).To(Equal(apierror.CodeInvalidRequest))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Payloads sent to an integer-only plugin
+		// Why: Clients need a distinct code to tell "wrong plugin for this
+		//      input" apart from a plugin crash.
+		// =====================================================================
+		Context("with a text payload for a plugin without the payload ABI", func() {
+			BeforeEach(func() {
+				pluginPath := filepath.Join("plugins", "hello", "hello.wasm")
+				if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+					Skip("Test plugin not found: " + pluginPath)
+				}
+			})
+
+			It("should return 422 payload_unsupported", func() {
+				originalDir, _ := os.Getwd()
+				os.Chdir(filepath.Join("..", ".."))
+				defer os.Chdir(originalDir)
+
+				jsonBody := []byte(`{"plugin": "hello", "text": "hi"}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Code).To(Equal(apierror.CodePayloadUnsupported))
+			})
+		})
+
+		Context("with a JSON payload for a plugin without process_json", func() {
+			BeforeEach(func() {
+				pluginPath := filepath.Join("plugins", "upper", "upper.wasm")
+				if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+					Skip("Test plugin not found: " + pluginPath)
+				}
+			})
+
+			It("should return 422 payload_unsupported", func() {
+				originalDir, _ := os.Getwd()
+				os.Chdir(filepath.Join("..", ".."))
+				defer os.Chdir(originalDir)
+
+				jsonBody := []byte(`{"plugin": "upper", "payload": {"id": 1}}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Code).To(Equal(apierror.CodePayloadUnsupported))
+				Expect(problem.Detail).To(ContainSubstring("JSON payload ABI"))
+			})
+		})
+
+		// =====================================================================
+		// TEST: JSON payloads
+		// Why: Structured plugins return documents, which must come back as
+		//      JSON in the response rather than as an escaped string.
+		// =====================================================================
+		Context("with a JSON payload for a plugin implementing process_json", func() {
+			BeforeEach(func() {
+				pluginPath := filepath.Join("plugins", "envelope", "envelope.wasm")
+				if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+					Skip("Test plugin not found: " + pluginPath)
+				}
+			})
+
+			It("should return the plugin's document as payload", func() {
+				originalDir, _ := os.Getwd()
+				os.Chdir(filepath.Join("..", ".."))
+				defer os.Chdir(originalDir)
+
+				jsonBody := []byte(`{"plugin": "envelope", "payload": {"id": 1}}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				var result Response
+				Expect(json.NewDecoder(resp.Body).Decode(&result)).To(Succeed())
+				Expect(result.Payload).To(MatchJSON(`{"bytes": 9, "input": {"id": 1}}`))
+				Expect(*result.Output).To(Equal(len(result.Payload)))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Protobuf fields
+		// Why: Fields are converted to typed protobuf values before the call;
+		//      JSON without a protobuf value must be rejected as the client's
+		//      mistake, and integers must reach the plugin as integers.
+		// =====================================================================
+		Context("with fields and a JSON payload", func() {
+			It("should return 400 Bad Request", func() {
+				jsonBody := []byte(`{"plugin": "pbecho", "fields": {"n": 1}, "payload": {"id": 1}}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("mutually exclusive"))
+				Expect(problem.Code).To(Equal(apierror.CodeInvalidRequest))
+			})
+		})
+
+		Context("with fields without a protobuf value", func() {
+			It("should return 400 Bad Request", func() {
+				jsonBody := []byte(`{"plugin": "pbecho", "fields": {"nested": {"n": 1}}}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring(`field "nested"`))
+				Expect(problem.Code).To(Equal(apierror.CodeInvalidRequest))
+			})
+		})
+
+		Context("with fields for a plugin without process_pb", func() {
+			BeforeEach(func() {
+				pluginPath := filepath.Join("plugins", "envelope", "envelope.wasm")
+				if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+					Skip("Test plugin not found: " + pluginPath)
+				}
+			})
+
+			It("should return 422 payload_unsupported", func() {
+				originalDir, _ := os.Getwd()
+				os.Chdir(filepath.Join("..", ".."))
+				defer os.Chdir(originalDir)
+
+				jsonBody := []byte(`{"plugin": "envelope", "fields": {"n": 1}}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Code).To(Equal(apierror.CodePayloadUnsupported))
+				Expect(problem.Detail).To(ContainSubstring("protobuf payload ABI"))
+			})
+		})
+
+		Context("with fields for a plugin implementing process_pb", func() {
+			BeforeEach(func() {
+				pluginPath := filepath.Join("plugins", "pbecho", "pbecho.wasm")
+				if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+					Skip("Test plugin not found: " + pluginPath)
+				}
+			})
+
+			It("should return the plugin's output fields", func() {
+				originalDir, _ := os.Getwd()
+				os.Chdir(filepath.Join("..", ".."))
+				defer os.Chdir(originalDir)
+
+				jsonBody := []byte(`{"plugin": "pbecho", "fields": {"count": 2, "ratio": 0.5, "name": "x"}}`)
+
+				resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				var result map[string]json.RawMessage
+				Expect(json.NewDecoder(resp.Body).Decode(&result)).To(Succeed())
+				Expect(result["fields"]).To(MatchJSON(`{"count": 2, "ratio": 0.5, "name": "x", "fields": 3}`))
 			})
 		})
 
@@ -134,9 +363,10 @@ var _ = Describe("HTTP Server", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
 
-				var errorResp ErrorResponse
-				json.NewDecoder(resp.Body).Decode(&errorResp)
-				Expect(errorResp.Error).To(ContainSubstring("invalid plugin name"))
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("invalid plugin name"))
+				Expect(problem.Code).To(Equal(apierror.CodeInvalidPluginName))
 			})
 
 			It("should return 400 Bad Request for special characters", func() {
@@ -165,9 +395,10 @@ var _ = Describe("HTTP Server", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
 
-				var errorResp ErrorResponse
-				json.NewDecoder(resp.Body).Decode(&errorResp)
-				Expect(errorResp.Error).To(ContainSubstring("plugin not found"))
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("plugin not found"))
+				Expect(problem.Code).To(Equal(apierror.CodePluginNotFound))
 			})
 		})
 
@@ -182,9 +413,10 @@ var _ = Describe("HTTP Server", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
 
-				var errorResp ErrorResponse
-				json.NewDecoder(resp.Body).Decode(&errorResp)
-				Expect(errorResp.Error).To(ContainSubstring("method not allowed"))
+				var problem apierror.Problem
+				json.NewDecoder(resp.Body).Decode(&problem)
+				Expect(problem.Detail).To(ContainSubstring("method not allowed"))
+				Expect(problem.Code).To(Equal(apierror.CodeMethodNotAllowed))
 			})
 		})
 	})
@@ -195,14 +427,87 @@ var _ = Describe("HTTP Server", func() {
 	//      structure.
 	// =========================================================================
 	Describe("Response Format", func() {
-		It("should return application/json Content-Type", func() {
-			reqBody := Request{Plugin: "hello", Input: 21}
+		It("should return application/problem+json Content-Type for errors", func() {
+			reqBody := Request{Plugin: "nonexistent", Input: 21}
 			jsonBody, _ := json.Marshal(reqBody)
 
 			resp, err := http.Post(server.URL+"/run", "application/json", bytes.NewBuffer(jsonBody))
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(resp.Header.Get("Content-Type")).To(Equal(apierror.ContentType))
+		})
+
+		// =====================================================================
+		// TEST: RFC 7807 problem fields
+		// Why: Clients branch on type/code/status; all must be present and
+		//      consistent with the HTTP status line.
+		// =====================================================================
+		It("should return a complete problem document", func() {
+			resp, err := http.Get(server.URL + "/run")
+			Expect(err).NotTo(HaveOccurred())
+
+			var problem apierror.Problem
+			Expect(json.NewDecoder(resp.Body).Decode(&problem)).To(Succeed())
+			Expect(problem.Type).To(Equal("urn:wasm-plugin-system:problem:method_not_allowed"))
+			Expect(problem.Title).NotTo(BeEmpty())
+			Expect(problem.Status).To(Equal(resp.StatusCode))
+			Expect(problem.Instance).To(Equal("/run"))
+		})
+
+		It("should localize the title from Accept-Language", func() {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/run", nil)
+			req.Header.Set("Accept-Language", "es-ES,es;q=0.9,en;q=0.8")
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Header.Get("Content-Language")).To(Equal("es"))
+
+			var problem apierror.Problem
+			Expect(json.NewDecoder(resp.Body).Decode(&problem)).To(Succeed())
+			Expect(problem.Title).To(Equal("Método no permitido"))
+			Expect(problem.Code).To(Equal(apierror.CodeMethodNotAllowed))
+		})
+
+		// =====================================================================
+		// TEST: Warnings envelope
+		// Why: "warnings" is optional - clients written before it existed
+		//      must keep seeing the original {"output": n} shape.
+		// =====================================================================
+		It("should omit warnings when there are none", func() {
+			output := 43
+			body, err := json.Marshal(Response{Output: &output})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"output":43}`))
+		})
+
+		It("should encode a missing output as null", func() {
+			body, err := json.Marshal(Response{})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"output":null}`))
+		})
+
+		It("should include warnings with stable codes", func() {
+			output := 43
+			body, err := json.Marshal(Response{
+				Output:   &output,
+				Warnings: []runtime.Warning{{Code: runtime.WarnDeprecated, Message: "unversioned"}},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"output":43,"warnings":[{"code":"deprecated","message":"unversioned"}]}`))
+		})
+
+		It("should return text payloads as text and byte payloads as base64", func() {
+			text, textLen, dataLen := "HELLO", 5, 2
+			body, err := json.Marshal(Response{Output: &textLen, Text: &text})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"output":5,"text":"HELLO"}`))
+
+			body, err = json.Marshal(Response{Output: &dataLen, Data: []byte{0x00, 0xff}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"output":2,"data":"AP8="}`))
 		})
 	})
 })
@@ -225,6 +530,9 @@ var _ = Describe("isValidPluginName", func() {
 		Entry("with underscore", "my_plugin", true),
 		Entry("with hyphen", "my-plugin", true),
 		Entry("complex valid", "My_Plugin-123", true),
+		Entry("with version", "hello@1.2.0", true),
+		Entry("with pre-release version", "hello@v2.0.0-rc.1", true),
+		Entry("with latest alias", "hello@latest", true),
 
 		// Invalid names
 		Entry("empty string", "", false),
@@ -235,7 +543,472 @@ var _ = Describe("isValidPluginName", func() {
 		Entry("with semicolon", "plugin;rm", false),
 		Entry("path traversal", "../etc", false),
 		Entry("with null byte", "plugin\x00bad", false),
+		Entry("with empty version", "hello@", false),
+		Entry("with non-numeric version", "hello@beta", false),
+		Entry("version traversal", "hello@../1", false),
+		Entry("version only", "@1.0.0", false),
+	)
+})
+
+// =========================================================================
+// TEST: Execution timeouts
+// Why: Requests may tighten the server's limit but never lift it, or a
+//      single client could pin instances with a runaway plugin.
+// =========================================================================
+var _ = Describe("Execution timeout", func() {
+	DescribeTable("timeout selection",
+		func(server, requested, expected time.Duration) {
+			s := &Server{execTimeout: server}
+			Expect(s.timeout(Request{TimeoutMs: int(requested.Milliseconds())})).To(Equal(expected))
+		},
+		Entry("server default", 30*time.Second, time.Duration(0), 30*time.Second),
+		Entry("shorter request", 30*time.Second, 250*time.Millisecond, 250*time.Millisecond),
+		Entry("longer request is capped", 30*time.Second, time.Minute, 30*time.Second),
+		Entry("no server limit", time.Duration(0), time.Second, time.Second),
+		Entry("no limit at all", time.Duration(0), time.Duration(0), time.Duration(0)),
 	)
+
+	It("should reject a negative timeout_ms", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(filepath.Join("..", "..", "plugins")))
+		body := bytes.NewBufferString(`{"plugin": "hello", "input": 1, "timeout_ms": -1}`)
+		req := httptest.NewRequest(http.MethodPost, "/run", body)
+		rec := httptest.NewRecorder()
+
+		srv.handleRun(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("timeout_ms"))
+	})
+
+	It("should return 504 plugin_timeout for a runaway plugin", func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "spin", "spin.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: spin.wasm")
+		}
+
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.poolOptions = runtime.PoolOptions{Reset: runtime.ResetRecreate}
+		body := bytes.NewBufferString(`{"plugin": "spin", "input": 1, "timeout_ms": 100}`)
+		req := httptest.NewRequest(http.MethodPost, "/run", body)
+		rec := httptest.NewRecorder()
+
+		srv.handleRun(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusGatewayTimeout))
+		var problem apierror.Problem
+		Expect(json.NewDecoder(rec.Body).Decode(&problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(apierror.CodePluginTimeout))
+	})
+})
+
+// =========================================================================
+// TEST: Invalid manifest
+// Why: A deployed plugin with a broken plugin.json is an operator error,
+//      not a missing plugin; clients must not be told to check the name.
+// =========================================================================
+var _ = Describe("Invalid manifest", func() {
+	It("should return 500 plugin_load_failed", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, "hello"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "hello", "hello.wasm"), []byte("dummy"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "hello", "plugin.json"), []byte(`{"name": "other", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+		srv := NewServer(fluid.NewLocalPluginStore(dir))
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello", "input": 1}`))
+		rec := httptest.NewRecorder()
+
+		srv.handleRun(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		var problem apierror.Problem
+		Expect(json.NewDecoder(rec.Body).Decode(&problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(apierror.CodePluginLoadFailed))
+		Expect(problem.Detail).To(ContainSubstring("manifest"))
+	})
+})
+
+// =========================================================================
+// TEST: Plugin-reported errors
+// Why: Clients diagnosing a failed call need the plugin's own code and
+//      message as fields, not buried in the detail string.
+// =========================================================================
+var _ = Describe("Plugin errors", func() {
+	It("should include the plugin's code and message in the problem", func() {
+		err := apierror.Wrap(apierror.CodePluginExecutionFailed, fmt.Errorf("failed to execute plugin: %w",
+			&runtime.PluginError{Function: "process", Code: runtime.ABIErrorInvalidInput, Message: "bad input", Path: "calc.wasm"}))
+		rec := httptest.NewRecorder()
+
+		writeExecutionError(rec, httptest.NewRequest(http.MethodPost, "/run", nil), err)
+
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		var problem apierror.Problem
+		Expect(json.NewDecoder(rec.Body).Decode(&problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(apierror.CodePluginExecutionFailed))
+		Expect(problem.PluginError).To(Equal(&apierror.PluginError{
+			Code:    -3,
+			Name:    "ABI_ERROR_INVALID_INPUT",
+			Message: "bad input",
+		}))
+	})
+
+	It("should omit plugin_error for host-side failures", func() {
+		rec := httptest.NewRecorder()
+
+		writeExecutionError(rec, httptest.NewRequest(http.MethodPost, "/run", nil),
+			apierror.Wrap(apierror.CodePluginLoadFailed, fmt.Errorf("failed to load plugin")))
+
+		Expect(rec.Body.String()).NotTo(ContainSubstring("plugin_error"))
+	})
+
+	It("should return the message from last_error() over HTTP", func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "calc", "calc.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: calc.wasm")
+		}
+
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "calc", "input": -1}`))
+		rec := httptest.NewRecorder()
+
+		srv.handleRun(rec, req)
+
+		var problem apierror.Problem
+		Expect(json.NewDecoder(rec.Body).Decode(&problem)).To(Succeed())
+		Expect(problem.PluginError).NotTo(BeNil())
+		Expect(problem.PluginError.Message).To(Equal("input must not be negative"))
+	})
+})
+
+// =========================================================================
+// TEST: No output
+// Why: A side-effect plugin's success must come back as 200 with a null
+//      output, not as a 500, and its instance must be kept for reuse.
+// =========================================================================
+var _ = Describe("No output", func() {
+	It("should return a null output and keep the instance", func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "notify", "notify.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: notify.wasm")
+		}
+
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		for _, body := range []string{`{"plugin": "notify", "input": 1}`, `{"plugin": "notify", "text": "hi"}`} {
+			rec := httptest.NewRecorder()
+			srv.handleRun(rec, httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body)))
+
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			var response Response
+			Expect(json.NewDecoder(rec.Body).Decode(&response)).To(Succeed())
+			Expect(response.Output).To(BeNil())
+			Expect(response.Text).To(BeNil())
+		}
+
+		pools := srv.poolInfos()
+		Expect(pools).To(HaveLen(1))
+		Expect(pools[0].Evictions).To(BeEmpty())
+	})
+})
+
+// =========================================================================
+// TEST: Memory limits
+// Why: A plugin exhausting its memory cap on some input is the request's
+//      problem (422), while one that cannot even start under the cap is a
+//      deployment problem (500); clients must be able to tell them apart.
+// =========================================================================
+var _ = Describe("Memory limits", func() {
+	var pluginsDir string
+
+	BeforeEach(func() {
+		pluginsDir = filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "calc", "calc.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: calc.wasm")
+		}
+	})
+
+	run := func(srv *Server, body string) (*httptest.ResponseRecorder, apierror.Problem) {
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body)))
+		var problem apierror.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		return rec, problem
+	}
+
+	It("should return 500 plugin_load_failed when the plugin does not fit", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.poolOptions.MaxMemoryPages = 1
+
+		rec, problem := run(srv, `{"plugin": "calc", "input": 1}`)
+
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(problem.Code).To(Equal(apierror.CodePluginLoadFailed))
+		Expect(problem.Detail).To(ContainSubstring("memory limit"))
+	})
+
+	It("should return 422 memory_limit_exceeded when a request exhausts the limit", func() {
+		upperPath := filepath.Join(pluginsDir, "upper", "upper.wasm")
+		module, err := wasminfo.Open(upperPath)
+		if errors.Is(err, os.ErrNotExist) {
+			Skip("Test plugin not found: upper.wasm")
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		// No room to grow: a payload larger than upper's heap cannot be allocated
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.poolOptions.MaxMemoryPages = module.MemoryPages
+
+		rec, _ := run(srv, `{"plugin": "upper", "text": "small"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+
+		rec, problem := run(srv, fmt.Sprintf(`{"plugin": "upper", "text": %q}`, strings.Repeat("x", 2<<20)))
+
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity), rec.Body.String())
+		Expect(problem.Code).To(Equal(apierror.CodeMemoryLimitExceeded))
+	})
+})
+
+// =========================================================================
+// TEST: Per-environment plugin config
+// Why: PLUGIN_CONFIG_DIR lets operators parameterize one plugin artifact
+//      per environment without rebuilding it; overlays must apply in order,
+//      be skipped when absent, and be reported when malformed rather than
+//      passed to the plugin.
+// =========================================================================
+var _ = Describe("Plugin config overlays", func() {
+	var srv *Server
+
+	BeforeEach(func() {
+		srv = NewServer(fluid.NewLocalPluginStore(filepath.Join("..", "..", "plugins")))
+		srv.configDir = GinkgoT().TempDir()
+		srv.environment = "prod"
+	})
+
+	writeConfig := func(file, data string) {
+		Expect(os.WriteFile(filepath.Join(srv.configDir, file), []byte(data), 0644)).To(Succeed())
+	}
+
+	It("should return no overlays without files", func() {
+		overlays, err := srv.configOverlays("calc")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overlays).To(BeEmpty())
+	})
+
+	It("should order shared overlays before the environment's", func() {
+		writeConfig("calc.prod.json", `{"offset": 2}`)
+		writeConfig("calc.staging.json", `{"offset": 3}`)
+		writeConfig("calc.json", `{"offset": 1}`)
+
+		overlays, err := srv.configOverlays("calc")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overlays).To(Equal([][]byte{[]byte(`{"offset": 1}`), []byte(`{"offset": 2}`)}))
+	})
+
+	It("should reject an overlay that is not a JSON object", func() {
+		writeConfig("calc.prod.json", `[1, 2]`)
+
+		_, err := srv.configOverlays("calc")
+
+		Expect(err).To(MatchError(ContainSubstring("must be a JSON object")))
+	})
+
+	It("should pass the resolved config to the plugin", func() {
+		if _, err := os.Stat(filepath.Join("..", "..", "plugins", "calc", "calc.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: calc.wasm")
+		}
+		writeConfig("calc.json", `{"offset": 10}`)
+		writeConfig("calc.prod.json", `{"offset": 20}`)
+
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "calc", "input": 1}`)))
+
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+		var response Response
+		Expect(json.NewDecoder(rec.Body).Decode(&response)).To(Succeed())
+		Expect(*response.Output).To(Equal(21))
+	})
+})
+
+// =========================================================================
+// TEST: Pool configuration from the environment
+// Why: A typo in POOL_* must stop the server at startup instead of silently
+//      running with unbounded pools.
+// =========================================================================
+var _ = Describe("Plugin logs", func() {
+	var (
+		srv  *Server
+		logs *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "logger", "logger.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: logger.wasm")
+		}
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		logs = &bytes.Buffer{}
+		srv.logger = slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	})
+
+	run := func(body string, header http.Header) (*httptest.ResponseRecorder, Response) {
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body))
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+		var resp Response
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	It("should return captured logs tagged with the request ID", func() {
+		rec, resp := run(`{"plugin": "logger", "input": 0, "include_logs": true}`,
+			http.Header{RequestIDHeader: {"req-42"}})
+
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+		Expect(rec.Header().Get(RequestIDHeader)).To(Equal("req-42"))
+		Expect(resp.Logs).To(HaveLen(2))
+		Expect(resp.Logs[0].Level).To(Equal(runtime.LogWarn))
+		Expect(resp.Logs[0].Message).To(Equal("input is zero"))
+		Expect(resp.Logs[1].Message).To(Equal("processed input"))
+		for _, entry := range resp.Logs {
+			Expect(entry.Plugin).To(Equal("logger"))
+			Expect(entry.RequestID).To(Equal("req-42"))
+		}
+	})
+
+	It("should omit logs unless requested but still write them", func() {
+		rec, resp := run(`{"plugin": "logger", "input": 0}`, nil)
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(resp.Logs).To(BeEmpty())
+		Expect(rec.Body.String()).NotTo(ContainSubstring(`"logs"`))
+
+		id := rec.Header().Get(RequestIDHeader)
+		Expect(id).To(HaveLen(16))
+		Expect(logs.String()).To(ContainSubstring(`"msg":"input is zero"`))
+		Expect(logs.String()).To(ContainSubstring(`"request_id":"` + id + `"`))
+	})
+
+	It("should replace an unusable request ID", func() {
+		rec, _ := run(`{"plugin": "logger", "input": 1}`,
+			http.Header{RequestIDHeader: {strings.Repeat("x", 200)}})
+
+		Expect(rec.Header().Get(RequestIDHeader)).To(HaveLen(16))
+	})
+})
+
+var _ = Describe("Plugin output", func() {
+	var (
+		srv  *Server
+		logs *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "printer", "printer.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: printer.wasm")
+		}
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		logs = &bytes.Buffer{}
+		srv.logger = slog.New(slog.NewJSONHandler(logs, nil))
+	})
+
+	run := func(body string) (*httptest.ResponseRecorder, Response) {
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+		var resp Response
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	It("should return stdout and stderr when requested", func() {
+		rec, resp := run(`{"plugin": "printer", "input": 7, "include_output": true}`)
+
+		Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+		Expect(resp.Stdout).To(Equal("input: 7\n"))
+		Expect(resp.Stderr).To(BeEmpty())
+		Expect(resp.OutputTruncated).To(BeFalse())
+		Expect(logs.String()).NotTo(ContainSubstring("plugin output"))
+	})
+
+	It("should cut output at the limit", func() {
+		srv.maxOutput = 5
+		_, resp := run(`{"plugin": "printer", "input": 7, "include_output": true}`)
+
+		Expect(resp.Stdout).To(Equal("input"))
+		Expect(resp.OutputTruncated).To(BeTrue())
+	})
+
+	It("should log output of failed and ordinary requests", func() {
+		rec, resp := run(`{"plugin": "printer", "input": -1, "include_output": true}`)
+		Expect(rec.Code).NotTo(Equal(http.StatusOK))
+		Expect(resp.Stdout).To(BeEmpty())
+		Expect(logs.String()).To(ContainSubstring(`"stderr":"input must not be negative\n"`))
+
+		rec, _ = run(`{"plugin": "printer", "input": 2}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).NotTo(ContainSubstring(`"stdout"`))
+		Expect(logs.String()).To(ContainSubstring(`"stdout":"input: 2\n"`))
+		Expect(logs.String()).To(ContainSubstring(`"request_id":"` + rec.Header().Get(RequestIDHeader) + `"`))
+	})
+})
+
+var _ = Describe("poolOptionsFromConfig", func() {
+	It("should default to unbounded pools without idle eviction", func() {
+		Expect(poolOptionsFromConfig(config.Default())).To(Equal(runtime.PoolOptions{}))
+	})
+
+	It("should take sizes and durations from the config", func() {
+		cfg, err := config.Load(nil, func(name string) string {
+			return map[string]string{
+				"POOL_MIN_SIZE":           "2",
+				"POOL_MAX_SIZE":           "8",
+				"POOL_MEMORY_BUDGET_MIB":  "512",
+				"POOL_IDLE_TIMEOUT":       "5m",
+				"HEALTH_CHECK_INTERVAL":   "30s",
+				"MAX_MEMORY_PAGES":        "256",
+				"PLUGIN_SHUTDOWN_TIMEOUT": "2s",
+				"REQUIRE_DIGEST":          "true",
+				"WASI_ALLOW_ENV":          "TZ,LANG",
+				"WASI_DIRS":               "/data=/srv/data",
+			}[name]
+		})
+		Expect(err).NotTo(HaveOccurred())
+		opts := poolOptionsFromConfig(cfg)
+		Expect(opts.MinSize).To(Equal(2))
+		Expect(opts.MaxSize).To(Equal(8))
+		Expect(opts.MemoryBudgetMiB).To(Equal(512))
+		Expect(opts.IdleTimeout).To(Equal(5 * time.Minute))
+		Expect(opts.HealthInterval).To(Equal(30 * time.Second))
+		Expect(opts.ShutdownTimeout).To(Equal(2 * time.Second))
+		Expect(opts.MaxMemoryPages).To(Equal(256))
+		Expect(opts.RequireDigest).To(BeTrue())
+		Expect(opts.WASI).To(Equal(runtime.WASIOptions{
+			AllowEnv: []string{"TZ", "LANG"},
+			Dirs:     map[string]string{"/data": "/srv/data"},
+		}))
+	})
+
+	// =========================================================================
+	// TEST: Documented defaults
+	// Why: CONFIG.md states each default; it must be the one the component
+	//      would use by itself, not a second value that drifts from it.
+	// =========================================================================
+	It("should default to the components' own defaults", func() {
+		cfg := config.Default()
+		Expect(cfg.Execution.Timeout).To(Equal(DefaultExecutionTimeout))
+		Expect(cfg.Dedup.TTL).To(Equal(DefaultDedupTTL))
+		Expect(cfg.Store.WatchInterval).To(Equal(fluid.DefaultWatchInterval))
+		Expect(cfg.Store.Cache.TTL).To(Equal(fluid.DefaultRevalidateInterval))
+		Expect(cfg.Cache.QuotaBytes).To(Equal(int64(runtime.DefaultCacheQuotaBytes)))
+		Expect(cfg.Cache.MaxValueBytes).To(Equal(runtime.DefaultCacheMaxValueBytes))
+		Expect(cfg.Cache.MaxTTL).To(Equal(runtime.DefaultCacheMaxTTL))
+		Expect(cfg.Blobs.MaxGetBytes).To(Equal(int64(runtime.DefaultMaxBlobBytes)))
+		Expect(cfg.Blobs.MaxPutBytes).To(Equal(int64(runtime.DefaultMaxBlobBytes)))
+	})
 })
 
 // =========================================================================
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("loadHostModules", func() {
+	writeConfig := func(contents string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "host-modules.json")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	It("should load the declared modules", func() {
+		modules, err := loadHostModules(writeConfig(`{"modules": [{"name": "queue",
+			"rpc": {"url": "http://127.0.0.1:7400/call"},
+			"functions": [{"name": "publish", "params": ["bytes"], "results": ["i32"]}]}]}`), nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(modules).To(HaveLen(1))
+		Expect(modules[0].Name()).To(Equal("queue"))
+	})
+
+	// =========================================================================
+	// TEST: Reserved module names
+	// Why: A site module named like one the server registers would make
+	//      every plugin fail to load; it must be rejected at startup.
+	// =========================================================================
+	It("should reject the server's own module names", func() {
+		for _, name := range []string{"logging", "outbox", "host", "sql", "std"} {
+			_, err := loadHostModules(writeConfig(`{"modules": [{"name": "`+name+`",
+				"rpc": {"url": "http://127.0.0.1:7400/call"}, "functions": [{"name": "f"}]}]}`), nil)
+			Expect(err).To(MatchError(ContainSubstring("reserved")), name)
+		}
+	})
+})
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/jobs"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// maxJobRetryDelay bounds the wait before running a job again that was
+// rejected because the server was saturated or the plugin paused for
+// maintenance.
+const maxJobRetryDelay = time.Minute
+
+// jobQueueFullRetry is the Retry-After of submissions rejected because the
+// job queue is full.
+const jobQueueFullRetry = 5 * time.Second
+
+// jobInput is what a job runs: a /run request and the metadata of the
+// request that submitted it.
+type jobInput struct {
+	Request Request `json:"request"`
+	Tenant  string  `json:"tenant,omitempty"`
+	Caller  string  `json:"caller,omitempty"`
+}
+
+// JobInfo describes a job in the responses of /jobs.
+type JobInfo struct {
+	ID     string            `json:"id"`
+	Plugin string            `json:"plugin"`
+	State  jobs.State        `json:"state"`
+	Result *Response         `json:"result,omitempty"` // The /run response, once the job succeeded
+	Error  *apierror.Problem `json:"error,omitempty"`  // Why the job failed
+
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitzero"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// jobInfoOf returns the description of job.
+func jobInfoOf(job jobs.Job) JobInfo {
+	info := JobInfo{
+		ID:       job.ID,
+		State:    job.State,
+		Error:    job.Error,
+		Created:  job.Created,
+		Started:  job.Started,
+		Finished: job.Finished,
+	}
+	var input jobInput
+	if json.Unmarshal(job.Input, &input) == nil {
+		info.Plugin = input.Request.Plugin
+	}
+	if job.Result != nil {
+		var resp Response
+		if json.Unmarshal(job.Result, &resp) == nil {
+			info.Result = &resp
+		}
+	}
+	return info
+}
+
+// newJobQueue creates the queue running the jobs of POST /jobs, each with
+// the execution timeout timeout.
+func (s *Server) newJobQueue(timeout time.Duration, opts jobs.Options) (*jobs.Queue, error) {
+	opts.Problem = executionProblem
+	return jobs.New(func(ctx context.Context, job jobs.Job) (json.RawMessage, error) {
+		return s.runJob(ctx, job, timeout)
+	}, opts)
+}
+
+// runJob runs a job's request like /run, with the job ID as request ID.
+// A job rejected because the server is saturated or the plugin paused for
+// maintenance waits and runs later instead of failing.
+func (s *Server) runJob(ctx context.Context, job jobs.Job, timeout time.Duration) (json.RawMessage, error) {
+	var input jobInput
+	if err := json.Unmarshal(job.Input, &input); err != nil {
+		return nil, apierror.Wrap(apierror.CodeInternal, fmt.Errorf("invalid job input: %w", err))
+	}
+	input.Request.timeoutLimit = timeout
+	call := runtime.CallInfo{RequestID: job.ID, Tenant: input.Tenant, Caller: input.Caller}
+	resp, err := s.runAdmitted(ctx, input.Request, call, maxJobRetryDelay, func() {})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
+// executionProblem describes the error of a failed execution outside of
+// an HTTP response, with the error the plugin reported, if any.
+func executionProblem(err error) *apierror.Problem {
+	problem := apierror.New(apierror.CodeOf(err), err.Error())
+	problem.PluginError = pluginErrorOf(err)
+	return problem
+}
+
+// submitJob validates a /run request and queues it as a job. Access and
+// rate limits are checked now, on behalf of the client submitting it;
+// maintenance windows are waited out when the job runs.
+func (s *Server) submitJob(ctx context.Context, req Request, tenant, caller string) (jobs.Job, error) {
+	if err := s.checkRequest(&req); err != nil {
+		return jobs.Job{}, err
+	}
+	call, err := callInfoOf(ctx, "", tenant, caller)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	name, _ := fluid.ParseReference(req.Plugin)
+	if err := s.checkPluginAccess(ctx, name); err != nil {
+		return jobs.Job{}, err
+	}
+	if _, err := fluid.ResolveIn(s.store, call.Tenant, req.Plugin); err != nil {
+		return jobs.Job{}, apierror.Wrap(apierror.CodePluginNotFound,
+			fmt.Errorf("plugin not found: %s", req.Plugin))
+	}
+	if err := s.checkRateLimit(ctx, name, call.Tenant); err != nil {
+		return jobs.Job{}, err
+	}
+
+	data, err := json.Marshal(jobInput{Request: req, Tenant: call.Tenant, Caller: caller})
+	if err != nil {
+		return jobs.Job{}, apierror.Wrap(apierror.CodeInternal, err)
+	}
+	var owner string
+	if p, ok := principalOf(ctx); ok {
+		owner = p.Name
+	}
+	job, err := s.jobs.Submit(data, owner)
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull):
+		return jobs.Job{}, apierror.WrapRetry(apierror.CodeTooManyExecutions, err, jobQueueFullRetry)
+	case err != nil:
+		return jobs.Job{}, apierror.Wrap(apierror.CodeInternal, err)
+	}
+	return job, nil
+}
+
+// canSeeJob reports whether the client of ctx may see and cancel job:
+// clients see their own jobs, and admins every job. Without
+// authentication every client may.
+func canSeeJob(ctx context.Context, job jobs.Job) bool {
+	p, ok := principalOf(ctx)
+	return !ok || p.Admin || p.Name == job.Owner
+}
+
+// collectJobMetrics reports the jobs waiting and running.
+func (s *Server) collectJobMetrics() []metrics.Family {
+	if s.jobs == nil {
+		return nil
+	}
+	queued, running := s.jobs.Counts()
+	return []metrics.Family{
+		{Name: "plugin_jobs", Help: "Jobs by state: waiting in the queue, or running.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{
+				{Labels: []metrics.Label{{Name: "state", Value: string(jobs.Queued)}}, Value: float64(queued)},
+				{Labels: []metrics.Label{{Name: "state", Value: string(jobs.Running)}}, Value: float64(running)},
+			}},
+	}
+}
+
+// handleJobs handles POST /jobs and GET and DELETE /jobs/{id}, running
+// /run requests asynchronously for plugins that take longer than clients
+// can wait for a response.
+//
+// POST takes the body of POST /run, queues it, and returns 202 with the
+// job and its URL in the Location header. GET reports the job's state and,
+// once it has finished, its result or error. DELETE cancels the job,
+// interrupting the plugin if it is running.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/jobs"), "/")
+	allowed := (id == "" && r.Method == http.MethodPost) ||
+		(id != "" && (r.Method == http.MethodGet || r.Method == http.MethodDelete))
+	if !allowed {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.jobs == nil {
+		writeError(w, r, apierror.CodeJobNotFound, "jobs are disabled on this server")
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		job, err := s.submitJob(r.Context(), req, r.Header.Get(TenantHeader), r.Header.Get(CallerHeader))
+		if err != nil {
+			writeExecutionError(w, r, err)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, jobInfoOf(job))
+		return
+	}
+
+	// Other clients' jobs are reported missing rather than forbidden, so
+	// that their IDs cannot be probed
+	job, ok := s.jobs.Get(id)
+	if !ok || !canSeeJob(r.Context(), job) {
+		writeError(w, r, apierror.CodeJobNotFound, fmt.Sprintf("no job %s", id))
+		return
+	}
+	if r.Method == http.MethodDelete {
+		job, _ = s.jobs.Cancel(id)
+	}
+	writeJSON(w, http.StatusOK, jobInfoOf(job))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/jobs"
+)
+
+var _ = Describe("Jobs", func() {
+	var srv *Server
+
+	BeforeEach(func() {
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		// Not a valid module, so every job that runs fails to load it
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		var err error
+		srv.jobs, err = srv.newJobQueue(time.Hour, jobs.Options{MaxQueued: 2})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// runQueue starts the workers, which a test may leave stopped to keep
+	// its jobs queued.
+	runQueue := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			srv.jobs.Run(ctx)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			<-done
+		})
+	}
+
+	send := func(method, path, body string, p *principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if p != nil {
+			req = req.WithContext(withPrincipal(req.Context(), p))
+		}
+		rec := httptest.NewRecorder()
+		srv.handleJobs(rec, req)
+		return rec
+	}
+
+	decode := func(rec *httptest.ResponseRecorder) JobInfo {
+		var info JobInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &info)).To(Succeed())
+		return info
+	}
+
+	// =========================================================================
+	// TEST: Submitting and polling
+	// Why: POST must return before the plugin runs, and the outcome must be
+	//      retrievable from the URL it returns, failures included.
+	// =========================================================================
+	It("should run a submitted request and report its outcome", func() {
+		runQueue()
+		rec := send(http.MethodPost, "/jobs", `{"plugin": "hello", "input": 21}`, nil)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		submitted := decode(rec)
+		Expect(submitted.Plugin).To(Equal("hello"))
+		Expect(rec.Header().Get("Location")).To(Equal("/jobs/" + submitted.ID))
+
+		var info JobInfo
+		Eventually(func() jobs.State {
+			info = decode(send(http.MethodGet, "/jobs/"+submitted.ID, "", nil))
+			return info.State
+		}).Should(Equal(jobs.Failed))
+		Expect(info.Error.Code).To(Equal(apierror.CodePluginLoadFailed))
+		Expect(info.Result).To(BeNil())
+		Expect(info.Finished).NotTo(BeZero())
+	})
+
+	It("should reject invalid requests when they are submitted", func() {
+		Expect(send(http.MethodPost, "/jobs", `{"plugin": "nope"}`, nil).Code).To(Equal(http.StatusNotFound))
+		Expect(send(http.MethodPost, "/jobs", `{"plugin": "../etc"}`, nil).Code).To(Equal(http.StatusBadRequest))
+		Expect(send(http.MethodPost, "/jobs", `{"plugin": "hello"}`, &principal{Name: "ci", Plugins: []string{"other"}}).Code).
+			To(Equal(http.StatusForbidden))
+		Expect(send(http.MethodGet, "/jobs", "", nil).Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(send(http.MethodGet, "/jobs/0123456789abcdef", "", nil).Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should cancel a queued job", func() {
+		id := decode(send(http.MethodPost, "/jobs", `{"plugin": "hello"}`, nil)).ID
+
+		rec := send(http.MethodDelete, "/jobs/"+id, "", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(decode(rec).State).To(Equal(jobs.Cancelled))
+	})
+
+	It("should reject jobs beyond the queue limit with Retry-After", func() {
+		send(http.MethodPost, "/jobs", `{"plugin": "hello"}`, nil)
+		send(http.MethodPost, "/jobs", `{"plugin": "hello"}`, nil)
+
+		rec := send(http.MethodPost, "/jobs", `{"plugin": "hello"}`, nil)
+		Expect(rec.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rec.Header().Get("Retry-After")).To(Equal("5"))
+	})
+
+	// =========================================================================
+	// TEST: Ownership
+	// Why: A job's input and result belong to the client that submitted
+	//      it; other clients must not be able to read or cancel it.
+	// =========================================================================
+	It("should only show a job to its owner and admins", func() {
+		owner := &principal{Name: "payments", Plugins: []string{"*"}}
+		id := decode(send(http.MethodPost, "/jobs", `{"plugin": "hello"}`, owner)).ID
+
+		Expect(send(http.MethodGet, "/jobs/"+id, "", owner).Code).To(Equal(http.StatusOK))
+		Expect(send(http.MethodGet, "/jobs/"+id, "", adminPrincipal).Code).To(Equal(http.StatusOK))
+		other := &principal{Name: "reports", Plugins: []string{"*"}}
+		Expect(send(http.MethodGet, "/jobs/"+id, "", other).Code).To(Equal(http.StatusNotFound))
+		Expect(send(http.MethodDelete, "/jobs/"+id, "", other).Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// jwksRefreshInterval is how often the JWKS is fetched again, so rotated
+// signing keys are picked up.
+const jwksRefreshInterval = 15 * time.Minute
+
+// jwksMinRefresh bounds how often a token signed by an unknown key makes
+// the JWKS be fetched again.
+const jwksMinRefresh = 30 * time.Second
+
+// jwtLeeway tolerates clock skew between the server and the issuer.
+const jwtLeeway = time.Minute
+
+// maxJWKSBytes bounds the JWKS document read from the issuer.
+const maxJWKSBytes = 1 << 20
+
+// jwtConfig configures the validation of JWT bearer tokens against the
+// signing keys published at JWKSURL.
+type jwtConfig struct {
+	JWKSURL  string `json:"jwks_url"`
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+
+	// PluginsClaim names the claim listing the plugins a token may run,
+	// "plugins" by default; AdminClaim the boolean claim granting the
+	// admin endpoints, "admin" by default; TenantClaim the string claim
+	// binding the token to a tenant, "tenant" by default
+	PluginsClaim string `json:"plugins_claim,omitempty"`
+	AdminClaim   string `json:"admin_claim,omitempty"`
+	TenantClaim  string `json:"tenant_claim,omitempty"`
+}
+
+// jwtAuthenticator authenticates bearer tokens signed by a JWKS key.
+type jwtAuthenticator struct {
+	cfg  jwtConfig
+	http *http.Client
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey // By key ID
+	fetched  time.Time
+	fetchErr error // Of the last fetch
+	now      func() time.Time
+
+	refresh singleflight.Group // The JWKS fetch in flight, made without holding mu
+}
+
+// newJWTAuthenticator creates an authenticator for cfg. Keys are fetched
+// on first use.
+func newJWTAuthenticator(cfg jwtConfig) (*jwtAuthenticator, error) {
+	if !strings.HasPrefix(cfg.JWKSURL, "https://") && !strings.HasPrefix(cfg.JWKSURL, "http://") {
+		return nil, fmt.Errorf("jwt.jwks_url must be an http(s) URL, got %q", cfg.JWKSURL)
+	}
+	if cfg.PluginsClaim == "" {
+		cfg.PluginsClaim = "plugins"
+	}
+	if cfg.AdminClaim == "" {
+		cfg.AdminClaim = "admin"
+	}
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant"
+	}
+	return &jwtAuthenticator{cfg: cfg, http: &http.Client{Timeout: 10 * time.Second}, now: time.Now}, nil
+}
+
+// authenticate validates a bearer token. Credentials without one are not
+// its kind.
+func (a *jwtAuthenticator) authenticate(ctx context.Context, creds authCredentials) (*principal, error) {
+	if creds.bearer == "" || strings.Count(creds.bearer, ".") != 2 {
+		return nil, nil
+	}
+	claims, err := a.verify(ctx, creds.bearer)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &principal{Admin: claims[a.cfg.AdminClaim] == true}
+	p.Name, _ = claims["sub"].(string)
+	if p.Name == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if tenant, ok := claims[a.cfg.TenantClaim]; ok {
+		p.Tenant, _ = tenant.(string)
+		if !validID(p.Tenant) {
+			return nil, fmt.Errorf("invalid %s claim", a.cfg.TenantClaim)
+		}
+	}
+	switch plugins := claims[a.cfg.PluginsClaim].(type) {
+	case string:
+		p.Plugins = strings.Fields(plugins)
+	case []any:
+		for _, plugin := range plugins {
+			if name, ok := plugin.(string); ok {
+				p.Plugins = append(p.Plugins, name)
+			}
+		}
+	}
+	return p, nil
+}
+
+// verify checks a token's signature and registered claims and returns its
+// claims.
+func (a *jwtAuthenticator) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	now := a.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return nil, errors.New("token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if a.cfg.Issuer != "" && claims["iss"] != a.cfg.Issuer {
+		return nil, fmt.Errorf("token issuer %v is not %s", claims["iss"], a.cfg.Issuer)
+	}
+	if a.cfg.Audience != "" {
+		var audiences []string
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		case []any:
+			for _, v := range aud {
+				if s, ok := v.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+		if !slices.Contains(audiences, a.cfg.Audience) {
+			return nil, fmt.Errorf("token is not meant for audience %s", a.cfg.Audience)
+		}
+	}
+	return claims, nil
+}
+
+// decodeJWTPart decodes a base64url JSON part of a token into v.
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifyJWTSignature checks a signature of signed made by alg. Only the
+// asymmetric algorithms are accepted: a JWKS publishes public keys.
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			if rsa.VerifyPKCS1v15(key, hash, digest, signature) != nil {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		case "PS":
+			if rsa.VerifyPSS(key, hash, digest, signature, nil) != nil {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] == "ES" && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if !ecdsa.Verify(key, digest, r, s) {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("token algorithm %q does not match its signing key", alg)
+}
+
+// key returns the signing key of ID kid, fetching the JWKS when it is due
+// for a refresh or does not know kid yet.
+func (a *jwtAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	now := a.now()
+	_, known := a.keys[kid]
+	due := a.fetched.IsZero() || now.Sub(a.fetched) >= jwksRefreshInterval || (!known && now.Sub(a.fetched) >= jwksMinRefresh)
+	a.mu.Unlock()
+	if due {
+		// Concurrent requests share one fetch, and requests with known
+		// keys are not held up by it
+		a.refresh.Do("", func() (any, error) {
+			a.mu.Lock()
+			recent := !a.fetched.IsZero() && a.now().Sub(a.fetched) < jwksMinRefresh
+			a.mu.Unlock()
+			if recent {
+				return nil, nil // Refreshed since this request looked
+			}
+
+			// A failed refresh keeps the keys already known, and a client
+			// giving up must not fail it for everyone
+			keys, err := a.fetch(context.WithoutCancel(ctx))
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.fetched = a.now()
+			if err == nil {
+				a.keys = keys
+			}
+			a.fetchErr = err
+			return nil, nil
+		})
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	// Tokens may omit the ID if the JWKS has a single key
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+	if a.fetchErr != nil {
+		return nil, a.fetchErr
+	}
+	return nil, fmt.Errorf("token signed by unknown key %q", kid)
+}
+
+// jwk is a JSON Web Key; only the members of RSA and EC public keys are
+// read, and those of the Ed25519 key of delegated tokens written.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// fetch reads the signing keys from the JWKS URL. Keys of other types or
+// uses are skipped.
+func (a *jwtAuthenticator) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBytes)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA or EC key.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on its curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// LimitRetryAfter is the Retry-After of executions rejected because too
+// many are running. Plugin calls are short, so a slot is likely free by
+// then.
+const LimitRetryAfter = time.Second
+
+// limiter bounds how many plugin executions run at once, in total and per
+// plugin. Executions beyond a limit are rejected rather than queued: every
+// running one holds a VM instance and its linear memory, so a load spike
+// must not pile them up.
+type limiter struct {
+	global    chan struct{} // Semaphore of all executions; nil without a global limit
+	perPlugin int           // Zero without a per-plugin limit
+
+	mu      sync.Mutex
+	running map[string]int // Executions by plugin; plugins without any are dropped
+
+	rejectedGlobal metrics.Counter
+	rejectedPlugin metrics.Counter
+}
+
+// newLimiter creates a limiter allowing global executions in total and
+// perPlugin of each plugin; zero means no limit.
+func newLimiter(global, perPlugin int) *limiter {
+	l := &limiter{perPlugin: perPlugin, running: make(map[string]int)}
+	if global > 0 {
+		l.global = make(chan struct{}, global)
+	}
+	return l
+}
+
+// acquire takes a slot for an execution of plugin, or returns a
+// CodeTooManyExecutions error if none is free. The returned function
+// gives the slot back.
+func (l *limiter) acquire(plugin string) (func(), error) {
+	l.mu.Lock()
+	if l.perPlugin > 0 && l.running[plugin] >= l.perPlugin {
+		l.mu.Unlock()
+		l.rejectedPlugin.Inc()
+		return nil, apierror.WrapRetry(apierror.CodeTooManyExecutions,
+			fmt.Errorf("plugin %s already runs %d executions, the most allowed", plugin, l.perPlugin), LimitRetryAfter)
+	}
+	l.running[plugin]++
+	l.mu.Unlock()
+
+	if l.global != nil {
+		select {
+		case l.global <- struct{}{}:
+		default:
+			l.done(plugin)
+			l.rejectedGlobal.Inc()
+			return nil, apierror.WrapRetry(apierror.CodeTooManyExecutions,
+				fmt.Errorf("the server already runs %d executions, the most allowed", cap(l.global)), LimitRetryAfter)
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.global != nil {
+				<-l.global
+			}
+			l.done(plugin)
+		})
+	}, nil
+}
+
+// done counts an execution of plugin as finished.
+func (l *limiter) done(plugin string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[plugin]--; l.running[plugin] <= 0 {
+		delete(l.running, plugin)
+	}
+}
+
+// collectMetrics reports running and rejected executions as metric
+// families.
+func (l *limiter) collectMetrics() []metrics.Family {
+	running := metrics.Family{Name: "plugin_executions_running", Help: "Plugin executions in progress.", Type: metrics.TypeGauge}
+	l.mu.Lock()
+	plugins := make([]string, 0, len(l.running))
+	for plugin := range l.running {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	for _, plugin := range plugins {
+		running.Samples = append(running.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "plugin", Value: plugin}},
+			Value:  float64(l.running[plugin]),
+		})
+	}
+	l.mu.Unlock()
+
+	return []metrics.Family{
+		running,
+		{Name: "plugin_executions_rejected_total", Help: "Plugin executions rejected because a concurrency limit was reached, by limit.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: []metrics.Label{{Name: "limit", Value: "global"}}, Value: float64(l.rejectedGlobal.Value())},
+				{Labels: []metrics.Label{{Name: "limit", Value: "plugin"}}, Value: float64(l.rejectedPlugin.Value())},
+			}},
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+var _ = Describe("Concurrency limiter", func() {
+	// =========================================================================
+	// TEST: Per-plugin and global limits
+	// Why: One busy plugin must not take every slot, and the server as a
+	//      whole must not run more VMs than its memory allows.
+	// =========================================================================
+	It("should reject executions beyond the per-plugin limit", func() {
+		l := newLimiter(0, 2)
+		first, err := l.acquire("billing")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = l.acquire("billing")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = l.acquire("billing")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTooManyExecutions))
+		Expect(apierror.RetryAfterOf(err)).To(Equal(LimitRetryAfter))
+		_, err = l.acquire("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Releasing twice frees one slot only
+		first()
+		first()
+		_, err = l.acquire("billing")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = l.acquire("billing")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTooManyExecutions))
+	})
+
+	It("should reject executions beyond the global limit", func() {
+		l := newLimiter(2, 0)
+		release, err := l.acquire("billing")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = l.acquire("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = l.acquire("rollup")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTooManyExecutions))
+
+		release()
+		_, err = l.acquire("rollup")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should report running and rejected executions", func() {
+		l := newLimiter(1, 0)
+		release, err := l.acquire("billing")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = l.acquire("hello")
+		Expect(err).To(HaveOccurred())
+
+		families := l.collectMetrics()
+		Expect(families[0].Samples).To(Equal([]metrics.Sample{
+			{Labels: []metrics.Label{{Name: "plugin", Value: "billing"}}, Value: 1},
+		}))
+		Expect(families[1].Samples).To(ContainElement(metrics.Sample{
+			Labels: []metrics.Label{{Name: "limit", Value: "global"}}, Value: 1,
+		}))
+
+		// Plugins without running executions are dropped
+		release()
+		Expect(l.collectMetrics()[0].Samples).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Saturated /run
+	// Why: Clients must be told to back off briefly rather than see a
+	//      failure of the plugin.
+	// =========================================================================
+	It("should answer /run with 429 and Retry-After when saturated", func() {
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.limiter = newLimiter(0, 1)
+		_, err := srv.limiter.acquire("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello", "input": 1}`))
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rec.Header().Get("Retry-After")).To(Equal("1"))
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(apierror.CodeTooManyExecutions))
+		Expect(retryAfterSeconds(1500 * time.Millisecond)).To(Equal(2))
+	})
+})
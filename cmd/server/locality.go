@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// LocalityHopHeader marks a /run request another replica routed here,
+// naming that replica's node. Such requests run where they land, so that
+// replicas disagreeing about a placement cannot bounce them around.
+const LocalityHopHeader = "X-Locality-Hop"
+
+// localityRouter routes /run requests to the replica whose node's Fluid
+// cache holds the most bytes of the plugin and its data files, so large
+// plugins run where their data is instead of being pulled across nodes.
+// Placements are reused for ttl to keep cache state queries off the
+// request path.
+type localityRouter struct {
+	node    string            // Node of this replica
+	peers   map[string]string // Base URL of the replica on each other node
+	root    string            // Dataset mount the plugin store reads
+	store   fluid.PluginStore
+	locator fluid.CacheLocator
+	ttl     time.Duration
+	client  *http.Client
+	logger  *slog.Logger
+
+	mu         sync.Mutex
+	placements map[string]placement // By plugin reference
+	now        func() time.Time
+
+	local, routed, failed metrics.Counter
+}
+
+// placement is the node a plugin last ran best on.
+type placement struct {
+	node    string
+	expires time.Time
+}
+
+// newLocalityRouter creates a router for the replica on node. peers maps
+// the other nodes to the base URLs of their replicas.
+func newLocalityRouter(node string, peers map[string]string, root string, store fluid.PluginStore, locator fluid.CacheLocator, ttl time.Duration, logger *slog.Logger) *localityRouter {
+	return &localityRouter{
+		node:       node,
+		peers:      peers,
+		root:       root,
+		store:      store,
+		locator:    locator,
+		ttl:        ttl,
+		client:     &http.Client{},
+		logger:     logger,
+		placements: make(map[string]placement),
+		now:        time.Now,
+	}
+}
+
+// place returns the node that should run ref: the one caching the most of
+// its files, this one on ties, or when the cache state is unknown.
+func (l *localityRouter) place(ctx context.Context, ref string) string {
+	l.mu.Lock()
+	p, ok := l.placements[ref]
+	l.mu.Unlock()
+	if ok && l.now().Before(p.expires) {
+		return p.node
+	}
+
+	node := l.node
+	if files := l.files(ref); len(files) > 0 {
+		cached, err := fluid.Locate(ctx, l.locator, files)
+		if err != nil {
+			l.logger.Warn("failed to query the cache state", slog.String("plugin", ref), slog.String("error", err.Error()))
+		}
+		for candidate, n := range cached {
+			if _, ok := l.peers[candidate]; ok && n > cached[node] {
+				node = candidate
+			}
+		}
+	}
+
+	l.mu.Lock()
+	l.placements[ref] = placement{node: node, expires: l.now().Add(l.ttl)}
+	l.mu.Unlock()
+	return node
+}
+
+// files returns the plugin's binary and the data files its manifest
+// declares, relative to the dataset root. Plugins outside the dataset
+// have none.
+func (l *localityRouter) files(ref string) []string {
+	path, err := l.store.Resolve(ref)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(l.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	files := []string{filepath.ToSlash(rel)}
+	if m, err := manifest.ForPlugin(path); err == nil && m != nil {
+		files = append(files, m.Data...)
+	}
+	return files
+}
+
+// forward sends a /run request to the replica on node and copies its
+// response to w. It reports false, having written nothing, if the replica
+// cannot be reached.
+func (l *localityRouter) forward(w http.ResponseWriter, r *http.Request, node string, body []byte) bool {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, strings.TrimRight(l.peers[node], "/")+"/run", bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Set(LocalityHopHeader, l.node)
+	// The receiving replica takes the place of this one behind the same
+	// proxies, so it identifies the client by the X-Forwarded-For they
+	// set, or else by the address this replica saw
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil && r.Header.Get("X-Forwarded-For") == "" {
+		req.Header.Set("X-Forwarded-For", host)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		l.failed.Inc()
+		l.logger.Warn("failed to route request to the replica caching the plugin",
+			slog.String("node", node), slog.String("error", err.Error()))
+		return false
+	}
+	defer resp.Body.Close()
+	l.routed.Inc()
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+	return true
+}
+
+// collectMetrics reports where /run requests ran.
+func (l *localityRouter) collectMetrics() []metrics.Family {
+	return []metrics.Family{
+		{Name: "plugin_locality_requests_total", Help: "Requests placed by locality routing, by outcome: run locally, routed to another replica, or run locally because that replica failed.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: []metrics.Label{{Name: "outcome", Value: "local"}}, Value: float64(l.local.Value())},
+				{Labels: []metrics.Label{{Name: "outcome", Value: "routed"}}, Value: float64(l.routed.Value())},
+				{Labels: []metrics.Label{{Name: "outcome", Value: "failed"}}, Value: float64(l.failed.Value())},
+			}},
+	}
+}
+
+// localityRouted is middleware running /run requests on the replica whose
+// node caches their plugin, when locality routing is configured.
+func (s *Server) localityRouted(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := s.locality
+		if l == nil || r.Method != http.MethodPost || r.Header.Get(LocalityHopHeader) != "" {
+			next(w, r)
+			return
+		}
+
+		// The plugin is named in the body, which the handler reads again
+		body, err := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var req struct {
+			Plugin  string `json:"plugin"`
+			Version string `json:"version"`
+		}
+		if err != nil || json.Unmarshal(body, &req) != nil || checkPluginName(req.Plugin) != nil {
+			next(w, r)
+			return
+		}
+		ref := req.Plugin
+		if req.Version != "" {
+			name, _ := fluid.ParseReference(ref)
+			ref = fluid.Reference(name, req.Version)
+		}
+
+		if node := l.place(r.Context(), ref); node == l.node {
+			l.local.Inc()
+		} else if l.forward(w, r, node, body) {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
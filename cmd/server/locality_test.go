@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// cacheState is a fluid.CacheLocator with fixed answers, counting queries.
+type cacheState struct {
+	mu      sync.Mutex
+	cached  map[string]map[string]int64 // Bytes by node, by file
+	queries int
+}
+
+func (c *cacheState) CachedBytes(_ context.Context, name string) (map[string]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries++
+	return c.cached[name], nil
+}
+
+var _ = Describe("Locality routing", func() {
+	var (
+		state   *cacheState
+		peer    *httptest.Server
+		peerReq *http.Request
+		srv     *Server
+		now     time.Time
+		ranHere bool
+	)
+
+	BeforeEach(func() {
+		root := GinkgoT().TempDir()
+		dir := filepath.Join(root, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"name": "hello", "version": "1.0.0", "data": ["models/big.bin"]}`), 0644)).To(Succeed())
+
+		state = &cacheState{cached: map[string]map[string]int64{
+			"hello/hello.wasm": {"node-a": 100},
+			"models/big.bin":   {"node-b": 1 << 30},
+		}}
+		peerReq = nil
+		peer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			peerReq = r.Clone(context.Background())
+			peerReq.Body = io.NopCloser(bytes.NewReader(body))
+			w.Header().Set(RequestIDHeader, "from-peer")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"output": 43}`))
+		}))
+		DeferCleanup(peer.Close)
+
+		logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		store := fluid.NewLocalPluginStore(root)
+		srv = NewServer(store)
+		srv.logger = logger
+		srv.locality = newLocalityRouter("node-a", map[string]string{"node-b": peer.URL}, root, store, state, time.Minute, logger)
+		now = time.Now()
+		srv.locality.now = func() time.Time { return now }
+		ranHere = false
+	})
+
+	send := func(body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body))
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		srv.localityRouted(func(w http.ResponseWriter, r *http.Request) {
+			// The handler must still see the whole body
+			data, _ := io.ReadAll(r.Body)
+			Expect(string(data)).To(Equal(body))
+			ranHere = true
+			w.WriteHeader(http.StatusTeapot)
+		})(rec, req)
+		return rec
+	}
+
+	// =========================================================================
+	// TEST: Placement by cached bytes
+	// Why: A plugin whose data is cached on another node must run there,
+	//      with the request and response passed through unchanged.
+	// =========================================================================
+	It("should route to the replica caching most of the plugin and its data", func() {
+		rec := send(`{"plugin": "hello", "input": 21}`, http.Header{"Authorization": {"Bearer k"}})
+		Expect(ranHere).To(BeFalse())
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal(`{"output": 43}`))
+		Expect(rec.Header().Get(RequestIDHeader)).To(Equal("from-peer"))
+
+		Expect(peerReq.URL.Path).To(Equal("/run"))
+		Expect(peerReq.Header.Get(LocalityHopHeader)).To(Equal("node-a"))
+		Expect(peerReq.Header.Get("Authorization")).To(Equal("Bearer k"))
+		body, _ := io.ReadAll(peerReq.Body)
+		Expect(string(body)).To(Equal(`{"plugin": "hello", "input": 21}`))
+
+		// The placement is reused until it expires
+		send(`{"plugin": "hello", "input": 1}`, nil)
+		Expect(state.queries).To(Equal(2))
+		now = now.Add(time.Minute)
+		send(`{"plugin": "hello", "input": 1}`, nil)
+		Expect(state.queries).To(Equal(4))
+	})
+
+	It("should run locally when this node caches the most", func() {
+		state.cached["models/big.bin"] = map[string]int64{"node-a": 10, "node-b": 10}
+		Expect(send(`{"plugin": "hello"}`, nil).Code).To(Equal(http.StatusTeapot))
+		Expect(ranHere).To(BeTrue())
+		Expect(peerReq).To(BeNil())
+	})
+
+	It("should run requests routed here and unknown plugins locally", func() {
+		send(`{"plugin": "hello"}`, http.Header{LocalityHopHeader: {"node-b"}})
+		Expect(ranHere).To(BeTrue())
+
+		ranHere = false
+		send(`{"plugin": "nope"}`, nil)
+		Expect(ranHere).To(BeTrue())
+		Expect(state.queries).To(BeZero())
+	})
+
+	It("should run locally when the chosen replica is unreachable", func() {
+		peer.Close()
+		Expect(send(`{"plugin": "hello"}`, nil).Code).To(Equal(http.StatusTeapot))
+		Expect(ranHere).To(BeTrue())
+		Expect(srv.locality.failed.Value()).To(BeEquivalentTo(1))
+	})
+})
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// DefaultLogOverrideDuration is how long a log override lasts when the
+// request does not say.
+const DefaultLogOverrideDuration = 15 * time.Minute
+
+// MaxLogOverrideDuration bounds a log override, so verbose logging left on
+// by mistake turns itself off.
+const MaxLogOverrideDuration = 24 * time.Hour
+
+// LogOverride changes the log verbosity of one plugin for a limited time.
+type LogOverride struct {
+	Plugin string           `json:"plugin"`
+	Level  runtime.LogLevel `json:"level"` // Lowest level of the plugin's messages that is logged
+
+	// Trace records every run of the plugin in a debug trace, kept for
+	// GET /debug/traces as if each request had set debug
+	Trace bool `json:"trace,omitempty"`
+
+	// Expires is when the plugin returns to the server's log level; zero
+	// when no override is active
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// LogOverrideRequest is the JSON request body for PUT /plugins/{name}/logging
+type LogOverrideRequest struct {
+	Level runtime.LogLevel `json:"level"` // Defaults to debug
+	Trace bool             `json:"trace,omitempty"`
+
+	// DurationSeconds is how long the override lasts; zero uses
+	// DefaultLogOverrideDuration
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+}
+
+// logOverrides holds the active log overrides by plugin name. Expired
+// overrides are dropped as they are looked up.
+type logOverrides struct {
+	mu       sync.Mutex
+	byPlugin map[string]LogOverride
+	now      func() time.Time
+}
+
+// newLogOverrides creates an empty set of overrides.
+func newLogOverrides() *logOverrides {
+	return &logOverrides{byPlugin: make(map[string]LogOverride), now: time.Now}
+}
+
+// get returns the active override of a plugin.
+func (o *logOverrides) get(plugin string) (LogOverride, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	override, ok := o.byPlugin[plugin]
+	if ok && !o.now().Before(override.Expires) {
+		delete(o.byPlugin, plugin)
+		return LogOverride{}, false
+	}
+	return override, ok
+}
+
+// set activates an override for duration, replacing the plugin's current
+// one, and returns it.
+func (o *logOverrides) set(plugin string, level runtime.LogLevel, trace bool, duration time.Duration) LogOverride {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	override := LogOverride{
+		Plugin:  plugin,
+		Level:   level,
+		Trace:   trace,
+		Expires: o.now().Add(duration).UTC(),
+	}
+	o.byPlugin[plugin] = override
+	return override
+}
+
+// remove ends the override of a plugin, if any.
+func (o *logOverrides) remove(plugin string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.byPlugin, plugin)
+}
+
+// handlePluginLogging handles GET, PUT and DELETE /plugins/{name}/logging,
+// admin endpoints changing the log verbosity of a single plugin.
+//
+// PUT logs the plugin's messages from the given level up, whatever the
+// server's log level, until the override expires. With trace set, every
+// run of the plugin is also recorded as a debug trace. GET returns the
+// active override, or the server's level when there is none, and DELETE
+// ends the override early.
+func (s *Server) handlePluginLogging(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/plugins/"), "/logging")
+	if err := checkPluginName(name); err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+	// Overrides apply to every version of the plugin
+	name, _ = fluid.ParseReference(name)
+	if _, err := s.store.Resolve(name); err != nil {
+		writeError(w, r, apierror.CodePluginNotFound, fmt.Sprintf("plugin not found: %s", name))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		override, ok := s.logOverrides.get(name)
+		if !ok {
+			override = LogOverride{Plugin: name, Level: s.pluginLogLevel()}
+		}
+		writeJSON(w, http.StatusOK, override)
+
+	case http.MethodPut:
+		var req LogOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		duration, err := overrideDuration(req.DurationSeconds)
+		if err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, err.Error())
+			return
+		}
+		if req.Trace && s.traces == nil {
+			writeError(w, r, apierror.CodeInvalidRequest, errTracesDisabled.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s.logOverrides.set(name, req.Level, req.Trace, duration))
+
+	case http.MethodDelete:
+		s.logOverrides.remove(name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// overrideDuration validates the duration of a log override request.
+func overrideDuration(seconds int) (time.Duration, error) {
+	duration := time.Duration(seconds) * time.Second
+	switch {
+	case seconds < 0:
+		return 0, errors.New("duration_seconds must not be negative")
+	case seconds == 0:
+		return DefaultLogOverrideDuration, nil
+	case duration > MaxLogOverrideDuration:
+		return 0, fmt.Errorf("duration_seconds must not exceed %d", int(MaxLogOverrideDuration.Seconds()))
+	}
+	return duration, nil
+}
+
+// pluginLogLevel returns the lowest level of plugin messages the server's
+// logger writes.
+func (s *Server) pluginLogLevel() runtime.LogLevel {
+	for level := runtime.LogDebug; level < runtime.LogError; level++ {
+		if s.logger.Enabled(context.Background(), slogLevel(level)) {
+			return level
+		}
+	}
+	return runtime.LogError
+}
+
+// logPluginMessage writes a plugin's log message as a structured log line.
+//
+// While the plugin has a log override, the override alone decides which
+// messages are written: the record goes to the handler directly, past the
+// logger's own level.
+func (s *Server) logPluginMessage(entry runtime.LogEntry) {
+	attrs := []slog.Attr{
+		slog.String("plugin", entry.Plugin),
+		slog.String("request_id", entry.RequestID),
+	}
+	if entry.Tenant != "" {
+		attrs = append(attrs, slog.String("tenant", entry.Tenant))
+	}
+	override, ok := s.logOverrides.get(entry.Plugin)
+	if !ok {
+		s.logger.LogAttrs(context.Background(), slogLevel(entry.Level), entry.Message, attrs...)
+		return
+	}
+	if entry.Level < override.Level {
+		return
+	}
+	record := slog.NewRecord(entry.Time, slogLevel(entry.Level), entry.Message, 0)
+	record.AddAttrs(attrs...)
+	_ = s.logger.Handler().Handle(context.Background(), record)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Log overrides", func() {
+	// =========================================================================
+	// TEST: Override expiry
+	// Why: Verbose logging switched on to debug one plugin must switch itself
+	//      off; a forgotten override must not keep flooding the logs.
+	// =========================================================================
+	Describe("logOverrides", func() {
+		It("should drop an override once it expires", func() {
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			overrides := newLogOverrides()
+			overrides.now = func() time.Time { return now }
+
+			set := overrides.set("hello", runtime.LogDebug, false, time.Minute)
+			Expect(set.Expires).To(Equal(now.Add(time.Minute)))
+
+			override, ok := overrides.get("hello")
+			Expect(ok).To(BeTrue())
+			Expect(override.Level).To(Equal(runtime.LogDebug))
+			_, ok = overrides.get("echo")
+			Expect(ok).To(BeFalse())
+
+			now = now.Add(time.Minute)
+			_, ok = overrides.get("hello")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	// =========================================================================
+	// TEST: Per-plugin verbosity
+	// Why: Debug messages of the plugin under investigation must be written
+	//      while the server stays at its own level for every other plugin.
+	// =========================================================================
+	Describe("logPluginMessage", func() {
+		var (
+			srv  *Server
+			logs *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			srv = NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+			logs = &bytes.Buffer{}
+			srv.logger = slog.New(slog.NewJSONHandler(logs, nil))
+		})
+
+		log := func(plugin string, level runtime.LogLevel, message string) {
+			srv.logPluginMessage(runtime.LogEntry{Time: time.Now(), Level: level, Plugin: plugin, Message: message})
+		}
+
+		It("should write debug messages of an overridden plugin only", func() {
+			srv.logOverrides.set("hello", runtime.LogDebug, false, time.Minute)
+
+			log("hello", runtime.LogDebug, "hello debug")
+			log("echo", runtime.LogDebug, "echo debug")
+			log("echo", runtime.LogInfo, "echo info")
+
+			Expect(logs.String()).To(ContainSubstring(`"level":"DEBUG","msg":"hello debug","plugin":"hello"`))
+			Expect(logs.String()).NotTo(ContainSubstring("echo debug"))
+			Expect(logs.String()).To(ContainSubstring("echo info"))
+		})
+
+		It("should silence messages below a raised level", func() {
+			srv.logOverrides.set("hello", runtime.LogError, false, time.Minute)
+
+			log("hello", runtime.LogWarn, "hello warn")
+			log("hello", runtime.LogError, "hello error")
+
+			Expect(logs.String()).NotTo(ContainSubstring("hello warn"))
+			Expect(logs.String()).To(ContainSubstring("hello error"))
+		})
+	})
+
+	Describe("/plugins/{name}/logging", func() {
+		var srv *Server
+
+		BeforeEach(func() {
+			pluginsDir := GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(pluginsDir, "hello"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(pluginsDir, "hello", "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+			srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+			srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+			srv.adminToken = "secret"
+		})
+
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			srv.handlePlugin(rec, req)
+			return rec
+		}
+
+		problemCode := func(rec *httptest.ResponseRecorder) apierror.Code {
+			var problem apierror.Problem
+			Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+			return problem.Code
+		}
+
+		// =====================================================================
+		// TEST: Override lifecycle
+		// Why: Operators set, inspect and end an override through the API;
+		//      GET must tell whether one is active and until when.
+		// =====================================================================
+		It("should set, report and end an override", func() {
+			rec := send(http.MethodGet, "/plugins/hello/logging", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"plugin": "hello", "level": "info"}`))
+
+			rec = send(http.MethodPut, "/plugins/hello/logging", `{"level": "debug", "duration_seconds": 60}`)
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			var override LogOverride
+			Expect(json.Unmarshal(rec.Body.Bytes(), &override)).To(Succeed())
+			Expect(override.Level).To(Equal(runtime.LogDebug))
+			Expect(override.Expires).To(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+
+			rec = send(http.MethodGet, "/plugins/hello/logging", "")
+			Expect(json.Unmarshal(rec.Body.Bytes(), &override)).To(Succeed())
+			Expect(override.Level).To(Equal(runtime.LogDebug))
+
+			rec = send(http.MethodDelete, "/plugins/hello/logging", "")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			_, ok := srv.logOverrides.get("hello")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should default to a debug override of DefaultLogOverrideDuration", func() {
+			rec := send(http.MethodPut, "/plugins/hello/logging", `{}`)
+
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			override, ok := srv.logOverrides.get("hello")
+			Expect(ok).To(BeTrue())
+			Expect(override.Level).To(Equal(runtime.LogDebug))
+			Expect(override.Expires).To(BeTemporally("~", time.Now().Add(DefaultLogOverrideDuration), 5*time.Second))
+		})
+
+		It("should reject invalid overrides", func() {
+			for _, body := range []string{
+				`{"level": "verbose"}`,
+				`{"duration_seconds": -1}`,
+				`{"duration_seconds": 86401}`,
+				`{"trace": true}`, // DEBUG_TRACES is unset
+			} {
+				rec := send(http.MethodPut, "/plugins/hello/logging", body)
+				Expect(rec.Code).To(Equal(http.StatusBadRequest), body)
+				Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidRequest))
+			}
+		})
+
+		It("should return 404 for an unknown plugin", func() {
+			rec := send(http.MethodPut, "/plugins/missing/logging", `{}`)
+
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(problemCode(rec)).To(Equal(apierror.CodePluginNotFound))
+		})
+
+		It("should require the admin token", func() {
+			req := httptest.NewRequest(http.MethodPut, "/plugins/hello/logging", bytes.NewBufferString(`{}`))
+			rec := httptest.NewRecorder()
+			srv.handlePlugin(rec, req)
+
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rec.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+			_, ok := srv.logOverrides.get("hello")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should return 405 when admin endpoints are disabled", func() {
+			srv.adminToken = ""
+			rec := send(http.MethodGet, "/plugins/hello/logging", "")
+
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
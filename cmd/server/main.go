@@ -1,13 +1,41 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/propagation"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrhapile/wasm-plugin-system/abi"
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/config"
 	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/hostext"
+	"github.com/mrhapile/wasm-plugin-system/jobs"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
 	"github.com/mrhapile/wasm-plugin-system/runtime"
+	_ "github.com/mrhapile/wasm-plugin-system/runtime/wazero" // ENGINE or SHADOW_ENGINE=wazero
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+	"github.com/mrhapile/wasm-plugin-system/websocket"
 )
 
 // Server encapsulates the HTTP server dependencies.
@@ -18,27 +46,343 @@ import (
 //   - Clear dependency injection
 type Server struct {
 	store fluid.PluginStore
+
+	// pools holds one instance pool per resolved plugin path, so repeated
+	// requests reuse initialized VMs instead of recreating them.
+	// poolCreation creates each path's pool once, without holding poolsMu.
+	poolsMu      sync.Mutex
+	pools        map[string]*runtime.Pool
+	poolCreation singleflight.Group
+
+	// poolUsed is when each pool was last looked up for a request, and
+	// eviction what bounds the pools no request uses; evictions counts
+	// the pools evicted, by reason
+	poolUsed  map[string]time.Time
+	eviction  evictionPolicy
+	evictions map[string]*metrics.Counter
+
+	// poolOptions configures every pool the server creates.
+	poolOptions runtime.PoolOptions
+
+	// configDir holds plugin config overlays: <plugin>.json for every
+	// environment and <plugin>.<environment>.json for this one. Empty
+	// disables overlays.
+	configDir   string
+	environment string
+
+	// execTimeout bounds each plugin call; requests may only shorten it.
+	// Zero disables the server-wide limit.
+	execTimeout time.Duration
+
+	// maxOutput bounds the stdout and stderr captured per stream and
+	// request; zero leaves plugins writing to the server's own streams.
+	maxOutput int
+
+	// limiter rejects executions beyond the concurrency limits
+	limiter *limiter
+
+	// auth authenticates API clients and authorizes the plugins they
+	// run; nil leaves the API open, but for the admin token
+	auth *auth
+	// delegator signs the tokens the bridge calls and effects of plugins
+	// run by authenticated clients carry; nil sends them without
+	delegator *delegator
+
+	// locality routes /run requests to the replica caching their plugin;
+	// nil runs every request where it lands
+	locality *localityRouter
+
+	// cacheStats exports the cache metrics of the Fluid runtime behind the
+	// plugin dataset; nil when they are not collected
+	cacheStats *cacheStatsCollector
+
+	// rateLimits rejects requests beyond their client's rate limits; nil
+	// disables rate limiting
+	rateLimits *rateLimiter
+	// tenants enforces the quotas of tenants; nil leaves them unlimited
+	tenants *tenantQuotas
+
+	// verifier re-verifies every build in the store on a schedule; nil
+	// when verification is disabled
+	verifier *storeVerifier
+
+	// shadow repeats sampled executions on a second engine; nil when
+	// shadowing is disabled
+	shadow *shadower
+
+	// metrics aggregates collectors exposed at GET /metrics.
+	metrics *metrics.Registry
+
+	// logModule is registered with every pool so plugins can import the
+	// structured logging host API; messages are written to logger.
+	logModule *runtime.HostModule
+	logger    *slog.Logger
+
+	// logOverrides change the log verbosity of single plugins for a while
+	logOverrides *logOverrides
+
+	// dedup makes requests carrying a dedup key run at most once
+	dedup *deduplicator
+
+	// outboxModule is registered with every pool so plugins can import the
+	// outbox host API; outbox delivers the effects of successful calls.
+	// A nil outbox disables effects: enqueue() reports the outbox
+	// unavailable.
+	outboxModule *runtime.HostModule
+	outbox       *outboxDispatcher
+
+	// hostModule is registered with every pool so plugins can import the
+	// execution context, metrics, cache, object storage, and publishing
+	// host APIs; pluginMetrics holds the metrics they record, exported at
+	// GET /metrics. A nil cache, blobs, or topics reports that API
+	// unavailable.
+	hostModule    *runtime.HostModule
+	pluginMetrics *runtime.PluginMetrics
+	cache         *runtime.Cache
+	blobs         *runtime.Blobs
+	topics        *runtime.Topics
+
+	// stdlibModule is registered with every pool so plugins can use the
+	// host's regex, JSON path, compression, encoding, and hashing instead
+	// of bundling their own.
+	stdlibModule *runtime.HostModule
+
+	// traces keeps the traces of recent debug requests; nil rejects them
+	traces *traceStore
+
+	// schedules runs the schedules plugin manifests declare; nil when
+	// this server does not run them
+	schedules *scheduler
+
+	// maintenance holds the windows during which executions are paused
+	maintenance *maintenance
+
+	// batches runs plugins over files of inputs; nil disables batches
+	batches *batchRunner
+
+	// jobs runs the requests of POST /jobs asynchronously; nil disables
+	// jobs
+	jobs *jobs.Queue
+
+	// streamIdleTimeout closes /run/ws connections idle for that long;
+	// zero keeps them open. streamMaxMessage bounds their messages.
+	streamIdleTimeout time.Duration
+	streamMaxMessage  int64
+
+	// adminToken authorizes plugin uploads and deletion; empty disables
+	// them.
+	adminToken string
+
+	// engine is what the WasmEdge library supports; limits needing what
+	// it lacks are not enforced and reported degraded at GET /version
+	engine runtime.Capabilities
+
+	// startup describes how the server booted, served at
+	// GET /debug/startup
+	startup *StartupReport
 }
 
+// RequestIDHeader carries the ID that tags a /run request's plugin logs.
+// A valid ID sent by the client is kept; otherwise one is generated. The
+// response always carries the ID used.
+const RequestIDHeader = "X-Request-ID"
+
+// TenantHeader carries the tenant a /run request acts on behalf of, and
+// CallerHeader the identity of the client sending it, as established by
+// an authenticating proxy in front of the server. Plugins see both through
+// the execution context host API.
+const (
+	TenantHeader = "X-Tenant"
+	CallerHeader = "X-Caller"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
+// DefaultExecutionTimeout bounds a plugin call when EXECUTION_TIMEOUT is unset.
+// A plugin stuck in a loop is interrupted instead of holding the request open.
+const DefaultExecutionTimeout = 30 * time.Second
+
+// DefaultMaxOutputBytes bounds the plugin stdout and stderr captured per
+// stream and request when MAX_OUTPUT_BYTES is unset.
+const DefaultMaxOutputBytes = 64 << 10
+
 // NewServer creates a Server with the given plugin store.
 func NewServer(store fluid.PluginStore) *Server {
-	return &Server{store: store}
+	s := &Server{
+		store:        store,
+		pools:        make(map[string]*runtime.Pool),
+		poolUsed:     make(map[string]time.Time),
+		evictions:    make(map[string]*metrics.Counter, len(evictReasons)),
+		metrics:      metrics.NewRegistry(),
+		execTimeout:  DefaultExecutionTimeout,
+		maxOutput:    DefaultMaxOutputBytes,
+		logger:       slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		logOverrides: newLogOverrides(),
+		limiter:      newLimiter(0, 0),
+		engine:       runtime.EngineCapabilities(),
+
+		streamIdleTimeout: DefaultStreamIdleTimeout,
+		streamMaxMessage:  websocket.DefaultMaxMessageSize,
+	}
+	s.logModule = runtime.NewLogModule(s.logPluginMessage)
+	s.outboxModule = runtime.NewOutboxModule(s.checkEffect)
+	s.pluginMetrics = runtime.NewPluginMetrics()
+	s.cache = runtime.NewCache(runtime.NewMemoryCache(0), runtime.CacheOptions{})
+	s.hostModule = s.newHostModule()
+	s.stdlibModule = runtime.NewStdlibModule()
+	s.dedup, _ = newDeduplicator(DefaultDedupTTL, "") // Cannot fail without a directory
+	s.maintenance, _ = newMaintenance("")             // Cannot fail without a directory
+	for _, reason := range evictReasons {
+		s.evictions[reason] = &metrics.Counter{}
+	}
+	s.metrics.Register(s.collectPoolMetrics)
+	s.metrics.Register(s.collectEvictionMetrics)
+	s.metrics.Register(s.collectCompilerMetrics)
+	s.metrics.Register(s.collectModuleCacheMetrics)
+	s.metrics.Register(s.collectOutboxMetrics)
+	s.metrics.Register(s.collectLimiterMetrics)
+	s.metrics.Register(s.collectRateLimitMetrics)
+	s.metrics.Register(s.collectTenantMetrics)
+	s.metrics.Register(s.collectLocalityMetrics)
+	s.metrics.Register(s.collectCacheStatsMetrics)
+	s.metrics.Register(s.collectVerifyMetrics)
+	s.metrics.Register(s.collectShadowMetrics)
+	s.metrics.Register(s.collectJobMetrics)
+	s.metrics.Register(s.pluginMetrics.Collect)
+	return s
 }
 
 // Request represents the JSON request body for POST /run
+//
+// Exactly one input form is used: Text or Data select the memory-based
+// payload ABI (process_bytes), Payload the JSON payload ABI
+// (process_json), Fields the protobuf payload ABI (process_pb); otherwise
+// Input is passed to process().
 type Request struct {
-	Plugin string `json:"plugin"` // Plugin name (e.g., "hello")
-	Input  int    `json:"input"`  // Integer input to pass to process()
+	Plugin  string          `json:"plugin"`            // Plugin name (e.g., "hello" or "hello@1.2.0")
+	Input   int             `json:"input"`             // Integer input to pass to process()
+	Text    *string         `json:"text,omitempty"`    // UTF-8 payload for process_bytes()
+	Data    []byte          `json:"data,omitempty"`    // Binary payload for process_bytes() (base64 in JSON)
+	Payload json.RawMessage `json:"payload,omitempty"` // JSON document for process_json()
+	Fields  json.RawMessage `json:"fields,omitempty"`  // Object of typed fields for process_pb()
+
+	// TimeoutMs shortens the server's execution timeout for this call
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// IncludeLogs returns the messages the plugin logged during the call
+	IncludeLogs bool `json:"include_logs,omitempty"`
+
+	// IncludeOutput returns what the plugin wrote to stdout and stderr
+	// during the call, rather than logging it
+	IncludeOutput bool `json:"include_output,omitempty"`
+
+	// DedupKey makes the request run at most once: a redelivery with the
+	// same plugin and key gets the first successful response back
+	DedupKey string `json:"dedup_key,omitempty"`
+
+	// Debug records every export and host function call of the run in a
+	// trace, returned in the response and kept for GET /debug/traces
+	Debug bool `json:"debug,omitempty"`
+
+	// Version pins the plugin build to run, like "hello@1.2.0" in Plugin,
+	// so a caller can reproduce a run while newer versions are published
+	Version string `json:"version,omitempty"`
+
+	// timeoutLimit replaces the server's execution timeout, e.g. for jobs;
+	// zero keeps it
+	timeoutLimit time.Duration
 }
 
 // Response represents the JSON response body
+//
+// For payload requests the output comes back in the same form as the input
+// (Text, Data, Payload, or Fields) and Output holds its length in bytes.
+//
+// Output is null when the plugin succeeded without producing a result
+// (runtime.ErrNoOutput), which clients can tell apart from a zero result.
 type Response struct {
-	Output int `json:"output"` // Result from plugin's process() function
+	Output   *int               `json:"output"`             // Result from plugin's process() function, nil for no output
+	Text     *string            `json:"text,omitempty"`     // process_bytes() output for Text requests
+	Data     []byte             `json:"data,omitempty"`     // process_bytes() output for Data requests
+	Payload  json.RawMessage    `json:"payload,omitempty"`  // process_json() output for Payload requests
+	Fields   map[string]any     `json:"fields,omitempty"`   // process_pb() output fields for Fields requests
+	Warnings []runtime.Warning  `json:"warnings,omitempty"` // Non-fatal conditions observed during the call
+	Logs     []runtime.LogEntry `json:"logs,omitempty"`     // Plugin log messages, if the request asked for them
+	Replayed bool               `json:"replayed,omitempty"` // Recorded response of an earlier request with the same dedup key
+	Effects  int                `json:"effects,omitempty"`  // Outbox effects the plugin enqueued, now committed for delivery
+	Trace    *TraceRecord       `json:"trace,omitempty"`    // Host-call trace of a debug request
+	Version  string             `json:"version,omitempty"`  // Version of the build that ran, empty if unversioned
+
+	// Stdout and Stderr hold what the plugin wrote to them, if the request
+	// asked for it; OutputTruncated is set when either exceeded the limit
+	Stdout          string `json:"stdout,omitempty"`
+	Stderr          string `json:"stderr,omitempty"`
+	OutputTruncated bool   `json:"output_truncated,omitempty"`
+
+	// elapsed is how long the plugin call took
+	elapsed time.Duration
 }
 
-// ErrorResponse represents an error in JSON format
-type ErrorResponse struct {
-	Error string `json:"error"` // Human-readable error message
+// timeout returns the execution timeout for a request: the request's own
+// timeout when it is shorter than the server's, else the server's.
+// Zero means no limit.
+func (s *Server) timeout(req Request) time.Duration {
+	limit := s.execTimeout
+	if req.timeoutLimit > 0 {
+		limit = req.timeoutLimit
+	}
+	requested := time.Duration(req.TimeoutMs) * time.Millisecond
+	if requested > 0 && (limit == 0 || requested < limit) {
+		return requested
+	}
+	return limit
+}
+
+// hasPayload reports whether the request uses the payload ABI.
+func (req Request) hasPayload() bool {
+	return req.Text != nil || req.Data != nil
+}
+
+// checkInput rejects requests using more than one input form, and fields
+// without a protobuf value.
+func (req Request) checkInput() error {
+	if req.Text != nil && req.Data != nil {
+		return apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("text and data are mutually exclusive"))
+	}
+	if req.Payload != nil && req.hasPayload() {
+		return apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("payload is mutually exclusive with text and data"))
+	}
+	if req.Fields != nil {
+		if req.Payload != nil || req.hasPayload() {
+			return apierror.Wrap(apierror.CodeInvalidRequest,
+				errors.New("fields is mutually exclusive with text, data, and payload"))
+		}
+		if _, err := abi.NewFieldsJSON(req.Fields); err != nil {
+			return apierror.Wrap(apierror.CodeInvalidRequest, err)
+		}
+	}
+	return nil
+}
+
+// checkSupport rejects requests whose input form the plugin does not
+// implement the ABI of.
+func (req Request) checkSupport(plugin runtime.Instance) error {
+	if req.hasPayload() && !plugin.SupportsPayloads() {
+		return apierror.Wrap(apierror.CodePayloadUnsupported,
+			fmt.Errorf("plugin %s does not implement the payload ABI", req.Plugin))
+	}
+	if req.Payload != nil && !plugin.SupportsJSON() {
+		return apierror.Wrap(apierror.CodePayloadUnsupported,
+			fmt.Errorf("plugin %s does not implement the JSON payload ABI", req.Plugin))
+	}
+	if req.Fields != nil && !plugin.SupportsProto() {
+		return apierror.Wrap(apierror.CodePayloadUnsupported,
+			fmt.Errorf("plugin %s does not implement the protobuf payload ABI", req.Plugin))
+	}
+	return nil
 }
 
 // handleRun handles POST /run requests
@@ -46,100 +390,669 @@ type ErrorResponse struct {
 // Request lifecycle per call:
 // 1. Parse and validate JSON request
 // 2. Resolve plugin path via PluginStore
-// 3. Load plugin (creates isolated VM)
-// 4. Initialize plugin (calls init())
-// 5. Execute plugin (calls process(input))
-// 6. Cleanup plugin (calls cleanup())
-// 7. Close VM (release all resources)
-// 8. Return JSON response
+// 3. Check out an initialized instance from the plugin's pool
+// 4. Execute plugin (calls process(input) or process_bytes(payload))
+// 5. Return the instance to the pool (reset) or discard it on error
+// 6. Return JSON response
 //
-// On any error, cleanup is guaranteed via defer.
+// Clients accepting text/event-stream get the progress the plugin reports
+// and then the response as Server-Sent Events instead.
+//
+// Plugin log messages are tagged with the request ID, which is returned in
+// the X-Request-ID header of every response.
 func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDOf(r.Header.Get(RequestIDHeader))
+	w.Header().Set(RequestIDHeader, requestID)
+
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
+	// Continue the caller's trace, if any
+	ctx, span := startRequestSpan(r.Context(), "POST /run", propagation.HeaderCarrier(r.Header), requestID)
+	var err error
+	defer func() { endRequestSpan(span, err) }()
+
 	// Parse JSON request body
 	var req Request
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+	if decodeErr := json.NewDecoder(r.Body).Decode(&req); decodeErr != nil {
+		err = apierror.Wrap(apierror.CodeInvalidRequest, decodeErr)
+		writeError(w, r, apierror.CodeInvalidRequest, fmt.Sprintf("invalid JSON: %v", decodeErr))
 		return
 	}
+	annotateRequestSpan(ctx, req)
+	notePlugin(ctx, req.Plugin)
 
-	// Validate plugin name (basic sanitization)
-	if req.Plugin == "" {
-		writeError(w, http.StatusBadRequest, "plugin name is required")
+	call, err := callInfoOf(ctx, requestID, r.Header.Get(TenantHeader), r.Header.Get(CallerHeader))
+	if err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+
+	// Clients accepting an event stream follow the plugin's progress
+	if acceptsEventStream(r) {
+		err = s.runEvents(ctx, w, r, req, call)
 		return
 	}
-	if !isValidPluginName(req.Plugin) {
-		writeError(w, http.StatusBadRequest, "invalid plugin name")
+
+	// A disconnecting client cancels the call
+	resp, err := s.run(ctx, req, call)
+	if err != nil {
+		// The error carries the code of the check or lifecycle stage that failed
+		writeExecutionError(w, r, err)
 		return
 	}
 
+	// Return successful response
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// run validates a request and executes it on behalf of call, whose request
+// ID tags the plugin's log messages. It is shared by the HTTP and gRPC
+// APIs, so every error carries an apierror code.
+func (s *Server) run(ctx context.Context, req Request, call runtime.CallInfo) (Response, error) {
+	if err := s.checkRequest(&req); err != nil {
+		return Response{}, err
+	}
+	name, _ := fluid.ParseReference(req.Plugin)
+	if err := s.checkPluginAccess(ctx, name); err != nil {
+		return Response{}, err
+	}
+	if err := s.checkRateLimit(ctx, name, call.Tenant); err != nil {
+		return Response{}, err
+	}
+	if err := s.maintenance.check(name, call.Tenant); err != nil {
+		return Response{}, err
+	}
+	if req.DedupKey == "" {
+		return s.execute(ctx, req, call)
+	}
+
+	// Redeliveries of a request that already succeeded get its response
+	// back instead of running a side-effecting plugin again
+	if !validID(req.DedupKey) {
+		return Response{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			fmt.Errorf("dedup_key must be printable ASCII of at most %d bytes", maxRequestIDLength))
+	}
+	// Keys are unique per tenant and client, so one cannot replay, or
+	// block, another's request by guessing its key
+	scope := dedupScope{Tenant: call.Tenant, Plugin: req.Plugin}
+	if p, ok := principalOf(ctx); ok {
+		scope.Principal = p.Name
+	}
+	recorded, err := s.dedup.claim(scope, req.DedupKey)
+	if err != nil {
+		return Response{}, err
+	}
+	if recorded != nil {
+		recorded.Replayed = true
+		return *recorded, nil
+	}
+
+	resp, err := s.execute(ctx, req, call)
+	if err != nil {
+		s.dedup.release(scope, req.DedupKey)
+		return Response{}, err
+	}
+	// The plugin ran; failing the request now would invite a second run
+	if err := s.dedup.complete(scope, req.DedupKey, resp); err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "failed to record dedup key",
+			slog.String("plugin", req.Plugin),
+			slog.String("request_id", call.RequestID),
+			slog.String("error", err.Error()))
+	}
+	return resp, nil
+}
+
+// checkRequest validates a request, folding its version into the plugin
+// reference.
+func (s *Server) checkRequest(req *Request) error {
+	if req.Version != "" {
+		name, version := fluid.ParseReference(req.Plugin)
+		if version != "" && version != req.Version {
+			return apierror.Wrap(apierror.CodeInvalidRequest,
+				fmt.Errorf("plugin %s conflicts with version %s", req.Plugin, req.Version))
+		}
+		req.Plugin = fluid.Reference(name, req.Version)
+	}
+
+	// Validate plugin name (basic sanitization)
+	if err := checkPluginName(req.Plugin); err != nil {
+		return err
+	}
+	if err := req.checkInput(); err != nil {
+		return err
+	}
+	if req.TimeoutMs < 0 {
+		return apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("timeout_ms must not be negative"))
+	}
+	if req.Debug && s.traces == nil {
+		return apierror.Wrap(apierror.CodeInvalidRequest, errTracesDisabled)
+	}
+	return nil
+}
+
+// runAdmitted is run for callers that can wait: a request rejected because
+// the server is saturated or the plugin is paused for maintenance is run
+// again after the rejection's Retry-After, waiting at most maxDelay, until
+// ctx is done. retried is called before each retry.
+func (s *Server) runAdmitted(ctx context.Context, req Request, call runtime.CallInfo, maxDelay time.Duration, retried func()) (Response, error) {
+	for {
+		resp, err := s.run(ctx, req, call)
+		if code := apierror.CodeOf(err); err == nil || (code != apierror.CodeTooManyExecutions && code != apierror.CodeMaintenance) {
+			return resp, err
+		}
+
+		retried()
+		delay := min(max(apierror.RetryAfterOf(err), 10*time.Millisecond), maxDelay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return Response{}, err
+		}
+	}
+}
+
+// execute resolves and runs a validated request.
+func (s *Server) execute(ctx context.Context, req Request, call runtime.CallInfo) (Response, error) {
+	requestID := call.RequestID
+
 	// Resolve plugin path via PluginStore
 	// This abstracts the difference between local and Fluid storage
-	pluginPath, err := s.store.Resolve(req.Plugin)
+	pluginPath, err := s.resolve(ctx, call.Tenant, req.Plugin)
 	if err != nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("plugin not found: %s", req.Plugin))
-		return
+		// A plugin that exists but has an invalid manifest must not be
+		// reported as missing
+		if errors.Is(err, manifest.ErrInvalid) {
+			return Response{}, apierror.Wrap(apierror.CodePluginLoadFailed, err)
+		}
+		return Response{}, apierror.Wrap(apierror.CodePluginNotFound,
+			fmt.Errorf("plugin not found: %s", req.Plugin))
+	}
+
+	// Concurrency limits apply to every version of the plugin together
+	name, _ := fluid.ParseReference(req.Plugin)
+	release, err := s.limiter.acquire(name)
+	if err != nil {
+		return Response{}, err
+	}
+	defer release()
+	releaseTenant, err := s.tenants.acquire(call.Tenant)
+	if err != nil {
+		return Response{}, err
+	}
+	defer releaseTenant()
+
+	// Bound execution by the timeout
+	if timeout := s.timeout(req); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Tell the plugin who the request comes from, and collect its log
+	// messages and output for this request
+	ctx = runtime.WithCallInfo(ctx, call)
+	capture := runtime.NewLogCapture(requestID)
+	ctx = runtime.WithLogCapture(ctx, capture)
+	output := runtime.NewOutputCapture(s.maxOutput)
+	ctx = runtime.WithOutputCapture(ctx, output)
+
+	// Hold the plugin's effects until the call has succeeded
+	var outbox *runtime.Outbox
+	if s.outbox != nil {
+		outbox = runtime.NewOutbox()
+		ctx = runtime.WithOutbox(ctx, outbox)
 	}
 
+	// Record the run's host interactions for debug requests, and for every
+	// run of a plugin whose log override asks for traces
+	override, _ := s.logOverrides.get(name)
+	var trace *runtime.Trace
+	if req.Debug || (override.Trace && s.traces != nil) {
+		trace = runtime.NewTrace()
+		ctx = runtime.WithTrace(ctx, trace)
+	}
+	started := time.Now()
+
 	// Execute plugin with full lifecycle management
-	output, err := executePlugin(pluginPath, req.Input)
+	resp, err := s.executePlugin(ctx, pluginPath, req)
+	if req.IncludeOutput && err == nil {
+		resp.Stdout, resp.Stderr = string(output.Stdout()), string(output.Stderr())
+		resp.OutputTruncated = output.Truncated()
+	} else {
+		// Output nobody asked for, or of a failed run, is logged
+		s.logOutput(requestID, req.Plugin, output)
+	}
+	if trace != nil {
+		// Failed runs are kept too; they are the ones worth inspecting
+		record := traceRecord(requestID, req.Plugin, started, trace, err)
+		s.traces.add(record)
+		if req.Debug {
+			resp.Trace = record
+		}
+	}
 	if err != nil {
-		// Determine appropriate HTTP status code based on error
-		writeError(w, http.StatusInternalServerError, err.Error())
+		// The effects of a failed call are discarded
+		return Response{}, err
+	}
+	if outbox != nil {
+		effects := outbox.Effects()
+		if err := s.outbox.commit(requestID, delegationOf(ctx, name), effects); err != nil {
+			return Response{}, apierror.Wrap(apierror.CodeInternal,
+				fmt.Errorf("failed to commit plugin effects: %w", err))
+		}
+		resp.Effects = len(effects)
+	}
+	if req.IncludeLogs {
+		resp.Logs = capture.Entries()
+	}
+	resp.Version = fluid.BuildVersion(pluginPath)
+	if s.shadow != nil {
+		s.shadow.maybeRun(name, pluginPath, req, call, resp, s.timeout(req), s.outbox != nil)
+	}
+	return resp, nil
+}
+
+// logOutput logs what a plugin wrote to stdout and stderr during a
+// request, if anything.
+func (s *Server) logOutput(requestID, plugin string, output *runtime.OutputCapture) {
+	stdout, stderr := output.Stdout(), output.Stderr()
+	if len(stdout) == 0 && len(stderr) == 0 {
 		return
 	}
+	s.logger.Info("plugin output",
+		slog.String("request_id", requestID),
+		slog.String("plugin", plugin),
+		slog.String("stdout", string(stdout)),
+		slog.String("stderr", string(stderr)),
+		slog.Bool("truncated", output.Truncated()))
+}
 
-	// Return successful response
-	writeJSON(w, http.StatusOK, Response{Output: output})
+// checkPluginName rejects empty and invalid plugin names with the
+// matching apierror code.
+func checkPluginName(name string) error {
+	if name == "" {
+		return apierror.Wrap(apierror.CodeMissingPluginName, errors.New("plugin name is required"))
+	}
+	if !isValidPluginName(name) {
+		return apierror.Wrap(apierror.CodeInvalidPluginName, errors.New("invalid plugin name"))
+	}
+	return nil
+}
+
+// checkBuildName is checkPluginName for requests that write or remove a
+// single build, which "latest" cannot name.
+func checkBuildName(name string) error {
+	if err := checkPluginName(name); err != nil {
+		return err
+	}
+	if _, version := fluid.ParseReference(name); version == fluid.LatestVersion {
+		return apierror.Wrap(apierror.CodeInvalidPluginName,
+			errors.New("latest is an alias; name the version to change"))
+	}
+	return nil
 }
 
-// executePlugin loads, initializes, executes, and cleans up a plugin
+// executePlugin runs a plugin on an instance checked out from its pool
 //
 // This function guarantees:
-// - Plugin is always closed (VM resources released)
-// - Cleanup is called if init succeeded
-// - Errors are wrapped with context
-func executePlugin(pluginPath string, input int) (int, error) {
-	// Step 1: Load the plugin
-	// This creates an isolated WasmEdge VM instance
-	plugin, err := runtime.LoadPlugin(pluginPath)
+//   - The instance is returned to the pool after a successful call
+//   - The instance is discarded (cleanup + close) if execution failed or
+//     was interrupted because ctx was done
+//   - Errors are wrapped with context
+//
+// Non-fatal conditions observed after the call are returned as warnings.
+func (s *Server) executePlugin(ctx context.Context, pluginPath string, req Request) (Response, error) {
+	pool, err := s.pool(req.Plugin, pluginPath)
 	if err != nil {
-		return 0, fmt.Errorf("failed to load plugin: %w", err)
+		return Response{}, apierror.Wrap(apierror.CodePluginLoadFailed,
+			fmt.Errorf("failed to load plugin: %w", err))
 	}
 
-	// Guarantee VM resources are released when we're done
-	defer plugin.Close()
+	// Check out an initialized instance
+	// A fresh one is loaded and initialized if none are idle; waiting for
+	// one at the pool's max size counts against the timeout
+	plugin, err := pool.GetInstance(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return Response{}, s.executionError(req, err)
+		}
+		return Response{}, apierror.Wrap(apierror.CodePluginInitFailed,
+			fmt.Errorf("failed to initialize plugin: %w", err))
+	}
 
-	// Step 2: Initialize the plugin
-	// Calls the exported init() function
-	if err := plugin.Init(); err != nil {
-		return 0, fmt.Errorf("failed to initialize plugin: %w", err)
+	// The instance counts against the tenant's memory quota while it runs
+	unreserve, err := s.reserveInstance(runtime.CallInfoFrom(ctx).Tenant, plugin)
+	if err != nil {
+		pool.PutInstance(ctx, plugin)
+		return Response{}, err
 	}
+	defer unreserve()
 
-	// Guarantee cleanup is called after successful init
-	defer func() {
-		// Best effort cleanup - don't fail the request if cleanup fails
-		_ = plugin.Cleanup()
-	}()
+	// Payload requests need the allocate/deallocate/process_bytes or
+	// process_json exports. The instance is healthy, so it goes back to
+	// the pool.
+	if err := req.checkSupport(plugin); err != nil {
+		pool.PutInstance(ctx, plugin)
+		return Response{}, err
+	}
 
-	// Step 3: Execute the plugin's process function
-	// Calls the exported process(int) function
-	output, err := plugin.Execute(input)
+	started := time.Now()
+	resp, err := invoke(ctx, plugin, req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to execute plugin: %w", err)
+		// The instance may be in a broken state - never reuse it
+		pool.DiscardInstance(ctx, plugin)
+		return Response{}, s.executionError(req, err)
+	}
+	resp.elapsed = time.Since(started)
+
+	// Inspect the instance before it is reset for the next request
+	resp.Warnings = diagnose(plugin)
+
+	pool.PutInstance(ctx, plugin)
+	return resp, nil
+}
+
+// executionError wraps the error of a failed plugin call with the
+// apierror code of its cause.
+func (s *Server) executionError(req Request, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apierror.Wrap(apierror.CodePluginTimeout,
+			fmt.Errorf("plugin %s exceeded the %s execution timeout", req.Plugin, s.timeout(req)))
+	}
+	if errors.Is(err, runtime.ErrMemoryLimit) {
+		return apierror.Wrap(apierror.CodeMemoryLimitExceeded,
+			fmt.Errorf("plugin %s ran out of memory: %w", req.Plugin, err))
+	}
+	if errors.Is(err, runtime.ErrInstructionLimit) {
+		return apierror.Wrap(apierror.CodeInstructionLimitExceeded,
+			fmt.Errorf("plugin %s exceeded its instruction limit: %w", req.Plugin, err))
+	}
+	if errors.Is(err, runtime.ErrFileQuota) {
+		return apierror.Wrap(apierror.CodeFileQuotaExceeded,
+			fmt.Errorf("plugin %s exceeded its file quota: %w", req.Plugin, err))
+	}
+	return apierror.Wrap(apierror.CodePluginExecutionFailed,
+		fmt.Errorf("failed to execute plugin: %w", err))
+}
+
+// requestIDOf returns the request ID a client supplied if it is valid, or
+// a new random one.
+func requestIDOf(id string) string {
+	if validID(id) {
+		return id
+	}
+
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// callInfoOf returns the metadata passed to the plugin of a request made
+// with ctx. The tenant and caller are optional, but rejected if unusable
+// rather than dropped, so that a plugin never attributes work to the wrong
+// party. A principal bound to a tenant acts on behalf of that tenant only.
+func callInfoOf(ctx context.Context, requestID, tenant, caller string) (runtime.CallInfo, error) {
+	for _, value := range []struct{ name, id string }{{"tenant", tenant}, {"caller", caller}} {
+		if value.id != "" && !validID(value.id) {
+			return runtime.CallInfo{}, apierror.Wrap(apierror.CodeInvalidRequest,
+				fmt.Errorf("%s must be printable ASCII of at most %d bytes", value.name, maxRequestIDLength))
+		}
+	}
+	if p, ok := principalOf(ctx); ok && p.Tenant != "" {
+		if tenant != "" && tenant != p.Tenant {
+			return runtime.CallInfo{}, apierror.Wrap(apierror.CodeForbidden,
+				fmt.Errorf("%s may not act on behalf of tenant %s", p.Name, tenant))
+		}
+		tenant = p.Tenant
+	}
+	return runtime.CallInfo{RequestID: requestID, Tenant: tenant, Caller: caller}, nil
+}
+
+// validID reports whether a client-supplied identifier is reasonable:
+// printable ASCII without spaces, at most maxRequestIDLength bytes.
+func validID(id string) bool {
+	valid := id != "" && len(id) <= maxRequestIDLength
+	for i := 0; valid && i < len(id); i++ {
+		valid = id[i] > ' ' && id[i] < 0x7f
+	}
+	return valid
+}
+
+// slogLevel maps a plugin log level to the matching slog level.
+func slogLevel(level runtime.LogLevel) slog.Level {
+	switch level {
+	case runtime.LogDebug:
+		return slog.LevelDebug
+	case runtime.LogWarn:
+		return slog.LevelWarn
+	case runtime.LogError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
 	}
+}
+
+// invoke calls the plugin entry point matching the request's input form.
+func invoke(ctx context.Context, plugin runtime.Instance, req Request) (Response, error) {
+	switch {
+	case req.Text != nil:
+		// Calls the exported process_bytes(ptr, len) function
+		output, err := plugin.ExecuteBytesContext(ctx, []byte(*req.Text))
+		if err != nil {
+			return noOutput(err)
+		}
+		text := string(output)
+		length := len(text)
+		return Response{Output: &length, Text: &text}, nil
+
+	case req.Data != nil:
+		data, err := plugin.ExecuteBytesContext(ctx, req.Data)
+		if err != nil {
+			return noOutput(err)
+		}
+		length := len(data)
+		return Response{Output: &length, Data: data}, nil
 
-	return output, nil
+	case req.Payload != nil:
+		// Calls the exported process_json(ptr, len) function
+		payload, err := plugin.ExecuteJSONContext(ctx, req.Payload)
+		if err != nil {
+			return noOutput(err)
+		}
+		length := len(payload)
+		return Response{Output: &length, Payload: payload}, nil
+
+	case req.Fields != nil:
+		// Calls the exported process_pb(ptr, len) function; checkInput
+		// has validated the fields
+		fields, err := abi.NewFieldsJSON(req.Fields)
+		if err != nil {
+			return Response{}, err
+		}
+		resp, err := plugin.ExecuteProtoContext(ctx, &abi.PluginRequest{
+			Fields:    fields,
+			RequestId: runtime.CallInfoFrom(ctx).RequestID,
+		})
+		if err != nil {
+			return noOutput(err)
+		}
+		length := proto.Size(resp)
+		return Response{Output: &length, Fields: abi.AsMap(resp.Fields)}, nil
+
+	default:
+		// Calls the exported process(int) function
+		output, err := plugin.ExecuteContext(ctx, req.Input)
+		if err != nil {
+			return noOutput(err)
+		}
+		return Response{Output: &output}, nil
+	}
+}
+
+// noOutput turns runtime.ErrNoOutput into a successful response without
+// output; any other error is returned unchanged.
+func noOutput(err error) (Response, error) {
+	if errors.Is(err, runtime.ErrNoOutput) {
+		return Response{}, nil
+	}
+	return Response{}, err
+}
+
+// pool returns the instance pool for a plugin path, creating it on first
+// use. A pool created for a request measures its reset strategy in the
+// background, so that the request only waits for its first instances.
+func (s *Server) pool(name, pluginPath string) (*runtime.Pool, error) {
+	return s.openPool(name, pluginPath, true)
+}
+
+// openPool returns the instance pool for a plugin path, creating it if
+// there is none, measuring its reset strategy in the background or before
+// it is returned. Concurrent calls for a path share one creation, which
+// calls for other paths do not wait for.
+func (s *Server) openPool(name, pluginPath string, background bool) (*runtime.Pool, error) {
+	if pool := s.currentPool(pluginPath); pool != nil {
+		return pool, nil
+	}
+
+	pool, err, _ := s.poolCreation.Do(pluginPath, func() (interface{}, error) {
+		// Another creation may have finished since the lookup above
+		if pool := s.currentPool(pluginPath); pool != nil {
+			return pool, nil
+		}
+		opts, err := s.pluginPoolOptions(name)
+		if err != nil {
+			return nil, err
+		}
+		opts.CalibrateInBackground = background
+		pool, err := runtime.NewPool(pluginPath, opts)
+		if err != nil {
+			return nil, err
+		}
+		s.poolsMu.Lock()
+		s.pools[pluginPath] = pool
+		s.poolUsed[pluginPath] = time.Now()
+		s.poolsMu.Unlock()
+		return pool, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pool.(*runtime.Pool), nil
+}
+
+// currentPool returns the pool of a plugin path and marks it used, or nil
+// if there is none. A pool whose artifact was replaced (e.g., by
+// `pluginctl dev`) is retired, so that new requests run the new build;
+// its instances still checked out are destroyed when they are returned.
+func (s *Server) currentPool(pluginPath string) *runtime.Pool {
+	s.poolsMu.Lock()
+	pool, ok := s.pools[pluginPath]
+	if ok && !pool.Stale() {
+		s.poolUsed[pluginPath] = time.Now()
+		s.poolsMu.Unlock()
+		return pool
+	}
+	if ok {
+		delete(s.pools, pluginPath)
+	}
+	s.poolsMu.Unlock()
+
+	if ok {
+		pool.Close()
+	}
+	return nil
+}
+
+// pluginPoolOptions returns the options of the pools of the plugin called
+// name, in any version.
+func (s *Server) pluginPoolOptions(name string) (runtime.PoolOptions, error) {
+	opts := s.poolOptions
+	opts.HostModules = s.hostModules()
+	opts.CaptureOutput = s.maxOutput > 0
+	// Configuration applies to every version of a plugin
+	base, _ := fluid.ParseReference(name)
+	overlays, err := s.configOverlays(base)
+	if err != nil {
+		return runtime.PoolOptions{}, err
+	}
+	opts.ConfigOverlays = overlays
+	return opts, nil
+}
+
+// newHostModule returns the "host" module, with the functions of the
+// host APIs the server implements itself.
+func (s *Server) newHostModule() *runtime.HostModule {
+	module := runtime.DefineProgress(s.cache.Define(s.pluginMetrics.Define(runtime.NewContextModule())))
+	return s.topics.Define(s.blobs.Define(module))
+}
+
+// hostModules returns the host modules registered with every instance:
+// the configured ones, the logging and outbox APIs, the "host" module,
+// and the standard library.
+func (s *Server) hostModules() []*runtime.HostModule {
+	return append(append([]*runtime.HostModule(nil), s.poolOptions.HostModules...),
+		s.logModule, s.outboxModule, s.hostModule, s.stdlibModule)
+}
+
+// checkEffect vets an effect a plugin enqueues; see outboxDispatcher.check.
+func (s *Server) checkEffect(effect runtime.Effect) error {
+	if s.outbox == nil {
+		return errors.New("plugin effects are disabled")
+	}
+	return s.outbox.check(effect)
+}
+
+// configOverlays reads the config overlays for a plugin, in the order they
+// apply on top of its manifest's config block:
+//  1. <configDir>/<name>.json, shared by every environment
+//  2. <configDir>/<name>.<environment>.json
+//
+// Missing files are skipped. The directory is typically a mounted
+// ConfigMap, whose keys become file names.
+func (s *Server) configOverlays(name string) ([][]byte, error) {
+	if s.configDir == "" {
+		return nil, nil
+	}
+	files := []string{name + ".json"}
+	if s.environment != "" {
+		files = append(files, name+"."+s.environment+".json")
+	}
+
+	var overlays [][]byte
+	for _, file := range files {
+		path := filepath.Join(s.configDir, file)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plugin config: %w", err)
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil || object == nil {
+			return nil, fmt.Errorf("plugin config %s must be a JSON object", path)
+		}
+		overlays = append(overlays, data)
+	}
+	return overlays, nil
 }
 
 // isValidPluginName checks if the plugin name is safe to use in file paths
 // Prevents path traversal attacks (e.g., "../etc/passwd")
+//
+// The name may carry a version, "hello@1.2.0" or "hello@latest".
 func isValidPluginName(name string) bool {
+	name, version, versioned := strings.Cut(name, "@")
+	if versioned && version != fluid.LatestVersion && !fluid.ValidVersion(version) {
+		return false
+	}
+
 	// Must be non-empty
 	if len(name) == 0 {
 		return false
@@ -165,52 +1078,776 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// writeError writes a JSON error response with the given status code
-func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, ErrorResponse{Error: message})
+// writeError writes an RFC 7807 problem response for the given code.
+// The HTTP status is determined by the code; the title is translated to the
+// best match for the request's Accept-Language header.
+func writeError(w http.ResponseWriter, r *http.Request, code apierror.Code, detail string) {
+	writeProblem(w, localizedProblem(w, r, code, detail))
+}
+
+// writeExecutionError writes the problem for a failed plugin execution.
+// If the plugin reported the failure itself, its code and message are
+// included so clients need not parse them out of the detail.
+func writeExecutionError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := localizedProblem(w, r, apierror.CodeOf(err), err.Error())
+	if after := apierror.RetryAfterOf(err); after > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(after)))
+	}
+
+	problem.PluginError = pluginErrorOf(err)
+	writeProblem(w, problem)
+}
+
+// pluginErrorOf returns the error the plugin reported itself, if it did.
+func pluginErrorOf(err error) *apierror.PluginError {
+	var pluginErr *runtime.PluginError
+	if !errors.As(err, &pluginErr) {
+		return nil
+	}
+	return &apierror.PluginError{
+		Code:    pluginErr.Code,
+		Name:    pluginErr.Name(),
+		Message: pluginErr.Message,
+	}
+}
+
+// localizedProblem creates a problem for the request, translating the title
+// to the best match for its Accept-Language header.
+func localizedProblem(w http.ResponseWriter, r *http.Request, code apierror.Code, detail string) *apierror.Problem {
+	locale := apierror.MatchLocale(r.Header.Get("Accept-Language"))
+
+	problem := apierror.NewLocalized(code, detail, locale)
+	problem.Instance = r.URL.Path
+
+	w.Header().Set("Content-Language", locale.String())
+	w.Header().Add("Vary", "Accept-Language")
+	return problem
+}
+
+// writeProblem writes a problem as application/problem+json, tagged with
+// the request's ID
+func writeProblem(w http.ResponseWriter, problem *apierror.Problem) {
+	problem.RequestID = w.Header().Get(RequestIDHeader)
+	if recorder, ok := w.(*accessRecorder); ok {
+		recorder.entry.code = problem.Code
+	}
+	w.Header().Set("Content-Type", apierror.ContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// poolOptionsFromConfig returns the pool options of cfg. Pool sizes left
+// unset are seeded per plugin from its manifest's sizing hints; see
+// runtime.SuggestPoolSize.
+func poolOptionsFromConfig(cfg *config.Config) runtime.PoolOptions {
+	return runtime.PoolOptions{
+		MinSize:           cfg.Pool.MinSize,
+		MaxSize:           cfg.Pool.MaxSize,
+		MemoryBudgetMiB:   cfg.Pool.MemoryBudgetMiB,
+		IdleTimeout:       cfg.Pool.IdleTimeout,
+		HealthInterval:    cfg.Pool.HealthInterval,
+		ShutdownTimeout:   cfg.Pool.ShutdownTimeout,
+		InitTimeout:       cfg.Pool.InitTimeout,
+		MaxMemoryPages:    cfg.Execution.MaxMemoryPages,
+		MaxInstructions:   uint64(cfg.Execution.MaxInstructions),
+		RequireDigest:     cfg.Plugins.RequireDigest,
+		RequireABIVersion: cfg.Plugins.RequireABI,
+		WASI: runtime.WASIOptions{
+			Env:             cfg.WASI.EnvVars(),
+			AllowEnv:        cfg.WASI.AllowEnv,
+			Dirs:            cfg.WASI.DirMap(),
+			ScratchDir:      cfg.WASI.ScratchDir,
+			ScratchMaxBytes: int64(cfg.WASI.ScratchMaxMiB) << 20,
+			ScratchMaxFiles: cfg.WASI.ScratchMaxFiles,
+		},
+	}
+}
+
+// loadHostModules loads the host modules declared in the hostext
+// configuration file at path, whose RPC bridge calls carry the tokens
+// credentials returns. The modules the server provides itself cannot be
+// replaced.
+func loadHostModules(path string, credentials hostext.Credentials) ([]*runtime.HostModule, error) {
+	cfg, err := hostext.ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range cfg.Modules {
+		switch m.Name {
+		case runtime.LogModule, runtime.OutboxModule, runtime.ContextModule, runtime.SQLModule, runtime.StdlibModule:
+			return nil, fmt.Errorf("module name %s is reserved for the server's host API", m.Name)
+		}
+	}
+	cfg.Credentials = credentials
+	return cfg.Load()
+}
+
+// objectStoreOptions returns the options of the S3 and HTTP stores,
+// which download plugins into a cache directory.
+func objectStoreOptions(cfg *config.Config) fluid.ObjectStoreOptions {
+	opts := fluid.ObjectStoreOptions{CacheDir: cfg.Store.Cache.Dir, Revalidate: cfg.Store.Cache.TTL}
+	if opts.CacheDir == "" {
+		opts.CacheDir = fluid.DefaultCacheDir()
+	}
+	return opts
+}
+
+// newLogger returns the server's JSON logger, writing records at level and
+// above, and the level, which PUT /admin/log-level changes.
+func newLogger(level string) (*slog.Logger, *slog.LevelVar) {
+	l := new(slog.LevelVar)
+	l.UnmarshalText([]byte(level)) // Validated by config.Load
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: l})), l
 }
 
 func main() {
-	// Determine which plugin store to use based on environment.
+	// Settings come from defaults, the YAML file named by -config or
+	// CONFIG_FILE, the environment, and flags, in increasing precedence;
+	// CONFIG.md lists them all
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Determine which plugin store to use.
 	//
 	// In production with Fluid:
 	//   PLUGIN_STORE=fluid
 	//   FLUID_MOUNT_PATH=/mnt/fluid/plugins
 	//
+	// Without Fluid, from an S3 or S3-compatible bucket:
+	//   PLUGIN_STORE=s3
+	//   S3_BUCKET=my-plugins
+	//
+	// Or from a web server:
+	//   PLUGIN_STORE=http
+	//   PLUGIN_BASE_URL=https://plugins.example.com/
+	//
+	// Or from a backend registered with fluid.RegisterStore:
+	//   PLUGIN_STORE=gcs
+	//   PLUGIN_STORE_OPTIONS=bucket=my-plugins
+	//
+	// Or from an empty temporary directory, as demo mode (-demo) does:
+	//   PLUGIN_STORE=memory
+	//
 	// In development (default):
-	//   Plugins are loaded from ./plugins/
+	//   Plugins are loaded from ./plugins/ (override with PLUGIN_DIR)
 	var store fluid.PluginStore
+	var storeDir string // Directory of the local, Fluid, and memory stores, watched for changes
+
+	// The startup report records what the configuration set up; it is
+	// logged once the server listens and served at GET /debug/startup
+	report := newStartupReport()
+
+	// Demo mode needs no plugins of its own: the sample plugins are
+	// published to a fresh memory store
+	if cfg.Demo {
+		cfg.Store.Type = "memory"
+	}
 
-	storeType := os.Getenv("PLUGIN_STORE")
-	switch storeType {
+	switch cfg.Store.Type {
 	case "fluid":
 		// Production: use Fluid dataset mount
-		mountPath := os.Getenv("FLUID_MOUNT_PATH")
-		if mountPath == "" {
-			mountPath = "/mnt/fluid/plugins" // Default Fluid mount path
+		store = fluid.NewFluidPluginStore(cfg.Store.FluidMount)
+		storeDir = cfg.Store.FluidMount
+		report.Store = StoreReport{Type: "fluid", Location: storeDir}
+	case "s3":
+		// Plugins are downloaded from the bucket into the cache directory
+		// on first use and revalidated every store.cache.ttl
+		client, err := fluid.NewS3Client(fluid.S3Config{
+			Bucket:          cfg.Store.S3.Bucket,
+			Region:          cfg.Store.S3.Region,
+			Endpoint:        cfg.Store.S3.Endpoint,
+			AccessKeyID:     cfg.AWS.AccessKeyID,
+			SecretAccessKey: cfg.AWS.SecretAccessKey,
+			SessionToken:    cfg.AWS.SessionToken,
+		})
+		if err != nil {
+			fmt.Printf("Invalid S3 configuration: %v\n", err)
+			os.Exit(1)
 		}
-		store = fluid.NewFluidPluginStore(mountPath)
-		fmt.Printf("Using Fluid plugin store: %s\n", mountPath)
-	default:
+		opts := objectStoreOptions(cfg)
+		opts.Prefix = cfg.Store.S3.Prefix
+		store = fluid.NewObjectPluginStore(client, opts)
+		report.Store = StoreReport{Type: "s3", Location: "s3://" + cfg.Store.S3.Bucket + "/" + opts.Prefix, Cache: opts.CacheDir}
+	case "http":
+		// Plugins are downloaded from <base_url>/<name>/<name>.wasm,
+		// cached like the S3 store
+		httpCfg := fluid.HTTPConfig{BaseURL: cfg.Store.HTTP.BaseURL}
+		if token := cfg.Store.HTTP.Token; token != "" {
+			httpCfg.Header = http.Header{"Authorization": {"Bearer " + token}}
+		}
+		opts := objectStoreOptions(cfg)
+		httpStore, err := fluid.NewHTTPPluginStore(httpCfg, opts)
+		if err != nil {
+			fmt.Printf("Invalid PLUGIN_BASE_URL: %v\n", err)
+			os.Exit(1)
+		}
+		store = httpStore
+		report.Store = StoreReport{Type: "http", Location: httpCfg.BaseURL, Cache: opts.CacheDir}
+	case "local":
 		// Development: use local filesystem
-		store = fluid.NewLocalPluginStore("./plugins")
-		fmt.Println("Using local plugin store: ./plugins")
+		store = fluid.NewLocalPluginStore(cfg.Store.Dir)
+		storeDir = cfg.Store.Dir
+		report.Store = StoreReport{Type: "local", Location: storeDir}
+	case "memory":
+		memStore, err := fluid.NewMemoryPluginStore()
+		if err != nil {
+			fmt.Printf("Invalid plugin store: %v\n", err)
+			os.Exit(1)
+		}
+		defer memStore.Close()
+		store = memStore
+		storeDir = memStore.Dir()
+		report.Store = StoreReport{Type: "memory", Location: storeDir}
+		if cfg.Demo {
+			names, err := seedDemo(memStore)
+			if err != nil {
+				fmt.Printf("Invalid demo plugins: %v\n", err)
+				os.Exit(1)
+			}
+			report.enable("demo", "Serving the sample plugins %s", strings.Join(names, ", "))
+		}
+	default:
+		// A backend registered with fluid.RegisterStore by a package
+		// compiled into the server, configured by store.options
+		store, err = fluid.NewStore(cfg.Store.Type, fluid.StoreConfig{
+			Options: cfg.Store.OptionMap(),
+			Cache:   objectStoreOptions(cfg),
+		})
+		if err != nil {
+			fmt.Printf("Invalid plugin store: %v\n", err)
+			os.Exit(1)
+		}
+		report.Store = StoreReport{Type: cfg.Store.Type}
 	}
 
 	// Create server with the plugin store
 	server := NewServer(store)
+	var logLevel *slog.LevelVar
+	server.logger, logLevel = newLogger(cfg.Log.Level)
+
+	// Servers built with the leakcheck tag log plugins that are garbage
+	// collected without Close
+	if runtime.LeakDetection {
+		runtime.SetLeakHandler(func(leak runtime.LeakReport) {
+			server.logger.Error("plugin garbage collected without Close; its VM is leaked",
+				slog.String("plugin", leak.Path), slog.String("loaded_at", leak.Stack))
+		})
+		report.enable("leak_detection", "Reporting plugins garbage collected without Close")
+	}
+	server.poolOptions = poolOptionsFromConfig(cfg)
+
+	// execution.engine runs every plugin on an engine other than WasmEdge,
+	// without the features only WasmEdge provides
+	engine, err := runtime.LookupEngine(cfg.Execution.Engine)
+	if err != nil {
+		fmt.Printf("Invalid ENGINE: %v; registered: %v\n", err, runtime.RegisteredEngines())
+		os.Exit(1)
+	}
+	if engine != runtime.WasmEdge {
+		server.poolOptions.Engine = engine
+		server.engine = runtime.Capabilities{Name: engine.Name(), Version: server.engine.Version}
+		report.enable("engine", "Running plugins on %s", engine.Name())
+	}
+
+	// tracing.otlp_endpoint exports OpenTelemetry spans of every run, from
+	// the request through the store lookup to the plugin's Load, Init,
+	// Execute, and Cleanup, e.g. to Jaeger or Tempo
+	if endpoint := cfg.Tracing.OTLPEndpoint; endpoint != "" {
+		exporter, err := tracing.NewOTLPExporter(tracing.OTLPConfig{
+			Endpoint:    endpoint,
+			Headers:     cfg.Tracing.Headers(),
+			ServiceName: cfg.Tracing.ServiceName,
+		})
+		if err != nil {
+			fmt.Printf("Invalid OTEL_EXPORTER_OTLP_ENDPOINT: %v\n", err)
+			os.Exit(1)
+		}
+		provider := tracing.NewProvider(exporter, tracing.Options{
+			SampleRatio: cfg.Tracing.SampleRatio,
+			OnError: func(err error) {
+				server.logger.Warn("failed to export spans", slog.String("error", err.Error()))
+			},
+		})
+		defer provider.Shutdown(context.Background())
+		tracing.SetTracerProvider(provider)
+		report.enable("tracing", "Exporting traces to %s (sample ratio %g)", endpoint, cfg.Tracing.SampleRatio)
+	}
+
+	// host.modules_file declares site-specific host modules, loaded from Go
+	// plugins or implemented by RPC bridges, that every plugin may import
+	if path := cfg.Host.ModulesFile; path != "" {
+		modules, err := loadHostModules(path, server.bridgeCredentials)
+		if err != nil {
+			fmt.Printf("Invalid HOST_MODULES_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		server.poolOptions.HostModules = append(server.poolOptions.HostModules, modules...)
+		report.enable("host_modules", "Loaded %d host modules from %s", len(modules), path)
+	}
+
+	// host.sql_file declares the databases and prepared statements plugins
+	// may use through the sql host module, and which plugin may run which
+	// statement
+	if path := cfg.Host.SQLFile; path != "" {
+		module, dbs, err := loadSQLModule(path, os.Getenv)
+		if err != nil {
+			fmt.Printf("Invalid SQL_CONFIG_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		for _, db := range dbs {
+			defer db.Close()
+		}
+		server.poolOptions.HostModules = append(server.poolOptions.HostModules, module)
+		report.enable("sql", "Loaded %d databases from %s", len(dbs), path)
+	}
+
+	// Plugins memoize with cache_get() and cache_set() in memory, or in
+	// Redis if cache.redis_url is set
+	cache, cacheCloser, err := newCache(cfg.Cache)
+	if err != nil {
+		fmt.Printf("Invalid cache configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if cacheCloser != nil {
+		defer cacheCloser.Close()
+		report.enable("redis_cache", "Using Redis plugin cache")
+	}
+	server.cache = cache
+
+	// blobs.bucket gives plugins blob_get() and blob_put() on a bucket,
+	// limited to the key prefixes their manifests declare
+	blobs, err := newBlobs(cfg)
+	if err != nil {
+		fmt.Printf("Invalid blob storage configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if blobs != nil {
+		server.blobs = blobs
+		report.enable("blobs", "Using blob storage: s3://%s/%s", cfg.Blobs.Bucket, cfg.Blobs.Prefix)
+	}
+
+	// host.publish_file declares the Kafka or NATS broker plugins publish
+	// events to with publish(), and the topics each plugin may use
+	if path := cfg.Host.PublishFile; path != "" {
+		topics, publisher, err := loadTopics(path, os.Getenv)
+		if err != nil {
+			fmt.Printf("Invalid PUBLISH_CONFIG_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		defer publisher.Close()
+		server.topics = topics
+		report.enable("publish", "Publishing plugin messages as configured in %s", path)
+	}
+	server.hostModule = server.newHostModule()
+
+	// Parsed modules are shared by every instance of the same plugin build
+	// unless plugins.module_cache is off
+	if cfg.Plugins.ModuleCache {
+		server.poolOptions.Modules = runtime.NewModuleCache()
+	}
+
+	// plugins.aot_cache_dir enables ahead-of-time compilation; compiled
+	// artifacts are kept there across restarts
+	if dir := cfg.Plugins.AOTCacheDir; dir != "" {
+		cache, err := runtime.NewCompilerCache(dir)
+		if err != nil {
+			fmt.Printf("Invalid AOT_CACHE_DIR: %v\n", err)
+			os.Exit(1)
+		}
+		server.poolOptions.Compiler = cache
+		report.enable("aot", "Using AOT compiler cache: %s", dir)
+	}
+
+	// plugins.trusted_keys_file holds PEM Ed25519 public keys, one of which
+	// must have signed every plugin (<name>.wasm.sig)
+	if path := cfg.Plugins.TrustedKeys; path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			server.poolOptions.TrustedKeys, err = runtime.ParseTrustedKeys(data)
+		}
+		if err != nil {
+			fmt.Printf("Invalid TRUSTED_KEYS_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		report.enable("signatures", "Requiring plugin signatures by %d trusted key(s) from %s", len(server.poolOptions.TrustedKeys), path)
+	}
+
+	// plugins.config_dir holds config overlays merged onto the config block
+	// of each plugin's manifest; plugins.environment (e.g. "prod") selects
+	// the environment-specific ones
+	if dir := cfg.Plugins.ConfigDir; dir != "" {
+		server.configDir = dir
+		server.environment = cfg.Plugins.Environment
+		report.enable("config_overlays", "Using plugin config overlays: %s (environment %q)", dir, server.environment)
+	}
+
+	server.execTimeout = cfg.Execution.Timeout
+	server.maxOutput = cfg.Execution.MaxOutputBytes
+
+	// Limits the engine cannot enforce are logged and reported as
+	// degraded at GET /version; the server still starts
+	server.checkEngine()
+	report.Engine = server.engine
+	report.Limits = server.features()
+
+	// Executions beyond the concurrency limits are rejected with 429
+	server.limiter = newLimiter(cfg.Execution.MaxConcurrent, cfg.Execution.MaxConcurrentPerPlugin)
+	if cfg.Execution.MaxConcurrent > 0 || cfg.Execution.MaxConcurrentPerPlugin > 0 {
+		report.enable("concurrency_limits", "Limiting concurrent executions to %d in total and %d per plugin (0 is unlimited)",
+			cfg.Execution.MaxConcurrent, cfg.Execution.MaxConcurrentPerPlugin)
+	}
+
+	// auth.file configures the API keys and JWT issuer clients
+	// authenticate with, and what each may do
+	if path := cfg.Auth.File; path != "" {
+		server.auth, err = loadAuth(path, os.Getenv)
+		if err != nil {
+			fmt.Printf("Invalid AUTH_CONFIG_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		report.enable("auth", "Authenticating API clients as configured in %s", path)
+	}
+
+	// auth.delegation.key_file signs a token for each bridge call and
+	// effect of a plugin run by an authenticated client, so that the
+	// systems it reaches see that client rather than a shared credential
+	if path := cfg.Auth.Delegation.KeyFile; path != "" {
+		if server.auth == nil {
+			fmt.Println("Invalid DELEGATION_KEY_FILE: token delegation needs AUTH_CONFIG_FILE")
+			os.Exit(1)
+		}
+		server.delegator, err = loadDelegator(path, cfg.Auth.Delegation.Issuer, cfg.Auth.Delegation.TTL)
+		if err != nil {
+			fmt.Printf("Invalid DELEGATION_KEY_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		report.enable("delegation", "Signing delegated tokens as %s with key %s", cfg.Auth.Delegation.Issuer, server.delegator.keyID)
+	}
+
+	// locality.cache_url routes /run requests to the replica on the node
+	// whose Fluid cache already holds the plugin and its data
+	if cfg.Locality.CacheURL != "" {
+		if storeDir == "" {
+			fmt.Println("Invalid LOCALITY_CACHE_URL: locality routing needs the fluid or local store")
+			os.Exit(1)
+		}
+		if cfg.Locality.Node == "" {
+			fmt.Println("Invalid LOCALITY_NODE: the node of this replica is required for locality routing")
+			os.Exit(1)
+		}
+		locator, err := fluid.NewAlluxioCacheLocator(fluid.AlluxioConfig{URL: cfg.Locality.CacheURL, Root: cfg.Locality.CacheRoot})
+		if err != nil {
+			fmt.Printf("Invalid LOCALITY_CACHE_URL: %v\n", err)
+			os.Exit(1)
+		}
+		peers := cfg.Locality.PeerURLs()
+		server.locality = newLocalityRouter(cfg.Locality.Node, peers, storeDir, store, locator, cfg.Locality.TTL, server.logger)
+		report.enable("locality", "Routing /run to the replica caching the plugin (node %s, %d peers)", cfg.Locality.Node, len(peers))
+	}
+
+	// cache_stats.url exports the hit ratio and cached bytes of the Fluid
+	// runtime caching the plugin dataset next to the server's own metrics
+	if endpoint := cfg.CacheStats.URL; endpoint != "" {
+		reader, err := fluid.NewCacheStatsReader(cfg.CacheStats.Runtime, endpoint, nil)
+		if err != nil {
+			fmt.Printf("Invalid CACHE_STATS_URL: %v\n", err)
+			os.Exit(1)
+		}
+		server.cacheStats = newCacheStatsCollector(reader, cfg.CacheStats.Runtime, cfg.CacheStats.Dataset, cfg.CacheStats.Interval, server.logger)
+		go server.cacheStats.run(context.Background())
+		report.enable("cache_stats", "Exporting %s cache metrics of dataset %s from %s", cfg.CacheStats.Runtime, cfg.CacheStats.Dataset, endpoint)
+	}
+
+	// verify.interval re-verifies every plugin build in the store, so a
+	// corrupted object or an engine a plugin no longer loads on is found
+	// before a request runs into it
+	if interval := cfg.Verify.Interval; interval > 0 {
+		server.verifier = newStoreVerifier(server.store, server.verifyLoad, interval, server.logger)
+		go server.verifier.run(context.Background())
+		report.enable("verify", "Verifying every plugin build every %s", interval)
+	}
+
+	// shadow.engine repeats a sample of the executions of shadow.plugins
+	// on a second engine, so it can be trusted before it runs them
+	if engine := cfg.Shadow.Engine; engine != "" {
+		server.shadow, err = server.newShadowerFromConfig(engine, cfg.Shadow.Ratio, cfg.Shadow.Plugins, cfg.Shadow.MaxConcurrent)
+		if err != nil {
+			fmt.Printf("Invalid shadow engine: %v\n", err)
+			os.Exit(1)
+		}
+		report.enable("shadow", "Shadowing %g of the executions of %s on %s", cfg.Shadow.Ratio, strings.Join(cfg.Shadow.Plugins, ", "), engine)
+	}
+
+	// rate_limits.file limits how often each client, identified by API key
+	// or IP address, may run each plugin. Behind rate_limits.trusted_hops
+	// reverse proxies, the IP address is the one the first of them saw.
+	if path := cfg.RateLimits.File; path != "" {
+		hops := 0
+		if cfg.RateLimits.TrustProxy {
+			hops = cfg.RateLimits.TrustedHops
+		}
+		server.rateLimits, err = loadRateLimits(path, os.Getenv, hops)
+		if err != nil {
+			fmt.Printf("Invalid RATE_LIMITS_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		report.enable("rate_limits", "Rate limiting /run as configured in %s", path)
+	}
+
+	// tenants.file limits the rate, concurrency, and memory of the
+	// requests of each tenant, across plugins and clients
+	if path := cfg.Tenants.File; path != "" {
+		server.tenants, err = loadTenants(path)
+		if err != nil {
+			fmt.Printf("Invalid TENANTS_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		report.enable("tenant_quotas", "Enforcing tenant quotas as configured in %s", path)
+	}
+
+	// dedup.dir keeps dedup keys on disk across restarts
+	server.dedup, err = newDeduplicator(cfg.Dedup.TTL, cfg.Dedup.Dir)
+	if err != nil {
+		fmt.Printf("Invalid DEDUP_DIR: %v\n", err)
+		os.Exit(1)
+	}
+
+	// maintenance.dir shares maintenance windows with other replicas
+	server.maintenance, err = newMaintenance(cfg.Maintenance.Dir)
+	if err != nil {
+		fmt.Printf("Invalid MAINTENANCE_DIR: %v\n", err)
+		os.Exit(1)
+	}
+
+	// eviction releases the pools of plugins no request uses, by idle
+	// time and, least recently used first, total memory and instances
+	if cfg.Eviction.Enabled() {
+		server.eviction = evictionPolicy{
+			idleTimeout:  cfg.Eviction.IdleTimeout,
+			maxMemory:    int64(cfg.Eviction.MaxMemoryMiB) << 20,
+			maxInstances: cfg.Eviction.MaxInstances,
+			interval:     cfg.Eviction.Interval,
+		}
+		go server.runEviction(context.Background())
+		report.enable("eviction", "Evicting idle plugin pools (idle timeout %s, max memory %d MiB, max instances %d)",
+			cfg.Eviction.IdleTimeout, cfg.Eviction.MaxMemoryMiB, cfg.Eviction.MaxInstances)
+	}
+
+	// admin.token enables plugin uploads (POST /plugins) and deletion
+	// (DELETE /plugins/{name}) for requests carrying it as a bearer token
+	server.adminToken = cfg.Admin.Token
+
+	// outbox.http_hosts and outbox.message_url enable plugin effects: HTTP
+	// calls to those hosts and messages published through that bridge.
+	// outbox.dir keeps undelivered effects on disk across restarts
+	if cfg.Outbox.Enabled() {
+		server.outbox, err = newOutboxDispatcher(cfg.Outbox.HTTPHosts, cfg.Outbox.MessageURL, cfg.Outbox.Dir, server.logger)
+		if err != nil {
+			fmt.Printf("Invalid outbox configuration: %v\n", err)
+			os.Exit(1)
+		}
+		server.outbox.delegator = server.delegator
+		go server.outbox.run(context.Background())
+		report.enable("outbox", "Delivering plugin effects to hosts %q, messages via %q", cfg.Outbox.HTTPHosts, cfg.Outbox.MessageURL)
+	}
+
+	// The plugin directory is watched so that builds replaced or deleted
+	// there are evicted as soon as they change, and rescanned every
+	// store.watch_interval regardless, e.g. for changes other nodes make
+	// on a Fluid mount. POST /admin/reload rescans it on demand either way.
+	var watcher *fluid.Watcher
+	if storeDir != "" {
+		watcher = fluid.NewWatcher(storeDir, cfg.Store.WatchInterval)
+	}
+	if watcher != nil && cfg.Store.Watch {
+		go watcher.Watch(context.Background(), func(change fluid.Change) {
+			// Draining the old build's calls must not hold up other changes
+			go server.reload(change)
+		})
+		report.enable("watch", "Watching %s for plugin changes", storeDir)
+	}
+
+	// Schedules declared in plugin manifests are registered as plugins are
+	// published and run from here unless schedules.enabled is off, e.g. on
+	// all but one of the replicas sharing a store
+	if cfg.Schedules.Enabled {
+		server.schedules = newScheduler(server)
+		go server.schedules.run(context.Background(), cfg.Schedules.SyncInterval)
+		report.enable("schedules", "Running plugin schedules")
+	}
+
+	// batches.dir, typically on the Fluid mount, holds the input files of
+	// bulk reprocessing runs and receives their results
+	if dir := cfg.Batches.Dir; dir != "" {
+		server.batches = newBatchRunner(server, dir, cfg.Batches.MaxConcurrency)
+		report.enable("batches", "Running batches over inputs in %s (%d lines at once)", dir, cfg.Batches.MaxConcurrency)
+	}
+
+	// POST /jobs runs requests in the background on jobs.workers workers,
+	// keeping them in jobs.dir across restarts if it is set
+	server.jobs, err = server.newJobQueue(cfg.Jobs.Timeout, jobs.Options{
+		Workers:   cfg.Jobs.Workers,
+		MaxQueued: cfg.Jobs.MaxQueued,
+		Retention: cfg.Jobs.Retention,
+		Dir:       cfg.Jobs.Dir,
+	})
+	if err != nil {
+		fmt.Printf("Invalid job configuration: %v\n", err)
+		os.Exit(1)
+	}
+	go server.jobs.Run(context.Background())
+
+	// Streams hold an instance and an execution slot while they are open
+	server.streamIdleTimeout = cfg.Streams.IdleTimeout
+	server.streamMaxMessage = cfg.Streams.MaxMessageSize
 
 	// Register the /run endpoint
-	http.HandleFunc("/run", server.handleRun)
+	http.HandleFunc("/run", server.authenticated(server.rateLimited(server.localityRouted(server.handleRun))))
+	http.HandleFunc("/run/ws", server.authenticated(server.rateLimited(server.handleStream)))
+	http.HandleFunc("/plugins", server.authenticated(server.handlePlugins))
+	http.HandleFunc("/plugins/", server.authenticated(server.handlePlugin))
+	http.HandleFunc("/jobs", server.authenticated(server.rateLimited(server.handleJobs)))
+	http.HandleFunc("/jobs/", server.authenticated(server.handleJobs))
+	http.HandleFunc("/batches", server.authenticated(server.handleBatches))
+	http.HandleFunc("/batches/", server.authenticated(server.handleBatches))
+	http.HandleFunc("/maintenance", server.authenticated(server.handleMaintenance))
+	http.HandleFunc("/maintenance/", server.authenticated(server.handleMaintenance))
+	http.HandleFunc("/catalog", server.authenticated(server.handleCatalog))
+	http.HandleFunc("/admin/", server.authenticated(newAdminAPI(server, cfg, watcher, logLevel).ServeHTTP))
+
+	// execution.debug_traces is the number of debug request traces to
+	// keep; zero rejects debug requests
+	if size := cfg.Execution.DebugTraces; size > 0 {
+		server.traces = newTraceStore(size)
+		report.enable("debug_traces", "Keeping the traces of the last %d debug requests", size)
+	}
 
-	// Start the server
-	addr := ":8080"
-	fmt.Printf("Starting WASM plugin server on %s\n", addr)
-	fmt.Println("POST /run - Execute a plugin")
-	fmt.Println("  Request:  { \"plugin\": \"hello\", \"input\": 21 }")
-	fmt.Println("  Response: { \"output\": 43 }")
+	// Register observability endpoints
+	http.Handle("/metrics", server.metrics.Handler())
+	http.HandleFunc("/version", server.handleVersion)
+	http.HandleFunc("/readyz", server.handleReadyz)
+	http.HandleFunc("/debug/memory", server.debugOnly(server.handleDebugMemory))
+	http.HandleFunc("/debug/traces", server.debugOnly(server.handleDebugTraces))
+	http.HandleFunc("/debug/traces/", server.debugOnly(server.handleDebugTraces))
+	http.HandleFunc("/debug/verify", server.debugOnly(server.handleDebugVerify))
+	http.HandleFunc("/debug/shadow", server.debugOnly(server.handleDebugShadow))
+	http.HandleFunc("/debug/startup", server.debugOnly(server.handleDebugStartup))
+	http.HandleFunc("/.well-known/jwks.json", server.handleJWKS)
+	report.Endpoints = []string{
+		"POST /run",
+		"GET /run/ws",
+		"POST /jobs",
+		"GET /jobs/{id}",
+		"GET /plugins",
+		"POST /plugins",
+		"DELETE /plugins/{name}",
+		"PUT /plugins/{name}/logging",
+		"PUT /plugins/{name}/schedules/{schedule}",
+		"POST /batches",
+		"POST /maintenance",
+		"GET /catalog",
+		"POST /catalog",
+		"POST /admin/reload",
+		"POST /admin/evict",
+		"GET /admin/pools",
+		"GET /admin/config",
+		"GET /admin/log-level",
+		"PUT /admin/log-level",
+		"GET /metrics",
+		"GET /version",
+		"GET /readyz",
+		"GET /debug/memory",
+		"GET /debug/traces/{request_id}",
+		"GET /debug/verify",
+		"GET /debug/shadow",
+		"GET /debug/startup",
+		"GET /.well-known/jwks.json",
+	}
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	// ui.enabled serves the web playground, which demo mode always does
+	if cfg.UI.Enabled || cfg.Demo {
+		http.Handle("/ui/", uiHandler())
+		report.Endpoints = append(report.Endpoints, "GET /ui/")
+		report.enable("ui", "Serving the web playground at /ui/")
+	}
+
+	// With a certificate, both APIs serve TLS only
+	var grpcOptions []grpc.ServerOption
+	if cfg.TLS.Enabled() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			fmt.Printf("Invalid TLS configuration: %v\n", err)
+			os.Exit(1)
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(creds))
+		report.enable("tls", "Serving TLS with certificate %s", cfg.TLS.CertFile)
+	}
+
+	// plugins.preload is warmed up before the server listens, so that the
+	// first requests find instances ready
+	if len(cfg.Plugins.Preload) > 0 {
+		report.Warmup = server.warmup(cfg.Plugins.Preload)
+		for _, result := range report.Warmup {
+			if result.Error != "" {
+				server.logger.Warn("failed to warm up plugin",
+					slog.String("plugin", result.Plugin),
+					slog.String("error", result.Error))
+			}
+		}
+		report.enable("preload", "Warming up %d plugin(s) before serving", len(cfg.Plugins.Preload))
+	}
+
+	// log.requests logs every HTTP request; all of them get an ID either way
+	handler := server.accessLogged(http.DefaultServeMux, cfg.Log.Requests)
+	if cfg.Log.Requests {
+		report.enable("request_log", "Logging every HTTP request")
+	}
+
+	// Both listeners are bound before the report is logged, so that it
+	// holds the actual addresses and a logged report means a server that
+	// accepts connections. The gRPC API shares the server's store and
+	// pools on its own port.
+	httpListener, err := net.Listen("tcp", cfg.Listen.HTTP)
+	if err != nil {
+		fmt.Printf("Server error: %v\n", err)
+		os.Exit(1)
+	}
+	grpcListener, err := net.Listen("tcp", cfg.Listen.GRPC)
+	if err != nil {
+		fmt.Printf("gRPC server error: %v\n", err)
+		os.Exit(1)
+	}
+	report.Listen = ListenReport{
+		HTTP: httpListener.Addr().String(),
+		GRPC: grpcListener.Addr().String(),
+		TLS:  cfg.TLS.Enabled(),
+	}
+	report.Started = time.Now()
+	server.startup = report
+	server.logger.Info("WASM plugin server started", slog.Any("startup", report))
+	if cfg.Demo {
+		fmt.Print(demoExamples(report.Listen.HTTP, report.Listen.TLS))
+	}
+
+	// Either server failing stops the process
+	errs := make(chan error, 2)
+	go func() {
+		errs <- newGRPCServer(server, grpcOptions...).Serve(grpcListener)
+	}()
+	go func() {
+		if cfg.TLS.Enabled() {
+			errs <- http.ServeTLS(httpListener, handler, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+			return
+		}
+		errs <- http.Serve(httpListener, handler)
+	}()
+	if err := <-errs; err != nil {
 		fmt.Printf("Server error: %v\n", err)
 	}
 }
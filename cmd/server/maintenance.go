@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// MaxMaintenanceDuration bounds a maintenance window, so that a typo does
+// not pause executions for years.
+const MaxMaintenanceDuration = 7 * 24 * time.Hour
+
+// maintenanceReloadInterval bounds how often windows are reread from the
+// directory shared with other replicas.
+const maintenanceReloadInterval = 5 * time.Second
+
+// MaintenanceWindow is a period during which executions are paused: /run
+// requests are rejected with CodeMaintenance and schedules do not fire.
+// An empty Plugin or Tenant matches every plugin or tenant.
+type MaintenanceWindow struct {
+	ID     string    `json:"id"`
+	Plugin string    `json:"plugin,omitempty"`
+	Tenant string    `json:"tenant,omitempty"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// MaintenanceRequest is the JSON request body for POST /maintenance.
+// Start defaults to now; the window ends at End or DurationSeconds after
+// Start, whichever is given.
+type MaintenanceRequest struct {
+	Plugin          string    `json:"plugin,omitempty"`
+	Tenant          string    `json:"tenant,omitempty"`
+	Start           time.Time `json:"start,omitzero"`
+	End             time.Time `json:"end,omitzero"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+}
+
+// matches reports whether the window pauses executions of plugin on behalf
+// of tenant at t.
+func (w MaintenanceWindow) matches(plugin, tenant string, t time.Time) bool {
+	return (w.Plugin == "" || w.Plugin == plugin) &&
+		(w.Tenant == "" || w.Tenant == tenant) &&
+		!t.Before(w.Start) && t.Before(w.End)
+}
+
+// maintenance holds the maintenance windows that have not ended. Windows
+// are kept in memory, or as one JSON file per window in dir so that they
+// survive restarts and apply to every replica sharing dir.
+type maintenance struct {
+	dir string // Empty keeps windows in memory only
+
+	mu         sync.Mutex
+	windows    map[string]MaintenanceWindow
+	lastReload time.Time
+	now        func() time.Time
+}
+
+// newMaintenance creates a set of maintenance windows kept in dir if it
+// is not empty, loading the windows already there. The directory is
+// created if needed.
+func newMaintenance(dir string) (*maintenance, error) {
+	m := &maintenance{dir: dir, windows: make(map[string]MaintenanceWindow), now: time.Now}
+	if dir == "" {
+		return m, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create maintenance directory: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reload replaces the windows with those in dir, at most once per
+// maintenanceReloadInterval. m.mu must be held.
+func (m *maintenance) reload() error {
+	now := m.now()
+	if m.dir == "" || (!m.lastReload.IsZero() && now.Sub(m.lastReload) < maintenanceReloadInterval) {
+		return nil
+	}
+	m.lastReload = now
+
+	paths, err := filepath.Glob(filepath.Join(m.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	windows := make(map[string]MaintenanceWindow, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Deleted by another replica meanwhile
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read maintenance window: %w", err)
+		}
+		var window MaintenanceWindow
+		if err := json.Unmarshal(data, &window); err != nil {
+			return fmt.Errorf("invalid maintenance window %s: %w", filepath.Base(path), err)
+		}
+		windows[window.ID] = window
+	}
+	m.windows = windows
+	return nil
+}
+
+// prune forgets the windows that have ended. m.mu must be held.
+func (m *maintenance) prune() {
+	now := m.now()
+	for id, window := range m.windows {
+		if !now.Before(window.End) {
+			delete(m.windows, id)
+			if m.dir != "" {
+				os.Remove(filepath.Join(m.dir, id+".json"))
+			}
+		}
+	}
+}
+
+// activeUntil returns when the maintenance of plugin on behalf of tenant
+// active at t ends, or the zero time if executions are not paused.
+// Overlapping and adjoining windows count as one.
+func (m *maintenance) activeUntil(plugin, tenant string, t time.Time) (time.Time, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.reload(); err != nil {
+		return time.Time{}, "", err
+	}
+
+	var until time.Time
+	var reason string
+	for extended := true; extended; {
+		extended = false
+		at := t
+		if !until.IsZero() {
+			at = until
+		}
+		for _, window := range m.windows {
+			if window.matches(plugin, tenant, at) && window.End.After(until) {
+				if until.IsZero() {
+					reason = window.Reason
+				}
+				until = window.End
+				extended = true
+			}
+		}
+	}
+	return until, reason, nil
+}
+
+// check returns a CodeMaintenance error if executions of plugin on behalf
+// of tenant are paused, telling clients to retry when the maintenance
+// ends.
+func (m *maintenance) check(plugin, tenant string) error {
+	now := m.now()
+	until, reason, err := m.activeUntil(plugin, tenant, now)
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInternal, err)
+	}
+	if until.IsZero() {
+		return nil
+	}
+	msg := fmt.Sprintf("plugin %s is paused for maintenance until %s", plugin, until.UTC().Format(time.RFC3339))
+	if reason != "" {
+		msg += ": " + reason
+	}
+	return apierror.WrapRetry(apierror.CodeMaintenance, errors.New(msg), until.Sub(now))
+}
+
+// list returns the windows that have not ended, sorted by start.
+func (m *maintenance) list() ([]MaintenanceWindow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	m.prune()
+
+	windows := make([]MaintenanceWindow, 0, len(m.windows))
+	for _, window := range m.windows {
+		windows = append(windows, window)
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		if !windows[i].Start.Equal(windows[j].Start) {
+			return windows[i].Start.Before(windows[j].Start)
+		}
+		return windows[i].ID < windows[j].ID
+	})
+	return windows, nil
+}
+
+// add validates a request and stores the window it defines.
+func (m *maintenance) add(req MaintenanceRequest) (MaintenanceWindow, error) {
+	now := m.now()
+	window := MaintenanceWindow{
+		ID:     requestIDOf(""),
+		Tenant: req.Tenant,
+		Start:  req.Start,
+		End:    req.End,
+		Reason: req.Reason,
+	}
+	if req.Plugin != "" {
+		if err := checkPluginName(req.Plugin); err != nil {
+			return MaintenanceWindow{}, err
+		}
+		// Maintenance applies to every version of the plugin
+		window.Plugin, _ = fluid.ParseReference(req.Plugin)
+	}
+	if req.Tenant != "" && !validID(req.Tenant) {
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			fmt.Errorf("tenant must be printable ASCII of at most %d bytes", maxRequestIDLength))
+	}
+	if window.Start.IsZero() {
+		window.Start = now
+	}
+	switch {
+	case req.DurationSeconds < 0:
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("duration_seconds must not be negative"))
+	case req.DurationSeconds > 0 && !req.End.IsZero():
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("end and duration_seconds are mutually exclusive"))
+	case req.DurationSeconds > 0:
+		window.End = window.Start.Add(time.Duration(req.DurationSeconds) * time.Second)
+	case req.End.IsZero():
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("end or duration_seconds is required"))
+	}
+	switch {
+	case !window.End.After(window.Start):
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("end must be after start"))
+	case !window.End.After(now):
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("end must be in the future"))
+	case window.End.Sub(window.Start) > MaxMaintenanceDuration:
+		return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			fmt.Errorf("a maintenance window must not exceed %s", MaxMaintenanceDuration))
+	}
+	window.Start = window.Start.UTC()
+	window.End = window.End.UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dir != "" {
+		if err := writeRecord(m.dir, window.ID, window); err != nil {
+			return MaintenanceWindow{}, apierror.Wrap(apierror.CodeInternal,
+				fmt.Errorf("failed to write maintenance window: %w", err))
+		}
+	}
+	m.windows[window.ID] = window
+	return window, nil
+}
+
+// remove ends a window early, reporting whether it existed.
+func (m *maintenance) remove(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.reload(); err != nil {
+		return false, err
+	}
+	if _, ok := m.windows[id]; !ok {
+		return false, nil
+	}
+	if m.dir != "" {
+		err := os.Remove(filepath.Join(m.dir, id+".json"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("failed to remove maintenance window: %w", err)
+		}
+	}
+	delete(m.windows, id)
+	return true, nil
+}
+
+// handleMaintenance handles GET and POST /maintenance and
+// DELETE /maintenance/{id}, admin endpoints defining the windows during
+// which executions are paused, e.g. while a backend plugins depend on is
+// maintained.
+//
+// While a window is active, /run requests for the plugins and tenants it
+// matches are rejected with 503 and a Retry-After header pointing at its
+// end, and their schedules do not fire. Runs already started are not
+// interrupted.
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/maintenance"), "/")
+	allowed := (id == "" && (r.Method == http.MethodGet || r.Method == http.MethodPost)) ||
+		(id != "" && r.Method == http.MethodDelete)
+	if !allowed {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		windows, err := s.maintenance.list()
+		if err != nil {
+			writeError(w, r, apierror.CodeInternal, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, windows)
+
+	case http.MethodPost:
+		var req MaintenanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		window, err := s.maintenance.add(req)
+		if err != nil {
+			writeExecutionError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, window)
+
+	case http.MethodDelete:
+		ok, err := s.maintenance.remove(id)
+		if err != nil {
+			writeError(w, r, apierror.CodeInternal, err.Error())
+			return
+		}
+		if !ok {
+			writeError(w, r, apierror.CodeMaintenanceWindowNotFound, fmt.Sprintf("no maintenance window %s", id))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// retryAfterSeconds converts a retry delay to the whole seconds of a
+// Retry-After header, rounding up so that clients do not retry early.
+func retryAfterSeconds(after time.Duration) int {
+	return max(1, int(math.Ceil(after.Seconds())))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Maintenance", func() {
+	var (
+		srv *Server
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		srv = NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.adminToken = "secret"
+		now = time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+		srv.maintenance.now = func() time.Time { return now }
+	})
+
+	problemCode := func(rec *httptest.ResponseRecorder) apierror.Code {
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+		return problem.Code
+	}
+
+	// =========================================================================
+	// TEST: Window matching
+	// Why: A window must pause exactly the plugins and tenants it names, and
+	//      only while it lasts.
+	// =========================================================================
+	It("should pause the matching plugins and tenants while a window lasts", func() {
+		_, err := srv.maintenance.add(MaintenanceRequest{Plugin: "billing@1.2.0", Tenant: "payments",
+			Start: now.Add(time.Hour), DurationSeconds: 1800})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(srv.maintenance.check("billing", "payments")).To(Succeed())
+		now = now.Add(time.Hour)
+		err = srv.maintenance.check("billing", "payments")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeMaintenance))
+		Expect(apierror.RetryAfterOf(err)).To(Equal(30 * time.Minute))
+
+		Expect(srv.maintenance.check("billing", "")).To(Succeed())
+		Expect(srv.maintenance.check("hello", "payments")).To(Succeed())
+		now = now.Add(30 * time.Minute)
+		Expect(srv.maintenance.check("billing", "payments")).To(Succeed())
+	})
+
+	It("should report the end of adjoining windows", func() {
+		_, err := srv.maintenance.add(MaintenanceRequest{Reason: "database upgrade", DurationSeconds: 600})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = srv.maintenance.add(MaintenanceRequest{Plugin: "billing", Start: now.Add(10 * time.Minute), DurationSeconds: 600})
+		Expect(err).NotTo(HaveOccurred())
+
+		until, reason, err := srv.maintenance.activeUntil("billing", "", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(until).To(Equal(now.Add(20 * time.Minute)))
+		Expect(reason).To(Equal("database upgrade"))
+
+		until, _, err = srv.maintenance.activeUntil("hello", "", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(until).To(Equal(now.Add(10 * time.Minute)))
+	})
+
+	DescribeTable("should reject invalid windows",
+		func(req MaintenanceRequest) {
+			_, err := srv.maintenance.add(req)
+			Expect(apierror.CodeOf(err)).To(BeElementOf(apierror.CodeInvalidRequest, apierror.CodeInvalidPluginName))
+		},
+		Entry("without an end", MaintenanceRequest{}),
+		Entry("with both end and duration", MaintenanceRequest{End: time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC), DurationSeconds: 60}),
+		Entry("ending before its start", MaintenanceRequest{Start: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), End: time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC)}),
+		Entry("in the past", MaintenanceRequest{Start: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 10, 15, 1, 0, 0, 0, time.UTC)}),
+		Entry("too long", MaintenanceRequest{DurationSeconds: int((8 * 24 * time.Hour).Seconds())}),
+		Entry("with an invalid plugin", MaintenanceRequest{Plugin: "../etc", DurationSeconds: 60}),
+		Entry("with an invalid tenant", MaintenanceRequest{Tenant: "team a", DurationSeconds: 60}),
+	)
+
+	// =========================================================================
+	// TEST: Persistence
+	// Why: Replicas sharing the directory must honor each other's windows,
+	//      and a restart must not end a maintenance early.
+	// =========================================================================
+	It("should share windows through the directory", func() {
+		dir := GinkgoT().TempDir()
+		first, err := newMaintenance(dir)
+		Expect(err).NotTo(HaveOccurred())
+		window, err := first.add(MaintenanceRequest{Plugin: "billing", DurationSeconds: 3600})
+		Expect(err).NotTo(HaveOccurred())
+
+		second, err := newMaintenance(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.list()).To(Equal([]MaintenanceWindow{window}))
+
+		Expect(second.remove(window.ID)).To(BeTrue())
+		first.lastReload = time.Time{}
+		Expect(first.list()).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Rejected runs
+	// Why: Clients must learn that the failure is temporary and when to
+	//      retry, instead of treating it as a plugin error.
+	// =========================================================================
+	It("should reject /run requests with 503 and Retry-After", func() {
+		_, err := srv.maintenance.add(MaintenanceRequest{Plugin: "billing", DurationSeconds: 90})
+		Expect(err).NotTo(HaveOccurred())
+
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "billing@2.0.0", "input": 1}`))
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rec.Header().Get("Retry-After")).To(Equal("90"))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeMaintenance))
+
+		req = httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello", "input": 1}`))
+		rec = httptest.NewRecorder()
+		srv.handleRun(rec, req)
+		Expect(rec.Header().Get("Retry-After")).To(BeEmpty())
+		Expect(problemCode(rec)).To(Equal(apierror.CodePluginNotFound))
+	})
+
+	Describe("/maintenance", func() {
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			srv.handleMaintenance(rec, req)
+			return rec
+		}
+
+		It("should create, list, and end windows", func() {
+			rec := send(http.MethodPost, "/maintenance", `{"plugin": "billing", "end": "2026-10-16T12:00:00Z", "reason": "failover"}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			var window MaintenanceWindow
+			Expect(json.Unmarshal(rec.Body.Bytes(), &window)).To(Succeed())
+			Expect(window).To(Equal(MaintenanceWindow{ID: window.ID, Plugin: "billing", Start: now,
+				End: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), Reason: "failover"}))
+
+			rec = send(http.MethodGet, "/maintenance", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var windows []MaintenanceWindow
+			Expect(json.Unmarshal(rec.Body.Bytes(), &windows)).To(Succeed())
+			Expect(windows).To(Equal([]MaintenanceWindow{window}))
+
+			Expect(send(http.MethodDelete, "/maintenance/"+window.ID, "").Code).To(Equal(http.StatusNoContent))
+			rec = send(http.MethodDelete, "/maintenance/"+window.ID, "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(problemCode(rec)).To(Equal(apierror.CodeMaintenanceWindowNotFound))
+		})
+
+		It("should reject invalid requests", func() {
+			rec := send(http.MethodPost, "/maintenance", `{"duration_seconds": -1}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(send(http.MethodPut, "/maintenance", "").Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("should require the admin token", func() {
+			req := httptest.NewRequest(http.MethodGet, "/maintenance", nil)
+			rec := httptest.NewRecorder()
+			srv.handleMaintenance(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// Effect delivery retries back off exponentially between these bounds.
+const (
+	outboxMinBackoff = time.Second
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+// outboxDeliveryTimeout bounds a single delivery attempt.
+const outboxDeliveryTimeout = 10 * time.Second
+
+// outboxRecord is a committed effect awaiting delivery.
+type outboxRecord struct {
+	ID          string         `json:"id"` // Sent as the Idempotency-Key of every attempt
+	RequestID   string         `json:"request_id"`
+	Effect      runtime.Effect `json:"effect"`
+	Delegation  *delegation    `json:"delegation,omitempty"` // Of the request, if it had a principal
+	Attempts    int            `json:"attempts"`
+	NextAttempt time.Time      `json:"next_attempt"`
+}
+
+// outboxStats counts delivery outcomes since startup.
+type outboxStats struct {
+	Delivered uint64
+	Retries   uint64
+	Dropped   uint64
+}
+
+// outboxDispatcher delivers the effects plugins enqueue through the outbox
+// host API (see runtime.NewOutboxModule). Effects reach it only when the
+// call that enqueued them succeeded, and are then delivered at least once:
+//   - EffectHTTP: POST the payload to the target URL, whose host must be
+//     in hosts
+//   - EffectMessage: POST the payload to messageURL, the message bridge,
+//     with the topic in the X-Outbox-Topic header
+//
+// Every attempt carries the record ID in the Idempotency-Key header, so
+// receivers can drop the duplicates a retry may cause. With a delegator,
+// the attempts of an effect whose request had a principal also carry a
+// token minted for that principal. Network errors and
+// 408, 429, and 5xx responses are retried with exponential backoff until
+// they succeed; any other response drops the effect.
+//
+// Records are kept in memory, or as one JSON file each in dir so that
+// pending effects survive restarts.
+type outboxDispatcher struct {
+	dir        string // Empty keeps records in memory
+	messageURL string
+	hosts      map[string]bool
+	delegator  *delegator // Nil sends effects without credentials
+	client     *http.Client
+	logger     *slog.Logger
+	minBackoff time.Duration
+
+	mu      sync.Mutex
+	pending []*outboxRecord
+	stats   outboxStats
+	wake    chan struct{}
+	now     func() time.Time
+}
+
+// newOutboxDispatcher creates a dispatcher allowing HTTP effects to hosts
+// and message effects if messageURL is set. Records left pending in dir,
+// if it is not empty, are loaded for delivery; the directory is created if
+// needed.
+func newOutboxDispatcher(hosts []string, messageURL, dir string, logger *slog.Logger) (*outboxDispatcher, error) {
+	if messageURL != "" {
+		u, err := url.Parse(messageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("message URL must be an http or https URL, got %q", messageURL)
+		}
+	}
+	d := &outboxDispatcher{
+		dir:        dir,
+		messageURL: messageURL,
+		hosts:      make(map[string]bool),
+		client:     &http.Client{Timeout: outboxDeliveryTimeout},
+		logger:     logger,
+		minBackoff: outboxMinBackoff,
+		wake:       make(chan struct{}, 1),
+		now:        time.Now,
+	}
+	for _, host := range hosts {
+		if host = strings.TrimSpace(host); host != "" {
+			d.hosts[strings.ToLower(host)] = true
+		}
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+		if err := d.load(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// check vets an effect when a plugin enqueues it, so effects that could
+// never be delivered fail the enqueue() call instead of the delivery.
+func (d *outboxDispatcher) check(effect runtime.Effect) error {
+	switch effect.Kind {
+	case runtime.EffectHTTP:
+		u, err := url.Parse(effect.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("effect target must be an http or https URL, got %q", effect.Target)
+		}
+		if !d.hosts[strings.ToLower(u.Hostname())] {
+			return fmt.Errorf("host %q is not allowed", u.Hostname())
+		}
+	case runtime.EffectMessage:
+		if d.messageURL == "" {
+			return errors.New("no message bridge is configured")
+		}
+		if !validID(effect.Target) {
+			return fmt.Errorf("invalid topic %q", effect.Target)
+		}
+	default:
+		return fmt.Errorf("unknown effect kind %s", effect.Kind)
+	}
+	return nil
+}
+
+// commit queues the effects of a successful request, made under grant if
+// it had a principal, for delivery. Either all of them are stored or, on
+// error, none.
+func (d *outboxDispatcher) commit(requestID string, grant *delegation, effects []runtime.Effect) error {
+	if len(effects) == 0 {
+		return nil
+	}
+	now := d.now()
+	records := make([]*outboxRecord, 0, len(effects))
+	for _, effect := range effects {
+		var id [16]byte
+		_, _ = rand.Read(id[:])
+		records = append(records, &outboxRecord{
+			ID:          hex.EncodeToString(id[:]),
+			RequestID:   requestID,
+			Effect:      effect,
+			Delegation:  grant,
+			NextAttempt: now,
+		})
+	}
+
+	if d.dir != "" {
+		for i, record := range records {
+			if err := writeRecord(d.dir, record.ID, record); err != nil {
+				for _, written := range records[:i] {
+					os.Remove(d.recordPath(written))
+				}
+				return fmt.Errorf("failed to write outbox record: %w", err)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.pending = append(d.pending, records...)
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run delivers pending effects until ctx is done.
+func (d *outboxDispatcher) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+
+		next := d.deliverDue(ctx)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if !next.IsZero() {
+			timer.Reset(next.Sub(d.now()))
+		}
+	}
+}
+
+// deliverDue attempts every record whose next attempt is due and returns
+// the time of the earliest remaining one, or zero if none is left.
+func (d *outboxDispatcher) deliverDue(ctx context.Context) time.Time {
+	now := d.now()
+	d.mu.Lock()
+	var due []*outboxRecord
+	for _, record := range d.pending {
+		if !record.NextAttempt.After(now) {
+			due = append(due, record)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(map[*outboxRecord]bool)
+	for _, record := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		retry, err := d.deliver(ctx, record)
+		switch {
+		case err == nil:
+			done[record] = true
+			d.count(&d.stats.Delivered)
+		case retry:
+			record.Attempts++
+			record.NextAttempt = d.now().Add(d.backoff(record.Attempts))
+			d.count(&d.stats.Retries)
+			if d.dir != "" {
+				// A stale file only resets the backoff after a restart
+				_ = writeRecord(d.dir, record.ID, record)
+			}
+		default:
+			done[record] = true
+			d.count(&d.stats.Dropped)
+			d.logger.LogAttrs(ctx, slog.LevelError, "dropped plugin effect",
+				slog.String("plugin", record.Effect.Plugin),
+				slog.String("request_id", record.RequestID),
+				slog.String("kind", record.Effect.Kind.String()),
+				slog.String("target", record.Effect.Target),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var next time.Time
+	remaining := d.pending[:0]
+	for _, record := range d.pending {
+		if done[record] {
+			if d.dir != "" {
+				os.Remove(d.recordPath(record))
+			}
+			continue
+		}
+		remaining = append(remaining, record)
+		if next.IsZero() || record.NextAttempt.Before(next) {
+			next = record.NextAttempt
+		}
+	}
+	d.pending = remaining
+	return next
+}
+
+// deliver makes one delivery attempt. On failure it reports whether the
+// attempt should be retried.
+func (d *outboxDispatcher) deliver(ctx context.Context, record *outboxRecord) (retry bool, err error) {
+	target := record.Effect.Target
+	if record.Effect.Kind == runtime.EffectMessage {
+		if d.messageURL == "" {
+			return false, errors.New("no message bridge is configured")
+		}
+		target = d.messageURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(record.Effect.Payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Idempotency-Key", record.ID)
+	req.Header.Set("X-Plugin", record.Effect.Plugin)
+	req.Header.Set(RequestIDHeader, record.RequestID)
+	if record.Effect.Kind == runtime.EffectMessage {
+		req.Header.Set("X-Outbox-Topic", record.Effect.Target)
+	}
+	if d.delegator != nil && record.Delegation != nil {
+		// Minted per attempt, so that a retry does not outlive its token
+		token, err := d.delegator.mint(record.Delegation, target, "outbox:"+record.Effect.Kind.String())
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return true, fmt.Errorf("%s responded %s", target, resp.Status)
+	default:
+		return false, fmt.Errorf("%s responded %s", target, resp.Status)
+	}
+}
+
+// backoff returns the delay before the next attempt after attempts
+// failures.
+func (d *outboxDispatcher) backoff(attempts int) time.Duration {
+	delay := d.minBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxBackoff)
+}
+
+// count increments a stats counter.
+func (d *outboxDispatcher) count(counter *uint64) {
+	d.mu.Lock()
+	*counter++
+	d.mu.Unlock()
+}
+
+// snapshot returns the delivery counters and the number of pending effects.
+func (d *outboxDispatcher) snapshot() (outboxStats, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats, len(d.pending)
+}
+
+// load reads the records left pending in dir. Unreadable records are
+// logged and skipped.
+func (d *outboxDispatcher) load() error {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(d.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		var record outboxRecord
+		if err == nil {
+			err = json.Unmarshal(data, &record)
+		}
+		if err == nil && record.ID == "" {
+			err = errors.New("record has no ID")
+		}
+		if err != nil {
+			d.logger.LogAttrs(context.Background(), slog.LevelError, "skipped unreadable outbox record",
+				slog.String("path", path),
+				slog.String("error", err.Error()))
+			continue
+		}
+		d.pending = append(d.pending, &record)
+	}
+	return nil
+}
+
+// recordPath returns the file a record is stored in.
+func (d *outboxDispatcher) recordPath(record *outboxRecord) string {
+	return filepath.Join(d.dir, record.ID+".json")
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Outbox dispatcher", func() {
+	// =========================================================================
+	// TEST: At-least-once effect delivery
+	// Why: Committed effects must reach their receivers despite transient
+	//      failures and restarts, with a stable key to drop duplicates,
+	//      while effects that can never succeed must not be retried forever.
+	// =========================================================================
+	var (
+		receiver *httptest.Server
+		mu       sync.Mutex
+		received []*http.Request
+		statuses []int
+		logger   = slog.New(slog.NewTextHandler(io.Discard, nil))
+	)
+
+	BeforeEach(func() {
+		received, statuses = nil, nil
+		receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, r)
+			if len(statuses) > 0 {
+				w.WriteHeader(statuses[0])
+				statuses = statuses[1:]
+			}
+		}))
+		DeferCleanup(receiver.Close)
+	})
+
+	requests := func() []*http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*http.Request(nil), received...)
+	}
+
+	newDispatcher := func(dir string) *outboxDispatcher {
+		u, err := url.Parse(receiver.URL)
+		Expect(err).NotTo(HaveOccurred())
+		d, err := newOutboxDispatcher([]string{u.Hostname()}, receiver.URL+"/bridge", dir, logger)
+		Expect(err).NotTo(HaveOccurred())
+		return d
+	}
+
+	httpEffect := func() runtime.Effect {
+		return runtime.Effect{Kind: runtime.EffectHTTP, Target: receiver.URL + "/hook", Payload: []byte("7"), Plugin: "relay"}
+	}
+
+	It("should only accept effects it can deliver", func() {
+		d := newDispatcher("")
+
+		Expect(d.check(httpEffect())).To(Succeed())
+		Expect(d.check(runtime.Effect{Kind: runtime.EffectHTTP, Target: "http://elsewhere.example/hook"})).
+			To(MatchError(ContainSubstring("is not allowed")))
+		Expect(d.check(runtime.Effect{Kind: runtime.EffectHTTP, Target: "file:///etc/passwd"})).
+			To(MatchError(ContainSubstring("http or https URL")))
+		Expect(d.check(runtime.Effect{Kind: runtime.EffectMessage, Target: "orders.created"})).To(Succeed())
+		Expect(d.check(runtime.Effect{Kind: runtime.EffectMessage, Target: "has space"})).
+			To(MatchError(ContainSubstring("invalid topic")))
+
+		noBridge, err := newOutboxDispatcher([]string{"example.com"}, "", "", logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(noBridge.check(runtime.Effect{Kind: runtime.EffectMessage, Target: "orders.created"})).
+			To(MatchError(ContainSubstring("no message bridge")))
+	})
+
+	It("should reject an invalid message bridge URL", func() {
+		_, err := newOutboxDispatcher(nil, "not a url", "", logger)
+		Expect(err).To(MatchError(ContainSubstring("message URL")))
+	})
+
+	It("should deliver committed effects with an idempotency key", func() {
+		d := newDispatcher("")
+		message := runtime.Effect{Kind: runtime.EffectMessage, Target: "orders.created", Payload: []byte("7"), Plugin: "relay"}
+		Expect(d.commit("req-1", nil, []runtime.Effect{httpEffect(), message})).To(Succeed())
+
+		Expect(d.deliverDue(context.Background())).To(BeZero())
+		reqs := requests()
+		Expect(reqs).To(HaveLen(2))
+		Expect(reqs[0].URL.Path).To(Equal("/hook"))
+		Expect(reqs[0].Header.Get("Idempotency-Key")).NotTo(BeEmpty())
+		Expect(reqs[0].Header.Get("X-Plugin")).To(Equal("relay"))
+		Expect(reqs[0].Header.Get(RequestIDHeader)).To(Equal("req-1"))
+		Expect(reqs[1].URL.Path).To(Equal("/bridge"))
+		Expect(reqs[1].Header.Get("X-Outbox-Topic")).To(Equal("orders.created"))
+		Expect(reqs[1].Header.Get("Idempotency-Key")).NotTo(Equal(reqs[0].Header.Get("Idempotency-Key")))
+
+		stats, pending := d.snapshot()
+		Expect(stats).To(Equal(outboxStats{Delivered: 2}))
+		Expect(pending).To(BeZero())
+	})
+
+	It("should retry transient failures with the same key after a backoff", func() {
+		d := newDispatcher("")
+		clock := time.Now()
+		d.now = func() time.Time { return clock }
+		statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+		Expect(d.commit("req-1", nil, []runtime.Effect{httpEffect()})).To(Succeed())
+
+		Expect(d.deliverDue(context.Background())).To(Equal(clock.Add(time.Second)))
+		// Not due yet
+		Expect(d.deliverDue(context.Background())).To(Equal(clock.Add(time.Second)))
+		Expect(requests()).To(HaveLen(1))
+
+		clock = clock.Add(time.Second)
+		Expect(d.deliverDue(context.Background())).To(Equal(clock.Add(2 * time.Second)))
+		clock = clock.Add(2 * time.Second)
+		Expect(d.deliverDue(context.Background())).To(BeZero())
+
+		reqs := requests()
+		Expect(reqs).To(HaveLen(3))
+		Expect(reqs[2].Header.Get("Idempotency-Key")).To(Equal(reqs[0].Header.Get("Idempotency-Key")))
+		stats, pending := d.snapshot()
+		Expect(stats).To(Equal(outboxStats{Delivered: 1, Retries: 2}))
+		Expect(pending).To(BeZero())
+	})
+
+	It("should drop effects the receiver rejects", func() {
+		d := newDispatcher("")
+		statuses = []int{http.StatusBadRequest}
+		Expect(d.commit("req-1", nil, []runtime.Effect{httpEffect()})).To(Succeed())
+
+		Expect(d.deliverDue(context.Background())).To(BeZero())
+		stats, pending := d.snapshot()
+		Expect(stats).To(Equal(outboxStats{Dropped: 1}))
+		Expect(pending).To(BeZero())
+	})
+
+	It("should cap the backoff", func() {
+		d := newDispatcher("")
+		Expect(d.backoff(1)).To(Equal(time.Second))
+		Expect(d.backoff(4)).To(Equal(8 * time.Second))
+		Expect(d.backoff(100)).To(Equal(outboxMaxBackoff))
+	})
+
+	It("should keep pending effects in its directory across restarts", func() {
+		dir := filepath.Join(GinkgoT().TempDir(), "outbox")
+		statuses = []int{http.StatusInternalServerError}
+
+		first := newDispatcher(dir)
+		Expect(first.commit("req-1", nil, []runtime.Effect{httpEffect()})).To(Succeed())
+		first.deliverDue(context.Background())
+
+		second := newDispatcher(dir)
+		_, pending := second.snapshot()
+		Expect(pending).To(Equal(1))
+		second.now = func() time.Time { return time.Now().Add(time.Minute) }
+		Expect(second.deliverDue(context.Background())).To(BeZero())
+
+		reqs := requests()
+		Expect(reqs).To(HaveLen(2))
+		Expect(reqs[1].Header.Get("Idempotency-Key")).To(Equal(reqs[0].Header.Get("Idempotency-Key")))
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should deliver committed effects in the background", func() {
+		d := newDispatcher("")
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go d.run(ctx)
+
+		Expect(d.commit("req-1", nil, []runtime.Effect{httpEffect()})).To(Succeed())
+		Eventually(requests).Should(HaveLen(1))
+	})
+
+	It("should refuse effects while they are disabled", func() {
+		server := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		Expect(server.checkEffect(httpEffect())).To(MatchError(ContainSubstring("disabled")))
+
+		server.outbox = newDispatcher("")
+		Expect(server.checkEffect(httpEffect())).To(Succeed())
+	})
+})
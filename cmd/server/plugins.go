@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// maxPluginUploadBytes bounds the size of an uploaded plugin binary.
+const maxPluginUploadBytes = 64 << 20
+
+// requiredExports are the ABI functions every uploaded plugin must export.
+var requiredExports = []string{"init", "process", "cleanup"}
+
+// handlePlugins handles GET /plugins and POST /plugins
+//
+// GET returns the plugins in the store with their file size and
+// modification time, so clients can discover what they can run before
+// calling /run; with ?digest=true every entry also carries the SHA-256 of
+// its binary (see listedPlugin). POST uploads a plugin build (see
+// handleUpload).
+func (s *Server) handlePlugins(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.handleUpload(w, r)
+		return
+	default:
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	plugins, err := s.store.List()
+	if err != nil {
+		writeError(w, r, apierror.CodeInternal, fmt.Sprintf("failed to list plugins: %v", err))
+		return
+	}
+
+	plugins = runnablePlugins(plugins)
+	if r.URL.Query().Get("digest") != "true" {
+		writeJSON(w, http.StatusOK, plugins)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.withDigests(plugins))
+}
+
+// listedPlugin is a GET /plugins?digest=true entry.
+type listedPlugin struct {
+	fluid.PluginInfo
+
+	// Digest is the hex SHA-256 of the stored binary, unlike SHA256, which
+	// is only the digest declared for it. Deployment tools compare it to
+	// the build they mean to run; it is empty for builds that fail to
+	// resolve.
+	Digest string `json:"digest,omitempty"`
+}
+
+// withDigests hashes the binary of every plugin. It reads each file in
+// full, and for remote stores downloads it, so it is only done on request.
+func (s *Server) withDigests(plugins []fluid.PluginInfo) []listedPlugin {
+	listed := make([]listedPlugin, len(plugins))
+	for i, p := range plugins {
+		listed[i].PluginInfo = p
+		ref := p.Name
+		if p.Version != "" {
+			ref += "@" + p.Version
+		}
+		path, err := s.store.Resolve(ref)
+		if err != nil {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			sum := sha256.Sum256(data)
+			listed[i].Digest = hex.EncodeToString(sum[:])
+		}
+	}
+	return listed
+}
+
+// handleUpload handles POST /plugins, an admin endpoint installing a plugin
+// build into the store.
+//
+// The binary is sent either as the raw request body with the plugin name
+// in the name query parameter, or as the file field of a multipart form,
+// named by the name field or else the file name without .wasm. It must
+// export init, process, and cleanup, match the plugin's manifest and
+// declared digest if it has them, and load in the runtime. Running pools pick up the new build on
+// their next checkout.
+//
+// Uploads are disabled unless the server has an admin token and its store
+// accepts writes; requests must carry the token as a bearer token.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	writer, ok := s.adminWriter(w, r)
+	if !ok {
+		return
+	}
+
+	name, data, err := readUpload(w, r)
+	if err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+	if err := checkBuildName(name); err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+	if err := s.validateUpload(name, data); err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+
+	info, err := writer.Put(name, bytes.NewReader(data))
+	if err != nil {
+		writeError(w, r, apierror.CodeInternal, fmt.Sprintf("failed to store plugin: %v", err))
+		return
+	}
+	// The new build's manifest may declare schedules
+	s.syncSchedules()
+	writeJSON(w, http.StatusCreated, info)
+}
+
+// handlePlugin handles DELETE /plugins/{name}, an admin endpoint retiring a
+// plugin at runtime.
+//
+// The plugin is removed from the store first, so new requests for it fail
+// with plugin_not_found. Its pool is then closed and drained: the response
+// is sent once calls already running have completed, and every instance
+// and cached module of the plugin has been released. The metrics the
+// plugin recorded are dropped too.
+//
+// Like uploads, deletion requires the admin token and a writable store.
+// Requests for /plugins/{name}/logging are passed on to
+// handlePluginLogging, and those for /plugins/{name}/schedules to
+// handlePluginSchedules.
+func (s *Server) handlePlugin(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/logging") {
+		s.handlePluginLogging(w, r)
+		return
+	}
+	if isSchedulesPath(r.URL.Path) {
+		s.handlePluginSchedules(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	writer, ok := s.adminWriter(w, r)
+	if !ok {
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/plugins/")
+	if err := checkBuildName(name); err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+
+	// A plugin with an invalid manifest cannot have a pool, but can still
+	// be deleted
+	path, resolveErr := s.store.Resolve(name)
+	if err := writer.Delete(name); err != nil {
+		if errors.Is(err, fluid.ErrPluginNotFound) {
+			writeError(w, r, apierror.CodePluginNotFound, fmt.Sprintf("plugin not found: %s", name))
+			return
+		}
+		writeError(w, r, apierror.CodeInternal, fmt.Sprintf("failed to delete plugin: %v", err))
+		return
+	}
+	if resolveErr == nil {
+		s.retire(r.Context(), name, path)
+	}
+	s.syncSchedules()
+	// Metrics are kept per plugin, across its versions
+	base, _ := fluid.ParseReference(name)
+	if _, err := s.store.Resolve(base); errors.Is(err, fluid.ErrPluginNotFound) {
+		s.pluginMetrics.Forget(base)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadDrainTimeout bounds how long reload waits for the calls still
+// running on a build that changed on disk.
+const reloadDrainTimeout = 30 * time.Second
+
+// reload evicts what the server derived from a plugin build that changed
+// on disk, as reported by a fluid.Watcher, so the next request loads the
+// file as it is now. A new build needs nothing evicted: requests resolve
+// it, and the latest version, as they come in.
+func (s *Server) reload(change fluid.Change) {
+	ref := fluid.Reference(change.Name, change.Version)
+	s.logger.LogAttrs(context.Background(), slog.LevelInfo, "plugin build changed on disk",
+		slog.String("plugin", ref),
+		slog.String("change", change.Op.String()))
+	s.syncSchedules()
+	if change.Op == fluid.BuildCreated {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reloadDrainTimeout)
+	defer cancel()
+	s.retire(ctx, ref, change.Path)
+}
+
+// retire evicts a deleted or replaced plugin build: it removes the build's
+// pool, waits for the calls still running on it, and releases its cached
+// module and AOT artifact. If ctx is done first, the remaining instances
+// are destroyed as their calls finish.
+func (s *Server) retire(ctx context.Context, name, path string) {
+	// Wait for a creation of the pool in progress, which would otherwise
+	// add it after it was removed
+	s.poolCreation.Do(path, func() (interface{}, error) { return nil, nil })
+
+	s.poolsMu.Lock()
+	pool := s.pools[path]
+	delete(s.pools, path)
+	delete(s.poolUsed, path)
+	s.poolsMu.Unlock()
+
+	if pool != nil {
+		if err := pool.Drain(ctx); err != nil {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "stopped waiting for calls to a retired plugin build",
+				slog.String("plugin", name),
+				slog.String("error", err.Error()))
+		}
+	}
+	if s.poolOptions.Modules != nil {
+		s.poolOptions.Modules.Forget(path)
+	}
+	if s.poolOptions.Compiler != nil {
+		s.poolOptions.Compiler.Forget(path)
+	}
+}
+
+// adminWriter returns the store's PluginWriter for an admin request. If
+// admin endpoints are disabled or the request lacks the admin token, it
+// writes the error response and reports false.
+func (s *Server) adminWriter(w http.ResponseWriter, r *http.Request) (fluid.PluginWriter, bool) {
+	writer, ok := s.store.(fluid.PluginWriter)
+	if !ok {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "plugin administration is disabled")
+		return nil, false
+	}
+	return writer, s.admin(w, r)
+}
+
+// admin reports whether an admin request may proceed: it carries the admin
+// token, or authenticated as a principal with admin rights. If admin
+// endpoints are disabled or the request is not authorized, it writes the
+// error response and reports false.
+func (s *Server) admin(w http.ResponseWriter, r *http.Request) bool {
+	if s.adminToken == "" && s.auth == nil {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "plugin administration is disabled")
+		return false
+	}
+	if s.authorized(r) {
+		return true
+	}
+	if p, ok := principalOf(r.Context()); ok {
+		if p.Admin {
+			return true
+		}
+		writeError(w, r, apierror.CodeForbidden, p.Name+" may not call admin endpoints")
+		return false
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeError(w, r, apierror.CodeUnauthorized, "a valid admin bearer token is required")
+	return false
+}
+
+// authorized reports whether the request carries the admin token.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.adminToken == "" {
+		return false
+	}
+	got := []byte(r.Header.Get("Authorization"))
+	want := []byte("Bearer " + s.adminToken)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// readUpload returns the plugin name and binary of an upload request,
+// reading at most maxPluginUploadBytes.
+func readUpload(w http.ResponseWriter, r *http.Request) (name string, data []byte, err error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPluginUploadBytes)
+	body := io.Reader(r.Body)
+	name = r.URL.Query().Get("name")
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return "", nil, uploadError(fmt.Errorf("invalid multipart upload: %w", err))
+		}
+		defer file.Close()
+		body = file
+
+		// FormValue also covers the query parameter
+		name = r.FormValue("name")
+		if name == "" {
+			name = strings.TrimSuffix(header.Filename, ".wasm")
+		}
+	}
+
+	data, err = io.ReadAll(body)
+	if err != nil {
+		return "", nil, uploadError(fmt.Errorf("failed to read upload: %w", err))
+	}
+	return name, data, nil
+}
+
+// uploadError classifies a failure to read an upload: too large, or
+// otherwise malformed.
+func uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return apierror.Wrap(apierror.CodePluginTooLarge,
+			fmt.Errorf("plugin exceeds %d bytes", maxPluginUploadBytes))
+	}
+	return apierror.Wrap(apierror.CodeInvalidRequest, err)
+}
+
+// validateUpload checks that data is a plugin the server can run as name:
+// it exports the required ABI functions, matches the plugin's deployed
+// manifest and digest, if any, loads with the host modules pools provide,
+// which includes implementing a supported ABI version, and passes the
+// tests the manifest declares.
+func (s *Server) validateUpload(name string, data []byte) error {
+	module, err := wasminfo.Parse(data)
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInvalidPlugin, err)
+	}
+	for _, export := range requiredExports {
+		if _, ok := module.Export(export); !ok {
+			return apierror.Wrap(apierror.CodeInvalidPlugin, fmt.Errorf("plugin must export %s()", export))
+		}
+	}
+
+	// A new build must not break the manifest deployed next to it, nor the
+	// digest pinned for it
+	var deployed *manifest.Manifest
+	if path, err := s.store.Resolve(name); err == nil {
+		m, err := manifest.ForPlugin(path)
+		if err == nil && m != nil {
+			deployed = m
+			if err := m.Check(module); err != nil {
+				return apierror.Wrap(apierror.CodeInvalidPlugin,
+					fmt.Errorf("plugin does not match its manifest: %w", err))
+			}
+		}
+		if expected, err := manifest.Digest(path, m); err == nil && expected != "" {
+			if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != expected {
+				return apierror.Wrap(apierror.CodeInvalidPlugin,
+					fmt.Errorf("plugin does not match the sha256 %s declared for it", expected))
+			}
+		}
+	}
+
+	// Load a private copy; the module cache must not see the temporary path
+	tmp, err := os.CreateTemp("", "upload-*.wasm")
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInternal, fmt.Errorf("failed to stage upload: %w", err))
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInternal, fmt.Errorf("failed to stage upload: %w", err))
+	}
+
+	plugin, err := runtime.LoadPluginWithOptions(tmp.Name(), runtime.LoadOptions{
+		MaxMemoryPages:    s.poolOptions.MaxMemoryPages,
+		HostModules:       s.hostModules(),
+		RequireABIVersion: s.poolOptions.RequireABIVersion,
+	})
+	if err != nil {
+		return apierror.Wrap(apierror.CodeInvalidPlugin, fmt.Errorf("plugin failed to load: %w", err))
+	}
+	plugin.Close()
+	return s.testBuild(name, data, deployed)
+}
+
+// runnablePlugins drops plugins whose names /run would reject, so every
+// listed name can be passed to /run as-is.
+func runnablePlugins(plugins []fluid.PluginInfo) []fluid.PluginInfo {
+	runnable := make([]fluid.PluginInfo, 0, len(plugins))
+	for _, p := range plugins {
+		if isValidPluginName(p.Name) {
+			runnable = append(runnable, p)
+		}
+	}
+	return runnable
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("GET /plugins", func() {
+	var pluginsDir string
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		for _, name := range []string{"hello", "echo", "bad.name"} {
+			Expect(os.MkdirAll(filepath.Join(pluginsDir, name), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(pluginsDir, name, name+".wasm"), []byte("wasm"), 0644)).To(Succeed())
+		}
+	})
+
+	get := func(store fluid.PluginStore, method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		NewServer(store).handlePlugins(rec, httptest.NewRequest(method, "/plugins", nil))
+		return rec
+	}
+
+	// =========================================================================
+	// TEST: Plugin discovery
+	// Why: Clients use this list to decide what to run; every listed name
+	//      must be accepted by /run.
+	// =========================================================================
+	It("should list runnable plugins with size and modification time", func() {
+		rec := get(fluid.NewLocalPluginStore(pluginsDir), http.MethodGet)
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var plugins []fluid.PluginInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &plugins)).To(Succeed())
+		Expect(plugins).To(HaveLen(2))
+		Expect(plugins[0].Name).To(Equal("echo"))
+		Expect(plugins[1].Name).To(Equal("hello"))
+		Expect(plugins[1].Size).To(Equal(int64(4)))
+		Expect(plugins[1].ModTime).NotTo(BeZero())
+	})
+
+	It("should add the digest of every binary on request", func() {
+		rec := httptest.NewRecorder()
+		NewServer(fluid.NewLocalPluginStore(pluginsDir)).handlePlugins(rec,
+			httptest.NewRequest(http.MethodGet, "/plugins?digest=true", nil))
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var plugins []listedPlugin
+		Expect(json.Unmarshal(rec.Body.Bytes(), &plugins)).To(Succeed())
+		Expect(plugins).To(HaveLen(2))
+		Expect(plugins[1].Name).To(Equal("hello"))
+		Expect(plugins[1].Digest).To(Equal("336154bf67f765f8f75d16a0accee61b5ee5f6a75b2a2905703df913bd550f3e")) // sha256("wasm")
+
+		Expect(get(fluid.NewLocalPluginStore(pluginsDir), http.MethodGet).Body.String()).NotTo(ContainSubstring(`"digest"`))
+	})
+
+	It("should return an empty array for an empty store", func() {
+		rec := get(fluid.NewLocalPluginStore(filepath.Join(pluginsDir, "missing")), http.MethodGet)
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`[]`))
+	})
+
+	It("should return 500 when the store cannot be listed", func() {
+		rec := get(fluid.NewFluidPluginStore(filepath.Join(pluginsDir, "unmounted")), http.MethodGet)
+
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(apierror.CodeInternal))
+	})
+
+	It("should return 405 for POST when uploads are disabled", func() {
+		rec := get(fluid.NewLocalPluginStore(pluginsDir), http.MethodPost)
+
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should return 405 for DELETE", func() {
+		rec := get(fluid.NewLocalPluginStore(pluginsDir), http.MethodDelete)
+
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
+
+// exportsModule encodes a WebAssembly binary exporting one `() -> i32`
+// function per name. Names must be shorter than 128 bytes.
+func exportsModule(names ...string) []byte {
+	n := byte(len(names))
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = append(out, 0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f) // type 0: () -> i32
+
+	funcs := []byte{n}
+	exports := []byte{n}
+	code := []byte{n}
+	for i, name := range names {
+		funcs = append(funcs, 0x00)
+		exports = append(exports, byte(len(name)))
+		exports = append(exports, name...)
+		exports = append(exports, 0x00, byte(i))
+		code = append(code, 0x04, 0x00, 0x41, 0x00, 0x0b) // i32.const 0
+	}
+	for _, s := range []struct {
+		id      byte
+		payload []byte
+	}{{0x03, funcs}, {0x07, exports}, {0x0a, code}} {
+		out = append(out, s.id, byte(len(s.payload)))
+		out = append(out, s.payload...)
+	}
+	return out
+}
+
+var _ = Describe("POST /plugins", func() {
+	// =========================================================================
+	// TEST: Plugin uploads
+	// Why: An upload replaces what /run executes; only admins may upload,
+	//      and a build that cannot run must never reach the store.
+	// =========================================================================
+	const token = "s3cret"
+
+	var (
+		pluginsDir string
+		srv        *Server
+	)
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.adminToken = token
+	})
+
+	upload := func(req *http.Request) *httptest.ResponseRecorder {
+		if req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.handlePlugins(rec, req)
+		return rec
+	}
+
+	raw := func(name string, data []byte) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/plugins?name="+name, bytes.NewReader(data))
+	}
+
+	problemCode := func(rec *httptest.ResponseRecorder) apierror.Code {
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed(), rec.Body.String())
+		return problem.Code
+	}
+
+	helloWasm := func() []byte {
+		data, err := os.ReadFile(filepath.Join("..", "..", "plugins", "hello", "hello.wasm"))
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: hello.wasm")
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+
+	It("should reject a missing or wrong token", func() {
+		req := raw("hello", exportsModule("init", "process", "cleanup"))
+		req.Header.Set("Authorization", "Bearer wrong")
+		rec := upload(req)
+
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeUnauthorized))
+	})
+
+	It("should reject an invalid plugin name", func() {
+		rec := upload(raw("bad.name", exportsModule("init", "process", "cleanup")))
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPluginName))
+	})
+
+	It("should reject uploads to the latest alias", func() {
+		rec := upload(raw("hello@latest", exportsModule("init", "process", "cleanup")))
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPluginName))
+	})
+
+	It("should store a version next to the others", func() {
+		data := helloWasm()
+		Expect(upload(raw("greeter@1.0.0", data)).Code).To(Equal(http.StatusCreated))
+
+		rec := upload(raw("greeter@1.1.0", data))
+
+		Expect(rec.Code).To(Equal(http.StatusCreated), rec.Body.String())
+		var info fluid.PluginInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &info)).To(Succeed())
+		Expect(info.Version).To(Equal("1.1.0"))
+		Expect(filepath.Join(pluginsDir, "greeter", "1.0.0", "greeter.wasm")).To(BeAnExistingFile())
+		Expect(filepath.Join(pluginsDir, "greeter", "1.1.0", "greeter.wasm")).To(BeAnExistingFile())
+	})
+
+	It("should reject a binary missing a required export", func() {
+		rec := upload(raw("hello", exportsModule("init", "process")))
+
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPlugin))
+		Expect(rec.Body.String()).To(ContainSubstring("cleanup"))
+		Expect(filepath.Join(pluginsDir, "hello")).NotTo(BeADirectory())
+	})
+
+	It("should reject a binary that breaks the deployed manifest", func() {
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("old"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, manifest.FileName),
+			[]byte(`{"name": "hello", "version": "1.0.0", "exports": ["process_bytes"]}`), 0644)).To(Succeed())
+
+		rec := upload(raw("hello", exportsModule("init", "process", "cleanup")))
+
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(ContainSubstring("process_bytes"))
+		Expect(os.ReadFile(filepath.Join(dir, "hello.wasm"))).To(Equal([]byte("old")))
+	})
+
+	It("should reject a binary that does not match the pinned digest", func() {
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		wasmPath := filepath.Join(dir, "hello.wasm")
+		Expect(os.WriteFile(wasmPath, []byte("old"), 0644)).To(Succeed())
+		digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+		Expect(os.WriteFile(wasmPath+manifest.DigestSuffix, []byte(digest+"  hello.wasm\n"), 0644)).To(Succeed())
+
+		rec := upload(raw("hello", exportsModule("init", "process", "cleanup")))
+
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPlugin))
+		Expect(rec.Body.String()).To(ContainSubstring(digest))
+		Expect(os.ReadFile(wasmPath)).To(Equal([]byte("old")))
+	})
+
+	It("should store a binary only if it passes the tests of the deployed manifest", func() {
+		data := helloWasm()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		wasmPath := filepath.Join(dir, "hello.wasm")
+		Expect(os.WriteFile(wasmPath, []byte("old"), 0644)).To(Succeed())
+		deploy := func(output int) {
+			Expect(os.WriteFile(filepath.Join(dir, manifest.FileName), []byte(fmt.Sprintf(
+				`{"name": "hello", "version": "1.0.0", "tests": [{"name": "doubles", "input": 2, "output": %d}]}`, output)), 0644)).To(Succeed())
+		}
+
+		deploy(4)
+		rec := upload(raw("hello", data))
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPlugin))
+		Expect(rec.Body.String()).To(ContainSubstring("plugin failed test doubles: expected output 4"))
+		Expect(os.ReadFile(wasmPath)).To(Equal([]byte("old")))
+
+		deploy(5)
+		rec = upload(raw("hello", data))
+		Expect(rec.Code).To(Equal(http.StatusCreated), rec.Body.String())
+		Expect(os.ReadFile(wasmPath)).To(Equal(data))
+	})
+
+	It("should reject a binary over the size limit", func() {
+		rec := upload(raw("hello", make([]byte, maxPluginUploadBytes+1)))
+
+		Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(problemCode(rec)).To(Equal(apierror.CodePluginTooLarge))
+	})
+
+	It("should store a raw upload", func() {
+		data := helloWasm()
+
+		rec := upload(raw("greeter", data))
+
+		Expect(rec.Code).To(Equal(http.StatusCreated), rec.Body.String())
+		var info fluid.PluginInfo
+		Expect(json.Unmarshal(rec.Body.Bytes(), &info)).To(Succeed())
+		Expect(info.Name).To(Equal("greeter"))
+		Expect(info.Size).To(Equal(int64(len(data))))
+		Expect(os.ReadFile(filepath.Join(pluginsDir, "greeter", "greeter.wasm"))).To(Equal(data))
+	})
+
+	It("should name a multipart upload after its file", func() {
+		data := helloWasm()
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		part, err := form.CreateFormFile("file", "greeter.wasm")
+		Expect(err).NotTo(HaveOccurred())
+		part.Write(data)
+		Expect(form.Close()).To(Succeed())
+
+		req := httptest.NewRequest(http.MethodPost, "/plugins", body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := upload(req)
+
+		Expect(rec.Code).To(Equal(http.StatusCreated), rec.Body.String())
+		Expect(filepath.Join(pluginsDir, "greeter", "greeter.wasm")).To(BeAnExistingFile())
+	})
+})
+
+var _ = Describe("DELETE /plugins/{name}", func() {
+	// =========================================================================
+	// TEST: Plugin retirement
+	// Why: Operators retire plugins without a restart; only admins may, new
+	//      requests must stop finding the plugin, and calls already running
+	//      must finish before its instances go away.
+	// =========================================================================
+	const token = "s3cret"
+
+	var (
+		pluginsDir string
+		srv        *Server
+	)
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.adminToken = token
+		Expect(os.MkdirAll(filepath.Join(pluginsDir, "hello"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(pluginsDir, "hello", "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+	})
+
+	remove := func(method, path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		srv.handlePlugin(rec, req)
+		return rec
+	}
+
+	problemCode := func(rec *httptest.ResponseRecorder) apierror.Code {
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed(), rec.Body.String())
+		return problem.Code
+	}
+
+	It("should return 405 when administration is disabled", func() {
+		srv.adminToken = ""
+		rec := remove(http.MethodDelete, "/plugins/hello", "Bearer "+token)
+
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(filepath.Join(pluginsDir, "hello", "hello.wasm")).To(BeAnExistingFile())
+	})
+
+	It("should return 405 for other methods", func() {
+		rec := remove(http.MethodGet, "/plugins/hello", "Bearer "+token)
+
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should reject a missing or wrong token", func() {
+		rec := remove(http.MethodDelete, "/plugins/hello", "Bearer wrong")
+
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeUnauthorized))
+		Expect(filepath.Join(pluginsDir, "hello", "hello.wasm")).To(BeAnExistingFile())
+	})
+
+	It("should reject an invalid plugin name", func() {
+		rec := remove(http.MethodDelete, "/plugins/..%2Fhello", "Bearer "+token)
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPluginName))
+	})
+
+	It("should return 404 for a missing plugin", func() {
+		rec := remove(http.MethodDelete, "/plugins/missing", "Bearer "+token)
+
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(problemCode(rec)).To(Equal(apierror.CodePluginNotFound))
+	})
+
+	It("should delete the plugin so new requests no longer find it", func() {
+		rec := remove(http.MethodDelete, "/plugins/hello", "Bearer "+token)
+
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(filepath.Join(pluginsDir, "hello")).NotTo(BeADirectory())
+
+		_, err := srv.run(context.Background(), Request{Plugin: "hello", Input: 1}, runtime.CallInfo{RequestID: "req-1"})
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginNotFound))
+	})
+
+	It("should wait for running calls and evict the plugin's pool", func() {
+		data, err := os.ReadFile(filepath.Join("..", "..", "plugins", "hello", "hello.wasm"))
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: hello.wasm")
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(pluginsDir, "hello", "hello.wasm"), data, 0644)).To(Succeed())
+		srv.poolOptions.Modules = runtime.NewModuleCache()
+
+		_, err = srv.run(context.Background(), Request{Plugin: "hello", Input: 1}, runtime.CallInfo{RequestID: "req-1"})
+		Expect(err).NotTo(HaveOccurred())
+		path, err := srv.store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		pool, err := srv.pool("hello", path)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Stands in for a call in flight
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- remove(http.MethodDelete, "/plugins/hello", "Bearer "+token)
+		}()
+		Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+
+		pool.Put(plugin)
+		var rec *httptest.ResponseRecorder
+		Eventually(done).Should(Receive(&rec))
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(srv.poolInfos()).To(BeEmpty())
+		Expect(srv.poolOptions.Modules.Stats().Modules).To(BeZero())
+	})
+})
+
+var _ = Describe("Plugin reload", func() {
+	// =========================================================================
+	// TEST: Hot reload
+	// Why: A build pushed to the plugin directory must replace the running
+	//      one without a restart, and a deleted one must stop being served.
+	// =========================================================================
+	var (
+		pluginsDir string
+		srv        *Server
+	)
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+	})
+
+	It("should evict the pool of an updated or removed build", func() {
+		data, err := os.ReadFile(filepath.Join("..", "..", "plugins", "hello", "hello.wasm"))
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: hello.wasm")
+		}
+		Expect(err).NotTo(HaveOccurred())
+		path := filepath.Join(pluginsDir, "hello", "hello.wasm")
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+		srv.poolOptions.Modules = runtime.NewModuleCache()
+		watcher := fluid.NewWatcher(pluginsDir, time.Hour)
+
+		_, err = srv.run(context.Background(), Request{Plugin: "hello", Input: 1}, runtime.CallInfo{RequestID: "req-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(srv.poolInfos()).To(HaveLen(1))
+
+		Expect(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))).To(Succeed())
+		changes := watcher.Scan()
+		Expect(changes).To(HaveLen(1))
+		srv.reload(changes[0])
+		Expect(srv.poolInfos()).To(BeEmpty())
+		Expect(srv.poolOptions.Modules.Stats().Modules).To(BeZero())
+
+		_, err = srv.run(context.Background(), Request{Plugin: "hello", Input: 1}, runtime.CallInfo{RequestID: "req-2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(srv.poolInfos()).To(HaveLen(1))
+
+		Expect(os.Remove(path)).To(Succeed())
+		changes = watcher.Scan()
+		Expect(changes).To(ConsistOf(fluid.Change{Name: "hello", Path: path, Op: fluid.BuildRemoved}))
+		srv.reload(changes[0])
+		Expect(srv.poolInfos()).To(BeEmpty())
+	})
+
+	It("should leave pools alone for a new build", func() {
+		srv.reload(fluid.Change{Name: "hello", Version: "2.0.0", Path: filepath.Join(pluginsDir, "hello", "2.0.0", "hello.wasm"), Op: fluid.BuildCreated})
+
+		Expect(srv.poolInfos()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// publishConfig is the PUBLISH_CONFIG_FILE format: the broker plugins
+// publish to and the topics each plugin may publish to.
+//
+//	{
+//	  "broker": {"type": "nats", "url": "nats://nats:4222", "token_env": "NATS_TOKEN"},
+//	  "plugins": {"enrich": ["orders.enriched"], "audit": ["audit.*"]}
+//	}
+type publishConfig struct {
+	Broker  brokerConfig        `json:"broker"`
+	Plugins map[string][]string `json:"plugins"` // Topics, or prefixes ending in "*", by plugin
+}
+
+// brokerConfig is a message broker: "nats", spoken to natively, or
+// "kafka", through a Confluent-compatible REST Proxy. The token, if any,
+// is read from the environment variable TokenEnv; NATS takes it as its
+// auth token, the REST Proxy as a bearer token.
+type brokerConfig struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	TokenEnv  string `json:"token_env,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // Default 5000
+}
+
+// defaultPublishTimeout bounds a publish when timeout_ms is unset.
+const defaultPublishTimeout = 5 * time.Second
+
+// loadTopics reads PUBLISH_CONFIG_FILE at path and returns the publishing
+// API with a publisher to its broker, which the caller closes on shutdown.
+// getenv looks up token_env. The broker is not contacted until a plugin
+// publishes.
+func loadTopics(path string, getenv func(string) string) (*runtime.Topics, io.Closer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg publishConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var token string
+	if cfg.Broker.TokenEnv != "" {
+		if token = getenv(cfg.Broker.TokenEnv); token == "" {
+			return nil, nil, fmt.Errorf("broker: %s is not set", cfg.Broker.TokenEnv)
+		}
+	}
+	timeout := defaultPublishTimeout
+	if cfg.Broker.TimeoutMs > 0 {
+		timeout = time.Duration(cfg.Broker.TimeoutMs) * time.Millisecond
+	}
+
+	var publisher interface {
+		runtime.Publisher
+		io.Closer
+	}
+	switch cfg.Broker.Type {
+	case "nats":
+		publisher = &natsPublisher{addr: strings.TrimPrefix(cfg.Broker.URL, "nats://"), token: token, timeout: timeout}
+	case "kafka":
+		publisher = &kafkaRESTPublisher{
+			baseURL: strings.TrimSuffix(cfg.Broker.URL, "/"),
+			token:   token,
+			client:  &http.Client{Timeout: timeout},
+		}
+	}
+	return runtime.NewTopics(publisher, cfg.Plugins), publisher, nil
+}
+
+// validate checks that the broker is usable and the grants well-formed.
+func (c *publishConfig) validate() error {
+	switch c.Broker.Type {
+	case "nats":
+		u, err := url.Parse(c.Broker.URL)
+		if err != nil || u.Scheme != "nats" || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("broker: NATS url must be nats://host:port, got %q", c.Broker.URL)
+		}
+	case "kafka":
+		u, err := url.Parse(c.Broker.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("broker: Kafka REST Proxy url must be an http or https URL, got %q", c.Broker.URL)
+		}
+	case "":
+		return errors.New("no broker declared")
+	default:
+		return fmt.Errorf("broker: unknown type %q, want nats or kafka", c.Broker.Type)
+	}
+	if c.Broker.TimeoutMs < 0 {
+		return errors.New("broker: timeout_ms must not be negative")
+	}
+	for plugin, topics := range c.Plugins {
+		if !isValidPluginName(plugin) || strings.Contains(plugin, "@") {
+			return fmt.Errorf("invalid plugin name %q", plugin)
+		}
+		for _, topic := range topics {
+			if topic == "" || topic == "*" || strings.ContainsAny(topic, " \t\r\n") {
+				return fmt.Errorf("plugin %s: invalid topic %q", plugin, topic)
+			}
+		}
+	}
+	return nil
+}
+
+// natsPublisher publishes with the NATS client protocol over one
+// connection, dialed on first use and again after it fails. Each message
+// is followed by a PING, so the PONG confirms the server processed it; a
+// -ERR before it, e.g. for a permissions violation, fails the publish.
+// Publishes are serialized.
+type natsPublisher struct {
+	addr    string
+	token   string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Publish sends payload to the subject topic, retrying once on a new
+// connection if the current one has gone stale.
+func (p *natsPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		fresh := p.conn == nil
+		if fresh {
+			if err = p.connect(ctx); err != nil {
+				return err
+			}
+		}
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "PUB %s %d\r\n", topic, len(payload))
+		buf.Write(payload)
+		buf.WriteString("\r\nPING\r\n")
+		if err = p.roundTrip(ctx, buf.Bytes()); err == nil {
+			return nil
+		}
+		var serverErr natsError
+		if errors.As(err, &serverErr) || fresh {
+			break
+		}
+	}
+	return err
+}
+
+// Close closes the connection, if any.
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reset()
+}
+
+// connect dials the server and authenticates.
+func (p *natsPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	p.conn, p.reader = conn, bufio.NewReader(conn)
+
+	// The server greets with INFO before accepting CONNECT
+	p.conn.SetReadDeadline(time.Now().Add(p.timeout))
+	line, err := p.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		p.reset()
+		return fmt.Errorf("failed to connect to NATS: unexpected greeting %q: %v", strings.TrimSpace(line), err)
+	}
+	options, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "wasm-plugin-server",
+		"lang":       "go",
+		"version":    "1.0.0",
+		"protocol":   0,
+		"auth_token": p.token,
+	})
+	if err := p.roundTrip(ctx, []byte("CONNECT "+string(options)+"\r\nPING\r\n")); err != nil {
+		p.reset()
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return nil
+}
+
+// roundTrip writes data, which must end in a PING, and reads up to the
+// PONG. Any error but a -ERR that leaves the connection open closes it.
+func (p *natsPublisher) roundTrip(ctx context.Context, data []byte) error {
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	p.conn.SetDeadline(deadline)
+
+	if _, err := p.conn.Write(data); err != nil {
+		p.reset()
+		return err
+	}
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			p.reset()
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			// The server's keepalive
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				p.reset()
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			message := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+			// Only permission errors leave the connection open
+			if !strings.HasPrefix(strings.ToLower(message), "permissions violation") {
+				p.reset()
+			}
+			return natsError(message)
+		}
+		// +OK and INFO updates need no answer
+	}
+}
+
+// reset closes the connection so the next publish dials a new one.
+func (p *natsPublisher) reset() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn, p.reader = nil, nil
+	return err
+}
+
+// natsError is an -ERR message from the server.
+type natsError string
+
+func (e natsError) Error() string {
+	return "NATS: " + string(e)
+}
+
+// kafkaRESTPublisher publishes to Kafka through a Confluent-compatible
+// REST Proxy (POST /topics/<topic>, v2 binary embedded format), so the
+// server needs no Kafka client.
+type kafkaRESTPublisher struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// Publish produces payload as one record to topic.
+func (p *kafkaRESTPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"records": []map[string]string{{"value": base64.StdEncoding.EncodeToString(payload)}},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.binary.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("REST Proxy responded %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	// A record the broker rejected is reported per offset, with status 200
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid REST Proxy response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			return fmt.Errorf("kafka rejected the record: %s", offset.Error)
+		}
+	}
+	return nil
+}
+
+// Close releases idle connections.
+func (p *kafkaRESTPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
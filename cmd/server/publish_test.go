@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// fakeNATS is a NATS server that accepts clients with its token, records
+// published messages, and denies subjects starting with "denied.".
+type fakeNATS struct {
+	listener net.Listener
+	token    string
+
+	mu       sync.Mutex
+	messages []string // "<subject> <payload>"
+	conns    []net.Conn
+}
+
+func newFakeNATS(token string) *fakeNATS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	f := &fakeNATS{listener: listener, token: token}
+	go f.accept()
+	DeferCleanup(f.close)
+	return f
+}
+
+func (f *fakeNATS) accept() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.mu.Unlock()
+		go f.serve(conn)
+	}
+}
+
+func (f *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"auth_required\":true}\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch verb {
+		case "CONNECT":
+			var options struct {
+				AuthToken string `json:"auth_token"`
+			}
+			json.Unmarshal([]byte(args), &options)
+			if options.AuthToken != f.token {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PUB":
+			fields := strings.Fields(args)
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if strings.HasPrefix(fields[0], "denied.") {
+				fmt.Fprintf(conn, "-ERR 'Permissions Violation for Publish to %s'\r\n", fields[0])
+				continue
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, fields[0]+" "+string(payload[:size]))
+			f.mu.Unlock()
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
+
+// dropConnections closes every client connection, as a restarting server
+// would.
+func (f *fakeNATS) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeNATS) published() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.messages...)
+}
+
+func (f *fakeNATS) close() {
+	f.listener.Close()
+	f.dropConnections()
+}
+
+var _ = Describe("loadTopics", func() {
+	writeConfig := func(contents string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "publish.json")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	getenv := func(name string) string {
+		if name == "BROKER_TOKEN" {
+			return "secret"
+		}
+		return ""
+	}
+
+	load := func(broker string) *runtime.Topics {
+		topics, publisher, err := loadTopics(writeConfig(`{
+			"broker": `+broker+`,
+			"plugins": {"enrich": ["orders.enriched", "denied.*"]}
+		}`), getenv)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(publisher.Close)
+		return topics
+	}
+
+	ctx := context.Background()
+
+	// =========================================================================
+	// TEST: NATS publishing
+	// Why: A publish must only succeed once the server processed it, and a
+	//      connection the server dropped must not fail the next publish.
+	// =========================================================================
+	It("should publish to NATS and reconnect after a dropped connection", func() {
+		server := newFakeNATS("secret")
+		topics := load(`{"type": "nats", "url": "nats://` + server.listener.Addr().String() + `", "token_env": "BROKER_TOKEN"}`)
+
+		Expect(topics.Publish(ctx, "enrich", "orders.enriched", []byte(`{"id":1}`))).To(Succeed())
+		server.dropConnections()
+		Expect(topics.Publish(ctx, "enrich", "orders.enriched", []byte(`{"id":2}`))).To(Succeed())
+		Expect(server.published()).To(Equal([]string{`orders.enriched {"id":1}`, `orders.enriched {"id":2}`}))
+
+		// A subject the server denies fails the publish, not the connection
+		err := topics.Publish(ctx, "enrich", "denied.audit", []byte("x"))
+		Expect(err).To(MatchError(ContainSubstring("Permissions Violation")))
+		Expect(topics.Publish(ctx, "enrich", "orders.enriched", []byte(`{"id":3}`))).To(Succeed())
+	})
+
+	It("should fail to publish to NATS with the wrong token", func() {
+		server := newFakeNATS("other")
+		topics := load(`{"type": "nats", "url": "nats://` + server.listener.Addr().String() + `", "token_env": "BROKER_TOKEN"}`)
+
+		err := topics.Publish(ctx, "enrich", "orders.enriched", []byte("x"))
+		Expect(err).To(MatchError(ContainSubstring("Authorization Violation")))
+	})
+
+	It("should publish to Kafka through the REST Proxy", func() {
+		var (
+			mu      sync.Mutex
+			records []string
+		)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/vnd.kafka.binary.v2+json"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer secret"))
+			if r.URL.Path == "/topics/denied.audit" {
+				fmt.Fprint(w, `{"offsets": [{"partition": null, "offset": null, "error_code": 40301, "error": "Not authorized"}]}`)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			records = append(records, r.URL.Path+" "+string(body))
+			mu.Unlock()
+			fmt.Fprint(w, `{"offsets": [{"partition": 0, "offset": 7}]}`)
+		}))
+		DeferCleanup(proxy.Close)
+		topics := load(`{"type": "kafka", "url": "` + proxy.URL + `", "token_env": "BROKER_TOKEN"}`)
+
+		Expect(topics.Publish(ctx, "enrich", "orders.enriched", []byte("hi"))).To(Succeed())
+		Expect(records).To(Equal([]string{`/topics/orders.enriched {"records":[{"value":"aGk="}]}`}))
+
+		err := topics.Publish(ctx, "enrich", "denied.audit", []byte("x"))
+		Expect(err).To(MatchError(ContainSubstring("Not authorized")))
+		Expect(errors.Is(err, runtime.ErrPublishDenied)).To(BeFalse())
+	})
+
+	DescribeTable("should reject invalid configurations",
+		func(contents, message string) {
+			_, _, err := loadTopics(writeConfig(contents), getenv)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("no broker", `{}`, "no broker"),
+		Entry("unknown type", `{"broker": {"type": "rabbitmq", "url": "amqp://mq"}}`, `unknown type "rabbitmq"`),
+		Entry("NATS url without scheme", `{"broker": {"type": "nats", "url": "nats:4222"}}`, "nats://host:port"),
+		Entry("Kafka broker address", `{"broker": {"type": "kafka", "url": "kafka:9092"}}`, "REST Proxy url"),
+		Entry("missing token", `{"broker": {"type": "nats", "url": "nats://nats:4222", "token_env": "UNSET"}}`, "UNSET is not set"),
+		Entry("wildcard grant", `{"broker": {"type": "nats", "url": "nats://nats:4222"}, "plugins": {"enrich": ["*"]}}`, `invalid topic "*"`),
+		Entry("invalid plugin", `{"broker": {"type": "nats", "url": "nats://nats:4222"}, "plugins": {"a/b": ["t"]}}`, `invalid plugin name "a/b"`),
+	)
+})
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// APIKeyHeader carries the API key identifying a /run client for rate
+// limiting. Requests without a configured key are limited by client IP.
+const APIKeyHeader = "X-API-Key"
+
+// grpcAPIKeyKey is the metadata counterpart of APIKeyHeader.
+const grpcAPIKeyKey = "x-api-key"
+
+// rateLimitPruneInterval bounds how often idle buckets are dropped.
+const rateLimitPruneInterval = time.Minute
+
+// RateLimit is a token bucket: Rate requests per second on average, and
+// up to Burst at once. A zero Rate is no limit; a zero Burst means Rate
+// rounded up.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// burst returns the bucket size of the limit.
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// rateLimitConfig is the RATE_LIMITS_FILE format: the limit every client
+// gets per plugin by default, the limits of single plugins, and the API
+// keys clients identify with, each read from an environment variable and
+// optionally with a limit of its own.
+//
+//	{
+//	  "default": {"rate": 20, "burst": 40},
+//	  "plugins": {"billing": {"rate": 2, "burst": 5}},
+//	  "keys": {"payments": {"key_env": "PAYMENTS_API_KEY", "rate": 100, "burst": 200}}
+//	}
+type rateLimitConfig struct {
+	Default RateLimit            `json:"default"`
+	Plugins map[string]RateLimit `json:"plugins"`
+	Keys    map[string]apiKey    `json:"keys"` // By client name
+}
+
+// apiKey is a client's API key and its limit, if it has its own.
+type apiKey struct {
+	KeyEnv string `json:"key_env"`
+	RateLimit
+}
+
+// validate checks the limits.
+func (c *rateLimitConfig) validate() error {
+	check := func(name string, l RateLimit) error {
+		if l.Rate < 0 || l.Burst < 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate) {
+			return fmt.Errorf("%s: rate and burst must not be negative", name)
+		}
+		return nil
+	}
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for plugin, l := range c.Plugins {
+		if !isValidPluginName(plugin) {
+			return fmt.Errorf("plugins: invalid plugin name %q", plugin)
+		}
+		if err := check("plugins."+plugin, l); err != nil {
+			return err
+		}
+	}
+	for name, key := range c.Keys {
+		if key.KeyEnv == "" {
+			return fmt.Errorf("keys.%s: key_env is required", name)
+		}
+		if err := check("keys."+name, key.RateLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenBucket is the state of a client's limit for a plugin.
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket for limit.
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: limit.burst(), last: now}
+}
+
+// take refills the bucket up to now and takes a token, or returns how
+// long it takes until the next one is due.
+func (b *tokenBucket) take(now time.Time) (time.Duration, bool) {
+	b.tokens = math.Min(b.limit.burst(), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second)), false
+}
+
+// full reports whether the bucket has refilled by now, which makes it
+// equivalent to a new one.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= b.limit.burst()
+}
+
+// rateLimiter limits how often each client may run each plugin. Clients
+// are identified by API key, or else by IP address. The limit of a call
+// is its plugin's if set, else its key's, else the default.
+type rateLimiter struct {
+	cfg  rateLimitConfig
+	keys map[string]string // Client name by API key
+
+	// trustedHops is how many reverse proxies in front of the server
+	// append to X-Forwarded-For, which the client IP is taken from; 0
+	// ignores the header
+	trustedHops int
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	limited   map[string]*metrics.Counter // Rejections by plugin
+	now       func() time.Time
+}
+
+// loadRateLimits reads RATE_LIMITS_FILE at path. getenv looks up the API
+// keys.
+func loadRateLimits(path string, getenv func(string) string, trustedHops int) (*rateLimiter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg rateLimitConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]string, len(cfg.Keys))
+	for name, key := range cfg.Keys {
+		value := getenv(key.KeyEnv)
+		if value == "" {
+			return nil, fmt.Errorf("keys.%s: %s is not set", name, key.KeyEnv)
+		}
+		if other, ok := keys[value]; ok {
+			return nil, fmt.Errorf("keys.%s: same key as keys.%s", name, other)
+		}
+		keys[value] = name
+	}
+	return newRateLimiter(cfg, keys, trustedHops), nil
+}
+
+// newRateLimiter creates a limiter for the given limits and API keys.
+func newRateLimiter(cfg rateLimitConfig, keys map[string]string, trustedHops int) *rateLimiter {
+	return &rateLimiter{
+		cfg:         cfg,
+		keys:        keys,
+		trustedHops: trustedHops,
+		buckets:     make(map[string]*tokenBucket),
+		limited:     make(map[string]*metrics.Counter),
+		now:         time.Now,
+	}
+}
+
+// rateClient identifies a client: the name of its API key, or its IP
+// address.
+type rateClient struct {
+	key string // Name of the API key; empty for clients identified by IP
+	ip  string
+}
+
+// String returns the client's bucket identity.
+func (c rateClient) String() string {
+	if c.key != "" {
+		return "key:" + c.key
+	}
+	return "ip:" + c.ip
+}
+
+// client identifies the client sending apiKey from remoteAddr. Unknown
+// keys are ignored rather than rejected, so that a client cannot escape
+// its IP's limit by inventing keys.
+func (rl *rateLimiter) client(apiKey, remoteAddr string) rateClient {
+	for key, name := range rl.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+			return rateClient{key: name}
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return rateClient{ip: host}
+}
+
+// limit returns the limit of client's calls to plugin.
+func (rl *rateLimiter) limit(client rateClient, plugin string) RateLimit {
+	if l, ok := rl.cfg.Plugins[plugin]; ok {
+		return l
+	}
+	if key, ok := rl.cfg.Keys[client.key]; ok && key.Rate > 0 {
+		return key.RateLimit
+	}
+	return rl.cfg.Default
+}
+
+// allow takes a token from client's bucket for plugin, or returns a
+// CodeRateLimited error telling the client when the next one is due.
+func (rl *rateLimiter) allow(client rateClient, plugin string) error {
+	limit := rl.limit(client, plugin)
+	if limit.Rate == 0 {
+		return nil
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	rl.prune(now)
+
+	id := client.String() + "\x00" + plugin
+	bucket, ok := rl.buckets[id]
+	if !ok {
+		bucket = newTokenBucket(limit, now)
+		rl.buckets[id] = bucket
+	}
+	wait, ok := bucket.take(now)
+	if ok {
+		return nil
+	}
+
+	counter, ok := rl.limited[plugin]
+	if !ok {
+		counter = &metrics.Counter{}
+		rl.limited[plugin] = counter
+	}
+	counter.Inc()
+	return apierror.WrapRetry(apierror.CodeRateLimited,
+		fmt.Errorf("%s exceeded the rate limit of %g requests per second to plugin %s", client, limit.Rate, plugin), wait)
+}
+
+// prune drops the buckets that have refilled, which are equivalent to
+// new ones. rl.mu must be held.
+func (rl *rateLimiter) prune(now time.Time) {
+	if now.Sub(rl.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	rl.lastPrune = now
+	for id, bucket := range rl.buckets {
+		if bucket.full(now) {
+			delete(rl.buckets, id)
+		}
+	}
+}
+
+// collectMetrics reports the requests rejected by rate limits.
+func (rl *rateLimiter) collectMetrics() []metrics.Family {
+	family := metrics.Family{Name: "plugin_rate_limited_total", Help: "Requests rejected by a rate limit, by plugin.", Type: metrics.TypeCounter}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	plugins := make([]string, 0, len(rl.limited))
+	for plugin := range rl.limited {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	for _, plugin := range plugins {
+		family.Samples = append(family.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "plugin", Value: plugin}},
+			Value:  float64(rl.limited[plugin].Value()),
+		})
+	}
+	return []metrics.Family{family}
+}
+
+// rateClientKey is the context key of the client a request is limited as.
+type rateClientKey struct{}
+
+// withRateClient returns ctx carrying the client a request is limited as.
+func withRateClient(ctx context.Context, client rateClient) context.Context {
+	return context.WithValue(ctx, rateClientKey{}, client)
+}
+
+// rateClientOf returns the client set by withRateClient. Requests without
+// one, like scheduled and batch runs, are not rate limited.
+func rateClientOf(ctx context.Context) (rateClient, bool) {
+	client, ok := ctx.Value(rateClientKey{}).(rateClient)
+	return client, ok
+}
+
+// checkRateLimit applies the rate limit of the request's client, if any,
+// and that of tenant to a call of plugin.
+func (s *Server) checkRateLimit(ctx context.Context, plugin, tenant string) error {
+	client, ok := rateClientOf(ctx)
+	if !ok {
+		return nil
+	}
+	if s.rateLimits != nil {
+		if err := s.rateLimits.allow(client, plugin); err != nil {
+			return err
+		}
+	}
+	return s.tenants.allow(ctx, tenant)
+}
+
+// rateLimited is middleware identifying the client of an HTTP request for
+// the rate limits its plugin calls are subject to.
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.rateLimits == nil {
+			// Only the tenant's limit applies, to any client
+			if s.tenants != nil {
+				r = r.WithContext(withRateClient(r.Context(), rateClient{}))
+			}
+			next(w, r)
+			return
+		}
+		remoteAddr := r.RemoteAddr
+		if forwarded := r.Header.Get("X-Forwarded-For"); s.rateLimits.trustedHops > 0 && forwarded != "" {
+			remoteAddr = forwardedClient(forwarded, s.rateLimits.trustedHops)
+		}
+		client := s.rateLimits.client(r.Header.Get(APIKeyHeader), remoteAddr)
+		next(w, r.WithContext(withRateClient(r.Context(), client)))
+	}
+}
+
+// grpcRateClient is the gRPC counterpart of rateLimited, identifying the
+// client of an Execute call from its metadata and peer address.
+func (s *Server) grpcRateClient(ctx context.Context, md metadata.MD) context.Context {
+	if s.rateLimits == nil {
+		if s.tenants != nil {
+			return withRateClient(ctx, rateClient{})
+		}
+		return ctx
+	}
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	if forwarded := firstValue(md, "x-forwarded-for"); s.rateLimits.trustedHops > 0 && forwarded != "" {
+		remoteAddr = forwardedClient(forwarded, s.rateLimits.trustedHops)
+	}
+	return withRateClient(ctx, s.rateLimits.client(firstValue(md, grpcAPIKeyKey), remoteAddr))
+}
+
+// forwardedClient returns the client address of an X-Forwarded-For value
+// appended to by hops trusted proxies: the entry hops from the right, as
+// the first of them saw it. Entries left of it are the client's own and
+// may be forged. A value with fewer entries has only passed trusted
+// proxies, and its first entry is the client.
+func forwardedClient(forwarded string, hops int) string {
+	entries := strings.Split(forwarded, ",")
+	return strings.TrimSpace(entries[max(len(entries)-hops, 0)])
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+var _ = Describe("Rate limits", func() {
+	var (
+		now time.Time
+		rl  *rateLimiter
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		rl = newRateLimiter(rateLimitConfig{
+			Default: RateLimit{Rate: 1, Burst: 2},
+			Plugins: map[string]RateLimit{"billing": {Rate: 0.5}},
+			Keys:    map[string]apiKey{"payments": {KeyEnv: "PAYMENTS_API_KEY", RateLimit: RateLimit{Rate: 10, Burst: 10}}},
+		}, map[string]string{"k3y": "payments"}, 0)
+		rl.now = func() time.Time { return now }
+	})
+
+	// =========================================================================
+	// TEST: Token buckets
+	// Why: A client may burst up to its bucket size, then only as fast as
+	//      the bucket refills, without affecting other clients.
+	// =========================================================================
+	It("should allow a burst and then the rate", func() {
+		client := rl.client("", "10.0.0.1:5000")
+		Expect(client.String()).To(Equal("ip:10.0.0.1"))
+		Expect(rl.allow(client, "hello")).To(Succeed())
+		Expect(rl.allow(client, "hello")).To(Succeed())
+
+		err := rl.allow(client, "hello")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeRateLimited))
+		Expect(apierror.RetryAfterOf(err)).To(Equal(time.Second))
+
+		// Other clients and plugins have buckets of their own
+		Expect(rl.allow(rl.client("", "10.0.0.2:5000"), "hello")).To(Succeed())
+		Expect(rl.allow(client, "rollup")).To(Succeed())
+
+		now = now.Add(500 * time.Millisecond)
+		err = rl.allow(client, "hello")
+		Expect(apierror.RetryAfterOf(err)).To(Equal(500 * time.Millisecond))
+		now = now.Add(500 * time.Millisecond)
+		Expect(rl.allow(client, "hello")).To(Succeed())
+	})
+
+	It("should apply the limits of plugins and API keys", func() {
+		payments := rl.client("k3y", "10.0.0.1:5000")
+		Expect(payments.String()).To(Equal("key:payments"))
+		for range 10 {
+			Expect(rl.allow(payments, "hello")).To(Succeed())
+		}
+		Expect(rl.allow(payments, "hello")).NotTo(Succeed())
+
+		// A plugin's limit applies to every client; its burst defaults to
+		// the rate rounded up
+		Expect(rl.allow(payments, "billing")).To(Succeed())
+		err := rl.allow(payments, "billing")
+		Expect(apierror.RetryAfterOf(err)).To(Equal(2 * time.Second))
+
+		// Unknown keys are limited by IP
+		Expect(rl.client("guess", "10.0.0.1:5000").String()).To(Equal("ip:10.0.0.1"))
+	})
+
+	DescribeTable("should identify clients by X-Forwarded-For as the trusted proxies saw them",
+		func(forwarded string, hops int, client string) {
+			Expect(forwardedClient(forwarded, hops)).To(Equal(client))
+		},
+		Entry("behind one proxy", "203.0.113.7", 1, "203.0.113.7"),
+		Entry("with a spoofed leading entry", "198.51.100.1, 203.0.113.7", 1, "203.0.113.7"),
+		Entry("behind two proxies", "198.51.100.1,203.0.113.7, 10.0.0.1", 2, "203.0.113.7"),
+		Entry("with fewer entries than proxies", "203.0.113.7", 2, "203.0.113.7"),
+	)
+
+	It("should drop refilled buckets and count rejections", func() {
+		client := rl.client("", "10.0.0.1:5000")
+		for range 3 {
+			_ = rl.allow(client, "hello")
+		}
+		Expect(rl.buckets).To(HaveLen(1))
+
+		now = now.Add(rateLimitPruneInterval)
+		Expect(rl.allow(rl.client("", "10.0.0.2:5000"), "hello")).To(Succeed())
+		Expect(rl.buckets).To(HaveLen(1))
+
+		Expect(rl.collectMetrics()[0].Samples).To(Equal([]metrics.Sample{
+			{Labels: []metrics.Label{{Name: "plugin", Value: "hello"}}, Value: 1},
+		}))
+	})
+
+	DescribeTable("should reject invalid configuration",
+		func(body string, env map[string]string) {
+			path := filepath.Join(GinkgoT().TempDir(), "limits.json")
+			Expect(os.WriteFile(path, []byte(body), 0644)).To(Succeed())
+			_, err := loadRateLimits(path, func(name string) string { return env[name] }, 0)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("a negative rate", `{"default": {"rate": -1}}`, nil),
+		Entry("an invalid plugin name", `{"plugins": {"../x": {"rate": 1}}}`, nil),
+		Entry("a key without key_env", `{"keys": {"payments": {"rate": 1}}}`, nil),
+		Entry("an unset key", `{"keys": {"payments": {"key_env": "PAYMENTS_API_KEY"}}}`, nil),
+		Entry("a shared key", `{"keys": {"a": {"key_env": "A"}, "b": {"key_env": "B"}}}`, map[string]string{"A": "k", "B": "k"}),
+	)
+
+	// =========================================================================
+	// TEST: Limited /run
+	// Why: The middleware must identify the client so that /run answers a
+	//      noisy one with 429 and Retry-After instead of running the plugin.
+	// =========================================================================
+	It("should answer /run with 429 and Retry-After beyond the limit", func() {
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.rateLimits = newRateLimiter(rateLimitConfig{Default: RateLimit{Rate: 1}}, nil, 2)
+		srv.rateLimits.now = func() time.Time { return now }
+		// Take the client's only token so that the request is rejected
+		// before it reaches the plugin
+		Expect(srv.rateLimits.allow(rateClient{ip: "203.0.113.7"}, "hello")).To(Succeed())
+
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "hello", "input": 1}`))
+		// Through two proxies; the client's own entry is ignored
+		req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 10.0.0.1")
+		rec := httptest.NewRecorder()
+		srv.rateLimited(srv.handleRun)(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rec.Header().Get("Retry-After")).To(Equal("1"))
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(apierror.CodeRateLimited))
+	})
+})
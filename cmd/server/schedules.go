@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/cron"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// ScheduleCaller is the caller of scheduled runs, as plugins see it in
+// their execution context.
+const ScheduleCaller = "scheduler"
+
+// scheduleTick is how often the scheduler looks for due schedules.
+const scheduleTick = time.Second
+
+// ScheduleInfo describes a plugin's schedule in
+// GET /plugins/{name}/schedules.
+type ScheduleInfo struct {
+	Plugin  string `json:"plugin"`
+	Name    string `json:"name"`
+	Cron    string `json:"cron"`
+	Enabled bool   `json:"enabled"`
+
+	// Next is when the schedule runs next; zero while it is disabled
+	Next time.Time `json:"next,omitzero"`
+
+	// The last run, if any, and why it failed
+	LastRun       time.Time `json:"last_run,omitzero"`
+	LastRequestID string    `json:"last_request_id,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// ScheduleRequest is the JSON request body for
+// PUT /plugins/{name}/schedules/{schedule}
+type ScheduleRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// scheduleKey identifies a schedule across the plugin's builds.
+type scheduleKey struct {
+	plugin, name string
+}
+
+// scheduleEntry is a registered schedule and the state of its runs.
+type scheduleEntry struct {
+	schedule manifest.Schedule
+	cron     *cron.Schedule
+	next     time.Time
+	running  bool // A run has not finished; the next one is skipped
+
+	lastRun       time.Time
+	lastRequestID string
+	lastError     string
+}
+
+// scheduler runs the schedules declared in the manifests of the latest
+// builds in the store. Publishing or deleting a plugin registers or drops
+// its schedules on the next sync.
+type scheduler struct {
+	server *Server
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[scheduleKey]*scheduleEntry
+
+	// enabled holds the admin API's decisions, which outlive the builds
+	// whose manifests set the default
+	enabled map[scheduleKey]bool
+}
+
+// newScheduler creates a scheduler running schedules on server.
+func newScheduler(server *Server) *scheduler {
+	return &scheduler{
+		server:  server,
+		now:     time.Now,
+		entries: make(map[scheduleKey]*scheduleEntry),
+		enabled: make(map[scheduleKey]bool),
+	}
+}
+
+// run syncs the schedules with the store every syncInterval and starts
+// due runs until ctx is done.
+func (sch *scheduler) run(ctx context.Context, syncInterval time.Duration) {
+	sch.syncAndLog()
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+	lastSync := sch.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := sch.now()
+			if now.Sub(lastSync) >= syncInterval {
+				sch.syncAndLog()
+				lastSync = now
+			}
+			for _, key := range sch.due(now) {
+				go sch.fire(ctx, key)
+			}
+		}
+	}
+}
+
+// syncAndLog syncs the schedules, logging a failure.
+func (sch *scheduler) syncAndLog() {
+	if err := sch.sync(); err != nil {
+		sch.server.logger.LogAttrs(context.Background(), slog.LevelWarn, "failed to sync plugin schedules",
+			slog.String("error", err.Error()))
+	}
+}
+
+// sync registers the schedules of the latest build of every plugin and
+// drops those no longer declared. A schedule whose expression is
+// unchanged keeps its next activation and run history.
+func (sch *scheduler) sync() error {
+	plugins, err := sch.server.store.List()
+	if err != nil {
+		return fmt.Errorf("failed to list plugins: %w", err)
+	}
+
+	declared := make(map[scheduleKey]manifest.Schedule)
+	seen := make(map[string]bool)
+	for _, p := range runnablePlugins(plugins) {
+		if seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		for _, schedule := range latestSchedules(sch.server.store, p.Name, plugins) {
+			declared[scheduleKey{p.Name, schedule.Name}] = schedule
+		}
+	}
+
+	now := sch.now()
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	for key, entry := range sch.entries {
+		if schedule, ok := declared[key]; !ok || schedule.Cron != entry.schedule.Cron {
+			delete(sch.entries, key)
+		}
+	}
+	for key, schedule := range declared {
+		if entry, ok := sch.entries[key]; ok {
+			entry.schedule = schedule
+			continue
+		}
+		// Manifests are validated, so the expression parses
+		parsed, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			continue
+		}
+		sch.entries[key] = &scheduleEntry{schedule: schedule, cron: parsed, next: parsed.Next(now)}
+	}
+	return nil
+}
+
+// latestSchedules returns the schedules in the manifest of the build of
+// name that requests run, or none if it cannot be resolved.
+func latestSchedules(store fluid.PluginStore, name string, plugins []fluid.PluginInfo) []manifest.Schedule {
+	path, err := store.Resolve(name)
+	if err != nil {
+		return nil
+	}
+	version := fluid.BuildVersion(path)
+	for _, p := range plugins {
+		if p.Name == name && p.Version == version && p.Manifest != nil {
+			return p.Manifest.Schedules
+		}
+	}
+	return nil
+}
+
+// isEnabled reports whether a schedule runs: as the admin API last set it,
+// or else unless its manifest disables it. sch.mu must be held.
+func (sch *scheduler) isEnabled(key scheduleKey, entry *scheduleEntry) bool {
+	if enabled, ok := sch.enabled[key]; ok {
+		return enabled
+	}
+	return !entry.schedule.Disabled
+}
+
+// due returns the enabled schedules due at now that are not running or
+// paused for maintenance, and marks them running. Schedules missed while
+// their last run was still going, or the server was down, are not made up.
+func (sch *scheduler) due(now time.Time) []scheduleKey {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	var keys []scheduleKey
+	for key, entry := range sch.entries {
+		if entry.next.IsZero() || now.Before(entry.next) {
+			continue
+		}
+		entry.next = entry.cron.Next(now)
+		if entry.running || !sch.isEnabled(key, entry) {
+			continue
+		}
+		// Runs paused for maintenance are skipped, not postponed
+		if until, _, err := sch.server.maintenance.activeUntil(key.plugin, "", now); err != nil || !until.IsZero() {
+			continue
+		}
+		entry.running = true
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].plugin+"/"+keys[i].name < keys[j].plugin+"/"+keys[j].name
+	})
+	return keys
+}
+
+// fire runs a schedule marked running by due as a /run request and
+// records the outcome.
+func (sch *scheduler) fire(ctx context.Context, key scheduleKey) {
+	sch.mu.Lock()
+	entry, ok := sch.entries[key]
+	if !ok {
+		sch.mu.Unlock()
+		return
+	}
+	schedule := entry.schedule
+	sch.mu.Unlock()
+
+	req := Request{Plugin: key.plugin, Input: schedule.Input, Text: schedule.Text, TimeoutMs: schedule.TimeoutMs}
+	call := runtime.CallInfo{RequestID: requestIDOf(""), Caller: ScheduleCaller}
+	started := sch.now()
+	_, err := sch.server.run(ctx, req, call)
+
+	attrs := []slog.Attr{
+		slog.String("plugin", key.plugin),
+		slog.String("schedule", key.name),
+		slog.String("request_id", call.RequestID),
+		slog.Duration("duration", sch.now().Sub(started)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		sch.server.logger.LogAttrs(ctx, slog.LevelWarn, "scheduled run failed", attrs...)
+	} else {
+		sch.server.logger.LogAttrs(ctx, slog.LevelInfo, "scheduled run completed", attrs...)
+	}
+
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	entry.running = false
+	entry.lastRun = started.UTC()
+	entry.lastRequestID = call.RequestID
+	entry.lastError = ""
+	if err != nil {
+		entry.lastError = err.Error()
+	}
+}
+
+// list returns the schedules of a plugin, sorted by name.
+func (sch *scheduler) list(plugin string) []ScheduleInfo {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	infos := []ScheduleInfo{}
+	for key, entry := range sch.entries {
+		if key.plugin == plugin {
+			infos = append(infos, sch.info(key, entry))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// get returns one schedule of a plugin.
+func (sch *scheduler) get(plugin, name string) (ScheduleInfo, bool) {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	key := scheduleKey{plugin, name}
+	entry, ok := sch.entries[key]
+	if !ok {
+		return ScheduleInfo{}, false
+	}
+	return sch.info(key, entry), true
+}
+
+// setEnabled enables or disables a schedule, overriding its manifest. An
+// enabled schedule next runs at its first activation from now.
+func (sch *scheduler) setEnabled(plugin, name string, enabled bool) (ScheduleInfo, bool) {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	key := scheduleKey{plugin, name}
+	entry, ok := sch.entries[key]
+	if !ok {
+		return ScheduleInfo{}, false
+	}
+	sch.enabled[key] = enabled
+	entry.next = entry.cron.Next(sch.now())
+	return sch.info(key, entry), true
+}
+
+// info describes a schedule. sch.mu must be held.
+func (sch *scheduler) info(key scheduleKey, entry *scheduleEntry) ScheduleInfo {
+	info := ScheduleInfo{
+		Plugin:        key.plugin,
+		Name:          key.name,
+		Cron:          entry.schedule.Cron,
+		Enabled:       sch.isEnabled(key, entry),
+		LastRun:       entry.lastRun,
+		LastRequestID: entry.lastRequestID,
+		LastError:     entry.lastError,
+	}
+	if info.Enabled {
+		info.Next = entry.next
+	}
+	return info
+}
+
+// errSchedulesDisabled rejects schedule requests to a server not running
+// schedules.
+var errSchedulesDisabled = errors.New("schedules are disabled on this server")
+
+// handlePluginSchedules handles GET /plugins/{name}/schedules and GET and
+// PUT /plugins/{name}/schedules/{schedule}, admin endpoints listing the
+// schedules a plugin's manifest declares and enabling or disabling them.
+//
+// Schedules are registered from the manifest of the plugin's latest build.
+// PUT overrides the manifest's disabled flag for as long as the server
+// runs, across new builds of the plugin.
+func (s *Server) handlePluginSchedules(w http.ResponseWriter, r *http.Request) {
+	name, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/plugins/"), "/")
+	scheduleName := strings.TrimPrefix(strings.TrimPrefix(sub, "schedules"), "/")
+
+	allowed := r.Method == http.MethodGet || (r.Method == http.MethodPut && scheduleName != "")
+	if !allowed {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+	if s.schedules == nil {
+		writeError(w, r, apierror.CodeScheduleNotFound, errSchedulesDisabled.Error())
+		return
+	}
+
+	if err := checkPluginName(name); err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+	// Schedules belong to the plugin, not a build
+	name, _ = fluid.ParseReference(name)
+	if _, err := s.store.Resolve(name); err != nil {
+		writeError(w, r, apierror.CodePluginNotFound, fmt.Sprintf("plugin not found: %s", name))
+		return
+	}
+
+	if scheduleName == "" {
+		writeJSON(w, http.StatusOK, s.schedules.list(name))
+		return
+	}
+
+	var info ScheduleInfo
+	var ok bool
+	switch r.Method {
+	case http.MethodGet:
+		info, ok = s.schedules.get(name, scheduleName)
+	case http.MethodPut:
+		var req ScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, apierror.CodeInvalidRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		info, ok = s.schedules.setEnabled(name, scheduleName, req.Enabled)
+	}
+	if !ok {
+		writeError(w, r, apierror.CodeScheduleNotFound,
+			fmt.Sprintf("plugin %s declares no schedule %s", name, scheduleName))
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// isSchedulesPath reports whether path is /plugins/{name}/schedules or
+// below it.
+func isSchedulesPath(path string) bool {
+	_, sub, _ := strings.Cut(strings.TrimPrefix(path, "/plugins/"), "/")
+	return sub == "schedules" || strings.HasPrefix(sub, "schedules/")
+}
+
+// syncSchedules registers the schedules of a plugin just published or
+// deleted, instead of waiting for the next periodic sync.
+func (s *Server) syncSchedules() {
+	if s.schedules != nil {
+		s.schedules.syncAndLog()
+	}
+}
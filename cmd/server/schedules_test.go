@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Schedules", func() {
+	var (
+		pluginsDir string
+		srv        *Server
+		now        time.Time
+	)
+
+	// publish installs a plugin whose manifest declares schedules.
+	publish := func(name, schedules string) {
+		dir := filepath.Join(pluginsDir, name)
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, name+".wasm"), []byte("wasm"), 0644)).To(Succeed())
+		manifest := `{"name": "` + name + `", "version": "1.0.0", "schedules": ` + schedules + `}`
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(manifest), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.adminToken = "secret"
+		srv.schedules = newScheduler(srv)
+		now = time.Date(2026, 10, 16, 10, 17, 30, 0, time.UTC)
+		srv.schedules.now = func() time.Time { return now }
+	})
+
+	// =========================================================================
+	// TEST: Registration from manifests
+	// Why: Publishing a plugin is all it takes to schedule it; deleting it
+	//      must stop its schedules.
+	// =========================================================================
+	It("should register the schedules of published plugins and drop deleted ones", func() {
+		publish("rollup", `[
+			{"name": "nightly", "cron": "0 2 * * *", "text": "all"},
+			{"name": "hourly", "cron": "@hourly", "disabled": true}
+		]`)
+		publish("hello", `[]`)
+		Expect(srv.schedules.sync()).To(Succeed())
+
+		Expect(srv.schedules.list("rollup")).To(Equal([]ScheduleInfo{
+			{Plugin: "rollup", Name: "hourly", Cron: "@hourly", Enabled: false},
+			{Plugin: "rollup", Name: "nightly", Cron: "0 2 * * *", Enabled: true,
+				Next: time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)},
+		}))
+		Expect(srv.schedules.list("hello")).To(BeEmpty())
+
+		Expect(os.RemoveAll(filepath.Join(pluginsDir, "rollup"))).To(Succeed())
+		Expect(srv.schedules.sync()).To(Succeed())
+		Expect(srv.schedules.list("rollup")).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Due runs
+	// Why: A schedule must run once per activation, never overlap a run
+	//      still going, and stay quiet while disabled.
+	// =========================================================================
+	It("should start due, enabled schedules that are not running", func() {
+		publish("rollup", `[{"name": "every", "cron": "*/5 * * * *"}, {"name": "off", "cron": "* * * * *", "disabled": true}]`)
+		Expect(srv.schedules.sync()).To(Succeed())
+
+		Expect(srv.schedules.due(now)).To(BeEmpty())
+		now = time.Date(2026, 10, 16, 10, 20, 0, 0, time.UTC)
+		Expect(srv.schedules.due(now)).To(Equal([]scheduleKey{{"rollup", "every"}}))
+
+		// Still running at the next activation
+		now = now.Add(5 * time.Minute)
+		Expect(srv.schedules.due(now)).To(BeEmpty())
+		info, _ := srv.schedules.get("rollup", "every")
+		Expect(info.Next).To(Equal(time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)))
+	})
+
+	It("should skip schedules paused for maintenance", func() {
+		publish("rollup", `[{"name": "every", "cron": "* * * * *"}]`)
+		Expect(srv.schedules.sync()).To(Succeed())
+		srv.maintenance.now = func() time.Time { return now }
+		_, err := srv.maintenance.add(MaintenanceRequest{Plugin: "rollup", DurationSeconds: 120})
+		Expect(err).NotTo(HaveOccurred())
+
+		now = now.Add(time.Minute)
+		Expect(srv.schedules.due(now)).To(BeEmpty())
+		now = now.Add(2 * time.Minute)
+		Expect(srv.schedules.due(now)).To(HaveLen(1))
+	})
+
+	It("should record the outcome of a run", func() {
+		publish("rollup", `[{"name": "every", "cron": "* * * * *"}]`)
+		Expect(srv.schedules.sync()).To(Succeed())
+		now = now.Add(time.Minute)
+		Expect(srv.schedules.due(now)).To(HaveLen(1))
+
+		// The binary is not a valid module, so the run fails
+		srv.schedules.fire(GinkgoT().Context(), scheduleKey{"rollup", "every"})
+
+		info, _ := srv.schedules.get("rollup", "every")
+		Expect(info.LastRun).To(Equal(now))
+		Expect(info.LastRequestID).NotTo(BeEmpty())
+		Expect(info.LastError).NotTo(BeEmpty())
+		Expect(srv.schedules.due(info.Next)).To(HaveLen(1))
+	})
+
+	Describe("/plugins/{name}/schedules", func() {
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			srv.handlePlugin(rec, req)
+			return rec
+		}
+
+		problemCode := func(rec *httptest.ResponseRecorder) apierror.Code {
+			var problem apierror.Problem
+			Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed())
+			return problem.Code
+		}
+
+		BeforeEach(func() {
+			publish("rollup", `[{"name": "nightly", "cron": "0 2 * * *"}]`)
+			Expect(srv.schedules.sync()).To(Succeed())
+		})
+
+		// =====================================================================
+		// TEST: Enable and disable
+		// Why: Operators pause a misbehaving schedule without republishing
+		//      the plugin, and the decision must survive a new build.
+		// =====================================================================
+		It("should disable and re-enable a schedule", func() {
+			rec := send(http.MethodPut, "/plugins/rollup/schedules/nightly", `{"enabled": false}`)
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			Expect(rec.Body.String()).To(MatchJSON(`{"plugin": "rollup", "name": "nightly", "cron": "0 2 * * *", "enabled": false}`))
+
+			publish("rollup", `[{"name": "nightly", "cron": "0 2 * * *"}]`)
+			Expect(srv.schedules.sync()).To(Succeed())
+			rec = send(http.MethodGet, "/plugins/rollup/schedules", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`[{"plugin": "rollup", "name": "nightly", "cron": "0 2 * * *", "enabled": false}]`))
+
+			rec = send(http.MethodPut, "/plugins/rollup/schedules/nightly", `{"enabled": true}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			rec = send(http.MethodGet, "/plugins/rollup/schedules/nightly", "")
+			Expect(rec.Body.String()).To(MatchJSON(`{"plugin": "rollup", "name": "nightly", "cron": "0 2 * * *", "enabled": true,
+				"next": "2026-10-17T02:00:00Z"}`))
+		})
+
+		It("should return 404 for an unknown schedule or plugin", func() {
+			rec := send(http.MethodPut, "/plugins/rollup/schedules/weekly", `{"enabled": true}`)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(problemCode(rec)).To(Equal(apierror.CodeScheduleNotFound))
+
+			rec = send(http.MethodGet, "/plugins/missing/schedules", "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(problemCode(rec)).To(Equal(apierror.CodePluginNotFound))
+		})
+
+		It("should reject requests while the server does not run schedules", func() {
+			srv.schedules = nil
+			rec := send(http.MethodGet, "/plugins/rollup/schedules", "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(problemCode(rec)).To(Equal(apierror.CodeScheduleNotFound))
+		})
+
+		It("should require the admin token", func() {
+			req := httptest.NewRequest(http.MethodPut, "/plugins/rollup/schedules/nightly", bytes.NewBufferString(`{"enabled": false}`))
+			rec := httptest.NewRecorder()
+			srv.handlePlugin(rec, req)
+
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			info, _ := srv.schedules.get("rollup", "nightly")
+			Expect(info.Enabled).To(BeTrue())
+		})
+
+		It("should only allow PUT on a single schedule", func() {
+			rec := send(http.MethodPut, "/plugins/rollup/schedules", `{"enabled": false}`)
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// Outcomes of a sampled execution, as ShadowMismatch.Outcome and the
+// outcome label of plugin_shadow_runs_total.
+const (
+	shadowMatch    = "match"    // The shadow engine returned the same output
+	shadowMismatch = "mismatch" // It returned a different output
+	shadowError    = "error"    // It failed to load, initialize, or run the plugin
+	shadowSkipped  = "skipped"  // Too many shadow runs were in flight to start one
+)
+
+// maxShadowMismatches bounds the mismatches and errors kept for
+// GET /debug/shadow.
+const maxShadowMismatches = 50
+
+// ShadowReport is the state of execution shadowing.
+type ShadowReport struct {
+	Engine     string           `json:"engine"`
+	Ratio      float64          `json:"ratio"`
+	Plugins    []ShadowStats    `json:"plugins"`
+	Mismatches []ShadowMismatch `json:"mismatches"` // Most recent first
+}
+
+// ShadowStats counts the shadow runs of a plugin. The latencies are the
+// mean call times of the runs that were compared, on either engine.
+type ShadowStats struct {
+	Plugin     string  `json:"plugin"`
+	Matches    int64   `json:"matches"`
+	Mismatches int64   `json:"mismatches"`
+	Errors     int64   `json:"errors"`
+	Skipped    int64   `json:"skipped"`
+	PrimaryMs  float64 `json:"primary_ms"`
+	ShadowMs   float64 `json:"shadow_ms"`
+
+	primary, shadow time.Duration // Total call times of compared runs
+}
+
+// ShadowMismatch is a sampled execution the shadow engine did not
+// reproduce.
+type ShadowMismatch struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Plugin    string    `json:"plugin"`
+	Outcome   string    `json:"outcome"`          // mismatch or error
+	Primary   string    `json:"primary"`          // Output of the primary engine, as JSON
+	Shadow    string    `json:"shadow,omitempty"` // Output of the shadow engine, as JSON
+	Error     string    `json:"error,omitempty"`  // Why the shadow run failed
+}
+
+// shadower runs a sample of the successful executions of some plugins on
+// a second engine as well, to build confidence in it before it runs them:
+// it records whether the engine returned the same output and how long the
+// call took on either. Shadow runs happen in the background, bounded in
+// number, and never affect the response.
+//
+// The shadow instance's init() and call get the same host APIs, made to
+// skip their side effects with runtime.WithoutSideEffects, so that a
+// shadowed execution does not publish, store, or write twice; outbox
+// effects are recorded but never delivered. Host modules of
+// host.modules_file run as usual. The execution's timeout bounds init()
+// and the call together.
+type shadower struct {
+	engine  runtime.Engine
+	ratio   float64
+	plugins map[string]bool
+	options func(name string) (runtime.PoolOptions, error) // Of the plugin's pools
+	logger  *slog.Logger
+	slots   chan struct{}
+	sample  func() float64 // In [0, 1); tests replace it
+
+	mu         sync.Mutex
+	stats      map[string]*ShadowStats // By plugin name
+	mismatches []ShadowMismatch        // Oldest first
+	running    sync.WaitGroup
+}
+
+// newShadower creates a shadower running up to maxConcurrent shadow runs
+// of plugins at once on engine.
+func newShadower(engine runtime.Engine, ratio float64, plugins []string, maxConcurrent int, options func(string) (runtime.PoolOptions, error), logger *slog.Logger) *shadower {
+	sh := &shadower{
+		engine:  engine,
+		ratio:   ratio,
+		plugins: make(map[string]bool, len(plugins)),
+		options: options,
+		logger:  logger,
+		slots:   make(chan struct{}, maxConcurrent),
+		sample:  rand.Float64,
+		stats:   make(map[string]*ShadowStats),
+	}
+	for _, name := range plugins {
+		sh.plugins[name] = true
+	}
+	return sh
+}
+
+// maybeRun starts a shadow run of a successful execution of the plugin
+// called name if it is sampled; outbox tells whether the call had one.
+func (sh *shadower) maybeRun(name, path string, req Request, call runtime.CallInfo, primary Response, timeout time.Duration, outbox bool) {
+	if !sh.plugins[name] || sh.sample() >= sh.ratio {
+		return
+	}
+	select {
+	case sh.slots <- struct{}{}:
+	default:
+		sh.record(name, shadowSkipped, 0, 0, nil)
+		return
+	}
+	sh.running.Add(1)
+	go func() {
+		defer func() {
+			<-sh.slots
+			sh.running.Done()
+		}()
+		sh.run(name, path, req, call, primary, timeout, outbox)
+	}()
+}
+
+// run loads the plugin on the shadow engine, repeats the call, and
+// records the outcome.
+func (sh *shadower) run(name, path string, req Request, call runtime.CallInfo, primary Response, timeout time.Duration, outbox bool) {
+	mismatch := ShadowMismatch{Time: time.Now().UTC(), RequestID: call.RequestID, Plugin: req.Plugin, Primary: shadowOutput(primary)}
+	fail := func(err error) {
+		mismatch.Outcome, mismatch.Error = shadowError, err.Error()
+		sh.record(name, shadowError, 0, 0, &mismatch)
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx = runtime.WithCallInfo(ctx, call)
+	ctx = runtime.WithOutputCapture(ctx, runtime.NewOutputCapture(0))
+	ctx = runtime.WithoutSideEffects(ctx, runtime.NewSkippedEffects())
+	if outbox {
+		ctx = runtime.WithOutbox(ctx, runtime.NewOutbox())
+	}
+
+	opts, err := sh.options(name)
+	if err != nil {
+		fail(err)
+		return
+	}
+	instance, err := sh.engine.Load(path, opts.LoadOptions())
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer instance.Close()
+
+	m, _ := manifest.ForPlugin(path)
+	config, withConfig, err := opts.InitConfig(m)
+	if err == nil && withConfig {
+		err = instance.InitWithConfigContext(ctx, config)
+	} else if err == nil {
+		err = instance.InitContext(ctx)
+	}
+	if err == nil {
+		err = req.checkSupport(instance)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	started := time.Now()
+	resp, err := invoke(ctx, instance, req)
+	elapsed := time.Since(started)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	mismatch.Shadow = shadowOutput(resp)
+	if mismatch.Shadow == mismatch.Primary {
+		sh.record(name, shadowMatch, primary.elapsed, elapsed, nil)
+		return
+	}
+	mismatch.Outcome = shadowMismatch
+	sh.record(name, shadowMismatch, primary.elapsed, elapsed, &mismatch)
+}
+
+// shadowOutput returns the output of a response as JSON, in the form the
+// request asked for, so that outputs of both engines can be compared.
+func shadowOutput(resp Response) string {
+	data, _ := json.Marshal(struct {
+		Output  *int            `json:"output"`
+		Text    *string         `json:"text,omitempty"`
+		Data    []byte          `json:"data,omitempty"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+		Fields  map[string]any  `json:"fields,omitempty"`
+	}{resp.Output, resp.Text, resp.Data, resp.Payload, resp.Fields})
+	return string(data)
+}
+
+// record counts the outcome of a shadow run, and keeps and logs it if the
+// shadow engine did not reproduce the execution.
+func (sh *shadower) record(name, outcome string, primary, shadow time.Duration, mismatch *ShadowMismatch) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	stats, ok := sh.stats[name]
+	if !ok {
+		stats = &ShadowStats{Plugin: name}
+		sh.stats[name] = stats
+	}
+	switch outcome {
+	case shadowMatch:
+		stats.Matches++
+	case shadowMismatch:
+		stats.Mismatches++
+	case shadowError:
+		stats.Errors++
+	case shadowSkipped:
+		stats.Skipped++
+	}
+	if outcome == shadowMatch || outcome == shadowMismatch {
+		stats.primary += primary
+		stats.shadow += shadow
+	}
+
+	if mismatch == nil {
+		return
+	}
+	if len(sh.mismatches) == maxShadowMismatches {
+		sh.mismatches = sh.mismatches[1:]
+	}
+	sh.mismatches = append(sh.mismatches, *mismatch)
+	sh.logger.Warn("shadow engine did not reproduce an execution",
+		slog.String("engine", sh.engine.Name()),
+		slog.String("plugin", mismatch.Plugin),
+		slog.String("request_id", mismatch.RequestID),
+		slog.String("outcome", mismatch.Outcome),
+		slog.String("primary", mismatch.Primary),
+		slog.String("shadow", mismatch.Shadow),
+		slog.String("error", mismatch.Error))
+}
+
+// report returns the counts by plugin and the recent mismatches.
+func (sh *shadower) report() ShadowReport {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	report := ShadowReport{Engine: sh.engine.Name(), Ratio: sh.ratio, Plugins: []ShadowStats{}, Mismatches: []ShadowMismatch{}}
+	for _, stats := range sh.stats {
+		s := *stats
+		if compared := s.Matches + s.Mismatches; compared > 0 {
+			s.PrimaryMs = float64(s.primary.Microseconds()) / 1000 / float64(compared)
+			s.ShadowMs = float64(s.shadow.Microseconds()) / 1000 / float64(compared)
+		}
+		report.Plugins = append(report.Plugins, s)
+	}
+	sort.Slice(report.Plugins, func(i, j int) bool { return report.Plugins[i].Plugin < report.Plugins[j].Plugin })
+	for i := len(sh.mismatches) - 1; i >= 0; i-- {
+		report.Mismatches = append(report.Mismatches, sh.mismatches[i])
+	}
+	return report
+}
+
+// collectMetrics reports the shadow runs by plugin and outcome, and the
+// call time of compared runs on either engine.
+func (sh *shadower) collectMetrics() []metrics.Family {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	names := make([]string, 0, len(sh.stats))
+	for name := range sh.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runs := metrics.Family{Name: "plugin_shadow_runs_total", Help: "Sampled executions repeated on the shadow engine, by outcome.", Type: metrics.TypeCounter}
+	seconds := metrics.Family{Name: "plugin_shadow_call_seconds_total", Help: "Call time of executions compared on the shadow engine, by engine run.", Type: metrics.TypeCounter}
+	for _, name := range names {
+		s := sh.stats[name]
+		for _, outcome := range []struct {
+			name  string
+			count int64
+		}{{shadowMatch, s.Matches}, {shadowMismatch, s.Mismatches}, {shadowError, s.Errors}, {shadowSkipped, s.Skipped}} {
+			runs.Samples = append(runs.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "plugin", Value: s.Plugin}, {Name: "outcome", Value: outcome.name}},
+				Value:  float64(outcome.count),
+			})
+		}
+		for _, run := range []struct {
+			name  string
+			total time.Duration
+		}{{"primary", s.primary}, {"shadow", s.shadow}} {
+			seconds.Samples = append(seconds.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "plugin", Value: s.Plugin}, {Name: "run", Value: run.name}},
+				Value:  run.total.Seconds(),
+			})
+		}
+	}
+	return []metrics.Family{runs, seconds}
+}
+
+// wait blocks until the shadow runs in flight have finished.
+func (sh *shadower) wait() {
+	sh.running.Wait()
+}
+
+// collectShadowMetrics reports execution shadowing, if enabled.
+func (s *Server) collectShadowMetrics() []metrics.Family {
+	if s.shadow == nil {
+		return nil
+	}
+	return s.shadow.collectMetrics()
+}
+
+// newShadowerFromConfig creates the shadower of shadow.engine, which
+// must be registered with runtime.RegisterEngine.
+func (s *Server) newShadowerFromConfig(engine string, ratio float64, plugins []string, maxConcurrent int) (*shadower, error) {
+	shadowEngine, err := runtime.LookupEngine(engine)
+	if err != nil {
+		return nil, fmt.Errorf("%w; registered: %v", err, runtime.RegisteredEngines())
+	}
+	return newShadower(shadowEngine, ratio, plugins, maxConcurrent, s.pluginPoolOptions, s.logger), nil
+}
+
+// handleDebugShadow handles GET /debug/shadow
+//
+// Returns how often sampled executions matched on the shadow engine, by
+// plugin, and the most recent ones that did not. Without shadow.engine the
+// endpoint is disabled.
+func (s *Server) handleDebugShadow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.shadow == nil {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "execution shadowing is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.shadow.report())
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	"github.com/mrhapile/wasm-plugin-system/runtime/wazero"
+)
+
+// scaleEngine loads instances whose process() multiplies its input by
+// factor and whose process_bytes() upper-cases it, or fails to load with
+// err.
+type scaleEngine struct {
+	factor int
+	err    error
+}
+
+func (scaleEngine) Name() string { return "scale" }
+
+func (e scaleEngine) Load(string, runtime.LoadOptions) (runtime.Instance, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &scaleInstance{factor: e.factor}, nil
+}
+
+// scaleInstance implements the calls the shadow specs make; others panic.
+type scaleInstance struct {
+	runtime.Instance
+	factor int
+}
+
+func (i *scaleInstance) Init() error                                         { return nil }
+func (i *scaleInstance) InitContext(context.Context) error                   { return nil }
+func (i *scaleInstance) InitWithConfig([]byte) error                         { return nil }
+func (i *scaleInstance) InitWithConfigContext(context.Context, []byte) error { return nil }
+func (i *scaleInstance) SupportsPayloads() bool                              { return true }
+func (i *scaleInstance) Close()                                              {}
+func (i *scaleInstance) ExecuteContext(_ context.Context, input int) (int, error) {
+	return input * i.factor, nil
+}
+func (i *scaleInstance) ExecuteBytesContext(_ context.Context, input []byte) ([]byte, error) {
+	return bytes.ToUpper(input), nil
+}
+
+// publishEngine loads instances whose init() publishes to the "doubled"
+// topic through topics, as the publish() host function does, and whose
+// process() publishes its input there and returns it doubled.
+type publishEngine struct {
+	topics *runtime.Topics
+}
+
+func (publishEngine) Name() string { return "publish" }
+
+func (e publishEngine) Load(string, runtime.LoadOptions) (runtime.Instance, error) {
+	return &publishInstance{scaleInstance: scaleInstance{factor: 2}, topics: e.topics}, nil
+}
+
+type publishInstance struct {
+	scaleInstance
+	topics *runtime.Topics
+}
+
+func (i *publishInstance) InitContext(ctx context.Context) error {
+	return i.topics.Publish(ctx, "double", "doubled", []byte("init"))
+}
+
+func (i *publishInstance) ExecuteContext(ctx context.Context, input int) (int, error) {
+	if err := i.topics.Publish(ctx, "double", "doubled", []byte(strconv.Itoa(input))); err != nil {
+		return 0, err
+	}
+	return i.scaleInstance.ExecuteContext(ctx, input)
+}
+
+// countingPublisher counts the messages it is given by topic.
+type countingPublisher struct {
+	mu       sync.Mutex
+	messages map[string]int
+}
+
+func (p *countingPublisher) Publish(_ context.Context, topic string, _ []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages[topic]++
+	return nil
+}
+
+var _ = Describe("Execution shadowing", func() {
+	var (
+		logs    *bytes.Buffer
+		sampled float64
+	)
+
+	shadow := func(engine runtime.Engine) *shadower {
+		logs = &bytes.Buffer{}
+		options := func(string) (runtime.PoolOptions, error) { return runtime.PoolOptions{}, nil }
+		sh := newShadower(engine, 0.5, []string{"double"}, 1, options, slog.New(slog.NewJSONHandler(logs, nil)))
+		sh.sample = func() float64 { return sampled }
+		return sh
+	}
+	output := func(n int) Response { return Response{Output: &n} }
+
+	BeforeEach(func() {
+		sampled = 0.1
+	})
+
+	// =========================================================================
+	// TEST: Comparison
+	// Why: A second engine is only trusted for a plugin once it returns what
+	//      the primary did for real traffic; differences must be kept with
+	//      enough detail to reproduce them.
+	// =========================================================================
+	It("should count executions the shadow engine reproduces", func() {
+		sh := shadow(scaleEngine{factor: 2})
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double", Input: 2}, runtime.CallInfo{RequestID: "r1"}, output(4), 0, false)
+		sh.wait()
+
+		report := sh.report()
+		Expect(report.Engine).To(Equal("scale"))
+		Expect(report.Plugins).To(ConsistOf(HaveField("Matches", int64(1))))
+		Expect(report.Mismatches).To(BeEmpty())
+		Expect(logs.String()).To(BeEmpty())
+	})
+
+	It("should keep and log mismatches and errors", func() {
+		sh := shadow(scaleEngine{factor: 3})
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double", Input: 2}, runtime.CallInfo{RequestID: "r1"}, output(4), 0, false)
+		sh.wait()
+		text := "hi"
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double@1.0.0", Text: &text}, runtime.CallInfo{RequestID: "r2"}, Response{Output: new(int), Text: &text}, 0, false)
+		sh.wait()
+
+		report := sh.report()
+		Expect(report.Plugins).To(ConsistOf(And(HaveField("Mismatches", int64(2)), HaveField("Plugin", "double"))))
+		Expect(report.Mismatches).To(HaveLen(2))
+		Expect(report.Mismatches[0].RequestID).To(Equal("r2"))
+		Expect(report.Mismatches[0].Plugin).To(Equal("double@1.0.0"))
+		Expect(report.Mismatches[0].Shadow).To(ContainSubstring(`"text":"HI"`))
+		Expect(report.Mismatches[1]).To(And(
+			HaveField("Outcome", shadowMismatch),
+			HaveField("Primary", `{"output":4}`),
+			HaveField("Shadow", `{"output":6}`)))
+		Expect(logs.String()).To(ContainSubstring(`"request_id":"r1"`))
+
+		sh = shadow(scaleEngine{err: errors.New("unknown import")})
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double"}, runtime.CallInfo{}, output(0), 0, false)
+		sh.wait()
+		Expect(sh.report().Mismatches).To(ConsistOf(And(HaveField("Outcome", shadowError), HaveField("Error", "unknown import"))))
+	})
+
+	// =========================================================================
+	// TEST: Side effects
+	// Why: The primary execution already made its effects; repeating them
+	//      on the shadow engine would deliver every sampled event twice.
+	// =========================================================================
+	It("should not deliver what a shadowed init() or call publishes twice", func() {
+		publisher := &countingPublisher{messages: map[string]int{}}
+		topics := runtime.NewTopics(publisher, map[string][]string{"double": {"doubled"}})
+		Expect(topics.Publish(context.Background(), "double", "doubled", []byte("2"))).To(Succeed()) // The primary call
+
+		sh := shadow(publishEngine{topics: topics})
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double", Input: 2}, runtime.CallInfo{RequestID: "r1"}, output(4), 0, false)
+		sh.wait()
+
+		Expect(sh.report().Plugins).To(ConsistOf(HaveField("Matches", int64(1))))
+		Expect(publisher.messages).To(Equal(map[string]int{"doubled": 1}))
+	})
+
+	// =========================================================================
+	// TEST: Sampling and load
+	// Why: Shadowing doubles the work of what it samples; it must stay
+	//      within the configured plugins, ratio, and concurrency.
+	// =========================================================================
+	It("should only shadow sampled executions of listed plugins", func() {
+		sh := shadow(scaleEngine{factor: 2})
+		sh.maybeRun("upper", "upper.wasm", Request{Plugin: "upper"}, runtime.CallInfo{}, output(0), 0, false)
+		sampled = 0.5
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double"}, runtime.CallInfo{}, output(0), 0, false)
+		sh.wait()
+		Expect(sh.report().Plugins).To(BeEmpty())
+
+		sh.slots <- struct{}{}
+		sampled = 0.1
+		sh.maybeRun("double", "double.wasm", Request{Plugin: "double"}, runtime.CallInfo{}, output(0), 0, false)
+		Expect(sh.report().Plugins).To(ConsistOf(HaveField("Skipped", int64(1))))
+	})
+
+	It("should export metrics and serve /debug/shadow", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		get := func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			srv.handleDebugShadow(rec, httptest.NewRequest(http.MethodGet, "/debug/shadow", nil))
+			return rec
+		}
+		Expect(get().Code).To(Equal(http.StatusMethodNotAllowed))
+
+		srv.shadow = shadow(scaleEngine{factor: 2})
+		srv.shadow.maybeRun("double", "double.wasm", Request{Plugin: "double", Input: 1}, runtime.CallInfo{}, output(2), 0, false)
+		srv.shadow.wait()
+
+		rec := get()
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var report ShadowReport
+		Expect(json.Unmarshal(rec.Body.Bytes(), &report)).To(Succeed())
+		Expect(report.Ratio).To(Equal(0.5))
+		Expect(report.Plugins).To(HaveLen(1))
+
+		var text strings.Builder
+		Expect(srv.metrics.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(ContainSubstring(`plugin_shadow_runs_total{plugin="double",outcome="match"} 1`))
+		Expect(text.String()).To(ContainSubstring(`plugin_shadow_call_seconds_total{plugin="double",run="shadow"}`))
+	})
+
+	It("should shadow on wazero, which the server includes", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		s, err := srv.newShadowerFromConfig("wazero", 0.1, []string{"double"}, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.engine).To(Equal(wazero.Engine))
+	})
+
+	It("should refuse engines that are not registered", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		_, err := srv.newShadowerFromConfig("wasmer", 0.1, []string{"double"}, 1)
+		Expect(err).To(MatchError(ContainSubstring("registered: [")))
+	})
+})
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+// tracer records the server's spans: one per /run request or Execute
+// call, and the store.Resolve span of each run. The runtime adds the
+// plugin's Load, Init, Execute, and Cleanup spans below them.
+var tracer = tracing.Tracer("github.com/mrhapile/wasm-plugin-system/cmd/server")
+
+// propagator continues the caller's trace from its W3C traceparent and
+// tracestate headers or metadata.
+var propagator = propagation.TraceContext{}
+
+// startRequestSpan starts the server span of a run, continuing the trace
+// carried by carrier.
+func startRequestSpan(ctx context.Context, name string, carrier propagation.TextMapCarrier, requestID string) (context.Context, trace.Span) {
+	ctx = propagator.Extract(ctx, carrier)
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(tracing.AttrRequestID.String(requestID)))
+}
+
+// annotateRequestSpan adds the plugin and input size of req to the span
+// in ctx.
+func annotateRequestSpan(ctx context.Context, req Request) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(tracing.AttrPlugin.String(req.Plugin))
+	switch {
+	case req.Text != nil:
+		span.SetAttributes(tracing.AttrInputSize.Int(len(*req.Text)))
+	case req.Data != nil:
+		span.SetAttributes(tracing.AttrInputSize.Int(len(req.Data)))
+	case req.Payload != nil:
+		span.SetAttributes(tracing.AttrInputSize.Int(len(req.Payload)))
+	case req.Fields != nil:
+		span.SetAttributes(tracing.AttrInputSize.Int(len(req.Fields)))
+	}
+}
+
+// endRequestSpan ends a server span, recording the apierror code of err.
+func endRequestSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetAttributes(tracing.AttrErrorCode.String(string(apierror.CodeOf(err))))
+	}
+	tracing.End(span, err)
+}
+
+// resolve resolves name in tenant's namespace of the store, in a
+// store.Resolve span, which shows how much of a run a Fluid or object
+// store lookup takes.
+func (s *Server) resolve(ctx context.Context, tenant, name string) (string, error) {
+	_, span := tracer.Start(ctx, "store.Resolve", trace.WithAttributes(tracing.AttrPlugin.String(name)))
+	path, err := fluid.ResolveIn(s.store, tenant, name)
+	tracing.End(span, err)
+	return path, err
+}
+
+// metadataCarrier adapts incoming gRPC metadata to the propagator.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return firstValue(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
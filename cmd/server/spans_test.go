@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+// spanRecorder collects exported spans.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(_ context.Context, spans []tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+var _ = Describe("Tracing", func() {
+	// =========================================================================
+	// TEST: Request spans
+	// Why: A caller's trace must continue into the server, and a failed run
+	//      must be findable by its error code in Jaeger or Tempo.
+	// =========================================================================
+	It("should continue the caller's trace and record the error code", func() {
+		recorder := &spanRecorder{}
+		provider := tracing.NewProvider(recorder, tracing.Options{SampleRatio: 1})
+		tracing.SetTracerProvider(provider)
+		DeferCleanup(tracing.SetTracerProvider, noop.NewTracerProvider())
+
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(`{"plugin": "missing", "text": "hello"}`))
+		req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		req.Header.Set(RequestIDHeader, "req-1")
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(provider.Shutdown(context.Background())).To(Succeed())
+
+		Expect(recorder.spans).To(HaveLen(2))
+		resolve, run := recorder.spans[0], recorder.spans[1]
+		Expect(resolve.Name).To(Equal("store.Resolve"))
+		Expect(resolve.Parent.SpanID()).To(Equal(run.SpanContext.SpanID()))
+
+		Expect(run.Name).To(Equal("POST /run"))
+		Expect(run.Kind).To(Equal(trace.SpanKindServer))
+		Expect(run.SpanContext.TraceID().String()).To(Equal("0af7651916cd43dd8448eb211c80319c"))
+		Expect(run.Parent.SpanID().String()).To(Equal("b7ad6b7169203331"))
+		Expect(run.Status).To(Equal(codes.Error))
+		Expect(run.Attributes).To(ContainElements(
+			tracing.AttrRequestID.String("req-1"),
+			tracing.AttrPlugin.String("missing"),
+			tracing.AttrInputSize.Int(5),
+			tracing.AttrErrorCode.String(string(apierror.CodePluginNotFound)),
+		))
+	})
+})
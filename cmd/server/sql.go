@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// sqlConfig is the SQL_CONFIG_FILE format: the databases plugins may
+// reach, the statements they may run on them, and which plugin may run
+// which statement.
+//
+//	{
+//	  "databases": {"crm": {"driver": "postgres", "dsn_env": "CRM_DSN"}},
+//	  "statements": {
+//	    "customer_by_id": {"database": "crm", "query": "SELECT id, name FROM customers WHERE id = $1"},
+//	    "touch_customer": {"database": "crm", "exec": "UPDATE customers SET seen = now() WHERE id = $1", "timeout_ms": 500}
+//	  },
+//	  "plugins": {"enrich": ["customer_by_id"], "audit": ["crm.*"]}
+//	}
+type sqlConfig struct {
+	Databases  map[string]sqlDatabaseConfig  `json:"databases"`
+	Statements map[string]sqlStatementConfig `json:"statements"`
+	Plugins    map[string][]string           `json:"plugins"` // Statement names, or "<database>.*", by plugin
+}
+
+// sqlDatabaseConfig is a database connection. The DSN is given inline or,
+// to keep credentials out of the file, in the environment variable DSNEnv.
+type sqlDatabaseConfig struct {
+	Driver       string `json:"driver"`
+	DSN          string `json:"dsn,omitempty"`
+	DSNEnv       string `json:"dsn_env,omitempty"`
+	MaxOpenConns int    `json:"max_open_conns,omitempty"` // Default 4
+}
+
+// sqlStatementConfig is a statement plugins may run: a query, returning
+// rows, or an exec, returning the number of rows affected.
+type sqlStatementConfig struct {
+	Database  string `json:"database"`
+	Query     string `json:"query,omitempty"`
+	Exec      string `json:"exec,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // Default 5000
+}
+
+// Defaults of SQL_CONFIG_FILE settings.
+const (
+	defaultSQLMaxOpenConns = 4
+	defaultSQLTimeout      = 5 * time.Second
+)
+
+// loadSQLModule reads SQL_CONFIG_FILE at path, opens its databases,
+// prepares its statements, and returns the SQL host module together with
+// the databases, which the caller closes on shutdown. getenv looks up
+// dsn_env variables.
+//
+// Drivers are not part of the server: they register themselves with
+// database/sql, so the binary must link them in, or a Go plugin from
+// HOST_MODULES_FILE, which is loaded first, must import them.
+func loadSQLModule(path string, getenv func(string) string) (*runtime.HostModule, []*sql.DB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg sqlConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var dbs []*sql.DB
+	fail := func(err error) (*runtime.HostModule, []*sql.DB, error) {
+		for _, db := range dbs {
+			db.Close()
+		}
+		return nil, nil, err
+	}
+
+	// Sorted, so the first failure reported is the same on every start
+	opened := make(map[string]*sql.DB, len(cfg.Databases))
+	for _, name := range sortedKeys(cfg.Databases) {
+		dbCfg := cfg.Databases[name]
+		if !slices.Contains(sql.Drivers(), dbCfg.Driver) {
+			return fail(fmt.Errorf("database %s: driver %q is not registered; link it into the server or load it from a HOST_MODULES_FILE plugin", name, dbCfg.Driver))
+		}
+		dsn := dbCfg.DSN
+		if dbCfg.DSNEnv != "" {
+			if dsn = getenv(dbCfg.DSNEnv); dsn == "" {
+				return fail(fmt.Errorf("database %s: %s is not set", name, dbCfg.DSNEnv))
+			}
+		}
+		db, err := sql.Open(dbCfg.Driver, dsn)
+		if err != nil {
+			return fail(fmt.Errorf("database %s: %w", name, err))
+		}
+		dbs = append(dbs, db)
+		maxOpen := dbCfg.MaxOpenConns
+		if maxOpen == 0 {
+			maxOpen = defaultSQLMaxOpenConns
+		}
+		db.SetMaxOpenConns(maxOpen)
+		db.SetMaxIdleConns(maxOpen)
+		opened[name] = db
+	}
+
+	statements := make(map[string]runtime.SQLStatement, len(cfg.Statements))
+	for _, name := range sortedKeys(cfg.Statements) {
+		stmtCfg := cfg.Statements[name]
+		text := stmtCfg.Query
+		if stmtCfg.Exec != "" {
+			text = stmtCfg.Exec
+		}
+		// Preparing checks the statement against the database at startup
+		stmt, err := opened[stmtCfg.Database].Prepare(text)
+		if err != nil {
+			return fail(fmt.Errorf("statement %s: %w", name, err))
+		}
+		timeout := defaultSQLTimeout
+		if stmtCfg.TimeoutMs > 0 {
+			timeout = time.Duration(stmtCfg.TimeoutMs) * time.Millisecond
+		}
+		statements[name] = runtime.SQLStatement{
+			Database: stmtCfg.Database,
+			Stmt:     stmt,
+			Exec:     stmtCfg.Exec != "",
+			Timeout:  timeout,
+		}
+	}
+
+	return runtime.NewSQLModule(runtime.NewSQLCatalog(statements, cfg.Plugins)), dbs, nil
+}
+
+// validate checks that the configuration is complete and that every
+// statement and grant refers to something declared.
+func (c *sqlConfig) validate() error {
+	if len(c.Databases) == 0 {
+		return errors.New("no databases declared")
+	}
+	for name, db := range c.Databases {
+		switch {
+		case !isValidPluginName(name) || strings.Contains(name, "@"):
+			return fmt.Errorf("database name %q must contain only letters, digits, '_' and '-'", name)
+		case db.Driver == "":
+			return fmt.Errorf("database %s has no driver", name)
+		case (db.DSN == "") == (db.DSNEnv == ""):
+			return fmt.Errorf("database %s needs exactly one of dsn and dsn_env", name)
+		case db.MaxOpenConns < 0:
+			return fmt.Errorf("database %s: max_open_conns must not be negative", name)
+		}
+	}
+	for name, stmt := range c.Statements {
+		switch {
+		case name == "" || strings.HasSuffix(name, ".*"):
+			return fmt.Errorf("invalid statement name %q", name)
+		case !c.hasDatabase(stmt.Database):
+			return fmt.Errorf("statement %s: unknown database %q", name, stmt.Database)
+		case (stmt.Query == "") == (stmt.Exec == ""):
+			return fmt.Errorf("statement %s needs exactly one of query and exec", name)
+		case stmt.TimeoutMs < 0:
+			return fmt.Errorf("statement %s: timeout_ms must not be negative", name)
+		}
+	}
+	for plugin, grants := range c.Plugins {
+		if !isValidPluginName(plugin) || strings.Contains(plugin, "@") {
+			return fmt.Errorf("invalid plugin name %q", plugin)
+		}
+		for _, grant := range grants {
+			if database, ok := strings.CutSuffix(grant, ".*"); ok {
+				if !c.hasDatabase(database) {
+					return fmt.Errorf("plugin %s: unknown database %q", plugin, database)
+				}
+			} else if _, ok := c.Statements[grant]; !ok {
+				return fmt.Errorf("plugin %s: unknown statement %q", plugin, grant)
+			}
+		}
+	}
+	return nil
+}
+
+// hasDatabase reports whether the database name is declared.
+func (c *sqlConfig) hasDatabase(name string) bool {
+	_, ok := c.Databases[name]
+	return ok
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// prepareDriver is a database/sql driver that only prepares statements,
+// failing those that mention missing_table as a database would.
+type prepareDriver struct{}
+
+func init() {
+	sql.Register("server-fake", prepareDriver{})
+}
+
+func (prepareDriver) Open(string) (driver.Conn, error) { return prepareConn{}, nil }
+
+type prepareConn struct{}
+
+func (prepareConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "missing_table") {
+		return nil, errors.New(`relation "missing_table" does not exist`)
+	}
+	return preparedStmt{}, nil
+}
+func (prepareConn) Close() error              { return nil }
+func (prepareConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type preparedStmt struct{}
+
+func (preparedStmt) Close() error  { return nil }
+func (preparedStmt) NumInput() int { return -1 }
+func (preparedStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (preparedStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var _ = Describe("loadSQLModule", func() {
+	writeConfig := func(contents string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "sql.json")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	getenv := func(name string) string {
+		if name == "CRM_DSN" {
+			return "crm"
+		}
+		return ""
+	}
+
+	It("should prepare the declared statements", func() {
+		module, dbs, err := loadSQLModule(writeConfig(`{
+			"databases": {"crm": {"driver": "server-fake", "dsn_env": "CRM_DSN"}},
+			"statements": {
+				"customer_by_id": {"database": "crm", "query": "SELECT name FROM customers WHERE id = $1"},
+				"touch_customer": {"database": "crm", "exec": "UPDATE customers SET seen = now()", "timeout_ms": 500}
+			},
+			"plugins": {"enrich": ["customer_by_id"], "audit": ["crm.*"]}
+		}`), getenv)
+		Expect(err).NotTo(HaveOccurred())
+		defer dbs[0].Close()
+
+		Expect(module.Name()).To(Equal(runtime.SQLModule))
+		Expect(dbs).To(HaveLen(1))
+	})
+
+	// =========================================================================
+	// TEST: SQL configuration errors
+	// Why: A statement a plugin cannot run, or a grant that names nothing,
+	//      would only surface as failed calls; the server must refuse to
+	//      start instead.
+	// =========================================================================
+	DescribeTable("should reject invalid configurations",
+		func(contents, message string) {
+			_, _, err := loadSQLModule(writeConfig(contents), getenv)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("no databases", `{}`, "no databases"),
+		Entry("unregistered driver",
+			`{"databases": {"crm": {"driver": "oracle", "dsn": "x"}}}`, `driver "oracle" is not registered`),
+		Entry("missing DSN variable",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn_env": "UNSET"}}}`, "UNSET is not set"),
+		Entry("both DSNs",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn": "x", "dsn_env": "CRM_DSN"}}}`, "exactly one of dsn and dsn_env"),
+		Entry("unknown database",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn": "x"}},
+			  "statements": {"s": {"database": "orders", "query": "SELECT 1"}}}`, `unknown database "orders"`),
+		Entry("query and exec",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn": "x"}},
+			  "statements": {"s": {"database": "crm", "query": "SELECT 1", "exec": "DELETE FROM t"}}}`, "exactly one of query and exec"),
+		Entry("unknown statement grant",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn": "x"}},
+			  "plugins": {"enrich": ["customer_by_id"]}}`, `unknown statement "customer_by_id"`),
+		Entry("unknown database grant",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn": "x"}},
+			  "plugins": {"enrich": ["orders.*"]}}`, `unknown database "orders"`),
+		Entry("statement the database rejects",
+			`{"databases": {"crm": {"driver": "server-fake", "dsn": "x"}},
+			  "statements": {"s": {"database": "crm", "query": "SELECT * FROM missing_table"}}}`, "statement s"),
+	)
+})
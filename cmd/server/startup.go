@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// StartupReport describes how the server booted: the plugin store, the
+// engine, the features the configuration enabled, and where it listens.
+// It is logged once when the server is ready and served at
+// GET /debug/startup, so automation can verify a boot without parsing
+// log lines.
+type StartupReport struct {
+	Started time.Time            `json:"started"` // When the listeners were bound
+	Version string               `json:"version"` // Module version of the build, "(devel)" for local builds
+	Store   StoreReport          `json:"store"`
+	Engine  runtime.Capabilities `json:"engine"`
+	Limits  Features             `json:"limits"` // Execution limits in force, as at GET /version
+
+	// Features are the optional features the configuration enabled, in
+	// the order they were set up
+	Features []FeatureReport `json:"features"`
+
+	// Warmup lists the plugins loaded before the server started serving,
+	// in load order
+	Warmup []WarmupResult `json:"warmup"`
+
+	Listen    ListenReport `json:"listen"`
+	Endpoints []string     `json:"endpoints"` // "METHOD /path" of every HTTP endpoint
+}
+
+// StoreReport is the plugin store the server loads plugins from.
+type StoreReport struct {
+	Type     string `json:"type"`               // fluid, s3, http, local, memory, or a registered backend
+	Location string `json:"location,omitempty"` // Directory, bucket, or base URL
+	Cache    string `json:"cache,omitempty"`    // Download cache directory of remote stores
+}
+
+// FeatureReport is an optional feature the configuration enabled.
+type FeatureReport struct {
+	Name   string `json:"name"`   // Stable identifier, e.g. "tracing"
+	Detail string `json:"detail"` // How it is configured, for people
+}
+
+// WarmupResult is the outcome of loading one plugin before serving.
+type WarmupResult struct {
+	Plugin   string        `json:"plugin"`
+	Duration time.Duration `json:"duration_ns"`
+	Compiled bool          `json:"compiled"`        // The instance runs AOT-compiled code
+	Error    string        `json:"error,omitempty"` // Empty if the plugin loaded
+}
+
+// Readiness is the response of GET /readyz.
+type Readiness struct {
+	Ready  bool           `json:"ready"`  // Every plugin of the preload list was warmed up
+	Warmup []WarmupResult `json:"warmup"` // As in the startup report
+}
+
+// ListenReport holds the addresses the server listens on.
+type ListenReport struct {
+	HTTP string `json:"http"`
+	GRPC string `json:"grpc"`
+	TLS  bool   `json:"tls"` // Both APIs serve TLS only
+}
+
+// newStartupReport returns an empty report of this build.
+func newStartupReport() *StartupReport {
+	return &StartupReport{
+		Version:  buildVersion(),
+		Features: []FeatureReport{},
+		Warmup:   []WarmupResult{},
+	}
+}
+
+// enable records the feature name, described by format and args.
+func (r *StartupReport) enable(name, format string, args ...any) {
+	r.Features = append(r.Features, FeatureReport{Name: name, Detail: fmt.Sprintf(format, args...)})
+}
+
+// warmup resolves each plugin in refs, and creates its pool with at
+// least one idle instance, compiled ahead of time if the server has an
+// AOT cache, so the first requests for it find the instance ready. A
+// plugin that fails is reported and left to be loaded on demand.
+func (s *Server) warmup(refs []string) []WarmupResult {
+	results := make([]WarmupResult, 0, len(refs))
+	for _, ref := range refs {
+		started := time.Now()
+		result := WarmupResult{Plugin: ref}
+		compiled, err := s.warmupPlugin(ref)
+		result.Duration = time.Since(started)
+		result.Compiled = compiled
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// warmupPlugin warms up the plugin ref refers to, and reports whether its
+// instances are compiled.
+func (s *Server) warmupPlugin(ref string) (bool, error) {
+	if err := checkPluginName(ref); err != nil {
+		return false, err
+	}
+	path, err := s.store.Resolve(ref)
+	if err != nil {
+		return false, err
+	}
+	// Unlike on a request, the reset strategy is measured before serving
+	pool, err := s.openPool(ref, path, false)
+	if err != nil {
+		return false, err
+	}
+
+	// A pool with MinSize zero creates its first instance on checkout
+	instance, err := pool.GetInstance(context.Background())
+	if err != nil {
+		return false, err
+	}
+	plugin, ok := instance.(*runtime.Plugin)
+	compiled := ok && plugin.Compiled()
+	pool.PutInstance(context.Background(), instance)
+	return compiled, nil
+}
+
+// handleReadyz serves GET /readyz: 200 if every plugin of the preload list
+// was warmed up, 503 otherwise, with the warm-up results either way. The
+// server only listens once warm-up is over, so a probe that cannot
+// connect means it is still warming up.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	readiness := Readiness{Ready: true, Warmup: []WarmupResult{}}
+	if s.startup != nil {
+		readiness.Warmup = s.startup.Warmup
+	}
+	for _, result := range readiness.Warmup {
+		readiness.Ready = readiness.Ready && result.Error == ""
+	}
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, readiness)
+}
+
+// handleDebugStartup serves GET /debug/startup.
+func (s *Server) handleDebugStartup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.startup)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("GET /debug/startup", func() {
+	// =========================================================================
+	// TEST: Startup report
+	// Why: Deployment automation verifies a boot from this report: which
+	//      store and features it came up with and where it listens. Empty
+	//      lists must be present, so a missing feature is told apart from a
+	//      field the server does not report.
+	// =========================================================================
+	It("should serve the report of the boot", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		report := newStartupReport()
+		report.Store = StoreReport{Type: "local", Location: "./plugins"}
+		report.enable("tracing", "Exporting traces to %s (sample ratio %g)", "http://collector:4318", 0.5)
+		report.Listen = ListenReport{HTTP: "127.0.0.1:8080", GRPC: "127.0.0.1:9090"}
+		srv.startup = report
+
+		rec := httptest.NewRecorder()
+		srv.handleDebugStartup(rec, httptest.NewRequest(http.MethodGet, "/debug/startup", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var body map[string]json.RawMessage
+		Expect(json.Unmarshal(rec.Body.Bytes(), &body)).To(Succeed())
+		Expect(body["store"]).To(MatchJSON(`{"type": "local", "location": "./plugins"}`))
+		Expect(body["features"]).To(MatchJSON(`[{"name": "tracing", "detail": "Exporting traces to http://collector:4318 (sample ratio 0.5)"}]`))
+		Expect(body["warmup"]).To(MatchJSON(`[]`))
+		Expect(body["listen"]).To(MatchJSON(`{"http": "127.0.0.1:8080", "grpc": "127.0.0.1:9090", "tls": false}`))
+		Expect(body).To(HaveKey("version"))
+		Expect(body).To(HaveKey("engine"))
+		Expect(body).To(HaveKey("limits"))
+	})
+
+	It("should reject other methods", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		srv.startup = newStartupReport()
+
+		rec := httptest.NewRecorder()
+		srv.handleDebugStartup(rec, httptest.NewRequest(http.MethodPost, "/debug/startup", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
+
+var _ = Describe("Warm-up", func() {
+	readiness := func(srv *Server) (int, Readiness) {
+		rec := httptest.NewRecorder()
+		srv.handleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body Readiness
+		Expect(json.Unmarshal(rec.Body.Bytes(), &body)).To(Succeed())
+		return rec.Code, body
+	}
+
+	// =========================================================================
+	// TEST: Preload list
+	// Why: Plugins warmed up at boot must have a pool with an idle instance
+	//      before the first request. One that cannot be warmed up must keep
+	//      the server from being reported ready, not from starting.
+	// =========================================================================
+	It("should pool the plugins of the preload list", func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "hello", "hello.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: hello.wasm")
+		}
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.startup = newStartupReport()
+		srv.startup.Warmup = srv.warmup([]string{"hello"})
+
+		Expect(srv.startup.Warmup).To(HaveLen(1))
+		Expect(srv.startup.Warmup[0].Error).To(BeEmpty())
+		Expect(srv.poolInfos()).To(ConsistOf(HaveField("Plugin", "hello")))
+		Expect(srv.poolInfos()[0].Idle).To(BeNumerically(">=", 1))
+
+		code, body := readiness(srv)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body.Ready).To(BeTrue())
+	})
+
+	It("should share one pool creation between concurrent requests", func() {
+		pluginsDir := filepath.Join("..", "..", "plugins")
+		if _, err := os.Stat(filepath.Join(pluginsDir, "hello", "hello.wasm")); os.IsNotExist(err) {
+			Skip("Test plugin not found: hello.wasm")
+		}
+		srv := NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		path, err := srv.store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		pools := make(chan *runtime.Pool, 8)
+		for i := 0; i < cap(pools); i++ {
+			go func() {
+				defer GinkgoRecover()
+				pool, err := srv.pool("hello", path)
+				Expect(err).NotTo(HaveOccurred())
+				pools <- pool
+			}()
+		}
+		first := <-pools
+		for i := 1; i < cap(pools); i++ {
+			Expect(<-pools).To(BeIdenticalTo(first))
+		}
+		Expect(srv.poolInfos()).To(HaveLen(1))
+	})
+
+	It("should report plugins that could not be warmed up as not ready", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		srv.startup = newStartupReport()
+		srv.startup.Warmup = srv.warmup([]string{"missing", "../etc"})
+
+		Expect(srv.startup.Warmup).To(HaveLen(2))
+		Expect(srv.startup.Warmup[0].Plugin).To(Equal("missing"))
+		Expect(srv.startup.Warmup[0].Error).NotTo(BeEmpty())
+		Expect(srv.startup.Warmup[1].Error).To(ContainSubstring("invalid plugin name"))
+		Expect(srv.poolInfos()).To(BeEmpty())
+
+		code, body := readiness(srv)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(body.Ready).To(BeFalse())
+		Expect(body.Warmup).To(HaveLen(2))
+	})
+
+	It("should be ready without a preload list", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+		code, body := readiness(srv)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(Equal(Readiness{Ready: true, Warmup: []WarmupResult{}}))
+	})
+})
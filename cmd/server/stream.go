@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	"github.com/mrhapile/wasm-plugin-system/websocket"
+)
+
+// DefaultStreamIdleTimeout closes /run/ws connections that sent no message
+// for that long, returning their instance to the pool.
+const DefaultStreamIdleTimeout = 5 * time.Minute
+
+// StreamMessage is a message a client sends on /run/ws: one input for the
+// stream's plugin, in the input forms of POST /run.
+type StreamMessage struct {
+	ID    string  `json:"id,omitempty"`   // Echoed in the result; defaults to the message's sequence number
+	Input int     `json:"input"`          // Integer input to pass to process()
+	Text  *string `json:"text,omitempty"` // UTF-8 payload for process_bytes()
+	Data  []byte  `json:"data,omitempty"` // Binary payload for process_bytes() (base64 in JSON)
+
+	// Payload is a JSON document for process_json()
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// Fields is an object of typed fields for process_pb()
+	Fields json.RawMessage `json:"fields,omitempty"`
+
+	// TimeoutMs shortens the server's execution timeout for this call
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// IncludeLogs returns the messages the plugin logged during the call
+	IncludeLogs bool `json:"include_logs,omitempty"`
+}
+
+// StreamResult is the message the server sends back for each
+// StreamMessage, in the order they were received: the /run response, or
+// the problem if the call failed.
+type StreamResult struct {
+	ID string `json:"id"`
+	*Response
+	Error *apierror.Problem `json:"error,omitempty"`
+}
+
+// stream is a /run/ws connection, running its messages one after another
+// on the same instance of its plugin.
+type stream struct {
+	server *Server
+	conn   *websocket.Conn
+	locale language.Tag
+	path   string // Path of the handshake request, the problems' instance
+
+	plugin     string // Plugin reference, as requested
+	name       string // Plugin name, without the version
+	pluginPath string
+	call       runtime.CallInfo
+	pool       *runtime.Pool
+
+	// instance is checked out for the whole connection; nil after a call
+	// failed, until the next message checks out a fresh one
+	instance runtime.Instance
+	// unreserve gives back the tenant memory the instance reserves
+	unreserve func()
+
+	// release gives back the execution slots the stream holds
+	release func()
+}
+
+// handleStream handles GET /run/ws, a WebSocket connection streaming
+// inputs to a single initialized instance of a plugin
+//
+// Connection lifecycle:
+//  1. Validate the plugin named by the plugin and version query parameters
+//  2. Resolve it and check out an instance from its pool, holding one
+//     execution slot for the connection
+//  3. Upgrade to WebSocket
+//  4. Run each StreamMessage on the instance and send its StreamResult
+//  5. Return the instance to the pool when the connection closes
+//
+// Unlike POST /run, the instance is not reset between messages, so
+// high-frequency callers skip the per-call init and cleanup; a plugin
+// keeps whatever state it builds up for the stream's lifetime. A call that
+// fails discards the instance, and the next message gets a fresh one.
+//
+// Everything that fails before the upgrade is reported as an HTTP
+// problem response; failed calls are reported in their StreamResult and
+// leave the connection open.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDOf(r.Header.Get(RequestIDHeader))
+	w.Header().Set(RequestIDHeader, requestID)
+
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !websocket.IsUpgrade(r) {
+		writeError(w, r, apierror.CodeInvalidRequest, "expected a WebSocket handshake")
+		return
+	}
+
+	req := Request{Plugin: r.URL.Query().Get("plugin"), Version: r.URL.Query().Get("version")}
+	notePlugin(r.Context(), req.Plugin)
+	if err := s.checkRequest(&req); err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+	call, err := callInfoOf(r.Context(), requestID, r.Header.Get(TenantHeader), r.Header.Get(CallerHeader))
+	if err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+
+	st, err := s.openStream(r.Context(), req.Plugin, call)
+	if err != nil {
+		writeExecutionError(w, r, err)
+		return
+	}
+	defer st.close()
+
+	conn, err := websocket.Upgrade(w, r, websocket.Options{MaxMessageSize: s.streamMaxMessage})
+	if err != nil {
+		writeError(w, r, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	st.conn = conn
+	st.locale = apierror.MatchLocale(r.Header.Get("Accept-Language"))
+	st.path = r.URL.Path
+
+	// Hijacked connections outlive the request context's cancellation
+	// checks; the idle timeout and per-call timeouts bound them instead
+	st.serve(r.Context())
+}
+
+// openStream checks out an instance of plugin for a stream, after the
+// checks of a /run request. The execution slot it takes is held until
+// the stream is closed.
+func (s *Server) openStream(ctx context.Context, plugin string, call runtime.CallInfo) (*stream, error) {
+	name, _ := fluid.ParseReference(plugin)
+	if err := s.checkPluginAccess(ctx, name); err != nil {
+		return nil, err
+	}
+	if err := s.maintenance.check(name, call.Tenant); err != nil {
+		return nil, err
+	}
+
+	pluginPath, err := s.resolve(ctx, call.Tenant, plugin)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.CodePluginNotFound, fmt.Errorf("plugin not found: %s", plugin))
+	}
+	pool, err := s.pool(plugin, pluginPath)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.CodePluginLoadFailed,
+			fmt.Errorf("failed to load plugin: %w", err))
+	}
+
+	releasePlugin, err := s.limiter.acquire(name)
+	if err != nil {
+		return nil, err
+	}
+	releaseTenant, err := s.tenants.acquire(call.Tenant)
+	if err != nil {
+		releasePlugin()
+		return nil, err
+	}
+	release := func() {
+		releaseTenant()
+		releasePlugin()
+	}
+	instance, err := pool.GetInstance(ctx)
+	if err != nil {
+		release()
+		return nil, apierror.Wrap(apierror.CodePluginInitFailed,
+			fmt.Errorf("failed to initialize plugin: %w", err))
+	}
+	unreserve, err := s.reserveInstance(call.Tenant, instance)
+	if err != nil {
+		pool.PutInstance(ctx, instance)
+		release()
+		return nil, err
+	}
+	return &stream{
+		server:     s,
+		plugin:     plugin,
+		name:       name,
+		pluginPath: pluginPath,
+		call:       call,
+		pool:       pool,
+		instance:   instance,
+		unreserve:  unreserve,
+		release:    release,
+	}, nil
+}
+
+// serve runs the stream's messages until the client closes the
+// connection or stays idle for longer than the idle timeout.
+func (st *stream) serve(ctx context.Context) {
+	for seq := 1; ; seq++ {
+		if st.server.streamIdleTimeout > 0 {
+			st.conn.SetReadDeadline(time.Now().Add(st.server.streamIdleTimeout))
+		}
+		_, data, err := st.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			switch {
+			case errors.As(err, &closeErr), errors.Is(err, websocket.ErrMessageTooBig):
+				// The connection has already been closed
+				st.conn.Close(websocket.CloseNormal, "")
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				st.conn.Close(websocket.CloseGoingAway, "idle timeout")
+			default:
+				st.conn.Close(websocket.CloseProtocolError, "")
+			}
+			return
+		}
+
+		result := StreamResult{ID: strconv.Itoa(seq)}
+		var msg StreamMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			result.Error = st.problem(apierror.Wrap(apierror.CodeInvalidRequest, fmt.Errorf("invalid JSON: %w", err)))
+		} else {
+			if msg.ID != "" {
+				result.ID = msg.ID
+			}
+			call := st.call
+			call.RequestID = st.call.RequestID + "-" + strconv.Itoa(seq)
+			resp, err := st.run(ctx, msg, call)
+			if err != nil {
+				result.Error = st.problem(err)
+			} else {
+				result.Response = &resp
+			}
+		}
+
+		out, _ := json.Marshal(result)
+		if err := st.conn.WriteMessage(websocket.TextMessage, out); err != nil {
+			st.conn.Close(websocket.CloseGoingAway, "")
+			return
+		}
+	}
+}
+
+// run executes one message on the stream's instance, checking out a fresh
+// one if the previous call failed.
+func (st *stream) run(ctx context.Context, msg StreamMessage, call runtime.CallInfo) (Response, error) {
+	s := st.server
+	req := Request{
+		Plugin:      st.plugin,
+		Input:       msg.Input,
+		Text:        msg.Text,
+		Data:        msg.Data,
+		Payload:     msg.Payload,
+		Fields:      msg.Fields,
+		TimeoutMs:   msg.TimeoutMs,
+		IncludeLogs: msg.IncludeLogs,
+	}
+	if err := req.checkInput(); err != nil {
+		return Response{}, err
+	}
+	if req.TimeoutMs < 0 {
+		return Response{}, apierror.Wrap(apierror.CodeInvalidRequest,
+			errors.New("timeout_ms must not be negative"))
+	}
+
+	// Every message counts against the rate limits, and a maintenance
+	// window starting during the stream pauses it
+	if err := s.checkRateLimit(ctx, st.name, call.Tenant); err != nil {
+		return Response{}, err
+	}
+	if err := s.maintenance.check(st.name, call.Tenant); err != nil {
+		return Response{}, err
+	}
+
+	if st.instance == nil {
+		instance, err := st.pool.GetInstance(ctx)
+		if err != nil {
+			return Response{}, apierror.Wrap(apierror.CodePluginInitFailed,
+				fmt.Errorf("failed to initialize plugin: %w", err))
+		}
+		unreserve, err := s.reserveInstance(call.Tenant, instance)
+		if err != nil {
+			st.pool.PutInstance(ctx, instance)
+			return Response{}, err
+		}
+		st.instance, st.unreserve = instance, unreserve
+	}
+	if err := req.checkSupport(st.instance); err != nil {
+		return Response{}, err
+	}
+
+	if timeout := s.timeout(req); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx = runtime.WithCallInfo(ctx, call)
+	capture := runtime.NewLogCapture(call.RequestID)
+	ctx = runtime.WithLogCapture(ctx, capture)
+	output := runtime.NewOutputCapture(s.maxOutput)
+	ctx = runtime.WithOutputCapture(ctx, output)
+	var outbox *runtime.Outbox
+	if s.outbox != nil {
+		outbox = runtime.NewOutbox()
+		ctx = runtime.WithOutbox(ctx, outbox)
+	}
+
+	resp, err := invoke(ctx, st.instance, req)
+	s.logOutput(call.RequestID, req.Plugin, output)
+	if err != nil {
+		// The instance may be in a broken state - never reuse it
+		st.pool.DiscardInstance(ctx, st.instance)
+		st.instance = nil
+		st.unreserve()
+		return Response{}, s.executionError(req, err)
+	}
+	resp.Warnings = diagnose(st.instance)
+
+	if outbox != nil {
+		effects := outbox.Effects()
+		if err := s.outbox.commit(call.RequestID, delegationOf(ctx, st.name), effects); err != nil {
+			return Response{}, apierror.Wrap(apierror.CodeInternal,
+				fmt.Errorf("failed to commit plugin effects: %w", err))
+		}
+		resp.Effects = len(effects)
+	}
+	if req.IncludeLogs {
+		resp.Logs = capture.Entries()
+	}
+	resp.Version = fluid.BuildVersion(st.pluginPath)
+	return resp, nil
+}
+
+// problem describes the error of a failed message in the client's
+// language.
+func (st *stream) problem(err error) *apierror.Problem {
+	problem := apierror.NewLocalized(apierror.CodeOf(err), err.Error(), st.locale)
+	problem.Instance = st.path
+	problem.PluginError = pluginErrorOf(err)
+	return problem
+}
+
+// close returns the stream's instance to the pool, reset for the next
+// request, and gives its execution slot back.
+func (st *stream) close() {
+	if st.instance != nil {
+		st.pool.PutInstance(context.Background(), st.instance)
+		st.instance = nil
+		st.unreserve()
+	}
+	st.release()
+}
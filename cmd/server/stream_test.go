@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/websocket"
+)
+
+var _ = Describe("Streams", func() {
+	var (
+		srv *Server
+		ts  *httptest.Server
+	)
+
+	BeforeEach(func() {
+		pluginsDir := GinkgoT().TempDir()
+		dir := filepath.Join(pluginsDir, "hello")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		// Not a valid module, so every stream fails to load it
+		Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+		srv.logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+		srv.limiter = newLimiter(1, 0)
+		ts = httptest.NewServer(http.HandlerFunc(srv.handleStream))
+		DeferCleanup(ts.Close)
+	})
+
+	// dial opens a stream with the query, returning the problem the server
+	// refused it with.
+	dial := func(query string) *apierror.Problem {
+		url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/run/ws?" + query
+		conn, resp, err := websocket.Dial(context.Background(), url, nil, websocket.Options{})
+		if err == nil {
+			conn.Close(websocket.CloseNormal, "")
+		}
+		Expect(err).To(MatchError(websocket.ErrBadHandshake))
+		defer resp.Body.Close()
+		var problem apierror.Problem
+		Expect(json.NewDecoder(resp.Body).Decode(&problem)).To(Succeed())
+		Expect(problem.Status).To(Equal(resp.StatusCode))
+		return &problem
+	}
+
+	// =========================================================================
+	// TEST: Opening a stream
+	// Why: A stream's plugin is checked out before the upgrade, so clients
+	//      get the same problem responses as from POST /run for streams
+	//      that cannot run, and a failed stream must not keep its slot.
+	// =========================================================================
+	It("should refuse streams of missing and unloadable plugins", func() {
+		Expect(dial("").Code).To(Equal(apierror.CodeMissingPluginName))
+		Expect(dial("plugin=../etc").Code).To(Equal(apierror.CodeInvalidPluginName))
+		Expect(dial("plugin=missing").Code).To(Equal(apierror.CodePluginNotFound))
+
+		// Each attempt gave its execution slot back
+		for range 2 {
+			Expect(dial("plugin=hello").Code).To(Equal(apierror.CodePluginLoadFailed))
+		}
+	})
+
+	It("should refuse streams of plugins the client may not run", func() {
+		req := httptest.NewRequest(http.MethodGet, "/run/ws?plugin=hello", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req = req.WithContext(withPrincipal(req.Context(), &principal{Name: "ci", Plugins: []string{"other"}}))
+		rec := httptest.NewRecorder()
+		srv.handleStream(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("should reject requests that are no WebSocket handshake", func() {
+		resp, err := ts.Client().Get(ts.URL + "/run/ws?plugin=hello")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err = ts.Client().Post(ts.URL+"/run/ws?plugin=hello", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// TenantQuota is what the requests of one tenant may use together, across
+// all plugins and clients. Zero fields do not limit.
+type TenantQuota struct {
+	// Rate limits the tenant's requests, like a client's
+	RateLimit
+
+	// MaxConcurrent is how many of its executions may run at once
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+
+	// MaxMemoryMiB is how much linear memory its running executions may
+	// reserve together. Each reserves the most its instance can grow to.
+	MaxMemoryMiB int `json:"max_memory_mib,omitempty"`
+}
+
+// tenantConfig is the TENANTS_FILE format: the quota every tenant gets by
+// default, and those of single tenants.
+//
+//	{
+//	  "default": {"rate": 50, "burst": 100, "max_concurrent": 8, "max_memory_mib": 512},
+//	  "tenants": {"acme": {"rate": 200, "max_concurrent": 32, "max_memory_mib": 2048}}
+//	}
+type tenantConfig struct {
+	Default TenantQuota            `json:"default"`
+	Tenants map[string]TenantQuota `json:"tenants"`
+}
+
+// validate checks the quotas.
+func (c *tenantConfig) validate() error {
+	check := func(name string, q TenantQuota) error {
+		if q.Rate < 0 || q.Burst < 0 || math.IsInf(q.Rate, 0) || math.IsNaN(q.Rate) {
+			return fmt.Errorf("%s: rate and burst must not be negative", name)
+		}
+		if q.MaxConcurrent < 0 || q.MaxMemoryMiB < 0 {
+			return fmt.Errorf("%s: max_concurrent and max_memory_mib must not be negative", name)
+		}
+		return nil
+	}
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for tenant, q := range c.Tenants {
+		if !validID(tenant) {
+			return fmt.Errorf("tenants: invalid tenant %q", tenant)
+		}
+		if err := check("tenants."+tenant, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantQuotas enforces the quotas of tenants. Requests without a tenant
+// share the default quota, as if they all came from one tenant.
+type tenantQuotas struct {
+	cfg tenantConfig
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	running   map[string]int   // Executions by tenant; tenants without any are dropped
+	reserved  map[string]int64 // Memory reserved by tenant, in bytes
+
+	rejectedRate        metrics.Counter
+	rejectedConcurrency metrics.Counter
+	rejectedMemory      metrics.Counter
+	now                 func() time.Time
+}
+
+// loadTenants reads TENANTS_FILE at path.
+func loadTenants(path string) (*tenantQuotas, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg tenantConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return newTenantQuotas(cfg), nil
+}
+
+// newTenantQuotas creates an enforcer of the quotas of cfg.
+func newTenantQuotas(cfg tenantConfig) *tenantQuotas {
+	return &tenantQuotas{
+		cfg:      cfg,
+		buckets:  make(map[string]*tokenBucket),
+		running:  make(map[string]int),
+		reserved: make(map[string]int64),
+		now:      time.Now,
+	}
+}
+
+// quota returns the quota of tenant.
+func (q *tenantQuotas) quota(tenant string) TenantQuota {
+	if quota, ok := q.cfg.Tenants[tenant]; ok {
+		return quota
+	}
+	return q.cfg.Default
+}
+
+// admit returns a CodeForbidden error for a tenant that TENANTS_FILE does
+// not list, unless the principal of ctx is bound to it: a tenant is
+// otherwise only a header, and a client naming a new one with every
+// request would get a fresh default quota each time.
+func (q *tenantQuotas) admit(ctx context.Context, tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+	if _, ok := q.cfg.Tenants[tenant]; ok {
+		return nil
+	}
+	if p, ok := principalOf(ctx); ok && p.Tenant == tenant {
+		return nil
+	}
+	return apierror.Wrap(apierror.CodeForbidden,
+		fmt.Errorf("tenant %s is not configured; only principals bound to it may act on its behalf", tenant))
+}
+
+// allow admits the tenant of a request made with ctx and takes a token
+// from its bucket, or returns a CodeRateLimited error telling the client
+// when the next one is due. A nil q allows everything.
+func (q *tenantQuotas) allow(ctx context.Context, tenant string) error {
+	if q == nil {
+		return nil
+	}
+	if err := q.admit(ctx, tenant); err != nil {
+		return err
+	}
+	limit := q.quota(tenant).RateLimit
+	if limit.Rate == 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	if now.Sub(q.lastPrune) >= rateLimitPruneInterval {
+		q.lastPrune = now
+		for id, bucket := range q.buckets {
+			if bucket.full(now) {
+				delete(q.buckets, id)
+			}
+		}
+	}
+	bucket, ok := q.buckets[tenant]
+	if !ok {
+		bucket = newTokenBucket(limit, now)
+		q.buckets[tenant] = bucket
+	}
+	wait, ok := bucket.take(now)
+	if ok {
+		return nil
+	}
+	q.rejectedRate.Inc()
+	return apierror.WrapRetry(apierror.CodeRateLimited,
+		fmt.Errorf("%s exceeded its rate limit of %g requests per second", tenantLabel(tenant), limit.Rate), wait)
+}
+
+// acquire takes one of tenant's execution slots, or returns a
+// CodeTooManyExecutions error if all are taken. The returned function
+// gives the slot back. A nil q has unlimited slots.
+func (q *tenantQuotas) acquire(tenant string) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+	limit := q.quota(tenant).MaxConcurrent
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limit > 0 && q.running[tenant] >= limit {
+		q.rejectedConcurrency.Inc()
+		return nil, apierror.WrapRetry(apierror.CodeTooManyExecutions,
+			fmt.Errorf("%s already runs %d executions, the most allowed", tenantLabel(tenant), limit), LimitRetryAfter)
+	}
+	q.running[tenant]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			if q.running[tenant]--; q.running[tenant] <= 0 {
+				delete(q.running, tenant)
+			}
+		})
+	}, nil
+}
+
+// reserve counts bytes of linear memory against tenant's quota while an
+// execution runs, or returns a CodeTooManyExecutions error if the
+// tenant's other executions hold too much to fit them. An execution that
+// would not fit even on its own fails with CodeMemoryLimitExceeded. The
+// returned function gives the memory back. A nil q has unlimited memory.
+func (q *tenantQuotas) reserve(tenant string, bytes int64) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+	limit := int64(q.quota(tenant).MaxMemoryMiB) << 20
+	if limit == 0 {
+		return func() {}, nil
+	}
+	if bytes > limit {
+		q.rejectedMemory.Inc()
+		return nil, apierror.Wrap(apierror.CodeMemoryLimitExceeded,
+			fmt.Errorf("the instance may grow to %d MiB, more than the %d MiB of %s; cap its memory_pages", bytes>>20, limit>>20, tenantLabel(tenant)))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.reserved[tenant]+bytes > limit {
+		q.rejectedMemory.Inc()
+		return nil, apierror.WrapRetry(apierror.CodeTooManyExecutions,
+			fmt.Errorf("%s already holds %d of its %d MiB of memory", tenantLabel(tenant), q.reserved[tenant]>>20, limit>>20), LimitRetryAfter)
+	}
+	q.reserved[tenant] += bytes
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			if q.reserved[tenant] -= bytes; q.reserved[tenant] <= 0 {
+				delete(q.reserved, tenant)
+			}
+		})
+	}, nil
+}
+
+// reserveInstance reserves the memory instance may grow to for an
+// execution on behalf of tenant.
+func (s *Server) reserveInstance(tenant string, instance runtime.Instance) (func(), error) {
+	if s.tenants == nil {
+		return func() {}, nil
+	}
+	return s.tenants.reserve(tenant, instance.MaxMemoryBytes())
+}
+
+// collectMetrics reports the tenants' running executions and reserved
+// memory, and the requests their quotas rejected.
+func (q *tenantQuotas) collectMetrics() []metrics.Family {
+	running := metrics.Family{Name: "plugin_tenant_executions_running", Help: "Plugin executions in progress, by tenant.", Type: metrics.TypeGauge}
+	reserved := metrics.Family{Name: "plugin_tenant_memory_reserved_bytes", Help: "Linear memory reserved by running executions, by tenant.", Type: metrics.TypeGauge}
+	q.mu.Lock()
+	for _, tenant := range sortedKeys(q.running) {
+		running.Samples = append(running.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "tenant", Value: tenant}},
+			Value:  float64(q.running[tenant]),
+		})
+	}
+	for _, tenant := range sortedKeys(q.reserved) {
+		reserved.Samples = append(reserved.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "tenant", Value: tenant}},
+			Value:  float64(q.reserved[tenant]),
+		})
+	}
+	q.mu.Unlock()
+
+	return []metrics.Family{
+		running,
+		reserved,
+		{Name: "plugin_tenant_quota_rejected_total", Help: "Requests rejected by a tenant quota, by quota.", Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: []metrics.Label{{Name: "quota", Value: "rate"}}, Value: float64(q.rejectedRate.Value())},
+				{Labels: []metrics.Label{{Name: "quota", Value: "concurrency"}}, Value: float64(q.rejectedConcurrency.Value())},
+				{Labels: []metrics.Label{{Name: "quota", Value: "memory"}}, Value: float64(q.rejectedMemory.Value())},
+			}},
+	}
+}
+
+// tenantLabel names tenant in errors. Requests without a tenant share the
+// default one.
+func tenantLabel(tenant string) string {
+	if tenant == "" {
+		return "the default tenant"
+	}
+	return "tenant " + tenant
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+var _ = Describe("Tenant quotas", func() {
+	var (
+		now time.Time
+		q   *tenantQuotas
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		q = newTenantQuotas(tenantConfig{
+			Default: TenantQuota{RateLimit: RateLimit{Rate: 1, Burst: 2}, MaxConcurrent: 1, MaxMemoryMiB: 64},
+			Tenants: map[string]TenantQuota{"acme": {MaxConcurrent: 2, MaxMemoryMiB: 128}},
+		})
+		q.now = func() time.Time { return now }
+	})
+
+	// =========================================================================
+	// TEST: Tenant quotas
+	// Why: One tenant must not be able to use up the server for the others,
+	//      however many clients and plugins it spreads its requests over.
+	// =========================================================================
+	It("should limit the request rate of each tenant", func() {
+		globex := withPrincipal(context.Background(), &principal{Name: "globex-ci", Tenant: "globex"})
+		Expect(q.allow(globex, "globex")).To(Succeed())
+		Expect(q.allow(globex, "globex")).To(Succeed())
+		err := q.allow(globex, "globex")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeRateLimited))
+		Expect(apierror.RetryAfterOf(err)).To(Equal(time.Second))
+
+		// acme has no rate limit
+		for i := 0; i < 5; i++ {
+			Expect(q.allow(context.Background(), "acme")).To(Succeed())
+		}
+
+		now = now.Add(time.Second)
+		Expect(q.allow(globex, "globex")).To(Succeed())
+	})
+
+	// =========================================================================
+	// TEST: Requests without a tenant
+	// Why: The tenant is a header the client sets. Leaving it out, or naming
+	//      a tenant nobody configured, must not get around the quotas.
+	// =========================================================================
+	It("should charge requests without a tenant to one default bucket", func() {
+		ctx := context.Background()
+		Expect(q.allow(ctx, "")).To(Succeed())
+		Expect(q.allow(ctx, "")).To(Succeed())
+		err := q.allow(ctx, "")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeRateLimited))
+		Expect(err.Error()).To(ContainSubstring("the default tenant"))
+
+		release, err := q.acquire("")
+		Expect(err).NotTo(HaveOccurred())
+		defer release()
+		_, err = q.acquire("")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTooManyExecutions))
+
+		_, err = q.reserve("", 65<<20)
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeMemoryLimitExceeded))
+	})
+
+	It("should refuse tenants that are neither configured nor bound to the principal", func() {
+		for i := 0; i < 3; i++ {
+			err := q.allow(context.Background(), fmt.Sprintf("tenant-%d", i))
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeForbidden))
+		}
+		other := withPrincipal(context.Background(), &principal{Name: "ci", Tenant: "acme"})
+		Expect(apierror.CodeOf(q.allow(other, "globex"))).To(Equal(apierror.CodeForbidden))
+	})
+
+	It("should limit the concurrent executions of each tenant", func() {
+		release, err := q.acquire("globex")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.acquire("globex")
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTooManyExecutions))
+		Expect(apierror.RetryAfterOf(err)).To(Equal(LimitRetryAfter))
+
+		for i := 0; i < 2; i++ {
+			_, err = q.acquire("acme")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err = q.acquire("acme")
+		Expect(err).To(HaveOccurred())
+
+		// Releasing twice gives back one slot only
+		release()
+		release()
+		_, err = q.acquire("globex")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.acquire("globex")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should limit the memory the executions of each tenant reserve", func() {
+		unreserve, err := q.reserve("globex", 48<<20)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.reserve("globex", 32<<20)
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeTooManyExecutions))
+
+		// An instance larger than the whole quota can never run
+		_, err = q.reserve("globex", 65<<20)
+		Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeMemoryLimitExceeded))
+
+		unreserve()
+		_, err = q.reserve("globex", 64<<20)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = q.reserve("acme", 128<<20)
+		Expect(err).NotTo(HaveOccurred())
+
+		families := q.collectMetrics()
+		Expect(families[1].Samples).To(HaveLen(2))
+		Expect(families[1].Samples[0].Value).To(Equal(float64(128 << 20)))
+		Expect(families[2].Samples[2].Value).To(Equal(float64(2)))
+	})
+
+	It("should not limit anything without TENANTS_FILE", func() {
+		var none *tenantQuotas
+		Expect(none.allow(context.Background(), "globex")).To(Succeed())
+		release, err := none.acquire("acme")
+		Expect(err).NotTo(HaveOccurred())
+		release()
+		unreserve, err := none.reserve("acme", 1<<40)
+		Expect(err).NotTo(HaveOccurred())
+		unreserve()
+	})
+
+	DescribeTable("should reject invalid configuration",
+		func(body string) {
+			path := filepath.Join(GinkgoT().TempDir(), "tenants.json")
+			Expect(os.WriteFile(path, []byte(body), 0644)).To(Succeed())
+			_, err := loadTenants(path)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("that is no JSON", `{`),
+		Entry("with a negative rate", `{"default": {"rate": -1}}`),
+		Entry("with negative memory", `{"tenants": {"acme": {"max_memory_mib": -1}}}`),
+		Entry("with an invalid tenant", `{"tenants": {"team a": {}}}`),
+	)
+})
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// TraceRecord is the trace of a debug run: every export the server called
+// and every host function the plugin reached, with arguments, results, and
+// the guest memory passed through them.
+type TraceRecord struct {
+	RequestID string               `json:"request_id"`
+	Plugin    string               `json:"plugin"`
+	Time      time.Time            `json:"time"`
+	Error     string               `json:"error,omitempty"` // Why the run failed
+	Events    []runtime.TraceEvent `json:"events"`
+	Dropped   int                  `json:"dropped,omitempty"` // Events beyond the per-trace limit
+
+	// WASI lists the WASI functions the plugin imports. They run inside
+	// WasmEdge, so their calls do not appear in Events.
+	WASI []string `json:"wasi,omitempty"`
+}
+
+// TraceSummary describes a kept trace in GET /debug/traces.
+type TraceSummary struct {
+	RequestID string    `json:"request_id"`
+	Plugin    string    `json:"plugin"`
+	Time      time.Time `json:"time"`
+	Error     string    `json:"error,omitempty"`
+	Events    int       `json:"events"`
+}
+
+// traceStore keeps the traces of the most recent debug runs, successful
+// or not, so a failing run can be inspected after the fact by request ID.
+type traceStore struct {
+	mu      sync.Mutex
+	size    int
+	records []*TraceRecord // Oldest first
+}
+
+// newTraceStore creates a store keeping up to size traces.
+func newTraceStore(size int) *traceStore {
+	return &traceStore{size: size}
+}
+
+// add keeps a trace, evicting the oldest one when the store is full. A
+// trace with the ID of a kept one replaces it.
+func (t *traceStore) add(record *TraceRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, kept := range t.records {
+		if kept.RequestID == record.RequestID {
+			t.records = append(t.records[:i], t.records[i+1:]...)
+			break
+		}
+	}
+	if len(t.records) >= t.size {
+		t.records = t.records[1:]
+	}
+	t.records = append(t.records, record)
+}
+
+// get returns the trace of a request, or nil.
+func (t *traceStore) get(requestID string) *TraceRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, record := range t.records {
+		if record.RequestID == requestID {
+			return record
+		}
+	}
+	return nil
+}
+
+// list summarizes the kept traces, newest first.
+func (t *traceStore) list() []TraceSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	summaries := make([]TraceSummary, 0, len(t.records))
+	for i := len(t.records) - 1; i >= 0; i-- {
+		record := t.records[i]
+		summaries = append(summaries, TraceSummary{
+			RequestID: record.RequestID,
+			Plugin:    record.Plugin,
+			Time:      record.Time,
+			Error:     record.Error,
+			Events:    len(record.Events),
+		})
+	}
+	return summaries
+}
+
+// traceRecord builds the record of a finished debug run.
+func traceRecord(requestID, plugin string, started time.Time, trace *runtime.Trace, err error) *TraceRecord {
+	record := &TraceRecord{
+		RequestID: requestID,
+		Plugin:    plugin,
+		Time:      started,
+		Events:    trace.Events(),
+		Dropped:   trace.Dropped(),
+		WASI:      trace.WASI(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// handleDebugTraces handles GET /debug/traces and
+// GET /debug/traces/{request_id}
+//
+// Lists the traces of recent debug runs, or returns one in full.
+func (s *Server) handleDebugTraces(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.traces == nil {
+		writeError(w, r, apierror.CodeTraceNotFound, errTracesDisabled.Error())
+		return
+	}
+
+	requestID := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/debug/traces"), "/")
+	if requestID == "" {
+		writeJSON(w, http.StatusOK, s.traces.list())
+		return
+	}
+	record := s.traces.get(requestID)
+	if record == nil {
+		writeError(w, r, apierror.CodeTraceNotFound, "no trace kept for request "+requestID)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// errTracesDisabled is reported for debug requests and trace lookups while
+// DEBUG_TRACES is unset.
+var errTracesDisabled = errors.New("debug traces are disabled")
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Debug traces", func() {
+	// =========================================================================
+	// TEST: Trace retention
+	// Why: Traces are looked up after the fact by request ID; the store must
+	//      stay bounded and always keep the most recent runs.
+	// =========================================================================
+	Describe("traceStore", func() {
+		It("should keep the most recent traces", func() {
+			store := newTraceStore(2)
+			for i := 1; i <= 3; i++ {
+				store.add(&TraceRecord{RequestID: fmt.Sprintf("req-%d", i), Plugin: "hello"})
+			}
+
+			Expect(store.get("req-1")).To(BeNil())
+			Expect(store.get("req-3").Plugin).To(Equal("hello"))
+			summaries := store.list()
+			Expect(summaries).To(HaveLen(2))
+			Expect(summaries[0].RequestID).To(Equal("req-3"))
+			Expect(summaries[1].RequestID).To(Equal("req-2"))
+		})
+
+		It("should replace the trace of a reused request ID", func() {
+			store := newTraceStore(2)
+			store.add(&TraceRecord{RequestID: "req-1", Plugin: "hello"})
+			store.add(&TraceRecord{RequestID: "req-2", Plugin: "hello"})
+			store.add(&TraceRecord{RequestID: "req-1", Plugin: "logger"})
+
+			Expect(store.list()).To(HaveLen(2))
+			Expect(store.get("req-1").Plugin).To(Equal("logger"))
+			Expect(store.get("req-2")).NotTo(BeNil())
+		})
+	})
+
+	Describe("GET /debug/traces", func() {
+		var srv *Server
+
+		BeforeEach(func() {
+			srv = NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+			srv.traces = newTraceStore(10)
+		})
+
+		get := func(path string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			srv.handleDebugTraces(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			return rec
+		}
+
+		It("should list and return kept traces", func() {
+			srv.traces.add(&TraceRecord{
+				RequestID: "req-1",
+				Plugin:    "hello",
+				Error:     "trap",
+				Events:    []runtime.TraceEvent{{Seq: 1, Kind: runtime.TraceExport, Function: "process", Args: []interface{}{int32(1)}}},
+			})
+
+			rec := get("/debug/traces")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var summaries []TraceSummary
+			Expect(json.Unmarshal(rec.Body.Bytes(), &summaries)).To(Succeed())
+			Expect(summaries).To(ConsistOf(TraceSummary{RequestID: "req-1", Plugin: "hello", Error: "trap", Events: 1}))
+
+			rec = get("/debug/traces/req-1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"function":"process","args":[1]`))
+		})
+
+		It("should report unknown request IDs", func() {
+			rec := get("/debug/traces/req-2")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(ContainSubstring(string(apierror.CodeTraceNotFound)))
+		})
+
+		It("should report traces disabled", func() {
+			srv.traces = nil
+			rec := get("/debug/traces")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(ContainSubstring("disabled"))
+		})
+
+		It("should reject other methods", func() {
+			rec := httptest.NewRecorder()
+			srv.handleDebugTraces(rec, httptest.NewRequest(http.MethodDelete, "/debug/traces", nil))
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Debug runs
+	// Why: A debug request is only useful if the failing run's trace can be
+	//      found afterwards, and it must not be honored on servers that have
+	//      not opted into keeping traces.
+	// =========================================================================
+	Describe("POST /run with debug", func() {
+		var srv *Server
+
+		run := func(body, requestID string) (*httptest.ResponseRecorder, Response) {
+			req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body))
+			req.Header.Set(RequestIDHeader, requestID)
+			rec := httptest.NewRecorder()
+			srv.handleRun(rec, req)
+			var resp Response
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			return rec, resp
+		}
+
+		It("should reject debug requests while traces are disabled", func() {
+			srv = NewServer(fluid.NewLocalPluginStore(GinkgoT().TempDir()))
+			rec, _ := run(`{"plugin": "logger", "debug": true}`, "req-1")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("debug traces are disabled"))
+		})
+
+		Context("with the logger plugin", func() {
+			BeforeEach(func() {
+				pluginsDir := filepath.Join("..", "..", "plugins")
+				if _, err := os.Stat(filepath.Join(pluginsDir, "logger", "logger.wasm")); os.IsNotExist(err) {
+					Skip("Test plugin not found: logger.wasm")
+				}
+				srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+				srv.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+				srv.traces = newTraceStore(10)
+			})
+
+			It("should return the trace of a successful run", func() {
+				rec, resp := run(`{"plugin": "logger", "input": 0, "debug": true}`, "req-1")
+				Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+
+				Expect(resp.Trace).NotTo(BeNil())
+				Expect(resp.Trace.RequestID).To(Equal("req-1"))
+				Expect(resp.Trace.Error).To(BeEmpty())
+				Expect(resp.Trace.Events).To(HaveLen(3)) // process, two log calls
+				Expect(resp.Trace.Events[0].Function).To(Equal("process"))
+				Expect(resp.Trace.Events[1].Module).To(Equal(runtime.LogModule))
+				Expect(string(resp.Trace.Events[1].Memory[0].Data)).To(Equal("input is zero"))
+				Expect(srv.traces.get("req-1")).NotTo(BeNil())
+			})
+
+			It("should keep the trace of a failed run", func() {
+				rec, _ := run(`{"plugin": "logger", "input": -1, "debug": true}`, "req-2")
+				Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+				Expect(rec.Body.String()).NotTo(ContainSubstring(`"events"`))
+
+				trace := srv.traces.get("req-2")
+				Expect(trace).NotTo(BeNil())
+				Expect(trace.Error).To(ContainSubstring("failed to execute plugin"))
+				Expect(trace.Events).To(HaveLen(2))
+				Expect(string(trace.Events[1].Memory[0].Data)).To(Equal("input must not be negative"))
+				Expect(trace.Time).To(BeTemporally("~", time.Now(), time.Minute))
+			})
+
+			It("should not trace runs without debug", func() {
+				rec, resp := run(`{"plugin": "logger", "input": 1}`, "req-3")
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(resp.Trace).To(BeNil())
+				Expect(srv.traces.list()).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+// uiFiles are the static files of the web playground.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiContentSecurityPolicy confines the playground to its own files and the
+// API of the server it is served by.
+const uiContentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; " +
+	"connect-src 'self'; img-src 'self' data:; form-action 'self'; frame-ancestors 'none'"
+
+// uiHandler serves the web playground below /ui/: one page listing the
+// plugins, running them with test inputs, and uploading builds. The page
+// only calls the public HTTP API with the credentials the user enters, so
+// it grants nothing curl could not do.
+func uiHandler() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embedded directory exists
+	}
+	fileServer := http.StripPrefix("/ui/", http.FileServer(http.FS(files)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
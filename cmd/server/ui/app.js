@@ -0,0 +1,222 @@
+// Web playground of the plugin server. It only calls the public HTTP API:
+// GET /plugins, POST /run, GET /admin/pools, and POST /plugins.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+// Credentials live in sessionStorage, so they are forgotten with the tab
+for (const id of ["api-key", "bearer"]) {
+  const input = $(id);
+  input.value = sessionStorage.getItem(id) || "";
+  input.addEventListener("change", () => sessionStorage.setItem(id, input.value));
+}
+
+function headers(extra) {
+  const h = new Headers(extra);
+  if ($("api-key").value) h.set("X-API-Key", $("api-key").value);
+  if ($("bearer").value) h.set("Authorization", "Bearer " + $("bearer").value);
+  return h;
+}
+
+// problemText describes a failed response: the problem details the server
+// returns for API errors, or the status line otherwise.
+async function problemText(response) {
+  try {
+    const problem = await response.json();
+    let text = `${problem.status} ${problem.code}: ${problem.detail || problem.title}`;
+    if (problem.plugin_error) {
+      text += `\nplugin error ${problem.plugin_error.code}` +
+        (problem.plugin_error.message ? `: ${problem.plugin_error.message}` : "");
+    }
+    return text;
+  } catch {
+    return `${response.status} ${response.statusText}`;
+  }
+}
+
+function formatSize(bytes) {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KiB`;
+  return `${(bytes / 1024 / 1024).toFixed(1)} MiB`;
+}
+
+// Plugins
+
+async function loadPlugins() {
+  const status = $("plugins-status");
+  const list = $("plugin-list");
+  status.textContent = "Loading…";
+  status.classList.remove("error");
+  const response = await fetch("../plugins", { headers: headers() });
+  if (!response.ok) {
+    status.textContent = await problemText(response);
+    status.classList.add("error");
+    return;
+  }
+  const plugins = await response.json();
+  list.replaceChildren(...plugins.map((plugin) => {
+    const ref = plugin.version ? `${plugin.name}@${plugin.version}` : plugin.name;
+    const item = document.createElement("li");
+    const name = document.createElement("strong");
+    name.textContent = ref;
+    const meta = document.createElement("div");
+    meta.className = "meta";
+    meta.textContent = formatSize(plugin.size) + (plugin.manifest?.description ? ` · ${plugin.manifest.description}` : "");
+    item.append(name, meta);
+    item.addEventListener("click", () => {
+      for (const other of list.children) other.classList.remove("selected");
+      item.classList.add("selected");
+      $("plugin").value = ref;
+    });
+    return item;
+  }));
+  status.textContent = plugins.length ? "" : "The store has no plugins yet.";
+}
+
+$("refresh").addEventListener("click", loadPlugins);
+
+// Run
+
+const placeholders = {
+  input: "0",
+  text: "hello, wasm",
+  payload: '{"name": "wasm"}',
+  fields: '{"name": "wasm", "count": 2}',
+};
+
+$("mode").addEventListener("change", () => {
+  $("input").value = placeholders[$("mode").value];
+});
+
+function runRequest() {
+  const mode = $("mode").value;
+  const value = $("input").value;
+  const request = { plugin: $("plugin").value.trim(), include_logs: $("include-logs").checked };
+  if ($("debug").checked) request.debug = true;
+  if ($("timeout").value) request.timeout_ms = Number($("timeout").value);
+  switch (mode) {
+    case "input": {
+      const input = Number(value.trim());
+      if (!Number.isInteger(input)) throw new Error("The input must be an integer.");
+      request.input = input;
+      break;
+    }
+    case "text":
+      request.text = value;
+      break;
+    default:
+      try {
+        request[mode] = JSON.parse(value);
+      } catch (err) {
+        throw new Error(`The input must be JSON: ${err.message}`);
+      }
+  }
+  return request;
+}
+
+function showStatus(ok, text) {
+  const badge = $("result-status");
+  badge.textContent = text;
+  badge.className = "badge " + (ok ? "ok" : "error");
+}
+
+function showLogs(logs) {
+  $("logs-block").hidden = !logs || logs.length === 0;
+  $("logs").tBodies[0].replaceChildren(...(logs || []).map((entry) => {
+    const row = document.createElement("tr");
+    for (const text of [entry.level, entry.message]) {
+      const cell = document.createElement("td");
+      cell.textContent = text;
+      row.append(cell);
+    }
+    return row;
+  }));
+}
+
+// showStats lists the round trip and, for admin credentials, the plugin's
+// pool from /admin/pools.
+async function showStats(plugin, elapsed) {
+  const stats = [["Round trip", `${elapsed.toFixed(1)} ms`]];
+  const response = await fetch("../admin/pools", { headers: headers() });
+  if (response.ok) {
+    const name = plugin.split("@")[0];
+    const pool = (await response.json()).find((p) => p.plugin === name)?.stats;
+    if (pool) {
+      const evictions = Object.values(pool.evictions || {}).reduce((a, b) => a + b, 0);
+      stats.push(
+        ["Idle instances", pool.idle],
+        ["In use", pool.in_use],
+        ["Instantiated", pool.instantiated],
+        ["Restored", pool.restored],
+        ["Evictions", evictions],
+        ["Reset strategy", pool.strategy],
+      );
+    }
+  }
+  $("stats").replaceChildren(...stats.flatMap(([term, value]) => {
+    const dt = document.createElement("dt");
+    dt.textContent = term;
+    const dd = document.createElement("dd");
+    dd.textContent = String(value);
+    return [dt, dd];
+  }));
+}
+
+$("run-form").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  $("result").hidden = false;
+  let request;
+  try {
+    request = runRequest();
+  } catch (err) {
+    showStatus(false, "invalid input");
+    $("output").textContent = err.message;
+    showLogs([]);
+    return;
+  }
+
+  const started = performance.now();
+  const response = await fetch("../run", {
+    method: "POST",
+    headers: headers({ "Content-Type": "application/json" }),
+    body: JSON.stringify(request),
+  });
+  const elapsed = performance.now() - started;
+  if (response.ok) {
+    const result = await response.json();
+    showStatus(true, `${response.status} OK`);
+    const logs = result.logs;
+    delete result.logs;
+    $("output").textContent = JSON.stringify(result, null, 2);
+    showLogs(logs);
+  } else {
+    showStatus(false, String(response.status));
+    $("output").textContent = await problemText(response);
+    showLogs([]);
+  }
+  await showStats(request.plugin, elapsed);
+});
+
+// Upload
+
+$("upload-form").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const status = $("upload-status");
+  const form = new FormData();
+  form.append("file", $("upload-file").files[0]);
+  if ($("upload-name").value.trim()) form.append("name", $("upload-name").value.trim());
+  status.classList.remove("error");
+  status.textContent = "Uploading…";
+  const response = await fetch("../plugins", { method: "POST", headers: headers(), body: form });
+  if (!response.ok) {
+    status.textContent = await problemText(response);
+    status.classList.add("error");
+    return;
+  }
+  const plugin = await response.json();
+  status.textContent = `Installed ${plugin.version ? plugin.name + "@" + plugin.version : plugin.name}.`;
+  $("upload-form").reset();
+  await loadPlugins();
+});
+
+loadPlugins();
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /ui/", func() {
+	var mux *http.ServeMux
+
+	BeforeEach(func() {
+		mux = http.NewServeMux()
+		mux.Handle("/ui/", uiHandler())
+	})
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	// =========================================================================
+	// TEST: Playground
+	// Why: Plugin authors without curl start from this page; it must load
+	//      its script and styles from the server, and the policy must keep
+	//      anything else from running with their credentials.
+	// =========================================================================
+	It("should serve the page and its assets", func() {
+		rec := get(http.MethodGet, "/ui/")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(rec.Header().Get("Content-Security-Policy")).To(ContainSubstring("script-src 'self'"))
+		Expect(rec.Body.String()).To(ContainSubstring(`<script src="app.js" defer></script>`))
+
+		rec = get(http.MethodGet, "/ui/app.js")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/javascript"))
+		Expect(rec.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+
+		Expect(get(http.MethodGet, "/ui/style.css").Code).To(Equal(http.StatusOK))
+	})
+
+	It("should redirect /ui to the page", func() {
+		rec := get(http.MethodGet, "/ui")
+		Expect(rec.Code).To(BeNumerically(">=", 300))
+		Expect(rec.Code).To(BeNumerically("<", 400))
+		Expect(rec.Header().Get("Location")).To(Equal("/ui/"))
+	})
+
+	It("should return 404 for unknown files and 405 for other methods", func() {
+		Expect(get(http.MethodGet, "/ui/missing.js").Code).To(Equal(http.StatusNotFound))
+		Expect(get(http.MethodPost, "/ui/").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
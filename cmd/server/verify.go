@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// Checks a build can fail in a verification run, as VerifyFailure.Check.
+const (
+	verifyResolve   = "resolve"   // The store cannot return the build
+	verifyManifest  = "manifest"  // Its manifest or checksum file is invalid
+	verifyIntegrity = "integrity" // Its binary does not match its digest or signature
+	verifyABI       = "abi"       // It implements an ABI version the runtime does not support
+	verifyLoad      = "load"      // It does not load on this engine
+	verifyDrift     = "drift"     // Its contents changed, but not its size and modification time
+)
+
+// VerifyReport is the outcome of one verification run over the store.
+type VerifyReport struct {
+	Started    time.Time       `json:"started"`
+	DurationMs int64           `json:"duration_ms"`
+	Builds     int             `json:"builds"`   // Builds verified
+	Failures   []VerifyFailure `json:"failures"` // Builds that failed, by reference
+}
+
+// VerifyFailure is a build that failed verification.
+type VerifyFailure struct {
+	Plugin string `json:"plugin"`
+	Check  string `json:"check"`
+	Error  string `json:"error"`
+}
+
+// verifiedBuild is what a run remembers of a build to detect drift.
+type verifiedBuild struct {
+	size    int64
+	modTime time.Time
+	digest  string
+}
+
+// storeVerifier re-verifies every build in the plugin store on a schedule,
+// the way a pool would load it: its digest and signature, its ABI version,
+// and that it loads on the engine the server runs now. A silently
+// corrupted object, or an engine upgrade a plugin no longer loads on, is
+// then logged and exported as a metric before a request runs into it.
+type storeVerifier struct {
+	store    fluid.PluginStore
+	load     func(path string) error // Loads and closes a build
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	last   *VerifyReport
+	builds map[string]verifiedBuild // By reference, from the last run
+}
+
+// newStoreVerifier creates a verifier loading builds with load.
+func newStoreVerifier(store fluid.PluginStore, load func(path string) error, interval time.Duration, logger *slog.Logger) *storeVerifier {
+	return &storeVerifier{store: store, load: load, interval: interval, logger: logger}
+}
+
+// run verifies the store right away, so an incompatible engine is found
+// at startup, and then every interval until ctx is done.
+func (v *storeVerifier) run(ctx context.Context) {
+	v.verify(ctx)
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.verify(ctx)
+		}
+	}
+}
+
+// verify checks every build in the store once and keeps the report.
+func (v *storeVerifier) verify(ctx context.Context) *VerifyReport {
+	report := &VerifyReport{Started: time.Now().UTC(), Failures: []VerifyFailure{}}
+	plugins, err := v.store.List()
+	if err != nil {
+		v.logger.LogAttrs(ctx, slog.LevelWarn, "failed to list plugins for verification",
+			slog.String("error", err.Error()))
+		return nil
+	}
+
+	v.mu.Lock()
+	previous := v.builds
+	v.mu.Unlock()
+	builds := make(map[string]verifiedBuild, len(plugins))
+	for _, p := range runnablePlugins(plugins) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		ref := p.Reference()
+		report.Builds++
+		build, check, err := v.verifyBuild(ref, p)
+		if err == nil {
+			if old, ok := previous[ref]; ok && old.size == build.size && old.modTime.Equal(build.modTime) && old.digest != build.digest {
+				check, err = verifyDrift, errors.New("the binary changed since the last run without a new size or modification time")
+			}
+			builds[ref] = build
+		}
+		if err != nil {
+			report.Failures = append(report.Failures, VerifyFailure{Plugin: ref, Check: check, Error: err.Error()})
+			v.logger.LogAttrs(ctx, slog.LevelError, "plugin build failed verification",
+				slog.String("plugin", ref),
+				slog.String("check", check),
+				slog.String("error", err.Error()))
+		}
+	}
+	report.DurationMs = time.Since(report.Started).Milliseconds()
+
+	v.mu.Lock()
+	v.last, v.builds = report, builds
+	v.mu.Unlock()
+	v.logger.LogAttrs(ctx, slog.LevelInfo, "plugin store verified",
+		slog.Int("builds", report.Builds),
+		slog.Int("failures", len(report.Failures)),
+		slog.Int64("duration_ms", report.DurationMs))
+	return report
+}
+
+// verifyBuild resolves and loads one build. On failure it returns the
+// check that failed.
+func (v *storeVerifier) verifyBuild(ref string, p fluid.PluginInfo) (verifiedBuild, string, error) {
+	path, err := v.store.Resolve(ref)
+	if errors.Is(err, manifest.ErrInvalid) {
+		return verifiedBuild{}, verifyManifest, err
+	}
+	if err != nil {
+		return verifiedBuild{}, verifyResolve, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return verifiedBuild{}, verifyResolve, err
+	}
+
+	if err := v.load(path); err != nil {
+		switch {
+		case errors.Is(err, runtime.ErrIntegrity):
+			return verifiedBuild{}, verifyIntegrity, err
+		case errors.Is(err, manifest.ErrInvalid):
+			return verifiedBuild{}, verifyManifest, err
+		case errors.Is(err, runtime.ErrABIVersion):
+			return verifiedBuild{}, verifyABI, err
+		}
+		return verifiedBuild{}, verifyLoad, err
+	}
+	sum := sha256.Sum256(data)
+	return verifiedBuild{size: p.Size, modTime: p.ModTime, digest: hex.EncodeToString(sum[:])}, "", nil
+}
+
+// lastReport returns the report of the last completed run, or nil before
+// the first.
+func (v *storeVerifier) lastReport() *VerifyReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.last
+}
+
+// collectMetrics reports when the store was last verified and how many
+// builds failed each check.
+func (v *storeVerifier) collectMetrics() []metrics.Family {
+	report := v.lastReport()
+	if report == nil {
+		return nil
+	}
+	failed := metrics.Family{Name: "plugin_store_verify_failed_builds", Help: "Plugin builds that failed the last store verification, by failed check.", Type: metrics.TypeGauge}
+	for _, check := range []string{verifyResolve, verifyManifest, verifyIntegrity, verifyABI, verifyLoad, verifyDrift} {
+		count := 0
+		for _, f := range report.Failures {
+			if f.Check == check {
+				count++
+			}
+		}
+		failed.Samples = append(failed.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "check", Value: check}},
+			Value:  float64(count),
+		})
+	}
+	return []metrics.Family{
+		{Name: "plugin_store_verify_last_run_timestamp_seconds", Help: "Unix time the last store verification started.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Value: float64(report.Started.Unix())}}},
+		{Name: "plugin_store_verified_builds", Help: "Plugin builds checked by the last store verification.", Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{{Value: float64(report.Builds)}}},
+		failed,
+	}
+}
+
+// verifyLoad loads and closes the plugin at path on the server's engine
+// with the options of its pools, which checks its digest, signature, and
+// ABI version.
+func (s *Server) verifyLoad(path string) error {
+	plugin, err := s.primaryEngine().Load(path, runtime.LoadOptions{
+		MaxMemoryPages:    s.poolOptions.MaxMemoryPages,
+		HostModules:       s.hostModules(),
+		RequireDigest:     s.poolOptions.RequireDigest,
+		TrustedKeys:       s.poolOptions.TrustedKeys,
+		RequireABIVersion: s.poolOptions.RequireABIVersion,
+		AnyABIVersion:     s.poolOptions.AnyABIVersion,
+	})
+	if err != nil {
+		return err
+	}
+	plugin.Close()
+	return nil
+}
+
+// collectVerifyMetrics reports the outcome of the last store verification.
+func (s *Server) collectVerifyMetrics() []metrics.Family {
+	if s.verifier == nil {
+		return nil
+	}
+	return s.verifier.collectMetrics()
+}
+
+// handleDebugVerify handles GET /debug/verify
+//
+// Returns the report of the last store verification run, or null before
+// the first has completed. Without verify.interval the endpoint is
+// disabled.
+func (s *Server) handleDebugVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.verifier == nil {
+		writeError(w, r, apierror.CodeMethodNotAllowed, "store verification is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.verifier.lastReport())
+}
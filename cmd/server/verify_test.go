@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Store verification", func() {
+	// =========================================================================
+	// TEST: Disaster-recovery verification
+	// Why: A corrupted binary or an engine a plugin no longer loads on must
+	//      be reported by the job, with the check that failed, before a
+	//      request runs into it.
+	// =========================================================================
+	var (
+		dir      string
+		verifier *storeVerifier
+		loadErrs map[string]error // By file name
+	)
+
+	write := func(path, data string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, path), []byte(data), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		write("hello/hello.wasm", "hello")
+		write("upper/1.0.0/upper.wasm", "upper")
+		loadErrs = map[string]error{}
+		load := func(path string) error { return loadErrs[filepath.Base(path)] }
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		verifier = newStoreVerifier(fluid.NewLocalPluginStore(dir), load, time.Hour, logger)
+	})
+
+	It("should report every build that fails a check", func() {
+		loadErrs["hello.wasm"] = fmt.Errorf("%w: plugin hello.wasm has sha256 00, expected 01", runtime.ErrIntegrity)
+		write("broken/broken.wasm", "broken")
+		write("broken/plugin.json", `{"name": "other", "version": "1.0.0"}`)
+
+		report := verifier.verify(context.Background())
+		Expect(report.Builds).To(Equal(3))
+		Expect(report.Failures).To(ConsistOf(
+			HaveField("Plugin", "broken"),
+			VerifyFailure{Plugin: "hello", Check: verifyIntegrity, Error: loadErrs["hello.wasm"].Error()},
+		))
+		Expect(report.Failures[0].Check).To(Equal(verifyManifest))
+		Expect(verifier.lastReport()).To(BeIdenticalTo(report))
+
+		loadErrs["upper.wasm"] = fmt.Errorf("%w: upper implements ABI 2.0.0", runtime.ErrABIVersion)
+		Expect(verifier.verify(context.Background()).Failures).To(ContainElement(HaveField("Check", verifyABI)))
+	})
+
+	It("should report a binary changed behind the store's back", func() {
+		Expect(verifier.verify(context.Background()).Failures).To(BeEmpty())
+
+		// Same size and modification time, different contents
+		path := filepath.Join(dir, "hello", "hello.wasm")
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		write("hello/hello.wasm", "jello")
+		Expect(os.Chtimes(path, info.ModTime(), info.ModTime())).To(Succeed())
+
+		report := verifier.verify(context.Background())
+		Expect(report.Failures).To(HaveLen(1))
+		Expect(report.Failures[0].Plugin).To(Equal("hello"))
+		Expect(report.Failures[0].Check).To(Equal(verifyDrift))
+
+		// A new upload changes the modification time and is no drift
+		write("upper/1.0.0/upper.wasm", "UPPER")
+		Expect(os.Chtimes(filepath.Join(dir, "upper", "1.0.0", "upper.wasm"), time.Now().Add(time.Hour), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(verifier.verify(context.Background()).Failures).NotTo(ContainElement(HaveField("Plugin", "upper@1.0.0")))
+	})
+
+	It("should export the last run as metrics and at /debug/verify", func() {
+		srv := NewServer(fluid.NewLocalPluginStore(dir))
+		get := func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			srv.handleDebugVerify(rec, httptest.NewRequest(http.MethodGet, "/debug/verify", nil))
+			return rec
+		}
+		Expect(get().Code).To(Equal(http.StatusMethodNotAllowed))
+
+		srv.verifier = verifier
+		Expect(get().Body.String()).To(Equal("null\n"))
+		Expect(srv.collectVerifyMetrics()).To(BeEmpty())
+
+		loadErrs["hello.wasm"] = fmt.Errorf("failed to instantiate")
+		verifier.verify(context.Background())
+		rec := get()
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"check":"load"`))
+
+		var text bytes.Buffer
+		Expect(srv.metrics.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(ContainSubstring(`plugin_store_verify_failed_builds{check="load"} 1`))
+		Expect(text.String()).To(ContainSubstring("plugin_store_verified_builds 2"))
+	})
+})
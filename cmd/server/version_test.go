@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plugin versions", func() {
+	// =========================================================================
+	// TEST: Plugin versions
+	// Why: A caller pinning a version must get exactly that build, and must
+	//      be told which build ran when it asks for the latest one.
+	// =========================================================================
+	var (
+		pluginsDir string
+		srv        *Server
+	)
+
+	BeforeEach(func() {
+		pluginsDir = GinkgoT().TempDir()
+		srv = NewServer(fluid.NewLocalPluginStore(pluginsDir))
+	})
+
+	run := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.handleRun(rec, httptest.NewRequest(http.MethodPost, "/run", bytes.NewBufferString(body)))
+		return rec
+	}
+
+	problemCode := func(rec *httptest.ResponseRecorder) apierror.Code {
+		var problem apierror.Problem
+		Expect(json.Unmarshal(rec.Body.Bytes(), &problem)).To(Succeed(), rec.Body.String())
+		return problem.Code
+	}
+
+	It("should reject a version conflicting with the plugin reference", func() {
+		rec := run(`{"plugin": "hello@1.0.0", "version": "1.1.0", "input": 1}`)
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidRequest))
+	})
+
+	It("should reject an invalid version", func() {
+		rec := run(`{"plugin": "hello", "version": "../1", "input": 1}`)
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(problemCode(rec)).To(Equal(apierror.CodeInvalidPluginName))
+	})
+
+	It("should report a missing version as plugin_not_found", func() {
+		rec := run(`{"plugin": "hello", "version": "9.9.9", "input": 1}`)
+
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(problemCode(rec)).To(Equal(apierror.CodePluginNotFound))
+	})
+
+	It("should run the pinned version and report the version that ran", func() {
+		data, err := os.ReadFile(filepath.Join("..", "..", "plugins", "hello", "hello.wasm"))
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: hello.wasm")
+		}
+		Expect(err).NotTo(HaveOccurred())
+		for _, version := range []string{"1.0.0", "1.1.0"} {
+			dir := filepath.Join(pluginsDir, "hello", version)
+			Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "hello.wasm"), data, 0644)).To(Succeed())
+		}
+
+		for body, version := range map[string]string{
+			`{"plugin": "hello", "version": "1.0.0", "input": 1}`: "1.0.0",
+			`{"plugin": "hello@1.0.0", "input": 1}`:               "1.0.0",
+			`{"plugin": "hello@latest", "input": 1}`:              "1.1.0",
+			`{"plugin": "hello", "input": 1}`:                     "1.1.0",
+		} {
+			rec := run(body)
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			var response Response
+			Expect(json.Unmarshal(rec.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Version).To(Equal(version), body)
+		}
+
+		// Each version runs in its own pool
+		Expect(srv.poolInfos()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,5 @@
+//go:build wasmtime
+
+package main
+
+import _ "github.com/mrhapile/wasm-plugin-system/runtime/wasmtime" // ENGINE or SHADOW_ENGINE=wasmtime
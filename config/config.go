@@ -0,0 +1,726 @@
+// Package config loads the plugin server's configuration.
+//
+// Every setting has a key in a YAML file, an environment variable, and a
+// command-line flag. Later sources override earlier ones:
+//
+//  1. the default
+//  2. the YAML file named by -config or CONFIG_FILE
+//  3. the environment variable, e.g. POOL_MAX_SIZE=8
+//  4. the flag, e.g. -pool-max-size=8
+//
+// so a deployment can check in a file and still override single settings.
+// The environment variables are those the server has always read. An
+// empty variable counts as unset, and a boolean flag alone, e.g. -demo,
+// turns its setting on.
+//
+// CONFIG.md, generated by Reference, documents every setting.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// Config is the server's configuration. Each field's tags declare its
+// setting: the yaml key, env the environment variable, default its value
+// when no source sets it, and usage its description. Integers and
+// durations must not be negative; check:"positive" rejects zero too.
+// secret:"true" marks credentials, which Redacted hides.
+type Config struct {
+	Listen      Listen      `yaml:"listen"`
+	TLS         TLS         `yaml:"tls"`
+	Log         Log         `yaml:"log"`
+	Store       Store       `yaml:"store"`
+	AWS         AWS         `yaml:"aws"`
+	Plugins     Plugins     `yaml:"plugins"`
+	Pool        Pool        `yaml:"pool"`
+	Eviction    Eviction    `yaml:"eviction"`
+	Execution   Execution   `yaml:"execution"`
+	Dedup       Dedup       `yaml:"dedup"`
+	Admin       Admin       `yaml:"admin"`
+	Host        Host        `yaml:"host"`
+	WASI        WASI        `yaml:"wasi"`
+	Cache       Cache       `yaml:"cache"`
+	Blobs       Blobs       `yaml:"blobs"`
+	Outbox      Outbox      `yaml:"outbox"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Schedules   Schedules   `yaml:"schedules"`
+	Maintenance Maintenance `yaml:"maintenance"`
+	Batches     Batches     `yaml:"batches"`
+	Jobs        Jobs        `yaml:"jobs"`
+	Streams     Streams     `yaml:"streams"`
+	RateLimits  RateLimits  `yaml:"rate_limits"`
+	Tenants     Tenants     `yaml:"tenants"`
+	Auth        Auth        `yaml:"auth"`
+	Locality    Locality    `yaml:"locality"`
+	CacheStats  CacheStats  `yaml:"cache_stats"`
+	Verify      Verify      `yaml:"verify"`
+	Shadow      Shadow      `yaml:"shadow"`
+	UI          UI          `yaml:"ui"`
+
+	// Demo serves the server's sample plugins from a memory store and the
+	// web playground, for trying it out without building or publishing
+	// plugins
+	Demo bool `yaml:"demo" env:"DEMO" usage:"Serve the built-in sample plugins from the memory store, serve the web playground, and print example requests"`
+}
+
+// Listen holds the addresses the server listens on.
+type Listen struct {
+	HTTP string `yaml:"http" env:"LISTEN_ADDR" default:":8080" usage:"Address of the HTTP API"`
+	GRPC string `yaml:"grpc" env:"GRPC_LISTEN_ADDR" default:":9090" usage:"Address of the gRPC API"`
+}
+
+// TLS holds the server certificate. Without one, both APIs serve
+// plaintext.
+type TLS struct {
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE" usage:"PEM certificate (chain) served by both APIs; requires tls.key_file"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE" usage:"PEM private key of tls.cert_file"`
+}
+
+// Enabled reports whether a certificate is configured.
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Log configures the server's own log.
+type Log struct {
+	Level    string `yaml:"level" env:"LOG_LEVEL" default:"info" usage:"Minimum level of server and plugin log records: debug, info, warn, or error"`
+	Requests bool   `yaml:"requests" env:"LOG_REQUESTS" default:"true" usage:"Log every HTTP request with its ID, plugin, status, and duration"`
+}
+
+// Store selects and configures the plugin store.
+type Store struct {
+	Type          string        `yaml:"type" env:"PLUGIN_STORE" default:"local" usage:"Plugin store: local, fluid, s3, http, memory, or one registered with fluid.RegisterStore"`
+	Dir           string        `yaml:"dir" env:"PLUGIN_DIR" default:"./plugins" usage:"Plugin directory of the local store"`
+	FluidMount    string        `yaml:"fluid_mount_path" env:"FLUID_MOUNT_PATH" default:"/mnt/fluid/plugins" usage:"Fluid dataset mount of the fluid store"`
+	Watch         bool          `yaml:"watch" env:"PLUGIN_WATCH" default:"true" usage:"Watch the local or Fluid plugin directory and evict changed builds"`
+	WatchInterval time.Duration `yaml:"watch_interval" env:"PLUGIN_WATCH_INTERVAL" default:"10s" check:"positive" usage:"How often the watched directory is rescanned regardless of change events"`
+	S3            S3            `yaml:"s3"`
+	HTTP          HTTPStore     `yaml:"http"`
+	Cache         StoreCache    `yaml:"cache"`
+	Options       []string      `yaml:"options" env:"PLUGIN_STORE_OPTIONS" usage:"Settings of a registered store, as key=value"`
+}
+
+// OptionMap returns the options of a registered store by key.
+func (s Store) OptionMap() map[string]string {
+	options := make(map[string]string, len(s.Options))
+	for _, option := range s.Options {
+		key, value, _ := strings.Cut(option, "=")
+		options[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return options
+}
+
+// S3 locates the bucket of the s3 store.
+type S3 struct {
+	Bucket   string `yaml:"bucket" env:"S3_BUCKET" usage:"Bucket of the s3 store"`
+	Region   string `yaml:"region" env:"S3_REGION" usage:"Region of the bucket"`
+	Endpoint string `yaml:"endpoint" env:"S3_ENDPOINT" usage:"Endpoint of an S3-compatible service, e.g. MinIO"`
+	Prefix   string `yaml:"prefix" env:"S3_PREFIX" usage:"Key prefix of the plugins in the bucket"`
+}
+
+// HTTPStore locates the web server of the http store.
+type HTTPStore struct {
+	BaseURL string `yaml:"base_url" env:"PLUGIN_BASE_URL" usage:"Base URL of the http store; plugins are fetched from <base_url>/<name>/<name>.wasm"`
+	Token   string `yaml:"token" env:"PLUGIN_BASE_URL_TOKEN" secret:"true" usage:"Bearer token sent to the http store"`
+}
+
+// StoreCache configures where the s3 and http stores keep downloads.
+type StoreCache struct {
+	Dir string        `yaml:"dir" env:"PLUGIN_CACHE_DIR" usage:"Directory of downloaded plugins (default: wasm-plugins in the user cache directory)"`
+	TTL time.Duration `yaml:"ttl" env:"PLUGIN_CACHE_TTL" default:"30s" check:"positive" usage:"How long a downloaded plugin is used before it is revalidated"`
+}
+
+// AWS holds the credentials of the s3 store and blob storage.
+type AWS struct {
+	AccessKeyID     string `yaml:"access_key_id" env:"AWS_ACCESS_KEY_ID" usage:"Access key; anonymous requests without one"`
+	SecretAccessKey string `yaml:"secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true" usage:"Secret key of aws.access_key_id"`
+	SessionToken    string `yaml:"session_token" env:"AWS_SESSION_TOKEN" secret:"true" usage:"Session token of temporary credentials"`
+}
+
+// Plugins configures how plugin builds are loaded and verified.
+type Plugins struct {
+	ConfigDir     string `yaml:"config_dir" env:"PLUGIN_CONFIG_DIR" usage:"Directory of config overlays merged onto plugin manifests"`
+	Environment   string `yaml:"environment" env:"PLUGIN_ENV" usage:"Environment selecting overlays in plugins.config_dir, e.g. prod"`
+	ModuleCache   bool   `yaml:"module_cache" env:"MODULE_CACHE" default:"true" usage:"Share parsed modules between instances of a plugin build"`
+	AOTCacheDir   string `yaml:"aot_cache_dir" env:"AOT_CACHE_DIR" usage:"Enables ahead-of-time compilation, keeping compiled plugins in this directory"`
+	RequireDigest bool   `yaml:"require_digest" env:"REQUIRE_DIGEST" usage:"Refuse plugins whose manifest declares no SHA-256 digest"`
+	RequireABI    bool   `yaml:"require_abi_version" env:"REQUIRE_ABI_VERSION" usage:"Refuse plugins that do not export get_abi_version"`
+	TrustedKeys   string `yaml:"trusted_keys_file" env:"TRUSTED_KEYS_FILE" usage:"PEM Ed25519 public keys, one of which must have signed every plugin"`
+
+	Preload []string `yaml:"preload" env:"PLUGIN_PRELOAD" usage:"Plugins (name or name@version) loaded, compiled, and pooled before the server listens; GET /readyz fails if one cannot be"`
+}
+
+// Pool sizes the instance pools. Sizes left at zero are seeded per
+// plugin from its manifest's sizing hints.
+type Pool struct {
+	MinSize         int           `yaml:"min_size" env:"POOL_MIN_SIZE" usage:"Instances kept warm per plugin"`
+	MaxSize         int           `yaml:"max_size" env:"POOL_MAX_SIZE" usage:"Live instances per plugin, 0 for unlimited; requests wait when the pool is full"`
+	MemoryBudgetMiB int           `yaml:"memory_budget_mib" env:"POOL_MEMORY_BUDGET_MIB" usage:"Memory per plugin that limits a max size seeded from sizing hints, 0 for none"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"POOL_IDLE_TIMEOUT" usage:"Idle time before an instance is evicted, 0 for never"`
+	HealthInterval  time.Duration `yaml:"health_check_interval" env:"HEALTH_CHECK_INTERVAL" usage:"How often idle instances exporting health() are checked, 0 for only after init"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"PLUGIN_SHUTDOWN_TIMEOUT" usage:"Bound on the on_shutdown() call before an instance is discarded, 0 for 1s"`
+	InitTimeout     time.Duration `yaml:"init_timeout" env:"PLUGIN_INIT_TIMEOUT" usage:"Bound on the init() call of each new instance, 0 for 30s"`
+}
+
+// Eviction bounds what the pools of plugins no request uses keep in
+// memory. A plugin's evicted pool is created again by its next request.
+type Eviction struct {
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"EVICT_IDLE_TIMEOUT" usage:"Time without requests after which a plugin build's pool and cached module are released, 0 for never"`
+	MaxMemoryMiB int           `yaml:"max_memory_mib" env:"EVICT_MAX_MEMORY_MIB" usage:"Linear memory and snapshots all pools may hold before the least recently used are released, 0 for no limit"`
+	MaxInstances int           `yaml:"max_instances" env:"EVICT_MAX_INSTANCES" usage:"Live instances all pools may hold before the least recently used are released, 0 for no limit"`
+	Interval     time.Duration `yaml:"interval" env:"EVICT_INTERVAL" default:"30s" check:"positive" usage:"How often the eviction bounds are checked"`
+}
+
+// Enabled reports whether any eviction bound is set.
+func (e Eviction) Enabled() bool {
+	return e.IdleTimeout > 0 || e.MaxMemoryMiB > 0 || e.MaxInstances > 0
+}
+
+// MaxMemoryPages is the most 64 KiB pages a wasm32 memory can have.
+const MaxMemoryPages = 65536
+
+// Execution limits plugin calls.
+type Execution struct {
+	Engine         string        `yaml:"engine" env:"ENGINE" default:"wasmedge" usage:"Engine registered with runtime.RegisterEngine that plugins run on; only wasmedge restores instances from snapshots, loads AOT-compiled plugins, captures plugin output, and enforces execution.max_instructions"`
+	Timeout        time.Duration `yaml:"timeout" env:"EXECUTION_TIMEOUT" default:"30s" usage:"Bound on each plugin call, 0 for none"`
+	MaxMemoryPages int           `yaml:"max_memory_pages" env:"MAX_MEMORY_PAGES" usage:"Linear memory cap per instance in 64 KiB pages unless the manifest sets one, 0 for none"`
+	DebugTraces    int           `yaml:"debug_traces" env:"DEBUG_TRACES" usage:"Number of debug request traces kept, 0 to reject debug requests"`
+	MaxOutputBytes int           `yaml:"max_output_bytes" env:"MAX_OUTPUT_BYTES" default:"65536" usage:"Plugin stdout and stderr captured per stream and request, 0 to pass them to the server's own"`
+
+	MaxInstructions int64 `yaml:"max_instructions" env:"EXECUTION_MAX_INSTRUCTIONS" usage:"Instructions one plugin call may execute, 0 for no limit; needs WasmEdge statistics, else only the timeout applies"`
+
+	MaxConcurrent          int `yaml:"max_concurrent" env:"MAX_CONCURRENT_EXECUTIONS" usage:"Plugin executions running at once across all plugins before requests get 429, 0 for no limit"`
+	MaxConcurrentPerPlugin int `yaml:"max_concurrent_per_plugin" env:"MAX_CONCURRENT_EXECUTIONS_PER_PLUGIN" usage:"Executions of one plugin, in any version, running at once before requests get 429, 0 for no limit"`
+}
+
+// Dedup configures request deduplication.
+type Dedup struct {
+	TTL time.Duration `yaml:"ttl" env:"DEDUP_TTL" default:"24h" check:"positive" usage:"How long dedup keys are remembered"`
+	Dir string        `yaml:"dir" env:"DEDUP_DIR" usage:"Directory keeping dedup keys across restarts"`
+}
+
+// Admin configures the administrative endpoints.
+type Admin struct {
+	Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true" usage:"Bearer token enabling plugin uploads, deletion, and log level changes"`
+}
+
+// UI configures the web playground.
+type UI struct {
+	Enabled bool `yaml:"enabled" env:"UI_ENABLED" usage:"Serve the web playground at /ui/; demo mode always does"`
+}
+
+// Host names the files configuring optional host APIs.
+type Host struct {
+	ModulesFile string `yaml:"modules_file" env:"HOST_MODULES_FILE" usage:"Site-specific host modules every plugin may import"`
+	SQLFile     string `yaml:"sql_file" env:"SQL_CONFIG_FILE" usage:"Databases and prepared statements of the sql host API"`
+	PublishFile string `yaml:"publish_file" env:"PUBLISH_CONFIG_FILE" usage:"Broker and topic grants of the publish host API"`
+}
+
+// WASI configures what plugins may be granted through WASI. Plugins ask
+// for it in their manifest's wasi block.
+type WASI struct {
+	Env      []string `yaml:"env" env:"WASI_ENV" usage:"Environment variables set for every plugin, as NAME=value"`
+	AllowEnv []string `yaml:"allow_env" env:"WASI_ALLOW_ENV" usage:"Server environment variables plugins may inherit by listing them in wasi.inherit_env (comma-separated in the environment and flags)"`
+	Dirs     []string `yaml:"dirs" env:"WASI_DIRS" usage:"Directories plugins may have pre-opened by listing the guest path in wasi.dirs, as guest=host"`
+
+	ScratchDir      string `yaml:"scratch_dir" env:"WASI_SCRATCH_DIR" usage:"Where the private /tmp directories of plugins granted directories are created, emptied after every call; the system temporary directory if empty"`
+	ScratchMaxMiB   int    `yaml:"scratch_max_mib" env:"WASI_SCRATCH_MAX_MIB" usage:"File data one call may leave in its /tmp before it fails, 0 for no limit"`
+	ScratchMaxFiles int    `yaml:"scratch_max_files" env:"WASI_SCRATCH_MAX_FILES" usage:"Files and directories one call may leave in its /tmp before it fails, 0 for no limit"`
+}
+
+// EnvVars returns the variables set for every plugin, by name, or nil if
+// there are none.
+func (w WASI) EnvVars() map[string]string {
+	if len(w.Env) == 0 {
+		return nil
+	}
+	vars := make(map[string]string, len(w.Env))
+	for _, v := range w.Env {
+		name, value, _ := strings.Cut(v, "=")
+		vars[strings.TrimSpace(name)] = value
+	}
+	return vars
+}
+
+// DirMap returns the host directories plugins may pre-open, by guest path,
+// or nil if there are none.
+func (w WASI) DirMap() map[string]string {
+	if len(w.Dirs) == 0 {
+		return nil
+	}
+	dirs := make(map[string]string, len(w.Dirs))
+	for _, dir := range w.Dirs {
+		guest, host, _ := strings.Cut(dir, "=")
+		dirs[strings.TrimSpace(guest)] = strings.TrimSpace(host)
+	}
+	return dirs
+}
+
+// Cache configures the cache host API.
+type Cache struct {
+	Enabled       bool          `yaml:"enabled" env:"CACHE" default:"true" usage:"Offer cache_get() and cache_set() to plugins"`
+	RedisURL      string        `yaml:"redis_url" env:"CACHE_REDIS_URL" secret:"true" usage:"Cache in Redis, e.g. redis://:password@cache:6379/2, rather than in memory"`
+	QuotaBytes    int64         `yaml:"quota_bytes" env:"CACHE_QUOTA_BYTES" default:"16777216" check:"positive" usage:"Bytes each plugin may cache in memory, for each tenant"`
+	MaxValueBytes int           `yaml:"max_value_bytes" env:"CACHE_MAX_VALUE_BYTES" default:"1048576" check:"positive" usage:"Largest value a plugin may cache"`
+	MaxTTL        time.Duration `yaml:"max_ttl" env:"CACHE_MAX_TTL" default:"1h" check:"positive" usage:"Longest TTL; longer ones are shortened to it"`
+}
+
+// Blobs configures the object storage host API. Credentials are those of
+// AWS.
+type Blobs struct {
+	Bucket      string `yaml:"bucket" env:"BLOB_BUCKET" usage:"Bucket of blob_get() and blob_put(), which are unavailable without one"`
+	Region      string `yaml:"region" env:"BLOB_REGION" usage:"Region of the bucket (default: store.s3.region)"`
+	Endpoint    string `yaml:"endpoint" env:"BLOB_ENDPOINT" usage:"Endpoint of the bucket, e.g. https://storage.googleapis.com (default: store.s3.endpoint)"`
+	Prefix      string `yaml:"prefix" env:"BLOB_PREFIX" usage:"Prepended to every key plugins use"`
+	MaxGetBytes int64  `yaml:"max_get_bytes" env:"BLOB_MAX_GET_BYTES" default:"67108864" check:"positive" usage:"Largest object a plugin may read"`
+	MaxPutBytes int64  `yaml:"max_put_bytes" env:"BLOB_MAX_PUT_BYTES" default:"67108864" check:"positive" usage:"Largest object a plugin may write"`
+}
+
+// Outbox configures the delivery of plugin effects.
+type Outbox struct {
+	HTTPHosts  []string `yaml:"http_hosts" env:"OUTBOX_HTTP_HOSTS" usage:"Hosts plugins may call through the outbox (comma-separated in the environment and flags)"`
+	MessageURL string   `yaml:"message_url" env:"OUTBOX_MESSAGE_URL" usage:"Bridge plugin messages are published through"`
+	Dir        string   `yaml:"dir" env:"OUTBOX_DIR" usage:"Directory keeping undelivered effects across restarts"`
+}
+
+// Enabled reports whether plugin effects are delivered.
+func (o Outbox) Enabled() bool {
+	return len(o.HTTPHosts) > 0 || o.MessageURL != ""
+}
+
+// Tracing configures OpenTelemetry tracing. The environment variables are
+// those of the OpenTelemetry SDKs.
+type Tracing struct {
+	OTLPEndpoint string   `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"OTLP/HTTP collector spans are exported to, e.g. http://otel-collector:4318; no tracing without one"`
+	OTLPHeaders  []string `yaml:"otlp_headers" env:"OTEL_EXPORTER_OTLP_HEADERS" secret:"true" usage:"Headers sent to the collector, as key=value"`
+	ServiceName  string   `yaml:"service_name" env:"OTEL_SERVICE_NAME" default:"wasm-plugin-server" usage:"service.name of the exported spans"`
+	SampleRatio  float64  `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" default:"1" usage:"Fraction of new traces recorded; requests continuing a trace follow its caller's decision"`
+}
+
+// Headers returns the OTLP headers by name.
+func (t Tracing) Headers() map[string]string {
+	headers := make(map[string]string, len(t.OTLPHeaders))
+	for _, header := range t.OTLPHeaders {
+		name, value, _ := strings.Cut(header, "=")
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// Schedules configures the scheduler running the periodic executions
+// plugin manifests declare.
+type Schedules struct {
+	Enabled      bool          `yaml:"enabled" env:"SCHEDULES_ENABLED" default:"true" usage:"Run manifest schedules; turn off on all but one replica sharing a store"`
+	SyncInterval time.Duration `yaml:"sync_interval" env:"SCHEDULES_SYNC_INTERVAL" default:"1m" check:"positive" usage:"How often the store is rescanned for schedules of plugins published elsewhere"`
+}
+
+// Maintenance configures the windows during which executions are paused.
+type Maintenance struct {
+	Dir string `yaml:"dir" env:"MAINTENANCE_DIR" usage:"Directory keeping maintenance windows across restarts, shared by replicas"`
+}
+
+// Batches configures the bulk runs of a plugin over files of inputs.
+type Batches struct {
+	Dir            string `yaml:"dir" env:"BATCH_DIR" usage:"Directory, e.g. on the Fluid mount, batch inputs are read from and results written to; empty disables batches"`
+	MaxConcurrency int    `yaml:"max_concurrency" env:"BATCH_MAX_CONCURRENCY" default:"4" check:"positive" usage:"Lines of a batch run at once"`
+}
+
+// Jobs configures the asynchronous runs of POST /jobs.
+type Jobs struct {
+	Workers   int           `yaml:"workers" env:"JOB_WORKERS" default:"4" check:"positive" usage:"Jobs run at once"`
+	MaxQueued int           `yaml:"max_queued" env:"JOB_MAX_QUEUED" default:"1000" check:"positive" usage:"Jobs that may wait to run; more are rejected with 429"`
+	Timeout   time.Duration `yaml:"timeout" env:"JOB_TIMEOUT" default:"1h" check:"positive" usage:"Execution timeout of a job, replacing execution.timeout"`
+	Retention time.Duration `yaml:"retention" env:"JOB_RETENTION" default:"1h" check:"positive" usage:"How long finished jobs and their results are kept"`
+	Dir       string        `yaml:"dir" env:"JOB_DIR" usage:"Directory jobs are kept in across restarts; empty keeps them in memory"`
+}
+
+// Streams configures the WebSocket connections of /run/ws.
+type Streams struct {
+	IdleTimeout    time.Duration `yaml:"idle_timeout" env:"STREAM_IDLE_TIMEOUT" default:"5m" usage:"Connections sending no message for that long are closed; 0 keeps them open"`
+	MaxMessageSize int64         `yaml:"max_message_size" env:"STREAM_MAX_MESSAGE_SIZE" default:"1048576" check:"positive" usage:"Largest message a client may send; larger ones close the connection"`
+}
+
+// Auth configures how API clients authenticate.
+type Auth struct {
+	File string `yaml:"file" env:"AUTH_CONFIG_FILE" usage:"JSON file of API keys and the JWT issuer clients authenticate with, and the plugins each may run; empty leaves the API open"`
+
+	// Delegation mints the tokens plugin calls to bridges and outbox
+	// effects carry for the client the plugin runs for
+	Delegation Delegation `yaml:"delegation"`
+}
+
+// Delegation configures the short-lived tokens the server signs for the
+// outbound calls a plugin makes on behalf of an authenticated client.
+type Delegation struct {
+	KeyFile string        `yaml:"key_file" env:"DELEGATION_KEY_FILE" usage:"PEM Ed25519 private key signing the tokens of host module bridge calls and outbox effects, in place of their configured credentials; requires auth.file"`
+	Issuer  string        `yaml:"issuer" env:"DELEGATION_ISSUER" default:"wasm-plugin-server" usage:"iss claim of delegated tokens"`
+	TTL     time.Duration `yaml:"ttl" env:"DELEGATION_TOKEN_TTL" default:"1m" check:"positive" usage:"Lifetime of a delegated token"`
+}
+
+// Locality configures routing /run requests to the replica on the node
+// whose Fluid cache holds the plugin and its data.
+type Locality struct {
+	CacheURL  string        `yaml:"cache_url" env:"LOCALITY_CACHE_URL" usage:"Alluxio proxy REST API of the Fluid runtime caching the plugin dataset, e.g. http://plugins-master-0:39999; empty disables locality routing"`
+	CacheRoot string        `yaml:"cache_root" env:"LOCALITY_CACHE_ROOT" default:"/" usage:"Path of the plugin dataset in the cache namespace"`
+	Node      string        `yaml:"node" env:"LOCALITY_NODE" usage:"Host of the node this replica runs on, as cache workers report it"`
+	Peers     []string      `yaml:"peers" env:"LOCALITY_PEERS" usage:"Replicas on other nodes, as node=URL"`
+	TTL       time.Duration `yaml:"ttl" env:"LOCALITY_TTL" default:"30s" check:"positive" usage:"How long a plugin's placement is reused before the cache state is queried again"`
+}
+
+// PeerURLs returns the base URLs of the replicas on other nodes, by node.
+func (l Locality) PeerURLs() map[string]string {
+	peers := make(map[string]string, len(l.Peers))
+	for _, peer := range l.Peers {
+		node, url, _ := strings.Cut(peer, "=")
+		peers[strings.TrimSpace(node)] = strings.TrimSpace(url)
+	}
+	return peers
+}
+
+// CacheStats configures exporting the cache metrics of the Fluid runtime
+// behind the plugin dataset.
+type CacheStats struct {
+	Runtime  string        `yaml:"runtime" env:"CACHE_STATS_RUNTIME" default:"alluxio" usage:"Fluid runtime caching the plugin dataset: alluxio or juicefs"`
+	URL      string        `yaml:"url" env:"CACHE_STATS_URL" usage:"Metrics endpoint of the runtime: the Alluxio master web server, e.g. http://plugins-master-0:19999, or the Prometheus endpoint of a JuiceFS client; empty disables cache metrics"`
+	Dataset  string        `yaml:"dataset" env:"CACHE_STATS_DATASET" default:"plugins" usage:"Dataset name the cache metrics are labeled with"`
+	Interval time.Duration `yaml:"interval" env:"CACHE_STATS_INTERVAL" default:"15s" check:"positive" usage:"How often the runtime's metrics are read"`
+}
+
+// Verify configures the job re-verifying every plugin build in the store.
+type Verify struct {
+	Interval time.Duration `yaml:"interval" env:"VERIFY_INTERVAL" usage:"How often every plugin build in the store is checked for its digest, signature, and loading on this engine, starting at startup; 0 disables"`
+}
+
+// Shadow configures repeating sampled executions on a second engine.
+type Shadow struct {
+	Engine        string   `yaml:"engine" env:"SHADOW_ENGINE" usage:"Engine registered with runtime.RegisterEngine that sampled executions of shadow.plugins are repeated on, comparing output and latency; empty disables shadowing"`
+	Plugins       []string `yaml:"plugins" env:"SHADOW_PLUGINS" usage:"Plugins whose executions are shadowed; the shadow call skips the side effects of the built-in host APIs, but not of HOST_MODULES_FILE modules (comma-separated in the environment and flags)"`
+	Ratio         float64  `yaml:"ratio" env:"SHADOW_RATIO" default:"0.01" usage:"Fraction of the successful executions of shadow.plugins that are repeated"`
+	MaxConcurrent int      `yaml:"max_concurrent" env:"SHADOW_MAX_CONCURRENT" default:"4" check:"positive" usage:"Shadow runs in flight at once; sampled executions beyond are skipped"`
+}
+
+// RateLimits configures the request rate limits of /run clients.
+type RateLimits struct {
+	File        string `yaml:"file" env:"RATE_LIMITS_FILE" usage:"JSON file of request rate limits by plugin and API key; empty disables rate limiting"`
+	TrustProxy  bool   `yaml:"trust_proxy" env:"RATE_LIMIT_TRUST_PROXY" usage:"Identify clients without an API key by X-Forwarded-For, as set by a reverse proxy"`
+	TrustedHops int    `yaml:"trusted_hops" env:"RATE_LIMIT_TRUSTED_HOPS" default:"1" check:"positive" usage:"Reverse proxies in front of the server that append to X-Forwarded-For; the client is the entry this many from the right, so entries the client sent itself are ignored"`
+}
+
+// Tenants configures the quotas of tenants.
+type Tenants struct {
+	File string `yaml:"file" env:"TENANTS_FILE" usage:"JSON file of request rate, concurrency, and memory quotas by tenant; empty leaves tenants unlimited"`
+}
+
+// Default returns the configuration with every setting at its default.
+func Default() *Config {
+	cfg := &Config{}
+	for _, s := range settings(cfg) {
+		if s.def != "" {
+			if err := s.set(s.def); err != nil {
+				panic(fmt.Sprintf("config: invalid default of %s: %v", s.key, err))
+			}
+		}
+	}
+	return cfg
+}
+
+// RedactedValue replaces the secret settings of a Redacted configuration.
+const RedactedValue = "[redacted]"
+
+// Redacted returns a copy of c whose secret settings, where set, read
+// RedactedValue, for showing the configuration to operators.
+func (c *Config) Redacted() *Config {
+	out := *c
+	for _, s := range settings(&out) {
+		if !s.secret || s.value.IsZero() {
+			continue
+		}
+		switch s.value.Kind() {
+		case reflect.String:
+			s.value.SetString(RedactedValue)
+		case reflect.Slice:
+			s.value.Set(reflect.ValueOf([]string{RedactedValue}))
+		}
+	}
+	return &out
+}
+
+// Load builds the configuration from the defaults, the YAML file, the
+// environment read with getenv, and the command-line arguments args, in
+// that order, and validates it. It returns flag.ErrHelp for -h.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := flags.String("config", getenv("CONFIG_FILE"), "YAML configuration file (env CONFIG_FILE)")
+	for _, s := range settings(cfg) {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		if s.value.Kind() == reflect.Bool {
+			// -demo alone turns a boolean on, as with flag.Bool
+			flags.Var(&boolFlag{text: s.def}, s.flag, usage)
+			continue
+		}
+		flags.String(s.flag, s.def, usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	byFlag := make(map[string]setting)
+	for _, s := range settings(cfg) {
+		byFlag[s.flag] = s
+		if value := getenv(s.env); value != "" {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", s.env, value, err)
+			}
+		}
+	}
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok && err == nil {
+			if setErr := s.set(f.Value.String()); setErr != nil {
+				err = fmt.Errorf("invalid -%s %q: %v", f.Name, f.Value.String(), setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// boolFlag is the flag of a boolean setting. It keeps the text, which Load
+// parses with the setting's other sources, but may be given without one.
+type boolFlag struct {
+	text string
+}
+
+func (f *boolFlag) String() string        { return f.text }
+func (f *boolFlag) Set(text string) error { f.text = text; return nil }
+func (f *boolFlag) IsBoolFlag() bool      { return true }
+
+// readFile merges the YAML file at path onto c. Unknown keys are errors,
+// so a misspelled setting does not silently keep its default.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks the settings and how they combine.
+func (c *Config) Validate() error {
+	for _, s := range settings(c) {
+		var n int64
+		switch s.value.Kind() {
+		case reflect.Int, reflect.Int64:
+			n = s.value.Int()
+		default:
+			continue
+		}
+		if s.positive && n <= 0 {
+			return fmt.Errorf("%s (%s) must be positive, got %s", s.key, s.env, s)
+		}
+		if n < 0 {
+			return fmt.Errorf("%s (%s) must not be negative, got %s", s.key, s.env, s)
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log.level (LOG_LEVEL) must be debug, info, warn, or error, got %q", c.Log.Level)
+	}
+	switch c.Store.Type {
+	case "local", "fluid", "http", "memory":
+	case "s3":
+		if c.Store.S3.Bucket == "" {
+			return errors.New("store.s3.bucket (S3_BUCKET) is required by the s3 store")
+		}
+	default:
+		// Stores outside the fluid package register in init functions,
+		// which have run by now
+		if !slices.Contains(fluid.RegisteredStores(), c.Store.Type) {
+			return fmt.Errorf("store.type (PLUGIN_STORE) must be %s, got %q",
+				strings.Join(slices.Concat(fluid.BuiltinStores, fluid.RegisteredStores()), ", "), c.Store.Type)
+		}
+	}
+	if c.Store.Type == "http" && c.Store.HTTP.BaseURL == "" {
+		return errors.New("store.http.base_url (PLUGIN_BASE_URL) is required by the http store")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("tls.cert_file (TLS_CERT_FILE) and tls.key_file (TLS_KEY_FILE) must be set together")
+	}
+	if c.Execution.MaxMemoryPages > MaxMemoryPages {
+		return fmt.Errorf("execution.max_memory_pages (MAX_MEMORY_PAGES) must not exceed %d (4 GiB), got %d", MaxMemoryPages, c.Execution.MaxMemoryPages)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio (OTEL_TRACES_SAMPLER_ARG) must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	if c.Shadow.Ratio < 0 || c.Shadow.Ratio > 1 {
+		return fmt.Errorf("shadow.ratio (SHADOW_RATIO) must be between 0 and 1, got %g", c.Shadow.Ratio)
+	}
+	if c.Shadow.Engine != "" && len(c.Shadow.Plugins) == 0 {
+		return errors.New("shadow.plugins (SHADOW_PLUGINS) is required by shadow.engine (SHADOW_ENGINE)")
+	}
+	if c.Shadow.Engine != "" && c.Shadow.Engine == c.Execution.Engine {
+		return fmt.Errorf("shadow.engine (SHADOW_ENGINE) must differ from execution.engine (ENGINE), got %s for both", c.Shadow.Engine)
+	}
+	if c.Plugins.AOTCacheDir != "" && c.Execution.Engine != "wasmedge" {
+		return fmt.Errorf("plugins.aot_cache_dir (AOT_CACHE_DIR) needs execution.engine (ENGINE) wasmedge, got %s", c.Execution.Engine)
+	}
+	for _, header := range c.Tracing.OTLPHeaders {
+		if name, _, ok := strings.Cut(header, "="); !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("tracing.otlp_headers (OTEL_EXPORTER_OTLP_HEADERS) must be key=value pairs, got %q", header)
+		}
+	}
+	for _, v := range c.WASI.Env {
+		if name, _, ok := strings.Cut(v, "="); !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("wasi.env (WASI_ENV) must be NAME=value pairs, got %q", v)
+		}
+	}
+	for _, dir := range c.WASI.Dirs {
+		if guest, host, ok := strings.Cut(dir, "="); !ok || !path.IsAbs(strings.TrimSpace(guest)) || strings.TrimSpace(host) == "" {
+			return fmt.Errorf("wasi.dirs (WASI_DIRS) must be guest=host pairs with absolute guest paths, got %q", dir)
+		}
+	}
+	switch c.CacheStats.Runtime {
+	case "alluxio", "juicefs":
+	default:
+		return fmt.Errorf("cache_stats.runtime (CACHE_STATS_RUNTIME) must be alluxio or juicefs, got %q", c.CacheStats.Runtime)
+	}
+	if c.Pool.MaxSize > 0 && c.Pool.MinSize > c.Pool.MaxSize {
+		return fmt.Errorf("pool.min_size (POOL_MIN_SIZE) %d exceeds pool.max_size (POOL_MAX_SIZE) %d", c.Pool.MinSize, c.Pool.MaxSize)
+	}
+	return nil
+}
+
+// setting is one field of a Config, described by its tags.
+type setting struct {
+	key      string // Dotted YAML path, e.g. pool.max_size
+	env      string
+	flag     string // The key with dashes, e.g. pool-max-size
+	def      string
+	usage    string
+	positive bool
+	secret   bool
+	value    reflect.Value
+}
+
+// settings returns the settings of cfg in declaration order.
+func settings(cfg *Config) []setting {
+	var out []setting
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := prefix + field.Tag.Get("yaml")
+			if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			out = append(out, setting{
+				key:      key,
+				env:      field.Tag.Get("env"),
+				flag:     strings.NewReplacer(".", "-", "_", "-").Replace(key),
+				def:      field.Tag.Get("default"),
+				usage:    field.Tag.Get("usage"),
+				positive: field.Tag.Get("check") == "positive",
+				secret:   field.Tag.Get("secret") == "true",
+				value:    v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return out
+}
+
+// set parses value into the setting. Booleans also accept on and off,
+// as in MODULE_CACHE=off, and lists are comma-separated.
+func (s setting) set(value string) error {
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(value)
+	case bool:
+		switch strings.ToLower(value) {
+		case "on":
+			s.value.SetBool(true)
+		case "off":
+			s.value.SetBool(false)
+		default:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.New("must be true or false")
+			}
+			s.value.SetBool(b)
+		}
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration, e.g. 30s")
+		}
+		s.value.SetInt(int64(d))
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		s.value.SetFloat(f)
+	case int, int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		s.value.SetInt(n)
+	case []string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(list))
+	default:
+		panic(fmt.Sprintf("config: unsupported type of %s", s.key))
+	}
+	return nil
+}
+
+// String formats the setting's current value the way set parses it.
+func (s setting) String() string {
+	switch v := s.value.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,225 @@
+package config_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+func init() {
+	// A store backend registered from outside the fluid package
+	fluid.RegisterStore("gcs", func(fluid.StoreConfig) (fluid.PluginStore, error) {
+		return fluid.NewLocalPluginStore("."), nil
+	})
+}
+
+var _ = Describe("Load", func() {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	writeFile := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "server.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	It("should default every setting", func() {
+		cfg, err := config.Load(nil, env(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg).To(Equal(config.Default()))
+		Expect(cfg.Listen.HTTP).To(Equal(":8080"))
+		Expect(cfg.Store.Type).To(Equal("local"))
+		Expect(cfg.Store.Watch).To(BeTrue())
+		Expect(cfg.Execution.Timeout).To(Equal(30 * time.Second))
+		Expect(cfg.Pool).To(Equal(config.Pool{}))
+	})
+
+	// =========================================================================
+	// TEST: Precedence
+	// Why: A deployment checks in a file and overrides single settings per
+	//      environment or invocation; the more specific source must win.
+	// =========================================================================
+	It("should layer the file, the environment, and flags", func() {
+		path := writeFile(`
+listen:
+  http: ":8443"
+  grpc: ":9443"
+pool:
+  min_size: 1
+  max_size: 4
+  idle_timeout: 5m
+outbox:
+  http_hosts: [api.example.com]
+`)
+		cfg, err := config.Load([]string{"-pool-max-size=8", "-listen-grpc", ":9999"}, env(map[string]string{
+			"CONFIG_FILE":   path,
+			"POOL_MAX_SIZE": "6",
+			"POOL_MIN_SIZE": "2",
+			"MODULE_CACHE":  "off",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Listen.HTTP).To(Equal(":8443"))
+		Expect(cfg.Listen.GRPC).To(Equal(":9999"))
+		Expect(cfg.Pool.MinSize).To(Equal(2))
+		Expect(cfg.Pool.MaxSize).To(Equal(8))
+		Expect(cfg.Pool.IdleTimeout).To(Equal(5 * time.Minute))
+		Expect(cfg.Plugins.ModuleCache).To(BeFalse())
+		Expect(cfg.Outbox.HTTPHosts).To(Equal([]string{"api.example.com"}))
+	})
+
+	It("should turn booleans on by their flag alone", func() {
+		cfg, err := config.Load([]string{"-demo", "-store-type=memory"}, env(map[string]string{"MODULE_CACHE": "off"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Demo).To(BeTrue())
+		Expect(cfg.Store.Type).To(Equal("memory"))
+		Expect(cfg.Plugins.ModuleCache).To(BeFalse())
+
+		cfg, err = config.Load([]string{"-store-watch=false", "-plugins-module-cache"}, env(map[string]string{"MODULE_CACHE": "off"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Store.Watch).To(BeFalse())
+		Expect(cfg.Plugins.ModuleCache).To(BeTrue())
+	})
+
+	It("should take the file from -config and split lists", func() {
+		path := writeFile("log:\n  level: debug\n")
+		cfg, err := config.Load([]string{"-config", path}, env(map[string]string{
+			"OUTBOX_HTTP_HOSTS": "a.example.com, b.example.com",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Log.Level).To(Equal("debug"))
+		Expect(cfg.Outbox.HTTPHosts).To(Equal([]string{"a.example.com", "b.example.com"}))
+		Expect(cfg.Outbox.Enabled()).To(BeTrue())
+	})
+
+	It("should read OpenTelemetry's exporter variables", func() {
+		cfg, err := config.Load(nil, env(map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318",
+			"OTEL_EXPORTER_OTLP_HEADERS":  "api-key=secret,tenant = acme",
+			"OTEL_TRACES_SAMPLER_ARG":     "0.25",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Tracing.OTLPEndpoint).To(Equal("http://collector:4318"))
+		Expect(cfg.Tracing.Headers()).To(Equal(map[string]string{"api-key": "secret", "tenant": "acme"}))
+		Expect(cfg.Tracing.SampleRatio).To(Equal(0.25))
+		Expect(cfg.Tracing.ServiceName).To(Equal("wasm-plugin-server"))
+	})
+
+	It("should read locality peers by node", func() {
+		cfg, err := config.Load(nil, env(map[string]string{
+			"LOCALITY_PEERS": "node-b=http://10.0.0.2:8080, node-c = http://10.0.0.3:8080",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Locality.PeerURLs()).To(Equal(map[string]string{
+			"node-b": "http://10.0.0.2:8080",
+			"node-c": "http://10.0.0.3:8080",
+		}))
+		Expect(cfg.Locality.TTL).To(Equal(30 * time.Second))
+	})
+
+	It("should accept registered stores and their options", func() {
+		cfg, err := config.Load(nil, env(map[string]string{
+			"PLUGIN_STORE":         "gcs",
+			"PLUGIN_STORE_OPTIONS": "bucket=my-plugins, prefix = prod/",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Store.OptionMap()).To(Equal(map[string]string{"bucket": "my-plugins", "prefix": "prod/"}))
+	})
+
+	It("should reject unknown keys in the file", func() {
+		path := writeFile("pool:\n  max_szie: 4\n")
+		_, err := config.Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+		Expect(err).To(MatchError(ContainSubstring("max_szie")))
+	})
+
+	It("should return flag.ErrHelp for -h", func() {
+		_, err := config.Load([]string{"-h"}, env(nil))
+		Expect(err).To(MatchError(flag.ErrHelp))
+	})
+
+	DescribeTable("invalid values",
+		func(vars map[string]string, message string) {
+			_, err := config.Load(nil, env(vars))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("non-numeric size", map[string]string{"POOL_MAX_SIZE": "lots"}, "POOL_MAX_SIZE"),
+		Entry("negative size", map[string]string{"POOL_MIN_SIZE": "-1"}, "POOL_MIN_SIZE"),
+		Entry("bad duration", map[string]string{"POOL_IDLE_TIMEOUT": "5"}, "POOL_IDLE_TIMEOUT"),
+		Entry("negative health interval", map[string]string{"HEALTH_CHECK_INTERVAL": "-1s"}, "HEALTH_CHECK_INTERVAL"),
+		Entry("min above max", map[string]string{"POOL_MIN_SIZE": "4", "POOL_MAX_SIZE": "2"}, "exceeds"),
+		Entry("memory beyond wasm32", map[string]string{"MAX_MEMORY_PAGES": "65537"}, "MAX_MEMORY_PAGES"),
+		Entry("zero dedup TTL", map[string]string{"DEDUP_TTL": "0s"}, "DEDUP_TTL"),
+		Entry("bad boolean", map[string]string{"REQUIRE_DIGEST": "maybe"}, "REQUIRE_DIGEST"),
+		Entry("unknown store", map[string]string{"PLUGIN_STORE": "ftp"}, "PLUGIN_STORE"),
+		Entry("s3 store without bucket", map[string]string{"PLUGIN_STORE": "s3"}, "S3_BUCKET"),
+		Entry("http store without URL", map[string]string{"PLUGIN_STORE": "http"}, "PLUGIN_BASE_URL"),
+		Entry("certificate without key", map[string]string{"TLS_CERT_FILE": "tls.crt"}, "TLS_KEY_FILE"),
+		Entry("unknown log level", map[string]string{"LOG_LEVEL": "verbose"}, "LOG_LEVEL"),
+		Entry("zero cache quota", map[string]string{"CACHE_QUOTA_BYTES": "0"}, "CACHE_QUOTA_BYTES"),
+		Entry("non-numeric blob cap", map[string]string{"BLOB_MAX_PUT_BYTES": "lots"}, "BLOB_MAX_PUT_BYTES"),
+		Entry("sample ratio above one", map[string]string{"OTEL_TRACES_SAMPLER_ARG": "1.5"}, "OTEL_TRACES_SAMPLER_ARG"),
+		Entry("unknown cache runtime", map[string]string{"CACHE_STATS_RUNTIME": "jindo"}, "CACHE_STATS_RUNTIME"),
+		Entry("header without value", map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "api-key"}, "OTEL_EXPORTER_OTLP_HEADERS"),
+		Entry("WASI variable without value", map[string]string{"WASI_ENV": "TZ"}, "WASI_ENV"),
+		Entry("relative WASI guest directory", map[string]string{"WASI_DIRS": "data=/srv/data"}, "WASI_DIRS"),
+		Entry("negative shadow ratio", map[string]string{"SHADOW_RATIO": "-0.1"}, "SHADOW_RATIO"),
+		Entry("shadow engine without plugins", map[string]string{"SHADOW_ENGINE": "wazero"}, "SHADOW_PLUGINS"),
+		Entry("shadow engine running the plugins", map[string]string{"ENGINE": "wazero", "SHADOW_ENGINE": "wazero", "SHADOW_PLUGINS": "hello"}, "SHADOW_ENGINE"),
+		Entry("AOT compilation on another engine", map[string]string{"ENGINE": "wazero", "AOT_CACHE_DIR": "/var/cache/aot"}, "AOT_CACHE_DIR"),
+	)
+
+	It("should reject invalid flags and stray arguments", func() {
+		_, err := config.Load([]string{"-pool-max-size=lots"}, env(nil))
+		Expect(err).To(MatchError(ContainSubstring("-pool-max-size")))
+		_, err = config.Load([]string{"serve"}, env(nil))
+		Expect(err).To(MatchError(ContainSubstring("serve")))
+	})
+})
+
+var _ = Describe("Redacted", func() {
+	// =========================================================================
+	// TEST: Secrets stay hidden
+	// Why: Operators inspect the running configuration through the admin
+	//      API and in support tickets; credentials must not travel with it.
+	// =========================================================================
+	It("should hide the secret settings that are set", func() {
+		cfg := config.Default()
+		cfg.Admin.Token = "admin-secret"
+		cfg.AWS.SecretAccessKey = "aws-secret"
+		cfg.Tracing.OTLPHeaders = []string{"authorization=Bearer otlp-secret"}
+		cfg.Store.S3.Bucket = "plugins"
+
+		redacted := cfg.Redacted()
+		Expect(redacted.Admin.Token).To(Equal(config.RedactedValue))
+		Expect(redacted.AWS.SecretAccessKey).To(Equal(config.RedactedValue))
+		Expect(redacted.Tracing.OTLPHeaders).To(Equal([]string{config.RedactedValue}))
+		Expect(redacted.AWS.SessionToken).To(BeEmpty(), "unset secrets stay unset")
+		Expect(redacted.Store.S3.Bucket).To(Equal("plugins"))
+
+		// The original is untouched
+		Expect(cfg.Admin.Token).To(Equal("admin-secret"))
+		Expect(cfg.Tracing.OTLPHeaders).To(Equal([]string{"authorization=Bearer otlp-secret"}))
+	})
+})
+
+var _ = Describe("Reference", func() {
+	// =========================================================================
+	// TEST: Checked-in reference matches the settings
+	// Why: Adding or changing a setting without regenerating CONFIG.md
+	//      would leave deployments documented against the wrong schema.
+	// =========================================================================
+	It("should match the checked-in CONFIG.md", func() {
+		checkedIn, err := os.ReadFile(filepath.Join("..", "CONFIG.md"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(string(config.Reference())).To(Equal(string(checkedIn)),
+			"CONFIG.md is stale; run: go run ./config/gen CONFIG.md")
+	})
+})
@@ -0,0 +1,24 @@
+// Command gen writes the server configuration reference.
+//
+// Usage (from the repository root):
+//
+//	go run ./config/gen CONFIG.md
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mrhapile/wasm-plugin-system/config"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gen <CONFIG.md>")
+		os.Exit(2)
+	}
+	if err := os.WriteFile(os.Args[1], config.Reference(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "gen: %v\n", err)
+		os.Exit(1)
+	}
+}
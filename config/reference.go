@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Reference returns CONFIG.md, the Markdown reference of every setting:
+// its YAML key, environment variable, flag, default, and description.
+func Reference() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`# Server Configuration
+
+<!-- Generated by go run ./config/gen CONFIG.md; do not edit. -->
+
+The server reads each setting from, in increasing precedence, its
+default, the YAML file named by ` + "`-config`" + ` or ` + "`CONFIG_FILE`" + `,
+its environment variable, and its flag. An empty environment variable
+counts as unset. Booleans also accept ` + "`on`" + ` and ` + "`off`" + `, and
+their flags alone, like ` + "`-demo`" + `, turn them on;
+durations are Go durations like ` + "`30s`" + `, and lists are YAML
+sequences, comma-separated in the environment and flags.
+
+` + "```yaml" + `
+listen:
+  http: ":8443"
+tls:
+  cert_file: /etc/wasm-plugin/tls.crt
+  key_file: /etc/wasm-plugin/tls.key
+store:
+  type: s3
+  s3:
+    bucket: my-plugins
+pool:
+  max_size: 8
+` + "```" + `
+`)
+
+	section := ""
+	for _, s := range settings(Default()) {
+		if top, _, _ := strings.Cut(s.key, "."); top != section {
+			section = top
+			fmt.Fprintf(&buf, "\n## %s\n\n", section)
+			buf.WriteString("| Key | Environment | Flag | Default | Description |\n")
+			buf.WriteString("|-----|-------------|------|---------|-------------|\n")
+		}
+		def := ""
+		if s.def != "" {
+			def = "`" + s.def + "`"
+		}
+		fmt.Fprintf(&buf, "| `%s` | `%s` | `-%s` | %s | %s |\n",
+			s.key, s.env, s.flag, def, strings.ReplaceAll(s.usage, "|", `\|`))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,242 @@
+// Package cron parses cron expressions and computes when they next fire.
+//
+// An expression has the five standard fields, evaluated in UTC:
+//
+//	┌──────── minute        0-59
+//	│ ┌────── hour          0-23
+//	│ │ ┌──── day of month  1-31
+//	│ │ │ ┌── month         1-12 or jan-dec
+//	│ │ │ │ ┌ day of week   0-6 or sun-sat (7 is also Sunday)
+//	* * * * *
+//
+// Each field is "*", a value, a range "a-b", or a comma-separated list of
+// them, each optionally stepped with "/n". As in Vixie cron, when both the
+// day of month and the day of week are restricted, a day matching either
+// fires. The descriptors @yearly (@annually), @monthly, @weekly, @daily
+// (@midnight), and @hourly abbreviate common expressions, and
+// "@every <duration>" fires at a fixed interval, e.g. "@every 90s".
+//
+// Like wasminfo, the package is pure Go and usable without WasmEdge.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned for expressions that cannot be parsed.
+var ErrInvalid = errors.New("invalid cron expression")
+
+// MinInterval is the shortest "@every" interval accepted.
+const MinInterval = time.Second
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	expr string
+
+	// Field bit sets; bit n is set if value n matches
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // The day field was "*" (or "?")
+
+	every time.Duration // Fixed interval of "@every"; zero for field schedules
+}
+
+// descriptors maps the @ shorthands to their expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field describes the values one field accepts.
+type field struct {
+	name     string
+	min, max int
+	names    []string // Names of min, min+1, ...; nil if none
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12,
+		names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// 7 is accepted as Sunday and folded into 0
+	dowField = field{name: "day of week", min: 0, max: 7,
+		names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalid, expr, err)
+		}
+		if every < MinInterval {
+			return nil, fmt.Errorf("%w: %q: interval must be at least %s", ErrInvalid, expr, MinInterval)
+		}
+		return &Schedule{expr: expr, every: every}, nil
+	}
+
+	fields := strings.Fields(expr)
+	if strings.HasPrefix(expr, "@") {
+		standard, ok := descriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown descriptor %q", ErrInvalid, expr)
+		}
+		fields = strings.Fields(standard)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: want 5 fields, got %d", ErrInvalid, expr, len(fields))
+	}
+
+	s := &Schedule{expr: expr}
+	var err error
+	parse := func(text string, f field) uint64 {
+		if err != nil {
+			return 0
+		}
+		var bits uint64
+		bits, err = f.parse(text)
+		if err != nil {
+			err = fmt.Errorf("%w: %q: %v", ErrInvalid, expr, err)
+		}
+		return bits
+	}
+	s.minute = parse(fields[0], minuteField)
+	s.hour = parse(fields[1], hourField)
+	s.dom = parse(fields[2], domField)
+	s.month = parse(fields[3], monthField)
+	s.dow = parse(fields[4], dowField)
+	if err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = isStar(fields[2])
+	s.dowStar = isStar(fields[4])
+	return s, nil
+}
+
+// isStar reports whether a day field leaves the day unrestricted.
+func isStar(text string) bool {
+	return text == "*" || text == "?"
+}
+
+// parse parses a field into its bit set.
+func (f field) parse(text string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s step %q must be a positive number", f.name, stepText)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangeText == "*" || rangeText == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangeText, "-"):
+			loText, hiText, _ := strings.Cut(rangeText, "-")
+			var err error
+			if lo, err = f.value(loText); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiText); err != nil {
+				return 0, err
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("%s range %s is backwards", f.name, rangeText)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rangeText); err != nil {
+				return 0, err
+			}
+			// "5/15" means from 5 to the end in steps of 15
+			hi = lo
+			if stepped {
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value of the field: a number or a name.
+func (f field) value(text string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(text, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s %q must be between %d and %d", f.name, text, f.min, f.max)
+	}
+	return n, nil
+}
+
+// String returns the expression as it was parsed.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// maxSearch bounds the search for the next activation; an expression like
+// "0 0 30 2 *" never fires.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first activation strictly after t, in UTC, or the zero
+// time if the schedule never fires.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC()
+	if s.every > 0 {
+		return t.Truncate(time.Second).Add(s.every)
+	}
+
+	limit := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day fields.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
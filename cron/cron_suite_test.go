@@ -0,0 +1,15 @@
+package cron_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestCron bootstraps the Ginkgo test suite for the cron package.
+// Run with: go test -v ./cron/...
+func TestCron(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cron Suite")
+}
@@ -0,0 +1,81 @@
+package cron_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/cron"
+)
+
+var _ = Describe("Schedule", func() {
+	// Friday, 2026-10-16 10:17:30 UTC
+	now := time.Date(2026, 10, 16, 10, 17, 30, 0, time.UTC)
+
+	next := func(expr string, from time.Time) time.Time {
+		s, err := cron.Parse(expr)
+		Expect(err).NotTo(HaveOccurred())
+		return s.Next(from)
+	}
+
+	// =========================================================================
+	// TEST: Standard expressions
+	// Why: Manifest schedules are written by plugin authors used to crontab;
+	//      each field form must fire when crontab would.
+	// =========================================================================
+	DescribeTable("should compute the next activation",
+		func(expr string, want time.Time) {
+			Expect(next(expr, now)).To(Equal(want))
+		},
+		Entry("every minute", "* * * * *", time.Date(2026, 10, 16, 10, 18, 0, 0, time.UTC)),
+		Entry("a step", "*/15 * * * *", time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)),
+		Entry("a stepped start", "5/20 * * * *", time.Date(2026, 10, 16, 10, 25, 0, 0, time.UTC)),
+		Entry("a list and range", "0 9-11,14 * * *", time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC)),
+		Entry("the next day", "30 2 * * *", time.Date(2026, 10, 17, 2, 30, 0, 0, time.UTC)),
+		Entry("a weekday name", "0 6 * * mon", time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)),
+		Entry("Sunday as 7", "0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)),
+		Entry("a month name", "0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("either day field", "0 0 1 * fri", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)),
+		Entry("a leap day", "0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)),
+		Entry("@hourly", "@hourly", time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC)),
+		Entry("@daily", "@daily", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)),
+		Entry("@weekly", "@weekly", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)),
+		Entry("@every", "@every 90s", time.Date(2026, 10, 16, 10, 19, 0, 0, time.UTC)),
+	)
+
+	It("should fire strictly after the given time", func() {
+		at := time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)
+		Expect(next("30 10 * * *", at)).To(Equal(at.AddDate(0, 0, 1)))
+	})
+
+	It("should evaluate in UTC", func() {
+		local := now.In(time.FixedZone("UTC+2", 2*60*60))
+		Expect(next("0 12 * * *", local)).To(Equal(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	})
+
+	It("should report a schedule that never fires", func() {
+		Expect(next("0 0 30 2 *", now).IsZero()).To(BeTrue())
+	})
+
+	// =========================================================================
+	// TEST: Invalid expressions
+	// Why: A typo in a manifest must be rejected when the plugin is
+	//      published, not silently never run.
+	// =========================================================================
+	DescribeTable("should reject invalid expressions",
+		func(expr string) {
+			_, err := cron.Parse(expr)
+			Expect(err).To(MatchError(cron.ErrInvalid))
+		},
+		Entry("too few fields", "* * * *"),
+		Entry("too many fields", "0 * * * * *"),
+		Entry("out of range", "60 * * * *"),
+		Entry("a backwards range", "0 5-3 * * *"),
+		Entry("a zero step", "*/0 * * * *"),
+		Entry("an unknown name", "0 0 * * funday"),
+		Entry("an unknown descriptor", "@fortnightly"),
+		Entry("a short interval", "@every 10ms"),
+		Entry("a bad interval", "@every soon"),
+	)
+})
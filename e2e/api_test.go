@@ -0,0 +1,198 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+var _ = Describe("HTTP API", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// =========================================================================
+	// TEST: Runs
+	// Why: The server binary, as deployed, must load uploaded plugins from
+	//      its store and run them with each ABI the fixtures use.
+	// =========================================================================
+	Describe("POST /run", func() {
+		It("should run plugins with integer and text inputs", func() {
+			ci := e.client(ciKey)
+			resp, err := ci.Run(ctx, "hello", 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Output).To(HaveValue(Equal(11)))
+
+			text, err := ci.RunString(ctx, "upper", "hello, wasm")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(text).To(Equal("HELLO, WASM"))
+		})
+
+		It("should report unknown plugins", func() {
+			_, err := e.client(adminKey).Run(ctx, "missing", 1)
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginNotFound))
+		})
+
+		It("should cache in Redis", func() {
+			if e.redisURL == "" {
+				Skip("no Redis; set E2E_REDIS_URL or install Docker")
+			}
+			resp, err := e.client(ciKey).Run(ctx, "memo", 7)
+			Expect(err).NotTo(HaveOccurred())
+			// Any other output is 7 plus the code cache_set() failed with
+			Expect(resp.Output).To(HaveValue(Equal(7)))
+
+			value, err := redisCommand(e.redisURL, "GET", "wasm-plugin:cache:memo:e2e")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal("ok"))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Authentication
+	// Why: Every client must authenticate, and may only run the plugins it
+	//      is allowed and, unless an admin, not change the store.
+	// =========================================================================
+	Describe("Authentication", func() {
+		It("should reject requests without valid credentials", func() {
+			_, err := e.client("").Run(ctx, "hello", 1)
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeUnauthorized))
+			_, err = e.client("guess").Run(ctx, "hello", 1)
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeUnauthorized))
+		})
+
+		It("should only let clients run their plugins", func() {
+			reports := e.client(reportsKey)
+			_, err := reports.Run(ctx, "hello", 1)
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeForbidden))
+			_, err = reports.RunString(ctx, "upper", "ok")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should only let admins upload and delete plugins", func() {
+			hello := fixtures("..")["hello"]
+			_, err := e.client(ciKey).UploadPlugin(ctx, "greet", bytes.NewReader(hello))
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeForbidden))
+			Expect(apierror.CodeOf(e.client(ciKey).DeletePlugin(ctx, "hello"))).To(Equal(apierror.CodeForbidden))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Uploads
+	// Why: A plugin uploaded to a running server must be listed and run at
+	//      once, and be gone once deleted.
+	// =========================================================================
+	Describe("POST /plugins", func() {
+		It("should serve uploaded plugins until they are deleted", func() {
+			admin := e.client(adminKey)
+			info, err := admin.UploadPlugin(ctx, "greet", bytes.NewReader(fixtures("..")["hello"]))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Name).To(Equal("greet"))
+
+			plugins, err := admin.Plugins(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugins).To(ContainElement(HaveField("Name", "greet")))
+			resp, err := e.client(ciKey).Run(ctx, "greet", 20)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Output).To(HaveValue(Equal(41)))
+
+			Expect(admin.DeletePlugin(ctx, "greet")).To(Succeed())
+			_, err = e.client(ciKey).Run(ctx, "greet", 20)
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodePluginNotFound))
+		})
+
+		It("should reject modules that are not plugins", func() {
+			_, err := e.client(adminKey).UploadPlugin(ctx, "broken", bytes.NewReader([]byte("not wasm")))
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeInvalidPlugin))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Jobs
+	// Why: A job must run in the background to the same result as /run, and
+	//      only be visible to the client that submitted it.
+	// =========================================================================
+	Describe("POST /jobs", func() {
+		It("should run jobs in the background", func() {
+			ci := e.client(ciKey)
+			job, err := ci.SubmitJob(ctx, client.RunRequest{Plugin: "hello", Input: 20})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() string {
+				job, err = ci.Job(ctx, job.ID)
+				Expect(err).NotTo(HaveOccurred())
+				return job.State
+			}).WithTimeout(30 * time.Second).Should(Equal("succeeded"))
+			Expect(job.Result.Output).To(HaveValue(Equal(41)))
+
+			_, err = e.client(reportsKey).Job(ctx, job.ID)
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeJobNotFound))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Batches
+	// Why: A batch must run every line of its input file and write each
+	//      result, failed lines included, next to it.
+	// =========================================================================
+	Describe("POST /batches", func() {
+		It("should run a plugin over every line of a file", func() {
+			input := filepath.Join(e.batchDir, "numbers.jsonl")
+			// hello cannot take text: the third line fails
+			Expect(os.WriteFile(input, []byte("1\n2\n\"three\"\n\n4\n"), 0644)).To(Succeed())
+
+			admin := e.client(adminKey)
+			batch, err := admin.StartBatch(ctx, client.BatchRequest{Plugin: "hello", Input: "numbers.jsonl"})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() string {
+				batch, err = admin.Batch(ctx, batch.ID)
+				Expect(err).NotTo(HaveOccurred())
+				return batch.State
+			}).WithTimeout(30 * time.Second).Should(Equal("completed"))
+			Expect(batch.Processed).To(Equal(4))
+			Expect(batch.Succeeded).To(Equal(3))
+			Expect(batch.Failed).To(Equal(1))
+
+			results, err := os.Open(filepath.Join(e.batchDir, batch.Output))
+			Expect(err).NotTo(HaveOccurred())
+			defer results.Close()
+			outputs := map[int]int{}
+			scanner := bufio.NewScanner(results)
+			for scanner.Scan() {
+				var result struct {
+					Line   int                 `json:"line"`
+					Result *client.RunResponse `json:"result"`
+					Error  *apierror.Problem   `json:"error"`
+				}
+				Expect(json.Unmarshal(scanner.Bytes(), &result)).To(Succeed())
+				if result.Result != nil {
+					Expect(result.Result.Output).NotTo(BeNil())
+					outputs[result.Line] = *result.Result.Output
+				} else {
+					Expect(result.Line).To(Equal(3))
+					Expect(result.Error).NotTo(BeNil())
+				}
+			}
+			Expect(outputs).To(Equal(map[int]int{1: 3, 2: 5, 5: 9}))
+		})
+
+		It("should be for admins only", func() {
+			_, err := e.client(ciKey).StartBatch(ctx, client.BatchRequest{Plugin: "hello", Input: "numbers.jsonl"})
+			Expect(apierror.CodeOf(err)).To(Equal(apierror.CodeForbidden))
+		})
+	})
+})
@@ -0,0 +1,17 @@
+// Package e2e tests the server end to end, the way it is deployed: the
+// server binary is built and started with the memory store, a Redis cache,
+// API key authentication, and batches enabled, its fixture plugins are
+// uploaded through the API, and the specs drive every part of the HTTP
+// API a client uses (runs, uploads, jobs, batches, and authentication)
+// with the Go client.
+//
+// The specs are built only with the e2e tag and need a WasmEdge
+// installation like the server itself:
+//
+//	make e2e
+//
+// Redis runs in a Docker container started for the suite, or at
+// E2E_REDIS_URL; without either, the cache specs are skipped. The fixture
+// plugins are the sample plugins of demo mode and modules the suite
+// writes itself, so no plugin has to be built first.
+package e2e
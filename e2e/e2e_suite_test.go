@@ -0,0 +1,24 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestE2E bootstraps the Ginkgo suite of the end-to-end specs.
+// Run with: make e2e
+func TestE2E(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "E2E Suite")
+}
+
+// e is the environment every spec runs against, started once per suite.
+var e *env
+
+var _ = BeforeSuite(func() {
+	e = startEnv()
+})
@@ -0,0 +1,262 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/client"
+)
+
+// redisImage is the image of the Redis container the suite starts.
+const redisImage = "redis:7-alpine"
+
+// startTimeout bounds how long the server may take to build and start.
+const startTimeout = 2 * time.Minute
+
+// API keys of the clients the server is configured with.
+const (
+	adminKey   = "e2e-admin-key"   // May call the admin endpoints and run every plugin
+	ciKey      = "e2e-ci-key"      // May run the fixture plugins
+	reportsKey = "e2e-reports-key" // May only run upper
+)
+
+// authConfig is the AUTH_CONFIG_FILE of the server.
+const authConfig = `{
+  "keys": {
+    "deploy": {"key_env": "E2E_ADMIN_KEY", "plugins": ["*"], "admin": true},
+    "ci": {"key_env": "E2E_CI_KEY", "plugins": ["hello", "upper", "memo", "greet"]},
+    "reports": {"key_env": "E2E_REPORTS_KEY", "plugins": ["upper"]}
+  }
+}`
+
+// env is a running server and what it was started with.
+type env struct {
+	baseURL  string
+	batchDir string // BATCH_DIR of the server
+	redisURL string // CACHE_REDIS_URL of the server; empty if no Redis runs
+}
+
+// startEnv builds and starts the server with the fixture plugins, and
+// stops it when the suite ends.
+func startEnv() *env {
+	root, err := filepath.Abs("..")
+	Expect(err).NotTo(HaveOccurred())
+	tmp := GinkgoT().TempDir()
+
+	By("building the server")
+	binary := filepath.Join(tmp, "server")
+	build := exec.Command("go", "build", "-o", binary, "./cmd/server")
+	build.Dir = root
+	out, err := build.CombinedOutput()
+	Expect(err).NotTo(HaveOccurred(), string(out))
+
+	e := &env{batchDir: filepath.Join(tmp, "batches"), redisURL: startRedis()}
+	Expect(os.MkdirAll(e.batchDir, 0755)).To(Succeed())
+	authFile := filepath.Join(tmp, "auth.json")
+	Expect(os.WriteFile(authFile, []byte(authConfig), 0644)).To(Succeed())
+
+	By("starting the server")
+	server := exec.Command(binary)
+	server.Dir = tmp
+	server.Env = append(os.Environ(),
+		"PLUGIN_STORE=memory",
+		"LISTEN_ADDR=127.0.0.1:0",
+		"GRPC_LISTEN_ADDR=127.0.0.1:0",
+		"AUTH_CONFIG_FILE="+authFile,
+		"E2E_ADMIN_KEY="+adminKey,
+		"E2E_CI_KEY="+ciKey,
+		"E2E_REPORTS_KEY="+reportsKey,
+		"BATCH_DIR="+e.batchDir,
+		"CACHE_REDIS_URL="+e.redisURL,
+		"LOG_REQUESTS=false",
+	)
+	stdout, err := server.StdoutPipe()
+	Expect(err).NotTo(HaveOccurred())
+	server.Stderr = GinkgoWriter
+	Expect(server.Start()).To(Succeed())
+	exited := make(chan struct{})
+	go func() {
+		_ = server.Wait()
+		close(exited)
+	}()
+	DeferCleanup(func() {
+		_ = server.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-exited:
+		case <-time.After(10 * time.Second):
+			_ = server.Process.Kill()
+			<-exited
+		}
+	})
+
+	listening := make(chan string, 1)
+	go watchLog(stdout, listening)
+	select {
+	case addr := <-listening:
+		e.baseURL = "http://" + addr
+	case <-exited:
+		Fail("the server exited before it listened; see its output above")
+	case <-time.After(startTimeout):
+		Fail("the server did not listen within " + startTimeout.String())
+	}
+
+	By("uploading the fixture plugins")
+	admin := e.client(adminKey)
+	for name, wasm := range fixtures(root) {
+		_, err := admin.UploadPlugin(context.Background(), name, bytes.NewReader(wasm))
+		Expect(err).NotTo(HaveOccurred(), name)
+	}
+	return e
+}
+
+// client returns a client of the server authenticating with key.
+func (e *env) client(key string) *client.Client {
+	if key == "" {
+		return client.New(e.baseURL)
+	}
+	return client.New(e.baseURL, client.WithAPIKey(key))
+}
+
+// watchLog copies the server's log to the Ginkgo writer, sending the
+// address of the HTTP API to listening once the server logs that it
+// started.
+func watchLog(log io.Reader, listening chan<- string) {
+	scanner := bufio.NewScanner(log)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		fmt.Fprintf(GinkgoWriter, "[server] %s\n", line)
+
+		var record struct {
+			Msg     string `json:"msg"`
+			Startup struct {
+				Listen struct {
+					HTTP string `json:"http"`
+				} `json:"listen"`
+			} `json:"startup"`
+		}
+		if json.Unmarshal(line, &record) == nil && record.Msg == "WASM plugin server started" {
+			listening <- record.Startup.Listen.HTTP
+		}
+	}
+}
+
+// startRedis returns the URL of the Redis the server caches in: that of
+// E2E_REDIS_URL, or of a container started for the suite and removed when
+// it ends. Without Docker it returns "", leaving the server to cache in
+// memory.
+func startRedis() string {
+	if redisURL := os.Getenv("E2E_REDIS_URL"); redisURL != "" {
+		return redisURL
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		fmt.Fprintln(GinkgoWriter, "docker not found; caching in memory")
+		return ""
+	}
+
+	By("starting Redis")
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-p", "127.0.0.1::6379", redisImage).Output()
+	Expect(err).NotTo(HaveOccurred(), "docker run %s", redisImage)
+	id := strings.TrimSpace(string(out))
+	DeferCleanup(func() {
+		_ = exec.Command("docker", "rm", "-f", id).Run()
+	})
+
+	out, err = exec.Command("docker", "port", id, "6379/tcp").Output()
+	Expect(err).NotTo(HaveOccurred())
+	redisURL := "redis://" + strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	Eventually(func() error {
+		_, err := redisCommand(redisURL, "PING")
+		return err
+	}).WithTimeout(30 * time.Second).WithPolling(200 * time.Millisecond).Should(Succeed())
+	return redisURL
+}
+
+// redisCommand sends a command to the Redis at redisURL, after the AUTH
+// and SELECT the URL asks for, and returns its reply: a status, integer,
+// or bulk string, and "" for a nil reply.
+func redisCommand(redisURL string, args ...string) (string, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return "", err
+	}
+	var commands [][]string
+	if password, ok := u.User.Password(); ok {
+		if user := u.User.Username(); user != "" {
+			commands = append(commands, []string{"AUTH", user, password})
+		} else {
+			commands = append(commands, []string{"AUTH", password})
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		commands = append(commands, []string{"SELECT", db})
+	}
+	commands = append(commands, args)
+
+	conn, err := net.DialTimeout("tcp", u.Host, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	var reply string
+	for _, command := range commands {
+		fmt.Fprintf(conn, "*%d\r\n", len(command))
+		for _, arg := range command {
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+		if reply, err = readReply(r); err != nil {
+			return "", err
+		}
+	}
+	return reply, nil
+}
+
+// readReply reads a RESP reply other than an array.
+func readReply(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return "", err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return "", err
+		}
+		return string(data[:n]), nil
+	}
+	return "", fmt.Errorf("unexpected reply %q", line)
+}
@@ -0,0 +1,134 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/gomega"
+)
+
+// Fixture plugins the suite uploads, by name:
+//
+//   - hello computes (input * 2) + 1 (cmd/server/demo/hello.wat)
+//   - upper upper-cases text (cmd/server/demo/upper.wat)
+//   - memo caches "ok" at key "e2e" and returns its input, or its input
+//     plus the negative code cache_set() failed with
+func fixtures(root string) map[string][]byte {
+	plugins := map[string][]byte{"memo": memoPlugin()}
+	for _, name := range []string{"hello", "upper"} {
+		wasm, err := os.ReadFile(filepath.Join(root, "cmd", "server", "demo", name+".wasm"))
+		Expect(err).NotTo(HaveOccurred())
+		plugins[name] = wasm
+	}
+	return plugins
+}
+
+// memoTTL is the TTL, in milliseconds, of the entry memo caches.
+const memoTTL = 60000
+
+// memoPlugin returns the memo plugin:
+//
+//	(module
+//	  (import "host" "cache_set" (func $cache_set (param i32 i32 i32 i32 i32) (result i32)))
+//	  (memory (export "memory") 1)
+//	  (data (i32.const 0) "e2eok")
+//	  (func (export "init") (result i32) (i32.const 0))
+//	  (func (export "process") (param $input i32) (result i32)
+//	    (i32.add
+//	      (call $cache_set (i32.const 0) (i32.const 3) (i32.const 3) (i32.const 2) (i32.const 60000))
+//	      (local.get $input)))
+//	  (func (export "cleanup") (result i32) (i32.const 0)))
+func memoPlugin() []byte {
+	i32 := byte(0x7f)
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	// Types
+	module = append(module, section(0x01, vector(
+		[]byte{0x60, 0x05, i32, i32, i32, i32, i32, 0x01, i32}, // cache_set
+		[]byte{0x60, 0x00, 0x01, i32},                          // init, cleanup
+		[]byte{0x60, 0x01, i32, 0x01, i32},                     // process
+	))...)
+	// Imports
+	module = append(module, section(0x02, vector(
+		concat(name("host"), name("cache_set"), []byte{0x00, 0x00}),
+	))...)
+	module = append(module, section(0x03, vector([]byte{0x01}, []byte{0x02}, []byte{0x01}))...) // Functions
+	module = append(module, section(0x05, vector([]byte{0x00, 0x01}))...)                       // One page of memory
+	// Exports
+	module = append(module, section(0x07, vector(
+		concat(name("memory"), []byte{0x02, 0x00}),
+		concat(name("init"), []byte{0x00, 0x01}),
+		concat(name("process"), []byte{0x00, 0x02}),
+		concat(name("cleanup"), []byte{0x00, 0x03}),
+	))...)
+
+	constant := []byte{0x00, 0x41, 0x00, 0x0b} // No locals, i32.const 0, end
+	process := []byte{0x00,
+		0x41, 0x00, 0x41, 0x03, // Key "e2e"
+		0x41, 0x03, 0x41, 0x02} // Value "ok"
+	process = append(append(process, 0x41), sleb(memoTTL)...)
+	process = append(process,
+		0x10, 0x00, // call $cache_set
+		0x20, 0x00, // local.get $input
+		0x6a, // i32.add
+		0x0b)
+	// Code
+	module = append(module, section(0x0a, vector(
+		concat(uleb(len(constant)), constant),
+		concat(uleb(len(process)), process),
+		concat(uleb(len(constant)), constant),
+	))...)
+	// Data at address 0
+	return append(module, section(0x0b, vector(
+		concat([]byte{0x00, 0x41, 0x00, 0x0b}, name("e2eok")),
+	))...)
+}
+
+// section encodes a module section.
+func section(id byte, payload []byte) []byte {
+	return concat([]byte{id}, uleb(len(payload)), payload)
+}
+
+// vector encodes a vector of encoded items.
+func vector(items ...[]byte) []byte {
+	return concat(append([][]byte{uleb(len(items))}, items...)...)
+}
+
+// name encodes a name.
+func name(s string) []byte {
+	return concat(uleb(len(s)), []byte(s))
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// uleb encodes n as unsigned LEB128.
+func uleb(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		if n >>= 7; n == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// sleb encodes v as signed LEB128, the immediate of i32.const.
+func sleb(v int32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
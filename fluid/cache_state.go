@@ -0,0 +1,145 @@
+package fluid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// CacheLocator reports where the distributed cache behind a dataset mount
+// holds files, so that work reading them can be placed on those nodes.
+type CacheLocator interface {
+	// CachedBytes returns how many bytes of the file at name, relative to
+	// the dataset root, the cache holds on each node, by node host. Nodes
+	// holding none are omitted.
+	CachedBytes(ctx context.Context, name string) (map[string]int64, error)
+}
+
+// AlluxioConfig locates the Alluxio cache of a Fluid dataset.
+type AlluxioConfig struct {
+	// URL is the Alluxio proxy REST API, e.g.
+	// http://plugins-master-0.default:39999; Fluid's AlluxioRuntime runs
+	// one with the master.
+	URL string
+
+	// Root is the dataset's path in the Alluxio namespace, "/" by default.
+	Root string
+
+	// HTTPClient is used for requests; http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// AlluxioCacheLocator is a CacheLocator reading block locations from the
+// Alluxio proxy REST API, the cache engine of Fluid's AlluxioRuntime.
+type AlluxioCacheLocator struct {
+	base   string
+	root   string
+	client *http.Client
+}
+
+// NewAlluxioCacheLocator creates a locator for the Alluxio proxy at
+// cfg.URL.
+func NewAlluxioCacheLocator(cfg AlluxioConfig) (*AlluxioCacheLocator, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Alluxio URL %q", cfg.URL)
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	root := cfg.Root
+	if root == "" {
+		root = "/"
+	}
+	return &AlluxioCacheLocator{base: strings.TrimRight(cfg.URL, "/"), root: root, client: client}, nil
+}
+
+// alluxioStatus is the part of an Alluxio URIStatus naming the workers
+// holding each block of a file.
+type alluxioStatus struct {
+	FileBlockInfos []struct {
+		BlockInfo struct {
+			Length    int64 `json:"length"`
+			Locations []struct {
+				WorkerAddress struct {
+					Host string `json:"host"`
+				} `json:"workerAddress"`
+			} `json:"locations"`
+		} `json:"blockInfo"`
+	} `json:"fileBlockInfos"`
+}
+
+// CachedBytes implements CacheLocator with the get-status call of the
+// proxy's paths API. A file missing from the namespace is cached nowhere.
+func (l *AlluxioCacheLocator) CachedBytes(ctx context.Context, name string) (map[string]int64, error) {
+	full := path.Join(l.root, name)
+	endpoint := l.base + "/api/v1/paths/" + escapePath(strings.TrimPrefix(full, "/")) + "/get-status"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the cache state of %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return map[string]int64{}, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to query the cache state of %s: %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var status alluxioStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid cache state of %s: %w", name, err)
+	}
+	cached := make(map[string]int64)
+	for _, block := range status.FileBlockInfos {
+		for _, location := range block.BlockInfo.Locations {
+			if host := location.WorkerAddress.Host; host != "" {
+				cached[host] += block.BlockInfo.Length
+			}
+		}
+	}
+	return cached, nil
+}
+
+// cacheStateTimeout bounds a cache state query when the caller sets no
+// deadline; placement is an optimization and must not hold up a request.
+const cacheStateTimeout = 2 * time.Second
+
+// Locate sums the bytes of files the cache holds on each node, querying
+// locator for every file. Files it fails to locate count as cached
+// nowhere; the first error is returned with the partial result.
+func Locate(ctx context.Context, locator CacheLocator, files []string) (map[string]int64, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cacheStateTimeout)
+		defer cancel()
+	}
+	total := make(map[string]int64)
+	var firstErr error
+	for _, file := range files {
+		cached, err := locator.CachedBytes(ctx, file)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for node, n := range cached {
+			total[node] += n
+		}
+	}
+	return total, firstErr
+}
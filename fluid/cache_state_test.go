@@ -0,0 +1,90 @@
+package fluid_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// fakeLocator is a CacheLocator with fixed answers.
+type fakeLocator map[string]map[string]int64
+
+func (f fakeLocator) CachedBytes(_ context.Context, name string) (map[string]int64, error) {
+	cached, ok := f[name]
+	if !ok {
+		return nil, errors.New("unreachable")
+	}
+	return cached, nil
+}
+
+var _ = Describe("AlluxioCacheLocator", func() {
+	var (
+		paths  []string
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		paths = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			paths = append(paths, r.URL.EscapedPath())
+			switch r.URL.Path {
+			case "/api/v1/paths/datasets/plugins/hello/hello.wasm/get-status":
+				_, _ = w.Write([]byte(`{"length": 300, "inAlluxioPercentage": 100, "fileBlockInfos": [
+					{"blockInfo": {"blockId": 1, "length": 200, "locations": [
+						{"workerAddress": {"host": "node-a"}, "tierAlias": "MEM"},
+						{"workerAddress": {"host": "node-b"}, "tierAlias": "SSD"}]}},
+					{"blockInfo": {"blockId": 2, "length": 100, "locations": [
+						{"workerAddress": {"host": "node-a"}, "tierAlias": "MEM"}]}}
+				]}`))
+			case "/api/v1/paths/datasets/plugins/broken/get-status":
+				http.Error(w, "master unavailable", http.StatusServiceUnavailable)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	// =========================================================================
+	// TEST: Block locations
+	// Why: Routing weighs nodes by the bytes of a plugin their cache
+	//      workers hold, so every block must count on each of its workers.
+	// =========================================================================
+	It("should sum the cached bytes of a file by worker host", func() {
+		locator, err := fluid.NewAlluxioCacheLocator(fluid.AlluxioConfig{URL: server.URL + "/", Root: "/datasets/plugins"})
+		Expect(err).NotTo(HaveOccurred())
+
+		cached, err := locator.CachedBytes(context.Background(), "hello/hello.wasm")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(Equal(map[string]int64{"node-a": 300, "node-b": 200}))
+
+		cached, err = locator.CachedBytes(context.Background(), "missing.bin")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeEmpty())
+
+		_, err = locator.CachedBytes(context.Background(), "broken")
+		Expect(err).To(MatchError(ContainSubstring("master unavailable")))
+	})
+
+	It("should reject URLs that are not HTTP", func() {
+		_, err := fluid.NewAlluxioCacheLocator(fluid.AlluxioConfig{URL: "alluxio://master:19998"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should locate several files, skipping those that fail", func() {
+		locator := fakeLocator{
+			"hello.wasm": {"node-a": 10, "node-b": 5},
+			"model.bin":  {"node-b": 100},
+		}
+		total, err := fluid.Locate(context.Background(), locator, []string{"hello.wasm", "model.bin", "gone"})
+		Expect(err).To(MatchError("unreachable"))
+		Expect(total).To(Equal(map[string]int64{"node-a": 10, "node-b": 105}))
+	})
+})
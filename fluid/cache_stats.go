@@ -0,0 +1,177 @@
+package fluid
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CacheStats is the state of the distributed cache behind a dataset
+// mount, as its runtime reports it.
+type CacheStats struct {
+	// CachedBytes is how much of the dataset the cache holds.
+	CachedBytes int64
+
+	// CapacityBytes is how much the cache may hold; 0 if the runtime does
+	// not report it.
+	CapacityBytes int64
+
+	// HitBytes and MissBytes count the bytes read from the cache and from
+	// the underlying storage since the runtime started.
+	HitBytes, MissBytes int64
+}
+
+// HitRatio returns the share of bytes read that the cache served, 0 if
+// nothing was read.
+func (s CacheStats) HitRatio() float64 {
+	if total := s.HitBytes + s.MissBytes; total > 0 {
+		return float64(s.HitBytes) / float64(total)
+	}
+	return 0
+}
+
+// CacheStatsReader reads the cache statistics of a Fluid runtime.
+type CacheStatsReader interface {
+	CacheStats(ctx context.Context) (CacheStats, error)
+}
+
+// NewCacheStatsReader creates a reader for the metrics endpoint of a Fluid
+// runtime: "alluxio" for an AlluxioRuntime's master web server, e.g.
+// http://plugins-master-0.default:19999, or "juicefs" for the Prometheus
+// endpoint of a JuiceFSRuntime's FUSE client, e.g.
+// http://10.0.0.1:9567/metrics. client is http.DefaultClient if nil.
+func NewCacheStatsReader(runtime, endpoint string, client *http.Client) (CacheStatsReader, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s metrics URL %q", runtime, endpoint)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch runtime {
+	case "alluxio":
+		return &alluxioStats{url: strings.TrimRight(endpoint, "/") + "/metrics/json/", client: client}, nil
+	case "juicefs":
+		return &juicefsStats{url: endpoint, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported Fluid runtime %q: must be alluxio or juicefs", runtime)
+	}
+}
+
+// alluxioStats reads the cluster metrics of an Alluxio master, which
+// Fluid runs one of per dataset. The hit ratio counts local and remote
+// worker reads as hits and reads from the under file system as misses,
+// as Fluid itself does for the dataset's status.
+type alluxioStats struct {
+	url    string
+	client *http.Client
+}
+
+// alluxioMetrics is the JSON of the master's metrics servlet. Cluster
+// metrics are gauges or counters depending on the Alluxio version.
+type alluxioMetrics struct {
+	Gauges   map[string]struct{ Value json.Number } `json:"gauges"`
+	Counters map[string]struct{ Count json.Number } `json:"counters"`
+}
+
+// value returns the metric called name, 0 if there is none.
+func (m alluxioMetrics) value(name string) int64 {
+	n := m.Gauges[name].Value
+	if n == "" {
+		n = m.Counters[name].Count
+	}
+	f, _ := n.Float64()
+	return int64(f)
+}
+
+// CacheStats implements CacheStatsReader.
+func (a *alluxioStats) CacheStats(ctx context.Context) (CacheStats, error) {
+	body, err := fetchMetrics(ctx, a.client, a.url)
+	if err != nil {
+		return CacheStats{}, err
+	}
+	defer body.Close()
+	var m alluxioMetrics
+	if err := json.NewDecoder(body).Decode(&m); err != nil {
+		return CacheStats{}, fmt.Errorf("invalid Alluxio metrics: %w", err)
+	}
+	return CacheStats{
+		CachedBytes:   m.value("Cluster.CapacityUsed"),
+		CapacityBytes: m.value("Cluster.CapacityTotal"),
+		HitBytes:      m.value("Cluster.BytesReadLocal") + m.value("Cluster.BytesReadRemote"),
+		MissBytes:     m.value("Cluster.BytesReadUfsAll"),
+	}, nil
+}
+
+// juicefsStats reads the block cache metrics of a JuiceFS client from its
+// Prometheus endpoint. Samples are summed across labels, e.g. the cache
+// directories of a client.
+type juicefsStats struct {
+	url    string
+	client *http.Client
+}
+
+// CacheStats implements CacheStatsReader.
+func (j *juicefsStats) CacheStats(ctx context.Context) (CacheStats, error) {
+	body, err := fetchMetrics(ctx, j.client, j.url)
+	if err != nil {
+		return CacheStats{}, err
+	}
+	defer body.Close()
+
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest := line, ""
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name, rest = line[:i], line[i:]
+		}
+		if i := strings.LastIndex(rest, "}"); i >= 0 {
+			rest = rest[i+1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			values[name] += v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return CacheStats{}, fmt.Errorf("invalid JuiceFS metrics: %w", err)
+	}
+	return CacheStats{
+		CachedBytes: int64(values["juicefs_blockcache_bytes"]),
+		HitBytes:    int64(values["juicefs_blockcache_hit_bytes"]),
+		MissBytes:   int64(values["juicefs_blockcache_miss_bytes"]),
+	}, nil
+}
+
+// fetchMetrics GETs a metrics endpoint, returning the body of a 200
+// response.
+func fetchMetrics(ctx context.Context, client *http.Client, endpoint string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache metrics: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to read cache metrics: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
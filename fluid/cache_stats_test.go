@@ -0,0 +1,88 @@
+package fluid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("CacheStatsReader", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodGet))
+			switch r.URL.Path {
+			case "/metrics/json/":
+				_, _ = w.Write([]byte(`{"version": "4.0.0", "gauges": {
+					"Cluster.CapacityUsed": {"value": 1048576},
+					"Cluster.CapacityTotal": {"value": 4194304},
+					"Cluster.BytesReadLocal": {"value": 600},
+					"Cluster.BytesReadRemote": {"value": 150}
+				}, "counters": {
+					"Cluster.BytesReadUfsAll": {"count": 250}
+				}}`))
+			case "/metrics":
+				_, _ = w.Write([]byte(`# HELP juicefs_blockcache_bytes number of cached bytes
+# TYPE juicefs_blockcache_bytes gauge
+juicefs_blockcache_bytes{mp="/runtime-mnt",vol_name="plugins",dir="/cache1"} 3000
+juicefs_blockcache_bytes{mp="/runtime-mnt",vol_name="plugins",dir="/cache2"} 2000
+juicefs_blockcache_hit_bytes{mp="/runtime-mnt",vol_name="plugins"} 900
+juicefs_blockcache_miss_bytes{mp="/runtime-mnt",vol_name="plugins"} 100
+juicefs_uptime 12.5
+`))
+			default:
+				http.Error(w, "no such metrics", http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	// =========================================================================
+	// TEST: Runtime-specific metrics
+	// Why: Each runtime names its cache metrics differently; both must map
+	//      to the same stats so dashboards work with either.
+	// =========================================================================
+	It("should read the cluster metrics of an Alluxio master", func() {
+		reader, err := fluid.NewCacheStatsReader("alluxio", server.URL+"/", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		stats, err := reader.CacheStats(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(Equal(fluid.CacheStats{CachedBytes: 1048576, CapacityBytes: 4194304, HitBytes: 750, MissBytes: 250}))
+		Expect(stats.HitRatio()).To(Equal(0.75))
+	})
+
+	It("should sum the block cache metrics of a JuiceFS client", func() {
+		reader, err := fluid.NewCacheStatsReader("juicefs", server.URL+"/metrics", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		stats, err := reader.CacheStats(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(Equal(fluid.CacheStats{CachedBytes: 5000, HitBytes: 900, MissBytes: 100}))
+		Expect(stats.HitRatio()).To(Equal(0.9))
+	})
+
+	It("should report endpoints that fail", func() {
+		reader, err := fluid.NewCacheStatsReader("juicefs", server.URL+"/gone", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reader.CacheStats(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("no such metrics")))
+	})
+
+	It("should reject unknown runtimes and URLs that are not HTTP", func() {
+		_, err := fluid.NewCacheStatsReader("jindo", server.URL, nil)
+		Expect(err).To(MatchError(ContainSubstring("jindo")))
+		_, err = fluid.NewCacheStatsReader("alluxio", "alluxio://master:19998", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should report a zero hit ratio before anything was read", func() {
+		Expect(fluid.CacheStats{}.HitRatio()).To(BeZero())
+	})
+})
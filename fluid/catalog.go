@@ -0,0 +1,300 @@
+package fluid
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// CatalogFormat is the version of the bundle layout ExportCatalog writes
+// and ImportCatalog reads.
+const CatalogFormat = 1
+
+// CatalogIndexName is the name of the index file of a catalog bundle.
+const CatalogIndexName = "catalog.json"
+
+// ErrInvalidCatalog is returned by ImportCatalog for a bundle that is
+// malformed or does not match its index.
+var ErrInvalidCatalog = errors.New("invalid catalog bundle")
+
+// CatalogIndex describes the builds of a catalog bundle.
+//
+// A bundle is a gzip-compressed tar archive of every build in the store,
+// laid out as a local store, <name>/[<version>/]<name>.wasm with the
+// build's manifest, checksum file, and signature next to it, followed by
+// the index as catalog.json. Extracted, it is a plugin directory for
+// PLUGIN_STORE=local.
+type CatalogIndex struct {
+	Format   int            `json:"format"`
+	Exported time.Time      `json:"exported"`
+	Plugins  []CatalogEntry `json:"plugins"`
+
+	// Skipped lists the builds that could not be exported, e.g. for an
+	// invalid manifest, with the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// CatalogEntry is one build of a catalog bundle.
+type CatalogEntry struct {
+	PluginInfo
+
+	// Digest is the hex SHA-256 of the .wasm file in the bundle.
+	Digest string `json:"digest"`
+
+	// Files are the bundle paths of the build's files, the .wasm file
+	// first.
+	Files []string `json:"files"`
+}
+
+// BuildFileWriter is implemented by PluginWriters that can also replace
+// the files next to a build, as ImportCatalog needs to restore them.
+type BuildFileWriter interface {
+	PluginWriter
+
+	// PutFiles replaces the manifest, checksum file, and signature of the
+	// build pluginName with files, keyed by file name (plugin.json,
+	// <name>.wasm.sha256, <name>.wasm.sig). Those missing from files are
+	// removed. The build's binary is left as is.
+	PutFiles(pluginName string, files map[string][]byte) error
+}
+
+// companionFiles returns the names of the files that may accompany the
+// build of the plugin name.
+func companionFiles(name string) []string {
+	wasm := name + ".wasm"
+	return []string{manifest.FileName, wasm + manifest.DigestSuffix, wasm + manifest.SignatureSuffix}
+}
+
+// ExportCatalog writes every build in store to w as a catalog bundle and
+// returns its index. Builds that fail to resolve are skipped and listed in
+// the index, so one broken plugin does not prevent a backup.
+func ExportCatalog(store PluginStore, w io.Writer) (*CatalogIndex, error) {
+	plugins, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	index := &CatalogIndex{Format: CatalogFormat, Exported: time.Now().UTC(), Plugins: []CatalogEntry{}}
+	for _, p := range plugins {
+		ref := p.Reference()
+		wasmPath, err := store.Resolve(ref)
+		if err != nil {
+			if index.Skipped == nil {
+				index.Skipped = make(map[string]string)
+			}
+			index.Skipped[ref] = err.Error()
+			continue
+		}
+
+		dir := p.Name
+		if p.Version != "" {
+			dir = path.Join(p.Name, p.Version)
+		}
+		entry := CatalogEntry{PluginInfo: p}
+		for _, file := range append([]string{p.Name + ".wasm"}, companionFiles(p.Name)...) {
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(wasmPath), file))
+			if errors.Is(err, os.ErrNotExist) && file != p.Name+".wasm" {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to export %s: %w", ref, err)
+			}
+			if file == p.Name+".wasm" {
+				sum := sha256.Sum256(data)
+				entry.Digest = hex.EncodeToString(sum[:])
+				entry.Size = int64(len(data))
+			}
+			name := path.Join(dir, file)
+			if err := writeTarFile(tw, name, data, p.ModTime); err != nil {
+				return nil, err
+			}
+			entry.Files = append(entry.Files, name)
+		}
+		index.Plugins = append(index.Plugins, entry)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, CatalogIndexName, data, index.Exported); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write catalog: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return index, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return nil
+}
+
+// ImportCatalog installs every build of the catalog bundle read from r into
+// store, replacing builds of the same name and version; other builds are
+// left as is. At most limit bytes are read from the bundle once
+// uncompressed.
+//
+// The whole bundle is checked before anything is written: every build
+// must have a valid name, the binary the index's digest names, and a valid
+// manifest matching its name and version, if it has one. check, if not
+// nil, is then called with every build's reference, binary, and manifest,
+// nil if it has none; an error from it fails the import too. Each build's
+// manifest, checksum file, and signature are written before its binary,
+// which is replaced atomically as by Put.
+func ImportCatalog(store BuildFileWriter, r io.Reader, limit int64, check func(ref string, wasm []byte, m *manifest.Manifest) error) ([]PluginInfo, error) {
+	files, err := readCatalog(r, limit)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[CatalogIndexName]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s", ErrInvalidCatalog, CatalogIndexName)
+	}
+	var index CatalogIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCatalog, CatalogIndexName, err)
+	}
+	if index.Format != CatalogFormat {
+		return nil, fmt.Errorf("%w: format %d, expected %d", ErrInvalidCatalog, index.Format, CatalogFormat)
+	}
+
+	builds := make([]map[string][]byte, len(index.Plugins))
+	for i, entry := range index.Plugins {
+		if builds[i], err = catalogBuild(entry, files); err != nil {
+			return nil, err
+		}
+	}
+	for i, entry := range index.Plugins {
+		if check == nil {
+			break
+		}
+		var m *manifest.Manifest
+		if data, ok := builds[i][manifest.FileName]; ok {
+			m, _ = manifest.Parse(data) // Validated by catalogBuild
+		}
+		if err := check(entry.Reference(), builds[i][entry.Name+".wasm"], m); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Reference(), err)
+		}
+	}
+
+	imported := make([]PluginInfo, 0, len(index.Plugins))
+	for i, entry := range index.Plugins {
+		ref := entry.Reference()
+		wasm := builds[i][entry.Name+".wasm"]
+		delete(builds[i], entry.Name+".wasm")
+		if err := store.PutFiles(ref, builds[i]); err != nil {
+			return imported, fmt.Errorf("failed to import %s: %w", ref, err)
+		}
+		info, err := store.Put(ref, bytes.NewReader(wasm))
+		if err != nil {
+			return imported, fmt.Errorf("failed to import %s: %w", ref, err)
+		}
+		imported = append(imported, info)
+	}
+	return imported, nil
+}
+
+// readCatalog reads the regular files of a catalog bundle by path.
+func readCatalog(r io.Reader, limit int64) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string][]byte)
+	var total int64
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if total += header.Size; total > limit {
+			return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidCatalog, limit)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+		}
+		files[path.Clean(header.Name)] = data
+	}
+}
+
+// catalogBuild returns the files of one build of a bundle by file name,
+// checking them against the index entry.
+func catalogBuild(entry CatalogEntry, files map[string][]byte) (map[string][]byte, error) {
+	ref := entry.Reference()
+	name, version, err := parseBuild(ref)
+	if err != nil || version == LatestVersion {
+		return nil, fmt.Errorf("%w: build %q", ErrInvalidCatalog, ref)
+	}
+	dir := name
+	if version != "" {
+		dir = path.Join(name, version)
+	}
+
+	allowed := map[string]bool{name + ".wasm": true}
+	for _, file := range companionFiles(name) {
+		allowed[file] = true
+	}
+	build := make(map[string][]byte)
+	for _, file := range entry.Files {
+		base := path.Base(file)
+		if path.Dir(file) != dir || !allowed[base] {
+			return nil, fmt.Errorf("%w: %s is not a file of %s", ErrInvalidCatalog, file, ref)
+		}
+		data, ok := files[file]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidCatalog, file)
+		}
+		build[base] = data
+	}
+
+	wasm, ok := build[name+".wasm"]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no binary", ErrInvalidCatalog, ref)
+	}
+	if sum := sha256.Sum256(wasm); hex.EncodeToString(sum[:]) != entry.Digest {
+		return nil, fmt.Errorf("%w: %s does not match its digest", ErrInvalidCatalog, ref)
+	}
+	if data, ok := build[manifest.FileName]; ok {
+		m, err := manifest.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCatalog, ref, err)
+		}
+		if m.Name != name || (version != "" && m.Version != version) {
+			return nil, fmt.Errorf("%w: the manifest of %s names %s@%s", ErrInvalidCatalog, ref, m.Name, m.Version)
+		}
+	}
+	return build, nil
+}
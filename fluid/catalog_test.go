@@ -0,0 +1,154 @@
+package fluid_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+var _ = Describe("Catalog bundles", func() {
+	// =========================================================================
+	// TEST: Export and import
+	// Why: A bundle is how a store is cloned or restored after a loss; every
+	//      build must come back with its manifest and checksum, and a bundle
+	//      that does not match its index must not touch the target store.
+	// =========================================================================
+	var source string
+
+	write := func(root, path, data string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, path), []byte(data), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		source = GinkgoT().TempDir()
+		write(source, "hello/hello.wasm", "hello")
+		write(source, "upper/1.0.0/upper.wasm", "upper")
+		write(source, "upper/1.0.0/plugin.json", `{"name": "upper", "version": "1.0.0"}`)
+		write(source, "upper/1.0.0/upper.wasm.sha256", "aee610558292023758a4229ddcf75f167c9904313a83cf795232ed7f7e2131c9  upper.wasm\n")
+		// An invalid manifest keeps this build from resolving
+		write(source, "broken/broken.wasm", "broken")
+		write(source, "broken/plugin.json", `{"name": "other", "version": "1.0.0"}`)
+	})
+
+	export := func() *bytes.Buffer {
+		var bundle bytes.Buffer
+		_, err := fluid.ExportCatalog(fluid.NewLocalPluginStore(source), &bundle)
+		Expect(err).NotTo(HaveOccurred())
+		return &bundle
+	}
+
+	It("should restore every build with its files", func() {
+		var bundle bytes.Buffer
+		index, err := fluid.ExportCatalog(fluid.NewLocalPluginStore(source), &bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(index.Plugins).To(HaveLen(2))
+		Expect(index.Plugins[0].Reference()).To(Equal("hello"))
+		Expect(index.Plugins[1].Files).To(Equal([]string{
+			"upper/1.0.0/upper.wasm", "upper/1.0.0/plugin.json", "upper/1.0.0/upper.wasm.sha256",
+		}))
+		Expect(index.Skipped).To(HaveKey("broken"))
+
+		target := GinkgoT().TempDir()
+		write(target, "hello/hello.wasm", "old hello")
+		write(target, "hello/plugin.json", `{"name": "hello", "version": "0.1.0"}`)
+		write(target, "other/other.wasm", "other")
+
+		imported, err := fluid.ImportCatalog(fluid.NewLocalPluginStore(target), &bundle, 1<<20, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imported).To(HaveLen(2))
+
+		store := fluid.NewLocalPluginStore(target)
+		path, err := store.Resolve("upper@1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(path)).To(Equal([]byte("upper")))
+		Expect(filepath.Join(target, "upper/1.0.0/upper.wasm.sha256")).To(BeAnExistingFile())
+
+		// The bundle's hello had no manifest, so the old one is gone
+		Expect(os.ReadFile(filepath.Join(target, "hello/hello.wasm"))).To(Equal([]byte("hello")))
+		Expect(filepath.Join(target, "hello/plugin.json")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(target, "other/other.wasm")).To(BeAnExistingFile())
+	})
+
+	It("should reject a bundle whose binary does not match its digest", func() {
+		bundle := retar(export(), "hello/hello.wasm", "tampered")
+
+		target := GinkgoT().TempDir()
+		_, err := fluid.ImportCatalog(fluid.NewLocalPluginStore(target), bundle, 1<<20, nil)
+		Expect(errors.Is(err, fluid.ErrInvalidCatalog)).To(BeTrue(), "%v", err)
+		Expect(err).To(MatchError(ContainSubstring("hello does not match its digest")))
+		entries, _ := os.ReadDir(target)
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should import nothing when a build fails the check", func() {
+		var checked []string
+		check := func(ref string, wasm []byte, m *manifest.Manifest) error {
+			checked = append(checked, ref)
+			if m != nil && m.Name == "upper" {
+				return errors.New("failed its tests")
+			}
+			return nil
+		}
+
+		target := GinkgoT().TempDir()
+		_, err := fluid.ImportCatalog(fluid.NewLocalPluginStore(target), export(), 1<<20, check)
+		Expect(err).To(MatchError("upper@1.0.0: failed its tests"))
+		Expect(checked).To(Equal([]string{"hello", "upper@1.0.0"}))
+		entries, _ := os.ReadDir(target)
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should reject bundles over the limit and without an index", func() {
+		_, err := fluid.ImportCatalog(fluid.NewLocalPluginStore(GinkgoT().TempDir()), export(), 16, nil)
+		Expect(err).To(MatchError(ContainSubstring("larger than 16 bytes")))
+
+		_, err = fluid.ImportCatalog(fluid.NewLocalPluginStore(GinkgoT().TempDir()),
+			retar(export(), fluid.CatalogIndexName, ""), 1<<20, nil)
+		Expect(err).To(MatchError(ContainSubstring("no catalog.json")))
+	})
+})
+
+// retar rewrites a catalog bundle with the file at name replaced by data,
+// or dropped if data is empty.
+func retar(bundle *bytes.Buffer, name, data string) *bytes.Buffer {
+	gz, err := gzip.NewReader(bundle)
+	Expect(err).NotTo(HaveOccurred())
+	tr := tar.NewReader(gz)
+
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(tr)
+		Expect(err).NotTo(HaveOccurred())
+		if header.Name == name {
+			if data == "" {
+				continue
+			}
+			content = []byte(data)
+		}
+		header.Size = int64(len(content))
+		Expect(tw.WriteHeader(header)).To(Succeed())
+		_, err = tw.Write(content)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(tw.Close()).To(Succeed())
+	Expect(gw.Close()).To(Succeed())
+	return &out
+}
@@ -0,0 +1,121 @@
+package fluid
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPConfig configures an HTTPObjectStore.
+type HTTPConfig struct {
+	// BaseURL is prepended to every object key, e.g.
+	// "https://plugins.example.com/" serves hello from
+	// https://plugins.example.com/hello/hello.wasm
+	BaseURL string
+
+	// Header is added to every request, e.g. an Authorization header for
+	// a private server
+	Header http.Header
+
+	// HTTPClient sends the requests; nil uses a client with a 60s timeout.
+	// Redirects are followed, so plugins can be served through a CDN.
+	HTTPClient *http.Client
+}
+
+// HTTPObjectStore is an ObjectStore reading objects from a plain web
+// server, with conditional GETs on the ETag and Last-Modified headers.
+// Web servers cannot be listed, so List returns ErrListNotSupported.
+type HTTPObjectStore struct {
+	baseURL *url.URL
+	header  http.Header
+	http    *http.Client
+}
+
+// NewHTTPObjectStore creates an object store for cfg.BaseURL.
+func NewHTTPObjectStore(cfg HTTPConfig) (*HTTPObjectStore, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid plugin base URL %q: must be an http or https URL", cfg.BaseURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	base.RawPath = ""
+
+	hc := cfg.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &HTTPObjectStore{baseURL: base, header: cfg.Header, http: hc}, nil
+}
+
+// NewHTTPPluginStore creates a store resolving plugins from a web server,
+// e.g. https://plugins.example.com/<name>/<name>.wasm for a base URL of
+// https://plugins.example.com/. See ObjectPluginStore for the caching.
+func NewHTTPPluginStore(cfg HTTPConfig, opts ObjectStoreOptions) (*ObjectPluginStore, error) {
+	objects, err := NewHTTPObjectStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewObjectPluginStore(objects, opts), nil
+}
+
+// Get fetches <BaseURL><key>, conditionally on the cached version.
+func (s *HTTPObjectStore) Get(ctx context.Context, key string, cached ObjectInfo) (io.ReadCloser, ObjectInfo, error) {
+	u := *s.baseURL
+	u.Path += key
+	u.RawPath = escapePath(u.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, ObjectInfo{}, fmt.Errorf("failed to build request: %w", err)
+	}
+	for name, values := range s.header {
+		req.Header[name] = values
+	}
+	setConditions(req, cached)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, ObjectInfo{}, fmt.Errorf("plugin download failed: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, responseInfo(resp, key), nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, ObjectInfo{}, ErrNotModified
+	case http.StatusNotFound, http.StatusGone:
+		resp.Body.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	default:
+		resp.Body.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("HTTP %s for %s", resp.Status, u.Redacted())
+	}
+}
+
+// List returns ErrListNotSupported.
+func (s *HTTPObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	return nil, ErrListNotSupported
+}
+
+// setConditions makes req conditional on the cached version of an object:
+// If-None-Match for its ETag, or If-Modified-Since for servers that send
+// no ETag.
+func setConditions(req *http.Request, cached ObjectInfo) {
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	} else if !cached.ModTime.IsZero() {
+		req.Header.Set("If-Modified-Since", cached.ModTime.UTC().Format(http.TimeFormat))
+	}
+}
+
+// responseInfo describes the object in a successful GET response.
+func responseInfo(resp *http.Response, key string) ObjectInfo {
+	info := ObjectInfo{Key: key, Size: resp.ContentLength, ETag: resp.Header.Get("ETag")}
+	info.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return info
+}
@@ -0,0 +1,204 @@
+package fluid_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// webFile is a file served by fakeWeb.
+type webFile struct {
+	data    string
+	etag    string // Not sent when empty
+	modTime time.Time
+}
+
+// fakeWeb serves files with http.ServeContent, which answers conditional
+// requests the way common web servers and CDNs do.
+type fakeWeb struct {
+	mu        sync.Mutex
+	files     map[string]webFile
+	status    int // Returned for every request when set
+	downloads int // Responses with a body
+	requests  []*http.Request
+}
+
+func (f *fakeWeb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	file, ok := f.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rec := httptest.NewRecorder()
+	if file.etag != "" {
+		rec.Header().Set("ETag", file.etag)
+	}
+	http.ServeContent(rec, r, r.URL.Path, file.modTime, strings.NewReader(file.data))
+	if rec.Code == http.StatusOK {
+		f.downloads++
+	}
+	for name, values := range rec.Header() {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+func (f *fakeWeb) set(path string, file webFile) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[path] = file
+}
+
+func (f *fakeWeb) takeDownloads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := f.downloads
+	f.downloads = 0
+	return n
+}
+
+func (f *fakeWeb) lastRequest(path string) *http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.requests) - 1; i >= 0; i-- {
+		if f.requests[i].URL.Path == path {
+			return f.requests[i]
+		}
+	}
+	return nil
+}
+
+var _ = Describe("HTTPPluginStore", func() {
+	var (
+		web      *fakeWeb
+		server   *httptest.Server
+		cacheDir string
+		store    *fluid.ObjectPluginStore
+		modTime  time.Time
+	)
+
+	BeforeEach(func() {
+		modTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		web = &fakeWeb{files: map[string]webFile{
+			"/repo/hello/hello.wasm": {data: "hello v1", etag: `"v1"`, modTime: modTime},
+		}}
+		server = httptest.NewServer(web)
+		DeferCleanup(server.Close)
+		cacheDir = GinkgoT().TempDir()
+
+		var err error
+		store, err = fluid.NewHTTPPluginStore(fluid.HTTPConfig{
+			BaseURL: server.URL + "/repo",
+			Header:  http.Header{"Authorization": {"Bearer secret"}},
+		}, fluid.ObjectStoreOptions{CacheDir: cacheDir, Revalidate: -1})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("should reject an invalid base URL", func() {
+		for _, base := range []string{"", "plugins.example.com", "ftp://plugins.example.com/"} {
+			_, err := fluid.NewHTTPPluginStore(fluid.HTTPConfig{BaseURL: base}, fluid.ObjectStoreOptions{})
+			Expect(err).To(MatchError(ContainSubstring("invalid plugin base URL")), base)
+		}
+	})
+
+	// =========================================================================
+	// TEST: Conditional GET
+	// Why: Plugins must update without redeploying the service, but an
+	//      unchanged plugin must not be downloaded on every revalidation.
+	// =========================================================================
+	It("should download a plugin and revalidate it with If-None-Match", func() {
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(path)).To(Equal("hello v1"))
+		Expect(web.takeDownloads()).To(Equal(1))
+		Expect(web.lastRequest("/repo/hello/hello.wasm").Header.Get("Authorization")).To(Equal("Bearer secret"))
+
+		_, err = store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(web.takeDownloads()).To(Equal(0))
+		Expect(web.lastRequest("/repo/hello/hello.wasm").Header.Get("If-None-Match")).To(Equal(`"v1"`))
+
+		web.set("/repo/hello/hello.wasm", webFile{data: "hello v2", etag: `"v2"`, modTime: modTime})
+		path, err = store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(path)).To(Equal("hello v2"))
+	})
+
+	It("should revalidate with If-Modified-Since when the server sends no ETag", func() {
+		web.set("/repo/hello/hello.wasm", webFile{data: "hello v1", modTime: modTime})
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		web.takeDownloads()
+
+		_, err = store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(web.takeDownloads()).To(Equal(0))
+		Expect(web.lastRequest("/repo/hello/hello.wasm").Header.Get("If-Modified-Since")).To(Equal("Wed, 01 May 2024 12:00:00 GMT"))
+
+		web.set("/repo/hello/hello.wasm", webFile{data: "hello v2", modTime: modTime.Add(time.Hour)})
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(path)).To(Equal("hello v2"))
+	})
+
+	It("should return ErrPluginNotFound for a missing plugin", func() {
+		_, err := store.Resolve("missing")
+		Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+	})
+
+	It("should keep serving the cached copy while the server fails", func() {
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		web.status = http.StatusBadGateway
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(path)).To(Equal("hello v1"))
+
+		web.set("/repo/echo/echo.wasm", webFile{data: "echo"})
+		_, err = store.Resolve("echo")
+		Expect(err).To(MatchError(ContainSubstring("502 Bad Gateway")))
+	})
+
+	// =========================================================================
+	// TEST: Listing without an index
+	// Why: A web server cannot be listed, so GET /plugins shows what this
+	//      instance has fetched instead of failing.
+	// =========================================================================
+	It("should list the cached plugins", func() {
+		plugins, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(BeEmpty())
+
+		_, err = store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		plugins, err = store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(1))
+		Expect(plugins[0].Name).To(Equal("hello"))
+		Expect(plugins[0].Size).To(Equal(int64(8)))
+	})
+})
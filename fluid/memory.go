@@ -0,0 +1,35 @@
+package fluid
+
+import (
+	"fmt"
+	"os"
+)
+
+// MemoryPluginStore is a LocalPluginStore that starts empty and forgets its
+// plugins when closed, for demos and tests that publish every plugin they
+// run. Since the runtime loads plugins from paths, the builds are kept in a
+// temporary directory rather than in process memory; Close removes it.
+type MemoryPluginStore struct {
+	*LocalPluginStore
+	dir string
+}
+
+// NewMemoryPluginStore creates an empty MemoryPluginStore below the
+// system's temporary directory.
+func NewMemoryPluginStore() (*MemoryPluginStore, error) {
+	dir, err := os.MkdirTemp("", "wasm-plugins-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory store: %w", err)
+	}
+	return &MemoryPluginStore{LocalPluginStore: NewLocalPluginStore(dir), dir: dir}, nil
+}
+
+// Dir returns the temporary directory holding the store's builds.
+func (s *MemoryPluginStore) Dir() string {
+	return s.dir
+}
+
+// Close removes every plugin with the store's directory.
+func (s *MemoryPluginStore) Close() error {
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,442 @@
+package fluid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// ErrNotModified is returned by ObjectStore.Get when the object did not
+// change since the version the caller holds.
+var ErrNotModified = errors.New("object not modified")
+
+// ErrObjectNotFound is returned by ObjectStore.Get for a missing object.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrListNotSupported is returned by ObjectStore.List for stores that
+// cannot enumerate their objects, such as plain HTTP servers.
+var ErrListNotSupported = errors.New("object listing not supported")
+
+// ObjectInfo describes an object in an ObjectStore.
+type ObjectInfo struct {
+	Key     string    `json:"key,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	ETag    string    `json:"etag,omitempty"`    // Opaque version tag that changes with the content
+	ModTime time.Time `json:"modified,omitzero"` // Last modification, if the store reports it
+}
+
+// ObjectStore is the part of an object storage API that ObjectPluginStore
+// needs. S3Client implements it for S3 and S3-compatible services (MinIO,
+// Ceph, R2, ...) and HTTPObjectStore for plain web servers; other backends
+// can be used by implementing it.
+type ObjectStore interface {
+	// Get fetches the object at key. If cached carries an ETag or ModTime
+	// and the object did not change since, Get returns ErrNotModified
+	// without a body. Returns ErrObjectNotFound if there is no object at
+	// key.
+	Get(ctx context.Context, key string, cached ObjectInfo) (io.ReadCloser, ObjectInfo, error)
+
+	// List returns every object whose key starts with prefix, or
+	// ErrListNotSupported.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// DefaultRevalidateInterval is how long ObjectPluginStore trusts a cached
+// file before asking the object store whether it changed.
+const DefaultRevalidateInterval = 30 * time.Second
+
+// DefaultCacheDir returns the cache directory used when
+// ObjectStoreOptions.CacheDir is empty.
+func DefaultCacheDir() string {
+	return filepath.Join(os.TempDir(), "wasm-plugins")
+}
+
+// ObjectStoreOptions configures an ObjectPluginStore.
+type ObjectStoreOptions struct {
+	// Prefix is prepended to every object key, e.g. "plugins/"
+	Prefix string
+
+	// CacheDir holds the downloaded plugins; it is created if needed and
+	// may be reused across restarts. Empty uses DefaultCacheDir.
+	CacheDir string
+
+	// Revalidate is how long a cached file is used before its ETag is
+	// checked again. Zero uses DefaultRevalidateInterval; a negative value
+	// checks on every Resolve.
+	Revalidate time.Duration
+}
+
+// ObjectPluginStore resolves plugins from an object store, such as an S3
+// bucket, for deployments without Fluid or Kubernetes.
+//
+// Objects are laid out like the directories of the other stores:
+//
+//	<prefix>hello/hello.wasm
+//	<prefix>hello/plugin.json          (optional)
+//	<prefix>hello/hello.wasm.sha256    (optional)
+//	<prefix>hello/hello.wasm.sig       (optional)
+//	<prefix>hello/1.2.0/hello.wasm     (version 1.2.0, with optional files alike)
+//
+// Resolve downloads a plugin's files into CacheDir on first use and returns
+// the cached path. Afterwards each file is revalidated with its ETag or
+// modification time once the Revalidate interval has passed, so unchanged plugins are not
+// downloaded again, and a plugin deleted from the bucket stops resolving.
+// If the object store cannot be reached, the cached copy keeps being
+// served. Replaced files are renamed into place, so a plugin being loaded
+// never sees a partial download.
+//
+// List reads the bucket listing and fetches manifests and checksum files,
+// but no binaries. Stores without a listing list the cached plugins, and
+// resolve LatestVersion among the cached versions; pin versions when
+// reading plugins from a plain web server.
+type ObjectPluginStore struct {
+	objects    ObjectStore
+	prefix     string
+	cacheDir   string
+	revalidate time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	locks   map[string]*sync.Mutex        // Serializes downloads per plugin
+	checked map[string]time.Time          // Last revalidation per object key
+	tenants map[string]*ObjectPluginStore // Namespaces by tenant, see ResolveIn
+}
+
+// NewObjectPluginStore creates a store reading plugins from objects.
+func NewObjectPluginStore(objects ObjectStore, opts ObjectStoreOptions) *ObjectPluginStore {
+	revalidate := opts.Revalidate
+	if revalidate == 0 {
+		revalidate = DefaultRevalidateInterval
+	}
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		cacheDir = DefaultCacheDir()
+	}
+	return &ObjectPluginStore{
+		objects:    objects,
+		prefix:     opts.Prefix,
+		cacheDir:   cacheDir,
+		revalidate: revalidate,
+		now:        time.Now,
+		locks:      make(map[string]*sync.Mutex),
+		checked:    make(map[string]time.Time),
+		tenants:    make(map[string]*ObjectPluginStore),
+	}
+}
+
+// Resolve returns the path of the plugin's cached .wasm file, downloading
+// or revalidating it first.
+//
+// Path format: <CacheDir>/<name>/[<version>/]<name>.wasm
+func (s *ObjectPluginStore) Resolve(pluginName string) (string, error) {
+	name, version := ParseReference(pluginName)
+	if !validPluginName(name) || (version != "" && version != LatestVersion && !ValidVersion(version)) {
+		return "", fmt.Errorf("%w: %s", ErrPluginNotFound, pluginName)
+	}
+	ctx := context.Background()
+
+	// As in the other stores, a bare name prefers the unversioned build
+	bare := version == ""
+	if bare {
+		wasmPath, err := s.fetch(ctx, name, "")
+		if !errors.Is(err, ErrPluginNotFound) {
+			return wasmPath, err
+		}
+		version = LatestVersion
+	}
+	if version == LatestVersion {
+		version = latestVersion(s.versions(ctx, name))
+		if version == "" && bare {
+			return "", fmt.Errorf("%w: %s", ErrPluginNotFound, pluginName)
+		}
+	}
+	return s.fetch(ctx, name, version)
+}
+
+// fetch downloads or revalidates a build and returns its cached path.
+func (s *ObjectPluginStore) fetch(ctx context.Context, name, version string) (string, error) {
+	wasmPath := filepath.Join(buildDir(s.cacheDir, name, version), name+".wasm")
+
+	if err := s.refresh(ctx, name, version, pluginFiles(name)); err != nil {
+		if errors.Is(err, ErrPluginNotFound) {
+			return "", err
+		}
+		// An unreachable object store must not take down plugins that
+		// were already running
+		if _, statErr := os.Stat(wasmPath); statErr != nil {
+			return "", fmt.Errorf("failed to fetch plugin %s: %w", Reference(name, version), err)
+		}
+	}
+
+	if err := checkPlugin(name, version, wasmPath); err != nil {
+		return "", err
+	}
+	return wasmPath, nil
+}
+
+// versions returns the versions of name in the object store, lowest
+// first. If the store cannot be listed, the cached versions are used.
+func (s *ObjectPluginStore) versions(ctx context.Context, name string) []string {
+	objects, err := s.objects.List(ctx, s.prefix+name+"/")
+	if err != nil {
+		return localVersions(s.cacheDir, name)
+	}
+	var versions []string
+	for _, object := range objects {
+		if objectName, version, ok := s.parseKey(object.Key); ok && objectName == name && version != "" {
+			versions = append(versions, version)
+		}
+	}
+	sortVersions(versions)
+	return versions
+}
+
+// parseKey returns the build whose binary is stored at key, if any.
+func (s *ObjectPluginStore) parseKey(key string) (name, version string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(key, s.prefix), "/")
+	switch {
+	case len(parts) == 2 && parts[1] == parts[0]+".wasm":
+		return parts[0], "", validPluginName(parts[0])
+	case len(parts) == 3 && parts[2] == parts[0]+".wasm":
+		return parts[0], parts[1], validPluginName(parts[0]) && ValidVersion(parts[1])
+	}
+	return "", "", false
+}
+
+// key returns the object key of a build's file.
+func (s *ObjectPluginStore) key(name, version, file string) string {
+	if version == "" {
+		return s.prefix + name + "/" + file
+	}
+	return s.prefix + name + "/" + version + "/" + file
+}
+
+// List returns the plugins in the object store.
+func (s *ObjectPluginStore) List() ([]PluginInfo, error) {
+	ctx := context.Background()
+	objects, err := s.objects.List(ctx, s.prefix)
+	if errors.Is(err, ErrListNotSupported) {
+		plugins, err := listPlugins(s.cacheDir)
+		if os.IsNotExist(err) {
+			return []PluginInfo{}, nil
+		}
+		return plugins, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins in object store: %w", err)
+	}
+
+	plugins := make([]PluginInfo, 0, len(objects))
+	for _, object := range objects {
+		name, version, ok := s.parseKey(object.Key)
+		if !ok {
+			continue
+		}
+
+		// Metadata is cheap to fetch; an invalid or unreachable one is
+		// reported by Resolve, not here
+		wasmPath := filepath.Join(buildDir(s.cacheDir, name, version), name+".wasm")
+		_ = s.refresh(ctx, name, version, pluginFiles(name)[1:])
+		m, _ := readManifest(name, version, wasmPath)
+		digest, _ := manifest.Digest(wasmPath, m)
+		plugins = append(plugins, PluginInfo{
+			Name:     name,
+			Version:  version,
+			Size:     object.Size,
+			ModTime:  object.ModTime,
+			Manifest: m,
+			SHA256:   digest,
+		})
+	}
+
+	sortPlugins(plugins)
+	return plugins, nil
+}
+
+// pluginFiles returns the names of the files making up a plugin, binary
+// first.
+func pluginFiles(name string) []string {
+	wasm := name + ".wasm"
+	return []string{
+		wasm,
+		filepath.Base(manifest.Path(wasm)),
+		wasm + manifest.DigestSuffix,
+		wasm + manifest.SignatureSuffix,
+	}
+}
+
+// versionsFile keeps the versions (ETag and modification time) of a
+// plugin's cached files, so that a restart revalidates the cache instead of
+// downloading it again.
+const versionsFile = ".versions.json"
+
+// refresh brings the given files of a build's cache directory up to date
+// with the object store. A missing binary removes the whole cached build
+// and returns ErrPluginNotFound; other missing files are optional.
+func (s *ObjectPluginStore) refresh(ctx context.Context, name, version string, files []string) error {
+	ref := Reference(name, version)
+	lock := s.lock(ref)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dir := buildDir(s.cacheDir, name, version)
+	versions := readVersions(dir)
+	changed := false
+	for _, file := range files {
+		key := s.key(name, version, file)
+		path := filepath.Join(dir, file)
+		if s.fresh(key) {
+			continue
+		}
+
+		cached := versions[file]
+		if _, err := os.Stat(path); err != nil {
+			cached = ObjectInfo{}
+		}
+		body, info, err := s.objects.Get(ctx, key, cached)
+		switch {
+		case errors.Is(err, ErrNotModified):
+		case errors.Is(err, ErrObjectNotFound):
+			if file == name+".wasm" {
+				s.forget(name, version)
+				if err := removeCached(s.cacheDir, name, version); err != nil {
+					return fmt.Errorf("failed to remove cached plugin %s: %w", ref, err)
+				}
+				return fmt.Errorf("%w: %s", ErrPluginNotFound, ref)
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove cached %s: %w", file, err)
+			}
+			if _, ok := versions[file]; ok {
+				delete(versions, file)
+				changed = true
+			}
+		case err != nil:
+			return err
+		default:
+			err := writeCached(dir, file, body)
+			body.Close()
+			if err != nil {
+				return err
+			}
+			versions[file] = ObjectInfo{ETag: info.ETag, ModTime: info.ModTime}
+			changed = true
+		}
+		s.mark(key)
+	}
+
+	if changed {
+		return writeVersions(dir, versions)
+	}
+	return nil
+}
+
+// lock returns the download lock of a build.
+func (s *ObjectPluginStore) lock(ref string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.locks[ref]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[ref] = lock
+	}
+	return lock
+}
+
+// fresh reports whether key was revalidated within the interval.
+func (s *ObjectPluginStore) fresh(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checked, ok := s.checked[key]
+	return ok && s.revalidate > 0 && s.now().Sub(checked) < s.revalidate
+}
+
+// mark records that key was just revalidated.
+func (s *ObjectPluginStore) mark(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checked[key] = s.now()
+}
+
+// forget drops the revalidation times of a deleted build's files.
+func (s *ObjectPluginStore) forget(name, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, file := range pluginFiles(name) {
+		delete(s.checked, s.key(name, version, file))
+	}
+}
+
+// removeCached removes a build's cached files and then its directories
+// if they are empty; an unversioned build shares its directory with the
+// plugin's version directories.
+func removeCached(cacheDir, name, version string) error {
+	dir := buildDir(cacheDir, name, version)
+	for _, file := range append(pluginFiles(name), versionsFile) {
+		if err := os.Remove(filepath.Join(dir, file)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// Fails, as intended, while other files remain
+	_ = os.Remove(dir)
+	if version != "" {
+		_ = os.Remove(filepath.Join(cacheDir, name))
+	}
+	return nil
+}
+
+// writeCached writes a downloaded file to dir through a temporary file
+// renamed into place.
+func writeCached(dir, file string, r io.Reader) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to cache %s: %w", file, err)
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, file))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to cache %s: %w", file, err)
+	}
+	return nil
+}
+
+// readVersions returns the versions recorded in dir; a missing or
+// unreadable record is empty, so every file is downloaded again.
+func readVersions(dir string) map[string]ObjectInfo {
+	versions := make(map[string]ObjectInfo)
+	if data, err := os.ReadFile(filepath.Join(dir, versionsFile)); err == nil {
+		_ = json.Unmarshal(data, &versions)
+	}
+	return versions
+}
+
+// writeVersions records the versions of the files cached in dir.
+func writeVersions(dir string, versions map[string]ObjectInfo) error {
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	return writeCached(dir, versionsFile, bytes.NewReader(data))
+}
@@ -0,0 +1,275 @@
+package fluid_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// memoryObjects is an in-memory ObjectStore that records the conditional
+// requests it receives.
+type memoryObjects struct {
+	mu       sync.Mutex
+	objects  map[string]string
+	versions map[string]int
+	gets     []string // Key and ETag of every Get
+	err      error    // Returned by every call when set
+}
+
+func newMemoryObjects() *memoryObjects {
+	return &memoryObjects{objects: make(map[string]string), versions: make(map[string]int)}
+}
+
+func (m *memoryObjects) put(key, data string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	m.versions[key]++
+}
+
+func (m *memoryObjects) remove(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+}
+
+func (m *memoryObjects) etag(key string) string {
+	return fmt.Sprintf(`"%s-%d"`, key, m.versions[key])
+}
+
+func (m *memoryObjects) Get(ctx context.Context, key string, cached fluid.ObjectInfo) (io.ReadCloser, fluid.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets = append(m.gets, key+" "+cached.ETag)
+	if m.err != nil {
+		return nil, fluid.ObjectInfo{}, m.err
+	}
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, fluid.ObjectInfo{}, fluid.ErrObjectNotFound
+	}
+	if cached.ETag != "" && cached.ETag == m.etag(key) {
+		return nil, fluid.ObjectInfo{}, fluid.ErrNotModified
+	}
+	return io.NopCloser(strings.NewReader(data)), fluid.ObjectInfo{Key: key, Size: int64(len(data)), ETag: m.etag(key)}, nil
+}
+
+func (m *memoryObjects) List(ctx context.Context, prefix string) ([]fluid.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	var objects []fluid.ObjectInfo
+	for key, data := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, fluid.ObjectInfo{
+				Key:     key,
+				Size:    int64(len(data)),
+				ETag:    m.etag(key),
+				ModTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// takeGets returns the Gets since the last call.
+func (m *memoryObjects) takeGets() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	gets := m.gets
+	m.gets = nil
+	return gets
+}
+
+var _ = Describe("ObjectPluginStore", func() {
+	var (
+		objects  *memoryObjects
+		cacheDir string
+		store    *fluid.ObjectPluginStore
+	)
+
+	BeforeEach(func() {
+		objects = newMemoryObjects()
+		objects.put("plugins/hello/hello.wasm", "wasm v1")
+		cacheDir = GinkgoT().TempDir()
+		store = fluid.NewObjectPluginStore(objects, fluid.ObjectStoreOptions{
+			Prefix:     "plugins/",
+			CacheDir:   cacheDir,
+			Revalidate: -1,
+		})
+	})
+
+	cached := func(path string) string {
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	// =========================================================================
+	// TEST: Download on demand
+	// Why: The runtime loads plugins from paths; the store must return a
+	//      complete local copy of the object, and only fetch it again once it
+	//      changed.
+	// =========================================================================
+	It("should download a plugin into the cache", func() {
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(cacheDir, "hello", "hello.wasm")))
+		Expect(cached(path)).To(Equal("wasm v1"))
+		Expect(objects.takeGets()).To(ConsistOf(
+			"plugins/hello/hello.wasm ",
+			"plugins/hello/plugin.json ",
+			"plugins/hello/hello.wasm.sha256 ",
+			"plugins/hello/hello.wasm.sig ",
+		))
+	})
+
+	It("should revalidate the cached copy with its ETag", func() {
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		objects.takeGets()
+
+		_, err = store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects.takeGets()).To(ContainElement(`plugins/hello/hello.wasm "plugins/hello/hello.wasm-1"`))
+
+		objects.put("plugins/hello/hello.wasm", "wasm v2")
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached(path)).To(Equal("wasm v2"))
+	})
+
+	It("should keep ETags across restarts", func() {
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		objects.takeGets()
+
+		restarted := fluid.NewObjectPluginStore(objects, fluid.ObjectStoreOptions{Prefix: "plugins/", CacheDir: cacheDir})
+		_, err = restarted.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects.takeGets()).To(ContainElement(`plugins/hello/hello.wasm "plugins/hello/hello.wasm-1"`))
+	})
+
+	It("should not ask the object store again within the revalidation interval", func() {
+		store = fluid.NewObjectPluginStore(objects, fluid.ObjectStoreOptions{Prefix: "plugins/", CacheDir: cacheDir})
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		objects.takeGets()
+
+		_, err = store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects.takeGets()).To(BeEmpty())
+	})
+
+	It("should stop resolving a plugin deleted from the object store", func() {
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		objects.remove("plugins/hello/hello.wasm")
+		_, err = store.Resolve("hello")
+		Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+		Expect(filepath.Join(cacheDir, "hello")).NotTo(BeADirectory())
+	})
+
+	It("should return ErrPluginNotFound for unknown and invalid names", func() {
+		for _, name := range []string{"missing", "../hello", ""} {
+			_, err := store.Resolve(name)
+			Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue(), name)
+		}
+	})
+
+	// =========================================================================
+	// TEST: Unreachable object store
+	// Why: An outage of the bucket must not stop plugins that were already
+	//      downloaded; only plugins never fetched fail.
+	// =========================================================================
+	It("should serve the cached copy while the object store fails", func() {
+		_, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		objects.err = errors.New("connection refused")
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached(path)).To(Equal("wasm v1"))
+
+		objects.put("plugins/other/other.wasm", "wasm")
+		_, err = store.Resolve("other")
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+		Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeFalse())
+	})
+
+	Context("when the plugin has a manifest", func() {
+		It("should download and validate it", func() {
+			objects.put("plugins/hello/plugin.json", `{"name": "goodbye", "version": "1.0.0"}`)
+
+			_, err := store.Resolve("hello")
+			Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+
+			objects.put("plugins/hello/plugin.json", `{"name": "hello", "version": "1.0.0"}`)
+			_, err = store.Resolve("hello")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should drop a manifest removed from the object store", func() {
+			objects.put("plugins/hello/plugin.json", `{"name": "hello", "version": "1.0.0"}`)
+			path, err := store.Resolve("hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Path(path)).To(BeAnExistingFile())
+
+			objects.remove("plugins/hello/plugin.json")
+			_, err = store.Resolve("hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Path(path)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	// =========================================================================
+	// TEST: Listing without downloads
+	// Why: Listing a bucket must stay cheap: it reads manifests and checksum
+	//      files but never pulls every binary.
+	// =========================================================================
+	It("should list plugins without downloading their binaries", func() {
+		objects.put("plugins/echo/echo.wasm", "echo wasm")
+		objects.put("plugins/echo/plugin.json", `{"name": "echo", "version": "2.0.0"}`)
+		objects.put("plugins/echo/README.md", "docs")
+		objects.put("plugins/stray.wasm", "wasm")
+		objects.put("other/x/x.wasm", "wasm")
+
+		plugins, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(2))
+		Expect(plugins[0].Name).To(Equal("echo"))
+		Expect(plugins[0].Size).To(Equal(int64(9)))
+		Expect(plugins[0].Manifest.Version).To(Equal("2.0.0"))
+		Expect(plugins[1].Name).To(Equal("hello"))
+		Expect(plugins[1].Manifest).To(BeNil())
+
+		Expect(objects.takeGets()).NotTo(ContainElement(HavePrefix("plugins/echo/echo.wasm ")))
+		Expect(filepath.Join(cacheDir, "echo", "echo.wasm")).NotTo(BeAnExistingFile())
+	})
+
+	It("should fail to list when the object store fails", func() {
+		objects.err = errors.New("access denied")
+
+		_, err := store.List()
+		Expect(err).To(MatchError(ContainSubstring("access denied")))
+	})
+
+	It("should implement PluginStore", func() {
+		var _ fluid.PluginStore = store
+	})
+})
@@ -24,34 +24,163 @@
 //   - Environment detection (caller decides which store to use)
 //
 // This keeps the plugin system portable and testable without a cluster.
+//
+// # Versions
+//
+// Every store accepts plugin references of the form "name@version". A
+// version lives in its own directory below the plugin's:
+//
+//	<base>/hello/1.0.0/hello.wasm
+//	<base>/hello/1.1.0/hello.wasm
+//	<base>/hello/1.1.0/plugin.json
+//
+// "hello@1.0.0" resolves to that exact build, which keeps callers pinned to
+// it while newer versions are published. "hello@latest" (LatestVersion)
+// resolves to the highest version in semantic version order, skipping
+// pre-releases. The bare name "hello" resolves to an unversioned
+// <base>/hello/hello.wasm if there is one, the layout used before versions
+// existed, and otherwise to the latest version. A manifest in a version
+// directory must declare that version.
+//
+// # Tenants
+//
+// Stores implementing NamespacedStore also keep plugins per tenant, below
+// TenantsDir, laid out like the root. ResolveIn looks a plugin up in the
+// tenant's namespace first and among the shared plugins second, so each
+// tenant sees the shared plugins and its own, but no other tenant's.
+//
+// # Watching
+//
+// Stores resolve plugins anew on every call, but services keep instances
+// of the builds they resolved. A Watcher reports builds added, replaced,
+// or deleted under a LocalPluginStore or FluidPluginStore directory, so
+// those can be evicted as soon as a new build is pushed.
+//
+// # Object Storage Without Fluid
+//
+// Deployments without Fluid can read plugins straight from an S3 bucket, a
+// web server, or any ObjectStore with ObjectPluginStore. Since the runtime
+// loads plugins from paths, it keeps a local cache of downloaded plugins
+// and revalidates it with conditional requests; that is the one store
+// doing its own caching.
+//
+// # Store Backends
+//
+// Backends outside this package, such as Google Cloud Storage or Azure
+// Blob Storage, register a StoreFactory with RegisterStore and are then
+// created by name from the configuration with NewStore. The storetest
+// package holds the conformance specs they are expected to pass.
+//
+// # Cache Locality
+//
+// With several replicas on different nodes, the node whose Fluid cache
+// workers already hold a large plugin and its data serves it without
+// moving them across the network. A CacheLocator reports where the cache
+// holds files; AlluxioCacheLocator asks the REST API of the Alluxio cache
+// engine of Fluid's AlluxioRuntime over plain HTTP, so this stays free of
+// Kubernetes and Fluid client libraries too.
+//
+// A CacheStatsReader reads how much of a dataset the runtime caches and
+// how many reads it serves, from the metrics endpoint of an Alluxio master
+// or a JuiceFS client, for export next to the service's own metrics.
 package fluid
 
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
 )
 
 // ErrPluginNotFound is returned when a plugin cannot be resolved.
 var ErrPluginNotFound = errors.New("plugin not found")
 
+// PluginInfo describes a plugin available in a store.
+type PluginInfo struct {
+	Name    string    `json:"name"`              // Plugin name
+	Version string    `json:"version,omitempty"` // Build version, empty for an unversioned build
+	Size    int64     `json:"size"`              // Size of the .wasm file in bytes
+	ModTime time.Time `json:"mod_time"`          // Last modification time of the .wasm file
+
+	// Manifest is the plugin's plugin.json, or nil if it has none or it
+	// is invalid (Resolve reports why).
+	Manifest *manifest.Manifest `json:"manifest,omitempty"`
+
+	// SHA256 is the digest the .wasm file is expected to have, from its
+	// <name>.wasm.sha256 file or manifest; the runtime refuses to load a
+	// binary that does not match. Empty if none is declared.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
 // PluginStore resolves plugin names to filesystem paths.
 //
 // Implementations must:
 //   - Return the absolute path to the .wasm file
 //   - Return ErrPluginNotFound if the plugin doesn't exist
-//   - NOT modify or cache plugin files
+//   - Reject plugins whose manifest or checksum file is invalid
+//     (manifest.ErrInvalid)
+//   - NOT modify plugin files, except through PluginWriter; remote stores
+//     (ObjectPluginStore) may keep a local copy to return paths into
 type PluginStore interface {
-	// Resolve converts a plugin name to its filesystem path.
+	// Resolve converts a plugin reference, "name" or "name@version", to
+	// its filesystem path.
 	//
 	// The returned path points to the compiled .wasm file, ready for loading
 	// by the runtime. The path format is implementation-specific:
-	//   - LocalPluginStore: ./plugins/<name>/<name>.wasm
-	//   - FluidPluginStore: /mnt/fluid/plugins/<name>/<name>.wasm
+	//   - LocalPluginStore: ./plugins/<name>/[<version>/]<name>.wasm
+	//   - FluidPluginStore: /mnt/fluid/plugins/<name>/[<version>/]<name>.wasm
 	//
-	// Returns ErrPluginNotFound if the plugin does not exist.
+	// If the plugin has a manifest (plugin.json next to the .wasm file), it
+	// is read and validated, and its name must match the plugin's, as must
+	// its version for a versioned build. A checksum
+	// file (<name>.wasm.sha256) must hold a digest that agrees with the
+	// manifest's.
+	//
+	// Returns ErrPluginNotFound if the plugin does not exist, or an error
+	// wrapping manifest.ErrInvalid if its manifest or checksum file is
+	// invalid.
 	Resolve(pluginName string) (string, error)
+
+	// List returns every build in the store, sorted by name and then by
+	// version, the unversioned build first.
+	//
+	// A build is listed only if Resolve would find it, i.e. its directory
+	// contains a <name>.wasm file. Other files and directories are
+	// ignored.
+	List() ([]PluginInfo, error)
+}
+
+// PluginWriter is implemented by stores that accept new plugin builds and
+// plugin removal.
+type PluginWriter interface {
+	// Put installs the .wasm binary read from r as pluginName, which may
+	// name a version ("hello@1.2.0") but not LatestVersion, creating the
+	// plugin's directory if needed. An existing build is replaced
+	// atomically, so concurrent Resolve callers see either the old or the
+	// new file, never a partial one. The plugin's manifest is left as is.
+	//
+	// Put does not validate the binary; callers must. A checksum file or
+	// signature next to the old build is left as is too, so the new build
+	// only loads once they are replaced to match it.
+	Put(pluginName string, r io.Reader) (PluginInfo, error)
+
+	// Delete removes the plugin's .wasm binary and then its manifest,
+	// checksum file, and signature, so Resolve stops finding the plugin
+	// first. The plugin's directory is
+	// removed too if nothing else is left in it, e.g. sources.
+	//
+	// A bare name removes the unversioned build and "name@version" that
+	// version only; other versions are left as is.
+	//
+	// Returns ErrPluginNotFound if the build has no binary.
+	Delete(pluginName string) error
 }
 
 // LocalPluginStore resolves plugins from the local filesystem.
@@ -65,7 +194,10 @@ type PluginStore interface {
 //	├── hello/
 //	│   └── hello.wasm
 //	├── transform/
-//	│   └── transform.wasm
+//	│   ├── 1.0.0/
+//	│   │   └── transform.wasm
+//	│   └── 1.1.0/
+//	│       └── transform.wasm
 //	└── validate/
 //	    └── validate.wasm
 type LocalPluginStore struct {
@@ -89,9 +221,12 @@ func NewLocalPluginStore(basePath string) *LocalPluginStore {
 
 // Resolve returns the path to a plugin's .wasm file.
 //
-// Path format: <basePath>/<pluginName>/<pluginName>.wasm
+// Path format: <basePath>/<name>/[<version>/]<name>.wasm
 func (s *LocalPluginStore) Resolve(pluginName string) (string, error) {
-	wasmPath := filepath.Join(s.basePath, pluginName, pluginName+".wasm")
+	name, version, wasmPath, ok := localBuild(s.basePath, pluginName)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrPluginNotFound, pluginName)
+	}
 
 	// Check if the file exists
 	if _, err := os.Stat(wasmPath); err != nil {
@@ -101,9 +236,44 @@ func (s *LocalPluginStore) Resolve(pluginName string) (string, error) {
 		return "", fmt.Errorf("failed to access plugin: %w", err)
 	}
 
+	if err := checkPlugin(name, version, wasmPath); err != nil {
+		return "", err
+	}
+
 	return wasmPath, nil
 }
 
+// List returns the plugins under basePath.
+//
+// A missing basePath is treated as an empty store, so a fresh checkout
+// without built plugins lists nothing instead of failing.
+func (s *LocalPluginStore) List() ([]PluginInfo, error) {
+	plugins, err := listPlugins(s.basePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []PluginInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	return plugins, nil
+}
+
+// Put writes a plugin build to <basePath>/<name>/[<version>/]<name>.wasm.
+func (s *LocalPluginStore) Put(pluginName string, r io.Reader) (PluginInfo, error) {
+	return putPlugin(s.basePath, pluginName, r)
+}
+
+// Delete removes a plugin build from <basePath>/<name>.
+func (s *LocalPluginStore) Delete(pluginName string) error {
+	return deletePlugin(s.basePath, pluginName)
+}
+
+// PutFiles replaces the manifest, checksum file, and signature of a build
+// below <basePath>/<name>.
+func (s *LocalPluginStore) PutFiles(pluginName string, files map[string][]byte) error {
+	return putBuildFiles(s.basePath, pluginName, files)
+}
+
 // FluidPluginStore resolves plugins from a Fluid dataset mount.
 //
 // In production, Fluid mounts a Dataset (backed by S3, HDFS, etc.) as a
@@ -159,7 +329,8 @@ func (s *LocalPluginStore) Resolve(pluginName string) (string, error) {
 //	├── hello/
 //	│   └── hello.wasm
 //	├── transform/
-//	│   └── transform.wasm
+//	│   └── 1.1.0/
+//	│       └── transform.wasm
 //	└── validate/
 //	    └── validate.wasm
 type FluidPluginStore struct {
@@ -186,13 +357,16 @@ func NewFluidPluginStore(mountPath string) *FluidPluginStore {
 
 // Resolve returns the path to a plugin's .wasm file from the Fluid mount.
 //
-// Path format: <mountPath>/<pluginName>/<pluginName>.wasm
+// Path format: <mountPath>/<name>/[<version>/]<name>.wasm
 //
 // The underlying storage (S3, HDFS, etc.) is abstracted by Fluid.
 // This method simply constructs the path and verifies the file exists.
 // Caching and data locality are handled transparently by the Fluid runtime.
 func (s *FluidPluginStore) Resolve(pluginName string) (string, error) {
-	wasmPath := filepath.Join(s.mountPath, pluginName, pluginName+".wasm")
+	name, version, wasmPath, ok := localBuild(s.mountPath, pluginName)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrPluginNotFound, pluginName)
+	}
 
 	// Check if the file exists on the mount
 	// Fluid's FUSE layer handles fetching from remote storage if needed
@@ -205,5 +379,282 @@ func (s *FluidPluginStore) Resolve(pluginName string) (string, error) {
 		return "", fmt.Errorf("failed to access plugin on Fluid mount: %w", err)
 	}
 
+	if err := checkPlugin(name, version, wasmPath); err != nil {
+		return "", err
+	}
+
 	return wasmPath, nil
 }
+
+// List returns the plugins on the Fluid mount.
+//
+// Unlike LocalPluginStore, a missing mount path is an error: it means the
+// dataset is not mounted, not that it is empty.
+func (s *FluidPluginStore) List() ([]PluginInfo, error) {
+	plugins, err := listPlugins(s.mountPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins on Fluid mount: %w", err)
+	}
+	return plugins, nil
+}
+
+// Put writes a plugin build to the Fluid mount. The dataset must be
+// mounted read-write; Fluid propagates the file to the backing storage.
+func (s *FluidPluginStore) Put(pluginName string, r io.Reader) (PluginInfo, error) {
+	info, err := putPlugin(s.mountPath, pluginName, r)
+	if err != nil {
+		return PluginInfo{}, fmt.Errorf("failed to write plugin to Fluid mount: %w", err)
+	}
+	return info, nil
+}
+
+// Delete removes a plugin from the Fluid mount. The dataset must be
+// mounted read-write.
+func (s *FluidPluginStore) Delete(pluginName string) error {
+	if err := deletePlugin(s.mountPath, pluginName); err != nil {
+		return fmt.Errorf("failed to delete plugin from Fluid mount: %w", err)
+	}
+	return nil
+}
+
+// PutFiles replaces the manifest, checksum file, and signature of a build
+// on the Fluid mount, which must be mounted read-write.
+func (s *FluidPluginStore) PutFiles(pluginName string, files map[string][]byte) error {
+	if err := putBuildFiles(s.mountPath, pluginName, files); err != nil {
+		return fmt.Errorf("failed to write plugin files to Fluid mount: %w", err)
+	}
+	return nil
+}
+
+// putPlugin writes r to root/<name>/[<version>/]<name>.wasm through a
+// temporary file renamed into place.
+func putPlugin(root, ref string, r io.Reader) (PluginInfo, error) {
+	// The name and version become path components; they must not escape root
+	name, version, err := parseBuild(ref)
+	if err != nil {
+		return PluginInfo{}, err
+	}
+	dir := buildDir(root, name, version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return PluginInfo{}, fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*.wasm")
+	if err != nil {
+		return PluginInfo{}, fmt.Errorf("failed to write plugin %s: %w", name, err)
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	wasmPath := filepath.Join(dir, name+".wasm")
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), wasmPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return PluginInfo{}, fmt.Errorf("failed to write plugin %s: %w", name, err)
+	}
+
+	info, err := os.Stat(wasmPath)
+	if err != nil {
+		return PluginInfo{}, fmt.Errorf("failed to access plugin: %w", err)
+	}
+	// An invalid manifest or checksum file is reported by Resolve, not here
+	m, _ := readManifest(name, version, wasmPath)
+	digest, _ := manifest.Digest(wasmPath, m)
+	return PluginInfo{Name: name, Version: version, Size: info.Size(), ModTime: info.ModTime(), Manifest: m, SHA256: digest}, nil
+}
+
+// putBuildFiles replaces the companion files of a build in
+// root/<name>/[<version>/] with files, removing those files does not
+// have. Each file is replaced atomically.
+func putBuildFiles(root, ref string, files map[string][]byte) error {
+	name, version, err := parseBuild(ref)
+	if err != nil {
+		return err
+	}
+	for file := range files {
+		if !slices.Contains(companionFiles(name), file) {
+			return fmt.Errorf("%s is not a file of plugin %s", file, name)
+		}
+	}
+	dir := buildDir(root, name, version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	for _, file := range companionFiles(name) {
+		target := filepath.Join(dir, file)
+		data, ok := files[file]
+		if !ok {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", file, err)
+			}
+			continue
+		}
+		tmp, err := os.CreateTemp(dir, ".upload-*")
+		if err != nil {
+			return fmt.Errorf("failed to write %s of plugin %s: %w", file, name, err)
+		}
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), 0644)
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), target)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("failed to write %s of plugin %s: %w", file, name, err)
+		}
+	}
+	return nil
+}
+
+// deletePlugin removes root/<name>/[<version>/]<name>.wasm, the build's
+// manifest, checksum file, and signature, and its directories if they are
+// then empty.
+func deletePlugin(root, ref string) error {
+	name, version, err := parseBuild(ref)
+	if err != nil {
+		return err
+	}
+	dir := buildDir(root, name, version)
+	wasmPath := filepath.Join(dir, name+".wasm")
+
+	if err := os.Remove(wasmPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrPluginNotFound, ref)
+		}
+		return fmt.Errorf("failed to delete plugin %s: %w", ref, err)
+	}
+	for _, path := range []string{manifest.Path(wasmPath), wasmPath + manifest.DigestSuffix, wasmPath + manifest.SignatureSuffix} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s of plugin %s: %w", filepath.Base(path), ref, err)
+		}
+	}
+	// Fails, as intended, while other files remain
+	_ = os.Remove(dir)
+	if version != "" {
+		_ = os.Remove(filepath.Join(root, name))
+	}
+	return nil
+}
+
+// validPluginName reports whether name can be used as a single path
+// component or object key segment without escaping the store.
+func validPluginName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name && !strings.Contains(name, "/")
+}
+
+// parseBuild splits a reference naming one stored build, as Put and
+// Delete take, rejecting LatestVersion and invalid names and versions.
+func parseBuild(ref string) (name, version string, err error) {
+	name, version = ParseReference(ref)
+	if !validPluginName(name) {
+		return "", "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	if name == TenantsDir {
+		return "", "", fmt.Errorf("plugin name %q is reserved for tenant namespaces", name)
+	}
+	if strings.Contains(ref, "@") && !ValidVersion(version) {
+		return "", "", fmt.Errorf("invalid plugin version %q", version)
+	}
+	return name, version, nil
+}
+
+// listPlugins scans root for <name>/<name>.wasm and
+// <name>/<version>/<name>.wasm files.
+//
+// Entries that disappear or become unreadable during the scan are skipped
+// rather than failing the whole listing.
+func listPlugins(root string) ([]PluginInfo, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := make([]PluginInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		versions := append([]string{""}, localVersions(root, name)...)
+		for _, version := range versions {
+			wasmPath := filepath.Join(buildDir(root, name, version), name+".wasm")
+			info, err := os.Stat(wasmPath)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			// An invalid manifest or checksum file is reported by Resolve, not here
+			m, _ := readManifest(name, version, wasmPath)
+			digest, _ := manifest.Digest(wasmPath, m)
+			plugins = append(plugins, PluginInfo{
+				Name:     name,
+				Version:  version,
+				Size:     info.Size(),
+				ModTime:  info.ModTime(),
+				Manifest: m,
+				SHA256:   digest,
+			})
+		}
+	}
+
+	sortPlugins(plugins)
+	return plugins, nil
+}
+
+// sortPlugins sorts builds by name and then by version, the unversioned
+// build first.
+func sortPlugins(plugins []PluginInfo) {
+	sort.Slice(plugins, func(i, j int) bool {
+		a, b := plugins[i], plugins[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version == "" || b.Version == "" {
+			return a.Version == ""
+		}
+		return CompareVersions(a.Version, b.Version) < 0
+	})
+}
+
+// checkPlugin validates the manifest and checksum file of the plugin at
+// wasmPath, if any. Whether the binary matches its digest is left to the
+// runtime, which reads the binary anyway.
+func checkPlugin(pluginName, version, wasmPath string) error {
+	m, err := readManifest(pluginName, version, wasmPath)
+	if err != nil {
+		return err
+	}
+	if _, err := manifest.Digest(wasmPath, m); err != nil {
+		return fmt.Errorf("plugin %s: %w", pluginName, err)
+	}
+	return nil
+}
+
+// readManifest reads the manifest of the plugin at wasmPath, if any, and
+// checks that it describes pluginName and, for a versioned build, version.
+func readManifest(pluginName, version, wasmPath string) (*manifest.Manifest, error) {
+	m, err := manifest.ForPlugin(wasmPath)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", pluginName, err)
+	}
+	if m != nil && m.Name != pluginName {
+		return nil, fmt.Errorf("plugin %s: %w: manifest names plugin %q",
+			pluginName, manifest.ErrInvalid, m.Name)
+	}
+	if m != nil && version != "" && m.Version != version {
+		return nil, fmt.Errorf("plugin %s: %w: manifest declares version %q, stored as %q",
+			pluginName, manifest.ErrInvalid, m.Version, version)
+	}
+	return m, nil
+}
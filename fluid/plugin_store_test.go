@@ -1,11 +1,14 @@
 package fluid_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -108,6 +111,215 @@ var _ = Describe("PluginStore", func() {
 
 				Expect(err).To(HaveOccurred())
 			})
+
+			It("should list no plugins", func() {
+				plugins, err := store.List()
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins).To(BeEmpty())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Plugin listing
+		// Why: Clients discover runnable plugins through List; it must return
+		//      exactly the names Resolve accepts, with file metadata.
+		// =====================================================================
+		Context("when listing plugins", func() {
+			BeforeEach(func() {
+				// <tempDir>/alpha/alpha.wasm - listed before hello
+				Expect(os.MkdirAll(filepath.Join(tempDir, "alpha"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tempDir, "alpha", "alpha.wasm"), []byte("a"), 0644)).To(Succeed())
+
+				// Directory without a matching .wasm file, and a stray file
+				Expect(os.MkdirAll(filepath.Join(tempDir, "src-only"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tempDir, "src-only", "other.wasm"), []byte("x"), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tempDir, "README.md"), []byte("x"), 0644)).To(Succeed())
+			})
+
+			It("should return resolvable plugins sorted by name", func() {
+				plugins, err := store.List()
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins).To(HaveLen(2))
+				Expect(plugins[0].Name).To(Equal("alpha"))
+				Expect(plugins[1].Name).To(Equal("hello"))
+
+				for _, p := range plugins {
+					_, err := store.Resolve(p.Name)
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			It("should report file size and modification time", func() {
+				info, err := os.Stat(filepath.Join(tempDir, "hello", "hello.wasm"))
+				Expect(err).NotTo(HaveOccurred())
+
+				plugins, err := store.List()
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins[1].Size).To(Equal(int64(len("dummy wasm content"))))
+				Expect(plugins[1].ModTime).To(BeTemporally("==", info.ModTime()))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Plugin manifests
+		// Why: Resolve is the last point before the runtime loads a plugin;
+		//      a manifest that is unreadable or describes another plugin
+		//      must stop it there.
+		// =====================================================================
+		Context("when the plugin has a manifest", func() {
+			writeManifest := func(data string) {
+				path := filepath.Join(tempDir, "hello", manifest.FileName)
+				Expect(os.WriteFile(path, []byte(data), 0644)).To(Succeed())
+			}
+
+			It("should resolve a valid manifest and list it", func() {
+				writeManifest(`{"name": "hello", "version": "1.0.0", "description": "greets"}`)
+
+				_, err := store.Resolve("hello")
+				Expect(err).NotTo(HaveOccurred())
+
+				plugins, err := store.List()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins[0].Manifest).NotTo(BeNil())
+				Expect(plugins[0].Manifest.Description).To(Equal("greets"))
+			})
+
+			It("should reject an invalid manifest", func() {
+				writeManifest(`{"name": "hello"}`)
+
+				_, err := store.Resolve("hello")
+
+				Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+				Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeFalse())
+			})
+
+			It("should reject a manifest for another plugin", func() {
+				writeManifest(`{"name": "goodbye", "version": "1.0.0"}`)
+
+				_, err := store.Resolve("hello")
+
+				Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("goodbye"))
+			})
+
+			It("should report the digest the binary is expected to have", func() {
+				digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+				writeManifest(`{"name": "hello", "version": "1.0.0", "sha256": "` + digest + `"}`)
+
+				_, err := store.Resolve("hello")
+				Expect(err).NotTo(HaveOccurred())
+
+				plugins, err := store.List()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins[0].SHA256).To(Equal(digest))
+			})
+
+			It("should reject a malformed checksum file", func() {
+				writeManifest(`{"name": "hello", "version": "1.0.0"}`)
+				checksum := filepath.Join(tempDir, "hello", "hello.wasm"+manifest.DigestSuffix)
+				Expect(os.WriteFile(checksum, []byte("deadbeef\n"), 0644)).To(Succeed())
+
+				_, err := store.Resolve("hello")
+
+				Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue(), "%v", err)
+				Expect(err.Error()).To(ContainSubstring("hello.wasm.sha256"))
+			})
+
+			It("should still list a plugin with an invalid manifest", func() {
+				writeManifest(`not json`)
+
+				plugins, err := store.List()
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins).To(HaveLen(1))
+				Expect(plugins[0].Manifest).To(BeNil())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Plugin uploads
+		// Why: Uploaded builds replace running plugins; a reader must never
+		//      see a partial file, and a name must not escape the store.
+		// =====================================================================
+		Context("when putting a plugin", func() {
+			It("should create a new plugin that Resolve finds", func() {
+				info, err := store.Put("upper", strings.NewReader("new wasm"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Name).To(Equal("upper"))
+				Expect(info.Size).To(Equal(int64(8)))
+
+				path, err := store.Resolve("upper")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.ReadFile(path)).To(Equal([]byte("new wasm")))
+			})
+
+			It("should replace an existing build and keep its manifest", func() {
+				manifestPath := filepath.Join(tempDir, "hello", manifest.FileName)
+				Expect(os.WriteFile(manifestPath, []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+
+				info, err := store.Put("hello", strings.NewReader("rebuilt"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Manifest).NotTo(BeNil())
+
+				Expect(os.ReadFile(filepath.Join(tempDir, "hello", "hello.wasm"))).To(Equal([]byte("rebuilt")))
+				entries, err := os.ReadDir(filepath.Join(tempDir, "hello"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(2), "no temporary files left behind")
+			})
+
+			It("should reject names that escape the store", func() {
+				for _, name := range []string{"", "..", "../evil", "a/b"} {
+					_, err := store.Put(name, strings.NewReader("wasm"))
+					Expect(err).To(HaveOccurred(), name)
+				}
+				Expect(filepath.Join(filepath.Dir(tempDir), "evil")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Plugin removal
+		// Why: A retired plugin must stop resolving, while files the store
+		//      does not own, such as sources, stay where they are.
+		// =====================================================================
+		Context("when deleting a plugin", func() {
+			It("should remove the plugin and its empty directory", func() {
+				manifestPath := filepath.Join(tempDir, "hello", manifest.FileName)
+				Expect(os.WriteFile(manifestPath, []byte(`{"name": "hello", "version": "1.0.0"}`), 0644)).To(Succeed())
+				wasmPath := filepath.Join(tempDir, "hello", "hello.wasm")
+				for _, suffix := range []string{manifest.DigestSuffix, manifest.SignatureSuffix} {
+					Expect(os.WriteFile(wasmPath+suffix, []byte("sidecar"), 0644)).To(Succeed())
+				}
+
+				Expect(store.Delete("hello")).To(Succeed())
+
+				_, err := store.Resolve("hello")
+				Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+				Expect(filepath.Join(tempDir, "hello")).NotTo(BeADirectory())
+			})
+
+			It("should keep other files in the plugin's directory", func() {
+				source := filepath.Join(tempDir, "hello", "hello.cpp")
+				Expect(os.WriteFile(source, []byte("int main() {}"), 0644)).To(Succeed())
+
+				Expect(store.Delete("hello")).To(Succeed())
+
+				Expect(source).To(BeAnExistingFile())
+				plugins, err := store.List()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins).To(BeEmpty())
+			})
+
+			It("should report a missing plugin", func() {
+				err := store.Delete("missing")
+				Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+			})
+
+			It("should reject names that escape the store", func() {
+				Expect(store.Delete("../hello")).NotTo(Succeed())
+			})
 		})
 	})
 
@@ -148,6 +360,28 @@ var _ = Describe("PluginStore", func() {
 				Expect(err.Error()).To(ContainSubstring("plugin not found"))
 			})
 		})
+
+		It("should list plugins on the mount", func() {
+			plugins, err := store.List()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugins).To(HaveLen(1))
+			Expect(plugins[0].Name).To(Equal("hello"))
+		})
+
+		// =====================================================================
+		// TEST: Missing mount
+		// Why: An unmounted dataset must surface as an error, not as an empty
+		//      store that silently hides every plugin.
+		// =====================================================================
+		Context("when the mount is missing", func() {
+			It("should fail to list", func() {
+				_, err := fluid.NewFluidPluginStore("/nonexistent/mount").List()
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Fluid mount"))
+			})
+		})
 	})
 
 	// =========================================================================
@@ -161,5 +395,10 @@ var _ = Describe("PluginStore", func() {
 		It("FluidPluginStore should implement PluginStore", func() {
 			var _ fluid.PluginStore = fluid.NewFluidPluginStore("/mnt/fluid/plugins")
 		})
+
+		It("both stores should implement PluginWriter", func() {
+			var _ fluid.PluginWriter = fluid.NewLocalPluginStore("./plugins")
+			var _ fluid.PluginWriter = fluid.NewFluidPluginStore("/mnt/fluid/plugins")
+		})
 	})
 })
@@ -0,0 +1,129 @@
+package fluid
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// StoreConfig configures a store created by NewStore.
+type StoreConfig struct {
+	// Options are the store's settings, from store.options
+	// (PLUGIN_STORE_OPTIONS) as key=value pairs. Each store documents the
+	// keys it reads.
+	Options map[string]string
+
+	// Cache is where stores that download plugins keep them, configured
+	// like the built-in s3 and http stores (store.cache).
+	Cache ObjectStoreOptions
+}
+
+// Option returns the option key, or def if it is not set.
+func (c StoreConfig) Option(key, def string) string {
+	if value, ok := c.Options[key]; ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// Require returns an error naming the first of keys that is not set.
+func (c StoreConfig) Require(keys ...string) error {
+	for _, key := range keys {
+		if c.Options[key] == "" {
+			return fmt.Errorf("store option %s is required", key)
+		}
+	}
+	return nil
+}
+
+// StoreFactory creates a store from its configuration.
+type StoreFactory func(cfg StoreConfig) (PluginStore, error)
+
+// BuiltinStores are the stores the server configures itself, from their
+// own settings: LocalPluginStore, FluidPluginStore, ObjectPluginStore over
+// S3Client and HTTPObjectStore, and MemoryPluginStore. Their names cannot
+// be registered.
+var BuiltinStores = []string{"local", "fluid", "s3", "http", "memory"}
+
+var (
+	storesMu sync.RWMutex
+	stores   = make(map[string]StoreFactory)
+)
+
+// RegisterStore makes a store backend available under name, e.g. "gcs",
+// so that configuring store.type (PLUGIN_STORE) as name creates it with
+// factory. Backends outside this package register themselves from an
+// init function, and the server includes them with a blank import:
+//
+//	package gcsstore
+//
+//	func init() {
+//		fluid.RegisterStore("gcs", fluid.ObjectStoreFactory(newBucket))
+//	}
+//
+// Like database/sql.Register, RegisterStore panics if name is not a
+// plugin name, is one of BuiltinStores, or is already registered, or if
+// factory is nil.
+func RegisterStore(name string, factory StoreFactory) {
+	if !validPluginName(name) {
+		panic(fmt.Sprintf("fluid: invalid store name %q", name))
+	}
+	for _, builtin := range BuiltinStores {
+		if name == builtin {
+			panic("fluid: store " + name + " is built in")
+		}
+	}
+	if factory == nil {
+		panic("fluid: RegisterStore factory is nil")
+	}
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if _, dup := stores[name]; dup {
+		panic("fluid: RegisterStore called twice for store " + name)
+	}
+	stores[name] = factory
+}
+
+// RegisteredStores returns the names of the registered stores, sorted.
+func RegisteredStores() []string {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStore creates the registered store called name.
+func NewStore(name string, cfg StoreConfig) (PluginStore, error) {
+	storesMu.RLock()
+	factory, ok := stores[name]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no plugin store %q is registered", name)
+	}
+	store, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %w", name, err)
+	}
+	return store, nil
+}
+
+// ObjectStoreFactory returns a StoreFactory for backends that are object
+// stores, such as Google Cloud Storage, Azure Blob Storage, or
+// Artifactory: newObjects creates the ObjectStore, and an
+// ObjectPluginStore caches its plugins in cfg.Cache, below the key prefix
+// of the "prefix" option.
+func ObjectStoreFactory(newObjects func(cfg StoreConfig) (ObjectStore, error)) StoreFactory {
+	return func(cfg StoreConfig) (PluginStore, error) {
+		objects, err := newObjects(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts := cfg.Cache
+		opts.Prefix = cfg.Option("prefix", opts.Prefix)
+		return NewObjectPluginStore(objects, opts), nil
+	}
+}
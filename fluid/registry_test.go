@@ -0,0 +1,87 @@
+package fluid_test
+
+import (
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Store registry", func() {
+	// objects backs the "memory-objects" store registered below
+	var objects *memoryObjects
+
+	BeforeEach(func() {
+		objects = newMemoryObjects()
+	})
+
+	// Registering is global and cannot be undone, so each store is
+	// registered once for the whole suite
+	registered := map[string]bool{}
+	register := func(name string, factory fluid.StoreFactory) {
+		if !registered[name] {
+			fluid.RegisterStore(name, factory)
+			registered[name] = true
+		}
+	}
+
+	BeforeEach(func() {
+		register("memory-objects", fluid.ObjectStoreFactory(func(cfg fluid.StoreConfig) (fluid.ObjectStore, error) {
+			if err := cfg.Require("bucket"); err != nil {
+				return nil, err
+			}
+			return objects, nil
+		}))
+		register("broken", func(fluid.StoreConfig) (fluid.PluginStore, error) {
+			return nil, errors.New("no credentials")
+		})
+	})
+
+	// =========================================================================
+	// TEST: Config-driven construction
+	// Why: Third-party backends are selected by name from the configuration
+	//      and get their settings as options, without changes to fluid.
+	// =========================================================================
+	It("should create registered stores from their configuration", func() {
+		objects.put("prod/hello/hello.wasm", "hello build")
+
+		store, err := fluid.NewStore("memory-objects", fluid.StoreConfig{
+			Options: map[string]string{"bucket": "plugins", "prefix": "prod/"},
+			Cache:   fluid.ObjectStoreOptions{CacheDir: GinkgoT().TempDir()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(path)).To(BeEquivalentTo("hello build"))
+		Expect(fluid.RegisteredStores()).To(ContainElements("broken", "memory-objects"))
+	})
+
+	It("should report unknown stores and factory errors", func() {
+		_, err := fluid.NewStore("ftp", fluid.StoreConfig{})
+		Expect(err).To(MatchError(ContainSubstring(`no plugin store "ftp"`)))
+
+		_, err = fluid.NewStore("memory-objects", fluid.StoreConfig{})
+		Expect(err).To(MatchError("store memory-objects: store option bucket is required"))
+
+		_, err = fluid.NewStore("broken", fluid.StoreConfig{})
+		Expect(err).To(MatchError(ContainSubstring("no credentials")))
+	})
+
+	It("should refuse to register a name twice or a built-in name", func() {
+		factory := func(fluid.StoreConfig) (fluid.PluginStore, error) { return nil, nil }
+		Expect(func() { fluid.RegisterStore("broken", factory) }).To(Panic())
+		Expect(func() { fluid.RegisterStore("s3", factory) }).To(Panic())
+		Expect(func() { fluid.RegisterStore("../gcs", factory) }).To(Panic())
+		Expect(func() { fluid.RegisterStore("gcs", nil) }).To(Panic())
+	})
+
+	It("should fall back to defaults for unset options", func() {
+		cfg := fluid.StoreConfig{Options: map[string]string{"region": "", "bucket": "plugins"}}
+		Expect(cfg.Option("bucket", "other")).To(Equal("plugins"))
+		Expect(cfg.Option("region", "eu")).To(Equal("eu"))
+		Expect(cfg.Require("bucket", "region")).To(MatchError("store option region is required"))
+	})
+})
@@ -0,0 +1,333 @@
+package fluid
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3Client.
+type S3Config struct {
+	Bucket string
+	Region string // Defaults to us-east-1
+
+	// Endpoint is the base URL of an S3-compatible service, e.g.
+	// "http://minio:9000", addressed path-style. Empty uses AWS S3 with
+	// virtual-hosted-style URLs.
+	Endpoint string
+
+	// Credentials sign every request (AWS Signature Version 4). Without an
+	// access key, requests are sent unsigned, for public buckets.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// HTTPClient sends the requests; nil uses a client with a 60s timeout.
+	HTTPClient *http.Client
+}
+
+// S3Client is an ObjectStore reading objects from an S3 bucket with the
+// plain REST API, so no AWS SDK is needed. It also writes objects, for
+// hosts that give plugins a bucket (see Put).
+type S3Client struct {
+	cfg     S3Config
+	baseURL *url.URL // Bucket URL; keys are appended to its path
+	http    *http.Client
+	now     func() time.Time
+}
+
+// NewS3Client creates a client for cfg.Bucket.
+func NewS3Client(cfg S3Config) (*S3Client, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	var base *url.URL
+	if cfg.Endpoint == "" {
+		base = &url.URL{Scheme: "https", Host: cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com", Path: "/"}
+	} else {
+		endpoint, err := url.Parse(cfg.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+		}
+		base = endpoint.JoinPath(cfg.Bucket)
+		base.Path += "/"
+	}
+
+	hc := cfg.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3Client{cfg: cfg, baseURL: base, http: hc, now: time.Now}, nil
+}
+
+// Get fetches an object, conditionally on the cached version (GetObject).
+func (c *S3Client) Get(ctx context.Context, key string, cached ObjectInfo) (io.ReadCloser, ObjectInfo, error) {
+	req, err := c.request(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	setConditions(req, cached)
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, ObjectInfo{}, ErrNotModified
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	default:
+		return nil, ObjectInfo{}, s3Error(resp, key)
+	}
+
+	return resp.Body, responseInfo(resp, key), nil
+}
+
+// listBucketResult is the response of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the objects under prefix (ListObjectsV2), following
+// continuation tokens.
+func (c *S3Client) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := c.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.send(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, s3Error(resp, prefix)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 listing: %w", err)
+		}
+		for _, object := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:     object.Key,
+				Size:    object.Size,
+				ETag:    object.ETag,
+				ModTime: object.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Put stores data as the object at key, replacing any object there
+// (PutObject). GCS buckets accept it through their S3-compatible endpoint,
+// https://storage.googleapis.com, with HMAC keys as credentials.
+func (c *S3Client) Put(ctx context.Context, key string, data []byte) error {
+	req, err := c.request(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, key)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// request builds a request for key, or for the bucket with query. A body
+// is sent with its SHA-256, which the signature covers.
+func (c *S3Client) request(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += key
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	if body != nil {
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	}
+	return req, nil
+}
+
+// send signs and sends a request.
+func (c *S3Client) send(req *http.Request) (*http.Response, error) {
+	if c.cfg.AccessKeyID != "" {
+		c.sign(req, c.now())
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// emptySHA256 is the hex SHA-256 of an empty payload, the body of every
+// request but PutObject.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds an AWS Signature Version 4 Authorization header covering the
+// host and every header already set on req. The payload hash is taken
+// from the X-Amz-Content-Sha256 header, set by request for a body, and is
+// that of an empty payload otherwise.
+func (c *S3Client) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = emptySHA256
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + c.cfg.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), day)
+	key = hmacSHA256(key, c.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query sorted by key with RFC 3986 escaping, as
+// both the URL and the signature need it.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath escapes every segment of an object path with uriEncode.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode escapes every byte except the RFC 3986 unreserved characters.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			b.WriteString("%" + strings.ToUpper(strconv.FormatUint(uint64(ch)|0x100, 16)[1:]))
+		}
+	}
+	return b.String()
+}
+
+// s3Error turns an unexpected response into an error carrying the S3
+// error code, e.g. AccessDenied, and closes its body.
+func s3Error(resp *http.Response, key string) error {
+	defer resp.Body.Close()
+	var problem struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &problem) == nil && problem.Code != "" {
+		return fmt.Errorf("S3 %s for %q: %s: %s", resp.Status, key, problem.Code, problem.Message)
+	}
+	return fmt.Errorf("S3 %s for %q", resp.Status, key)
+}
@@ -0,0 +1,270 @@
+package fluid_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+// fakeS3 serves GetObject, PutObject, and ListObjectsV2 for one bucket,
+// path-style.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string]string
+	pageSize int
+	requests []*http.Request
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`)
+		return
+	}
+	if key == "private.wasm" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = string(data)
+		w.Header().Set("ETag", `"`+key+`"`)
+		return
+	}
+
+	if key == "" && r.URL.Query().Get("list-type") == "2" {
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		// Sorted like S3, paginated by pageSize
+		sort.Strings(keys)
+		start := 0
+		if token := r.URL.Query().Get("continuation-token"); token != "" {
+			fmt.Sscanf(token, "page-%d", &start)
+		}
+		end := min(start+f.pageSize, len(keys))
+		fmt.Fprint(w, `<ListBucketResult>`)
+		for _, k := range keys[start:end] {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size><ETag>"%s"</ETag><LastModified>2024-05-01T12:00:00.000Z</LastModified></Contents>`,
+				k, len(f.objects[k]), k)
+		}
+		if end < len(keys) {
+			fmt.Fprintf(w, `<IsTruncated>true</IsTruncated><NextContinuationToken>page-%d</NextContinuationToken>`, end)
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+		return
+	}
+
+	data, ok := f.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+		return
+	}
+	etag := `"` + key + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", "Wed, 01 May 2024 12:00:00 GMT")
+	fmt.Fprint(w, data)
+}
+
+func (f *fakeS3) lastRequest() *http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+var _ = Describe("S3Client", func() {
+	var (
+		fake   *fakeS3
+		server *httptest.Server
+		client *fluid.S3Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		fake = &fakeS3{
+			objects: map[string]string{
+				"plugins/hello/hello.wasm": "hello wasm",
+				"plugins/echo/echo.wasm":   "echo wasm",
+				"plugins/echo/plugin.json": `{"name": "echo", "version": "1.0.0"}`,
+				"plugins/a b/a b.wasm":     "spaced",
+			},
+			pageSize: 1000,
+		}
+		server = httptest.NewServer(fake)
+		DeferCleanup(server.Close)
+		ctx = context.Background()
+
+		var err error
+		client, err = fluid.NewS3Client(fluid.S3Config{Bucket: "bucket", Endpoint: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	read := func(body io.ReadCloser) string {
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("should reject an invalid configuration", func() {
+		_, err := fluid.NewS3Client(fluid.S3Config{})
+		Expect(err).To(MatchError(ContainSubstring("bucket is required")))
+
+		_, err = fluid.NewS3Client(fluid.S3Config{Bucket: "bucket", Endpoint: "minio:9000"})
+		Expect(err).To(MatchError(ContainSubstring("invalid S3 endpoint")))
+	})
+
+	// =========================================================================
+	// TEST: Conditional GetObject
+	// Why: Revalidation relies on S3 answering 304 to a matching ETag, so an
+	//      unchanged plugin costs a request but no download.
+	// =========================================================================
+	It("should get an object and revalidate it with its ETag", func() {
+		body, info, err := client.Get(ctx, "plugins/hello/hello.wasm", fluid.ObjectInfo{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(body)).To(Equal("hello wasm"))
+		Expect(info.ETag).To(Equal(`"plugins/hello/hello.wasm"`))
+		Expect(info.Size).To(Equal(int64(10)))
+		Expect(info.ModTime.IsZero()).To(BeFalse())
+
+		_, _, err = client.Get(ctx, "plugins/hello/hello.wasm", info)
+		Expect(err).To(MatchError(fluid.ErrNotModified))
+		Expect(fake.lastRequest().Header.Get("If-None-Match")).To(Equal(info.ETag))
+	})
+
+	It("should escape keys", func() {
+		body, _, err := client.Get(ctx, "plugins/a b/a b.wasm", fluid.ObjectInfo{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(body)).To(Equal("spaced"))
+		Expect(fake.lastRequest().RequestURI).To(Equal("/bucket/plugins/a%20b/a%20b.wasm"))
+	})
+
+	It("should map S3 errors", func() {
+		_, _, err := client.Get(ctx, "plugins/missing/missing.wasm", fluid.ObjectInfo{})
+		Expect(errors.Is(err, fluid.ErrObjectNotFound)).To(BeTrue())
+
+		_, _, err = client.Get(ctx, "private.wasm", fluid.ObjectInfo{})
+		Expect(err).To(MatchError(ContainSubstring("AccessDenied: Access Denied")))
+		Expect(errors.Is(err, fluid.ErrObjectNotFound)).To(BeFalse())
+	})
+
+	It("should put objects", func() {
+		Expect(client.Put(ctx, "outputs/a b.bin", []byte("result"))).To(Succeed())
+		Expect(fake.lastRequest().Method).To(Equal(http.MethodPut))
+		Expect(fake.lastRequest().RequestURI).To(Equal("/bucket/outputs/a%20b.bin"))
+
+		body, _, err := client.Get(ctx, "outputs/a b.bin", fluid.ObjectInfo{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read(body)).To(Equal("result"))
+
+		err = client.Put(ctx, "private.wasm", []byte("x"))
+		Expect(err).To(MatchError(ContainSubstring("AccessDenied")))
+	})
+
+	It("should list objects across pages", func() {
+		fake.pageSize = 1
+
+		objects, err := client.List(ctx, "plugins/")
+		Expect(err).NotTo(HaveOccurred())
+		keys := make([]string, len(objects))
+		for i, object := range objects {
+			keys[i] = object.Key
+		}
+		Expect(keys).To(Equal([]string{
+			"plugins/a b/a b.wasm",
+			"plugins/echo/echo.wasm",
+			"plugins/echo/plugin.json",
+			"plugins/hello/hello.wasm",
+		}))
+		Expect(objects[1].Size).To(Equal(int64(9)))
+		Expect(objects[1].ModTime.Year()).To(Equal(2024))
+	})
+
+	It("should fail to list a missing bucket", func() {
+		client, err := fluid.NewS3Client(fluid.S3Config{Bucket: "other", Endpoint: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.List(ctx, "")
+		Expect(err).To(MatchError(ContainSubstring("NoSuchBucket")))
+	})
+
+	// =========================================================================
+	// TEST: Request signing
+	// Why: Private buckets reject unsigned requests; public buckets must
+	//      still work without credentials.
+	// =========================================================================
+	It("should sign requests only when credentials are configured", func() {
+		_, _, err := client.Get(ctx, "plugins/missing/missing.wasm", fluid.ObjectInfo{})
+		Expect(errors.Is(err, fluid.ErrObjectNotFound)).To(BeTrue())
+		Expect(fake.lastRequest().Header.Get("Authorization")).To(BeEmpty())
+
+		signed, err := fluid.NewS3Client(fluid.S3Config{
+			Bucket:          "bucket",
+			Region:          "eu-west-1",
+			Endpoint:        server.URL,
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "secret",
+			SessionToken:    "token",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = signed.List(ctx, "plugins/")
+		Expect(err).NotTo(HaveOccurred())
+
+		req := fake.lastRequest()
+		Expect(req.Header.Get("Authorization")).To(MatchRegexp(
+			`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/\d{8}/eu-west-1/s3/aws4_request, ` +
+				`SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=[0-9a-f]{64}$`))
+		Expect(req.Header.Get("X-Amz-Security-Token")).To(Equal("token"))
+
+		// A body is signed by its hash, SHA-256("result")
+		Expect(signed.Put(ctx, "outputs/result.bin", []byte("result"))).To(Succeed())
+		Expect(fake.lastRequest().Header.Get("X-Amz-Content-Sha256")).To(Equal(
+			"f6a214f7a5fcda0c2cee9660b7fc29f5649e3c68aad48e20e950137c98913a68"))
+	})
+
+	It("should serve an ObjectPluginStore", func() {
+		store := fluid.NewObjectPluginStore(client, fluid.ObjectStoreOptions{
+			Prefix:   "plugins/",
+			CacheDir: GinkgoT().TempDir(),
+		})
+
+		path, err := store.Resolve("hello")
+		Expect(err).NotTo(HaveOccurred())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("hello wasm"))
+
+		plugins, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(3))
+		Expect(plugins[1].Name).To(Equal("echo"))
+		Expect(plugins[1].Manifest.Version).To(Equal("1.0.0"))
+	})
+})
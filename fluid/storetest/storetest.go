@@ -0,0 +1,426 @@
+// Package storetest verifies that a fluid.PluginStore keeps the contract
+// the server relies on, so that every store, built in or registered with
+// fluid.RegisterStore, is checked against the same specs: how references
+// resolve, which errors are returned, what List shows, that resolved paths
+// can be opened, that changes published behind the store's back are seen,
+// and that all of it holds under concurrent use.
+//
+// A backend's Ginkgo suite declares the specs with Describe, passing a
+// Factory that publishes a set of files to a fresh instance of the store:
+//
+//	var _ = storetest.Describe("GCS store", func(files map[string][]byte) storetest.Fixture {
+//		bucket := newFakeBucket()
+//		for path, data := range files {
+//			bucket.put("plugins/"+path, data)
+//		}
+//		store, err := fluid.NewStore("gcs", fluid.StoreConfig{
+//			Options: map[string]string{"prefix": "plugins/"},
+//			Cache:   fluid.ObjectStoreOptions{CacheDir: GinkgoT().TempDir(), Revalidate: -1},
+//		})
+//		Expect(err).NotTo(HaveOccurred())
+//		return storetest.Fixture{
+//			Store:   store,
+//			Publish: func(path string, data []byte) { bucket.put("plugins/"+path, data) },
+//			Remove:  func(path string) { bucket.remove("plugins/" + path) },
+//		}
+//	})
+//
+// Packages without a Ginkgo suite call Run from a standard test instead.
+// Run with -race: the concurrency specs rely on it to report data races.
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// Fixture is a store under test and the means to change what it holds the
+// way publishing from elsewhere does.
+type Fixture struct {
+	Store fluid.PluginStore
+
+	// Publish adds or replaces the file at path, and Remove deletes it,
+	// below the store's root and bypassing the store. The store must see
+	// a change on the next Resolve, so caching stores are created with
+	// revalidation on every call. The specs of such changes are skipped
+	// unless both are set.
+	Publish func(path string, data []byte)
+	Remove  func(path string)
+
+	// Root is the directory the store reads its builds from, if a
+	// fluid.Watcher can watch it; the specs of watching are skipped
+	// without one.
+	Root string
+}
+
+// Factory returns a fixture whose store holds files, keyed by their
+// slash-separated path below the store's root, e.g.
+// "hello/1.0.0/hello.wasm". It is called from within each spec, so it may
+// use GinkgoT, DeferCleanup, and Gomega assertions.
+type Factory func(files map[string][]byte) Fixture
+
+// concurrency is how many goroutines the concurrency specs run at once.
+const concurrency = 16
+
+// Run runs the conformance specs of the store newStore creates as the
+// test t, for packages that do not use Ginkgo. It calls RunSpecs, so it
+// can only be called once per test binary, and not alongside a Ginkgo
+// suite; call Describe from those.
+func Run(t *testing.T, text string, newStore Factory) {
+	Describe(text, newStore)
+	RegisterFailHandler(ginkgo.Fail)
+	ginkgo.RunSpecs(t, "Store Conformance Suite")
+}
+
+// Describe declares the conformance specs of the store newStore creates,
+// under a container called text.
+func Describe(text string, newStore Factory) bool {
+	return ginkgo.Describe(text, func() {
+		// read returns the content of the file at path
+		read := func(path string) string {
+			data, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred(), "opening the resolved path %s", path)
+			return string(data)
+		}
+
+		// resolve resolves ref and returns the content of the build
+		resolve := func(store fluid.PluginStore, ref string) string {
+			path, err := store.Resolve(ref)
+			Expect(err).NotTo(HaveOccurred(), "resolving %s", ref)
+			return read(path)
+		}
+
+		// builds returns the builds List reports, as name@version
+		builds := func(store fluid.PluginStore) []string {
+			plugins, err := store.List()
+			Expect(err).NotTo(HaveOccurred())
+			var refs []string
+			for _, p := range plugins {
+				refs = append(refs, p.Name+"@"+p.Version)
+			}
+			return refs
+		}
+
+		// changing returns a fixture for specs that publish behind the
+		// store's back, skipping the spec if the store cannot be changed so
+		changing := func(files map[string][]byte) Fixture {
+			fixture := newStore(files)
+			if fixture.Publish == nil || fixture.Remove == nil {
+				ginkgo.Skip("the fixture cannot publish behind the store's back")
+			}
+			return fixture
+		}
+
+		// writer returns the store as a PluginWriter, skipping the spec if
+		// it is read-only
+		writer := func(store fluid.PluginStore) fluid.PluginWriter {
+			w, ok := store.(fluid.PluginWriter)
+			if !ok {
+				ginkgo.Skip("the store is read-only")
+			}
+			return w
+		}
+
+		// =====================================================================
+		// TEST: Resolving builds
+		// Why: The runtime loads whatever path Resolve returns, and callers
+		//      pin versions; every store must pick the same build for the
+		//      same reference.
+		// =====================================================================
+		ginkgo.Context("resolving", func() {
+			ginkgo.It("should resolve a plugin to a path holding its binary", func() {
+				store := newStore(map[string][]byte{"hello/hello.wasm": []byte("hello build")}).Store
+				Expect(resolve(store, "hello")).To(Equal("hello build"))
+			})
+
+			ginkgo.It("should resolve versions, latest skipping pre-releases", func() {
+				store := newStore(map[string][]byte{
+					"hello/1.0.0/hello.wasm":      []byte("1.0.0"),
+					"hello/1.10.0/hello.wasm":     []byte("1.10.0"),
+					"hello/1.9.0/hello.wasm":      []byte("1.9.0"),
+					"hello/2.0.0-rc.1/hello.wasm": []byte("2.0.0-rc.1"),
+				}).Store
+				Expect(resolve(store, "hello@1.0.0")).To(Equal("1.0.0"))
+				Expect(resolve(store, "hello@2.0.0-rc.1")).To(Equal("2.0.0-rc.1"))
+				Expect(resolve(store, "hello@"+fluid.LatestVersion)).To(Equal("1.10.0"))
+				Expect(resolve(store, "hello")).To(Equal("1.10.0"))
+			})
+
+			ginkgo.It("should prefer the unversioned build for a bare name", func() {
+				store := newStore(map[string][]byte{
+					"hello/hello.wasm":       []byte("unversioned"),
+					"hello/1.0.0/hello.wasm": []byte("1.0.0"),
+				}).Store
+				Expect(resolve(store, "hello")).To(Equal("unversioned"))
+				Expect(resolve(store, "hello@1.0.0")).To(Equal("1.0.0"))
+			})
+
+			ginkgo.It("should keep resolving to a path that can be opened", func() {
+				store := newStore(map[string][]byte{"hello/hello.wasm": []byte("hello build")}).Store
+				first, err := store.Resolve("hello")
+				Expect(err).NotTo(HaveOccurred())
+				second, err := store.Resolve("hello")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(second).To(Equal(first))
+				Expect(read(first)).To(Equal("hello build"))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Error types
+		// Why: The server maps ErrPluginNotFound to 404 and
+		//      manifest.ErrInvalid to a load failure; other errors are
+		//      reported as internal.
+		// =====================================================================
+		ginkgo.Context("errors", func() {
+			ginkgo.It("should return ErrPluginNotFound for missing plugins and versions", func() {
+				store := newStore(map[string][]byte{"hello/1.0.0/hello.wasm": []byte("1.0.0")}).Store
+				for _, ref := range []string{"nope", "hello@2.0.0", "nope@" + fluid.LatestVersion} {
+					_, err := store.Resolve(ref)
+					Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue(), "resolving %s: %v", ref, err)
+				}
+			})
+
+			ginkgo.It("should not resolve references escaping the store", func() {
+				store := newStore(map[string][]byte{"hello/hello.wasm": []byte("hello build")}).Store
+				for _, ref := range []string{"", ".", "..", "../hello", "hello/../hello", "hello@../hello"} {
+					_, err := store.Resolve(ref)
+					Expect(err).To(HaveOccurred(), "resolving %q", ref)
+				}
+			})
+
+			ginkgo.It("should reject a plugin whose manifest or checksum file is invalid", func() {
+				store := newStore(map[string][]byte{
+					"hello/hello.wasm":          []byte("hello build"),
+					"hello/plugin.json":         []byte(`{"name": "goodbye", "version": "1.0.0"}`),
+					"broken/broken.wasm":        []byte("broken build"),
+					"broken/plugin.json":        []byte(`{`),
+					"pinned/1.0.0/pinned.wasm":  []byte("pinned build"),
+					"pinned/1.0.0/plugin.json":  []byte(`{"name": "pinned", "version": "2.0.0"}`),
+					"summed/summed.wasm":        []byte("summed build"),
+					"summed/summed.wasm.sha256": []byte("not a digest"),
+					"other/other.wasm":          []byte("other build"),
+					"other/plugin.json":         []byte(`{"name": "other", "version": "1.0.0"}`),
+				}).Store
+				for _, ref := range []string{"hello", "broken", "pinned@1.0.0", "summed"} {
+					_, err := store.Resolve(ref)
+					Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue(), "resolving %s: %v", ref, err)
+					Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeFalse())
+				}
+				Expect(resolve(store, "other")).To(Equal("other build"))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Listing
+		// Why: GET /plugins, schedules, and warm-up walk List; it must show
+		//      exactly the builds Resolve finds, in a stable order.
+		// =====================================================================
+		ginkgo.Context("listing", func() {
+			ginkgo.It("should list every build sorted by name and version", func() {
+				store := newStore(map[string][]byte{
+					"zeta/zeta.wasm":          []byte("z"),
+					"hello/1.10.0/hello.wasm": []byte("1.10.0"),
+					"hello/1.9.0/hello.wasm":  []byte("1.9.0"),
+					"hello/hello.wasm":        []byte("unversioned"),
+					"hello/plugin.json":       []byte(`{"name": "hello", "version": "0.1.0", "description": "greets"}`),
+					"src-only/other.wasm":     []byte("not a build"),
+				}).Store
+
+				Expect(builds(store)).To(Equal([]string{"hello@", "hello@1.9.0", "hello@1.10.0", "zeta@"}))
+				plugins, _ := store.List()
+				Expect(plugins[0].Size).To(BeEquivalentTo(len("unversioned")))
+				Expect(plugins[0].Manifest).NotTo(BeNil())
+				Expect(plugins[0].Manifest.Description).To(Equal("greets"))
+				for _, p := range plugins {
+					ref := p.Name
+					if p.Version != "" {
+						ref += "@" + p.Version
+					}
+					_, err := store.Resolve(ref)
+					Expect(err).NotTo(HaveOccurred(), "resolving listed build %s", ref)
+				}
+			})
+
+			ginkgo.It("should list nothing for an empty store", func() {
+				Expect(builds(newStore(map[string][]byte{}).Store)).To(BeEmpty())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Changes published elsewhere
+		// Why: Plugins are pushed to the bucket or mount by CI, not through
+		//      the server; a store that served stale builds would keep
+		//      running code that was replaced or withdrawn.
+		// =====================================================================
+		ginkgo.Context("when builds change behind the store's back", func() {
+			ginkgo.It("should resolve the replaced build", func() {
+				fixture := changing(map[string][]byte{"hello/hello.wasm": []byte("old build")})
+				Expect(resolve(fixture.Store, "hello")).To(Equal("old build"))
+
+				fixture.Publish("hello/hello.wasm", []byte("new build, longer"))
+				Expect(resolve(fixture.Store, "hello")).To(Equal("new build, longer"))
+			})
+
+			ginkgo.It("should pick up new versions and stop resolving removed ones", func() {
+				fixture := changing(map[string][]byte{"hello/1.0.0/hello.wasm": []byte("1.0.0")})
+				Expect(resolve(fixture.Store, "hello@latest")).To(Equal("1.0.0"))
+
+				fixture.Publish("hello/1.1.0/hello.wasm", []byte("1.1.0"))
+				Expect(resolve(fixture.Store, "hello@latest")).To(Equal("1.1.0"))
+
+				fixture.Remove("hello/1.1.0/hello.wasm")
+				_, err := fixture.Store.Resolve("hello@1.1.0")
+				Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue(), "resolving a removed build: %v", err)
+				Expect(resolve(fixture.Store, "hello@latest")).To(Equal("1.0.0"))
+			})
+
+			ginkgo.It("should report the changes to a watcher with the paths Resolve returns", func() {
+				fixture := changing(map[string][]byte{"hello/hello.wasm": []byte("old build")})
+				if fixture.Root == "" {
+					ginkgo.Skip("the store cannot be watched")
+				}
+				watcher := fluid.NewWatcher(fixture.Root, 0)
+
+				fixture.Publish("hello/hello.wasm", []byte("new build, longer"))
+				fixture.Publish("hello/1.0.0/hello.wasm", []byte("1.0.0"))
+				path, _ := fixture.Store.Resolve("hello")
+				versioned, _ := fixture.Store.Resolve("hello@1.0.0")
+				Expect(watcher.Scan()).To(ConsistOf(
+					fluid.Change{Name: "hello", Path: path, Op: fluid.BuildUpdated},
+					fluid.Change{Name: "hello", Version: "1.0.0", Path: versioned, Op: fluid.BuildCreated},
+				))
+
+				fixture.Remove("hello/1.0.0/hello.wasm")
+				Expect(watcher.Scan()).To(ConsistOf(
+					fluid.Change{Name: "hello", Version: "1.0.0", Path: versioned, Op: fluid.BuildRemoved},
+				))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Writing
+		// Why: POST and DELETE /plugins go through PluginWriter where a
+		//      store implements it; callers resolving at the same time must
+		//      see a whole build or none.
+		// =====================================================================
+		ginkgo.Context("writing", func() {
+			ginkgo.It("should install builds that Resolve finds, keeping the manifest", func() {
+				store := newStore(map[string][]byte{
+					"hello/hello.wasm":  []byte("old build"),
+					"hello/plugin.json": []byte(`{"name": "hello", "version": "1.0.0", "description": "greets"}`),
+				}).Store
+				w := writer(store)
+
+				info, err := w.Put("hello", strings.NewReader("new build"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Name).To(Equal("hello"))
+				Expect(info.Size).To(BeEquivalentTo(len("new build")))
+				Expect(resolve(store, "hello")).To(Equal("new build"))
+
+				_, err = w.Put("hello@2.0.0", strings.NewReader("2.0.0"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resolve(store, "hello@latest")).To(Equal("2.0.0"))
+
+				plugins, err := store.List()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugins[0].Manifest.Description).To(Equal("greets"))
+			})
+
+			ginkgo.It("should reject references that name no single build", func() {
+				w := writer(newStore(map[string][]byte{}).Store)
+				for _, ref := range []string{"", "..", "../hello", "hello@" + fluid.LatestVersion, "hello@../1.0.0"} {
+					_, err := w.Put(ref, strings.NewReader("build"))
+					Expect(err).To(HaveOccurred(), "putting %q", ref)
+				}
+			})
+
+			ginkgo.It("should delete builds, reporting missing ones", func() {
+				store := newStore(map[string][]byte{
+					"hello/hello.wasm":       []byte("unversioned"),
+					"hello/1.0.0/hello.wasm": []byte("1.0.0"),
+				}).Store
+				w := writer(store)
+
+				Expect(w.Delete("hello@1.0.0")).To(Succeed())
+				_, err := store.Resolve("hello@1.0.0")
+				Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue(), "resolving a deleted build: %v", err)
+				Expect(resolve(store, "hello")).To(Equal("unversioned"))
+
+				Expect(errors.Is(w.Delete("hello@1.0.0"), fluid.ErrPluginNotFound)).To(BeTrue())
+				Expect(errors.Is(w.Delete("nope"), fluid.ErrPluginNotFound)).To(BeTrue())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Concurrency
+		// Why: Every /run request resolves its plugin, while GET /plugins,
+		//      schedules, and uploads use the same store from other
+		//      goroutines.
+		// =====================================================================
+		ginkgo.Context("under concurrent use", func() {
+			ginkgo.It("should resolve and list consistently", func() {
+				files := map[string][]byte{}
+				for i := 0; i < concurrency; i++ {
+					name := fmt.Sprintf("plugin%02d", i)
+					files[name+"/"+name+".wasm"] = []byte(name)
+				}
+				store := newStore(files).Store
+
+				var wg sync.WaitGroup
+				for i := 0; i < concurrency; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer ginkgo.GinkgoRecover()
+						name := fmt.Sprintf("plugin%02d", i)
+						for range 10 {
+							Expect(resolve(store, name)).To(Equal(name))
+							plugins, err := store.List()
+							Expect(err).NotTo(HaveOccurred())
+							Expect(plugins).To(HaveLen(concurrency))
+						}
+					}()
+				}
+				wg.Wait()
+			})
+
+			ginkgo.It("should never resolve a partially written build", func() {
+				old := bytes.Repeat([]byte("o"), 64<<10)
+				store := newStore(map[string][]byte{"hello/hello.wasm": old}).Store
+				w := writer(store)
+				replacement := bytes.Repeat([]byte("n"), 64<<10)
+
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					defer ginkgo.GinkgoRecover()
+					for range 20 {
+						_, err := w.Put("hello", bytes.NewReader(replacement))
+						Expect(err).NotTo(HaveOccurred())
+					}
+				}()
+				for {
+					select {
+					case <-done:
+						Expect(resolve(store, "hello")).To(Equal(string(replacement)))
+						return
+					default:
+					}
+					Expect(resolve(store, "hello")).To(Or(Equal(string(old)), Equal(string(replacement))))
+				}
+			})
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package storetest_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/fluid/storetest"
+)
+
+// TestStoretest bootstraps the Ginkgo test suite checking the built-in
+// stores against the conformance specs.
+// Run with: go test -v ./fluid/storetest/...
+func TestStoretest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Store Conformance Suite")
+}
+
+// bucket is an in-memory fluid.ObjectStore, standing in for the backend of
+// a registered store.
+type bucket map[string][]byte
+
+func (b bucket) Get(ctx context.Context, key string, cached fluid.ObjectInfo) (io.ReadCloser, fluid.ObjectInfo, error) {
+	data, ok := b[key]
+	if !ok {
+		return nil, fluid.ObjectInfo{}, fluid.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), fluid.ObjectInfo{Key: key, Size: int64(len(data))}, nil
+}
+
+func (b bucket) List(ctx context.Context, prefix string) ([]fluid.ObjectInfo, error) {
+	var objects []fluid.ObjectInfo
+	for key, data := range b {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, fluid.ObjectInfo{Key: key, Size: int64(len(data))})
+		}
+	}
+	return objects, nil
+}
+
+func init() {
+	// The "bucket" option names the bucket a test published its files to
+	fluid.RegisterStore("bucket", fluid.ObjectStoreFactory(func(cfg fluid.StoreConfig) (fluid.ObjectStore, error) {
+		if err := cfg.Require("bucket"); err != nil {
+			return nil, err
+		}
+		return buckets[cfg.Option("bucket", "")], nil
+	}))
+}
+
+// buckets are the buckets of the bucket store by name.
+var buckets = make(map[string]bucket)
+
+// dirFixture writes files below dir, returning a fixture changing it for
+// store, which reads its builds from dir.
+func dirFixture(dir string, files map[string][]byte, store fluid.PluginStore) storetest.Fixture {
+	publish := func(path string, data []byte) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+	}
+	for path, data := range files {
+		publish(path, data)
+	}
+	return storetest.Fixture{
+		Store:   store,
+		Publish: publish,
+		Remove: func(path string) {
+			Expect(os.Remove(filepath.Join(dir, filepath.FromSlash(path)))).To(Succeed())
+		},
+		Root: dir,
+	}
+}
+
+var _ = storetest.Describe("LocalPluginStore", func(files map[string][]byte) storetest.Fixture {
+	dir := GinkgoT().TempDir()
+	return dirFixture(dir, files, fluid.NewLocalPluginStore(dir))
+})
+
+var _ = storetest.Describe("FluidPluginStore", func(files map[string][]byte) storetest.Fixture {
+	dir := GinkgoT().TempDir()
+	return dirFixture(dir, files, fluid.NewFluidPluginStore(dir))
+})
+
+var _ = storetest.Describe("MemoryPluginStore", func(files map[string][]byte) storetest.Fixture {
+	store, err := fluid.NewMemoryPluginStore()
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(store.Close)
+	return dirFixture(store.Dir(), files, store)
+})
+
+var _ = storetest.Describe("Registered object store", func(files map[string][]byte) storetest.Fixture {
+	b := make(bucket)
+	for path, data := range files {
+		b["plugins/"+path] = data
+	}
+	name := CurrentSpecReport().FullText()
+	buckets[name] = b
+	DeferCleanup(func() { delete(buckets, name) })
+
+	store, err := fluid.NewStore("bucket", fluid.StoreConfig{
+		Options: map[string]string{"bucket": name, "prefix": "plugins/"},
+		Cache:   fluid.ObjectStoreOptions{CacheDir: GinkgoT().TempDir(), Revalidate: -1},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return storetest.Fixture{
+		Store:   store,
+		Publish: func(path string, data []byte) { b["plugins/"+path] = data },
+		Remove:  func(path string) { delete(b, "plugins/"+path) },
+	}
+})
@@ -0,0 +1,90 @@
+package fluid
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// TenantsDir is the directory, or key prefix, below a store's root that
+// holds the plugin namespaces of tenants. Each is laid out like the root:
+//
+//	<base>/hello/hello.wasm                       (shared)
+//	<base>/tenants/acme/hello/hello.wasm          (acme's own hello)
+//	<base>/tenants/acme/billing/1.0.0/billing.wasm
+//
+// No plugin can be named like it.
+const TenantsDir = "tenants"
+
+// NamespacedStore is implemented by stores that keep plugins per tenant
+// next to the shared ones.
+type NamespacedStore interface {
+	// ResolveIn is Resolve within tenant's namespace: pluginName resolves
+	// to the tenant's own build if the namespace has it, and otherwise to
+	// the shared plugin, so that a tenant can replace a shared plugin
+	// without affecting the others. An empty tenant, or one that cannot be
+	// a directory name, has no namespace and resolves like Resolve.
+	ResolveIn(tenant, pluginName string) (string, error)
+}
+
+// ResolveIn resolves pluginName for tenant in store: with ResolveIn if
+// the store keeps namespaces, otherwise with Resolve.
+func ResolveIn(store PluginStore, tenant, pluginName string) (string, error) {
+	if namespaced, ok := store.(NamespacedStore); ok {
+		return namespaced.ResolveIn(tenant, pluginName)
+	}
+	return store.Resolve(pluginName)
+}
+
+// resolveIn implements ResolveIn for shared, whose namespace of a tenant
+// is the store namespace returns.
+func resolveIn(shared PluginStore, namespace func(tenant string) PluginStore, tenant, pluginName string) (string, error) {
+	if tenant == "" || !validPluginName(tenant) {
+		return shared.Resolve(pluginName)
+	}
+	path, err := namespace(tenant).Resolve(pluginName)
+	if errors.Is(err, ErrPluginNotFound) {
+		return shared.Resolve(pluginName)
+	}
+	return path, err
+}
+
+// tenantRoot returns the root of tenant's namespace below root.
+func tenantRoot(root, tenant string) string {
+	return filepath.Join(root, TenantsDir, tenant)
+}
+
+// ResolveIn resolves a plugin in <basePath>/tenants/<tenant> before
+// <basePath>.
+func (s *LocalPluginStore) ResolveIn(tenant, pluginName string) (string, error) {
+	return resolveIn(s, func(tenant string) PluginStore {
+		return NewLocalPluginStore(tenantRoot(s.basePath, tenant))
+	}, tenant, pluginName)
+}
+
+// ResolveIn resolves a plugin in <mountPath>/tenants/<tenant> before
+// <mountPath>.
+func (s *FluidPluginStore) ResolveIn(tenant, pluginName string) (string, error) {
+	return resolveIn(s, func(tenant string) PluginStore {
+		return NewFluidPluginStore(tenantRoot(s.mountPath, tenant))
+	}, tenant, pluginName)
+}
+
+// ResolveIn resolves a plugin below <prefix>tenants/<tenant>/ before
+// <prefix>, caching the tenant's builds in <CacheDir>/tenants/<tenant>.
+func (s *ObjectPluginStore) ResolveIn(tenant, pluginName string) (string, error) {
+	return resolveIn(s, func(tenant string) PluginStore {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		store, ok := s.tenants[tenant]
+		if !ok {
+			store = NewObjectPluginStore(s.objects, ObjectStoreOptions{
+				Prefix:     s.prefix + TenantsDir + "/" + tenant + "/",
+				CacheDir:   tenantRoot(s.cacheDir, tenant),
+				Revalidate: s.revalidate,
+			})
+			store.now = s.now
+			s.tenants[tenant] = store
+		}
+		return store
+	}, tenant, pluginName)
+}
@@ -0,0 +1,105 @@
+package fluid_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+)
+
+var _ = Describe("Tenant namespaces", func() {
+	// =========================================================================
+	// TEST: Namespaced resolution
+	// Why: A tenant must get its own build of a plugin where it has one and
+	//      the shared build otherwise, and must never reach the builds of
+	//      another tenant, however it names itself.
+	// =========================================================================
+	var root string
+
+	put := func(rel string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(rel), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		put("hello/hello.wasm")
+		put("billing/billing.wasm")
+		put("tenants/acme/hello/hello.wasm")
+		put("tenants/acme/hello/2.0.0/hello.wasm")
+		put("tenants/globex/billing/billing.wasm")
+	})
+
+	for _, kind := range []string{"local", "fluid"} {
+		kind := kind
+		It("should resolve the tenant's builds before the shared ones in a "+kind+" store", func() {
+			var store fluid.PluginStore = fluid.NewLocalPluginStore(root)
+			if kind == "fluid" {
+				store = fluid.NewFluidPluginStore(root)
+			}
+			resolve := func(tenant, name string) string {
+				path, err := fluid.ResolveIn(store, tenant, name)
+				Expect(err).NotTo(HaveOccurred(), tenant+" "+name)
+				rel, err := filepath.Rel(root, path)
+				Expect(err).NotTo(HaveOccurred())
+				return filepath.ToSlash(rel)
+			}
+
+			Expect(resolve("acme", "hello")).To(Equal("tenants/acme/hello/hello.wasm"))
+			Expect(resolve("acme", "hello@2.0.0")).To(Equal("tenants/acme/hello/2.0.0/hello.wasm"))
+			Expect(resolve("acme", "billing")).To(Equal("billing/billing.wasm"))
+			Expect(resolve("globex", "hello")).To(Equal("hello/hello.wasm"))
+			Expect(resolve("globex", "billing")).To(Equal("tenants/globex/billing/billing.wasm"))
+			Expect(resolve("", "hello")).To(Equal("hello/hello.wasm"))
+
+			// Tenants that are no directory name have no namespace
+			Expect(resolve("../tenants/acme", "hello")).To(Equal("hello/hello.wasm"))
+			Expect(resolve("acme/hello", "hello")).To(Equal("hello/hello.wasm"))
+
+			_, err := fluid.ResolveIn(store, "acme", "missing")
+			Expect(err).To(MatchError(fluid.ErrPluginNotFound))
+		})
+	}
+
+	It("should keep tenant namespaces out of the shared plugins", func() {
+		store := fluid.NewLocalPluginStore(root)
+		plugins, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, p := range plugins {
+			names = append(names, p.Name)
+		}
+		Expect(names).To(Equal([]string{"billing", "hello"}))
+
+		_, err = store.Put(fluid.TenantsDir, strings.NewReader("wasm"))
+		Expect(err).To(MatchError(ContainSubstring("reserved")))
+	})
+
+	It("should resolve tenant builds in an object store", func() {
+		objects := newMemoryObjects()
+		objects.put("plugins/hello/hello.wasm", "shared")
+		objects.put("plugins/tenants/acme/hello/hello.wasm", "acme")
+		cacheDir := GinkgoT().TempDir()
+		store := fluid.NewObjectPluginStore(objects, fluid.ObjectStoreOptions{Prefix: "plugins/", CacheDir: cacheDir, Revalidate: -1})
+
+		path, err := store.ResolveIn("acme", "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(cacheDir, "tenants", "acme", "hello", "hello.wasm")))
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("acme"))
+
+		path, err = store.ResolveIn("globex", "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(cacheDir, "hello", "hello.wasm")))
+
+		plugins, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(1))
+	})
+})
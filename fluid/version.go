@@ -0,0 +1,199 @@
+package fluid
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LatestVersion is the version alias resolving to a plugin's highest
+// version, e.g. "hello@latest". Pre-releases ("1.3.0-rc.1") are skipped
+// unless a plugin has nothing else.
+const LatestVersion = "latest"
+
+// ParseReference splits a plugin reference of the form "name" or
+// "name@version" into its name and version. The version is empty for a
+// bare name.
+func ParseReference(ref string) (name, version string) {
+	name, version, _ = strings.Cut(ref, "@")
+	return name, version
+}
+
+// Reference joins a plugin name and version into the form Resolve
+// accepts; it is the bare name for an empty version.
+func Reference(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// Reference returns the reference Resolve accepts for this build.
+func (p PluginInfo) Reference() string {
+	return Reference(p.Name, p.Version)
+}
+
+// ValidVersion reports whether version can name a version directory:
+// it starts with a digit, optionally after a "v", and consists of
+// letters, digits, '.', '_', '+' and '-' ("1.2.0", "v2", "1.3.0-rc.1").
+// LatestVersion is an alias, not a version.
+func ValidVersion(version string) bool {
+	v := strings.TrimPrefix(version, "v")
+	if v == "" || v[0] < '0' || v[0] > '9' || len(version) > 64 {
+		return false
+	}
+	for _, c := range version {
+		if !((c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') ||
+			c == '.' || c == '_' || c == '+' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildVersion returns the version of the build at a path Resolve
+// returned: the name of its version directory, or "" for an unversioned
+// build stored directly in the plugin's directory.
+func BuildVersion(wasmPath string) string {
+	dir := filepath.Dir(wasmPath)
+	if filepath.Base(dir) == strings.TrimSuffix(filepath.Base(wasmPath), ".wasm") {
+		return ""
+	}
+	return filepath.Base(dir)
+}
+
+// CompareVersions orders versions like semantic versions: dot-separated
+// numeric parts compare numerically, a release sorts after its
+// pre-releases ("1.3.0-rc.1" < "1.3.0" < "1.10.0"), and build metadata
+// after '+' is ignored. A leading "v" is ignored too. Parts that are not
+// numbers compare as strings.
+func CompareVersions(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "+")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "+")
+	aRelease, aPre, aHasPre := strings.Cut(a, "-")
+	bRelease, bPre, bHasPre := strings.Cut(b, "-")
+
+	if c := compareParts(aRelease, bRelease); c != 0 {
+		return c
+	}
+	switch {
+	case aHasPre && !bHasPre:
+		return -1
+	case !aHasPre && bHasPre:
+		return 1
+	}
+	return compareParts(aPre, bPre)
+}
+
+// compareParts compares dot-separated version parts.
+func compareParts(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		x, xErr := strconv.ParseUint(aParts[i], 10, 64)
+		y, yErr := strconv.ParseUint(bParts[i], 10, 64)
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil: // Numeric parts sort before other parts
+			return -1
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aParts[i], bParts[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// sortVersions sorts versions from lowest to highest.
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+}
+
+// latestVersion returns the version LatestVersion refers to among
+// versions sorted lowest first, or "" if there are none.
+func latestVersion(versions []string) string {
+	for i := len(versions) - 1; i >= 0; i-- {
+		release, _, _ := strings.Cut(versions[i], "+")
+		if !strings.Contains(release, "-") {
+			return versions[i]
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	return versions[len(versions)-1]
+}
+
+// buildDir returns the directory holding a build of name under root:
+// root/<name> for an unversioned build, root/<name>/<version> otherwise.
+func buildDir(root, name, version string) string {
+	if version == "" {
+		return filepath.Join(root, name)
+	}
+	return filepath.Join(root, name, version)
+}
+
+// localVersions returns the versions of name under root that have a
+// <name>.wasm file, lowest first.
+func localVersions(root, name string) []string {
+	entries, err := os.ReadDir(filepath.Join(root, name))
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !ValidVersion(entry.Name()) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, name, entry.Name(), name+".wasm"))
+		if err == nil && info.Mode().IsRegular() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sortVersions(versions)
+	return versions
+}
+
+// localBuild returns the path of the build ref refers to under root,
+// without checking that it exists, and the build's name and version.
+//
+// A bare name refers to the unversioned build if there is one and to the
+// latest version otherwise, so plugins keep resolving as they move from
+// the flat layout to versioned directories. LatestVersion refers to the
+// latest version, or the unversioned build of a plugin without versions. ok is false for a reference that cannot name a build.
+func localBuild(root, ref string) (name, version, wasmPath string, ok bool) {
+	name, version = ParseReference(ref)
+	if !validPluginName(name) {
+		return name, version, "", false
+	}
+	switch {
+	case version == "":
+		flat := filepath.Join(root, name, name+".wasm")
+		if _, err := os.Stat(flat); err == nil {
+			return name, "", flat, true
+		}
+		fallthrough
+	case version == LatestVersion:
+		version = latestVersion(localVersions(root, name))
+		if version == "" {
+			return name, "", filepath.Join(root, name, name+".wasm"), true
+		}
+	case !ValidVersion(version):
+		return name, version, "", false
+	}
+	return name, version, filepath.Join(buildDir(root, name, version), name+".wasm"), true
+}
@@ -0,0 +1,210 @@
+package fluid_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+var _ = Describe("Versions", func() {
+	It("should parse and build references", func() {
+		name, version := fluid.ParseReference("hello@1.2.0")
+		Expect(name).To(Equal("hello"))
+		Expect(version).To(Equal("1.2.0"))
+
+		name, version = fluid.ParseReference("hello")
+		Expect(name).To(Equal("hello"))
+		Expect(version).To(BeEmpty())
+
+		Expect(fluid.Reference("hello", "1.2.0")).To(Equal("hello@1.2.0"))
+		Expect(fluid.Reference("hello", "")).To(Equal("hello"))
+	})
+
+	It("should accept only version-like directory names", func() {
+		for _, version := range []string{"1", "1.2.0", "v2", "1.3.0-rc.1", "1.0.0+build.5"} {
+			Expect(fluid.ValidVersion(version)).To(BeTrue(), version)
+		}
+		for _, version := range []string{"", "latest", "v", "..", "beta", "1/2", "1 2", strings.Repeat("1", 65)} {
+			Expect(fluid.ValidVersion(version)).To(BeFalse(), version)
+		}
+	})
+
+	// =========================================================================
+	// TEST: Version order
+	// Why: "latest" must pick the release a human would call the newest,
+	//      not the lexically greatest directory name.
+	// =========================================================================
+	It("should order versions semantically", func() {
+		versions := []string{"1.10.0", "1.2.0", "1.3.0-rc.1", "1.3.0", "v1.9", "1.3.0-beta", "2"}
+		sort.Slice(versions, func(i, j int) bool {
+			return fluid.CompareVersions(versions[i], versions[j]) < 0
+		})
+		Expect(versions).To(Equal([]string{"1.2.0", "1.3.0-beta", "1.3.0-rc.1", "1.3.0", "v1.9", "1.10.0", "2"}))
+		Expect(fluid.CompareVersions("1.0.0+a", "1.0.0+b")).To(BeZero())
+	})
+
+	It("should report the version of a resolved path", func() {
+		Expect(fluid.BuildVersion("/plugins/hello/1.2.0/hello.wasm")).To(Equal("1.2.0"))
+		Expect(fluid.BuildVersion("/plugins/hello/hello.wasm")).To(BeEmpty())
+	})
+
+	Describe("LocalPluginStore", func() {
+		var (
+			root  string
+			store *fluid.LocalPluginStore
+		)
+
+		writeBuild := func(dir, contents string) {
+			Expect(os.MkdirAll(filepath.Join(root, dir), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, dir, "hello.wasm"), []byte(contents), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			root = GinkgoT().TempDir()
+			store = fluid.NewLocalPluginStore(root)
+			writeBuild("hello/1.2.0", "v1.2.0")
+			writeBuild("hello/1.10.0", "v1.10.0")
+			writeBuild("hello/1.11.0-rc.1", "v1.11.0-rc.1")
+		})
+
+		// =====================================================================
+		// TEST: Pinned and latest versions
+		// Why: A pinned reference must keep running the same build while
+		//      new versions are published; "latest" follows the newest.
+		// =====================================================================
+		It("should resolve pinned versions and latest", func() {
+			path, err := store.Resolve("hello@1.2.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(root, "hello", "1.2.0", "hello.wasm")))
+
+			path, err = store.Resolve("hello@latest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fluid.BuildVersion(path)).To(Equal("1.10.0"))
+
+			// Pre-releases are skipped by latest but can be pinned
+			path, err = store.Resolve("hello@1.11.0-rc.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fluid.BuildVersion(path)).To(Equal("1.11.0-rc.1"))
+
+			_, err = store.Resolve("hello@1.3.0")
+			Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+			_, err = store.Resolve("hello@../..")
+			Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+		})
+
+		It("should resolve a bare name to the unversioned build, else the latest", func() {
+			path, err := store.Resolve("hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fluid.BuildVersion(path)).To(Equal("1.10.0"))
+
+			writeBuild("hello", "flat")
+			path, err = store.Resolve("hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(root, "hello", "hello.wasm")))
+		})
+
+		It("should reject a manifest declaring another version", func() {
+			path := filepath.Join(root, "hello", "1.2.0", manifest.FileName)
+			Expect(os.WriteFile(path, []byte(`{"name": "hello", "version": "1.2.1"}`), 0644)).To(Succeed())
+
+			_, err := store.Resolve("hello@1.2.0")
+			Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+		})
+
+		It("should list every build by version", func() {
+			writeBuild("hello", "flat")
+
+			plugins, err := store.List()
+			Expect(err).NotTo(HaveOccurred())
+			var refs []string
+			for _, p := range plugins {
+				refs = append(refs, p.Reference())
+			}
+			Expect(refs).To(Equal([]string{"hello", "hello@1.2.0", "hello@1.10.0", "hello@1.11.0-rc.1"}))
+		})
+
+		// =====================================================================
+		// TEST: Publishing and retiring versions
+		// Why: Versions are managed one at a time; removing one must not
+		//      touch the others.
+		// =====================================================================
+		It("should put and delete single versions", func() {
+			info, err := store.Put("hello@2.0.0", strings.NewReader("v2"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("2.0.0"))
+
+			path, err := store.Resolve("hello@latest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fluid.BuildVersion(path)).To(Equal("2.0.0"))
+
+			Expect(store.Delete("hello@2.0.0")).To(Succeed())
+			Expect(filepath.Join(root, "hello", "2.0.0")).NotTo(BeADirectory())
+			Expect(filepath.Join(root, "hello", "1.2.0", "hello.wasm")).To(BeARegularFile())
+
+			err = store.Delete("hello")
+			Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+
+			_, err = store.Put("hello@latest", strings.NewReader("x"))
+			Expect(err).To(MatchError(ContainSubstring("invalid plugin version")))
+		})
+	})
+
+	Describe("ObjectPluginStore", func() {
+		var (
+			objects  *memoryObjects
+			cacheDir string
+			store    *fluid.ObjectPluginStore
+		)
+
+		BeforeEach(func() {
+			objects = newMemoryObjects()
+			objects.put("plugins/hello/1.0.0/hello.wasm", "v1")
+			objects.put("plugins/hello/1.1.0/hello.wasm", "v1.1")
+			cacheDir = GinkgoT().TempDir()
+			store = fluid.NewObjectPluginStore(objects, fluid.ObjectStoreOptions{
+				Prefix:     "plugins/",
+				CacheDir:   cacheDir,
+				Revalidate: -1,
+			})
+		})
+
+		It("should download pinned versions and find the latest in the listing", func() {
+			path, err := store.Resolve("hello@1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(cacheDir, "hello", "1.0.0", "hello.wasm")))
+
+			path, err = store.Resolve("hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(cacheDir, "hello", "1.1.0", "hello.wasm")))
+
+			plugins, err := store.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugins).To(HaveLen(2))
+			Expect(plugins[1].Reference()).To(Equal("hello@1.1.0"))
+		})
+
+		It("should drop a deleted version without touching the others", func() {
+			_, err := store.Resolve("hello@1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = store.Resolve("hello@1.1.0")
+			Expect(err).NotTo(HaveOccurred())
+
+			objects.remove("plugins/hello/1.1.0/hello.wasm")
+			_, err = store.Resolve("hello@1.1.0")
+			Expect(errors.Is(err, fluid.ErrPluginNotFound)).To(BeTrue())
+			Expect(filepath.Join(cacheDir, "hello", "1.1.0")).NotTo(BeADirectory())
+
+			path, err := store.Resolve("hello@latest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fluid.BuildVersion(path)).To(Equal("1.0.0"))
+		})
+	})
+})
@@ -0,0 +1,226 @@
+package fluid
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// ChangeOp is what happened to a plugin build.
+type ChangeOp int
+
+// Changes a Watcher reports.
+const (
+	BuildCreated ChangeOp = iota // A new <name>.wasm appeared
+	BuildUpdated                 // The .wasm file or its manifest was replaced or modified
+	BuildRemoved                 // The .wasm file is gone
+)
+
+// String returns the operation name, e.g. "updated".
+func (op ChangeOp) String() string {
+	switch op {
+	case BuildCreated:
+		return "created"
+	case BuildUpdated:
+		return "updated"
+	case BuildRemoved:
+		return "removed"
+	default:
+		return fmt.Sprintf("op(%d)", int(op))
+	}
+}
+
+// Change is a plugin build that appeared, changed, or disappeared under a
+// watched directory.
+type Change struct {
+	Name    string
+	Version string // Empty for an unversioned build
+	Path    string // The build's .wasm file, as Resolve returns it
+	Op      ChangeOp
+}
+
+// DefaultWatchInterval is how often a Watcher rescans its directory when
+// no interval is given.
+const DefaultWatchInterval = 10 * time.Second
+
+// watchSettle is how long a Watcher waits after the last file system event
+// before rescanning, so a file copied in several writes is reported once.
+const watchSettle = 100 * time.Millisecond
+
+// Watcher reports plugin builds that are added, replaced, or deleted in a
+// directory laid out like LocalPluginStore's and FluidPluginStore's, so a
+// service can drop what it derived from the old files as soon as a new
+// build is pushed.
+//
+// On Linux, inotify events trigger a rescan within watchSettle. Every
+// watcher also rescans on a fixed interval, which is the only trigger on
+// other systems and catches what inotify cannot see: on a FUSE mount such
+// as Fluid's, files written by other nodes raise no events. Builds are
+// compared by the size and modification time of the .wasm file and its
+// manifest.
+type Watcher struct {
+	root     string
+	interval time.Duration
+
+	mu     sync.Mutex
+	builds map[string]buildStamp // By .wasm path
+}
+
+// buildStamp identifies the contents of a build without reading it.
+type buildStamp struct {
+	change        Change
+	size          int64
+	modTime       time.Time
+	configSize    int64 // Of the manifest, if any
+	configModTime time.Time
+}
+
+// NewWatcher creates a watcher of root that rescans it every interval, or
+// every DefaultWatchInterval if interval is zero. The builds present now
+// are the baseline: they are not reported as created.
+func NewWatcher(root string, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &Watcher{root: root, interval: interval}
+	w.builds = w.scan()
+	return w
+}
+
+// Scan rescans the directory and returns the changes since the previous
+// scan, in no particular order. Watch calls it; call it directly to check
+// for changes at a time of your choosing.
+func (w *Watcher) Scan() []Change {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	builds := w.scan()
+	var changes []Change
+	for path, stamp := range builds {
+		old, ok := w.builds[path]
+		switch {
+		case !ok:
+			changes = append(changes, stamp.withOp(BuildCreated))
+		case !old.same(stamp):
+			changes = append(changes, stamp.withOp(BuildUpdated))
+		}
+	}
+	for path, stamp := range w.builds {
+		if _, ok := builds[path]; !ok {
+			changes = append(changes, stamp.withOp(BuildRemoved))
+		}
+	}
+	w.builds = builds
+	return changes
+}
+
+// Watch calls fn with every change until ctx is done, then returns
+// ctx.Err(). fn is called from a single goroutine; a slow fn delays the
+// next scan.
+func (w *Watcher) Watch(ctx context.Context, fn func(Change)) error {
+	events := make(chan struct{}, 1)
+	// Without a notifier, rescanning on the interval still catches every
+	// change
+	notifier, _ := newNotifier(events)
+	if notifier != nil {
+		defer notifier.close()
+		w.watchDirs(notifier)
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	// Scan once the directories are watched, for changes made since
+	// NewWatcher that raised no events
+	settle := time.NewTimer(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-events:
+			settle.Reset(watchSettle)
+			continue
+		case <-settle.C:
+		case <-ticker.C:
+		}
+		for _, change := range w.Scan() {
+			fn(change)
+		}
+		if notifier != nil {
+			w.watchDirs(notifier)
+		}
+	}
+}
+
+// watchDirs adds the root, plugin, and version directories to notifier.
+// Adding a directory twice is harmless.
+func (w *Watcher) watchDirs(n notifier) {
+	n.add(w.root)
+	entries, _ := os.ReadDir(w.root)
+	for _, entry := range entries {
+		if !entry.IsDir() || !validPluginName(entry.Name()) {
+			continue
+		}
+		dir := filepath.Join(w.root, entry.Name())
+		n.add(dir)
+		versions, _ := os.ReadDir(dir)
+		for _, version := range versions {
+			if version.IsDir() && ValidVersion(version.Name()) {
+				n.add(filepath.Join(dir, version.Name()))
+			}
+		}
+	}
+}
+
+// scan returns the builds under root. A missing or unreadable root has
+// no builds, so removing the whole directory reports every build removed.
+func (w *Watcher) scan() map[string]buildStamp {
+	builds := make(map[string]buildStamp)
+	entries, _ := os.ReadDir(w.root)
+	for _, entry := range entries {
+		if !entry.IsDir() || !validPluginName(entry.Name()) {
+			continue
+		}
+		name := entry.Name()
+		for _, version := range append([]string{""}, localVersions(w.root, name)...) {
+			wasmPath := filepath.Join(buildDir(w.root, name, version), name+".wasm")
+			info, err := os.Stat(wasmPath)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			stamp := buildStamp{
+				change:  Change{Name: name, Version: version, Path: wasmPath},
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			}
+			if config, err := os.Stat(manifest.Path(wasmPath)); err == nil {
+				stamp.configSize, stamp.configModTime = config.Size(), config.ModTime()
+			}
+			builds[wasmPath] = stamp
+		}
+	}
+	return builds
+}
+
+// same reports whether s and other stamp the same contents.
+func (s buildStamp) same(other buildStamp) bool {
+	return s.size == other.size && s.modTime.Equal(other.modTime) &&
+		s.configSize == other.configSize && s.configModTime.Equal(other.configModTime)
+}
+
+// withOp returns the build's change with op.
+func (s buildStamp) withOp(op ChangeOp) Change {
+	change := s.change
+	change.Op = op
+	return change
+}
+
+// notifier signals file system events in the directories added to it.
+type notifier interface {
+	add(dir string)
+	close()
+}
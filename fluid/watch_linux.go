@@ -0,0 +1,55 @@
+package fluid
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the events that can change a build: files written,
+// renamed into or out of a directory, or deleted.
+const inotifyMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MODIFY | unix.IN_ATTRIB |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE | unix.IN_DELETE_SELF
+
+// inotifyNotifier signals inotify events without decoding them: the
+// Watcher rescans anyway, so which file changed does not matter.
+type inotifyNotifier struct {
+	fd   int
+	file *os.File
+}
+
+// newNotifier starts an inotify instance that signals events on events,
+// dropping signals while one is pending.
+func newNotifier(events chan<- struct{}) (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	// A non-blocking descriptor is read through the runtime poller, so
+	// closing the file unblocks the reader
+	n := &inotifyNotifier{fd: fd, file: os.NewFile(uintptr(fd), "inotify")}
+	go func() {
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			if _, err := n.file.Read(buf); err != nil {
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return n, nil
+}
+
+// add watches dir; errors, e.g. for a directory removed meanwhile or an
+// exhausted watch limit, leave it to the periodic rescan.
+func (n *inotifyNotifier) add(dir string) {
+	_, _ = unix.InotifyAddWatch(n.fd, dir, inotifyMask)
+}
+
+// close stops the reader and releases the inotify instance.
+func (n *inotifyNotifier) close() {
+	n.file.Close()
+}
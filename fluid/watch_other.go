@@ -0,0 +1,9 @@
+//go:build !linux
+
+package fluid
+
+// newNotifier returns no notifier: outside Linux, a Watcher relies on its
+// periodic rescan.
+func newNotifier(events chan<- struct{}) (notifier, error) {
+	return nil, nil
+}
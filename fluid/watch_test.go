@@ -0,0 +1,144 @@
+package fluid_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/fluid"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+var _ = Describe("Watcher", func() {
+	var root string
+
+	writeBuild := func(dir, name, contents string) string {
+		Expect(os.MkdirAll(filepath.Join(root, dir), 0755)).To(Succeed())
+		path := filepath.Join(root, dir, name+".wasm")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+	})
+
+	// =========================================================================
+	// TEST: Build changes
+	// Why: A service caching instances of a build must learn when that
+	//      build's file is replaced or deleted, but not about files that are
+	//      not plugin builds.
+	// =========================================================================
+	It("should report created, updated, and removed builds", func() {
+		hello := writeBuild("hello", "hello", "v1")
+		watcher := fluid.NewWatcher(root, time.Hour)
+		Expect(watcher.Scan()).To(BeEmpty())
+
+		versioned := writeBuild("hello/1.0.0", "hello", "v1.0.0")
+		writeBuild("hello/notes", "hello", "not a version")
+		writeBuild("other", "hello", "not named after its directory")
+		Expect(watcher.Scan()).To(ConsistOf(
+			fluid.Change{Name: "hello", Version: "1.0.0", Path: versioned, Op: fluid.BuildCreated},
+		))
+
+		Expect(os.WriteFile(hello, []byte("v2 is longer"), 0644)).To(Succeed())
+		Expect(os.Remove(versioned)).To(Succeed())
+		Expect(watcher.Scan()).To(ConsistOf(
+			fluid.Change{Name: "hello", Path: hello, Op: fluid.BuildUpdated},
+			fluid.Change{Name: "hello", Version: "1.0.0", Path: versioned, Op: fluid.BuildRemoved},
+		))
+		Expect(watcher.Scan()).To(BeEmpty())
+	})
+
+	It("should report a changed manifest as an updated build", func() {
+		hello := writeBuild("hello", "hello", "v1")
+		watcher := fluid.NewWatcher(root, time.Hour)
+
+		Expect(os.WriteFile(manifest.Path(hello), []byte(`{"name": "hello"}`), 0644)).To(Succeed())
+		Expect(watcher.Scan()).To(ConsistOf(
+			fluid.Change{Name: "hello", Path: hello, Op: fluid.BuildUpdated},
+		))
+	})
+
+	It("should report every build removed when the directory disappears", func() {
+		hello := writeBuild("hello", "hello", "v1")
+		watcher := fluid.NewWatcher(root, time.Hour)
+
+		Expect(os.RemoveAll(root)).To(Succeed())
+		Expect(watcher.Scan()).To(ConsistOf(
+			fluid.Change{Name: "hello", Path: hello, Op: fluid.BuildRemoved},
+		))
+	})
+
+	Describe("Watch", func() {
+		watch := func(interval time.Duration) func() []fluid.Change {
+			var (
+				mu      sync.Mutex
+				changes []fluid.Change
+			)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			watcher := fluid.NewWatcher(root, interval)
+			go func() {
+				done <- watcher.Watch(ctx, func(change fluid.Change) {
+					mu.Lock()
+					defer mu.Unlock()
+					changes = append(changes, change)
+				})
+			}()
+			DeferCleanup(func() {
+				cancel()
+				Eventually(done).Should(Receive(MatchError(context.Canceled)))
+			})
+			return func() []fluid.Change {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]fluid.Change(nil), changes...)
+			}
+		}
+
+		It("should report changes found by the periodic rescan", func() {
+			changes := watch(20 * time.Millisecond)
+
+			hello := writeBuild("hello", "hello", "v1")
+			Eventually(changes).Should(ConsistOf(
+				fluid.Change{Name: "hello", Path: hello, Op: fluid.BuildCreated},
+			))
+		})
+
+		// =====================================================================
+		// TEST: Immediate reload
+		// Why: A build pushed to the plugin directory should take effect
+		//      right away, not at the next rescan.
+		// =====================================================================
+		It("should report changes without waiting for the rescan on Linux", func() {
+			if goruntime.GOOS != "linux" {
+				Skip("inotify is only used on Linux")
+			}
+			writeBuild("hello", "hello", "v1")
+			changes := watch(time.Hour)
+
+			// New plugin and version directories are watched too
+			versioned := writeBuild("hello/1.0.0", "hello", "v1.0.0")
+			Eventually(changes, 2*time.Second).Should(ConsistOf(
+				fluid.Change{Name: "hello", Version: "1.0.0", Path: versioned, Op: fluid.BuildCreated},
+			))
+			Expect(os.WriteFile(versioned, []byte("v1.0.0 rebuilt"), 0644)).To(Succeed())
+			Eventually(changes, 2*time.Second).Should(ContainElement(
+				fluid.Change{Name: "hello", Version: "1.0.0", Path: versioned, Op: fluid.BuildUpdated},
+			))
+		})
+	})
+
+	It("should name operations", func() {
+		Expect(fluid.BuildCreated.String()).To(Equal("created"))
+		Expect(fluid.BuildUpdated.String()).To(Equal("updated"))
+		Expect(fluid.BuildRemoved.String()).To(Equal("removed"))
+	})
+})
@@ -0,0 +1,55 @@
+package hostext
+
+import (
+	"encoding/json"
+	"fmt"
+	"plugin"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// GoPluginSymbol is the function a Go plugin must export:
+//
+//	package main
+//
+//	func NewHostModule(config json.RawMessage) (*runtime.HostModule, error) {
+//	    db, err := openInventory(config)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return runtime.NewHostModule("inventory").
+//	        Func("stock", []runtime.ValueType{runtime.ValueI32, runtime.ValueI32},
+//	            []runtime.ValueType{runtime.ValueI64}, db.stock), nil
+//	}
+//
+// Build it with go build -buildmode=plugin, with the same Go toolchain and
+// the same version of this module as the server; the Go runtime refuses to
+// load plugins built otherwise. config is the module's "config" block.
+const GoPluginSymbol = "NewHostModule"
+
+// OpenGoPlugin loads the Go plugin at path and creates its host module.
+// Go plugins cannot be unloaded; each path is opened once per process.
+func OpenGoPlugin(path string, config json.RawMessage) (*runtime.HostModule, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Go plugin: %w", err)
+	}
+	symbol, err := p.Lookup(GoPluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("Go plugin %s: %w", path, err)
+	}
+	newModule, ok := symbol.(func(json.RawMessage) (*runtime.HostModule, error))
+	if !ok {
+		return nil, fmt.Errorf("Go plugin %s: %s has type %T, want func(json.RawMessage) (*runtime.HostModule, error)",
+			path, GoPluginSymbol, symbol)
+	}
+
+	module, err := newModule(config)
+	if err != nil {
+		return nil, fmt.Errorf("Go plugin %s: %w", path, err)
+	}
+	if module == nil {
+		return nil, fmt.Errorf("Go plugin %s: %s returned no module", path, GoPluginSymbol)
+	}
+	return module, nil
+}
@@ -0,0 +1,199 @@
+// Package hostext loads host modules declared in a configuration file, so
+// operators can extend the host functions plugins import without forking
+// the server.
+//
+// Site-specific integrations (internal databases, queues, feature flags)
+// are provided in one of two ways:
+//
+//   - A Go plugin: a .so file built with -buildmode=plugin against the same
+//     version of this module, exporting a NewHostModule function (see
+//     GoPluginSymbol). It runs in-process and has full access to HostCall.
+//   - An RPC bridge: an HTTP service implementing functions the file
+//     declares with their signatures. Each call is POSTed to the bridge as
+//     JSON (see RPCRequest), so the integration can be written in any
+//     language and restarted independently of the server.
+//
+// The file lists the modules:
+//
+//	{
+//	  "modules": [
+//	    {"name": "inventory", "plugin": "/opt/wasm-host/inventory.so",
+//	     "config": {"dsn": "postgres://inventory"}},
+//	    {"name": "queue", "rpc": {"url": "http://127.0.0.1:7400/call", "timeout_ms": 2000},
+//	     "functions": [
+//	       {"name": "publish", "params": ["bytes", "bytes"], "results": ["i32"]},
+//	       {"name": "depth", "params": ["bytes"], "results": ["i64"]}
+//	     ]}
+//	  ]
+//	}
+//
+// Like runtime host modules, every loaded module is a definition shared by
+// all plugins; the Go plugin or bridge must be safe for concurrent calls.
+package hostext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// ErrInvalidConfig is returned for a configuration file that cannot be
+// loaded as declared.
+var ErrInvalidConfig = errors.New("invalid host module configuration")
+
+// Config is the contents of a host module configuration file.
+type Config struct {
+	Modules []ModuleConfig `json:"modules"`
+
+	// Credentials is set on the bridge of every RPC module by Load
+	Credentials Credentials `json:"-"`
+}
+
+// ModuleConfig declares one host module. Exactly one of Plugin and RPC is
+// set.
+type ModuleConfig struct {
+	// Name is the import module name plugins use
+	Name string `json:"name"`
+
+	// Plugin is the path of a Go plugin providing the module
+	Plugin string `json:"plugin,omitempty"`
+
+	// Config is passed verbatim to the Go plugin's NewHostModule
+	Config json.RawMessage `json:"config,omitempty"`
+
+	// RPC is the bridge implementing Functions
+	RPC *RPCConfig `json:"rpc,omitempty"`
+
+	// Functions declares the functions of an RPC module
+	Functions []FunctionConfig `json:"functions,omitempty"`
+}
+
+// FunctionConfig declares an RPC function's signature. Params and results
+// are runtime value types ("i32", "i64", "f32", "f64") or "bytes":
+//
+//   - a "bytes" parameter is an (i32 ptr, i32 len) pair whose contents are
+//     sent to the bridge
+//   - a "bytes" result, which must be the only one, makes the function take
+//     a trailing (i32 ptr, i32 cap) pair and return an i32: the bridge's
+//     output is written to ptr if it fits in cap bytes, and its length is
+//     returned either way, so the plugin can retry with a larger buffer
+type FunctionConfig struct {
+	Name    string   `json:"name"`
+	Params  []string `json:"params,omitempty"`
+	Results []string `json:"results,omitempty"`
+}
+
+// TypeBytes is the FunctionConfig type of a buffer in linear memory.
+const TypeBytes = "bytes"
+
+// ReadConfig reads and validates a configuration file.
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks that every module is declared completely and once.
+// Loading may still fail, e.g. for a .so file that does not exist.
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for i, m := range c.Modules {
+		if m.Name == "" {
+			return fmt.Errorf("%w: module %d has no name", ErrInvalidConfig, i)
+		}
+		if names[m.Name] {
+			return fmt.Errorf("%w: module %s is declared twice", ErrInvalidConfig, m.Name)
+		}
+		names[m.Name] = true
+
+		switch {
+		case (m.Plugin == "") == (m.RPC == nil):
+			return fmt.Errorf("%w: module %s must set exactly one of plugin and rpc", ErrInvalidConfig, m.Name)
+		case m.Plugin != "" && len(m.Functions) > 0:
+			return fmt.Errorf("%w: module %s: functions are declared by the Go plugin itself", ErrInvalidConfig, m.Name)
+		case m.RPC != nil:
+			if err := m.RPC.validate(); err != nil {
+				return fmt.Errorf("%w: module %s: %v", ErrInvalidConfig, m.Name, err)
+			}
+			if len(m.Functions) == 0 {
+				return fmt.Errorf("%w: module %s declares no functions", ErrInvalidConfig, m.Name)
+			}
+			for _, f := range m.Functions {
+				if err := f.validate(); err != nil {
+					return fmt.Errorf("%w: module %s: %v", ErrInvalidConfig, m.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks a function's name and types.
+func (f FunctionConfig) validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("function name must not be empty")
+	}
+	for _, t := range f.Params {
+		if t != TypeBytes && !validValueType(t) {
+			return fmt.Errorf("function %s: unsupported parameter type %q", f.Name, t)
+		}
+	}
+	for _, t := range f.Results {
+		if t == TypeBytes && len(f.Results) > 1 {
+			return fmt.Errorf("function %s: a bytes result must be the only one", f.Name)
+		}
+		if t != TypeBytes && !validValueType(t) {
+			return fmt.Errorf("function %s: unsupported result type %q", f.Name, t)
+		}
+	}
+	return nil
+}
+
+// validValueType reports whether t is a runtime.ValueType.
+func validValueType(t string) bool {
+	switch runtime.ValueType(t) {
+	case runtime.ValueI32, runtime.ValueI64, runtime.ValueF32, runtime.ValueF64:
+		return true
+	}
+	return false
+}
+
+// Load creates the host modules c declares, opening Go plugins and setting
+// up RPC bridges. It fails on the first module that cannot be loaded.
+func (c *Config) Load() ([]*runtime.HostModule, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	modules := make([]*runtime.HostModule, 0, len(c.Modules))
+	for _, m := range c.Modules {
+		var module *runtime.HostModule
+		var err error
+		if m.Plugin != "" {
+			module, err = OpenGoPlugin(m.Plugin, m.Config)
+			if err == nil && module.Name() != m.Name {
+				err = fmt.Errorf("Go plugin %s provides module %s, not %s", m.Plugin, module.Name(), m.Name)
+			}
+		} else {
+			rpc := *m.RPC
+			rpc.Credentials = c.Credentials
+			module, err = NewRPCModule(m.Name, rpc, m.Functions)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("host module %s: %w", m.Name, err)
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
@@ -0,0 +1,15 @@
+package hostext_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestHostext bootstraps the Ginkgo test suite for the hostext package.
+// Run with: go test -v ./hostext/...
+func TestHostext(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hostext Suite")
+}
@@ -0,0 +1,90 @@
+package hostext_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/hostext"
+)
+
+var _ = Describe("Config", func() {
+	writeConfig := func(contents string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "host-modules.json")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	It("should read modules of both kinds", func() {
+		cfg, err := hostext.ReadConfig(writeConfig(`{"modules": [
+			{"name": "inventory", "plugin": "/opt/inventory.so", "config": {"dsn": "postgres://inventory"}},
+			{"name": "queue", "rpc": {"url": "http://127.0.0.1:7400/call", "timeout_ms": 2000},
+			 "functions": [{"name": "publish", "params": ["bytes", "bytes"], "results": ["i32"]}]}
+		]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Modules).To(HaveLen(2))
+		Expect(string(cfg.Modules[0].Config)).To(MatchJSON(`{"dsn": "postgres://inventory"}`))
+		Expect(cfg.Modules[1].RPC.TimeoutMs).To(Equal(2000))
+		Expect(cfg.Modules[1].Functions[0].Params).To(Equal([]string{"bytes", "bytes"}))
+	})
+
+	// =========================================================================
+	// TEST: Configuration validation
+	// Why: A mistyped declaration must stop the server at startup rather
+	//      than surface as a load failure of every plugin importing it.
+	// =========================================================================
+	It("should reject invalid declarations", func() {
+		rpc := `"rpc": {"url": "http://127.0.0.1:7400/call"}`
+		for _, modules := range []string{
+			`[{"plugin": "/opt/x.so"}]`,
+			`[{"name": "x"}]`,
+			`[{"name": "x", "plugin": "/opt/x.so", ` + rpc + `}]`,
+			`[{"name": "x", "plugin": "/opt/x.so"}, {"name": "x", "plugin": "/opt/y.so"}]`,
+			`[{"name": "x", "plugin": "/opt/x.so", "functions": [{"name": "f"}]}]`,
+			`[{"name": "x", ` + rpc + `}]`,
+			`[{"name": "x", "rpc": {"url": "unix:///tmp/bridge"}, "functions": [{"name": "f"}]}]`,
+			`[{"name": "x", "rpc": {"url": "http://bridge", "timeout_ms": -1}, "functions": [{"name": "f"}]}]`,
+			`[{"name": "x", ` + rpc + `, "functions": [{"name": ""}]}]`,
+			`[{"name": "x", ` + rpc + `, "functions": [{"name": "f", "params": ["string"]}]}]`,
+			`[{"name": "x", ` + rpc + `, "functions": [{"name": "f", "results": ["bytes", "i32"]}]}]`,
+		} {
+			_, err := hostext.ReadConfig(writeConfig(`{"modules": ` + modules + `}`))
+			Expect(errors.Is(err, hostext.ErrInvalidConfig)).To(BeTrue(), modules)
+		}
+
+		_, err := hostext.ReadConfig(writeConfig(`{"modules": {}}`))
+		Expect(errors.Is(err, hostext.ErrInvalidConfig)).To(BeTrue())
+	})
+
+	Describe("Load", func() {
+		It("should create RPC modules with their functions", func() {
+			cfg := hostext.Config{Modules: []hostext.ModuleConfig{{
+				Name: "queue",
+				RPC:  &hostext.RPCConfig{URL: "http://127.0.0.1:7400/call"},
+				Functions: []hostext.FunctionConfig{
+					{Name: "publish", Params: []string{"bytes", "bytes"}, Results: []string{"i32"}},
+					{Name: "peek", Params: []string{"bytes"}, Results: []string{"bytes"}},
+				},
+			}}}
+
+			modules, err := cfg.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(modules).To(HaveLen(1))
+			Expect(modules[0].Name()).To(Equal("queue"))
+			Expect(modules[0].Functions()).To(Equal([]string{"publish", "peek"}))
+		})
+
+		It("should fail for a Go plugin that cannot be opened", func() {
+			cfg := hostext.Config{Modules: []hostext.ModuleConfig{{
+				Name:   "inventory",
+				Plugin: filepath.Join(GinkgoT().TempDir(), "missing.so"),
+			}}}
+
+			_, err := cfg.Load()
+			Expect(err).To(MatchError(ContainSubstring("host module inventory: failed to open Go plugin")))
+		})
+	})
+})
@@ -0,0 +1,286 @@
+package hostext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// DefaultRPCTimeout bounds a bridge call when RPCConfig.TimeoutMs is unset.
+// The plugin call's own deadline applies as well.
+const DefaultRPCTimeout = 5 * time.Second
+
+// maxRPCResponse bounds the response body read from a bridge.
+const maxRPCResponse = 4 << 20
+
+// RPCConfig configures the bridge of an RPC module.
+type RPCConfig struct {
+	// URL receives every call as a POST
+	URL string `json:"url"`
+
+	// TimeoutMs bounds each call; zero uses DefaultRPCTimeout
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// Headers are added to every call, e.g. for authentication
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Credentials, if set, mints the bearer token of each call in place
+	// of an Authorization header from Headers
+	Credentials Credentials `json:"-"`
+}
+
+// Credentials returns the bearer token of a call to the bridge at
+// audience, e.g. one scoped to the function called and minted for the
+// client the plugin call runs for. ctx is the plugin call's context. An
+// empty token leaves the call with the headers the module configures.
+type Credentials func(ctx context.Context, audience string, req RPCRequest) (string, error)
+
+// validate checks the bridge URL and timeout.
+func (c RPCConfig) validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("rpc url %q must be an http or https URL", c.URL)
+	}
+	if c.TimeoutMs < 0 {
+		return fmt.Errorf("rpc timeout_ms must not be negative")
+	}
+	return nil
+}
+
+// RPCRequest is the JSON body POSTed to a bridge for each call:
+//
+//	{"module": "queue", "function": "publish", "plugin": "orders",
+//	 "request_id": "9c1d2e3f4a5b6c7d", "tenant": "payments",
+//	 "args": ["b3JkZXJz", "eyJpZCI6NDJ9"]}
+//
+// Args holds one value per declared parameter: numbers for value types,
+// base64 strings for "bytes".
+type RPCRequest struct {
+	Module    string        `json:"module"`
+	Function  string        `json:"function"`
+	Plugin    string        `json:"plugin"` // Plugin name, from its file name
+	RequestID string        `json:"request_id,omitempty"`
+	Tenant    string        `json:"tenant,omitempty"`
+	Args      []interface{} `json:"args"`
+}
+
+// RPCResponse is the JSON body a bridge answers with, with status 200:
+//
+//	{"results": [0]}
+//	{"output": "eyJzdG9jayI6IDd9"}
+//
+// Results holds one number per declared value result; Output is the
+// base64 data of a "bytes" result. A non-empty Error, or any other status,
+// traps the plugin.
+type RPCResponse struct {
+	Results []json.Number `json:"results,omitempty"`
+	Output  []byte        `json:"output,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// RPCBridge sends calls to a bridge service.
+type RPCBridge struct {
+	url         string
+	header      http.Header
+	credentials Credentials
+	timeout     time.Duration
+	http        *http.Client
+}
+
+// NewRPCBridge creates a bridge client for cfg.
+func NewRPCBridge(cfg RPCConfig) (*RPCBridge, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	timeout := DefaultRPCTimeout
+	if cfg.TimeoutMs > 0 {
+		timeout = time.Duration(cfg.TimeoutMs) * time.Millisecond
+	}
+	header := make(http.Header)
+	for name, value := range cfg.Headers {
+		header.Set(name, value)
+	}
+	return &RPCBridge{url: cfg.URL, header: header, credentials: cfg.Credentials, timeout: timeout, http: &http.Client{}}, nil
+}
+
+// Call sends req to the bridge and returns its response. Transport
+// failures, statuses other than 200, and responses carrying an error are
+// returned as errors.
+func (b *RPCBridge) Call(ctx context.Context, req RPCRequest) (*RPCResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range b.header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.credentials != nil {
+		token, err := b.credentials(ctx, b.url, req)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: failed to get credentials: %w", req.Module, req.Function, err)
+		}
+		if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := b.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s: bridge call failed: %w", req.Module, req.Function, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRPCResponse))
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s: failed to read bridge response: %w", req.Module, req.Function, err)
+	}
+
+	var out RPCResponse
+	decodeErr := json.Unmarshal(data, &out)
+	switch {
+	case decodeErr == nil && out.Error != "":
+		return nil, fmt.Errorf("%s.%s: %s", req.Module, req.Function, out.Error)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s.%s: bridge returned %s", req.Module, req.Function, resp.Status)
+	case decodeErr != nil:
+		return nil, fmt.Errorf("%s.%s: invalid bridge response: %w", req.Module, req.Function, decodeErr)
+	}
+	return &out, nil
+}
+
+// NewRPCModule creates a host module named name whose functions are
+// implemented by the bridge cfg describes.
+func NewRPCModule(name string, cfg RPCConfig, functions []FunctionConfig) (*runtime.HostModule, error) {
+	bridge, err := NewRPCBridge(cfg)
+	if err != nil {
+		return nil, err
+	}
+	module := runtime.NewHostModule(name)
+	for _, f := range functions {
+		if err := f.validate(); err != nil {
+			return nil, err
+		}
+		params, results := f.signature()
+		module.Func(f.Name, params, results, bridge.hostFunc(name, f))
+	}
+	return module, nil
+}
+
+// signature returns the WebAssembly signature of f.
+func (f FunctionConfig) signature() (params, results []runtime.ValueType) {
+	for _, t := range f.Params {
+		if t == TypeBytes {
+			params = append(params, runtime.ValueI32, runtime.ValueI32)
+		} else {
+			params = append(params, runtime.ValueType(t))
+		}
+	}
+	if f.bytesResult() {
+		return append(params, runtime.ValueI32, runtime.ValueI32), []runtime.ValueType{runtime.ValueI32}
+	}
+	for _, t := range f.Results {
+		results = append(results, runtime.ValueType(t))
+	}
+	return params, results
+}
+
+// bytesResult reports whether f returns its output through a buffer.
+func (f FunctionConfig) bytesResult() bool {
+	return len(f.Results) == 1 && f.Results[0] == TypeBytes
+}
+
+// hostFunc implements f by forwarding each call to the bridge.
+func (b *RPCBridge) hostFunc(module string, f FunctionConfig) runtime.HostFunc {
+	return func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+		info := runtime.CallInfoFrom(call.Context())
+		req := RPCRequest{
+			Module:    module,
+			Function:  f.Name,
+			Plugin:    strings.TrimSuffix(filepath.Base(call.Path()), ".wasm"),
+			RequestID: info.RequestID,
+			Tenant:    info.Tenant,
+			Args:      make([]interface{}, 0, len(f.Params)),
+		}
+		i := 0
+		for _, t := range f.Params {
+			if t == TypeBytes {
+				data, err := call.Read(args[i].(int32), args[i+1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				req.Args = append(req.Args, data)
+				i += 2
+				continue
+			}
+			req.Args = append(req.Args, args[i])
+			i++
+		}
+
+		resp, err := b.Call(call.Context(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.bytesResult() {
+			ptr, capacity := args[i].(int32), args[i+1].(int32)
+			if int64(len(resp.Output)) > math.MaxInt32 {
+				return nil, fmt.Errorf("%s.%s: bridge output too large", module, f.Name)
+			}
+			if int32(len(resp.Output)) <= capacity {
+				if err := call.Write(ptr, resp.Output); err != nil {
+					return nil, err
+				}
+			}
+			return []interface{}{int32(len(resp.Output))}, nil
+		}
+		return convertResults(module, f, resp.Results)
+	}
+}
+
+// convertResults converts a bridge's results to f's declared types.
+func convertResults(module string, f FunctionConfig, values []json.Number) ([]interface{}, error) {
+	if len(values) != len(f.Results) {
+		return nil, fmt.Errorf("%s.%s: bridge returned %d results, want %d", module, f.Name, len(values), len(f.Results))
+	}
+	results := make([]interface{}, len(values))
+	for i, value := range values {
+		var err error
+		switch runtime.ValueType(f.Results[i]) {
+		case runtime.ValueI32:
+			var n int64
+			n, err = value.Int64()
+			if err == nil && (n < math.MinInt32 || n > math.MaxInt32) {
+				err = fmt.Errorf("%s overflows i32", value)
+			}
+			results[i] = int32(n)
+		case runtime.ValueI64:
+			results[i], err = value.Int64()
+		case runtime.ValueF32:
+			var x float64
+			x, err = value.Float64()
+			results[i] = float32(x)
+		case runtime.ValueF64:
+			results[i], err = value.Float64()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: result %d: %w", module, f.Name, i, err)
+		}
+	}
+	return results, nil
+}
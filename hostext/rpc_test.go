@@ -0,0 +1,192 @@
+package hostext_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/hostext"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// fakeBridge answers bridge calls with respond and records the requests.
+type fakeBridge struct {
+	mu       sync.Mutex
+	requests []hostext.RPCRequest
+	headers  []http.Header
+	respond  func(req hostext.RPCRequest) (int, string)
+}
+
+func (b *fakeBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req hostext.RPCRequest
+	Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+	b.mu.Lock()
+	b.requests = append(b.requests, req)
+	b.headers = append(b.headers, r.Header)
+	b.mu.Unlock()
+
+	status, body := b.respond(req)
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+var _ = Describe("RPCBridge", func() {
+	var (
+		bridge *fakeBridge
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		bridge = &fakeBridge{respond: func(hostext.RPCRequest) (int, string) {
+			return http.StatusOK, `{"results": [7]}`
+		}}
+		server = httptest.NewServer(bridge)
+		DeferCleanup(server.Close)
+	})
+
+	call := func(cfg hostext.RPCConfig) (*hostext.RPCResponse, error) {
+		client, err := hostext.NewRPCBridge(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		return client.Call(context.Background(), hostext.RPCRequest{
+			Module:   "inventory",
+			Function: "stock",
+			Plugin:   "orders",
+			Args:     []interface{}{[]byte("sku-1"), int32(2)},
+		})
+	}
+
+	It("should post the call and return the results", func() {
+		resp, err := call(hostext.RPCConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Results).To(Equal([]json.Number{"7"}))
+
+		Expect(bridge.requests).To(HaveLen(1))
+		Expect(bridge.requests[0].Module).To(Equal("inventory"))
+		Expect(bridge.requests[0].Plugin).To(Equal("orders"))
+		Expect(bridge.requests[0].Args).To(Equal([]interface{}{"c2t1LTE=", float64(2)}))
+		Expect(bridge.headers[0].Get("Authorization")).To(Equal("Bearer secret"))
+	})
+
+	// =========================================================================
+	// TEST: Per-call credentials
+	// Why: A bridge should see the client a plugin call runs for, with a
+	//      token for that call only, rather than one credential shared by
+	//      every plugin and client.
+	// =========================================================================
+	It("should send minted credentials in place of the configured ones", func() {
+		var audiences []string
+		token := "minted"
+		cfg := hostext.RPCConfig{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+			Credentials: func(_ context.Context, audience string, req hostext.RPCRequest) (string, error) {
+				audiences = append(audiences, audience+" "+req.Module+"."+req.Function)
+				return token, nil
+			},
+		}
+		_, err := call(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bridge.headers[0].Get("Authorization")).To(Equal("Bearer minted"))
+		Expect(audiences).To(Equal([]string{server.URL + " inventory.stock"}))
+
+		// Calls no token is minted for keep the configured credentials
+		token = ""
+		_, err = call(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bridge.headers[1].Get("Authorization")).To(Equal("Bearer secret"))
+
+		cfg.Credentials = func(context.Context, string, hostext.RPCRequest) (string, error) {
+			return "", errors.New("no signing key")
+		}
+		_, err = call(cfg)
+		Expect(err).To(MatchError("inventory.stock: failed to get credentials: no signing key"))
+		Expect(bridge.requests).To(HaveLen(2))
+	})
+
+	// =========================================================================
+	// TEST: Bridge failures
+	// Why: A failed integration must fail the plugin call rather than hand
+	//      it a made-up result.
+	// =========================================================================
+	It("should return bridge failures as errors", func() {
+		bridge.respond = func(hostext.RPCRequest) (int, string) {
+			return http.StatusOK, `{"error": "inventory database unavailable"}`
+		}
+		_, err := call(hostext.RPCConfig{URL: server.URL})
+		Expect(err).To(MatchError("inventory.stock: inventory database unavailable"))
+
+		bridge.respond = func(hostext.RPCRequest) (int, string) {
+			return http.StatusBadGateway, "upstream down"
+		}
+		_, err = call(hostext.RPCConfig{URL: server.URL})
+		Expect(err).To(MatchError(ContainSubstring("bridge returned 502 Bad Gateway")))
+
+		bridge.respond = func(hostext.RPCRequest) (int, string) {
+			time.Sleep(200 * time.Millisecond)
+			return http.StatusOK, `{}`
+		}
+		_, err = call(hostext.RPCConfig{URL: server.URL, TimeoutMs: 20})
+		Expect(err).To(MatchError(ContainSubstring("bridge call failed")))
+	})
+
+	Describe("with the hostcall plugin", func() {
+		var plugin *runtime.Plugin
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "hostcall", "hostcall.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			bridge.respond = func(req hostext.RPCRequest) (int, string) {
+				if req.Function == "kv_get" {
+					return http.StatusOK, `{"results": [3]}`
+				}
+				return http.StatusOK, `{}`
+			}
+			module, err := hostext.NewRPCModule("host", hostext.RPCConfig{URL: server.URL}, []hostext.FunctionConfig{
+				{Name: "log", Params: []string{"bytes"}},
+				{Name: "kv_get", Params: []string{"bytes"}, Results: []string{"i32"}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		It("should implement the plugin's imports through the bridge", func() {
+			ctx := runtime.WithCallInfo(context.Background(), runtime.CallInfo{RequestID: "req-1", Tenant: "payments"})
+			result, err := plugin.ExecuteContext(ctx, 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(15))
+
+			var kvGet hostext.RPCRequest
+			for _, req := range bridge.requests {
+				if req.Function == "kv_get" {
+					kvGet = req
+				}
+			}
+			Expect(kvGet.Plugin).To(Equal("hostcall"))
+			Expect(kvGet.RequestID).To(Equal("req-1"))
+			Expect(kvGet.Tenant).To(Equal("payments"))
+			Expect(kvGet.Args).To(Equal([]interface{}{"ZmFjdG9y"})) // "factor"
+		})
+	})
+})
@@ -0,0 +1,402 @@
+// Package jobs runs work asynchronously for callers that cannot wait for
+// it. Submit queues a job and returns its ID at once; a fixed number of
+// workers run queued jobs in submission order; Get reports a job's state
+// and, once it has finished, its result or error; Cancel stops a job
+// whether it is still queued or already running.
+//
+// Jobs are kept in memory, and optionally as one JSON file per job in a
+// directory so that they survive restarts. After a restart, queued jobs
+// are run, while jobs that were running fail: their work may have been
+// partly done, and only the submitter can tell whether repeating it is
+// safe. Finished jobs are forgotten after a retention period.
+//
+// Like cron, the package knows nothing of plugins; the Func given to New
+// does the work.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+)
+
+// State is the state of a job.
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+	Cancelled State = "cancelled"
+)
+
+// Finished reports whether a job in state s will not run (again).
+func (s State) Finished() bool {
+	return s == Succeeded || s == Failed || s == Cancelled
+}
+
+// Job is a unit of asynchronous work and its outcome.
+type Job struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner,omitempty"` // Who submitted it, as the caller names them
+	State State  `json:"state"`
+
+	// Input is what the job runs on, passed to the Func unchanged
+	Input json.RawMessage `json:"input"`
+
+	// Result is the Func's result if the job succeeded, and Error why it
+	// failed
+	Result json.RawMessage   `json:"result,omitempty"`
+	Error  *apierror.Problem `json:"error,omitempty"`
+
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitzero"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// Func runs a job and returns its result. ctx is done when the job is
+// cancelled.
+type Func func(ctx context.Context, job Job) (json.RawMessage, error)
+
+// Errors returned by Submit.
+var (
+	ErrQueueFull = errors.New("job queue is full")
+	ErrStopped   = errors.New("job queue is stopped")
+)
+
+// DefaultRetention is how long finished jobs are kept when
+// Options.Retention is zero.
+const DefaultRetention = time.Hour
+
+// pruneInterval bounds how often finished jobs past their retention are
+// removed.
+const pruneInterval = time.Minute
+
+// Options configures a Queue.
+type Options struct {
+	// Workers is how many jobs run at once; 1 if zero.
+	Workers int
+
+	// MaxQueued is how many jobs may wait to run; Submit returns
+	// ErrQueueFull beyond it. Zero means no limit.
+	MaxQueued int
+
+	// Retention is how long finished jobs are kept; DefaultRetention if
+	// zero.
+	Retention time.Duration
+
+	// Dir, if set, keeps every job as <id>.json there across restarts.
+	// It is created if needed.
+	Dir string
+
+	// Problem describes the error of a failed job; by default its
+	// apierror code and message.
+	Problem func(error) *apierror.Problem
+}
+
+// entry is a job and the means to stop it.
+type entry struct {
+	job       Job
+	cancel    context.CancelFunc // Set while running
+	cancelled bool               // Cancel was called while running
+	done      chan struct{}      // Closed when the job has finished
+}
+
+// Queue runs jobs on a pool of workers. It is safe for concurrent use.
+type Queue struct {
+	run  Func
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	ready     *sync.Cond // Signalled when a job is queued or the queue stops
+	jobs      map[string]*entry
+	queue     []*entry // Queued jobs, oldest first
+	running   int
+	stopped   bool
+	lastPrune time.Time
+}
+
+// New creates a queue running jobs with run. With opts.Dir, the jobs kept
+// there are loaded; Run must be called to start running them.
+func New(run Func, opts Options) (*Queue, error) {
+	if opts.Workers < 0 || opts.MaxQueued < 0 || opts.Retention < 0 {
+		return nil, errors.New("job queue options must not be negative")
+	}
+	if opts.Workers == 0 {
+		opts.Workers = 1
+	}
+	if opts.Retention == 0 {
+		opts.Retention = DefaultRetention
+	}
+	if opts.Problem == nil {
+		opts.Problem = func(err error) *apierror.Problem {
+			return apierror.New(apierror.CodeOf(err), err.Error())
+		}
+	}
+	q := &Queue{run: run, opts: opts, now: time.Now, jobs: make(map[string]*entry)}
+	q.ready = sync.NewCond(&q.mu)
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create job directory: %w", err)
+		}
+		if err := q.load(); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// load restores the jobs kept in the directory. Jobs that were running
+// when the previous process stopped fail.
+func (q *Queue) load() error {
+	files, err := filepath.Glob(filepath.Join(q.opts.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil || job.ID == "" {
+			return fmt.Errorf("invalid job %s", file)
+		}
+		e := &entry{job: job, done: make(chan struct{})}
+		switch job.State {
+		case Queued:
+			q.queue = append(q.queue, e)
+		case Running:
+			e.job.State = Failed
+			e.job.Finished = q.now().UTC()
+			e.job.Error = apierror.New(apierror.CodeInternal, "the server restarted while the job was running")
+			q.persist(e.job)
+			close(e.done)
+		default:
+			close(e.done)
+		}
+		q.jobs[job.ID] = e
+	}
+	sort.SliceStable(q.queue, func(i, j int) bool { return q.queue[i].job.Created.Before(q.queue[j].job.Created) })
+	return nil
+}
+
+// Run runs queued jobs on the workers until ctx is done, then cancels the
+// jobs running and waits for them. Jobs still queued stay queued.
+func (q *Queue) Run(ctx context.Context) {
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		q.stopped = true
+		q.mu.Unlock()
+		q.ready.Broadcast()
+	})
+	defer stop()
+
+	var workers sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			q.work(ctx)
+		}()
+	}
+	workers.Wait()
+}
+
+// work runs queued jobs until the queue stops.
+func (q *Queue) work(ctx context.Context) {
+	for {
+		q.mu.Lock()
+		for len(q.queue) == 0 && !q.stopped {
+			q.ready.Wait()
+		}
+		if q.stopped {
+			q.mu.Unlock()
+			return
+		}
+		e := q.queue[0]
+		q.queue = q.queue[1:]
+		jobCtx, cancel := context.WithCancel(ctx)
+		e.cancel = cancel
+		e.job.State = Running
+		e.job.Started = q.now().UTC()
+		q.running++
+		job := e.job
+		q.mu.Unlock()
+		q.persist(job)
+
+		result, err := q.run(jobCtx, job)
+		cancel()
+
+		q.mu.Lock()
+		q.running--
+		e.cancel = nil
+		e.job.Finished = q.now().UTC()
+		switch {
+		case err == nil:
+			e.job.State = Succeeded
+			e.job.Result = result
+		case e.cancelled:
+			e.job.State = Cancelled
+		default:
+			e.job.State = Failed
+			e.job.Error = q.opts.Problem(err)
+		}
+		job = e.job
+		q.mu.Unlock()
+		q.persist(job)
+		close(e.done)
+	}
+}
+
+// Submit queues a job running on input on behalf of owner and returns it.
+func (q *Queue) Submit(input json.RawMessage, owner string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+	if q.stopped {
+		return Job{}, ErrStopped
+	}
+	if q.opts.MaxQueued > 0 && len(q.queue) >= q.opts.MaxQueued {
+		return Job{}, ErrQueueFull
+	}
+
+	e := &entry{
+		job: Job{
+			ID:      newID(),
+			Owner:   owner,
+			State:   Queued,
+			Input:   input,
+			Created: q.now().UTC(),
+		},
+		done: make(chan struct{}),
+	}
+	if err := q.persist(e.job); err != nil {
+		return Job{}, err
+	}
+	q.jobs[e.job.ID] = e
+	q.queue = append(q.queue, e)
+	q.ready.Signal()
+	return e.job, nil
+}
+
+// Get returns a job.
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+	e, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// Cancel stops a job and returns its final state. A queued job is
+// cancelled at once; a running one is interrupted through its context,
+// and Cancel waits for it to return. Finished jobs are left as they are.
+func (q *Queue) Cancel(id string) (Job, bool) {
+	q.mu.Lock()
+	e, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return Job{}, false
+	}
+	switch e.job.State {
+	case Queued:
+		for i, queued := range q.queue {
+			if queued == e {
+				q.queue = append(q.queue[:i:i], q.queue[i+1:]...)
+				break
+			}
+		}
+		e.job.State = Cancelled
+		e.job.Finished = q.now().UTC()
+		job := e.job
+		q.mu.Unlock()
+		q.persist(job)
+		close(e.done)
+		return job, true
+	case Running:
+		e.cancelled = true
+		e.cancel()
+	}
+	q.mu.Unlock()
+
+	<-e.done
+	return q.Get(id)
+}
+
+// Counts returns the number of jobs queued and running.
+func (q *Queue) Counts() (queued, running int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue), q.running
+}
+
+// prune forgets finished jobs past their retention, at most once per
+// pruneInterval. Called with mu held.
+func (q *Queue) prune() {
+	now := q.now()
+	if now.Sub(q.lastPrune) < pruneInterval {
+		return
+	}
+	q.lastPrune = now
+	for id, e := range q.jobs {
+		if e.job.State.Finished() && now.Sub(e.job.Finished) >= q.opts.Retention {
+			delete(q.jobs, id)
+			if q.opts.Dir != "" {
+				os.Remove(filepath.Join(q.opts.Dir, id+".json"))
+			}
+		}
+	}
+}
+
+// persist writes a job to the directory, if there is one. It writes a
+// temporary file and renames it into place, so a crash never leaves a
+// truncated job. Only a failure to record a new job is reported; later
+// states are best effort.
+func (q *Queue) persist(job Job) error {
+	if q.opts.Dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(q.opts.Dir, job.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(q.opts.Dir, job.ID+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
+
+// newID returns a random job ID.
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,15 @@
+package jobs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestJobs bootstraps the Ginkgo test suite for the jobs package.
+// Run with: go test -v ./jobs/...
+func TestJobs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Jobs Suite")
+}
@@ -0,0 +1,171 @@
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/apierror"
+	"github.com/mrhapile/wasm-plugin-system/jobs"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		release chan struct{} // Closed to let blocking jobs finish
+		started chan string   // Receives the input of each job started
+	)
+
+	// run echoes its input, fails on "fail", and blocks on "block" until
+	// released or cancelled.
+	run := func(ctx context.Context, job jobs.Job) (json.RawMessage, error) {
+		var input string
+		Expect(json.Unmarshal(job.Input, &input)).To(Succeed())
+		started <- input
+		switch input {
+		case "fail":
+			return nil, apierror.Wrap(apierror.CodePluginExecutionFailed, errors.New("plugin trapped"))
+		case "block":
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return json.RawMessage(`{"echo":` + string(job.Input) + `}`), nil
+	}
+
+	start := func(opts jobs.Options) *jobs.Queue {
+		q, err := jobs.New(run, opts)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			q.Run(ctx)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			<-done
+		})
+		return q
+	}
+
+	submit := func(q *jobs.Queue, input string) jobs.Job {
+		data, _ := json.Marshal(input)
+		job, err := q.Submit(data, "payments")
+		Expect(err).NotTo(HaveOccurred())
+		return job
+	}
+
+	stateOf := func(q *jobs.Queue, id string) func() jobs.State {
+		return func() jobs.State {
+			job, _ := q.Get(id)
+			return job.State
+		}
+	}
+
+	BeforeEach(func() {
+		release = make(chan struct{})
+		started = make(chan string, 16)
+	})
+
+	// =========================================================================
+	// TEST: Asynchronous results
+	// Why: Submit must return before the work runs, and the outcome must
+	//      be retrievable afterwards, whether it succeeded or failed.
+	// =========================================================================
+	It("should run submitted jobs and keep their outcome", func() {
+		q := start(jobs.Options{Workers: 2})
+
+		ok := submit(q, "hello")
+		Expect(ok.ID).To(HaveLen(16))
+		Expect(ok.State).To(Equal(jobs.Queued))
+		Expect(ok.Owner).To(Equal("payments"))
+		failed := submit(q, "fail")
+
+		Eventually(stateOf(q, ok.ID)).Should(Equal(jobs.Succeeded))
+		job, _ := q.Get(ok.ID)
+		Expect(job.Result).To(MatchJSON(`{"echo": "hello"}`))
+		Expect(job.Started).NotTo(BeZero())
+		Expect(job.Finished).NotTo(BeZero())
+
+		Eventually(stateOf(q, failed.ID)).Should(Equal(jobs.Failed))
+		job, _ = q.Get(failed.ID)
+		Expect(job.Result).To(BeNil())
+		Expect(job.Error.Code).To(Equal(apierror.CodePluginExecutionFailed))
+		Expect(job.Error.Detail).To(ContainSubstring("plugin trapped"))
+
+		_, ok2 := q.Get("0123456789abcdef")
+		Expect(ok2).To(BeFalse())
+	})
+
+	It("should cancel queued and running jobs", func() {
+		q := start(jobs.Options{Workers: 1})
+		running := submit(q, "block")
+		Eventually(started).Should(Receive(Equal("block")))
+		queued := submit(q, "hello")
+
+		job, ok := q.Cancel(queued.ID)
+		Expect(ok).To(BeTrue())
+		Expect(job.State).To(Equal(jobs.Cancelled))
+
+		job, _ = q.Cancel(running.ID)
+		Expect(job.State).To(Equal(jobs.Cancelled))
+		Expect(job.Error).To(BeNil())
+
+		// Cancelling a finished job changes nothing
+		job, _ = q.Cancel(running.ID)
+		Expect(job.State).To(Equal(jobs.Cancelled))
+		Consistently(started).ShouldNot(Receive())
+	})
+
+	It("should reject jobs beyond the queue limit", func() {
+		q := start(jobs.Options{Workers: 1, MaxQueued: 1})
+		submit(q, "block")
+		Eventually(started).Should(Receive())
+		submit(q, "hello")
+
+		_, err := q.Submit(json.RawMessage(`"more"`), "")
+		Expect(err).To(MatchError(jobs.ErrQueueFull))
+		queued, running := q.Counts()
+		Expect(queued).To(Equal(1))
+		Expect(running).To(Equal(1))
+		close(release)
+	})
+
+	// =========================================================================
+	// TEST: Persistence
+	// Why: With a directory, jobs must survive a restart; queued ones run
+	//      afterwards, but work that was interrupted must not silently
+	//      run twice.
+	// =========================================================================
+	It("should restore jobs from the directory", func() {
+		dir := GinkgoT().TempDir()
+		q, err := jobs.New(run, jobs.Options{Dir: dir})
+		Expect(err).NotTo(HaveOccurred())
+		queued := submit(q, "hello")
+
+		// A job the previous process was running when it stopped
+		interrupted := jobs.Job{ID: "00000000000000aa", State: jobs.Running, Input: json.RawMessage(`"hello"`), Created: time.Now()}
+		data, _ := json.Marshal(interrupted)
+		Expect(os.WriteFile(filepath.Join(dir, interrupted.ID+".json"), data, 0o644)).To(Succeed())
+
+		restarted := start(jobs.Options{Dir: dir})
+		Eventually(stateOf(restarted, queued.ID)).Should(Equal(jobs.Succeeded))
+		job, ok := restarted.Get(interrupted.ID)
+		Expect(ok).To(BeTrue())
+		Expect(job.State).To(Equal(jobs.Failed))
+		Expect(job.Error.Code).To(Equal(apierror.CodeInternal))
+	})
+
+	It("should reject negative options", func() {
+		_, err := jobs.New(run, jobs.Options{Workers: -1})
+		Expect(err).To(HaveOccurred())
+	})
+})
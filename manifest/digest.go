@@ -0,0 +1,57 @@
+package manifest
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DigestSuffix is appended to a plugin's .wasm path to name its checksum
+// file, e.g. hello.wasm.sha256. The file holds the hex SHA-256 digest of
+// the binary, optionally followed by the file name as sha256sum prints it.
+const DigestSuffix = ".sha256"
+
+// SignatureSuffix is appended to a plugin's .wasm path to name its
+// detached signature, e.g. hello.wasm.sig: the base64 Ed25519 signature
+// of the binary's contents.
+const SignatureSuffix = ".sig"
+
+// Digest returns the SHA-256 digest the plugin at pluginPath is expected to
+// have, in lowercase hex: the one in its checksum file, or else the one its
+// manifest m (which may be nil) declares. It returns "" if neither declares
+// one.
+//
+// A malformed checksum file, or one that disagrees with the manifest, is
+// reported as ErrInvalid rather than silently preferring either.
+func Digest(pluginPath string, m *Manifest) (string, error) {
+	var declared string
+	if m != nil {
+		declared = strings.ToLower(m.SHA256)
+	}
+
+	data, err := os.ReadFile(pluginPath + DigestSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return declared, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || !validDigest(fields[0]) {
+		return "", fmt.Errorf("%w: %s%s must start with 64 hex digits", ErrInvalid, pluginPath, DigestSuffix)
+	}
+
+	digest := strings.ToLower(fields[0])
+	if declared != "" && declared != digest {
+		return "", fmt.Errorf("%w: sha256 %s in %s contradicts %s%s", ErrInvalid, declared, FileName, pluginPath, DigestSuffix)
+	}
+	return digest, nil
+}
+
+// validDigest reports whether s is a hex SHA-256 digest.
+func validDigest(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == 32
+}
@@ -0,0 +1,425 @@
+// Package manifest reads and validates plugin manifests.
+//
+// A manifest is an optional plugin.json file stored next to a plugin's
+// .wasm file. It describes the plugin (name, version, description), the
+// ABI version it implements, the exports the host may rely on, and the
+// resources it expects to need:
+//
+//	{
+//	  "name": "upper",
+//	  "version": "1.2.0",
+//	  "abi_version": 10000,
+//	  "description": "Upper-cases text payloads",
+//	  "exports": ["init", "process_bytes", "cleanup"],
+//	  "limits": {"memory_pages": 32, "timeout_ms": 500},
+//	  "sizing": {"expected_qps": 200, "latency_target_ms": 20, "instance_memory_mib": 4},
+//	  "config": {"locale": "tr"},
+//	  "blobs": {"read": ["inputs/"], "write": ["outputs/upper/"], "max_put_bytes": 10485760},
+//	  "schedules": [{"name": "nightly", "cron": "0 2 * * *", "text": "rollup"}],
+//	  "tests": [{"name": "shouts", "text": "hi", "output_text": "HI"}],
+//	  "data": ["models/upper/casing.bin"],
+//	  "wasi": {"args": ["--strict"], "env": {"LANG": "tr_TR"}, "inherit_env": ["TZ"], "dirs": ["/data"]},
+//	  "sha256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+//	}
+//
+// The config block is passed to the plugin's init_with_config() export when
+// an instance is initialized; deployments may replace it without rebuilding
+// the plugin.
+//
+// Stores read the manifest when resolving a plugin, and the runtime checks
+// it against the binary on load, so a plugin whose manifest promises
+// exports it does not have is rejected before it serves a request. The
+// same holds for a binary whose digest differs from the one declared in
+// the manifest or in a <name>.wasm.sha256 file next to it (see Digest).
+//
+// Like wasminfo, the package is pure Go and usable without WasmEdge.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/cron"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// FileName is the name of the manifest file in a plugin's directory.
+const FileName = "plugin.json"
+
+// ErrInvalid is returned for manifests that cannot be parsed, fail
+// validation, or do not match the plugin they describe.
+var ErrInvalid = errors.New("invalid plugin manifest")
+
+// Manifest describes a plugin.
+type Manifest struct {
+	Name        string `json:"name"`                  // Plugin name; must match the store name
+	Version     string `json:"version"`               // Plugin release, e.g. "1.2.0"
+	ABIVersion  int    `json:"abi_version,omitempty"` // Value get_abi_version() returns (0 = undeclared)
+	Description string `json:"description,omitempty"`
+
+	// Exports lists functions the plugin must export. The runtime rejects
+	// the plugin if any of them is missing from the binary.
+	Exports []string `json:"exports,omitempty"`
+
+	Limits Limits `json:"limits,omitempty"`
+
+	// Sizing hints seed the plugin's instance pool where the operator has
+	// not sized it explicitly.
+	Sizing Sizing `json:"sizing,omitempty"`
+
+	// Config is a JSON object passed verbatim to init_with_config(). Plugins
+	// declaring it must export that function.
+	Config json.RawMessage `json:"config,omitempty"`
+
+	// Blobs declares the object storage keys the plugin reads and writes
+	// through blob_get() and blob_put(). Without it, both are denied.
+	Blobs Blobs `json:"blobs,omitempty"`
+
+	// Reentrant declares that the plugin's exports may be called
+	// concurrently on one instance, e.g. because they keep no state in
+	// linear memory. The runtime serializes calls to all other plugins.
+	Reentrant bool `json:"reentrant,omitempty"`
+
+	// Schedules are periodic executions the server registers when the
+	// plugin is published, and drops when it is deleted.
+	Schedules []Schedule `json:"schedules,omitempty"`
+
+	// Tests are calls the server makes to a build before publishing it,
+	// with the outcome each must have. A build failing any of them is not
+	// published.
+	Tests []Test `json:"tests,omitempty"`
+
+	// Data lists the files of the plugin store's dataset, relative to its
+	// root, the plugin depends on, e.g. a model it reads through a mount.
+	// Locality routing prefers replicas whose cache already holds them.
+	Data []string `json:"data,omitempty"`
+
+	// WASI declares the arguments, environment variables, and directories
+	// the plugin gets through WASI. Without it, it gets none.
+	WASI WASI `json:"wasi,omitempty"`
+
+	// SHA256 is the hex SHA-256 digest of the plugin's .wasm file. The
+	// runtime refuses to load a binary with any other digest.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// InitWithConfigExport is the export that receives Config.
+const InitWithConfigExport = "init_with_config"
+
+// Limits declares the resources a plugin expects to need. Zero means the
+// plugin declares no limit and the host default applies.
+type Limits struct {
+	MemoryPages int `json:"memory_pages,omitempty"` // Linear memory, in 64 KiB pages
+	TimeoutMs   int `json:"timeout_ms,omitempty"`   // Execution time per call
+}
+
+// Sizing describes the load a plugin expects, so hosts can size its
+// instance pool without per-plugin tuning. Zero means no hint.
+type Sizing struct {
+	ExpectedQPS       float64 `json:"expected_qps,omitempty"`        // Steady-state requests per second
+	LatencyTargetMs   int     `json:"latency_target_ms,omitempty"`   // Typical duration of one call
+	InstanceMemoryMiB int     `json:"instance_memory_mib,omitempty"` // Memory one instance needs
+}
+
+// Blobs declares a plugin's object storage access. Keys and prefixes are
+// relative to the blob prefix the host configures; a prefix should end in
+// "/" unless it is meant to match partial key segments.
+type Blobs struct {
+	Read        []string `json:"read,omitempty"`          // Key prefixes blob_get() may read, e.g. "inputs/"
+	Write       []string `json:"write,omitempty"`         // Key prefixes blob_put() may write
+	MaxGetBytes int64    `json:"max_get_bytes,omitempty"` // Largest object read (0 = host limit)
+	MaxPutBytes int64    `json:"max_put_bytes,omitempty"` // Largest object written (0 = host limit)
+}
+
+// WASI declares a plugin's WASI environment. Host environment variables
+// and directories are only granted if the host allows them; a plugin
+// needing a directory the host does not grant fails to load.
+type WASI struct {
+	Args       []string          `json:"args,omitempty"`        // Arguments after the program name
+	Env        map[string]string `json:"env,omitempty"`         // Variables set for the plugin, by name
+	InheritEnv []string          `json:"inherit_env,omitempty"` // Host variables passed through, e.g. "TZ"
+	Dirs       []string          `json:"dirs,omitempty"`        // Absolute guest paths of pre-opened directories, e.g. "/data"
+}
+
+// Schedule is a periodic execution of a plugin. Each run is a /run
+// request for the plugin's latest build with the given input.
+type Schedule struct {
+	Name string `json:"name"` // Unique within the manifest, e.g. "nightly-rollup"
+	Cron string `json:"cron"` // Cron expression in UTC, e.g. "*/5 * * * *" or "@hourly" (see package cron)
+
+	Input     int     `json:"input,omitempty"`      // Number passed to process()
+	Text      *string `json:"text,omitempty"`       // Payload passed to process_bytes() instead
+	TimeoutMs int     `json:"timeout_ms,omitempty"` // Execution time per run (0 = host limit)
+
+	// Disabled registers the schedule without running it until it is
+	// enabled through the server's admin API.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Test is a call to a plugin build and its expected outcome: exactly one
+// of Output, OutputText, and ErrorCode.
+type Test struct {
+	Name string `json:"name"` // Unique within the manifest, e.g. "empty-input"
+
+	Input int     `json:"input,omitempty"` // Number passed to process()
+	Text  *string `json:"text,omitempty"`  // Payload passed to process_bytes() instead
+
+	Output     *int    `json:"output,omitempty"`      // Result process() must return
+	OutputText *string `json:"output_text,omitempty"` // Payload process_bytes() must return
+	ErrorCode  int32   `json:"error_code,omitempty"`  // Negative ABI error code the call must fail with
+}
+
+// maxMemoryPages is the 4 GiB address space of a wasm32 module in pages.
+const maxMemoryPages = 65536
+
+// Path returns the manifest path for the plugin at pluginPath.
+func Path(pluginPath string) string {
+	return filepath.Join(filepath.Dir(pluginPath), FileName)
+}
+
+// ForPlugin reads the manifest next to the plugin at pluginPath.
+// It returns nil without an error if the plugin has no manifest.
+func ForPlugin(pluginPath string) (*Manifest, error) {
+	m, err := Load(Path(pluginPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// Load reads and validates the manifest at path.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a manifest.
+//
+// Unknown fields are rejected so that a misspelled limit is reported
+// instead of silently ignored.
+func Parse(data []byte) (*Manifest, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that required fields are set and values are in range.
+func (m *Manifest) Validate() error {
+	switch {
+	case m.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	case m.Version == "":
+		return fmt.Errorf("%w: version is required", ErrInvalid)
+	case m.ABIVersion < 0:
+		return fmt.Errorf("%w: abi_version must not be negative", ErrInvalid)
+	case m.Limits.MemoryPages < 0 || m.Limits.TimeoutMs < 0:
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalid)
+	case m.Limits.MemoryPages > maxMemoryPages:
+		return fmt.Errorf("%w: limits.memory_pages exceeds %d", ErrInvalid, maxMemoryPages)
+	case m.Sizing.ExpectedQPS < 0 || m.Sizing.LatencyTargetMs < 0 || m.Sizing.InstanceMemoryMiB < 0:
+		return fmt.Errorf("%w: sizing hints must not be negative", ErrInvalid)
+	case m.SHA256 != "" && !validDigest(m.SHA256):
+		return fmt.Errorf("%w: sha256 must be 64 hex digits", ErrInvalid)
+	case m.Blobs.MaxGetBytes < 0 || m.Blobs.MaxPutBytes < 0:
+		return fmt.Errorf("%w: blob size caps must not be negative", ErrInvalid)
+	}
+
+	for _, prefix := range append(append([]string(nil), m.Blobs.Read...), m.Blobs.Write...) {
+		if !validBlobPrefix(prefix) {
+			return fmt.Errorf("%w: blob prefix %q must be relative and must not contain \"..\" segments", ErrInvalid, prefix)
+		}
+	}
+
+	for _, path := range m.Data {
+		if !validBlobPrefix(path) {
+			return fmt.Errorf("%w: data path %q must be relative and must not contain \"..\" segments", ErrInvalid, path)
+		}
+	}
+
+	if err := m.WASI.validate(); err != nil {
+		return err
+	}
+
+	if len(m.Config) > 0 {
+		if trimmed := bytes.TrimSpace(m.Config); len(trimmed) == 0 || trimmed[0] != '{' {
+			return fmt.Errorf("%w: config must be a JSON object", ErrInvalid)
+		}
+	}
+
+	if err := validateSchedules(m.Schedules); err != nil {
+		return err
+	}
+
+	if err := validateTests(m.Tests); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(m.Exports))
+	for _, name := range m.Exports {
+		if name == "" {
+			return fmt.Errorf("%w: export names must not be empty", ErrInvalid)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: export %s is listed twice", ErrInvalid, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Check verifies that a binary matches the manifest: every declared export
+// is an exported function, and a declared ABI version equals the constant
+// get_abi_version() returns. A get_abi_version that does not simply return
+// a constant cannot be checked and is accepted. A manifest with a config
+// block requires the binary to export init_with_config.
+func (m *Manifest) Check(module *wasminfo.Module) error {
+	required := m.Exports
+	if len(m.Config) > 0 {
+		required = append(required[:len(required):len(required)], InitWithConfigExport)
+	}
+	for _, name := range required {
+		export, ok := module.Export(name)
+		if !ok || export.Kind != wasminfo.KindFunc {
+			return fmt.Errorf("%w: declared export %s is not exported by the binary", ErrInvalid, name)
+		}
+	}
+
+	if m.ABIVersion != 0 {
+		if _, ok := module.Export(wasminfo.ABIVersionExport); !ok {
+			return fmt.Errorf("%w: declares abi_version %d but the binary does not export %s",
+				ErrInvalid, m.ABIVersion, wasminfo.ABIVersionExport)
+		}
+		if module.ABIVersion != nil && *module.ABIVersion != m.ABIVersion {
+			return fmt.Errorf("%w: declares abi_version %d but the binary reports %d",
+				ErrInvalid, m.ABIVersion, *module.ABIVersion)
+		}
+	}
+	return nil
+}
+
+// validate checks that variable names are usable and directories are
+// distinct, clean absolute paths.
+func (w WASI) validate() error {
+	names := slices.Collect(maps.Keys(w.Env))
+	for _, name := range append(names, w.InheritEnv...) {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("%w: wasi environment variable name %q must be non-empty and must not contain '='", ErrInvalid, name)
+		}
+	}
+	seen := make(map[string]bool, len(w.Dirs))
+	for _, dir := range w.Dirs {
+		if !path.IsAbs(dir) || path.Clean(dir) != dir {
+			return fmt.Errorf("%w: wasi directory %q must be a clean absolute path", ErrInvalid, dir)
+		}
+		if seen[dir] {
+			return fmt.Errorf("%w: wasi directory %s is listed twice", ErrInvalid, dir)
+		}
+		seen[dir] = true
+	}
+	return nil
+}
+
+// validateSchedules checks that schedules have unique, URL-safe names,
+// valid cron expressions, and non-negative timeouts.
+func validateSchedules(schedules []Schedule) error {
+	seen := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		if !validScheduleName(schedule.Name) {
+			return fmt.Errorf("%w: schedule name %q must be 1-64 letters, digits, '-' or '_'", ErrInvalid, schedule.Name)
+		}
+		if seen[schedule.Name] {
+			return fmt.Errorf("%w: schedule %s is declared twice", ErrInvalid, schedule.Name)
+		}
+		seen[schedule.Name] = true
+		if _, err := cron.Parse(schedule.Cron); err != nil {
+			return fmt.Errorf("%w: schedule %s: %v", ErrInvalid, schedule.Name, err)
+		}
+		if schedule.TimeoutMs < 0 {
+			return fmt.Errorf("%w: schedule %s: timeout_ms must not be negative", ErrInvalid, schedule.Name)
+		}
+	}
+	return nil
+}
+
+// validateTests checks that tests have unique names and expect exactly
+// one outcome, of the function their input calls.
+func validateTests(tests []Test) error {
+	seen := make(map[string]bool, len(tests))
+	for _, test := range tests {
+		if !validScheduleName(test.Name) {
+			return fmt.Errorf("%w: test name %q must be 1-64 letters, digits, '-' or '_'", ErrInvalid, test.Name)
+		}
+		if seen[test.Name] {
+			return fmt.Errorf("%w: test %s is declared twice", ErrInvalid, test.Name)
+		}
+		seen[test.Name] = true
+
+		expected := 0
+		for _, set := range []bool{test.Output != nil, test.OutputText != nil, test.ErrorCode != 0} {
+			if set {
+				expected++
+			}
+		}
+		switch {
+		case expected != 1:
+			return fmt.Errorf("%w: test %s must expect exactly one of output, output_text, and error_code", ErrInvalid, test.Name)
+		case test.ErrorCode > 0:
+			return fmt.Errorf("%w: test %s: error_code must be negative", ErrInvalid, test.Name)
+		case test.Output != nil && test.Text != nil:
+			return fmt.Errorf("%w: test %s: process_bytes() returns output_text, not output", ErrInvalid, test.Name)
+		case test.OutputText != nil && test.Text == nil:
+			return fmt.Errorf("%w: test %s: output_text needs a text input", ErrInvalid, test.Name)
+		}
+	}
+	return nil
+}
+
+// validScheduleName reports whether name can name a schedule in a URL path.
+func validScheduleName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validBlobPrefix reports whether prefix is a non-empty relative key
+// prefix that cannot climb out of the host's blob prefix.
+func validBlobPrefix(prefix string) bool {
+	if prefix == "" || strings.HasPrefix(prefix, "/") {
+		return false
+	}
+	for _, segment := range strings.Split(prefix, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,309 @@
+package manifest_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+func TestManifest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Manifest Suite")
+}
+
+var _ = Describe("Parse", func() {
+	It("should decode every field", func() {
+		rollup, hi, shouted := "rollup", "hi", "HI"
+		m, err := manifest.Parse([]byte(`{
+			"name": "upper",
+			"version": "1.2.0",
+			"abi_version": 10000,
+			"description": "Upper-cases text",
+			"exports": ["init", "process_bytes"],
+			"limits": {"memory_pages": 32, "timeout_ms": 500},
+			"sizing": {"expected_qps": 200, "latency_target_ms": 20, "instance_memory_mib": 4},
+			"blobs": {"read": ["inputs/"], "write": ["outputs/upper/"], "max_put_bytes": 1048576},
+			"reentrant": true,
+			"schedules": [{"name": "nightly", "cron": "0 2 * * *", "text": "rollup", "timeout_ms": 60000, "disabled": true}],
+			"tests": [{"name": "shouts", "text": "hi", "output_text": "HI"}, {"name": "rejects", "input": -1, "error_code": -2}],
+			"wasi": {"args": ["--strict"], "env": {"LANG": "tr_TR"}, "inherit_env": ["TZ"], "dirs": ["/data"]}
+		}`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(Equal(&manifest.Manifest{
+			Name:        "upper",
+			Version:     "1.2.0",
+			ABIVersion:  10000,
+			Description: "Upper-cases text",
+			Exports:     []string{"init", "process_bytes"},
+			Limits:      manifest.Limits{MemoryPages: 32, TimeoutMs: 500},
+			Sizing:      manifest.Sizing{ExpectedQPS: 200, LatencyTargetMs: 20, InstanceMemoryMiB: 4},
+			Blobs:       manifest.Blobs{Read: []string{"inputs/"}, Write: []string{"outputs/upper/"}, MaxPutBytes: 1 << 20},
+			Reentrant:   true,
+			Schedules: []manifest.Schedule{
+				{Name: "nightly", Cron: "0 2 * * *", Text: &rollup, TimeoutMs: 60000, Disabled: true},
+			},
+			Tests: []manifest.Test{
+				{Name: "shouts", Text: &hi, OutputText: &shouted},
+				{Name: "rejects", Input: -1, ErrorCode: -2},
+			},
+			WASI: manifest.WASI{
+				Args:       []string{"--strict"},
+				Env:        map[string]string{"LANG": "tr_TR"},
+				InheritEnv: []string{"TZ"},
+				Dirs:       []string{"/data"},
+			},
+		}))
+	})
+
+	// =========================================================================
+	// TEST: Malformed manifests
+	// Why: A manifest that is silently misread would let a plugin run with
+	//      limits or exports its author did not intend.
+	// =========================================================================
+	DescribeTable("should reject invalid manifests",
+		func(data string) {
+			_, err := manifest.Parse([]byte(data))
+			Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue(), "%v", err)
+		},
+		Entry("malformed JSON", `{"name": `),
+		Entry("missing name", `{"version": "1.0.0"}`),
+		Entry("missing version", `{"name": "hello"}`),
+		Entry("unknown field", `{"name": "hello", "version": "1.0.0", "limits": {"memory": 1}}`),
+		Entry("negative ABI version", `{"name": "hello", "version": "1.0.0", "abi_version": -1}`),
+		Entry("negative limit", `{"name": "hello", "version": "1.0.0", "limits": {"timeout_ms": -1}}`),
+		Entry("negative sizing hint", `{"name": "hello", "version": "1.0.0", "sizing": {"expected_qps": -1}}`),
+		Entry("memory beyond wasm32", `{"name": "hello", "version": "1.0.0", "limits": {"memory_pages": 65537}}`),
+		Entry("empty export", `{"name": "hello", "version": "1.0.0", "exports": [""]}`),
+		Entry("duplicate export", `{"name": "hello", "version": "1.0.0", "exports": ["init", "init"]}`),
+		Entry("config that is not an object", `{"name": "hello", "version": "1.0.0", "config": [1]}`),
+		Entry("malformed digest", `{"name": "hello", "version": "1.0.0", "sha256": "abc"}`),
+		Entry("negative blob cap", `{"name": "hello", "version": "1.0.0", "blobs": {"max_get_bytes": -1}}`),
+		Entry("absolute blob prefix", `{"name": "hello", "version": "1.0.0", "blobs": {"read": ["/etc/"]}}`),
+		Entry("blob prefix climbing out", `{"name": "hello", "version": "1.0.0", "blobs": {"write": ["outputs/../secrets/"]}}`),
+		Entry("data path climbing out", `{"name": "hello", "version": "1.0.0", "data": ["../secrets/key"]}`),
+		Entry("unnamed schedule", `{"name": "hello", "version": "1.0.0", "schedules": [{"cron": "@hourly"}]}`),
+		Entry("schedule name with a slash", `{"name": "hello", "version": "1.0.0", "schedules": [{"name": "a/b", "cron": "@hourly"}]}`),
+		Entry("duplicate schedule", `{"name": "hello", "version": "1.0.0", "schedules": [{"name": "a", "cron": "@hourly"}, {"name": "a", "cron": "@daily"}]}`),
+		Entry("invalid cron expression", `{"name": "hello", "version": "1.0.0", "schedules": [{"name": "a", "cron": "0 25 * * *"}]}`),
+		Entry("negative schedule timeout", `{"name": "hello", "version": "1.0.0", "schedules": [{"name": "a", "cron": "@hourly", "timeout_ms": -1}]}`),
+		Entry("unnamed test", `{"name": "hello", "version": "1.0.0", "tests": [{"output": 1}]}`),
+		Entry("duplicate test", `{"name": "hello", "version": "1.0.0", "tests": [{"name": "a", "output": 1}, {"name": "a", "output": 2}]}`),
+		Entry("test without an expected outcome", `{"name": "hello", "version": "1.0.0", "tests": [{"name": "a", "input": 1}]}`),
+		Entry("test with two expected outcomes", `{"name": "hello", "version": "1.0.0", "tests": [{"name": "a", "output": 1, "error_code": -1}]}`),
+		Entry("positive test error code", `{"name": "hello", "version": "1.0.0", "tests": [{"name": "a", "error_code": 1}]}`),
+		Entry("test expecting output text of process()", `{"name": "hello", "version": "1.0.0", "tests": [{"name": "a", "output_text": "x"}]}`),
+		Entry("WASI variable name with '='", `{"name": "hello", "version": "1.0.0", "wasi": {"env": {"A=B": "c"}}}`),
+		Entry("empty inherited variable name", `{"name": "hello", "version": "1.0.0", "wasi": {"inherit_env": [""]}}`),
+		Entry("relative WASI directory", `{"name": "hello", "version": "1.0.0", "wasi": {"dirs": ["data"]}}`),
+		Entry("WASI directory climbing out", `{"name": "hello", "version": "1.0.0", "wasi": {"dirs": ["/data/../etc"]}}`),
+		Entry("duplicate WASI directory", `{"name": "hello", "version": "1.0.0", "wasi": {"dirs": ["/data", "/data"]}}`),
+	)
+
+	It("should keep the config block verbatim", func() {
+		m, err := manifest.Parse([]byte(`{"name": "calc", "version": "1.0.0", "config": {"offset": 7}}`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(m.Config)).To(Equal(`{"offset": 7}`))
+	})
+})
+
+var _ = Describe("ForPlugin", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should return nil for a plugin without a manifest", func() {
+		m, err := manifest.ForPlugin(filepath.Join(dir, "hello.wasm"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(BeNil())
+	})
+
+	It("should read plugin.json next to the plugin", func() {
+		data := []byte(`{"name": "hello", "version": "1.0.0"}`)
+		Expect(os.WriteFile(filepath.Join(dir, manifest.FileName), data, 0644)).To(Succeed())
+
+		m, err := manifest.ForPlugin(filepath.Join(dir, "hello.wasm"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Name).To(Equal("hello"))
+	})
+
+	It("should report an invalid manifest with its path", func() {
+		Expect(os.WriteFile(filepath.Join(dir, manifest.FileName), []byte(`{}`), 0644)).To(Succeed())
+
+		_, err := manifest.ForPlugin(filepath.Join(dir, "hello.wasm"))
+
+		Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(manifest.FileName))
+	})
+})
+
+var _ = Describe("Digest", func() {
+	// =========================================================================
+	// TEST: Declared digests
+	// Why: The runtime refuses binaries that differ from the digest declared
+	//      for them; two sources that disagree must not let either one win
+	//      silently.
+	// =========================================================================
+	const (
+		digest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+		other  = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+	)
+
+	var wasmPath string
+
+	BeforeEach(func() {
+		wasmPath = filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+	})
+
+	writeChecksum := func(data string) {
+		Expect(os.WriteFile(wasmPath+manifest.DigestSuffix, []byte(data), 0644)).To(Succeed())
+	}
+
+	It("should return nothing when no digest is declared", func() {
+		Expect(manifest.Digest(wasmPath, nil)).To(BeEmpty())
+		Expect(manifest.Digest(wasmPath, &manifest.Manifest{Name: "hello"})).To(BeEmpty())
+	})
+
+	It("should read the manifest's digest in lowercase", func() {
+		m := &manifest.Manifest{SHA256: strings.ToUpper(digest)}
+		Expect(manifest.Digest(wasmPath, m)).To(Equal(digest))
+	})
+
+	It("should read a checksum file as sha256sum writes it", func() {
+		writeChecksum(digest + "  hello.wasm\n")
+		Expect(manifest.Digest(wasmPath, nil)).To(Equal(digest))
+		Expect(manifest.Digest(wasmPath, &manifest.Manifest{SHA256: digest})).To(Equal(digest))
+	})
+
+	It("should reject a malformed checksum file", func() {
+		writeChecksum("not a digest\n")
+		_, err := manifest.Digest(wasmPath, nil)
+		Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue(), "%v", err)
+	})
+
+	It("should reject a checksum file that contradicts the manifest", func() {
+		writeChecksum(digest)
+		_, err := manifest.Digest(wasmPath, &manifest.Manifest{SHA256: other})
+		Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue(), "%v", err)
+	})
+})
+
+var _ = Describe("Check", func() {
+	var module *wasminfo.Module
+
+	BeforeEach(func() {
+		version := 10000
+		module = &wasminfo.Module{
+			Exports: []wasminfo.Export{
+				{Name: "get_abi_version", Kind: wasminfo.KindFunc},
+				{Name: "init", Kind: wasminfo.KindFunc},
+				{Name: "memory", Kind: wasminfo.KindMemory},
+				{Name: "process", Kind: wasminfo.KindFunc},
+			},
+			ABIVersion: &version,
+		}
+	})
+
+	It("should accept a binary that matches", func() {
+		m := &manifest.Manifest{Name: "hello", Version: "1.0.0", ABIVersion: 10000, Exports: []string{"init", "process"}}
+
+		Expect(m.Check(module)).To(Succeed())
+	})
+
+	// =========================================================================
+	// TEST: Manifest/binary mismatch
+	// Why: The manifest is what operators and tooling read; a binary that
+	//      contradicts it must be rejected rather than served.
+	// =========================================================================
+	It("should reject a missing export", func() {
+		m := &manifest.Manifest{Name: "hello", Version: "1.0.0", Exports: []string{"process_bytes"}}
+
+		err := m.Check(module)
+
+		Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("process_bytes"))
+	})
+
+	It("should reject a declared export that is not a function", func() {
+		m := &manifest.Manifest{Name: "hello", Version: "1.0.0", Exports: []string{"memory"}}
+
+		Expect(errors.Is(m.Check(module), manifest.ErrInvalid)).To(BeTrue())
+	})
+
+	It("should reject a different ABI version", func() {
+		m := &manifest.Manifest{Name: "hello", Version: "1.0.0", ABIVersion: 20000}
+
+		err := m.Check(module)
+
+		Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("10000"))
+	})
+
+	It("should require init_with_config for a manifest with config", func() {
+		m := &manifest.Manifest{Name: "hello", Version: "1.0.0", Config: []byte(`{"offset": 1}`)}
+
+		err := m.Check(module)
+
+		Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(manifest.InitWithConfigExport))
+	})
+
+	It("should reject a declared ABI version without get_abi_version", func() {
+		module.Exports = module.Exports[1:]
+		m := &manifest.Manifest{Name: "hello", Version: "1.0.0", ABIVersion: 10000}
+
+		Expect(errors.Is(m.Check(module), manifest.ErrInvalid)).To(BeTrue())
+	})
+})
+
+var _ = Describe("MergeConfig", func() {
+	// =========================================================================
+	// TEST: Config overlays
+	// Why: Each environment patches only what differs from the manifest; a
+	//      patch that replaced nested objects wholesale, or could not remove
+	//      a default, would force deployments to repeat the entire config.
+	// =========================================================================
+	It("should apply overlays in order as merge patches", func() {
+		merged, err := manifest.MergeConfig(
+			[]byte(`{"locale": "en", "cache": {"size": 10, "ttl": 60}, "debug": true}`),
+			[]byte(`{"cache": {"size": 100}}`),
+			[]byte(`{"locale": "tr", "debug": null}`),
+		)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged).To(MatchJSON(`{"locale": "tr", "cache": {"size": 100, "ttl": 60}}`))
+	})
+
+	It("should start from an empty object without a base", func() {
+		merged, err := manifest.MergeConfig(nil, []byte(`{"offset": 1}`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged).To(MatchJSON(`{"offset": 1}`))
+	})
+
+	It("should keep large numbers exact", func() {
+		merged, err := manifest.MergeConfig([]byte(`{"id": 9007199254740993}`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(merged)).To(Equal(`{"id":9007199254740993}`))
+	})
+
+	It("should reject an overlay that is not an object", func() {
+		_, err := manifest.MergeConfig([]byte(`{}`), []byte(`{}`), []byte(`"prod"`))
+
+		Expect(err).To(MatchError(ContainSubstring("config overlay 2")))
+	})
+})
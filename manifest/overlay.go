@@ -0,0 +1,63 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MergeConfig applies overlays to a base config block as JSON merge patches
+// (RFC 7386), in order: objects are merged key by key, a null value removes
+// the key, and any other value replaces it. An empty base is treated as {}.
+//
+// Overlays let one plugin artifact run with different settings per
+// environment: the manifest carries the defaults and each deployment
+// patches only what differs.
+func MergeConfig(base json.RawMessage, overlays ...[]byte) (json.RawMessage, error) {
+	merged := map[string]interface{}{}
+	if len(base) > 0 {
+		if err := decodeObject(base, &merged); err != nil {
+			return nil, fmt.Errorf("%w: config: %v", ErrInvalid, err)
+		}
+	}
+
+	for i, overlay := range overlays {
+		var patch map[string]interface{}
+		if err := decodeObject(overlay, &patch); err != nil {
+			return nil, fmt.Errorf("config overlay %d: %w", i+1, err)
+		}
+		merged = mergePatch(merged, patch).(map[string]interface{})
+	}
+
+	return json.Marshal(merged)
+}
+
+// decodeObject decodes a JSON object, keeping numbers as written.
+func decodeObject(data []byte, v *map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil || *v == nil {
+		return fmt.Errorf("must be a JSON object")
+	}
+	return nil
+}
+
+// mergePatch implements the MergePatch function of RFC 7386.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,268 @@
+// Package metrics provides minimal, dependency-free metric primitives and a
+// Prometheus text exposition writer.
+//
+// Components that own their state (pools, caches, stores) keep their own
+// counters and histograms and register a Collector that reports them when
+// scraped. This keeps hot paths free of global registries and lets the
+// runtime package stay usable without any metrics backend.
+//
+// # Exposition
+//
+// Registry.WriteText renders all collected families in the Prometheus text
+// format (version 0.0.4), so any Prometheus-compatible scraper can read
+// the /metrics endpoint without a client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types as they appear in the # TYPE line.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultDurationBuckets are histogram bounds in seconds suitable for
+// request-scale latencies (100µs to 10s).
+var DefaultDurationBuckets = []float64{
+	0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10,
+}
+
+// Counter is a monotonically increasing value safe for concurrent use.
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// Histogram counts observations into fixed buckets. It is safe for
+// concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64 // Upper bounds, sorted ascending
+	counts []uint64  // Per-bucket (non-cumulative) counts; last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram with the given upper bounds.
+// A +Inf bucket is always added implicitly.
+func NewHistogram(bounds []float64) *Histogram {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	return &Histogram{
+		bounds: sorted,
+		counts: make([]uint64, len(sorted)+1),
+	}
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Snapshot returns a consistent copy of the histogram's state.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snap := HistogramSnapshot{
+		Bounds: append([]float64(nil), h.bounds...),
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    h.sum,
+		Count:  h.count,
+	}
+
+	// Store cumulative counts, matching Prometheus bucket semantics
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		snap.Counts[i] = cumulative
+	}
+	return snap
+}
+
+// HistogramSnapshot is a point-in-time copy of a Histogram.
+//
+// Counts are cumulative: Counts[i] is the number of observations <= Bounds[i],
+// and the final element counts all observations (the +Inf bucket).
+type HistogramSnapshot struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// Label is a single name/value pair attached to a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one labeled value within a Family. Histogram families set
+// Histogram instead of Value.
+type Sample struct {
+	Labels    []Label
+	Value     float64
+	Histogram *HistogramSnapshot
+}
+
+// Family is a named group of samples sharing a type and help text.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector reports the current metric families of a component.
+// It is called on every scrape and must be safe for concurrent use.
+type Collector func() []Family
+
+// Registry aggregates collectors for exposition.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a collector to the registry.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Gather calls every collector and returns the families sorted by name.
+func (r *Registry) Gather() []Family {
+	r.mu.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	var families []Family
+	for _, c := range collectors {
+		families = append(families, c()...)
+	}
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
+
+// WriteText writes all gathered families in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	for _, f := range r.Gather() {
+		if err := writeFamily(w, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns an http.Handler serving the registry in text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = r.WriteText(w)
+	})
+}
+
+// writeFamily renders a single family including HELP and TYPE lines.
+func writeFamily(w io.Writer, f Family) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n",
+		f.Name, escapeHelp(f.Help), f.Name, f.Type); err != nil {
+		return err
+	}
+
+	for _, s := range f.Samples {
+		if s.Histogram == nil {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n",
+				f.Name, formatLabels(s.Labels), formatValue(s.Value)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		h := s.Histogram
+		for i, bound := range h.Bounds {
+			labels := append(append([]Label(nil), s.Labels...), Label{"le", formatValue(bound)})
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n",
+				f.Name, formatLabels(labels), h.Counts[i]); err != nil {
+				return err
+			}
+		}
+		labels := append(append([]Label(nil), s.Labels...), Label{"le", "+Inf"})
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			f.Name, formatLabels(labels), h.Count,
+			f.Name, formatLabels(s.Labels), formatValue(h.Sum),
+			f.Name, formatLabels(s.Labels), h.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatLabels renders labels as {a="1",b="2"}, or nothing if empty.
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf("%s=%q", l.Name, l.Value)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue renders a float the way Prometheus expects.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return fmt.Sprintf("%g", v)
+	}
+}
+
+// escapeHelp escapes backslashes and newlines in help text.
+func escapeHelp(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
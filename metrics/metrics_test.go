@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics Suite")
+}
+
+var _ = Describe("Counter", func() {
+	It("should count concurrently", func() {
+		var c metrics.Counter
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					c.Inc()
+				}
+			}()
+		}
+		wg.Wait()
+		c.Add(5)
+
+		Expect(c.Value()).To(Equal(uint64(1005)))
+	})
+})
+
+var _ = Describe("Histogram", func() {
+	// =========================================================================
+	// TEST: Cumulative bucket counts
+	// Why: Prometheus requires cumulative buckets; off-by-one bucket placement
+	//      silently skews every latency percentile.
+	// =========================================================================
+	It("should report cumulative bucket counts", func() {
+		h := metrics.NewHistogram([]float64{1, 5, 10})
+
+		for _, v := range []float64{0.5, 1, 3, 7, 20} {
+			h.Observe(v)
+		}
+		snap := h.Snapshot()
+
+		Expect(snap.Bounds).To(Equal([]float64{1, 5, 10}))
+		Expect(snap.Counts).To(Equal([]uint64{2, 3, 4, 5}))
+		Expect(snap.Count).To(Equal(uint64(5)))
+		Expect(snap.Sum).To(BeNumerically("~", 31.5))
+	})
+
+	It("should sort unsorted bounds", func() {
+		h := metrics.NewHistogram([]float64{10, 1})
+		h.Observe(2)
+
+		snap := h.Snapshot()
+		Expect(snap.Bounds).To(Equal([]float64{1, 10}))
+		Expect(snap.Counts).To(Equal([]uint64{0, 1, 1}))
+	})
+})
+
+var _ = Describe("Registry", func() {
+	var reg *metrics.Registry
+
+	BeforeEach(func() {
+		reg = metrics.NewRegistry()
+		reg.Register(func() []metrics.Family {
+			h := metrics.NewHistogram([]float64{0.5})
+			h.Observe(0.25)
+			snap := h.Snapshot()
+
+			return []metrics.Family{
+				{
+					Name: "test_requests_total", Help: "Requests.", Type: metrics.TypeCounter,
+					Samples: []metrics.Sample{{Labels: []metrics.Label{{Name: "plugin", Value: "hello"}}, Value: 3}},
+				},
+				{
+					Name: "test_latency_seconds", Help: "Latency.", Type: metrics.TypeHistogram,
+					Samples: []metrics.Sample{{Histogram: &snap}},
+				},
+			}
+		})
+	})
+
+	// =========================================================================
+	// TEST: Prometheus text exposition
+	// Why: The /metrics endpoint must be parseable by standard scrapers.
+	// =========================================================================
+	It("should write families in Prometheus text format sorted by name", func() {
+		var buf bytes.Buffer
+		Expect(reg.WriteText(&buf)).To(Succeed())
+
+		Expect(buf.String()).To(Equal(`# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.5"} 1
+test_latency_seconds_bucket{le="+Inf"} 1
+test_latency_seconds_sum 0.25
+test_latency_seconds_count 1
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{plugin="hello"} 3
+`))
+	})
+
+	It("should serve the text format over HTTP", func() {
+		rec := httptest.NewRecorder()
+		reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(rec.Header().Get("Content-Type")).To(ContainSubstring("text/plain"))
+		Expect(rec.Body.String()).To(ContainSubstring(`test_requests_total{plugin="hello"} 3`))
+	})
+})
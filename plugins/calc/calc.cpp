@@ -0,0 +1,214 @@
+// Calc Plugin - Example WASM plugin with multi-parameter exports
+//
+// Exercises Plugin.Call(): integer, floating-point, and boolean parameters,
+// plus strings and slices passed as (ptr, len) through allocate().
+// process() adds a configured offset (0 unless init_with_config() received
+// {"offset": N}) so the plugin also satisfies the core ABI; it rejects
+// negative input and explains why through last_error().
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -Wl,--export=allocate -Wl,--export=deallocate \
+//   -Wl,--export=add -Wl,--export=mul_add -Wl,--export=scale \
+//   -Wl,--export=pick -Wl,--export=count_byte -Wl,--export=sum_i64 \
+//   -Wl,--export=mean_f64 -Wl,--export=last_error \
+//   -Wl,--export=init_with_config -Wl,--export=reserve \
+//   -O3 -o calc.wasm calc.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+
+#define HEAP_SIZE (1 << 20)
+
+static int initialized = 0;
+static int offset = 0;
+
+// Message for the most recent error code, returned by last_error()
+static const char *error_message = 0;
+static int error_length = 0;
+
+static void set_error(const char *message) {
+    error_message = message;
+    error_length = 0;
+    while (message[error_length]) {
+        error_length++;
+    }
+}
+
+// () -> i64: (msg_ptr << 32) | msg_len, or 0 if there is no message
+extern "C" long long last_error() {
+    if (error_message == 0) {
+        return 0;
+    }
+    return ((long long)(unsigned int)(unsigned long)error_message << 32) | (unsigned int)error_length;
+}
+
+// Bump allocator over a static heap, as in plugins/upper: the host frees
+// every argument buffer after each call.
+static unsigned char heap[HEAP_SIZE];
+static unsigned int heap_top = 0;
+static unsigned int live_allocations = 0;
+
+extern "C" int allocate(int size) {
+    if (size < 0) {
+        return 0;
+    }
+    // Keep buffers 8-byte aligned so i64 and f64 slices can be read in place
+    unsigned int aligned = ((unsigned int)size + 7u) & ~7u;
+    if (aligned > HEAP_SIZE - heap_top) {
+        return 0;
+    }
+    unsigned char *ptr = heap + heap_top;
+    heap_top += aligned;
+    live_allocations++;
+    return (int)(unsigned long)ptr;
+}
+
+extern "C" void deallocate(int ptr, int size) {
+    (void)ptr;
+    (void)size;
+    if (live_allocations > 0 && --live_allocations == 0) {
+        heap_top = 0;
+    }
+}
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+// (i32 ptr, i32 len) -> i32: init() with a JSON config object. Only the
+// "offset" key is read; the buffer is freed by the host after the call.
+extern "C" int init_with_config(const char *config, int len) {
+    offset = 0;
+    static const char key[] = "\"offset\"";
+    const int key_len = sizeof(key) - 1;
+
+    for (int i = 0; i + key_len <= len; i++) {
+        int match = 1;
+        for (int j = 0; j < key_len && match; j++) {
+            match = config[i + j] == key[j];
+        }
+        if (!match) {
+            continue;
+        }
+
+        // Skip to the value: whitespace, ':', whitespace
+        int k = i + key_len;
+        while (k < len && (config[k] == ' ' || config[k] == '\t' || config[k] == '\n' || config[k] == '\r')) {
+            k++;
+        }
+        if (k >= len || config[k] != ':') {
+            continue;
+        }
+        k++;
+        while (k < len && (config[k] == ' ' || config[k] == '\t' || config[k] == '\n' || config[k] == '\r')) {
+            k++;
+        }
+
+        int sign = 1;
+        if (k < len && config[k] == '-') {
+            sign = -1;
+            k++;
+        }
+        if (k >= len || config[k] < '0' || config[k] > '9') {
+            set_error("offset must be an integer");
+            return ABI_ERROR_INVALID_INPUT;
+        }
+        int value = 0;
+        while (k < len && config[k] >= '0' && config[k] <= '9') {
+            value = value * 10 + (config[k] - '0');
+            k++;
+        }
+        offset = sign * value;
+        break;
+    }
+
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (input < 0) {
+        set_error("input must not be negative");
+        return ABI_ERROR_INVALID_INPUT;
+    }
+    return input + offset;
+}
+
+// (i32, i32) -> i32
+extern "C" int add(int a, int b) {
+    return a + b;
+}
+
+// (i64, i32, i64) -> i64
+extern "C" long long mul_add(long long a, int b, long long c) {
+    return a * b + c;
+}
+
+// (f64, f32) -> f64
+extern "C" double scale(double x, float factor) {
+    return x * factor;
+}
+
+// (i32, i32, i32) -> i32; flag is a boolean
+extern "C" int pick(int flag, int a, int b) {
+    return flag ? a : b;
+}
+
+// (i32 ptr, i32 len, i32) -> i32: occurrences of byte c in a string
+extern "C" int count_byte(const unsigned char *s, int len, int c) {
+    int n = 0;
+    for (int i = 0; i < len; i++) {
+        if (s[i] == (unsigned char)c) {
+            n++;
+        }
+    }
+    return n;
+}
+
+// (i32 ptr, i32 count) -> i64
+extern "C" long long sum_i64(const long long *xs, int count) {
+    long long sum = 0;
+    for (int i = 0; i < count; i++) {
+        sum += xs[i];
+    }
+    return sum;
+}
+
+// (i32 ptr, i32 count) -> f64; 0 for an empty slice
+extern "C" double mean_f64(const double *xs, int count) {
+    if (count <= 0) {
+        return 0;
+    }
+    double sum = 0;
+    for (int i = 0; i < count; i++) {
+        sum += xs[i];
+    }
+    return sum / count;
+}
+
+// (i32) -> i32: grows linear memory by pages and returns its previous size.
+// Traps when the host refuses to grow it, as an allocator that cannot
+// satisfy a request would abort.
+extern "C" int reserve(int pages) {
+    int previous = __builtin_wasm_memory_grow(0, pages);
+    if (previous < 0) {
+        __builtin_trap();
+    }
+    return previous;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    offset = 0;
+    error_message = 0;
+    heap_top = 0;
+    live_allocations = 0;
+    return ABI_SUCCESS;
+}
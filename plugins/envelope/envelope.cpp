@@ -0,0 +1,120 @@
+// Envelope Plugin - Example WASM plugin using the JSON payload ABI
+//
+// process_json() wraps its input document in an object recording its size:
+// {"bytes": <input length>, "input": <input>}. A null input produces no
+// output (ABI_NO_OUTPUT). process() is the identity so the plugin also
+// satisfies the core integer ABI.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -Wl,--export=allocate -Wl,--export=deallocate -Wl,--export=process_json \
+//   -O3 -o envelope.wasm envelope.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+#define ABI_ERROR_INTERNAL -4
+#define ABI_NO_OUTPUT -5
+
+#define HEAP_SIZE (1 << 20)
+
+static int initialized = 0;
+
+// Bump allocator over a static heap, as in the upper plugin
+static unsigned char heap[HEAP_SIZE];
+static unsigned int heap_top = 0;
+static unsigned int live_allocations = 0;
+
+extern "C" int allocate(int size) {
+    if (size < 0) {
+        return 0;
+    }
+    // Keep buffers 8-byte aligned
+    unsigned int aligned = ((unsigned int)size + 7u) & ~7u;
+    if (aligned > HEAP_SIZE - heap_top) {
+        return 0;
+    }
+    unsigned char *ptr = heap + heap_top;
+    heap_top += aligned;
+    live_allocations++;
+    return (int)(unsigned long)ptr;
+}
+
+extern "C" void deallocate(int ptr, int size) {
+    (void)ptr;
+    (void)size;
+    if (live_allocations > 0 && --live_allocations == 0) {
+        heap_top = 0;
+    }
+}
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    return input;
+}
+
+// append copies len bytes to dst, returning the position after them
+static unsigned char *append(unsigned char *dst, const char *src, int len) {
+    for (int i = 0; i < len; i++) {
+        *dst++ = (unsigned char)src[i];
+    }
+    return dst;
+}
+
+extern "C" long long process_json(int ptr, int len) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (len < 0) {
+        return ABI_ERROR_INVALID_INPUT;
+    }
+
+    const char *in = (const char *)(unsigned long)ptr;
+    if (len == 4 && in[0] == 'n' && in[1] == 'u' && in[2] == 'l' && in[3] == 'l') {
+        return ABI_NO_OUTPUT;
+    }
+
+    // Decimal digits of len, most significant first
+    char digits[10];
+    int ndigits = 0;
+    int rest = len;
+    do {
+        digits[ndigits++] = (char)('0' + rest % 10);
+        rest /= 10;
+    } while (rest > 0);
+
+    static const char prefix[] = "{\"bytes\":";
+    static const char middle[] = ",\"input\":";
+    int out_len = (int)(sizeof(prefix) - 1) + ndigits + (int)(sizeof(middle) - 1) + len + 1;
+    int out = allocate(out_len);
+    if (out == 0) {
+        return ABI_ERROR_INTERNAL;
+    }
+
+    unsigned char *dst = (unsigned char *)(unsigned long)out;
+    dst = append(dst, prefix, sizeof(prefix) - 1);
+    while (ndigits > 0) {
+        *dst++ = (unsigned char)digits[--ndigits];
+    }
+    dst = append(dst, middle, sizeof(middle) - 1);
+    dst = append(dst, in, len);
+    *dst = '}';
+
+    // Pack the output location: high 32 bits pointer, low 32 bits length
+    return ((long long)(unsigned int)out << 32) | (unsigned int)out_len;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    heap_top = 0;
+    live_allocations = 0;
+    return ABI_SUCCESS;
+}
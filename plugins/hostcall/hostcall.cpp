@@ -0,0 +1,85 @@
+// Hostcall Plugin - Example plugin importing host functions
+//
+// Calls back into the embedding service through functions it registers as
+// the "host" module (see runtime.HostModule): log() to report progress and
+// kv_get() to look up a value by key. process() multiplies its input by the
+// "factor" the host provides. health() reports the plugin unhealthy while
+// the host's "healthy" key is 0. on_shutdown() flushes the number of inputs
+// processed, buffered in memory, through log() as "processed N".
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -Wl,--export=health -Wl,--export=on_shutdown \
+//   -O3 -o hostcall.wasm hostcall.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INTERNAL -4
+
+// (i32 ptr, i32 len) -> (): log a UTF-8 message
+__attribute__((import_module("host"), import_name("log")))
+extern "C" void host_log(const char *message, int len);
+
+// (i32 ptr, i32 len) -> i32: value for a key, negative if there is none
+__attribute__((import_module("host"), import_name("kv_get")))
+extern "C" int host_kv_get(const char *key, int len);
+
+static int initialized = 0;
+static int processed = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    host_log("initialized", 11);
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    int factor = host_kv_get("factor", 6);
+    if (factor < 0) {
+        return ABI_ERROR_INTERNAL;
+    }
+    processed++;
+    return input * factor;
+}
+
+// Self-check called by the host after init() and periodically while idle
+extern "C" int health() {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (host_kv_get("healthy", 7) == 0) {
+        return ABI_ERROR_INTERNAL;
+    }
+    return ABI_SUCCESS;
+}
+
+// Called by the host before the instance is discarded
+extern "C" int on_shutdown() {
+    static char message[32] = "processed ";
+    int len = 10;
+
+    // Append the decimal digits of processed
+    char digits[12];
+    int n = 0;
+    unsigned int value = (unsigned int)processed;
+    do {
+        digits[n++] = (char)('0' + value % 10);
+        value /= 10;
+    } while (value > 0);
+    while (n > 0) {
+        message[len++] = digits[--n];
+    }
+
+    host_log(message, len);
+    processed = 0;
+    return ABI_SUCCESS;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
@@ -0,0 +1,60 @@
+// Logger Plugin - Example plugin using the structured logging host API
+//
+// Imports log(level, ptr, len) from the "logging" host module (see
+// runtime.NewLogModule) and logs at each level while processing:
+// process() returns its input unchanged, warns about zero, and rejects
+// negative input with an error message.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o logger.wasm logger.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+
+#define LOG_DEBUG 0
+#define LOG_INFO 1
+#define LOG_WARN 2
+#define LOG_ERROR 3
+
+// (i32 level, i32 ptr, i32 len) -> (): log a UTF-8 message
+__attribute__((import_module("logging"), import_name("log")))
+extern "C" void host_log(int level, const char *message, int len);
+
+static void log_message(int level, const char *message) {
+    int len = 0;
+    while (message[len]) {
+        len++;
+    }
+    host_log(level, message, len);
+}
+
+static int initialized = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    log_message(LOG_DEBUG, "initialized");
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (input < 0) {
+        log_message(LOG_ERROR, "input must not be negative");
+        return ABI_ERROR_INVALID_INPUT;
+    }
+    if (input == 0) {
+        log_message(LOG_WARN, "input is zero");
+    }
+    log_message(LOG_INFO, "processed input");
+    return input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
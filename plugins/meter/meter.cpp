@@ -0,0 +1,67 @@
+// Meter Plugin - Example plugin using the metrics host API
+//
+// Imports metric_incr and metric_observe from the "host" module (see
+// runtime.PluginMetrics). process() counts its calls in requests_total,
+// tagged with the parity of its input, records the input in the
+// input_value histogram, and returns its input unchanged. Negative input
+// is counted under rejected_total and rejected.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o meter.wasm meter.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+#define ABI_ERROR_INTERNAL -4
+
+#define METRIC_OK 0
+
+// (i32 ptr, i32 len, f64 value, i32 ptr, i32 len) -> i32: add value to a counter
+__attribute__((import_module("host"), import_name("metric_incr")))
+extern "C" int metric_incr(const char *name, int name_len, double value,
+                           const char *tags, int tags_len);
+
+// (i32 ptr, i32 len, f64 value, i32 ptr, i32 len) -> i32: record value in a histogram
+__attribute__((import_module("host"), import_name("metric_observe")))
+extern "C" int metric_observe(const char *name, int name_len, double value,
+                              const char *tags, int tags_len);
+
+static const char requests[] = "requests_total";
+static const char rejected[] = "rejected_total";
+static const char input_value[] = "input_value";
+static const char even[] = "parity=even";
+static const char odd[] = "parity=odd";
+
+static int initialized = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (input < 0) {
+        metric_incr(rejected, sizeof(rejected) - 1, 1, "", 0);
+        return ABI_ERROR_INVALID_INPUT;
+    }
+
+    const char *tags = input % 2 == 0 ? even : odd;
+    int tags_len = input % 2 == 0 ? sizeof(even) - 1 : sizeof(odd) - 1;
+    if (metric_incr(requests, sizeof(requests) - 1, 1, tags, tags_len) != METRIC_OK) {
+        return ABI_ERROR_INTERNAL;
+    }
+    if (metric_observe(input_value, sizeof(input_value) - 1, input, "", 0) != METRIC_OK) {
+        return ABI_ERROR_INTERNAL;
+    }
+    return input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
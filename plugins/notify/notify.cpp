@@ -0,0 +1,82 @@
+// Notify Plugin - Example side-effect plugin with no output
+//
+// process() is declared void: it records its input and returns nothing, so
+// the host reports "no output" rather than a result or an error.
+// process_bytes() returns ABI_NO_OUTPUT to say the same dynamically.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -Wl,--export=allocate -Wl,--export=deallocate -Wl,--export=process_bytes \
+//   -Wl,--export=get_last_input \
+//   -O3 -o notify.wasm notify.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_NO_OUTPUT -5
+
+#define HEAP_SIZE (1 << 16)
+
+static int initialized = 0;
+static int last_input = 0;
+
+// Bump allocator over a static heap, as in plugins/upper
+static unsigned char heap[HEAP_SIZE];
+static unsigned int heap_top = 0;
+static unsigned int live_allocations = 0;
+
+extern "C" int allocate(int size) {
+    if (size < 0) {
+        return 0;
+    }
+    unsigned int aligned = ((unsigned int)size + 7u) & ~7u;
+    if (aligned > HEAP_SIZE - heap_top) {
+        return 0;
+    }
+    unsigned char *ptr = heap + heap_top;
+    heap_top += aligned;
+    live_allocations++;
+    return (int)(unsigned long)ptr;
+}
+
+extern "C" void deallocate(int ptr, int size) {
+    (void)ptr;
+    (void)size;
+    if (live_allocations > 0 && --live_allocations == 0) {
+        heap_top = 0;
+    }
+}
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+// Side effect only: nothing to return
+extern "C" void process(int input) {
+    if (initialized) {
+        last_input = input;
+    }
+}
+
+// Records the payload length and reports that there is no output
+extern "C" long long process_bytes(int ptr, int len) {
+    (void)ptr;
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    last_input = len;
+    return ABI_NO_OUTPUT;
+}
+
+extern "C" int get_last_input() {
+    return last_input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    last_input = 0;
+    heap_top = 0;
+    live_allocations = 0;
+    return ABI_SUCCESS;
+}
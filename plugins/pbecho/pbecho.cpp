@@ -0,0 +1,192 @@
+// Protobuf Echo Plugin - Example WASM plugin using the protobuf payload ABI
+//
+// process_pb() decodes a PluginRequest (abi/abi.proto) and returns a
+// PluginResponse holding the request's fields plus "fields", the number
+// of fields it received. A request without fields produces no output
+// (ABI_NO_OUTPUT). It reads the wire format directly rather than linking
+// a protobuf library: map entries of PluginRequest.fields (field 1) are
+// copied as they are to PluginResponse.fields (also field 1).
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -Wl,--export=allocate -Wl,--export=deallocate -Wl,--export=process_pb \
+//   -O3 -o pbecho.wasm pbecho.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+#define ABI_ERROR_INTERNAL -4
+#define ABI_NO_OUTPUT -5
+
+#define HEAP_SIZE (1 << 20)
+
+static int initialized = 0;
+
+// Bump allocator over a static heap, as in the upper plugin
+static unsigned char heap[HEAP_SIZE];
+static unsigned int heap_top = 0;
+static unsigned int live_allocations = 0;
+
+extern "C" int allocate(int size) {
+    if (size < 0) {
+        return 0;
+    }
+    // Keep buffers 8-byte aligned
+    unsigned int aligned = ((unsigned int)size + 7u) & ~7u;
+    if (aligned > HEAP_SIZE - heap_top) {
+        return 0;
+    }
+    unsigned char *ptr = heap + heap_top;
+    heap_top += aligned;
+    live_allocations++;
+    return (int)(unsigned long)ptr;
+}
+
+extern "C" void deallocate(int ptr, int size) {
+    (void)ptr;
+    (void)size;
+    if (live_allocations > 0 && --live_allocations == 0) {
+        heap_top = 0;
+    }
+}
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    return input;
+}
+
+// read_varint decodes a varint at *pos, advancing it; returns 0 if the
+// input ends or the varint is longer than 64 bits
+static int read_varint(const unsigned char *in, int len, int *pos, unsigned long long *value) {
+    *value = 0;
+    for (int shift = 0; shift < 64; shift += 7) {
+        if (*pos >= len) {
+            return 0;
+        }
+        unsigned char b = in[(*pos)++];
+        *value |= (unsigned long long)(b & 0x7f) << shift;
+        if ((b & 0x80) == 0) {
+            return 1;
+        }
+    }
+    return 0;
+}
+
+// write_varint encodes value at dst, returning the position after it
+static unsigned char *write_varint(unsigned char *dst, unsigned long long value) {
+    while (value >= 0x80) {
+        *dst++ = (unsigned char)(value | 0x80);
+        value >>= 7;
+    }
+    *dst++ = (unsigned char)value;
+    return dst;
+}
+
+// varint_size returns the encoded size of value
+static int varint_size(unsigned long long value) {
+    int size = 1;
+    while (value >= 0x80) {
+        value >>= 7;
+        size++;
+    }
+    return size;
+}
+
+extern "C" long long process_pb(int ptr, int len) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (len < 0) {
+        return ABI_ERROR_INVALID_INPUT;
+    }
+
+    // Room for the copied entries and the "fields" entry
+    int out_cap = len + 32;
+    int out = allocate(out_cap);
+    if (out == 0) {
+        return ABI_ERROR_INTERNAL;
+    }
+    const unsigned char *in = (const unsigned char *)(unsigned long)ptr;
+    unsigned char *dst = (unsigned char *)(unsigned long)out;
+
+    unsigned long long count = 0;
+    int pos = 0;
+    while (pos < len) {
+        unsigned long long tag, n;
+        if (!read_varint(in, len, &pos, &tag)) {
+            return ABI_ERROR_INVALID_INPUT;
+        }
+        switch (tag & 7) {
+        case 0: // varint
+            if (!read_varint(in, len, &pos, &n)) {
+                return ABI_ERROR_INVALID_INPUT;
+            }
+            break;
+        case 1: // fixed64
+            pos += 8;
+            break;
+        case 2: // length-delimited
+            if (!read_varint(in, len, &pos, &n) || n > (unsigned long long)(len - pos)) {
+                return ABI_ERROR_INVALID_INPUT;
+            }
+            if ((tag >> 3) == 1) {
+                // A fields entry: same field number and type in the response
+                *dst++ = 0x0a;
+                dst = write_varint(dst, n);
+                for (unsigned long long i = 0; i < n; i++) {
+                    *dst++ = in[pos + i];
+                }
+                count++;
+            }
+            pos += (int)n;
+            break;
+        case 5: // fixed32
+            pos += 4;
+            break;
+        default:
+            return ABI_ERROR_INVALID_INPUT;
+        }
+        if (pos > len) {
+            return ABI_ERROR_INVALID_INPUT;
+        }
+    }
+    if (count == 0) {
+        deallocate(out, out_cap);
+        return ABI_NO_OUTPUT;
+    }
+
+    // Entry {key: "fields", value: Value{int_value: count}}
+    static const char key[] = "fields";
+    int value_len = 1 + varint_size(count);
+    int entry_len = 2 + (int)(sizeof(key) - 1) + 2 + value_len;
+    *dst++ = 0x0a;
+    dst = write_varint(dst, entry_len);
+    *dst++ = 0x0a; // key, field 1
+    *dst++ = (unsigned char)(sizeof(key) - 1);
+    for (int i = 0; i < (int)sizeof(key) - 1; i++) {
+        *dst++ = (unsigned char)key[i];
+    }
+    *dst++ = 0x12; // value, field 2
+    *dst++ = (unsigned char)value_len;
+    *dst++ = 0x10; // int_value, field 2 of Value
+    dst = write_varint(dst, count);
+
+    // Pack the output location: high 32 bits pointer, low 32 bits length
+    int out_len = (int)(dst - (unsigned char *)(unsigned long)out);
+    return ((long long)(unsigned int)out << 32) | (unsigned int)out_len;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    heap_top = 0;
+    live_allocations = 0;
+    return ABI_SUCCESS;
+}
@@ -0,0 +1,78 @@
+// Printer Plugin - Example plugin debugging with printf-style output
+//
+// Writes to stdout and stderr through WASI fd_write, without a libc:
+// process() prints its input to stdout and returns it, and complains on
+// stderr about negative input. The server returns the output of a /run
+// request with "include_output": true.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o printer.wasm printer.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+
+#define STDOUT 1
+#define STDERR 2
+
+struct iovec {
+    const char *buf;
+    unsigned long len;
+};
+
+// (i32 fd, i32 iovs, i32 iovs_len, i32 nwritten) -> i32 errno
+__attribute__((import_module("wasi_snapshot_preview1"), import_name("fd_write")))
+extern "C" int fd_write(int fd, const iovec *iovs, int iovs_len, unsigned long *nwritten);
+
+static void print(int fd, const char *message) {
+    iovec iov = {message, 0};
+    while (message[iov.len]) {
+        iov.len++;
+    }
+    unsigned long written;
+    fd_write(fd, &iov, 1, &written);
+}
+
+// print_int writes n in decimal followed by a newline.
+static void print_int(int fd, int n) {
+    char buf[16];
+    int i = sizeof(buf) - 1;
+    buf[i] = 0;
+    buf[--i] = '\n';
+    unsigned int u = n < 0 ? -(unsigned int)n : n;
+    do {
+        buf[--i] = '0' + u % 10;
+        u /= 10;
+    } while (u);
+    if (n < 0) {
+        buf[--i] = '-';
+    }
+    print(fd, buf + i);
+}
+
+static int initialized = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    print(STDOUT, "input: ");
+    print_int(STDOUT, input);
+    if (input < 0) {
+        print(STDERR, "input must not be negative\n");
+        return ABI_ERROR_INVALID_INPUT;
+    }
+    return input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
@@ -0,0 +1,45 @@
+// Progress Plugin - Example plugin using the progress host API
+//
+// Imports report_progress(percent) from the "host" module (see
+// runtime.DefineProgress). process(n) works through n items, reporting
+// its progress after each, and returns the number of items processed, so
+// the host can follow a long-running call as it happens.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o progress.wasm progress.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+
+// (i32 percent) -> (): report how much of the call's work is done
+__attribute__((import_module("host"), import_name("report_progress")))
+extern "C" void report_progress(int percent);
+
+static int initialized = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int items) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (items < 0) {
+        return ABI_ERROR_INVALID_INPUT;
+    }
+    report_progress(0);
+    for (int done = 1; done <= items; done++) {
+        report_progress(done * 100 / items);
+    }
+    return items;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
@@ -0,0 +1,82 @@
+// Relay Plugin - Example plugin using the transactional outbox host API
+//
+// Imports enqueue(kind, target, len, payload, len) from the "outbox" host
+// module (see runtime.NewOutboxModule). process() enqueues an HTTP call
+// carrying its input in decimal and a message on the "relay.processed"
+// topic, then returns its input unchanged. Negative input is rejected
+// after enqueueing, showing that the effects of a failed call are never
+// delivered.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o relay.wasm relay.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+#define ABI_ERROR_INTERNAL -4
+
+#define EFFECT_HTTP 0
+#define EFFECT_MESSAGE 1
+
+#define OUTBOX_OK 0
+
+// (i32 kind, i32 ptr, i32 len, i32 ptr, i32 len) -> i32: enqueue an effect
+__attribute__((import_module("outbox"), import_name("enqueue")))
+extern "C" int outbox_enqueue(int kind, const char *target, int target_len,
+                              const char *payload, int payload_len);
+
+static const char webhook[] = "http://hooks.internal/relay";
+static const char topic[] = "relay.processed";
+
+static int initialized = 0;
+
+// format writes n in decimal to buf and returns its length
+static int format(int n, char *buf) {
+    char digits[12];
+    unsigned int u = n < 0 ? 0u - (unsigned int)n : (unsigned int)n;
+    int len = 0;
+    do {
+        digits[len++] = (char)('0' + u % 10);
+        u /= 10;
+    } while (u > 0);
+
+    int out = 0;
+    if (n < 0) {
+        buf[out++] = '-';
+    }
+    while (len > 0) {
+        buf[out++] = digits[--len];
+    }
+    return out;
+}
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+
+    char payload[12];
+    int len = format(input, payload);
+    if (outbox_enqueue(EFFECT_HTTP, webhook, sizeof(webhook) - 1, payload, len) != OUTBOX_OK ||
+        outbox_enqueue(EFFECT_MESSAGE, topic, sizeof(topic) - 1, payload, len) != OUTBOX_OK) {
+        return ABI_ERROR_INTERNAL;
+    }
+
+    // The host discards the effects enqueued above
+    if (input < 0) {
+        return ABI_ERROR_INVALID_INPUT;
+    }
+    return input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
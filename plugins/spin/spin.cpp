@@ -0,0 +1,38 @@
+// Spin Plugin - Test plugin whose process() never returns
+//
+// Used to verify that execution timeouts interrupt a runaway plugin instead
+// of hanging the request. Not meant for production use.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o spin.wasm spin.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+
+static int initialized = 0;
+
+// volatile keeps the compiler from proving the loop has no side effects
+// and removing it
+static volatile unsigned int counter = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    for (;;) {
+        counter = counter + 1;
+    }
+    return input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
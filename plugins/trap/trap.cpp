@@ -0,0 +1,44 @@
+// Trap Plugin - Test plugin whose process() traps on request
+//
+// Used to verify that a trap fails the call with an error instead of
+// crashing the host, and that the engine stays usable afterwards. Not
+// meant for production use.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o trap.wasm trap.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+
+static int initialized = 0;
+
+// volatile keeps the compiler from folding the division away
+static volatile int divisor = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+// process(-1) executes unreachable, process(0) divides by zero; any other
+// input is returned unchanged.
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (input < 0) {
+        __builtin_trap();
+    }
+    if (input == 0) {
+        divisor = input;
+        return 1 / divisor;
+    }
+    return input;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
@@ -0,0 +1,92 @@
+// Upper Plugin - Example WASM plugin using the memory-based payload ABI
+//
+// process_bytes() returns an upper-cased copy of its input. process() is the
+// identity so the plugin also satisfies the core integer ABI.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -Wl,--export=allocate -Wl,--export=deallocate -Wl,--export=process_bytes \
+//   -O3 -o upper.wasm upper.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+#define ABI_ERROR_INTERNAL -4
+
+#define HEAP_SIZE (1 << 20)
+
+static int initialized = 0;
+
+// Bump allocator over a static heap. Without libc there is no malloc; the
+// host frees every buffer after each call, so the heap is rewound once no
+// allocations are outstanding.
+static unsigned char heap[HEAP_SIZE];
+static unsigned int heap_top = 0;
+static unsigned int live_allocations = 0;
+
+extern "C" int allocate(int size) {
+    if (size < 0) {
+        return 0;
+    }
+    // Keep buffers 8-byte aligned
+    unsigned int aligned = ((unsigned int)size + 7u) & ~7u;
+    if (aligned > HEAP_SIZE - heap_top) {
+        return 0;
+    }
+    unsigned char *ptr = heap + heap_top;
+    heap_top += aligned;
+    live_allocations++;
+    return (int)(unsigned long)ptr;
+}
+
+extern "C" void deallocate(int ptr, int size) {
+    (void)ptr;
+    (void)size;
+    if (live_allocations > 0 && --live_allocations == 0) {
+        heap_top = 0;
+    }
+}
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int input) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    return input;
+}
+
+extern "C" long long process_bytes(int ptr, int len) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (len < 0) {
+        return ABI_ERROR_INVALID_INPUT;
+    }
+
+    int out = allocate(len);
+    if (out == 0 && len > 0) {
+        return ABI_ERROR_INTERNAL;
+    }
+
+    const unsigned char *in = (const unsigned char *)(unsigned long)ptr;
+    unsigned char *dst = (unsigned char *)(unsigned long)out;
+    for (int i = 0; i < len; i++) {
+        unsigned char c = in[i];
+        dst[i] = (c >= 'a' && c <= 'z') ? (unsigned char)(c - 'a' + 'A') : c;
+    }
+
+    // Pack the output location: high 32 bits pointer, low 32 bits length
+    return ((long long)(unsigned int)out << 32) | (unsigned int)len;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    heap_top = 0;
+    live_allocations = 0;
+    return ABI_SUCCESS;
+}
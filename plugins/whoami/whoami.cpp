@@ -0,0 +1,56 @@
+// Whoami Plugin - Example plugin using the execution context host API
+//
+// Imports context(buf, cap) from the "host" module (see
+// runtime.NewContextModule) and log(level, ptr, len) from the "logging"
+// module. process(cap) fetches the call's context into a buffer of cap
+// bytes (at most 512), logs the JSON object if it fit, and returns its
+// length, so the host can see both the metadata and the retry protocol.
+//
+// Build command:
+// clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry -Wl,--allow-undefined \
+//   -Wl,--export=init -Wl,--export=process -Wl,--export=cleanup \
+//   -O3 -o whoami.wasm whoami.cpp
+
+#define ABI_SUCCESS 0
+#define ABI_ERROR_NOT_INITIALIZED -1
+#define ABI_ERROR_INVALID_INPUT -3
+
+#define LOG_INFO 1
+
+#define MAX_CONTEXT 512
+
+// (i32 ptr, i32 cap) -> i32: write the call's context as JSON, return its length
+__attribute__((import_module("host"), import_name("context")))
+extern "C" int host_context(char *buf, int cap);
+
+// (i32 level, i32 ptr, i32 len) -> (): log a UTF-8 message
+__attribute__((import_module("logging"), import_name("log")))
+extern "C" void host_log(int level, const char *message, int len);
+
+static char buffer[MAX_CONTEXT];
+static int initialized = 0;
+
+extern "C" int init() {
+    initialized = 1;
+    return ABI_SUCCESS;
+}
+
+extern "C" int process(int cap) {
+    if (!initialized) {
+        return ABI_ERROR_NOT_INITIALIZED;
+    }
+    if (cap < 0 || cap > MAX_CONTEXT) {
+        return ABI_ERROR_INVALID_INPUT;
+    }
+
+    int len = host_context(buffer, cap);
+    if (len <= cap) {
+        host_log(LOG_INFO, buffer, len);
+    }
+    return len;
+}
+
+extern "C" int cleanup() {
+    initialized = 0;
+    return ABI_SUCCESS;
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ExportABIVersion is the optional export reporting the ABI version a
+// plugin was built against; see engine.ExportABIVersion.
+const ExportABIVersion = engine.ExportABIVersion
+
+// ABIVersion is a plugin ABI version as get_abi_version() returns it; see
+// engine.ABIVersion.
+type ABIVersion = engine.ABIVersion
+
+// ABIRange is an inclusive range of ABI versions.
+type ABIRange = engine.ABIRange
+
+// SupportedABI is the range of ABI versions this runtime implements: every
+// 1.x version.
+var SupportedABI = engine.SupportedABI
+
+// ErrABIVersion is returned by LoadPlugin and its variants for a plugin
+// whose ABI version is outside SupportedABI or, with
+// LoadOptions.RequireABIVersion, that reports none.
+var ErrABIVersion = engine.ErrABIVersion
+
+// ABIVersion returns the version the plugin's get_abi_version() reported
+// when it was loaded, or zero if it does not export one.
+func (p *Plugin) ABIVersion() ABIVersion {
+	return p.abiVersion
+}
+
+// negotiateABI queries the plugin's ABI version and checks it against
+// SupportedABI as opts require. It runs before the plugin is initialized,
+// so get_abi_version() must not depend on init().
+func (p *Plugin) negotiateABI(opts LoadOptions) error {
+	if !p.HasExport(ExportABIVersion) {
+		if opts.RequireABIVersion {
+			return fmt.Errorf("%w: %s does not export %s", ErrABIVersion, p.path, ExportABIVersion)
+		}
+		return nil
+	}
+
+	result, err := p.vm.Execute(ExportABIVersion)
+	if err != nil {
+		return fmt.Errorf("failed to call %s for %s: %w", ExportABIVersion, p.path, err)
+	}
+	var version int32
+	ok := len(result) == 1
+	if ok {
+		version, ok = result[0].(int32)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s of %s must return an i32", ErrABIVersion, ExportABIVersion, p.path)
+	}
+	p.abiVersion = ABIVersion(version)
+	if !opts.AnyABIVersion && !SupportedABI.Contains(p.abiVersion) {
+		return fmt.Errorf("%w: %s implements ABI %s (%d), the runtime supports %s",
+			ErrABIVersion, p.path, p.abiVersion, version, SupportedABI)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package runtime_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("ABIVersion", func() {
+	It("should decode MAJOR * 10000 + MINOR * 100 + PATCH", func() {
+		v := runtime.ABIVersion(10203)
+		Expect(v.Major()).To(Equal(1))
+		Expect(v.Minor()).To(Equal(2))
+		Expect(v.Patch()).To(Equal(3))
+		Expect(v.String()).To(Equal("1.2.3"))
+		Expect(runtime.ABIVersion(0).String()).To(Equal("unversioned"))
+	})
+
+	// =========================================================================
+	// TEST: Supported range
+	// Why: Minor versions only add optional exports, so every 1.x plugin
+	//      must load, while a 2.x plugin expects a contract the runtime does
+	//      not implement.
+	// =========================================================================
+	DescribeTable("SupportedABI",
+		func(version int, supported bool) {
+			Expect(runtime.SupportedABI.Contains(runtime.ABIVersion(version))).To(Equal(supported))
+		},
+		Entry("1.0.0", 10000, true),
+		Entry("1.0.1", 10001, true),
+		Entry("1.99.99", 19999, true),
+		Entry("0.99.0", 9900, false),
+		Entry("2.0.0", 20000, false),
+		Entry("unversioned", 0, false),
+	)
+
+	It("should describe the range", func() {
+		Expect(runtime.SupportedABI.String()).To(Equal("1.0.0 to 1.99.99"))
+	})
+})
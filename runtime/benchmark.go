@@ -0,0 +1,196 @@
+package runtime
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Defaults of BenchmarkOptions.
+const (
+	defaultBenchmarkRounds     = 10
+	defaultBenchmarkIterations = 1000
+)
+
+// BenchmarkOptions configures Benchmark. Zero Rounds and Iterations take
+// the defaults.
+type BenchmarkOptions struct {
+	Rounds     int // Cold loads and inits to measure (default 10)
+	Iterations int // process() calls to measure (default 1000)
+	Warmup     int // process() calls before measuring, not counted
+	Input      int // Passed to process()
+
+	// Load is how the plugin is loaded, e.g. with the host modules it
+	// imports
+	Load LoadOptions
+}
+
+// LatencyStats summarizes measured durations. Percentiles are
+// nearest-rank.
+type LatencyStats struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// BenchmarkResult is what Benchmark measured. Durations marshal to JSON in
+// nanoseconds.
+type BenchmarkResult struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"` // Binary size in bytes
+	Rounds     int    `json:"rounds"`
+	Iterations int    `json:"iterations"`
+
+	Load    LatencyStats `json:"load"`    // LoadPlugin(), once per round
+	Init    LatencyStats `json:"init"`    // Init(), once per round
+	Execute LatencyStats `json:"execute"` // Execute(), once per iteration
+
+	// AllocsPerCall and BytesPerCall are the Go heap allocations the host
+	// makes per Execute(), as testing.AllocsPerRun counts them. The
+	// plugin's linear memory is not Go heap and is not counted.
+	AllocsPerCall float64 `json:"allocs_per_call"`
+	BytesPerCall  float64 `json:"bytes_per_call"`
+}
+
+// Benchmark measures the plugin at path, for tracking its performance
+// across builds:
+//   - Load and Init: each round loads the plugin cold, as an empty pool
+//     would, and calls Init()
+//   - Execute: the instance of the last round runs process() with
+//     opts.Input Iterations times, after Warmup calls
+//
+// A process() without output (ErrNoOutput) counts as a successful call;
+// any other error ends the benchmark. Measurements are taken on the
+// calling goroutine, one call at a time.
+func Benchmark(path string, opts BenchmarkOptions) (*BenchmarkResult, error) {
+	if opts.Rounds < 0 || opts.Iterations < 0 || opts.Warmup < 0 {
+		return nil, fmt.Errorf("benchmark rounds, iterations, and warmup must not be negative")
+	}
+	if opts.Rounds == 0 {
+		opts.Rounds = defaultBenchmarkRounds
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = defaultBenchmarkIterations
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin file not found: %w", err)
+	}
+
+	loads := make([]time.Duration, 0, opts.Rounds)
+	inits := make([]time.Duration, 0, opts.Rounds)
+	var plugin *Plugin
+	for i := 0; i < opts.Rounds; i++ {
+		if plugin != nil {
+			plugin.Close()
+		}
+		start := time.Now()
+		plugin, err = LoadPluginWithOptions(path, opts.Load)
+		if err != nil {
+			return nil, err
+		}
+		loaded := time.Now()
+		if err := plugin.Init(); err != nil {
+			plugin.Close()
+			return nil, err
+		}
+		loads = append(loads, loaded.Sub(start))
+		inits = append(inits, time.Since(loaded))
+	}
+	defer plugin.Close()
+
+	execute := func() error {
+		if _, err := plugin.Execute(opts.Input); err != nil && !errors.Is(err, ErrNoOutput) {
+			return err
+		}
+		return nil
+	}
+	for i := 0; i < opts.Warmup; i++ {
+		if err := execute(); err != nil {
+			return nil, err
+		}
+	}
+
+	calls := make([]time.Duration, opts.Iterations)
+	var before, after goruntime.MemStats
+	goruntime.ReadMemStats(&before)
+	for i := range calls {
+		start := time.Now()
+		if err := execute(); err != nil {
+			return nil, err
+		}
+		calls[i] = time.Since(start)
+	}
+	goruntime.ReadMemStats(&after)
+	if err := plugin.Cleanup(); err != nil {
+		return nil, err
+	}
+
+	return &BenchmarkResult{
+		Path:          path,
+		Size:          info.Size(),
+		Rounds:        opts.Rounds,
+		Iterations:    opts.Iterations,
+		Load:          newLatencyStats(loads),
+		Init:          newLatencyStats(inits),
+		Execute:       newLatencyStats(calls),
+		AllocsPerCall: float64(after.Mallocs-before.Mallocs) / float64(opts.Iterations),
+		BytesPerCall:  float64(after.TotalAlloc-before.TotalAlloc) / float64(opts.Iterations),
+	}, nil
+}
+
+// newLatencyStats summarizes samples, reordering them.
+func newLatencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	var total time.Duration
+	for _, sample := range samples {
+		total += sample
+	}
+	at := func(q float64) time.Duration {
+		return samples[max(int(math.Ceil(q*float64(len(samples))))-1, 0)]
+	}
+	return LatencyStats{
+		Min:  samples[0],
+		Mean: total / time.Duration(len(samples)),
+		P50:  at(0.5),
+		P95:  at(0.95),
+		P99:  at(0.99),
+		Max:  samples[len(samples)-1],
+	}
+}
+
+// WriteCSV writes r as a header and one row, durations in nanoseconds.
+// Runs appended to one file without their headers make a history to
+// track regressions in.
+func (r *BenchmarkResult) WriteCSV(w io.Writer) error {
+	stats := []string{"min", "mean", "p50", "p95", "p99", "max"}
+	header := []string{"path", "size", "rounds", "iterations"}
+	row := []string{r.Path, strconv.FormatInt(r.Size, 10), strconv.Itoa(r.Rounds), strconv.Itoa(r.Iterations)}
+	for _, measured := range []struct {
+		name  string
+		stats LatencyStats
+	}{{"load", r.Load}, {"init", r.Init}, {"execute", r.Execute}} {
+		s := measured.stats
+		for i, d := range []time.Duration{s.Min, s.Mean, s.P50, s.P95, s.P99, s.Max} {
+			header = append(header, measured.name+"_"+stats[i]+"_ns")
+			row = append(row, strconv.FormatInt(d.Nanoseconds(), 10))
+		}
+	}
+	header = append(header, "allocs_per_call", "bytes_per_call")
+	row = append(row, strconv.FormatFloat(r.AllocsPerCall, 'f', -1, 64), strconv.FormatFloat(r.BytesPerCall, 'f', -1, 64))
+
+	return csv.NewWriter(w).WriteAll([][]string{header, row})
+}
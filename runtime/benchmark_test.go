@@ -0,0 +1,79 @@
+package runtime_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Benchmark", func() {
+	It("should fail for a missing plugin", func() {
+		_, err := runtime.Benchmark(filepath.Join(GinkgoT().TempDir(), "missing.wasm"), runtime.BenchmarkOptions{})
+		Expect(err).To(MatchError(ContainSubstring("plugin file not found")))
+
+		_, err = runtime.Benchmark("plugin.wasm", runtime.BenchmarkOptions{Iterations: -1})
+		Expect(err).To(MatchError(ContainSubstring("must not be negative")))
+	})
+
+	// =========================================================================
+	// TEST: Benchmark results
+	// Why: Regression tracking compares results across builds; each phase
+	//      must be measured on its own and written in a stable format.
+	// =========================================================================
+	It("should measure load, init, and execute", func() {
+		path := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+
+		result, err := runtime.Benchmark(path, runtime.BenchmarkOptions{Rounds: 3, Iterations: 50, Warmup: 5, Input: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Size).To(Equal(info.Size()))
+		Expect(result.Rounds).To(Equal(3))
+		Expect(result.Iterations).To(Equal(50))
+		for _, stats := range []runtime.LatencyStats{result.Load, result.Init, result.Execute} {
+			Expect(stats.Min).To(BeNumerically(">", 0))
+			Expect(stats.Min).To(BeNumerically("<=", stats.P50))
+			Expect(stats.P50).To(BeNumerically("<=", stats.P99))
+			Expect(stats.P99).To(BeNumerically("<=", stats.Max))
+		}
+		Expect(result.AllocsPerCall).To(BeNumerically(">=", 0))
+	})
+
+	It("should write results as CSV", func() {
+		result := &runtime.BenchmarkResult{
+			Path:          "hello.wasm",
+			Size:          1024,
+			Rounds:        10,
+			Iterations:    1000,
+			Load:          runtime.LatencyStats{Min: time.Millisecond, Mean: 2 * time.Millisecond, P50: 2 * time.Millisecond, P95: 3 * time.Millisecond, P99: 4 * time.Millisecond, Max: 5 * time.Millisecond},
+			Execute:       runtime.LatencyStats{Min: 800, Max: 1500},
+			AllocsPerCall: 4.5,
+			BytesPerCall:  128,
+		}
+		var out bytes.Buffer
+		Expect(result.WriteCSV(&out)).To(Succeed())
+
+		records, err := csv.NewReader(&out).ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = records[1][i]
+		}
+		Expect(row).To(HaveLen(24))
+		Expect(row).To(HaveKeyWithValue("path", "hello.wasm"))
+		Expect(row).To(HaveKeyWithValue("load_p99_ns", "4000000"))
+		Expect(row).To(HaveKeyWithValue("init_mean_ns", "0"))
+		Expect(row).To(HaveKeyWithValue("execute_max_ns", "1500"))
+		Expect(row).To(HaveKeyWithValue("allocs_per_call", "4.5"))
+	})
+})
@@ -0,0 +1,263 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// BlobModule is the import module of the object storage host API. Like
+// context(), its functions live in the "host" module:
+//
+//	__attribute__((import_module("host"), import_name("blob_get")))
+//	extern "C" int blob_get(const char *key, int key_len, char *buf, int cap);
+//
+//	__attribute__((import_module("host"), import_name("blob_put")))
+//	extern "C" int blob_put(const char *key, int key_len,
+//	                        const char *data, int data_len);
+//
+// blob_get writes the object at key to buf and returns its size. If the
+// object is larger than cap, nothing is written and the plugin can retry
+// with a buffer of the returned size. blob_put stores data at key,
+// replacing any object there, and returns BlobOK.
+//
+// Keys are relative to the host's blob prefix, and a plugin may only use
+// keys under the prefixes its manifest declares in blobs.read and
+// blobs.write.
+//
+// Add the functions to a module with Blobs.Define.
+const BlobModule = "host"
+
+// Result codes blob_get() and blob_put() return to the plugin instead of a
+// size.
+const (
+	BlobOK          int32 = 0
+	BlobUnavailable int32 = -1 // The host has no blob store
+	BlobDenied      int32 = -2 // Invalid key, or not under a prefix the manifest declares
+	BlobNotFound    int32 = -3 // blob_get() of a key without an object
+	BlobTooLarge    int32 = -4 // Over the manifest's or the host's size cap
+	BlobFailed      int32 = -5 // The object store returned an error
+)
+
+// ErrBlobNotFound is returned for a key without an object. BlobStore
+// implementations wrap it.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// ErrBlobDenied is returned for a key a plugin may not use.
+var ErrBlobDenied = errors.New("blob access denied")
+
+// ErrBlobTooLarge is returned for an object over the size caps.
+var ErrBlobTooLarge = errors.New("blob too large")
+
+// maxBlobKey bounds the length of a key, as S3 does.
+const maxBlobKey = 1024
+
+// DefaultMaxBlobBytes is the host's size cap of BlobOptions when none is
+// given.
+const DefaultMaxBlobBytes = 64 << 20
+
+// BlobStore is the object storage behind blob_get() and blob_put(), e.g.
+// an S3 or GCS bucket. Implementations must be safe for concurrent use.
+type BlobStore interface {
+	// Open returns the object at key and its size, or an error wrapping
+	// ErrBlobNotFound. The size is -1 if the store does not know it.
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+
+	// Put stores data at key, replacing any object there.
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// BlobOptions configures Blobs.
+type BlobOptions struct {
+	// Prefix is prepended to every key plugins use, e.g. "plugin-data/",
+	// so they cannot reach the rest of the bucket.
+	Prefix string
+
+	// MaxGetBytes and MaxPutBytes cap the objects any plugin reads and
+	// writes; a manifest can only lower them. Zero uses
+	// DefaultMaxBlobBytes.
+	MaxGetBytes int64
+	MaxPutBytes int64
+}
+
+// Blobs serves blob_get() and blob_put() from a BlobStore, limited per
+// plugin by the blobs section of its manifest. It is safe for concurrent
+// use.
+type Blobs struct {
+	store BlobStore
+	opts  BlobOptions
+}
+
+// NewBlobs creates the object storage API over store.
+func NewBlobs(store BlobStore, opts BlobOptions) *Blobs {
+	if opts.MaxGetBytes <= 0 {
+		opts.MaxGetBytes = DefaultMaxBlobBytes
+	}
+	if opts.MaxPutBytes <= 0 {
+		opts.MaxPutBytes = DefaultMaxBlobBytes
+	}
+	return &Blobs{store: store, opts: opts}
+}
+
+// Define adds blob_get and blob_put to module, serving them from b, and
+// returns module. A nil b defines functions that return BlobUnavailable,
+// so plugins importing them load on hosts without a blob store. Denied
+// keys, missing objects, and store errors are reported to the plugin as
+// result codes rather than traps.
+func (b *Blobs) Define(module *HostModule) *HostModule {
+	code := func(err error) []interface{} {
+		switch {
+		case errors.Is(err, ErrBlobDenied):
+			return []interface{}{BlobDenied}
+		case errors.Is(err, ErrBlobNotFound):
+			return []interface{}{BlobNotFound}
+		case errors.Is(err, ErrBlobTooLarge):
+			return []interface{}{BlobTooLarge}
+		default:
+			return []interface{}{BlobFailed}
+		}
+	}
+
+	return module.
+		Func("blob_get", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				if b == nil {
+					return []interface{}{BlobUnavailable}, nil
+				}
+				if args[1].(int32) > maxBlobKey {
+					return []interface{}{BlobDenied}, nil
+				}
+				key, err := call.ReadString(args[0].(int32), args[1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				data, size, err := b.Get(call.Context(), call.Manifest(), key, int64(args[3].(int32)))
+				if err != nil {
+					return code(err), nil
+				}
+				if data != nil {
+					if err := call.Write(args[2].(int32), data); err != nil {
+						return nil, err
+					}
+				}
+				return []interface{}{int32(size)}, nil
+			}).
+		Func("blob_put", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				if b == nil {
+					return []interface{}{BlobUnavailable}, nil
+				}
+				if args[1].(int32) > maxBlobKey {
+					return []interface{}{BlobDenied}, nil
+				}
+				key, err := call.ReadString(args[0].(int32), args[1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				// Checked before copying the data out of the plugin's memory
+				if int64(args[3].(int32)) > b.maxPut(call.Manifest()) {
+					return []interface{}{BlobTooLarge}, nil
+				}
+				data, err := call.Read(args[2].(int32), args[3].(int32))
+				if err != nil {
+					return nil, err
+				}
+				if err := b.Put(call.Context(), call.Manifest(), key, data); err != nil {
+					return code(err), nil
+				}
+				return []interface{}{BlobOK}, nil
+			})
+}
+
+// Get reads the object at key for a plugin with manifest m, which may be
+// nil. If the object is larger than limit, Get returns its size without
+// the data, as blob_get() does for a short buffer.
+func (b *Blobs) Get(ctx context.Context, m *manifest.Manifest, key string, limit int64) (data []byte, size int64, err error) {
+	var read []string
+	if m != nil {
+		read = m.Blobs.Read
+	}
+	if err := checkBlobKey(key, read); err != nil {
+		return nil, 0, err
+	}
+	maxGet := b.opts.MaxGetBytes
+	if m != nil && m.Blobs.MaxGetBytes > 0 {
+		maxGet = min(maxGet, m.Blobs.MaxGetBytes)
+	}
+
+	body, size, err := b.store.Open(ctx, b.opts.Prefix+key)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer body.Close()
+	limit = max(limit, 0)
+	switch {
+	case size > maxGet:
+		return nil, 0, fmt.Errorf("%w: %s is %d bytes, over the cap of %d", ErrBlobTooLarge, key, size, maxGet)
+	case size > limit:
+		return nil, size, nil
+	}
+
+	// The size is the store's claim, or unknown; read no more than the cap
+	data, err = io.ReadAll(io.LimitReader(body, maxGet+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	switch {
+	case int64(len(data)) > maxGet:
+		return nil, 0, fmt.Errorf("%w: %s is over the cap of %d bytes", ErrBlobTooLarge, key, maxGet)
+	case int64(len(data)) > limit:
+		return nil, int64(len(data)), nil
+	}
+	return data, int64(len(data)), nil
+}
+
+// Put stores data at key for a plugin with manifest m, which may be nil,
+// or only records it in a call made with WithoutSideEffects.
+func (b *Blobs) Put(ctx context.Context, m *manifest.Manifest, key string, data []byte) error {
+	var write []string
+	if m != nil {
+		write = m.Blobs.Write
+	}
+	if err := checkBlobKey(key, write); err != nil {
+		return err
+	}
+	if maxPut := b.maxPut(m); int64(len(data)) > maxPut {
+		return fmt.Errorf("%w: %d bytes for %s, over the cap of %d", ErrBlobTooLarge, len(data), key, maxPut)
+	}
+	if skipEffect(ctx, "blob_put", key) {
+		return nil
+	}
+	return b.store.Put(ctx, b.opts.Prefix+key, data)
+}
+
+// maxPut returns the largest object a plugin with manifest m may write.
+func (b *Blobs) maxPut(m *manifest.Manifest) int64 {
+	if m != nil && m.Blobs.MaxPutBytes > 0 {
+		return min(b.opts.MaxPutBytes, m.Blobs.MaxPutBytes)
+	}
+	return b.opts.MaxPutBytes
+}
+
+// checkBlobKey checks that key is a relative key without "." or ".."
+// segments under one of prefixes.
+func checkBlobKey(key string, prefixes []string) error {
+	if key == "" || len(key) > maxBlobKey || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("%w: invalid key %q", ErrBlobDenied, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("%w: invalid key %q", ErrBlobDenied, key)
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not under a prefix the plugin declares", ErrBlobDenied, key)
+}
@@ -0,0 +1,139 @@
+package runtime_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// memoryBlobs is an in-memory BlobStore that can hide object sizes, as
+// stores serving chunked responses do.
+type memoryBlobs struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	unknownLen bool
+}
+
+func (m *memoryBlobs) Open(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", runtime.ErrBlobNotFound, key)
+	}
+	size := int64(len(data))
+	if m.unknownLen {
+		size = -1
+	}
+	return io.NopCloser(bytes.NewReader(data)), size, nil
+}
+
+func (m *memoryBlobs) Put(_ context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+var _ = Describe("Blobs", func() {
+	var (
+		store *memoryBlobs
+		blobs *runtime.Blobs
+		m     *manifest.Manifest
+		ctx   context.Context
+	)
+
+	BeforeEach(func() {
+		store = &memoryBlobs{objects: map[string][]byte{
+			"data/inputs/a.csv":  []byte("a,b,c"),
+			"data/secrets/token": []byte("hunter2"),
+		}}
+		blobs = runtime.NewBlobs(store, runtime.BlobOptions{Prefix: "data/", MaxGetBytes: 1024, MaxPutBytes: 1024})
+		m = &manifest.Manifest{Name: "upper", Blobs: manifest.Blobs{
+			Read:  []string{"inputs/", "outputs/upper/"},
+			Write: []string{"outputs/upper/"},
+		}}
+		ctx = context.Background()
+	})
+
+	// =========================================================================
+	// TEST: Per-plugin prefixes
+	// Why: The bucket is shared by every plugin; one must not read or
+	//      overwrite keys its manifest does not declare, nor escape the
+	//      host's prefix with ".." segments.
+	// =========================================================================
+	It("should only allow keys under the declared prefixes", func() {
+		data, size, err := blobs.Get(ctx, m, "inputs/a.csv", 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("a,b,c"))
+		Expect(size).To(Equal(int64(5)))
+
+		_, _, err = blobs.Get(ctx, m, "secrets/token", 100)
+		Expect(errors.Is(err, runtime.ErrBlobDenied)).To(BeTrue())
+		_, _, err = blobs.Get(ctx, m, "inputs/../secrets/token", 100)
+		Expect(errors.Is(err, runtime.ErrBlobDenied)).To(BeTrue())
+
+		// Read-only prefixes cannot be written
+		Expect(errors.Is(blobs.Put(ctx, m, "inputs/a.csv", []byte("x")), runtime.ErrBlobDenied)).To(BeTrue())
+		Expect(blobs.Put(ctx, m, "outputs/upper/a.csv", []byte("A,B,C"))).To(Succeed())
+		Expect(store.objects).To(HaveKeyWithValue("data/outputs/upper/a.csv", []byte("A,B,C")))
+	})
+
+	It("should deny everything to plugins without a manifest", func() {
+		_, _, err := blobs.Get(ctx, nil, "inputs/a.csv", 100)
+		Expect(errors.Is(err, runtime.ErrBlobDenied)).To(BeTrue())
+		Expect(errors.Is(blobs.Put(ctx, nil, "outputs/upper/a.csv", nil), runtime.ErrBlobDenied)).To(BeTrue())
+	})
+
+	It("should report missing objects", func() {
+		_, _, err := blobs.Get(ctx, m, "inputs/missing.csv", 100)
+		Expect(errors.Is(err, runtime.ErrBlobNotFound)).To(BeTrue())
+	})
+
+	It("should return only the size of an object larger than the buffer", func() {
+		data, size, err := blobs.Get(ctx, m, "inputs/a.csv", 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+		Expect(size).To(Equal(int64(5)))
+
+		store.unknownLen = true
+		data, size, err = blobs.Get(ctx, m, "inputs/a.csv", 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+		Expect(size).To(Equal(int64(5)))
+	})
+
+	// =========================================================================
+	// TEST: Size caps
+	// Why: A manifest may lower the host's caps but never raise them, and a
+	//      store that does not report sizes must still not be read past the
+	//      cap.
+	// =========================================================================
+	It("should enforce the lower of the host's and the manifest's caps", func() {
+		m.Blobs.MaxGetBytes = 4
+		_, _, err := blobs.Get(ctx, m, "inputs/a.csv", 100)
+		Expect(errors.Is(err, runtime.ErrBlobTooLarge)).To(BeTrue())
+		store.unknownLen = true
+		_, _, err = blobs.Get(ctx, m, "inputs/a.csv", 100)
+		Expect(errors.Is(err, runtime.ErrBlobTooLarge)).To(BeTrue())
+
+		m.Blobs.MaxPutBytes = 1 << 20
+		err = blobs.Put(ctx, m, "outputs/upper/big", make([]byte, 2048))
+		Expect(errors.Is(err, runtime.ErrBlobTooLarge)).To(BeTrue())
+	})
+
+	It("should define blob_get and blob_put, even without a store", func() {
+		Expect(blobs.Define(runtime.NewHostModule(runtime.BlobModule)).Functions()).To(Equal([]string{"blob_get", "blob_put"}))
+		var unavailable *runtime.Blobs
+		Expect(unavailable.Define(runtime.NewHostModule(runtime.BlobModule)).Functions()).To(Equal([]string{"blob_get", "blob_put"}))
+	})
+})
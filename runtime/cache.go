@@ -0,0 +1,337 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheModule is the import module of the cache host API. Like context(),
+// its functions live in the "host" module:
+//
+//	__attribute__((import_module("host"), import_name("cache_get")))
+//	extern "C" int cache_get(const char *key, int key_len, char *buf, int cap);
+//
+//	__attribute__((import_module("host"), import_name("cache_set")))
+//	extern "C" int cache_set(const char *key, int key_len,
+//	                         const char *value, int value_len, int ttl_ms);
+//
+// cache_get() writes the value cached at key to buf and returns its length.
+// If the value is longer than cap, nothing is written and the plugin can
+// retry with a buffer of the returned length. cache_set() caches value at
+// key for ttl_ms milliseconds and returns CacheOK.
+//
+// The cache is for memoizing: an entry may be evicted before its TTL runs
+// out, and every plugin has its own keys for each tenant, shared by all its
+// instances and versions.
+//
+// Add the functions to a module with Cache.Define.
+const CacheModule = "host"
+
+// Result codes cache_get() and cache_set() return to the plugin instead of
+// a length.
+const (
+	CacheOK          int32 = 0
+	CacheUnavailable int32 = -1 // The host has no cache
+	CacheMiss        int32 = -2 // cache_get() of a key without a live entry
+	CacheInvalid     int32 = -3 // Empty or overlong key, or a TTL that is not positive
+	CacheTooLarge    int32 = -4 // The value is over the value limit or the plugin's quota
+	CacheFailed      int32 = -5 // The cache store returned an error
+)
+
+// ErrCacheMiss is returned for a key without a live entry.
+var ErrCacheMiss = errors.New("cache miss")
+
+// ErrCacheInvalid is returned for an invalid key or TTL.
+var ErrCacheInvalid = errors.New("invalid cache entry")
+
+// ErrCacheTooLarge is returned for a value over the value limit or the
+// plugin's quota. CacheStore implementations wrap it.
+var ErrCacheTooLarge = errors.New("cache entry too large")
+
+// maxCacheKey bounds the length of a key.
+const maxCacheKey = 512
+
+// Defaults of CacheOptions.
+const (
+	DefaultCacheMaxValueBytes = 1 << 20
+	DefaultCacheMaxTTL        = time.Hour
+	DefaultCacheQuotaBytes    = 16 << 20
+)
+
+// CacheStore holds cache entries, e.g. in memory or in Redis. Entries are
+// grouped by namespace, the plugin name under the call's tenant (see
+// Cache.Get). Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the live entry at key, or an error wrapping ErrCacheMiss.
+	Get(ctx context.Context, namespace, key string) ([]byte, error)
+
+	// Set caches value at key for ttl.
+	Set(ctx context.Context, namespace, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheOptions configures Cache.
+type CacheOptions struct {
+	// MaxValueBytes caps a single value; zero uses
+	// DefaultCacheMaxValueBytes.
+	MaxValueBytes int
+
+	// MaxTTL caps the TTL plugins ask for; longer TTLs are shortened to it.
+	// Zero uses DefaultCacheMaxTTL.
+	MaxTTL time.Duration
+}
+
+// Cache serves cache_get() and cache_set() from a CacheStore, in a
+// namespace per tenant and plugin. It is safe for concurrent use.
+type Cache struct {
+	store CacheStore
+	opts  CacheOptions
+}
+
+// NewCache creates the cache API over store.
+func NewCache(store CacheStore, opts CacheOptions) *Cache {
+	if opts.MaxValueBytes <= 0 {
+		opts.MaxValueBytes = DefaultCacheMaxValueBytes
+	}
+	if opts.MaxTTL <= 0 {
+		opts.MaxTTL = DefaultCacheMaxTTL
+	}
+	return &Cache{store: store, opts: opts}
+}
+
+// Get returns the value plugin cached at key, for the tenant of ctx's
+// CallInfo. Tenants running plugins of the same name have namespaces of
+// their own, "<tenant>/<plugin>" with the tenant query-escaped, so they neither see each other's entries
+// nor evict them; calls without a tenant use the plugin name.
+func (c *Cache) Get(ctx context.Context, plugin, key string) ([]byte, error) {
+	if key == "" || len(key) > maxCacheKey {
+		return nil, fmt.Errorf("%w: invalid key %q", ErrCacheInvalid, key)
+	}
+	return c.store.Get(ctx, cacheNamespace(ctx, plugin), key)
+}
+
+// Set caches value at key for plugin for ttl, shortened to the maximum
+// TTL, in the namespace Get reads. In a call made with WithoutSideEffects
+// it only records the key.
+func (c *Cache) Set(ctx context.Context, plugin, key string, value []byte, ttl time.Duration) error {
+	switch {
+	case key == "" || len(key) > maxCacheKey:
+		return fmt.Errorf("%w: invalid key %q", ErrCacheInvalid, key)
+	case ttl <= 0:
+		return fmt.Errorf("%w: TTL must be positive", ErrCacheInvalid)
+	case len(value) > c.opts.MaxValueBytes:
+		return fmt.Errorf("%w: %d bytes, over the limit of %d", ErrCacheTooLarge, len(value), c.opts.MaxValueBytes)
+	}
+	if skipEffect(ctx, "cache_set", key) {
+		return nil
+	}
+	return c.store.Set(ctx, cacheNamespace(ctx, plugin), key, value, min(ttl, c.opts.MaxTTL))
+}
+
+// cacheNamespace returns the namespace of plugin's entries in calls made
+// with ctx. The tenant is query-escaped, so no tenant name makes its
+// namespace that of another tenant or plugin, even as part of a Redis key.
+func cacheNamespace(ctx context.Context, plugin string) string {
+	if tenant := CallInfoFrom(ctx).Tenant; tenant != "" {
+		return url.QueryEscape(tenant) + "/" + plugin
+	}
+	return plugin
+}
+
+// Define adds cache_get and cache_set to module, serving them from c, and
+// returns module. A nil c defines functions that return CacheUnavailable,
+// so plugins importing them load on hosts without a cache. Misses, invalid
+// entries, and store errors are reported to the plugin as result codes
+// rather than traps.
+func (c *Cache) Define(module *HostModule) *HostModule {
+	code := func(err error) []interface{} {
+		switch {
+		case errors.Is(err, ErrCacheMiss):
+			return []interface{}{CacheMiss}
+		case errors.Is(err, ErrCacheInvalid):
+			return []interface{}{CacheInvalid}
+		case errors.Is(err, ErrCacheTooLarge):
+			return []interface{}{CacheTooLarge}
+		default:
+			return []interface{}{CacheFailed}
+		}
+	}
+
+	return module.
+		Func("cache_get", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				if c == nil {
+					return []interface{}{CacheUnavailable}, nil
+				}
+				if args[1].(int32) > maxCacheKey {
+					return []interface{}{CacheInvalid}, nil
+				}
+				key, err := call.ReadString(args[0].(int32), args[1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				plugin := strings.TrimSuffix(filepath.Base(call.Path()), ".wasm")
+				value, err := c.Get(call.Context(), plugin, key)
+				if err != nil {
+					return code(err), nil
+				}
+				if int32(len(value)) <= args[3].(int32) {
+					if err := call.Write(args[2].(int32), value); err != nil {
+						return nil, err
+					}
+				}
+				return []interface{}{int32(len(value))}, nil
+			}).
+		Func("cache_set", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				if c == nil {
+					return []interface{}{CacheUnavailable}, nil
+				}
+				if args[1].(int32) > maxCacheKey {
+					return []interface{}{CacheInvalid}, nil
+				}
+				// Checked before copying the value out of the plugin's memory
+				if int(args[3].(int32)) > c.opts.MaxValueBytes {
+					return []interface{}{CacheTooLarge}, nil
+				}
+				key, err := call.ReadString(args[0].(int32), args[1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				value, err := call.Read(args[2].(int32), args[3].(int32))
+				if err != nil {
+					return nil, err
+				}
+				plugin := strings.TrimSuffix(filepath.Base(call.Path()), ".wasm")
+				ttl := time.Duration(args[4].(int32)) * time.Millisecond
+				if err := c.Set(call.Context(), plugin, key, value, ttl); err != nil {
+					return code(err), nil
+				}
+				return []interface{}{CacheOK}, nil
+			})
+}
+
+// MemoryCache is a CacheStore in the host's memory. Each namespace holds
+// at most a quota of bytes of keys and values; storing past it evicts the
+// namespace's expired entries, then those closest to expiring, so one
+// plugin filling its quota never evicts another's entries, nor one tenant
+// another's. It is safe for concurrent use.
+type MemoryCache struct {
+	quota int64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	spaces map[string]*cacheSpace
+}
+
+// cacheSpace is the entries of one namespace.
+type cacheSpace struct {
+	entries map[string]cacheEntry
+	bytes   int64
+}
+
+// cacheEntry is a cached value and when it expires.
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates an in-memory cache holding up to quota bytes per
+// namespace, or DefaultCacheQuotaBytes if quota is zero.
+func NewMemoryCache(quota int64) *MemoryCache {
+	if quota <= 0 {
+		quota = DefaultCacheQuotaBytes
+	}
+	return &MemoryCache{quota: quota, now: time.Now, spaces: make(map[string]*cacheSpace)}
+}
+
+// Get returns the live entry at key.
+func (m *MemoryCache) Get(_ context.Context, namespace, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	space := m.spaces[namespace]
+	if space == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCacheMiss, key)
+	}
+	entry, ok := space.entries[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCacheMiss, key)
+	}
+	if !m.now().Before(entry.expires) {
+		space.remove(key)
+		return nil, fmt.Errorf("%w: %s", ErrCacheMiss, key)
+	}
+	return entry.value, nil
+}
+
+// Set caches a copy of value at key for ttl, evicting entries of the
+// namespace to stay within the quota.
+func (m *MemoryCache) Set(_ context.Context, namespace, key string, value []byte, ttl time.Duration) error {
+	size := int64(len(key) + len(value))
+	if size > m.quota {
+		return fmt.Errorf("%w: %d bytes, over the quota of %d", ErrCacheTooLarge, size, m.quota)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	space := m.spaces[namespace]
+	if space == nil {
+		space = &cacheSpace{entries: make(map[string]cacheEntry)}
+		m.spaces[namespace] = space
+	}
+	space.remove(key)
+	now := m.now()
+	if space.bytes+size > m.quota {
+		space.evict(now, m.quota-size)
+	}
+	space.entries[key] = cacheEntry{value: append([]byte(nil), value...), expires: now.Add(ttl)}
+	space.bytes += size
+	return nil
+}
+
+// Size returns the number of bytes namespace holds, expired entries
+// included until they are evicted.
+func (m *MemoryCache) Size(namespace string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if space := m.spaces[namespace]; space != nil {
+		return space.bytes
+	}
+	return 0
+}
+
+// remove deletes the entry at key, if any.
+func (s *cacheSpace) remove(key string) {
+	if entry, ok := s.entries[key]; ok {
+		s.bytes -= int64(len(key) + len(entry.value))
+		delete(s.entries, key)
+	}
+}
+
+// evict removes expired entries, then those expiring first, until the
+// space holds at most limit bytes.
+func (s *cacheSpace) evict(now time.Time, limit int64) {
+	keys := make([]string, 0, len(s.entries))
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			s.remove(key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return s.entries[keys[i]].expires.Before(s.entries[keys[j]].expires)
+	})
+	for _, key := range keys {
+		if s.bytes <= limit {
+			return
+		}
+		s.remove(key)
+	}
+}
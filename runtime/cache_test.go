@@ -0,0 +1,118 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		store *runtime.MemoryCache
+		cache *runtime.Cache
+		ctx   context.Context
+	)
+
+	BeforeEach(func() {
+		store = runtime.NewMemoryCache(100)
+		cache = runtime.NewCache(store, runtime.CacheOptions{MaxValueBytes: 50, MaxTTL: time.Minute})
+		ctx = context.Background()
+	})
+
+	// =========================================================================
+	// TEST: Per-plugin namespaces
+	// Why: Plugins memoize under keys of their own choosing; two plugins
+	//      using the same key must never see each other's values.
+	// =========================================================================
+	It("should keep each plugin's entries apart", func() {
+		Expect(cache.Set(ctx, "enrich", "rate:EUR", []byte("1.08"), time.Minute)).To(Succeed())
+		Expect(cache.Get(ctx, "enrich", "rate:EUR")).To(Equal([]byte("1.08")))
+
+		_, err := cache.Get(ctx, "audit", "rate:EUR")
+		Expect(errors.Is(err, runtime.ErrCacheMiss)).To(BeTrue())
+	})
+
+	It("should keep each tenant's entries of a plugin apart", func() {
+		acme := runtime.WithCallInfo(ctx, runtime.CallInfo{Tenant: "acme"})
+		globex := runtime.WithCallInfo(ctx, runtime.CallInfo{Tenant: "globex"})
+		Expect(cache.Set(acme, "enrich", "rate:EUR", []byte("1.08"), time.Minute)).To(Succeed())
+		Expect(cache.Get(acme, "enrich", "rate:EUR")).To(Equal([]byte("1.08")))
+
+		_, err := cache.Get(globex, "enrich", "rate:EUR")
+		Expect(errors.Is(err, runtime.ErrCacheMiss)).To(BeTrue())
+		_, err = cache.Get(ctx, "enrich", "rate:EUR")
+		Expect(errors.Is(err, runtime.ErrCacheMiss)).To(BeTrue())
+	})
+
+	It("should expire entries after their TTL", func() {
+		Expect(cache.Set(ctx, "enrich", "short", []byte("x"), 20*time.Millisecond)).To(Succeed())
+		Expect(cache.Get(ctx, "enrich", "short")).To(Equal([]byte("x")))
+
+		Eventually(func() error {
+			_, err := cache.Get(ctx, "enrich", "short")
+			return err
+		}).Should(MatchError(runtime.ErrCacheMiss))
+		Expect(store.Size("enrich")).To(BeZero())
+	})
+
+	It("should reject invalid keys, TTLs, and oversized values", func() {
+		err := cache.Set(ctx, "enrich", "", []byte("x"), time.Minute)
+		Expect(errors.Is(err, runtime.ErrCacheInvalid)).To(BeTrue())
+		err = cache.Set(ctx, "enrich", strings.Repeat("k", 513), []byte("x"), time.Minute)
+		Expect(errors.Is(err, runtime.ErrCacheInvalid)).To(BeTrue())
+		err = cache.Set(ctx, "enrich", "key", []byte("x"), 0)
+		Expect(errors.Is(err, runtime.ErrCacheInvalid)).To(BeTrue())
+		err = cache.Set(ctx, "enrich", "key", make([]byte, 51), time.Minute)
+		Expect(errors.Is(err, runtime.ErrCacheTooLarge)).To(BeTrue())
+	})
+
+	// =========================================================================
+	// TEST: Quotas
+	// Why: A plugin caching aggressively must only evict its own entries,
+	//      starting with those closest to expiring, never another plugin's.
+	// =========================================================================
+	It("should evict within a plugin's quota", func() {
+		value := make([]byte, 38) // 40 bytes with its key
+		Expect(cache.Set(ctx, "audit", "a1", value, time.Minute)).To(Succeed())
+		Expect(cache.Set(ctx, "enrich", "e1", value, time.Minute)).To(Succeed())
+		Expect(cache.Set(ctx, "enrich", "e2", value, 30*time.Second)).To(Succeed())
+		Expect(cache.Set(ctx, "enrich", "e3", value, time.Minute)).To(Succeed())
+
+		_, err := cache.Get(ctx, "enrich", "e2")
+		Expect(errors.Is(err, runtime.ErrCacheMiss)).To(BeTrue())
+		Expect(cache.Get(ctx, "enrich", "e1")).To(HaveLen(38))
+		Expect(cache.Get(ctx, "enrich", "e3")).To(HaveLen(38))
+		Expect(cache.Get(ctx, "audit", "a1")).To(HaveLen(38))
+		Expect(store.Size("enrich")).To(Equal(int64(80)))
+
+		// Replacing an entry frees its old size first
+		Expect(cache.Set(ctx, "enrich", "e1", []byte("small"), time.Minute)).To(Succeed())
+		Expect(store.Size("enrich")).To(Equal(int64(47)))
+	})
+
+	It("should charge each tenant's entries to its own quota", func() {
+		value := make([]byte, 38) // 40 bytes with its key
+		acme := runtime.WithCallInfo(ctx, runtime.CallInfo{Tenant: "acme"})
+		globex := runtime.WithCallInfo(ctx, runtime.CallInfo{Tenant: "globex"})
+		Expect(cache.Set(globex, "enrich", "g1", value, time.Minute)).To(Succeed())
+		for _, key := range []string{"a1", "a2", "a3"} {
+			Expect(cache.Set(acme, "enrich", key, value, time.Minute)).To(Succeed())
+		}
+
+		Expect(store.Size("acme/enrich")).To(Equal(int64(80)))
+		Expect(store.Size("globex/enrich")).To(Equal(int64(40)))
+		Expect(cache.Get(globex, "enrich", "g1")).To(HaveLen(38))
+	})
+
+	It("should define cache_get and cache_set, even without a cache", func() {
+		Expect(cache.Define(runtime.NewHostModule(runtime.CacheModule)).Functions()).To(Equal([]string{"cache_get", "cache_set"}))
+		var unavailable *runtime.Cache
+		Expect(unavailable.Define(runtime.NewHostModule(runtime.CacheModule)).Functions()).To(Equal([]string{"cache_get", "cache_set"}))
+	})
+})
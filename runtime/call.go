@@ -0,0 +1,116 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// Call invokes any exported function, marshaling args to the parameter
+// types in the function's WebAssembly signature.
+//
+// Supported arguments and the parameters they fill:
+//   - signed and unsigned integers: one i32 or i64 (range-checked for i32)
+//   - float32, float64: one f32 or f64 (range-checked for f32)
+//   - bool: one i32 or i64, 1 for true and 0 for false
+//   - string, []byte: two i32 parameters (ptr, len in bytes)
+//   - []int32, []int64, []uint32, []uint64, []float32, []float64: two i32
+//     parameters (ptr, element count), little-endian as the guest reads them
+//
+// Strings and slices are copied into buffers from the plugin's allocate()
+// export and released with deallocate() after the call, so they require
+// the allocator half of the payload ABI.
+//
+// Results are returned as WasmEdge produces them: int32, int64, float32,
+// or float64. Unlike Execute, negative results are not treated as ABI
+// error codes, since Call does not know what the function returns.
+//
+// Example:
+//
+//	// extern "C" int count_byte(const char *s, int len, int c);
+//	result, err := plugin.Call("count_byte", "banana", 'a')
+//	n := result[0].(int32) // 3
+func (p *Plugin) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return p.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is Call with cancellation, as in ExecuteContext.
+func (p *Plugin) CallContext(ctx context.Context, name string, args ...interface{}) (results []interface{}, err error) {
+	ctx, span := p.startExecuteSpan(ctx, name)
+	defer func() { endSpan(span, err) }()
+	defer p.lock()()
+
+	if p.vm == nil {
+		return nil, fmt.Errorf("plugin is closed")
+	}
+
+	signature, err := p.signature(name)
+	if err != nil {
+		return nil, err
+	}
+
+	params, buffers, err := engine.MarshalArgs(pluginAllocator{p}, name, signature, args)
+	defer func() {
+		for _, b := range buffers {
+			p.deallocate(b.Ptr, b.Size)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.call(ctx, name, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s() for %s: %w", name, p.path, err)
+	}
+	return result, nil
+}
+
+// module returns the parsed interface of the plugin file.
+//
+// WasmEdge-go cannot report parameter types reliably, so the plugin file is
+// parsed with wasminfo on first use, unless the loader already did, and the
+// result kept for the lifetime of the instance. Concurrent first calls into
+// a reentrant plugin parse it once.
+func (p *Plugin) module() (*wasminfo.Module, error) {
+	p.parsed.Do(func() {
+		if p.exports != nil {
+			return
+		}
+		p.exports, p.parseErr = wasminfo.Open(p.path)
+		if p.parseErr != nil {
+			p.parseErr = fmt.Errorf("failed to read exports of %s: %w", p.path, p.parseErr)
+		}
+	})
+	return p.exports, p.parseErr
+}
+
+// signature returns the type of the exported function name.
+func (p *Plugin) signature(name string) (*wasminfo.FuncType, error) {
+	module, err := p.module()
+	if err != nil {
+		return nil, err
+	}
+
+	export, ok := module.Export(name)
+	if !ok || export.Kind != wasminfo.KindFunc || export.Func == nil {
+		return nil, fmt.Errorf("plugin %s does not export a function named %s", p.path, name)
+	}
+	return export.Func, nil
+}
+
+// pluginAllocator lets engine.MarshalArgs copy arguments into the memory
+// of a locked plugin.
+type pluginAllocator struct {
+	*Plugin
+}
+
+func (a pluginAllocator) Allocate(size int) (uint32, error) {
+	return a.allocate(size)
+}
+
+func (a pluginAllocator) WriteMemory(ptr uint32, data []byte) error {
+	return a.writeMemory(ptr, data)
+}
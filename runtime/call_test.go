@@ -0,0 +1,104 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Call", func() {
+	var plugin *runtime.Plugin
+
+	BeforeEach(func() {
+		calcPluginPath := filepath.Join("..", "plugins", "calc", "calc.wasm")
+		if _, err := os.Stat(calcPluginPath); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + calcPluginPath)
+		}
+
+		var err error
+		plugin, err = runtime.LoadPlugin(calcPluginPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugin.Init()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		plugin.Close()
+	})
+
+	// =========================================================================
+	// TEST: Parameter marshaling
+	// Why: Each Go value must reach the guest as the WebAssembly type the
+	//      export declares; a silently truncated or reinterpreted argument
+	//      produces wrong results with no error.
+	// =========================================================================
+	DescribeTable("should marshal arguments to the export's signature",
+		func(name string, args []interface{}, expected interface{}) {
+			result, err := plugin.Call(name, args...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]interface{}{expected}))
+		},
+		Entry("ints to i32", "add", []interface{}{20, int8(22)}, int32(42)),
+		Entry("mixed i64 and i32", "mul_add", []interface{}{int64(1) << 40, 2, uint64(5)}, int64(1)<<41+5),
+		Entry("floats to f64 and f32", "scale", []interface{}{1.5, float32(4)}, float64(6)),
+		Entry("bool to i32", "pick", []interface{}{true, 1, 2}, int32(1)),
+		Entry("string as (ptr, len)", "count_byte", []interface{}{"banana", 'a'}, int32(3)),
+		Entry("[]byte as (ptr, len)", "count_byte", []interface{}{[]byte{0, 1, 0}, 0}, int32(2)),
+		Entry("[]int64 as (ptr, count)", "sum_i64", []interface{}{[]int64{1, 2, 1 << 40}}, int64(3+1<<40)),
+		Entry("[]float64 as (ptr, count)", "mean_f64", []interface{}{[]float64{1, 2, 6}}, float64(3)),
+		Entry("empty slice", "mean_f64", []interface{}{[]float64{}}, float64(0)),
+	)
+
+	It("should release argument buffers after each call", func() {
+		for i := 0; i < 1000; i++ {
+			_, err := plugin.Call("count_byte", string(make([]byte, 4096)), 0)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	// =========================================================================
+	// TEST: Mismatch errors
+	// Why: A wrong argument list is a caller bug; the error must name the
+	//      argument and show the signature instead of trapping in the guest.
+	// =========================================================================
+	DescribeTable("should reject arguments that do not fit the signature",
+		func(name string, args []interface{}, message string) {
+			_, err := plugin.Call(name, args...)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("too few", "add", []interface{}{1},
+			"cannot call add(i32, i32) -> i32: too few arguments: parameter 2 (i32) has no value"),
+		Entry("too many", "add", []interface{}{1, 2, 3},
+			"too many arguments: argument 3 (int) has no parameter"),
+		Entry("float for an int", "add", []interface{}{1, 2.5},
+			"argument 2 (float64) for parameter 2 (i32): type mismatch"),
+		Entry("int for a float", "scale", []interface{}{1, float32(2)},
+			"argument 1 (int) for parameter 1 (f64): type mismatch"),
+		Entry("i32 overflow", "add", []interface{}{1, int64(1) << 40},
+			"1099511627776 overflows i32"),
+		Entry("f32 overflow", "scale", []interface{}{1.0, 1e300},
+			"overflows f32"),
+		Entry("string where a pair does not fit", "scale", []interface{}{"x"},
+			"argument 1 (string) needs two i32 parameters (ptr, len) at parameter 1"),
+		Entry("slice without a fixed size", "sum_i64", []interface{}{[]int{1}},
+			"[]int is not supported"),
+		Entry("unsupported type", "add", []interface{}{struct{}{}, 1},
+			"unsupported argument type"),
+	)
+
+	It("should reject unknown exports", func() {
+		_, err := plugin.Call("missing", 1)
+		Expect(err).To(MatchError(ContainSubstring("does not export a function named missing")))
+	})
+
+	It("should fail when the plugin is closed", func() {
+		plugin.Close()
+
+		_, err := plugin.Call("add", 1, 2)
+		Expect(err).To(MatchError("plugin is closed"))
+	})
+})
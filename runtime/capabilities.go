@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"sync"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+)
+
+// Capabilities describes the WasmEdge library the process runs with.
+// Optional features it lacks degrade rather than fail: a limit that needs
+// one is not enforced, and callers are expected to report that.
+type Capabilities struct {
+	// Name is the engine plugins run on: "wasmedge" here, or the engine a
+	// host selected instead, which then provides none of the features
+	// below.
+	Name string `json:"name"`
+
+	// Version is the WasmEdge version, empty if the library is unusable.
+	Version string `json:"version"`
+
+	// Statistics is instruction counting with cost limits, which enforce
+	// LoadOptions.MaxInstructions. Without it calls are bounded by their
+	// context's deadline only.
+	Statistics bool `json:"statistics"`
+}
+
+var (
+	capabilitiesOnce sync.Once
+	capabilities     Capabilities
+)
+
+// EngineCapabilities probes the WasmEdge library once and returns what it
+// supports.
+func EngineCapabilities() Capabilities {
+	capabilitiesOnce.Do(func() {
+		capabilities = Capabilities{Name: WasmEdge.Name(), Version: probe(wasmedge.GetVersion), Statistics: probe(probeStatistics) != ""}
+	})
+	return capabilities
+}
+
+// probe returns the result of f, or "" if it panics, as bindings built
+// without a feature of the library do.
+func probe(f func() string) (result string) {
+	defer func() {
+		if recover() != nil {
+			result = ""
+		}
+	}()
+	return f()
+}
+
+// probeStatistics creates a VM measuring costs and returns "ok" if it has
+// statistics to limit them with.
+func probeStatistics() string {
+	config := wasmedge.NewConfigure()
+	if config == nil {
+		return ""
+	}
+	defer config.Release()
+	config.SetStatisticsInstructionCounting(true)
+	config.SetStatisticsCostMeasuring(true)
+	if !config.IsStatisticsCostMeasuring() {
+		return ""
+	}
+
+	vm := wasmedge.NewVMWithConfig(config)
+	if vm == nil {
+		return ""
+	}
+	defer vm.Release()
+	if vm.GetStatistics() == nil {
+		return ""
+	}
+	return "ok"
+}
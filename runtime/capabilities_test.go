@@ -0,0 +1,65 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("EngineCapabilities", func() {
+	pluginPath := func(name string) string {
+		path := filepath.Join("..", "plugins", name, name+".wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		return path
+	}
+
+	It("should probe the engine once", func() {
+		Expect(runtime.EngineCapabilities()).To(Equal(runtime.EngineCapabilities()))
+	})
+
+	// =========================================================================
+	// TEST: Instruction limits
+	// Why: A runaway plugin must be stopped by the instructions it executes
+	//      where the engine can count them, and loading must still succeed
+	//      where it cannot, leaving the call's deadline to stop it.
+	// =========================================================================
+	It("should ignore instruction limits the engine cannot enforce", func() {
+		if runtime.EngineCapabilities().Statistics {
+			Skip("WasmEdge supports statistics")
+		}
+		plugin, err := runtime.LoadPluginWithOptions(pluginPath("hello"), runtime.LoadOptions{MaxInstructions: 1000})
+		Expect(err).NotTo(HaveOccurred())
+		defer plugin.Close()
+		Expect(plugin.InstructionLimit()).To(BeZero())
+	})
+
+	It("should stop calls exceeding their instruction limit", func() {
+		if !runtime.EngineCapabilities().Statistics {
+			Skip("WasmEdge lacks statistics")
+		}
+		plugin, err := runtime.LoadPluginWithOptions(pluginPath("spin"), runtime.LoadOptions{MaxInstructions: 1_000_000})
+		Expect(err).NotTo(HaveOccurred())
+		defer plugin.Close()
+		Expect(plugin.InstructionLimit()).To(Equal(uint64(1_000_000)))
+		Expect(plugin.Init()).To(Succeed())
+
+		// Stopped by the limit long before the deadline
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, err = plugin.ExecuteContext(ctx, 1)
+		Expect(errors.Is(err, runtime.ErrInstructionLimit)).To(BeTrue(), "%v", err)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeFalse())
+
+		// The limit applies per call, not to the host's own calls
+		Expect(plugin.Cleanup()).To(Succeed())
+	})
+})
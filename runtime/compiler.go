@@ -0,0 +1,293 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sync"
+	"time"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+)
+
+// CompilerCache compiles plugins ahead of time with the WasmEdge AOT
+// compiler and keeps the native artifacts in a directory.
+//
+// Artifacts are keyed by a hash of the plugin's contents, the WasmEdge
+// version, and the host platform, so:
+//   - identical plugins at different paths share one artifact
+//   - a rebuilt plugin gets a new key and is recompiled on its next load;
+//     the artifact of the previous build is deleted
+//   - upgrading WasmEdge or moving the cache to another platform never
+//     loads an incompatible artifact
+//
+// Compilation happens on the first load of a given build and is shared by
+// concurrent loads. If it fails, the plugin is interpreted instead.
+//
+// CompilerCache is safe for concurrent use, and by several processes
+// sharing one directory: artifacts are written to a temporary file and
+// renamed into place.
+type CompilerCache struct {
+	dir string
+
+	mu        sync.Mutex
+	sources   map[string]sourceKey      // Last key computed per plugin path
+	compiling map[string]*compileResult // In-progress compilations by key
+	stats     CompilerCacheStats
+}
+
+// CompilerCacheStats counts cache outcomes since creation.
+type CompilerCacheStats struct {
+	Hits     uint64 `json:"hits"`     // Loads served from an existing artifact
+	Compiled uint64 `json:"compiled"` // Artifacts produced by the AOT compiler
+	Failures uint64 `json:"failures"` // Compilations that failed (plugin interpreted instead)
+}
+
+// sourceKey caches the content key of a plugin file, so unchanged files are
+// not hashed on every load.
+type sourceKey struct {
+	size    int64
+	modTime time.Time
+	key     string
+}
+
+// compileResult is shared by loads waiting on the same compilation.
+type compileResult struct {
+	done chan struct{}
+	err  error
+}
+
+// NewCompilerCache creates a cache storing artifacts in dir, creating the
+// directory if needed.
+func NewCompilerCache(dir string) (*CompilerCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create compiler cache %s: %w", dir, err)
+	}
+	return &CompilerCache{
+		dir:       dir,
+		sources:   make(map[string]sourceKey),
+		compiling: make(map[string]*compileResult),
+	}, nil
+}
+
+// Dir returns the artifact directory.
+func (c *CompilerCache) Dir() string {
+	return c.dir
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *CompilerCache) Stats() CompilerCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Load loads the plugin at path from its compiled artifact, compiling it
+// first if needed. If compilation fails, the .wasm file is loaded and
+// interpreted; Plugin.Compiled() reports which happened.
+func (c *CompilerCache) Load(path string) (*Plugin, error) {
+	return c.load(path, LoadOptions{})
+}
+
+// load is Load with options, as in LoadPluginWithOptions.
+func (c *CompilerCache) load(path string, opts LoadOptions) (*Plugin, error) {
+	artifact, err := c.Artifact(path)
+	if err != nil {
+		return loadModule(path, path, opts)
+	}
+	return loadModule(path, artifact, opts)
+}
+
+// Artifact returns the path of the compiled artifact for the plugin at
+// path, compiling it if the cache has none for the plugin's current
+// contents.
+//
+// Lookup steps:
+// 1. Compute the content key (re-hashing only if size or mtime changed)
+// 2. Return the artifact if it exists
+// 3. Otherwise compile it, or wait for a concurrent compilation of the same key
+// 4. Delete the artifact of the plugin's previous build, if unused
+func (c *CompilerCache) Artifact(path string) (string, error) {
+	key, previous, err := c.key(path)
+	if err != nil {
+		return "", err
+	}
+	artifact := c.artifactPath(key)
+
+	if err := c.ensure(path, key, artifact); err != nil {
+		return "", err
+	}
+	if previous != "" && previous != key {
+		c.removeUnused(previous)
+	}
+	return artifact, nil
+}
+
+// Forget drops what the cache knows about the plugin at path, typically
+// because it was deleted, and deletes its artifact unless another path has
+// the same contents.
+func (c *CompilerCache) Forget(path string) {
+	c.mu.Lock()
+	source, ok := c.sources[path]
+	delete(c.sources, path)
+	c.mu.Unlock()
+
+	if ok {
+		c.removeUnused(source.key)
+	}
+}
+
+// artifactSize returns the size of the artifact last used for the plugin
+// at path, or 0 if it has none.
+func (c *CompilerCache) artifactSize(path string) int64 {
+	c.mu.Lock()
+	source, ok := c.sources[path]
+	c.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	info, err := os.Stat(c.artifactPath(source.key))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// ensure makes the artifact for key exist, compiling path if needed or
+// waiting for a concurrent compilation of the same contents.
+func (c *CompilerCache) ensure(path, key, artifact string) error {
+	c.mu.Lock()
+	if _, err := os.Stat(artifact); err == nil {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return nil
+	}
+	if result, ok := c.compiling[key]; ok {
+		c.mu.Unlock()
+		<-result.done
+		return result.err
+	}
+	result := &compileResult{done: make(chan struct{})}
+	c.compiling[key] = result
+	c.mu.Unlock()
+
+	result.err = c.compile(path, artifact)
+
+	c.mu.Lock()
+	delete(c.compiling, key)
+	if result.err != nil {
+		c.stats.Failures++
+	} else {
+		c.stats.Compiled++
+	}
+	c.mu.Unlock()
+	close(result.done)
+
+	return result.err
+}
+
+// key returns the content key for path and the key it had on the previous
+// call, if it changed.
+func (c *CompilerCache) key(path string) (key, previous string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("plugin file not found: %w", err)
+	}
+
+	c.mu.Lock()
+	cached, ok := c.sources[path]
+	c.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.key, "", nil
+	}
+
+	key, err = contentKey(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	c.mu.Lock()
+	c.sources[path] = sourceKey{size: info.Size(), modTime: info.ModTime(), key: key}
+	c.mu.Unlock()
+	return key, cached.key, nil
+}
+
+// contentKey hashes the plugin together with everything that determines
+// whether a compiled artifact can be loaded.
+func contentKey(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open plugin: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "wasmedge %s %s/%s\x00", wasmedge.GetVersion(), goruntime.GOOS, goruntime.GOARCH)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash plugin %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// artifactPath returns where the artifact for key is stored. The extension
+// follows the platform's shared library convention, which WasmEdge uses for
+// native output.
+func (c *CompilerCache) artifactPath(key string) string {
+	ext := ".so"
+	switch goruntime.GOOS {
+	case "darwin":
+		ext = ".dylib"
+	case "windows":
+		ext = ".dll"
+	}
+	return filepath.Join(c.dir, key+ext)
+}
+
+// compile runs the AOT compiler on src and installs the result at dst.
+func (c *CompilerCache) compile(src, dst string) error {
+	tmp, err := os.CreateTemp(c.dir, ".compile-*"+filepath.Ext(dst))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary artifact: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	config := wasmedge.NewConfigure(wasmedge.WASI)
+	if config == nil {
+		return fmt.Errorf("failed to create WasmEdge configuration")
+	}
+	defer config.Release()
+	config.SetCompilerOptimizationLevel(wasmedge.CompilerOptLevel_O3)
+	config.SetCompilerOutputFormat(wasmedge.CompilerOutputFormat_Native)
+
+	compiler := wasmedge.NewCompilerWithConfig(config)
+	if compiler == nil {
+		return fmt.Errorf("failed to create WasmEdge compiler")
+	}
+	defer compiler.Release()
+
+	if err := compiler.Compile(src, tmp.Name()); err != nil {
+		return fmt.Errorf("failed to compile %s: %w", src, err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to install artifact for %s: %w", src, err)
+	}
+	return nil
+}
+
+// removeUnused deletes the artifact for key unless another plugin path
+// still has those contents.
+func (c *CompilerCache) removeUnused(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, source := range c.sources {
+		if source.key == key {
+			return
+		}
+	}
+	os.Remove(c.artifactPath(key))
+}
@@ -0,0 +1,164 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("CompilerCache", func() {
+	var (
+		cacheDir string
+		cache    *runtime.CompilerCache
+	)
+
+	BeforeEach(func() {
+		cacheDir = filepath.Join(GinkgoT().TempDir(), "aot")
+
+		var err error
+		cache, err = runtime.NewCompilerCache(cacheDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// artifacts lists the compiled artifacts, ignoring temporary files.
+	artifacts := func() []string {
+		entries, err := os.ReadDir(cacheDir)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			if e.Name()[0] != '.' {
+				names = append(names, e.Name())
+			}
+		}
+		return names
+	}
+
+	// copyPlugin copies the hello plugin to a private path the test may modify.
+	copyPlugin := func() string {
+		src := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		data, err := os.ReadFile(src)
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: " + src)
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		path := filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+		return path
+	}
+
+	It("should create the cache directory", func() {
+		info, err := os.Stat(cacheDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+		Expect(cache.Dir()).To(Equal(cacheDir))
+	})
+
+	It("should fail for a missing plugin", func() {
+		_, err := cache.Artifact(filepath.Join(cacheDir, "missing.wasm"))
+		Expect(err).To(MatchError(ContainSubstring("plugin file not found")))
+
+		_, err = cache.Load(filepath.Join(cacheDir, "missing.wasm"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	// =========================================================================
+	// TEST: Compile once, load many
+	// Why: AOT compilation takes far longer than interpreting a cold module;
+	//      the cache only pays off if every later load reuses the artifact.
+	// =========================================================================
+	It("should compile on first load and reuse the artifact", func() {
+		path := copyPlugin()
+
+		plugin, err := cache.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer plugin.Close()
+		Expect(plugin.Compiled()).To(BeTrue())
+		Expect(plugin.Path()).To(Equal(path))
+
+		Expect(plugin.Init()).To(Succeed())
+		Expect(plugin.Execute(21)).To(Equal(43))
+
+		again, err := cache.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		again.Close()
+
+		Expect(cache.Stats()).To(Equal(runtime.CompilerCacheStats{Hits: 1, Compiled: 1}))
+		Expect(artifacts()).To(HaveLen(1))
+	})
+
+	It("should compile once for concurrent loads", func() {
+		path := copyPlugin()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				_, err := cache.Artifact(path)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		Expect(cache.Stats().Compiled).To(Equal(uint64(1)))
+	})
+
+	// =========================================================================
+	// TEST: Invalidation
+	// Why: A rebuilt plugin must never run the previous build's native code,
+	//      and stale artifacts must not accumulate in the cache directory.
+	// =========================================================================
+	It("should recompile when the source changes and drop the old artifact", func() {
+		path := copyPlugin()
+
+		first, err := cache.Artifact(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Appending a custom section changes the contents but keeps the module valid
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.Write([]byte{0x00, 0x04, 0x03, 'r', 'e', 'v'})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		future := time.Now().Add(time.Minute)
+		Expect(os.Chtimes(path, future, future)).To(Succeed())
+
+		second, err := cache.Artifact(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).NotTo(Equal(first))
+
+		_, err = os.Stat(first)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(artifacts()).To(HaveLen(1))
+	})
+
+	It("should delete the artifact of a forgotten plugin", func() {
+		path := copyPlugin()
+		artifact, err := cache.Artifact(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		cache.Forget(path)
+
+		_, err = os.Stat(artifact)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(artifacts()).To(BeEmpty())
+	})
+
+	It("should count failed compilations and fall back to the interpreter", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "broken.wasm")
+		Expect(os.WriteFile(path, []byte("not wasm"), 0644)).To(Succeed())
+
+		_, err := cache.Load(path)
+		Expect(err).To(HaveOccurred())
+		Expect(cache.Stats().Failures).To(Equal(uint64(1)))
+		Expect(artifacts()).To(BeEmpty())
+	})
+})
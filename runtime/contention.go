@@ -0,0 +1,16 @@
+package runtime
+
+import (
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// Contention counts the calls that found a non-reentrant plugin instance
+// busy with another call and waited for it; see engine.Contention.
+type Contention = engine.Contention
+
+// CallContention returns the contention since the process started, by the
+// path the plugins were loaded from. Plugins never contended for are not
+// included.
+func CallContention() map[string]Contention {
+	return engine.CallContention()
+}
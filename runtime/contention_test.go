@@ -0,0 +1,59 @@
+package runtime_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("CallContention", func() {
+	// =========================================================================
+	// TEST: Concurrent calls into one instance
+	// Why: The VM is not reentrant. Overlapping calls must wait for each
+	//      other instead of entering it together, and be counted, so that
+	//      callers sharing an instance between goroutines show up.
+	// =========================================================================
+	It("should serialize overlapping calls and count the one that waited", func() {
+		path := filepath.Join("..", "plugins", "spin", "spin.wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		plugin, err := runtime.LoadPlugin(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer plugin.Close()
+		Expect(plugin.Init()).To(Succeed())
+
+		before := runtime.CallContention()[path]
+
+		// The first call holds the instance until its deadline
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		started := make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			close(started)
+			_, err := plugin.ExecuteContext(ctx, 1)
+			done <- err
+		}()
+		<-started
+		time.Sleep(50 * time.Millisecond)
+
+		// The second starts only once the first has been interrupted, and
+		// spins in turn until its own deadline
+		second, cancelSecond := context.WithTimeout(context.Background(), 600*time.Millisecond)
+		defer cancelSecond()
+		_, err = plugin.ExecuteContext(second, 1)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Eventually(done).Should(Receive(MatchError(context.DeadlineExceeded)))
+
+		after := runtime.CallContention()[path]
+		Expect(after.Waits).To(Equal(before.Waits + 1))
+		Expect(after.WaitTime - before.WaitTime).To(BeNumerically(">", 100*time.Millisecond))
+	})
+})
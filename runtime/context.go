@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ContextModule is the import module of the execution context host API:
+//
+//	__attribute__((import_module("host"), import_name("context")))
+//	extern "C" int host_context(char *buf, int cap);
+//
+// context() writes the call's metadata to buf as a JSON object, e.g.
+//
+//	{"plugin":"whoami","request_id":"9c1d2e3f4a5b6c7d","tenant":"payments",
+//	 "caller":"billing-service","deadline":"2024-05-01T12:00:30Z","remaining_ms":29950}
+//
+// and returns its length. If the object is longer than cap, nothing is
+// written and the plugin can retry with a buffer of the returned length.
+// Fields the host does not know are omitted; deadline and remaining_ms
+// are measured when context() is called, so a plugin can check how much
+// time it has left before starting slow work.
+//
+// Register it with NewContextModule.
+const ContextModule = "host"
+
+// CallInfo describes who a plugin call runs for. The host attaches it to
+// the call's context with WithCallInfo so plugins can attribute their logs,
+// keys, and outbound calls.
+type CallInfo struct {
+	RequestID string `json:"request_id,omitempty"`
+	Tenant    string `json:"tenant,omitempty"` // Tenant the request acts on behalf of
+	Caller    string `json:"caller,omitempty"` // Identity of the client that sent the request
+}
+
+// callContext is the JSON object context() returns.
+type callContext struct {
+	Plugin string `json:"plugin"` // Plugin name, from its file name
+	CallInfo
+	Deadline    time.Time `json:"deadline,omitzero"`
+	RemainingMs *int64    `json:"remaining_ms,omitempty"` // Never negative
+}
+
+// callInfoKey is the context key of the call's CallInfo.
+type callInfoKey struct{}
+
+// WithCallInfo returns a context that makes plugin calls made with it
+// (ExecuteContext and friends) see info through context(), and tags their
+// log() messages with its tenant.
+func WithCallInfo(ctx context.Context, info CallInfo) context.Context {
+	return context.WithValue(ctx, callInfoKey{}, info)
+}
+
+// CallInfoFrom returns the CallInfo carried by ctx, or a zero CallInfo.
+// Host functions call it with HostCall.Context().
+func CallInfoFrom(ctx context.Context) CallInfo {
+	info, _ := ctx.Value(callInfoKey{}).(CallInfo)
+	return info
+}
+
+// NewContextModule creates the ContextModule host module.
+func NewContextModule() *HostModule {
+	return NewHostModule(ContextModule).
+		Func("context", []ValueType{ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				ctx := call.Context()
+				value := callContext{
+					Plugin:   strings.TrimSuffix(filepath.Base(call.Path()), ".wasm"),
+					CallInfo: CallInfoFrom(ctx),
+				}
+				if deadline, ok := ctx.Deadline(); ok {
+					remaining := max(time.Until(deadline).Milliseconds(), 0)
+					value.Deadline = deadline.UTC()
+					value.RemainingMs = &remaining
+				}
+				data, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+
+				if int32(len(data)) <= args[1].(int32) {
+					if err := call.Write(args[0].(int32), data); err != nil {
+						return nil, err
+					}
+				}
+				return []interface{}{int32(len(data))}, nil
+			})
+}
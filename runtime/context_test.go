@@ -0,0 +1,98 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Context module", func() {
+	// =========================================================================
+	// TEST: Execution context
+	// Why: Plugins attribute their logs, keys, and outbound calls with the
+	//      metadata context() returns; it must describe the current call
+	//      only, and a buffer that is too small must not be overrun.
+	// =========================================================================
+	Describe("with the whoami plugin", func() {
+		var (
+			plugin *runtime.Plugin
+			logged []runtime.LogEntry
+		)
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "whoami", "whoami.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			logged = nil
+			var err error
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{
+					runtime.NewContextModule(),
+					runtime.NewLogModule(func(entry runtime.LogEntry) { logged = append(logged, entry) }),
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		// fetch runs process(cap) and decodes the context the plugin logged
+		fetch := func(ctx context.Context) map[string]interface{} {
+			length, err := plugin.ExecuteContext(ctx, 512)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0].Message).To(HaveLen(length))
+
+			var fields map[string]interface{}
+			Expect(json.Unmarshal([]byte(logged[0].Message), &fields)).To(Succeed())
+			return fields
+		}
+
+		It("should describe the call and its deadline", func() {
+			ctx := runtime.WithCallInfo(context.Background(), runtime.CallInfo{
+				RequestID: "req-1",
+				Tenant:    "payments",
+				Caller:    "billing-service",
+			})
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+
+			fields := fetch(ctx)
+			Expect(fields).To(HaveKeyWithValue("plugin", "whoami"))
+			Expect(fields).To(HaveKeyWithValue("request_id", "req-1"))
+			Expect(fields).To(HaveKeyWithValue("tenant", "payments"))
+			Expect(fields).To(HaveKeyWithValue("caller", "billing-service"))
+			Expect(fields).To(HaveKey("deadline"))
+			Expect(fields["remaining_ms"]).To(BeNumerically("~", time.Minute.Milliseconds(), 5000))
+			Expect(logged[0].Tenant).To(Equal("payments"))
+		})
+
+		It("should omit what the host does not know", func() {
+			fields := fetch(context.Background())
+			Expect(fields).To(Equal(map[string]interface{}{"plugin": "whoami"}))
+			Expect(logged[0].Tenant).To(BeEmpty())
+		})
+
+		It("should return the length without writing to a short buffer", func() {
+			ctx := runtime.WithCallInfo(context.Background(), runtime.CallInfo{RequestID: "req-1"})
+			length, err := plugin.ExecuteContext(ctx, 8)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(length).To(Equal(len(`{"plugin":"whoami","request_id":"req-1"}`)))
+			Expect(logged).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// Engine is a WebAssembly runtime plugins are loaded on; see
+// engine.Engine. The package runs plugins on WasmEdge; alternatives are
+// checked against the same behavior with the runtime/enginetest
+// conformance specs before they are offered.
+type Engine = engine.Engine
+
+// Instance is a plugin loaded by an Engine; see engine.Instance. *Plugin
+// implements it.
+type Instance = engine.Instance
+
+// Engines other than WasmEdge implement the plugin ABI with the helpers of
+// the engine package, which does not use cgo.
+type (
+	GuestModule   = engine.GuestModule
+	GuestMemory   = engine.GuestMemory
+	GuestLoad     = engine.GuestLoad
+	GuestFunction = engine.GuestFunction
+)
+
+// PrepareGuestLoad is engine.PrepareGuestLoad.
+func PrepareGuestLoad(path string, opts LoadOptions) (*GuestLoad, error) {
+	return engine.PrepareGuestLoad(path, opts)
+}
+
+// NewGuestInstance is engine.NewGuestInstance.
+func NewGuestInstance(load *GuestLoad, module GuestModule) (Instance, error) {
+	return engine.NewGuestInstance(load, module)
+}
+
+// WasmEdge is the engine of LoadPlugin, and of Pool unless
+// PoolOptions.Engine selects another.
+var WasmEdge Engine = wasmEdgeEngine{}
+
+func init() {
+	engine.Register(WasmEdge)
+}
+
+// wasmEdgeEngine loads plugins as *Plugin.
+type wasmEdgeEngine struct{}
+
+func (wasmEdgeEngine) Name() string { return "wasmedge" }
+
+func (wasmEdgeEngine) Load(path string, opts LoadOptions) (Instance, error) {
+	plugin, err := LoadPluginWithOptions(path, opts)
+	if err != nil {
+		// A nil *Plugin must not become a non-nil Instance
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// RegisterEngine makes an engine available by its name, as
+// engine.Register does. WasmEdge is always registered.
+func RegisterEngine(e Engine) {
+	engine.Register(e)
+}
+
+// RegisteredEngines returns the names of the registered engines, sorted.
+func RegisteredEngines() []string {
+	return engine.Registered()
+}
+
+// LookupEngine returns the registered engine called name.
+func LookupEngine(name string) (Engine, error) {
+	return engine.Lookup(name)
+}
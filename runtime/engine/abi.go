@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// ABI error codes returned by plugin functions
+const (
+	ABISuccess                 = 0  // Operation completed successfully
+	ABIErrorNotInitialized     = -1 // Plugin not initialized (init not called)
+	ABIErrorAlreadyInitialized = -2 // Plugin already initialized (init called twice)
+	ABIErrorInvalidInput       = -3 // Invalid input parameter
+	ABIErrorInternal           = -4 // Internal plugin error
+
+	// ABINoOutput is not an error: process() or process_bytes() succeeded
+	// and has nothing to return for this input.
+	ABINoOutput = -5
+)
+
+// ExportInitWithConfig is the optional initializer that receives
+// configuration bytes:
+//
+//	extern "C" int init_with_config(int ptr, int len);
+const ExportInitWithConfig = manifest.InitWithConfigExport
+
+// ExportHealth is the optional self-check a plugin exports to report
+// whether it can still serve calls:
+//
+//	extern "C" int health(); // ABI_SUCCESS, or an ABI error code
+//
+// It must not change the plugin's state.
+const ExportHealth = "health"
+
+// ExportOnShutdown is the optional export called before an instance is
+// discarded, so a plugin can flush buffered state through host functions:
+//
+//	extern "C" int on_shutdown(); // ABI_SUCCESS, or an ABI error code
+const ExportOnShutdown = "on_shutdown"
+
+// Exports of the memory-based payload ABI.
+//
+// Plugins that accept byte or string payloads export, in addition to the
+// core ABI:
+//
+//	extern "C" int allocate(int size);                  // returns a pointer, 0 on failure
+//	extern "C" void deallocate(int ptr, int size);      // frees a buffer from allocate()
+//	extern "C" long long process_bytes(int ptr, int len);
+//
+// process_bytes returns the output location packed as (out_ptr << 32) | out_len,
+// ABINoOutput if there is no output (as opposed to an empty one), or a
+// negative ABI error code. The output buffer must come from allocate();
+// the host copies it out and releases it with deallocate().
+const (
+	ExportAllocate     = "allocate"
+	ExportDeallocate   = "deallocate"
+	ExportProcessBytes = "process_bytes"
+)
+
+// ExportProcessJSON is the export of the JSON payload ABI:
+//
+//	extern "C" long long process_json(int ptr, int len);
+//
+// It is called like process_bytes, with allocate() and deallocate() from
+// the payload ABI, but its input is a JSON document (UTF-8, RFC 8259) and
+// so must be its output. Plugins can export both.
+const ExportProcessJSON = "process_json"
+
+// ExportProcessPB is the export of the protobuf payload ABI:
+//
+//	extern "C" long long process_pb(int ptr, int len);
+//
+// It is called like process_bytes, with allocate() and deallocate() from
+// the payload ABI, but its input is a serialized abi.PluginRequest and its
+// output must be a serialized abi.PluginResponse (abi/abi.proto).
+const ExportProcessPB = "process_pb"
+
+// MaxPayloadSize bounds input and output payloads (16 MiB). It protects the
+// host from copying out arbitrary regions reported by a misbehaving plugin.
+const MaxPayloadSize = 16 << 20
+
+// WASIModule is the import module of the WASI functions engines provide
+// themselves.
+const WASIModule = "wasi_snapshot_preview1"
+
+// ErrNoOutput is returned by the Execute family when the plugin succeeded
+// without producing a result, either because its process() export returns
+// nothing (a side-effect plugin declared `void process(int)`) or because it
+// returned ABINoOutput. The instance is healthy and may be reused; callers
+// should check errors.Is(err, ErrNoOutput) before treating err as a failure.
+var ErrNoOutput = errors.New("plugin produced no output")
+
+// ErrInvalidJSON is returned when process_json produces output that is not
+// a JSON document.
+var ErrInvalidJSON = errors.New("output is not valid JSON")
+
+// ErrInvalidProto is returned when process_pb produces output that is not
+// a PluginResponse.
+var ErrInvalidProto = errors.New("output is not a protobuf PluginResponse")
+
+// ABIErrorName converts ABI error codes to human-readable strings, e.g.
+// "ABI_ERROR_INVALID_INPUT".
+func ABIErrorName(code int32) string {
+	switch code {
+	case ABISuccess:
+		return "success"
+	case ABIErrorNotInitialized:
+		return "ABI_ERROR_NOT_INITIALIZED"
+	case ABIErrorAlreadyInitialized:
+		return "ABI_ERROR_ALREADY_INITIALIZED"
+	case ABIErrorInvalidInput:
+		return "ABI_ERROR_INVALID_INPUT"
+	case ABIErrorInternal:
+		return "ABI_ERROR_INTERNAL"
+	case ABINoOutput:
+		return "ABI_NO_OUTPUT"
+	default:
+		return fmt.Sprintf("unknown error code %d", code)
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// ExportABIVersion is the optional export reporting the ABI version a
+// plugin was built against:
+//
+//	extern "C" int get_abi_version(); // MAJOR * 10000 + MINOR * 100 + PATCH
+//
+// Plugins without it load as unversioned, unless
+// LoadOptions.RequireABIVersion is set.
+const ExportABIVersion = wasminfo.ABIVersionExport
+
+// ABIVersion is a plugin ABI version as get_abi_version() returns it,
+// MAJOR * 10000 + MINOR * 100 + PATCH, e.g. 10200 for 1.2.0. Zero stands
+// for an unversioned plugin.
+type ABIVersion int32
+
+// Major returns the major version, which changes with breaking changes.
+func (v ABIVersion) Major() int { return int(v) / 10000 }
+
+// Minor returns the minor version, which changes with optional additions.
+func (v ABIVersion) Minor() int { return int(v) % 10000 / 100 }
+
+// Patch returns the patch version.
+func (v ABIVersion) Patch() int { return int(v) % 100 }
+
+// String formats v as "1.2.0", or "unversioned" for zero.
+func (v ABIVersion) String() string {
+	if v == 0 {
+		return "unversioned"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+}
+
+// ABIRange is an inclusive range of ABI versions.
+type ABIRange struct {
+	Min, Max ABIVersion
+}
+
+// Contains reports whether v is within r.
+func (r ABIRange) Contains(v ABIVersion) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// String formats r as "1.0.0 to 1.99.99".
+func (r ABIRange) String() string {
+	return r.Min.String() + " to " + r.Max.String()
+}
+
+// SupportedABI is the range of ABI versions this runtime implements: every
+// 1.x version. Minor versions only add optional exports, which the runtime
+// looks for before calling them, so newer 1.x plugins load too.
+var SupportedABI = ABIRange{Min: 10000, Max: 19999}
+
+// ErrABIVersion is returned by Engine.Load for a plugin
+// whose ABI version is outside SupportedABI or, with
+// LoadOptions.RequireABIVersion, that reports none.
+var ErrABIVersion = errors.New("unsupported plugin ABI version")
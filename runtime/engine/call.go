@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// Buffer is a buffer obtained from allocate() for one call.
+type Buffer struct {
+	Ptr  uint32
+	Size int
+}
+
+// Allocator is an instance whose allocate() export MarshalArgs copies
+// strings and slices into.
+type Allocator interface {
+	HasExport(name string) bool
+	Allocate(size int) (uint32, error)
+	WriteMemory(ptr uint32, data []byte) error
+}
+
+// MarshalArgs converts args to WebAssembly values for the signature of
+// the export name, as runtime.Plugin.Call documents, copying strings and
+// slices into the guest memory of p. The returned buffers must be released
+// with deallocate() by the caller, even when an error is returned.
+func MarshalArgs(p Allocator, name string, signature *wasminfo.FuncType, args []interface{}) ([]interface{}, []Buffer, error) {
+	var (
+		params  = make([]interface{}, 0, len(signature.Params))
+		buffers []Buffer
+	)
+
+	// mismatch formats errors so the caller sees the full signature
+	mismatch := func(format string, a ...interface{}) error {
+		return fmt.Errorf("cannot call %s%s: %s", name, signature, fmt.Sprintf(format, a...))
+	}
+
+	next := 0 // Index of the next unfilled parameter
+	for i, arg := range args {
+		if next >= len(signature.Params) {
+			return params, buffers, mismatch("too many arguments: argument %d (%T) has no parameter", i+1, arg)
+		}
+
+		data, isBuffer, err := bufferBytes(arg)
+		if err != nil {
+			return params, buffers, mismatch("argument %d: %v", i+1, err)
+		}
+
+		if !isBuffer {
+			value, err := scalarValue(arg, signature.Params[next])
+			if err != nil {
+				return params, buffers, mismatch("argument %d (%T) for parameter %d (%s): %v",
+					i+1, arg, next+1, signature.Params[next], err)
+			}
+			params = append(params, value)
+			next++
+			continue
+		}
+
+		// Strings and slices fill a (ptr, len) pair of i32 parameters
+		if next+1 >= len(signature.Params) ||
+			signature.Params[next] != "i32" || signature.Params[next+1] != "i32" {
+			return params, buffers, mismatch("argument %d (%T) needs two i32 parameters (ptr, len) at parameter %d",
+				i+1, arg, next+1)
+		}
+		if !p.HasExport(ExportAllocate) || !p.HasExport(ExportDeallocate) {
+			return params, buffers, mismatch("argument %d (%T) requires the plugin to export %s and %s",
+				i+1, arg, ExportAllocate, ExportDeallocate)
+		}
+		if len(data) > MaxPayloadSize {
+			return params, buffers, mismatch("argument %d of %d bytes exceeds the %d byte payload limit",
+				i+1, len(data), MaxPayloadSize)
+		}
+
+		ptr, err := p.Allocate(len(data))
+		if err != nil {
+			return params, buffers, err
+		}
+		buffers = append(buffers, Buffer{Ptr: ptr, Size: len(data)})
+		if err := p.WriteMemory(ptr, data); err != nil {
+			return params, buffers, err
+		}
+
+		params = append(params, int32(ptr), int32(reflect.ValueOf(arg).Len()))
+		next += 2
+	}
+
+	if next < len(signature.Params) {
+		return params, buffers, mismatch("too few arguments: parameter %d (%s) has no value",
+			next+1, signature.Params[next])
+	}
+	return params, buffers, nil
+}
+
+// scalarValue converts a Go number or bool to the value engines expect
+// for a parameter of type param.
+func scalarValue(arg interface{}, param string) (interface{}, error) {
+	v := reflect.ValueOf(arg)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("nil is not supported")
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		switch param {
+		case "i32":
+			if n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("%d overflows i32", n)
+			}
+			return int32(n), nil
+		case "i64":
+			return n, nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// WebAssembly integers are sign-agnostic: pass the bits unchanged
+		n := v.Uint()
+		switch param {
+		case "i32":
+			if n > math.MaxUint32 {
+				return nil, fmt.Errorf("%d overflows i32", n)
+			}
+			return int32(uint32(n)), nil
+		case "i64":
+			return int64(n), nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch param {
+		case "f32":
+			if !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+				return nil, fmt.Errorf("%g overflows f32", f)
+			}
+			return float32(f), nil
+		case "f64":
+			return f, nil
+		}
+
+	case reflect.Bool:
+		var n int32
+		if v.Bool() {
+			n = 1
+		}
+		switch param {
+		case "i32":
+			return n, nil
+		case "i64":
+			return int64(n), nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported argument type")
+	}
+
+	return nil, fmt.Errorf("type mismatch")
+}
+
+// bufferBytes returns the guest memory representation of strings and
+// slices. isBuffer is false for every other argument.
+func bufferBytes(arg interface{}) (data []byte, isBuffer bool, err error) {
+	v := reflect.ValueOf(arg)
+	if !v.IsValid() {
+		return nil, false, nil
+	}
+
+	if v.Kind() == reflect.String {
+		return []byte(v.String()), true, nil
+	}
+	if v.Kind() != reflect.Slice {
+		return nil, false, nil
+	}
+
+	switch v.Type().Elem().Kind() {
+	case reflect.Uint8:
+		return v.Bytes(), true, nil
+	case reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, binary.LittleEndian, arg); err != nil {
+			return nil, true, fmt.Errorf("failed to encode %T: %w", arg, err)
+		}
+		return buf.Bytes(), true, nil
+	default:
+		return nil, true, fmt.Errorf("%T is not supported; use a slice of a fixed-size type such as []int32 or []float64", arg)
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Contention counts the calls that found a non-reentrant plugin instance
+// busy with another call and waited for it. Calls are serialized rather
+// than run concurrently, since engines do not support that; contention
+// means a caller shares an instance between goroutines, e.g. a Plugin
+// used directly instead of through a Pool.
+type Contention struct {
+	Waits    int64         `json:"waits"`   // Calls that had to wait
+	WaitTime time.Duration `json:"wait_ns"` // Total time they waited
+}
+
+// contentionCounters are the counts of the instances of one plugin.
+type contentionCounters struct {
+	waits, waitNanos atomic.Int64
+}
+
+// contention maps plugin paths to *contentionCounters.
+var contention sync.Map
+
+// CallContention returns the contention since the process started, by the
+// path the plugins were loaded from. Plugins never contended for are not
+// included.
+func CallContention() map[string]Contention {
+	counts := make(map[string]Contention)
+	contention.Range(func(key, value interface{}) bool {
+		c := value.(*contentionCounters)
+		counts[key.(string)] = Contention{
+			Waits:    c.waits.Load(),
+			WaitTime: time.Duration(c.waitNanos.Load()),
+		}
+		return true
+	})
+	return counts
+}
+
+// RecordContention counts a call into the plugin at path that waited for
+// another call into the same instance. Engines call it from the lock that
+// serializes calls.
+func RecordContention(path string, waited time.Duration) {
+	value, _ := contention.LoadOrStore(path, &contentionCounters{})
+	c := value.(*contentionCounters)
+	c.waits.Add(1)
+	c.waitNanos.Add(int64(waited))
+}
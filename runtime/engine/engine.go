@@ -0,0 +1,114 @@
+// Package engine defines what the server relies on from a WebAssembly
+// runtime, and implements the plugin ABI on top of any runtime that can
+// load modules, call their exports, and access their memory.
+//
+// The runtime package runs plugins on WasmEdge through cgo and re-exports
+// the types of this package. This package does not use cgo, so an engine
+// written in Go, such as runtime/wazero, builds with CGO_ENABLED=0 by
+// importing it instead of the runtime package. Such an engine checks a
+// plugin with PrepareGuestLoad, instantiates it, and wraps it with
+// NewGuestInstance; the runtime/enginetest specs check that it behaves as
+// WasmEdge does.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mrhapile/wasm-plugin-system/abi"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// Engine is a WebAssembly runtime plugins are loaded on. The interface
+// describes what the server relies on from any runtime, so that
+// alternatives can be checked against the same behavior with the
+// runtime/enginetest conformance specs before they are offered.
+type Engine interface {
+	// Name identifies the engine in logs, configuration, and test
+	// reports.
+	Name() string
+
+	// Load loads the plugin at path, as runtime.LoadPluginWithOptions
+	// does.
+	Load(path string, opts LoadOptions) (Instance, error)
+}
+
+// Instance is a plugin loaded by an Engine. *runtime.Plugin implements it.
+//
+// The errors of an instance follow those of *runtime.Plugin: interrupted
+// calls wrap ctx.Err(), calls failing with memory at the limit wrap
+// ErrMemoryLimit, negative ABI results are *PluginError, and plugins
+// without output return ErrNoOutput. Load negotiates the ABI version as
+// PrepareGuestLoad and NewGuestInstance do, failing with ErrABIVersion.
+type Instance interface {
+	Init() error
+	InitContext(ctx context.Context) error
+	InitWithConfig(config []byte) error
+	InitWithConfigContext(ctx context.Context, config []byte) error
+	ExecuteContext(ctx context.Context, input int) (int, error)
+	ExecuteBytesContext(ctx context.Context, input []byte) ([]byte, error)
+	ExecuteJSONContext(ctx context.Context, input json.RawMessage) (json.RawMessage, error)
+	ExecuteProtoContext(ctx context.Context, req *abi.PluginRequest) (*abi.PluginResponse, error)
+	CallContext(ctx context.Context, name string, args ...interface{}) ([]interface{}, error)
+	HealthContext(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+	SupportsPayloads() bool
+	SupportsJSON() bool
+	SupportsProto() bool
+	Manifest() *manifest.Manifest
+	MemoryLimit() uint
+	MaxMemoryBytes() int64
+	ABIVersion() ABIVersion
+	Cleanup() error
+	Close()
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]Engine{}
+)
+
+// Register makes an engine available by its name, e.g. to run or shadow
+// executions on it. Engines register themselves from an init function,
+// and programs include them with a blank import; the runtime package
+// registers WasmEdge.
+//
+// Like database/sql.Register, Register panics if engine is nil, or if an
+// engine of the same name is already registered.
+func Register(engine Engine) {
+	if engine == nil {
+		panic("engine: Register engine is nil")
+	}
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	if _, dup := engines[engine.Name()]; dup {
+		panic("engine: Register called twice for engine " + engine.Name())
+	}
+	engines[engine.Name()] = engine
+}
+
+// Registered returns the names of the registered engines, sorted.
+func Registered() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the registered engine called name.
+func Lookup(name string) (Engine, error) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	engine, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("no engine %q is registered", name)
+	}
+	return engine, nil
+}
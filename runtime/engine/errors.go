@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMemoryLimit is wrapped by errors from plugins that need more linear
+// memory than their limit allows: at load, when the module's initial memory
+// exceeds it, and during a call that failed with memory grown to the limit.
+var ErrMemoryLimit = errors.New("plugin memory limit exceeded")
+
+// ExportLastError is the optional export through which a plugin explains
+// the error code it most recently returned:
+//
+//	extern "C" long long last_error(); // (msg_ptr << 32) | msg_len, or 0
+//
+// The message is UTF-8 text in plugin-owned memory (typically a static
+// buffer); the host copies it and never deallocates it. It only has to stay
+// valid until the plugin's next call.
+const ExportLastError = "last_error"
+
+// MaxErrorMessage bounds the message copied from last_error(). Longer
+// messages are truncated.
+const MaxErrorMessage = 4096
+
+// PluginError is returned when a plugin export reports a negative ABI error
+// code. Message holds the plugin's own explanation when it exports
+// last_error(), so callers can tell "invalid input" apart from why the
+// input was invalid.
+//
+// Use errors.As to inspect it:
+//
+//	var pluginErr *runtime.PluginError
+//	if errors.As(err, &pluginErr) && pluginErr.Code == runtime.ABIErrorInvalidInput {
+//	    log.Printf("rejected: %s", pluginErr.Message)
+//	}
+type PluginError struct {
+	Function string // Export that returned the code, e.g. "process"
+	Code     int32  // ABI error code
+	Message  string // Explanation from last_error(), empty if none
+	Path     string // Plugin path
+}
+
+// Error formats the code and, if present, the plugin's message.
+func (e *PluginError) Error() string {
+	msg := fmt.Sprintf("%s() returned error code %d for %s: %s",
+		e.Function, e.Code, e.Path, e.Name())
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Name returns the symbolic name of Code, e.g. "ABI_ERROR_INVALID_INPUT".
+func (e *PluginError) Name() string {
+	return ABIErrorName(e.Code)
+}
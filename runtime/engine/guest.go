@@ -0,0 +1,645 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrhapile/wasm-plugin-system/abi"
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// Linear memory is sized in 64 KiB pages, at most 4 GiB of them in wasm32.
+const (
+	wasmPageSize   = 65536
+	maxWasm32Pages = 65536
+)
+
+// GuestModule is a module instance loaded by an Engine other than
+// WasmEdge. NewGuestInstance implements the plugin ABI on top of it, so
+// that such an engine only provides loading, calls, and memory access, and
+// behaves like *runtime.Plugin wherever the ABI is concerned.
+type GuestModule interface {
+	GuestMemory
+
+	// Call calls the exported function name and returns its results.
+	// Parameters and results are int32, int64, float32, or float64
+	// values. A call stopped because ctx is done may leave the module
+	// unusable.
+	Call(ctx context.Context, name string, params ...interface{}) ([]interface{}, error)
+
+	// HasExport reports whether the module exports a function called name.
+	HasExport(name string) bool
+
+	// Close releases the module. It is called once.
+	Close()
+}
+
+// GuestMemory is the linear memory of a plugin, as host functions reach
+// it through a HostCall.
+type GuestMemory interface {
+	// Read returns a copy of size bytes at ptr, or an error if they lie
+	// outside memory.
+	Read(ptr, size uint32) ([]byte, error)
+
+	// Write copies data to ptr, or returns an error if it does not fit.
+	Write(ptr uint32, data []byte) error
+
+	// Pages returns the size of memory in 64 KiB pages. ok is false if
+	// the module has no memory.
+	Pages() (pages uint, ok bool)
+}
+
+// NewGuestInstance returns the Instance of a plugin that an engine loaded
+// as module, after PrepareGuestLoad. It negotiates the ABI version as
+// runtime.LoadPluginWithOptions does; on error, module is closed.
+//
+// Calls into the instance are serialized, whether or not the plugin is
+// reentrant.
+func NewGuestInstance(load *GuestLoad, module GuestModule) (Instance, error) {
+	g := &guestInstance{path: load.Path, guest: module, manifest: load.Manifest, memoryLimit: load.MemoryLimit, exports: load.Module}
+	if err := g.negotiateABI(load.opts); err != nil {
+		g.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// GuestFunction is a function of a HostModule, as engines define it on the
+// instances they load.
+type GuestFunction struct {
+	Name            string
+	Params, Results []ValueType
+
+	// Call runs the function for a call into the plugin made with ctx;
+	// memory is the plugin's linear memory, nil if it has none. Its
+	// results match Results. An error traps the plugin.
+	Call func(ctx context.Context, memory GuestMemory, args []interface{}) ([]interface{}, error)
+}
+
+// GuestFunctions returns the functions of m for the plugin at path with
+// manifest mf, or the error of an invalid definition.
+func (m *HostModule) GuestFunctions(path string, mf *manifest.Manifest) ([]GuestFunction, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	functions := make([]GuestFunction, len(m.functions))
+	for i, f := range m.functions {
+		functions[i] = GuestFunction{
+			Name:    f.name,
+			Params:  append([]ValueType(nil), f.params...),
+			Results: append([]ValueType(nil), f.results...),
+			Call: func(ctx context.Context, memory GuestMemory, args []interface{}) ([]interface{}, error) {
+				var event *TraceEvent
+				trace := TraceFrom(ctx)
+				if trace != nil {
+					event = trace.Begin(TraceHost, m.name, f.name, args)
+				}
+				call := &HostCall{ctx: ctx, path: path, manifest: mf, memory: memory, traced: event != nil}
+				results, err := f.invoke(call, args)
+				if event != nil {
+					trace.End(event, results, call.accesses, err)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("host function %s.%s failed: %w", m.name, f.name, err)
+				}
+				return results, nil
+			},
+		}
+	}
+	return functions, nil
+}
+
+// guestInstance implements the plugin ABI over a GuestModule, as
+// *runtime.Plugin does over a WasmEdge VM.
+type guestInstance struct {
+	mu sync.Mutex // Serializes calls
+
+	path        string
+	guest       GuestModule        // nil once closed
+	manifest    *manifest.Manifest // nil if none
+	memoryLimit uint               // Max linear memory in pages (0 = module's own limit)
+	abiVersion  ABIVersion         // Result of get_abi_version() at load (0 = unversioned)
+
+	exports  *wasminfo.Module // Parsed interface, for CallContext
+	parsed   sync.Once
+	parseErr error
+}
+
+// lock serializes calls, counting those that wait as CallContention.
+func (g *guestInstance) lock() func() {
+	if !g.mu.TryLock() {
+		started := time.Now()
+		g.mu.Lock()
+		RecordContention(g.path, time.Since(started))
+	}
+	return g.mu.Unlock
+}
+
+// startExecuteSpan starts the plugin.Execute span of a call to export.
+func (g *guestInstance) startExecuteSpan(ctx context.Context, export string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	attrs = append(attrs, tracing.AttrExport.String(export))
+	if g.abiVersion != 0 {
+		attrs = append(attrs, tracing.AttrABIVersion.Int(int(g.abiVersion)))
+	}
+	ctx, span := startSpan(ctx, "plugin.Execute", g.path, attrs...)
+	return ctx, func(err error) { endSpan(span, err) }
+}
+
+// HasExport reports whether the plugin exports a function called name.
+func (g *guestInstance) HasExport(name string) bool {
+	return g.guest != nil && g.guest.HasExport(name)
+}
+
+// call runs an exported function, recording the call in the Trace carried
+// by ctx, if any. A call interrupted because ctx is done returns the
+// context's error, so callers can tell timeouts from traps.
+func (g *guestInstance) call(ctx context.Context, name string, params ...interface{}) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var event *TraceEvent
+	trace := TraceFrom(ctx)
+	if trace != nil {
+		event = trace.Begin(TraceExport, "", name, params)
+	}
+	result, err := g.guest.Call(ctx, name, params...)
+	if err != nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	} else {
+		err = g.memoryLimitError(err)
+	}
+	if event != nil {
+		trace.End(event, result, nil, err)
+	}
+	return result, err
+}
+
+// memoryLimitError marks a failed call as caused by the memory limit if
+// the plugin's memory reached it, as *runtime.Plugin does.
+func (g *guestInstance) memoryLimitError(err error) error {
+	if err == nil || g.memoryLimit == 0 || errors.Is(err, ErrMemoryLimit) {
+		return err
+	}
+	if pages, ok := g.guest.Pages(); ok && pages >= g.memoryLimit {
+		return fmt.Errorf("%w (%d pages): %w", ErrMemoryLimit, g.memoryLimit, err)
+	}
+	return err
+}
+
+// negotiateABI queries the plugin's ABI version and checks it against
+// SupportedABI as opts require.
+func (g *guestInstance) negotiateABI(opts LoadOptions) error {
+	if !g.HasExport(ExportABIVersion) {
+		if opts.RequireABIVersion {
+			return fmt.Errorf("%w: %s does not export %s", ErrABIVersion, g.path, ExportABIVersion)
+		}
+		return nil
+	}
+	result, err := g.guest.Call(context.Background(), ExportABIVersion)
+	if err != nil {
+		return fmt.Errorf("failed to call %s for %s: %w", ExportABIVersion, g.path, err)
+	}
+	var version int32
+	ok := len(result) == 1
+	if ok {
+		version, ok = result[0].(int32)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s of %s must return an i32", ErrABIVersion, ExportABIVersion, g.path)
+	}
+	g.abiVersion = ABIVersion(version)
+	if !opts.AnyABIVersion && !SupportedABI.Contains(g.abiVersion) {
+		return fmt.Errorf("%w: %s implements ABI %s (%d), the runtime supports %s",
+			ErrABIVersion, g.path, g.abiVersion, version, SupportedABI)
+	}
+	return nil
+}
+
+// status calls an export returning an ABI status code, such as init().
+func (g *guestInstance) status(ctx context.Context, name string, params ...interface{}) error {
+	result, err := g.call(ctx, name, params...)
+	if err != nil {
+		return fmt.Errorf("failed to execute %s() for %s: %w", name, g.path, err)
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("%s() did not return a value for %s", name, g.path)
+	}
+	if code := result[0].(int32); code != ABISuccess {
+		return g.pluginError(name, code)
+	}
+	return nil
+}
+
+// Init is runtime.Plugin.Init.
+func (g *guestInstance) Init() error {
+	return g.InitContext(context.Background())
+}
+
+// InitContext is runtime.Plugin.InitContext.
+func (g *guestInstance) InitContext(ctx context.Context) error {
+	defer g.lock()()
+
+	if g.guest == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if g.manifest != nil && len(g.manifest.Config) > 0 {
+		return g.initWithConfig(ctx, g.manifest.Config)
+	}
+	return g.status(ctx, "init")
+}
+
+// InitWithConfig is runtime.Plugin.InitWithConfig.
+func (g *guestInstance) InitWithConfig(config []byte) error {
+	return g.InitWithConfigContext(context.Background(), config)
+}
+
+// InitWithConfigContext is runtime.Plugin.InitWithConfigContext.
+func (g *guestInstance) InitWithConfigContext(ctx context.Context, config []byte) error {
+	defer g.lock()()
+
+	return g.initWithConfig(ctx, config)
+}
+
+func (g *guestInstance) initWithConfig(ctx context.Context, config []byte) error {
+	if g.guest == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if !g.HasExport(ExportInitWithConfig) {
+		return fmt.Errorf("plugin %s does not export %s and cannot be configured",
+			g.path, ExportInitWithConfig)
+	}
+	if len(config) > MaxPayloadSize {
+		return fmt.Errorf("config of %d bytes exceeds the %d byte payload limit",
+			len(config), MaxPayloadSize)
+	}
+
+	var ptr uint32
+	if len(config) > 0 {
+		if !g.HasExport(ExportAllocate) || !g.HasExport(ExportDeallocate) {
+			return fmt.Errorf("plugin %s must export %s and %s to receive config",
+				g.path, ExportAllocate, ExportDeallocate)
+		}
+		var err error
+		if ptr, err = g.Allocate(len(config)); err != nil {
+			return err
+		}
+		defer g.deallocate(ptr, len(config))
+		if err := g.WriteMemory(ptr, config); err != nil {
+			return err
+		}
+	}
+	return g.status(ctx, ExportInitWithConfig, int32(ptr), int32(len(config)))
+}
+
+// ExecuteContext is runtime.Plugin.ExecuteContext.
+func (g *guestInstance) ExecuteContext(ctx context.Context, input int) (output int, err error) {
+	ctx, end := g.startExecuteSpan(ctx, "process")
+	defer func() { end(err) }()
+	defer g.lock()()
+
+	if g.guest == nil {
+		return 0, fmt.Errorf("plugin is closed")
+	}
+	result, err := g.call(ctx, "process", int32(input))
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute process(%d) for %s: %w", input, g.path, err)
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("process() for %s: %w", g.path, ErrNoOutput)
+	}
+	value := result[0].(int32)
+	if value == ABINoOutput {
+		return 0, fmt.Errorf("process() for %s: %w", g.path, ErrNoOutput)
+	}
+	if value < 0 {
+		return 0, g.pluginError("process", value)
+	}
+	return int(value), nil
+}
+
+// SupportsPayloads is runtime.Plugin.SupportsPayloads.
+func (g *guestInstance) SupportsPayloads() bool {
+	return g.HasExport(ExportAllocate) && g.HasExport(ExportDeallocate) && g.HasExport(ExportProcessBytes)
+}
+
+// SupportsJSON is runtime.Plugin.SupportsJSON.
+func (g *guestInstance) SupportsJSON() bool {
+	return g.HasExport(ExportAllocate) && g.HasExport(ExportDeallocate) && g.HasExport(ExportProcessJSON)
+}
+
+// SupportsProto is runtime.Plugin.SupportsProto.
+func (g *guestInstance) SupportsProto() bool {
+	return g.HasExport(ExportAllocate) && g.HasExport(ExportDeallocate) && g.HasExport(ExportProcessPB)
+}
+
+// ExecuteBytesContext is runtime.Plugin.ExecuteBytesContext.
+func (g *guestInstance) ExecuteBytesContext(ctx context.Context, input []byte) ([]byte, error) {
+	return g.executePayload(ctx, ExportProcessBytes, input)
+}
+
+// ExecuteJSONContext is runtime.Plugin.ExecuteJSONContext.
+func (g *guestInstance) ExecuteJSONContext(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	if !json.Valid(input) {
+		return nil, fmt.Errorf("input for %s() of %s is not valid JSON", ExportProcessJSON, g.path)
+	}
+	output, err := g.executePayload(ctx, ExportProcessJSON, input)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(output) {
+		return nil, fmt.Errorf("%s() for %s: %w", ExportProcessJSON, g.path, ErrInvalidJSON)
+	}
+	return output, nil
+}
+
+// ExecuteProtoContext is runtime.Plugin.ExecuteProtoContext.
+func (g *guestInstance) ExecuteProtoContext(ctx context.Context, req *abi.PluginRequest) (*abi.PluginResponse, error) {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("input for %s() of %s: %w", ExportProcessPB, g.path, err)
+	}
+	output, err := g.executePayload(ctx, ExportProcessPB, input)
+	if err != nil {
+		return nil, err
+	}
+	var resp abi.PluginResponse
+	if err := proto.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("%s() for %s: %w: %v", ExportProcessPB, g.path, ErrInvalidProto, err)
+	}
+	return &resp, nil
+}
+
+// executePayload runs export on input following the payload ABI call
+// sequence of Plugin.ExecuteBytes.
+func (g *guestInstance) executePayload(ctx context.Context, export string, input []byte) (output []byte, err error) {
+	ctx, end := g.startExecuteSpan(ctx, export, tracing.AttrInputSize.Int(len(input)))
+	defer func() { end(err) }()
+	defer g.lock()()
+
+	if g.guest == nil {
+		return nil, fmt.Errorf("plugin is closed")
+	}
+	if !g.HasExport(ExportAllocate) || !g.HasExport(ExportDeallocate) || !g.HasExport(export) {
+		return nil, fmt.Errorf("plugin %s does not implement the payload ABI (%s, %s, %s)",
+			g.path, ExportAllocate, ExportDeallocate, export)
+	}
+	if len(input) > MaxPayloadSize {
+		return nil, fmt.Errorf("input of %d bytes exceeds the %d byte payload limit",
+			len(input), MaxPayloadSize)
+	}
+
+	inPtr, err := g.Allocate(len(input))
+	if err != nil {
+		return nil, err
+	}
+	defer g.deallocate(inPtr, len(input))
+	if err := g.WriteMemory(inPtr, input); err != nil {
+		return nil, err
+	}
+
+	result, err := g.call(ctx, export, int32(inPtr), int32(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s() for %s: %w", export, g.path, err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%s() did not return a value for %s", export, g.path)
+	}
+	packed := result[0].(int64)
+	if packed == ABINoOutput {
+		return nil, fmt.Errorf("%s() for %s: %w", export, g.path, ErrNoOutput)
+	}
+	if packed < 0 {
+		return nil, g.pluginError(export, int32(packed))
+	}
+
+	outPtr := uint32(packed >> 32)
+	outLen := int(uint32(packed))
+	if outLen > MaxPayloadSize {
+		g.deallocate(outPtr, outLen)
+		return nil, fmt.Errorf("output of %d bytes from %s exceeds the %d byte payload limit",
+			outLen, g.path, MaxPayloadSize)
+	}
+	if outLen == 0 {
+		return []byte{}, nil
+	}
+	defer g.deallocate(outPtr, outLen)
+
+	data, err := g.guest.Read(outPtr, uint32(outLen))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %d bytes at %#x for %s: %w", outLen, outPtr, g.path, err)
+	}
+	return data, nil
+}
+
+// Allocate reserves size bytes in guest memory via the plugin's allocator.
+func (g *guestInstance) Allocate(size int) (uint32, error) {
+	result, err := g.guest.Call(context.Background(), ExportAllocate, int32(size))
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute %s(%d) for %s: %w", ExportAllocate, size, g.path, g.memoryLimitError(err))
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("%s() did not return a value for %s", ExportAllocate, g.path)
+	}
+	ptr := result[0].(int32)
+	if ptr == 0 && size > 0 {
+		return 0, g.memoryLimitError(fmt.Errorf("%s(%d) failed for %s: out of memory", ExportAllocate, size, g.path))
+	}
+	if ptr < 0 {
+		return 0, fmt.Errorf("%s(%d) returned error code %d for %s: %s",
+			ExportAllocate, size, ptr, g.path, ABIErrorName(ptr))
+	}
+	return uint32(ptr), nil
+}
+
+// WriteMemory copies data into linear memory at ptr.
+func (g *guestInstance) WriteMemory(ptr uint32, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := g.guest.Write(ptr, data); err != nil {
+		return fmt.Errorf("failed to write %d bytes at %#x for %s: %w", len(data), ptr, g.path, err)
+	}
+	return nil
+}
+
+// deallocate releases a buffer returned by allocate(), ignoring failures.
+func (g *guestInstance) deallocate(ptr uint32, size int) {
+	g.guest.Call(context.Background(), ExportDeallocate, int32(ptr), int32(size))
+}
+
+// CallContext is runtime.Plugin.CallContext.
+func (g *guestInstance) CallContext(ctx context.Context, name string, args ...interface{}) (results []interface{}, err error) {
+	ctx, end := g.startExecuteSpan(ctx, name)
+	defer func() { end(err) }()
+	defer g.lock()()
+
+	if g.guest == nil {
+		return nil, fmt.Errorf("plugin is closed")
+	}
+	signature, err := g.signature(name)
+	if err != nil {
+		return nil, err
+	}
+	params, buffers, err := MarshalArgs(g, name, signature, args)
+	defer func() {
+		for _, b := range buffers {
+			g.deallocate(b.Ptr, b.Size)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := g.call(ctx, name, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s() for %s: %w", name, g.path, err)
+	}
+	return result, nil
+}
+
+// signature returns the type of the exported function name, parsing the
+// plugin file on first use unless the load checks did.
+func (g *guestInstance) signature(name string) (*wasminfo.FuncType, error) {
+	g.parsed.Do(func() {
+		if g.exports != nil {
+			return
+		}
+		g.exports, g.parseErr = wasminfo.Open(g.path)
+		if g.parseErr != nil {
+			g.parseErr = fmt.Errorf("failed to read exports of %s: %w", g.path, g.parseErr)
+		}
+	})
+	if g.parseErr != nil {
+		return nil, g.parseErr
+	}
+	export, ok := g.exports.Export(name)
+	if !ok || export.Kind != wasminfo.KindFunc || export.Func == nil {
+		return nil, fmt.Errorf("plugin %s does not export a function named %s", g.path, name)
+	}
+	return export.Func, nil
+}
+
+// pluginError builds the error for a negative code returned by function,
+// asking the plugin for its explanation.
+func (g *guestInstance) pluginError(function string, code int32) *PluginError {
+	return &PluginError{Function: function, Code: code, Message: g.lastError(), Path: g.path}
+}
+
+// lastError is runtime.Plugin.lastError.
+func (g *guestInstance) lastError() string {
+	if !g.HasExport(ExportLastError) {
+		return ""
+	}
+	result, err := g.guest.Call(context.Background(), ExportLastError)
+	if err != nil || len(result) == 0 {
+		return ""
+	}
+	packed, ok := result[0].(int64)
+	if !ok || packed <= 0 {
+		return ""
+	}
+	ptr := uint32(packed >> 32)
+	size := uint32(packed)
+	truncated := size > MaxErrorMessage
+	if truncated {
+		size = MaxErrorMessage
+	}
+	data, err := g.guest.Read(ptr, size)
+	if err != nil {
+		return ""
+	}
+	message := strings.ToValidUTF8(string(data), "�")
+	if truncated {
+		message += "..."
+	}
+	return message
+}
+
+// HealthContext is runtime.Plugin.HealthContext.
+func (g *guestInstance) HealthContext(ctx context.Context) error {
+	defer g.lock()()
+
+	if g.guest == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if !g.HasExport(ExportHealth) {
+		return nil
+	}
+	return g.status(ctx, ExportHealth)
+}
+
+// Shutdown is runtime.Plugin.Shutdown.
+func (g *guestInstance) Shutdown(ctx context.Context) error {
+	defer g.lock()()
+
+	if g.guest == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if !g.HasExport(ExportOnShutdown) {
+		return nil
+	}
+	return g.status(ctx, ExportOnShutdown)
+}
+
+// Manifest is runtime.Plugin.Manifest.
+func (g *guestInstance) Manifest() *manifest.Manifest {
+	return g.manifest
+}
+
+// MemoryLimit is runtime.Plugin.MemoryLimit.
+func (g *guestInstance) MemoryLimit() uint {
+	return g.memoryLimit
+}
+
+// MaxMemoryBytes is runtime.Plugin.MaxMemoryBytes, except that a module's
+// declared maximum, which GuestMemory does not report, is not taken into
+// account: without a memory limit, it is 4 GiB.
+func (g *guestInstance) MaxMemoryBytes() int64 {
+	if g.guest == nil {
+		return 0
+	}
+	if _, ok := g.guest.Pages(); !ok {
+		return 0
+	}
+	pages := uint(maxWasm32Pages)
+	if g.memoryLimit > 0 && g.memoryLimit < pages {
+		pages = g.memoryLimit
+	}
+	return int64(pages) * wasmPageSize
+}
+
+// ABIVersion is runtime.Plugin.ABIVersion.
+func (g *guestInstance) ABIVersion() ABIVersion {
+	return g.abiVersion
+}
+
+// Cleanup is runtime.Plugin.Cleanup.
+func (g *guestInstance) Cleanup() error {
+	defer g.lock()()
+
+	if g.guest == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	return g.status(context.Background(), "cleanup")
+}
+
+// Close releases the module. Later calls fail, and a second Close does
+// nothing.
+func (g *guestInstance) Close() {
+	defer g.lock()()
+
+	if g.guest != nil {
+		g.guest.Close()
+		g.guest = nil
+	}
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// ValueType is a WebAssembly value type of a host function parameter or
+// result, spelled as in wasminfo signatures.
+type ValueType string
+
+// Value types host functions can take and return. Values are passed as
+// int32, int64, float32, and float64 respectively.
+const (
+	ValueI32 ValueType = "i32"
+	ValueI64 ValueType = "i64"
+	ValueF32 ValueType = "f32"
+	ValueF64 ValueType = "f64"
+)
+
+// HostFunc implements a host function. args hold one value per declared
+// parameter; the returned slice must hold one value per declared result.
+//
+// Returning an error traps the plugin: the call that reached the host
+// function fails with an error wrapping the one returned here.
+type HostFunc func(call *HostCall, args []interface{}) ([]interface{}, error)
+
+// HostModule is a set of Go functions that plugins import under a module
+// name, letting the embedding service expose capabilities such as logging
+// or configuration lookups:
+//
+//	host := runtime.NewHostModule("host").
+//	    Func("log", []runtime.ValueType{runtime.ValueI32, runtime.ValueI32}, nil,
+//	        func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+//	            msg, err := call.ReadString(args[0].(int32), args[1].(int32))
+//	            if err != nil {
+//	                return nil, err
+//	            }
+//	            log.Printf("%s: %s", call.Path(), msg)
+//	            return nil, nil
+//	        })
+//
+//	plugin, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+//	    HostModules: []*runtime.HostModule{host},
+//	})
+//
+// A plugin imports the function by module and name:
+//
+//	__attribute__((import_module("host"), import_name("log")))
+//	void host_log(const char *msg, int len);
+//
+// A HostModule is a definition: every plugin loaded with it gets its own
+// instance, so one HostModule may be shared by any number of plugins and
+// pools. Its functions may be called concurrently by different plugins.
+type HostModule struct {
+	name      string
+	functions []hostFunction
+	err       error // First definition error, reported on load
+}
+
+// hostFunction is a function defined on a HostModule.
+type hostFunction struct {
+	name    string
+	params  []ValueType
+	results []ValueType
+	fn      HostFunc
+}
+
+// NewHostModule creates an empty host module that plugins import as name.
+func NewHostModule(name string) *HostModule {
+	m := &HostModule{name: name}
+	switch {
+	case name == "":
+		m.err = fmt.Errorf("host module name must not be empty")
+	case name == WASIModule:
+		m.err = fmt.Errorf("host module name %s is reserved for WASI", name)
+	}
+	return m
+}
+
+// Name returns the import module name.
+func (m *HostModule) Name() string {
+	return m.name
+}
+
+// Func defines a function with the given signature and returns m, so that
+// definitions can be chained. Invalid definitions (an empty or duplicate
+// name, an unknown value type, a nil fn) are reported when a plugin is
+// loaded with the module.
+func (m *HostModule) Func(name string, params, results []ValueType, fn HostFunc) *HostModule {
+	if m.err != nil {
+		return m
+	}
+	switch {
+	case name == "":
+		m.err = fmt.Errorf("host module %s: function name must not be empty", m.name)
+	case fn == nil:
+		m.err = fmt.Errorf("host module %s: function %s has no implementation", m.name, name)
+	case m.function(name) != nil:
+		m.err = fmt.Errorf("host module %s: function %s is defined twice", m.name, name)
+	}
+	for _, t := range append(append([]ValueType(nil), params...), results...) {
+		if m.err == nil && !t.valid() {
+			m.err = fmt.Errorf("host module %s: function %s: unsupported value type %q", m.name, name, t)
+		}
+	}
+	if m.err != nil {
+		return m
+	}
+
+	m.functions = append(m.functions, hostFunction{
+		name:    name,
+		params:  append([]ValueType(nil), params...),
+		results: append([]ValueType(nil), results...),
+		fn:      fn,
+	})
+	return m
+}
+
+// Functions returns the names of the defined functions in definition order.
+func (m *HostModule) Functions() []string {
+	names := make([]string, len(m.functions))
+	for i, f := range m.functions {
+		names[i] = f.name
+	}
+	return names
+}
+
+// function returns the definition of name, or nil.
+func (m *HostModule) function(name string) *hostFunction {
+	for i := range m.functions {
+		if m.functions[i].name == name {
+			return &m.functions[i]
+		}
+	}
+	return nil
+}
+
+// HostCall is passed to a HostFunc. It gives access to the calling
+// plugin's linear memory, through which strings and buffers are passed as
+// (ptr, len) pairs. It is only valid for the duration of the call.
+type HostCall struct {
+	ctx      context.Context
+	path     string
+	manifest *manifest.Manifest
+	memory   GuestMemory // nil if the plugin has none
+
+	traced   bool           // The call's context carries a Trace
+	accesses []MemoryAccess // Memory read and written, if traced
+}
+
+// NewHostCall returns the HostCall of a call, made with ctx, into a host
+// function that the plugin at path, with manifest m, imports. memory is
+// the plugin's linear memory, nil if it has none. GuestFunctions makes
+// the calls into the functions of a HostModule; engines call NewHostCall
+// for host functions of their own.
+func NewHostCall(ctx context.Context, path string, m *manifest.Manifest, memory GuestMemory) *HostCall {
+	return &HostCall{ctx: ctx, path: path, manifest: m, memory: memory}
+}
+
+// Context returns the context of the plugin call that reached the host
+// function, e.g. the one passed to ExecuteContext, so host functions can
+// see request-scoped values. Calls without one use context.Background().
+func (c *HostCall) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Path returns the path of the plugin that made the call.
+func (c *HostCall) Path() string {
+	return c.path
+}
+
+// Manifest returns the manifest of the plugin that made the call, or nil
+// if it has none, so host functions can honor what the plugin declares.
+func (c *HostCall) Manifest() *manifest.Manifest {
+	return c.manifest
+}
+
+// Read copies length bytes at ptr out of the plugin's memory.
+func (c *HostCall) Read(ptr, length int32) ([]byte, error) {
+	if c.memory == nil {
+		return nil, fmt.Errorf("plugin %s has no linear memory", c.path)
+	}
+	if ptr < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid buffer (%d, %d) from %s", ptr, length, c.path)
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+	data, err := c.memory.Read(uint32(ptr), uint32(length))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %d bytes at %#x from %s: %w", length, ptr, c.path, err)
+	}
+	if c.traced {
+		c.accesses = append(c.accesses, memoryAccess("read", ptr, length, data))
+	}
+	return data, nil
+}
+
+// ReadString reads a UTF-8 string; invalid sequences are replaced with
+// U+FFFD.
+func (c *HostCall) ReadString(ptr, length int32) (string, error) {
+	data, err := c.Read(ptr, length)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToValidUTF8(string(data), "�"), nil
+}
+
+// Write copies data into the plugin's memory at ptr, typically a buffer the
+// plugin passed in together with its capacity.
+func (c *HostCall) Write(ptr int32, data []byte) error {
+	if c.memory == nil {
+		return fmt.Errorf("plugin %s has no linear memory", c.path)
+	}
+	if ptr < 0 {
+		return fmt.Errorf("invalid buffer pointer %d from %s", ptr, c.path)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := c.memory.Write(uint32(ptr), data); err != nil {
+		return fmt.Errorf("failed to write %d bytes at %#x to %s: %w", len(data), ptr, c.path, err)
+	}
+	if c.traced {
+		c.accesses = append(c.accesses, memoryAccess("write", ptr, int32(len(data)), data))
+	}
+	return nil
+}
+
+// invoke calls the implementation, converting a panic into an error and
+// checking the results against the declared signature.
+func (f *hostFunction) invoke(call *HostCall, args []interface{}) (results []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			results, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	results, err = f.fn(call, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != len(f.results) {
+		return nil, fmt.Errorf("returned %d results, declared %d", len(results), len(f.results))
+	}
+	for i, t := range f.results {
+		if !hasValueType(results[i], t) {
+			return nil, fmt.Errorf("result %d is %T, declared %s", i+1, results[i], t)
+		}
+	}
+	return results, nil
+}
+
+// hasValueType reports whether v is the Go representation of t.
+func hasValueType(v interface{}, t ValueType) bool {
+	switch v.(type) {
+	case int32:
+		return t == ValueI32
+	case int64:
+		return t == ValueI64
+	case float32:
+		return t == ValueF32
+	case float64:
+		return t == ValueF64
+	}
+	return false
+}
+
+// valid reports whether t is one of the supported value types.
+func (t ValueType) valid() bool {
+	switch t {
+	case ValueI32, ValueI64, ValueF32, ValueF64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// LoadOptions configures how a plugin is loaded.
+type LoadOptions struct {
+	// MaxMemoryPages caps the plugin's linear memory in 64 KiB pages,
+	// unless its manifest declares limits.memory_pages. Zero means no limit
+	// beyond the module's own.
+	MaxMemoryPages int
+
+	// MaxInstructions caps the instructions each call of an export may
+	// execute, failing it with runtime.ErrInstructionLimit, so a runaway
+	// plugin is stopped by the work it does rather than the time it takes.
+	// Only WasmEdge enforces it, and only with statistics
+	// (runtime.EngineCapabilities); otherwise it is ignored and only the
+	// call's context bounds it. Zero means no limit.
+	MaxInstructions uint64
+
+	// HostModules are Go functions the plugin may import. Each is
+	// instantiated for the plugin and registered under its name before the
+	// plugin is instantiated, so imports are resolved against them.
+	HostModules []*HostModule
+
+	// Modules, if set, serves the parsed module from a cache shared by all
+	// loads of the same .wasm contents. Engines ignore caches they do not
+	// know; WasmEdge uses a *runtime.ModuleCache.
+	Modules ModuleSource
+
+	// RequireDigest rejects plugins that declare no SHA-256 digest, in
+	// their manifest or a checksum file. A declared digest is checked
+	// either way.
+	RequireDigest bool
+
+	// TrustedKeys, if set, rejects plugins without a detached signature
+	// (<name>.wasm.sig) by one of these keys. See ParseTrustedKeys.
+	TrustedKeys []ed25519.PublicKey
+
+	// RequireABIVersion rejects plugins that do not export
+	// get_abi_version, which otherwise load as unversioned.
+	RequireABIVersion bool
+
+	// AnyABIVersion loads plugins whose ABI version is outside
+	// SupportedABI, for hosts that check Instance.ABIVersion themselves.
+	AnyABIVersion bool
+
+	// WASI grants the plugin arguments, environment variables, and
+	// directories. The zero value grants none.
+	WASI WASIOptions
+
+	// CaptureOutput records what the plugin writes to stdout and stderr in
+	// the runtime.OutputCapture of the current call, and writes it to the
+	// host's streams outside such calls. Only WasmEdge captures output. It
+	// does not apply to plugins granted WASI directories, whose file writes
+	// share the WASI function.
+	CaptureOutput bool
+}
+
+// ModuleSource shares parsed modules between the loads of an engine.
+type ModuleSource interface {
+	// Forget drops what the source knows about the plugin at path, e.g.
+	// after the file was replaced or deleted.
+	Forget(path string)
+}
+
+// GuestLoad is a plugin checked for loading by PrepareGuestLoad, with what
+// an engine needs to know to instantiate it.
+type GuestLoad struct {
+	Path     string
+	Manifest *manifest.Manifest // plugin.json next to the plugin (nil if none)
+
+	// Module is the plugin's parsed interface, if the checks needed to
+	// parse it (nil otherwise)
+	Module *wasminfo.Module
+
+	// MemoryLimit is the maximum linear memory in pages, from LoadOptions
+	// or the manifest (0 = module's own limit)
+	MemoryLimit uint
+
+	// Args, Env (as NAME=value), and Dirs (host directory by guest path)
+	// are the WASI environment the host and the manifest grant
+	Args []string
+	Env  []string
+	Dirs map[string]string
+
+	opts LoadOptions
+}
+
+// PrepareGuestLoad checks the plugin at path before an engine loads it:
+// its digest and signature, its manifest, and that its initial memory fits
+// the limit. Engines call it first, instantiate the module with what it
+// returns, and, unless they implement the plugin ABI themselves as
+// WasmEdge does, pass both to NewGuestInstance.
+//
+// Engines using NewGuestInstance do not give plugins a scratch directory,
+// capture their output, or enforce LoadOptions.MaxInstructions.
+func PrepareGuestLoad(path string, opts LoadOptions) (*GuestLoad, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("plugin file not found: %w", err)
+	}
+	m, module, limit, err := checkModule(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	args, env, preopens, err := wasiEnvironment(path, m, opts.WASI)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]string, len(preopens))
+	for _, preopen := range preopens {
+		guest, host, _ := strings.Cut(preopen, ":")
+		dirs[guest] = host
+	}
+	return &GuestLoad{
+		Path:        path,
+		Manifest:    m,
+		Module:      module,
+		MemoryLimit: limit,
+		Args:        args,
+		Env:         env,
+		Dirs:        dirs,
+		opts:        opts,
+	}, nil
+}
+
+// checkModule reads the manifest of the plugin at path, verifies the binary
+// against its declared digest and signature (see verifyModule), checks the
+// manifest against the binary, and resolves the memory limit: the
+// manifest's limits.memory_pages if declared, otherwise opts.MaxMemoryPages.
+// The binary is only parsed if there is a manifest or a limit; module is
+// nil otherwise.
+func checkModule(path string, opts LoadOptions) (m *manifest.Manifest, module *wasminfo.Module, limit uint, err error) {
+	m, err = manifest.ForPlugin(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if err := verifyModule(path, m, opts); err != nil {
+		return nil, nil, 0, err
+	}
+	if opts.MaxMemoryPages > 0 {
+		limit = uint(opts.MaxMemoryPages)
+	}
+	if m != nil && m.Limits.MemoryPages > 0 {
+		limit = uint(m.Limits.MemoryPages)
+	}
+	if m == nil && limit == 0 {
+		return nil, nil, 0, nil
+	}
+
+	module, err = wasminfo.Open(path)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to read exports of %s: %w", path, err)
+	}
+	if m != nil {
+		if err := m.Check(module); err != nil {
+			return nil, nil, 0, fmt.Errorf("plugin %s does not match its manifest: %w", path, err)
+		}
+	}
+	if limit > 0 && uint(module.MemoryPages) > limit {
+		return nil, nil, 0, fmt.Errorf("plugin %s needs %d pages of memory but is limited to %d: %w",
+			path, module.MemoryPages, limit, ErrMemoryLimit)
+	}
+	return m, module, limit, nil
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+// tracer records the plugin.Execute spans of guest instances under the
+// runtime package's instrumentation name, as WasmEdge's.
+var tracer = tracing.Tracer("github.com/mrhapile/wasm-plugin-system/runtime")
+
+// startSpan starts a span about the plugin at path.
+func startSpan(ctx context.Context, name, path string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, tracing.AttrPlugin.String(strings.TrimSuffix(filepath.Base(path), ".wasm")))
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, recording err unless it is ErrNoOutput, which is a
+// successful call, and the ABI error code of a *PluginError.
+func endSpan(span trace.Span, err error) {
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) {
+		span.SetAttributes(tracing.AttrABIErrorCode.Int(int(pluginErr.Code)))
+	}
+	if errors.Is(err, ErrNoOutput) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
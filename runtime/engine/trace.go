@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxTraceEvents bounds the events a Trace keeps, so a plugin calling the
+// host in a loop cannot grow a trace without limit.
+const maxTraceEvents = 1000
+
+// maxTraceData bounds the bytes recorded per memory access; longer
+// accesses are truncated.
+const maxTraceData = 256
+
+// Trace event kinds.
+const (
+	TraceExport = "export" // The host called an export of the plugin
+	TraceHost   = "host"   // The plugin called a host function
+)
+
+// TraceEvent is one call recorded in a Trace. Host calls made while an
+// export runs follow its event, so Seq order reads like a call tree.
+type TraceEvent struct {
+	Seq      int            `json:"seq"`
+	Time     time.Time      `json:"time"`
+	Kind     string         `json:"kind"`             // TraceExport or TraceHost
+	Module   string         `json:"module,omitempty"` // Import module of a host function
+	Function string         `json:"function"`
+	Args     []interface{}  `json:"args"`
+	Results  []interface{}  `json:"results,omitempty"`
+	Error    string         `json:"error,omitempty"` // Trap or host function error
+	Duration time.Duration  `json:"duration_ns"`
+	Memory   []MemoryAccess `json:"memory,omitempty"` // Guest memory a host function read or wrote
+}
+
+// MemoryAccess is a read or write of guest memory by a host function,
+// such as a string passed in as a (ptr, len) pair.
+type MemoryAccess struct {
+	Op        string `json:"op"` // "read" or "write"
+	Ptr       int32  `json:"ptr"`
+	Len       int32  `json:"len"`
+	Data      []byte `json:"data,omitempty"`      // The first maxTraceData bytes (base64 in JSON)
+	Truncated bool   `json:"truncated,omitempty"` // Data is shorter than Len
+}
+
+// Trace records the exports called and the host functions reached during
+// calls whose context carries it (see WithTrace), typically one debug
+// request. It is safe for concurrent use.
+//
+// WASI functions are implemented inside the engine and never reach Go,
+// so their calls cannot be recorded; WASI lists the ones the plugin
+// imports instead, on engines that report them.
+type Trace struct {
+	mu      sync.Mutex
+	events  []*TraceEvent
+	dropped int
+	wasi    []string
+}
+
+// NewTrace creates an empty trace.
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// Events returns copies of the recorded events in Seq order. Events of
+// calls still running have no results or duration yet.
+func (t *Trace) Events() []TraceEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	events := make([]TraceEvent, len(t.events))
+	for i, event := range t.events {
+		events[i] = *event
+	}
+	return events
+}
+
+// Dropped returns the number of events discarded after the first
+// maxTraceEvents.
+func (t *Trace) Dropped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// WASI returns the WASI functions imported by the traced plugin, e.g.
+// "fd_write", whose calls the trace cannot show.
+func (t *Trace) WASI() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.wasi...)
+}
+
+// Begin records the start of a call and returns its event for End, or nil
+// once the trace is full. Engines record the exports they call, and the
+// host functions that GuestFunctions does not record for them.
+func (t *Trace) Begin(kind, module, function string, args []interface{}) *TraceEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.events) >= maxTraceEvents {
+		t.dropped++
+		return nil
+	}
+	event := &TraceEvent{
+		Seq:      len(t.events) + 1,
+		Time:     time.Now(),
+		Kind:     kind,
+		Module:   module,
+		Function: function,
+		Args:     traceValues(args),
+	}
+	t.events = append(t.events, event)
+	return event
+}
+
+// End completes the event of a finished call. A nil event is ignored.
+func (t *Trace) End(event *TraceEvent, results []interface{}, memory []MemoryAccess, err error) {
+	if event == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	event.Results = traceValues(results)
+	event.Memory = memory
+	event.Duration = time.Since(event.Time)
+	if err != nil {
+		event.Error = err.Error()
+	}
+}
+
+// SetWASI records the plugin's WASI imports once.
+func (t *Trace) SetWASI(imports []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.wasi == nil {
+		t.wasi = imports
+	}
+}
+
+// traceKey is the context key of the call's Trace.
+type traceKey struct{}
+
+// WithTrace returns a context that makes plugin calls made with it
+// (ExecuteContext and friends) record their exports and host function
+// calls in trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFrom returns the Trace carried by ctx, or nil.
+func TraceFrom(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// traceValues copies WebAssembly values for a trace. JSON has no NaN or
+// infinities, so those floats are recorded as strings.
+func traceValues(values []interface{}) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		switch f := v.(type) {
+		case float32:
+			if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+				v = fmt.Sprint(f)
+			}
+		case float64:
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				v = fmt.Sprint(f)
+			}
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// memoryAccess describes an access of length bytes at ptr for a trace.
+func memoryAccess(op string, ptr, length int32, data []byte) MemoryAccess {
+	access := MemoryAccess{Op: op, Ptr: ptr, Len: length}
+	if len(data) > maxTraceData {
+		data, access.Truncated = data[:maxTraceData], true
+	}
+	access.Data = append([]byte(nil), data...)
+	return access
+}
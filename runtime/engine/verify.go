@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// ErrIntegrity is wrapped by load errors for plugin binaries that do not
+// match the digest declared for them, or lack a signature by a trusted key.
+var ErrIntegrity = errors.New("plugin integrity check failed")
+
+// verifyModule checks the binary of the plugin at path against the digest
+// its checksum file or manifest m declares (see manifest.Digest) and, if
+// trusted keys are configured, against its detached signature. The binary
+// is only read if there is something to check.
+func verifyModule(path string, m *manifest.Manifest, opts LoadOptions) error {
+	expected, err := manifest.Digest(path, m)
+	if err != nil {
+		return err
+	}
+	if expected == "" && opts.RequireDigest {
+		return fmt.Errorf("%w: plugin %s declares no sha256 digest", ErrIntegrity, path)
+	}
+	if expected == "" && len(opts.TrustedKeys) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin %s: %w", path, err)
+	}
+	if expected != "" {
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != expected {
+			return fmt.Errorf("%w: plugin %s has sha256 %s, expected %s", ErrIntegrity, path, actual, expected)
+		}
+	}
+	if len(opts.TrustedKeys) > 0 {
+		return verifySignature(path, data, opts.TrustedKeys)
+	}
+	return nil
+}
+
+// verifySignature checks that the detached signature next to the plugin at
+// path is a signature of data by one of keys.
+func verifySignature(path string, data []byte, keys []ed25519.PublicKey) error {
+	encoded, err := os.ReadFile(path + manifest.SignatureSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: plugin %s is not signed", ErrIntegrity, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read signature of %s: %w", path, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: %s%s is not a base64 Ed25519 signature", ErrIntegrity, path, manifest.SignatureSuffix)
+	}
+
+	for _, key := range keys {
+		if ed25519.Verify(key, data, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: plugin %s is not signed by a trusted key", ErrIntegrity, path)
+}
+
+// ParseTrustedKeys decodes the PEM "PUBLIC KEY" blocks in data, such as a
+// cosign.pub file or the output of `openssl pkey -pubout`, into keys for
+// LoadOptions.TrustedKeys. Every block must hold an Ed25519 key.
+func ParseTrustedKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unexpected PEM block %q, want PUBLIC KEY", block.Type)
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %d: %w", len(keys)+1, err)
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key %d is a %T, not an Ed25519 key", len(keys)+1, parsed)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM public keys found")
+	}
+	return keys, nil
+}
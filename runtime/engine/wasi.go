@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// WASIOptions configures the WASI environment plugins run in. The zero
+// value is the sandbox: a plugin gets no arguments beyond its program name,
+// no environment variables, and no directories, whatever the host process
+// has. A plugin's manifest asks for more in its wasi block, and is granted
+// the host's variables and directories only if they are allowed here.
+//
+// Engines connect a plugin's stdin, stdout, and stderr to the host
+// process's; WasmEdge captures its output instead if it is loaded with
+// LoadOptions.CaptureOutput.
+type WASIOptions struct {
+	// Args are passed to every plugin after its program name, unless its
+	// manifest declares wasi.args.
+	Args []string
+
+	// Env sets environment variables for every plugin, by name. They take
+	// precedence over the manifest's wasi.env.
+	Env map[string]string
+
+	// AllowEnv lists the host environment variables a plugin may inherit.
+	// A variable is passed only to plugins that list it in
+	// wasi.inherit_env, and only if the host has it set.
+	AllowEnv []string
+
+	// Dirs maps guest paths to the host directories plugins may have
+	// pre-opened there. A directory is opened only for plugins that list
+	// its guest path in wasi.dirs; a plugin listing a guest path missing
+	// from Dirs fails to load.
+	Dirs map[string]string
+
+	// ScratchDir is where WasmEdge creates the scratch directories of
+	// plugins granted directories; the system's temporary directory if
+	// empty. Each instance of such a plugin has a directory of its own
+	// pre-opened at runtime.ScratchGuestPath, emptied after every call and
+	// removed when the instance is closed, so that concurrent calls, which
+	// always run on different instances, never see each other's temporary
+	// files. Calls into a reentrant plugin share the directory of their
+	// instance, which is then only emptied when the instance is closed. A
+	// plugin whose manifest lists runtime.ScratchGuestPath in wasi.dirs gets
+	// the directory granted there instead.
+	ScratchDir string
+
+	// ScratchMaxBytes and ScratchMaxFiles bound the bytes in regular files,
+	// and the files and directories, a call may create in its scratch
+	// directory. A call going over either is interrupted, or fails if it
+	// returned first, with an error wrapping runtime.ErrFileQuota, so that
+	// a buggy plugin cannot fill the node's disk. Zero values disable a
+	// bound.
+	ScratchMaxBytes int64
+	ScratchMaxFiles int
+}
+
+// wasiEnvironment returns the arguments, environment variables (as
+// NAME=value), and pre-opened directories (as guest:host) of the plugin at
+// path, from the host options and the plugin's manifest, if any.
+func wasiEnvironment(path string, m *manifest.Manifest, opts WASIOptions) (args, env, preopens []string, err error) {
+	var declared manifest.WASI
+	if m != nil {
+		declared = m.WASI
+	}
+
+	args = append([]string{filepath.Base(path)}, opts.Args...)
+	if declared.Args != nil {
+		args = append(args[:1], declared.Args...)
+	}
+
+	vars := make(map[string]string, len(declared.InheritEnv)+len(declared.Env)+len(opts.Env))
+	for _, name := range declared.InheritEnv {
+		if value, ok := os.LookupEnv(name); ok && slices.Contains(opts.AllowEnv, name) {
+			vars[name] = value
+		}
+	}
+	for name, value := range declared.Env {
+		vars[name] = value
+	}
+	for name, value := range opts.Env {
+		vars[name] = value
+	}
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	slices.Sort(env)
+
+	for _, guest := range declared.Dirs {
+		host, ok := opts.Dirs[guest]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("plugin %s needs directory %s, which the host does not grant", path, guest)
+		}
+		if info, err := os.Stat(host); err != nil || !info.IsDir() {
+			return nil, nil, nil, fmt.Errorf("directory %s granted to plugin %s at %s is not a directory", host, path, guest)
+		}
+		preopens = append(preopens, guest+":"+host)
+	}
+	return args, env, preopens, nil
+}
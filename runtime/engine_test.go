@@ -0,0 +1,45 @@
+package runtime_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// namedEngine is an engine that loads nothing, for registry specs.
+type namedEngine string
+
+func (e namedEngine) Name() string { return string(e) }
+
+func (namedEngine) Load(string, runtime.LoadOptions) (runtime.Instance, error) {
+	return nil, runtime.ErrNoOutput
+}
+
+var _ = Describe("Engine registry", func() {
+	// =========================================================================
+	// TEST: Registration by name
+	// Why: Engines outside the package are selected by name from the
+	//      server's configuration, e.g. to shadow executions on them.
+	// =========================================================================
+	It("should find WasmEdge and registered engines by name", func() {
+		runtime.RegisterEngine(namedEngine("registry-test"))
+
+		Expect(runtime.RegisteredEngines()).To(ContainElements("registry-test", "wasmedge"))
+		engine, err := runtime.LookupEngine("registry-test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(engine.Name()).To(Equal("registry-test"))
+
+		engine, err = runtime.LookupEngine("wasmedge")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(engine).To(Equal(runtime.WasmEdge))
+
+		_, err = runtime.LookupEngine("wasmer")
+		Expect(err).To(MatchError(`no engine "wasmer" is registered`))
+	})
+
+	It("should refuse duplicate and nil engines", func() {
+		Expect(func() { runtime.RegisterEngine(namedEngine("wasmedge")) }).To(Panic())
+		Expect(func() { runtime.RegisterEngine(nil) }).To(Panic())
+	})
+})
@@ -0,0 +1,446 @@
+// Package enginetest verifies that an engine.Engine behaves the way the
+// server relies on, so that every engine (WasmEdge, and any alternative
+// runtime) is checked against the same specs: the plugin lifecycle,
+// memory limits, interruption of runaway calls, traps, passing memory
+// between host and plugin, and concurrent use. Behavior that drifts
+// between engines fails here rather than in production.
+//
+// The specs load the test plugins of the repository's plugins directory
+// (hello, upper, calc, spin, and trap), built to <name>/<name>.wasm as CI
+// does; a spec whose plugin is not built is skipped. The ABI version specs
+// write modules of their own. A backend's Ginkgo
+// suite declares the specs with Describe:
+//
+//	var _ = enginetest.Describe(wazero.Engine, filepath.Join("..", "..", "plugins"))
+//
+// Packages without a Ginkgo suite call Run from a standard test instead.
+// The specs only import runtime/engine, so engines that do not use cgo
+// are checked without it.
+// Run with -race: the concurrency specs rely on it to report data races.
+package enginetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// concurrency is how many goroutines the concurrency specs run at once.
+const concurrency = 8
+
+// interruptWithin bounds how long an engine may take to stop a runaway
+// call once its context is done.
+const interruptWithin = 5 * time.Second
+
+// Run runs the conformance specs of e as the test t, for packages
+// that do not use Ginkgo. It calls RunSpecs, so it can only be called once
+// per test binary, and not alongside a Ginkgo suite; call Describe from
+// those.
+func Run(t *testing.T, e engine.Engine, plugins string) {
+	Describe(e, plugins)
+	RegisterFailHandler(ginkgo.Fail)
+	ginkgo.RunSpecs(t, "Engine Conformance Suite")
+}
+
+// versioned returns a module whose one export, get_abi_version, returns
+// version.
+func versioned(version int32) []byte {
+	var leb []byte // i32.const immediate, signed LEB128
+	for v := version; ; v >>= 7 {
+		b := byte(v & 0x7f)
+		if (v>>7 == 0 && b&0x40 == 0) || (v>>7 == -1 && b&0x40 != 0) {
+			leb = append(leb, b)
+			break
+		}
+		leb = append(leb, b|0x80)
+	}
+	body := append(append([]byte{0x00, 0x41}, leb...), 0x0b)
+	export := append([]byte{0x01, byte(len(engine.ExportABIVersion))}, engine.ExportABIVersion...)
+	export = append(export, 0x00, 0x00)
+
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	module = append(module, 0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f) // Type () -> i32
+	module = append(module, 0x03, 0x02, 0x01, 0x00)                   // One function of it
+	module = append(append(module, 0x07, byte(len(export))), export...)
+	module = append(module, 0x0a, byte(len(body)+2), 0x01, byte(len(body)))
+	return append(module, body...)
+}
+
+// Describe declares the conformance specs of e, loading the test
+// plugins from below the directory plugins, under a container named after
+// the engine.
+func Describe(e engine.Engine, plugins string) bool {
+	return ginkgo.Describe(e.Name(), func() {
+		// path returns the path of a test plugin, skipping the spec if it
+		// is not built
+		path := func(name string) string {
+			path := filepath.Join(plugins, name, name+".wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				ginkgo.Skip("Test plugin not found: " + path)
+			}
+			return path
+		}
+
+		// load loads path and closes the instance when the spec ends
+		load := func(path string, opts engine.LoadOptions) engine.Instance {
+			instance, err := e.Load(path, opts)
+			Expect(err).NotTo(HaveOccurred(), "loading %s", path)
+			ginkgo.DeferCleanup(instance.Close)
+			return instance
+		}
+
+		// initialized loads a test plugin and initializes it
+		initialized := func(name string) engine.Instance {
+			instance := load(path(name), engine.LoadOptions{})
+			Expect(instance.Init()).To(Succeed())
+			return instance
+		}
+
+		// =====================================================================
+		// TEST: Lifecycle
+		// Why: Pools drive every instance through load, init, calls,
+		//      cleanup, and close; each step must succeed or fail the same
+		//      way on every engine.
+		// =====================================================================
+		ginkgo.Context("lifecycle", func() {
+			ginkgo.It("should run a plugin through init, process, and cleanup", func() {
+				instance := load(path("hello"), engine.LoadOptions{})
+				Expect(instance.Init()).To(Succeed())
+				Expect(instance.ExecuteContext(context.Background(), 0)).To(Equal(1))
+				Expect(instance.ExecuteContext(context.Background(), 21)).To(Equal(43))
+				Expect(instance.Cleanup()).To(Succeed())
+			})
+
+			ginkgo.It("should report ABI error codes as plugin errors", func() {
+				instance := load(path("hello"), engine.LoadOptions{})
+
+				// hello returns ABI_ERROR_NOT_INITIALIZED before init()
+				_, err := instance.ExecuteContext(context.Background(), 1)
+				var pluginErr *engine.PluginError
+				Expect(errors.As(err, &pluginErr)).To(BeTrue(), "%v", err)
+				Expect(pluginErr.Function).To(Equal("process"))
+				Expect(pluginErr.Code).To(Equal(int32(engine.ABIErrorNotInitialized)))
+
+				calc := initialized("calc")
+				_, err = calc.ExecuteContext(context.Background(), -5)
+				Expect(errors.As(err, &pluginErr)).To(BeTrue(), "%v", err)
+				Expect(pluginErr.Message).To(Equal("input must not be negative"))
+			})
+
+			ginkgo.It("should reject missing and invalid modules", func() {
+				_, err := e.Load(filepath.Join(ginkgo.GinkgoT().TempDir(), "missing.wasm"), engine.LoadOptions{})
+				Expect(err).To(HaveOccurred())
+
+				invalid := filepath.Join(ginkgo.GinkgoT().TempDir(), "invalid.wasm")
+				Expect(os.WriteFile(invalid, []byte("not a wasm module"), 0644)).To(Succeed())
+				instance, err := e.Load(invalid, engine.LoadOptions{})
+				Expect(err).To(HaveOccurred())
+				Expect(instance).To(BeNil())
+			})
+
+			ginkgo.It("should fail calls on a closed instance instead of crashing", func() {
+				instance, err := e.Load(path("hello"), engine.LoadOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instance.Init()).To(Succeed())
+				instance.Close()
+
+				_, err = instance.ExecuteContext(context.Background(), 1)
+				Expect(err).To(HaveOccurred())
+				Expect(instance.Cleanup()).NotTo(Succeed())
+				instance.Close() // A second Close is harmless
+			})
+		})
+
+		// =====================================================================
+		// TEST: ABI version negotiation
+		// Why: A plugin built against another major ABI version would be
+		//      called with the wrong contract; every engine must refuse it
+		//      at load, unless the host asks to check the version itself.
+		// =====================================================================
+		ginkgo.Context("ABI version", func() {
+			// write writes a plugin reporting version
+			write := func(version int32) string {
+				path := filepath.Join(ginkgo.GinkgoT().TempDir(), "versioned.wasm")
+				Expect(os.WriteFile(path, versioned(version), 0644)).To(Succeed())
+				return path
+			}
+
+			ginkgo.It("should report the version of supported plugins", func() {
+				instance := load(write(10200), engine.LoadOptions{RequireABIVersion: true})
+				Expect(instance.ABIVersion()).To(Equal(engine.ABIVersion(10200)))
+			})
+
+			ginkgo.It("should reject versions outside the supported range unless relaxed", func() {
+				for _, version := range []int32{20000, 9900, 0, -1} {
+					instance, err := e.Load(write(version), engine.LoadOptions{})
+					Expect(errors.Is(err, engine.ErrABIVersion)).To(BeTrue(), "version %d: %v", version, err)
+					Expect(instance).To(BeNil())
+				}
+
+				instance := load(write(20000), engine.LoadOptions{AnyABIVersion: true})
+				Expect(instance.ABIVersion()).To(Equal(engine.ABIVersion(20000)))
+			})
+
+			ginkgo.It("should load unversioned plugins unless a version is required", func() {
+				Expect(load(path("hello"), engine.LoadOptions{}).ABIVersion()).To(BeZero())
+
+				instance, err := e.Load(path("hello"), engine.LoadOptions{RequireABIVersion: true})
+				Expect(errors.Is(err, engine.ErrABIVersion)).To(BeTrue(), "%v", err)
+				Expect(instance).To(BeNil())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Limits
+		// Why: The memory limit keeps a plugin from exhausting the host; it
+		//      must be enforced at load and on growth, and be reported as
+		//      ErrMemoryLimit so the server can tell it from other failures.
+		// =====================================================================
+		ginkgo.Context("limits", func() {
+			var (
+				calcPath string
+				pages    int
+			)
+
+			ginkgo.BeforeEach(func() {
+				calcPath = path("calc")
+				wasm, err := os.ReadFile(calcPath)
+				Expect(err).NotTo(HaveOccurred())
+				module, err := wasminfo.Parse(wasm)
+				Expect(err).NotTo(HaveOccurred())
+				pages = module.MemoryPages
+			})
+
+			ginkgo.It("should reject a module whose initial memory exceeds the limit", func() {
+				instance, err := e.Load(calcPath, engine.LoadOptions{MaxMemoryPages: pages - 1})
+				Expect(errors.Is(err, engine.ErrMemoryLimit)).To(BeTrue(), "%v", err)
+				Expect(instance).To(BeNil())
+			})
+
+			ginkgo.It("should stop memory from growing past the limit", func() {
+				instance := load(calcPath, engine.LoadOptions{MaxMemoryPages: pages + 1})
+				Expect(instance.MemoryLimit()).To(Equal(uint(pages + 1)))
+
+				_, err := instance.CallContext(context.Background(), "reserve", 1)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = instance.CallContext(context.Background(), "reserve", 1)
+				Expect(errors.Is(err, engine.ErrMemoryLimit)).To(BeTrue(), "%v", err)
+			})
+		})
+
+		// =====================================================================
+		// TEST: Interruption
+		// Why: Execution timeouts are only as good as the engine's ability
+		//      to stop a plugin that never returns.
+		// =====================================================================
+		ginkgo.Context("interruption", func() {
+			ginkgo.It("should stop a runaway call when its deadline passes", func() {
+				instance := initialized("spin")
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+
+				started := time.Now()
+				_, err := instance.ExecuteContext(ctx, 1)
+				Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue(), "%v", err)
+				Expect(time.Since(started)).To(BeNumerically("<", interruptWithin))
+			})
+
+			ginkgo.It("should stop a runaway call when it is cancelled", func() {
+				instance := initialized("spin")
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+
+				_, err := instance.ExecuteContext(ctx, 1)
+				Expect(errors.Is(err, context.Canceled)).To(BeTrue(), "%v", err)
+			})
+
+			ginkgo.It("should not start calls whose context is already done", func() {
+				instance := initialized("hello")
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				_, err := instance.ExecuteContext(ctx, 1)
+				Expect(errors.Is(err, context.Canceled)).To(BeTrue(), "%v", err)
+			})
+
+			ginkgo.It("should not start an init() whose context is already done", func() {
+				instance := load(path("hello"), engine.LoadOptions{})
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err := instance.InitContext(ctx)
+				Expect(errors.Is(err, context.Canceled)).To(BeTrue(), "%v", err)
+				Expect(instance.InitContext(context.Background())).To(Succeed())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Traps
+		// Why: A trapping plugin must fail its call, not the host, and must
+		//      not be mistaken for a plugin reporting an error code.
+		// =====================================================================
+		ginkgo.Context("traps", func() {
+			ginkgo.DescribeTable("should fail the call with an error",
+				func(input int) {
+					instance := initialized("trap")
+					_, err := instance.ExecuteContext(context.Background(), input)
+					Expect(err).To(HaveOccurred())
+					var pluginErr *engine.PluginError
+					Expect(errors.As(err, &pluginErr)).To(BeFalse(), "a trap is no ABI error code: %v", err)
+					Expect(errors.Is(err, engine.ErrMemoryLimit)).To(BeFalse())
+				},
+				ginkgo.Entry("unreachable", -1),
+				ginkgo.Entry("integer division by zero", 0),
+			)
+
+			ginkgo.It("should keep other instances usable after a trap", func() {
+				trapped := initialized("trap")
+				_, err := trapped.ExecuteContext(context.Background(), -1)
+				Expect(err).To(HaveOccurred())
+
+				fresh := initialized("trap")
+				Expect(fresh.ExecuteContext(context.Background(), 7)).To(Equal(7))
+			})
+		})
+
+		// =====================================================================
+		// TEST: Memory passing
+		// Why: The payload ABI and Call copy data through guest memory; it
+		//      must arrive intact at every size, and repeated calls must not
+		//      leak guest memory.
+		// =====================================================================
+		ginkgo.Context("memory passing", func() {
+			ginkgo.It("should pass payloads of any size through process_bytes", func() {
+				instance := initialized("upper")
+				Expect(instance.SupportsPayloads()).To(BeTrue())
+
+				for _, size := range []int{0, 1, 4095, 4096, 64 << 10} {
+					input := bytes.Repeat([]byte("ab"), size/2+1)[:size]
+					output, err := instance.ExecuteBytesContext(context.Background(), input)
+					Expect(err).NotTo(HaveOccurred(), "size %d", size)
+					Expect(output).To(Equal(bytes.ToUpper(input)), "size %d", size)
+				}
+			})
+
+			ginkgo.It("should pass binary data unchanged", func() {
+				instance := initialized("upper")
+				input := make([]byte, 256)
+				for i := range input {
+					input[i] = byte(i)
+				}
+				output, err := instance.ExecuteBytesContext(context.Background(), input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).To(Equal(bytes.ToUpper(input)))
+			})
+
+			ginkgo.It("should not exhaust guest memory across many calls", func() {
+				instance := initialized("upper")
+				input := bytes.Repeat([]byte("x"), 32<<10)
+				for range 200 {
+					_, err := instance.ExecuteBytesContext(context.Background(), input)
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			ginkgo.It("should marshal arguments of Call through guest memory", func() {
+				instance := initialized("calc")
+				result, err := instance.CallContext(context.Background(), "count_byte", "banana", 'a')
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal([]interface{}{int32(3)}))
+
+				result, err = instance.CallContext(context.Background(), "sum_i64", []int64{1 << 40, 2, 3})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal([]interface{}{int64(1<<40 + 5)}))
+			})
+
+			ginkgo.It("should report plugins without the payload ABI", func() {
+				Expect(initialized("hello").SupportsPayloads()).To(BeFalse())
+			})
+		})
+
+		// =====================================================================
+		// TEST: Concurrency
+		// Why: Pools run instances of one plugin on many goroutines at once,
+		//      and may hand one instance to goroutines in turn; instances
+		//      must not share state, and one instance must serialize calls.
+		// =====================================================================
+		ginkgo.Context("concurrent use", func() {
+			ginkgo.It("should run separate instances in parallel", func() {
+				instances := make([]engine.Instance, concurrency)
+				for i := range instances {
+					instances[i] = initialized("upper")
+				}
+
+				var wg sync.WaitGroup
+				errs := make(chan error, concurrency)
+				for i, instance := range instances {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer ginkgo.GinkgoRecover()
+						input := bytes.Repeat([]byte{'a' + byte(i)}, 1024)
+						for range 50 {
+							output, err := instance.ExecuteBytesContext(context.Background(), input)
+							if err != nil {
+								errs <- err
+								return
+							}
+							Expect(output).To(Equal(bytes.ToUpper(input)))
+						}
+					}()
+				}
+				wg.Wait()
+				close(errs)
+				Expect(errs).To(BeEmpty())
+			})
+
+			ginkgo.It("should serialize calls into one instance from many goroutines", func() {
+				instance := initialized("hello")
+
+				var wg sync.WaitGroup
+				for i := range concurrency {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer ginkgo.GinkgoRecover()
+						for j := range 50 {
+							input := i*100 + j
+							Expect(instance.ExecuteContext(context.Background(), input)).To(Equal(input*2 + 1))
+						}
+					}()
+				}
+				wg.Wait()
+			})
+
+			ginkgo.It("should load and close instances concurrently", func() {
+				helloPath := path("hello")
+				var wg sync.WaitGroup
+				for range concurrency {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer ginkgo.GinkgoRecover()
+						instance, err := e.Load(helloPath, engine.LoadOptions{})
+						Expect(err).NotTo(HaveOccurred())
+						defer instance.Close()
+						Expect(instance.Init()).To(Succeed())
+						Expect(instance.ExecuteContext(context.Background(), 1)).To(Equal(3))
+						Expect(instance.Cleanup()).To(Succeed())
+					}()
+				}
+				wg.Wait()
+			})
+		})
+	})
+}
@@ -0,0 +1,22 @@
+package enginetest_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+	"github.com/mrhapile/wasm-plugin-system/runtime/enginetest"
+)
+
+// TestEnginetest bootstraps the Ginkgo test suite checking the WasmEdge
+// engine against the conformance specs.
+// Run with: go test -v ./runtime/enginetest/...
+func TestEnginetest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Engine Conformance Suite")
+}
+
+var _ = enginetest.Describe(runtime.WasmEdge, filepath.Join("..", "..", "plugins"))
@@ -0,0 +1,102 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ErrMemoryLimit is wrapped by errors from plugins that need more linear
+// memory than their limit allows; see engine.ErrMemoryLimit.
+var ErrMemoryLimit = engine.ErrMemoryLimit
+
+// ErrInstructionLimit is wrapped by errors from calls stopped for executing
+// more instructions than LoadOptions.MaxInstructions allows.
+var ErrInstructionLimit = errors.New("plugin instruction limit exceeded")
+
+// ErrFileQuota is wrapped by errors from calls that left more in their
+// scratch directory than WASIOptions.ScratchMaxBytes or ScratchMaxFiles
+// allow.
+var ErrFileQuota = errors.New("plugin file quota exceeded")
+
+// ExportLastError is the optional export through which a plugin explains
+// the error code it most recently returned; see engine.ExportLastError.
+const ExportLastError = engine.ExportLastError
+
+// PluginError is returned when a plugin export reports a negative ABI error
+// code; see engine.PluginError.
+type PluginError = engine.PluginError
+
+// memoryLimitError marks a failed call as caused by the memory limit if the
+// plugin's memory reached it; otherwise err is returned unchanged. A guest
+// whose memory.grow is refused typically traps or fails to allocate, so
+// this is the only trace the limit leaves.
+func (p *Plugin) memoryLimitError(err error) error {
+	if err == nil || p.memoryLimit == 0 || errors.Is(err, ErrMemoryLimit) {
+		return err
+	}
+	if pages, _, ok := p.memoryPages(); ok && pages >= p.memoryLimit {
+		return fmt.Errorf("%w (%d pages): %w", ErrMemoryLimit, p.memoryLimit, err)
+	}
+	return err
+}
+
+// instructionLimitError wraps err with ErrInstructionLimit if the failed
+// call ran into its cost limit.
+func (p *Plugin) instructionLimitError(err error) error {
+	if err == nil || p.stats == nil || errors.Is(err, ErrInstructionLimit) {
+		return err
+	}
+	if p.stats.GetTotalCost() >= p.costLimit {
+		return fmt.Errorf("%w (%d instructions): %w", ErrInstructionLimit, p.instructionLimit, err)
+	}
+	return err
+}
+
+// pluginError builds the error for a negative code returned by function,
+// asking the plugin for its explanation.
+func (p *Plugin) pluginError(function string, code int32) *PluginError {
+	return &PluginError{
+		Function: function,
+		Code:     code,
+		Message:  p.lastError(),
+		Path:     p.path,
+	}
+}
+
+// lastError returns the message reported by last_error(), or "" if the
+// plugin does not export it, reports no message, or reports one that lies
+// outside linear memory. A failure to explain an error must not hide the
+// error itself.
+func (p *Plugin) lastError() string {
+	if !p.HasExport(ExportLastError) {
+		return ""
+	}
+	result, err := p.vm.Execute(ExportLastError)
+	if err != nil || len(result) == 0 {
+		return ""
+	}
+	packed, ok := result[0].(int64)
+	if !ok || packed <= 0 {
+		return ""
+	}
+
+	ptr := uint32(packed >> 32)
+	size := int(uint32(packed))
+	truncated := size > engine.MaxErrorMessage
+	if truncated {
+		size = engine.MaxErrorMessage
+	}
+	data, err := p.readMemory(ptr, size)
+	if err != nil {
+		return ""
+	}
+
+	message := strings.ToValidUTF8(string(data), "�")
+	if truncated {
+		message += "..."
+	}
+	return message
+}
@@ -0,0 +1,61 @@
+package runtime_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("PluginError", func() {
+	It("should format the code, its name, and the plugin's message", func() {
+		err := &runtime.PluginError{
+			Function: "process",
+			Code:     runtime.ABIErrorInvalidInput,
+			Message:  "input must not be negative",
+			Path:     "calc.wasm",
+		}
+
+		Expect(err.Name()).To(Equal("ABI_ERROR_INVALID_INPUT"))
+		Expect(err.Error()).To(Equal("process() returned error code -3 for calc.wasm: " +
+			"ABI_ERROR_INVALID_INPUT: input must not be negative"))
+	})
+
+	It("should omit an empty message", func() {
+		err := &runtime.PluginError{Function: "init", Code: runtime.ABIErrorInternal, Path: "x.wasm"}
+
+		Expect(err.Error()).To(Equal("init() returned error code -4 for x.wasm: ABI_ERROR_INTERNAL"))
+	})
+
+	// =========================================================================
+	// TEST: Messages from last_error()
+	// Why: The message is the only way to learn why a plugin rejected its
+	//      input; it must reach the caller through the typed error.
+	// =========================================================================
+	DescribeTable("should decode the message reported by the plugin",
+		func(name, message string) {
+			path := filepath.Join("..", "plugins", name, name+".wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			plugin, err := runtime.LoadPlugin(path)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.Init()).To(Succeed())
+
+			_, err = plugin.Execute(-5)
+
+			var pluginErr *runtime.PluginError
+			Expect(errors.As(err, &pluginErr)).To(BeTrue(), "%v", err)
+			Expect(pluginErr.Function).To(Equal("process"))
+			Expect(pluginErr.Message).To(Equal(message))
+		},
+		Entry("plugin exporting last_error", "calc", "input must not be negative"),
+		Entry("plugin without last_error", "hello", ""),
+	)
+})
@@ -1,18 +1,39 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sync"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ABI error codes returned by plugin functions, as the engine package
+// defines them.
+const (
+	ABISuccess                 = engine.ABISuccess
+	ABIErrorNotInitialized     = engine.ABIErrorNotInitialized
+	ABIErrorAlreadyInitialized = engine.ABIErrorAlreadyInitialized
+	ABIErrorInvalidInput       = engine.ABIErrorInvalidInput
+	ABIErrorInternal           = engine.ABIErrorInternal
+	ABINoOutput                = engine.ABINoOutput
 )
 
-// ABI error codes returned by plugin functions
+// Optional exports of the core ABI, as the engine package defines them:
+// the initializer receiving config bytes, the self-check, and the
+// notification before an instance is discarded.
 const (
-	ABISuccess                 = 0  // Operation completed successfully
-	ABIErrorNotInitialized     = -1 // Plugin not initialized (init not called)
-	ABIErrorAlreadyInitialized = -2 // Plugin already initialized (init called twice)
-	ABIErrorInvalidInput       = -3 // Invalid input parameter
-	ABIErrorInternal           = -4 // Internal plugin error
+	ExportInitWithConfig = engine.ExportInitWithConfig
+	ExportHealth         = engine.ExportHealth
+	ExportOnShutdown     = engine.ExportOnShutdown
 )
 
+// ErrNoOutput is returned by the Execute family when the plugin succeeded
+// without producing a result; see engine.ErrNoOutput. The instance is
+// healthy and may be reused.
+var ErrNoOutput = engine.ErrNoOutput
+
 // Init initializes the plugin by calling its exported "init" function.
 //
 // This must be called once before any Execute() calls. Calling Init() multiple
@@ -20,16 +41,31 @@ const (
 //
 // Returns an error if:
 // - The plugin does not export an "init" function
-// - The init function returns a non-zero error code
+// - The init function returns a non-zero error code (a *PluginError)
 // - The VM is in an invalid state
+//
+// If the plugin's manifest has a config block, Init() passes it to
+// init_with_config() instead (see InitWithConfig).
 func (p *Plugin) Init() error {
+	return p.InitContext(context.Background())
+}
+
+// InitContext is Init with cancellation of the init() call, as in
+// ExecuteContext, so that a plugin stuck in init() cannot hold its caller
+// forever.
+func (p *Plugin) InitContext(ctx context.Context) error {
+	defer p.lock()()
+
 	if p.vm == nil {
 		return fmt.Errorf("plugin is closed")
 	}
+	if p.manifest != nil && len(p.manifest.Config) > 0 {
+		return p.initWithConfig(ctx, p.manifest.Config)
+	}
 
 	// Call the exported "init" function
 	// Expected signature: int init()
-	result, err := p.vm.Execute("init")
+	result, err := p.call(ctx, "init")
 	if err != nil {
 		return fmt.Errorf("failed to execute init() for %s: %w", p.path, err)
 	}
@@ -44,8 +80,80 @@ func (p *Plugin) Init() error {
 
 	// Check for error codes
 	if returnCode != ABISuccess {
-		return fmt.Errorf("init() returned error code %d for %s: %s",
-			returnCode, p.path, abiErrorString(returnCode))
+		return p.pluginError("init", returnCode)
+	}
+
+	return nil
+}
+
+// InitWithConfig initializes the plugin by calling its exported
+// "init_with_config" function with configuration bytes, in place of Init().
+//
+// The bytes are copied into a buffer from the plugin's allocate() export
+// and released with deallocate() once init_with_config returns, so the
+// plugin must copy anything it keeps. An empty config is passed as (0, 0)
+// and does not require the allocator.
+//
+// Returns an error if:
+// - The plugin does not export an "init_with_config" function
+// - The config exceeds MaxPayloadSize
+// - init_with_config returns a non-zero error code (a *PluginError)
+func (p *Plugin) InitWithConfig(config []byte) error {
+	return p.InitWithConfigContext(context.Background(), config)
+}
+
+// InitWithConfigContext is InitWithConfig with cancellation of the
+// init_with_config() call, as in ExecuteContext.
+func (p *Plugin) InitWithConfigContext(ctx context.Context, config []byte) error {
+	defer p.lock()()
+
+	return p.initWithConfig(ctx, config)
+}
+
+// initWithConfig implements InitWithConfigContext for callers holding the
+// lock.
+func (p *Plugin) initWithConfig(ctx context.Context, config []byte) error {
+	if p.vm == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if !p.HasExport(ExportInitWithConfig) {
+		return fmt.Errorf("plugin %s does not export %s and cannot be configured",
+			p.path, ExportInitWithConfig)
+	}
+	if len(config) > MaxPayloadSize {
+		return fmt.Errorf("config of %d bytes exceeds the %d byte payload limit",
+			len(config), MaxPayloadSize)
+	}
+
+	// Copy the config into guest memory
+	var ptr uint32
+	if len(config) > 0 {
+		if !p.HasExport(ExportAllocate) || !p.HasExport(ExportDeallocate) {
+			return fmt.Errorf("plugin %s must export %s and %s to receive config",
+				p.path, ExportAllocate, ExportDeallocate)
+		}
+		var err error
+		if ptr, err = p.allocate(len(config)); err != nil {
+			return err
+		}
+		defer p.deallocate(ptr, len(config))
+		if err := p.writeMemory(ptr, config); err != nil {
+			return err
+		}
+	}
+
+	// Expected signature: int init_with_config(int ptr, int len)
+	result, err := p.call(ctx, ExportInitWithConfig, int32(ptr), int32(len(config)))
+	if err != nil {
+		return fmt.Errorf("failed to execute %s() for %s: %w", ExportInitWithConfig, p.path, err)
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("%s() did not return a value for %s", ExportInitWithConfig, p.path)
+	}
+
+	returnCode := result[0].(int32)
+	if returnCode != ABISuccess {
+		return p.pluginError(ExportInitWithConfig, returnCode)
 	}
 
 	return nil
@@ -56,41 +164,148 @@ func (p *Plugin) Init() error {
 // The plugin must be initialized with Init() before calling Execute().
 // Execute() can be called multiple times after a successful Init().
 //
-// Returns the result value from the plugin, or an error if:
+// Returns the result value from the plugin, ErrNoOutput if the plugin
+// succeeded without a result, or an error if:
 // - The plugin does not export a "process" function
-// - The process function returns a negative error code
+// - The process function returns a negative error code (a *PluginError)
 // - The VM is in an invalid state
 func (p *Plugin) Execute(input int) (int, error) {
+	return p.ExecuteContext(context.Background(), input)
+}
+
+// ExecuteContext is Execute with cancellation: when ctx is done before
+// process() returns, the VM is interrupted and ctx.Err() is returned
+// (wrapped, so errors.Is(err, context.DeadlineExceeded) reports timeouts).
+//
+// An interrupted instance is left mid-call and must be discarded, not reused.
+func (p *Plugin) ExecuteContext(ctx context.Context, input int) (output int, err error) {
+	ctx, span := p.startExecuteSpan(ctx, "process")
+	defer func() { endSpan(span, err) }()
+	defer p.lock()()
+
 	if p.vm == nil {
 		return 0, fmt.Errorf("plugin is closed")
 	}
 
 	// Call the exported "process" function with int32 argument
 	// Expected signature: int process(int)
-	result, err := p.vm.Execute("process", int32(input))
+	result, err := p.call(ctx, "process", int32(input))
 	if err != nil {
 		return 0, fmt.Errorf("failed to execute process(%d) for %s: %w",
 			input, p.path, err)
 	}
 
-	// Check that we got a return value
+	// A process() declared without a result never has output
 	if len(result) == 0 {
-		return 0, fmt.Errorf("process() did not return a value for %s", p.path)
+		return 0, fmt.Errorf("process() for %s: %w", p.path, ErrNoOutput)
 	}
 
 	// Extract return value (i32 -> int32)
 	returnValue := result[0].(int32)
 
-	// Check for error codes (negative values indicate errors)
+	// Check for error codes (negative values other than ABINoOutput are errors)
+	if returnValue == ABINoOutput {
+		return 0, fmt.Errorf("process() for %s: %w", p.path, ErrNoOutput)
+	}
 	if returnValue < 0 {
-		return 0, fmt.Errorf("process() returned error code %d for %s: %s",
-			returnValue, p.path, abiErrorString(returnValue))
+		return 0, p.pluginError("process", returnValue)
 	}
 
 	// Success - return the computed result
 	return int(returnValue), nil
 }
 
+// call executes an exported function, recording the call in the Trace
+// carried by ctx, if any.
+func (p *Plugin) call(ctx context.Context, name string, params ...interface{}) ([]interface{}, error) {
+	trace := engine.TraceFrom(ctx)
+	if trace == nil {
+		return p.execute(ctx, name, params...)
+	}
+
+	trace.SetWASI(p.wasiImports())
+	event := trace.Begin(TraceExport, "", name, params)
+	result, err := p.execute(ctx, name, params...)
+	trace.End(event, result, nil, err)
+	return result, err
+}
+
+// execute runs an exported function, interrupting the VM if ctx is done
+// first, or if the call goes over its file quota. Contexts that can never
+// be done take the synchronous path.
+func (p *Plugin) execute(ctx context.Context, name string, params ...interface{}) ([]interface{}, error) {
+	if p.host != nil {
+		defer p.host.end(p.host.begin(ctx))
+	}
+	if p.stats != nil {
+		// Costs accumulate over the VM's lifetime; this call gets its own
+		// allowance on top of them. Calls the host makes outside execute
+		// (allocate, deallocate, cleanup) must not fail on a spent one.
+		p.costLimit = p.stats.GetTotalCost() + p.instructionLimit
+		p.stats.SetCostLimit(p.costLimit)
+		defer p.stats.SetCostLimit(math.MaxUint)
+	}
+	quota := p.scratch != "" && hasFileQuota(p.options.WASI)
+	if quota {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		go watchScratch(ctx, cancel, p.scratch, p.options.WASI)
+	}
+	if ctx.Done() == nil {
+		result, err := p.vm.Execute(name, params...)
+		return result, p.callError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	async := p.vm.AsyncExecute(name, params...)
+	defer async.Release()
+
+	// Cancel from a watcher goroutine; it must exit before Release()
+	stop := make(chan struct{})
+	var watcher sync.WaitGroup
+	watcher.Add(1)
+	go func() {
+		defer watcher.Done()
+		select {
+		case <-ctx.Done():
+			async.Cancel()
+		case <-stop:
+		}
+	}()
+
+	result, err := async.GetResult()
+	close(stop)
+	watcher.Wait()
+
+	// WasmEdge reports an interrupted call as a generic failure; surface
+	// the context error, or the quota error that caused it, so callers can
+	// tell timeouts and quotas from traps
+	if err != nil && ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	// A call may go over its quota between two measurements and return
+	if err == nil && quota {
+		if err := checkScratch(p.scratch, p.options.WASI); err != nil {
+			return nil, err
+		}
+	}
+	return result, p.callError(err)
+}
+
+// callError attributes a failed call to the host function that trapped it,
+// the memory limit, or the instruction limit, if any applies.
+func (p *Plugin) callError(err error) error {
+	if err != nil && p.host != nil {
+		if hostErr := p.host.takeError(); hostErr != nil {
+			return fmt.Errorf("%w: %w", hostErr, err)
+		}
+	}
+	return p.instructionLimitError(p.memoryLimitError(err))
+}
+
 // Cleanup calls the plugin's "cleanup" function to release any resources.
 //
 // This should be called when the plugin is no longer needed, before Close().
@@ -98,9 +313,11 @@ func (p *Plugin) Execute(input int) (int, error) {
 //
 // Returns an error if:
 // - The plugin does not export a "cleanup" function
-// - The cleanup function returns a non-zero error code
+// - The cleanup function returns a non-zero error code (a *PluginError)
 // - The VM is in an invalid state
 func (p *Plugin) Cleanup() error {
+	defer p.lock()()
+
 	if p.vm == nil {
 		return fmt.Errorf("plugin is closed")
 	}
@@ -122,27 +339,79 @@ func (p *Plugin) Cleanup() error {
 
 	// Check for error codes
 	if returnCode != ABISuccess {
-		return fmt.Errorf("cleanup() returned error code %d for %s: %s",
-			returnCode, p.path, abiErrorString(returnCode))
+		return p.pluginError("cleanup", returnCode)
 	}
 
 	return nil
 }
 
-// abiErrorString converts ABI error codes to human-readable strings.
-func abiErrorString(code int32) string {
-	switch code {
-	case ABISuccess:
-		return "success"
-	case ABIErrorNotInitialized:
-		return "ABI_ERROR_NOT_INITIALIZED"
-	case ABIErrorAlreadyInitialized:
-		return "ABI_ERROR_ALREADY_INITIALIZED"
-	case ABIErrorInvalidInput:
-		return "ABI_ERROR_INVALID_INPUT"
-	case ABIErrorInternal:
-		return "ABI_ERROR_INTERNAL"
-	default:
-		return fmt.Sprintf("unknown error code %d", code)
+// Health calls the plugin's "health" export, if it has one.
+//
+// Plugins without the export are always healthy. Returns an error if:
+// - The health function returns a non-zero error code (a *PluginError)
+// - The health function traps or the VM is in an invalid state
+func (p *Plugin) Health() error {
+	return p.HealthContext(context.Background())
+}
+
+// HealthContext is Health with cancellation, as in ExecuteContext. A plugin
+// that does not answer before ctx is done is unhealthy.
+func (p *Plugin) HealthContext(ctx context.Context) error {
+	defer p.lock()()
+
+	if p.vm == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if !p.HasExport(ExportHealth) {
+		return nil
+	}
+
+	// Expected signature: int health()
+	result, err := p.call(ctx, ExportHealth)
+	if err != nil {
+		return fmt.Errorf("failed to execute %s() for %s: %w", ExportHealth, p.path, err)
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("%s() did not return a value for %s", ExportHealth, p.path)
 	}
+
+	returnCode := result[0].(int32)
+	if returnCode != ABISuccess {
+		return p.pluginError(ExportHealth, returnCode)
+	}
+
+	return nil
+}
+
+// Shutdown notifies the plugin that the instance is about to be discarded by
+// calling its "on_shutdown" export, if it has one. When ctx is done first,
+// the call is interrupted and ctx.Err() is returned (wrapped).
+//
+// Shutdown does not release anything; callers still call Cleanup() and
+// Close(). Plugins without the export have nothing to flush.
+func (p *Plugin) Shutdown(ctx context.Context) error {
+	defer p.lock()()
+
+	if p.vm == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if !p.HasExport(ExportOnShutdown) {
+		return nil
+	}
+
+	// Expected signature: int on_shutdown()
+	result, err := p.call(ctx, ExportOnShutdown)
+	if err != nil {
+		return fmt.Errorf("failed to execute %s() for %s: %w", ExportOnShutdown, p.path, err)
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("%s() did not return a value for %s", ExportOnShutdown, p.path)
+	}
+
+	returnCode := result[0].(int32)
+	if returnCode != ABISuccess {
+		return p.pluginError(ExportOnShutdown, returnCode)
+	}
+
+	return nil
 }
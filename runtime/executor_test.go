@@ -1,13 +1,17 @@
 package runtime_test
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/mrhapile/wasm-plugin-system/manifest"
 	"github.com/mrhapile/wasm-plugin-system/runtime"
 )
 
@@ -239,3 +243,211 @@ var _ = Describe("Executor", func() {
 		})
 	})
 })
+
+// =========================================================================
+// TEST: ExecuteContext cancellation
+// Why: A plugin stuck in a loop must not hang its caller; the VM has to be
+//      interrupted and the context error surfaced so callers can report a
+//      timeout rather than a generic failure.
+// =========================================================================
+var _ = Describe("ExecuteContext", func() {
+	load := func(name string) *runtime.Plugin {
+		path := filepath.Join("..", "plugins", name, name+".wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+
+		plugin, err := runtime.LoadPlugin(path)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		Expect(plugin.Init()).To(Succeed())
+		return plugin
+	}
+
+	It("should run normally with a live context", func() {
+		plugin := load("hello")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		result, err := plugin.ExecuteContext(ctx, 21)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(43))
+	})
+
+	It("should not start when the context is already canceled", func() {
+		plugin := load("hello")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := plugin.ExecuteContext(ctx, 21)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should interrupt a plugin that never returns", func() {
+		plugin := load("spin")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := plugin.ExecuteContext(ctx, 1)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+})
+
+// =========================================================================
+// TEST: No output
+// Why: Side-effect plugins succeed without a result; callers must be able
+//      to tell that apart from both a zero result and a failure, and the
+//      instance must remain usable.
+// =========================================================================
+var _ = Describe("No output", func() {
+	var plugin *runtime.Plugin
+
+	BeforeEach(func() {
+		path := filepath.Join("..", "plugins", "notify", "notify.wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+
+		var err error
+		plugin, err = runtime.LoadPlugin(path)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		Expect(plugin.Init()).To(Succeed())
+	})
+
+	It("should report ErrNoOutput for a process() without a result", func() {
+		_, err := plugin.Execute(7)
+		Expect(err).To(MatchError(runtime.ErrNoOutput))
+
+		// The side effect happened and the instance is still usable
+		result, err := plugin.Call("get_last_input")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]interface{}{int32(7)}))
+	})
+
+	It("should report ErrNoOutput when process_bytes returns ABI_NO_OUTPUT", func() {
+		output, err := plugin.ExecuteString("abc")
+		Expect(err).To(MatchError(runtime.ErrNoOutput))
+		Expect(output).To(BeEmpty())
+	})
+})
+
+// =============================================================================
+// TEST: Configured initialization
+// Why: Config reaches the plugin only through init_with_config; it must be
+//      delivered from the manifest or an override, and a plugin rejecting it
+//      must fail initialization with its explanation.
+// =============================================================================
+var _ = Describe("InitWithConfig", func() {
+	var calcPath string
+
+	BeforeEach(func() {
+		wasm, err := os.ReadFile(filepath.Join("..", "plugins", "calc", "calc.wasm"))
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: calc.wasm")
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		// A private copy, so tests can place a manifest next to it
+		calcPath = filepath.Join(GinkgoT().TempDir(), "calc.wasm")
+		Expect(os.WriteFile(calcPath, wasm, 0644)).To(Succeed())
+	})
+
+	load := func() *runtime.Plugin {
+		plugin, err := runtime.LoadPlugin(calcPath)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		return plugin
+	}
+
+	It("should pass the config to the plugin", func() {
+		plugin := load()
+
+		Expect(plugin.InitWithConfig([]byte(`{"offset": 100}`))).To(Succeed())
+
+		Expect(plugin.Execute(5)).To(Equal(105))
+	})
+
+	It("should accept an empty config", func() {
+		plugin := load()
+
+		Expect(plugin.InitWithConfig(nil)).To(Succeed())
+
+		Expect(plugin.Execute(5)).To(Equal(5))
+	})
+
+	It("should report a config the plugin rejects", func() {
+		plugin := load()
+
+		err := plugin.InitWithConfig([]byte(`{"offset": "ten"}`))
+
+		var pluginErr *runtime.PluginError
+		Expect(errors.As(err, &pluginErr)).To(BeTrue(), "%v", err)
+		Expect(pluginErr.Function).To(Equal(runtime.ExportInitWithConfig))
+		Expect(pluginErr.Message).To(Equal("offset must be an integer"))
+	})
+
+	It("should use the manifest's config in Init", func() {
+		data := []byte(`{"name": "calc", "version": "1.0.0", "config": {"offset": 7}}`)
+		Expect(os.WriteFile(filepath.Join(filepath.Dir(calcPath), manifest.FileName), data, 0644)).To(Succeed())
+		plugin := load()
+
+		Expect(plugin.Init()).To(Succeed())
+
+		Expect(plugin.Execute(5)).To(Equal(12))
+	})
+
+	It("should let the pool's config override the manifest's", func() {
+		data := []byte(`{"name": "calc", "version": "1.0.0", "config": {"offset": 7}}`)
+		Expect(os.WriteFile(filepath.Join(filepath.Dir(calcPath), manifest.FileName), data, 0644)).To(Succeed())
+
+		pool, err := runtime.NewPool(calcPath, runtime.PoolOptions{
+			Reset:  runtime.ResetRestore,
+			Config: []byte(`{"offset": 1000}`),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		// Restored instances keep the configured state
+		for i := 0; i < 2; i++ {
+			plugin, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Execute(5)).To(Equal(1005))
+			pool.Put(plugin)
+		}
+	})
+
+	It("should apply the pool's config overlays to the manifest's config", func() {
+		data := []byte(`{"name": "calc", "version": "1.0.0", "config": {"offset": 7, "unused": true}}`)
+		Expect(os.WriteFile(filepath.Join(filepath.Dir(calcPath), manifest.FileName), data, 0644)).To(Succeed())
+
+		pool, err := runtime.NewPool(calcPath, runtime.PoolOptions{
+			Reset:          runtime.ResetRecreate,
+			ConfigOverlays: [][]byte{[]byte(`{"offset": 50}`), []byte(`{"unused": null}`)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Put(plugin)
+		Expect(plugin.Execute(5)).To(Equal(55))
+	})
+
+	It("should reject a plugin without init_with_config", func() {
+		helloPath := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		if _, err := os.Stat(helloPath); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + helloPath)
+		}
+		plugin, err := runtime.LoadPlugin(helloPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer plugin.Close()
+
+		Expect(plugin.InitWithConfig([]byte(`{}`))).To(MatchError(ContainSubstring("does not export init_with_config")))
+	})
+})
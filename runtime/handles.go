@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"sync/atomic"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+)
+
+// Handles counts the WasmEdge objects the package holds on the C side. The
+// Go garbage collector cannot see them: a plugin or pool that is dropped
+// without Close, or a failed load that skips a Release, leaks them for the
+// life of the process. With every plugin closed, both counts are zero.
+type Handles struct {
+	VMs         int64 `json:"vms"`          // VMs of loaded plugins, with their configurations
+	HostModules int64 `json:"host_modules"` // Host module instances registered with those VMs
+}
+
+var liveVMs, liveHostModules atomic.Int64
+
+// LiveHandles returns the WasmEdge objects currently held, e.g. to check
+// for leaks after a test or a load cycle.
+func LiveHandles() Handles {
+	return Handles{VMs: liveVMs.Load(), HostModules: liveHostModules.Load()}
+}
+
+// newVM creates a VM with config and counts it. The VM must be released
+// with releaseVM.
+func newVM(config *wasmedge.Configure) *wasmedge.VM {
+	vm := wasmedge.NewVMWithConfig(config)
+	if vm != nil {
+		liveVMs.Add(1)
+	}
+	return vm
+}
+
+// releaseVM releases a VM from newVM together with its configuration.
+func releaseVM(vm *wasmedge.VM, config *wasmedge.Configure) {
+	vm.Release()
+	config.Release()
+	liveVMs.Add(-1)
+}
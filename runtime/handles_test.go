@@ -0,0 +1,47 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("LiveHandles", func() {
+	// =========================================================================
+	// TEST: Handle accounting
+	// Why: Leak checks (the soak specs) compare the counts before and after
+	//      a run; they must follow every load and Close exactly.
+	// =========================================================================
+	It("should count the handles of loaded plugins until they are closed", func() {
+		path := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+
+		before := runtime.LiveHandles()
+		plugin, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+			HostModules: []*runtime.HostModule{runtime.NewContextModule(), runtime.NewLogModule(nil)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runtime.LiveHandles()).To(Equal(runtime.Handles{
+			VMs:         before.VMs + 1,
+			HostModules: before.HostModules + 2,
+		}))
+
+		plugin.Close()
+		plugin.Close()
+		Expect(runtime.LiveHandles()).To(Equal(before))
+
+		// A load failing after the VM was created releases it
+		context := runtime.NewContextModule()
+		_, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+			HostModules: []*runtime.HostModule{context, context},
+		})
+		Expect(err).To(MatchError(ContainSubstring("registered twice")))
+		Expect(runtime.LiveHandles()).To(Equal(before))
+	})
+})
@@ -0,0 +1,224 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ValueType is a WebAssembly value type of a host function parameter or
+// result; see engine.ValueType.
+type ValueType = engine.ValueType
+
+// Value types host functions can take and return. Values are passed as
+// int32, int64, float32, and float64 respectively.
+const (
+	ValueI32 = engine.ValueI32
+	ValueI64 = engine.ValueI64
+	ValueF32 = engine.ValueF32
+	ValueF64 = engine.ValueF64
+)
+
+// HostFunc implements a host function. args hold one value per declared
+// parameter; the returned slice must hold one value per declared result.
+//
+// Returning an error traps the plugin: the call that reached the host
+// function fails with an error wrapping the one returned here.
+type HostFunc = engine.HostFunc
+
+// HostModule is a set of Go functions that plugins import under a module
+// name, letting the embedding service expose capabilities such as logging
+// or configuration lookups:
+//
+//	host := runtime.NewHostModule("host").
+//	    Func("log", []runtime.ValueType{runtime.ValueI32, runtime.ValueI32}, nil,
+//	        func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+//	            msg, err := call.ReadString(args[0].(int32), args[1].(int32))
+//	            if err != nil {
+//	                return nil, err
+//	            }
+//	            log.Printf("%s: %s", call.Path(), msg)
+//	            return nil, nil
+//	        })
+//
+//	plugin, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+//	    HostModules: []*runtime.HostModule{host},
+//	})
+//
+// A plugin imports the function by module and name:
+//
+//	__attribute__((import_module("host"), import_name("log")))
+//	void host_log(const char *msg, int len);
+//
+// A HostModule is a definition: every plugin loaded with it gets its own
+// instance, so one HostModule may be shared by any number of plugins and
+// pools, on any engine. Its functions may be called concurrently by
+// different plugins.
+type HostModule = engine.HostModule
+
+// HostCall is passed to a HostFunc. It gives access to the calling
+// plugin's linear memory, through which strings and buffers are passed as
+// (ptr, len) pairs. It is only valid for the duration of the call.
+type HostCall = engine.HostCall
+
+// NewHostModule creates an empty host module that plugins import as name.
+func NewHostModule(name string) *HostModule {
+	return engine.NewHostModule(name)
+}
+
+// wasiModule is the import module of the WASI functions the runtime
+// provides itself.
+const wasiModule = engine.WASIModule
+
+// wasmEdgeMemory is the linear memory of a WasmEdge instance, as host
+// functions reach it.
+type wasmEdgeMemory struct {
+	memory *wasmedge.Memory
+}
+
+// guestMemory returns memory as a GuestMemory, or nil if there is none.
+func guestMemory(memory *wasmedge.Memory) GuestMemory {
+	if memory == nil {
+		return nil
+	}
+	return wasmEdgeMemory{memory}
+}
+
+func (m wasmEdgeMemory) Read(ptr, size uint32) ([]byte, error) {
+	data, err := m.memory.GetData(uint(ptr), uint(size))
+	if err != nil {
+		return nil, err
+	}
+	// GetData aliases guest memory; copy before the guest can change it
+	return append([]byte(nil), data...), nil
+}
+
+func (m wasmEdgeMemory) Write(ptr uint32, data []byte) error {
+	return m.memory.SetData(data, uint(ptr), uint(len(data)))
+}
+
+func (m wasmEdgeMemory) Pages() (uint, bool) {
+	return m.memory.GetPageSize(), true
+}
+
+// hostState carries the plugin's current call to its host functions: the
+// call's context in, and the error of the host function that trapped the
+// call out, so that the call can report it.
+//
+// A reentrant plugin has several current calls; the state then holds the
+// most recent one's, so attribution is best effort.
+type hostState struct {
+	manifest *manifest.Manifest // Of the plugin, if any; set on load
+
+	mu    sync.Mutex
+	ctx   context.Context
+	err   error
+	calls uint64 // Calls begun, identifying the one ctx belongs to
+}
+
+// begin records the context of a new call, clears the error of the
+// previous one, and returns the call's number for end.
+func (s *hostState) begin(ctx context.Context) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.err = ctx, nil
+	s.calls++
+	return s.calls
+}
+
+// end forgets the context of the given call unless a later one replaced
+// it, so the instance does not keep request values alive while idle.
+func (s *hostState) end(call uint64) {
+	s.mu.Lock()
+	if s.calls == call {
+		s.ctx = nil
+	}
+	s.mu.Unlock()
+}
+
+// context returns the current call's context, or nil outside a call.
+func (s *hostState) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+// fail records the error of a host function that trapped the call.
+func (s *hostState) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// takeError returns and clears the recorded host function error.
+func (s *hostState) takeError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// instantiateHostModule creates a WasmEdge module instance of m for the
+// plugin at path. The caller registers it with the plugin's VM and releases
+// it after the VM with releaseModules.
+func instantiateHostModule(m *HostModule, path string, state *hostState) (*wasmedge.Module, error) {
+	functions, err := m.GuestFunctions(path, state.manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	module := wasmedge.NewModule(m.Name())
+	if module == nil {
+		return nil, fmt.Errorf("failed to create host module %s", m.Name())
+	}
+	for _, f := range functions {
+		f := f
+		ftype := wasmedge.NewFunctionType(wasmValTypes(f.Params), wasmValTypes(f.Results))
+		function := wasmedge.NewFunction(ftype, func(_ interface{}, frame *wasmedge.CallingFrame, params []interface{}) ([]interface{}, wasmedge.Result) {
+			results, err := f.Call(state.context(), guestMemory(frame.GetMemoryByIndex(0)), params)
+			if err != nil {
+				state.fail(err)
+				return nil, wasmedge.Result_Fail
+			}
+			return results, wasmedge.Result_Success
+		}, nil, 0)
+		ftype.Release()
+		if function == nil {
+			module.Release()
+			return nil, fmt.Errorf("failed to create host function %s.%s", m.Name(), f.Name)
+		}
+		module.AddFunction(f.Name, function)
+	}
+	liveHostModules.Add(1)
+	return module, nil
+}
+
+// wasmValType returns the WasmEdge type of t, or nil if it is unsupported.
+func wasmValType(t ValueType) *wasmedge.ValType {
+	switch t {
+	case ValueI32:
+		return wasmedge.NewValTypeI32()
+	case ValueI64:
+		return wasmedge.NewValTypeI64()
+	case ValueF32:
+		return wasmedge.NewValTypeF32()
+	case ValueF64:
+		return wasmedge.NewValTypeF64()
+	}
+	return nil
+}
+
+// wasmValTypes converts validated value types.
+func wasmValTypes(types []ValueType) []*wasmedge.ValType {
+	out := make([]*wasmedge.ValType, len(types))
+	for i, t := range types {
+		out[i] = wasmValType(t)
+	}
+	return out
+}
@@ -0,0 +1,347 @@
+package runtime_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("HostModule", func() {
+	i32 := runtime.ValueI32
+
+	// kvModule builds the "host" module the hostcall plugin imports, with
+	// values served from kv and log messages recorded in logs.
+	kvModule := func(kv map[string]int32, logs *[]string) *runtime.HostModule {
+		var mu sync.Mutex
+		return runtime.NewHostModule("host").
+			Func("log", []runtime.ValueType{i32, i32}, nil,
+				func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+					msg, err := call.ReadString(args[0].(int32), args[1].(int32))
+					if err != nil {
+						return nil, err
+					}
+					mu.Lock()
+					*logs = append(*logs, msg)
+					mu.Unlock()
+					return nil, nil
+				}).
+			Func("kv_get", []runtime.ValueType{i32, i32}, []runtime.ValueType{i32},
+				func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+					key, err := call.ReadString(args[0].(int32), args[1].(int32))
+					if err != nil {
+						return nil, err
+					}
+					value, ok := kv[key]
+					if !ok {
+						return []interface{}{int32(-1)}, nil
+					}
+					return []interface{}{value}, nil
+				})
+	}
+
+	// =========================================================================
+	// TEST: Definition errors
+	// Why: Func() is chainable, so a bad definition cannot fail on the spot.
+	//      It must still fail the load rather than leave the plugin with a
+	//      missing or mistyped import.
+	// =========================================================================
+	Describe("definitions", func() {
+		var helloPath string
+
+		BeforeEach(func() {
+			helloPath = filepath.Join("..", "plugins", "hello", "hello.wasm")
+			if _, err := os.Stat(helloPath); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + helloPath)
+			}
+		})
+
+		noop := func(*runtime.HostCall, []interface{}) ([]interface{}, error) { return nil, nil }
+
+		DescribeTable("should fail the load",
+			func(module *runtime.HostModule, message string) {
+				plugin, err := runtime.LoadPluginWithOptions(helloPath, runtime.LoadOptions{
+					HostModules: []*runtime.HostModule{module},
+				})
+
+				Expect(err).To(MatchError(ContainSubstring(message)))
+				Expect(plugin).To(BeNil())
+			},
+			Entry("with an empty module name", runtime.NewHostModule(""), "must not be empty"),
+			Entry("with the WASI module name", runtime.NewHostModule("wasi_snapshot_preview1"), "reserved for WASI"),
+			Entry("with an empty function name", runtime.NewHostModule("host").Func("", nil, nil, noop), "must not be empty"),
+			Entry("with a nil implementation", runtime.NewHostModule("host").Func("log", nil, nil, nil), "has no implementation"),
+			Entry("with a duplicate function", runtime.NewHostModule("host").
+				Func("log", nil, nil, noop).Func("log", nil, nil, noop), "defined twice"),
+			Entry("with an unknown value type", runtime.NewHostModule("host").
+				Func("log", []runtime.ValueType{"v128"}, nil, noop), "unsupported value type"),
+		)
+
+		It("should reject two modules with the same name", func() {
+			plugin, err := runtime.LoadPluginWithOptions(helloPath, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{
+					runtime.NewHostModule("host").Func("a", nil, nil, noop),
+					runtime.NewHostModule("host").Func("b", nil, nil, noop),
+				},
+			})
+
+			Expect(err).To(MatchError(ContainSubstring("host")))
+			Expect(plugin).To(BeNil())
+		})
+
+		It("should list functions in definition order", func() {
+			module := runtime.NewHostModule("host").
+				Func("log", nil, nil, noop).
+				Func("kv_get", nil, nil, noop)
+
+			Expect(module.Name()).To(Equal("host"))
+			Expect(module.Functions()).To(Equal([]string{"log", "kv_get"}))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Calls into the host
+	// Why: This is the point of host modules: a plugin reads data owned by
+	//      the service through an import, and a host-side failure surfaces
+	//      as the cause of the plugin call instead of an opaque trap.
+	// =========================================================================
+	Describe("calls from a plugin", func() {
+		var hostcallPath string
+
+		BeforeEach(func() {
+			hostcallPath = filepath.Join("..", "plugins", "hostcall", "hostcall.wasm")
+			if _, err := os.Stat(hostcallPath); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + hostcallPath)
+			}
+		})
+
+		It("should call host functions with access to plugin memory", func() {
+			var logs []string
+			plugin, err := runtime.LoadPluginWithOptions(hostcallPath, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{kvModule(map[string]int32{"factor": 3}, &logs)},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+
+			Expect(plugin.Init()).To(Succeed())
+			Expect(logs).To(Equal([]string{"initialized"}))
+
+			result, err := plugin.Execute(14)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(42))
+		})
+
+		It("should give each plugin its own instance of a shared module", func() {
+			var logs []string
+			module := kvModule(map[string]int32{"factor": 2}, &logs)
+
+			for i := 0; i < 2; i++ {
+				plugin, err := runtime.LoadPluginWithOptions(hostcallPath, runtime.LoadOptions{
+					HostModules: []*runtime.HostModule{module},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugin.Init()).To(Succeed())
+				Expect(plugin.Execute(5)).To(Equal(10))
+				plugin.Close()
+			}
+			Expect(logs).To(HaveLen(2))
+		})
+
+		It("should fail the call with the host function's error", func() {
+			errUnavailable := errors.New("kv store unavailable")
+			failing := runtime.NewHostModule("host").
+				Func("log", []runtime.ValueType{i32, i32}, nil,
+					func(*runtime.HostCall, []interface{}) ([]interface{}, error) { return nil, nil }).
+				Func("kv_get", []runtime.ValueType{i32, i32}, []runtime.ValueType{i32},
+					func(*runtime.HostCall, []interface{}) ([]interface{}, error) { return nil, errUnavailable })
+
+			plugin, err := runtime.LoadPluginWithOptions(hostcallPath, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{failing},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.Init()).To(Succeed())
+
+			_, err = plugin.Execute(1)
+			Expect(errors.Is(err, errUnavailable)).To(BeTrue(), "%v", err)
+			Expect(err).To(MatchError(ContainSubstring("host.kv_get")))
+		})
+
+		It("should fail a call whose host function returns the wrong results", func() {
+			module := runtime.NewHostModule("host").
+				Func("log", []runtime.ValueType{i32, i32}, nil,
+					func(*runtime.HostCall, []interface{}) ([]interface{}, error) { return nil, nil }).
+				Func("kv_get", []runtime.ValueType{i32, i32}, []runtime.ValueType{i32},
+					func(*runtime.HostCall, []interface{}) ([]interface{}, error) {
+						return []interface{}{int64(3)}, nil
+					})
+
+			plugin, err := runtime.LoadPluginWithOptions(hostcallPath, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.Init()).To(Succeed())
+
+			_, err = plugin.Execute(1)
+			Expect(err).To(MatchError(ContainSubstring("declared i32")))
+		})
+
+		// =====================================================================
+		// TEST: health() checks
+		// Why: A plugin can degrade while it sits in a pool (here, because a
+		//      host dependency fails). The pool must evict such instances
+		//      after Init() and on its periodic checks instead of handing
+		//      them to requests.
+		// =====================================================================
+		Describe("health()", func() {
+			var healthy atomic.Int32
+
+			healthModule := func() *runtime.HostModule {
+				return runtime.NewHostModule("host").
+					Func("log", []runtime.ValueType{i32, i32}, nil,
+						func(*runtime.HostCall, []interface{}) ([]interface{}, error) { return nil, nil }).
+					Func("kv_get", []runtime.ValueType{i32, i32}, []runtime.ValueType{i32},
+						func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+							key, err := call.ReadString(args[0].(int32), args[1].(int32))
+							if err != nil {
+								return nil, err
+							}
+							switch key {
+							case "healthy":
+								return []interface{}{healthy.Load()}, nil
+							case "factor":
+								return []interface{}{int32(1)}, nil
+							}
+							return []interface{}{int32(-1)}, nil
+						})
+			}
+
+			BeforeEach(func() {
+				healthy.Store(1)
+			})
+
+			It("should report the plugin's health code", func() {
+				plugin, err := runtime.LoadPluginWithOptions(hostcallPath, runtime.LoadOptions{
+					HostModules: []*runtime.HostModule{healthModule()},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				defer plugin.Close()
+				Expect(plugin.Init()).To(Succeed())
+
+				Expect(plugin.Health()).To(Succeed())
+
+				healthy.Store(0)
+				var pluginErr *runtime.PluginError
+				Expect(errors.As(plugin.Health(), &pluginErr)).To(BeTrue())
+				Expect(pluginErr.Function).To(Equal(runtime.ExportHealth))
+				Expect(pluginErr.Code).To(Equal(int32(runtime.ABIErrorInternal)))
+			})
+
+			It("should evict instances that are unhealthy after init", func() {
+				healthy.Store(0)
+
+				pool, err := runtime.NewPool(hostcallPath, runtime.PoolOptions{
+					Reset:       runtime.ResetRecreate,
+					HostModules: []*runtime.HostModule{healthModule()},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				defer pool.Close()
+
+				_, err = pool.Get()
+				Expect(err).To(MatchError(ContainSubstring("unhealthy after init")))
+				Expect(pool.Stats().Evictions).To(HaveKeyWithValue(runtime.EvictUnhealthy, uint64(1)))
+				Expect(pool.Stats().InUse).To(Equal(0))
+			})
+
+			It("should evict idle instances that become unhealthy", func() {
+				pool, err := runtime.NewPool(hostcallPath, runtime.PoolOptions{
+					Reset:          runtime.ResetRestore,
+					MinSize:        1,
+					HealthInterval: 20 * time.Millisecond,
+					HostModules:    []*runtime.HostModule{healthModule()},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				defer pool.Close()
+				Expect(pool.Stats().Idle).To(Equal(1))
+
+				healthy.Store(0)
+				Eventually(func() uint64 {
+					return pool.Stats().Evictions[runtime.EvictUnhealthy]
+				}).Should(BeNumerically(">=", 1))
+			})
+		})
+
+		// =====================================================================
+		// TEST: on_shutdown() notifications
+		// Why: Stateful plugins buffer data between calls. The pool must let
+		//      them flush it through host functions before discarding an
+		//      instance, but a plugin that hangs must not stall the pool.
+		// =====================================================================
+		Describe("on_shutdown()", func() {
+			It("should flush state before the pool discards an instance", func() {
+				var logs []string
+				pool, err := runtime.NewPool(hostcallPath, runtime.PoolOptions{
+					Reset:       runtime.ResetRecreate,
+					HostModules: []*runtime.HostModule{kvModule(map[string]int32{"factor": 2}, &logs)},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				defer pool.Close()
+
+				plugin, err := pool.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plugin.Execute(1)).To(Equal(2))
+				Expect(plugin.Execute(2)).To(Equal(4))
+				pool.Put(plugin)
+
+				Expect(logs).To(Equal([]string{"initialized", "processed 2"}))
+				Expect(pool.Stats().ShutdownFailures).To(BeZero())
+			})
+
+			It("should give up on a plugin that does not finish in time", func() {
+				module := runtime.NewHostModule("host").
+					Func("log", []runtime.ValueType{i32, i32}, nil,
+						func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+							msg, err := call.ReadString(args[0].(int32), args[1].(int32))
+							if err == nil && msg != "initialized" {
+								time.Sleep(200 * time.Millisecond)
+							}
+							return nil, err
+						}).
+					Func("kv_get", []runtime.ValueType{i32, i32}, []runtime.ValueType{i32},
+						func(*runtime.HostCall, []interface{}) ([]interface{}, error) {
+							return []interface{}{int32(1)}, nil
+						})
+
+				pool, err := runtime.NewPool(hostcallPath, runtime.PoolOptions{
+					Reset:           runtime.ResetRecreate,
+					ShutdownTimeout: 20 * time.Millisecond,
+					HostModules:     []*runtime.HostModule{module},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				defer pool.Close()
+
+				plugin, err := pool.Get()
+				Expect(err).NotTo(HaveOccurred())
+				pool.Discard(plugin)
+
+				Expect(pool.Stats().ShutdownFailures).To(Equal(uint64(1)))
+				Expect(pool.Stats().Evictions).To(HaveKeyWithValue(runtime.EvictDiscarded, uint64(1)))
+			})
+		})
+
+		It("should fail to load a plugin whose imports are not provided", func() {
+			plugin, err := runtime.LoadPlugin(hostcallPath)
+
+			Expect(err).To(HaveOccurred())
+			Expect(plugin).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ExportProcessJSON is the export of the JSON payload ABI; see
+// engine.ExportProcessJSON.
+const ExportProcessJSON = engine.ExportProcessJSON
+
+// ErrInvalidJSON is returned when process_json produces output that is not
+// a JSON document.
+var ErrInvalidJSON = engine.ErrInvalidJSON
+
+// SupportsJSON reports whether the plugin implements the JSON payload ABI
+// required by ExecuteJSON.
+func (p *Plugin) SupportsJSON() bool {
+	return p.HasExport(ExportAllocate) &&
+		p.HasExport(ExportDeallocate) &&
+		p.HasExport(ExportProcessJSON)
+}
+
+// ExecuteJSON calls the plugin's "process_json" function with the given
+// JSON document and returns the document it produced.
+//
+// The call sequence and errors are those of ExecuteBytes. In addition, the
+// input must be valid JSON, and output that is not fails the call with
+// ErrInvalidJSON, so callers can embed it in their own documents as is.
+func (p *Plugin) ExecuteJSON(input json.RawMessage) (json.RawMessage, error) {
+	return p.ExecuteJSONContext(context.Background(), input)
+}
+
+// ExecuteJSONContext is ExecuteJSON with cancellation of the
+// process_json() call, as in ExecuteContext.
+func (p *Plugin) ExecuteJSONContext(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	if !json.Valid(input) {
+		return nil, fmt.Errorf("input for %s() of %s is not valid JSON", ExportProcessJSON, p.path)
+	}
+	output, err := p.executePayload(ctx, ExportProcessJSON, input)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(output) {
+		return nil, fmt.Errorf("%s() for %s: %w", ExportProcessJSON, p.path, ErrInvalidJSON)
+	}
+	return output, nil
+}
@@ -0,0 +1,58 @@
+package runtime_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("JSON payload ABI", func() {
+	load := func(name string) *runtime.Plugin {
+		path := filepath.Join("..", "plugins", name, name+".wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		plugin, err := runtime.LoadPlugin(path)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		Expect(plugin.Init()).To(Succeed())
+		return plugin
+	}
+
+	// =========================================================================
+	// TEST: JSON round trip
+	// Why: Structured plugins take and return documents; the host must hand
+	//      the document over unchanged and only return valid JSON, so that
+	//      the server can embed the output in its response.
+	// =========================================================================
+	It("should pass documents to process_json and return its output", func() {
+		plugin := load("envelope")
+		Expect(plugin.SupportsJSON()).To(BeTrue())
+		Expect(plugin.SupportsPayloads()).To(BeFalse())
+
+		output, err := plugin.ExecuteJSON(json.RawMessage(`{"items":[1,2,3]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(MatchJSON(`{"bytes": 17, "input": {"items": [1, 2, 3]}}`))
+
+		_, err = plugin.ExecuteJSON(json.RawMessage(`null`))
+		Expect(err).To(MatchError(runtime.ErrNoOutput))
+	})
+
+	It("should reject input that is not JSON without calling the plugin", func() {
+		plugin := load("envelope")
+		_, err := plugin.ExecuteJSON(json.RawMessage(`{"items":`))
+		Expect(err).To(MatchError(ContainSubstring("not valid JSON")))
+	})
+
+	It("should refuse plugins without process_json", func() {
+		plugin := load("upper")
+		Expect(plugin.SupportsJSON()).To(BeFalse())
+		_, err := plugin.ExecuteJSON(json.RawMessage(`{}`))
+		Expect(err).To(MatchError(ContainSubstring("does not implement the payload ABI")))
+	})
+})
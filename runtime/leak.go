@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"fmt"
+	"log/slog"
+	goruntime "runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// LeakReport describes a plugin that was garbage collected without being
+// closed, which leaks its VM.
+type LeakReport struct {
+	Path  string // Plugin file
+	Stack string // Where the plugin was loaded
+}
+
+// leakHandler is told about leaked plugins; nil logs them.
+var leakHandler atomic.Pointer[func(LeakReport)]
+
+// SetLeakHandler sets the function told about plugins garbage collected
+// without being closed, e.g. to log them with the service's logger. nil
+// restores the default, which logs them with slog's default logger at
+// error level. Leaks are only detected with LeakDetection.
+func SetLeakHandler(handler func(LeakReport)) {
+	if handler == nil {
+		leakHandler.Store(nil)
+		return
+	}
+	leakHandler.Store(&handler)
+}
+
+// watchLeak has p reported, with LeakDetection, if it becomes unreachable
+// before Close(). Its VM is not released then, so that LiveHandles keeps
+// showing the leak.
+func watchLeak(p *Plugin) {
+	if !LeakDetection {
+		return
+	}
+	callers := make([]uintptr, 32)
+	callers = callers[:goruntime.Callers(3, callers)] // From loadModule's caller on
+	goruntime.SetFinalizer(p, func(p *Plugin) {
+		if p.vm != nil {
+			reportLeak(LeakReport{Path: p.path, Stack: formatStack(callers)})
+		}
+	})
+}
+
+// unwatchLeak stops watching p, which was closed.
+func unwatchLeak(p *Plugin) {
+	if LeakDetection {
+		goruntime.SetFinalizer(p, nil)
+	}
+}
+
+// reportLeak passes a leak to the handler set with SetLeakHandler.
+func reportLeak(report LeakReport) {
+	if handler := leakHandler.Load(); handler != nil {
+		(*handler)(report)
+		return
+	}
+	slog.Error("plugin garbage collected without Close; its VM is leaked",
+		slog.String("plugin", report.Path),
+		slog.String("loaded_at", report.Stack))
+}
+
+// formatStack formats program counters as a stack trace, one
+// "function file:line" frame per line.
+func formatStack(callers []uintptr) string {
+	var b strings.Builder
+	frames := goruntime.CallersFrames(callers)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s %s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,9 @@
+//go:build !leakcheck
+
+package runtime
+
+// LeakDetection reports plugins that are garbage collected without being
+// closed (see SetLeakHandler). It is enabled by building with the
+// leakcheck tag, e.g. go test -tags leakcheck ./..., as it records a stack
+// trace on every load.
+const LeakDetection = false
@@ -0,0 +1,76 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Leak detection", func() {
+	// =========================================================================
+	// TEST: Plugins dropped without Close
+	// Why: A forgotten Close leaks a VM the garbage collector cannot see;
+	//      leakcheck builds must name the plugin and where it was loaded.
+	//      Run with: go test -tags leakcheck ./runtime/...
+	// =========================================================================
+	var (
+		path    string
+		mu      sync.Mutex
+		reports []runtime.LeakReport
+	)
+
+	BeforeEach(func() {
+		if !runtime.LeakDetection {
+			Skip("Leak detection needs the leakcheck build tag")
+		}
+		path = filepath.Join("..", "plugins", "hello", "hello.wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+
+		reports = nil
+		runtime.SetLeakHandler(func(report runtime.LeakReport) {
+			mu.Lock()
+			reports = append(reports, report)
+			mu.Unlock()
+		})
+		DeferCleanup(runtime.SetLeakHandler, nil)
+	})
+
+	leaked := func() []runtime.LeakReport {
+		goruntime.GC()
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]runtime.LeakReport(nil), reports...)
+	}
+
+	// load loads a plugin in a frame of its own, so that it is unreachable
+	// once load returns.
+	load := func(close bool) {
+		plugin, err := runtime.LoadPlugin(path)
+		Expect(err).NotTo(HaveOccurred())
+		if close {
+			plugin.Close()
+		}
+	}
+
+	It("should report plugins garbage collected without Close", func() {
+		load(false)
+
+		Eventually(leaked).Should(ContainElement(And(
+			HaveField("Path", path),
+			HaveField("Stack", ContainSubstring("leak_test.go")))))
+	})
+
+	It("should not report closed plugins", func() {
+		load(true)
+
+		Consistently(leaked, "200ms").Should(BeEmpty())
+	})
+})
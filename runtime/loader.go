@@ -3,28 +3,82 @@ package runtime
 import (
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/second-state/WasmEdge-go/wasmedge"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
 )
 
 // Plugin represents a loaded WebAssembly plugin with its own isolated VM instance.
 // Each Plugin owns its WasmEdge VM, configuration, and lifecycle state.
-// Plugins are not safe for concurrent use - caller must synchronize access.
+//
+// Plugin is safe for concurrent use: calls into it are serialized by a
+// per-instance lock, unless its manifest declares it reentrant (see
+// Reentrant). To run calls in parallel, give each goroutine its own
+// instance with Clone, or use a Pool. Calls that wait for one another
+// are counted in CallContention.
 type Plugin struct {
-	path   string              // Original file path for error reporting
-	vm     *wasmedge.VM        // WasmEdge VM instance (owns module execution)
-	config *wasmedge.Configure // VM configuration (WASI support)
+	mu sync.RWMutex // Serializes calls into non-reentrant plugins; Close waits for calls into reentrant ones
+
+	path       string              // Original file path for error reporting
+	modulePath string              // File the VM was loaded from: path, or its AOT artifact
+	options    LoadOptions         // Options the plugin was loaded with, reused by Clone()
+	vm         *wasmedge.VM        // WasmEdge VM instance (owns module execution)
+	config     *wasmedge.Configure // VM configuration (WASI support)
+
+	snapshot *memorySnapshot    // Post-Init memory state for Restore() (nil until Snapshot())
+	compiled bool               // Loaded from an AOT-compiled artifact
+	exports  *wasminfo.Module   // Parsed interface (nil until module() or parsed at load)
+	parsed   sync.Once          // Fills exports once, for concurrent calls into reentrant plugins
+	parseErr error              // Error parsing the plugin file for exports
+	manifest *manifest.Manifest // plugin.json next to the plugin (nil if none)
+
+	memoryLimit uint       // Max linear memory in pages (0 = module's own limit)
+	abiVersion  ABIVersion // Result of get_abi_version() at load (0 = unversioned)
+
+	stats            *wasmedge.Statistics // Cost measurement of the VM (nil unless MaxInstructions is enforced)
+	instructionLimit uint                 // Instructions each export call may execute (0 = no limit)
+	costLimit        uint                 // Total cost at which the current call is stopped
+
+	hostModules []*wasmedge.Module // Instances of LoadOptions.HostModules, released after vm
+	host        *hostState         // Error of the host function that trapped the current call
+	scratch     string             // Host directory pre-opened at ScratchGuestPath ("" if none)
 }
 
+// LoadOptions configures how a plugin is loaded; see engine.LoadOptions.
+type LoadOptions = engine.LoadOptions
+
+// ModuleSource shares parsed modules between loads; *ModuleCache is the
+// one WasmEdge uses.
+type ModuleSource = engine.ModuleSource
+
 // LoadPlugin loads a WebAssembly module from disk and creates an isolated VM instance.
 //
 // The function performs the complete loading sequence:
-// 1. Creates WasmEdge configuration with WASI support
-// 2. Initializes a new VM with the configuration
-// 3. Initializes WASI interface (required for wasm32-wasi modules)
-// 4. Loads the WASM file from disk
-// 5. Validates module structure and bytecode
-// 6. Instantiates the module (allocates memory, prepares exports)
+// 1. Checks the binary against its digest and manifest (plugin.json), if declared
+// 2. Creates WasmEdge configuration with WASI support and the memory limit
+// 3. Initializes a new VM with the configuration
+// 4. Initializes WASI interface (required for wasm32-wasi modules)
+// 5. Loads the WASM file from disk
+// 6. Validates module structure and bytecode
+// 7. Instantiates the module (allocates memory, prepares exports)
+// 8. Queries the ABI version from get_abi_version, if exported
+//
+// A plugin whose manifest declares exports or an ABI version the binary
+// does not have is rejected with an error wrapping manifest.ErrInvalid.
+// One implementing an ABI version outside SupportedABI is rejected with an
+// error wrapping ErrABIVersion; see LoadOptions to require or relax this.
+// The manifest's limits.memory_pages, if set, caps the plugin's memory,
+// and its wasi block asks for the arguments, environment variables, and
+// directories the plugin gets, as far as LoadOptions.WASI allows.
+//
+// A binary whose SHA-256 digest differs from the one declared in its
+// manifest or its <name>.wasm.sha256 file is rejected with an error
+// wrapping ErrIntegrity, before any of it is parsed.
 //
 // If any step fails, all resources are cleaned up before returning the error.
 // The returned Plugin must be closed with Close() when no longer needed.
@@ -37,81 +91,236 @@ type Plugin struct {
 //	}
 //	defer plugin.Close()
 func LoadPlugin(path string) (*Plugin, error) {
+	return loadModule(path, path, LoadOptions{})
+}
+
+// LoadPluginWithOptions is LoadPlugin with a memory limit and host modules.
+//
+// A plugin importing a function that no host module (or WASI) provides
+// fails to instantiate. A host function returning an error traps the
+// plugin; the failed call's error wraps the host function's.
+func LoadPluginWithOptions(path string, opts LoadOptions) (*Plugin, error) {
+	return loadModule(path, path, opts)
+}
+
+// LoadPluginWithMemoryLimit is LoadPlugin with the plugin's linear memory
+// capped at maxPages 64 KiB pages, unless its manifest declares its own
+// limits.memory_pages. Zero means no limit beyond the module's own.
+//
+// A module whose initial memory exceeds the limit is rejected with an
+// error wrapping ErrMemoryLimit. At run time, memory.grow past the limit
+// fails inside the plugin; calls that fail with memory at the limit return
+// errors wrapping ErrMemoryLimit.
+func LoadPluginWithMemoryLimit(path string, maxPages int) (*Plugin, error) {
+	return loadModule(path, path, LoadOptions{MaxMemoryPages: maxPages})
+}
+
+// loadModule loads the module file at modulePath (the plugin itself or its
+// AOT-compiled artifact) and reports errors against the plugin path.
+func loadModule(path, modulePath string, opts LoadOptions) (*Plugin, error) {
 	// Verify file exists before attempting to load
-	if _, err := os.Stat(path); err != nil {
+	if _, err := os.Stat(modulePath); err != nil {
 		return nil, fmt.Errorf("plugin file not found: %w", err)
 	}
 
-	// Step 1: Create configuration with WASI support
+	// Step 1: Verify the binary, then check the manifest and memory limit
+	// against it. The original .wasm is checked even when an AOT artifact
+	// is loaded.
+	load, err := engine.PrepareGuestLoad(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	m, module, limit := load.Manifest, load.Module, load.MemoryLimit
+	args, env, preopens := load.Args, load.Env, wasiPreopens(load)
+	scratch, err := newScratchDir(path, m, preopens, opts.WASI)
+	if err != nil {
+		return nil, err
+	}
+	if scratch != "" {
+		preopens = append(preopens, ScratchGuestPath+":"+scratch)
+	}
+	loaded := false
+	defer func() {
+		if !loaded && scratch != "" {
+			os.RemoveAll(scratch)
+		}
+	}()
+
+	// Step 2: Create configuration with WASI support
 	// This enables wasm32-wasi modules to work even if they don't use WASI syscalls
 	config := wasmedge.NewConfigure(wasmedge.WASI)
 	if config == nil {
 		return nil, fmt.Errorf("failed to create WasmEdge configuration")
 	}
+	if limit > 0 {
+		// memory.grow beyond the limit fails inside the plugin
+		config.SetMaxMemoryPage(limit)
+	}
+	metered := opts.MaxInstructions > 0 && EngineCapabilities().Statistics
+	if metered {
+		// Every instruction costs 1, so the cost limit counts instructions
+		config.SetStatisticsInstructionCounting(true)
+		config.SetStatisticsCostMeasuring(true)
+	}
 
-	// Step 2: Create VM instance with the configuration
+	// Step 3: Create VM instance with the configuration
 	// Each plugin gets its own isolated VM for sandboxing
-	vm := wasmedge.NewVMWithConfig(config)
+	vm := newVM(config)
 	if vm == nil {
 		config.Release()
 		return nil, fmt.Errorf("failed to create WasmEdge VM")
 	}
 
-	// Step 3: Initialize WASI interface
+	// Step 4: Initialize WASI interface
 	// Required for wasm32-wasi target even if plugin doesn't use WASI features
 	wasi := vm.GetImportModule(wasmedge.WASI)
 	if wasi == nil {
-		vm.Release()
-		config.Release()
+		releaseVM(vm, config)
 		return nil, fmt.Errorf("failed to get WASI module")
 	}
 
-	// Initialize WASI with minimal environment
-	// No command-line args, inherit host environment, no pre-opened directories
-	wasi.InitWasi(
-		[]string{},   // No command-line arguments
-		os.Environ(), // Inherit host environment variables
-		[]string{},   // No pre-opened directories (sandbox)
-	)
+	// Initialize WASI with the environment the host and the manifest grant
+	// Nothing of the host environment is inherited unless allowed
+	wasi.InitWasi(args, env, preopens)
 
-	// Step 4: Load WASM file from disk
-	// Reads and parses the WebAssembly binary
-	if err := vm.LoadWasmFile(path); err != nil {
-		vm.Release()
-		config.Release()
-		return nil, fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	// Register host modules so the plugin's imports resolve against them
+	// Each plugin gets its own instances, released together with the VM
+	host := &hostState{manifest: m}
+	if opts.CaptureOutput && len(preopens) == 0 {
+		// Route stdout and stderr through the host, so each call's output
+		// can be captured
+		if err := captureOutput(wasi, path, host); err != nil {
+			releaseVM(vm, config)
+			return nil, err
+		}
+	}
+	hostModules, err := registerHostModules(vm, path, opts.HostModules, host)
+	if err != nil {
+		releaseVM(vm, config)
+		return nil, err
 	}
 
-	// Step 5: Validate the module
-	// Verifies bytecode structure, type checking, and instruction validity
+	// Step 5: Load WASM file from disk
+	// Reads and parses the WebAssembly binary, or maps the native code of an
+	// AOT-compiled artifact. A module cache skips parsing a .wasm file whose
+	// contents it has seen.
+	if cache, ok := opts.Modules.(*ModuleCache); ok && cache != nil && modulePath == path {
+		err = cache.loadInto(vm, path)
+	} else if err = vm.LoadWasmFile(modulePath); err != nil {
+		err = fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	}
+	if err != nil {
+		releaseVM(vm, config)
+		releaseModules(hostModules)
+		return nil, err
+	}
+
+	// Step 6: Validate the module
+	// Verifies bytecode structure, type checking, and instruction validity.
+	// The VM workflow requires this step for cached modules too.
 	if err := vm.Validate(); err != nil {
-		vm.Release()
-		config.Release()
+		releaseVM(vm, config)
+		releaseModules(hostModules)
 		return nil, fmt.Errorf("WASM module validation failed for %s: %w", path, err)
 	}
 
-	// Step 6: Instantiate the module
+	// Step 7: Instantiate the module
 	// Allocates linear memory, initializes globals, runs start functions (if any)
 	// After this point, exports are callable
 	if err := vm.Instantiate(); err != nil {
-		vm.Release()
-		config.Release()
+		releaseVM(vm, config)
+		releaseModules(hostModules)
 		return nil, fmt.Errorf("WASM module instantiation failed for %s: %w", path, err)
 	}
 
 	// Success - return initialized plugin
-	return &Plugin{
-		path:   path,
-		vm:     vm,
-		config: config,
-	}, nil
+	plugin := &Plugin{
+		path:        path,
+		modulePath:  modulePath,
+		options:     opts,
+		vm:          vm,
+		config:      config,
+		compiled:    modulePath != path,
+		exports:     module,
+		manifest:    m,
+		memoryLimit: limit,
+		hostModules: hostModules,
+		host:        host,
+		scratch:     scratch,
+	}
+	loaded = true
+	watchLeak(plugin)
+	if metered {
+		// The statistics are owned and released by the VM
+		plugin.stats = vm.GetStatistics()
+		plugin.instructionLimit = uint(opts.MaxInstructions)
+	}
+
+	// Step 8: Negotiate the ABI version before anything else is called
+	if err := plugin.negotiateABI(opts); err != nil {
+		plugin.Close()
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// registerHostModules instantiates the host modules for the plugin at path
+// and registers them with vm. On error, the instances created so far are
+// released.
+func registerHostModules(vm *wasmedge.VM, path string, modules []*HostModule, host *hostState) ([]*wasmedge.Module, error) {
+	var instances []*wasmedge.Module
+	seen := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		if seen[m.Name()] {
+			releaseModules(instances)
+			return nil, fmt.Errorf("host module %s is registered twice", m.Name())
+		}
+		seen[m.Name()] = true
+
+		instance, err := instantiateHostModule(m, path, host)
+		if err != nil {
+			releaseModules(instances)
+			return nil, err
+		}
+		if err := vm.RegisterModule(instance); err != nil {
+			releaseModules([]*wasmedge.Module{instance})
+			releaseModules(instances)
+			return nil, fmt.Errorf("failed to register host module %s for %s: %w", m.Name(), path, err)
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// releaseModules releases host module instances.
+func releaseModules(modules []*wasmedge.Module) {
+	for _, m := range modules {
+		m.Release()
+		liveHostModules.Add(-1)
+	}
+}
+
+// wasiPreopens returns the directories of load as guest:host, in the order
+// the manifest lists them.
+func wasiPreopens(load *GuestLoad) []string {
+	if load.Manifest == nil {
+		return nil
+	}
+	var preopens []string
+	for _, guest := range load.Manifest.WASI.Dirs {
+		if host, ok := load.Dirs[guest]; ok {
+			preopens = append(preopens, guest+":"+host)
+		}
+	}
+	return preopens
 }
 
 // Close releases all VM resources owned by this plugin.
 //
 // This method must be called when the plugin is no longer needed to prevent
 // resource leaks. It's safe to call Close() multiple times - subsequent calls
-// are no-ops.
+// are no-ops. Close waits for calls in progress, including concurrent
+// calls into a reentrant plugin, to return.
 //
 // After Close() is called, Init(), Execute(), and Cleanup() must not be called.
 //
@@ -120,14 +329,126 @@ func LoadPlugin(path string) (*Plugin, error) {
 //	plugin, _ := runtime.LoadPlugin("plugin.wasm")
 //	defer plugin.Close()
 func (p *Plugin) Close() {
+	if p.Reentrant() {
+		// Calls into a reentrant plugin share the lock; wait for them all
+		// before the VM goes away under them
+		p.mu.Lock()
+		defer p.mu.Unlock()
+	} else {
+		defer p.lock()()
+	}
+
 	if p.vm != nil {
-		p.vm.Release()
+		releaseVM(p.vm, p.config)
 		p.vm = nil
-	}
-	if p.config != nil {
-		p.config.Release()
 		p.config = nil
+		unwatchLeak(p)
+	}
+	if p.scratch != "" {
+		os.RemoveAll(p.scratch)
+		p.scratch = ""
+	}
+	releaseModules(p.hostModules)
+	p.hostModules = nil
+	p.snapshot = nil
+}
+
+// Clone loads a new, independent instance of the plugin from the same
+// module, with the same memory limit and host modules. Callers that need
+// to run a non-reentrant plugin in parallel can clone it once per goroutine
+// instead of serializing on one instance.
+//
+// If a snapshot was taken (see Snapshot), the clone starts in the
+// snapshotted state, typically right after Init(), and shares the snapshot
+// for Restore(); it is ready to Execute without calling Init. Otherwise it
+// is in the state of a freshly loaded plugin and must be initialized.
+//
+// The module is reloaded from disk, so a plugin file replaced since p was
+// loaded yields a clone of the new build, checked against its manifest as
+// on any load. The clone must be closed with Close() like any Plugin.
+func (p *Plugin) Clone() (*Plugin, error) {
+	unlock := p.lock()
+	closed := p.vm == nil
+	path, modulePath, opts, snap := p.path, p.modulePath, p.options, p.snapshot
+	unlock()
+	if closed {
+		return nil, fmt.Errorf("plugin is closed")
+	}
+
+	clone, err := loadModule(path, modulePath, opts)
+	if err != nil {
+		return nil, err
 	}
+	if snap != nil {
+		if err := snap.seed(clone); err != nil {
+			clone.Close()
+			return nil, fmt.Errorf("failed to restore snapshot into clone of %s: %w", path, err)
+		}
+	}
+	return clone, nil
+}
+
+// Manifest returns the plugin's manifest, or nil if it has none.
+func (p *Plugin) Manifest() *manifest.Manifest {
+	return p.manifest
+}
+
+// MemoryLimit returns the cap on the plugin's linear memory in 64 KiB
+// pages, or 0 if only the module's own maximum applies.
+func (p *Plugin) MemoryLimit() uint {
+	return p.memoryLimit
+}
+
+// InstructionLimit returns the instructions each call may execute, or 0 if
+// calls are not limited, including when LoadOptions.MaxInstructions is
+// set but the engine cannot enforce it.
+func (p *Plugin) InstructionLimit() uint64 {
+	return uint64(p.instructionLimit)
+}
+
+// Reentrant reports whether the plugin's manifest declares that its
+// exports may run concurrently on this instance. Calls into a reentrant
+// plugin skip the per-instance lock, so the plugin itself must tolerate
+// them; host function error attribution and log capture are best effort
+// for overlapping calls.
+func (p *Plugin) Reentrant() bool {
+	return p.manifest != nil && p.manifest.Reentrant
+}
+
+// lock serializes access to the instance unless it is reentrant, and
+// returns the matching unlock:
+//
+//	defer p.lock()()
+//
+// Calls into a reentrant plugin share the lock, so that they run
+// concurrently with each other but not with Close. A call that has to wait
+// for another one is counted in CallContention. The scratch directory, if
+// any, is emptied before the next call gets the instance.
+func (p *Plugin) lock() func() {
+	if p.Reentrant() {
+		p.mu.RLock()
+		return p.mu.RUnlock
+	}
+	if !p.mu.TryLock() {
+		started := time.Now()
+		p.mu.Lock()
+		engine.RecordContention(p.path, time.Since(started))
+	}
+	if p.scratch == "" {
+		return p.mu.Unlock
+	}
+	return func() {
+		if p.scratch != "" {
+			clearScratch(p.scratch)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Compiled reports whether the plugin runs AOT-compiled native code rather
+// than interpreted bytecode.
+func (p *Plugin) Compiled() bool {
+	return p.compiled
 }
 
 // Path returns the original file path of the loaded plugin.
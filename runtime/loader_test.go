@@ -1,13 +1,18 @@
 package runtime_test
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/mrhapile/wasm-plugin-system/manifest"
 	"github.com/mrhapile/wasm-plugin-system/runtime"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
 )
 
 var _ = Describe("Loader", func() {
@@ -111,6 +116,380 @@ var _ = Describe("Loader", func() {
 		})
 	})
 
+	// =========================================================================
+	// TEST: Manifest validation on load
+	// Why: A manifest promising exports the binary lacks would only fail at
+	//      the first request that needs them; LoadPlugin must reject it.
+	// =========================================================================
+	Describe("LoadPlugin with a manifest", func() {
+		var pluginPath string
+
+		BeforeEach(func() {
+			wasm, err := os.ReadFile(validPluginPath)
+			if os.IsNotExist(err) {
+				Skip("Test plugin not found")
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			pluginPath = filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+			Expect(os.WriteFile(pluginPath, wasm, 0644)).To(Succeed())
+		})
+
+		writeManifest := func(data string) {
+			path := filepath.Join(filepath.Dir(pluginPath), manifest.FileName)
+			Expect(os.WriteFile(path, []byte(data), 0644)).To(Succeed())
+		}
+
+		It("should expose a matching manifest", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "exports": ["init", "process", "cleanup"]}`)
+
+			plugin, err := runtime.LoadPlugin(pluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+
+			Expect(plugin.Manifest()).NotTo(BeNil())
+			Expect(plugin.Manifest().Version).To(Equal("1.0.0"))
+		})
+
+		It("should reject a manifest declaring a missing export", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "exports": ["process_bytes"]}`)
+
+			plugin, err := runtime.LoadPlugin(pluginPath)
+
+			Expect(errors.Is(err, manifest.ErrInvalid)).To(BeTrue())
+			Expect(plugin).To(BeNil())
+		})
+
+		It("should load without a manifest", func() {
+			plugin, err := runtime.LoadPlugin(pluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+
+			Expect(plugin.Manifest()).To(BeNil())
+		})
+
+		It("should report a plugin declared reentrant", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "reentrant": true}`)
+
+			plugin, err := runtime.LoadPlugin(pluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+
+			Expect(plugin.Reentrant()).To(BeTrue())
+		})
+
+		It("should close a reentrant plugin only after its calls return", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "reentrant": true}`)
+			plugin, err := runtime.LoadPlugin(pluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+
+			var calls sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				calls.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer calls.Done()
+					for j := 0; j < 50; j++ {
+						if output, err := plugin.ExecuteContext(context.Background(), 1); err != nil {
+							Expect(err).To(MatchError(ContainSubstring("closed")))
+						} else {
+							Expect(output).To(Equal(3))
+						}
+					}
+				}()
+			}
+			plugin.Close()
+			calls.Wait()
+		})
+
+		It("should pre-open only the WASI directories the host grants", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "wasi": {"args": ["-v"], "inherit_env": ["HOME"], "dirs": ["/data"]}}`)
+
+			plugin, err := runtime.LoadPlugin(pluginPath)
+			Expect(err).To(MatchError(ContainSubstring("needs directory /data, which the host does not grant")))
+			Expect(plugin).To(BeNil())
+
+			plugin, err = runtime.LoadPluginWithOptions(pluginPath, runtime.LoadOptions{
+				WASI: runtime.WASIOptions{AllowEnv: []string{"HOME"}, Dirs: map[string]string{"/data": GinkgoT().TempDir()}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			plugin.Close()
+		})
+
+		It("should give each instance granted directories a scratch directory emptied after every call", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "wasi": {"dirs": ["/data"]}}`)
+			scratch := GinkgoT().TempDir()
+			opts := runtime.LoadOptions{
+				WASI: runtime.WASIOptions{Dirs: map[string]string{"/data": GinkgoT().TempDir()}, ScratchDir: scratch},
+			}
+			scratchDirs := func() []string {
+				entries, err := os.ReadDir(scratch)
+				Expect(err).NotTo(HaveOccurred())
+				var dirs []string
+				for _, entry := range entries {
+					dirs = append(dirs, filepath.Join(scratch, entry.Name()))
+				}
+				return dirs
+			}
+
+			plugin, err := runtime.LoadPluginWithOptions(pluginPath, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+			clone, err := plugin.Clone()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scratchDirs()).To(HaveLen(2))
+
+			// What a call leaves behind is gone before the next one
+			for _, dir := range scratchDirs() {
+				Expect(os.WriteFile(filepath.Join(dir, "left-over"), []byte("x"), 0o644)).To(Succeed())
+			}
+			_, err = plugin.Execute(1)
+			Expect(err).NotTo(HaveOccurred())
+			cleared := 0
+			for _, dir := range scratchDirs() {
+				if entries, _ := os.ReadDir(dir); len(entries) == 0 {
+					cleared++
+				}
+			}
+			Expect(cleared).To(Equal(1), "only the instance called is cleared")
+
+			plugin.Close()
+			clone.Close()
+			Expect(scratchDirs()).To(BeEmpty())
+
+			// A manifest asking for /tmp gets the granted directory
+			writeManifest(`{"name": "hello", "version": "1.0.0", "wasi": {"dirs": ["/tmp"]}}`)
+			opts.WASI.Dirs = map[string]string{"/tmp": GinkgoT().TempDir()}
+			plugin, err = runtime.LoadPluginWithOptions(pluginPath, opts)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(scratchDirs()).To(BeEmpty())
+		})
+
+		It("should fail calls that leave more in their scratch directory than the quota allows", func() {
+			writeManifest(`{"name": "hello", "version": "1.0.0", "wasi": {"dirs": ["/data"]}}`)
+			scratch := GinkgoT().TempDir()
+			plugin, err := runtime.LoadPluginWithOptions(pluginPath, runtime.LoadOptions{
+				WASI: runtime.WASIOptions{
+					Dirs:            map[string]string{"/data": GinkgoT().TempDir()},
+					ScratchDir:      scratch,
+					ScratchMaxBytes: 4,
+					ScratchMaxFiles: 2,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.Init()).To(Succeed())
+			entries, err := os.ReadDir(scratch)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			dir := filepath.Join(scratch, entries[0].Name())
+
+			// Within the quota
+			Expect(os.WriteFile(filepath.Join(dir, "a"), []byte("1234"), 0o644)).To(Succeed())
+			_, err = plugin.Execute(1)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Too many bytes
+			Expect(os.WriteFile(filepath.Join(dir, "a"), []byte("12345"), 0o644)).To(Succeed())
+			_, err = plugin.Execute(1)
+			Expect(err).To(MatchError(runtime.ErrFileQuota))
+
+			// Too many files, counting directories
+			Expect(os.MkdirAll(filepath.Join(dir, "b", "c"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "b", "c", "d"), nil, 0o644)).To(Succeed())
+			_, err = plugin.Execute(1)
+			Expect(err).To(MatchError(runtime.ErrFileQuota))
+		})
+
+		It("should serialize concurrent calls to other plugins", func() {
+			plugin, err := runtime.LoadPlugin(pluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.Reentrant()).To(BeFalse())
+			Expect(plugin.Init()).To(Succeed())
+
+			expected, err := plugin.Execute(21)
+			Expect(err).NotTo(HaveOccurred())
+
+			var wg sync.WaitGroup
+			results := make(chan int, 8*50)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						result, err := plugin.Execute(21)
+						Expect(err).NotTo(HaveOccurred())
+						results <- result
+					}
+				}()
+			}
+			wg.Wait()
+			close(results)
+
+			for result := range results {
+				Expect(result).To(Equal(expected))
+			}
+		})
+	})
+
+	// =========================================================================
+	// TEST: Memory limits
+	// Why: The limit is what keeps a misbehaving plugin from exhausting host
+	//      memory; it must be enforced when memory grows, be attributable in
+	//      errors, and yield to the plugin's own manifest.
+	// =========================================================================
+	Describe("LoadPluginWithMemoryLimit", func() {
+		var (
+			calcPath string
+			pages    int
+		)
+
+		BeforeEach(func() {
+			wasm, err := os.ReadFile(filepath.Join("..", "plugins", "calc", "calc.wasm"))
+			if os.IsNotExist(err) {
+				Skip("Test plugin not found: calc.wasm")
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			calcPath = filepath.Join(GinkgoT().TempDir(), "calc.wasm")
+			Expect(os.WriteFile(calcPath, wasm, 0644)).To(Succeed())
+
+			module, err := wasminfo.Parse(wasm)
+			Expect(err).NotTo(HaveOccurred())
+			pages = module.MemoryPages
+		})
+
+		It("should reject a module whose initial memory exceeds the limit", func() {
+			plugin, err := runtime.LoadPluginWithMemoryLimit(calcPath, pages-1)
+
+			Expect(errors.Is(err, runtime.ErrMemoryLimit)).To(BeTrue(), "%v", err)
+			Expect(plugin).To(BeNil())
+		})
+
+		It("should stop memory from growing past the limit", func() {
+			plugin, err := runtime.LoadPluginWithMemoryLimit(calcPath, pages+1)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.MemoryLimit()).To(Equal(uint(pages + 1)))
+
+			_, err = plugin.Call("reserve", 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = plugin.Call("reserve", 1)
+			Expect(errors.Is(err, runtime.ErrMemoryLimit)).To(BeTrue(), "%v", err)
+		})
+
+		It("should prefer the manifest's memory_pages", func() {
+			data := []byte(`{"name": "calc", "version": "1.0.0", "limits": {"memory_pages": 65536}}`)
+			Expect(os.WriteFile(filepath.Join(filepath.Dir(calcPath), manifest.FileName), data, 0644)).To(Succeed())
+
+			plugin, err := runtime.LoadPluginWithMemoryLimit(calcPath, pages-1)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+
+			Expect(plugin.MemoryLimit()).To(Equal(uint(65536)))
+		})
+
+		It("should not limit plugins loaded without one", func() {
+			plugin, err := runtime.LoadPlugin(calcPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+
+			Expect(plugin.MemoryLimit()).To(BeZero())
+		})
+	})
+
+	// =========================================================================
+	// TEST: Clone
+	// Why: Clones let callers run a non-reentrant plugin in parallel; each
+	//      must be an independent instance, and a snapshotted plugin must
+	//      clone into its initialized state without running Init() again.
+	// =========================================================================
+	Describe("Clone", func() {
+		var plugin *runtime.Plugin
+
+		BeforeEach(func() {
+			if _, err := os.Stat(validPluginPath); os.IsNotExist(err) {
+				Skip("Test plugin not found")
+			}
+
+			var err error
+			plugin, err = runtime.LoadPlugin(validPluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(plugin.Close)
+		})
+
+		It("should load an uninitialized instance without a snapshot", func() {
+			Expect(plugin.Init()).To(Succeed())
+
+			clone, err := plugin.Clone()
+			Expect(err).NotTo(HaveOccurred())
+			defer clone.Close()
+
+			_, err = clone.Execute(21)
+			Expect(err).To(HaveOccurred(), "hello requires init()")
+
+			Expect(clone.Init()).To(Succeed())
+			Expect(clone.Execute(21)).To(Equal(43))
+		})
+
+		It("should start from the snapshot when one was taken", func() {
+			Expect(plugin.Init()).To(Succeed())
+			Expect(plugin.Snapshot()).To(Succeed())
+
+			clone, err := plugin.Clone()
+			Expect(err).NotTo(HaveOccurred())
+			defer clone.Close()
+			Expect(clone.Execute(21)).To(Equal(43))
+
+			// The clone is independent of the original
+			Expect(plugin.Cleanup()).To(Succeed())
+			Expect(clone.Execute(1)).To(Equal(3))
+
+			// and shares the snapshot for Restore()
+			Expect(clone.Cleanup()).To(Succeed())
+			Expect(clone.Restore()).To(Succeed())
+			Expect(clone.Execute(1)).To(Equal(3))
+		})
+
+		It("should run clones in parallel", func() {
+			Expect(plugin.Init()).To(Succeed())
+			Expect(plugin.Snapshot()).To(Succeed())
+
+			var wg sync.WaitGroup
+			outputs := make([]int, 4)
+			errs := make([]error, 4)
+			for i := range outputs {
+				clone, err := plugin.Clone()
+				Expect(err).NotTo(HaveOccurred())
+				defer clone.Close()
+
+				wg.Add(1)
+				go func(i int, clone *runtime.Plugin) {
+					defer wg.Done()
+					outputs[i], errs[i] = clone.Execute(i)
+				}(i, clone)
+			}
+			wg.Wait()
+
+			for i := range outputs {
+				Expect(errs[i]).NotTo(HaveOccurred())
+				Expect(outputs[i]).To(Equal(2*i + 1))
+			}
+		})
+
+		It("should fail for a closed plugin", func() {
+			plugin.Close()
+
+			_, err := plugin.Clone()
+			Expect(err).To(MatchError(ContainSubstring("closed")))
+		})
+	})
+
 	// =========================================================================
 	// TEST: Close() idempotency
 	// Why: Close() must be safe to call multiple times without panicking.
@@ -0,0 +1,194 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogModule is the import module of the structured logging host API:
+//
+//	__attribute__((import_module("logging"), import_name("log")))
+//	extern "C" void log(int level, const char *msg, int len);
+//
+// Register it with NewLogModule.
+const LogModule = "logging"
+
+// LogLevel is the severity a plugin passes to log().
+type LogLevel int32
+
+// Log levels, in increasing severity. Levels outside this range are clamped.
+const (
+	LogDebug LogLevel = 0
+	LogInfo  LogLevel = 1
+	LogWarn  LogLevel = 2
+	LogError LogLevel = 3
+)
+
+// maxLogMessage bounds a single log message; longer ones are truncated.
+const maxLogMessage = 4096
+
+// maxCapturedLogs bounds the entries a LogCapture keeps, so a chatty plugin
+// cannot grow a response without limit.
+const maxCapturedLogs = 100
+
+// String returns the level name, e.g. "warn".
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+}
+
+// MarshalText encodes the level by name, so JSON carries "warn" rather
+// than 2.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level name produced by MarshalText.
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	for level := LogDebug; level <= LogError; level++ {
+		if string(text) == level.String() {
+			*l = level
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %q", text)
+}
+
+// LogEntry is one message a plugin logged.
+type LogEntry struct {
+	Time      time.Time `json:"time"`
+	Level     LogLevel  `json:"level"`
+	Plugin    string    `json:"plugin"`               // Plugin name, from its file name
+	RequestID string    `json:"request_id,omitempty"` // From the call's LogCapture
+	Tenant    string    `json:"tenant,omitempty"`     // From the call's CallInfo
+	Message   string    `json:"message"`
+}
+
+// LogCapture collects the messages logged during calls whose context
+// carries it (see WithLogCapture), typically one request. It is safe for
+// concurrent use.
+type LogCapture struct {
+	requestID string
+
+	mu      sync.Mutex
+	entries []LogEntry
+	dropped int
+}
+
+// NewLogCapture creates a capture whose entries are tagged with requestID.
+func NewLogCapture(requestID string) *LogCapture {
+	return &LogCapture{requestID: requestID}
+}
+
+// RequestID returns the request ID entries are tagged with.
+func (c *LogCapture) RequestID() string {
+	return c.requestID
+}
+
+// Entries returns the captured entries in the order they were logged.
+func (c *LogCapture) Entries() []LogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]LogEntry(nil), c.entries...)
+}
+
+// Dropped returns the number of entries discarded after the first
+// maxCapturedLogs.
+func (c *LogCapture) Dropped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// add records an entry, dropping it once the capture is full.
+func (c *LogCapture) add(entry LogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCapturedLogs {
+		c.dropped++
+		return
+	}
+	c.entries = append(c.entries, entry)
+}
+
+// logCaptureKey is the context key of the call's LogCapture.
+type logCaptureKey struct{}
+
+// WithLogCapture returns a context that makes plugin calls made with it
+// (ExecuteContext and friends) record their log() messages in capture.
+func WithLogCapture(ctx context.Context, capture *LogCapture) context.Context {
+	return context.WithValue(ctx, logCaptureKey{}, capture)
+}
+
+// logCaptureFrom returns the LogCapture carried by ctx, or nil.
+func logCaptureFrom(ctx context.Context) *LogCapture {
+	capture, _ := ctx.Value(logCaptureKey{}).(*LogCapture)
+	return capture
+}
+
+// NewLogModule creates the LogModule host module. Every message is passed
+// to sink, if non-nil, and recorded in the LogCapture of the call that
+// logged it, if any. sink may be called concurrently by different plugins.
+func NewLogModule(sink func(LogEntry)) *HostModule {
+	return NewHostModule(LogModule).
+		Func("log", []ValueType{ValueI32, ValueI32, ValueI32}, nil,
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				length := args[2].(int32)
+				truncated := length > maxLogMessage
+				if truncated {
+					length = maxLogMessage
+				}
+				message, err := call.ReadString(args[1].(int32), length)
+				if err != nil {
+					return nil, err
+				}
+				if truncated {
+					message += "..."
+				}
+
+				entry := LogEntry{
+					Time:    time.Now(),
+					Level:   clampLogLevel(LogLevel(args[0].(int32))),
+					Plugin:  strings.TrimSuffix(filepath.Base(call.Path()), ".wasm"),
+					Tenant:  CallInfoFrom(call.Context()).Tenant,
+					Message: message,
+				}
+				capture := logCaptureFrom(call.Context())
+				if capture != nil {
+					entry.RequestID = capture.requestID
+				}
+
+				if sink != nil {
+					sink(entry)
+				}
+				if capture != nil {
+					capture.add(entry)
+				}
+				return nil, nil
+			})
+}
+
+// clampLogLevel maps levels outside LogDebug..LogError to the nearest one.
+func clampLogLevel(level LogLevel) LogLevel {
+	switch {
+	case level < LogDebug:
+		return LogDebug
+	case level > LogError:
+		return LogError
+	}
+	return level
+}
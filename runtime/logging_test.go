@@ -0,0 +1,130 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Log module", func() {
+	// =========================================================================
+	// TEST: Level encoding
+	// Why: Levels reach operators and clients as JSON; names are stable
+	//      across languages where raw numbers are not.
+	// =========================================================================
+	DescribeTable("LogLevel JSON",
+		func(level runtime.LogLevel, name string) {
+			data, err := json.Marshal(level)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`"` + name + `"`))
+
+			var decoded runtime.LogLevel
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(level))
+		},
+		Entry("debug", runtime.LogDebug, "debug"),
+		Entry("info", runtime.LogInfo, "info"),
+		Entry("warn", runtime.LogWarn, "warn"),
+		Entry("error", runtime.LogError, "error"),
+	)
+
+	It("should reject unknown level names", func() {
+		var level runtime.LogLevel
+		Expect(json.Unmarshal([]byte(`"fatal"`), &level)).To(MatchError(ContainSubstring("unknown log level")))
+	})
+
+	// =========================================================================
+	// TEST: Capture and sink
+	// Why: Messages must reach both the service's log and, for debugging,
+	//      the request that caused them, tagged so they can be correlated.
+	// =========================================================================
+	Describe("with the logger plugin", func() {
+		var (
+			plugin *runtime.Plugin
+			mu     sync.Mutex
+			sunk   []runtime.LogEntry
+		)
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "logger", "logger.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			sunk = nil
+			module := runtime.NewLogModule(func(entry runtime.LogEntry) {
+				mu.Lock()
+				sunk = append(sunk, entry)
+				mu.Unlock()
+			})
+
+			var err error
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		It("should pass every message to the sink", func() {
+			Expect(sunk).To(HaveLen(1))
+			Expect(sunk[0].Level).To(Equal(runtime.LogDebug))
+			Expect(sunk[0].Message).To(Equal("initialized"))
+			Expect(sunk[0].Plugin).To(Equal("logger"))
+			Expect(sunk[0].RequestID).To(BeEmpty())
+		})
+
+		It("should capture messages of calls made with a capture", func() {
+			capture := runtime.NewLogCapture("req-1")
+			ctx := runtime.WithLogCapture(context.Background(), capture)
+
+			_, err := plugin.ExecuteContext(ctx, -1)
+			Expect(err).To(HaveOccurred())
+
+			entries := capture.Entries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Level).To(Equal(runtime.LogError))
+			Expect(entries[0].Message).To(Equal("input must not be negative"))
+			Expect(entries[0].RequestID).To(Equal("req-1"))
+			Expect(sunk).To(ContainElement(entries[0]))
+		})
+
+		It("should not capture messages of later calls without it", func() {
+			capture := runtime.NewLogCapture("req-1")
+			_, err := plugin.ExecuteContext(runtime.WithLogCapture(context.Background(), capture), 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = plugin.Execute(1)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(capture.Entries()).To(HaveLen(1))
+			Expect(sunk[len(sunk)-1].RequestID).To(BeEmpty())
+		})
+
+		It("should drop messages beyond the capture limit", func() {
+			capture := runtime.NewLogCapture("req-1")
+			ctx := runtime.WithLogCapture(context.Background(), capture)
+
+			for i := 0; i < 105; i++ {
+				_, err := plugin.ExecuteContext(ctx, 1)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(capture.Entries()).To(HaveLen(100))
+			Expect(capture.Dropped()).To(Equal(5))
+		})
+	})
+})
@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// PluginMemory is the host memory attributed to one plugin build,
+// identified by the SHA-256 digest of its .wasm file. Plugins deployed
+// under several names with the same contents share one entry.
+//
+// WasmEdge allocates outside the Go heap, so these figures explain a
+// large resident set that Go's own heap statistics cannot.
+type PluginMemory struct {
+	Digest string   `json:"digest"`
+	Paths  []string `json:"paths"` // Plugin files with these contents, sorted
+
+	// Instances counts the live instances of the build's pools, idle or
+	// checked out.
+	Instances int `json:"instances"`
+
+	// LinearMemoryBytes is the instances' linear memory, as of their
+	// creation or last return to the pool.
+	LinearMemoryBytes int64 `json:"linear_memory_bytes"`
+
+	// SnapshotBytes is the post-Init memory copies kept for Restore().
+	SnapshotBytes int64 `json:"snapshot_bytes"`
+
+	// ModuleBytes is the size of the .wasm file whose parsed module the
+	// module cache holds. The parsed form is typically a few times larger.
+	ModuleBytes int64 `json:"module_bytes"`
+
+	// ArtifactBytes is the size of the AOT-compiled artifact, which every
+	// instance maps into the process.
+	ArtifactBytes int64 `json:"artifact_bytes"`
+
+	TotalBytes int64 `json:"total_bytes"` // Sum of the above
+}
+
+// PoolMemory is the memory held by a pool's live instances.
+type PoolMemory struct {
+	Instances         int   `json:"instances"`
+	LinearMemoryBytes int64 `json:"linear_memory_bytes"`
+	SnapshotBytes     int64 `json:"snapshot_bytes"`
+}
+
+// footprint is the memory of one instance.
+type footprint struct {
+	linear   int64
+	snapshot int64
+}
+
+// AttributeMemory breaks down the memory held by pools and the caches they
+// load from (either may be nil) by plugin build, largest first. Modules
+// still cached for builds without a pool are included too.
+func AttributeMemory(pools []*Pool, modules *ModuleCache, compiler *CompilerCache) []PluginMemory {
+	builds := make(map[string]*PluginMemory)
+	build := func(digest string) *PluginMemory {
+		b, ok := builds[digest]
+		if !ok {
+			b = &PluginMemory{Digest: digest}
+			builds[digest] = b
+		}
+		return b
+	}
+	addPath := func(b *PluginMemory, path string) {
+		for _, known := range b.Paths {
+			if known == path {
+				return
+			}
+		}
+		b.Paths = append(b.Paths, path)
+	}
+
+	for _, pool := range pools {
+		b := build(pool.Digest())
+		addPath(b, pool.Path())
+		usage := pool.Memory()
+		b.Instances += usage.Instances
+		b.LinearMemoryBytes += usage.LinearMemoryBytes
+		b.SnapshotBytes += usage.SnapshotBytes
+
+		// Identical contents share one artifact
+		if compiler != nil && b.ArtifactBytes == 0 {
+			b.ArtifactBytes = compiler.artifactSize(pool.Path())
+		}
+	}
+
+	if modules != nil {
+		for digest, module := range modules.usage() {
+			b := build(digest)
+			b.ModuleBytes = module.size
+			for _, path := range module.paths {
+				addPath(b, path)
+			}
+		}
+	}
+
+	result := make([]PluginMemory, 0, len(builds))
+	for _, b := range builds {
+		sort.Strings(b.Paths)
+		b.TotalBytes = b.LinearMemoryBytes + b.SnapshotBytes + b.ModuleBytes + b.ArtifactBytes
+		result = append(result, *b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalBytes != result[j].TotalBytes {
+			return result[i].TotalBytes > result[j].TotalBytes
+		}
+		return result[i].Digest < result[j].Digest
+	})
+	return result
+}
+
+// MaxMemoryBytes returns the most linear memory the instance can grow
+// to: its memory limit, or else the module's declared maximum, or else
+// 4 GiB. It is 0 if the plugin exports no memory.
+func (p *Plugin) MaxMemoryBytes() int64 {
+	_, maxPages, ok := p.memoryPages()
+	if !ok {
+		return 0
+	}
+	return int64(maxPages) * wasmPageSize
+}
+
+// instanceFootprint is the footprint of instance. Engines other than
+// WasmEdge do not report memory; their instances count without it.
+func instanceFootprint(instance Instance) footprint {
+	if plugin, ok := instance.(*Plugin); ok {
+		return plugin.footprint()
+	}
+	return footprint{}
+}
+
+// footprint measures the instance's linear memory and snapshot. The caller
+// must own the instance, so no call is changing its memory.
+func (p *Plugin) footprint() footprint {
+	var f footprint
+	if pages, _, ok := p.memoryPages(); ok {
+		f.linear = int64(pages) * wasmPageSize
+	}
+	if p.snapshot != nil {
+		f.snapshot = int64(len(p.snapshot.memory))
+	}
+	return f
+}
+
+// fileDigest returns the hex SHA-256 of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open plugin: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash plugin %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,94 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("AttributeMemory", func() {
+	It("should report nothing without pools or caches", func() {
+		Expect(runtime.AttributeMemory(nil, nil, nil)).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Memory attribution
+	// Why: "The server uses 6 GB" is only actionable if the memory can be
+	//      traced to the plugin builds holding it, counting shared modules
+	//      once and following instances as they come and go.
+	// =========================================================================
+	Describe("with the hello plugin", func() {
+		var (
+			source string
+			data   []byte
+		)
+
+		BeforeEach(func() {
+			source = filepath.Join("..", "plugins", "hello", "hello.wasm")
+			var err error
+			data, err = os.ReadFile(source)
+			if os.IsNotExist(err) {
+				Skip("Test plugin not found: " + source)
+			}
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should attribute instances, snapshots, and the cached module to the build", func() {
+			cache := runtime.NewModuleCache()
+			defer cache.Close()
+
+			// The same build deployed under a second name
+			copyPath := filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+			Expect(os.WriteFile(copyPath, data, 0644)).To(Succeed())
+
+			opts := runtime.PoolOptions{Reset: runtime.ResetRestore, MinSize: 2, Modules: cache}
+			first, err := runtime.NewPool(source, opts)
+			Expect(err).NotTo(HaveOccurred())
+			defer first.Close()
+			second, err := runtime.NewPool(copyPath, opts)
+			Expect(err).NotTo(HaveOccurred())
+			defer second.Close()
+			Expect(first.Digest()).To(Equal(second.Digest()))
+
+			usage := first.Memory()
+			Expect(usage.Instances).To(Equal(2))
+			Expect(usage.LinearMemoryBytes).To(BeNumerically(">", 0))
+			Expect(usage.SnapshotBytes).To(Equal(usage.LinearMemoryBytes))
+
+			builds := runtime.AttributeMemory([]*runtime.Pool{first, second}, cache, nil)
+			Expect(builds).To(HaveLen(1))
+			Expect(builds[0].Digest).To(Equal(first.Digest()))
+			Expect(builds[0].Paths).To(ConsistOf(source, copyPath))
+			Expect(builds[0].Instances).To(Equal(4))
+			Expect(builds[0].ModuleBytes).To(Equal(int64(len(data))))
+			Expect(builds[0].TotalBytes).To(Equal(2*usage.LinearMemoryBytes + 2*usage.SnapshotBytes + int64(len(data))))
+		})
+
+		It("should follow instances as they are checked out and discarded", func() {
+			pool, err := runtime.NewPool(source, runtime.PoolOptions{Reset: runtime.ResetRestore, MinSize: 1})
+			Expect(err).NotTo(HaveOccurred())
+			defer pool.Close()
+
+			plugin, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.Memory().Instances).To(Equal(1), "checked-out instances still hold memory")
+
+			pool.Discard(plugin)
+			Eventually(func() int { return pool.Memory().Instances }).WithTimeout(5*time.Second).Should(Equal(1), "MinSize is refilled")
+
+			extra, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			more, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.Memory().Instances).To(Equal(2))
+			pool.Put(extra)
+			pool.Put(more)
+			Expect(pool.Memory().Instances).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,206 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+)
+
+// ModuleCache keeps parsed and validated modules in memory, so loading a
+// plugin whose bytes were seen before skips reading and parsing the file.
+// Every load still gets a fresh VM with its own memory, globals, and host
+// module instances; only the immutable module structure is shared.
+//
+// Modules are keyed by the SHA-256 of the file's contents, so:
+//   - identical plugins at different paths share one module
+//   - a rebuilt plugin gets a new key and is parsed on its next load; the
+//     module of the previous build is released once no path has it
+//
+// The cache only applies to .wasm files; plugins loaded from AOT artifacts
+// bypass it. ModuleCache is safe for concurrent use.
+type ModuleCache struct {
+	mu      sync.Mutex
+	sources map[string]sourceKey     // Last key computed per plugin path
+	modules map[string]*wasmedge.AST // Validated modules by key
+	stats   ModuleCacheStats
+}
+
+// ModuleCacheStats counts cache outcomes since creation.
+type ModuleCacheStats struct {
+	Hits    uint64 `json:"hits"`    // Loads served from a cached module
+	Misses  uint64 `json:"misses"`  // Loads that parsed and validated the file
+	Modules int    `json:"modules"` // Modules currently cached
+}
+
+// NewModuleCache creates an empty cache.
+func NewModuleCache() *ModuleCache {
+	return &ModuleCache{
+		sources: make(map[string]sourceKey),
+		modules: make(map[string]*wasmedge.AST),
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *ModuleCache) Stats() ModuleCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Modules = len(c.modules)
+	return stats
+}
+
+// Close releases every cached module. Plugins loaded from the cache are
+// unaffected; the cache must not be used afterwards.
+func (c *ModuleCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, ast := range c.modules {
+		ast.Release()
+		delete(c.modules, key)
+	}
+}
+
+// Forget drops what the cache knows about the plugin at path, typically
+// because it was deleted, and releases its module unless another path has
+// the same contents.
+func (c *ModuleCache) Forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	source, ok := c.sources[path]
+	if !ok {
+		return
+	}
+	delete(c.sources, path)
+	c.releaseUnused(source.key)
+}
+
+// loadInto loads the module at path into vm, from the cache if its
+// contents were loaded before.
+//
+// A module is parsed and validated under the cache lock, so concurrent
+// first loads of one plugin parse it once. The VM copies the module it
+// loads, which is why cached modules can be released while plugins loaded
+// from them live on.
+func (c *ModuleCache) loadInto(vm *wasmedge.VM, path string) error {
+	key, previous, err := c.key(path)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ast, ok := c.modules[key]
+	if ok {
+		c.stats.Hits++
+	} else {
+		ast, err = parseModule(path)
+		if err != nil {
+			return err
+		}
+		c.modules[key] = ast
+		c.stats.Misses++
+	}
+	if previous != "" && previous != key {
+		c.releaseUnused(previous)
+	}
+
+	if err := vm.LoadWasmAST(ast); err != nil {
+		return fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	}
+	return nil
+}
+
+// key returns the content key for path and the key it had on the previous
+// call, if it changed. Files are only re-hashed when their size or mtime
+// changed.
+func (c *ModuleCache) key(path string) (key, previous string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("plugin file not found: %w", err)
+	}
+
+	c.mu.Lock()
+	cached, ok := c.sources[path]
+	c.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.key, "", nil
+	}
+
+	key, err = fileDigest(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	c.mu.Lock()
+	c.sources[path] = sourceKey{size: info.Size(), modTime: info.ModTime(), key: key}
+	c.mu.Unlock()
+	return key, cached.key, nil
+}
+
+// cachedModule is a module held by the cache, for AttributeMemory.
+type cachedModule struct {
+	size  int64    // Size of the .wasm file it was parsed from
+	paths []string // Plugin files with its contents
+}
+
+// usage returns the cached modules by key, which is the SHA-256 of their
+// contents.
+func (c *ModuleCache) usage() map[string]cachedModule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	modules := make(map[string]cachedModule, len(c.modules))
+	for path, source := range c.sources {
+		if _, ok := c.modules[source.key]; !ok {
+			continue
+		}
+		module := modules[source.key]
+		module.size = source.size
+		module.paths = append(module.paths, path)
+		modules[source.key] = module
+	}
+	return modules
+}
+
+// releaseUnused releases the module for key unless another plugin path
+// still has those contents. Called with mu held.
+func (c *ModuleCache) releaseUnused(key string) {
+	for _, source := range c.sources {
+		if source.key == key {
+			return
+		}
+	}
+	if ast, ok := c.modules[key]; ok {
+		ast.Release()
+		delete(c.modules, key)
+	}
+}
+
+// parseModule reads, parses, and validates the module at path.
+func parseModule(path string) (*wasmedge.AST, error) {
+	loader := wasmedge.NewLoader()
+	if loader == nil {
+		return nil, fmt.Errorf("failed to create WasmEdge loader")
+	}
+	defer loader.Release()
+
+	ast, err := loader.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	}
+
+	validator := wasmedge.NewValidator()
+	if validator == nil {
+		ast.Release()
+		return nil, fmt.Errorf("failed to create WasmEdge validator")
+	}
+	defer validator.Release()
+
+	if err := validator.Validate(ast); err != nil {
+		ast.Release()
+		return nil, fmt.Errorf("WASM module validation failed for %s: %w", path, err)
+	}
+	return ast, nil
+}
@@ -0,0 +1,108 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("ModuleCache", func() {
+	var cache *runtime.ModuleCache
+
+	BeforeEach(func() {
+		cache = runtime.NewModuleCache()
+		DeferCleanup(cache.Close)
+	})
+
+	// copyPlugin copies the hello plugin to a private path the test may modify.
+	copyPlugin := func() string {
+		src := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		data, err := os.ReadFile(src)
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: " + src)
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		path := filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+		return path
+	}
+
+	load := func(path string) *runtime.Plugin {
+		plugin, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{Modules: cache})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		return plugin
+	}
+
+	It("should fail for a missing plugin without caching anything", func() {
+		_, err := runtime.LoadPluginWithOptions(filepath.Join(GinkgoT().TempDir(), "missing.wasm"),
+			runtime.LoadOptions{Modules: cache})
+		Expect(err).To(MatchError(ContainSubstring("plugin file not found")))
+		Expect(cache.Stats()).To(Equal(runtime.ModuleCacheStats{}))
+	})
+
+	// =========================================================================
+	// TEST: Parse once, instantiate many
+	// Why: Pools load the same plugin for every instance; only the first load
+	//      may parse the file, while each instance keeps its own state.
+	// =========================================================================
+	It("should parse on first load and give each load its own instance", func() {
+		path := copyPlugin()
+
+		first := load(path)
+		second := load(path)
+		Expect(cache.Stats()).To(Equal(runtime.ModuleCacheStats{Hits: 1, Misses: 1, Modules: 1}))
+
+		Expect(first.Init()).To(Succeed())
+		Expect(second.Init()).To(Succeed())
+		Expect(first.Execute(21)).To(Equal(43))
+		Expect(second.Execute(1)).To(Equal(3))
+	})
+
+	It("should share a module between identical plugins at different paths", func() {
+		load(copyPlugin())
+		load(copyPlugin())
+
+		Expect(cache.Stats()).To(Equal(runtime.ModuleCacheStats{Hits: 1, Misses: 1, Modules: 1}))
+	})
+
+	It("should parse a rebuilt plugin and release the previous build", func() {
+		path := copyPlugin()
+		load(path)
+
+		// Appending a custom section changes the contents but keeps the module valid
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.Write([]byte{0x00, 0x04, 0x03, 'x', 'y', 'z'})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		load(path)
+		Expect(cache.Stats()).To(Equal(runtime.ModuleCacheStats{Misses: 2, Modules: 1}))
+	})
+
+	It("should release the module of a forgotten plugin", func() {
+		first, second := copyPlugin(), copyPlugin()
+		load(first)
+		load(second)
+
+		cache.Forget(first)
+		Expect(cache.Stats().Modules).To(Equal(1), "the second path still has the contents")
+		cache.Forget(second)
+		Expect(cache.Stats().Modules).To(Equal(0))
+	})
+
+	It("should not cache an invalid module", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "invalid.wasm")
+		Expect(os.WriteFile(path, []byte("not wasm"), 0644)).To(Succeed())
+
+		_, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{Modules: cache})
+		Expect(err).To(HaveOccurred())
+		Expect(cache.Stats().Modules).To(Equal(0))
+	})
+})
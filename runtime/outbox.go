@@ -0,0 +1,180 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// OutboxModule is the import module of the transactional outbox host API:
+//
+//	__attribute__((import_module("outbox"), import_name("enqueue")))
+//	extern "C" int enqueue(int kind, const char *target, int target_len,
+//	                       const char *payload, int payload_len);
+//
+// Register it with NewOutboxModule.
+const OutboxModule = "outbox"
+
+// EffectKind selects how an enqueued effect is delivered.
+type EffectKind int32
+
+// Effect kinds a plugin passes to enqueue().
+const (
+	EffectHTTP    EffectKind = 0 // POST the payload to the target URL
+	EffectMessage EffectKind = 1 // Publish the payload to the target topic
+)
+
+// Result codes enqueue() returns to the plugin.
+const (
+	OutboxOK          int32 = 0  // The effect was enqueued
+	OutboxUnavailable int32 = -1 // The call has no outbox (see WithOutbox)
+	OutboxFull        int32 = -2 // The call reached maxOutboxEffects, or the effect is too large
+	OutboxRejected    int32 = -3 // Unknown kind, or the host refused the target
+)
+
+// maxOutboxEffects bounds the effects one outbox holds.
+const maxOutboxEffects = 100
+
+// maxEffectTarget and maxEffectPayload bound a single effect.
+const (
+	maxEffectTarget  = 2048
+	maxEffectPayload = 1 << 20
+)
+
+// String returns the kind name, e.g. "http".
+func (k EffectKind) String() string {
+	switch k {
+	case EffectHTTP:
+		return "http"
+	case EffectMessage:
+		return "message"
+	default:
+		return fmt.Sprintf("kind(%d)", int32(k))
+	}
+}
+
+// MarshalText encodes the kind by name.
+func (k EffectKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind name produced by MarshalText.
+func (k *EffectKind) UnmarshalText(text []byte) error {
+	for kind := EffectHTTP; kind <= EffectMessage; kind++ {
+		if string(text) == kind.String() {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown effect kind %q", text)
+}
+
+// Effect is an outbound side effect a plugin enqueued.
+type Effect struct {
+	Kind    EffectKind `json:"kind"`
+	Target  string     `json:"target"`            // URL for EffectHTTP, topic for EffectMessage
+	Payload []byte     `json:"payload,omitempty"` // Request body or message
+	Plugin  string     `json:"plugin"`            // Plugin name, from its file name
+}
+
+// Outbox collects the effects enqueued during calls whose context carries
+// it (see WithOutbox), typically one request. The runtime never delivers
+// them: the caller commits the outbox once the call succeeded and discards
+// it otherwise, so a failed or retried call leaks no effects. It is safe
+// for concurrent use.
+type Outbox struct {
+	mu      sync.Mutex
+	effects []Effect
+}
+
+// NewOutbox creates an empty outbox.
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+// Effects returns the enqueued effects in the order they were enqueued.
+func (o *Outbox) Effects() []Effect {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Effect(nil), o.effects...)
+}
+
+// add enqueues an effect, reporting false once the outbox is full.
+func (o *Outbox) add(effect Effect) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.effects) >= maxOutboxEffects {
+		return false
+	}
+	o.effects = append(o.effects, effect)
+	return true
+}
+
+// outboxKey is the context key of the call's Outbox.
+type outboxKey struct{}
+
+// WithOutbox returns a context that makes plugin calls made with it
+// (ExecuteContext and friends) enqueue their effects in outbox.
+func WithOutbox(ctx context.Context, outbox *Outbox) context.Context {
+	return context.WithValue(ctx, outboxKey{}, outbox)
+}
+
+// outboxFrom returns the Outbox carried by ctx, or nil.
+func outboxFrom(ctx context.Context) *Outbox {
+	outbox, _ := ctx.Value(outboxKey{}).(*Outbox)
+	return outbox
+}
+
+// NewOutboxModule creates the OutboxModule host module. check, if non-nil,
+// vets every effect before it is enqueued, e.g. against allowed hosts; an
+// effect it refuses is dropped and the plugin gets OutboxRejected. check
+// may be called concurrently by different plugins.
+//
+// Failures are reported to the plugin as result codes rather than traps,
+// so it can decide whether to fail the call.
+func NewOutboxModule(check func(Effect) error) *HostModule {
+	return NewHostModule(OutboxModule).
+		Func("enqueue", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				result := func(code int32) ([]interface{}, error) {
+					return []interface{}{code}, nil
+				}
+
+				outbox := outboxFrom(call.Context())
+				if outbox == nil {
+					return result(OutboxUnavailable)
+				}
+				kind := EffectKind(args[0].(int32))
+				if kind != EffectHTTP && kind != EffectMessage {
+					return result(OutboxRejected)
+				}
+				if args[2].(int32) > maxEffectTarget || args[4].(int32) > maxEffectPayload {
+					return result(OutboxFull)
+				}
+
+				target, err := call.ReadString(args[1].(int32), args[2].(int32))
+				if err != nil {
+					return nil, err
+				}
+				payload, err := call.Read(args[3].(int32), args[4].(int32))
+				if err != nil {
+					return nil, err
+				}
+
+				effect := Effect{
+					Kind:    kind,
+					Target:  target,
+					Payload: payload,
+					Plugin:  strings.TrimSuffix(filepath.Base(call.Path()), ".wasm"),
+				}
+				if check != nil && check(effect) != nil {
+					return result(OutboxRejected)
+				}
+				if !outbox.add(effect) {
+					return result(OutboxFull)
+				}
+				return result(OutboxOK)
+			})
+}
@@ -0,0 +1,115 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Outbox module", func() {
+	DescribeTable("EffectKind JSON",
+		func(kind runtime.EffectKind, name string) {
+			data, err := json.Marshal(kind)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`"` + name + `"`))
+
+			var decoded runtime.EffectKind
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(kind))
+		},
+		Entry("http", runtime.EffectHTTP, "http"),
+		Entry("message", runtime.EffectMessage, "message"),
+	)
+
+	It("should reject unknown kind names", func() {
+		var kind runtime.EffectKind
+		Expect(json.Unmarshal([]byte(`"email"`), &kind)).To(MatchError(ContainSubstring("unknown effect kind")))
+	})
+
+	// =========================================================================
+	// TEST: Enqueue without delivery
+	// Why: Effects must only leave the host once the call that enqueued them
+	//      succeeded; the runtime collects them and leaves the decision to
+	//      the caller, even when the call fails.
+	// =========================================================================
+	Describe("with the relay plugin", func() {
+		var (
+			plugin  *runtime.Plugin
+			checked []runtime.Effect
+			refuse  error
+		)
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "relay", "relay.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			checked, refuse = nil, nil
+			module := runtime.NewOutboxModule(func(effect runtime.Effect) error {
+				checked = append(checked, effect)
+				return refuse
+			})
+
+			var err error
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		It("should collect effects in the call's outbox", func() {
+			outbox := runtime.NewOutbox()
+			output, err := plugin.ExecuteContext(runtime.WithOutbox(context.Background(), outbox), 7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal(7))
+
+			Expect(outbox.Effects()).To(Equal([]runtime.Effect{
+				{Kind: runtime.EffectHTTP, Target: "http://hooks.internal/relay", Payload: []byte("7"), Plugin: "relay"},
+				{Kind: runtime.EffectMessage, Target: "relay.processed", Payload: []byte("7"), Plugin: "relay"},
+			}))
+			Expect(checked).To(Equal(outbox.Effects()))
+		})
+
+		It("should keep the effects of a failed call for the caller to discard", func() {
+			outbox := runtime.NewOutbox()
+			_, err := plugin.ExecuteContext(runtime.WithOutbox(context.Background(), outbox), -1)
+			Expect(err).To(HaveOccurred())
+
+			Expect(outbox.Effects()).To(HaveLen(2))
+		})
+
+		It("should report the outbox unavailable to calls without one", func() {
+			_, err := plugin.Execute(7)
+
+			var pluginErr *runtime.PluginError
+			Expect(errors.As(err, &pluginErr)).To(BeTrue())
+			Expect(pluginErr.Code).To(Equal(int32(-4)))
+			Expect(checked).To(BeEmpty())
+		})
+
+		It("should not enqueue effects the host refuses", func() {
+			refuse = errors.New("host not allowed")
+			outbox := runtime.NewOutbox()
+			_, err := plugin.ExecuteContext(runtime.WithOutbox(context.Background(), outbox), 7)
+			Expect(err).To(HaveOccurred())
+
+			Expect(outbox.Effects()).To(BeEmpty())
+			Expect(checked).To(HaveLen(1))
+		})
+	})
+})
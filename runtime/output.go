@@ -0,0 +1,162 @@
+package runtime
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// WASI file descriptors and error numbers the fd_write replacement of
+// CaptureOutput uses.
+const (
+	wasiStdout = 1
+	wasiStderr = 2
+
+	wasiErrnoSuccess = 0
+	wasiErrnoBadf    = 8
+	wasiErrnoFault   = 21
+	wasiErrnoInval   = 28
+
+	// maxIovecs is the IOV_MAX of wasi-libc; longer vectors are invalid.
+	maxIovecs = 1024
+)
+
+// OutputCapture collects what a plugin writes to its WASI stdout and stderr
+// during calls whose context carries it (see WithOutputCapture), typically
+// one request. Each stream keeps its first limit bytes; the rest is
+// dropped. It is safe for concurrent use.
+type OutputCapture struct {
+	limit int
+
+	mu        sync.Mutex
+	stdout    []byte
+	stderr    []byte
+	truncated bool
+}
+
+// NewOutputCapture creates a capture keeping up to limit bytes per stream.
+func NewOutputCapture(limit int) *OutputCapture {
+	return &OutputCapture{limit: limit}
+}
+
+// Stdout returns what was written to stdout.
+func (c *OutputCapture) Stdout() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.stdout...)
+}
+
+// Stderr returns what was written to stderr.
+func (c *OutputCapture) Stderr() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.stderr...)
+}
+
+// Truncated reports whether output was dropped at the limit.
+func (c *OutputCapture) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.truncated
+}
+
+// write appends data to the stream of fd, up to the limit.
+func (c *OutputCapture) write(fd int32, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stream := &c.stdout
+	if fd == wasiStderr {
+		stream = &c.stderr
+	}
+	if room := c.limit - len(*stream); len(data) > room {
+		data = data[:max(room, 0)]
+		c.truncated = true
+	}
+	*stream = append(*stream, data...)
+}
+
+// outputCaptureKey is the context key of the call's OutputCapture.
+type outputCaptureKey struct{}
+
+// WithOutputCapture returns a context that makes calls made with it into
+// plugins loaded with LoadOptions.CaptureOutput record their stdout and
+// stderr in capture instead of writing them to the host's.
+func WithOutputCapture(ctx context.Context, capture *OutputCapture) context.Context {
+	return context.WithValue(ctx, outputCaptureKey{}, capture)
+}
+
+// outputCaptureFrom returns the OutputCapture carried by ctx, or nil.
+func outputCaptureFrom(ctx context.Context) *OutputCapture {
+	if ctx == nil {
+		return nil
+	}
+	capture, _ := ctx.Value(outputCaptureKey{}).(*OutputCapture)
+	return capture
+}
+
+// captureOutput replaces fd_write of the plugin's WASI module with one
+// that writes stdout and stderr to the current call's OutputCapture, or to
+// the host's streams outside such calls. WasmEdge offers no other way to
+// redirect a VM's standard streams.
+//
+// The replacement cannot reach the WASI file table, so writes to any other
+// descriptor fail with EBADF; it is only installed for plugins without
+// pre-opened directories, which have no files to write.
+func captureOutput(wasi *wasmedge.Module, path string, state *hostState) error {
+	i32 := []ValueType{ValueI32, ValueI32, ValueI32, ValueI32}
+	ftype := wasmedge.NewFunctionType(wasmValTypes(i32), wasmValTypes(i32[:1]))
+	function := wasmedge.NewFunction(ftype, func(_ interface{}, frame *wasmedge.CallingFrame, params []interface{}) ([]interface{}, wasmedge.Result) {
+		call := engine.NewHostCall(state.context(), path, state.manifest, guestMemory(frame.GetMemoryByIndex(0)))
+		errno := fdWrite(call, params[0].(int32), params[1].(int32), params[2].(int32), params[3].(int32))
+		return []interface{}{errno}, wasmedge.Result_Success
+	}, nil, 0)
+	ftype.Release()
+	if function == nil {
+		return fmt.Errorf("failed to create the fd_write capturing output of %s", path)
+	}
+	wasi.AddFunction("fd_write", function)
+	return nil
+}
+
+// fdWrite implements WASI fd_write for stdout and stderr: it gathers the
+// iovecs at iovs, writes them, and stores the byte count at nwritten.
+func fdWrite(call *HostCall, fd, iovs, iovsLen, nwritten int32) int32 {
+	if fd != wasiStdout && fd != wasiStderr {
+		return wasiErrnoBadf
+	}
+	if iovsLen < 0 || iovsLen > maxIovecs {
+		return wasiErrnoInval
+	}
+	vectors, err := call.Read(iovs, iovsLen*8)
+	if err != nil {
+		return wasiErrnoFault
+	}
+	var data []byte
+	for i := 0; i < len(vectors); i += 8 {
+		buf := int32(binary.LittleEndian.Uint32(vectors[i:]))
+		length := int32(binary.LittleEndian.Uint32(vectors[i+4:]))
+		chunk, err := call.Read(buf, length)
+		if err != nil {
+			return wasiErrnoFault
+		}
+		data = append(data, chunk...)
+	}
+
+	if capture := outputCaptureFrom(call.Context()); capture != nil {
+		capture.write(fd, data)
+	} else if fd == wasiStdout {
+		os.Stdout.Write(data)
+	} else {
+		os.Stderr.Write(data)
+	}
+	if err := call.Write(nwritten, binary.LittleEndian.AppendUint32(nil, uint32(len(data)))); err != nil {
+		return wasiErrnoFault
+	}
+	return wasiErrnoSuccess
+}
@@ -0,0 +1,78 @@
+package runtime_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Output capture", func() {
+	// =========================================================================
+	// TEST: Per-call stdout and stderr
+	// Why: printf debugging only helps if the output reaches the request
+	//      that produced it, and a chatty plugin must not grow it unbounded.
+	// =========================================================================
+	var plugin *runtime.Plugin
+
+	load := func(opts runtime.LoadOptions) {
+		path := filepath.Join("..", "plugins", "printer", "printer.wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		var err error
+		plugin, err = runtime.LoadPluginWithOptions(path, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugin.Init()).To(Succeed())
+	}
+
+	AfterEach(func() {
+		if plugin != nil {
+			plugin.Close()
+			plugin = nil
+		}
+	})
+
+	It("should capture stdout and stderr of calls made with a capture", func() {
+		load(runtime.LoadOptions{CaptureOutput: true})
+		capture := runtime.NewOutputCapture(1024)
+		ctx := runtime.WithOutputCapture(context.Background(), capture)
+
+		_, err := plugin.ExecuteContext(ctx, -3)
+		Expect(err).To(HaveOccurred())
+
+		Expect(string(capture.Stdout())).To(Equal("input: -3\n"))
+		Expect(string(capture.Stderr())).To(Equal("input must not be negative\n"))
+		Expect(capture.Truncated()).To(BeFalse())
+	})
+
+	It("should keep only the first bytes of each stream", func() {
+		load(runtime.LoadOptions{CaptureOutput: true})
+		capture := runtime.NewOutputCapture(4)
+		ctx := runtime.WithOutputCapture(context.Background(), capture)
+
+		output, err := plugin.ExecuteContext(ctx, 12)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(Equal(12))
+
+		_, err = plugin.ExecuteContext(ctx, 34)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(string(capture.Stdout())).To(Equal("inpu"))
+		Expect(capture.Stderr()).To(BeEmpty())
+		Expect(capture.Truncated()).To(BeTrue())
+	})
+
+	It("should leave output alone unless loaded with CaptureOutput", func() {
+		load(runtime.LoadOptions{})
+		capture := runtime.NewOutputCapture(1024)
+
+		_, err := plugin.ExecuteContext(runtime.WithOutputCapture(context.Background(), capture), 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capture.Stdout()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,205 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+// Exports of the memory-based payload ABI; see engine.ExportAllocate.
+const (
+	ExportAllocate     = engine.ExportAllocate
+	ExportDeallocate   = engine.ExportDeallocate
+	ExportProcessBytes = engine.ExportProcessBytes
+)
+
+// MaxPayloadSize bounds input and output payloads (16 MiB). It protects the
+// host from copying out arbitrary regions reported by a misbehaving plugin.
+const MaxPayloadSize = engine.MaxPayloadSize
+
+// SupportsPayloads reports whether the plugin implements the memory-based
+// payload ABI required by ExecuteBytes and ExecuteString.
+func (p *Plugin) SupportsPayloads() bool {
+	return p.HasExport(ExportAllocate) &&
+		p.HasExport(ExportDeallocate) &&
+		p.HasExport(ExportProcessBytes)
+}
+
+// ExecuteBytes calls the plugin's "process_bytes" function with the given
+// input and returns the bytes it produced.
+//
+// Call sequence:
+// 1. allocate(len(input)) reserves a guest buffer, which the host fills
+// 2. process_bytes(ptr, len) runs the plugin and reports the output location
+// 3. The host copies the output and frees both buffers with deallocate()
+//
+// The plugin must be initialized with Init() before calling ExecuteBytes().
+// The returned slice is owned by the caller.
+//
+// Returns ErrNoOutput if process_bytes returns ABINoOutput, or an error if:
+// - The plugin does not implement the payload ABI
+// - The input or output exceeds MaxPayloadSize
+// - process_bytes returns a negative error code (a *PluginError)
+// - The reported output lies outside linear memory
+func (p *Plugin) ExecuteBytes(input []byte) ([]byte, error) {
+	return p.ExecuteBytesContext(context.Background(), input)
+}
+
+// ExecuteBytesContext is ExecuteBytes with cancellation of the
+// process_bytes() call, as in ExecuteContext.
+func (p *Plugin) ExecuteBytesContext(ctx context.Context, input []byte) ([]byte, error) {
+	return p.executePayload(ctx, ExportProcessBytes, input)
+}
+
+// executePayload runs export, process_bytes or process_json, on input
+// following the payload ABI call sequence.
+func (p *Plugin) executePayload(ctx context.Context, export string, input []byte) (output []byte, err error) {
+	ctx, span := p.startExecuteSpan(ctx, export, tracing.AttrInputSize.Int(len(input)))
+	defer func() { endSpan(span, err) }()
+	defer p.lock()()
+
+	if p.vm == nil {
+		return nil, fmt.Errorf("plugin is closed")
+	}
+	if !p.HasExport(ExportAllocate) || !p.HasExport(ExportDeallocate) || !p.HasExport(export) {
+		return nil, fmt.Errorf("plugin %s does not implement the payload ABI (%s, %s, %s)",
+			p.path, ExportAllocate, ExportDeallocate, export)
+	}
+	if len(input) > MaxPayloadSize {
+		return nil, fmt.Errorf("input of %d bytes exceeds the %d byte payload limit",
+			len(input), MaxPayloadSize)
+	}
+
+	// Step 1: Copy the input into a guest buffer
+	inPtr, err := p.allocate(len(input))
+	if err != nil {
+		return nil, err
+	}
+	defer p.deallocate(inPtr, len(input))
+
+	if err := p.writeMemory(inPtr, input); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Run the plugin
+	// Expected signature: long long process_bytes(int ptr, int len)
+	result, err := p.call(ctx, export, int32(inPtr), int32(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s() for %s: %w", export, p.path, err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%s() did not return a value for %s", export, p.path)
+	}
+
+	packed := result[0].(int64)
+	if packed == ABINoOutput {
+		return nil, fmt.Errorf("%s() for %s: %w", export, p.path, ErrNoOutput)
+	}
+	if packed < 0 {
+		return nil, p.pluginError(export, int32(packed))
+	}
+
+	// Step 3: Copy the output out of guest memory and release it
+	outPtr := uint32(packed >> 32)
+	outLen := int(uint32(packed))
+	if outLen > MaxPayloadSize {
+		p.deallocate(outPtr, outLen)
+		return nil, fmt.Errorf("output of %d bytes from %s exceeds the %d byte payload limit",
+			outLen, p.path, MaxPayloadSize)
+	}
+	if outLen == 0 {
+		return []byte{}, nil
+	}
+	defer p.deallocate(outPtr, outLen)
+
+	return p.readMemory(outPtr, outLen)
+}
+
+// ExecuteString is ExecuteBytes for UTF-8 text.
+func (p *Plugin) ExecuteString(input string) (string, error) {
+	return p.ExecuteStringContext(context.Background(), input)
+}
+
+// ExecuteStringContext is ExecuteBytesContext for UTF-8 text.
+func (p *Plugin) ExecuteStringContext(ctx context.Context, input string) (string, error) {
+	output, err := p.ExecuteBytesContext(ctx, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// allocate reserves size bytes in guest memory via the plugin's allocator.
+func (p *Plugin) allocate(size int) (uint32, error) {
+	result, err := p.vm.Execute(ExportAllocate, int32(size))
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute %s(%d) for %s: %w", ExportAllocate, size, p.path, p.memoryLimitError(err))
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("%s() did not return a value for %s", ExportAllocate, p.path)
+	}
+
+	ptr := result[0].(int32)
+	if ptr == 0 && size > 0 {
+		return 0, p.memoryLimitError(fmt.Errorf("%s(%d) failed for %s: out of memory", ExportAllocate, size, p.path))
+	}
+	if ptr < 0 {
+		return 0, fmt.Errorf("%s(%d) returned error code %d for %s: %s",
+			ExportAllocate, size, ptr, p.path, engine.ABIErrorName(ptr))
+	}
+	return uint32(ptr), nil
+}
+
+// deallocate releases a buffer returned by allocate(). Failures are ignored:
+// the buffer is at worst leaked until the instance is reset.
+func (p *Plugin) deallocate(ptr uint32, size int) {
+	p.vm.Execute(ExportDeallocate, int32(ptr), int32(size))
+}
+
+// writeMemory copies data into linear memory at ptr.
+func (p *Plugin) writeMemory(ptr uint32, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	mem, err := p.memory()
+	if err != nil {
+		return err
+	}
+	if err := mem.SetData(data, uint(ptr), uint(len(data))); err != nil {
+		return fmt.Errorf("failed to write %d bytes at %#x for %s: %w", len(data), ptr, p.path, err)
+	}
+	return nil
+}
+
+// readMemory returns a copy of size bytes of linear memory at ptr.
+func (p *Plugin) readMemory(ptr uint32, size int) ([]byte, error) {
+	mem, err := p.memory()
+	if err != nil {
+		return nil, err
+	}
+
+	// GetData fails for out-of-bounds regions and aliases the VM's memory,
+	// so the result must be copied before the buffer is deallocated
+	data, err := mem.GetData(uint(ptr), uint(size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %d bytes at %#x for %s: %w", size, ptr, p.path, err)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// memory returns the plugin's exported linear memory.
+func (p *Plugin) memory() (*wasmedge.Memory, error) {
+	module := p.vm.GetActiveModule()
+	if module == nil {
+		return nil, fmt.Errorf("no active module for %s", p.path)
+	}
+	mem := module.FindMemory("memory")
+	if mem == nil {
+		return nil, fmt.Errorf("plugin %s does not export its linear memory", p.path)
+	}
+	return mem, nil
+}
@@ -0,0 +1,116 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Payload ABI", func() {
+	var (
+		upperPluginPath string
+		helloPluginPath string
+	)
+
+	BeforeEach(func() {
+		upperPluginPath = filepath.Join("..", "plugins", "upper", "upper.wasm")
+		helloPluginPath = filepath.Join("..", "plugins", "hello", "hello.wasm")
+	})
+
+	Context("with a plugin implementing the payload ABI", func() {
+		var plugin *runtime.Plugin
+
+		BeforeEach(func() {
+			if _, err := os.Stat(upperPluginPath); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + upperPluginPath)
+			}
+
+			var err error
+			plugin, err = runtime.LoadPlugin(upperPluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			plugin.Close()
+		})
+
+		// =====================================================================
+		// TEST: Round trip through guest memory
+		// Why: Input must be copied in and output copied out intact, including
+		//      non-ASCII bytes the plugin passes through unchanged.
+		// =====================================================================
+		It("should round-trip strings", func() {
+			Expect(plugin.SupportsPayloads()).To(BeTrue())
+
+			output, err := plugin.ExecuteString("hello, wörld")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal("HELLO, WöRLD"))
+		})
+
+		It("should round-trip bytes including zeros", func() {
+			output, err := plugin.ExecuteBytes([]byte{'a', 0, 'b', 0xff})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal([]byte{'A', 0, 'B', 0xff}))
+		})
+
+		It("should handle empty input", func() {
+			output, err := plugin.ExecuteBytes(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(BeEmpty())
+		})
+
+		// =====================================================================
+		// TEST: Guest buffers are released
+		// Why: A leak in allocate/deallocate bookkeeping exhausts the guest heap
+		//      after enough calls on a pooled instance.
+		// =====================================================================
+		It("should not exhaust guest memory across many calls", func() {
+			input := strings.Repeat("x", 64<<10)
+			for i := 0; i < 64; i++ {
+				_, err := plugin.ExecuteString(input)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should reject inputs over MaxPayloadSize", func() {
+			_, err := plugin.ExecuteBytes(make([]byte, runtime.MaxPayloadSize+1))
+			Expect(err).To(MatchError(ContainSubstring("payload limit")))
+		})
+	})
+
+	Context("with a plugin implementing only the integer ABI", func() {
+		It("should report that payloads are unsupported", func() {
+			if _, err := os.Stat(helloPluginPath); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + helloPluginPath)
+			}
+
+			plugin, err := runtime.LoadPlugin(helloPluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer plugin.Close()
+			Expect(plugin.Init()).To(Succeed())
+
+			Expect(plugin.SupportsPayloads()).To(BeFalse())
+			_, err = plugin.ExecuteString("hi")
+			Expect(err).To(MatchError(ContainSubstring("does not implement the payload ABI")))
+		})
+	})
+
+	It("should fail on a closed plugin", func() {
+		if _, err := os.Stat(helloPluginPath); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + helloPluginPath)
+		}
+
+		plugin, err := runtime.LoadPlugin(helloPluginPath)
+		Expect(err).NotTo(HaveOccurred())
+		plugin.Close()
+
+		_, err = plugin.ExecuteBytes([]byte("x"))
+		Expect(err).To(MatchError("plugin is closed"))
+	})
+})
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrhapile/wasm-plugin-system/abi"
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ExportProcessPB is the export of the protobuf payload ABI; see
+// engine.ExportProcessPB.
+const ExportProcessPB = engine.ExportProcessPB
+
+// ErrInvalidProto is returned when process_pb produces output that is not
+// a PluginResponse.
+var ErrInvalidProto = engine.ErrInvalidProto
+
+// SupportsProto reports whether the plugin implements the protobuf payload
+// ABI required by ExecuteProto.
+func (p *Plugin) SupportsProto() bool {
+	return p.HasExport(ExportAllocate) &&
+		p.HasExport(ExportDeallocate) &&
+		p.HasExport(ExportProcessPB)
+}
+
+// ExecuteProto calls the plugin's "process_pb" function with the given
+// request and returns the response it produced.
+//
+// The call sequence and errors are those of ExecuteBytes. In addition,
+// output that does not decode as a PluginResponse fails the call with
+// ErrInvalidProto.
+func (p *Plugin) ExecuteProto(req *abi.PluginRequest) (*abi.PluginResponse, error) {
+	return p.ExecuteProtoContext(context.Background(), req)
+}
+
+// ExecuteProtoContext is ExecuteProto with cancellation of the
+// process_pb() call, as in ExecuteContext.
+func (p *Plugin) ExecuteProtoContext(ctx context.Context, req *abi.PluginRequest) (*abi.PluginResponse, error) {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("input for %s() of %s: %w", ExportProcessPB, p.path, err)
+	}
+	output, err := p.executePayload(ctx, ExportProcessPB, input)
+	if err != nil {
+		return nil, err
+	}
+	var resp abi.PluginResponse
+	if err := proto.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("%s() for %s: %w: %v", ExportProcessPB, p.path, ErrInvalidProto, err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,67 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/abi"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Protobuf payload ABI", func() {
+	load := func(name string) *runtime.Plugin {
+		path := filepath.Join("..", "plugins", name, name+".wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		plugin, err := runtime.LoadPlugin(path)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		Expect(plugin.Init()).To(Succeed())
+		return plugin
+	}
+
+	// =========================================================================
+	// TEST: Protobuf round trip
+	// Why: Plugins with several typed inputs and outputs exchange the
+	//      envelope of abi/abi.proto; every field must reach the plugin and
+	//      come back with its type.
+	// =========================================================================
+	It("should pass requests to process_pb and decode its response", func() {
+		plugin := load("pbecho")
+		Expect(plugin.SupportsProto()).To(BeTrue())
+		Expect(plugin.SupportsJSON()).To(BeFalse())
+
+		fields, err := abi.NewFields(map[string]any{
+			"name":  "widget",
+			"count": 3,
+			"ratio": 0.25,
+			"raw":   []byte{0, 1, 2},
+			"tags":  []any{"a", true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := plugin.ExecuteProto(&abi.PluginRequest{Fields: fields, RequestId: "req-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(abi.AsMap(resp.Fields)).To(Equal(map[string]any{
+			"name":   "widget",
+			"count":  int64(3),
+			"ratio":  0.25,
+			"raw":    []byte{0, 1, 2},
+			"tags":   []any{"a", true},
+			"fields": int64(5),
+		}))
+
+		_, err = plugin.ExecuteProto(&abi.PluginRequest{})
+		Expect(err).To(MatchError(runtime.ErrNoOutput))
+	})
+
+	It("should refuse plugins without process_pb", func() {
+		plugin := load("envelope")
+		Expect(plugin.SupportsProto()).To(BeFalse())
+		_, err := plugin.ExecuteProto(&abi.PluginRequest{})
+		Expect(err).To(MatchError(ContainSubstring("does not implement the payload ABI")))
+	})
+})
@@ -0,0 +1,316 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// MetricsModule is the import module of the plugin metrics host API. Like
+// context(), its functions live in the "host" module:
+//
+//	__attribute__((import_module("host"), import_name("metric_incr")))
+//	extern "C" int metric_incr(const char *name, int name_len, double value,
+//	                           const char *tags, int tags_len);
+//
+//	__attribute__((import_module("host"), import_name("metric_observe")))
+//	extern "C" int metric_observe(const char *name, int name_len, double value,
+//	                              const char *tags, int tags_len);
+//
+// metric_incr adds value to a counter, metric_observe records it in a
+// histogram. tags is a comma-separated list of key=value pairs, e.g.
+// "region=eu,tier=gold", and may be empty.
+//
+// Add the functions to a module with PluginMetrics.Define.
+const MetricsModule = "host"
+
+// Result codes metric_incr() and metric_observe() return to the plugin.
+const (
+	MetricOK      int32 = 0  // The value was recorded
+	MetricInvalid int32 = -1 // Invalid name, tags, or value, or the name is used by the other function
+	MetricLimited int32 = -2 // The plugin reached maxPluginSeries
+)
+
+// ErrInvalidMetric is returned for a metric name, tag list, or value that
+// cannot be recorded.
+var ErrInvalidMetric = errors.New("invalid metric")
+
+// ErrMetricLimit is returned when a plugin would exceed maxPluginSeries.
+var ErrMetricLimit = errors.New("too many metric series")
+
+// Limits on what a plugin can record, so a buggy plugin cannot make every
+// scrape arbitrarily large.
+const (
+	maxPluginSeries = 1000 // Distinct name and tag combinations per plugin
+	maxMetricName   = 64
+	maxMetricTags   = 8
+	maxTagValue     = 128
+)
+
+// PluginMetricBuckets are the histogram bounds of metric_observe(). The
+// values are the plugin's own, so these are generic rather than latencies.
+var PluginMetricBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// PluginMetricPrefix is prepended to every family a plugin defines,
+// together with its name: metric "orders_total" of plugin "shop" is
+// exported as plugin_custom_shop_orders_total.
+const PluginMetricPrefix = "plugin_custom_"
+
+// PluginMetrics is a registry of the counters and histograms plugins
+// record through the MetricsModule functions, namespaced per plugin. It is
+// safe for concurrent use; register Collect with a metrics.Registry to
+// export them.
+type PluginMetrics struct {
+	mu      sync.Mutex
+	plugins map[string]*pluginSeries // By plugin name
+}
+
+// pluginSeries holds the metrics of one plugin.
+type pluginSeries struct {
+	types  map[string]string        // Metric type by name
+	series map[string]*metricSeries // By name and tags
+}
+
+// metricSeries is one counter or histogram with a fixed set of tags.
+type metricSeries struct {
+	name      string
+	tags      []metrics.Label // Sorted by name
+	value     float64         // Counters
+	histogram *metrics.Histogram
+}
+
+// NewPluginMetrics creates an empty registry.
+func NewPluginMetrics() *PluginMetrics {
+	return &PluginMetrics{plugins: make(map[string]*pluginSeries)}
+}
+
+// Define adds metric_incr and metric_observe to module, recording into m,
+// and returns module. Invalid values and exhausted limits are reported to
+// the plugin as result codes rather than traps.
+func (m *PluginMetrics) Define(module *HostModule) *HostModule {
+	params := []ValueType{ValueI32, ValueI32, ValueF64, ValueI32, ValueI32}
+	define := func(name string, record func(plugin, name string, value float64, tags string) error) {
+		module.Func(name, params, []ValueType{ValueI32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				if args[1].(int32) > maxMetricName || args[4].(int32) > maxMetricTags*(maxMetricName+maxTagValue+2) {
+					return []interface{}{MetricInvalid}, nil
+				}
+				metric, err := call.ReadString(args[0].(int32), args[1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				tags, err := call.ReadString(args[3].(int32), args[4].(int32))
+				if err != nil {
+					return nil, err
+				}
+
+				plugin := strings.TrimSuffix(filepath.Base(call.Path()), ".wasm")
+				if skipEffect(call.Context(), name, metric) {
+					return []interface{}{MetricOK}, nil
+				}
+				err = record(plugin, metric, args[2].(float64), tags)
+				switch {
+				case errors.Is(err, ErrMetricLimit):
+					return []interface{}{MetricLimited}, nil
+				case err != nil:
+					return []interface{}{MetricInvalid}, nil
+				}
+				return []interface{}{MetricOK}, nil
+			})
+	}
+	define("metric_incr", m.Add)
+	define("metric_observe", m.Observe)
+	return module
+}
+
+// Add adds value, which must not be negative, to a counter of plugin.
+// tags is formatted as for metric_incr().
+func (m *PluginMetrics) Add(plugin, name string, value float64, tags string) error {
+	if value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%w: counter %s: value %g must be finite and not negative", ErrInvalidMetric, name, value)
+	}
+	series, err := m.series(plugin, name, metrics.TypeCounter, tags)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	series.value += value
+	m.mu.Unlock()
+	return nil
+}
+
+// Observe records value in a histogram of plugin. tags is formatted as for
+// metric_observe().
+func (m *PluginMetrics) Observe(plugin, name string, value float64, tags string) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%w: histogram %s: value %g must be finite", ErrInvalidMetric, name, value)
+	}
+	series, err := m.series(plugin, name, metrics.TypeHistogram, tags)
+	if err != nil {
+		return err
+	}
+	series.histogram.Observe(value)
+	return nil
+}
+
+// Forget drops the metrics of plugin, e.g. after it was deleted.
+func (m *PluginMetrics) Forget(plugin string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.plugins, plugin)
+}
+
+// series returns the series of plugin with the given name and tags,
+// creating it if needed.
+func (m *PluginMetrics) series(plugin, name, metricType, tags string) (*metricSeries, error) {
+	if !validMetricName(name) {
+		return nil, fmt.Errorf("%w: name %q", ErrInvalidMetric, name)
+	}
+	labels, err := parseMetricTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	key := name
+	for _, label := range labels {
+		key += "," + label.Name + "=" + label.Value
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.plugins[plugin]
+	if !ok {
+		p = &pluginSeries{types: make(map[string]string), series: make(map[string]*metricSeries)}
+		m.plugins[plugin] = p
+	}
+	if t, ok := p.types[name]; ok && t != metricType {
+		return nil, fmt.Errorf("%w: %s is a %s", ErrInvalidMetric, name, t)
+	}
+	if s, ok := p.series[key]; ok {
+		return s, nil
+	}
+	if len(p.series) >= maxPluginSeries {
+		return nil, fmt.Errorf("%w: plugin %s has %d", ErrMetricLimit, plugin, maxPluginSeries)
+	}
+
+	s := &metricSeries{name: name, tags: labels}
+	if metricType == metrics.TypeHistogram {
+		s.histogram = metrics.NewHistogram(PluginMetricBuckets)
+	}
+	p.types[name] = metricType
+	p.series[key] = s
+	return s, nil
+}
+
+// Collect reports every plugin's metrics, one family per plugin and name,
+// each sample labeled with the plugin. It is a metrics.Collector.
+func (m *PluginMetrics) Collect() []metrics.Family {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	families := make(map[string]*metrics.Family)
+	for plugin, p := range m.plugins {
+		for _, s := range p.series {
+			name := PluginMetricPrefix + metricNamePart(plugin) + "_" + s.name
+			family, ok := families[name]
+			if !ok {
+				family = &metrics.Family{
+					Name: name,
+					Help: fmt.Sprintf("Metric %s recorded by plugin %s.", s.name, plugin),
+					Type: p.types[s.name],
+				}
+				families[name] = family
+			} else if family.Type != p.types[s.name] {
+				// Two plugin names that differ only in characters
+				// replaced by metricNamePart; keep the first
+				continue
+			}
+
+			labels := append([]metrics.Label{{Name: "plugin", Value: plugin}}, s.tags...)
+			sample := metrics.Sample{Labels: labels, Value: s.value}
+			if s.histogram != nil {
+				snapshot := s.histogram.Snapshot()
+				sample.Histogram = &snapshot
+			}
+			family.Samples = append(family.Samples, sample)
+		}
+	}
+
+	result := make([]metrics.Family, 0, len(families))
+	for _, family := range families {
+		sort.Slice(family.Samples, func(i, j int) bool {
+			return labelKey(family.Samples[i].Labels) < labelKey(family.Samples[j].Labels)
+		})
+		result = append(result, *family)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// parseMetricTags parses "k=v,k2=v2" into labels sorted by name.
+func parseMetricTags(tags string) ([]metrics.Label, error) {
+	if tags == "" {
+		return nil, nil
+	}
+	pairs := strings.Split(tags, ",")
+	if len(pairs) > maxMetricTags {
+		return nil, fmt.Errorf("%w: more than %d tags", ErrInvalidMetric, maxMetricTags)
+	}
+	labels := make([]metrics.Label, 0, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !validMetricName(name) || name == "plugin" || name == "le" || len(value) > maxTagValue {
+			return nil, fmt.Errorf("%w: tag %q", ErrInvalidMetric, pair)
+		}
+		labels = append(labels, metrics.Label{Name: name, Value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	for i := 1; i < len(labels); i++ {
+		if labels[i].Name == labels[i-1].Name {
+			return nil, fmt.Errorf("%w: tag %s given twice", ErrInvalidMetric, labels[i].Name)
+		}
+	}
+	return labels, nil
+}
+
+// validMetricName reports whether name is a valid Prometheus metric or
+// label name without the reserved "__" prefix.
+func validMetricName(name string) bool {
+	if name == "" || len(name) > maxMetricName || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, ch := range name {
+		if !(ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || i > 0 && ch >= '0' && ch <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// metricNamePart maps a plugin name to characters valid in a metric name.
+func metricNamePart(plugin string) string {
+	return strings.Map(func(ch rune) rune {
+		if ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' {
+			return ch
+		}
+		return '_'
+	}, plugin)
+}
+
+// labelKey orders samples by their labels.
+func labelKey(labels []metrics.Label) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(label.Name + "=" + label.Value + ",")
+	}
+	return b.String()
+}
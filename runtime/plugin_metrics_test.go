@@ -0,0 +1,148 @@
+package runtime_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("PluginMetrics", func() {
+	var pluginMetrics *runtime.PluginMetrics
+
+	BeforeEach(func() {
+		pluginMetrics = runtime.NewPluginMetrics()
+	})
+
+	exposition := func() string {
+		registry := metrics.NewRegistry()
+		registry.Register(pluginMetrics.Collect)
+		var buf bytes.Buffer
+		Expect(registry.WriteText(&buf)).To(Succeed())
+		return buf.String()
+	}
+
+	// =========================================================================
+	// TEST: Namespaced plugin metrics
+	// Why: Plugin authors name their own metrics; two plugins using the same
+	//      name must not share a series, and tags must become labels.
+	// =========================================================================
+	It("should export counters and histograms per plugin", func() {
+		Expect(pluginMetrics.Add("shop", "orders_total", 1, "region=eu")).To(Succeed())
+		Expect(pluginMetrics.Add("shop", "orders_total", 2, "region=eu")).To(Succeed())
+		Expect(pluginMetrics.Add("shop", "orders_total", 1, "")).To(Succeed())
+		Expect(pluginMetrics.Add("billing", "orders_total", 5, "")).To(Succeed())
+		Expect(pluginMetrics.Observe("shop", "basket_size", 3, "tier=gold,region=eu")).To(Succeed())
+
+		text := exposition()
+		Expect(text).To(ContainSubstring("# TYPE plugin_custom_shop_orders_total counter\n"))
+		Expect(text).To(ContainSubstring(`plugin_custom_shop_orders_total{plugin="shop"} 1` + "\n"))
+		Expect(text).To(ContainSubstring(`plugin_custom_shop_orders_total{plugin="shop",region="eu"} 3` + "\n"))
+		Expect(text).To(ContainSubstring(`plugin_custom_billing_orders_total{plugin="billing"} 5` + "\n"))
+		Expect(text).To(ContainSubstring("# TYPE plugin_custom_shop_basket_size histogram\n"))
+		Expect(text).To(ContainSubstring(`plugin_custom_shop_basket_size_count{plugin="shop",region="eu",tier="gold"} 1` + "\n"))
+	})
+
+	It("should map plugin names to valid metric names", func() {
+		Expect(pluginMetrics.Add("image-resize", "jobs_total", 1, "")).To(Succeed())
+
+		Expect(exposition()).To(ContainSubstring(`plugin_custom_image_resize_jobs_total{plugin="image-resize"} 1`))
+	})
+
+	It("should reject invalid metrics", func() {
+		for _, call := range []func() error{
+			func() error { return pluginMetrics.Add("shop", "", 1, "") },
+			func() error { return pluginMetrics.Add("shop", "9lives", 1, "") },
+			func() error { return pluginMetrics.Add("shop", "orders-total", 1, "") },
+			func() error { return pluginMetrics.Add("shop", "__reserved", 1, "") },
+			func() error { return pluginMetrics.Add("shop", "orders_total", -1, "") },
+			func() error { return pluginMetrics.Add("shop", "orders_total", 1, "region") },
+			func() error { return pluginMetrics.Add("shop", "orders_total", 1, "plugin=other") },
+			func() error { return pluginMetrics.Add("shop", "orders_total", 1, "a=1,a=2") },
+			func() error { return pluginMetrics.Observe("shop", "latency", 1, "le=1") },
+		} {
+			Expect(errors.Is(call(), runtime.ErrInvalidMetric)).To(BeTrue())
+		}
+		Expect(pluginMetrics.Collect()).To(BeEmpty())
+	})
+
+	It("should not let one name be both a counter and a histogram", func() {
+		Expect(pluginMetrics.Add("shop", "orders", 1, "")).To(Succeed())
+
+		err := pluginMetrics.Observe("shop", "orders", 1, "")
+		Expect(errors.Is(err, runtime.ErrInvalidMetric)).To(BeTrue())
+		Expect(pluginMetrics.Observe("billing", "orders", 1, "")).To(Succeed())
+	})
+
+	// =========================================================================
+	// TEST: Series limit
+	// Why: A plugin tagging metrics with unbounded values (user IDs, ...)
+	//      must not grow every scrape without limit.
+	// =========================================================================
+	It("should limit the series of a plugin", func() {
+		var err error
+		for i := 0; err == nil; i++ {
+			err = pluginMetrics.Add("shop", "orders_total", 1, fmt.Sprintf("user=%d", i))
+		}
+		Expect(errors.Is(err, runtime.ErrMetricLimit)).To(BeTrue())
+		Expect(pluginMetrics.Add("shop", "orders_total", 1, "user=0")).To(Succeed())
+		Expect(pluginMetrics.Add("billing", "orders_total", 1, "")).To(Succeed())
+	})
+
+	It("should forget the metrics of a plugin", func() {
+		Expect(pluginMetrics.Add("shop", "orders_total", 1, "")).To(Succeed())
+		Expect(pluginMetrics.Add("billing", "orders_total", 1, "")).To(Succeed())
+
+		pluginMetrics.Forget("shop")
+		families := pluginMetrics.Collect()
+		Expect(families).To(HaveLen(1))
+		Expect(families[0].Name).To(Equal("plugin_custom_billing_orders_total"))
+	})
+
+	Describe("with the meter plugin", func() {
+		var plugin *runtime.Plugin
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "meter", "meter.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			var err error
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{pluginMetrics.Define(runtime.NewContextModule())},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		It("should record the metrics the plugin emits", func() {
+			for _, input := range []int{2, 4, 7} {
+				result, err := plugin.Execute(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(input))
+			}
+			_, err := plugin.Execute(-1)
+			Expect(err).To(HaveOccurred())
+
+			text := exposition()
+			Expect(text).To(ContainSubstring(`plugin_custom_meter_requests_total{plugin="meter",parity="even"} 2`))
+			Expect(text).To(ContainSubstring(`plugin_custom_meter_requests_total{plugin="meter",parity="odd"} 1`))
+			Expect(text).To(ContainSubstring(`plugin_custom_meter_rejected_total{plugin="meter"} 1`))
+			Expect(text).To(ContainSubstring(`plugin_custom_meter_input_value_sum{plugin="meter"} 13`))
+		})
+	})
+})
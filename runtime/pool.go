@@ -0,0 +1,993 @@
+package runtime
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/metrics"
+)
+
+// defaultCalibrationRounds is the number of rounds MeasureResetStrategy runs
+// per strategy when a pool is created with ResetAuto.
+const defaultCalibrationRounds = 20
+
+// defaultCalibrationTimeout bounds the measurement of ResetAuto when
+// PoolOptions.CalibrationTimeout is zero.
+const defaultCalibrationTimeout = 30 * time.Second
+
+// maxMaintenanceInterval bounds how long an expired idle instance or a
+// missing warm instance goes unnoticed.
+const maxMaintenanceInterval = time.Second
+
+// defaultShutdownTimeout bounds an on_shutdown() call when
+// PoolOptions.ShutdownTimeout is zero.
+const defaultShutdownTimeout = time.Second
+
+// defaultInitTimeout bounds the init() or init_with_config() call of a new
+// instance when PoolOptions.InitTimeout is zero.
+const defaultInitTimeout = 30 * time.Second
+
+// healthCheckTimeout bounds a health() call; a plugin that takes longer is
+// unhealthy.
+const healthCheckTimeout = 5 * time.Second
+
+// EvictionReason explains why a pool discarded an instance.
+type EvictionReason string
+
+const (
+	// EvictDiscarded means the caller discarded the instance (e.g., after
+	// a failed call).
+	EvictDiscarded EvictionReason = "discarded"
+
+	// EvictRecreate means the pool uses ResetRecreate and never reuses
+	// instances.
+	EvictRecreate EvictionReason = "recreate"
+
+	// EvictRestoreFailed means Restore() failed, typically because the
+	// plugin grew its linear memory.
+	EvictRestoreFailed EvictionReason = "restore_failed"
+
+	// EvictSnapshotFailed means a new instance could not be snapshotted.
+	EvictSnapshotFailed EvictionReason = "snapshot_failed"
+
+	// EvictPoolClosed means the instance was idle or returned after Close().
+	EvictPoolClosed EvictionReason = "pool_closed"
+
+	// EvictIdleTimeout means the instance sat idle longer than IdleTimeout
+	// while the pool was above MinSize.
+	EvictIdleTimeout EvictionReason = "idle_timeout"
+
+	// EvictUnhealthy means the instance's health() export reported an
+	// error, either right after Init() or in a periodic check.
+	EvictUnhealthy EvictionReason = "unhealthy"
+)
+
+// PoolStats is a point-in-time view of a pool's composition and history.
+type PoolStats struct {
+	Path     string `json:"path"`     // Plugin path served by the pool
+	Strategy string `json:"strategy"` // Reset strategy in effect
+
+	Idle    int `json:"idle"`     // Warm instances ready for checkout
+	InUse   int `json:"in_use"`   // Instances currently checked out
+	Waiting int `json:"waiting"`  // Get() calls blocked because the pool is at MaxSize
+	MinSize int `json:"min_size"` // Instances kept warm
+	MaxSize int `json:"max_size"` // Limit on live instances (0 = unlimited)
+
+	Instantiated uint64 `json:"instantiated"` // Instances created via full load + Init()
+	Restored     uint64 `json:"restored"`     // Instances reset via snapshot restore
+
+	Evictions map[EvictionReason]uint64 `json:"evictions"` // Discarded instances by reason
+
+	// ShutdownFailures counts discarded instances whose on_shutdown()
+	// failed or timed out, i.e. whose buffered state may have been lost.
+	ShutdownFailures uint64 `json:"shutdown_failures"`
+
+	// CheckoutWait is the distribution of time spent in Get(), in seconds,
+	// including instance creation when no idle instance was available.
+	CheckoutWait metrics.HistogramSnapshot `json:"checkout_wait_seconds"`
+}
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// Reset selects how instances are cleaned between checkouts.
+	// ResetAuto (the zero value) benchmarks the plugin when the pool is created.
+	Reset ResetStrategy
+
+	// CalibrationRounds is the number of rounds per strategy used by ResetAuto.
+	// Zero means defaultCalibrationRounds.
+	CalibrationRounds int
+
+	// CalibrationTimeout bounds the measurement of ResetAuto; a pool whose
+	// measurement runs out of time uses ResetRecreate. Zero means
+	// defaultCalibrationTimeout.
+	CalibrationTimeout time.Duration
+
+	// CalibrateInBackground makes NewPool return without waiting for the
+	// measurement of ResetAuto: the pool uses ResetRecreate, which suits
+	// every plugin, until the measured strategy is known.
+	CalibrateInBackground bool
+
+	// MinSize is the number of initialized instances kept alive. NewPool
+	// creates them up front and the pool replaces any that are discarded.
+	// Zero uses the size suggested by the manifest's sizing hints, if any
+	// (see SuggestPoolSize).
+	MinSize int
+
+	// MaxSize caps live instances (idle + checked out). Get() blocks while
+	// the pool is full. Zero uses the size suggested by the manifest's
+	// sizing hints, or means unlimited if there is none.
+	MaxSize int
+
+	// MemoryBudgetMiB limits a suggested MaxSize to the instances that fit
+	// in this much memory, for plugins whose manifest declares
+	// sizing.instance_memory_mib. Zero means no budget.
+	MemoryBudgetMiB int
+
+	// IdleTimeout evicts instances that stay idle this long, never shrinking
+	// the pool below MinSize. Zero keeps idle instances forever.
+	IdleTimeout time.Duration
+
+	// HealthInterval is how often idle instances of plugins that export
+	// health() are checked; unhealthy ones are evicted. New instances are
+	// always checked after Init(). Zero disables periodic checks.
+	HealthInterval time.Duration
+
+	// ShutdownTimeout bounds the on_shutdown() call made before an instance
+	// is discarded, for plugins that export it. Zero means
+	// defaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+
+	// InitTimeout bounds the init() or init_with_config() call of each new
+	// instance; a plugin that takes longer fails to load. Zero means
+	// defaultInitTimeout.
+	InitTimeout time.Duration
+
+	// MaxMemoryPages caps each instance's linear memory, in 64 KiB pages,
+	// unless the plugin's manifest declares limits.memory_pages. Zero
+	// leaves memory bounded only by the module itself.
+	MaxMemoryPages int
+
+	// MaxInstructions caps the instructions of each call, as in
+	// LoadOptions; without WasmEdge statistics it is not enforced.
+	MaxInstructions uint64
+
+	// HostModules are registered with every instance, as in LoadOptions.
+	HostModules []*HostModule
+
+	// Engine loads the instances; nil means WasmEdge. Only WasmEdge can
+	// snapshot instances and load AOT-compiled artifacts, so pools on
+	// other engines use ResetRecreate, and refuse ResetRestore and a
+	// Compiler.
+	Engine Engine
+
+	// Compiler, if set, loads instances from AOT-compiled artifacts instead
+	// of interpreting the .wasm file.
+	Compiler *CompilerCache
+
+	// Modules, if set, caches parsed modules across instances and pools, as
+	// in LoadOptions. It does not apply to AOT-compiled instances.
+	Modules *ModuleCache
+
+	// RequireDigest and TrustedKeys verify the plugin binary before each
+	// instance is loaded, as in LoadOptions.
+	RequireDigest bool
+	TrustedKeys   []ed25519.PublicKey
+
+	// RequireABIVersion and AnyABIVersion require or relax the ABI version
+	// check of each instance, as in LoadOptions.
+	RequireABIVersion bool
+	AnyABIVersion     bool
+
+	// WASI grants every instance arguments, environment variables, and
+	// directories, as in LoadOptions.
+	WASI WASIOptions
+
+	// CaptureOutput lets calls capture the stdout and stderr of every
+	// instance, as in LoadOptions.
+	CaptureOutput bool
+
+	// Config, if non-nil, replaces the manifest's config block and is passed
+	// to every instance's init_with_config(). Nil uses the manifest's.
+	Config []byte
+
+	// ConfigOverlays are JSON merge patches applied in order on top of the
+	// config block (Config or the manifest's) when an instance is loaded,
+	// e.g. deployment-wide settings followed by per-environment ones. See
+	// manifest.MergeConfig.
+	ConfigOverlays [][]byte
+}
+
+// validate rejects inconsistent sizing.
+func (o PoolOptions) validate() error {
+	if o.MinSize < 0 || o.MaxSize < 0 || o.MemoryBudgetMiB < 0 || o.IdleTimeout < 0 || o.HealthInterval < 0 || o.ShutdownTimeout < 0 || o.InitTimeout < 0 || o.CalibrationTimeout < 0 {
+		return fmt.Errorf("pool sizes and durations must not be negative")
+	}
+	if o.MaxMemoryPages < 0 || o.MaxMemoryPages > maxWasm32Pages {
+		return fmt.Errorf("max memory pages must be between 0 and %d", maxWasm32Pages)
+	}
+	if o.MaxSize > 0 && o.MinSize > o.MaxSize {
+		return fmt.Errorf("pool min size %d exceeds max size %d", o.MinSize, o.MaxSize)
+	}
+	if o.Engine != nil && o.Engine != WasmEdge {
+		if o.Reset == ResetRestore {
+			return fmt.Errorf("engine %s cannot snapshot instances for %s", o.Engine.Name(), ResetRestore)
+		}
+		if o.Compiler != nil {
+			return fmt.Errorf("engine %s cannot load AOT-compiled plugins", o.Engine.Name())
+		}
+	}
+	return nil
+}
+
+// engine returns the engine instances are loaded with.
+func (o PoolOptions) engine() Engine {
+	if o.Engine == nil {
+		return WasmEdge
+	}
+	return o.Engine
+}
+
+// initializer returns how instances of the plugin at path are
+// initialized: Init(), unless Config or ConfigOverlays are set, in which
+// case the config is resolved against each instance's manifest and passed
+// to InitWithConfig(). Either call is bounded by InitTimeout.
+func (o PoolOptions) initializer(path string) initFunc {
+	timeout := o.InitTimeout
+	if timeout == 0 {
+		timeout = defaultInitTimeout
+	}
+	return func(instance Instance) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		config, ok, err := o.InitConfig(instance.Manifest())
+		if err != nil {
+			return fmt.Errorf("failed to resolve config for %s: %w", path, err)
+		}
+		if !ok {
+			return instance.InitContext(ctx)
+		}
+		return instance.InitWithConfigContext(ctx, config)
+	}
+}
+
+// InitConfig returns the config instances of a plugin with manifest m
+// (nil if it has none) are initialized with by InitWithConfig(), and false
+// if they are initialized with Init() instead.
+func (o PoolOptions) InitConfig(m *manifest.Manifest) ([]byte, bool, error) {
+	if o.Config == nil && len(o.ConfigOverlays) == 0 {
+		return nil, false, nil
+	}
+	config := o.Config
+	if config == nil && m != nil {
+		config = m.Config
+	}
+	if len(o.ConfigOverlays) > 0 {
+		merged, err := manifest.MergeConfig(config, o.ConfigOverlays...)
+		if err != nil {
+			return nil, false, err
+		}
+		config = merged
+	}
+	return config, true, nil
+}
+
+// LoadOptions returns the options instances are loaded with.
+func (o PoolOptions) LoadOptions() LoadOptions {
+	opts := LoadOptions{
+		MaxMemoryPages:    o.MaxMemoryPages,
+		MaxInstructions:   o.MaxInstructions,
+		HostModules:       o.HostModules,
+		RequireDigest:     o.RequireDigest,
+		TrustedKeys:       o.TrustedKeys,
+		RequireABIVersion: o.RequireABIVersion,
+		AnyABIVersion:     o.AnyABIVersion,
+		WASI:              o.WASI,
+		CaptureOutput:     o.CaptureOutput,
+	}
+	if o.Modules != nil {
+		// A nil *ModuleCache must not become a non-nil ModuleSource
+		opts.Modules = o.Modules
+	}
+	return opts
+}
+
+// maintenanceInterval returns how often the pool checks for expired idle
+// instances, refills to MinSize, and checks whether a health check is due.
+func (o PoolOptions) maintenanceInterval() time.Duration {
+	interval := maxMaintenanceInterval
+	if o.IdleTimeout > 0 && o.IdleTimeout/2 < interval {
+		interval = o.IdleTimeout / 2
+	}
+	if o.HealthInterval > 0 && o.HealthInterval < interval {
+		interval = o.HealthInterval
+	}
+	return interval
+}
+
+// idleInstance is a warm instance and the time it became idle.
+type idleInstance struct {
+	plugin Instance
+	since  time.Time
+}
+
+// Pool keeps initialized instances of a single plugin so that requests can
+// reuse them instead of paying for VM creation, validation, and Init() on
+// every call.
+//
+// Instances handed out by Get() are in their post-Init state. When returned
+// with Put(), the pool resets them according to its ResetStrategy:
+//   - ResetRestore: linear memory is restored from the post-Init snapshot
+//     and the instance goes back to the idle list
+//   - ResetRecreate: the instance is discarded and the next Get() loads a
+//     fresh one
+//
+// Sizing is controlled by PoolOptions: MinSize instances are kept warm,
+// MaxSize bounds memory use by making Get() wait for a free instance, and
+// IdleTimeout returns capacity after traffic spikes. Sizes left at zero are
+// seeded from the sizing hints in the plugin's manifest.
+//
+// Instances of plugins that export health() are checked after Init() and,
+// with HealthInterval, periodically while idle. Unhealthy instances are
+// evicted so requests do not reach a plugin that has degraded.
+//
+// Every discarded instance, whatever the reason, first gets its
+// on_shutdown() export called (if any), bounded by ShutdownTimeout.
+//
+// Pool is safe for concurrent use. Each checked-out Plugin belongs to one
+// caller until it is handed back; calls to it are serialized unless the
+// plugin is reentrant.
+//
+// A pool on an engine other than WasmEdge (PoolOptions.Engine) hands out
+// its instances with GetInstance, and takes them back with PutInstance
+// and DiscardInstance.
+type Pool struct {
+	path     string
+	digest   string        // SHA-256 of the plugin file when the pool was created
+	strategy ResetStrategy // Guarded by mu; changes once if calibrated in the background
+	artifact os.FileInfo   // Plugin file as seen when the pool was created
+	engine   Engine        // Engine the instances run on
+	load     loadFunc      // The engine's Load, or the AOT compiler cache's
+	init     initFunc      // Init(), or InitWithConfig() with the resolved config
+
+	minSize         int
+	maxSize         int
+	idleTimeout     time.Duration
+	healthInterval  time.Duration
+	shutdownTimeout time.Duration
+
+	mu        sync.Mutex
+	available *sync.Cond     // Signaled when an instance is idled or a slot frees up
+	idle      []idleInstance // Oldest first; Get() takes the most recent
+	inUse     int            // Checked out, including instances being created for Get()
+	pending   int            // Being created to refill MinSize
+	checking  int            // Taken from idle for a health check
+	waiting   int            // Get() calls blocked on MaxSize
+	closed    bool
+	stop      chan struct{} // Closed by Close() to end maintenance
+	evictions map[EvictionReason]uint64
+
+	// footprints holds the memory of every live instance, measured when
+	// it was created or last returned
+	footprints map[Instance]footprint
+
+	instantiated     metrics.Counter
+	restored         metrics.Counter
+	shutdownFailures metrics.Counter
+	checkoutWait     *metrics.Histogram
+}
+
+// NewPool creates a pool for the plugin at path.
+//
+// With ResetAuto, the plugin is loaded and both reset strategies are measured
+// before the pool is returned, or after it with CalibrateInBackground; the
+// faster one is used for the pool's lifetime. MinSize instances are created
+// before NewPool returns.
+// Returns an error if the options are inconsistent or the plugin cannot be
+// loaded and initialized.
+func NewPool(path string, opts PoolOptions) (*Pool, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// Stat before loading so a replacement written during calibration is
+	// detected as stale rather than missed
+	artifact, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin file not found: %w", err)
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// An invalid manifest is left for the first load to report
+	if m, err := manifest.ForPlugin(path); err == nil {
+		opts = opts.withSizingHints(m)
+	}
+
+	loadOptions := opts.LoadOptions()
+	engine := opts.engine()
+	load := func(path string) (Instance, error) {
+		return engine.Load(path, loadOptions)
+	}
+	if compiler := opts.Compiler; compiler != nil {
+		load = func(path string) (Instance, error) {
+			plugin, err := compiler.load(path, loadOptions)
+			if err != nil {
+				return nil, err
+			}
+			return plugin, nil
+		}
+	}
+
+	rounds := opts.CalibrationRounds
+	if rounds == 0 {
+		rounds = defaultCalibrationRounds
+	}
+	calibrationTimeout := opts.CalibrationTimeout
+	if calibrationTimeout == 0 {
+		calibrationTimeout = defaultCalibrationTimeout
+	}
+	strategy := opts.Reset
+	if strategy == ResetAuto && engine != WasmEdge {
+		// Nothing to measure: the engine cannot restore instances
+		strategy = ResetRecreate
+	} else if strategy == ResetAuto && opts.CalibrateInBackground {
+		strategy = ResetRecreate
+	} else if strategy == ResetAuto {
+		ctx, cancel := context.WithTimeout(context.Background(), calibrationTimeout)
+		measured, err := measureResetStrategy(ctx, path, rounds, load, opts.initializer(path))
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to calibrate pool for %s: %w", path, err)
+		}
+		strategy = measured
+	}
+
+	shutdownTimeout := opts.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	p := &Pool{
+		path:            path,
+		digest:          digest,
+		strategy:        strategy,
+		artifact:        artifact,
+		engine:          engine,
+		load:            load,
+		init:            opts.initializer(path),
+		minSize:         opts.MinSize,
+		maxSize:         opts.MaxSize,
+		idleTimeout:     opts.IdleTimeout,
+		healthInterval:  opts.HealthInterval,
+		shutdownTimeout: shutdownTimeout,
+		stop:            make(chan struct{}),
+		evictions:       make(map[EvictionReason]uint64),
+		footprints:      make(map[Instance]footprint),
+		checkoutWait:    metrics.NewHistogram(metrics.DefaultDurationBuckets),
+	}
+	p.available = sync.NewCond(&p.mu)
+
+	// Pre-warm so the first requests do not pay for instantiation
+	if err := p.fill(); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to pre-warm pool for %s: %w", path, err)
+	}
+	background := opts.Reset == ResetAuto && opts.CalibrateInBackground && engine == WasmEdge
+	if background && len(p.idle) == 0 {
+		// As the measurement would, make sure the plugin loads, and keep
+		// the instance for the first call
+		plugin, err := p.create(context.Background())
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		p.idle = append(p.idle, idleInstance{plugin: plugin, since: time.Now()})
+	}
+
+	if p.minSize > 0 || p.idleTimeout > 0 || p.healthInterval > 0 {
+		go p.maintain(opts.maintenanceInterval())
+	}
+	if background {
+		go p.calibrate(rounds, calibrationTimeout)
+	}
+
+	return p, nil
+}
+
+// calibrate measures the reset strategies as the pool runs the plugin,
+// until timeout or Close(), and switches to the faster one. Instances
+// created before the switch have no snapshot and are recreated.
+func (p *Pool) calibrate(rounds int, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	measured, err := measureResetStrategy(ctx, p.path, rounds, p.load, p.init)
+	if err != nil {
+		return // Calls fail to create instances the same way
+	}
+	p.mu.Lock()
+	p.strategy = measured
+	p.mu.Unlock()
+}
+
+// Path returns the plugin path served by this pool.
+func (p *Pool) Path() string {
+	return p.path
+}
+
+// Digest returns the SHA-256 of the plugin file the pool was created for,
+// in hex.
+func (p *Pool) Digest() string {
+	return p.digest
+}
+
+// Stale reports whether the plugin file was replaced since the pool was
+// created, so callers can swap in a pool for the new build. A file that can
+// no longer be read is not considered stale; resolving it will fail instead.
+func (p *Pool) Stale() bool {
+	current, err := os.Stat(p.path)
+	if err != nil {
+		return false
+	}
+	return !current.ModTime().Equal(p.artifact.ModTime()) || current.Size() != p.artifact.Size()
+}
+
+// Engine returns the engine the pool's instances run on.
+func (p *Pool) Engine() Engine {
+	return p.engine
+}
+
+// Strategy returns the reset strategy in effect. It is never ResetAuto.
+func (p *Pool) Strategy() ResetStrategy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.strategy
+}
+
+// Get checks out an initialized instance, reusing an idle one if available
+// and creating a new one otherwise. When the pool is at MaxSize, Get waits
+// until an instance is returned or discarded.
+//
+// The caller must hand the instance back with Put() on success or Discard()
+// if the instance may be in a broken state.
+func (p *Pool) Get() (*Plugin, error) {
+	return p.GetContext(context.Background())
+}
+
+// GetContext is Get recording the plugin.Load and plugin.Init spans of a
+// new instance under ctx's span. A checkout waiting at MaxSize gives up
+// when ctx is done, returning an error wrapping ctx.Err().
+//
+// Pools on engines other than WasmEdge have no *Plugin to hand out; Get
+// and GetContext fail, and callers use GetInstance instead.
+func (p *Pool) GetContext(ctx context.Context) (*Plugin, error) {
+	if p.engine != WasmEdge {
+		return nil, fmt.Errorf("pool for %s runs on %s, not WasmEdge", p.path, p.engine.Name())
+	}
+	instance, err := p.GetInstance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return instance.(*Plugin), nil
+}
+
+// GetInstance is GetContext for pools on any engine. The instance is
+// handed back with PutInstance or DiscardInstance.
+func (p *Pool) GetInstance(ctx context.Context) (Instance, error) {
+	start := time.Now()
+	defer func() {
+		p.checkoutWait.Observe(time.Since(start).Seconds())
+	}()
+
+	var stop func() bool
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("pool for %s is closed", p.path)
+		}
+		if n := len(p.idle); n > 0 {
+			plugin := p.idle[n-1].plugin
+			p.idle = p.idle[:n-1]
+			p.inUse++
+			p.mu.Unlock()
+			return plugin, nil
+		}
+		if p.maxSize == 0 || p.live() < p.maxSize {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			// Pass on a wakeup this checkout may have taken from another
+			p.available.Signal()
+			p.mu.Unlock()
+			return nil, fmt.Errorf("waiting for an instance of %s: %w", p.path, err)
+		}
+		if stop == nil {
+			stop = context.AfterFunc(ctx, func() {
+				p.mu.Lock()
+				p.available.Broadcast()
+				p.mu.Unlock()
+			})
+		}
+		p.waiting++
+		p.available.Wait()
+		p.waiting--
+	}
+	// Reserve the slot before creating outside the lock
+	p.inUse++
+	p.mu.Unlock()
+
+	plugin, err := p.create(ctx)
+	if err != nil {
+		p.mu.Lock()
+		p.inUse--
+		p.available.Signal()
+		p.mu.Unlock()
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// Put returns a checked-out instance to the pool after a successful call.
+//
+// If the instance cannot be restored it is discarded instead, so the pool
+// never hands out an instance in an unknown state.
+func (p *Pool) Put(plugin *Plugin) {
+	p.PutContext(context.Background(), plugin)
+}
+
+// PutContext is Put recording the plugin.Cleanup span of a discarded
+// instance under ctx's span.
+func (p *Pool) PutContext(ctx context.Context, plugin *Plugin) {
+	p.PutInstance(ctx, plugin)
+}
+
+// PutInstance is PutContext for an instance from GetInstance.
+func (p *Pool) PutInstance(ctx context.Context, instance Instance) {
+	plugin, ok := instance.(*Plugin)
+	if !ok || p.Strategy() != ResetRestore || !plugin.hasSnapshot() {
+		p.release(ctx, instance, EvictRecreate)
+		return
+	}
+
+	if err := plugin.Restore(); err != nil {
+		p.release(ctx, plugin, EvictRestoreFailed)
+		return
+	}
+	p.restored.Inc()
+	usage := instanceFootprint(plugin)
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.release(ctx, plugin, EvictPoolClosed)
+		return
+	}
+	p.inUse--
+	p.footprints[plugin] = usage
+	p.idle = append(p.idle, idleInstance{plugin: plugin, since: time.Now()})
+	p.available.Signal()
+	p.mu.Unlock()
+}
+
+// Discard cleans up and closes a checked-out instance without returning it
+// to the pool.
+func (p *Pool) Discard(plugin *Plugin) {
+	p.DiscardContext(context.Background(), plugin)
+}
+
+// DiscardContext is Discard recording the plugin.Cleanup span under ctx's
+// span.
+func (p *Pool) DiscardContext(ctx context.Context, plugin *Plugin) {
+	p.DiscardInstance(ctx, plugin)
+}
+
+// DiscardInstance is DiscardContext for an instance from GetInstance.
+func (p *Pool) DiscardInstance(ctx context.Context, instance Instance) {
+	p.release(ctx, instance, EvictDiscarded)
+}
+
+// Stats returns the pool's current composition and counters.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	evictions := make(map[EvictionReason]uint64, len(p.evictions))
+	for reason, n := range p.evictions {
+		evictions[reason] = n
+	}
+	stats := PoolStats{
+		Path:      p.path,
+		Strategy:  p.strategy.String(),
+		Idle:      len(p.idle),
+		InUse:     p.inUse,
+		Waiting:   p.waiting,
+		MinSize:   p.minSize,
+		MaxSize:   p.maxSize,
+		Evictions: evictions,
+	}
+	p.mu.Unlock()
+
+	stats.Instantiated = p.instantiated.Value()
+	stats.Restored = p.restored.Value()
+	stats.ShutdownFailures = p.shutdownFailures.Value()
+	stats.CheckoutWait = p.checkoutWait.Snapshot()
+	return stats
+}
+
+// Memory returns the memory held by the pool's live instances. Instances
+// checked out are counted as of their creation or last return; one that
+// grows its memory during a call cannot be restored and is discarded.
+func (p *Pool) Memory() PoolMemory {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	usage := PoolMemory{Instances: len(p.footprints)}
+	for _, f := range p.footprints {
+		usage.LinearMemoryBytes += f.linear
+		usage.SnapshotBytes += f.snapshot
+	}
+	return usage
+}
+
+// Close discards all idle instances and wakes any waiting Get() calls.
+// Instances still checked out are discarded when they are returned with Put().
+// It is safe to call Close() more than once.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	close(p.stop)
+	p.available.Broadcast()
+	p.mu.Unlock()
+
+	for _, instance := range idle {
+		p.destroy(context.Background(), instance.plugin, EvictPoolClosed)
+	}
+}
+
+// Drain closes the pool and waits until every instance still checked out
+// has been handed back and destroyed, so no call to the plugin is left
+// running. It returns ctx's error if ctx is done first; the remaining
+// instances are then destroyed when they are handed back.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		p.available.Broadcast()
+		p.mu.Unlock()
+	})
+	defer stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.live() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.available.Wait()
+	}
+	return nil
+}
+
+// live returns the number of instances that exist or are being created.
+// The caller must hold p.mu.
+func (p *Pool) live() int {
+	return len(p.idle) + p.inUse + p.pending + p.checking
+}
+
+// maintain periodically evicts expired idle instances, checks the health of
+// the remaining ones every HealthInterval, and refills the pool to MinSize
+// until the pool is closed.
+func (p *Pool) maintain(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastHealthCheck := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.evictIdle(now)
+			if p.healthInterval > 0 && now.Sub(lastHealthCheck) >= p.healthInterval {
+				p.checkIdleHealth()
+				lastHealthCheck = now
+			}
+			// A failed refill is retried on the next tick; Get() still
+			// creates instances on demand in the meantime
+			_ = p.fill()
+		}
+	}
+}
+
+// evictIdle destroys instances idle for at least IdleTimeout, oldest first,
+// without shrinking the pool below MinSize.
+func (p *Pool) evictIdle(now time.Time) {
+	if p.idleTimeout <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	var expired []Instance
+	for len(p.idle) > 0 && p.live() > p.minSize && now.Sub(p.idle[0].since) >= p.idleTimeout {
+		expired = append(expired, p.idle[0].plugin)
+		p.idle = p.idle[1:]
+	}
+	p.mu.Unlock()
+
+	for _, plugin := range expired {
+		p.destroy(context.Background(), plugin, EvictIdleTimeout)
+	}
+}
+
+// checkIdleHealth runs the health check on every idle instance and evicts
+// the unhealthy ones. The instances are taken off the idle list while they
+// are checked, so Get() cannot hand them out mid-check.
+func (p *Pool) checkIdleHealth() {
+	p.mu.Lock()
+	checked := p.idle
+	p.idle = nil
+	p.checking += len(checked)
+	p.mu.Unlock()
+
+	healthy := make([]idleInstance, 0, len(checked))
+	for _, instance := range checked {
+		if err := p.checkHealth(instance.plugin); err != nil {
+			p.mu.Lock()
+			p.checking--
+			p.mu.Unlock()
+			p.destroy(context.Background(), instance.plugin, EvictUnhealthy)
+			continue
+		}
+		healthy = append(healthy, instance)
+	}
+
+	p.mu.Lock()
+	p.checking -= len(healthy)
+	if p.closed {
+		p.mu.Unlock()
+		for _, instance := range healthy {
+			p.destroy(context.Background(), instance.plugin, EvictPoolClosed)
+		}
+		return
+	}
+	// Instances idled during the check are newer; keep the list oldest first
+	p.idle = append(healthy, p.idle...)
+	p.available.Broadcast()
+	p.mu.Unlock()
+}
+
+// checkHealth calls the instance's health() export, if any, within
+// healthCheckTimeout.
+func (p *Pool) checkHealth(plugin Instance) error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	return plugin.HealthContext(ctx)
+}
+
+// fill creates idle instances until the pool holds MinSize live instances.
+func (p *Pool) fill() error {
+	for {
+		p.mu.Lock()
+		if p.closed || p.live() >= p.minSize || (p.maxSize > 0 && p.live() >= p.maxSize) {
+			p.mu.Unlock()
+			return nil
+		}
+		p.pending++
+		p.mu.Unlock()
+
+		plugin, err := p.create(context.Background())
+
+		p.mu.Lock()
+		p.pending--
+		if err != nil {
+			p.available.Signal()
+			p.mu.Unlock()
+			return err
+		}
+		if p.closed {
+			p.mu.Unlock()
+			p.destroy(context.Background(), plugin, EvictPoolClosed)
+			return nil
+		}
+		p.idle = append(p.idle, idleInstance{plugin: plugin, since: time.Now()})
+		p.available.Signal()
+		p.mu.Unlock()
+	}
+}
+
+// release discards a checked-out instance and records the reason.
+func (p *Pool) release(ctx context.Context, plugin Instance, reason EvictionReason) {
+	p.mu.Lock()
+	p.inUse--
+	p.mu.Unlock()
+
+	p.destroy(ctx, plugin, reason)
+}
+
+// destroy notifies, cleans up, and closes an instance that is not checked
+// out. ctx parents its plugin.Cleanup span; the shutdown timeout ignores
+// ctx's cancellation.
+func (p *Pool) destroy(ctx context.Context, plugin Instance, reason EvictionReason) {
+	ctx, span := startSpan(ctx, "plugin.Cleanup", p.path,
+		append(abiAttributes(plugin.ABIVersion()), attrEvictionReason.String(string(reason)))...)
+
+	// Let the plugin flush buffered state while its host functions are
+	// still registered
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.shutdownTimeout)
+	if err := plugin.Shutdown(shutdownCtx); err != nil {
+		p.shutdownFailures.Inc()
+	}
+	cancel()
+
+	// Best effort cleanup - the instance is going away regardless
+	err := plugin.Cleanup()
+	plugin.Close()
+	endSpan(span, err)
+
+	p.mu.Lock()
+	delete(p.footprints, plugin)
+	p.evictions[reason]++
+	// The instance's slot is free for a waiting Get(); once closed, only
+	// Drain() calls wait, and all of them must recheck
+	if p.closed {
+		p.available.Broadcast()
+	} else {
+		p.available.Signal()
+	}
+	p.mu.Unlock()
+}
+
+// create loads and initializes a new instance, checks its health, and takes
+// a snapshot when the pool restores instances between requests.
+func (p *Pool) create(ctx context.Context) (Instance, error) {
+	load, init := traced(ctx, p.path, p.load, p.init)
+	plugin, err := newInitializedInstance(p.path, load, init)
+	if err != nil {
+		return nil, err
+	}
+	p.instantiated.Inc()
+
+	if err := p.checkHealth(plugin); err != nil {
+		p.destroy(ctx, plugin, EvictUnhealthy)
+		return nil, fmt.Errorf("plugin %s is unhealthy after init: %w", p.path, err)
+	}
+
+	if restorable, ok := plugin.(*Plugin); ok && p.Strategy() == ResetRestore {
+		if err := restorable.Snapshot(); err != nil {
+			p.destroy(ctx, plugin, EvictSnapshotFailed)
+			return nil, fmt.Errorf("failed to snapshot %s: %w", p.path, err)
+		}
+	}
+
+	usage := instanceFootprint(plugin)
+	p.mu.Lock()
+	p.footprints[plugin] = usage
+	p.mu.Unlock()
+	return plugin, nil
+}
@@ -0,0 +1,503 @@
+package runtime_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Pool", func() {
+	var validPluginPath string
+
+	BeforeEach(func() {
+		validPluginPath = filepath.Join("..", "plugins", "hello", "hello.wasm")
+
+		if _, err := os.Stat(validPluginPath); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + validPluginPath)
+		}
+	})
+
+	// =========================================================================
+	// TEST: Snapshot/Restore round trip
+	// Why: Restore must bring an instance back to its post-Init state, or
+	//      pooled instances would leak state between requests.
+	// =========================================================================
+	Describe("Snapshot and Restore", func() {
+		var plugin *runtime.Plugin
+
+		BeforeEach(func() {
+			var err error
+			plugin, err = runtime.LoadPlugin(validPluginPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			plugin.Close()
+		})
+
+		It("should restore the post-Init state", func() {
+			Expect(plugin.Snapshot()).To(Succeed())
+
+			// cleanup() clears the plugin's initialized flag in linear memory
+			Expect(plugin.Cleanup()).To(Succeed())
+			_, err := plugin.Execute(21)
+			Expect(err).To(HaveOccurred())
+
+			Expect(plugin.Restore()).To(Succeed())
+			result, err := plugin.Execute(21)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(43))
+		})
+
+		It("should fail to restore without a snapshot", func() {
+			err := plugin.Restore()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no snapshot"))
+		})
+	})
+
+	// =========================================================================
+	// TEST: Pool checkout and reuse
+	// Why: Both reset strategies must hand out initialized instances that
+	//      produce correct results across repeated checkouts.
+	// =========================================================================
+	DescribeTable("reuse across checkouts",
+		func(strategy runtime.ResetStrategy) {
+			pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: strategy})
+			Expect(err).NotTo(HaveOccurred())
+			defer pool.Close()
+
+			Expect(pool.Strategy()).NotTo(Equal(runtime.ResetAuto))
+
+			for _, input := range []int{21, 10, 50} {
+				plugin, err := pool.Get()
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := plugin.Execute(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(input*2 + 1))
+
+				pool.Put(plugin)
+			}
+		},
+		Entry("auto", runtime.ResetAuto),
+		Entry("restore", runtime.ResetRestore),
+		Entry("recreate", runtime.ResetRecreate),
+	)
+
+	// =========================================================================
+	// TEST: Calibration
+	// Why: Measuring ResetAuto loads and initializes the plugin dozens of
+	//      times; a request creating a pool must not wait for that, and a
+	//      slow measurement must not hold up pool creation at all.
+	// =========================================================================
+	It("should serve calls while it calibrates in the background", func() {
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{CalibrateInBackground: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		Expect(pool.Stats().Idle).To(Equal(1))
+		for _, input := range []int{21, 10} {
+			plugin, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Execute(input)).To(Equal(input*2 + 1))
+			pool.Put(plugin)
+		}
+		Expect(pool.Strategy()).NotTo(Equal(runtime.ResetAuto))
+	})
+
+	It("should refuse a plugin that does not load even when calibrating in the background", func() {
+		_, err := runtime.NewPool(filepath.Join(GinkgoT().TempDir(), "missing.wasm"), runtime.PoolOptions{CalibrateInBackground: true})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should recreate instances when calibration runs out of time", func() {
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{CalibrationTimeout: time.Nanosecond})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		Expect(pool.Strategy()).To(Equal(runtime.ResetRecreate))
+	})
+
+	// =========================================================================
+	// TEST: Pool stats
+	// Why: Operators tune pools from these numbers; they must reflect what
+	//      actually happened to instances.
+	// =========================================================================
+	It("should report restores, instantiations, and evictions", func() {
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pool.Stats().InUse).To(Equal(1))
+		pool.Put(plugin)
+
+		plugin, err = pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		pool.Discard(plugin)
+
+		stats := pool.Stats()
+		Expect(stats.Strategy).To(Equal("restore"))
+		Expect(stats.Instantiated).To(Equal(uint64(1)))
+		Expect(stats.Restored).To(Equal(uint64(1)))
+		Expect(stats.Idle).To(Equal(0))
+		Expect(stats.InUse).To(Equal(0))
+		Expect(stats.Evictions).To(HaveKeyWithValue(runtime.EvictDiscarded, uint64(1)))
+		Expect(stats.CheckoutWait.Count).To(Equal(uint64(2)))
+	})
+
+	It("should refuse checkouts after Close", func() {
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRecreate})
+		Expect(err).NotTo(HaveOccurred())
+
+		pool.Close()
+		_, err = pool.Get()
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("closed"))
+	})
+})
+
+// =========================================================================
+// TEST: Pool sizing
+// Why: MinSize keeps first requests off the cold path, MaxSize bounds memory
+//      under load, and IdleTimeout returns capacity after a spike; each must
+//      hold without starving callers.
+// =========================================================================
+var _ = Describe("Pool sizing", func() {
+	var validPluginPath string
+
+	BeforeEach(func() {
+		validPluginPath = filepath.Join("..", "plugins", "hello", "hello.wasm")
+	})
+
+	requirePlugin := func() {
+		if _, err := os.Stat(validPluginPath); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + validPluginPath)
+		}
+	}
+
+	DescribeTable("should reject inconsistent options",
+		func(opts runtime.PoolOptions, message string) {
+			_, err := runtime.NewPool(validPluginPath, opts)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("min above max", runtime.PoolOptions{MinSize: 3, MaxSize: 2}, "exceeds max size"),
+		Entry("negative min", runtime.PoolOptions{MinSize: -1}, "must not be negative"),
+		Entry("negative idle timeout", runtime.PoolOptions{IdleTimeout: -time.Second}, "must not be negative"),
+		Entry("negative health interval", runtime.PoolOptions{HealthInterval: -time.Second}, "must not be negative"),
+		Entry("negative shutdown timeout", runtime.PoolOptions{ShutdownTimeout: -time.Second}, "must not be negative"),
+		Entry("negative init timeout", runtime.PoolOptions{InitTimeout: -time.Second}, "must not be negative"),
+		Entry("negative calibration timeout", runtime.PoolOptions{CalibrationTimeout: -time.Second}, "must not be negative"),
+		Entry("memory limit beyond wasm32", runtime.PoolOptions{MaxMemoryPages: 65537}, "max memory pages"),
+	)
+
+	It("should fail when pre-warming fails", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "broken.wasm")
+		Expect(os.WriteFile(path, []byte("not wasm"), 0644)).To(Succeed())
+
+		_, err := runtime.NewPool(path, runtime.PoolOptions{Reset: runtime.ResetRecreate, MinSize: 1})
+		Expect(err).To(MatchError(ContainSubstring("failed to pre-warm")))
+	})
+
+	It("should pre-warm MinSize instances", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MinSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		stats := pool.Stats()
+		Expect(stats.Idle).To(Equal(2))
+		Expect(stats.Instantiated).To(Equal(uint64(2)))
+		Expect(stats.MinSize).To(Equal(2))
+	})
+
+	It("should refill to MinSize after discards", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MinSize: 1, IdleTimeout: 50 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		pool.Discard(plugin)
+
+		Eventually(func() int { return pool.Stats().Idle }).Should(Equal(1))
+	})
+
+	It("should block checkouts at MaxSize until an instance is returned", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MaxSize: 1})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		first, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+
+		got := make(chan *runtime.Plugin)
+		go func() {
+			defer GinkgoRecover()
+			plugin, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			got <- plugin
+		}()
+
+		Eventually(func() int { return pool.Stats().Waiting }).Should(Equal(1))
+		Consistently(got, 50*time.Millisecond).ShouldNot(Receive())
+
+		pool.Put(first)
+		var second *runtime.Plugin
+		Eventually(got).Should(Receive(&second))
+		Expect(second).To(BeIdenticalTo(first))
+		pool.Put(second)
+
+		Expect(pool.Stats().Instantiated).To(Equal(uint64(1)))
+	})
+
+	It("should wake waiting checkouts on Close", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MaxSize: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+
+		errs := make(chan error)
+		go func() {
+			_, err := pool.Get()
+			errs <- err
+		}()
+		Eventually(func() int { return pool.Stats().Waiting }).Should(Equal(1))
+
+		pool.Close()
+		Eventually(errs).Should(Receive(MatchError(ContainSubstring("closed"))))
+		pool.Put(plugin)
+	})
+
+	It("should stop waiting at MaxSize when the context is done", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MaxSize: 1})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = pool.GetContext(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(pool.Stats().Waiting).To(BeZero())
+
+		pool.Put(plugin)
+		plugin, err = pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		pool.Put(plugin)
+	})
+
+	It("should drain until checked-out instances are returned", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MinSize: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+
+		drained := make(chan error)
+		go func() {
+			drained <- pool.Drain(context.Background())
+		}()
+		Consistently(drained, 50*time.Millisecond).ShouldNot(Receive())
+		_, err = pool.Get()
+		Expect(err).To(MatchError(ContainSubstring("closed")))
+
+		pool.Put(plugin)
+		Eventually(drained).Should(Receive(BeNil()))
+		Expect(pool.Stats().InUse).To(BeZero())
+		Expect(pool.Stats().Evictions[runtime.EvictPoolClosed]).To(Equal(uint64(1)))
+	})
+
+	It("should stop draining when the context is done", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore})
+		Expect(err).NotTo(HaveOccurred())
+
+		plugin, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(pool.Drain(ctx)).To(MatchError(context.DeadlineExceeded))
+
+		pool.Put(plugin)
+		Expect(pool.Stats().InUse).To(BeZero())
+	})
+
+	It("should evict idle instances above MinSize", func() {
+		requirePlugin()
+
+		pool, err := runtime.NewPool(validPluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MinSize: 1, IdleTimeout: 50 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		// Check out two instances so the pool grows past MinSize
+		first, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		second, err := pool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		pool.Put(first)
+		pool.Put(second)
+
+		Eventually(func() uint64 {
+			return pool.Stats().Evictions[runtime.EvictIdleTimeout]
+		}).Should(Equal(uint64(1)))
+		Expect(pool.Stats().Idle).To(Equal(1))
+	})
+})
+
+// =========================================================================
+// TEST: Stale artifact detection
+// Why: Servers swap in a new pool when a plugin is rebuilt (pluginctl dev);
+//      a missed replacement keeps serving the old build indefinitely.
+// =========================================================================
+var _ = Describe("Pool.Stale", func() {
+	It("should report when the plugin file is replaced", func() {
+		// ResetRecreate skips calibration, so the file need not be valid WASM
+		path := filepath.Join(GinkgoT().TempDir(), "demo.wasm")
+		Expect(os.WriteFile(path, []byte("v1"), 0644)).To(Succeed())
+
+		pool, err := runtime.NewPool(path, runtime.PoolOptions{Reset: runtime.ResetRecreate})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+		Expect(pool.Stale()).To(BeFalse())
+
+		Expect(os.WriteFile(path, []byte("v2 build"), 0644)).To(Succeed())
+		Expect(pool.Stale()).To(BeTrue())
+	})
+
+	It("should not report a removed file as stale", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "demo.wasm")
+		Expect(os.WriteFile(path, []byte("v1"), 0644)).To(Succeed())
+
+		pool, err := runtime.NewPool(path, runtime.PoolOptions{Reset: runtime.ResetRecreate})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		Expect(os.Remove(path)).To(Succeed())
+		Expect(pool.Stale()).To(BeFalse())
+	})
+
+	It("should fail to create a pool for a missing file", func() {
+		_, err := runtime.NewPool(filepath.Join(GinkgoT().TempDir(), "missing.wasm"), runtime.PoolOptions{Reset: runtime.ResetRecreate})
+		Expect(err).To(MatchError(ContainSubstring("plugin file not found")))
+	})
+})
+
+// =========================================================================
+// TEST: Pools on another engine
+// Why: ENGINE can run every plugin on an engine other than WasmEdge. Its
+//      pool must hand out that engine's instances and recreate them, as
+//      only WasmEdge can snapshot instances, rather than fail on them.
+// =========================================================================
+var _ = Describe("Pool on another engine", func() {
+	var path string
+
+	BeforeEach(func() {
+		// The engine does not read the file
+		path = filepath.Join(GinkgoT().TempDir(), "demo.wasm")
+		Expect(os.WriteFile(path, []byte("v1"), 0644)).To(Succeed())
+	})
+
+	It("should hand out the engine's instances and recreate them", func() {
+		engine := &fakeEngine{}
+		pool, err := runtime.NewPool(path, runtime.PoolOptions{Engine: engine})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+		Expect(pool.Engine()).To(Equal(runtime.Engine(engine)))
+		Expect(pool.Strategy()).To(Equal(runtime.ResetRecreate))
+
+		instance, err := pool.GetInstance(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.(*fakeInstance).initialized).To(BeTrue())
+		pool.PutInstance(context.Background(), instance)
+		Expect(instance.(*fakeInstance).closed).To(BeTrue())
+
+		stats := pool.Stats()
+		Expect(stats.Instantiated).To(Equal(uint64(1)))
+		Expect(stats.Evictions[runtime.EvictRecreate]).To(Equal(uint64(1)))
+	})
+
+	It("should not hand out a *Plugin", func() {
+		pool, err := runtime.NewPool(path, runtime.PoolOptions{Engine: &fakeEngine{}})
+		Expect(err).NotTo(HaveOccurred())
+		defer pool.Close()
+
+		_, err = pool.Get()
+		Expect(err).To(MatchError(ContainSubstring("runs on fake")))
+	})
+
+	It("should refuse to restore instances", func() {
+		_, err := runtime.NewPool(path, runtime.PoolOptions{Engine: &fakeEngine{}, Reset: runtime.ResetRestore})
+		Expect(err).To(MatchError(ContainSubstring("cannot snapshot")))
+	})
+})
+
+// fakeEngine loads fakeInstances.
+type fakeEngine struct{}
+
+func (*fakeEngine) Name() string { return "fake" }
+
+func (*fakeEngine) Load(path string, opts runtime.LoadOptions) (runtime.Instance, error) {
+	return &fakeInstance{}, nil
+}
+
+// fakeInstance records what the pool does with it. Methods the pool does
+// not call panic through the nil Instance.
+type fakeInstance struct {
+	runtime.Instance
+	initialized, closed bool
+}
+
+func (f *fakeInstance) InitContext(ctx context.Context) error {
+	f.initialized = true
+	return nil
+}
+
+func (f *fakeInstance) HealthContext(ctx context.Context) error { return nil }
+func (f *fakeInstance) Shutdown(ctx context.Context) error      { return nil }
+func (f *fakeInstance) Manifest() *manifest.Manifest           { return nil }
+func (f *fakeInstance) ABIVersion() runtime.ABIVersion          { return 0 }
+func (f *fakeInstance) Cleanup() error                          { return nil }
+func (f *fakeInstance) Close()                                  { f.closed = true }
+
+var _ = Describe("ResetStrategy", func() {
+	DescribeTable("String",
+		func(strategy runtime.ResetStrategy, expected string) {
+			Expect(strategy.String()).To(Equal(expected))
+		},
+		Entry("auto", runtime.ResetAuto, "auto"),
+		Entry("restore", runtime.ResetRestore, "restore"),
+		Entry("recreate", runtime.ResetRecreate, "recreate"),
+		Entry("unknown", runtime.ResetStrategy(42), "unknown(42)"),
+	)
+})
@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+)
+
+// defaultProfileRounds is the number of rounds ProfileStartup uses when
+// given zero.
+const defaultProfileRounds = 20
+
+// StartupProfile describes what a plugin costs before it serves a request.
+//
+// Durations are medians over the measured rounds, which keeps a single
+// scheduling hiccup from dominating the result.
+type StartupProfile struct {
+	Size      int64         `json:"size"`       // Binary size in bytes
+	Validate  time.Duration `json:"validate"`   // Parse + validate the module
+	ColdStart time.Duration `json:"cold_start"` // LoadPlugin() + Init(), as paid by an empty pool
+	Rounds    int           `json:"rounds"`
+}
+
+// ProfileStartup measures the size, validation time, and cold-start latency
+// of the plugin at path.
+//
+// Each round:
+//   - Validate: loads the binary into an AST and runs the validator
+//   - ColdStart: creates a VM, instantiates the plugin, and calls Init()
+func ProfileStartup(path string, rounds int) (StartupProfile, error) {
+	if rounds <= 0 {
+		rounds = defaultProfileRounds
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return StartupProfile{}, fmt.Errorf("plugin file not found: %w", err)
+	}
+
+	validate := make([]time.Duration, 0, rounds)
+	coldStart := make([]time.Duration, 0, rounds)
+	for i := 0; i < rounds; i++ {
+		elapsed, err := timeValidation(path)
+		if err != nil {
+			return StartupProfile{}, err
+		}
+		validate = append(validate, elapsed)
+
+		start := time.Now()
+		plugin, err := newInitializedInstance(path, loadWasmEdge(LoadOptions{}), Instance.Init)
+		if err != nil {
+			return StartupProfile{}, err
+		}
+		coldStart = append(coldStart, time.Since(start))
+		plugin.Close()
+	}
+
+	return StartupProfile{
+		Size:      info.Size(),
+		Validate:  median(validate),
+		ColdStart: median(coldStart),
+		Rounds:    rounds,
+	}, nil
+}
+
+// timeValidation parses and validates the module once.
+func timeValidation(path string) (time.Duration, error) {
+	loader := wasmedge.NewLoader()
+	if loader == nil {
+		return 0, fmt.Errorf("failed to create WasmEdge loader")
+	}
+	defer loader.Release()
+
+	validator := wasmedge.NewValidator()
+	if validator == nil {
+		return 0, fmt.Errorf("failed to create WasmEdge validator")
+	}
+	defer validator.Release()
+
+	start := time.Now()
+	ast, err := loader.LoadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	}
+	defer ast.Release()
+
+	if err := validator.Validate(ast); err != nil {
+		return 0, fmt.Errorf("WASM module validation failed for %s: %w", path, err)
+	}
+	return time.Since(start), nil
+}
+
+// median returns the middle value of samples, reordering them.
+func median(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[len(samples)/2]
+}
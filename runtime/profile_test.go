@@ -0,0 +1,33 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("ProfileStartup", func() {
+	It("should fail for a missing plugin", func() {
+		_, err := runtime.ProfileStartup(filepath.Join(GinkgoT().TempDir(), "missing.wasm"), 1)
+		Expect(err).To(MatchError(ContainSubstring("plugin file not found")))
+	})
+
+	It("should measure size, validation, and cold start", func() {
+		path := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+
+		profile, err := runtime.ProfileStartup(path, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Size).To(Equal(info.Size()))
+		Expect(profile.Rounds).To(Equal(3))
+		Expect(profile.Validate).To(BeNumerically(">", 0))
+		Expect(profile.ColdStart).To(BeNumerically(">", 0))
+	})
+})
@@ -0,0 +1,41 @@
+package runtime
+
+import "context"
+
+// ProgressFunc receives the progress a plugin reports during a call, in
+// percent from 0 to 100.
+type ProgressFunc func(percent int)
+
+// progressKey is the context key of the call's ProgressFunc.
+type progressKey struct{}
+
+// WithProgress returns a context that makes plugin calls made with it pass
+// the progress they report through report_progress() to fn. fn runs on the
+// goroutine executing the plugin, which waits for it to return.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress passes percent, clamped to 0..100, to the ProgressFunc
+// carried by ctx. Without one, progress is ignored.
+func ReportProgress(ctx context.Context, percent int) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(min(max(percent, 0), 100))
+	}
+}
+
+// DefineProgress adds report_progress to module and returns module:
+//
+//	__attribute__((import_module("host"), import_name("report_progress")))
+//	extern "C" void report_progress(int percent);
+//
+// A long-running plugin calls it as it works through its input; the
+// progress goes to the ProgressFunc of the call's context (WithProgress).
+// Reporting never fails, so plugins need not check whether anyone listens.
+func DefineProgress(module *HostModule) *HostModule {
+	return module.Func("report_progress", []ValueType{ValueI32}, nil,
+		func(call *HostCall, args []interface{}) ([]interface{}, error) {
+			ReportProgress(call.Context(), int(args[0].(int32)))
+			return nil, nil
+		})
+}
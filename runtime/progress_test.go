@@ -0,0 +1,74 @@
+package runtime_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Progress", func() {
+	// =========================================================================
+	// TEST: Progress reporting
+	// Why: Progress is shown to clients as a percentage; whatever a plugin
+	//      passes must stay in range, and calls nobody follows must not
+	//      fail because of it.
+	// =========================================================================
+	It("should pass clamped progress to the context's ProgressFunc", func() {
+		var reported []int
+		ctx := runtime.WithProgress(context.Background(), func(percent int) {
+			reported = append(reported, percent)
+		})
+		runtime.ReportProgress(ctx, -5)
+		runtime.ReportProgress(ctx, 40)
+		runtime.ReportProgress(ctx, 250)
+		Expect(reported).To(Equal([]int{0, 40, 100}))
+
+		// Without a ProgressFunc, progress is dropped
+		runtime.ReportProgress(context.Background(), 50)
+	})
+
+	Describe("with the progress plugin", func() {
+		var plugin *runtime.Plugin
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "progress", "progress.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			var err error
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{runtime.DefineProgress(runtime.NewContextModule())},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		It("should report progress while the call runs", func() {
+			var reported []int
+			ctx := runtime.WithProgress(context.Background(), func(percent int) {
+				reported = append(reported, percent)
+			})
+			result, err := plugin.ExecuteContext(ctx, 4)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(4))
+			Expect(reported).To(Equal([]int{0, 25, 50, 75, 100}))
+
+			// Calls without a ProgressFunc run all the same
+			result, err = plugin.Execute(2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(2))
+		})
+	})
+})
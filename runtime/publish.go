@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// PublishModule is the import module of the message publishing host API.
+// Like context(), publish() lives in the "host" module:
+//
+//	__attribute__((import_module("host"), import_name("publish")))
+//	extern "C" int publish(const char *topic, int topic_len,
+//	                       const char *data, int data_len);
+//
+// publish() sends data as one message to topic and returns PublishOK once
+// the broker accepted it. Unlike an EffectMessage enqueued in the outbox,
+// the message is sent during the call: it is not withdrawn if the call
+// later fails, so publish() suits plugins whose output is the events
+// themselves.
+//
+// Add the function to a module with Topics.Define.
+const PublishModule = "host"
+
+// Result codes publish() returns to the plugin.
+const (
+	PublishOK          int32 = 0
+	PublishUnavailable int32 = -1 // The host has no broker
+	PublishDenied      int32 = -2 // Invalid topic, or not granted to the plugin
+	PublishTooLarge    int32 = -3 // The message is over maxPublishPayload
+	PublishFailed      int32 = -4 // The broker did not accept the message
+)
+
+// ErrPublishDenied is returned for a topic the plugin was not granted.
+var ErrPublishDenied = errors.New("topic not allowed")
+
+// ErrPublishTooLarge is returned for a message over the size limit.
+var ErrPublishTooLarge = errors.New("message too large")
+
+// Limits on a single message, as for outbox effects.
+const (
+	maxPublishTopic   = 255
+	maxPublishPayload = 1 << 20
+)
+
+// Publisher sends messages to a broker, e.g. Kafka or NATS.
+// Implementations must be safe for concurrent use.
+type Publisher interface {
+	// Publish sends payload to topic, returning once the broker accepted
+	// it or ctx is done.
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// Topics serves publish() from a Publisher, allowing each plugin only the
+// topics it was granted. Grants map a plugin name to topic names; a grant
+// ending in "*", e.g. "orders.*", allows every topic starting with what
+// precedes it. A plugin without grants can publish nothing. It is safe for
+// concurrent use.
+type Topics struct {
+	publisher Publisher
+	grants    map[string][]string
+}
+
+// NewTopics creates the publishing API over publisher with grants.
+func NewTopics(publisher Publisher, grants map[string][]string) *Topics {
+	t := &Topics{publisher: publisher, grants: make(map[string][]string, len(grants))}
+	for plugin, topics := range grants {
+		t.grants[plugin] = append([]string(nil), topics...)
+	}
+	return t
+}
+
+// Allowed reports whether plugin may publish to topic.
+func (t *Topics) Allowed(plugin, topic string) bool {
+	for _, grant := range t.grants[plugin] {
+		if prefix, ok := strings.CutSuffix(grant, "*"); ok {
+			if strings.HasPrefix(topic, prefix) {
+				return true
+			}
+		} else if grant == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish sends payload to topic for plugin, or only records it in a
+// call made with WithoutSideEffects.
+func (t *Topics) Publish(ctx context.Context, plugin, topic string, payload []byte) error {
+	if !validTopic(topic) || !t.Allowed(plugin, topic) {
+		return fmt.Errorf("%w: plugin %s, topic %q", ErrPublishDenied, plugin, topic)
+	}
+	if len(payload) > maxPublishPayload {
+		return fmt.Errorf("%w: %d bytes for topic %s", ErrPublishTooLarge, len(payload), topic)
+	}
+	if skipEffect(ctx, "publish", topic) {
+		return nil
+	}
+	if err := t.publisher.Publish(ctx, topic, payload); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Define adds publish to module, serving it from t, and returns module. A
+// nil t defines a function that returns PublishUnavailable, so plugins
+// importing it load on hosts without a broker. Denied topics and broker
+// errors are reported to the plugin as result codes rather than traps.
+func (t *Topics) Define(module *HostModule) *HostModule {
+	return module.Func("publish", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+		func(call *HostCall, args []interface{}) ([]interface{}, error) {
+			result := func(code int32) ([]interface{}, error) {
+				return []interface{}{code}, nil
+			}
+
+			if t == nil {
+				return result(PublishUnavailable)
+			}
+			if args[1].(int32) > maxPublishTopic {
+				return result(PublishDenied)
+			}
+			// Checked before copying the message out of the plugin's memory
+			if args[3].(int32) > maxPublishPayload {
+				return result(PublishTooLarge)
+			}
+			topic, err := call.ReadString(args[0].(int32), args[1].(int32))
+			if err != nil {
+				return nil, err
+			}
+			payload, err := call.Read(args[2].(int32), args[3].(int32))
+			if err != nil {
+				return nil, err
+			}
+
+			plugin := strings.TrimSuffix(filepath.Base(call.Path()), ".wasm")
+			err = t.Publish(call.Context(), plugin, topic, payload)
+			switch {
+			case errors.Is(err, ErrPublishDenied):
+				return result(PublishDenied)
+			case errors.Is(err, ErrPublishTooLarge):
+				return result(PublishTooLarge)
+			case err != nil:
+				return result(PublishFailed)
+			}
+			return result(PublishOK)
+		})
+}
+
+// validTopic reports whether topic is a non-empty name within
+// maxPublishTopic bytes without spaces or control characters, which
+// brokers' wire protocols use as separators.
+func validTopic(topic string) bool {
+	if topic == "" || len(topic) > maxPublishTopic {
+		return false
+	}
+	for i := 0; i < len(topic); i++ {
+		if topic[i] <= ' ' || topic[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// recordingPublisher records the messages it is given, failing those to
+// topics starting with "down.".
+type recordingPublisher struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, topic string, payload []byte) error {
+	if strings.HasPrefix(topic, "down.") {
+		return errors.New("broker unavailable")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages[topic] = append(p.messages[topic], string(payload))
+	return nil
+}
+
+var _ = Describe("Topics", func() {
+	var (
+		publisher *recordingPublisher
+		topics    *runtime.Topics
+		ctx       context.Context
+	)
+
+	BeforeEach(func() {
+		publisher = &recordingPublisher{messages: map[string][]string{}}
+		topics = runtime.NewTopics(publisher, map[string][]string{
+			"enrich": {"orders.enriched"},
+			"audit":  {"audit.*", "down.*"},
+		})
+		ctx = context.Background()
+	})
+
+	// =========================================================================
+	// TEST: Topic allowlists
+	// Why: Topics are shared infrastructure; a plugin must only emit events
+	//      on the topics its operator granted it.
+	// =========================================================================
+	It("should only publish to granted topics", func() {
+		Expect(topics.Publish(ctx, "enrich", "orders.enriched", []byte(`{"id":1}`))).To(Succeed())
+		Expect(topics.Publish(ctx, "audit", "audit.logins", []byte("alice"))).To(Succeed())
+		Expect(publisher.messages).To(Equal(map[string][]string{
+			"orders.enriched": {`{"id":1}`},
+			"audit.logins":    {"alice"},
+		}))
+
+		for _, denied := range []struct{ plugin, topic string }{
+			{"enrich", "orders.enriched.v2"},
+			{"enrich", "audit.logins"},
+			{"unknown", "orders.enriched"},
+			{"audit", "audit"},
+			{"audit", "audit.x y"},
+			{"audit", "audit.x\r\nPUB other 0"},
+		} {
+			err := topics.Publish(ctx, denied.plugin, denied.topic, nil)
+			Expect(errors.Is(err, runtime.ErrPublishDenied)).To(BeTrue(), denied.topic)
+		}
+	})
+
+	It("should report oversized messages and broker failures", func() {
+		err := topics.Publish(ctx, "enrich", "orders.enriched", make([]byte, 2<<20))
+		Expect(errors.Is(err, runtime.ErrPublishTooLarge)).To(BeTrue())
+
+		err = topics.Publish(ctx, "audit", "down.events", []byte("x"))
+		Expect(err).To(MatchError(ContainSubstring("broker unavailable")))
+	})
+
+	It("should define publish, even without a broker", func() {
+		Expect(topics.Define(runtime.NewHostModule(runtime.PublishModule)).Functions()).To(Equal([]string{"publish"}))
+		var unavailable *runtime.Topics
+		Expect(unavailable.Define(runtime.NewHostModule(runtime.PublishModule)).Functions()).To(Equal([]string{"publish"}))
+	})
+})
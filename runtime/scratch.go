@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// ScratchGuestPath is where plugins granted directories find their
+// scratch directory.
+const ScratchGuestPath = "/tmp"
+
+// scratchPollInterval is how often the scratch directory of a running call
+// with a file quota is measured.
+const scratchPollInterval = 20 * time.Millisecond
+
+// newScratchDir creates the scratch directory of an instance of a plugin
+// with the given pre-opened directories, under opts.ScratchDir. It returns
+// "" for plugins granted no directory, and for those whose manifest asks
+// for ScratchGuestPath itself, which get the granted directory instead.
+func newScratchDir(path string, m *manifest.Manifest, preopens []string, opts WASIOptions) (string, error) {
+	if len(preopens) == 0 || slices.Contains(m.WASI.Dirs, ScratchGuestPath) {
+		return "", nil
+	}
+	dir, err := os.MkdirTemp(opts.ScratchDir, "wasm-scratch-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create scratch directory for %s: %w", path, err)
+	}
+	return dir, nil
+}
+
+// clearScratch removes everything a call left in a scratch directory. The
+// directory itself stays, as the instance has it pre-opened.
+func clearScratch(dir string) {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(dir, entry.Name()))
+	}
+}
+
+// hasFileQuota reports whether opts bound what calls leave in their
+// scratch directory.
+func hasFileQuota(opts WASIOptions) bool {
+	return opts.ScratchMaxBytes > 0 || opts.ScratchMaxFiles > 0
+}
+
+// checkScratch returns an error wrapping ErrFileQuota if dir holds more
+// than opts allow. Entries removed while it walks are skipped.
+func checkScratch(dir string, opts WASIOptions) error {
+	var bytes int64
+	var files int
+	var over error
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+		files++
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				bytes += info.Size()
+			}
+		}
+		switch {
+		case opts.ScratchMaxFiles > 0 && files > opts.ScratchMaxFiles:
+			over = fmt.Errorf("%w: more than %d files in %s", ErrFileQuota, opts.ScratchMaxFiles, ScratchGuestPath)
+		case opts.ScratchMaxBytes > 0 && bytes > opts.ScratchMaxBytes:
+			over = fmt.Errorf("%w: more than %d bytes in %s", ErrFileQuota, opts.ScratchMaxBytes, ScratchGuestPath)
+		default:
+			return nil
+		}
+		return fs.SkipAll
+	})
+	return over
+}
+
+// watchScratch measures dir every scratchPollInterval until ctx is done,
+// and cancels the call with the quota error once dir is over its quota.
+func watchScratch(ctx context.Context, cancel context.CancelCauseFunc, dir string, opts WASIOptions) {
+	ticker := time.NewTicker(scratchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := checkScratch(dir, opts); err != nil {
+				cancel(err)
+				return
+			}
+		}
+	}
+}
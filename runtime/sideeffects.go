@@ -0,0 +1,61 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+)
+
+// SkippedEffect is a side effect a host function skipped for a call made
+// with WithoutSideEffects.
+type SkippedEffect struct {
+	Function string `json:"function"` // Host function, e.g. "publish"
+	Target   string `json:"target"`   // Topic, key, statement, or metric it would have changed
+}
+
+// SkippedEffects records the side effects host functions skipped. It is
+// safe for concurrent use.
+type SkippedEffects struct {
+	mu      sync.Mutex
+	effects []SkippedEffect
+}
+
+// NewSkippedEffects creates an empty record of skipped effects.
+func NewSkippedEffects() *SkippedEffects {
+	return &SkippedEffects{}
+}
+
+// Effects returns the skipped effects, in the order they were skipped.
+func (s *SkippedEffects) Effects() []SkippedEffect {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SkippedEffect(nil), s.effects...)
+}
+
+// skippedEffectsKey is the context key of the call's SkippedEffects.
+type skippedEffectsKey struct{}
+
+// WithoutSideEffects returns a context that makes plugin calls made with
+// it (ExecuteContext and friends) run without the side effects of the
+// host APIs, recording each one in skipped instead: publish() sends
+// nothing, blob_put() and cache_set() store nothing, exec() does not run
+// its statement and reports no affected rows, and metric_incr() and
+// metric_observe() record nothing. Arguments and grants are checked as
+// usual, so a call that would have been refused still is. Reads, and the
+// functions of host modules the host does not implement itself, are not
+// affected.
+func WithoutSideEffects(ctx context.Context, skipped *SkippedEffects) context.Context {
+	return context.WithValue(ctx, skippedEffectsKey{}, skipped)
+}
+
+// skipEffect reports whether calls made with ctx run without side
+// effects, and if so records that function skipped the one on target.
+func skipEffect(ctx context.Context, function, target string) bool {
+	skipped, _ := ctx.Value(skippedEffectsKey{}).(*SkippedEffects)
+	if skipped == nil {
+		return false
+	}
+	skipped.mu.Lock()
+	defer skipped.mu.Unlock()
+	skipped.effects = append(skipped.effects, SkippedEffect{Function: function, Target: target})
+	return true
+}
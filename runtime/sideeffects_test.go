@@ -0,0 +1,63 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("WithoutSideEffects", func() {
+	// =========================================================================
+	// TEST: Skipped side effects
+	// Why: A call repeated for comparison, e.g. on a shadow engine, must not
+	//      publish, store, or write a second time, yet see the results the
+	//      first call got.
+	// =========================================================================
+	It("should record effects instead of making them", func() {
+		skipped := runtime.NewSkippedEffects()
+		ctx := runtime.WithoutSideEffects(context.Background(), skipped)
+
+		publisher := &recordingPublisher{messages: map[string][]string{}}
+		topics := runtime.NewTopics(publisher, map[string][]string{"enrich": {"orders.enriched"}})
+		Expect(topics.Publish(ctx, "enrich", "orders.enriched", []byte(`{"id":1}`))).To(Succeed())
+		Expect(publisher.messages).To(BeEmpty())
+
+		blobStore := &memoryBlobs{objects: map[string][]byte{}}
+		blobs := runtime.NewBlobs(blobStore, runtime.BlobOptions{})
+		m := &manifest.Manifest{Name: "upper", Blobs: manifest.Blobs{Write: []string{"outputs/"}}}
+		Expect(blobs.Put(ctx, m, "outputs/a.csv", []byte("A,B"))).To(Succeed())
+		Expect(blobStore.objects).To(BeEmpty())
+
+		cache := runtime.NewCache(runtime.NewMemoryCache(100), runtime.CacheOptions{MaxValueBytes: 50, MaxTTL: time.Minute})
+		Expect(cache.Set(ctx, "enrich", "rate:EUR", []byte("1.08"), time.Minute)).To(Succeed())
+		_, err := cache.Get(context.Background(), "enrich", "rate:EUR")
+		Expect(errors.Is(err, runtime.ErrCacheMiss)).To(BeTrue())
+
+		Expect(skipped.Effects()).To(Equal([]runtime.SkippedEffect{
+			{Function: "publish", Target: "orders.enriched"},
+			{Function: "blob_put", Target: "outputs/a.csv"},
+			{Function: "cache_set", Target: "rate:EUR"},
+		}))
+	})
+
+	It("should still refuse what the call may not do", func() {
+		skipped := runtime.NewSkippedEffects()
+		ctx := runtime.WithoutSideEffects(context.Background(), skipped)
+
+		topics := runtime.NewTopics(&recordingPublisher{messages: map[string][]string{}}, nil)
+		err := topics.Publish(ctx, "enrich", "orders.enriched", nil)
+		Expect(errors.Is(err, runtime.ErrPublishDenied)).To(BeTrue())
+
+		blobs := runtime.NewBlobs(&memoryBlobs{objects: map[string][]byte{}}, runtime.BlobOptions{})
+		err = blobs.Put(ctx, nil, "outputs/a.csv", nil)
+		Expect(errors.Is(err, runtime.ErrBlobDenied)).To(BeTrue())
+
+		Expect(skipped.Effects()).To(BeEmpty())
+	})
+})
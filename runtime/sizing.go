@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"math"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+)
+
+// sizingHeadroom is the ratio of the MaxSize suggested by sizing hints to
+// the expected concurrency, leaving room for bursts.
+const sizingHeadroom = 2
+
+// maxSuggestedPoolSize bounds sizes derived from expected load, so an
+// overstated expected_qps cannot make NewPool pre-warm thousands of
+// instances.
+const maxSuggestedPoolSize = 256
+
+// SuggestPoolSize returns the MinSize and MaxSize a plugin's sizing hints
+// suggest. Zero means no suggestion.
+//
+// The expected concurrency follows from Little's law: requests per second
+// times seconds per request. That many instances are kept warm and
+// sizingHeadroom times as many allowed. With a memory budget and a
+// declared instance memory, MaxSize is limited to the instances that fit
+// in the budget, and MinSize to MaxSize.
+func SuggestPoolSize(hints manifest.Sizing, memoryBudgetMiB int) (minSize, maxSize int) {
+	concurrency := hints.ExpectedQPS * float64(hints.LatencyTargetMs) / 1000
+	if concurrency > 0 {
+		minSize = int(math.Min(math.Ceil(concurrency), maxSuggestedPoolSize))
+		maxSize = int(math.Min(math.Ceil(concurrency*sizingHeadroom), maxSuggestedPoolSize))
+	}
+
+	if memoryBudgetMiB > 0 && hints.InstanceMemoryMiB > 0 {
+		fit := memoryBudgetMiB / hints.InstanceMemoryMiB
+		if fit < 1 {
+			fit = 1
+		}
+		if maxSize == 0 || fit < maxSize {
+			maxSize = fit
+		}
+		if minSize > maxSize {
+			minSize = maxSize
+		}
+	}
+	return minSize, maxSize
+}
+
+// withSizingHints fills MinSize and MaxSize from the manifest's sizing
+// hints where the options leave them zero. A suggested size yields to an
+// explicit one it would contradict.
+func (o PoolOptions) withSizingHints(m *manifest.Manifest) PoolOptions {
+	if m == nil {
+		return o
+	}
+	minSize, maxSize := SuggestPoolSize(m.Sizing, o.MemoryBudgetMiB)
+
+	if o.MinSize == 0 {
+		o.MinSize = minSize
+		if o.MaxSize > 0 && o.MinSize > o.MaxSize {
+			o.MinSize = o.MaxSize
+		}
+	}
+	if o.MaxSize == 0 && maxSize > 0 {
+		o.MaxSize = maxSize
+		if o.MaxSize < o.MinSize {
+			o.MaxSize = o.MinSize
+		}
+	}
+	return o
+}
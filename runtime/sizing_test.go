@@ -0,0 +1,85 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("SuggestPoolSize", func() {
+	// =========================================================================
+	// TEST: Pool sizing hints
+	// Why: Operators rely on manifests to size pools for many plugins at
+	//      once; the suggestion must follow the declared load, respect the
+	//      memory budget, and never override sizes configured explicitly.
+	// =========================================================================
+	DescribeTable("should derive sizes from the expected load",
+		func(hints manifest.Sizing, budgetMiB, wantMin, wantMax int) {
+			minSize, maxSize := runtime.SuggestPoolSize(hints, budgetMiB)
+			Expect(minSize).To(Equal(wantMin), "min size")
+			Expect(maxSize).To(Equal(wantMax), "max size")
+		},
+		Entry("no hints", manifest.Sizing{}, 0, 0, 0),
+		Entry("load without latency", manifest.Sizing{ExpectedQPS: 100}, 0, 0, 0),
+		Entry("Little's law with headroom",
+			manifest.Sizing{ExpectedQPS: 200, LatencyTargetMs: 20}, 0, 4, 8),
+		Entry("fractional concurrency rounds up",
+			manifest.Sizing{ExpectedQPS: 5, LatencyTargetMs: 10}, 0, 1, 1),
+		Entry("overstated load is bounded",
+			manifest.Sizing{ExpectedQPS: 1e6, LatencyTargetMs: 1000}, 0, 256, 256),
+		Entry("instance memory without a budget",
+			manifest.Sizing{ExpectedQPS: 200, LatencyTargetMs: 20, InstanceMemoryMiB: 64}, 0, 4, 8),
+		Entry("budget limits max size",
+			manifest.Sizing{ExpectedQPS: 200, LatencyTargetMs: 20, InstanceMemoryMiB: 64}, 384, 4, 6),
+		Entry("budget limits min size",
+			manifest.Sizing{ExpectedQPS: 200, LatencyTargetMs: 20, InstanceMemoryMiB: 64}, 192, 3, 3),
+		Entry("budget alone sets max size",
+			manifest.Sizing{InstanceMemoryMiB: 16}, 256, 0, 16),
+		Entry("budget always fits one instance",
+			manifest.Sizing{InstanceMemoryMiB: 512}, 256, 0, 1),
+	)
+
+	Describe("NewPool", func() {
+		var pluginPath string
+
+		BeforeEach(func() {
+			wasm, err := os.ReadFile(filepath.Join("..", "plugins", "hello", "hello.wasm"))
+			if os.IsNotExist(err) {
+				Skip("Test plugin not found: hello.wasm")
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			pluginPath = filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+			Expect(os.WriteFile(pluginPath, wasm, 0644)).To(Succeed())
+
+			data := []byte(`{"name": "hello", "version": "1.0.0", "sizing": {"expected_qps": 100, "latency_target_ms": 20}}`)
+			Expect(os.WriteFile(filepath.Join(filepath.Dir(pluginPath), manifest.FileName), data, 0644)).To(Succeed())
+		})
+
+		It("should seed sizes left at zero from the manifest", func() {
+			pool, err := runtime.NewPool(pluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore})
+			Expect(err).NotTo(HaveOccurred())
+			defer pool.Close()
+
+			stats := pool.Stats()
+			Expect(stats.MinSize).To(Equal(2))
+			Expect(stats.MaxSize).To(Equal(4))
+			Expect(stats.Idle).To(Equal(2))
+		})
+
+		It("should keep explicit sizes", func() {
+			pool, err := runtime.NewPool(pluginPath, runtime.PoolOptions{Reset: runtime.ResetRestore, MaxSize: 1})
+			Expect(err).NotTo(HaveOccurred())
+			defer pool.Close()
+
+			stats := pool.Stats()
+			Expect(stats.MinSize).To(Equal(1))
+			Expect(stats.MaxSize).To(Equal(1))
+		})
+	})
+})
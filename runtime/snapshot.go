@@ -0,0 +1,298 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/second-state/WasmEdge-go/wasmedge"
+)
+
+// wasmPageSize is the size of one WebAssembly linear memory page in bytes.
+const wasmPageSize = 65536
+
+// ResetStrategy selects how a pooled instance is returned to a clean state
+// between requests.
+type ResetStrategy int
+
+const (
+	// ResetAuto measures both strategies when a pool is created and keeps
+	// whichever is faster for that plugin.
+	ResetAuto ResetStrategy = iota
+
+	// ResetRestore copies the post-Init memory snapshot back into the
+	// instance's linear memory instead of re-instantiating it. Globals the
+	// plugin does not export and tables are not reset, so it only suits
+	// plugins that keep no state there; ResetAuto only picks it for
+	// plugins whose mutable globals are all exported.
+	ResetRestore
+
+	// ResetRecreate discards the instance and loads a fresh one.
+	ResetRecreate
+)
+
+// String returns the strategy name used in logs and debug output.
+func (s ResetStrategy) String() string {
+	switch s {
+	case ResetAuto:
+		return "auto"
+	case ResetRestore:
+		return "restore"
+	case ResetRecreate:
+		return "recreate"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// memorySnapshot holds a copy of a plugin's mutable state taken right after
+// Init() succeeded.
+type memorySnapshot struct {
+	memory  []byte                 // Copy of the exported linear memory
+	globals map[string]interface{} // Values of exported mutable globals
+}
+
+// Snapshot records the plugin's linear memory and exported mutable globals
+// so that Restore() can later return the instance to this exact state.
+//
+// Call Snapshot() once, directly after a successful Init(). Taking a new
+// snapshot replaces the previous one.
+//
+// Returns an error if the plugin is closed or does not export its memory.
+func (p *Plugin) Snapshot() error {
+	defer p.lock()()
+
+	if p.vm == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+
+	module := p.vm.GetActiveModule()
+	if module == nil {
+		return fmt.Errorf("no active module for %s", p.path)
+	}
+
+	mem := module.FindMemory("memory")
+	if mem == nil {
+		return fmt.Errorf("plugin %s does not export its linear memory", p.path)
+	}
+
+	size := mem.GetPageSize() * wasmPageSize
+	data, err := mem.GetData(0, size)
+	if err != nil {
+		return fmt.Errorf("failed to read linear memory for %s: %w", p.path, err)
+	}
+
+	// GetData aliases the VM's memory, so the snapshot must own a copy
+	snap := &memorySnapshot{
+		memory:  append([]byte(nil), data...),
+		globals: make(map[string]interface{}),
+	}
+
+	for _, name := range module.ListGlobal() {
+		global := module.FindGlobal(name)
+		if global == nil || global.GetGlobalType().GetMutability() != wasmedge.ValMut_Var {
+			continue
+		}
+		snap.globals[name] = global.GetValue()
+	}
+
+	p.snapshot = snap
+	return nil
+}
+
+// Restore resets the plugin's linear memory and exported mutable globals to
+// the state captured by Snapshot(), making the instance behave as if it had
+// just been loaded and initialized. Mutable globals the plugin does not
+// export and its tables cannot be reached through WasmEdge and keep their
+// values.
+//
+// Restore fails when no snapshot was taken or when the plugin grew its memory
+// since the snapshot: linear memory cannot shrink, so such an instance must be
+// recreated instead.
+func (p *Plugin) Restore() error {
+	defer p.lock()()
+
+	if p.vm == nil {
+		return fmt.Errorf("plugin is closed")
+	}
+	if p.snapshot == nil {
+		return fmt.Errorf("no snapshot taken for %s", p.path)
+	}
+
+	module := p.vm.GetActiveModule()
+	if module == nil {
+		return fmt.Errorf("no active module for %s", p.path)
+	}
+
+	mem := module.FindMemory("memory")
+	if mem == nil {
+		return fmt.Errorf("plugin %s does not export its linear memory", p.path)
+	}
+
+	size := uint(len(p.snapshot.memory))
+	if mem.GetPageSize()*wasmPageSize != size {
+		return fmt.Errorf("linear memory of %s grew since snapshot (%d -> %d pages)",
+			p.path, size/wasmPageSize, mem.GetPageSize())
+	}
+
+	return p.snapshot.apply(p.path, module, mem)
+}
+
+// hasSnapshot reports whether Snapshot() was taken, which instances
+// created before their pool switched to ResetRestore lack.
+func (p *Plugin) hasSnapshot() bool {
+	defer p.lock()()
+
+	return p.snapshot != nil
+}
+
+// apply copies the snapshot into a module whose linear memory is exactly
+// as large as the snapshot's.
+func (s *memorySnapshot) apply(path string, module *wasmedge.Module, mem *wasmedge.Memory) error {
+	// Copy straight into the VM's memory to avoid an intermediate buffer
+	data, err := mem.GetData(0, uint(len(s.memory)))
+	if err != nil {
+		return fmt.Errorf("failed to access linear memory for %s: %w", path, err)
+	}
+	copy(data, s.memory)
+
+	for name, value := range s.globals {
+		global := module.FindGlobal(name)
+		if global == nil {
+			return fmt.Errorf("global %q disappeared from %s", name, path)
+		}
+		if err := global.SetValue(value); err != nil {
+			return fmt.Errorf("failed to restore global %q for %s: %w", name, path, err)
+		}
+	}
+
+	return nil
+}
+
+// seed brings a freshly loaded instance of the snapshotted module to the
+// snapshot's state, growing its memory to the snapshot's size first.
+func (s *memorySnapshot) seed(p *Plugin) error {
+	module := p.vm.GetActiveModule()
+	if module == nil {
+		return fmt.Errorf("no active module for %s", p.path)
+	}
+
+	mem := module.FindMemory("memory")
+	if mem == nil {
+		return fmt.Errorf("plugin %s does not export its linear memory", p.path)
+	}
+
+	pages := uint(len(s.memory)) / wasmPageSize
+	if current := mem.GetPageSize(); current < pages {
+		if err := mem.GrowPage(pages - current); err != nil {
+			return fmt.Errorf("failed to grow linear memory of %s to %d pages: %w", p.path, pages, err)
+		}
+	}
+	if mem.GetPageSize() != pages {
+		return fmt.Errorf("linear memory of %s does not match the snapshot (%d pages, snapshot %d)",
+			p.path, mem.GetPageSize(), pages)
+	}
+
+	if err := s.apply(p.path, module, mem); err != nil {
+		return err
+	}
+	p.snapshot = s
+	return nil
+}
+
+// MeasureResetStrategy benchmarks both reset strategies for the plugin at
+// path and returns the faster one.
+//
+// Each strategy is timed over the given number of rounds:
+//   - ResetRestore: Restore() on a single snapshotted instance
+//   - ResetRecreate: LoadPlugin() + Init() followed by Close()
+//
+// If the plugin cannot be snapshotted (e.g., it does not export its memory)
+// or has mutable globals that it does not export, which Restore() would
+// not reset, ResetRecreate is returned without an error.
+func MeasureResetStrategy(path string, rounds int) (ResetStrategy, error) {
+	return measureResetStrategy(context.Background(), path, rounds, loadWasmEdge(LoadOptions{}), Instance.Init)
+}
+
+// measureResetStrategy is MeasureResetStrategy with the loader and
+// initialization used by the pool, so that plugins are timed as they will
+// run. If ctx is done before the measurement is, it returns ResetRecreate,
+// which suits every plugin, as it does for engines other than WasmEdge,
+// which cannot snapshot instances.
+func measureResetStrategy(ctx context.Context, path string, rounds int, load loadFunc, init initFunc) (ResetStrategy, error) {
+	if rounds <= 0 {
+		rounds = 1
+	}
+
+	instance, err := newInitializedInstance(path, load, init)
+	if err != nil {
+		return ResetRecreate, err
+	}
+	defer instance.Close()
+	plugin, ok := instance.(*Plugin)
+	if !ok {
+		return ResetRecreate, nil
+	}
+
+	if module, err := plugin.module(); err != nil || module.UnexportedMutableGlobals > 0 {
+		return ResetRecreate, nil
+	}
+	if err := plugin.Snapshot(); err != nil {
+		return ResetRecreate, nil
+	}
+
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		if err := plugin.Restore(); err != nil || ctx.Err() != nil {
+			return ResetRecreate, nil
+		}
+	}
+	restoreCost := time.Since(start)
+
+	start = time.Now()
+	for i := 0; i < rounds; i++ {
+		if ctx.Err() != nil {
+			return ResetRecreate, nil
+		}
+		fresh, err := newInitializedInstance(path, load, init)
+		if err != nil {
+			return ResetRecreate, err
+		}
+		fresh.Close()
+	}
+	recreateCost := time.Since(start)
+
+	if restoreCost < recreateCost {
+		return ResetRestore, nil
+	}
+	return ResetRecreate, nil
+}
+
+// loadFunc loads the plugin at path: LoadPlugin, CompilerCache.Load, or
+// the Load of the pool's engine, with the pool's LoadOptions applied.
+type loadFunc func(path string) (Instance, error)
+
+// initFunc initializes a loaded plugin: Init, or a call to InitWithConfig
+// with the pool's resolved config.
+type initFunc func(Instance) error
+
+// loadWasmEdge returns the loadFunc of LoadPluginWithOptions with opts.
+func loadWasmEdge(opts LoadOptions) loadFunc {
+	return func(path string) (Instance, error) {
+		return WasmEdge.Load(path, opts)
+	}
+}
+
+// newInitializedInstance loads a plugin and initializes it, closing it
+// again if initialization fails.
+func newInitializedInstance(path string, load loadFunc, init initFunc) (Instance, error) {
+	instance, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := init(instance); err != nil {
+		instance.Close()
+		return nil, err
+	}
+	return instance, nil
+}
@@ -0,0 +1,264 @@
+//go:build soak
+
+package runtime_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// The soak specs run thousands of load, execute, and close cycles and fail
+// if RSS, goroutines, or WasmEdge handles keep growing, catching leaks in
+// the Release paths and pools that a single run never shows. They take
+// minutes, so they are built only with the soak tag:
+//
+//	make soak
+//	SOAK_CYCLES=20000 go test -tags soak ./runtime -ginkgo.label-filter=soak -timeout 2h
+//
+// SOAK_CYCLES sets the cycles per spec (default 5000) and
+// SOAK_MAX_RSS_GROWTH_MIB the RSS growth tolerated after warm-up (default
+// 32).
+
+// soakSample is the resource usage at one point of a soak run.
+type soakSample struct {
+	rss        int64 // Bytes, -1 where /proc is unavailable
+	goroutines int
+	handles    runtime.Handles
+}
+
+func (s soakSample) String() string {
+	return fmt.Sprintf("rss=%.1fMiB goroutines=%d vms=%d host_modules=%d",
+		float64(s.rss)/(1<<20), s.goroutines, s.handles.VMs, s.handles.HostModules)
+}
+
+// sampleSoak collects garbage and returns the resource usage.
+func sampleSoak() soakSample {
+	goruntime.GC()
+	debug.FreeOSMemory()
+	return soakSample{rss: residentBytes(), goroutines: goruntime.NumGoroutine(), handles: runtime.LiveHandles()}
+}
+
+// residentBytes returns the process's resident set size, or -1.
+func residentBytes() int64 {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return -1
+	}
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return -1
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return pages * int64(os.Getpagesize())
+}
+
+// soakEnv returns the integer environment variable name, or def.
+func soakEnv(name string, def int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return def
+}
+
+var _ = Describe("Soak", Label("soak"), func() {
+	var (
+		cycles       int
+		maxRSSGrowth int64
+	)
+
+	BeforeEach(func() {
+		cycles = soakEnv("SOAK_CYCLES", 5000)
+		maxRSSGrowth = int64(soakEnv("SOAK_MAX_RSS_GROWTH_MIB", 32)) << 20
+	})
+
+	pluginPath := func(name string) string {
+		path := filepath.Join("..", "plugins", name, name+".wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		return path
+	}
+
+	// soak runs cycle n times, then teardown, if any. Every handle created
+	// on the way must be released by then. The first tenth of the cycles
+	// warms up caches and pools; RSS and goroutines are compared between the
+	// end of the warm-up and the end of the run, so only growth that
+	// continues under a steady load counts.
+	soak := func(n int, cycle func(i int), teardown func()) {
+		handles := runtime.LiveHandles()
+		warmup := max(n/10, 1)
+		for i := 0; i < warmup; i++ {
+			cycle(i)
+		}
+		before := sampleSoak()
+		started := time.Now()
+		for i := warmup; i < n; i++ {
+			cycle(i)
+			if (i+1)%max(n/10, 1) == 0 {
+				fmt.Fprintf(GinkgoWriter, "cycle %d/%d: %s\n", i+1, n, sampleSoak())
+			}
+		}
+		fmt.Fprintf(GinkgoWriter, "%d cycles in %s\n", n-warmup, time.Since(started).Round(time.Millisecond))
+		if teardown != nil {
+			teardown()
+		}
+
+		// Instances closed by pools and interrupted calls wind down in
+		// the background
+		Eventually(func() runtime.Handles { return sampleSoak().handles }).
+			WithTimeout(10 * time.Second).Should(Equal(handles))
+		Eventually(func() int { return sampleSoak().goroutines }).
+			WithTimeout(10 * time.Second).Should(BeNumerically("<=", before.goroutines+2))
+
+		after := sampleSoak()
+		fmt.Fprintf(GinkgoWriter, "before: %s\nafter:  %s\n", before, after)
+		if before.rss >= 0 && after.rss >= 0 {
+			Expect(after.rss-before.rss).To(BeNumerically("<=", maxRSSGrowth),
+				"RSS grew from %s to %s", before, after)
+		}
+	}
+
+	// =========================================================================
+	// TEST: Plugin lifecycle
+	// Why: Every load creates a VM, its configuration, and an instance of
+	//      each host module on the C side; Close must release all of them,
+	//      or a server reloading plugins runs out of memory over days.
+	// =========================================================================
+	It("should not leak across load, execute, and close cycles", func() {
+		path := pluginPath("hello")
+		opts := runtime.LoadOptions{
+			HostModules: []*runtime.HostModule{
+				runtime.NewContextModule(),
+				runtime.NewLogModule(func(runtime.LogEntry) {}),
+			},
+		}
+
+		soak(cycles, func(i int) {
+			plugin, err := runtime.LoadPluginWithOptions(path, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+
+			// Calls with a deadline take the interruptible path
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			_, err = plugin.ExecuteContext(ctx, i)
+			cancel()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(plugin.Cleanup()).To(Succeed())
+			plugin.Close()
+		}, nil)
+	})
+
+	It("should not leak across failed loads", func() {
+		path := pluginPath("hello")
+		module := runtime.NewContextModule()
+		invalid := filepath.Join(GinkgoT().TempDir(), "invalid.wasm")
+		Expect(os.WriteFile(invalid, []byte("\x00asm\x01\x00\x00\x00\xff"), 0644)).To(Succeed())
+
+		soak(cycles, func(i int) {
+			// Fails registering the second instance of the module, after
+			// the VM and the first instance were created
+			_, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module, module},
+			})
+			Expect(err).To(HaveOccurred())
+
+			// Fails parsing the module
+			_, err = runtime.LoadPluginWithOptions(invalid, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module},
+			})
+			Expect(err).To(HaveOccurred())
+		}, nil)
+	})
+
+	// =========================================================================
+	// TEST: Pools
+	// Why: Pools replace discarded instances, evict idle ones, and close
+	//      the rest in the background; an instance lost on any of these
+	//      paths is never released.
+	// =========================================================================
+	It("should not leak across pool checkouts, discards, and closes", func() {
+		path := pluginPath("hello")
+		opts := runtime.PoolOptions{
+			Reset:   runtime.ResetRecreate, // Every checkout loads and closes an instance
+			MinSize: 1,
+			MaxSize: 4,
+		}
+
+		var pool *runtime.Pool
+		closePool := func() {
+			if pool != nil {
+				pool.Close()
+				pool = nil
+			}
+		}
+		DeferCleanup(closePool)
+
+		soak(cycles, func(i int) {
+			// Rotate pools, so closing them is soaked too
+			if i%100 == 99 {
+				closePool()
+			}
+			if pool == nil {
+				var err error
+				pool, err = runtime.NewPool(path, opts)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			plugin, err := pool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = plugin.Execute(i)
+			Expect(err).NotTo(HaveOccurred())
+			if i%10 == 0 {
+				pool.Discard(plugin)
+			} else {
+				pool.Put(plugin)
+			}
+		}, closePool)
+	})
+
+	// =========================================================================
+	// TEST: Interrupted and trapping calls
+	// Why: A timeout abandons the call and a trap unwinds it; the resources
+	//      of the call must be released either way.
+	// =========================================================================
+	It("should not leak across interrupted and trapping calls", func() {
+		spin := pluginPath("spin")
+		trap := pluginPath("trap")
+
+		// Interrupting a call waits for its timeout, so these cycles are
+		// the slowest; a tenth of them still shows a leak
+		soak(max(cycles/10, 100), func(i int) {
+			plugin, err := runtime.LoadPlugin(spin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			_, err = plugin.ExecuteContext(ctx, 1)
+			cancel()
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			plugin.Close()
+
+			plugin, err = runtime.LoadPlugin(trap)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+			_, err = plugin.Execute(-1)
+			Expect(err).To(HaveOccurred())
+			plugin.Close()
+		}, nil)
+	})
+})
@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+// tracer records the runtime's OpenTelemetry spans: plugin.Load,
+// plugin.Init, plugin.Execute, and plugin.Cleanup.
+var tracer = tracing.Tracer("github.com/mrhapile/wasm-plugin-system/runtime")
+
+// attrEvictionReason is the EvictionReason of a plugin.Cleanup span.
+const attrEvictionReason = attribute.Key("plugin.eviction_reason")
+
+// startSpan starts a span about the plugin at path. The plugin's ABI
+// version is added once it is loaded.
+func startSpan(ctx context.Context, name, path string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, tracing.AttrPlugin.String(strings.TrimSuffix(filepath.Base(path), ".wasm")))
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// spanAttributes returns the attributes p adds to its spans.
+func (p *Plugin) spanAttributes() []attribute.KeyValue {
+	return abiAttributes(p.abiVersion)
+}
+
+// abiAttributes returns the span attributes of a plugin implementing ABI
+// version v, none if it is unversioned.
+func abiAttributes(v ABIVersion) []attribute.KeyValue {
+	if v == 0 {
+		return nil
+	}
+	return []attribute.KeyValue{tracing.AttrABIVersion.Int(int(v))}
+}
+
+// endSpan ends span, recording err unless it is ErrNoOutput, which is a
+// successful call, and the ABI error code of a *PluginError.
+func endSpan(span trace.Span, err error) {
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) {
+		span.SetAttributes(tracing.AttrABIErrorCode.Int(int(pluginErr.Code)))
+	}
+	if errors.Is(err, ErrNoOutput) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// startExecuteSpan starts the plugin.Execute span of a call to export.
+func (p *Plugin) startExecuteSpan(ctx context.Context, export string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, tracing.AttrExport.String(export))
+	return startSpan(ctx, "plugin.Execute", p.path, append(attrs, p.spanAttributes()...)...)
+}
+
+// traced wraps load and init of the plugin at path in plugin.Load and
+// plugin.Init spans under ctx.
+func traced(ctx context.Context, path string, load loadFunc, init initFunc) (loadFunc, initFunc) {
+	tracedLoad := func(path string) (instance Instance, err error) {
+		_, span := startSpan(ctx, "plugin.Load", path)
+		defer func() { endSpan(span, err) }()
+		return load(path)
+	}
+	tracedInit := func(instance Instance) (err error) {
+		_, span := startSpan(ctx, "plugin.Init", path, abiAttributes(instance.ABIVersion())...)
+		defer func() { endSpan(span, err) }()
+		return init(instance)
+	}
+	return tracedLoad, tracedInit
+}
@@ -0,0 +1,331 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SQLModule is the import module of the database host API:
+//
+//	__attribute__((import_module("sql"), import_name("query")))
+//	extern "C" int sql_query(const char *stmt, int stmt_len,
+//	                         const char *args, int args_len,
+//	                         char *buf, int cap);
+//
+//	__attribute__((import_module("sql"), import_name("exec")))
+//	extern "C" long long sql_exec(const char *stmt, int stmt_len,
+//	                              const char *args, int args_len);
+//
+// Plugins never send SQL: stmt names a statement the host prepared, and
+// args is a JSON array of its parameters, e.g. [42, "eu"], or empty for
+// none. query() writes the rows to buf as a JSON object,
+//
+//	{"columns":["id","name"],"rows":[[42,"Ada"]]}
+//
+// and returns its length; if the object is longer than cap, nothing is
+// written and the plugin can retry with a buffer of the returned length,
+// which runs the query again. exec() returns the number of rows affected.
+//
+// Register it with NewSQLModule.
+const SQLModule = "sql"
+
+// Result codes query() and exec() return to the plugin instead of a length
+// or row count.
+const (
+	SQLDenied   int32 = -1 // Unknown statement, or not granted to the plugin
+	SQLInvalid  int32 = -2 // Invalid arguments, or query() of an exec statement and vice versa
+	SQLFailed   int32 = -3 // The database returned an error or timed out
+	SQLTooLarge int32 = -4 // The result has more than maxSQLRows rows or maxSQLResult bytes
+)
+
+// ErrSQLDenied is returned for a statement that does not exist or that
+// the plugin was not granted.
+var ErrSQLDenied = errors.New("sql statement not allowed")
+
+// ErrSQLInvalid is returned for invalid statement arguments, or for a
+// statement run with the wrong function.
+var ErrSQLInvalid = errors.New("invalid sql call")
+
+// ErrSQLTooLarge is returned for a query result over the limits.
+var ErrSQLTooLarge = errors.New("sql result too large")
+
+// Limits on a single statement run, so a plugin cannot pull a table into
+// the host's memory.
+const (
+	maxSQLStatementName = 128
+	maxSQLArgs          = 64 << 10 // Bytes of JSON arguments
+	maxSQLRows          = 1000
+	maxSQLResult        = 1 << 20 // Bytes of JSON result
+)
+
+// SQLStatement is a statement plugins may run by name.
+type SQLStatement struct {
+	Database string        // Name of the database it runs on, for schema grants
+	Stmt     *sql.Stmt     // Prepared by the host
+	Exec     bool          // Run with exec() rather than query()
+	Timeout  time.Duration // Limit on each run; 0 leaves the call's deadline
+}
+
+// SQLRows is the result of a query, as query() returns it.
+type SQLRows struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// SQLCatalog holds the statements plugins may run and which plugin may run
+// which. Grants map a plugin name to the statement names it may run; a
+// grant of "<database>.*" allows every statement on that database. A
+// plugin without grants can run nothing. It is safe for concurrent use.
+type SQLCatalog struct {
+	statements map[string]SQLStatement
+	grants     map[string]map[string]bool // Statement names and "<database>.*" by plugin
+}
+
+// NewSQLCatalog creates a catalog of statements, by name, and grants.
+func NewSQLCatalog(statements map[string]SQLStatement, grants map[string][]string) *SQLCatalog {
+	c := &SQLCatalog{
+		statements: make(map[string]SQLStatement, len(statements)),
+		grants:     make(map[string]map[string]bool, len(grants)),
+	}
+	for name, stmt := range statements {
+		c.statements[name] = stmt
+	}
+	for plugin, names := range grants {
+		c.grants[plugin] = make(map[string]bool, len(names))
+		for _, name := range names {
+			c.grants[plugin][name] = true
+		}
+	}
+	return c
+}
+
+// Allowed reports whether plugin may run the named statement.
+func (c *SQLCatalog) Allowed(plugin, name string) bool {
+	stmt, ok := c.statements[name]
+	if !ok {
+		return false
+	}
+	grants := c.grants[plugin]
+	return grants[name] || grants[stmt.Database+".*"]
+}
+
+// Query runs the named query statement for plugin with args and returns
+// its rows.
+func (c *SQLCatalog) Query(ctx context.Context, plugin, name string, args []interface{}) (*SQLRows, error) {
+	stmt, err := c.statement(plugin, name, false)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := stmt.context(ctx)
+	defer cancel()
+
+	rows, err := stmt.Stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql statement %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("sql statement %s: %w", name, err)
+	}
+	result := &SQLRows{Columns: columns, Rows: [][]interface{}{}}
+	for rows.Next() {
+		if len(result.Rows) == maxSQLRows {
+			return nil, fmt.Errorf("%w: statement %s returned more than %d rows", ErrSQLTooLarge, name, maxSQLRows)
+		}
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("sql statement %s: %w", name, err)
+		}
+		for i, value := range values {
+			// Drivers return text as []byte, which JSON would encode as base64
+			if b, ok := value.([]byte); ok && utf8.Valid(b) {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql statement %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// Exec runs the named exec statement for plugin with args and returns the
+// number of rows affected. In a call made with WithoutSideEffects it only
+// records the statement and returns 0.
+func (c *SQLCatalog) Exec(ctx context.Context, plugin, name string, args []interface{}) (int64, error) {
+	stmt, err := c.statement(plugin, name, true)
+	if err != nil {
+		return 0, err
+	}
+	if skipEffect(ctx, "exec", name) {
+		return 0, nil
+	}
+	ctx, cancel := stmt.context(ctx)
+	defer cancel()
+
+	result, err := stmt.Stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, fmt.Errorf("sql statement %s: %w", name, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("sql statement %s: %w", name, err)
+	}
+	return affected, nil
+}
+
+// statement returns the named statement if plugin may run it with the
+// function exec selects.
+func (c *SQLCatalog) statement(plugin, name string, exec bool) (SQLStatement, error) {
+	if !c.Allowed(plugin, name) {
+		return SQLStatement{}, fmt.Errorf("%w: plugin %s, statement %q", ErrSQLDenied, plugin, name)
+	}
+	stmt := c.statements[name]
+	if stmt.Exec && !exec {
+		return SQLStatement{}, fmt.Errorf("%w: statement %s must be run with exec", ErrSQLInvalid, name)
+	}
+	if !stmt.Exec && exec {
+		return SQLStatement{}, fmt.Errorf("%w: statement %s must be run with query", ErrSQLInvalid, name)
+	}
+	return stmt, nil
+}
+
+// context returns ctx limited to the statement's timeout.
+func (s SQLStatement) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout > 0 {
+		return context.WithTimeout(ctx, s.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// ParseSQLArgs decodes statement arguments as query() and exec() take
+// them: a JSON array of strings, numbers, booleans, and nulls, or empty
+// for none. Integral numbers become int64, others float64.
+func ParseSQLArgs(data []byte) ([]interface{}, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw []interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: arguments must be a JSON array: %v", ErrSQLInvalid, err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%w: trailing data after arguments", ErrSQLInvalid)
+	}
+	args := make([]interface{}, len(raw))
+	for i, value := range raw {
+		switch v := value.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				args[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				args[i] = f
+			} else {
+				return nil, fmt.Errorf("%w: argument %d: %v", ErrSQLInvalid, i, err)
+			}
+		case string, bool, nil:
+			args[i] = v
+		default:
+			return nil, fmt.Errorf("%w: argument %d must be a string, number, boolean, or null", ErrSQLInvalid, i)
+		}
+	}
+	return args, nil
+}
+
+// NewSQLModule creates the SQLModule host module, running statements from
+// catalog for the calling plugin within the call's context. Denied
+// statements, invalid arguments, and database errors are reported to the
+// plugin as result codes rather than traps.
+func NewSQLModule(catalog *SQLCatalog) *HostModule {
+	// call reads the statement name and arguments and runs them with run,
+	// returning the result or a result code
+	call := func(c *HostCall, args []interface{}, run func(ctx context.Context, plugin, name string, sqlArgs []interface{}) (int64, error)) (int64, error) {
+		if args[1].(int32) > maxSQLStatementName {
+			return int64(SQLDenied), nil
+		}
+		if args[3].(int32) > maxSQLArgs {
+			return int64(SQLInvalid), nil
+		}
+		name, err := c.ReadString(args[0].(int32), args[1].(int32))
+		if err != nil {
+			return 0, err
+		}
+		data, err := c.Read(args[2].(int32), args[3].(int32))
+		if err != nil {
+			return 0, err
+		}
+		sqlArgs, err := ParseSQLArgs(data)
+		if err != nil {
+			return int64(SQLInvalid), nil
+		}
+
+		plugin := strings.TrimSuffix(filepath.Base(c.Path()), ".wasm")
+		n, err := run(c.Context(), plugin, name, sqlArgs)
+		switch {
+		case errors.Is(err, ErrSQLDenied):
+			return int64(SQLDenied), nil
+		case errors.Is(err, ErrSQLInvalid):
+			return int64(SQLInvalid), nil
+		case errors.Is(err, ErrSQLTooLarge):
+			return int64(SQLTooLarge), nil
+		case err != nil:
+			return int64(SQLFailed), nil
+		}
+		return n, nil
+	}
+
+	return NewHostModule(SQLModule).
+		Func("query", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI32},
+			func(c *HostCall, args []interface{}) ([]interface{}, error) {
+				var data []byte
+				n, err := call(c, args, func(ctx context.Context, plugin, name string, sqlArgs []interface{}) (int64, error) {
+					rows, err := catalog.Query(ctx, plugin, name, sqlArgs)
+					if err != nil {
+						return 0, err
+					}
+					encoded, err := json.Marshal(rows)
+					if err != nil {
+						return 0, err
+					}
+					if len(encoded) > maxSQLResult {
+						return 0, ErrSQLTooLarge
+					}
+					data = encoded
+					return int64(len(data)), nil
+				})
+				if err != nil {
+					return nil, err
+				}
+				if data != nil && int32(len(data)) <= args[5].(int32) {
+					if err := c.Write(args[4].(int32), data); err != nil {
+						return nil, err
+					}
+				}
+				return []interface{}{int32(n)}, nil
+			}).
+		Func("exec", []ValueType{ValueI32, ValueI32, ValueI32, ValueI32}, []ValueType{ValueI64},
+			func(c *HostCall, args []interface{}) ([]interface{}, error) {
+				n, err := call(c, args, catalog.Exec)
+				if err != nil {
+					return nil, err
+				}
+				return []interface{}{n}, nil
+			})
+}
@@ -0,0 +1,207 @@
+package runtime_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// fakeDB is a database/sql driver answering from canned results: a query
+// returns fakeDB.rows, an exec affects as many rows as it has arguments,
+// and a statement containing "sleep" blocks until its context is done.
+type fakeDB struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+	args [][]driver.Value // Arguments of every statement run
+}
+
+var fakeDriver = &fakeDB{}
+
+func init() {
+	sql.Register("runtime-fake", fakeDriver)
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(args)
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(args)
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return &fakeRows{rows: s.db.rows}, nil
+}
+
+func (s fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(s.query, "sleep") {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return s.Query(values)
+}
+
+func (d *fakeDB) record(args []driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.args = append(d.args, args)
+}
+
+type fakeRows struct{ rows [][]driver.Value }
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var _ = Describe("SQL module", func() {
+	var (
+		db      *sql.DB
+		catalog *runtime.SQLCatalog
+	)
+
+	prepare := func(query string) *sql.Stmt {
+		stmt, err := db.Prepare(query)
+		Expect(err).NotTo(HaveOccurred())
+		return stmt
+	}
+
+	BeforeEach(func() {
+		var err error
+		db, err = sql.Open("runtime-fake", "")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(db.Close)
+
+		fakeDriver.mu.Lock()
+		fakeDriver.rows = [][]driver.Value{{int64(42), []byte("Ada")}, {int64(43), nil}}
+		fakeDriver.args = nil
+		fakeDriver.mu.Unlock()
+
+		catalog = runtime.NewSQLCatalog(map[string]runtime.SQLStatement{
+			"customer_by_id": {Database: "crm", Stmt: prepare("SELECT id, name FROM customers WHERE id = ?")},
+			"touch_customer": {Database: "crm", Stmt: prepare("UPDATE customers SET seen = ? WHERE id = ?"), Exec: true},
+			"order_total":    {Database: "orders", Stmt: prepare("SELECT id, total FROM orders WHERE id = ?")},
+			"slow":           {Database: "orders", Stmt: prepare("SELECT sleep(10)"), Timeout: 10 * time.Millisecond},
+		}, map[string][]string{
+			"enrich": {"customer_by_id"},
+			"report": {"crm.*", "slow"},
+		})
+	})
+
+	// =========================================================================
+	// TEST: Statement allowlists
+	// Why: Plugins must only reach the statements an operator granted them,
+	//      either by name or for a whole database, and never send SQL.
+	// =========================================================================
+	It("should only run granted statements", func() {
+		Expect(catalog.Allowed("enrich", "customer_by_id")).To(BeTrue())
+		Expect(catalog.Allowed("enrich", "touch_customer")).To(BeFalse())
+		Expect(catalog.Allowed("report", "touch_customer")).To(BeTrue())
+		Expect(catalog.Allowed("report", "order_total")).To(BeFalse())
+		Expect(catalog.Allowed("other", "customer_by_id")).To(BeFalse())
+		Expect(catalog.Allowed("enrich", "SELECT * FROM customers")).To(BeFalse())
+
+		_, err := catalog.Query(context.Background(), "enrich", "order_total", nil)
+		Expect(errors.Is(err, runtime.ErrSQLDenied)).To(BeTrue())
+		_, err = catalog.Exec(context.Background(), "enrich", "touch_customer", nil)
+		Expect(errors.Is(err, runtime.ErrSQLDenied)).To(BeTrue())
+	})
+
+	It("should return rows with text as strings", func() {
+		rows, err := catalog.Query(context.Background(), "enrich", "customer_by_id", []interface{}{int64(42)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rows.Columns).To(Equal([]string{"id", "name"}))
+		Expect(rows.Rows).To(Equal([][]interface{}{{int64(42), "Ada"}, {int64(43), nil}}))
+
+		fakeDriver.mu.Lock()
+		defer fakeDriver.mu.Unlock()
+		Expect(fakeDriver.args).To(Equal([][]driver.Value{{int64(42)}}))
+	})
+
+	It("should run exec statements only with exec", func() {
+		affected, err := catalog.Exec(context.Background(), "report", "touch_customer", []interface{}{true, int64(42)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(affected).To(Equal(int64(2)))
+
+		_, err = catalog.Query(context.Background(), "report", "touch_customer", nil)
+		Expect(errors.Is(err, runtime.ErrSQLInvalid)).To(BeTrue())
+		_, err = catalog.Exec(context.Background(), "enrich", "customer_by_id", nil)
+		Expect(errors.Is(err, runtime.ErrSQLInvalid)).To(BeTrue())
+	})
+
+	It("should stop a statement at its timeout", func() {
+		_, err := catalog.Query(context.Background(), "report", "slow", nil)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("should refuse results over the row limit", func() {
+		fakeDriver.mu.Lock()
+		fakeDriver.rows = make([][]driver.Value, 1001)
+		for i := range fakeDriver.rows {
+			fakeDriver.rows[i] = []driver.Value{int64(i), "x"}
+		}
+		fakeDriver.mu.Unlock()
+
+		_, err := catalog.Query(context.Background(), "enrich", "customer_by_id", nil)
+		Expect(errors.Is(err, runtime.ErrSQLTooLarge)).To(BeTrue())
+	})
+
+	DescribeTable("ParseSQLArgs",
+		func(data string, expected []interface{}) {
+			args, err := runtime.ParseSQLArgs([]byte(data))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal(expected))
+		},
+		Entry("empty", "", []interface{}(nil)),
+		Entry("scalars", `[42, 1.5, "eu", true, null]`, []interface{}{int64(42), 1.5, "eu", true, nil}),
+		Entry("large integers", `[9007199254740993]`, []interface{}{int64(9007199254740993)}),
+	)
+
+	It("should reject arguments that are not a flat JSON array", func() {
+		for _, data := range []string{`{"id": 1}`, `[[1]]`, `[{"a": 1}]`, `[1] [2]`, `[1`} {
+			_, err := runtime.ParseSQLArgs([]byte(data))
+			Expect(errors.Is(err, runtime.ErrSQLInvalid)).To(BeTrue(), data)
+		}
+	})
+
+	It("should define query and exec", func() {
+		Expect(runtime.NewSQLModule(catalog).Functions()).To(Equal([]string{"query", "exec"}))
+	})
+})
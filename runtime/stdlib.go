@@ -0,0 +1,340 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StdlibModule is the import module of the standard library host API:
+// operations that would otherwise each add hundreds of kilobytes of
+// compiled library code to a plugin, implemented once in the host.
+//
+//	__attribute__((import_module("std"), import_name("regex_match")))
+//	extern "C" int regex_match(const char *pattern, int pattern_len,
+//	                           const char *input, int input_len);
+//
+//	__attribute__((import_module("std"), import_name("regex_replace")))
+//	extern "C" int regex_replace(const char *pattern, int pattern_len,
+//	                             const char *input, int input_len,
+//	                             const char *repl, int repl_len,
+//	                             char *buf, int cap);
+//
+//	__attribute__((import_module("std"), import_name("json_path")))
+//	extern "C" int json_path(const char *doc, int doc_len,
+//	                         const char *path, int path_len,
+//	                         char *buf, int cap);
+//
+//	__attribute__((import_module("std"), import_name("gzip")))
+//	extern "C" int std_gzip(const char *data, int len, char *buf, int cap);
+//
+//	__attribute__((import_module("std"), import_name("gunzip")))
+//	extern "C" int std_gunzip(const char *data, int len, char *buf, int cap);
+//
+//	__attribute__((import_module("std"), import_name("base64_encode")))
+//	extern "C" int base64_encode(const char *data, int len, char *buf, int cap);
+//
+//	__attribute__((import_module("std"), import_name("base64_decode")))
+//	extern "C" int base64_decode(const char *data, int len, char *buf, int cap);
+//
+//	__attribute__((import_module("std"), import_name("hash")))
+//	extern "C" int std_hash(const char *alg, int alg_len,
+//	                        const char *data, int len, char *buf, int cap);
+//
+// regex_match() returns 1 if the RE2 pattern matches input and 0 if not.
+// The other functions write their output to buf and return its length;
+// if the output is longer than cap, nothing is written and the plugin can
+// retry with a buffer of the returned length, which repeats the work.
+// json_path() outputs the selected value as JSON, hash() the hex digest.
+//
+// Register it with NewStdlibModule.
+const StdlibModule = "std"
+
+// Result codes the standard library functions return to the plugin
+// instead of a result or length.
+const (
+	StdInvalid  int32 = -1 // Invalid pattern, path, algorithm, or input
+	StdNotFound int32 = -2 // json_path() selected nothing
+	StdTooLarge int32 = -3 // The output is over maxStdOutput
+)
+
+// ErrStdInvalid is returned for an invalid pattern, path, algorithm, or
+// input.
+var ErrStdInvalid = errors.New("invalid std call")
+
+// ErrJSONPathNotFound is returned by JSONPath when the path selects
+// nothing.
+var ErrJSONPathNotFound = errors.New("json path not found")
+
+// Limits on a single call, so a plugin cannot make the host build an
+// arbitrarily large output, e.g. by gunzipping a compression bomb.
+const (
+	maxStdPattern = 4096
+	maxStdOutput  = 16 << 20
+)
+
+// maxRegexCache bounds the compiled patterns kept across calls.
+const maxRegexCache = 256
+
+// regexCache holds compiled patterns, since plugins typically use a few
+// patterns on every call.
+var regexCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// compileRegex returns the compiled pattern, from the cache if possible.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+	if re, ok := regexCache.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStdInvalid, err)
+	}
+	// Patterns are few in practice; start over rather than track use
+	if len(regexCache.patterns) >= maxRegexCache {
+		clear(regexCache.patterns)
+	}
+	regexCache.patterns[pattern] = re
+	return re, nil
+}
+
+// NewStdlibModule creates the StdlibModule host module. Invalid input and
+// oversized outputs are reported to the plugin as result codes rather than
+// traps.
+func NewStdlibModule() *HostModule {
+	i32 := ValueI32
+	// output defines a function whose last two parameters are the output
+	// buffer, computing the output from the (ptr, len) pairs before them
+	output := func(module *HostModule, name string, inputs int, fn func(in [][]byte) ([]byte, error)) *HostModule {
+		params := make([]ValueType, 2*inputs+2)
+		for i := range params {
+			params[i] = i32
+		}
+		return module.Func(name, params, []ValueType{i32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				in := make([][]byte, inputs)
+				for i := range in {
+					data, err := call.Read(args[2*i].(int32), args[2*i+1].(int32))
+					if err != nil {
+						return nil, err
+					}
+					in[i] = data
+				}
+				out, err := fn(in)
+				switch {
+				case errors.Is(err, ErrJSONPathNotFound):
+					return []interface{}{StdNotFound}, nil
+				case err != nil:
+					return []interface{}{StdInvalid}, nil
+				case len(out) > maxStdOutput:
+					return []interface{}{StdTooLarge}, nil
+				}
+				if int32(len(out)) <= args[2*inputs+1].(int32) {
+					if err := call.Write(args[2*inputs].(int32), out); err != nil {
+						return nil, err
+					}
+				}
+				return []interface{}{int32(len(out))}, nil
+			})
+	}
+
+	module := NewHostModule(StdlibModule).
+		Func("regex_match", []ValueType{i32, i32, i32, i32}, []ValueType{i32},
+			func(call *HostCall, args []interface{}) ([]interface{}, error) {
+				if args[1].(int32) > maxStdPattern {
+					return []interface{}{StdInvalid}, nil
+				}
+				pattern, err := call.ReadString(args[0].(int32), args[1].(int32))
+				if err != nil {
+					return nil, err
+				}
+				input, err := call.Read(args[2].(int32), args[3].(int32))
+				if err != nil {
+					return nil, err
+				}
+				re, err := compileRegex(pattern)
+				if err != nil {
+					return []interface{}{StdInvalid}, nil
+				}
+				if re.Match(input) {
+					return []interface{}{int32(1)}, nil
+				}
+				return []interface{}{int32(0)}, nil
+			})
+	module = output(module, "regex_replace", 3, func(in [][]byte) ([]byte, error) {
+		if len(in[0]) > maxStdPattern {
+			return nil, ErrStdInvalid
+		}
+		re, err := compileRegex(string(in[0]))
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAll(in[1], in[2]), nil
+	})
+	module = output(module, "json_path", 2, func(in [][]byte) ([]byte, error) {
+		return JSONPath(in[0], string(in[1]))
+	})
+	module = output(module, "gzip", 1, func(in [][]byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(in[0])
+		w.Close()
+		return buf.Bytes(), nil
+	})
+	module = output(module, "gunzip", 1, func(in [][]byte) ([]byte, error) {
+		r, err := gzip.NewReader(bytes.NewReader(in[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStdInvalid, err)
+		}
+		// One byte over the limit is enough to report StdTooLarge
+		out, err := io.ReadAll(io.LimitReader(r, maxStdOutput+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStdInvalid, err)
+		}
+		return out, nil
+	})
+	module = output(module, "base64_encode", 1, func(in [][]byte) ([]byte, error) {
+		return base64.StdEncoding.AppendEncode(nil, in[0]), nil
+	})
+	module = output(module, "base64_decode", 1, func(in [][]byte) ([]byte, error) {
+		out, err := base64.StdEncoding.AppendDecode(nil, in[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStdInvalid, err)
+		}
+		return out, nil
+	})
+	return output(module, "hash", 2, func(in [][]byte) ([]byte, error) {
+		return Hash(string(in[0]), in[1])
+	})
+}
+
+// Hash returns the hex digest of data with alg: "sha256", "sha512",
+// "sha1", "md5", or "crc32" (IEEE), as hash() computes it.
+func Hash(alg string, data []byte) ([]byte, error) {
+	var h hash.Hash
+	switch alg {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	case "sha1":
+		h = sha1.New()
+	case "md5":
+		h = md5.New()
+	case "crc32":
+		h = crc32.NewIEEE()
+	default:
+		return nil, fmt.Errorf("%w: unknown hash algorithm %q", ErrStdInvalid, alg)
+	}
+	h.Write(data)
+	return hex.AppendEncode(nil, h.Sum(nil)), nil
+}
+
+// JSONPath returns the value path selects in the JSON document doc, as
+// JSON. Paths are a subset of JSONPath: an optional "$" followed by
+// ".key", "[index]", and "[\"key\"]" steps, e.g. $.items[0].name or
+// .labels["app.kubernetes.io/name"]; a negative index counts from the end.
+// An empty path, or "$", selects the whole document.
+func JSONPath(doc []byte, path string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON document: %v", ErrStdInvalid, err)
+	}
+
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" {
+		var (
+			key     string
+			index   int
+			isIndex bool
+		)
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key, rest = rest[1:1+end], rest[1+end:]
+			if key == "" {
+				return nil, fmt.Errorf("%w: empty key in path %q", ErrStdInvalid, path)
+			}
+		case strings.HasPrefix(rest, "[\""):
+			// A quoted key ends at the first unescaped quote
+			end := 2
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end+1 >= len(rest) || rest[end+1] != ']' {
+				return nil, fmt.Errorf("%w: unterminated key in path %q", ErrStdInvalid, path)
+			}
+			unquoted, err := strconv.Unquote(rest[1 : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid key in path %q", ErrStdInvalid, path)
+			}
+			key, rest = unquoted, rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated index in path %q", ErrStdInvalid, path)
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid index in path %q", ErrStdInvalid, path)
+			}
+			index, isIndex, rest = n, true, rest[end+1:]
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q in path %q", ErrStdInvalid, rest, path)
+		}
+
+		if isIndex {
+			array, ok := value.([]interface{})
+			if index < 0 {
+				index += len(array)
+			}
+			if !ok || index < 0 || index >= len(array) {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+			value = array[index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+		if value, ok = object[key]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+	}
+	// Selected strings come back as they were, without HTML escaping
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,75 @@
+package runtime_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Standard library module", func() {
+	doc := []byte(`{"items": [{"name": "a<b", "price": 1.50}, {"name": "c"}],
+		"labels": {"app.kubernetes.io/name": "shop"}, "empty": null}`)
+
+	// =========================================================================
+	// TEST: JSON path selection
+	// Why: Plugins extract fields with json_path() instead of bundling a
+	//      JSON parser; the selected value must be returned as valid JSON,
+	//      numbers and strings unchanged.
+	// =========================================================================
+	DescribeTable("JSONPath",
+		func(path, expected string) {
+			value, err := runtime.JSONPath(doc, path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(value)).To(Equal(expected))
+		},
+		Entry("a nested field", "$.items[0].name", `"a<b"`),
+		Entry("a number as written", ".items[0].price", `1.50`),
+		Entry("an index from the end", "$.items[-1]", `{"name":"c"}`),
+		Entry("a quoted key", `$.labels["app.kubernetes.io/name"]`, `"shop"`),
+		Entry("null", "$.empty", `null`),
+		Entry("the document", "$", `{"empty":null,"items":[{"name":"a<b","price":1.50},{"name":"c"}],"labels":{"app.kubernetes.io/name":"shop"}}`),
+	)
+
+	It("should tell missing values from invalid paths", func() {
+		for _, path := range []string{"$.missing", "$.items[2]", "$.items.name", "$.labels[0]"} {
+			_, err := runtime.JSONPath(doc, path)
+			Expect(errors.Is(err, runtime.ErrJSONPathNotFound)).To(BeTrue(), path)
+		}
+		for _, path := range []string{"$..items", "$.items[x]", `$["unterminated`, "items", "$.items[0"} {
+			_, err := runtime.JSONPath(doc, path)
+			Expect(errors.Is(err, runtime.ErrStdInvalid)).To(BeTrue(), path)
+		}
+		_, err := runtime.JSONPath([]byte(`{"a":`), "$.a")
+		Expect(errors.Is(err, runtime.ErrStdInvalid)).To(BeTrue())
+	})
+
+	It("should hash with every supported algorithm", func() {
+		digests := map[string]string{
+			"sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			"sha1":   "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+			"md5":    "5d41402abc4b2a76b9719d911017c592",
+			"crc32":  "3610a686",
+		}
+		for alg, digest := range digests {
+			sum, err := runtime.Hash(alg, []byte("hello"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(sum)).To(Equal(digest), alg)
+		}
+		sum, err := runtime.Hash("sha512", []byte("hello"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sum).To(HaveLen(128))
+
+		_, err = runtime.Hash("blake3", []byte("hello"))
+		Expect(errors.Is(err, runtime.ErrStdInvalid)).To(BeTrue())
+	})
+
+	It("should define the library functions", func() {
+		Expect(runtime.NewStdlibModule().Functions()).To(Equal([]string{
+			"regex_match", "regex_replace", "json_path", "gzip", "gunzip",
+			"base64_encode", "base64_decode", "hash",
+		}))
+	})
+})
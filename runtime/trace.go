@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"context"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// Trace event kinds.
+const (
+	TraceExport = engine.TraceExport // The host called an export of the plugin
+	TraceHost   = engine.TraceHost   // The plugin called a host function
+)
+
+// Trace records the exports called and the host functions reached during
+// calls whose context carries it (see WithTrace); see engine.Trace.
+//
+// WASI functions are implemented inside WasmEdge and never reach Go, so
+// their calls cannot be recorded; WASI lists the ones the plugin imports
+// instead.
+type Trace = engine.Trace
+
+// TraceEvent is one call recorded in a Trace.
+type TraceEvent = engine.TraceEvent
+
+// MemoryAccess is a read or write of guest memory by a host function,
+// such as a string passed in as a (ptr, len) pair.
+type MemoryAccess = engine.MemoryAccess
+
+// NewTrace creates an empty trace.
+func NewTrace() *Trace {
+	return engine.NewTrace()
+}
+
+// WithTrace returns a context that makes plugin calls made with it
+// (ExecuteContext and friends) record their exports and host function
+// calls in trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return engine.WithTrace(ctx, trace)
+}
+
+// wasiImports returns the WASI functions the plugin imports, or nil if its
+// file cannot be parsed.
+func (p *Plugin) wasiImports() []string {
+	module, err := p.module()
+	if err != nil {
+		return nil
+	}
+
+	imports := []string{}
+	for _, imp := range module.Imports {
+		if imp.Module == wasiModule && imp.Kind == wasminfo.KindFunc {
+			imports = append(imports, imp.Name)
+		}
+	}
+	return imports
+}
@@ -0,0 +1,112 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Trace", func() {
+	It("should start empty", func() {
+		trace := runtime.NewTrace()
+		Expect(trace.Events()).To(BeEmpty())
+		Expect(trace.Dropped()).To(BeZero())
+		Expect(trace.WASI()).To(BeEmpty())
+	})
+
+	// =========================================================================
+	// TEST: Host-call capture
+	// Why: Authors debugging a failing run need to see what their plugin
+	//      exchanged with the host, in order: the export, each host call
+	//      with its arguments and results, and the memory passed through it.
+	// =========================================================================
+	Describe("with the hostcall plugin", func() {
+		var (
+			plugin *runtime.Plugin
+			kvErr  error
+		)
+
+		BeforeEach(func() {
+			path := filepath.Join("..", "plugins", "hostcall", "hostcall.wasm")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				Skip("Test plugin not found: " + path)
+			}
+
+			kvErr = nil
+			i32 := runtime.ValueI32
+			module := runtime.NewHostModule("host").
+				Func("log", []runtime.ValueType{i32, i32}, nil,
+					func(*runtime.HostCall, []interface{}) ([]interface{}, error) { return nil, nil }).
+				Func("kv_get", []runtime.ValueType{i32, i32}, []runtime.ValueType{i32},
+					func(call *runtime.HostCall, args []interface{}) ([]interface{}, error) {
+						if _, err := call.ReadString(args[0].(int32), args[1].(int32)); err != nil {
+							return nil, err
+						}
+						if kvErr != nil {
+							return nil, kvErr
+						}
+						return []interface{}{int32(3)}, nil
+					})
+
+			var err error
+			plugin, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				HostModules: []*runtime.HostModule{module},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.Init()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if plugin != nil {
+				plugin.Close()
+			}
+		})
+
+		It("should record the export and its host calls in order", func() {
+			trace := runtime.NewTrace()
+			Expect(plugin.ExecuteContext(runtime.WithTrace(context.Background(), trace), 14)).To(Equal(42))
+
+			events := trace.Events()
+			Expect(events).To(HaveLen(2))
+
+			Expect(events[0].Seq).To(Equal(1))
+			Expect(events[0].Kind).To(Equal(runtime.TraceExport))
+			Expect(events[0].Function).To(Equal("process"))
+			Expect(events[0].Args).To(Equal([]interface{}{int32(14)}))
+			Expect(events[0].Results).To(Equal([]interface{}{int32(42)}))
+
+			Expect(events[1].Seq).To(Equal(2))
+			Expect(events[1].Kind).To(Equal(runtime.TraceHost))
+			Expect(events[1].Module).To(Equal("host"))
+			Expect(events[1].Function).To(Equal("kv_get"))
+			Expect(events[1].Results).To(Equal([]interface{}{int32(3)}))
+			Expect(events[1].Memory).To(HaveLen(1))
+			Expect(events[1].Memory[0].Op).To(Equal("read"))
+			Expect(string(events[1].Memory[0].Data)).To(Equal("factor"))
+		})
+
+		It("should record the host function error that failed the call", func() {
+			kvErr = errors.New("kv store unavailable")
+			trace := runtime.NewTrace()
+			_, err := plugin.ExecuteContext(runtime.WithTrace(context.Background(), trace), 14)
+			Expect(err).To(HaveOccurred())
+
+			events := trace.Events()
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Error).To(ContainSubstring("kv store unavailable"))
+			Expect(events[1].Error).To(Equal("kv store unavailable"))
+		})
+
+		It("should not record calls without a trace", func() {
+			trace := runtime.NewTrace()
+			Expect(plugin.Execute(14)).To(Equal(42))
+			Expect(trace.Events()).To(BeEmpty())
+		})
+	})
+})
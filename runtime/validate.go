@@ -0,0 +1,289 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+// CheckStatus is the outcome of one check of ValidateABI.
+type CheckStatus string
+
+const (
+	CheckPassed  CheckStatus = "passed"
+	CheckWarning CheckStatus = "warning" // Loads, but should be fixed
+	CheckFailed  CheckStatus = "failed"  // Would not load or run
+	CheckSkipped CheckStatus = "skipped" // Could not be checked; see the message
+)
+
+// ABICheck is one check of ValidateABI.
+type ABICheck struct {
+	Name    string      `json:"name"` // Stable identifier, e.g. "export.init" or "dry_run.init"
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message,omitempty"`
+}
+
+// ABIReport is the result of ValidateABI, in a form CI pipelines can read.
+type ABIReport struct {
+	Path  string `json:"path"`
+	Valid bool   `json:"valid"` // No check failed
+
+	// ABIVersion is the version get_abi_version() returns, "unversioned"
+	// without the export, or "" if it could not be determined
+	ABIVersion  string `json:"abi_version"`
+	MemoryPages int    `json:"memory_pages"` // Initial size of linear memory
+
+	// Payloads are the payload ABIs the plugin implements besides
+	// process(): "bytes", "json", and "protobuf"
+	Payloads []string `json:"payloads,omitempty"`
+
+	Checks []ABICheck `json:"checks"`
+}
+
+// requiredABIExports are the exports every plugin must have, with the
+// signatures they may have. process() may return nothing (see ErrNoOutput).
+var requiredABIExports = []struct {
+	name       string
+	signatures []string
+}{
+	{"init", []string{"() -> i32"}},
+	{"process", []string{"(i32) -> i32", "(i32)"}},
+	{"cleanup", []string{"() -> i32"}},
+}
+
+// optionalABIExports are the signatures of the exports the runtime calls
+// if a plugin has them.
+var optionalABIExports = map[string]string{
+	ExportABIVersion:     "() -> i32",
+	ExportInitWithConfig: "(i32, i32) -> i32",
+	ExportAllocate:       "(i32) -> i32",
+	ExportDeallocate:     "(i32, i32)",
+	ExportProcessBytes:   "(i32, i32) -> i64",
+	ExportProcessJSON:    "(i32, i32) -> i64",
+	ExportProcessPB:      "(i32, i32) -> i64",
+	ExportLastError:      "() -> i64",
+	ExportHealth:         "() -> i32",
+	ExportOnShutdown:     "() -> i32",
+}
+
+// payloadExports are the payload ABIs, by the name ABIReport gives them.
+var payloadExports = []struct{ name, export string }{
+	{"bytes", ExportProcessBytes},
+	{"json", ExportProcessJSON},
+	{"protobuf", ExportProcessPB},
+}
+
+// ValidateABI checks that the plugin binary at path implements the plugin
+// ABI, for plugin authors to run before they publish a build:
+//
+//   - export.*: the required exports, and the optional ones it has, are
+//     functions with the signatures the runtime calls them with
+//   - payload: a plugin implementing a payload ABI exports allocate() and
+//     deallocate()
+//   - abi_version: get_abi_version() returns a version within SupportedABI,
+//     as opts require
+//   - memory: the plugin exports its linear memory as "memory", and its
+//     initial size fits the memory limit of opts and its manifest
+//   - manifest: the binary matches its plugin.json, if it has one
+//   - imports: every import resolves against WASI or opts.HostModules
+//   - dry_run.*: the plugin loads with opts, and init() and cleanup()
+//     succeed; process() is not called
+//
+// The dry run is skipped if a static check failed, or if the plugin
+// imports host modules opts does not provide, since it could not load.
+// A file that cannot be read or parsed fails the "parse" check, after
+// which nothing else is checked.
+func ValidateABI(path string, opts LoadOptions) *ABIReport {
+	report := &ABIReport{Path: path}
+	add := func(name string, status CheckStatus, format string, args ...interface{}) {
+		report.Checks = append(report.Checks, ABICheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	module, err := wasminfo.Open(path)
+	if err != nil {
+		add("parse", CheckFailed, "%v", err)
+		return report.finish()
+	}
+	add("parse", CheckPassed, "")
+	report.MemoryPages = module.MemoryPages
+
+	// Exports and their signatures
+	checkExport := func(name string, signatures ...string) {
+		export, ok := module.Export(name)
+		switch {
+		case !ok:
+			add("export."+name, CheckFailed, "%s is not exported", name)
+		case export.Kind != wasminfo.KindFunc:
+			add("export."+name, CheckFailed, "%s is a %s, not a function", name, export.Kind)
+		case !slices.Contains(signatures, export.Signature):
+			add("export."+name, CheckFailed, "%s has signature %s, expected %s", name, export.Signature, strings.Join(signatures, " or "))
+		default:
+			add("export."+name, CheckPassed, "")
+		}
+	}
+	for _, export := range requiredABIExports {
+		checkExport(export.name, export.signatures...)
+	}
+	optional := make([]string, 0, len(optionalABIExports))
+	for name := range optionalABIExports {
+		if _, ok := module.Export(name); ok {
+			optional = append(optional, name)
+		}
+	}
+	sort.Strings(optional)
+	for _, name := range optional {
+		checkExport(name, optionalABIExports[name])
+	}
+
+	// Payload ABIs need the allocator
+	for _, payload := range payloadExports {
+		if _, ok := module.Export(payload.export); ok {
+			report.Payloads = append(report.Payloads, payload.name)
+		}
+	}
+	if len(report.Payloads) > 0 {
+		_, allocate := module.Export(ExportAllocate)
+		_, deallocate := module.Export(ExportDeallocate)
+		if allocate && deallocate {
+			add("payload", CheckPassed, "")
+		} else {
+			add("payload", CheckFailed, "payload ABIs need both %s and %s exported", ExportAllocate, ExportDeallocate)
+		}
+	}
+
+	// ABI version
+	_, versioned := module.Export(ExportABIVersion)
+	switch {
+	case !versioned && opts.RequireABIVersion:
+		report.ABIVersion = ABIVersion(0).String()
+		add("abi_version", CheckFailed, "%s is not exported", ExportABIVersion)
+	case !versioned:
+		report.ABIVersion = ABIVersion(0).String()
+		add("abi_version", CheckWarning, "%s is not exported; unversioned plugins are deprecated", ExportABIVersion)
+	case module.ABIVersion == nil:
+		add("abi_version", CheckSkipped, "%s does not return a constant; the dry run calls it", ExportABIVersion)
+	default:
+		version := ABIVersion(*module.ABIVersion)
+		report.ABIVersion = version.String()
+		if opts.AnyABIVersion || SupportedABI.Contains(version) {
+			add("abi_version", CheckPassed, "")
+		} else {
+			add("abi_version", CheckFailed, "implements ABI %s, the runtime supports %s", version, SupportedABI)
+		}
+	}
+
+	// Manifest, and the memory limit it may set
+	limit := opts.MaxMemoryPages
+	m, err := manifest.ForPlugin(path)
+	switch {
+	case err != nil:
+		add("manifest", CheckFailed, "%v", err)
+	case m != nil:
+		if err := m.Check(module); err != nil {
+			add("manifest", CheckFailed, "%v", err)
+		} else {
+			add("manifest", CheckPassed, "")
+		}
+		if m.Limits.MemoryPages > 0 {
+			limit = m.Limits.MemoryPages
+		}
+	}
+
+	// Linear memory
+	memory, ok := module.Export("memory")
+	switch {
+	case !ok && len(report.Payloads) > 0:
+		add("memory", CheckFailed, "payload ABIs need the linear memory exported as \"memory\"")
+	case !ok:
+		add("memory", CheckWarning, "linear memory is not exported as \"memory\"; the host cannot report its use")
+	case memory.Kind != wasminfo.KindMemory:
+		add("memory", CheckFailed, "\"memory\" is a %s, not a memory", memory.Kind)
+	case limit > 0 && module.MemoryPages > limit:
+		add("memory", CheckFailed, "needs %d pages of memory but is limited to %d", module.MemoryPages, limit)
+	default:
+		add("memory", CheckPassed, "")
+	}
+
+	// Imports
+	provided := map[string]*HostModule{}
+	for _, hm := range opts.HostModules {
+		provided[hm.Name()] = hm
+	}
+	var missing, unknown []string
+	for _, imp := range module.Imports {
+		if imp.Module == wasiModule {
+			continue
+		}
+		hm, ok := provided[imp.Module]
+		if !ok {
+			if !slices.Contains(missing, imp.Module) {
+				missing = append(missing, imp.Module)
+			}
+			continue
+		}
+		if imp.Kind != wasminfo.KindFunc || !slices.Contains(hm.Functions(), imp.Name) {
+			unknown = append(unknown, imp.Module+"."+imp.Name)
+		}
+	}
+	switch {
+	case len(unknown) > 0:
+		add("imports", CheckFailed, "the host does not provide %s", strings.Join(unknown, ", "))
+	case len(missing) > 0:
+		add("imports", CheckSkipped, "imports host modules that are not available here: %s", strings.Join(missing, ", "))
+	default:
+		add("imports", CheckPassed, "")
+	}
+
+	// Dry run
+	dryRun := []string{"dry_run.load", "dry_run.init", "dry_run.cleanup"}
+	reason := ""
+	if !report.finish().Valid {
+		reason = "a static check failed"
+	} else if len(missing) > 0 {
+		reason = "the plugin imports host modules that are not available here"
+	}
+	if reason != "" {
+		for _, name := range dryRun {
+			add(name, CheckSkipped, "%s", reason)
+		}
+		return report.finish()
+	}
+	plugin, err := LoadPluginWithOptions(path, opts)
+	if err != nil {
+		add("dry_run.load", CheckFailed, "%v", err)
+		return report.finish()
+	}
+	defer plugin.Close()
+	add("dry_run.load", CheckPassed, "")
+	report.ABIVersion = plugin.ABIVersion().String()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultInitTimeout)
+	defer cancel()
+	if err := plugin.InitContext(ctx); err != nil {
+		add("dry_run.init", CheckFailed, "%v", err)
+		add("dry_run.cleanup", CheckSkipped, "init() failed")
+		return report.finish()
+	}
+	add("dry_run.init", CheckPassed, "")
+	if err := plugin.Cleanup(); err != nil {
+		add("dry_run.cleanup", CheckFailed, "%v", err)
+	} else {
+		add("dry_run.cleanup", CheckPassed, "")
+	}
+	return report.finish()
+}
+
+// finish sets Valid from the checks, and returns r.
+func (r *ABIReport) finish() *ABIReport {
+	r.Valid = true
+	for _, check := range r.Checks {
+		if check.Status == CheckFailed {
+			r.Valid = false
+		}
+	}
+	return r
+}
@@ -0,0 +1,203 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+// abiFunc is a function export of a module abiModule encodes. Params and
+// results are value type bytes: 0x7f for i32, 0x7e for i64.
+type abiFunc struct {
+	name            string
+	params, results []byte
+	constant        int32 // Returned by an i32 result
+}
+
+var (
+	i32Func = func(name string, params ...byte) abiFunc {
+		return abiFunc{name: name, params: params, results: []byte{0x7f}}
+	}
+	i64Func = func(name string, params ...byte) abiFunc {
+		return abiFunc{name: name, params: params, results: []byte{0x7e}}
+	}
+	abiBase = []abiFunc{i32Func("init"), i32Func("process", 0x7f), i32Func("cleanup")}
+)
+
+// abiModule encodes a module exporting funcs, and memory of pages pages
+// unless pages is negative. It imports a `() -> ()` function for each
+// "module.name" in imports.
+func abiModule(funcs []abiFunc, pages int, imports ...string) []byte {
+	vector := func(items [][]byte) []byte {
+		out := []byte{byte(len(items))}
+		for _, item := range items {
+			out = append(out, item...)
+		}
+		return out
+	}
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+	section := func(id byte, payload []byte) []byte {
+		return append([]byte{id, byte(len(payload))}, payload...)
+	}
+
+	var types, importEntries, functions, exports, code [][]byte
+	for _, imp := range imports {
+		module, field, _ := strings.Cut(imp, ".")
+		importEntries = append(importEntries, append(append(name(module), name(field)...), 0x00, byte(len(funcs))))
+	}
+	for i, f := range funcs {
+		types = append(types, append(append(append([]byte{0x60, byte(len(f.params))}, f.params...), byte(len(f.results))), f.results...))
+		functions = append(functions, []byte{byte(i)})
+		exports = append(exports, append(name(f.name), 0x00, byte(len(imports)+i)))
+		body := []byte{0x00}
+		for _, result := range f.results {
+			if result == 0x7f {
+				body = append(append(body, 0x41), sleb32(f.constant)...)
+			} else {
+				body = append(body, 0x42, 0x00)
+			}
+		}
+		body = append(body, 0x0b)
+		code = append(code, append([]byte{byte(len(body))}, body...))
+	}
+	types = append(types, []byte{0x60, 0x00, 0x00}) // Imported functions
+
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = append(out, section(0x01, vector(types))...)
+	if len(importEntries) > 0 {
+		out = append(out, section(0x02, vector(importEntries))...)
+	}
+	out = append(out, section(0x03, vector(functions))...)
+	if pages >= 0 {
+		out = append(out, section(0x05, vector([][]byte{{0x00, byte(pages)}}))...)
+		exports = append(exports, append(name("memory"), 0x02, 0x00))
+	}
+	out = append(out, section(0x07, vector(exports))...)
+	return append(out, section(0x0a, vector(code))...)
+}
+
+// sleb32 encodes v as signed LEB128.
+func sleb32(v int32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+var _ = Describe("ValidateABI", func() {
+	// =========================================================================
+	// TEST: Static checks
+	// Why: Plugin authors run the report in CI; it must name each problem
+	//      the runtime would trip over, and not load a binary that has one.
+	// =========================================================================
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	validate := func(wasm []byte, opts runtime.LoadOptions) *runtime.ABIReport {
+		path := filepath.Join(dir, "plugin.wasm")
+		Expect(os.WriteFile(path, wasm, 0644)).To(Succeed())
+		return runtime.ValidateABI(path, opts)
+	}
+
+	check := func(report *runtime.ABIReport, name string) runtime.ABICheck {
+		for _, c := range report.Checks {
+			if c.Name == name {
+				return c
+			}
+		}
+		Fail("no check " + name)
+		return runtime.ABICheck{}
+	}
+
+	It("should report missing exports and wrong signatures", func() {
+		report := validate(abiModule([]abiFunc{i32Func("init"), i32Func("process", 0x7f, 0x7f)}, 1), runtime.LoadOptions{})
+		Expect(report.Valid).To(BeFalse())
+		Expect(check(report, "export.init").Status).To(Equal(runtime.CheckPassed))
+		Expect(check(report, "export.process")).To(Equal(runtime.ABICheck{
+			Name:    "export.process",
+			Status:  runtime.CheckFailed,
+			Message: "process has signature (i32, i32) -> i32, expected (i32) -> i32 or (i32)",
+		}))
+		Expect(check(report, "export.cleanup").Message).To(Equal("cleanup is not exported"))
+		Expect(check(report, "abi_version").Status).To(Equal(runtime.CheckWarning))
+		Expect(report.ABIVersion).To(Equal("unversioned"))
+		for _, name := range []string{"dry_run.load", "dry_run.init", "dry_run.cleanup"} {
+			Expect(check(report, name).Status).To(Equal(runtime.CheckSkipped))
+		}
+	})
+
+	It("should check payload exports and memory", func() {
+		funcs := append(abiBase[:3:3], i64Func("process_bytes", 0x7f, 0x7f), i32Func("allocate", 0x7f))
+		report := validate(abiModule(funcs, -1), runtime.LoadOptions{})
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Payloads).To(Equal([]string{"bytes"}))
+		Expect(check(report, "export.process_bytes").Status).To(Equal(runtime.CheckPassed))
+		Expect(check(report, "payload").Status).To(Equal(runtime.CheckFailed))
+		Expect(check(report, "memory").Status).To(Equal(runtime.CheckFailed))
+
+		report = validate(abiModule(abiBase, 3), runtime.LoadOptions{MaxMemoryPages: 2})
+		Expect(report.MemoryPages).To(Equal(3))
+		Expect(check(report, "memory").Message).To(Equal("needs 3 pages of memory but is limited to 2"))
+	})
+
+	It("should check the ABI version", func() {
+		version := abiFunc{name: runtime.ExportABIVersion, results: []byte{0x7f}, constant: 20100}
+		report := validate(abiModule(append(abiBase[:3:3], version), 1), runtime.LoadOptions{})
+		Expect(report.ABIVersion).To(Equal("2.1.0"))
+		Expect(check(report, "abi_version").Status).To(Equal(runtime.CheckFailed))
+		Expect(report.Valid).To(BeFalse())
+
+		report = validate(abiModule(abiBase, 1), runtime.LoadOptions{RequireABIVersion: true})
+		Expect(check(report, "abi_version").Status).To(Equal(runtime.CheckFailed))
+	})
+
+	It("should check imports against the host modules", func() {
+		opts := runtime.LoadOptions{HostModules: []*runtime.HostModule{runtime.NewStdlibModule()}}
+		report := validate(abiModule(abiBase, 1, "std.no_such_function"), opts)
+		Expect(check(report, "imports").Message).To(Equal("the host does not provide std.no_such_function"))
+		Expect(report.Valid).To(BeFalse())
+
+		// Host modules that are not available leave the report valid, but
+		// cannot be loaded to run
+		report = validate(abiModule(abiBase, 1, "wasi_snapshot_preview1.fd_write", "host.cache_get"), opts)
+		Expect(check(report, "imports").Status).To(Equal(runtime.CheckSkipped))
+		Expect(check(report, "dry_run.load").Status).To(Equal(runtime.CheckSkipped))
+		Expect(report.Valid).To(BeTrue())
+	})
+
+	It("should fail files that are not WebAssembly", func() {
+		report := validate([]byte("not wasm"), runtime.LoadOptions{})
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Checks).To(HaveLen(1))
+		Expect(report.Checks[0].Name).To(Equal("parse"))
+	})
+
+	// =========================================================================
+	// TEST: Dry run
+	// Why: A binary can pass every static check and still fail to start;
+	//      the report must show that before the server does.
+	// =========================================================================
+	It("should load, initialize, and clean up a valid plugin", func() {
+		path := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + path)
+		}
+		report := runtime.ValidateABI(path, runtime.LoadOptions{})
+		Expect(report.Valid).To(BeTrue(), "%+v", report.Checks)
+		Expect(check(report, "dry_run.init").Status).To(Equal(runtime.CheckPassed))
+		Expect(check(report, "dry_run.cleanup").Status).To(Equal(runtime.CheckPassed))
+	})
+})
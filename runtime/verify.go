@@ -0,0 +1,18 @@
+package runtime
+
+import (
+	"crypto/ed25519"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// ErrIntegrity is wrapped by load errors for plugin binaries that do not
+// match the digest declared for them, or lack a signature by a trusted key.
+var ErrIntegrity = engine.ErrIntegrity
+
+// ParseTrustedKeys decodes the PEM "PUBLIC KEY" blocks in data, such as a
+// cosign.pub file or the output of `openssl pkey -pubout`, into keys for
+// LoadOptions.TrustedKeys. Every block must hold an Ed25519 key.
+func ParseTrustedKeys(data []byte) ([]ed25519.PublicKey, error) {
+	return engine.ParseTrustedKeys(data)
+}
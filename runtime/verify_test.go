@@ -0,0 +1,174 @@
+package runtime_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/manifest"
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Plugin verification", func() {
+	// =========================================================================
+	// TEST: Integrity checks
+	// Why: A binary swapped or corrupted in the store must never run; the
+	//      check has to reject it before WasmEdge parses any of it.
+	// =========================================================================
+	var (
+		path    string
+		data    []byte
+		trusted ed25519.PublicKey
+		private ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "hello.wasm")
+		data = []byte("not a real module")
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+
+		var err error
+		trusted, private, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	digestOf := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+
+	writeSignature := func(key ed25519.PrivateKey) {
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+		Expect(os.WriteFile(path+manifest.SignatureSuffix, []byte(signature+"\n"), 0644)).To(Succeed())
+	}
+
+	It("should reject a binary that does not match its checksum file", func() {
+		Expect(os.WriteFile(path+manifest.DigestSuffix, []byte(digestOf([]byte("original"))), 0644)).To(Succeed())
+
+		_, err := runtime.LoadPlugin(path)
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+		Expect(err.Error()).To(ContainSubstring(digestOf(data)))
+	})
+
+	It("should reject a binary that does not match its manifest", func() {
+		Expect(os.WriteFile(manifest.Path(path),
+			[]byte(`{"name": "hello", "version": "1.0.0", "sha256": "`+digestOf([]byte("original"))+`"}`), 0644)).To(Succeed())
+
+		_, err := runtime.LoadPlugin(path)
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+	})
+
+	It("should require a digest when asked to", func() {
+		_, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{RequireDigest: true})
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+		Expect(err.Error()).To(ContainSubstring("declares no sha256 digest"))
+	})
+
+	It("should reject an unsigned binary when keys are trusted", func() {
+		_, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{TrustedKeys: []ed25519.PublicKey{trusted}})
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+		Expect(err.Error()).To(ContainSubstring("is not signed"))
+	})
+
+	It("should reject a signature by an untrusted key", func() {
+		_, untrusted, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		writeSignature(untrusted)
+
+		_, err = runtime.LoadPluginWithOptions(path, runtime.LoadOptions{TrustedKeys: []ed25519.PublicKey{trusted}})
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+		Expect(err.Error()).To(ContainSubstring("not signed by a trusted key"))
+	})
+
+	It("should reject a malformed signature", func() {
+		Expect(os.WriteFile(path+manifest.SignatureSuffix, []byte("c2lnbmF0dXJl"), 0644)).To(Succeed())
+
+		_, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{TrustedKeys: []ed25519.PublicKey{trusted}})
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+	})
+
+	It("should check the digest of a signed binary too", func() {
+		writeSignature(private)
+		Expect(os.WriteFile(path+manifest.DigestSuffix, []byte(digestOf([]byte("original"))), 0644)).To(Succeed())
+
+		_, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{TrustedKeys: []ed25519.PublicKey{trusted}})
+
+		Expect(errors.Is(err, runtime.ErrIntegrity)).To(BeTrue(), "%v", err)
+		Expect(err.Error()).To(ContainSubstring("expected"))
+	})
+
+	Context("with the hello plugin", func() {
+		BeforeEach(func() {
+			source := filepath.Join("..", "plugins", "hello", "hello.wasm")
+			var err error
+			data, err = os.ReadFile(source)
+			if os.IsNotExist(err) {
+				Skip("Test plugin not found: " + source)
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+		})
+
+		It("should load a binary matching its digest and signature", func() {
+			Expect(os.WriteFile(path+manifest.DigestSuffix, []byte(digestOf(data)+"  hello.wasm\n"), 0644)).To(Succeed())
+			writeSignature(private)
+
+			plugin, err := runtime.LoadPluginWithOptions(path, runtime.LoadOptions{
+				RequireDigest: true,
+				TrustedKeys:   []ed25519.PublicKey{trusted},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			plugin.Close()
+		})
+	})
+
+	Describe("ParseTrustedKeys", func() {
+		encode := func(key interface{}) []byte {
+			der, err := x509.MarshalPKIXPublicKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		}
+
+		It("should read every PEM public key", func() {
+			other, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			keys, err := runtime.ParseTrustedKeys(append(encode(trusted), encode(other)...))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]ed25519.PublicKey{trusted, other}))
+		})
+
+		It("should reject keys that are not Ed25519", func() {
+			ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = runtime.ParseTrustedKeys(encode(&ecKey.PublicKey))
+
+			Expect(err).To(MatchError(ContainSubstring("not an Ed25519 key")))
+		})
+
+		It("should reject input without keys", func() {
+			_, err := runtime.ParseTrustedKeys([]byte("not pem"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
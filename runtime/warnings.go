@@ -0,0 +1,100 @@
+package runtime
+
+import (
+	"fmt"
+)
+
+// nearLimitThreshold is the fraction of a resource limit above which a
+// near-limit warning is reported.
+const nearLimitThreshold = 0.9
+
+// maxWasm32Pages is the largest linear memory a wasm32 module can address
+// (4 GiB), used when the module declares no maximum.
+const maxWasm32Pages = 65536
+
+// WarningCode identifies a class of non-fatal condition. Codes are stable
+// and safe for clients to branch on.
+type WarningCode string
+
+const (
+	// WarnDeprecated reports use of a plugin feature or ABI form that will
+	// stop working in a future release.
+	WarnDeprecated WarningCode = "deprecated"
+
+	// WarnNearResourceLimit reports that a call came close to a resource
+	// limit, such as linear memory, without exceeding it.
+	WarnNearResourceLimit WarningCode = "near_resource_limit"
+)
+
+// Warning describes a non-fatal condition observed while running a plugin.
+type Warning struct {
+	Code    WarningCode `json:"code"`    // Stable machine-readable code
+	Message string      `json:"message"` // Human-readable detail
+}
+
+// Diagnose inspects the plugin's current state and returns any non-fatal
+// conditions callers should know about. Call it after Execute() and before
+// the instance is reset or closed.
+//
+// Reported conditions:
+//   - WarnDeprecated: the plugin does not export get_abi_version
+//   - WarnNearResourceLimit: linear memory is above 90% of its maximum
+func (p *Plugin) Diagnose() []Warning {
+	defer p.lock()()
+
+	if p.vm == nil {
+		return nil
+	}
+
+	var warnings []Warning
+
+	if !p.HasExport("get_abi_version") {
+		warnings = append(warnings, Warning{
+			Code:    WarnDeprecated,
+			Message: "plugin does not export get_abi_version; unversioned plugins are deprecated",
+		})
+	}
+
+	if pages, maxPages, ok := p.memoryPages(); ok && maxPages > 0 {
+		if float64(pages) >= nearLimitThreshold*float64(maxPages) {
+			warnings = append(warnings, Warning{
+				Code: WarnNearResourceLimit,
+				Message: fmt.Sprintf("linear memory at %d of %d pages (%.0f%%)",
+					pages, maxPages, 100*float64(pages)/float64(maxPages)),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// HasExport reports whether the plugin exports a function with the given name.
+func (p *Plugin) HasExport(name string) bool {
+	if p.vm == nil {
+		return false
+	}
+	module := p.vm.GetActiveModule()
+	return module != nil && module.FindFunction(name) != nil
+}
+
+// memoryPages returns the current and maximum page counts of the plugin's
+// exported linear memory. ok is false if the memory is not exported.
+func (p *Plugin) memoryPages() (pages, maxPages uint, ok bool) {
+	module := p.vm.GetActiveModule()
+	if module == nil {
+		return 0, 0, false
+	}
+	mem := module.FindMemory("memory")
+	if mem == nil {
+		return 0, 0, false
+	}
+
+	maxPages = maxWasm32Pages
+	if limit := mem.GetMemoryType().GetLimit(); limit != nil && limit.HasMax() {
+		maxPages = limit.GetMax()
+	}
+	if p.memoryLimit > 0 && p.memoryLimit < maxPages {
+		maxPages = p.memoryLimit
+	}
+	return mem.GetPageSize(), maxPages, true
+}
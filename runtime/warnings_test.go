@@ -0,0 +1,58 @@
+package runtime_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime"
+)
+
+var _ = Describe("Diagnose", func() {
+	var plugin *runtime.Plugin
+
+	BeforeEach(func() {
+		validPluginPath := filepath.Join("..", "plugins", "hello", "hello.wasm")
+		if _, err := os.Stat(validPluginPath); os.IsNotExist(err) {
+			Skip("Test plugin not found: " + validPluginPath)
+		}
+
+		var err error
+		plugin, err = runtime.LoadPlugin(validPluginPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugin.Init()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		plugin.Close()
+	})
+
+	// =========================================================================
+	// TEST: Deprecation warning for unversioned plugins
+	// Why: The hello plugin predates get_abi_version; callers must learn about
+	//      it from the response instead of from server logs.
+	// =========================================================================
+	It("should warn that unversioned plugins are deprecated", func() {
+		_, err := plugin.Execute(21)
+		Expect(err).NotTo(HaveOccurred())
+
+		warnings := plugin.Diagnose()
+
+		Expect(warnings).To(ContainElement(HaveField("Code", runtime.WarnDeprecated)))
+		Expect(warnings).NotTo(ContainElement(HaveField("Code", runtime.WarnNearResourceLimit)))
+	})
+
+	It("should report exports", func() {
+		Expect(plugin.HasExport("process")).To(BeTrue())
+		Expect(plugin.HasExport("does_not_exist")).To(BeFalse())
+	})
+
+	It("should return nothing for a closed plugin", func() {
+		plugin.Close()
+
+		Expect(plugin.Diagnose()).To(BeEmpty())
+		Expect(plugin.HasExport("process")).To(BeFalse())
+	})
+})
@@ -0,0 +1,12 @@
+package runtime
+
+import (
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// WASIOptions configures the WASI environment plugins run in; see
+// engine.WASIOptions. The zero value is the sandbox.
+//
+// WasmEdge connects a plugin's stdin, stdout, and stderr to the host
+// process's, unless it is loaded with LoadOptions.CaptureOutput.
+type WASIOptions = engine.WASIOptions
@@ -0,0 +1,357 @@
+//go:build wasmtime
+
+// Package wasmtime runs plugins on Wasmtime through wasmtime-go. It is
+// built with the wasmtime tag only, since wasmtime-go links a static
+// Wasmtime library with cgo that builds without it do not need:
+//
+//	go build -tags wasmtime ./cmd/server
+//
+// Importing it registers Engine, e.g. so the server can run or shadow
+// executions on it. Like runtime/wazero, it only imports runtime/engine.
+//
+// Instances follow the plugin ABI as *runtime.Plugin does, which the
+// runtime/enginetest specs check. Unlike WasmEdge, Wasmtime gives plugins
+// no scratch directory, writes their standard output and error to those of
+// the host instead of capturing them, and does not enforce
+// LoadOptions.MaxInstructions. A call interrupted because its context is
+// done traps; the instance should not be reused.
+package wasmtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"strings"
+	"sync"
+
+	"github.com/bytecodealliance/wasmtime-go/v39"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// Engine loads plugins on Wasmtime.
+var Engine engine.Engine = wasmtimeEngine{}
+
+func init() {
+	engine.Register(Engine)
+}
+
+// compiled keeps the modules Wasmtime compiled, serialized by the SHA-256
+// of their bytes, so loading a plugin again, e.g. into a pool, does not
+// compile it again.
+var compiled sync.Map // [sha256.Size]byte -> []byte
+
+// wasmtimeEngine loads plugins into a Wasmtime engine of their own, since
+// a call is interrupted by incrementing the epoch of its engine, which
+// would interrupt every other call on it too.
+type wasmtimeEngine struct{}
+
+func (wasmtimeEngine) Name() string { return "wasmtime" }
+
+func (wasmtimeEngine) Load(path string, opts engine.LoadOptions) (engine.Instance, error) {
+	load, err := engine.PrepareGuestLoad(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	}
+
+	config := wasmtime.NewConfig()
+	config.SetEpochInterruption(true)
+	g := &guest{engine: wasmtime.NewEngineWithConfig(config), ctx: context.Background()}
+	g.store = wasmtime.NewStore(g.engine)
+	if err := g.instantiate(load, wasm, opts.HostModules); err != nil {
+		g.Close()
+		return nil, err
+	}
+	return engine.NewGuestInstance(load, g)
+}
+
+// compile compiles wasm for e, or deserializes it if it was compiled
+// before.
+func compile(e *wasmtime.Engine, path string, wasm []byte) (*wasmtime.Module, error) {
+	key := sha256.Sum256(wasm)
+	if artifact, ok := compiled.Load(key); ok {
+		if module, err := wasmtime.NewModuleDeserialize(e, artifact.([]byte)); err == nil {
+			return module, nil
+		}
+	}
+	module, err := wasmtime.NewModule(e, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("WASM module validation failed for %s: %w", path, err)
+	}
+	if artifact, err := module.Serialize(); err == nil {
+		compiled.Store(key, artifact)
+	}
+	return module, nil
+}
+
+// guest is a plugin instantiated in a store of its own.
+type guest struct {
+	engine   *wasmtime.Engine
+	store    *wasmtime.Store
+	instance *wasmtime.Instance
+	memory   *wasmtime.Memory // nil if the plugin has none
+
+	// ctx is the context of the running call, for host functions, and
+	// hostErr the error of the host function that trapped it, since a
+	// trap only carries a message
+	ctx     context.Context
+	hostErr error
+}
+
+// instantiate instantiates the plugin of load in g's store, after WASI and
+// the host modules it may import.
+func (g *guest) instantiate(load *engine.GuestLoad, wasm []byte, hostModules []*engine.HostModule) error {
+	wasi := wasmtime.NewWasiConfig()
+	wasi.SetArgv(load.Args)
+	names := make([]string, len(load.Env))
+	values := make([]string, len(load.Env))
+	for i, env := range load.Env {
+		names[i], values[i], _ = strings.Cut(env, "=")
+	}
+	wasi.SetEnv(names, values)
+	for guest, host := range load.Dirs {
+		err := wasi.PreopenDir(host, guest, wasmtime.DIR_READ|wasmtime.DIR_WRITE, wasmtime.FILE_READ|wasmtime.FILE_WRITE)
+		if err != nil {
+			return fmt.Errorf("failed to preopen %s as %s for %s: %w", host, guest, load.Path, err)
+		}
+	}
+	wasi.InheritStdout()
+	wasi.InheritStderr()
+	g.store.SetWasi(wasi)
+	if load.MemoryLimit > 0 {
+		g.store.Limiter(int64(load.MemoryLimit)*65536, -1, -1, -1, -1)
+	}
+
+	linker := wasmtime.NewLinker(g.engine)
+	defer linker.Close()
+	if err := linker.DefineWasi(); err != nil {
+		return fmt.Errorf("failed to instantiate WASI for %s: %w", load.Path, err)
+	}
+	seen := make(map[string]bool, len(hostModules))
+	for _, m := range hostModules {
+		if seen[m.Name()] {
+			return fmt.Errorf("host module %s is registered twice", m.Name())
+		}
+		seen[m.Name()] = true
+		if err := g.defineHostModule(linker, load, m); err != nil {
+			return err
+		}
+	}
+
+	module, err := compile(g.engine, load.Path, wasm)
+	if err != nil {
+		return err
+	}
+	defer module.Close()
+	// Only the module's start function runs; plugins are driven through
+	// init(), as on WasmEdge
+	instance, err := linker.Instantiate(g.store, module)
+	if err != nil {
+		return fmt.Errorf("WASM module instantiation failed for %s: %w", load.Path, err)
+	}
+	g.instance = instance
+	if export := instance.GetExport(g.store, "memory"); export != nil {
+		g.memory = export.Memory()
+	}
+	return nil
+}
+
+// defineHostModule defines the functions of m in linker for the plugin of
+// load.
+func (g *guest) defineHostModule(linker *wasmtime.Linker, load *engine.GuestLoad, m *engine.HostModule) error {
+	functions, err := m.GuestFunctions(load.Path, load.Manifest)
+	if err != nil {
+		return err
+	}
+	for _, f := range functions {
+		ty := wasmtime.NewFuncType(valTypes(f.Params), valTypes(f.Results))
+		err := linker.FuncNew(m.Name(), f.Name, ty, func(caller *wasmtime.Caller, args []wasmtime.Val) ([]wasmtime.Val, *wasmtime.Trap) {
+			var memory engine.GuestMemory
+			if export := caller.GetExport("memory"); export != nil && export.Memory() != nil {
+				memory = guestMemory{store: caller, memory: export.Memory()}
+			}
+			out, err := f.Call(g.ctx, memory, decode(args))
+			if err != nil {
+				g.hostErr = err
+				return nil, wasmtime.NewTrap(err.Error())
+			}
+			return encode(out), nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register host module %s for %s: %w", m.Name(), load.Path, err)
+		}
+	}
+	return nil
+}
+
+// valTypes returns the Wasmtime types of host function values.
+func valTypes(types []engine.ValueType) []*wasmtime.ValType {
+	out := make([]*wasmtime.ValType, len(types))
+	for i, t := range types {
+		switch t {
+		case engine.ValueI32:
+			out[i] = wasmtime.NewValType(wasmtime.KindI32)
+		case engine.ValueI64:
+			out[i] = wasmtime.NewValType(wasmtime.KindI64)
+		case engine.ValueF32:
+			out[i] = wasmtime.NewValType(wasmtime.KindF32)
+		case engine.ValueF64:
+			out[i] = wasmtime.NewValType(wasmtime.KindF64)
+		}
+	}
+	return out
+}
+
+// decode converts values to int32, int64, float32, or float64.
+func decode(values []wasmtime.Val) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v.Get()
+	}
+	return out
+}
+
+// encode converts values, of the Go types decode returns, to Wasmtime
+// values.
+func encode(values []interface{}) []wasmtime.Val {
+	out := make([]wasmtime.Val, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case int32:
+			out[i] = wasmtime.ValI32(v)
+		case int64:
+			out[i] = wasmtime.ValI64(v)
+		case float32:
+			out[i] = wasmtime.ValF32(v)
+		case float64:
+			out[i] = wasmtime.ValF64(v)
+		}
+	}
+	return out
+}
+
+func (g *guest) Call(ctx context.Context, name string, params ...interface{}) ([]interface{}, error) {
+	fn := g.instance.GetFunc(g.store, name)
+	if fn == nil {
+		return nil, fmt.Errorf("function %s is not exported", name)
+	}
+	types := fn.Type(g.store).Params()
+	if len(params) != len(types) {
+		return nil, fmt.Errorf("%s takes %d parameters, got %d", name, len(types), len(params))
+	}
+	for i, param := range params {
+		if !matches(param, types[i].Kind()) {
+			return nil, fmt.Errorf("parameter %d of %s is %s, got %T", i+1, name, types[i].Kind(), param)
+		}
+	}
+
+	// The call traps once the epoch is incremented past its deadline
+	g.store.SetEpochDeadline(1)
+	stop := context.AfterFunc(ctx, g.engine.IncrementEpoch)
+	defer stop()
+	g.ctx, g.hostErr = ctx, nil
+	defer func() { g.ctx = context.Background() }()
+
+	result, err := fn.Call(g.store, params...)
+	if err != nil {
+		if g.hostErr != nil {
+			return nil, g.hostErr
+		}
+		return nil, err
+	}
+	// Call returns nothing, a single value, or all of them
+	switch r := result.(type) {
+	case nil:
+		return nil, nil
+	case []wasmtime.Val:
+		return decode(r), nil
+	default:
+		return []interface{}{r}, nil
+	}
+}
+
+// matches reports whether value has the Go type of kind.
+func matches(value interface{}, kind wasmtime.ValKind) bool {
+	switch value.(type) {
+	case int32:
+		return kind == wasmtime.KindI32
+	case int64:
+		return kind == wasmtime.KindI64
+	case float32:
+		return kind == wasmtime.KindF32
+	case float64:
+		return kind == wasmtime.KindF64
+	}
+	return false
+}
+
+func (g *guest) HasExport(name string) bool {
+	return g.instance.GetFunc(g.store, name) != nil
+}
+
+func (g *guest) Read(ptr, size uint32) ([]byte, error) {
+	return guestMemory{g.store, g.memory}.Read(ptr, size)
+}
+
+func (g *guest) Write(ptr uint32, data []byte) error {
+	return guestMemory{g.store, g.memory}.Write(ptr, data)
+}
+
+func (g *guest) Pages() (uint, bool) {
+	return guestMemory{g.store, g.memory}.Pages()
+}
+
+func (g *guest) Close() {
+	g.store.Close()
+	g.engine.Close()
+}
+
+// errNoMemory is returned for memory access to plugins without memory.
+var errNoMemory = errors.New("plugin has no linear memory")
+
+// guestMemory is the linear memory of a plugin in store, the plugin's
+// store or the caller of a host function.
+type guestMemory struct {
+	store  wasmtime.Storelike
+	memory *wasmtime.Memory // nil if the plugin has none
+}
+
+func (m guestMemory) Read(ptr, size uint32) ([]byte, error) {
+	if m.memory == nil {
+		return nil, errNoMemory
+	}
+	data := m.memory.UnsafeData(m.store)
+	defer goruntime.KeepAlive(m.store)
+	if uint64(ptr)+uint64(size) > uint64(len(data)) {
+		return nil, fmt.Errorf("%d bytes at %#x are out of bounds", size, ptr)
+	}
+	// UnsafeData aliases guest memory; copy before the guest can change it
+	return append([]byte(nil), data[ptr:ptr+size]...), nil
+}
+
+func (m guestMemory) Write(ptr uint32, data []byte) error {
+	if m.memory == nil {
+		return errNoMemory
+	}
+	memory := m.memory.UnsafeData(m.store)
+	defer goruntime.KeepAlive(m.store)
+	if uint64(ptr)+uint64(len(data)) > uint64(len(memory)) {
+		return fmt.Errorf("%d bytes at %#x are out of bounds", len(data), ptr)
+	}
+	copy(memory[ptr:], data)
+	return nil
+}
+
+func (m guestMemory) Pages() (uint, bool) {
+	if m.memory == nil {
+		return 0, false
+	}
+	return uint(m.memory.Size(m.store)), true
+}
@@ -0,0 +1,24 @@
+//go:build wasmtime
+
+package wasmtime_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/enginetest"
+	"github.com/mrhapile/wasm-plugin-system/runtime/wasmtime"
+)
+
+// TestWasmtime bootstraps the Ginkgo test suite checking the Wasmtime
+// engine against the conformance specs.
+// Run with: go test -tags wasmtime -v ./runtime/wasmtime/...
+func TestWasmtime(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wasmtime Engine Suite")
+}
+
+var _ = enginetest.Describe(wasmtime.Engine, filepath.Join("..", "..", "plugins"))
@@ -0,0 +1,304 @@
+// Package wazero runs plugins on wazero, a WebAssembly runtime written in
+// Go. It only imports runtime/engine, not the runtime package, so it
+// builds with CGO_ENABLED=0 and without the WasmEdge shared library.
+// Importing it registers Engine, e.g. so the server can run or shadow
+// executions on it:
+//
+//	import _ "github.com/mrhapile/wasm-plugin-system/runtime/wazero"
+//
+// Instances follow the plugin ABI as *runtime.Plugin does, which the
+// runtime/enginetest specs check. Unlike WasmEdge, wazero gives plugins no
+// scratch directory, writes their standard output and error to those of
+// the host instead of capturing them, and does not enforce
+// LoadOptions.MaxInstructions. A call interrupted because its context is
+// done closes the instance; later calls fail.
+package wazero
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/engine"
+)
+
+// Engine loads plugins on wazero.
+var Engine engine.Engine = wazeroEngine{}
+
+func init() {
+	engine.Register(Engine)
+}
+
+// cache keeps the modules wazero compiled, so loading a plugin again, e.g.
+// into a pool, does not compile it again.
+var cache = wazero.NewCompilationCache()
+
+// wazeroEngine loads plugins into a wazero runtime of their own.
+type wazeroEngine struct{}
+
+func (wazeroEngine) Name() string { return "wazero" }
+
+func (wazeroEngine) Load(path string, opts engine.LoadOptions) (engine.Instance, error) {
+	load, err := engine.PrepareGuestLoad(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load WASM file %s: %w", path, err)
+	}
+
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().
+		WithCompilationCache(cache).
+		WithCloseOnContextDone(true)
+	if load.MemoryLimit > 0 {
+		config = config.WithMemoryLimitPages(uint32(min(load.MemoryLimit, math.MaxUint16+1)))
+	}
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	module, err := instantiate(ctx, r, load, wasm, opts.HostModules)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	return engine.NewGuestInstance(load, &guest{runtime: r, module: module})
+}
+
+// instantiate instantiates the plugin of load in r, after WASI and the
+// host modules it may import.
+func instantiate(ctx context.Context, r wazero.Runtime, load *engine.GuestLoad, wasm []byte, hostModules []*engine.HostModule) (api.Module, error) {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		return nil, fmt.Errorf("failed to instantiate WASI for %s: %w", load.Path, err)
+	}
+	seen := make(map[string]bool, len(hostModules))
+	for _, m := range hostModules {
+		if seen[m.Name()] {
+			return nil, fmt.Errorf("host module %s is registered twice", m.Name())
+		}
+		seen[m.Name()] = true
+		if err := instantiateHostModule(ctx, r, load, m); err != nil {
+			return nil, err
+		}
+	}
+
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("WASM module validation failed for %s: %w", load.Path, err)
+	}
+	fs := wazero.NewFSConfig()
+	for guest, host := range load.Dirs {
+		fs = fs.WithDirMount(host, guest)
+	}
+	config := wazero.NewModuleConfig().
+		WithArgs(load.Args...).
+		WithFSConfig(fs).
+		WithStdout(os.Stdout).
+		WithStderr(os.Stderr).
+		WithRandSource(rand.Reader).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithStartFunctions() // Plugins are driven through init(), as on WasmEdge
+	for _, env := range load.Env {
+		name, value, _ := strings.Cut(env, "=")
+		config = config.WithEnv(name, value)
+	}
+	module, err := r.InstantiateModule(ctx, compiled, config)
+	if err != nil {
+		return nil, fmt.Errorf("WASM module instantiation failed for %s: %w", load.Path, err)
+	}
+	return module, nil
+}
+
+// instantiateHostModule defines the functions of m in r for the plugin of
+// load.
+func instantiateHostModule(ctx context.Context, r wazero.Runtime, load *engine.GuestLoad, m *engine.HostModule) error {
+	functions, err := m.GuestFunctions(load.Path, load.Manifest)
+	if err != nil {
+		return err
+	}
+	builder := r.NewHostModuleBuilder(m.Name())
+	for _, f := range functions {
+		params, results := valueTypes(f.Params), valueTypes(f.Results)
+		builder.NewFunctionBuilder().
+			WithGoModuleFunction(api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+				var memory engine.GuestMemory
+				if mem := mod.Memory(); mem != nil {
+					memory = guestMemory{mem}
+				}
+				out, err := f.Call(ctx, memory, decode(stack, params))
+				if err != nil {
+					// wazero turns the panic into the error of the call
+					panic(err)
+				}
+				encode(stack, results, out)
+			}), params, results).
+			Export(f.Name)
+	}
+	if _, err := builder.Instantiate(ctx); err != nil {
+		return fmt.Errorf("failed to register host module %s for %s: %w", m.Name(), load.Path, err)
+	}
+	return nil
+}
+
+// valueTypes returns the wazero types of host function values.
+func valueTypes(types []engine.ValueType) []api.ValueType {
+	out := make([]api.ValueType, len(types))
+	for i, t := range types {
+		switch t {
+		case engine.ValueI32:
+			out[i] = api.ValueTypeI32
+		case engine.ValueI64:
+			out[i] = api.ValueTypeI64
+		case engine.ValueF32:
+			out[i] = api.ValueTypeF32
+		case engine.ValueF64:
+			out[i] = api.ValueTypeF64
+		}
+	}
+	return out
+}
+
+// decode converts the first len(types) values of stack to int32, int64,
+// float32, or float64.
+func decode(stack []uint64, types []api.ValueType) []interface{} {
+	values := make([]interface{}, len(types))
+	for i, t := range types {
+		switch t {
+		case api.ValueTypeI32:
+			values[i] = api.DecodeI32(stack[i])
+		case api.ValueTypeI64:
+			values[i] = int64(stack[i])
+		case api.ValueTypeF32:
+			values[i] = api.DecodeF32(stack[i])
+		case api.ValueTypeF64:
+			values[i] = api.DecodeF64(stack[i])
+		}
+	}
+	return values
+}
+
+// encode stores values, of the Go types decode returns, on stack.
+func encode(stack []uint64, types []api.ValueType, values []interface{}) {
+	for i := range types {
+		switch v := values[i].(type) {
+		case int32:
+			stack[i] = api.EncodeI32(v)
+		case int64:
+			stack[i] = api.EncodeI64(v)
+		case float32:
+			stack[i] = api.EncodeF32(v)
+		case float64:
+			stack[i] = api.EncodeF64(v)
+		}
+	}
+}
+
+// guest is a plugin instantiated in a runtime of its own.
+type guest struct {
+	runtime wazero.Runtime
+	module  api.Module
+}
+
+func (g *guest) Call(ctx context.Context, name string, params ...interface{}) ([]interface{}, error) {
+	fn := g.module.ExportedFunction(name)
+	if fn == nil {
+		return nil, fmt.Errorf("function %s is not exported", name)
+	}
+	definition := fn.Definition()
+	types := definition.ParamTypes()
+	if len(params) != len(types) {
+		return nil, fmt.Errorf("%s takes %d parameters, got %d", name, len(types), len(params))
+	}
+	stack := make([]uint64, max(len(types), len(definition.ResultTypes())))
+	for i, param := range params {
+		if !matches(param, types[i]) {
+			return nil, fmt.Errorf("parameter %d of %s is %s, got %T", i+1, name, api.ValueTypeName(types[i]), param)
+		}
+	}
+	encode(stack, types, params)
+	if err := fn.CallWithStack(ctx, stack); err != nil {
+		return nil, err
+	}
+	return decode(stack, definition.ResultTypes()), nil
+}
+
+// matches reports whether value has the Go type of t.
+func matches(value interface{}, t api.ValueType) bool {
+	switch value.(type) {
+	case int32:
+		return t == api.ValueTypeI32
+	case int64:
+		return t == api.ValueTypeI64
+	case float32:
+		return t == api.ValueTypeF32
+	case float64:
+		return t == api.ValueTypeF64
+	}
+	return false
+}
+
+func (g *guest) HasExport(name string) bool {
+	return g.module.ExportedFunction(name) != nil
+}
+
+func (g *guest) Read(ptr, size uint32) ([]byte, error) {
+	return guestMemory{g.module.Memory()}.Read(ptr, size)
+}
+
+func (g *guest) Write(ptr uint32, data []byte) error {
+	return guestMemory{g.module.Memory()}.Write(ptr, data)
+}
+
+func (g *guest) Pages() (uint, bool) {
+	return guestMemory{g.module.Memory()}.Pages()
+}
+
+func (g *guest) Close() {
+	g.runtime.Close(context.Background())
+}
+
+// errNoMemory is returned for memory access to plugins without memory.
+var errNoMemory = errors.New("plugin has no linear memory")
+
+// guestMemory is the linear memory of a plugin.
+type guestMemory struct {
+	memory api.Memory // nil if the plugin has none
+}
+
+func (m guestMemory) Read(ptr, size uint32) ([]byte, error) {
+	if m.memory == nil {
+		return nil, errNoMemory
+	}
+	data, ok := m.memory.Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("%d bytes at %#x are out of bounds", size, ptr)
+	}
+	// Read aliases guest memory; copy before the guest can change it
+	return append([]byte(nil), data...), nil
+}
+
+func (m guestMemory) Write(ptr uint32, data []byte) error {
+	if m.memory == nil {
+		return errNoMemory
+	}
+	if !m.memory.Write(ptr, data) {
+		return fmt.Errorf("%d bytes at %#x are out of bounds", len(data), ptr)
+	}
+	return nil
+}
+
+func (m guestMemory) Pages() (uint, bool) {
+	if m.memory == nil {
+		return 0, false
+	}
+	return uint(m.memory.Size() / 65536), true
+}
@@ -0,0 +1,22 @@
+package wazero_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/runtime/enginetest"
+	"github.com/mrhapile/wasm-plugin-system/runtime/wazero"
+)
+
+// TestWazero bootstraps the Ginkgo test suite checking the wazero engine
+// against the conformance specs.
+// Run with: go test -v ./runtime/wazero/...
+func TestWazero(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wazero Engine Suite")
+}
+
+var _ = enginetest.Describe(wazero.Engine, filepath.Join("..", "..", "plugins"))
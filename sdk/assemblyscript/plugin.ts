@@ -0,0 +1,270 @@
+// plugin.ts - Plugin ABI and host bindings for AssemblyScript
+//
+// Declares the ABI constants and every host function the server registers
+// (see ABI.md), with helpers to read payloads and return results. The
+// entry file re-exports the exports of the payload ABI:
+//
+//   export { allocate, deallocate, last_error } from "./plugin";
+//
+// and is compiled with asconfig.json's "use": ["abort=assembly/plugin/pluginAbort"],
+// since the server provides no env.abort import.
+
+// ============================================================================
+// ABI
+// ============================================================================
+
+// Version returned by get_abi_version(): MAJOR * 10000 + MINOR * 100 + PATCH
+export const PLUGIN_ABI_VERSION: i32 = 10000;
+
+export const ABI_SUCCESS: i32 = 0;
+export const ABI_ERROR_NOT_INITIALIZED: i32 = -1;
+export const ABI_ERROR_ALREADY_INITIALIZED: i32 = -2;
+export const ABI_ERROR_INVALID_INPUT: i32 = -3;
+export const ABI_ERROR_INTERNAL: i32 = -4;
+export const ABI_NO_OUTPUT: i32 = -5; // Not an error: the call has no result
+
+// Buffers for the host come from the runtime's heap rather than the
+// garbage collector, so they stay put until the host frees them.
+export function allocate(size: i32): i32 {
+  if (size < 0) {
+    return 0;
+  }
+  return <i32>heap.alloc(<usize>size);
+}
+
+export function deallocate(ptr: i32, size: i32): void {
+  if (ptr != 0) {
+    heap.free(<usize>ptr);
+  }
+}
+
+// Returns a copy of the payload the host passed to process_bytes().
+export function readBytes(ptr: i32, len: i32): Uint8Array {
+  const data = new Uint8Array(len);
+  memory.copy(data.dataStart, <usize>ptr, <usize>len);
+  return data;
+}
+
+// Decodes a UTF-8 payload, e.g. the input of process_json() or the config
+// passed to init_with_config().
+export function readString(ptr: i32, len: i32): string {
+  return String.UTF8.decodeUnsafe(<usize>ptr, <usize>len);
+}
+
+// Copies data into a buffer from allocate() and packs it as the return
+// value of process_bytes() and process_json(), or returns
+// ABI_ERROR_INTERNAL if the heap is exhausted.
+export function output(data: Uint8Array): i64 {
+  const ptr = allocate(data.length);
+  if (ptr == 0 && data.length > 0) {
+    return ABI_ERROR_INTERNAL;
+  }
+  memory.copy(<usize>ptr, data.dataStart, <usize>data.length);
+  return (<i64>ptr << 32) | <i64>data.length;
+}
+
+// Returns s, encoded as UTF-8, like output().
+export function outputString(s: string): i64 {
+  return output(Uint8Array.wrap(String.UTF8.encode(s)));
+}
+
+let errorMessage: ArrayBuffer | null = null;
+
+// Sets the message last_error() reports for the next error code returned.
+export function setError(message: string): void {
+  errorMessage = String.UTF8.encode(message);
+}
+
+// () -> i64: (msg_ptr << 32) | msg_len, or 0 if there is no message
+export function last_error(): i64 {
+  const message = errorMessage;
+  if (message == null) {
+    return 0;
+  }
+  return (<i64>changetype<usize>(message) << 32) | <i64>message.byteLength;
+}
+
+// Logs the failed assertion and traps, in place of the env.abort import.
+export function pluginAbort(message: string | null, fileName: string | null, line: u32, column: u32): void {
+  log(LOG_ERROR, "abort: " + (message ? message : "") + " at " +
+    (fileName ? fileName : "") + ":" + line.toString() + ":" + column.toString());
+  unreachable();
+}
+
+// ============================================================================
+// Logging (module "logging")
+// ============================================================================
+
+export const LOG_DEBUG: i32 = 0;
+export const LOG_INFO: i32 = 1;
+export const LOG_WARN: i32 = 2;
+export const LOG_ERROR: i32 = 3;
+
+// @ts-ignore: decorator
+@external("logging", "log")
+export declare function host_log(level: i32, msg: usize, len: i32): void;
+
+export function log(level: i32, message: string): void {
+  const data = String.UTF8.encode(message);
+  host_log(level, changetype<usize>(data), data.byteLength);
+}
+
+// ============================================================================
+// Outbox (module "outbox")
+// ============================================================================
+
+export const EFFECT_HTTP: i32 = 0; // target is a URL the payload is POSTed to
+export const EFFECT_MESSAGE: i32 = 1; // target is a topic the payload is published to
+
+export const OUTBOX_OK: i32 = 0;
+export const OUTBOX_UNAVAILABLE: i32 = -1; // The host does not accept effects
+export const OUTBOX_FULL: i32 = -2; // 100 effects per call, or target over 2 KiB, or payload over 1 MiB
+export const OUTBOX_REJECTED: i32 = -3; // Unknown kind, or target refused by the host
+
+// @ts-ignore: decorator
+@external("outbox", "enqueue")
+export declare function outbox_enqueue(kind: i32, target: usize, target_len: i32, payload: usize, payload_len: i32): i32;
+
+// ============================================================================
+// Execution context, metrics, and progress (module "host")
+// ============================================================================
+
+// Writes the call's context as a JSON object to buf and returns its length.
+// Nothing is written if the length exceeds cap.
+// @ts-ignore: decorator
+@external("host", "context")
+export declare function host_context(buf: usize, cap: i32): i32;
+
+export const METRIC_OK: i32 = 0;
+export const METRIC_INVALID: i32 = -1; // Invalid name, tags, or value, or the name is used by the other function
+export const METRIC_LIMITED: i32 = -2; // The plugin already has 1000 series
+
+// Adds value (finite, not negative) to a counter.
+// @ts-ignore: decorator
+@external("host", "metric_incr")
+export declare function metric_incr(name: usize, name_len: i32, value: f64, tags: usize, tags_len: i32): i32;
+
+// Records value (finite) in a histogram.
+// @ts-ignore: decorator
+@external("host", "metric_observe")
+export declare function metric_observe(name: usize, name_len: i32, value: f64, tags: usize, tags_len: i32): i32;
+
+// Reports how much of the call's work is done, in percent (clamped to 0..100).
+// @ts-ignore: decorator
+@external("host", "report_progress")
+export declare function report_progress(percent: i32): void;
+
+// ============================================================================
+// Cache, object storage, and publishing (module "host")
+// ============================================================================
+
+export const CACHE_OK: i32 = 0;
+export const CACHE_UNAVAILABLE: i32 = -1; // The host has no cache
+export const CACHE_MISS: i32 = -2; // No live entry at the key
+export const CACHE_INVALID: i32 = -3; // Empty key or over 512 bytes, or ttl_ms not positive
+export const CACHE_TOO_LARGE: i32 = -4; // The value is over the value limit or the plugin's quota
+export const CACHE_FAILED: i32 = -5; // The cache store returned an error
+
+// Writes the value cached at key to buf and returns its length.
+// Nothing is written if the length exceeds cap.
+// @ts-ignore: decorator
+@external("host", "cache_get")
+export declare function cache_get(key: usize, key_len: i32, buf: usize, cap: i32): i32;
+
+// Caches value at key for ttl_ms milliseconds.
+// @ts-ignore: decorator
+@external("host", "cache_set")
+export declare function cache_set(key: usize, key_len: i32, value: usize, value_len: i32, ttl_ms: i32): i32;
+
+export const BLOB_OK: i32 = 0;
+export const BLOB_UNAVAILABLE: i32 = -1; // The host has no blob store
+export const BLOB_DENIED: i32 = -2; // Invalid key, or not under a prefix the manifest declares
+export const BLOB_NOT_FOUND: i32 = -3; // No object at the key
+export const BLOB_TOO_LARGE: i32 = -4; // Over the manifest's or the host's size cap
+export const BLOB_FAILED: i32 = -5; // The object store returned an error
+
+// Writes the object at key to buf and returns its size.
+// Nothing is written if the size exceeds cap.
+// @ts-ignore: decorator
+@external("host", "blob_get")
+export declare function blob_get(key: usize, key_len: i32, buf: usize, cap: i32): i32;
+
+// Stores data at key, replacing any object there.
+// @ts-ignore: decorator
+@external("host", "blob_put")
+export declare function blob_put(key: usize, key_len: i32, data: usize, data_len: i32): i32;
+
+export const PUBLISH_OK: i32 = 0;
+export const PUBLISH_UNAVAILABLE: i32 = -1; // The host has no broker
+export const PUBLISH_DENIED: i32 = -2; // Invalid topic, or not granted to this plugin
+export const PUBLISH_TOO_LARGE: i32 = -3; // The message is over 1 MiB
+export const PUBLISH_FAILED: i32 = -4; // The broker did not accept the message
+
+// Sends data as one message to topic, returning once the broker accepted it.
+// @ts-ignore: decorator
+@external("host", "publish")
+export declare function publish(topic: usize, topic_len: i32, data: usize, data_len: i32): i32;
+
+// ============================================================================
+// SQL (module "sql", registered when the server has SQL_CONFIG_FILE)
+// ============================================================================
+
+export const SQL_DENIED: i32 = -1; // Unknown statement, or not granted to this plugin
+export const SQL_INVALID: i32 = -2; // Arguments are not a flat JSON array, or wrong function for the statement
+export const SQL_FAILED: i32 = -3; // The database returned an error or timed out
+export const SQL_TOO_LARGE: i32 = -4; // More than 1000 rows, or a result over 1 MiB
+
+// Runs a query statement and writes its rows to buf as a JSON object.
+// Returns the object's length, writing nothing if it exceeds cap.
+// @ts-ignore: decorator
+@external("sql", "query")
+export declare function sql_query(stmt: usize, stmt_len: i32, args: usize, args_len: i32, buf: usize, cap: i32): i32;
+
+// Runs an exec statement and returns the number of rows affected.
+// @ts-ignore: decorator
+@external("sql", "exec")
+export declare function sql_exec(stmt: usize, stmt_len: i32, args: usize, args_len: i32): i64;
+
+// ============================================================================
+// Standard library (module "std")
+// ============================================================================
+
+export const STD_INVALID: i32 = -1; // Invalid pattern, path, algorithm, or input
+export const STD_NOT_FOUND: i32 = -2; // json_path() selected nothing
+export const STD_TOO_LARGE: i32 = -3; // The output is over 16 MiB
+
+// Returns 1 if the RE2 pattern matches input, 0 if not.
+// @ts-ignore: decorator
+@external("std", "regex_match")
+export declare function regex_match(pattern: usize, pattern_len: i32, input: usize, input_len: i32): i32;
+
+// Replaces every match of pattern in input; repl may use $1 or ${name}.
+// @ts-ignore: decorator
+@external("std", "regex_replace")
+export declare function regex_replace(pattern: usize, pattern_len: i32, input: usize, input_len: i32, repl: usize, repl_len: i32, buf: usize, cap: i32): i32;
+
+// Writes the value path selects in the JSON document, as JSON.
+// @ts-ignore: decorator
+@external("std", "json_path")
+export declare function json_path(doc: usize, doc_len: i32, path: usize, path_len: i32, buf: usize, cap: i32): i32;
+
+// @ts-ignore: decorator
+@external("std", "gzip")
+export declare function std_gzip(data: usize, len: i32, buf: usize, cap: i32): i32;
+
+// @ts-ignore: decorator
+@external("std", "gunzip")
+export declare function std_gunzip(data: usize, len: i32, buf: usize, cap: i32): i32;
+
+// @ts-ignore: decorator
+@external("std", "base64_encode")
+export declare function base64_encode(data: usize, len: i32, buf: usize, cap: i32): i32;
+
+// @ts-ignore: decorator
+@external("std", "base64_decode")
+export declare function base64_decode(data: usize, len: i32, buf: usize, cap: i32): i32;
+
+// Writes the hex digest of data; alg is sha256, sha512, sha1, md5, or crc32.
+// @ts-ignore: decorator
+@external("std", "hash")
+export declare function std_hash(alg: usize, alg_len: i32, data: usize, len: i32, buf: usize, cap: i32): i32;
@@ -0,0 +1,291 @@
+// plugin.h - Plugin ABI and host bindings for C and C++
+//
+// Declares the ABI constants and every host function the server registers
+// (see ABI.md), so that a plugin does not have to copy them from the
+// example plugins. Works with clang from wasi-sdk or any clang with the
+// wasm32 target, without libc:
+//
+//   $WASI_SDK_PATH/bin/clang++ --target=wasm32-wasi -nostdlib -Wl,--no-entry
+//       -Wl,--allow-undefined -O3 -o my-plugin.wasm my-plugin.cpp
+//
+// Exports are marked with PLUGIN_EXPORT, so no -Wl,--export flags are
+// needed. In exactly one source file, define PLUGIN_IMPLEMENTATION before
+// including this header to also get the allocate() and deallocate()
+// exports of the payload ABI and the last_error() export, backed by a
+// static heap of PLUGIN_HEAP_SIZE bytes (1 MiB unless defined).
+
+#ifndef PLUGIN_H
+#define PLUGIN_H
+
+#ifdef __cplusplus
+#define PLUGIN_EXTERN_C extern "C"
+#else
+#define PLUGIN_EXTERN_C
+#endif
+
+// Exports a function under its own name, without C++ name mangling
+#define PLUGIN_EXPORT(name) PLUGIN_EXTERN_C __attribute__((export_name(#name)))
+
+// Imports a function from a host module
+#define PLUGIN_IMPORT(module, name) \
+    PLUGIN_EXTERN_C __attribute__((import_module(module), import_name(name)))
+
+// ============================================================================
+// ABI
+// ============================================================================
+
+// Version returned by get_abi_version(): MAJOR * 10000 + MINOR * 100 + PATCH
+#define PLUGIN_ABI_VERSION 10000
+
+#define ABI_SUCCESS                    0
+#define ABI_ERROR_NOT_INITIALIZED     -1
+#define ABI_ERROR_ALREADY_INITIALIZED -2
+#define ABI_ERROR_INVALID_INPUT       -3
+#define ABI_ERROR_INTERNAL            -4
+#define ABI_NO_OUTPUT                 -5  // Not an error: the call has no result
+
+// Packs a buffer from allocate() as the return value of process_bytes()
+// and process_json(): the pointer in the high 32 bits, the length in the
+// low 32 bits.
+static inline long long plugin_output(const void *ptr, int len) {
+    return ((long long)(unsigned int)(unsigned long)ptr << 32) | (unsigned int)len;
+}
+
+// Returns the length of a NUL-terminated string.
+static inline int plugin_strlen(const char *s) {
+    int n = 0;
+    while (s[n]) {
+        n++;
+    }
+    return n;
+}
+
+// Implemented with PLUGIN_IMPLEMENTATION
+PLUGIN_EXTERN_C int allocate(int size);
+PLUGIN_EXTERN_C void deallocate(int ptr, int size);
+
+// Sets the message last_error() reports for the next error code returned.
+// message must stay valid until the plugin's next call, e.g. a literal.
+PLUGIN_EXTERN_C void plugin_set_error(const char *message);
+
+// ============================================================================
+// Logging (module "logging")
+// ============================================================================
+
+#define LOG_DEBUG 0
+#define LOG_INFO  1
+#define LOG_WARN  2
+#define LOG_ERROR 3
+
+PLUGIN_IMPORT("logging", "log")
+void host_log(int level, const char *msg, int len);
+
+// Logs a NUL-terminated message.
+static inline void plugin_log(int level, const char *msg) {
+    host_log(level, msg, plugin_strlen(msg));
+}
+
+// ============================================================================
+// Outbox (module "outbox")
+// ============================================================================
+
+#define EFFECT_HTTP    0  // target is a URL the payload is POSTed to
+#define EFFECT_MESSAGE 1  // target is a topic the payload is published to
+
+#define OUTBOX_OK           0
+#define OUTBOX_UNAVAILABLE -1  // The host does not accept effects
+#define OUTBOX_FULL        -2  // 100 effects per call, or target over 2 KiB, or payload over 1 MiB
+#define OUTBOX_REJECTED    -3  // Unknown kind, or target refused by the host
+
+PLUGIN_IMPORT("outbox", "enqueue")
+int outbox_enqueue(int kind, const char *target, int target_len,
+                   const char *payload, int payload_len);
+
+// ============================================================================
+// Execution context, metrics, and progress (module "host")
+// ============================================================================
+
+// Writes the call's context as a JSON object to buf and returns its length.
+// Nothing is written if the length exceeds cap.
+PLUGIN_IMPORT("host", "context")
+int host_context(char *buf, int cap);
+
+#define METRIC_OK       0
+#define METRIC_INVALID -1  // Invalid name, tags, or value, or the name is used by the other function
+#define METRIC_LIMITED -2  // The plugin already has 1000 series
+
+// Adds value (finite, not negative) to a counter.
+PLUGIN_IMPORT("host", "metric_incr")
+int metric_incr(const char *name, int name_len, double value,
+                const char *tags, int tags_len);
+
+// Records value (finite) in a histogram.
+PLUGIN_IMPORT("host", "metric_observe")
+int metric_observe(const char *name, int name_len, double value,
+                   const char *tags, int tags_len);
+
+// Reports how much of the call's work is done, in percent (clamped to 0..100).
+PLUGIN_IMPORT("host", "report_progress")
+void report_progress(int percent);
+
+// ============================================================================
+// Cache, object storage, and publishing (module "host")
+// ============================================================================
+
+#define CACHE_OK           0
+#define CACHE_UNAVAILABLE -1  // The host has no cache
+#define CACHE_MISS        -2  // No live entry at the key
+#define CACHE_INVALID     -3  // Empty key or over 512 bytes, or ttl_ms not positive
+#define CACHE_TOO_LARGE   -4  // The value is over the value limit or the plugin's quota
+#define CACHE_FAILED      -5  // The cache store returned an error
+
+// Writes the value cached at key to buf and returns its length.
+// Nothing is written if the length exceeds cap.
+PLUGIN_IMPORT("host", "cache_get")
+int cache_get(const char *key, int key_len, char *buf, int cap);
+
+// Caches value at key for ttl_ms milliseconds.
+PLUGIN_IMPORT("host", "cache_set")
+int cache_set(const char *key, int key_len,
+              const char *value, int value_len, int ttl_ms);
+
+#define BLOB_OK           0
+#define BLOB_UNAVAILABLE -1  // The host has no blob store
+#define BLOB_DENIED      -2  // Invalid key, or not under a prefix the manifest declares
+#define BLOB_NOT_FOUND   -3  // No object at the key
+#define BLOB_TOO_LARGE   -4  // Over the manifest's or the host's size cap
+#define BLOB_FAILED      -5  // The object store returned an error
+
+// Writes the object at key to buf and returns its size.
+// Nothing is written if the size exceeds cap.
+PLUGIN_IMPORT("host", "blob_get")
+int blob_get(const char *key, int key_len, char *buf, int cap);
+
+// Stores data at key, replacing any object there.
+PLUGIN_IMPORT("host", "blob_put")
+int blob_put(const char *key, int key_len, const char *data, int data_len);
+
+#define PUBLISH_OK           0
+#define PUBLISH_UNAVAILABLE -1  // The host has no broker
+#define PUBLISH_DENIED      -2  // Invalid topic, or not granted to this plugin
+#define PUBLISH_TOO_LARGE   -3  // The message is over 1 MiB
+#define PUBLISH_FAILED      -4  // The broker did not accept the message
+
+// Sends data as one message to topic, returning once the broker accepted it.
+PLUGIN_IMPORT("host", "publish")
+int publish(const char *topic, int topic_len, const char *data, int data_len);
+
+// ============================================================================
+// SQL (module "sql", registered when the server has SQL_CONFIG_FILE)
+// ============================================================================
+
+#define SQL_DENIED    -1  // Unknown statement, or not granted to this plugin
+#define SQL_INVALID   -2  // Arguments are not a flat JSON array, or wrong function for the statement
+#define SQL_FAILED    -3  // The database returned an error or timed out
+#define SQL_TOO_LARGE -4  // More than 1000 rows, or a result over 1 MiB
+
+// Runs a query statement and writes its rows to buf as a JSON object.
+// Returns the object's length, writing nothing if it exceeds cap.
+PLUGIN_IMPORT("sql", "query")
+int sql_query(const char *stmt, int stmt_len, const char *args, int args_len,
+              char *buf, int cap);
+
+// Runs an exec statement and returns the number of rows affected.
+PLUGIN_IMPORT("sql", "exec")
+long long sql_exec(const char *stmt, int stmt_len, const char *args, int args_len);
+
+// ============================================================================
+// Standard library (module "std")
+// ============================================================================
+
+#define STD_INVALID   -1  // Invalid pattern, path, algorithm, or input
+#define STD_NOT_FOUND -2  // json_path() selected nothing
+#define STD_TOO_LARGE -3  // The output is over 16 MiB
+
+// Returns 1 if the RE2 pattern matches input, 0 if not.
+PLUGIN_IMPORT("std", "regex_match")
+int regex_match(const char *pattern, int pattern_len, const char *input, int input_len);
+
+// Replaces every match of pattern in input; repl may use $1 or ${name}.
+PLUGIN_IMPORT("std", "regex_replace")
+int regex_replace(const char *pattern, int pattern_len, const char *input, int input_len,
+                  const char *repl, int repl_len, char *buf, int cap);
+
+// Writes the value path selects in the JSON document, as JSON.
+PLUGIN_IMPORT("std", "json_path")
+int json_path(const char *doc, int doc_len, const char *path, int path_len,
+              char *buf, int cap);
+
+PLUGIN_IMPORT("std", "gzip")
+int std_gzip(const char *data, int len, char *buf, int cap);
+
+PLUGIN_IMPORT("std", "gunzip")
+int std_gunzip(const char *data, int len, char *buf, int cap);
+
+PLUGIN_IMPORT("std", "base64_encode")
+int base64_encode(const char *data, int len, char *buf, int cap);
+
+PLUGIN_IMPORT("std", "base64_decode")
+int base64_decode(const char *data, int len, char *buf, int cap);
+
+// Writes the hex digest of data; alg is sha256, sha512, sha1, md5, or crc32.
+PLUGIN_IMPORT("std", "hash")
+int std_hash(const char *alg, int alg_len, const char *data, int len, char *buf, int cap);
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+#ifdef PLUGIN_IMPLEMENTATION
+
+#ifndef PLUGIN_HEAP_SIZE
+#define PLUGIN_HEAP_SIZE (1 << 20)
+#endif
+
+// Bump allocator over a static heap. Without libc there is no malloc; the
+// host frees every buffer after each call, so the heap is rewound once no
+// allocations are outstanding.
+static unsigned char plugin_heap[PLUGIN_HEAP_SIZE];
+static unsigned int plugin_heap_top = 0;
+static unsigned int plugin_live_allocations = 0;
+
+PLUGIN_EXPORT(allocate) int allocate(int size) {
+    if (size < 0) {
+        return 0;
+    }
+    // Keep buffers 8-byte aligned so i64 and f64 slices can be read in place
+    unsigned int aligned = ((unsigned int)size + 7u) & ~7u;
+    if (aligned > PLUGIN_HEAP_SIZE - plugin_heap_top) {
+        return 0;
+    }
+    unsigned char *ptr = plugin_heap + plugin_heap_top;
+    plugin_heap_top += aligned;
+    plugin_live_allocations++;
+    return (int)(unsigned long)ptr;
+}
+
+PLUGIN_EXPORT(deallocate) void deallocate(int ptr, int size) {
+    (void)ptr;
+    (void)size;
+    if (plugin_live_allocations > 0 && --plugin_live_allocations == 0) {
+        plugin_heap_top = 0;
+    }
+}
+
+static const char *plugin_error_message = 0;
+
+PLUGIN_EXTERN_C void plugin_set_error(const char *message) {
+    plugin_error_message = message;
+}
+
+// () -> i64: (msg_ptr << 32) | msg_len, or 0 if there is no message
+PLUGIN_EXPORT(last_error) long long last_error(void) {
+    if (plugin_error_message == 0) {
+        return 0;
+    }
+    return plugin_output(plugin_error_message, plugin_strlen(plugin_error_message));
+}
+
+#endif // PLUGIN_IMPLEMENTATION
+
+#endif // PLUGIN_H
@@ -0,0 +1,287 @@
+// Command gen generates the Python SDK models from the OpenAPI spec.
+//
+// Usage (from the repository root):
+//
+//	go run ./sdk/python/gen api/openapi.yaml sdk/python/wasm_plugin_client/models.py
+//
+// Every object schema under components.schemas becomes a dataclass with
+// from_dict/to_dict helpers; every string enum becomes a str Enum. Schema
+// order in the spec is preserved so regenerated output diffs cleanly.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schema is the subset of an OpenAPI schema object the generator understands.
+type schema struct {
+	Type                 string    `yaml:"type"`
+	Nullable             bool      `yaml:"nullable"`
+	Ref                  string    `yaml:"$ref"`
+	Description          string    `yaml:"description"`
+	Enum                 []string  `yaml:"enum"`
+	Required             []string  `yaml:"required"`
+	Items                *schema   `yaml:"items"`
+	AdditionalProperties *schema   `yaml:"additionalProperties"`
+	Properties           yaml.Node `yaml:"properties"`
+	props                []property
+}
+
+// property is a named schema, kept in declaration order.
+type property struct {
+	name   string
+	schema *schema
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: gen <openapi.yaml> <models.py>")
+		os.Exit(2)
+	}
+
+	out, err := generate(os.Args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(os.Args[2], out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate reads the spec at path and returns the Python module source.
+func generate(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Components struct {
+			Schemas yaml.Node `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	schemas, err := orderedSchemas(&doc.Components.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range schemas {
+		if len(p.schema.Enum) > 0 {
+			enumSchemas[p.name] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`# Code generated by sdk/python/gen from api/openapi.yaml. DO NOT EDIT.
+"""Data models for the WASM plugin server API."""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+from enum import Enum
+from typing import Any, Dict, List, Optional, Type, TypeVar, Union
+
+_E = TypeVar("_E", bound=Enum)
+
+
+def _enum(enum_cls: Type[_E], value: Any) -> Union[_E, str]:
+    """Convert to an enum member, keeping unknown values as plain strings.
+
+    Servers may add enum values before clients are regenerated; callers
+    compare against members, which works for both since the enums are str.
+    """
+    try:
+        return enum_cls(value)
+    except ValueError:
+        return value
+`)
+
+	for _, p := range schemas {
+		buf.WriteString("\n\n")
+		switch {
+		case len(p.schema.Enum) > 0:
+			writeEnum(&buf, p.name, p.schema)
+		case p.schema.Type == "object" && len(p.schema.props) > 0:
+			writeDataclass(&buf, p.name, p.schema)
+		}
+	}
+
+	return append(bytes.TrimRight(buf.Bytes(), "\n"), '\n'), nil
+}
+
+// orderedSchemas decodes a mapping node of schemas, preserving key order
+// and resolving nested property order.
+func orderedSchemas(node *yaml.Node) ([]property, error) {
+	var result []property
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+
+		s := &schema{}
+		if err := node.Content[i+1].Decode(s); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		props, err := orderedSchemas(&s.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		s.props = props
+
+		result = append(result, property{name: name, schema: s})
+	}
+	return result, nil
+}
+
+// writeEnum emits a str Enum whose member names are the upper-cased values.
+func writeEnum(buf *bytes.Buffer, name string, s *schema) {
+	fmt.Fprintf(buf, "class %s(str, Enum):\n", name)
+	if s.Description != "" {
+		fmt.Fprintf(buf, "    %q\n\n", s.Description)
+	}
+	for _, value := range s.Enum {
+		fmt.Fprintf(buf, "    %s = %q\n", strings.ToUpper(value), value)
+	}
+}
+
+// writeDataclass emits a dataclass with from_dict/to_dict converters.
+// Required fields come first (no default), optional fields default to None.
+func writeDataclass(buf *bytes.Buffer, name string, s *schema) {
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	props := append([]property(nil), s.props...)
+	sort.SliceStable(props, func(i, j int) bool {
+		return required[props[i].name] && !required[props[j].name]
+	})
+
+	fmt.Fprintf(buf, "@dataclass\nclass %s:\n", name)
+	for _, p := range props {
+		pyType := pythonType(p.schema)
+		switch {
+		case required[p.name] && p.schema.Nullable:
+			// Always present, but may be null
+			fmt.Fprintf(buf, "    %s: Optional[%s]\n", p.name, pyType)
+		case required[p.name]:
+			fmt.Fprintf(buf, "    %s: %s\n", p.name, pyType)
+		case p.schema.Type == "array":
+			fmt.Fprintf(buf, "    %s: %s = field(default_factory=list)\n", p.name, pyType)
+		default:
+			fmt.Fprintf(buf, "    %s: Optional[%s] = None\n", p.name, pyType)
+		}
+	}
+
+	fmt.Fprintf(buf, "\n    @classmethod\n    def from_dict(cls, data: Dict[str, Any]) -> %q:\n        return cls(\n", name)
+	for _, p := range props {
+		optional := !required[p.name] || p.schema.Nullable
+		fmt.Fprintf(buf, "            %s=%s,\n", p.name, fromExpr(p.schema, fmt.Sprintf("data.get(%q)", p.name), optional))
+	}
+	buf.WriteString("        )\n")
+
+	buf.WriteString("\n    def to_dict(self) -> Dict[str, Any]:\n        result: Dict[str, Any] = {}\n")
+	for _, p := range props {
+		expr := toExpr(p.schema, "self."+p.name)
+		if p.schema.Nullable && expr != "self."+p.name {
+			expr = fmt.Sprintf("(%s if self.%s is not None else None)", expr, p.name)
+		}
+		switch {
+		case required[p.name]:
+			fmt.Fprintf(buf, "        result[%q] = %s\n", p.name, expr)
+		case p.schema.Type == "array":
+			// Empty lists are omitted like Go's omitempty
+			fmt.Fprintf(buf, "        if self.%s:\n            result[%q] = %s\n", p.name, p.name, expr)
+		default:
+			// Compare against None so zero values (e.g., input=0) are sent
+			fmt.Fprintf(buf, "        if self.%s is not None:\n            result[%q] = %s\n", p.name, p.name, expr)
+		}
+	}
+	buf.WriteString("        return result\n")
+}
+
+// refName returns the schema name referenced by a $ref, or "".
+func refName(s *schema) string {
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+// pythonType maps a schema to a Python type annotation.
+func pythonType(s *schema) string {
+	if s.Ref != "" {
+		return refName(s)
+	}
+	switch s.Type {
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "string":
+		return "str"
+	case "array":
+		return "List[" + pythonType(s.Items) + "]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "Dict[str, " + pythonType(s.AdditionalProperties) + "]"
+		}
+		return "Dict[str, Any]"
+	default:
+		return "Any"
+	}
+}
+
+// fromExpr returns an expression converting decoded JSON to the model type.
+// References to other schemas are converted via their from_dict or Enum
+// constructor; optional values stay None when absent.
+func fromExpr(s *schema, value string, optional bool) string {
+	switch {
+	case s.Ref != "":
+		conv := refName(s) + ".from_dict(%s)"
+		if isEnumRef(s) {
+			conv = "_enum(" + refName(s) + ", %s)"
+		}
+		if optional {
+			return fmt.Sprintf("("+conv+" if %s is not None else None)", value, value)
+		}
+		return fmt.Sprintf(conv, value)
+	case s.Type == "array" && s.Items != nil && s.Items.Ref != "":
+		return fmt.Sprintf("[%s for item in (%s or [])]", fromExpr(s.Items, "item", false), value)
+	case s.Type == "array":
+		return fmt.Sprintf("list(%s or [])", value)
+	default:
+		return value
+	}
+}
+
+// toExpr returns an expression converting a model value back to JSON.
+func toExpr(s *schema, value string) string {
+	switch {
+	case s.Ref != "" && isEnumRef(s):
+		return "getattr(" + value + `, "value", ` + value + ")"
+	case s.Ref != "":
+		return value + ".to_dict()"
+	case s.Type == "array" && s.Items != nil && s.Items.Ref != "":
+		return fmt.Sprintf("[%s for item in %s]", toExpr(s.Items, "item"), value)
+	default:
+		return value
+	}
+}
+
+// enumSchemas records which schema names are string enums. It is filled by
+// generate() before any dataclass is written.
+var enumSchemas = map[string]bool{}
+
+// isEnumRef reports whether a $ref points at an enum schema.
+func isEnumRef(s *schema) bool {
+	return enumSchemas[refName(s)]
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Python Model Generator Suite")
+}
+
+var _ = Describe("generate", func() {
+	// =========================================================================
+	// TEST: Checked-in models match the spec
+	// Why: Editing api/openapi.yaml without regenerating would ship a Python
+	//      SDK that silently disagrees with the server.
+	// =========================================================================
+	It("should match the checked-in models.py", func() {
+		root := filepath.Join("..", "..", "..")
+
+		generated, err := generate(filepath.Join(root, "api", "openapi.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		checkedIn, err := os.ReadFile(filepath.Join(root, "sdk", "python", "wasm_plugin_client", "models.py"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(string(generated)).To(Equal(string(checkedIn)),
+			"models.py is stale; run: go run ./sdk/python/gen api/openapi.yaml sdk/python/wasm_plugin_client/models.py")
+	})
+
+	It("should fail on a missing spec", func() {
+		_, err := generate("does-not-exist.yaml")
+
+		Expect(err).To(HaveOccurred())
+	})
+})
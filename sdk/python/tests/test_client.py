@@ -0,0 +1,216 @@
+"""Tests for the Python client against an in-process HTTP server."""
+
+import asyncio
+import base64
+import json
+import threading
+import unittest
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+from wasm_plugin_client import (
+    AsyncClient,
+    Client,
+    ErrorCode,
+    ProblemError,
+    RetryPolicy,
+    TransportError,
+)
+
+
+class FakeServer(BaseHTTPRequestHandler):
+    """Mimics the plugin server: hello computes input*2+1."""
+
+    # Statuses to return before succeeding, consumed per request
+    fail_with = []
+    requests = []
+
+    def log_message(self, *args):
+        pass
+
+    def do_POST(self):
+        body = json.loads(self.rfile.read(int(self.headers["Content-Length"])))
+        FakeServer.requests.append((self.path, body, dict(self.headers)))
+
+        if FakeServer.fail_with:
+            self._send(FakeServer.fail_with.pop(0), "text/plain", b"upstream unavailable")
+            return
+        if body["plugin"] == "notify":
+            self._send(200, "application/json", b'{"output": null}')
+            return
+        if body["plugin"] == "calc" and body.get("input", 0) < 0:
+            self._send(500, "application/problem+json", json.dumps({
+                "type": "urn:wasm-plugin-system:problem:plugin_execution_failed",
+                "title": "Plugin execution failed",
+                "status": 500,
+                "code": "plugin_execution_failed",
+                "plugin_error": {
+                    "code": -3,
+                    "name": "ABI_ERROR_INVALID_INPUT",
+                    "message": "input must not be negative",
+                },
+            }).encode())
+            return
+        if body["plugin"] != "hello":
+            self._send(404, "application/problem+json", json.dumps({
+                "type": "urn:wasm-plugin-system:problem:plugin_not_found",
+                "title": "Plugin not found",
+                "status": 404,
+                "detail": "plugin not found: " + body["plugin"],
+                "code": "plugin_not_found",
+            }).encode())
+            return
+        if "text" in body or "data" in body:
+            self._send(200, "application/json", json.dumps({
+                "output": len(body.get("text", "")),
+                **({"text": body["text"].upper()} if "text" in body else {"data": body["data"]}),
+            }).encode())
+            return
+        self._send(200, "application/json", json.dumps({
+            "output": body.get("input", 0) * 2 + 1,
+            "warnings": [{"code": "deprecated", "message": "unversioned"}],
+        }).encode())
+
+    def do_GET(self):
+        if self.path == "/plugins":
+            self._send(200, "application/json", json.dumps([
+                {"name": "hello", "size": 1234, "mod_time": "2024-05-01T12:00:00Z"},
+            ]).encode())
+            return
+        if self.path == "/admin/pools":
+            self._send(200, "application/json", json.dumps([{
+                "plugin": "hello", "last_used": "2024-05-01T12:00:00Z",
+                "stats": {
+                    "path": "/plugins/hello/hello.wasm", "strategy": "restore", "idle": 2, "in_use": 0,
+                    "evictions": {"discarded": 1},
+                    "checkout_wait_seconds": {"bounds": [0.1], "counts": [3, 3], "sum": 0.01, "count": 3},
+                },
+                "memory": {"instances": 2, "linear_memory_bytes": 131072, "snapshot_bytes": 131072},
+            }]).encode())
+            return
+        self._send(200, "text/plain", b"plugin_pool_warm_instances 2\n")
+
+    def _send(self, status, content_type, payload):
+        self.send_response(status)
+        self.send_header("Content-Type", content_type)
+        self.send_header("Content-Length", str(len(payload)))
+        self.end_headers()
+        self.wfile.write(payload)
+
+
+class ClientTest(unittest.TestCase):
+    @classmethod
+    def setUpClass(cls):
+        cls.httpd = HTTPServer(("127.0.0.1", 0), FakeServer)
+        cls.url = "http://127.0.0.1:%d" % cls.httpd.server_port
+        threading.Thread(target=cls.httpd.serve_forever, daemon=True).start()
+
+    @classmethod
+    def tearDownClass(cls):
+        cls.httpd.shutdown()
+
+    def setUp(self):
+        FakeServer.fail_with = []
+        FakeServer.requests = []
+        self.client = Client(self.url, retry=RetryPolicy(backoff=0.001))
+
+    def test_run_returns_output_and_warnings(self):
+        result = self.client.run("hello", 21)
+
+        self.assertEqual(result.output, 43)
+        self.assertEqual(result.warnings[0].code, "deprecated")
+
+    def test_run_sends_zero_input(self):
+        self.client.run("hello", 0)
+
+        self.assertEqual(FakeServer.requests[0][1], {"plugin": "hello", "input": 0})
+
+    def test_run_without_output(self):
+        result = self.client.run("notify", 0)
+
+        self.assertIsNone(result.output)
+
+    def test_run_text(self):
+        result = self.client.run_text("hello", "hi")
+
+        self.assertEqual(FakeServer.requests[0][1], {"plugin": "hello", "text": "hi"})
+        self.assertEqual(result.text, "HI")
+
+    def test_run_bytes_sends_base64(self):
+        result = self.client.run_bytes("hello", b"\x00\xff")
+
+        self.assertEqual(FakeServer.requests[0][1], {"plugin": "hello", "data": "AP8="})
+        self.assertEqual(base64.b64decode(result.data), b"\x00\xff")
+
+    def test_problem_is_raised_with_code(self):
+        with self.assertRaises(ProblemError) as ctx:
+            self.client.run("missing")
+
+        self.assertEqual(ctx.exception.code, ErrorCode.PLUGIN_NOT_FOUND)
+        self.assertEqual(ctx.exception.status, 404)
+        self.assertIn("missing", ctx.exception.detail)
+
+    def test_problem_carries_plugin_error(self):
+        with self.assertRaises(ProblemError) as ctx:
+            self.client.run("calc", -1)
+
+        self.assertEqual(ctx.exception.code, ErrorCode.PLUGIN_EXECUTION_FAILED)
+        self.assertEqual(ctx.exception.plugin_error.code, -3)
+        self.assertEqual(ctx.exception.plugin_error.message, "input must not be negative")
+
+    def test_retries_unavailable_then_succeeds(self):
+        FakeServer.fail_with = [503, 502]
+
+        result = self.client.run("hello", 1)
+
+        self.assertEqual(result.output, 3)
+        self.assertEqual(len(FakeServer.requests), 3)
+
+    def test_gives_up_after_attempts(self):
+        FakeServer.fail_with = [503, 503, 503]
+
+        with self.assertRaises(ProblemError) as ctx:
+            self.client.run("hello", 1)
+
+        self.assertEqual(ctx.exception.status, 503)
+        self.assertEqual(ctx.exception.code, ErrorCode.INTERNAL_ERROR)
+        self.assertEqual(len(FakeServer.requests), 3)
+
+    def test_connection_error_raises_transport_error(self):
+        client = Client("http://127.0.0.1:1", retry=RetryPolicy(attempts=2, backoff=0.001))
+
+        with self.assertRaises(TransportError):
+            client.run("hello", 1)
+
+    def test_accept_language_header(self):
+        Client(self.url, accept_language="de").run("hello", 1)
+
+        self.assertEqual(FakeServer.requests[0][2]["Accept-Language"], "de")
+
+    def test_list_plugins(self):
+        plugins = self.client.list_plugins()
+
+        self.assertEqual(plugins[0].name, "hello")
+        self.assertEqual(plugins[0].size, 1234)
+
+    def test_list_pools(self):
+        pools = self.client.list_pools()
+
+        self.assertEqual(pools[0].plugin, "hello")
+        self.assertEqual(pools[0].checkout_wait_seconds.count, 3)
+        self.assertEqual(pools[0].evictions, {"discarded": 1})
+
+    def test_metrics(self):
+        self.assertIn("plugin_pool_warm_instances", self.client.metrics())
+
+    def test_async_client(self):
+        client = AsyncClient(self.url)
+
+        async def run_all():
+            return await asyncio.gather(*(client.run("hello", i) for i in range(5)))
+
+        outputs = [r.output for r in asyncio.run(run_all())]
+        self.assertEqual(outputs, [1, 3, 5, 7, 9])
+
+
+if __name__ == "__main__":
+    unittest.main()
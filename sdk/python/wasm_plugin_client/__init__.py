@@ -0,0 +1,21 @@
+"""Python client for the WASM plugin server."""
+
+from .client import DEFAULT_TIMEOUT, AsyncClient, Client, RetryPolicy
+from .errors import ClientError, ProblemError, TransportError
+from .models import ErrorCode, PluginInfo, PoolInfo, Problem, RunResponse, Warning
+
+__all__ = [
+    "DEFAULT_TIMEOUT",
+    "AsyncClient",
+    "Client",
+    "ClientError",
+    "ErrorCode",
+    "PluginInfo",
+    "PoolInfo",
+    "Problem",
+    "ProblemError",
+    "RetryPolicy",
+    "RunResponse",
+    "TransportError",
+    "Warning",
+]
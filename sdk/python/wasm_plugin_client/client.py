@@ -0,0 +1,199 @@
+"""Sync and asyncio clients for the WASM plugin server.
+
+The clients use only the standard library so they install cleanly into
+notebooks and data-science environments without dependency conflicts.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import base64
+import json
+import random
+import socket
+import time
+import urllib.error
+import urllib.request
+from dataclasses import dataclass, field
+from typing import Any, Dict, FrozenSet, List, Mapping, Optional, Tuple
+
+from .errors import ProblemError, TransportError
+from .models import ErrorCode, PluginInfo, PoolInfo, Problem, RunRequest, RunResponse
+
+DEFAULT_TIMEOUT = 10.0
+"""Per-attempt timeout in seconds."""
+
+PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+
+@dataclass(frozen=True)
+class RetryPolicy:
+    """Controls retries of failed requests.
+
+    Only failures where the server certainly did not run the plugin are
+    retried: connection errors before a response, and the statuses in
+    ``retry_statuses``. Read timeouts are never retried because the plugin
+    may already have executed.
+    """
+
+    attempts: int = 3
+    backoff: float = 0.2
+    max_backoff: float = 2.0
+    retry_statuses: FrozenSet[int] = field(default_factory=lambda: frozenset({502, 503, 504}))
+
+    def delay(self, attempt: int) -> float:
+        """Exponential backoff with full jitter for the given 1-based attempt."""
+        return random.uniform(0, min(self.max_backoff, self.backoff * (2 ** (attempt - 1))))
+
+
+class Client:
+    """Synchronous client.
+
+    Example::
+
+        client = Client("http://localhost:8080")
+        result = client.run("hello", 21)
+        print(result.output)  # 43
+    """
+
+    def __init__(
+        self,
+        base_url: str,
+        *,
+        timeout: float = DEFAULT_TIMEOUT,
+        retry: Optional[RetryPolicy] = None,
+        headers: Optional[Mapping[str, str]] = None,
+        accept_language: Optional[str] = None,
+    ):
+        self.base_url = base_url.rstrip("/")
+        self.timeout = timeout
+        self.retry = retry or RetryPolicy()
+        self.headers: Dict[str, str] = dict(headers or {})
+        if accept_language:
+            self.headers["Accept-Language"] = accept_language
+
+    def run(self, plugin: str, input: int = 0) -> RunResponse:
+        """Execute a plugin and return its output and any warnings.
+
+        ``.output`` is None when the plugin succeeded without producing a
+        result, which is distinct from a result of 0.
+        """
+        body = RunRequest(plugin=plugin, input=input).to_dict()
+        return RunResponse.from_dict(self._request_json("POST", "/run", body))
+
+    def run_text(self, plugin: str, text: str) -> RunResponse:
+        """Execute a payload-ABI plugin on text; the result is in ``.text``."""
+        body = RunRequest(plugin=plugin, text=text).to_dict()
+        return RunResponse.from_dict(self._request_json("POST", "/run", body))
+
+    def run_bytes(self, plugin: str, data: bytes) -> RunResponse:
+        """Execute a payload-ABI plugin on bytes.
+
+        The result's ``.data`` is base64 as sent on the wire; decode it with
+        ``base64.b64decode``.
+        """
+        body = RunRequest(plugin=plugin, data=base64.b64encode(data).decode("ascii")).to_dict()
+        return RunResponse.from_dict(self._request_json("POST", "/run", body))
+
+    def list_plugins(self) -> List[PluginInfo]:
+        """Return the plugins available on the server, sorted by name."""
+        return [PluginInfo.from_dict(item) for item in self._request_json("GET", "/plugins")]
+
+    def list_pools(self) -> List[PoolInfo]:
+        """Return instance pool statistics for every plugin, least recently used first.
+
+        This is an admin endpoint: pass an admin token in ``headers``.
+        """
+        pools = []
+        for item in self._request_json("GET", "/admin/pools"):
+            info = PoolInfo.from_dict(item["stats"])
+            info.plugin = item["plugin"]
+            pools.append(info)
+        return pools
+
+    def metrics(self) -> str:
+        """Return the server's Prometheus metrics as text."""
+        _, _, body = self._request("GET", "/metrics")
+        return body.decode("utf-8")
+
+    def _request_json(self, method: str, path: str, body: Any = None) -> Any:
+        _, _, data = self._request(method, path, body)
+        return json.loads(data)
+
+    def _request(self, method: str, path: str, body: Any = None) -> Tuple[int, Mapping[str, str], bytes]:
+        data = None if body is None else json.dumps(body).encode("utf-8")
+        headers = {"Accept": "application/json", **self.headers}
+        if data is not None:
+            headers["Content-Type"] = "application/json"
+
+        attempt = 0
+        while True:
+            attempt += 1
+            request = urllib.request.Request(self.base_url + path, data=data, method=method, headers=headers)
+            try:
+                with urllib.request.urlopen(request, timeout=self.timeout) as resp:
+                    return resp.status, resp.headers, resp.read()
+            except urllib.error.HTTPError as err:
+                payload = err.read()
+                if err.code in self.retry.retry_statuses and attempt < self.retry.attempts:
+                    time.sleep(self.retry.delay(attempt))
+                    continue
+                raise _problem_error(err.code, err.headers, payload) from None
+            except (urllib.error.URLError, ConnectionError) as err:
+                reason = getattr(err, "reason", err)
+                if isinstance(reason, (socket.timeout, TimeoutError)):
+                    raise TransportError(f"{method} {path} timed out after {self.timeout}s") from err
+                if attempt < self.retry.attempts:
+                    time.sleep(self.retry.delay(attempt))
+                    continue
+                raise TransportError(f"{method} {path} failed after {attempt} attempts: {reason}") from err
+
+
+class AsyncClient:
+    """asyncio client with the same methods and defaults as ``Client``.
+
+    Requests run in the default executor, so concurrent calls do not block
+    the event loop.
+    """
+
+    def __init__(self, base_url: str, **kwargs: Any):
+        self._client = Client(base_url, **kwargs)
+
+    async def run(self, plugin: str, input: int = 0) -> RunResponse:
+        return await asyncio.to_thread(self._client.run, plugin, input)
+
+    async def run_text(self, plugin: str, text: str) -> RunResponse:
+        return await asyncio.to_thread(self._client.run_text, plugin, text)
+
+    async def run_bytes(self, plugin: str, data: bytes) -> RunResponse:
+        return await asyncio.to_thread(self._client.run_bytes, plugin, data)
+
+    async def list_plugins(self) -> List[PluginInfo]:
+        return await asyncio.to_thread(self._client.list_plugins)
+
+    async def list_pools(self) -> List[PoolInfo]:
+        return await asyncio.to_thread(self._client.list_pools)
+
+    async def metrics(self) -> str:
+        return await asyncio.to_thread(self._client.metrics)
+
+
+def _problem_error(status: int, headers: Mapping[str, str], payload: bytes) -> ProblemError:
+    """Build a ProblemError, synthesizing a problem for non-RFC 7807 bodies
+    (e.g., errors from a proxy in front of the server)."""
+    content_type = headers.get("Content-Type", "") if headers else ""
+    if content_type.startswith(PROBLEM_CONTENT_TYPE):
+        try:
+            return ProblemError(Problem.from_dict(json.loads(payload)))
+        except (ValueError, TypeError):
+            pass
+
+    return ProblemError(
+        Problem(
+            type="about:blank",
+            title=f"HTTP {status}",
+            status=status,
+            code=ErrorCode.INTERNAL_ERROR,
+            detail=payload.decode("utf-8", "replace")[:512] or None,
+        )
+    )
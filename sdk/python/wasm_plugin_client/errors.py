@@ -0,0 +1,51 @@
+"""Exceptions raised by the WASM plugin server client."""
+
+from __future__ import annotations
+
+from typing import Optional
+
+from .models import ErrorCode, PluginError, Problem
+
+
+class ClientError(Exception):
+    """Base class for all client errors."""
+
+
+class TransportError(ClientError):
+    """The server could not be reached or the connection failed.
+
+    Raised after all retry attempts are exhausted.
+    """
+
+
+class ProblemError(ClientError):
+    """The server returned an RFC 7807 problem response.
+
+    Branch on ``code`` (an ``ErrorCode`` member, or a plain string for codes
+    newer than this client); ``title`` and ``detail`` are for humans only.
+    """
+
+    def __init__(self, problem: Problem):
+        code = getattr(problem.code, "value", problem.code)
+        message = f"{problem.title} ({code})"
+        if problem.detail:
+            message += f": {problem.detail}"
+        super().__init__(message)
+        self.problem = problem
+
+    @property
+    def code(self) -> ErrorCode:
+        return self.problem.code
+
+    @property
+    def status(self) -> int:
+        return self.problem.status
+
+    @property
+    def detail(self) -> Optional[str]:
+        return self.problem.detail
+
+    @property
+    def plugin_error(self) -> Optional[PluginError]:
+        """The error code and message reported by the plugin, if any."""
+        return self.problem.plugin_error
@@ -0,0 +1,1855 @@
+# Code generated by sdk/python/gen from api/openapi.yaml. DO NOT EDIT.
+"""Data models for the WASM plugin server API."""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+from enum import Enum
+from typing import Any, Dict, List, Optional, Type, TypeVar, Union
+
+_E = TypeVar("_E", bound=Enum)
+
+
+def _enum(enum_cls: Type[_E], value: Any) -> Union[_E, str]:
+    """Convert to an enum member, keeping unknown values as plain strings.
+
+    Servers may add enum values before clients are regenerated; callers
+    compare against members, which works for both since the enums are str.
+    """
+    try:
+        return enum_cls(value)
+    except ValueError:
+        return value
+
+
+@dataclass
+class RunRequest:
+    plugin: str
+    input: Optional[int] = None
+    text: Optional[str] = None
+    data: Optional[str] = None
+    payload: Optional[Any] = None
+    fields: Optional[Dict[str, Any]] = None
+    timeout_ms: Optional[int] = None
+    include_logs: Optional[bool] = None
+    include_output: Optional[bool] = None
+    dedup_key: Optional[str] = None
+    debug: Optional[bool] = None
+    version: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "RunRequest":
+        return cls(
+            plugin=data.get("plugin"),
+            input=data.get("input"),
+            text=data.get("text"),
+            data=data.get("data"),
+            payload=data.get("payload"),
+            fields=data.get("fields"),
+            timeout_ms=data.get("timeout_ms"),
+            include_logs=data.get("include_logs"),
+            include_output=data.get("include_output"),
+            dedup_key=data.get("dedup_key"),
+            debug=data.get("debug"),
+            version=data.get("version"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        if self.input is not None:
+            result["input"] = self.input
+        if self.text is not None:
+            result["text"] = self.text
+        if self.data is not None:
+            result["data"] = self.data
+        if self.payload is not None:
+            result["payload"] = self.payload
+        if self.fields is not None:
+            result["fields"] = self.fields
+        if self.timeout_ms is not None:
+            result["timeout_ms"] = self.timeout_ms
+        if self.include_logs is not None:
+            result["include_logs"] = self.include_logs
+        if self.include_output is not None:
+            result["include_output"] = self.include_output
+        if self.dedup_key is not None:
+            result["dedup_key"] = self.dedup_key
+        if self.debug is not None:
+            result["debug"] = self.debug
+        if self.version is not None:
+            result["version"] = self.version
+        return result
+
+
+@dataclass
+class RunResponse:
+    output: Optional[int]
+    text: Optional[str] = None
+    data: Optional[str] = None
+    payload: Optional[Any] = None
+    fields: Optional[Dict[str, Any]] = None
+    warnings: List[Warning] = field(default_factory=list)
+    logs: List[LogEntry] = field(default_factory=list)
+    replayed: Optional[bool] = None
+    effects: Optional[int] = None
+    trace: Optional[TraceRecord] = None
+    version: Optional[str] = None
+    stdout: Optional[str] = None
+    stderr: Optional[str] = None
+    output_truncated: Optional[bool] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "RunResponse":
+        return cls(
+            output=data.get("output"),
+            text=data.get("text"),
+            data=data.get("data"),
+            payload=data.get("payload"),
+            fields=data.get("fields"),
+            warnings=[Warning.from_dict(item) for item in (data.get("warnings") or [])],
+            logs=[LogEntry.from_dict(item) for item in (data.get("logs") or [])],
+            replayed=data.get("replayed"),
+            effects=data.get("effects"),
+            trace=(TraceRecord.from_dict(data.get("trace")) if data.get("trace") is not None else None),
+            version=data.get("version"),
+            stdout=data.get("stdout"),
+            stderr=data.get("stderr"),
+            output_truncated=data.get("output_truncated"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["output"] = self.output
+        if self.text is not None:
+            result["text"] = self.text
+        if self.data is not None:
+            result["data"] = self.data
+        if self.payload is not None:
+            result["payload"] = self.payload
+        if self.fields is not None:
+            result["fields"] = self.fields
+        if self.warnings:
+            result["warnings"] = [item.to_dict() for item in self.warnings]
+        if self.logs:
+            result["logs"] = [item.to_dict() for item in self.logs]
+        if self.replayed is not None:
+            result["replayed"] = self.replayed
+        if self.effects is not None:
+            result["effects"] = self.effects
+        if self.trace is not None:
+            result["trace"] = self.trace.to_dict()
+        if self.version is not None:
+            result["version"] = self.version
+        if self.stdout is not None:
+            result["stdout"] = self.stdout
+        if self.stderr is not None:
+            result["stderr"] = self.stderr
+        if self.output_truncated is not None:
+            result["output_truncated"] = self.output_truncated
+        return result
+
+
+@dataclass
+class StreamMessage:
+    id: Optional[str] = None
+    input: Optional[int] = None
+    text: Optional[str] = None
+    data: Optional[str] = None
+    payload: Optional[Any] = None
+    fields: Optional[Dict[str, Any]] = None
+    timeout_ms: Optional[int] = None
+    include_logs: Optional[bool] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "StreamMessage":
+        return cls(
+            id=data.get("id"),
+            input=data.get("input"),
+            text=data.get("text"),
+            data=data.get("data"),
+            payload=data.get("payload"),
+            fields=data.get("fields"),
+            timeout_ms=data.get("timeout_ms"),
+            include_logs=data.get("include_logs"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.id is not None:
+            result["id"] = self.id
+        if self.input is not None:
+            result["input"] = self.input
+        if self.text is not None:
+            result["text"] = self.text
+        if self.data is not None:
+            result["data"] = self.data
+        if self.payload is not None:
+            result["payload"] = self.payload
+        if self.fields is not None:
+            result["fields"] = self.fields
+        if self.timeout_ms is not None:
+            result["timeout_ms"] = self.timeout_ms
+        if self.include_logs is not None:
+            result["include_logs"] = self.include_logs
+        return result
+
+
+@dataclass
+class ProgressEvent:
+    percent: int
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ProgressEvent":
+        return cls(
+            percent=data.get("percent"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["percent"] = self.percent
+        return result
+
+
+@dataclass
+class StreamResult:
+    id: str
+    output: Optional[int] = None
+    text: Optional[str] = None
+    data: Optional[str] = None
+    payload: Optional[Any] = None
+    fields: Optional[Dict[str, Any]] = None
+    warnings: List[Warning] = field(default_factory=list)
+    logs: List[LogEntry] = field(default_factory=list)
+    effects: Optional[int] = None
+    version: Optional[str] = None
+    error: Optional[Problem] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "StreamResult":
+        return cls(
+            id=data.get("id"),
+            output=data.get("output"),
+            text=data.get("text"),
+            data=data.get("data"),
+            payload=data.get("payload"),
+            fields=data.get("fields"),
+            warnings=[Warning.from_dict(item) for item in (data.get("warnings") or [])],
+            logs=[LogEntry.from_dict(item) for item in (data.get("logs") or [])],
+            effects=data.get("effects"),
+            version=data.get("version"),
+            error=(Problem.from_dict(data.get("error")) if data.get("error") is not None else None),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["id"] = self.id
+        if self.output is not None:
+            result["output"] = self.output
+        if self.text is not None:
+            result["text"] = self.text
+        if self.data is not None:
+            result["data"] = self.data
+        if self.payload is not None:
+            result["payload"] = self.payload
+        if self.fields is not None:
+            result["fields"] = self.fields
+        if self.warnings:
+            result["warnings"] = [item.to_dict() for item in self.warnings]
+        if self.logs:
+            result["logs"] = [item.to_dict() for item in self.logs]
+        if self.effects is not None:
+            result["effects"] = self.effects
+        if self.version is not None:
+            result["version"] = self.version
+        if self.error is not None:
+            result["error"] = self.error.to_dict()
+        return result
+
+
+@dataclass
+class TraceRecord:
+    request_id: str
+    plugin: str
+    time: str
+    events: List[TraceEvent]
+    error: Optional[str] = None
+    dropped: Optional[int] = None
+    wasi: List[str] = field(default_factory=list)
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "TraceRecord":
+        return cls(
+            request_id=data.get("request_id"),
+            plugin=data.get("plugin"),
+            time=data.get("time"),
+            events=[TraceEvent.from_dict(item) for item in (data.get("events") or [])],
+            error=data.get("error"),
+            dropped=data.get("dropped"),
+            wasi=list(data.get("wasi") or []),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["request_id"] = self.request_id
+        result["plugin"] = self.plugin
+        result["time"] = self.time
+        result["events"] = [item.to_dict() for item in self.events]
+        if self.error is not None:
+            result["error"] = self.error
+        if self.dropped is not None:
+            result["dropped"] = self.dropped
+        if self.wasi:
+            result["wasi"] = self.wasi
+        return result
+
+
+@dataclass
+class TraceEvent:
+    seq: int
+    time: str
+    kind: str
+    function: str
+    args: List[Any]
+    duration_ns: int
+    module: Optional[str] = None
+    results: List[Any] = field(default_factory=list)
+    error: Optional[str] = None
+    memory: List[MemoryAccess] = field(default_factory=list)
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "TraceEvent":
+        return cls(
+            seq=data.get("seq"),
+            time=data.get("time"),
+            kind=data.get("kind"),
+            function=data.get("function"),
+            args=list(data.get("args") or []),
+            duration_ns=data.get("duration_ns"),
+            module=data.get("module"),
+            results=list(data.get("results") or []),
+            error=data.get("error"),
+            memory=[MemoryAccess.from_dict(item) for item in (data.get("memory") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["seq"] = self.seq
+        result["time"] = self.time
+        result["kind"] = self.kind
+        result["function"] = self.function
+        result["args"] = self.args
+        result["duration_ns"] = self.duration_ns
+        if self.module is not None:
+            result["module"] = self.module
+        if self.results:
+            result["results"] = self.results
+        if self.error is not None:
+            result["error"] = self.error
+        if self.memory:
+            result["memory"] = [item.to_dict() for item in self.memory]
+        return result
+
+
+@dataclass
+class MemoryAccess:
+    op: str
+    ptr: int
+    len: int
+    data: Optional[str] = None
+    truncated: Optional[bool] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "MemoryAccess":
+        return cls(
+            op=data.get("op"),
+            ptr=data.get("ptr"),
+            len=data.get("len"),
+            data=data.get("data"),
+            truncated=data.get("truncated"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["op"] = self.op
+        result["ptr"] = self.ptr
+        result["len"] = self.len
+        if self.data is not None:
+            result["data"] = self.data
+        if self.truncated is not None:
+            result["truncated"] = self.truncated
+        return result
+
+
+@dataclass
+class TraceSummary:
+    request_id: str
+    plugin: str
+    time: str
+    events: int
+    error: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "TraceSummary":
+        return cls(
+            request_id=data.get("request_id"),
+            plugin=data.get("plugin"),
+            time=data.get("time"),
+            events=data.get("events"),
+            error=data.get("error"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["request_id"] = self.request_id
+        result["plugin"] = self.plugin
+        result["time"] = self.time
+        result["events"] = self.events
+        if self.error is not None:
+            result["error"] = self.error
+        return result
+
+
+@dataclass
+class LogEntry:
+    time: str
+    level: str
+    plugin: str
+    message: str
+    request_id: Optional[str] = None
+    tenant: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "LogEntry":
+        return cls(
+            time=data.get("time"),
+            level=data.get("level"),
+            plugin=data.get("plugin"),
+            message=data.get("message"),
+            request_id=data.get("request_id"),
+            tenant=data.get("tenant"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["time"] = self.time
+        result["level"] = self.level
+        result["plugin"] = self.plugin
+        result["message"] = self.message
+        if self.request_id is not None:
+            result["request_id"] = self.request_id
+        if self.tenant is not None:
+            result["tenant"] = self.tenant
+        return result
+
+
+@dataclass
+class LogOverrideRequest:
+    level: Optional[str] = None
+    trace: Optional[bool] = None
+    duration_seconds: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "LogOverrideRequest":
+        return cls(
+            level=data.get("level"),
+            trace=data.get("trace"),
+            duration_seconds=data.get("duration_seconds"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.level is not None:
+            result["level"] = self.level
+        if self.trace is not None:
+            result["trace"] = self.trace
+        if self.duration_seconds is not None:
+            result["duration_seconds"] = self.duration_seconds
+        return result
+
+
+@dataclass
+class LogOverride:
+    plugin: str
+    level: str
+    trace: Optional[bool] = None
+    expires: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "LogOverride":
+        return cls(
+            plugin=data.get("plugin"),
+            level=data.get("level"),
+            trace=data.get("trace"),
+            expires=data.get("expires"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["level"] = self.level
+        if self.trace is not None:
+            result["trace"] = self.trace
+        if self.expires is not None:
+            result["expires"] = self.expires
+        return result
+
+
+@dataclass
+class ScheduleRequest:
+    enabled: bool
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ScheduleRequest":
+        return cls(
+            enabled=data.get("enabled"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["enabled"] = self.enabled
+        return result
+
+
+@dataclass
+class ScheduleInfo:
+    plugin: str
+    name: str
+    cron: str
+    enabled: bool
+    next: Optional[str] = None
+    last_run: Optional[str] = None
+    last_request_id: Optional[str] = None
+    last_error: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ScheduleInfo":
+        return cls(
+            plugin=data.get("plugin"),
+            name=data.get("name"),
+            cron=data.get("cron"),
+            enabled=data.get("enabled"),
+            next=data.get("next"),
+            last_run=data.get("last_run"),
+            last_request_id=data.get("last_request_id"),
+            last_error=data.get("last_error"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["name"] = self.name
+        result["cron"] = self.cron
+        result["enabled"] = self.enabled
+        if self.next is not None:
+            result["next"] = self.next
+        if self.last_run is not None:
+            result["last_run"] = self.last_run
+        if self.last_request_id is not None:
+            result["last_request_id"] = self.last_request_id
+        if self.last_error is not None:
+            result["last_error"] = self.last_error
+        return result
+
+
+@dataclass
+class BatchRequest:
+    plugin: str
+    input: str
+    concurrency: Optional[int] = None
+    timeout_ms: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "BatchRequest":
+        return cls(
+            plugin=data.get("plugin"),
+            input=data.get("input"),
+            concurrency=data.get("concurrency"),
+            timeout_ms=data.get("timeout_ms"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["input"] = self.input
+        if self.concurrency is not None:
+            result["concurrency"] = self.concurrency
+        if self.timeout_ms is not None:
+            result["timeout_ms"] = self.timeout_ms
+        return result
+
+
+@dataclass
+class BatchInfo:
+    id: str
+    plugin: str
+    input: str
+    output: str
+    state: str
+    concurrency: int
+    size_bytes: int
+    bytes_read: int
+    processed: int
+    succeeded: int
+    failed: int
+    started: str
+    retries: Optional[int] = None
+    finished: Optional[str] = None
+    error: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "BatchInfo":
+        return cls(
+            id=data.get("id"),
+            plugin=data.get("plugin"),
+            input=data.get("input"),
+            output=data.get("output"),
+            state=data.get("state"),
+            concurrency=data.get("concurrency"),
+            size_bytes=data.get("size_bytes"),
+            bytes_read=data.get("bytes_read"),
+            processed=data.get("processed"),
+            succeeded=data.get("succeeded"),
+            failed=data.get("failed"),
+            started=data.get("started"),
+            retries=data.get("retries"),
+            finished=data.get("finished"),
+            error=data.get("error"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["id"] = self.id
+        result["plugin"] = self.plugin
+        result["input"] = self.input
+        result["output"] = self.output
+        result["state"] = self.state
+        result["concurrency"] = self.concurrency
+        result["size_bytes"] = self.size_bytes
+        result["bytes_read"] = self.bytes_read
+        result["processed"] = self.processed
+        result["succeeded"] = self.succeeded
+        result["failed"] = self.failed
+        result["started"] = self.started
+        if self.retries is not None:
+            result["retries"] = self.retries
+        if self.finished is not None:
+            result["finished"] = self.finished
+        if self.error is not None:
+            result["error"] = self.error
+        return result
+
+
+@dataclass
+class JobInfo:
+    id: str
+    plugin: str
+    state: str
+    created: str
+    result: Optional[RunResponse] = None
+    error: Optional[Problem] = None
+    started: Optional[str] = None
+    finished: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "JobInfo":
+        return cls(
+            id=data.get("id"),
+            plugin=data.get("plugin"),
+            state=data.get("state"),
+            created=data.get("created"),
+            result=(RunResponse.from_dict(data.get("result")) if data.get("result") is not None else None),
+            error=(Problem.from_dict(data.get("error")) if data.get("error") is not None else None),
+            started=data.get("started"),
+            finished=data.get("finished"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["id"] = self.id
+        result["plugin"] = self.plugin
+        result["state"] = self.state
+        result["created"] = self.created
+        if self.result is not None:
+            result["result"] = self.result.to_dict()
+        if self.error is not None:
+            result["error"] = self.error.to_dict()
+        if self.started is not None:
+            result["started"] = self.started
+        if self.finished is not None:
+            result["finished"] = self.finished
+        return result
+
+
+@dataclass
+class MaintenanceRequest:
+    plugin: Optional[str] = None
+    tenant: Optional[str] = None
+    start: Optional[str] = None
+    end: Optional[str] = None
+    duration_seconds: Optional[int] = None
+    reason: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "MaintenanceRequest":
+        return cls(
+            plugin=data.get("plugin"),
+            tenant=data.get("tenant"),
+            start=data.get("start"),
+            end=data.get("end"),
+            duration_seconds=data.get("duration_seconds"),
+            reason=data.get("reason"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.plugin is not None:
+            result["plugin"] = self.plugin
+        if self.tenant is not None:
+            result["tenant"] = self.tenant
+        if self.start is not None:
+            result["start"] = self.start
+        if self.end is not None:
+            result["end"] = self.end
+        if self.duration_seconds is not None:
+            result["duration_seconds"] = self.duration_seconds
+        if self.reason is not None:
+            result["reason"] = self.reason
+        return result
+
+
+@dataclass
+class MaintenanceWindow:
+    id: str
+    start: str
+    end: str
+    plugin: Optional[str] = None
+    tenant: Optional[str] = None
+    reason: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "MaintenanceWindow":
+        return cls(
+            id=data.get("id"),
+            start=data.get("start"),
+            end=data.get("end"),
+            plugin=data.get("plugin"),
+            tenant=data.get("tenant"),
+            reason=data.get("reason"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["id"] = self.id
+        result["start"] = self.start
+        result["end"] = self.end
+        if self.plugin is not None:
+            result["plugin"] = self.plugin
+        if self.tenant is not None:
+            result["tenant"] = self.tenant
+        if self.reason is not None:
+            result["reason"] = self.reason
+        return result
+
+
+@dataclass
+class Warning:
+    code: str
+    message: str
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Warning":
+        return cls(
+            code=data.get("code"),
+            message=data.get("message"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["code"] = self.code
+        result["message"] = self.message
+        return result
+
+
+@dataclass
+class Problem:
+    type: str
+    title: str
+    status: int
+    code: ErrorCode
+    detail: Optional[str] = None
+    instance: Optional[str] = None
+    plugin_error: Optional[PluginError] = None
+    request_id: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Problem":
+        return cls(
+            type=data.get("type"),
+            title=data.get("title"),
+            status=data.get("status"),
+            code=_enum(ErrorCode, data.get("code")),
+            detail=data.get("detail"),
+            instance=data.get("instance"),
+            plugin_error=(PluginError.from_dict(data.get("plugin_error")) if data.get("plugin_error") is not None else None),
+            request_id=data.get("request_id"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["type"] = self.type
+        result["title"] = self.title
+        result["status"] = self.status
+        result["code"] = getattr(self.code, "value", self.code)
+        if self.detail is not None:
+            result["detail"] = self.detail
+        if self.instance is not None:
+            result["instance"] = self.instance
+        if self.plugin_error is not None:
+            result["plugin_error"] = self.plugin_error.to_dict()
+        if self.request_id is not None:
+            result["request_id"] = self.request_id
+        return result
+
+
+@dataclass
+class PluginError:
+    code: int
+    name: str
+    message: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "PluginError":
+        return cls(
+            code=data.get("code"),
+            name=data.get("name"),
+            message=data.get("message"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["code"] = self.code
+        result["name"] = self.name
+        if self.message is not None:
+            result["message"] = self.message
+        return result
+
+
+class ErrorCode(str, Enum):
+    INVALID_REQUEST = "invalid_request"
+    MISSING_PLUGIN_NAME = "missing_plugin_name"
+    INVALID_PLUGIN_NAME = "invalid_plugin_name"
+    PLUGIN_NOT_FOUND = "plugin_not_found"
+    METHOD_NOT_ALLOWED = "method_not_allowed"
+    PLUGIN_LOAD_FAILED = "plugin_load_failed"
+    PLUGIN_INIT_FAILED = "plugin_init_failed"
+    PLUGIN_EXECUTION_FAILED = "plugin_execution_failed"
+    PAYLOAD_UNSUPPORTED = "payload_unsupported"
+    PLUGIN_TIMEOUT = "plugin_timeout"
+    DUPLICATE_REQUEST = "duplicate_request"
+    UNAUTHORIZED = "unauthorized"
+    FORBIDDEN = "forbidden"
+    INVALID_PLUGIN = "invalid_plugin"
+    PLUGIN_TOO_LARGE = "plugin_too_large"
+    TRACE_NOT_FOUND = "trace_not_found"
+    SCHEDULE_NOT_FOUND = "schedule_not_found"
+    MAINTENANCE = "maintenance"
+    MAINTENANCE_WINDOW_NOT_FOUND = "maintenance_window_not_found"
+    BATCH_NOT_FOUND = "batch_not_found"
+    JOB_NOT_FOUND = "job_not_found"
+    TOO_MANY_EXECUTIONS = "too_many_executions"
+    RATE_LIMITED = "rate_limited"
+    MEMORY_LIMIT_EXCEEDED = "memory_limit_exceeded"
+    INSTRUCTION_LIMIT_EXCEEDED = "instruction_limit_exceeded"
+    FILE_QUOTA_EXCEEDED = "file_quota_exceeded"
+    INTERNAL_ERROR = "internal_error"
+
+
+@dataclass
+class PluginInfo:
+    name: str
+    size: int
+    mod_time: str
+    version: Optional[str] = None
+    manifest: Optional[Manifest] = None
+    sha256: Optional[str] = None
+    digest: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "PluginInfo":
+        return cls(
+            name=data.get("name"),
+            size=data.get("size"),
+            mod_time=data.get("mod_time"),
+            version=data.get("version"),
+            manifest=(Manifest.from_dict(data.get("manifest")) if data.get("manifest") is not None else None),
+            sha256=data.get("sha256"),
+            digest=data.get("digest"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["name"] = self.name
+        result["size"] = self.size
+        result["mod_time"] = self.mod_time
+        if self.version is not None:
+            result["version"] = self.version
+        if self.manifest is not None:
+            result["manifest"] = self.manifest.to_dict()
+        if self.sha256 is not None:
+            result["sha256"] = self.sha256
+        if self.digest is not None:
+            result["digest"] = self.digest
+        return result
+
+
+@dataclass
+class Manifest:
+    name: str
+    version: str
+    abi_version: Optional[int] = None
+    description: Optional[str] = None
+    exports: List[str] = field(default_factory=list)
+    limits: Optional[ManifestLimits] = None
+    sizing: Optional[ManifestSizing] = None
+    config: Optional[Dict[str, Any]] = None
+    reentrant: Optional[bool] = None
+    schedules: List[ManifestSchedule] = field(default_factory=list)
+    tests: List[ManifestTest] = field(default_factory=list)
+    wasi: Optional[ManifestWASI] = None
+    sha256: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Manifest":
+        return cls(
+            name=data.get("name"),
+            version=data.get("version"),
+            abi_version=data.get("abi_version"),
+            description=data.get("description"),
+            exports=list(data.get("exports") or []),
+            limits=(ManifestLimits.from_dict(data.get("limits")) if data.get("limits") is not None else None),
+            sizing=(ManifestSizing.from_dict(data.get("sizing")) if data.get("sizing") is not None else None),
+            config=data.get("config"),
+            reentrant=data.get("reentrant"),
+            schedules=[ManifestSchedule.from_dict(item) for item in (data.get("schedules") or [])],
+            tests=[ManifestTest.from_dict(item) for item in (data.get("tests") or [])],
+            wasi=(ManifestWASI.from_dict(data.get("wasi")) if data.get("wasi") is not None else None),
+            sha256=data.get("sha256"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["name"] = self.name
+        result["version"] = self.version
+        if self.abi_version is not None:
+            result["abi_version"] = self.abi_version
+        if self.description is not None:
+            result["description"] = self.description
+        if self.exports:
+            result["exports"] = self.exports
+        if self.limits is not None:
+            result["limits"] = self.limits.to_dict()
+        if self.sizing is not None:
+            result["sizing"] = self.sizing.to_dict()
+        if self.config is not None:
+            result["config"] = self.config
+        if self.reentrant is not None:
+            result["reentrant"] = self.reentrant
+        if self.schedules:
+            result["schedules"] = [item.to_dict() for item in self.schedules]
+        if self.tests:
+            result["tests"] = [item.to_dict() for item in self.tests]
+        if self.wasi is not None:
+            result["wasi"] = self.wasi.to_dict()
+        if self.sha256 is not None:
+            result["sha256"] = self.sha256
+        return result
+
+
+@dataclass
+class ManifestSchedule:
+    name: str
+    cron: str
+    input: Optional[int] = None
+    text: Optional[str] = None
+    timeout_ms: Optional[int] = None
+    disabled: Optional[bool] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ManifestSchedule":
+        return cls(
+            name=data.get("name"),
+            cron=data.get("cron"),
+            input=data.get("input"),
+            text=data.get("text"),
+            timeout_ms=data.get("timeout_ms"),
+            disabled=data.get("disabled"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["name"] = self.name
+        result["cron"] = self.cron
+        if self.input is not None:
+            result["input"] = self.input
+        if self.text is not None:
+            result["text"] = self.text
+        if self.timeout_ms is not None:
+            result["timeout_ms"] = self.timeout_ms
+        if self.disabled is not None:
+            result["disabled"] = self.disabled
+        return result
+
+
+@dataclass
+class ManifestTest:
+    name: str
+    input: Optional[int] = None
+    text: Optional[str] = None
+    output: Optional[int] = None
+    output_text: Optional[str] = None
+    error_code: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ManifestTest":
+        return cls(
+            name=data.get("name"),
+            input=data.get("input"),
+            text=data.get("text"),
+            output=data.get("output"),
+            output_text=data.get("output_text"),
+            error_code=data.get("error_code"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["name"] = self.name
+        if self.input is not None:
+            result["input"] = self.input
+        if self.text is not None:
+            result["text"] = self.text
+        if self.output is not None:
+            result["output"] = self.output
+        if self.output_text is not None:
+            result["output_text"] = self.output_text
+        if self.error_code is not None:
+            result["error_code"] = self.error_code
+        return result
+
+
+@dataclass
+class ManifestWASI:
+    args: List[str] = field(default_factory=list)
+    env: Optional[Dict[str, str]] = None
+    inherit_env: List[str] = field(default_factory=list)
+    dirs: List[str] = field(default_factory=list)
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ManifestWASI":
+        return cls(
+            args=list(data.get("args") or []),
+            env=data.get("env"),
+            inherit_env=list(data.get("inherit_env") or []),
+            dirs=list(data.get("dirs") or []),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.args:
+            result["args"] = self.args
+        if self.env is not None:
+            result["env"] = self.env
+        if self.inherit_env:
+            result["inherit_env"] = self.inherit_env
+        if self.dirs:
+            result["dirs"] = self.dirs
+        return result
+
+
+@dataclass
+class ManifestLimits:
+    memory_pages: Optional[int] = None
+    timeout_ms: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ManifestLimits":
+        return cls(
+            memory_pages=data.get("memory_pages"),
+            timeout_ms=data.get("timeout_ms"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.memory_pages is not None:
+            result["memory_pages"] = self.memory_pages
+        if self.timeout_ms is not None:
+            result["timeout_ms"] = self.timeout_ms
+        return result
+
+
+@dataclass
+class ManifestSizing:
+    expected_qps: Optional[float] = None
+    latency_target_ms: Optional[int] = None
+    instance_memory_mib: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ManifestSizing":
+        return cls(
+            expected_qps=data.get("expected_qps"),
+            latency_target_ms=data.get("latency_target_ms"),
+            instance_memory_mib=data.get("instance_memory_mib"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.expected_qps is not None:
+            result["expected_qps"] = self.expected_qps
+        if self.latency_target_ms is not None:
+            result["latency_target_ms"] = self.latency_target_ms
+        if self.instance_memory_mib is not None:
+            result["instance_memory_mib"] = self.instance_memory_mib
+        return result
+
+
+@dataclass
+class HistogramSnapshot:
+    bounds: List[float] = field(default_factory=list)
+    counts: List[int] = field(default_factory=list)
+    sum: Optional[float] = None
+    count: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "HistogramSnapshot":
+        return cls(
+            bounds=list(data.get("bounds") or []),
+            counts=list(data.get("counts") or []),
+            sum=data.get("sum"),
+            count=data.get("count"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.bounds:
+            result["bounds"] = self.bounds
+        if self.counts:
+            result["counts"] = self.counts
+        if self.sum is not None:
+            result["sum"] = self.sum
+        if self.count is not None:
+            result["count"] = self.count
+        return result
+
+
+@dataclass
+class PoolInfo:
+    plugin: Optional[str] = None
+    path: Optional[str] = None
+    strategy: Optional[str] = None
+    idle: Optional[int] = None
+    in_use: Optional[int] = None
+    waiting: Optional[int] = None
+    min_size: Optional[int] = None
+    max_size: Optional[int] = None
+    instantiated: Optional[int] = None
+    restored: Optional[int] = None
+    evictions: Optional[Dict[str, int]] = None
+    shutdown_failures: Optional[int] = None
+    checkout_wait_seconds: Optional[HistogramSnapshot] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "PoolInfo":
+        return cls(
+            plugin=data.get("plugin"),
+            path=data.get("path"),
+            strategy=data.get("strategy"),
+            idle=data.get("idle"),
+            in_use=data.get("in_use"),
+            waiting=data.get("waiting"),
+            min_size=data.get("min_size"),
+            max_size=data.get("max_size"),
+            instantiated=data.get("instantiated"),
+            restored=data.get("restored"),
+            evictions=data.get("evictions"),
+            shutdown_failures=data.get("shutdown_failures"),
+            checkout_wait_seconds=(HistogramSnapshot.from_dict(data.get("checkout_wait_seconds")) if data.get("checkout_wait_seconds") is not None else None),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.plugin is not None:
+            result["plugin"] = self.plugin
+        if self.path is not None:
+            result["path"] = self.path
+        if self.strategy is not None:
+            result["strategy"] = self.strategy
+        if self.idle is not None:
+            result["idle"] = self.idle
+        if self.in_use is not None:
+            result["in_use"] = self.in_use
+        if self.waiting is not None:
+            result["waiting"] = self.waiting
+        if self.min_size is not None:
+            result["min_size"] = self.min_size
+        if self.max_size is not None:
+            result["max_size"] = self.max_size
+        if self.instantiated is not None:
+            result["instantiated"] = self.instantiated
+        if self.restored is not None:
+            result["restored"] = self.restored
+        if self.evictions is not None:
+            result["evictions"] = self.evictions
+        if self.shutdown_failures is not None:
+            result["shutdown_failures"] = self.shutdown_failures
+        if self.checkout_wait_seconds is not None:
+            result["checkout_wait_seconds"] = self.checkout_wait_seconds.to_dict()
+        return result
+
+
+@dataclass
+class MemoryReport:
+    process: ProcessMemory
+    plugins: List[PluginMemory]
+    attributed_bytes: int
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "MemoryReport":
+        return cls(
+            process=ProcessMemory.from_dict(data.get("process")),
+            plugins=[PluginMemory.from_dict(item) for item in (data.get("plugins") or [])],
+            attributed_bytes=data.get("attributed_bytes"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["process"] = self.process.to_dict()
+        result["plugins"] = [item.to_dict() for item in self.plugins]
+        result["attributed_bytes"] = self.attributed_bytes
+        return result
+
+
+@dataclass
+class VersionInfo:
+    version: str
+    go: str
+    engine: EngineInfo
+    features: Features
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "VersionInfo":
+        return cls(
+            version=data.get("version"),
+            go=data.get("go"),
+            engine=EngineInfo.from_dict(data.get("engine")),
+            features=Features.from_dict(data.get("features")),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["version"] = self.version
+        result["go"] = self.go
+        result["engine"] = self.engine.to_dict()
+        result["features"] = self.features.to_dict()
+        return result
+
+
+@dataclass
+class EngineInfo:
+    version: str
+    statistics: bool
+    name: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "EngineInfo":
+        return cls(
+            version=data.get("version"),
+            statistics=data.get("statistics"),
+            name=data.get("name"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["version"] = self.version
+        result["statistics"] = self.statistics
+        if self.name is not None:
+            result["name"] = self.name
+        return result
+
+
+@dataclass
+class Features:
+    wall_clock_limits: bool
+    instruction_limits: bool
+    degraded: List[str] = field(default_factory=list)
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Features":
+        return cls(
+            wall_clock_limits=data.get("wall_clock_limits"),
+            instruction_limits=data.get("instruction_limits"),
+            degraded=list(data.get("degraded") or []),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["wall_clock_limits"] = self.wall_clock_limits
+        result["instruction_limits"] = self.instruction_limits
+        if self.degraded:
+            result["degraded"] = self.degraded
+        return result
+
+
+@dataclass
+class StartupReport:
+    started: str
+    version: str
+    store: StoreReport
+    engine: EngineInfo
+    limits: Features
+    features: List[FeatureReport]
+    warmup: List[WarmupResult]
+    listen: ListenReport
+    endpoints: List[str]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "StartupReport":
+        return cls(
+            started=data.get("started"),
+            version=data.get("version"),
+            store=StoreReport.from_dict(data.get("store")),
+            engine=EngineInfo.from_dict(data.get("engine")),
+            limits=Features.from_dict(data.get("limits")),
+            features=[FeatureReport.from_dict(item) for item in (data.get("features") or [])],
+            warmup=[WarmupResult.from_dict(item) for item in (data.get("warmup") or [])],
+            listen=ListenReport.from_dict(data.get("listen")),
+            endpoints=list(data.get("endpoints") or []),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["started"] = self.started
+        result["version"] = self.version
+        result["store"] = self.store.to_dict()
+        result["engine"] = self.engine.to_dict()
+        result["limits"] = self.limits.to_dict()
+        result["features"] = [item.to_dict() for item in self.features]
+        result["warmup"] = [item.to_dict() for item in self.warmup]
+        result["listen"] = self.listen.to_dict()
+        result["endpoints"] = self.endpoints
+        return result
+
+
+@dataclass
+class StoreReport:
+    type: str
+    location: Optional[str] = None
+    cache: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "StoreReport":
+        return cls(
+            type=data.get("type"),
+            location=data.get("location"),
+            cache=data.get("cache"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["type"] = self.type
+        if self.location is not None:
+            result["location"] = self.location
+        if self.cache is not None:
+            result["cache"] = self.cache
+        return result
+
+
+@dataclass
+class FeatureReport:
+    name: str
+    detail: str
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "FeatureReport":
+        return cls(
+            name=data.get("name"),
+            detail=data.get("detail"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["name"] = self.name
+        result["detail"] = self.detail
+        return result
+
+
+@dataclass
+class WarmupResult:
+    plugin: str
+    duration_ns: int
+    compiled: bool
+    error: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "WarmupResult":
+        return cls(
+            plugin=data.get("plugin"),
+            duration_ns=data.get("duration_ns"),
+            compiled=data.get("compiled"),
+            error=data.get("error"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["duration_ns"] = self.duration_ns
+        result["compiled"] = self.compiled
+        if self.error is not None:
+            result["error"] = self.error
+        return result
+
+
+@dataclass
+class EvictRequest:
+    plugin: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "EvictRequest":
+        return cls(
+            plugin=data.get("plugin"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        if self.plugin is not None:
+            result["plugin"] = self.plugin
+        return result
+
+
+@dataclass
+class EvictResponse:
+    evicted: List[EvictedPool]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "EvictResponse":
+        return cls(
+            evicted=[EvictedPool.from_dict(item) for item in (data.get("evicted") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["evicted"] = [item.to_dict() for item in self.evicted]
+        return result
+
+
+@dataclass
+class EvictedPool:
+    plugin: str
+    reason: str
+    instances: int
+    memory_bytes: int
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "EvictedPool":
+        return cls(
+            plugin=data.get("plugin"),
+            reason=data.get("reason"),
+            instances=data.get("instances"),
+            memory_bytes=data.get("memory_bytes"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["reason"] = self.reason
+        result["instances"] = self.instances
+        result["memory_bytes"] = self.memory_bytes
+        return result
+
+
+@dataclass
+class ReloadResponse:
+    changes: List[ReloadedBuild]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ReloadResponse":
+        return cls(
+            changes=[ReloadedBuild.from_dict(item) for item in (data.get("changes") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["changes"] = [item.to_dict() for item in self.changes]
+        return result
+
+
+@dataclass
+class ReloadedBuild:
+    plugin: str
+    change: str
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ReloadedBuild":
+        return cls(
+            plugin=data.get("plugin"),
+            change=data.get("change"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["change"] = self.change
+        return result
+
+
+@dataclass
+class AdminPool:
+    plugin: str
+    last_used: str
+    stats: PoolInfo
+    memory: PoolMemory
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "AdminPool":
+        return cls(
+            plugin=data.get("plugin"),
+            last_used=data.get("last_used"),
+            stats=PoolInfo.from_dict(data.get("stats")),
+            memory=PoolMemory.from_dict(data.get("memory")),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["last_used"] = self.last_used
+        result["stats"] = self.stats.to_dict()
+        result["memory"] = self.memory.to_dict()
+        return result
+
+
+@dataclass
+class PoolMemory:
+    instances: int
+    linear_memory_bytes: int
+    snapshot_bytes: int
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "PoolMemory":
+        return cls(
+            instances=data.get("instances"),
+            linear_memory_bytes=data.get("linear_memory_bytes"),
+            snapshot_bytes=data.get("snapshot_bytes"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["instances"] = self.instances
+        result["linear_memory_bytes"] = self.linear_memory_bytes
+        result["snapshot_bytes"] = self.snapshot_bytes
+        return result
+
+
+@dataclass
+class JWKS:
+    keys: List[JWK]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "JWKS":
+        return cls(
+            keys=[JWK.from_dict(item) for item in (data.get("keys") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["keys"] = [item.to_dict() for item in self.keys]
+        return result
+
+
+@dataclass
+class JWK:
+    kty: str
+    kid: str
+    crv: str
+    x: str
+    use: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "JWK":
+        return cls(
+            kty=data.get("kty"),
+            kid=data.get("kid"),
+            crv=data.get("crv"),
+            x=data.get("x"),
+            use=data.get("use"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["kty"] = self.kty
+        result["kid"] = self.kid
+        result["crv"] = self.crv
+        result["x"] = self.x
+        if self.use is not None:
+            result["use"] = self.use
+        return result
+
+
+@dataclass
+class LogLevel:
+    level: str
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "LogLevel":
+        return cls(
+            level=data.get("level"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["level"] = self.level
+        return result
+
+
+@dataclass
+class Readiness:
+    ready: bool
+    warmup: List[WarmupResult]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Readiness":
+        return cls(
+            ready=data.get("ready"),
+            warmup=[WarmupResult.from_dict(item) for item in (data.get("warmup") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["ready"] = self.ready
+        result["warmup"] = [item.to_dict() for item in self.warmup]
+        return result
+
+
+@dataclass
+class ListenReport:
+    http: str
+    grpc: str
+    tls: bool
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ListenReport":
+        return cls(
+            http=data.get("http"),
+            grpc=data.get("grpc"),
+            tls=data.get("tls"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["http"] = self.http
+        result["grpc"] = self.grpc
+        result["tls"] = self.tls
+        return result
+
+
+@dataclass
+class VerifyReport:
+    started: str
+    duration_ms: int
+    builds: int
+    failures: List[VerifyFailure]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "VerifyReport":
+        return cls(
+            started=data.get("started"),
+            duration_ms=data.get("duration_ms"),
+            builds=data.get("builds"),
+            failures=[VerifyFailure.from_dict(item) for item in (data.get("failures") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["started"] = self.started
+        result["duration_ms"] = self.duration_ms
+        result["builds"] = self.builds
+        result["failures"] = [item.to_dict() for item in self.failures]
+        return result
+
+
+@dataclass
+class VerifyFailure:
+    plugin: str
+    check: str
+    error: str
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "VerifyFailure":
+        return cls(
+            plugin=data.get("plugin"),
+            check=data.get("check"),
+            error=data.get("error"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["check"] = self.check
+        result["error"] = self.error
+        return result
+
+
+@dataclass
+class ShadowReport:
+    engine: str
+    ratio: float
+    plugins: List[ShadowStats]
+    mismatches: List[ShadowMismatch]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ShadowReport":
+        return cls(
+            engine=data.get("engine"),
+            ratio=data.get("ratio"),
+            plugins=[ShadowStats.from_dict(item) for item in (data.get("plugins") or [])],
+            mismatches=[ShadowMismatch.from_dict(item) for item in (data.get("mismatches") or [])],
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["engine"] = self.engine
+        result["ratio"] = self.ratio
+        result["plugins"] = [item.to_dict() for item in self.plugins]
+        result["mismatches"] = [item.to_dict() for item in self.mismatches]
+        return result
+
+
+@dataclass
+class ShadowStats:
+    plugin: str
+    matches: int
+    mismatches: int
+    errors: int
+    skipped: int
+    primary_ms: float
+    shadow_ms: float
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ShadowStats":
+        return cls(
+            plugin=data.get("plugin"),
+            matches=data.get("matches"),
+            mismatches=data.get("mismatches"),
+            errors=data.get("errors"),
+            skipped=data.get("skipped"),
+            primary_ms=data.get("primary_ms"),
+            shadow_ms=data.get("shadow_ms"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugin"] = self.plugin
+        result["matches"] = self.matches
+        result["mismatches"] = self.mismatches
+        result["errors"] = self.errors
+        result["skipped"] = self.skipped
+        result["primary_ms"] = self.primary_ms
+        result["shadow_ms"] = self.shadow_ms
+        return result
+
+
+@dataclass
+class ShadowMismatch:
+    time: str
+    request_id: str
+    plugin: str
+    outcome: str
+    primary: str
+    shadow: Optional[str] = None
+    error: Optional[str] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ShadowMismatch":
+        return cls(
+            time=data.get("time"),
+            request_id=data.get("request_id"),
+            plugin=data.get("plugin"),
+            outcome=data.get("outcome"),
+            primary=data.get("primary"),
+            shadow=data.get("shadow"),
+            error=data.get("error"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["time"] = self.time
+        result["request_id"] = self.request_id
+        result["plugin"] = self.plugin
+        result["outcome"] = self.outcome
+        result["primary"] = self.primary
+        if self.shadow is not None:
+            result["shadow"] = self.shadow
+        if self.error is not None:
+            result["error"] = self.error
+        return result
+
+
+@dataclass
+class ProcessMemory:
+    go_heap_bytes: int
+    go_sys_bytes: int
+    rss_bytes: Optional[int] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "ProcessMemory":
+        return cls(
+            go_heap_bytes=data.get("go_heap_bytes"),
+            go_sys_bytes=data.get("go_sys_bytes"),
+            rss_bytes=data.get("rss_bytes"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["go_heap_bytes"] = self.go_heap_bytes
+        result["go_sys_bytes"] = self.go_sys_bytes
+        if self.rss_bytes is not None:
+            result["rss_bytes"] = self.rss_bytes
+        return result
+
+
+@dataclass
+class PluginMemory:
+    plugins: List[str]
+    digest: str
+    paths: List[str]
+    instances: int
+    linear_memory_bytes: int
+    snapshot_bytes: int
+    module_bytes: int
+    artifact_bytes: int
+    total_bytes: int
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "PluginMemory":
+        return cls(
+            plugins=list(data.get("plugins") or []),
+            digest=data.get("digest"),
+            paths=list(data.get("paths") or []),
+            instances=data.get("instances"),
+            linear_memory_bytes=data.get("linear_memory_bytes"),
+            snapshot_bytes=data.get("snapshot_bytes"),
+            module_bytes=data.get("module_bytes"),
+            artifact_bytes=data.get("artifact_bytes"),
+            total_bytes=data.get("total_bytes"),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result: Dict[str, Any] = {}
+        result["plugins"] = self.plugins
+        result["digest"] = self.digest
+        result["paths"] = self.paths
+        result["instances"] = self.instances
+        result["linear_memory_bytes"] = self.linear_memory_bytes
+        result["snapshot_bytes"] = self.snapshot_bytes
+        result["module_bytes"] = self.module_bytes
+        result["artifact_bytes"] = self.artifact_bytes
+        result["total_bytes"] = self.total_bytes
+        return result
@@ -0,0 +1,109 @@
+// Package sdk holds what plugin authors build against: the plugin ABI and
+// host bindings for C and C++ (c/plugin.h) and AssemblyScript
+// (assemblyscript/plugin.ts), and the project templates `pluginctl new`
+// scaffolds from. The Python client SDK lives in sdk/python.
+package sdk
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed c/plugin.h assemblyscript/plugin.ts all:templates
+var files embed.FS
+
+// ErrUnknownLanguage is returned by Scaffold for a language without a
+// template.
+var ErrUnknownLanguage = errors.New("unknown plugin language")
+
+// bindings are the files copied unchanged into a new project of each
+// language, by destination path.
+var bindings = map[string]map[string]string{
+	"c":              {"plugin.h": "c/plugin.h"},
+	"cpp":            {"plugin.h": "c/plugin.h"},
+	"assemblyscript": {"assembly/plugin.ts": "assemblyscript/plugin.ts"},
+}
+
+// Languages returns the languages Scaffold has templates for, sorted.
+func Languages() []string {
+	langs := make([]string, 0, len(bindings))
+	for lang := range bindings {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Scaffold writes a new plugin project named name in lang into dir,
+// creating it if needed, and returns the paths written, relative to dir.
+//
+// The project builds name.wasm implementing the core and payload ABIs,
+// with a plugin.json whose tests pass against it. Nothing is written if
+// any of its files already exists in dir.
+func Scaffold(dir, name, lang string) ([]string, error) {
+	if _, ok := bindings[lang]; !ok {
+		return nil, fmt.Errorf("%w %q (want %s)", ErrUnknownLanguage, lang, strings.Join(Languages(), ", "))
+	}
+
+	// Render everything first, so a conflict leaves dir untouched
+	project := map[string][]byte{}
+	root := path.Join("templates", lang)
+	err := fs.WalkDir(files, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		text, err := files.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(p).Parse(string(text))
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, struct{ Name string }{name}); err != nil {
+			return err
+		}
+		dest := strings.TrimSuffix(strings.TrimPrefix(p, root+"/"), ".tmpl")
+		project[strings.ReplaceAll(dest, "_name_", name)] = out.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s template: %w", lang, err)
+	}
+	for dest, src := range bindings[lang] {
+		data, err := files.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		project[dest] = data
+	}
+
+	paths := make([]string, 0, len(project))
+	for p := range project {
+		if _, err := os.Stat(filepath.Join(dir, p)); err == nil {
+			return nil, fmt.Errorf("%s already exists", filepath.Join(dir, p))
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		dest := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dest, project[p], 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
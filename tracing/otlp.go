@@ -0,0 +1,250 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// OTLPConfig configures OTLPExporter.
+type OTLPConfig struct {
+	// Endpoint is the collector's OTLP/HTTP base URL, e.g.
+	// http://otel-collector:4318; spans are posted to its /v1/traces.
+	Endpoint string
+
+	// Headers are sent with every export, e.g. an API key of a hosted
+	// backend.
+	Headers map[string]string
+
+	// ServiceName is the service.name resource attribute.
+	ServiceName string
+
+	// Client sends the requests; nil uses a client with a 10s timeout.
+	Client *http.Client
+}
+
+// OTLPExporter exports spans with OTLP/HTTP in its JSON encoding, which
+// Jaeger, Tempo, and the OpenTelemetry Collector accept on port 4318.
+type OTLPExporter struct {
+	url     string
+	headers map[string]string
+	service string
+	client  *http.Client
+}
+
+// NewOTLPExporter creates an exporter for cfg.
+func NewOTLPExporter(cfg OTLPConfig) (*OTLPExporter, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("OTLP endpoint must be an http or https URL, got %q", cfg.Endpoint)
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OTLPExporter{
+		url:     strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/traces",
+		headers: cfg.Headers,
+		service: cfg.ServiceName,
+		client:  client,
+	}, nil
+}
+
+// Export posts spans to the collector.
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(spans), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to export %d spans: %s: %s", len(spans), resp.Status, bytes.TrimSpace(detail))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// The OTLP JSON encoding of an ExportTraceServiceRequest. IDs are hex,
+// 64-bit integers and timestamps decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		TraceState        string         `json:"traceState,omitempty"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string         `json:"stringValue,omitempty"`
+		BoolValue   *bool           `json:"boolValue,omitempty"`
+		IntValue    *string         `json:"intValue,omitempty"`
+		DoubleValue *float64        `json:"doubleValue,omitempty"`
+		ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	}
+	otlpArrayValue struct {
+		Values []otlpValue `json:"values"`
+	}
+)
+
+// request groups spans by scope under the service's resource.
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	resource := otlpResourceSpans{Resource: otlpResource{
+		Attributes: otlpAttributes([]attribute.KeyValue{attribute.String("service.name", e.service)}),
+	}}
+	scopes := make(map[string]int)
+	for _, s := range spans {
+		i, ok := scopes[s.Scope]
+		if !ok {
+			i = len(resource.ScopeSpans)
+			scopes[s.Scope] = i
+			resource.ScopeSpans = append(resource.ScopeSpans, otlpScopeSpans{Scope: otlpScope{Name: s.Scope}})
+		}
+		span := otlpSpan{
+			TraceID:           s.SpanContext.TraceID().String(),
+			SpanID:            s.SpanContext.SpanID().String(),
+			TraceState:        s.SpanContext.TraceState().String(),
+			Name:              s.Name,
+			Kind:              int(s.Kind),
+			StartTimeUnixNano: unixNano(s.Start),
+			EndTimeUnixNano:   unixNano(s.End),
+			Attributes:        otlpAttributes(s.Attributes),
+		}
+		if s.Parent.IsValid() {
+			span.ParentSpanID = s.Parent.SpanID().String()
+		}
+		// OTLP numbers the codes differently: Unset 0, Ok 1, Error 2
+		switch s.Status {
+		case codes.Ok:
+			span.Status.Code = 1
+		case codes.Error:
+			span.Status = otlpStatus{Code: 2, Message: s.StatusMessage}
+		}
+		for _, event := range s.Events {
+			span.Events = append(span.Events, otlpEvent{
+				TimeUnixNano: unixNano(event.Time),
+				Name:         event.Name,
+				Attributes:   otlpAttributes(event.Attributes),
+			})
+		}
+		resource.ScopeSpans[i].Spans = append(resource.ScopeSpans[i].Spans, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{resource}}
+}
+
+// unixNano formats t as OTLP JSON does.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpAttributes converts attributes to OTLP key-values.
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, otlpKeyValue{Key: string(attr.Key), Value: otlpValueOf(attr.Value)})
+	}
+	return out
+}
+
+// otlpValueOf converts an attribute value to an OTLP AnyValue.
+func otlpValueOf(v attribute.Value) otlpValue {
+	str := func(s string) otlpValue { return otlpValue{StringValue: &s} }
+	integer := func(n int64) otlpValue {
+		s := strconv.FormatInt(n, 10)
+		return otlpValue{IntValue: &s}
+	}
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpValue{BoolValue: &b}
+	case attribute.INT64:
+		return integer(v.AsInt64())
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpValue{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		array := &otlpArrayValue{Values: []otlpValue{}}
+		for _, b := range v.AsBoolSlice() {
+			array.Values = append(array.Values, otlpValue{BoolValue: &b})
+		}
+		return otlpValue{ArrayValue: array}
+	case attribute.INT64SLICE:
+		array := &otlpArrayValue{Values: []otlpValue{}}
+		for _, n := range v.AsInt64Slice() {
+			array.Values = append(array.Values, integer(n))
+		}
+		return otlpValue{ArrayValue: array}
+	case attribute.FLOAT64SLICE:
+		array := &otlpArrayValue{Values: []otlpValue{}}
+		for _, f := range v.AsFloat64Slice() {
+			array.Values = append(array.Values, otlpValue{DoubleValue: &f})
+		}
+		return otlpValue{ArrayValue: array}
+	case attribute.STRINGSLICE:
+		array := &otlpArrayValue{Values: []otlpValue{}}
+		for _, s := range v.AsStringSlice() {
+			array.Values = append(array.Values, str(s))
+		}
+		return otlpValue{ArrayValue: array}
+	default:
+		return str(v.Emit())
+	}
+}
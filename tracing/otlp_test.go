@@ -0,0 +1,126 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+var _ = Describe("OTLPExporter", func() {
+	var (
+		requests chan *http.Request
+		bodies   chan []byte
+		status   int
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		requests = make(chan *http.Request, 1)
+		bodies = make(chan []byte, 1)
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			requests <- r
+			bodies <- body
+			w.WriteHeader(status)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	span := tracing.SpanData{
+		Name:  "plugin.Execute",
+		Scope: "runtime",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x0a, 0x0b},
+			SpanID:     trace.SpanID{0x01},
+			TraceFlags: trace.FlagsSampled,
+		}),
+		Parent: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{0x0a, 0x0b},
+			SpanID:  trace.SpanID{0x02},
+		}),
+		Kind:          trace.SpanKindInternal,
+		Start:         time.Unix(1, 0),
+		End:           time.Unix(2, 0),
+		Attributes:    []attribute.KeyValue{tracing.AttrPlugin.String("hello"), tracing.AttrInputSize.Int(5)},
+		Status:        codes.Error,
+		StatusMessage: "process() returned -3",
+	}
+
+	// =========================================================================
+	// TEST: OTLP/HTTP JSON encoding
+	// Why: Collectors reject spans whose IDs, timestamps, or status codes
+	//      are not encoded as the OTLP JSON mapping requires.
+	// =========================================================================
+	It("should post spans to /v1/traces as OTLP JSON", func() {
+		exporter, err := tracing.NewOTLPExporter(tracing.OTLPConfig{
+			Endpoint:    server.URL + "/",
+			Headers:     map[string]string{"Authorization": "Bearer secret"},
+			ServiceName: "wasm-plugin-server",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exporter.Export(context.Background(), []tracing.SpanData{span})).To(Succeed())
+
+		r := <-requests
+		Expect(r.URL.Path).To(Equal("/v1/traces"))
+		Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer secret"))
+		Expect(<-bodies).To(MatchJSON(`{"resourceSpans": [{
+			"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "wasm-plugin-server"}}]},
+			"scopeSpans": [{
+				"scope": {"name": "runtime"},
+				"spans": [{
+					"traceId": "0a0b0000000000000000000000000000",
+					"spanId": "0100000000000000",
+					"parentSpanId": "0200000000000000",
+					"name": "plugin.Execute",
+					"kind": 1,
+					"startTimeUnixNano": "1000000000",
+					"endTimeUnixNano": "2000000000",
+					"attributes": [
+						{"key": "plugin.name", "value": {"stringValue": "hello"}},
+						{"key": "plugin.input_size", "value": {"intValue": "5"}}
+					],
+					"status": {"code": 2, "message": "process() returned -3"}
+				}]
+			}]
+		}]}`))
+	})
+
+	It("should report a rejected export", func() {
+		status = http.StatusBadRequest
+		exporter, err := tracing.NewOTLPExporter(tracing.OTLPConfig{Endpoint: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exporter.Export(context.Background(), []tracing.SpanData{span})).To(
+			MatchError(ContainSubstring("400 Bad Request")))
+	})
+
+	It("should reject an endpoint that is not an http(s) URL", func() {
+		_, err := tracing.NewOTLPExporter(tracing.OTLPConfig{Endpoint: "collector:4317"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should export the spans of a provider", func() {
+		exporter, err := tracing.NewOTLPExporter(tracing.OTLPConfig{Endpoint: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		provider := tracing.NewProvider(exporter, tracing.Options{SampleRatio: 1})
+		_, s := provider.Tracer("test").Start(context.Background(), "POST /run")
+		s.End()
+		Expect(provider.Shutdown(context.Background())).To(Succeed())
+
+		var decoded map[string]any
+		Expect(json.Unmarshal(<-bodies, &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKey("resourceSpans"))
+	})
+})
@@ -0,0 +1,504 @@
+// Package tracing records OpenTelemetry spans and exports them over OTLP.
+//
+// Instrumented packages use the OpenTelemetry trace API through Tracer,
+// which delegates to the provider installed with SetTracerProvider and
+// records nothing until one is. Provider is a minimal tracer provider:
+// parent-based ratio sampling, a bounded queue, and batched export to an
+// Exporter such as OTLPExporter, so the server can send traces to Jaeger,
+// Tempo, or any OTLP collector without the OpenTelemetry SDK.
+//
+// # Span names
+//
+// Spans follow the request through the server: "POST /run" (or the gRPC
+// method), "store.Resolve", "plugin.Load", "plugin.Init", "plugin.Execute",
+// and "plugin.Cleanup" when an instance is discarded.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Attribute keys shared by the instrumented packages.
+const (
+	AttrPlugin       = attribute.Key("plugin.name")
+	AttrInputSize    = attribute.Key("plugin.input_size")
+	AttrABIVersion   = attribute.Key("plugin.abi_version")
+	AttrExport       = attribute.Key("plugin.export")
+	AttrErrorCode    = attribute.Key("error.code")        // apierror code of a failed request
+	AttrABIErrorCode = attribute.Key("plugin.error_code") // ABI code a plugin export returned
+	AttrRequestID    = attribute.Key("request.id")
+)
+
+// global holds the installed provider; a noop provider until one is set.
+var global atomic.Value
+
+func init() {
+	global.Store(holder{noop.NewTracerProvider()})
+}
+
+// holder wraps providers of different types for atomic.Value.
+type holder struct {
+	provider trace.TracerProvider
+}
+
+// SetTracerProvider installs the provider used by every Tracer, including
+// those obtained before the call.
+func SetTracerProvider(provider trace.TracerProvider) {
+	global.Store(holder{provider})
+}
+
+// Tracer returns a tracer named after the instrumented package, e.g.
+// "github.com/mrhapile/wasm-plugin-system/runtime".
+func Tracer(name string) trace.Tracer {
+	return delegate{name: name}
+}
+
+// delegate starts spans with the provider installed at the time.
+type delegate struct {
+	embedded.Tracer
+	name string
+}
+
+// Start starts a span with the installed provider's tracer.
+func (d delegate) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return global.Load().(holder).provider.Tracer(d.name).Start(ctx, name, opts...)
+}
+
+// End ends span, recording err, if not nil, as its error status.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SpanData is a finished span as exporters receive it.
+type SpanData struct {
+	Name          string
+	Scope         string // Name of the tracer that started the span
+	SpanContext   trace.SpanContext
+	Parent        trace.SpanContext // Invalid for a root span
+	Kind          trace.SpanKind
+	Start, End    time.Time
+	Attributes    []attribute.KeyValue
+	Events        []Event
+	Status        codes.Code
+	StatusMessage string
+}
+
+// Event is an event recorded on a span, such as an error.
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []attribute.KeyValue
+}
+
+// Exporter sends finished spans to a backend. Provider calls Export from
+// one goroutine at a time.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Options configures Provider.
+type Options struct {
+	// SampleRatio is the fraction of root traces recorded; spans with a
+	// parent follow its sampling decision. Zero records none.
+	SampleRatio float64
+
+	// BatchSize bounds the spans of one export; zero uses 512.
+	BatchSize int
+
+	// Interval is how long finished spans wait for a batch to fill before
+	// they are exported anyway; zero uses 5s.
+	Interval time.Duration
+
+	// QueueSize bounds the finished spans waiting for export; spans
+	// finished while it is full are dropped. Zero uses 2048.
+	QueueSize int
+
+	// OnError is called with export errors; nil ignores them.
+	OnError func(error)
+}
+
+// Provider is a trace.TracerProvider recording sampled spans and
+// exporting them in batches from a background goroutine. It is safe for
+// concurrent use.
+type Provider struct {
+	embedded.TracerProvider
+
+	exporter Exporter
+	opts     Options
+	queue    chan SpanData
+	flush    chan chan struct{}
+	done     chan struct{}
+	stopped  sync.WaitGroup
+	dropped  atomic.Uint64
+	shutdown sync.Once
+}
+
+// NewProvider creates a provider exporting to exporter. Call Shutdown to
+// export the remaining spans.
+func NewProvider(exporter Exporter, opts Options) *Provider {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2048
+	}
+	p := &Provider{
+		exporter: exporter,
+		opts:     opts,
+		queue:    make(chan SpanData, opts.QueueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	p.stopped.Add(1)
+	go p.run()
+	return p
+}
+
+// Tracer returns a tracer whose spans carry name as their scope.
+func (p *Provider) Tracer(name string, _ ...trace.TracerOption) trace.Tracer {
+	return &tracer{provider: p, scope: name}
+}
+
+// Dropped returns the number of spans dropped because the queue was full.
+func (p *Provider) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// ForceFlush exports the spans finished so far.
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case p.flush <- flushed:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and stops the provider. Spans
+// ending afterwards are dropped.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.shutdown.Do(func() { close(p.done) })
+	stopped := make(chan struct{})
+	go func() {
+		p.stopped.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run batches finished spans and exports them.
+func (p *Provider) run() {
+	defer p.stopped.Done()
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := p.exporter.Export(ctx, batch); err != nil && p.opts.OnError != nil {
+			p.opts.OnError(err)
+		}
+		cancel()
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-p.queue:
+				batch = append(batch, span)
+				if len(batch) >= p.opts.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.opts.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-p.flush:
+			drain()
+			close(flushed)
+		case <-p.done:
+			drain()
+			return
+		}
+	}
+}
+
+// enqueue hands a finished span to the export goroutine.
+func (p *Provider) enqueue(data SpanData) {
+	select {
+	case <-p.done:
+		p.dropped.Add(1)
+		return
+	default:
+	}
+	select {
+	case p.queue <- data:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// sampled decides whether a root span of traceID is recorded, from the
+// low 63 bits of its second half, as OpenTelemetry's TraceIDRatioBased.
+func (p *Provider) sampled(traceID trace.TraceID) bool {
+	if p.opts.SampleRatio >= 1 {
+		return true
+	}
+	bound := uint64(p.opts.SampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < bound
+}
+
+// tracer starts spans of one scope.
+type tracer struct {
+	embedded.Tracer
+	provider *Provider
+	scope    string
+}
+
+// Start starts a span, the child of the span in ctx, if any. Unsampled
+// spans record nothing but carry a span context to propagate.
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+	parent := trace.SpanContextFromContext(ctx)
+	if config.NewRoot() {
+		parent = trace.SpanContext{}
+	}
+
+	sc := trace.SpanContextConfig{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID, sc.TraceState = parent.TraceID(), parent.TraceState()
+		if parent.IsSampled() {
+			sc.TraceFlags = trace.FlagsSampled
+		}
+	} else {
+		sc.TraceID = newTraceID()
+		if t.provider.sampled(sc.TraceID) {
+			sc.TraceFlags = trace.FlagsSampled
+		}
+	}
+	spanContext := trace.NewSpanContext(sc)
+	if !spanContext.IsSampled() {
+		s := nonRecording{provider: t.provider, sc: spanContext}
+		return trace.ContextWithSpan(ctx, s), s
+	}
+
+	start := config.Timestamp()
+	if start.IsZero() {
+		start = time.Now()
+	}
+	s := &span{provider: t.provider, data: SpanData{
+		Name:        name,
+		Scope:       t.scope,
+		SpanContext: spanContext,
+		Parent:      parent,
+		Kind:        config.SpanKind(),
+		Start:       start,
+		Attributes:  append([]attribute.KeyValue(nil), config.Attributes()...),
+	}}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+// newTraceID returns a random, valid trace ID.
+func newTraceID() trace.TraceID {
+	var id trace.TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+// newSpanID returns a random, valid span ID.
+func newSpanID() trace.SpanID {
+	var id trace.SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
+
+// span is a recording span.
+type span struct {
+	embedded.Span
+	provider *Provider
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *span) End(options ...trace.SpanEndOption) {
+	config := trace.NewSpanEndConfig(options...)
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = config.Timestamp()
+	if s.data.End.IsZero() {
+		s.data.End = time.Now()
+	}
+	data := s.data
+	s.mu.Unlock()
+	s.provider.enqueue(data)
+}
+
+// AddEvent records an event.
+func (s *span) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	s.addEvent(Event{Name: name, Time: config.Timestamp(), Attributes: config.Attributes()})
+}
+
+// addEvent records an event unless the span has ended.
+func (s *span) addEvent(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Events = append(s.data.Events, event)
+	}
+}
+
+// AddLink is not supported; links are ignored.
+func (s *span) AddLink(trace.Link) {}
+
+// IsRecording reports whether the span has not ended yet.
+func (s *span) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+// RecordError records err as an "exception" event, as OpenTelemetry's
+// semantic conventions name it.
+func (s *span) RecordError(err error, options ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	config := trace.NewEventConfig(options...)
+	attrs := append([]attribute.KeyValue{
+		attribute.String("exception.message", err.Error()),
+	}, config.Attributes()...)
+	s.addEvent(Event{Name: "exception", Time: config.Timestamp(), Attributes: attrs})
+}
+
+// SpanContext returns the span's identity.
+func (s *span) SpanContext() trace.SpanContext {
+	return s.data.SpanContext
+}
+
+// SetStatus sets the status; Ok overrides Error, which overrides Unset.
+func (s *span) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || code == codes.Unset || s.data.Status == codes.Ok {
+		return
+	}
+	s.data.Status = code
+	s.data.StatusMessage = ""
+	if code == codes.Error {
+		s.data.StatusMessage = description
+	}
+}
+
+// SetName renames the span.
+func (s *span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Name = name
+	}
+}
+
+// SetAttributes sets attributes, replacing those with the same keys.
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+next:
+	for _, attr := range kv {
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i] = attr
+				continue next
+			}
+		}
+		s.data.Attributes = append(s.data.Attributes, attr)
+	}
+}
+
+// TracerProvider returns the provider that started the span.
+func (s *span) TracerProvider() trace.TracerProvider {
+	return s.provider
+}
+
+// nonRecording is an unsampled span: it records nothing but propagates
+// its span context, so downstream services make the same decision.
+type nonRecording struct {
+	embedded.Span
+	provider *Provider
+	sc       trace.SpanContext
+}
+
+func (nonRecording) End(...trace.SpanEndOption)              {}
+func (nonRecording) AddEvent(string, ...trace.EventOption)   {}
+func (nonRecording) AddLink(trace.Link)                      {}
+func (nonRecording) IsRecording() bool                       { return false }
+func (nonRecording) RecordError(error, ...trace.EventOption) {}
+func (s nonRecording) SpanContext() trace.SpanContext        { return s.sc }
+func (nonRecording) SetStatus(codes.Code, string)            {}
+func (nonRecording) SetName(string)                          {}
+func (nonRecording) SetAttributes(...attribute.KeyValue)     {}
+func (s nonRecording) TracerProvider() trace.TracerProvider  { return s.provider }
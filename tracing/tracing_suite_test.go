@@ -0,0 +1,15 @@
+package tracing_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TestTracing bootstraps the Ginkgo test suite for the tracing package.
+// Run with: go test -v ./tracing/...
+func TestTracing(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tracing Suite")
+}
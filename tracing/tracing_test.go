@@ -0,0 +1,133 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/mrhapile/wasm-plugin-system/tracing"
+)
+
+// memoryExporter collects exported spans.
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *memoryExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) exported() []tracing.SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]tracing.SpanData(nil), e.spans...)
+}
+
+var _ = Describe("Provider", func() {
+	var (
+		exporter *memoryExporter
+		provider *tracing.Provider
+	)
+
+	BeforeEach(func() {
+		exporter = &memoryExporter{}
+		provider = tracing.NewProvider(exporter, tracing.Options{SampleRatio: 1})
+		DeferCleanup(provider.Shutdown, context.Background())
+	})
+
+	// =========================================================================
+	// TEST: Nested spans form one trace
+	// Why: The latency breakdown of a run is only visible if the store and
+	//      plugin spans hang below the request span.
+	// =========================================================================
+	It("should export child spans with their parent", func() {
+		tracer := provider.Tracer("test")
+		ctx, parent := tracer.Start(context.Background(), "POST /run", trace.WithSpanKind(trace.SpanKindServer))
+		_, child := tracer.Start(ctx, "plugin.Execute", trace.WithAttributes(tracing.AttrPlugin.String("hello")))
+		child.End()
+		parent.End()
+		Expect(provider.ForceFlush(context.Background())).To(Succeed())
+
+		spans := exporter.exported()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("plugin.Execute"))
+		Expect(spans[0].Scope).To(Equal("test"))
+		Expect(spans[0].Attributes).To(ContainElement(tracing.AttrPlugin.String("hello")))
+		Expect(spans[0].SpanContext.TraceID()).To(Equal(spans[1].SpanContext.TraceID()))
+		Expect(spans[0].Parent.SpanID()).To(Equal(spans[1].SpanContext.SpanID()))
+		Expect(spans[1].Parent.IsValid()).To(BeFalse())
+		Expect(spans[1].Kind).To(Equal(trace.SpanKindServer))
+	})
+
+	// =========================================================================
+	// TEST: Errors
+	// Why: Failed runs are the ones worth finding in Jaeger or Tempo.
+	// =========================================================================
+	It("should record an error as the span's status", func() {
+		_, span := provider.Tracer("test").Start(context.Background(), "plugin.Init")
+		tracing.End(span, errors.New("init() returned -1"))
+		Expect(provider.Shutdown(context.Background())).To(Succeed())
+
+		spans := exporter.exported()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status).To(Equal(codes.Error))
+		Expect(spans[0].StatusMessage).To(Equal("init() returned -1"))
+		Expect(spans[0].Events).To(HaveLen(1))
+		Expect(spans[0].Events[0].Name).To(Equal("exception"))
+	})
+
+	// =========================================================================
+	// TEST: Sampling
+	// Why: Unsampled traces must cost nothing to export, but a caller's
+	//      sampling decision wins so its traces stay complete.
+	// =========================================================================
+	It("should follow the sample ratio for roots and the parent otherwise", func() {
+		unsampled := tracing.NewProvider(exporter, tracing.Options{SampleRatio: 0})
+		ctx, root := unsampled.Tracer("test").Start(context.Background(), "root")
+		Expect(root.SpanContext().IsValid()).To(BeTrue())
+		Expect(root.SpanContext().IsSampled()).To(BeFalse())
+		root.End()
+
+		remote := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{2},
+			TraceFlags: trace.FlagsSampled,
+			Remote:     true,
+		})
+		_, child := unsampled.Tracer("test").Start(trace.ContextWithRemoteSpanContext(ctx, remote), "child")
+		child.End()
+		Expect(unsampled.Shutdown(context.Background())).To(Succeed())
+
+		spans := exporter.exported()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name).To(Equal("child"))
+		Expect(spans[0].SpanContext.TraceID()).To(Equal(remote.TraceID()))
+	})
+
+	// =========================================================================
+	// TEST: Global tracer
+	// Why: Instrumented packages obtain their tracers at init, before the
+	//      server installs the configured provider.
+	// =========================================================================
+	It("should delegate to the provider installed later", func() {
+		tracer := tracing.Tracer("late")
+		tracing.SetTracerProvider(provider)
+		DeferCleanup(tracing.SetTracerProvider, noop.NewTracerProvider())
+
+		_, span := tracer.Start(context.Background(), "span")
+		span.End()
+		Expect(provider.ForceFlush(context.Background())).To(Succeed())
+		Expect(exporter.exported()).To(HaveLen(1))
+		Expect(exporter.exported()[0].Scope).To(Equal("late"))
+	})
+})
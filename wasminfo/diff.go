@@ -0,0 +1,188 @@
+package wasminfo
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChangeKind classifies a difference between two plugin versions.
+type ChangeKind string
+
+const (
+	ChangeExportRemoved     ChangeKind = "export_removed"
+	ChangeExportAdded       ChangeKind = "export_added"
+	ChangeExportChanged     ChangeKind = "export_changed"
+	ChangeImportRemoved     ChangeKind = "import_removed"
+	ChangeImportAdded       ChangeKind = "import_added"
+	ChangeImportChanged     ChangeKind = "import_changed"
+	ChangeCapabilityRemoved ChangeKind = "capability_removed"
+	ChangeCapabilityAdded   ChangeKind = "capability_added"
+	ChangeABIVersion        ChangeKind = "abi_version_changed"
+)
+
+// Change is one difference between two plugin versions.
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	Subject  string     `json:"subject"`          // Export, import, or capability name
+	Detail   string     `json:"detail,omitempty"` // Old and new values where relevant
+	Breaking bool       `json:"breaking"`
+}
+
+// Diff is the comparison of two plugin versions.
+type Diff struct {
+	OldSize int64    `json:"old_size"`
+	NewSize int64    `json:"new_size"`
+	Changes []Change `json:"changes"`
+}
+
+// Breaking returns the changes that can break hosts or callers of the
+// old version.
+func (d Diff) Breaking() []Change {
+	var breaking []Change
+	for _, c := range d.Changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+// SizeDelta returns the relative size change, e.g. 0.25 for 25% larger.
+func (d Diff) SizeDelta() float64 {
+	if d.OldSize == 0 {
+		return 0
+	}
+	return float64(d.NewSize-d.OldSize) / float64(d.OldSize)
+}
+
+// Compare reports how newer differs from older.
+//
+// Breaking changes are those that can make a host or caller that works
+// with older fail with newer:
+//   - an export is removed or its signature changes
+//   - an import's signature changes, or a non-WASI import is added
+//     (the host must provide it)
+//   - a capability is added (the plugin needs access it did not before)
+//   - the ABI version changes
+//
+// Additions of exports and removals of imports or capabilities are
+// reported but never breaking.
+func Compare(older, newer *Module) Diff {
+	d := Diff{OldSize: older.Size, NewSize: newer.Size, Changes: []Change{}}
+
+	// Step 1: Exports
+	oldExports := exportsByName(older)
+	newExports := exportsByName(newer)
+	for _, name := range unionKeys(oldExports, newExports) {
+		o, inOld := oldExports[name]
+		n, inNew := newExports[name]
+		switch {
+		case !inNew:
+			d.add(ChangeExportRemoved, name, describe(o.Kind, o.Signature), true)
+		case !inOld:
+			d.add(ChangeExportAdded, name, describe(n.Kind, n.Signature), false)
+		case o.Kind != n.Kind || o.Signature != n.Signature:
+			d.add(ChangeExportChanged, name,
+				fmt.Sprintf("%s -> %s", describe(o.Kind, o.Signature), describe(n.Kind, n.Signature)), true)
+		}
+	}
+
+	// Step 2: Imports
+	oldImports := importsByName(older)
+	newImports := importsByName(newer)
+	for _, name := range unionKeys(oldImports, newImports) {
+		o, inOld := oldImports[name]
+		n, inNew := newImports[name]
+		switch {
+		case !inNew:
+			d.add(ChangeImportRemoved, name, describe(o.Kind, o.Signature), false)
+		case !inOld:
+			d.add(ChangeImportAdded, name, describe(n.Kind, n.Signature), !isWASI(n.Module))
+		case o.Kind != n.Kind || o.Signature != n.Signature:
+			d.add(ChangeImportChanged, name,
+				fmt.Sprintf("%s -> %s", describe(o.Kind, o.Signature), describe(n.Kind, n.Signature)), true)
+		}
+	}
+
+	// Step 3: Capabilities
+	oldCaps := setOf(older.Capabilities())
+	newCaps := setOf(newer.Capabilities())
+	for _, c := range unionKeys(oldCaps, newCaps) {
+		switch {
+		case !newCaps[c]:
+			d.add(ChangeCapabilityRemoved, c, "", false)
+		case !oldCaps[c]:
+			d.add(ChangeCapabilityAdded, c, "", true)
+		}
+	}
+
+	// Step 4: ABI version
+	if oldVersion, newVersion := versionString(older.ABIVersion), versionString(newer.ABIVersion); oldVersion != newVersion {
+		d.add(ChangeABIVersion, ABIVersionExport, fmt.Sprintf("%s -> %s", oldVersion, newVersion), true)
+	}
+
+	return d
+}
+
+func (d *Diff) add(kind ChangeKind, subject, detail string, breaking bool) {
+	d.Changes = append(d.Changes, Change{Kind: kind, Subject: subject, Detail: detail, Breaking: breaking})
+}
+
+// describe formats an import or export for a change detail.
+func describe(kind Kind, signature string) string {
+	if signature == "" {
+		return string(kind)
+	}
+	if kind == KindFunc {
+		return signature
+	}
+	return string(kind) + " " + signature
+}
+
+func versionString(v *int) string {
+	if v == nil {
+		return "none"
+	}
+	return fmt.Sprint(*v)
+}
+
+func exportsByName(m *Module) map[string]Export {
+	byName := make(map[string]Export, len(m.Exports))
+	for _, e := range m.Exports {
+		byName[e.Name] = e
+	}
+	return byName
+}
+
+func importsByName(m *Module) map[string]Import {
+	byName := make(map[string]Import, len(m.Imports))
+	for _, imp := range m.Imports {
+		byName[imp.Module+"."+imp.Name] = imp
+	}
+	return byName
+}
+
+func setOf(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// unionKeys returns the sorted keys present in either map.
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
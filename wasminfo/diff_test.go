@@ -0,0 +1,109 @@
+package wasminfo_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+var _ = Describe("Compare", func() {
+	parse := func(imports []hostImport, fns []function) *wasminfo.Module {
+		m, err := wasminfo.Parse(buildModule(imports, fns))
+		Expect(err).NotTo(HaveOccurred())
+		return m
+	}
+
+	kinds := func(changes []wasminfo.Change) []wasminfo.ChangeKind {
+		var out []wasminfo.ChangeKind
+		for _, c := range changes {
+			out = append(out, c.Kind)
+		}
+		return out
+	}
+
+	It("should report no changes for identical plugins", func() {
+		d := wasminfo.Compare(parse(nil, corePlugin(1)), parse(nil, corePlugin(1)))
+		Expect(d.Changes).To(BeEmpty())
+		Expect(d.Breaking()).To(BeEmpty())
+		Expect(d.SizeDelta()).To(BeZero())
+	})
+
+	// =========================================================================
+	// TEST: Breaking change classification
+	// Why: The diff gates publishing in CI; a missed breaking change ships a
+	//      plugin existing hosts cannot run, and a false positive blocks
+	//      harmless releases.
+	// =========================================================================
+	It("should treat added exports as compatible", func() {
+		newer := append(corePlugin(1), function{name: "extra", results: []byte{i32}, body: []byte{0x41, 0x00}})
+
+		d := wasminfo.Compare(parse(nil, corePlugin(1)), parse(nil, newer))
+		Expect(kinds(d.Changes)).To(Equal([]wasminfo.ChangeKind{wasminfo.ChangeExportAdded}))
+		Expect(d.Breaking()).To(BeEmpty())
+		Expect(d.SizeDelta()).To(BeNumerically(">", 0))
+	})
+
+	It("should flag removed exports and changed signatures", func() {
+		newer := corePlugin(1)[1:] // Drop init
+		newer[0] = function{name: "process", params: []byte{i64}, results: []byte{i64}, body: []byte{0x20, 0x00}}
+
+		d := wasminfo.Compare(parse(nil, corePlugin(1)), parse(nil, newer))
+		Expect(kinds(d.Breaking())).To(ConsistOf(wasminfo.ChangeExportRemoved, wasminfo.ChangeExportChanged))
+
+		for _, c := range d.Changes {
+			if c.Kind == wasminfo.ChangeExportChanged {
+				Expect(c.Detail).To(Equal("(i32) -> i32 -> (i64) -> i64"))
+			}
+		}
+	})
+
+	It("should flag new capabilities and host imports but not new WASI calls within one", func() {
+		older := []hostImport{{module: "wasi_snapshot_preview1", name: "fd_write"}}
+		newer := []hostImport{
+			{module: "wasi_snapshot_preview1", name: "fd_write"},
+			{module: "wasi_snapshot_preview1", name: "fd_read"},
+			{module: "wasi_snapshot_preview1", name: "sock_send"},
+			{module: "env", name: "log"},
+		}
+
+		d := wasminfo.Compare(parse(older, corePlugin(1)), parse(newer, corePlugin(1)))
+
+		var breaking []string
+		for _, c := range d.Breaking() {
+			breaking = append(breaking, string(c.Kind)+" "+c.Subject)
+		}
+		Expect(breaking).To(ConsistOf(
+			"import_added env.log",
+			"capability_added host:env",
+			"capability_added network",
+		))
+		Expect(kinds(d.Changes)).To(ContainElement(wasminfo.ChangeImportAdded)) // fd_read, sock_send
+	})
+
+	It("should treat dropped capabilities as compatible", func() {
+		older := []hostImport{{module: "wasi_snapshot_preview1", name: "random_get"}}
+
+		d := wasminfo.Compare(parse(older, corePlugin(1)), parse(nil, corePlugin(1)))
+		Expect(kinds(d.Changes)).To(ConsistOf(wasminfo.ChangeImportRemoved, wasminfo.ChangeCapabilityRemoved))
+		Expect(d.Breaking()).To(BeEmpty())
+	})
+
+	DescribeTable("should flag ABI version changes",
+		func(older, newer int, detail string) {
+			d := wasminfo.Compare(parse(nil, corePlugin(older)), parse(nil, corePlugin(newer)))
+
+			var change *wasminfo.Change
+			for i := range d.Changes {
+				if d.Changes[i].Kind == wasminfo.ChangeABIVersion {
+					change = &d.Changes[i]
+				}
+			}
+			Expect(change).NotTo(BeNil())
+			Expect(change.Breaking).To(BeTrue())
+			Expect(change.Detail).To(Equal(detail))
+		},
+		Entry("bumped", 1, 2, "1 -> 2"),
+		Entry("added", -1, 1, "none -> 1"),
+	)
+})
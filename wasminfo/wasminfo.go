@@ -0,0 +1,659 @@
+// Package wasminfo reads the interface of a WebAssembly plugin binary
+// without instantiating it.
+//
+// It decodes only the sections needed to describe what a plugin offers and
+// what it requires from the host: exports, imports, and the constant
+// returned by get_abi_version. The package is pure Go so that tooling such
+// as `pluginctl diff` can inspect plugins on machines without WasmEdge.
+//
+// # Capabilities
+//
+// Plugins declare the host access they need through their imports. WASI
+// imports are grouped into capabilities (filesystem, network, clock, ...);
+// any other import module is reported as "host:<module>" because the
+// embedding host must provide it.
+package wasminfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ABIVersionExport is the export whose constant return value is reported
+// as the plugin's ABI version.
+const ABIVersionExport = "get_abi_version"
+
+// Kind is the kind of an import or export.
+type Kind string
+
+const (
+	KindFunc   Kind = "func"
+	KindTable  Kind = "table"
+	KindMemory Kind = "memory"
+	KindGlobal Kind = "global"
+)
+
+// Export is a definition the module makes available to the host.
+type Export struct {
+	Name      string `json:"name"`
+	Kind      Kind   `json:"kind"`
+	Signature string `json:"signature,omitempty"` // Function type, e.g. "(i32) -> i32"
+
+	// Func is the decoded function type of a KindFunc export; Signature
+	// is its string form.
+	Func *FuncType `json:"-"`
+}
+
+// FuncType is a function signature. Value types use their text format
+// names: "i32", "i64", "f32", "f64", "v128", "funcref", "externref".
+type FuncType struct {
+	Params  []string
+	Results []string
+}
+
+// String formats the type, e.g. "(i32, i32) -> i64".
+func (t FuncType) String() string {
+	return signature(t.Params, t.Results)
+}
+
+// Import is a definition the module requires from the host.
+type Import struct {
+	Module    string `json:"module"`
+	Name      string `json:"name"`
+	Kind      Kind   `json:"kind"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Module describes the interface of a plugin binary.
+type Module struct {
+	Size    int64    `json:"size"`    // Binary size in bytes
+	Exports []Export `json:"exports"` // Sorted by name
+	Imports []Import `json:"imports"` // Sorted by module, then name
+
+	// ABIVersion is the constant returned by get_abi_version, or nil if the
+	// export is missing or does not simply return a constant.
+	ABIVersion *int `json:"abi_version,omitempty"`
+
+	// MemoryPages is the initial size of the module's linear memory in
+	// 64 KiB pages, whether defined or imported (0 if it has none).
+	MemoryPages int `json:"memory_pages,omitempty"`
+
+	// UnexportedMutableGlobals counts the mutable globals, defined or
+	// imported, that the module does not export: state of an instance that
+	// the host can neither read nor reset, e.g. a linker's __stack_pointer.
+	UnexportedMutableGlobals int `json:"-"`
+}
+
+// ErrNotWasm is returned for input that does not start with the
+// WebAssembly magic number and version 1.
+var ErrNotWasm = errors.New("not a WebAssembly binary")
+
+// Section IDs used by the parser.
+const (
+	sectionType     = 1
+	sectionImport   = 2
+	sectionFunction = 3
+	sectionMemory   = 5
+	sectionGlobal   = 6
+	sectionExport   = 7
+	sectionCode     = 10
+)
+
+// Open reads and parses the plugin binary at path.
+func Open(path string) (*Module, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes a WebAssembly binary.
+//
+// Parsing steps:
+// 1. Check the magic number and version
+// 2. Walk the sections, decoding types, imports, functions, globals, and
+//    exports
+// 3. Resolve export signatures through the function index space
+// 4. Read get_abi_version's body to find its constant result
+// 5. Count the mutable globals that are not exported
+func Parse(data []byte) (*Module, error) {
+	if len(data) < 8 || !bytes.Equal(data[:4], []byte("\x00asm")) {
+		return nil, ErrNotWasm
+	}
+	if version := binary.LittleEndian.Uint32(data[4:8]); version != 1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrNotWasm, version)
+	}
+
+	var (
+		m        = &Module{Size: int64(len(data)), Exports: []Export{}, Imports: []Import{}}
+		types    []*FuncType // Function types by type index
+		funcSigs []*FuncType // Type of every function, imports first
+		mutable  []bool      // Mutability of every global, imports first
+		exports  []rawExport
+		codes    [][]byte // Bodies of defined functions
+	)
+	importedFuncs := 0
+
+	r := &reader{data: data, pos: 8}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return nil, fmt.Errorf("section %d: %w", id, err)
+		}
+		s := &reader{data: body}
+
+		switch id {
+		case sectionType:
+			types, err = readTypes(s)
+		case sectionImport:
+			var importedSigs []*FuncType
+			var importedGlobals []bool
+			m.Imports, importedSigs, importedGlobals, m.MemoryPages, err = readImports(s, types)
+			importedFuncs = len(importedSigs)
+			funcSigs = append(funcSigs, importedSigs...)
+			mutable = append(importedGlobals, mutable...)
+		case sectionFunction:
+			var indices []uint32
+			indices, err = readVec(s, (*reader).u32)
+			for _, t := range indices {
+				if int(t) >= len(types) {
+					return nil, fmt.Errorf("function type index %d out of range", t)
+				}
+				funcSigs = append(funcSigs, types[t])
+			}
+		case sectionMemory:
+			var minimums []uint32
+			if minimums, err = readVec(s, (*reader).limits); err == nil && len(minimums) > 0 {
+				m.MemoryPages = int(minimums[0])
+			}
+		case sectionGlobal:
+			var defined []bool
+			defined, err = readVec(s, readGlobal)
+			mutable = append(mutable, defined...)
+		case sectionExport:
+			exports, err = readVec(s, readExport)
+		case sectionCode:
+			codes, err = readVec(s, readCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("section %d: %w", id, err)
+		}
+	}
+
+	exportedGlobals := map[uint32]bool{}
+	for _, raw := range exports {
+		if raw.kind == KindGlobal {
+			exportedGlobals[raw.index] = true
+		}
+		export := Export{Name: raw.name, Kind: raw.kind}
+		if raw.kind == KindFunc && int(raw.index) < len(funcSigs) {
+			export.Func = funcSigs[raw.index]
+			export.Signature = export.Func.String()
+		}
+		m.Exports = append(m.Exports, export)
+
+		if raw.name == ABIVersionExport && raw.kind == KindFunc {
+			defined := int(raw.index) - importedFuncs
+			if defined >= 0 && defined < len(codes) {
+				m.ABIVersion = constantResult(codes[defined])
+			}
+		}
+	}
+
+	for i, mut := range mutable {
+		if mut && !exportedGlobals[uint32(i)] {
+			m.UnexportedMutableGlobals++
+		}
+	}
+
+	sort.Slice(m.Exports, func(i, j int) bool { return m.Exports[i].Name < m.Exports[j].Name })
+	sort.Slice(m.Imports, func(i, j int) bool {
+		if m.Imports[i].Module != m.Imports[j].Module {
+			return m.Imports[i].Module < m.Imports[j].Module
+		}
+		return m.Imports[i].Name < m.Imports[j].Name
+	})
+	return m, nil
+}
+
+// Export returns the export with the given name.
+func (m *Module) Export(name string) (Export, bool) {
+	for _, e := range m.Exports {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Export{}, false
+}
+
+// Capabilities returns the sorted, de-duplicated host capabilities the
+// module's imports require.
+func (m *Module) Capabilities() []string {
+	seen := map[string]bool{}
+	for _, imp := range m.Imports {
+		seen[capability(imp)] = true
+	}
+	caps := make([]string, 0, len(seen))
+	for c := range seen {
+		caps = append(caps, c)
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+// capability maps an import to the capability it requires.
+func capability(imp Import) string {
+	if !isWASI(imp.Module) {
+		return "host:" + imp.Module
+	}
+	switch {
+	case strings.HasPrefix(imp.Name, "fd_"), strings.HasPrefix(imp.Name, "path_"):
+		return "filesystem"
+	case strings.HasPrefix(imp.Name, "sock_"):
+		return "network"
+	case strings.HasPrefix(imp.Name, "clock_"), imp.Name == "poll_oneoff":
+		return "clock"
+	case imp.Name == "random_get":
+		return "random"
+	case strings.HasPrefix(imp.Name, "environ_"), strings.HasPrefix(imp.Name, "args_"):
+		return "environment"
+	case strings.HasPrefix(imp.Name, "proc_"), imp.Name == "sched_yield":
+		return "process"
+	default:
+		return "wasi:" + imp.Name
+	}
+}
+
+// isWASI reports whether an import module is a WASI namespace.
+func isWASI(module string) bool {
+	return strings.HasPrefix(module, "wasi_")
+}
+
+// constantResult recognizes a function body that is exactly
+// `i32.const N; end` (what compilers emit for `return N;`) and returns N.
+func constantResult(code []byte) *int {
+	r := &reader{data: code}
+	// Skip local declarations
+	groups, err := r.u32()
+	if err != nil {
+		return nil
+	}
+	for i := uint32(0); i < groups; i++ {
+		if _, err := r.u32(); err != nil {
+			return nil
+		}
+		if _, err := r.byte(); err != nil {
+			return nil
+		}
+	}
+
+	op, err := r.byte()
+	if err != nil || op != 0x41 { // i32.const
+		return nil
+	}
+	value, err := r.s32()
+	if err != nil {
+		return nil
+	}
+	if end, err := r.byte(); err != nil || end != 0x0b || !r.done() {
+		return nil
+	}
+	v := int(value)
+	return &v
+}
+
+// rawExport is an export before its signature is resolved.
+type rawExport struct {
+	name  string
+	kind  Kind
+	index uint32
+}
+
+func readTypes(r *reader) ([]*FuncType, error) {
+	return readVec(r, func(r *reader) (*FuncType, error) {
+		form, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if form != 0x60 {
+			return nil, fmt.Errorf("unexpected type form 0x%x", form)
+		}
+		params, err := readVec(r, readValType)
+		if err != nil {
+			return nil, err
+		}
+		results, err := readVec(r, readValType)
+		if err != nil {
+			return nil, err
+		}
+		return &FuncType{Params: params, Results: results}, nil
+	})
+}
+
+// signature formats a function type, e.g. "(i32, i32) -> i64".
+func signature(params, results []string) string {
+	s := "(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+		return s
+	case 1:
+		return s + " -> " + results[0]
+	default:
+		return s + " -> (" + strings.Join(results, ", ") + ")"
+	}
+}
+
+// readImports decodes the import section. It also returns the types of the
+// imported functions, which come first in the function index space, the
+// mutability of the imported globals, which come first in the global index
+// space, and the initial pages of an imported memory.
+func readImports(r *reader, types []*FuncType) ([]Import, []*FuncType, []bool, int, error) {
+	var (
+		funcs       []*FuncType
+		globals     []bool
+		memoryPages uint32
+	)
+	imports, err := readVec(r, func(r *reader) (Import, error) {
+		var imp Import
+		var err error
+		if imp.Module, err = r.name(); err != nil {
+			return imp, err
+		}
+		if imp.Name, err = r.name(); err != nil {
+			return imp, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return imp, err
+		}
+
+		switch kind {
+		case 0x00:
+			imp.Kind = KindFunc
+			index, err := r.u32()
+			if err != nil {
+				return imp, err
+			}
+			if int(index) >= len(types) {
+				return imp, fmt.Errorf("import %s.%s: type index %d out of range", imp.Module, imp.Name, index)
+			}
+			imp.Signature = types[index].String()
+			funcs = append(funcs, types[index])
+		case 0x01:
+			imp.Kind = KindTable
+			if _, err := r.byte(); err != nil { // Element type
+				return imp, err
+			}
+			_, err = r.limits()
+		case 0x02:
+			imp.Kind = KindMemory
+			memoryPages, err = r.limits()
+		case 0x03:
+			imp.Kind = KindGlobal
+			valType, err := readValType(r)
+			if err != nil {
+				return imp, err
+			}
+			mutable, err := r.byte()
+			if err != nil {
+				return imp, err
+			}
+			imp.Signature = valType
+			if mutable == 1 {
+				imp.Signature = "mut " + valType
+			}
+			globals = append(globals, mutable == 1)
+		default:
+			return imp, fmt.Errorf("import %s.%s: unknown kind 0x%x", imp.Module, imp.Name, kind)
+		}
+		return imp, err
+	})
+	return imports, funcs, globals, int(memoryPages), err
+}
+
+// readGlobal decodes a global definition and reports whether it is
+// mutable.
+func readGlobal(r *reader) (bool, error) {
+	if _, err := readValType(r); err != nil {
+		return false, err
+	}
+	mutable, err := r.byte()
+	if err != nil {
+		return false, err
+	}
+	return mutable == 1, skipConstExpr(r)
+}
+
+// skipConstExpr skips a constant expression, such as a global's
+// initializer, up to and including its end.
+func skipConstExpr(r *reader) error {
+	for {
+		op, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case 0x0b: // end
+			return nil
+		case 0x41, 0x42, 0x23, 0xd2: // i32.const, i64.const, global.get, ref.func
+			err = r.skipLEB()
+		case 0x43: // f32.const
+			_, err = r.bytes(4)
+		case 0x44: // f64.const
+			_, err = r.bytes(8)
+		case 0xd0: // ref.null
+			_, err = r.byte()
+		case 0x6a, 0x6b, 0x6c, 0x7c, 0x7d, 0x7e: // Extended constant arithmetic
+		case 0xfd: // v128.const
+			if _, err = r.u32(); err == nil {
+				_, err = r.bytes(16)
+			}
+		default:
+			return fmt.Errorf("unexpected opcode 0x%x in constant expression", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readExport(r *reader) (rawExport, error) {
+	var e rawExport
+	var err error
+	if e.name, err = r.name(); err != nil {
+		return e, err
+	}
+	kind, err := r.byte()
+	if err != nil {
+		return e, err
+	}
+	switch kind {
+	case 0x00:
+		e.kind = KindFunc
+	case 0x01:
+		e.kind = KindTable
+	case 0x02:
+		e.kind = KindMemory
+	case 0x03:
+		e.kind = KindGlobal
+	default:
+		return e, fmt.Errorf("export %s: unknown kind 0x%x", e.name, kind)
+	}
+	e.index, err = r.u32()
+	return e, err
+}
+
+func readCode(r *reader) ([]byte, error) {
+	size, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	return r.bytes(int(size))
+}
+
+func readValType(r *reader) (string, error) {
+	b, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 0x7f:
+		return "i32", nil
+	case 0x7e:
+		return "i64", nil
+	case 0x7d:
+		return "f32", nil
+	case 0x7c:
+		return "f64", nil
+	case 0x7b:
+		return "v128", nil
+	case 0x70:
+		return "funcref", nil
+	case 0x6f:
+		return "externref", nil
+	default:
+		return "", fmt.Errorf("unknown value type 0x%x", b)
+	}
+}
+
+func readVec[T any](r *reader, read func(*reader) (T, error)) ([]T, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	// Every element takes at least one byte; reject counts that cannot fit
+	if int(n) > len(r.data)-r.pos {
+		return nil, fmt.Errorf("vector length %d exceeds section size", n)
+	}
+	items := make([]T, 0, n)
+	for i := uint32(0); i < n; i++ {
+		item, err := read(r)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// reader decodes the primitive encodings of the binary format.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+var errTruncated = errors.New("unexpected end of data")
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.done() {
+		return 0, errTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	return string(b), err
+}
+
+// u32 decodes an unsigned LEB128 value of at most 32 bits.
+func (r *reader) u32() (uint32, error) {
+	var result uint32
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+	return 0, errors.New("malformed LEB128 integer")
+}
+
+// skipLEB skips a LEB128 value of at most 64 bits.
+func (r *reader) skipLEB() error {
+	for i := 0; i < 10; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if b&0x80 == 0 {
+			return nil
+		}
+	}
+	return errors.New("malformed LEB128 integer")
+}
+
+// s32 decodes a signed LEB128 value of at most 32 bits.
+func (r *reader) s32() (int32, error) {
+	var result int64
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= int64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			if shift+7 < 64 && b&0x40 != 0 {
+				result |= -1 << (shift + 7)
+			}
+			return int32(result), nil
+		}
+	}
+	return 0, errors.New("malformed LEB128 integer")
+}
+
+// limits reads a table or memory limits encoding and returns the minimum.
+func (r *reader) limits() (uint32, error) {
+	flags, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	min, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	if flags&0x01 != 0 {
+		_, err = r.u32()
+	}
+	return min, err
+}
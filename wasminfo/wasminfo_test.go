@@ -0,0 +1,216 @@
+package wasminfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/wasminfo"
+)
+
+func TestWasminfo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wasminfo Suite")
+}
+
+const (
+	i32 = 0x7f
+	i64 = 0x7e
+)
+
+// function is an exported function in a test module.
+type function struct {
+	name    string
+	params  []byte
+	results []byte
+	body    []byte // Instructions, without local declarations or the final end
+}
+
+// hostImport is an imported function in a test module.
+type hostImport struct {
+	module, name string
+	params       []byte
+	results      []byte
+}
+
+// buildModule encodes a minimal WebAssembly binary in which every function
+// has its own type and is exported under its name.
+func buildModule(imports []hostImport, functions []function) []byte {
+	uleb := func(v uint32) []byte {
+		var out []byte
+		for {
+			b := byte(v & 0x7f)
+			v >>= 7
+			if v != 0 {
+				out = append(out, b|0x80)
+				continue
+			}
+			return append(out, b)
+		}
+	}
+	name := func(s string) []byte { return append(uleb(uint32(len(s))), s...) }
+	vec := func(items [][]byte) []byte {
+		out := uleb(uint32(len(items)))
+		for _, item := range items {
+			out = append(out, item...)
+		}
+		return out
+	}
+	section := func(id byte, payload []byte) []byte {
+		return append(append([]byte{id}, uleb(uint32(len(payload)))...), payload...)
+	}
+	funcType := func(params, results []byte) []byte {
+		out := append([]byte{0x60}, uleb(uint32(len(params)))...)
+		out = append(out, params...)
+		out = append(out, uleb(uint32(len(results)))...)
+		return append(out, results...)
+	}
+
+	var types, importEntries, funcEntries, exportEntries, codeEntries [][]byte
+	for _, imp := range imports {
+		index := uint32(len(types))
+		types = append(types, funcType(imp.params, imp.results))
+		entry := append(name(imp.module), name(imp.name)...)
+		importEntries = append(importEntries, append(append(entry, 0x00), uleb(index)...))
+	}
+	for i, fn := range functions {
+		index := uint32(len(types))
+		types = append(types, funcType(fn.params, fn.results))
+		funcEntries = append(funcEntries, uleb(index))
+		exportEntries = append(exportEntries, append(append(name(fn.name), 0x00), uleb(uint32(len(imports)+i))...))
+
+		body := append([]byte{0x00}, fn.body...) // No locals
+		body = append(body, 0x0b)
+		codeEntries = append(codeEntries, append(uleb(uint32(len(body))), body...))
+	}
+
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = append(out, section(1, vec(types))...)
+	if len(importEntries) > 0 {
+		out = append(out, section(2, vec(importEntries))...)
+	}
+	out = append(out, section(3, vec(funcEntries))...)
+	out = append(out, section(7, vec(exportEntries))...)
+	out = append(out, section(10, vec(codeEntries))...)
+	return out
+}
+
+// corePlugin returns the core ABI exports, with get_abi_version returning
+// version when it is non-negative.
+func corePlugin(version int) []function {
+	fns := []function{
+		{name: "init", results: []byte{i32}, body: []byte{0x41, 0x00}},
+		{name: "process", params: []byte{i32}, results: []byte{i32}, body: []byte{0x20, 0x00}},
+		{name: "cleanup", results: []byte{i32}, body: []byte{0x41, 0x00}},
+	}
+	if version >= 0 {
+		fns = append(fns, function{name: "get_abi_version", results: []byte{i32}, body: []byte{0x41, byte(version)}})
+	}
+	return fns
+}
+
+var _ = Describe("Parse", func() {
+	// =========================================================================
+	// TEST: Interface extraction
+	// Why: Compatibility checks are only as good as the parsed interface;
+	//      signatures must resolve through imported functions, which occupy
+	//      the start of the function index space.
+	// =========================================================================
+	It("should read exports, imports, and the ABI version", func() {
+		data := buildModule(
+			[]hostImport{{module: "wasi_snapshot_preview1", name: "fd_write", params: []byte{i32, i32, i32, i32}, results: []byte{i32}}},
+			append(corePlugin(2), function{name: "process_bytes", params: []byte{i32, i32}, results: []byte{i64}, body: []byte{0x42, 0x00}}),
+		)
+
+		m, err := wasminfo.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Size).To(Equal(int64(len(data))))
+
+		Expect(m.Exports).To(HaveLen(5))
+		Expect(m.Exports[0].Name).To(Equal("cleanup")) // Sorted by name
+		processBytes, ok := m.Export("process_bytes")
+		Expect(ok).To(BeTrue())
+		Expect(processBytes.Signature).To(Equal("(i32, i32) -> i64"))
+		Expect(processBytes.Func).To(Equal(&wasminfo.FuncType{Params: []string{"i32", "i32"}, Results: []string{"i64"}}))
+		process, _ := m.Export("process")
+		Expect(process.Signature).To(Equal("(i32) -> i32"))
+
+		Expect(m.Imports).To(ConsistOf(wasminfo.Import{
+			Module: "wasi_snapshot_preview1", Name: "fd_write", Kind: wasminfo.KindFunc,
+			Signature: "(i32, i32, i32, i32) -> i32",
+		}))
+		Expect(m.Capabilities()).To(Equal([]string{"filesystem"}))
+
+		Expect(m.ABIVersion).NotTo(BeNil())
+		Expect(*m.ABIVersion).To(Equal(2))
+	})
+
+	It("should leave the ABI version unset when it is not a constant", func() {
+		fns := corePlugin(-1)
+		fns = append(fns, function{name: "get_abi_version", results: []byte{i32}, body: []byte{0x41, 0x01, 0x41, 0x01, 0x6a}}) // 1 + 1
+
+		m, err := wasminfo.Parse(buildModule(nil, fns))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ABIVersion).To(BeNil())
+	})
+
+	It("should map imports to capabilities", func() {
+		m, err := wasminfo.Parse(buildModule([]hostImport{
+			{module: "wasi_snapshot_preview1", name: "sock_accept"},
+			{module: "wasi_snapshot_preview1", name: "clock_time_get"},
+			{module: "wasi_snapshot_preview1", name: "random_get"},
+			{module: "env", name: "log"},
+		}, corePlugin(1)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Capabilities()).To(Equal([]string{"clock", "host:env", "network", "random"}))
+	})
+
+	DescribeTable("should reject malformed input",
+		func(data []byte, message string) {
+			_, err := wasminfo.Parse(data)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("empty", []byte{}, "not a WebAssembly binary"),
+		Entry("wrong magic", []byte("\x7fELF\x01\x00\x00\x00"), "not a WebAssembly binary"),
+		Entry("wrong version", []byte("\x00asm\x02\x00\x00\x00"), "unsupported version"),
+		Entry("truncated section", []byte("\x00asm\x01\x00\x00\x00\x01\x10"), "unexpected end of data"),
+	)
+
+	It("should open plugins from disk", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "p.wasm")
+		Expect(os.WriteFile(path, buildModule(nil, corePlugin(1)), 0644)).To(Succeed())
+
+		m, err := wasminfo.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Exports).To(HaveLen(4))
+	})
+
+	It("should read the initial memory size", func() {
+		data := buildModule(nil, corePlugin(1))
+		Expect(wasminfo.Parse(data)).To(HaveField("MemoryPages", 0))
+
+		// Memory section: one memory, limits {min: 17, max: 32}
+		data = append(data, 5, 4, 1, 0x01, 17, 32)
+
+		m, err := wasminfo.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.MemoryPages).To(Equal(17))
+	})
+
+	It("should count the mutable globals that are not exported", func() {
+		data := buildModule(nil, corePlugin(1))
+		Expect(wasminfo.Parse(data)).To(HaveField("UnexportedMutableGlobals", 0))
+
+		// Global section: a mutable i32 starting at 65536, as a stack
+		// pointer would, and an immutable one
+		data = append(data, 6, 13, 2, i32, 1, 0x41, 0x80, 0x80, 0x04, 0x0b, i32, 0, 0x41, 0x00, 0x0b)
+		Expect(wasminfo.Parse(data)).To(HaveField("UnexportedMutableGlobals", 1))
+
+		// Export section: the mutable global as "sp"
+		data = append(data, 7, 6, 1, 2, 's', 'p', 0x03, 0)
+		Expect(wasminfo.Parse(data)).To(HaveField("UnexportedMutableGlobals", 0))
+	})
+})
@@ -0,0 +1,475 @@
+// Package websocket implements the WebSocket protocol (RFC 6455) for the
+// streaming API: the server side of the opening handshake with Upgrade,
+// the client side with Dial, and message framing over the connection.
+//
+// It covers what the streaming API needs and no more: text and binary
+// messages, fragmented messages, ping and pong, and the closing
+// handshake. Extensions (such as permessage-deflate) and subprotocols
+// are not negotiated. Like the rest of the module, it has no dependencies
+// beyond the standard library.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+// Message types, numbered as their frame opcodes.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Opcodes of control frames.
+const (
+	opContinuation = 0
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// Close codes of the closing handshake.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseNoStatus        = 1005 // Received without a code; never sent
+	CloseInvalidData     = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+// DefaultMaxMessageSize bounds the messages a Conn reads when
+// Options.MaxMessageSize is zero.
+const DefaultMaxMessageSize = 1 << 20
+
+// acceptGUID is appended to the client's key to compute the accept key.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrBadHandshake is returned by Upgrade for requests that are not a
+// WebSocket opening handshake, and by Dial when the server refuses one.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// ErrMessageTooBig is returned by ReadMessage for messages longer than the
+// maximum size; the connection is closed with CloseMessageTooBig.
+var ErrMessageTooBig = errors.New("websocket: message too big")
+
+// CloseError is returned by ReadMessage once the peer closed the
+// connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Options configures a Conn.
+type Options struct {
+	// MaxMessageSize bounds the size of a message ReadMessage accepts,
+	// fragments included; DefaultMaxMessageSize if zero.
+	MaxMessageSize int64
+
+	// TLSConfig configures the TLS connections of Dial to wss:// URLs;
+	// nil uses the defaults.
+	TLSConfig *tls.Config
+}
+
+// Conn is a WebSocket connection. ReadMessage must be called from one
+// goroutine at a time; WriteMessage, Ping, and Close are safe for
+// concurrent use.
+type Conn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool // Clients mask the frames they send
+	max    int64
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool, opts Options) *Conn {
+	max := opts.MaxMessageSize
+	if max <= 0 {
+		max = DefaultMaxMessageSize
+	}
+	return &Conn{conn: conn, br: br, client: client, max: max}
+}
+
+// IsUpgrade reports whether r asks to upgrade to a WebSocket connection.
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the opening handshake of r and takes over its
+// connection. If r is not a valid handshake, Upgrade returns an error
+// wrapping ErrBadHandshake and writes nothing, so the caller can respond
+// with an error of its own; once it succeeds, w must not be used again.
+func Upgrade(w http.ResponseWriter, r *http.Request, opts Options) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		return nil, fmt.Errorf("%w: not a websocket upgrade request", ErrBadHandshake)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrBadHandshake, r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Key", ErrBadHandshake)
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	// Deadlines the server set for the request must not end the stream
+	conn.SetDeadline(time.Time{})
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	return newConn(conn, rw.Reader, false, opts), nil
+}
+
+// Dial opens a WebSocket connection to rawURL, a ws:// or wss:// URL (or
+// http:// or https://), sending header with the handshake. If the server
+// responds with anything but a successful upgrade, Dial returns the
+// response, with its body, and an error wrapping ErrBadHandshake.
+func Dial(ctx context.Context, rawURL string, header http.Header, opts Options) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket: %w", err)
+	}
+	var secure bool
+	switch u.Scheme {
+	case "ws", "http":
+	case "wss", "https":
+		secure = true
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), map[bool]string{false: "80", true: "443"}[secure])
+	}
+
+	var conn net.Conn
+	if secure {
+		config := &tls.Config{}
+		if opts.TLSConfig != nil {
+			config = opts.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		dialer := &tls.Dialer{Config: config}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket: %w", err)
+	}
+	// The handshake is bounded by ctx; the connection is not
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	u.Scheme = map[bool]string{false: "http", true: "https"}[secure]
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{}, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("websocket: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("websocket: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		// Keep the body readable for the caller
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		conn.Close()
+		resp.Body = io.NopCloser(strings.NewReader(string(body)))
+		return nil, resp, fmt.Errorf("%w: status %s", ErrBadHandshake, resp.Status)
+	}
+	if !stop() {
+		conn.Close()
+		return nil, nil, fmt.Errorf("websocket: %w", ctx.Err())
+	}
+	conn.SetDeadline(time.Time{})
+	return newConn(conn, br, true, opts), resp, nil
+}
+
+// ReadMessage returns the next data message. Ping frames received
+// meanwhile are answered. Once the peer closes the connection, the close
+// is acknowledged and a *CloseError returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ     MessageType
+		message []byte
+		started bool // A fragmented message is being read
+	)
+	for {
+		fin, op, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			if errors.Is(err, ErrMessageTooBig) {
+				c.Close(CloseMessageTooBig, "message too big")
+			}
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &CloseError{Code: CloseNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.Close(CloseNormal, "")
+			return 0, nil, closeErr
+		case opContinuation:
+			if !started {
+				return 0, nil, c.fail("continuation without a message")
+			}
+		case int(TextMessage), int(BinaryMessage):
+			if started {
+				return 0, nil, c.fail("new message inside a fragmented one")
+			}
+			typ, started = MessageType(op), true
+		default:
+			return 0, nil, c.fail(fmt.Sprintf("unknown opcode %d", op))
+		}
+		message = append(message, payload...)
+		if fin {
+			return typ, message, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single message of type typ.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	return c.writeFrame(int(typ), data)
+}
+
+// Ping sends a ping frame; the peer answers with a pong, which
+// ReadMessage consumes.
+func (c *Conn) Ping(data []byte) error {
+	return c.writeFrame(opPing, data)
+}
+
+// Close starts the closing handshake with code and reason, unless it has
+// already been started, and closes the connection. It does not wait for
+// the peer's acknowledgement.
+func (c *Conn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	sent := c.closeSent
+	c.closeSent = true
+	c.writeMu.Unlock()
+	if !sent {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+		if len(payload) > 125 {
+			payload = payload[:125]
+		}
+		c.writeControl(opClose, payload)
+	}
+	return c.conn.Close()
+}
+
+// SetReadDeadline sets the deadline of ReadMessage, e.g. to close idle
+// connections.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// fail closes the connection for a protocol error.
+func (c *Conn) fail(reason string) error {
+	c.Close(CloseProtocolError, reason)
+	return fmt.Errorf("websocket: protocol error: %s", reason)
+}
+
+// readFrame reads a frame, unmasking its payload. read is the length of
+// the message so far, bounding the frame by the maximum message size.
+func (c *Conn) readFrame(read int64) (fin bool, op int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = header[0]&0x80 != 0, int(header[0]&0x0f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail("reserved bits set")
+	}
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		// Clients must mask their frames and servers must not
+		return false, 0, nil, c.fail("wrong masking")
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]) & (1<<63 - 1))
+	}
+	control := op >= opClose
+	if control && (length > 125 || !fin) {
+		return false, 0, nil, c.fail("invalid control frame")
+	}
+	if !control && read+length > c.max {
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame sends payload as one final frame, unless the closing
+// handshake has started.
+func (c *Conn) writeFrame(op int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	return c.writeLocked(op, payload)
+}
+
+// writeControl sends a control frame even after closeSent was set, for
+// the close frame itself.
+func (c *Conn) writeControl(op int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeLocked(op, payload)
+}
+
+// writeLocked sends a frame. Called with writeMu held.
+func (c *Conn) writeLocked(op int, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|byte(op))
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value for key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether the comma-separated header name contains
+// token, ignoring case.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
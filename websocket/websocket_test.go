@@ -0,0 +1,171 @@
+package websocket_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mrhapile/wasm-plugin-system/websocket"
+)
+
+// TestWebsocket bootstraps the Ginkgo test suite for the websocket package.
+// Run with: go test -v ./websocket/...
+func TestWebsocket(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Websocket Suite")
+}
+
+var _ = Describe("Conn", func() {
+	var (
+		server   *httptest.Server
+		serverWS chan error // Receives how the server side's read loop ended
+	)
+
+	BeforeEach(func() {
+		ended := make(chan error, 1)
+		serverWS = ended
+		// An echo server accepting messages of up to 100 KiB
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := websocket.Upgrade(w, r, websocket.Options{MaxMessageSize: 100 << 10})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for {
+				typ, data, err := conn.ReadMessage()
+				if err != nil {
+					ended <- err
+					return
+				}
+				if err := conn.WriteMessage(typ, data); err != nil {
+					ended <- err
+					return
+				}
+			}
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	dial := func() *websocket.Conn {
+		conn, resp, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, websocket.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+		DeferCleanup(func() { conn.Close(websocket.CloseNormal, "") })
+		return conn
+	}
+
+	// =========================================================================
+	// TEST: Messages
+	// Why: Payload lengths take three encodings, and client frames are
+	//      masked while server frames are not; every combination must
+	//      arrive intact.
+	// =========================================================================
+	It("should exchange text and binary messages of any length", func() {
+		conn := dial()
+		for _, size := range []int{0, 125, 126, 65535, 65536, 100 << 10} {
+			data := []byte(strings.Repeat("x", size))
+			Expect(conn.WriteMessage(websocket.BinaryMessage, data)).To(Succeed())
+			typ, echoed, err := conn.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(typ).To(Equal(websocket.BinaryMessage))
+			Expect(echoed).To(HaveLen(size))
+		}
+
+		Expect(conn.Ping([]byte("are you there"))).To(Succeed())
+		Expect(conn.WriteMessage(websocket.TextMessage, []byte("hello"))).To(Succeed())
+		typ, echoed, err := conn.ReadMessage()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(typ).To(Equal(websocket.TextMessage))
+		Expect(string(echoed)).To(Equal("hello"))
+	})
+
+	It("should reassemble fragmented messages and answer pings between them", func() {
+		raw, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		Expect(err).NotTo(HaveOccurred())
+		defer raw.Close()
+		_, err = io.WriteString(raw, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+		Expect(err).NotTo(HaveOccurred())
+		br := bufio.NewReader(raw)
+		resp, err := http.ReadResponse(br, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Header.Get("Sec-WebSocket-Accept")).To(Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo="))
+
+		// Frames masked with a zero key, so payloads are sent as they are
+		frame := func(first byte, payload string) []byte {
+			return append([]byte{first, 0x80 | byte(len(payload)), 0, 0, 0, 0}, payload...)
+		}
+		_, err = raw.Write(append(append(append(
+			frame(0x01, "hel"),      // Text, not final
+			frame(0x89, "ping")...), // Ping
+			frame(0x00, "lo, ")...), // Continuation, not final
+			frame(0x80, "world")...)) // Continuation, final
+		Expect(err).NotTo(HaveOccurred())
+
+		reply := make([]byte, 2+len("ping")+2+len("hello, world"))
+		_, err = io.ReadFull(br, reply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply[:6]).To(Equal([]byte{0x8a, 4, 'p', 'i', 'n', 'g'}))
+		Expect(reply[6:8]).To(Equal([]byte{0x81, byte(len("hello, world"))}))
+		Expect(string(reply[8:])).To(Equal("hello, world"))
+	})
+
+	// =========================================================================
+	// TEST: Closing
+	// Why: The server must learn why a client left, and must not buffer
+	//      arbitrarily large messages from it.
+	// =========================================================================
+	It("should report the peer's close code", func() {
+		conn := dial()
+		Expect(conn.Close(websocket.CloseGoingAway, "bye")).To(Succeed())
+
+		var closeErr *websocket.CloseError
+		Eventually(serverWS).Should(Receive(BeAssignableToTypeOf(closeErr)))
+	})
+
+	It("should close the connection on messages beyond the maximum size", func() {
+		conn := dial()
+		Expect(conn.WriteMessage(websocket.BinaryMessage, make([]byte, 100<<10+1))).To(Succeed())
+
+		Eventually(serverWS).Should(Receive(MatchError(websocket.ErrMessageTooBig)))
+		_, _, err := conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		Expect(errors.As(err, &closeErr)).To(BeTrue())
+		Expect(closeErr.Code).To(Equal(websocket.CloseMessageTooBig))
+	})
+
+	It("should leave requests that are no handshake to the handler", func() {
+		_, resp, err := websocket.Dial(context.Background(), "http://"+strings.TrimPrefix(server.URL, "http://")+"/", nil, websocket.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+
+		plain, err := http.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer plain.Body.Close()
+		Expect(plain.StatusCode).To(Equal(http.StatusBadRequest))
+		body, _ := io.ReadAll(plain.Body)
+		Expect(string(body)).To(ContainSubstring("bad handshake"))
+	})
+
+	It("should return the response of a server refusing the upgrade", func() {
+		refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no streams here", http.StatusNotFound)
+		}))
+		defer refusing.Close()
+
+		_, resp, err := websocket.Dial(context.Background(), refusing.URL, nil, websocket.Options{})
+		Expect(err).To(MatchError(websocket.ErrBadHandshake))
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		body, _ := io.ReadAll(resp.Body)
+		Expect(string(body)).To(ContainSubstring("no streams here"))
+	})
+})